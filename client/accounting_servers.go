@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+type CreateAccountingServerOptions struct {
+	Name string `json:"name"`
+	// Address is "address" or "address:port"; the port defaults to 1813.
+	Address string `json:"address"`
+	Secret  string `json:"secret"`
+	// InterimInterval is in seconds, 0 or 60 to 86400. 0 sends no
+	// Interim-Updates.
+	InterimInterval int `json:"interim_interval"`
+}
+
+// UpdateAccountingServerOptions replaces a server's settings. An empty
+// Secret keeps the current one.
+type UpdateAccountingServerOptions struct {
+	Address         string `json:"address"`
+	Secret          string `json:"secret,omitempty"`
+	InterimInterval int    `json:"interim_interval"`
+}
+
+type GetAccountingServerOptions struct {
+	Name string `json:"name"`
+}
+
+type DeleteAccountingServerOptions struct {
+	Name string `json:"name"`
+}
+
+// AccountingServer receives RADIUS accounting for every session. The
+// shared secret is never returned.
+type AccountingServer struct {
+	Name            string `json:"name"`
+	Address         string `json:"address"`
+	InterimInterval int    `json:"interim_interval"`
+}
+
+type ListAccountingServersResponse struct {
+	Items []AccountingServer `json:"items"`
+}
+
+// CreateAccountingServer creates a new accounting server.
+func (c *Client) CreateAccountingServer(ctx context.Context, opts *CreateAccountingServerOptions) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "POST",
+		Path:   "api/v1/networking/accounting-servers",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAccountingServer retrieves an accounting server by name.
+func (c *Client) GetAccountingServer(ctx context.Context, opts *GetAccountingServerOptions) (*AccountingServer, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/accounting-servers/" + opts.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var server AccountingServer
+
+	err = resp.DecodeResult(&server)
+	if err != nil {
+		return nil, err
+	}
+
+	return &server, nil
+}
+
+// UpdateAccountingServer replaces the settings of an accounting server.
+func (c *Client) UpdateAccountingServer(ctx context.Context, name string, opts *UpdateAccountingServerOptions) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/accounting-servers/" + name,
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteAccountingServer deletes an accounting server by name. Records
+// still queued for it are dropped.
+func (c *Client) DeleteAccountingServer(ctx context.Context, opts *DeleteAccountingServerOptions) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/networking/accounting-servers/" + opts.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListAccountingServers lists all accounting servers.
+func (c *Client) ListAccountingServers(ctx context.Context) (*ListAccountingServersResponse, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/accounting-servers",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var servers ListAccountingServersResponse
+
+	err = resp.DecodeResult(&servers)
+	if err != nil {
+		return nil, err
+	}
+
+	return &servers, nil
+}
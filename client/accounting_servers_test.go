@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ellanetworks/core/client"
+)
+
+func TestCreateAccountingServer_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 201,
+			Headers:    http.Header{},
+			Result:     []byte(`{"message": "Accounting server created successfully"}`),
+		},
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	opts := &client.CreateAccountingServerOptions{
+		Name:            "billing",
+		Address:         "192.0.2.10",
+		Secret:          "testing123",
+		InterimInterval: 300,
+	}
+
+	err := clientObj.CreateAccountingServer(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Method != "POST" {
+		t.Fatalf("expected POST method, got: %s", fake.lastOpts.Method)
+	}
+
+	if fake.lastOpts.Path != "api/v1/networking/accounting-servers" {
+		t.Fatalf("expected path api/v1/networking/accounting-servers, got: %s", fake.lastOpts.Path)
+	}
+}
+
+func TestGetAccountingServer_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"name": "billing", "address": "192.0.2.10:1813", "interim_interval": 300}`),
+		},
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	server, err := clientObj.GetAccountingServer(context.Background(), &client.GetAccountingServerOptions{Name: "billing"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Path != "api/v1/networking/accounting-servers/billing" {
+		t.Fatalf("expected path api/v1/networking/accounting-servers/billing, got: %s", fake.lastOpts.Path)
+	}
+
+	if server.Address != "192.0.2.10:1813" || server.InterimInterval != 300 {
+		t.Fatalf("unexpected accounting server: %+v", server)
+	}
+}
+
+func TestDeleteAccountingServer_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "Accounting server not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	err := clientObj.DeleteAccountingServer(context.Background(), &client.DeleteAccountingServerOptions{Name: "missing"})
+	if err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+)
+
+// CDRFile is a closed file of charging data records. Format is "csv" or
+// "ber".
+type CDRFile struct {
+	Name       string `json:"name"`
+	Format     string `json:"format"`
+	Size       int64  `json:"size"`
+	ModifiedAt string `json:"modified_at"`
+}
+
+type ListCDRFilesResponse struct {
+	Items []CDRFile `json:"items"`
+}
+
+type DownloadCDRFileParams struct {
+	// Name is the file name, as listed.
+	Name string
+	// Path is where the file is saved.
+	Path string
+}
+
+// ListCDRFiles lists the closed CDR files of the node the client talks to.
+func (c *Client) ListCDRFiles(ctx context.Context) (*ListCDRFilesResponse, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/charging/cdr-files",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var files ListCDRFilesResponse
+
+	err = resp.DecodeResult(&files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &files, nil
+}
+
+// DownloadCDRFile downloads a closed CDR file and saves it to the specified
+// path.
+func (c *Client) DownloadCDRFile(ctx context.Context, p *DownloadCDRFileParams) error {
+	if p == nil {
+		return fmt.Errorf("DownloadCDRFileParams is nil")
+	}
+
+	if p.Name == "" || p.Path == "" {
+		return fmt.Errorf("name and path are required")
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   RawRequest,
+		Method: "GET",
+		Path:   "api/v1/charging/cdr-files/" + url.PathEscape(p.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download CDR file: %w", err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	out, err := os.Create(p.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create CDR file: %w", err)
+	}
+
+	defer func() {
+		_ = out.Close()
+	}()
+
+	_, err = io.Copy(out, resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write CDR file: %w", err)
+	}
+
+	return nil
+}
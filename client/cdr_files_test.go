@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ellanetworks/core/client"
+)
+
+func TestListCDRFiles_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"items": [{"name": "ella-core-1_20260304T050000Z_000000.csv", "format": "csv", "size": 120, "modified_at": "2026-03-04T05:15:02Z"}]}`),
+		},
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	files, err := clientObj.ListCDRFiles(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Path != "api/v1/charging/cdr-files" {
+		t.Fatalf("expected path api/v1/charging/cdr-files, got: %s", fake.lastOpts.Path)
+	}
+
+	if len(files.Items) != 1 || files.Items[0].Format != "csv" || files.Items[0].Size != 120 {
+		t.Fatalf("unexpected CDR files: %+v", files.Items)
+	}
+}
+
+func TestDownloadCDRFile_Success(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "records.csv")
+
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Body:       io.NopCloser(strings.NewReader("local_sequence_number\n")),
+		},
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	err := clientObj.DownloadCDRFile(context.Background(), &client.DownloadCDRFileParams{
+		Name: "ella-core-1_20260304T050000Z_000000.csv",
+		Path: path,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Path != "api/v1/charging/cdr-files/ella-core-1_20260304T050000Z_000000.csv" {
+		t.Fatalf("unexpected path: %s", fake.lastOpts.Path)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil || string(b) != "local_sequence_number\n" {
+		t.Fatalf("saved %q (%v)", b, err)
+	}
+}
+
+func TestDownloadCDRFile_MissingName(t *testing.T) {
+	clientObj := &client.Client{
+		Requester: &fakeRequester{},
+	}
+
+	err := clientObj.DownloadCDRFile(context.Background(), &client.DownloadCDRFileParams{Path: "x"})
+	if err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
@@ -11,12 +11,21 @@ import (
 	"net/url"
 )
 
+// DataNetworkEgress selects where a data network's uplink leaves the node.
+// An empty Interface means the N6 interface.
+type DataNetworkEgress struct {
+	Interface    string `json:"interface,omitempty"`
+	VlanID       int    `json:"vlan_id,omitempty"`
+	RoutingTable int    `json:"routing_table,omitempty"`
+}
+
 type CreateDataNetworkOptions struct {
-	Name     string `json:"name"`
-	IPv4Pool string `json:"ipv4_pool"`
-	IPv6Pool string `json:"ipv6_pool,omitempty"`
-	DNS      string `json:"dns"`
-	Mtu      int32  `json:"mtu"`
+	Name     string             `json:"name"`
+	IPv4Pool string             `json:"ipv4_pool"`
+	IPv6Pool string             `json:"ipv6_pool,omitempty"`
+	DNS      string             `json:"dns"`
+	Mtu      int32              `json:"mtu"`
+	Egress   *DataNetworkEgress `json:"egress,omitempty"`
 }
 
 type UpdateDataNetworkOptions struct {
@@ -25,6 +34,8 @@ type UpdateDataNetworkOptions struct {
 	IPv6Pool string `json:"ipv6_pool,omitempty"`
 	DNS      string `json:"dns"`
 	Mtu      int32  `json:"mtu"`
+	// Egress is left unchanged when nil; an empty value clears it.
+	Egress *DataNetworkEgress `json:"egress,omitempty"`
 }
 
 type GetDataNetworkOptions struct {
@@ -57,6 +68,7 @@ type DataNetwork struct {
 	Mtu          int32                    `json:"mtu"`
 	Status       DataNetworkStatus        `json:"status"`
 	IPAllocation *DataNetworkIPAllocation `json:"ip_allocation,omitempty"`
+	Egress       *DataNetworkEgress       `json:"egress,omitempty"`
 }
 
 type IPAllocation struct {
@@ -83,17 +95,19 @@ type ListDataNetworksResponse struct {
 // CreateDataNetwork creates a new data network with the provided options.
 func (c *Client) CreateDataNetwork(ctx context.Context, opts *CreateDataNetworkOptions) error {
 	payload := struct {
-		Name     string `json:"name"`
-		IPv4Pool string `json:"ipv4_pool"`
-		IPv6Pool string `json:"ipv6_pool,omitempty"`
-		DNS      string `json:"dns"`
-		Mtu      int32  `json:"mtu"`
+		Name     string             `json:"name"`
+		IPv4Pool string             `json:"ipv4_pool"`
+		IPv6Pool string             `json:"ipv6_pool,omitempty"`
+		DNS      string             `json:"dns"`
+		Mtu      int32              `json:"mtu"`
+		Egress   *DataNetworkEgress `json:"egress,omitempty"`
 	}{
 		Name:     opts.Name,
 		IPv4Pool: opts.IPv4Pool,
 		IPv6Pool: opts.IPv6Pool,
 		DNS:      opts.DNS,
 		Mtu:      opts.Mtu,
+		Egress:   opts.Egress,
 	}
 
 	var body bytes.Buffer
@@ -119,17 +133,19 @@ func (c *Client) CreateDataNetwork(ctx context.Context, opts *CreateDataNetworkO
 // UpdateDataNetwork updates an existing data network with the provided options.
 func (c *Client) UpdateDataNetwork(ctx context.Context, opts *UpdateDataNetworkOptions) error {
 	payload := struct {
-		Name     string `json:"name"`
-		IPv4Pool string `json:"ipv4_pool"`
-		IPv6Pool string `json:"ipv6_pool,omitempty"`
-		DNS      string `json:"dns"`
-		Mtu      int32  `json:"mtu"`
+		Name     string             `json:"name"`
+		IPv4Pool string             `json:"ipv4_pool"`
+		IPv6Pool string             `json:"ipv6_pool,omitempty"`
+		DNS      string             `json:"dns"`
+		Mtu      int32              `json:"mtu"`
+		Egress   *DataNetworkEgress `json:"egress,omitempty"`
 	}{
 		Name:     opts.Name,
 		IPv4Pool: opts.IPv4Pool,
 		IPv6Pool: opts.IPv6Pool,
 		DNS:      opts.DNS,
 		Mtu:      opts.Mtu,
+		Egress:   opts.Egress,
 	}
 
 	var body bytes.Buffer
@@ -437,6 +453,634 @@ func (c *Client) DeleteDataNetworkFramedRoute(ctx context.Context, dataNetwork,
 	return nil
 }
 
+// DataNetworkNAT is a data network's public source pool. An empty Addresses
+// list means the data network masquerades on its egress address.
+type DataNetworkNAT struct {
+	Addresses []string `json:"addresses"`
+	Mapping   string   `json:"mapping"`
+	PortMin   int      `json:"port_min,omitempty"`
+	PortMax   int      `json:"port_max,omitempty"`
+}
+
+type PortForward struct {
+	ID            string `json:"id"`
+	Protocol      string `json:"protocol"`
+	PublicAddress string `json:"public_address"`
+	PublicPort    int    `json:"public_port"`
+	IMSI          string `json:"imsi,omitempty"`
+	UEAddress     string `json:"ue_address,omitempty"`
+	UEPort        int    `json:"ue_port"`
+}
+
+type PortForwardList struct {
+	Items      []PortForward `json:"items"`
+	Page       int           `json:"page"`
+	PerPage    int           `json:"per_page"`
+	TotalCount int           `json:"total_count"`
+}
+
+type CreatePortForwardOptions struct {
+	Protocol      string `json:"protocol"`
+	PublicAddress string `json:"public_address"`
+	PublicPort    int    `json:"public_port"`
+	IMSI          string `json:"imsi,omitempty"`
+	UEAddress     string `json:"ue_address,omitempty"`
+	UEPort        int    `json:"ue_port"`
+}
+
+// GetDataNetworkNAT returns a data network's NAT pool.
+func (c *Client) GetDataNetworkNAT(ctx context.Context, dataNetwork string) (*DataNetworkNAT, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/nat",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var nat DataNetworkNAT
+
+	err = resp.DecodeResult(&nat)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nat, nil
+}
+
+// UpdateDataNetworkNAT replaces a data network's NAT pool. An empty address
+// list removes it.
+func (c *Client) UpdateDataNetworkNAT(ctx context.Context, dataNetwork string, nat *DataNetworkNAT) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(nat)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/nat",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListDataNetworkPortForwards lists the port forwards on a data network.
+func (c *Client) ListDataNetworkPortForwards(ctx context.Context, dataNetwork string) (*PortForwardList, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/port-forwards",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list PortForwardList
+
+	err = resp.DecodeResult(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// CreateDataNetworkPortForward exposes a UE port on a public address and
+// port, and returns the created forward.
+func (c *Client) CreateDataNetworkPortForward(ctx context.Context, dataNetwork string, opts *CreatePortForwardOptions) (*PortForward, error) {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "POST",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/port-forwards",
+		Body:   &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var fwd PortForward
+
+	err = resp.DecodeResult(&fwd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fwd, nil
+}
+
+// DeleteDataNetworkPortForward removes a port forward.
+func (c *Client) DeleteDataNetworkPortForward(ctx context.Context, dataNetwork, id string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/port-forwards/" + id,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DataNetworkTCPMSS is a data network's TCP MSS clamp. A zero MSS is derived
+// from the data network's MTU.
+type DataNetworkTCPMSS struct {
+	Enabled bool `json:"enabled"`
+	MSS     int  `json:"mss,omitempty"`
+}
+
+// GetDataNetworkTCPMSS returns a data network's TCP MSS clamp.
+func (c *Client) GetDataNetworkTCPMSS(ctx context.Context, dataNetwork string) (*DataNetworkTCPMSS, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/tcp-mss-clamp",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var clamp DataNetworkTCPMSS
+
+	err = resp.DecodeResult(&clamp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clamp, nil
+}
+
+// UpdateDataNetworkTCPMSS enables or disables a data network's TCP MSS clamp.
+func (c *Client) UpdateDataNetworkTCPMSS(ctx context.Context, dataNetwork string, clamp *DataNetworkTCPMSS) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(clamp)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/tcp-mss-clamp",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DataNetworkDNSResolver is a data network's embedded DNS forwarder. While
+// it is enabled, UEs are handed ListenAddress as their DNS server.
+type DataNetworkDNSResolver struct {
+	Enabled       bool     `json:"enabled"`
+	ListenAddress string   `json:"listen_address,omitempty"`
+	Upstreams     []string `json:"upstreams"`
+	LocalZones    []string `json:"local_zones"`
+	LogQueries    bool     `json:"log_queries"`
+}
+
+type DNSRecord struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	IMSI    string `json:"imsi,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+type DNSRecordList struct {
+	Items      []DNSRecord `json:"items"`
+	Page       int         `json:"page"`
+	PerPage    int         `json:"per_page"`
+	TotalCount int         `json:"total_count"`
+}
+
+type CreateDNSRecordOptions struct {
+	Name    string `json:"name"`
+	IMSI    string `json:"imsi,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// GetDataNetworkDNSResolver returns a data network's DNS resolver.
+func (c *Client) GetDataNetworkDNSResolver(ctx context.Context, dataNetwork string) (*DataNetworkDNSResolver, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/dns-resolver",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resolver DataNetworkDNSResolver
+
+	err = resp.DecodeResult(&resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resolver, nil
+}
+
+// UpdateDataNetworkDNSResolver enables, changes or disables a data
+// network's DNS resolver.
+func (c *Client) UpdateDataNetworkDNSResolver(ctx context.Context, dataNetwork string, resolver *DataNetworkDNSResolver) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(resolver)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/dns-resolver",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListDataNetworkDNSRecords lists the local DNS records of a data network.
+func (c *Client) ListDataNetworkDNSRecords(ctx context.Context, dataNetwork string) (*DNSRecordList, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/dns-records",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list DNSRecordList
+
+	err = resp.DecodeResult(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// CreateDataNetworkDNSRecord adds a local DNS record, for a fixed address or
+// a subscriber's, and returns the created record.
+func (c *Client) CreateDataNetworkDNSRecord(ctx context.Context, dataNetwork string, opts *CreateDNSRecordOptions) (*DNSRecord, error) {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "POST",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/dns-records",
+		Body:   &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rec DNSRecord
+
+	err = resp.DecodeResult(&rec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rec, nil
+}
+
+// DeleteDataNetworkDNSRecord removes a local DNS record.
+func (c *Client) DeleteDataNetworkDNSRecord(ctx context.Context, dataNetwork, id string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/dns-records/" + id,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DataNetworkAddressAllocation is where a data network's UE IPv4 addresses
+// come from: its pool, a DHCPv4 server Ella Core relays to from
+// RelayAddress, or a RADIUS server's Framed-IP-Address. Secret is
+// write-only; leaving it out on update keeps the current one.
+type DataNetworkAddressAllocation struct {
+	Mode           string `json:"mode"`
+	Server         string `json:"server,omitempty"`
+	RelayAddress   string `json:"relay_address,omitempty"`
+	Secret         string `json:"secret,omitempty"`
+	FallbackToPool bool   `json:"fallback_to_pool"`
+}
+
+// GetDataNetworkAddressAllocation returns where a data network's UE
+// addresses come from.
+func (c *Client) GetDataNetworkAddressAllocation(ctx context.Context, dataNetwork string) (*DataNetworkAddressAllocation, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/address-allocation",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var alloc DataNetworkAddressAllocation
+
+	err = resp.DecodeResult(&alloc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &alloc, nil
+}
+
+// UpdateDataNetworkAddressAllocation changes where a data network's UE
+// addresses come from.
+func (c *Client) UpdateDataNetworkAddressAllocation(ctx context.Context, dataNetwork string, alloc *DataNetworkAddressAllocation) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(alloc)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/address-allocation",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DataNetworkSecondaryAuth is whether a data network authenticates users
+// against a DN-AAA RADIUS server. Secret is write-only; leaving it out on
+// update keeps the current one.
+type DataNetworkSecondaryAuth struct {
+	Enabled bool   `json:"enabled"`
+	Server  string `json:"server,omitempty"`
+	Secret  string `json:"secret,omitempty"`
+}
+
+// GetDataNetworkSecondaryAuth returns a data network's secondary
+// authentication settings.
+func (c *Client) GetDataNetworkSecondaryAuth(ctx context.Context, dataNetwork string) (*DataNetworkSecondaryAuth, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/secondary-authentication",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var auth DataNetworkSecondaryAuth
+
+	err = resp.DecodeResult(&auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth, nil
+}
+
+// UpdateDataNetworkSecondaryAuth turns a data network's secondary
+// authentication on or off.
+func (c *Client) UpdateDataNetworkSecondaryAuth(ctx context.Context, dataNetwork string, auth *DataNetworkSecondaryAuth) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(auth)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/secondary-authentication",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DataNetworkOnlineCharging is whether sessions on a data network are held
+// to credit granted by an online charging system. Server is a Diameter peer
+// as host:port, or the Nchf API root as a URL; Realm applies to Diameter
+// only. FailureHandling is terminate (the default) or continue.
+type DataNetworkOnlineCharging struct {
+	Enabled         bool   `json:"enabled"`
+	Protocol        string `json:"protocol,omitempty"`
+	Server          string `json:"server,omitempty"`
+	Realm           string `json:"realm,omitempty"`
+	RatingGroup     int64  `json:"rating_group,omitempty"`
+	FailureHandling string `json:"failure_handling,omitempty"`
+}
+
+// GetDataNetworkOnlineCharging returns a data network's online charging
+// settings.
+func (c *Client) GetDataNetworkOnlineCharging(ctx context.Context, dataNetwork string) (*DataNetworkOnlineCharging, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/online-charging",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var oc DataNetworkOnlineCharging
+
+	err = resp.DecodeResult(&oc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oc, nil
+}
+
+// UpdateDataNetworkOnlineCharging turns a data network's online charging
+// on or off.
+func (c *Client) UpdateDataNetworkOnlineCharging(ctx context.Context, dataNetwork string, oc *DataNetworkOnlineCharging) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(oc)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/online-charging",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DataNetworkPolicyControl is whether an external policy decision point
+// sets the policy of 5G sessions on a data network. URL is the root of its
+// Npcf_SMPolicyControl API.
+type DataNetworkPolicyControl struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url,omitempty"`
+}
+
+// GetDataNetworkPolicyControl returns a data network's policy control
+// settings.
+func (c *Client) GetDataNetworkPolicyControl(ctx context.Context, dataNetwork string) (*DataNetworkPolicyControl, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/policy-control",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pc DataNetworkPolicyControl
+
+	err = resp.DecodeResult(&pc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pc, nil
+}
+
+// UpdateDataNetworkPolicyControl turns a data network's external policy
+// control on or off.
+func (c *Client) UpdateDataNetworkPolicyControl(ctx context.Context, dataNetwork string, pc *DataNetworkPolicyControl) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(pc)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/policy-control",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateDataNetworkQuotaOptions caps the sessions of a data network. A zero
+// limit is no limit, and a zero BackOffTimer sends the default back-off.
+type UpdateDataNetworkQuotaOptions struct {
+	MaxSessions  int `json:"max_sessions"`
+	BackOffTimer int `json:"back_off_timer"`
+}
+
+// DataNetworkQuota is a data network's quota alongside the sessions the
+// answering node currently counts against it.
+type DataNetworkQuota struct {
+	MaxSessions  int `json:"max_sessions"`
+	BackOffTimer int `json:"back_off_timer"`
+	Sessions     int `json:"sessions"`
+}
+
+// GetDataNetworkQuota returns a data network's admission quota and live
+// session count.
+func (c *Client) GetDataNetworkQuota(ctx context.Context, dataNetwork string) (*DataNetworkQuota, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/quota",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var quota DataNetworkQuota
+
+	err = resp.DecodeResult(&quota)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quota, nil
+}
+
+// UpdateDataNetworkQuota sets a data network's admission quota.
+func (c *Client) UpdateDataNetworkQuota(ctx context.Context, dataNetwork string, opts *UpdateDataNetworkQuotaOptions) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/quota",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteDataNetworkQuota lifts a data network's admission quota.
+func (c *Client) DeleteDataNetworkQuota(ctx context.Context, dataNetwork string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/networking/data-networks/" + dataNetwork + "/quota",
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // ListIPv4Allocations lists IPv4 allocations for a data network with pagination support.
 func (c *Client) ListIPv4Allocations(ctx context.Context, opts *ListIPAllocationsOptions, p *ListParams) (*ListIPAllocationsResponse, error) {
 	resp, err := c.Requester.Do(ctx, &RequestOptions{
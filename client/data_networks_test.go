@@ -546,3 +546,425 @@ func TestDeleteDataNetworkStaticIp_Failure(t *testing.T) {
 		t.Fatalf("expected error, got none")
 	}
 }
+
+func TestGetDataNetworkNAT_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"addresses": ["203.0.113.10", "203.0.113.11"], "mapping": "round_robin", "port_min": 20000, "port_max": 29999}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	nat, err := clientObj.GetDataNetworkNAT(context.Background(), "internet")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(nat.Addresses) != 2 || nat.Mapping != "round_robin" || nat.PortMin != 20000 || nat.PortMax != 29999 {
+		t.Fatalf("unexpected NAT pool: %+v", nat)
+	}
+
+	if fake.lastOpts.Path != "api/v1/networking/data-networks/internet/nat" {
+		t.Fatalf("unexpected path: %s", fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateDataNetworkNAT_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 409,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "NAT address 203.0.113.10 is already used by data network \"iot\""}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateDataNetworkNAT(context.Background(), "internet", &client.DataNetworkNAT{Addresses: []string{"203.0.113.10"}, Mapping: "deterministic"})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestGetDataNetworkTCPMSS_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"enabled": true, "mss": 1360}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	clamp, err := clientObj.GetDataNetworkTCPMSS(context.Background(), "internet")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !clamp.Enabled || clamp.MSS != 1360 {
+		t.Fatalf("unexpected TCP MSS clamp: %+v", clamp)
+	}
+
+	if fake.lastOpts.Path != "api/v1/networking/data-networks/internet/tcp-mss-clamp" {
+		t.Fatalf("unexpected path: %s", fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateDataNetworkTCPMSS_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 400,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "invalid mss, must be 0 or between 536 and 1460"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateDataNetworkTCPMSS(context.Background(), "internet", &client.DataNetworkTCPMSS{Enabled: true, MSS: 100})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestCreateDataNetworkPortForward_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 201,
+			Headers:    http.Header{},
+			Result:     []byte(`{"id": "0190", "protocol": "tcp", "public_address": "203.0.113.10", "public_port": 8080, "imsi": "001010000000001", "ue_port": 80}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	fwd, err := clientObj.CreateDataNetworkPortForward(context.Background(), "internet", &client.CreatePortForwardOptions{
+		Protocol:      "tcp",
+		PublicAddress: "203.0.113.10",
+		PublicPort:    8080,
+		IMSI:          "001010000000001",
+		UEPort:        80,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fwd.ID != "0190" || fwd.PublicPort != 8080 {
+		t.Fatalf("unexpected port forward: %+v", fwd)
+	}
+
+	if fake.lastOpts.Method != "POST" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/port-forwards" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestDeleteDataNetworkPortForward_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "Port forward not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.DeleteDataNetworkPortForward(context.Background(), "internet", "missing")
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestGetDataNetworkDNSResolver_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"enabled": true, "listen_address": "192.168.50.1", "upstreams": ["1.1.1.1"], "local_zones": ["corp.example"], "log_queries": true}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	resolver, err := clientObj.GetDataNetworkDNSResolver(context.Background(), "internet")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !resolver.Enabled || resolver.ListenAddress != "192.168.50.1" || len(resolver.LocalZones) != 1 || !resolver.LogQueries {
+		t.Fatalf("unexpected DNS resolver: %+v", resolver)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/dns-resolver" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateDataNetworkDNSResolver_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 400,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "invalid upstreams: between 1 and 4 upstreams are required"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateDataNetworkDNSResolver(context.Background(), "internet", &client.DataNetworkDNSResolver{Enabled: true, ListenAddress: "192.168.50.1"})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestCreateDataNetworkDNSRecord_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 201,
+			Headers:    http.Header{},
+			Result:     []byte(`{"id": "0190", "name": "camera.corp.example", "imsi": "001010000000001"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	rec, err := clientObj.CreateDataNetworkDNSRecord(context.Background(), "internet", &client.CreateDNSRecordOptions{
+		Name: "camera.corp.example",
+		IMSI: "001010000000001",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if rec.ID != "0190" || rec.IMSI != "001010000000001" {
+		t.Fatalf("unexpected DNS record: %+v", rec)
+	}
+
+	if fake.lastOpts.Method != "POST" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/dns-records" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestDeleteDataNetworkDNSRecord_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "DNS record not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.DeleteDataNetworkDNSRecord(context.Background(), "internet", "missing")
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestGetDataNetworkAddressAllocation_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"mode": "dhcp", "server": "10.100.0.2", "relay_address": "10.100.0.1", "fallback_to_pool": true}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	alloc, err := clientObj.GetDataNetworkAddressAllocation(context.Background(), "internet")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if alloc.Mode != "dhcp" || alloc.RelayAddress != "10.100.0.1" || !alloc.FallbackToPool {
+		t.Fatalf("unexpected address allocation: %+v", alloc)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/address-allocation" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateDataNetworkAddressAllocation_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 400,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "secret is required in radius mode"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateDataNetworkAddressAllocation(context.Background(), "internet", &client.DataNetworkAddressAllocation{Mode: "radius", Server: "10.100.0.3"})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestGetDataNetworkSecondaryAuth_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"enabled": true, "server": "10.100.0.3"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	auth, err := clientObj.GetDataNetworkSecondaryAuth(context.Background(), "internet")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !auth.Enabled || auth.Server != "10.100.0.3" {
+		t.Fatalf("unexpected secondary authentication: %+v", auth)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/secondary-authentication" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateDataNetworkSecondaryAuth_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 400,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "secret is required"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateDataNetworkSecondaryAuth(context.Background(), "internet", &client.DataNetworkSecondaryAuth{Enabled: true, Server: "10.100.0.3"})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestGetDataNetworkOnlineCharging_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"enabled": true, "protocol": "nchf", "server": "http://chf.example.org", "rating_group": 10, "failure_handling": "terminate"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	oc, err := clientObj.GetDataNetworkOnlineCharging(context.Background(), "internet")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !oc.Enabled || oc.Protocol != "nchf" || oc.RatingGroup != 10 {
+		t.Fatalf("unexpected online charging: %+v", oc)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/online-charging" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateDataNetworkOnlineCharging_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 400,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "realm is required for diameter"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateDataNetworkOnlineCharging(context.Background(), "internet", &client.DataNetworkOnlineCharging{Enabled: true, Protocol: "diameter", Server: "ocs.example.org:3868", RatingGroup: 1})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestGetDataNetworkPolicyControl_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"enabled": true, "url": "https://pcf.example.org"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	pc, err := clientObj.GetDataNetworkPolicyControl(context.Background(), "internet")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !pc.Enabled || pc.URL != "https://pcf.example.org" {
+		t.Fatalf("unexpected policy control: %+v", pc)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/policy-control" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateDataNetworkPolicyControl_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 400,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "invalid url, must be an http or https URL"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateDataNetworkPolicyControl(context.Background(), "internet", &client.DataNetworkPolicyControl{Enabled: true, URL: "pcf.example.org"})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestGetDataNetworkQuota_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"max_sessions": 500, "back_off_timer": 60, "sessions": 212}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	quota, err := clientObj.GetDataNetworkQuota(context.Background(), "internet")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if quota.MaxSessions != 500 || quota.BackOffTimer != 60 || quota.Sessions != 212 {
+		t.Fatalf("unexpected quota: %+v", quota)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/quota" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateDataNetworkQuota_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"message": "Data network quota updated successfully"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateDataNetworkQuota(context.Background(), "internet", &client.UpdateDataNetworkQuotaOptions{MaxSessions: 500})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Method != "PUT" || fake.lastOpts.Path != "api/v1/networking/data-networks/internet/quota" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// MonitoringSubscription is an application's subscription to an event of a
+// subscriber's UE. Reports counts the reports posted when MaxReports limits
+// them.
+type MonitoringSubscription struct {
+	ID              string `json:"id"`
+	IMSI            string `json:"imsi"`
+	Event           string `json:"event"`
+	NotificationURL string `json:"notification_url"`
+	MaxReports      int    `json:"max_reports,omitempty"`
+	Reports         int    `json:"reports"`
+	CreatedAt       string `json:"created_at"`
+	ExpiresAt       string `json:"expires_at,omitempty"`
+}
+
+type ListMonitoringSubscriptionsResponse struct {
+	Items []MonitoringSubscription `json:"items"`
+}
+
+// CreateMonitoringSubscriptionOptions subscribes to Event of the UE of
+// subscriber IMSI, reported to NotificationURL. A zero MaxReports reports
+// until the subscription is deleted or expires; a zero Duration never
+// expires it.
+type CreateMonitoringSubscriptionOptions struct {
+	IMSI            string `json:"imsi"`
+	Event           string `json:"event"`
+	NotificationURL string `json:"notification_url"`
+	MaxReports      int    `json:"max_reports,omitempty"`
+	Duration        int64  `json:"duration,omitempty"`
+}
+
+// ListMonitoringSubscriptions lists the monitoring subscriptions.
+func (c *Client) ListMonitoringSubscriptions(ctx context.Context) (*ListMonitoringSubscriptionsResponse, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/monitoring-subscriptions",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list ListMonitoringSubscriptionsResponse
+
+	err = resp.DecodeResult(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// CreateMonitoringSubscription subscribes to an event of a subscriber's UE
+// and returns the subscription as recorded.
+func (c *Client) CreateMonitoringSubscription(ctx context.Context, opts *CreateMonitoringSubscriptionOptions) (*MonitoringSubscription, error) {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "POST",
+		Path:   "api/v1/monitoring-subscriptions",
+		Body:   &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var sub MonitoringSubscription
+
+	err = resp.DecodeResult(&sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+// GetMonitoringSubscription retrieves a monitoring subscription by ID.
+func (c *Client) GetMonitoringSubscription(ctx context.Context, id string) (*MonitoringSubscription, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/monitoring-subscriptions/" + id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var sub MonitoringSubscription
+
+	err = resp.DecodeResult(&sub)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+// DeleteMonitoringSubscription deletes a monitoring subscription.
+func (c *Client) DeleteMonitoringSubscription(ctx context.Context, id string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/monitoring-subscriptions/" + id,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
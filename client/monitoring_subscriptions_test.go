@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ellanetworks/core/client"
+)
+
+func TestListMonitoringSubscriptions_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"items": [{"id": "ms-1", "imsi": "001010100007487", "event": "UE_REACHABILITY", "reports": 0}]}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	list, err := clientObj.ListMonitoringSubscriptions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(list.Items) != 1 || list.Items[0].ID != "ms-1" || list.Items[0].Event != "UE_REACHABILITY" {
+		t.Fatalf("unexpected monitoring subscriptions: %+v", list.Items)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/monitoring-subscriptions" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestCreateMonitoringSubscription_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 201,
+			Headers:    http.Header{},
+			Result:     []byte(`{"id": "ms-1", "imsi": "001010100007487", "event": "LOCATION_REPORTING", "max_reports": 3, "reports": 0}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	sub, err := clientObj.CreateMonitoringSubscription(context.Background(), &client.CreateMonitoringSubscriptionOptions{
+		IMSI:            "001010100007487",
+		Event:           "LOCATION_REPORTING",
+		NotificationURL: "https://app.example.com/ue-events",
+		MaxReports:      3,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if sub.ID != "ms-1" || sub.MaxReports != 3 {
+		t.Fatalf("unexpected monitoring subscription: %+v", sub)
+	}
+
+	if fake.lastOpts.Method != "POST" || fake.lastOpts.Path != "api/v1/monitoring-subscriptions" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestGetMonitoringSubscription_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"id": "ms-1", "event": "ROAMING_STATUS", "expires_at": "2026-10-20T09:00:00Z"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	sub, err := clientObj.GetMonitoringSubscription(context.Background(), "ms-1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if sub.ExpiresAt == "" || fake.lastOpts.Path != "api/v1/monitoring-subscriptions/ms-1" {
+		t.Fatalf("unexpected monitoring subscription %+v from %s", sub, fake.lastOpts.Path)
+	}
+}
+
+func TestDeleteMonitoringSubscription_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "Monitoring subscription not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	if err := clientObj.DeleteMonitoringSubscription(context.Background(), "missing"); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if fake.lastOpts.Method != "DELETE" || fake.lastOpts.Path != "api/v1/monitoring-subscriptions/missing" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
@@ -14,10 +14,42 @@ import (
 type PolicyRule struct {
 	Description  string  `json:"description"`
 	RemotePrefix *string `json:"remote_prefix,omitempty"`
+	FQDN         string  `json:"fqdn,omitempty"`
+	MatchSNI     bool    `json:"match_sni,omitempty"`
 	Protocol     int32   `json:"protocol"`
 	PortLow      int32   `json:"port_low"`
 	PortHigh     int32   `json:"port_high"`
 	Action       string  `json:"action"`
+	// RateLimit applies when Action is "rate_limit", e.g. "5 Mbps".
+	// RateLimitScope is "session" (the default) or "rule".
+	RateLimit      string `json:"rate_limit,omitempty"`
+	RateLimitScope string `json:"rate_limit_scope,omitempty"`
+	// Schedule names a schedule the rule is limited to. Empty applies
+	// the rule at all times.
+	Schedule string `json:"schedule,omitempty"`
+	// Breakout is required when Action is "breakout", on uplink rules only.
+	Breakout *RuleBreakout `json:"breakout,omitempty"`
+	// RatingGroup counts the traffic the rule passes under that rating
+	// group; ZeroRated leaves it out of the subscriber's usage. Neither
+	// applies to "deny" rules.
+	RatingGroup uint32 `json:"rating_group,omitempty"`
+	ZeroRated   bool   `json:"zero_rated,omitempty"`
+}
+
+// RuleBreakout is the local egress a breakout rule sends matching uplink
+// traffic through, such as the interface facing an edge compute subnet.
+type RuleBreakout struct {
+	Interface    string `json:"interface"`
+	VlanID       int    `json:"vlan_id,omitempty"`
+	RoutingTable int    `json:"routing_table,omitempty"`
+}
+
+// ScheduledAmbr is a Session AMBR applied instead of the policy's own
+// while the named schedule is active.
+type ScheduledAmbr struct {
+	Schedule            string `json:"schedule"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
 }
 
 type PolicyRules struct {
@@ -40,8 +72,9 @@ type CreatePolicyOptions struct {
 	// 69, 70, 79, 80.
 	Var5qi int32 `json:"var5qi"`
 	// Arp is the Allocation and Retention Priority (1–15, 1 = highest).
-	Arp   int32        `json:"arp"`
-	Rules *PolicyRules `json:"rules,omitempty"`
+	Arp           int32          `json:"arp"`
+	Rules         *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
 }
 
 type UpdatePolicyOptions struct {
@@ -54,8 +87,9 @@ type UpdatePolicyOptions struct {
 	// 69, 70, 79, 80.
 	Var5qi int32 `json:"var5qi,omitempty"`
 	// Arp is the Allocation and Retention Priority (1–15, 1 = highest).
-	Arp   int32        `json:"arp,omitempty"`
-	Rules *PolicyRules `json:"rules,omitempty"`
+	Arp           int32          `json:"arp,omitempty"`
+	Rules         *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
 }
 
 type GetPolicyOptions struct {
@@ -79,8 +113,9 @@ type Policy struct {
 	// 69, 70, 79, 80.
 	Var5qi int32 `json:"var5qi"`
 	// Arp is the Allocation and Retention Priority (1–15, 1 = highest).
-	Arp   int32        `json:"arp"`
-	Rules *PolicyRules `json:"rules,omitempty"`
+	Arp           int32          `json:"arp"`
+	Rules         *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
 }
 
 type ListPoliciesResponse struct {
@@ -94,15 +129,16 @@ type ListPoliciesResponse struct {
 // applied in the order they appear in opts.Rules.
 func (c *Client) CreatePolicy(ctx context.Context, opts *CreatePolicyOptions) error {
 	payload := struct {
-		Name                string       `json:"name"`
-		ProfileName         string       `json:"profile_name"`
-		SliceName           string       `json:"slice_name"`
-		DataNetworkName     string       `json:"data_network_name"`
-		SessionAmbrUplink   string       `json:"session_ambr_uplink"`
-		SessionAmbrDownlink string       `json:"session_ambr_downlink"`
-		Var5qi              int32        `json:"var5qi"`
-		Arp                 int32        `json:"arp"`
-		Rules               *PolicyRules `json:"rules,omitempty"`
+		Name                string         `json:"name"`
+		ProfileName         string         `json:"profile_name"`
+		SliceName           string         `json:"slice_name"`
+		DataNetworkName     string         `json:"data_network_name"`
+		SessionAmbrUplink   string         `json:"session_ambr_uplink"`
+		SessionAmbrDownlink string         `json:"session_ambr_downlink"`
+		Var5qi              int32          `json:"var5qi"`
+		Arp                 int32          `json:"arp"`
+		Rules               *PolicyRules   `json:"rules,omitempty"`
+		ScheduledAmbr       *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
 	}{
 		Name:                opts.Name,
 		ProfileName:         opts.ProfileName,
@@ -113,6 +149,7 @@ func (c *Client) CreatePolicy(ctx context.Context, opts *CreatePolicyOptions) er
 		Var5qi:              opts.Var5qi,
 		Arp:                 opts.Arp,
 		Rules:               opts.Rules,
+		ScheduledAmbr:       opts.ScheduledAmbr,
 	}
 
 	var body bytes.Buffer
@@ -160,16 +197,18 @@ func (c *Client) GetPolicy(ctx context.Context, opts *GetPolicyOptions) (*Policy
 // UpdatePolicy replaces an existing policy by name. Network rules are
 // destructively replaced on every update: if opts.Rules is nil, all
 // existing rules are deleted. To keep them, re-supply the current list.
+// The same holds for opts.ScheduledAmbr.
 func (c *Client) UpdatePolicy(ctx context.Context, name string, opts *UpdatePolicyOptions) error {
 	payload := struct {
-		ProfileName         string       `json:"profile_name,omitempty"`
-		SliceName           string       `json:"slice_name,omitempty"`
-		DataNetworkName     string       `json:"data_network_name,omitempty"`
-		SessionAmbrUplink   string       `json:"session_ambr_uplink,omitempty"`
-		SessionAmbrDownlink string       `json:"session_ambr_downlink,omitempty"`
-		Var5qi              int32        `json:"var5qi,omitempty"`
-		Arp                 int32        `json:"arp,omitempty"`
-		Rules               *PolicyRules `json:"rules,omitempty"`
+		ProfileName         string         `json:"profile_name,omitempty"`
+		SliceName           string         `json:"slice_name,omitempty"`
+		DataNetworkName     string         `json:"data_network_name,omitempty"`
+		SessionAmbrUplink   string         `json:"session_ambr_uplink,omitempty"`
+		SessionAmbrDownlink string         `json:"session_ambr_downlink,omitempty"`
+		Var5qi              int32          `json:"var5qi,omitempty"`
+		Arp                 int32          `json:"arp,omitempty"`
+		Rules               *PolicyRules   `json:"rules,omitempty"`
+		ScheduledAmbr       *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
 	}{
 		ProfileName:         opts.ProfileName,
 		SliceName:           opts.SliceName,
@@ -179,6 +218,7 @@ func (c *Client) UpdatePolicy(ctx context.Context, name string, opts *UpdatePoli
 		Var5qi:              opts.Var5qi,
 		Arp:                 opts.Arp,
 		Rules:               opts.Rules,
+		ScheduledAmbr:       opts.ScheduledAmbr,
 	}
 
 	var body bytes.Buffer
@@ -237,3 +277,152 @@ func (c *Client) ListPolicies(ctx context.Context, p *ListParams) (*ListPolicies
 
 	return &policies, nil
 }
+
+// PolicyCaptivePortal is a policy's captive portal. While enabled, the
+// uplink DNS and web traffic of subscribers not yet lifted goes to the
+// IPv4 portal at PortalAddress.
+type PolicyCaptivePortal struct {
+	Enabled       bool   `json:"enabled"`
+	PortalAddress string `json:"portal_address,omitempty"`
+}
+
+// GetPolicyCaptivePortal returns a policy's captive portal.
+func (c *Client) GetPolicyCaptivePortal(ctx context.Context, policy string) (*PolicyCaptivePortal, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/policies/" + policy + "/captive-portal",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var portal PolicyCaptivePortal
+
+	err = resp.DecodeResult(&portal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &portal, nil
+}
+
+// UpdatePolicyCaptivePortal enables or disables a policy's captive portal.
+func (c *Client) UpdatePolicyCaptivePortal(ctx context.Context, policy string, portal *PolicyCaptivePortal) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(portal)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/policies/" + policy + "/captive-portal",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PolicyLocationVariant is the Session-AMBR a policy applies while the
+// subscriber is served in a tracking area (TAC) or cell (CellID), and the
+// policy whose network rules apply there, if not its own. A cell variant
+// wins over a tracking area variant.
+type PolicyLocationVariant struct {
+	ID                  string `json:"id"`
+	Name                string `json:"name"`
+	TAC                 string `json:"tac,omitempty"`
+	CellID              string `json:"cell_id,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
+	RulesPolicy         string `json:"rules_policy,omitempty"`
+}
+
+type PolicyLocationVariantList struct {
+	Items      []PolicyLocationVariant `json:"items"`
+	Page       int                     `json:"page"`
+	PerPage    int                     `json:"per_page"`
+	TotalCount int                     `json:"total_count"`
+}
+
+// CreatePolicyLocationVariantOptions describes a new location variant.
+// Exactly one of TAC and CellID must be set. RulesPolicy names a policy of
+// the same data network whose network rules apply in the area.
+type CreatePolicyLocationVariantOptions struct {
+	Name                string `json:"name"`
+	TAC                 string `json:"tac,omitempty"`
+	CellID              string `json:"cell_id,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
+	RulesPolicy         string `json:"rules_policy,omitempty"`
+}
+
+// ListPolicyLocationVariants lists the location variants of a policy.
+func (c *Client) ListPolicyLocationVariants(ctx context.Context, policy string) (*PolicyLocationVariantList, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/policies/" + policy + "/location-variants",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list PolicyLocationVariantList
+
+	err = resp.DecodeResult(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// CreatePolicyLocationVariant adds a location variant to a policy and
+// returns the created variant.
+func (c *Client) CreatePolicyLocationVariant(ctx context.Context, policy string, opts *CreatePolicyLocationVariantOptions) (*PolicyLocationVariant, error) {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "POST",
+		Path:   "api/v1/policies/" + policy + "/location-variants",
+		Body:   &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var variant PolicyLocationVariant
+
+	err = resp.DecodeResult(&variant)
+	if err != nil {
+		return nil, err
+	}
+
+	return &variant, nil
+}
+
+// DeletePolicyLocationVariant removes a location variant from a policy.
+func (c *Client) DeletePolicyLocationVariant(ctx context.Context, policy, id string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/policies/" + policy + "/location-variants/" + id,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
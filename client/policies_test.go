@@ -567,3 +567,91 @@ func TestGetPolicy_WithoutRules_Success(t *testing.T) {
 		t.Fatalf("expected rules to be nil, got: %v", policy.Rules)
 	}
 }
+
+func TestGetPolicyCaptivePortal_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"enabled": true, "portal_address": "192.0.2.10"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	portal, err := clientObj.GetPolicyCaptivePortal(context.Background(), "guest")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if !portal.Enabled || portal.PortalAddress != "192.0.2.10" {
+		t.Fatalf("unexpected captive portal: %+v", portal)
+	}
+
+	if fake.lastOpts.Path != "api/v1/policies/guest/captive-portal" {
+		t.Fatalf("unexpected path: %s", fake.lastOpts.Path)
+	}
+}
+
+func TestUpdatePolicyCaptivePortal_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 400,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "invalid portal_address, must be an IPv4 address"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdatePolicyCaptivePortal(context.Background(), "guest", &client.PolicyCaptivePortal{Enabled: true, PortalAddress: "2001:db8::1"})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestCreatePolicyLocationVariant_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 201,
+			Headers:    http.Header{},
+			Result:     []byte(`{"id": "v1", "name": "parking-lot", "tac": "000002", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "10 Mbps", "rules_policy": "staff-parking"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	variant, err := clientObj.CreatePolicyLocationVariant(context.Background(), "staff", &client.CreatePolicyLocationVariantOptions{
+		Name:                "parking-lot",
+		TAC:                 "000002",
+		SessionAmbrUplink:   "5 Mbps",
+		SessionAmbrDownlink: "10 Mbps",
+		RulesPolicy:         "staff-parking",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if variant.ID != "v1" || variant.TAC != "000002" || variant.RulesPolicy != "staff-parking" {
+		t.Fatalf("unexpected location variant: %+v", variant)
+	}
+
+	if fake.lastOpts.Method != "POST" || fake.lastOpts.Path != "api/v1/policies/staff/location-variants" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestDeletePolicyLocationVariant_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "Policy location variant not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.DeletePolicyLocationVariant(context.Background(), "staff", "missing")
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
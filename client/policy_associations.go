@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// PolicyAssociation is a session whose policy an external policy decision
+// point controls, with the decision in force. Zero and empty decision
+// fields take the session's local policy.
+type PolicyAssociation struct {
+	ID                  string `json:"id"`
+	DataNetwork         string `json:"data_network"`
+	IMSI                string `json:"imsi"`
+	PDUSessionID        int    `json:"pdu_session_id"`
+	Var5qi              int32  `json:"var5qi,omitempty"`
+	Arp                 int32  `json:"arp,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink,omitempty"`
+	RulesPolicy         string `json:"rules_policy,omitempty"`
+}
+
+type ListPolicyAssociationsResponse struct {
+	Items []PolicyAssociation `json:"items"`
+}
+
+// UpdatePolicyDecisionOptions replaces the decision in force on a session.
+// RulesPolicy names a policy of the session's data network whose network
+// rules apply in place of the subscriber's.
+type UpdatePolicyDecisionOptions struct {
+	Var5qi              int32  `json:"var5qi,omitempty"`
+	Arp                 int32  `json:"arp,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink,omitempty"`
+	RulesPolicy         string `json:"rules_policy,omitempty"`
+}
+
+// ListPolicyAssociations lists the sessions under external policy control.
+func (c *Client) ListPolicyAssociations(ctx context.Context) (*ListPolicyAssociationsResponse, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/policy-control/associations",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list ListPolicyAssociationsResponse
+
+	err = resp.DecodeResult(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// GetPolicyAssociation retrieves a policy association by ID.
+func (c *Client) GetPolicyAssociation(ctx context.Context, id string) (*PolicyAssociation, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/policy-control/associations/" + id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var assoc PolicyAssociation
+
+	err = resp.DecodeResult(&assoc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &assoc, nil
+}
+
+// UpdatePolicyDecision changes the decision in force on a session.
+func (c *Client) UpdatePolicyDecision(ctx context.Context, id string, opts *UpdatePolicyDecisionOptions) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/policy-control/associations/" + id + "/decision",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ellanetworks/core/client"
+)
+
+func TestListPolicyAssociations_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"items": [{"id": "sm-1", "data_network": "internet", "imsi": "001010100007487", "pdu_session_id": 1, "var5qi": 7}]}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	list, err := clientObj.ListPolicyAssociations(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(list.Items) != 1 || list.Items[0].ID != "sm-1" || list.Items[0].Var5qi != 7 {
+		t.Fatalf("unexpected associations: %+v", list.Items)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/policy-control/associations" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestGetPolicyAssociation_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"id": "sm-1", "data_network": "internet", "imsi": "001010100007487", "pdu_session_id": 1, "rules_policy": "work-order-42"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	assoc, err := clientObj.GetPolicyAssociation(context.Background(), "sm-1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if assoc.RulesPolicy != "work-order-42" || fake.lastOpts.Path != "api/v1/policy-control/associations/sm-1" {
+		t.Fatalf("unexpected association %+v from %s", assoc, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdatePolicyDecision_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"message": "Policy decision updated successfully"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdatePolicyDecision(context.Background(), "sm-1", &client.UpdatePolicyDecisionOptions{Var5qi: 8, RulesPolicy: "work-order-42"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Method != "PUT" || fake.lastOpts.Path != "api/v1/policy-control/associations/sm-1/decision" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdatePolicyDecision_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "Policy association not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	if err := clientObj.UpdatePolicyDecision(context.Background(), "missing", &client.UpdatePolicyDecisionOptions{}); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// QosSession is an application's request for a dedicated QoS flow and how
+// it fares: requested, installing, active, or failed with a Reason.
+type QosSession struct {
+	ID              string `json:"id"`
+	IMSI            string `json:"imsi,omitempty"`
+	UEIP            string `json:"ue_ip,omitempty"`
+	RemotePrefix    string `json:"remote_prefix,omitempty"`
+	Protocol        int    `json:"protocol,omitempty"`
+	PortLow         int    `json:"port_low,omitempty"`
+	PortHigh        int    `json:"port_high,omitempty"`
+	Var5qi          int32  `json:"var5qi"`
+	GBRUplink       string `json:"gbr_uplink"`
+	GBRDownlink     string `json:"gbr_downlink"`
+	MBRUplink       string `json:"mbr_uplink"`
+	MBRDownlink     string `json:"mbr_downlink"`
+	NotificationURL string `json:"notification_url"`
+	CreatedAt       string `json:"created_at"`
+	ExpiresAt       string `json:"expires_at"`
+	Status          string `json:"status"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+type ListQosSessionsResponse struct {
+	Items []QosSession `json:"items"`
+}
+
+// CreateQosSessionOptions asks for a dedicated QoS flow carrying the traffic
+// a UE, named by exactly one of IMSI and UEIP, exchanges with a remote
+// party, for Duration seconds. An empty RemotePrefix, a zero Protocol and
+// zero ports match any.
+type CreateQosSessionOptions struct {
+	IMSI            string `json:"imsi,omitempty"`
+	UEIP            string `json:"ue_ip,omitempty"`
+	RemotePrefix    string `json:"remote_prefix,omitempty"`
+	Protocol        int32  `json:"protocol,omitempty"`
+	PortLow         int32  `json:"port_low,omitempty"`
+	PortHigh        int32  `json:"port_high,omitempty"`
+	Var5qi          int32  `json:"var5qi"`
+	GBRUplink       string `json:"gbr_uplink"`
+	GBRDownlink     string `json:"gbr_downlink"`
+	MBRUplink       string `json:"mbr_uplink"`
+	MBRDownlink     string `json:"mbr_downlink"`
+	Duration        int64  `json:"duration"`
+	NotificationURL string `json:"notification_url"`
+}
+
+// ListQosSessions lists the QoS sessions applications requested.
+func (c *Client) ListQosSessions(ctx context.Context) (*ListQosSessionsResponse, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/qos-sessions",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var list ListQosSessionsResponse
+
+	err = resp.DecodeResult(&list)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// CreateQosSession requests a QoS session and returns it as recorded.
+func (c *Client) CreateQosSession(ctx context.Context, opts *CreateQosSessionOptions) (*QosSession, error) {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "POST",
+		Path:   "api/v1/qos-sessions",
+		Body:   &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var qs QosSession
+
+	err = resp.DecodeResult(&qs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &qs, nil
+}
+
+// GetQosSession retrieves a QoS session by ID.
+func (c *Client) GetQosSession(ctx context.Context, id string) (*QosSession, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/qos-sessions/" + id,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var qs QosSession
+
+	err = resp.DecodeResult(&qs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &qs, nil
+}
+
+// DeleteQosSession ends a QoS session.
+func (c *Client) DeleteQosSession(ctx context.Context, id string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/qos-sessions/" + id,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
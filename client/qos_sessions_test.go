@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ellanetworks/core/client"
+)
+
+func TestListQosSessions_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"items": [{"id": "qs-1", "ue_ip": "10.45.0.3", "var5qi": 2, "status": "active"}]}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	list, err := clientObj.ListQosSessions(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(list.Items) != 1 || list.Items[0].ID != "qs-1" || list.Items[0].Status != "active" {
+		t.Fatalf("unexpected QoS sessions: %+v", list.Items)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/qos-sessions" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestCreateQosSession_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 201,
+			Headers:    http.Header{},
+			Result:     []byte(`{"id": "qs-1", "ue_ip": "10.45.0.3", "var5qi": 2, "status": "requested"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	qs, err := clientObj.CreateQosSession(context.Background(), &client.CreateQosSessionOptions{
+		UEIP:            "10.45.0.3",
+		Var5qi:          2,
+		GBRUplink:       "2 Mbps",
+		GBRDownlink:     "2 Mbps",
+		MBRUplink:       "4 Mbps",
+		MBRDownlink:     "4 Mbps",
+		Duration:        600,
+		NotificationURL: "https://app.example.com/qos-events",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if qs.ID != "qs-1" || qs.Status != "requested" {
+		t.Fatalf("unexpected QoS session: %+v", qs)
+	}
+
+	if fake.lastOpts.Method != "POST" || fake.lastOpts.Path != "api/v1/qos-sessions" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestGetQosSession_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"id": "qs-1", "status": "failed", "reason": "no session of the UE is established"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	qs, err := clientObj.GetQosSession(context.Background(), "qs-1")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if qs.Reason == "" || fake.lastOpts.Path != "api/v1/qos-sessions/qs-1" {
+		t.Fatalf("unexpected QoS session %+v from %s", qs, fake.lastOpts.Path)
+	}
+}
+
+func TestDeleteQosSession_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "QoS session not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	if err := clientObj.DeleteQosSession(context.Background(), "missing"); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if fake.lastOpts.Method != "DELETE" || fake.lastOpts.Path != "api/v1/qos-sessions/missing" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
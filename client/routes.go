@@ -16,6 +16,8 @@ type CreateRouteOptions struct {
 	Gateway     string `json:"gateway"`
 	Interface   string `json:"interface"`
 	Metric      int    `json:"metric"`
+	// DataNetwork installs the route in that data network's routing table.
+	DataNetwork string `json:"data_network,omitempty"`
 }
 
 type GetRouteOptions struct {
@@ -32,6 +34,7 @@ type Route struct {
 	Gateway     string `json:"gateway"`
 	Interface   string `json:"interface"`
 	Metric      int    `json:"metric"`
+	DataNetwork string `json:"data_network,omitempty"`
 }
 
 type ListRoutesResponse struct {
@@ -48,11 +51,13 @@ func (c *Client) CreateRoute(ctx context.Context, opts *CreateRouteOptions) erro
 		Gateway     string `json:"gateway"`
 		Interface   string `json:"interface"`
 		Metric      int    `json:"metric"`
+		DataNetwork string `json:"data_network,omitempty"`
 	}{
 		Destination: opts.Destination,
 		Gateway:     opts.Gateway,
 		Interface:   opts.Interface,
 		Metric:      opts.Metric,
+		DataNetwork: opts.DataNetwork,
 	}
 
 	var body bytes.Buffer
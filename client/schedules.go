@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// ScheduleWindow is a weekly time window. Days are "mon" to "sun"; Start
+// and End are "HH:MM" in the schedule's time zone. An End not after Start
+// runs past midnight into the next day.
+type ScheduleWindow struct {
+	Days  []string `json:"days"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+type CreateScheduleOptions struct {
+	Name string `json:"name"`
+	// TimeZone is an IANA time zone, e.g. "America/Toronto".
+	TimeZone string           `json:"time_zone"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+type UpdateScheduleOptions struct {
+	TimeZone string           `json:"time_zone"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+type GetScheduleOptions struct {
+	Name string `json:"name"`
+}
+
+type DeleteScheduleOptions struct {
+	Name string `json:"name"`
+}
+
+// Schedule can be attached to network rules and to a policy's alternate
+// Session AMBR. Active reports whether it is in one of its windows now.
+type Schedule struct {
+	Name     string           `json:"name"`
+	TimeZone string           `json:"time_zone"`
+	Windows  []ScheduleWindow `json:"windows"`
+	Active   bool             `json:"active"`
+}
+
+type ListSchedulesResponse struct {
+	Items []Schedule `json:"items"`
+}
+
+// CreateSchedule creates a new schedule.
+func (c *Client) CreateSchedule(ctx context.Context, opts *CreateScheduleOptions) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "POST",
+		Path:   "api/v1/schedules",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSchedule retrieves a schedule by name.
+func (c *Client) GetSchedule(ctx context.Context, opts *GetScheduleOptions) (*Schedule, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/schedules/" + opts.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule Schedule
+
+	err = resp.DecodeResult(&schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// UpdateSchedule replaces the time zone and windows of a schedule.
+func (c *Client) UpdateSchedule(ctx context.Context, name string, opts *UpdateScheduleOptions) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/schedules/" + name,
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteSchedule deletes a schedule by name. A schedule still attached to
+// a network rule or a policy cannot be deleted.
+func (c *Client) DeleteSchedule(ctx context.Context, opts *DeleteScheduleOptions) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/schedules/" + opts.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListSchedules lists all schedules.
+func (c *Client) ListSchedules(ctx context.Context) (*ListSchedulesResponse, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/schedules",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules ListSchedulesResponse
+
+	err = resp.DecodeResult(&schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schedules, nil
+}
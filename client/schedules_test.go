@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ellanetworks/core/client"
+)
+
+func TestCreateSchedule_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 201,
+			Headers:    http.Header{},
+			Result:     []byte(`{"message": "Schedule created successfully"}`),
+		},
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	opts := &client.CreateScheduleOptions{
+		Name:     "class-hours",
+		TimeZone: "America/Toronto",
+		Windows:  []client.ScheduleWindow{{Days: []string{"mon", "fri"}, Start: "08:30", End: "15:00"}},
+	}
+
+	err := clientObj.CreateSchedule(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Method != "POST" {
+		t.Fatalf("expected POST method, got: %s", fake.lastOpts.Method)
+	}
+
+	if fake.lastOpts.Path != "api/v1/schedules" {
+		t.Fatalf("expected path api/v1/schedules, got: %s", fake.lastOpts.Path)
+	}
+}
+
+func TestGetSchedule_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"name": "night", "time_zone": "UTC", "windows": [{"days": ["sat"], "start": "22:00", "end": "06:00"}], "active": true}`),
+		},
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	schedule, err := clientObj.GetSchedule(context.Background(), &client.GetScheduleOptions{Name: "night"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Path != "api/v1/schedules/night" {
+		t.Fatalf("expected path api/v1/schedules/night, got: %s", fake.lastOpts.Path)
+	}
+
+	if !schedule.Active || len(schedule.Windows) != 1 || schedule.Windows[0].End != "06:00" {
+		t.Fatalf("unexpected schedule: %+v", schedule)
+	}
+}
+
+func TestDeleteSchedule_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 409,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "Schedule is used by network rules or policies"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	err := clientObj.DeleteSchedule(context.Background(), &client.DeleteScheduleOptions{Name: "night"})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
@@ -164,3 +164,80 @@ func (c *Client) ListSlices(ctx context.Context, p *ListParams) (*ListSlicesResp
 
 	return &slices, nil
 }
+
+// UpdateSliceQuotaOptions caps a slice. A zero limit is no limit, and a zero
+// BackOffTimer sends the default back-off.
+type UpdateSliceQuotaOptions struct {
+	MaxRegisteredUEs         int `json:"max_registered_ues"`
+	MaxSessions              int `json:"max_sessions"`
+	MaxSessionsPerSubscriber int `json:"max_sessions_per_subscriber"`
+	BackOffTimer             int `json:"back_off_timer"`
+}
+
+// SliceQuota is a slice's quota alongside the registered UEs and sessions
+// the answering node currently counts against it.
+type SliceQuota struct {
+	MaxRegisteredUEs         int `json:"max_registered_ues"`
+	MaxSessions              int `json:"max_sessions"`
+	MaxSessionsPerSubscriber int `json:"max_sessions_per_subscriber"`
+	BackOffTimer             int `json:"back_off_timer"`
+	RegisteredUEs            int `json:"registered_ues"`
+	Sessions                 int `json:"sessions"`
+}
+
+// GetSliceQuota returns a slice's admission quota and live counters.
+func (c *Client) GetSliceQuota(ctx context.Context, name string) (*SliceQuota, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/slices/" + name + "/quota",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var quota SliceQuota
+
+	err = resp.DecodeResult(&quota)
+	if err != nil {
+		return nil, err
+	}
+
+	return &quota, nil
+}
+
+// UpdateSliceQuota sets a slice's admission quota.
+func (c *Client) UpdateSliceQuota(ctx context.Context, name string, opts *UpdateSliceQuotaOptions) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/slices/" + name + "/quota",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteSliceQuota lifts a slice's admission quota.
+func (c *Client) DeleteSliceQuota(ctx context.Context, name string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/slices/" + name + "/quota",
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -324,3 +324,63 @@ func TestListSlices_Failure(t *testing.T) {
 		t.Fatalf("expected error, got none")
 	}
 }
+
+func TestGetSliceQuota_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"max_registered_ues": 100, "max_sessions": 200, "max_sessions_per_subscriber": 2, "back_off_timer": 300, "registered_ues": 41, "sessions": 58}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	quota, err := clientObj.GetSliceQuota(context.Background(), "tenant-a")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if quota.MaxRegisteredUEs != 100 || quota.MaxSessionsPerSubscriber != 2 || quota.RegisteredUEs != 41 || quota.Sessions != 58 {
+		t.Fatalf("unexpected quota: %+v", quota)
+	}
+
+	if fake.lastOpts.Method != "GET" || fake.lastOpts.Path != "api/v1/slices/tenant-a/quota" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestUpdateSliceQuota_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 400,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "max_sessions must not be negative"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateSliceQuota(context.Background(), "tenant-a", &client.UpdateSliceQuotaOptions{MaxSessions: -1})
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
+
+func TestDeleteSliceQuota_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"message": "Slice quota deleted successfully"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	if err := clientObj.DeleteSliceQuota(context.Background(), "tenant-a"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Method != "DELETE" || fake.lastOpts.Path != "api/v1/slices/tenant-a/quota" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 )
 
@@ -259,3 +260,380 @@ func (c *Client) GetSubscriberCredentials(ctx context.Context, opts *GetSubscrib
 
 	return &creds, nil
 }
+
+// SubscriberCaptivePortal is whether a subscriber is let past the captive
+// portal of policies in redirect mode.
+type SubscriberCaptivePortal struct {
+	Lifted bool `json:"lifted"`
+}
+
+// GetSubscriberCaptivePortal returns a subscriber's captive portal state.
+func (c *Client) GetSubscriberCaptivePortal(ctx context.Context, imsi string) (*SubscriberCaptivePortal, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/subscribers/" + imsi + "/captive-portal",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var state SubscriberCaptivePortal
+
+	err = resp.DecodeResult(&state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// UpdateSubscriberCaptivePortal lifts or restores a subscriber's captive
+// portal.
+func (c *Client) UpdateSubscriberCaptivePortal(ctx context.Context, imsi string, state *SubscriberCaptivePortal) error {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(state)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/subscribers/" + imsi + "/captive-portal",
+		Body:   &body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SubscriberAmbrOverrideOptions overrides a subscriber's UE-AMBR,
+// Session-AMBR or both, each as an uplink and downlink pair. Duration is
+// in seconds; zero keeps the override until it is deleted.
+type SubscriberAmbrOverrideOptions struct {
+	UeAmbrUplink        string `json:"ue_ambr_uplink,omitempty"`
+	UeAmbrDownlink      string `json:"ue_ambr_downlink,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink,omitempty"`
+	Duration            int64  `json:"duration,omitempty"`
+}
+
+// SubscriberAmbrOverride is the AMBR override in force for a subscriber.
+// ExpiresAt is empty when the override does not expire.
+type SubscriberAmbrOverride struct {
+	UeAmbrUplink        string `json:"ue_ambr_uplink,omitempty"`
+	UeAmbrDownlink      string `json:"ue_ambr_downlink,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink,omitempty"`
+	CreatedAt           string `json:"created_at"`
+	ExpiresAt           string `json:"expires_at,omitempty"`
+}
+
+// GetSubscriberAmbrOverride returns the AMBR override in force for a
+// subscriber.
+func (c *Client) GetSubscriberAmbrOverride(ctx context.Context, imsi string) (*SubscriberAmbrOverride, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/subscribers/" + imsi + "/ambr-override",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var override SubscriberAmbrOverride
+
+	err = resp.DecodeResult(&override)
+	if err != nil {
+		return nil, err
+	}
+
+	return &override, nil
+}
+
+// SetSubscriberAmbrOverride overrides a subscriber's AMBR, replacing any
+// previous override.
+func (c *Client) SetSubscriberAmbrOverride(ctx context.Context, imsi string, opts *SubscriberAmbrOverrideOptions) (*SubscriberAmbrOverride, error) {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/subscribers/" + imsi + "/ambr-override",
+		Body:   &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var override SubscriberAmbrOverride
+
+	err = resp.DecodeResult(&override)
+	if err != nil {
+		return nil, err
+	}
+
+	return &override, nil
+}
+
+// DeleteSubscriberAmbrOverride removes a subscriber's AMBR override,
+// restoring the rates of its profile and policies.
+func (c *Client) DeleteSubscriberAmbrOverride(ctx context.Context, imsi string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/subscribers/" + imsi + "/ambr-override",
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetSubscriberLifecycleOptions puts a subscriber in State (active,
+// suspended, barred_data or expired) and, with ValidFrom or ValidUntil
+// (RFC 3339), bounds the time it may use the network.
+type SetSubscriberLifecycleOptions struct {
+	State      string `json:"state,omitempty"`
+	ValidFrom  string `json:"valid_from,omitempty"`
+	ValidUntil string `json:"valid_until,omitempty"`
+}
+
+// SubscriberLifecycle is a subscriber's configured lifecycle. EffectiveState
+// is the state it puts the subscriber in now.
+type SubscriberLifecycle struct {
+	State          string `json:"state"`
+	EffectiveState string `json:"effective_state"`
+	ValidFrom      string `json:"valid_from,omitempty"`
+	ValidUntil     string `json:"valid_until,omitempty"`
+	UpdatedAt      string `json:"updated_at,omitempty"`
+}
+
+// GetSubscriberLifecycle returns a subscriber's lifecycle.
+func (c *Client) GetSubscriberLifecycle(ctx context.Context, imsi string) (*SubscriberLifecycle, error) {
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "GET",
+		Path:   "api/v1/subscribers/" + imsi + "/lifecycle",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var lifecycle SubscriberLifecycle
+
+	err = resp.DecodeResult(&lifecycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lifecycle, nil
+}
+
+// SetSubscriberLifecycle sets a subscriber's state and validity window,
+// replacing any previous ones.
+func (c *Client) SetSubscriberLifecycle(ctx context.Context, imsi string, opts *SetSubscriberLifecycleOptions) (*SubscriberLifecycle, error) {
+	var body bytes.Buffer
+
+	err := json.NewEncoder(&body).Encode(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "PUT",
+		Path:   "api/v1/subscribers/" + imsi + "/lifecycle",
+		Body:   &body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var lifecycle SubscriberLifecycle
+
+	err = resp.DecodeResult(&lifecycle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lifecycle, nil
+}
+
+// DeleteSubscriberLifecycle makes a subscriber active again with no
+// validity window.
+func (c *Client) DeleteSubscriberLifecycle(ctx context.Context, imsi string) error {
+	_, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   SyncRequest,
+		Method: "DELETE",
+		Path:   "api/v1/subscribers/" + imsi + "/lifecycle",
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const (
+	// SubscriberImportAllOrNothing creates no subscriber unless every row
+	// of the import is valid.
+	SubscriberImportAllOrNothing = "all_or_nothing"
+	// SubscriberImportBestEffort creates the valid rows of the import and
+	// reports the others.
+	SubscriberImportBestEffort = "best_effort"
+)
+
+// ImportSubscribersOptions holds at most 5000 subscribers to provision,
+// either as Subscribers or as a CSV file with a header row naming the
+// imsi, key, opc, sequenceNumber and profile_name columns. CSV takes
+// precedence when both are set.
+type ImportSubscribersOptions struct {
+	Subscribers []CreateSubscriberOptions
+	CSV         io.Reader
+	// Mode is SubscriberImportAllOrNothing (the default) or
+	// SubscriberImportBestEffort.
+	Mode string
+	// DryRun validates the rows without creating any subscriber.
+	DryRun bool
+}
+
+type SubscriberImportRowError struct {
+	Row   int    `json:"row"`
+	Imsi  string `json:"imsi,omitempty"`
+	Error string `json:"error"`
+}
+
+// SubscriberImportReport lists the rows of an import that were rejected.
+// Row numbers are 1-based and do not count the CSV header.
+type SubscriberImportReport struct {
+	Mode     string                     `json:"mode"`
+	DryRun   bool                       `json:"dry_run"`
+	Total    int                        `json:"total"`
+	Valid    int                        `json:"valid"`
+	Imported int                        `json:"imported"`
+	Errors   []SubscriberImportRowError `json:"errors"`
+}
+
+// ImportSubscribers provisions subscribers in bulk. Invalid rows are
+// reported rather than returned as an error.
+func (c *Client) ImportSubscribers(ctx context.Context, opts *ImportSubscribersOptions) (*SubscriberImportReport, error) {
+	query := url.Values{}
+
+	if opts.Mode != "" {
+		query.Set("mode", opts.Mode)
+	}
+
+	if opts.DryRun {
+		query.Set("dry_run", "true")
+	}
+
+	body := opts.CSV
+	contentType := "text/csv"
+
+	if body == nil {
+		type row struct {
+			Imsi           string `json:"imsi"`
+			Key            string `json:"key"`
+			SequenceNumber string `json:"sequenceNumber"`
+			ProfileName    string `json:"profile_name"`
+			OPc            string `json:"opc,omitempty"`
+		}
+
+		rows := make([]row, 0, len(opts.Subscribers))
+		for _, s := range opts.Subscribers {
+			rows = append(rows, row{
+				Imsi:           s.Imsi,
+				Key:            s.Key,
+				SequenceNumber: s.SequenceNumber,
+				ProfileName:    s.ProfileName,
+				OPc:            s.OPc,
+			})
+		}
+
+		var buf bytes.Buffer
+
+		err := json.NewEncoder(&buf).Encode(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		body = &buf
+		contentType = "application/json"
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:    SyncRequest,
+		Method:  "POST",
+		Path:    "api/v1/subscribers/import",
+		Query:   query,
+		Headers: map[string]string{"Content-Type": contentType},
+		Body:    body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var report SubscriberImportReport
+
+	err = resp.DecodeResult(&report)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+type ExportSubscribersOptions struct {
+	// Format is "csv" (the default) or "json".
+	Format string
+	// IncludeCredentials adds each subscriber's key, OPc and sequence
+	// number, which makes the export importable elsewhere. Admin or
+	// Network Manager role required.
+	IncludeCredentials bool
+}
+
+// ExportSubscribers streams every subscriber, ordered by IMSI, to w.
+func (c *Client) ExportSubscribers(ctx context.Context, opts *ExportSubscribersOptions, w io.Writer) error {
+	query := url.Values{}
+
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+
+	if opts.IncludeCredentials {
+		query.Set("include_credentials", "true")
+	}
+
+	resp, err := c.Requester.Do(ctx, &RequestOptions{
+		Type:   RawRequest,
+		Method: "GET",
+		Path:   "api/v1/subscribers/export",
+		Query:  query,
+	})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("export subscribers: unexpected status %d", resp.StatusCode)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+
+	return err
+}
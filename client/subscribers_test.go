@@ -6,7 +6,9 @@ package client_test
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/ellanetworks/core/client"
@@ -410,3 +412,187 @@ func TestGetSubscriberCredentials_Failure(t *testing.T) {
 		t.Fatalf("expected error, got none")
 	}
 }
+
+func TestUpdateSubscriberCaptivePortal_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"message": "Subscriber captive portal updated successfully"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.UpdateSubscriberCaptivePortal(context.Background(), "001010100007487", &client.SubscriberCaptivePortal{Lifted: true})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Method != "PUT" || fake.lastOpts.Path != "api/v1/subscribers/001010100007487/captive-portal" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestSetSubscriberAmbrOverride_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"ue_ambr_uplink": "200 Mbps", "ue_ambr_downlink": "1 Gbps", "created_at": "2026-10-19T08:00:00Z", "expires_at": "2026-10-19T10:00:00Z"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	override, err := clientObj.SetSubscriberAmbrOverride(context.Background(), "001010100007487", &client.SubscriberAmbrOverrideOptions{
+		UeAmbrUplink:   "200 Mbps",
+		UeAmbrDownlink: "1 Gbps",
+		Duration:       7200,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if override.UeAmbrDownlink != "1 Gbps" || override.ExpiresAt != "2026-10-19T10:00:00Z" {
+		t.Fatalf("unexpected override: %+v", override)
+	}
+
+	if fake.lastOpts.Method != "PUT" || fake.lastOpts.Path != "api/v1/subscribers/001010100007487/ambr-override" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestDeleteSubscriberAmbrOverride_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "AMBR override not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.DeleteSubscriberAmbrOverride(context.Background(), "001010100007487")
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if fake.lastOpts.Method != "DELETE" {
+		t.Fatalf("unexpected method: %s", fake.lastOpts.Method)
+	}
+}
+
+func TestSetSubscriberLifecycle_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"state": "active", "effective_state": "active", "valid_until": "2026-11-19T00:00:00Z", "updated_at": "2026-10-19T08:00:00Z"}`),
+		},
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	lifecycle, err := clientObj.SetSubscriberLifecycle(context.Background(), "001010100007487", &client.SetSubscriberLifecycleOptions{
+		ValidUntil: "2026-11-19T00:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if lifecycle.EffectiveState != "active" || lifecycle.ValidUntil != "2026-11-19T00:00:00Z" {
+		t.Fatalf("unexpected lifecycle: %+v", lifecycle)
+	}
+
+	if fake.lastOpts.Method != "PUT" || fake.lastOpts.Path != "api/v1/subscribers/001010100007487/lifecycle" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+}
+
+func TestDeleteSubscriberLifecycle_Failure(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 404,
+			Headers:    http.Header{},
+			Result:     []byte(`{"error": "Subscriber lifecycle not found"}`),
+		},
+		err: errors.New("requester error"),
+	}
+	clientObj := &client.Client{Requester: fake}
+
+	err := clientObj.DeleteSubscriberLifecycle(context.Background(), "001010100007487")
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+
+	if fake.lastOpts.Method != "DELETE" {
+		t.Fatalf("unexpected method: %s", fake.lastOpts.Method)
+	}
+}
+
+func TestImportSubscribers_Success(t *testing.T) {
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Result:     []byte(`{"mode": "best_effort", "dry_run": true, "total": 2, "valid": 1, "imported": 0, "errors": [{"row": 2, "imsi": "001010100007488", "error": "Subscriber already exists"}]}`),
+		},
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	report, err := clientObj.ImportSubscribers(context.Background(), &client.ImportSubscribersOptions{
+		CSV:    strings.NewReader("imsi,key,sequenceNumber,profile_name\n"),
+		Mode:   client.SubscriberImportBestEffort,
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Method != "POST" || fake.lastOpts.Path != "api/v1/subscribers/import" {
+		t.Fatalf("unexpected request: %s %s", fake.lastOpts.Method, fake.lastOpts.Path)
+	}
+
+	if fake.lastOpts.Headers["Content-Type"] != "text/csv" {
+		t.Fatalf("expected text/csv, got: %q", fake.lastOpts.Headers["Content-Type"])
+	}
+
+	if fake.lastOpts.Query.Get("mode") != "best_effort" || fake.lastOpts.Query.Get("dry_run") != "true" {
+		t.Fatalf("unexpected query: %v", fake.lastOpts.Query)
+	}
+
+	if report.Valid != 1 || len(report.Errors) != 1 || report.Errors[0].Row != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestExportSubscribers_Success(t *testing.T) {
+	const export = "imsi,profile_name\n001010100007487,default\n"
+
+	fake := &fakeRequester{
+		response: &client.RequestResponse{
+			StatusCode: 200,
+			Headers:    http.Header{},
+			Body:       io.NopCloser(strings.NewReader(export)),
+		},
+	}
+	clientObj := &client.Client{
+		Requester: fake,
+	}
+
+	var out strings.Builder
+
+	err := clientObj.ExportSubscribers(context.Background(), &client.ExportSubscribersOptions{}, &out)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if fake.lastOpts.Type != client.RawRequest || fake.lastOpts.Path != "api/v1/subscribers/export" {
+		t.Fatalf("unexpected request: %+v", fake.lastOpts)
+	}
+
+	if out.String() != export {
+		t.Fatalf("unexpected export: %q", out.String())
+	}
+}
@@ -35,11 +35,11 @@ type ListUsageParams struct {
 }
 
 // ListUsage retrieves subscriber usage data based on the provided parameters.
-// The server groups results and rejects any GroupBy other than "day" or
-// "subscriber".
+// The server groups results and rejects any GroupBy other than "day",
+// "subscriber" or "rating_group".
 func (c *Client) ListUsage(ctx context.Context, p *ListUsageParams) (*ListUsageResponse, error) {
-	if p.GroupBy != "day" && p.GroupBy != "subscriber" {
-		return nil, fmt.Errorf("group_by must be \"day\" or \"subscriber\", got %q", p.GroupBy)
+	if p.GroupBy != "day" && p.GroupBy != "subscriber" && p.GroupBy != "rating_group" {
+		return nil, fmt.Errorf("group_by must be \"day\", \"subscriber\" or \"rating_group\", got %q", p.GroupBy)
 	}
 
 	query := url.Values{"group_by": {p.GroupBy}}
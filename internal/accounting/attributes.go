@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package accounting
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/radius"
+	"github.com/ellanetworks/core/nas"
+)
+
+// 3GPP vendor-specific attributes (TS 29.061 §16.4.7).
+const (
+	vsaIMSI             = 1
+	vsaIMEISV           = 20
+	vsaRATType          = 21
+	vsaUserLocationInfo = 22
+)
+
+// Geographic Location Types of 3GPP-User-Location-Info (TS 29.061
+// §16.4.7.2).
+const (
+	uliTAIAndECGI    = 130
+	uliFiveGSTAINCGI = 137
+)
+
+// record is what one accounting request says about a session.
+type record struct {
+	status    uint32
+	id        string
+	sess      Session
+	ue        UE
+	started   time.Time
+	now       time.Time
+	uplink    uint64
+	downlink  uint64
+	terminate uint32 // Acct-Terminate-Cause of a Stop, 0 for none
+}
+
+// attributes encodes the attributes of r. Acct-Delay-Time is left out: it
+// is added when the request is sent.
+func (r *record) attributes(nasID string) ([]byte, error) {
+	p := &radius.Packet{Code: radius.CodeAccountingRequest}
+
+	p.AddUint32(radius.TypeAcctStatusType, r.status)
+	p.AddString(radius.TypeAcctSessionID, r.id)
+	p.AddString(radius.TypeUserName, r.sess.IMSI)
+	p.AddString(radius.TypeCallingStationID, r.sess.IMSI)
+	p.AddString(radius.TypeCalledStationID, r.sess.DNN)
+	p.AddUint32(radius.TypeServiceType, radius.ServiceTypeFramed)
+	p.AddUint32(radius.TypeFramedProtocol, radius.FramedProtocolGPRSPDPCtxt)
+	p.AddString(radius.TypeNASIdentifier, nasID)
+
+	if r.sess.IPv4.Is4() {
+		p.AddAddr(radius.TypeFramedIPAddress, r.sess.IPv4)
+	}
+
+	if r.sess.IPv6Prefix.IsValid() {
+		p.Add(radius.TypeFramedIPv6Prefix, framedIPv6Prefix(r.sess.IPv6Prefix))
+	}
+
+	p.AddUint32(radius.TypeEventTimestamp, uint32(r.now.Unix()))
+
+	p.AddVendor(radius.VendorID3GPP, vsaIMSI, []byte(r.sess.IMSI))
+	p.AddVendor(radius.VendorID3GPP, vsaRATType, []byte{byte(r.sess.RAT)})
+
+	if r.ue.IMEI != "" {
+		p.AddVendor(radius.VendorID3GPP, vsaIMEISV, []byte(r.ue.IMEI))
+	}
+
+	if uli := userLocationInfo(r.ue.Location); uli != nil {
+		p.AddVendor(radius.VendorID3GPP, vsaUserLocationInfo, uli)
+	}
+
+	if r.status != radius.AcctStatusStart {
+		p.AddUint32(radius.TypeAcctSessionTime, uint32(r.now.Sub(r.started)/time.Second))
+		p.AddUint32(radius.TypeAcctInputOctets, uint32(r.uplink))
+		p.AddUint32(radius.TypeAcctOutputOctets, uint32(r.downlink))
+		p.AddUint32(radius.TypeAcctInputGigawords, uint32(r.uplink>>32))
+		p.AddUint32(radius.TypeAcctOutputGigawords, uint32(r.downlink>>32))
+	}
+
+	if r.status == radius.AcctStatusStop && r.terminate != 0 {
+		p.AddUint32(radius.TypeAcctTerminateCause, r.terminate)
+	}
+
+	return radius.AppendAttributes(nil, p.Attributes)
+}
+
+// framedIPv6Prefix encodes a Framed-IPv6-Prefix value (RFC 3162 §2.3).
+func framedIPv6Prefix(prefix netip.Prefix) []byte {
+	addr := prefix.Masked().Addr().As16()
+	n := (prefix.Bits() + 7) / 8
+
+	return append([]byte{0, byte(prefix.Bits())}, addr[:n]...)
+}
+
+// userLocationInfo encodes the cell a UE is in as 3GPP-User-Location-Info,
+// or returns nil when it is not known.
+func userLocationInfo(loc models.UserLocation) []byte {
+	switch {
+	case loc.NrLocation != nil && loc.NrLocation.Tai != nil && loc.NrLocation.Ncgi != nil:
+		tai, ok := taiOctets(loc.NrLocation.Tai, 3)
+		if !ok {
+			return nil
+		}
+
+		ncgi, ok := cellOctets(loc.NrLocation.Ncgi.PlmnID, loc.NrLocation.Ncgi.NrCellID, 5)
+		if !ok {
+			return nil
+		}
+
+		return append(append([]byte{uliFiveGSTAINCGI}, tai...), ncgi...)
+	case loc.EutraLocation != nil && loc.EutraLocation.Tai != nil && loc.EutraLocation.Ecgi != nil:
+		tai, ok := taiOctets(loc.EutraLocation.Tai, 2)
+		if !ok {
+			return nil
+		}
+
+		ecgi, ok := cellOctets(loc.EutraLocation.Ecgi.PlmnID, loc.EutraLocation.Ecgi.EutraCellID, 4)
+		if !ok {
+			return nil
+		}
+
+		return append(append([]byte{uliTAIAndECGI}, tai...), ecgi...)
+	default:
+		return nil
+	}
+}
+
+// taiOctets encodes a TAI with a TAC of tacLen octets: two for E-UTRAN,
+// three for 5GS.
+func taiOctets(tai *models.Tai, tacLen int) ([]byte, bool) {
+	return cellOctets(tai.PlmnID, tai.Tac, tacLen)
+}
+
+// cellOctets encodes a PLMN followed by a hexadecimal identity in idLen
+// octets, as TAIs and cell global identities are.
+func cellOctets(plmn *models.PlmnID, id string, idLen int) ([]byte, bool) {
+	if plmn == nil {
+		return nil, false
+	}
+
+	b, err := nas.PLMN{MCC: plmn.Mcc, MNC: plmn.Mnc}.AppendBinary(nil)
+	if err != nil {
+		return nil, false
+	}
+
+	v, err := strconv.ParseUint(id, 16, 64)
+	if err != nil || v>>(8*idLen) != 0 {
+		return nil, false
+	}
+
+	return append(b, binary.BigEndian.AppendUint64(nil, v)[8-idLen:]...), true
+}
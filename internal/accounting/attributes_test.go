@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package accounting
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func TestUserLocationInfo(t *testing.T) {
+	plmn := &models.PlmnID{Mcc: "001", Mnc: "01"}
+
+	tests := []struct {
+		name string
+		loc  models.UserLocation
+		want []byte
+	}{
+		{
+			name: "E-UTRAN",
+			loc: models.UserLocation{EutraLocation: &models.EutraLocation{
+				Tai:  &models.Tai{PlmnID: plmn, Tac: "0001"},
+				Ecgi: &models.Ecgi{PlmnID: plmn, EutraCellID: "0000101"},
+			}},
+			want: []byte{130, 0x00, 0xf1, 0x10, 0x00, 0x01, 0x00, 0xf1, 0x10, 0x00, 0x00, 0x01, 0x01},
+		},
+		{
+			name: "NR",
+			loc: models.UserLocation{NrLocation: &models.NrLocation{
+				Tai:  &models.Tai{PlmnID: plmn, Tac: "000001"},
+				Ncgi: &models.Ncgi{PlmnID: plmn, NrCellID: "000000010"},
+			}},
+			want: []byte{137, 0x00, 0xf1, 0x10, 0x00, 0x00, 0x01, 0x00, 0xf1, 0x10, 0x00, 0x00, 0x00, 0x00, 0x10},
+		},
+		{
+			name: "malformed TAC",
+			loc: models.UserLocation{EutraLocation: &models.EutraLocation{
+				Tai:  &models.Tai{PlmnID: plmn, Tac: "zz"},
+				Ecgi: &models.Ecgi{PlmnID: plmn, EutraCellID: "0000101"},
+			}},
+		},
+		{name: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := userLocationInfo(tt.loc); !bytes.Equal(got, tt.want) {
+				t.Errorf("got %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFramedIPv6Prefix(t *testing.T) {
+	got := framedIPv6Prefix(netip.MustParsePrefix("2001:db8:1:2::/64"))
+	want := []byte{0, 64, 0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x02}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package accounting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/radius"
+	"go.uber.org/zap"
+)
+
+const (
+	// retryDelay is how long a sender waits after its server failed to
+	// answer before it tries the same record again.
+	retryDelay = 30 * time.Second
+	// sendBatch is how many records a sender reads from the outbox at once.
+	sendBatch = 64
+)
+
+// sender delivers the outbox of one server in order. A record leaves the
+// outbox only once the server acknowledged it.
+type sender struct {
+	name    string
+	id      string
+	address string
+	secret  string
+	client  *radius.Client
+	store   Store
+	retry   time.Duration
+	now     func() time.Time
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newSender(srv db.AccountingServer, store Store, retry time.Duration) (*sender, error) {
+	addr, err := ParseServerAddress(srv.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := radius.NewClient(radius.Config{Server: addr, Secret: []byte(srv.Secret)})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &sender{
+		name:    srv.Name,
+		id:      srv.ID,
+		address: srv.Address,
+		secret:  srv.Secret,
+		client:  client,
+		store:   store,
+		retry:   retry,
+		now:     time.Now,
+		wake:    make(chan struct{}, 1),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go s.run(ctx)
+
+	return s, nil
+}
+
+// kick tells the sender records are waiting.
+func (s *sender) kick() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// stop ends the sender, abandoning any exchange in flight. The record it
+// was sending stays in the outbox.
+func (s *sender) stop() {
+	s.cancel()
+	<-s.done
+}
+
+func (s *sender) run(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		wait := s.wake
+
+		var retry <-chan time.Time
+
+		if err := s.drain(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			logger.AcctLog.Warn("accounting server unreachable, will retry",
+				zap.String("server", s.name), zap.Duration("retry_in", s.retry), zap.Error(err))
+
+			wait = nil
+			retry = time.After(s.retry)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wait:
+		case <-retry:
+		}
+	}
+}
+
+// drain sends queued records until the outbox is empty or one fails.
+func (s *sender) drain(ctx context.Context) error {
+	for {
+		records, err := s.store.ListAccountingRecords(ctx, s.id, sendBatch)
+		if err != nil {
+			return fmt.Errorf("list records: %w", err)
+		}
+
+		if len(records) == 0 {
+			return nil
+		}
+
+		for _, rec := range records {
+			if err := s.send(ctx, rec); err != nil {
+				return err
+			}
+
+			if err := s.store.DeleteAccountingRecord(ctx, rec.ID); err != nil {
+				return fmt.Errorf("delete record: %w", err)
+			}
+		}
+	}
+}
+
+// send delivers one record. A record that no longer decodes is logged and
+// reported as sent, so it cannot hold the queue up.
+func (s *sender) send(ctx context.Context, rec db.AccountingRecord) error {
+	attrs, err := radius.ParseAttributes(rec.Attributes)
+	if err != nil {
+		logger.AcctLog.Warn("dropping undecodable accounting record",
+			zap.String("server", s.name), zap.Int64("id", rec.ID), zap.Error(err))
+
+		return nil
+	}
+
+	req := &radius.Packet{Code: radius.CodeAccountingRequest, Attributes: attrs}
+
+	delay := s.now().Unix() - rec.CreatedAt
+	if delay < 0 {
+		delay = 0
+	}
+
+	req.AddUint32(radius.TypeAcctDelayTime, uint32(delay))
+
+	resp, err := s.client.Exchange(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if resp.Code != radius.CodeAccountingResponse {
+		return fmt.Errorf("answer is %s, not Accounting-Response", resp.Code)
+	}
+
+	return nil
+}
@@ -0,0 +1,408 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package accounting
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/radius"
+	"go.uber.org/zap"
+)
+
+const (
+	// reconcileBackstop is the sweep that runs when no wakeup fired.
+	reconcileBackstop = time.Minute
+	// interimTick is how often sessions are checked for a due
+	// Interim-Update; it bounds how late one can be.
+	interimTick = 10 * time.Second
+	// outboxLimit caps the records kept for one server. When a server
+	// stays unreachable the oldest go first.
+	outboxLimit = 100_000
+)
+
+// Service tracks the sessions of this node and queues their accounting
+// records for every configured server.
+type Service struct {
+	store    Store
+	ues      UEDirectory
+	nasID    string
+	wakeup   <-chan struct{}
+	backstop time.Duration
+	interim  time.Duration
+	retry    time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	servers  map[string]db.AccountingServer
+	senders  map[string]*sender
+	sessions map[string]*session
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// session is an open session and what has been reported for it.
+type session struct {
+	Session
+	id       string
+	ue       UE
+	started  time.Time
+	uplink   uint64
+	downlink uint64
+	// interims holds, per server, when the last record went out.
+	interims map[string]time.Time
+}
+
+// NewService wires a service over store. ues resolves the IMEI and
+// location records carry; nasID is sent as NAS-Identifier. wakeup is
+// signalled when the accounting servers changed; nil leaves only the
+// backstop sweep. Start must be called explicitly.
+func NewService(store Store, ues UEDirectory, nasID string, wakeup <-chan struct{}) *Service {
+	return &Service{
+		store:    store,
+		ues:      ues,
+		nasID:    nasID,
+		wakeup:   wakeup,
+		backstop: reconcileBackstop,
+		interim:  interimTick,
+		retry:    retryDelay,
+		now:      time.Now,
+		servers:  make(map[string]db.AccountingServer),
+		senders:  make(map[string]*sender),
+		sessions: make(map[string]*session),
+	}
+}
+
+// Start launches the reconcile loop. Calls without a paired Stop are
+// no-ops.
+func (s *Service) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx, s.done)
+}
+
+// Stop ends the reconcile loop and the senders. Sessions still open get a
+// Stop record with Terminate-Cause NAS-Reboot, left in the outbox for the
+// next start to send. Safe to call when not started.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	done := s.done
+	s.cancel = nil
+	s.done = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	for ref, sess := range s.sessions {
+		s.enqueue(sess, radius.AcctStatusStop, radius.TerminateCauseNASReboot, now, s.allServers())
+		delete(s.sessions, ref)
+	}
+
+	for id, snd := range s.senders {
+		snd.stop()
+		delete(s.senders, id)
+	}
+}
+
+// SessionStarted opens accounting for sess and queues its Start record.
+func (s *Service) SessionStarted(sess Session) {
+	id, err := newSessionID()
+	if err != nil {
+		logger.AcctLog.Warn("couldn't generate accounting session ID", zap.Error(err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	open := &session{
+		Session:  sess,
+		id:       id,
+		started:  now,
+		interims: make(map[string]time.Time),
+	}
+
+	for serverID := range s.servers {
+		open.interims[serverID] = now
+	}
+
+	s.sessions[sess.Ref] = open
+	s.enqueue(open, radius.AcctStatusStart, 0, now, s.allServers())
+}
+
+// SessionUsage adds traffic to a session's counters. uplink and downlink
+// are increments in octets.
+func (s *Service) SessionUsage(ref string, uplink, downlink uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.sessions[ref]; ok {
+		sess.uplink += uplink
+		sess.downlink += downlink
+	}
+}
+
+// SessionStopped closes accounting for a session and queues its Stop
+// record.
+func (s *Service) SessionStopped(ref string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[ref]
+	if !ok {
+		return
+	}
+
+	delete(s.sessions, ref)
+	s.enqueue(sess, radius.AcctStatusStop, radius.TerminateCauseUserRequest, s.now(), s.allServers())
+}
+
+// Reconcile reads the accounting servers and applies them: senders start,
+// restart on an address or secret change, and stop with their queue
+// dropped when the server is removed.
+func (s *Service) Reconcile(ctx context.Context) error {
+	servers, err := s.store.ListAccountingServers(ctx)
+	if err != nil {
+		return fmt.Errorf("list accounting servers: %w", err)
+	}
+
+	queued, err := s.store.ListAccountingRecordServers(ctx)
+	if err != nil {
+		return fmt.Errorf("list queued accounting records: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	desired := make(map[string]db.AccountingServer, len(servers))
+	for _, srv := range servers {
+		desired[srv.ID] = srv
+	}
+
+	for id, snd := range s.senders {
+		srv, ok := desired[id]
+		if ok && srv.Address == snd.address && srv.Secret == snd.secret {
+			continue
+		}
+
+		snd.stop()
+		delete(s.senders, id)
+	}
+
+	for _, id := range queued {
+		if _, ok := desired[id]; ok {
+			continue
+		}
+
+		if err := s.store.DeleteAccountingRecordsForServer(ctx, id); err != nil {
+			logger.AcctLog.Warn("couldn't drop records of a removed accounting server", zap.String("server_id", id), zap.Error(err))
+		}
+	}
+
+	now := s.now()
+
+	for id, srv := range desired {
+		if _, ok := s.servers[id]; !ok {
+			// A server added mid-session hears of it from the next
+			// Interim-Update on.
+			for _, sess := range s.sessions {
+				sess.interims[id] = now
+			}
+		}
+
+		if err := s.store.TrimAccountingRecords(ctx, id, outboxLimit); err != nil {
+			logger.AcctLog.Warn("couldn't trim accounting records", zap.String("server", srv.Name), zap.Error(err))
+		}
+
+		if _, ok := s.senders[id]; ok {
+			continue
+		}
+
+		snd, err := newSender(srv, s.store, s.retry)
+		if err != nil {
+			logger.AcctLog.Warn("couldn't start accounting sender", zap.String("server", srv.Name), zap.Error(err))
+			continue
+		}
+
+		s.senders[id] = snd
+
+		logger.AcctLog.Info("accounting sender started", zap.String("server", srv.Name), zap.String("address", snd.client.Server().String()))
+	}
+
+	s.servers = desired
+
+	for _, sess := range s.sessions {
+		for id := range sess.interims {
+			if _, ok := desired[id]; !ok {
+				delete(sess.interims, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sendInterims queues an Interim-Update for every session and server whose
+// interval has elapsed.
+func (s *Service) sendInterims() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	for _, sess := range s.sessions {
+		var due []string
+
+		for id, last := range sess.interims {
+			interval := time.Duration(s.servers[id].InterimInterval) * time.Second
+			if interval > 0 && now.Sub(last) >= interval {
+				due = append(due, id)
+				sess.interims[id] = now
+			}
+		}
+
+		if len(due) > 0 {
+			s.enqueue(sess, radius.AcctStatusInterimUpdate, 0, now, due)
+		}
+	}
+}
+
+// enqueue queues one record for each of servers and wakes their senders.
+// s.mu must be held.
+func (s *Service) enqueue(sess *session, status, cause uint32, now time.Time, servers []string) {
+	if len(servers) == 0 {
+		return
+	}
+
+	if s.ues != nil {
+		if ue, ok := s.ues.LookupUE(sess.IMSI, sess.RAT); ok {
+			sess.ue = ue
+		}
+	}
+
+	r := &record{
+		status:    status,
+		id:        sess.id,
+		sess:      sess.Session,
+		ue:        sess.ue,
+		started:   sess.started,
+		now:       now,
+		uplink:    sess.uplink,
+		downlink:  sess.downlink,
+		terminate: cause,
+	}
+
+	attrs, err := r.attributes(s.nasID)
+	if err != nil {
+		logger.AcctLog.Warn("couldn't encode accounting record", zap.String("imsi", sess.IMSI), zap.Error(err))
+		return
+	}
+
+	records := make([]db.AccountingRecord, 0, len(servers))
+	for _, id := range servers {
+		records = append(records, db.AccountingRecord{ServerID: id, CreatedAt: now.Unix(), Attributes: attrs})
+	}
+
+	if err := s.store.EnqueueAccountingRecords(context.Background(), records); err != nil {
+		logger.AcctLog.Warn("couldn't queue accounting record", zap.String("imsi", sess.IMSI), zap.Error(err))
+		return
+	}
+
+	for _, id := range servers {
+		if snd, ok := s.senders[id]; ok {
+			snd.kick()
+		}
+	}
+}
+
+// allServers lists the configured server IDs. s.mu must be held.
+func (s *Service) allServers() []string {
+	ids := make([]string, 0, len(s.servers))
+	for id := range s.servers {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func (s *Service) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	if err := s.Reconcile(ctx); err != nil {
+		logger.AcctLog.Warn("initial accounting reconcile failed", zap.Error(err))
+	}
+
+	backstop := time.NewTicker(s.backstop)
+	defer backstop.Stop()
+
+	interim := time.NewTicker(s.interim)
+	defer interim.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-interim.C:
+			s.sendInterims()
+			continue
+		case <-s.wakeup:
+		case <-backstop.C:
+		}
+
+		if err := s.Reconcile(ctx); err != nil {
+			logger.AcctLog.Warn("accounting reconcile failed", zap.Error(err))
+		}
+	}
+}
+
+// ParseServerAddress reads an accounting server address, "address" or
+// "address:port", defaulting to the accounting port.
+func ParseServerAddress(s string) (netip.AddrPort, error) {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap, nil
+	}
+
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid server address %q", s)
+	}
+
+	return netip.AddrPortFrom(a, radius.AccountingPort), nil
+}
+
+// newSessionID returns a random Acct-Session-Id.
+func newSessionID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
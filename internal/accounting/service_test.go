@@ -0,0 +1,324 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package accounting
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/radius"
+	"github.com/ellanetworks/core/internal/radius/radiustest"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	servers []db.AccountingServer
+	records []db.AccountingRecord
+	nextID  int64
+}
+
+func (f *fakeStore) ListAccountingServers(context.Context) ([]db.AccountingServer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.servers), nil
+}
+
+func (f *fakeStore) EnqueueAccountingRecords(_ context.Context, records []db.AccountingRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, r := range records {
+		f.nextID++
+		r.ID = f.nextID
+		f.records = append(f.records, r)
+	}
+
+	return nil
+}
+
+func (f *fakeStore) ListAccountingRecords(_ context.Context, serverID string, limit int) ([]db.AccountingRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var out []db.AccountingRecord
+
+	for _, r := range f.records {
+		if r.ServerID == serverID && len(out) < limit {
+			out = append(out, r)
+		}
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) DeleteAccountingRecord(_ context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records = slices.DeleteFunc(f.records, func(r db.AccountingRecord) bool { return r.ID == id })
+
+	return nil
+}
+
+func (f *fakeStore) TrimAccountingRecords(context.Context, string, int) error { return nil }
+
+func (f *fakeStore) ListAccountingRecordServers(context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ids []string
+
+	for _, r := range f.records {
+		if !slices.Contains(ids, r.ServerID) {
+			ids = append(ids, r.ServerID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (f *fakeStore) DeleteAccountingRecordsForServer(_ context.Context, serverID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.records = slices.DeleteFunc(f.records, func(r db.AccountingRecord) bool { return r.ServerID == serverID })
+
+	return nil
+}
+
+func (f *fakeStore) queued() []db.AccountingRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.records)
+}
+
+type fakeUEs struct{}
+
+func (fakeUEs) LookupUE(imsi string, rat RAT) (UE, bool) {
+	if imsi != testIMSI || rat != RATEUTRAN {
+		return UE{}, false
+	}
+
+	plmn := &models.PlmnID{Mcc: "001", Mnc: "01"}
+
+	return UE{IMEI: "356938035643809", Location: models.UserLocation{EutraLocation: &models.EutraLocation{
+		Tai:  &models.Tai{PlmnID: plmn, Tac: "0001"},
+		Ecgi: &models.Ecgi{PlmnID: plmn, EutraCellID: "0000101"},
+	}}}, true
+}
+
+const testIMSI = "001010000000001"
+
+// accountingServer answers Accounting-Requests on loopback, first with the
+// scripted codes, then with Accounting-Response.
+type accountingServer struct {
+	mu       sync.Mutex
+	codes    []radius.Code
+	requests []*radius.Packet
+}
+
+func startAccountingServer(t *testing.T, codes ...radius.Code) (*accountingServer, string) {
+	t.Helper()
+
+	a := &accountingServer{codes: codes}
+
+	srv, err := radiustest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), []byte("secret"), func(req *radius.Packet) *radius.Packet {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		// The server reuses its read buffer, so keep a copy.
+		kept := &radius.Packet{Code: req.Code}
+		for _, attr := range req.Attributes {
+			kept.Add(attr.Type, slices.Clone(attr.Value))
+		}
+
+		a.requests = append(a.requests, kept)
+
+		code := radius.CodeAccountingResponse
+		if len(a.codes) > 0 {
+			code, a.codes = a.codes[0], a.codes[1:]
+		}
+
+		return &radius.Packet{Code: code}
+	})
+	if err != nil {
+		t.Fatalf("start server: %v", err)
+	}
+
+	t.Cleanup(func() { _ = srv.Close() })
+
+	return a, srv.Addr().String()
+}
+
+// statuses waits until n requests arrived and returns their
+// Acct-Status-Types.
+func (a *accountingServer) statuses(t *testing.T, n int) []uint32 {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		a.mu.Lock()
+		got := slices.Clone(a.requests)
+		a.mu.Unlock()
+
+		if len(got) >= n || time.Now().After(deadline) {
+			statuses := make([]uint32, 0, len(got))
+			for _, p := range got {
+				v, _ := p.Uint32(radius.TypeAcctStatusType)
+				statuses = append(statuses, v)
+			}
+
+			return statuses
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (a *accountingServer) last() *radius.Packet {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.requests[len(a.requests)-1]
+}
+
+func newTestService(t *testing.T, store *fakeStore) (*Service, *time.Time) {
+	t.Helper()
+
+	now := time.Unix(1_700_000_000, 0)
+	s := NewService(store, fakeUEs{}, "ella-core-1", nil)
+	s.retry = 10 * time.Millisecond
+	s.now = func() time.Time { return now }
+
+	t.Cleanup(s.Stop)
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	return s, &now
+}
+
+var testSession = Session{
+	Ref:  "ref-1",
+	IMSI: testIMSI,
+	DNN:  "internet",
+	RAT:  RATEUTRAN,
+	IPv4: netip.MustParseAddr("10.45.0.2"),
+}
+
+func TestService_StartInterimStop(t *testing.T) {
+	srv, addr := startAccountingServer(t)
+	store := &fakeStore{servers: []db.AccountingServer{{ID: "s1", Name: "aaa", Address: addr, Secret: "secret", InterimInterval: 60}}}
+	s, now := newTestService(t, store)
+
+	s.SessionStarted(testSession)
+	s.SessionUsage("ref-1", 5<<32+100, 200)
+
+	*now = now.Add(30 * time.Second)
+	s.sendInterims()
+
+	*now = now.Add(30 * time.Second)
+	s.sendInterims()
+	s.SessionUsage("ref-1", 1, 1)
+
+	*now = now.Add(5 * time.Second)
+	s.SessionStopped("ref-1")
+
+	want := []uint32{radius.AcctStatusStart, radius.AcctStatusInterimUpdate, radius.AcctStatusStop}
+	if got := srv.statuses(t, 3); !slices.Equal(got, want) {
+		t.Fatalf("statuses = %v, want %v", got, want)
+	}
+
+	stop := srv.last()
+
+	if v, _ := stop.Uint32(radius.TypeAcctInputOctets); v != 101 {
+		t.Errorf("Acct-Input-Octets = %d, want 101", v)
+	}
+
+	if v, _ := stop.Uint32(radius.TypeAcctInputGigawords); v != 5 {
+		t.Errorf("Acct-Input-Gigawords = %d, want 5", v)
+	}
+
+	if v, _ := stop.Uint32(radius.TypeAcctSessionTime); v != 65 {
+		t.Errorf("Acct-Session-Time = %d, want 65", v)
+	}
+
+	if v, _ := stop.Uint32(radius.TypeAcctTerminateCause); v != radius.TerminateCauseUserRequest {
+		t.Errorf("Acct-Terminate-Cause = %d, want User-Request", v)
+	}
+
+	if _, ok := stop.Uint32(radius.TypeAcctDelayTime); !ok {
+		t.Error("no Acct-Delay-Time")
+	}
+
+	if rat, _ := stop.Vendor(radius.VendorID3GPP, vsaRATType); !slices.Equal(rat, []byte{byte(RATEUTRAN)}) {
+		t.Errorf("3GPP-RAT-Type = %x, want EUTRAN", rat)
+	}
+
+	if _, ok := stop.Vendor(radius.VendorID3GPP, vsaUserLocationInfo); !ok {
+		t.Error("no 3GPP-User-Location-Info")
+	}
+
+	if len(store.queued()) != 0 {
+		t.Errorf("outbox holds %d records, want none", len(store.queued()))
+	}
+}
+
+func TestService_RetriesInOrder(t *testing.T) {
+	srv, addr := startAccountingServer(t, radius.CodeAccessReject)
+	store := &fakeStore{servers: []db.AccountingServer{{ID: "s1", Name: "aaa", Address: addr, Secret: "secret"}}}
+	s, _ := newTestService(t, store)
+
+	s.SessionStarted(testSession)
+	s.SessionStopped("ref-1")
+
+	want := []uint32{radius.AcctStatusStart, radius.AcctStatusStart, radius.AcctStatusStop}
+	if got := srv.statuses(t, 3); !slices.Equal(got, want) {
+		t.Fatalf("statuses = %v, want %v", got, want)
+	}
+}
+
+func TestService_StopLeavesNASRebootQueued(t *testing.T) {
+	_, addr := startAccountingServer(t, radius.CodeAccessReject, radius.CodeAccessReject, radius.CodeAccessReject)
+	store := &fakeStore{servers: []db.AccountingServer{{ID: "s1", Name: "aaa", Address: addr, Secret: "secret"}}}
+	s, _ := newTestService(t, store)
+	s.retry = time.Hour
+
+	s.SessionStarted(testSession)
+	s.Stop()
+
+	queued := store.queued()
+	if len(queued) == 0 {
+		t.Fatal("outbox is empty, want the Stop record kept")
+	}
+
+	attrs, err := radius.ParseAttributes(queued[len(queued)-1].Attributes)
+	if err != nil {
+		t.Fatalf("decode record: %v", err)
+	}
+
+	p := &radius.Packet{Attributes: attrs}
+	if v, _ := p.Uint32(radius.TypeAcctTerminateCause); v != radius.TerminateCauseNASReboot {
+		t.Errorf("Acct-Terminate-Cause = %d, want NAS-Reboot", v)
+	}
+}
+
+func TestService_ReconcileDropsRemovedServer(t *testing.T) {
+	store := &fakeStore{records: []db.AccountingRecord{{ID: 1, ServerID: "gone"}}}
+	newTestService(t, store)
+
+	if len(store.queued()) != 0 {
+		t.Errorf("outbox holds %d records, want the removed server's dropped", len(store.queued()))
+	}
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package accounting sends RADIUS accounting (RFC 2866) for the sessions a
+// node serves. Each session produces a Start record, periodic
+// Interim-Updates and a Stop record, sent to every configured accounting
+// server. Records go through an outbox in the local database, so they
+// survive a server outage and a restart.
+package accounting
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// RAT is the radio access a session runs on, valued as 3GPP-RAT-Type
+// (TS 29.061 §16.4.7).
+type RAT uint8
+
+const (
+	RATEUTRAN RAT = 6
+	RATNR     RAT = 10
+)
+
+// Session describes a session when it starts.
+type Session struct {
+	// Ref identifies the session to the caller; later calls name it.
+	Ref        string
+	IMSI       string
+	DNN        string
+	RAT        RAT
+	IPv4       netip.Addr
+	IPv6Prefix netip.Prefix
+}
+
+// UE is what the access side knows of a subscriber's device.
+type UE struct {
+	IMEI     string
+	Location models.UserLocation
+}
+
+// UEDirectory looks up a UE on the access it is attached to. The AMF
+// answers for NR and the MME for E-UTRAN.
+type UEDirectory interface {
+	LookupUE(imsi string, rat RAT) (UE, bool)
+}
+
+// Store is the part of the database the service uses. *db.Database
+// satisfies it.
+type Store interface {
+	ListAccountingServers(ctx context.Context) ([]db.AccountingServer, error)
+	EnqueueAccountingRecords(ctx context.Context, records []db.AccountingRecord) error
+	ListAccountingRecords(ctx context.Context, serverID string, limit int) ([]db.AccountingRecord, error)
+	DeleteAccountingRecord(ctx context.Context, id int64) error
+	TrimAccountingRecords(ctx context.Context, serverID string, keep int) error
+	ListAccountingRecordServers(ctx context.Context) ([]string, error)
+	DeleteAccountingRecordsForServer(ctx context.Context, serverID string) error
+}
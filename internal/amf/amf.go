@@ -112,6 +112,8 @@ type DBer interface {
 	GetPolicyByProfileAndSlice(ctx context.Context, profileID, sliceID string) (*db.Policy, error)
 	ListAllNetworkSlices(ctx context.Context) ([]db.NetworkSlice, error)
 	ListPoliciesByProfile(ctx context.Context, profileID string) ([]db.Policy, error)
+	ActiveSubscriberAmbrOverride(ctx context.Context, imsi string, now time.Time) (*db.SubscriberAmbrOverride, error)
+	SubscriberStateAt(ctx context.Context, imsi string, now time.Time) (db.SubscriberState, error)
 	NodeID() int
 }
 
@@ -127,6 +129,17 @@ type LPPHandler interface {
 	ForwardLPP(ctx context.Context, supi etsi.SUPI, correlationID, lppData []byte) error
 }
 
+// RegistrationQuotas keeps the count of UEs registered to each network slice,
+// which every node of a cluster shares.
+type RegistrationQuotas interface {
+	// AdmitRegistration counts supi against the slices of nssai with room for
+	// it, replacing its previous count, and returns the full ones and the
+	// longest back-off their quotas ask for.
+	AdmitRegistration(ctx context.Context, supi etsi.SUPI, nssai []models.Snssai) ([]models.Snssai, time.Duration, error)
+	// ReleaseRegistration stops counting supi.
+	ReleaseRegistration(ctx context.Context, supi etsi.SUPI) error
+}
+
 // Concurrency model:
 //
 //   - AMF.mu guards the registry and connection lifecycle: the UE, radio and conn
@@ -175,6 +188,8 @@ type AMF struct {
 	NAS                      NASHandler
 	LPPHandler               LPPHandler
 	EPS                      interworking.EPSPeer
+	Quotas                   RegistrationQuotas
+	Observer                 UEObserver
 }
 
 func (a *AMF) HandoverGuardTimeout() time.Duration {
@@ -217,6 +232,9 @@ func (amf *AMF) CommitUEIdentity(ctx context.Context, ue *UeContext, _ AuthProof
 	superseded = superseded && old != ue
 	amf.UEs[ue.supi] = ue
 	ue.smf = amf.Session
+	ue.mu.Lock()
+	ue.observer = amf.Observer
+	ue.mu.Unlock()
 	amf.mu.Unlock()
 
 	if superseded {
@@ -316,11 +334,16 @@ func (amf *AMF) DeregisterAndRemoveUeContext(ctx context.Context, ue *UeContext)
 	// Only delete the SUPI index if it still points to this context: an authenticated
 	// re-registration indexes the new context under the same SUPI before this superseded
 	// context is torn down, and deleting unconditionally would drop the live registration.
-	if ue.supi.IsValid() && amf.UEs[ue.supi] == ue {
+	removed := ue.supi.IsValid() && amf.UEs[ue.supi] == ue
+	if removed {
 		delete(amf.UEs, ue.supi)
 	}
 
 	amf.mu.Unlock()
+
+	if removed {
+		amf.releaseRegistration(ctx, ue.supi)
+	}
 }
 
 func (amf *AMF) DeregisterSubscriber(ctx context.Context, supi etsi.SUPI) {
@@ -600,6 +623,44 @@ func (amf *AMF) CountRegisteredSubscribers() int {
 	return count
 }
 
+// CountRegisteredSubscribersBySlice returns the number of registered UEs
+// allowed on each slice, leaving out except.
+func (amf *AMF) CountRegisteredSubscribersBySlice(except etsi.SUPI) map[models.Snssai]int {
+	amf.mu.RLock()
+	defer amf.mu.RUnlock()
+
+	counts := make(map[models.Snssai]int)
+
+	for supi, ue := range amf.UEs {
+		if supi == except || ue.State() != Registered {
+			continue
+		}
+
+		for _, snssai := range ue.AllowedNssai {
+			counts[models.Snssai{Sst: snssai.Sst, Sd: models.NormalizeSD(snssai.Sd)}]++
+		}
+	}
+
+	return counts
+}
+
+// RegisteredSlices returns the allowed slices of each UE registered to this
+// AMF, for reconciling the slice registration count.
+func (amf *AMF) RegisteredSlices() map[etsi.SUPI][]models.Snssai {
+	amf.mu.RLock()
+	defer amf.mu.RUnlock()
+
+	out := make(map[etsi.SUPI][]models.Snssai)
+
+	for supi, ue := range amf.UEs {
+		if ue.State() == Registered && len(ue.AllowedNssai) > 0 {
+			out[supi] = append([]models.Snssai(nil), ue.AllowedNssai...)
+		}
+	}
+
+	return out
+}
+
 // RemoveRadio removes a radio and all UEs bound to it.
 func (amf *AMF) RemoveRadio(ctx context.Context, ran *Radio) {
 	amf.RemoveAllUeInRan(ctx, ran)
@@ -117,6 +117,8 @@ type UeContext struct {
 	pagingTimer guard.Guard
 
 	n1n2Message atomic.Pointer[models.N1N2MessageTransferRequest]
+
+	observer UEObserver
 }
 
 func NewUeContext() *UeContext {
@@ -218,6 +220,10 @@ func (a *AMF) attachUeConnLocked(ue *UeContext, ueConn *UeConn) *UeConn {
 
 	a.stopIdleTimersLocked(ue)
 
+	if oldUeConn == nil {
+		ue.connectionChanged()
+	}
+
 	return displaced
 }
 
@@ -653,6 +659,7 @@ func (a *AMF) detachUeConnLocked(ue *UeContext, target *UeConn) *UeConn {
 	}
 
 	ue.active.Store(nil)
+	ue.connectionChanged()
 
 	return cur
 }
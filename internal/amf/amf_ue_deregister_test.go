@@ -40,6 +40,8 @@ func (s *deregisterTestSmf) SessionsByDNN(string) []*smf.SMContext { return nil
 
 func (s *deregisterTestSmf) SessionCount() int { return 0 }
 
+func (s *deregisterTestSmf) SessionCountsBySlice() map[models.Snssai]int { return nil }
+
 func (s *deregisterTestSmf) CreateSmContext(context.Context, etsi.SUPI, uint8, string, *models.Snssai, fgs.RequestType, []byte, uint8) (string, []byte, error) {
 	return "", nil, nil
 }
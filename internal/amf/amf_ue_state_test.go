@@ -4,8 +4,11 @@
 package amf
 
 import (
+	"slices"
 	"sync"
 	"testing"
+
+	"github.com/ellanetworks/core/etsi"
 )
 
 func TestTransitionTo_AllowedTransitions(t *testing.T) {
@@ -154,3 +157,47 @@ func TestNewUeContext_DefaultState(t *testing.T) {
 		t.Fatalf("expected initial state Deregistered, got %s", ue.state)
 	}
 }
+
+type recordingObserver struct {
+	seen []UEStatus
+}
+
+func (r *recordingObserver) UEChanged(_ etsi.SUPI, status UEStatus) {
+	r.seen = append(r.seen, status)
+}
+
+func TestUEObserver_ToldOfRegistrationAndConnection(t *testing.T) {
+	supi, err := etsi.NewSUPIFromIMSI("001010000000001")
+	if err != nil {
+		t.Fatalf("invalid IMSI: %v", err)
+	}
+
+	obs := &recordingObserver{}
+	ue := NewUeContext()
+	ue.supi = supi
+	ue.observer = obs
+
+	ue.TransitionTo(RegistrationInitiated)
+	ue.active.Store(&UeConn{})
+	ue.connectionChanged()
+	ue.TransitionTo(Registered)
+
+	// A mobility registration update keeps the registration.
+	ue.TransitionTo(RegistrationInitiated)
+	ue.TransitionTo(Registered)
+
+	ue.active.Store(nil)
+	ue.connectionChanged()
+
+	ue.TransitionTo(Deregistered)
+
+	want := []UEStatus{
+		{Registered: true, Connected: true},
+		{Registered: true, Connected: true},
+		{Registered: true},
+		{},
+	}
+	if !slices.Equal(obs.seen, want) {
+		t.Fatalf("observed %+v, want %+v", obs.seen, want)
+	}
+}
@@ -128,8 +128,9 @@ func BuildServiceReject(cause fgs.GMMCause) ([]byte, error) {
 	return (&fgs.ServiceReject{Cause: cause}).MarshalBinary()
 }
 
-// T3346 timer are not supported
-func BuildRegistrationReject(t3502Value int, cause5GMM fgs.GMMCause) ([]byte, error) {
+// BuildRegistrationReject builds a REGISTRATION REJECT; a non-zero t3346 is the
+// back-off of a reject for congestion (TS 24.501 §5.5.1.2.5).
+func BuildRegistrationReject(t3502Value int, cause5GMM fgs.GMMCause, t3346 time.Duration) ([]byte, error) {
 	m := &fgs.RegistrationReject{Cause: cause5GMM}
 
 	if t3502Value != 0 {
@@ -141,6 +142,15 @@ func BuildRegistrationReject(t3502Value int, cause5GMM fgs.GMMCause) ([]byte, er
 		m.T3502 = &timer
 	}
 
+	if t3346 != 0 {
+		timer, err := nas.GPRSTimer2FromDuration(t3346)
+		if err != nil {
+			return nil, err
+		}
+
+		m.T3346 = &timer
+	}
+
 	return m.MarshalBinary()
 }
 
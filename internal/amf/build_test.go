@@ -130,6 +130,30 @@ func TestBuildConfigurationUpdateCommand_WithGUTI_InvalidGUTI_Error(t *testing.T
 	}
 }
 
+func TestBuildRegistrationReject_CongestionCarriesT3346(t *testing.T) {
+	raw, err := amf.BuildRegistrationReject(0, fgs.GMMCauseCongestion, 2*time.Minute)
+	if err != nil {
+		t.Fatalf("BuildRegistrationReject failed: %v", err)
+	}
+
+	reject, err := fgs.ParseRegistrationReject(raw)
+	if err != nil {
+		t.Fatalf("parse RegistrationReject: %v", err)
+	}
+
+	if reject.Cause != fgs.GMMCauseCongestion {
+		t.Errorf("cause %v, want congestion", reject.Cause)
+	}
+
+	if reject.T3346 == nil {
+		t.Fatal("T3346 missing")
+	}
+
+	if d, _ := reject.T3346.Duration(); d != 2*time.Minute {
+		t.Errorf("T3346 %v, want 2m", d)
+	}
+}
+
 func TestBuildRegistrationAccept_MultipleAllowedNSSAI(t *testing.T) {
 	amfInstance := amf.New(nil, nil, nil)
 
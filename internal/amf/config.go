@@ -5,18 +5,22 @@ package amf
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/amf/util"
 	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/nas"
 	"github.com/ellanetworks/core/ngap"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
 var tracer = otel.Tracer("ella-core/amf")
@@ -118,11 +122,19 @@ func getSupportedTAIs(operator *db.Operator) ([]models.Tai, error) {
 
 // SubscriberProfile holds the per-subscriber session configuration
 // derived from the subscriber's profile: allowed network slices and bitrate.
+// Allow5G and Allow4G are false while State refuses registration.
 type SubscriberProfile struct {
 	AllowedNssai []models.Snssai
 	Ambr         *models.Ambr
 	Allow5G      bool
 	Allow4G      bool
+	State        db.SubscriberState
+
+	// FullNssai is the subscribed slices AdmitRegistration left out of
+	// AllowedNssai because they hold their maximum of registered UEs;
+	// FullBackOff is the longest back-off their quotas ask for.
+	FullNssai   []models.Snssai
+	FullBackOff time.Duration
 }
 
 func (amf *AMF) SubscriberProfile(ctx context.Context, supi etsi.SUPI) (*SubscriberProfile, error) {
@@ -162,7 +174,7 @@ func (amf *AMF) SubscriberProfile(ctx context.Context, supi etsi.SUPI) (*Subscri
 		return nil, fmt.Errorf("couldn't list slices by IDs: %w", err)
 	}
 
-	var allowedNssai []models.Snssai
+	allowedNssai := make([]models.Snssai, 0, len(slices))
 
 	for _, slice := range slices {
 		sd := ""
@@ -181,12 +193,25 @@ func (amf *AMF) SubscriberProfile(ctx context.Context, supi etsi.SUPI) (*Subscri
 		return nil, fmt.Errorf("couldn't get profile %s: %v", subscriber.ProfileID, err)
 	}
 
-	ambrDL, err := models.ParseBitRate(profile.UeAmbrDownlink)
+	// A subscriber's own override, while it lasts, wins over the profile.
+	override, err := amf.DBInstance.ActiveSubscriberAmbrOverride(ctx, imsi, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get AMBR override of subscriber %s: %w", imsi, err)
+	}
+
+	ueAmbrUL, ueAmbrDL := override.UeAmbr(profile.UeAmbrUplink, profile.UeAmbrDownlink)
+
+	state, err := amf.DBInstance.SubscriberStateAt(ctx, imsi, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get state of subscriber %s: %w", imsi, err)
+	}
+
+	ambrDL, err := models.ParseBitRate(ueAmbrDL)
 	if err != nil {
 		return nil, fmt.Errorf("profile %s UE-AMBR downlink: %w", subscriber.ProfileID, err)
 	}
 
-	ambrUL, err := models.ParseBitRate(profile.UeAmbrUplink)
+	ambrUL, err := models.ParseBitRate(ueAmbrUL)
 	if err != nil {
 		return nil, fmt.Errorf("profile %s UE-AMBR uplink: %w", subscriber.ProfileID, err)
 	}
@@ -197,11 +222,62 @@ func (amf *AMF) SubscriberProfile(ctx context.Context, supi etsi.SUPI) (*Subscri
 			Downlink: ambrDL,
 			Uplink:   ambrUL,
 		},
-		Allow5G: profile.Allow5G,
-		Allow4G: profile.Allow4G,
+		Allow5G: profile.Allow5G && state.AllowsRegistration(),
+		Allow4G: profile.Allow4G && state.AllowsRegistration(),
+		State:   state,
 	}, nil
 }
 
+// AdmitRegistration counts a UE registering with profile against the
+// maximum of registered UEs of its slices, and moves the slices without room
+// from AllowedNssai to FullNssai (TS 23.501 §5.15.11). The count spans the
+// cluster, so a quota holds however many nodes serve the slice.
+//
+// A count that fails, as on a node cut off from the cluster leader, admits
+// every slice: the quotas must not make the leader a dependency of every
+// registration. The quota reconciler records the UE once it can.
+func (amf *AMF) AdmitRegistration(ctx context.Context, supi etsi.SUPI, profile *SubscriberProfile) {
+	if amf.Quotas == nil {
+		return
+	}
+
+	full, backOff, err := amf.Quotas.AdmitRegistration(ctx, supi, profile.AllowedNssai)
+	if err != nil {
+		logger.From(ctx, logger.AmfLog).Warn("couldn't count registration against the slice quotas; admitting it until reconciled",
+			logger.SUPI(supi.String()), zap.Error(err))
+
+		return
+	}
+
+	if len(full) == 0 {
+		return
+	}
+
+	allowed := make([]models.Snssai, 0, len(profile.AllowedNssai))
+
+	for _, snssai := range profile.AllowedNssai {
+		if !slices.ContainsFunc(full, snssai.Equal) {
+			allowed = append(allowed, snssai)
+		}
+	}
+
+	profile.AllowedNssai = allowed
+	profile.FullNssai = full
+	profile.FullBackOff = backOff
+}
+
+// releaseRegistration stops counting supi against the slice quotas.
+func (amf *AMF) releaseRegistration(ctx context.Context, supi etsi.SUPI) {
+	if amf.Quotas == nil {
+		return
+	}
+
+	if err := amf.Quotas.ReleaseRegistration(ctx, supi); err != nil {
+		logger.AmfLog.Warn("couldn't release slice registrations; they count against the quotas until reconciled",
+			logger.SUPI(supi.String()), zap.Error(err))
+	}
+}
+
 func (amf *AMF) SubscriberDnn(ctx context.Context, supi etsi.SUPI, snssai *models.Snssai) (string, error) {
 	if snssai == nil {
 		return "", fmt.Errorf("snssai is nil")
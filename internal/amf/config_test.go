@@ -5,12 +5,16 @@ package amf_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/amf"
 	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
 )
 
 // configTestDB implements amf.DBer for SubscriberProfile / OperatorInfo tests.
@@ -24,6 +28,8 @@ type configTestDB struct {
 	allSlices  []db.NetworkSlice
 	operator   *db.Operator
 	opErr      error
+	override   *db.SubscriberAmbrOverride
+	state      db.SubscriberState
 }
 
 func (d *configTestDB) GetOperator(context.Context) (*db.Operator, error) {
@@ -60,7 +66,7 @@ func (d *configTestDB) ListNetworkSlicesByIDs(_ context.Context, ids []string) (
 }
 
 func (d *configTestDB) GetProfileByID(_ context.Context, id string) (*db.Profile, error) {
-	return &db.Profile{ID: id, UeAmbrDownlink: "200 Mbps", UeAmbrUplink: "100 Mbps"}, nil
+	return &db.Profile{ID: id, UeAmbrDownlink: "200 Mbps", UeAmbrUplink: "100 Mbps", Allow4G: true, Allow5G: true}, nil
 }
 
 func (d *configTestDB) GetPolicyByProfileAndSlice(context.Context, string, string) (*db.Policy, error) {
@@ -85,6 +91,18 @@ func (d *configTestDB) ListPoliciesByProfile(_ context.Context, _ string) ([]db.
 	return d.policies, d.polErr
 }
 
+func (d *configTestDB) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return d.override, nil
+}
+
+func (d *configTestDB) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	if d.state == "" {
+		return db.SubscriberStateActive, nil
+	}
+
+	return d.state, nil
+}
+
 func (d *configTestDB) NodeID() int { return 0 }
 
 func mustSUPI(t *testing.T) etsi.SUPI {
@@ -126,6 +144,24 @@ func TestGetSubscriberProfile_SinglePolicy(t *testing.T) {
 	}
 }
 
+func TestGetSubscriberProfile_AmbrOverride(t *testing.T) {
+	fakeDB := &configTestDB{
+		subscriber: &db.Subscriber{ID: "sub-1", Imsi: "001010000000001", ProfileID: "profile-10"},
+		override:   &db.SubscriberAmbrOverride{IMSI: "001010000000001", UeAmbrUplink: "1 Gbps", UeAmbrDownlink: "2 Gbps"},
+	}
+
+	amfInstance := amf.New(fakeDB, nil, nil)
+
+	profile, err := amfInstance.SubscriberProfile(context.Background(), mustSUPI(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if profile.Ambr.Uplink.String() != "1 Gbps" || profile.Ambr.Downlink.String() != "2 Gbps" {
+		t.Fatalf("UE-AMBR = %s/%s, want the override's 1 Gbps/2 Gbps", profile.Ambr.Uplink, profile.Ambr.Downlink)
+	}
+}
+
 func TestGetSubscriberProfile_MultiplePoliciesDifferentSlices(t *testing.T) {
 	sd1 := "010203"
 	sd2 := "aabbcc"
@@ -256,6 +292,184 @@ func TestGetSubscriberProfile_PolicyListError(t *testing.T) {
 	}
 }
 
+// fakeRegistrationQuotas counts registrations as the cluster store does,
+// including ones another node admitted.
+type fakeRegistrationQuotas struct {
+	limits     map[models.Snssai]int
+	backOff    time.Duration
+	registered map[etsi.SUPI][]models.Snssai
+	err        error
+}
+
+func (f *fakeRegistrationQuotas) AdmitRegistration(_ context.Context, supi etsi.SUPI, nssai []models.Snssai) ([]models.Snssai, time.Duration, error) {
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+
+	var full, kept []models.Snssai
+
+	for _, snssai := range nssai {
+		count := 0
+
+		for other, held := range f.registered {
+			if other != supi && slices.Contains(held, snssai) {
+				count++
+			}
+		}
+
+		if limit := f.limits[snssai]; limit > 0 && count >= limit {
+			full = append(full, snssai)
+			continue
+		}
+
+		kept = append(kept, snssai)
+	}
+
+	f.registered[supi] = kept
+
+	if len(full) == 0 {
+		return nil, 0, nil
+	}
+
+	return full, f.backOff, nil
+}
+
+func (f *fakeRegistrationQuotas) ReleaseRegistration(_ context.Context, supi etsi.SUPI) error {
+	delete(f.registered, supi)
+	return nil
+}
+
+func TestAdmitRegistration_SliceFullOfRegisteredUEs(t *testing.T) {
+	sdA, sdB := "010203", "040506"
+	sliceA, sliceB := models.Snssai{Sst: 1, Sd: sdA}, models.Snssai{Sst: 1, Sd: sdB}
+
+	other, err := etsi.NewSUPIFromIMSI("001010000000007")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fakeDB := &configTestDB{
+		subscriber: &db.Subscriber{ID: "sub-1", Imsi: "001010000000001", ProfileID: "profile-10"},
+		policies: []db.Policy{
+			{ID: "policy-1", ProfileID: "profile-10", SliceID: "slice-a"},
+			{ID: "policy-2", ProfileID: "profile-10", SliceID: "slice-b"},
+		},
+		slices: map[string]*db.NetworkSlice{
+			"slice-a": {ID: "slice-a", Name: "slice-a", Sst: 1, Sd: &sdA},
+			"slice-b": {ID: "slice-b", Name: "slice-b", Sst: 1, Sd: &sdB},
+		},
+	}
+
+	// The UE filling slice-a is registered to another node.
+	quotas := &fakeRegistrationQuotas{
+		limits:     map[models.Snssai]int{sliceA: 1, sliceB: 1},
+		backOff:    2 * time.Minute,
+		registered: map[etsi.SUPI][]models.Snssai{other: {sliceA}},
+	}
+
+	amfInstance := amf.New(fakeDB, nil, nil)
+	amfInstance.Quotas = quotas
+
+	profile, err := amfInstance.SubscriberProfile(context.Background(), mustSUPI(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	amfInstance.AdmitRegistration(context.Background(), mustSUPI(t), profile)
+
+	if len(profile.AllowedNssai) != 1 || profile.AllowedNssai[0].Sd != sdB {
+		t.Fatalf("allowed NSSAI %+v, want only slice-b", profile.AllowedNssai)
+	}
+
+	if len(profile.FullNssai) != 1 || profile.FullNssai[0].Sd != sdA {
+		t.Fatalf("full NSSAI %+v, want slice-a", profile.FullNssai)
+	}
+
+	if profile.FullBackOff != 2*time.Minute {
+		t.Fatalf("back-off %v, want 2m", profile.FullBackOff)
+	}
+
+	// The subscriber's own registration does not count against its update.
+	profile, err = amfInstance.SubscriberProfile(context.Background(), mustSUPI(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	amfInstance.AdmitRegistration(context.Background(), mustSUPI(t), profile)
+
+	if len(profile.AllowedNssai) != 1 || profile.AllowedNssai[0].Sd != sdB {
+		t.Fatalf("allowed NSSAI %+v, want slice-b kept for its own UE", profile.AllowedNssai)
+	}
+
+	// Removing the UE context releases its registration.
+	ue := addTestUE(t, amfInstance, "001010000000001", func(ue *amf.UeContext) {
+		ue.ForceStateForTest(amf.Registered)
+		ue.AllowedNssai = profile.AllowedNssai
+	})
+
+	amfInstance.DeregisterAndRemoveUeContext(context.Background(), ue)
+
+	if _, ok := quotas.registered[mustSUPI(t)]; ok {
+		t.Fatal("registration still counted after the UE context was removed")
+	}
+}
+
+func TestAdmitRegistration_CountFailureAdmits(t *testing.T) {
+	sd := "010203"
+
+	fakeDB := &configTestDB{
+		subscriber: &db.Subscriber{ID: "sub-1", Imsi: "001010000000001", ProfileID: "profile-10"},
+		policies:   []db.Policy{{ID: "policy-1", ProfileID: "profile-10", SliceID: "slice-a"}},
+		slices: map[string]*db.NetworkSlice{
+			"slice-a": {ID: "slice-a", Name: "slice-a", Sst: 1, Sd: &sd},
+		},
+	}
+
+	amfInstance := amf.New(fakeDB, nil, nil)
+	amfInstance.Quotas = &fakeRegistrationQuotas{err: errors.New("no leader")}
+
+	profile, err := amfInstance.SubscriberProfile(context.Background(), mustSUPI(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	amfInstance.AdmitRegistration(context.Background(), mustSUPI(t), profile)
+
+	if len(profile.AllowedNssai) != 1 || len(profile.FullNssai) != 0 {
+		t.Fatalf("allowed NSSAI %+v, full NSSAI %+v, want the slice admitted", profile.AllowedNssai, profile.FullNssai)
+	}
+}
+
+func TestGetSubscriberProfile_SubscriberState(t *testing.T) {
+	for _, tc := range []struct {
+		state     db.SubscriberState
+		registers bool
+	}{
+		{db.SubscriberStateActive, true},
+		{db.SubscriberStateBarredData, true},
+		{db.SubscriberStateSuspended, false},
+		{db.SubscriberStateExpired, false},
+	} {
+		fakeDB := &configTestDB{
+			subscriber: &db.Subscriber{ID: "sub-1", Imsi: "001010000000001", ProfileID: "profile-10"},
+			state:      tc.state,
+		}
+
+		profile, err := amf.New(fakeDB, nil, nil).SubscriberProfile(context.Background(), mustSUPI(t))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.state, err)
+		}
+
+		if profile.Allow5G != tc.registers || profile.Allow4G != tc.registers {
+			t.Errorf("%s: Allow5G=%v Allow4G=%v, want %v", tc.state, profile.Allow5G, profile.Allow4G, tc.registers)
+		}
+
+		if profile.State != tc.state {
+			t.Errorf("state %q, want %q", profile.State, tc.state)
+		}
+	}
+}
+
 func TestListOperatorSnssai_MultipleSlices(t *testing.T) {
 	sd1 := "010203"
 	sd2 := "aabbcc"
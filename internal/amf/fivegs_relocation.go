@@ -109,6 +109,8 @@ func (a *AMF) ForwardRelocation(ctx context.Context, req interworking.FiveGSRelo
 		}}
 	}
 
+	a.AdmitRegistration(ctx, req.SUPI, subscriberProfile)
+
 	snssaiList := subscriberProfile.AllowedNssai
 	if len(snssaiList) == 0 {
 		return none, fmt.Errorf("amf: %s is subscribed to no network slice", req.SUPI)
@@ -36,12 +36,34 @@ func (ueConn *UeConn) UpdateLocation(ctx context.Context, uli ngap.UserLocationI
 
 	if ue := ueConn.ue.Load(); ue != nil {
 		ue.mu.Lock()
+		previous := ue.Location.ServingArea()
 		ue.Location = loc
 		ue.Tai = *tai
+
+		if ue.state == Registered {
+			ue.notifyLocked()
+		}
+
 		ue.mu.Unlock()
+
+		if previous != (models.ServingArea{}) && previous != loc.ServingArea() {
+			ueConn.servingAreaChanged(ue)
+		}
 	}
 }
 
+// servingAreaChanged reconciles the sessions of a registered UE that moved
+// to another tracking area or cell, whose policy may carry a location
+// variant there. It runs apart from the NGAP handler that reported the move,
+// which it would otherwise hold up on the database and the UE's answer.
+func (ueConn *UeConn) servingAreaChanged(ue *UeContext) {
+	if ueConn.amf == nil || ueConn.amf.Session == nil || ue.State() != Registered {
+		return
+	}
+
+	go ueConn.amf.ReconcileSessionsForUE(context.Background(), ue)
+}
+
 func (ueConn *UeConn) buildLocation(ctx context.Context, uli ngap.UserLocationInformation) (models.UserLocation, *models.Tai, bool) {
 	curTime := time.Now().UTC()
 	cellPlmnID := decodePLMN(uli.PLMNIdentity)
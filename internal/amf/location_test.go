@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/smf"
 	"github.com/ellanetworks/core/ngap"
 )
 
@@ -216,6 +217,43 @@ func TestUpdateLocationConcurrentReadWrite(t *testing.T) {
 	<-done
 }
 
+// A registered UE that moves to another cell has its sessions reconciled, so
+// a policy location variant for the new area takes effect; the first location
+// and a repeated one are not a move.
+func TestUpdateLocationReconcilesSessionsOnMove(t *testing.T) {
+	looked := make(chan string, 4)
+	fakeSmf := &deregisterTestSmf{session: func(ref string) *smf.SMContext {
+		looked <- ref
+		return nil
+	}}
+
+	ue := NewUeContext()
+	ue.SmContextList[1] = &SmContext{Ref: "ref-1"}
+	ue.ForceStateForTest(Registered)
+
+	c := &UeConn{amf: New(nil, nil, fakeSmf)}
+	c.ue.Store(ue)
+
+	c.UpdateLocation(context.Background(), testUserLocation(ngap.UserLocationNR, 1))
+	c.UpdateLocation(context.Background(), testUserLocation(ngap.UserLocationNR, 1))
+	c.UpdateLocation(context.Background(), testUserLocation(ngap.UserLocationNR, 2))
+
+	select {
+	case ref := <-looked:
+		if ref != "ref-1" {
+			t.Fatalf("reconciled %q, want ref-1", ref)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sessions not reconciled after the UE moved")
+	}
+
+	select {
+	case ref := <-looked:
+		t.Fatalf("unexpected second reconcile of %q", ref)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // operatorOnlyDB serves the operator record and nothing else; embedding the
 // interface leaves the rest nil, so a call this test does not expect panics.
 type operatorOnlyDB struct {
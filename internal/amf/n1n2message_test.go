@@ -33,6 +33,7 @@ type fakeNGAPSender struct {
 	pagingCalls                   int
 	locationReportingControlCalls int
 	nrppaTransportCalls           int
+	ueContextModificationCalls    int
 }
 
 // WriteMsg counts the sent NGAP PDU by procedure, standing in for a gNB
@@ -58,6 +59,8 @@ func (f *fakeNGAPSender) WriteMsg(b []byte, _ *sctp.SndRcvInfo) (int, error) {
 			f.locationReportingControlCalls++
 		case ngap.ProcDownlinkUEAssociatedNRPPaTransport:
 			f.nrppaTransportCalls++
+		case ngap.ProcUEContextModification:
+			f.ueContextModificationCalls++
 		}
 	}
 
@@ -104,13 +107,22 @@ func (f *fakeDBInstance) ListPoliciesByProfile(context.Context, string) ([]db.Po
 	return nil, nil
 }
 
+func (f *fakeDBInstance) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return nil, nil
+}
+
+func (f *fakeDBInstance) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateActive, nil
+}
+
 func (f *fakeDBInstance) NodeID() int { return 0 }
 
 type fakeSmf struct{}
 
-func (f *fakeSmf) GetSession(string) *smf.SMContext      { return nil }
-func (f *fakeSmf) SessionsByDNN(string) []*smf.SMContext { return nil }
-func (f *fakeSmf) SessionCount() int                     { return 0 }
+func (f *fakeSmf) GetSession(string) *smf.SMContext            { return nil }
+func (f *fakeSmf) SessionsByDNN(string) []*smf.SMContext       { return nil }
+func (f *fakeSmf) SessionCount() int                           { return 0 }
+func (f *fakeSmf) SessionCountsBySlice() map[models.Snssai]int { return nil }
 func (f *fakeSmf) CreateSmContext(context.Context, etsi.SUPI, uint8, string, *models.Snssai, fgs.RequestType, []byte, uint8) (string, []byte, error) {
 	return "", nil, nil
 }
@@ -6,6 +6,7 @@ package nas
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/amf"
@@ -115,6 +116,14 @@ func (fdb *fakeDBInstance) ListPoliciesByProfile(_ context.Context, _ string) ([
 	}, nil
 }
 
+func (fdb *fakeDBInstance) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return nil, nil
+}
+
+func (fdb *fakeDBInstance) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateActive, nil
+}
+
 func (fdb *fakeDBInstance) NodeID() int { return 0 }
 
 // fakeNGAPSender records the NGAP messages the AMF sends, standing in for an
@@ -411,6 +420,8 @@ func (s *fakeSmf) SessionsByDNN(_ string) []*smf.SMContext { return nil }
 
 func (s *fakeSmf) SessionCount() int { return 0 }
 
+func (s *fakeSmf) SessionCountsBySlice() map[models.Snssai]int { return nil }
+
 func (s *fakeSmf) ReconcileSmContext(_ context.Context, _ *models.SessionReconcileRequest) error {
 	return s.Error
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/ellanetworks/core/internal/amf"
 	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/internal/metrics"
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/nas/fgs"
 	"github.com/ellanetworks/core/ngap"
 	"go.uber.org/zap"
@@ -77,7 +78,8 @@ func HandleInitialRegistration(ctx context.Context, amfInstance *amf.AMF, ue *am
 
 		metrics.RegistrationAttempt(metrics.RAT5G, registrationTypeName(conn.RegistrationType5GS), metrics.ResultReject)
 
-		logger.From(ctx, logger.AmfLog).Info("registration rejected: 5G not allowed for subscriber")
+		logger.From(ctx, logger.AmfLog).Info("registration rejected: 5G not allowed for subscriber",
+			zap.String("state", string(subscriberProfile.State)))
 
 		amf.SendRegistrationReject(ctx, ueConn, fgs.GMMCauseServicesNotAllowed)
 
@@ -86,6 +88,10 @@ func HandleInitialRegistration(ctx context.Context, amfInstance *amf.AMF, ue *am
 		return
 	}
 
+	amfInstance.AdmitRegistration(ctx, ue.Supi(), subscriberProfile)
+
+	recordFullNssai(subscriberProfile)
+
 	if len(subscriberProfile.AllowedNssai) == 0 {
 		ueConn := ue.Conn()
 		if ueConn == nil {
@@ -95,7 +101,7 @@ func HandleInitialRegistration(ctx context.Context, amfInstance *amf.AMF, ue *am
 
 		metrics.RegistrationAttempt(metrics.RAT5G, registrationTypeName(conn.RegistrationType5GS), metrics.ResultReject)
 
-		amf.SendRegistrationReject(ctx, ueConn, fgs.GMMCauseServicesNotAllowed)
+		sendNoAllowedNssaiReject(ctx, ueConn, subscriberProfile)
 
 		releaseAbortedRegistration(ctx, ueConn)
 
@@ -146,3 +152,25 @@ func HandleInitialRegistration(ctx context.Context, amfInstance *amf.AMF, ue *am
 
 	amf.SendRegistrationAccept(ctx, amfInstance, ue, pduSessionStatus, nil, nil, nil, nil, *operatorInfo.Guami.PlmnID, operatorInfo.Guami)
 }
+
+// sendNoAllowedNssaiReject rejects a registration left with no slice to allow:
+// for congestion with the quota's back-off when the subscribed slices are full
+// of registered UEs, and otherwise as services not allowed.
+func sendNoAllowedNssaiReject(ctx context.Context, ueConn *amf.UeConn, profile *amf.SubscriberProfile) {
+	if len(profile.FullNssai) > 0 {
+		logger.From(ctx, logger.AmfLog).Info("registration rejected: subscribed slices hold their maximum of registered UEs")
+		amf.SendRegistrationRejectCongestion(ctx, ueConn, profile.FullBackOff)
+
+		return
+	}
+
+	amf.SendRegistrationReject(ctx, ueConn, fgs.GMMCauseServicesNotAllowed)
+}
+
+// recordFullNssai counts each subscribed slice the registration is refused
+// because it holds its maximum of registered UEs.
+func recordFullNssai(profile *amf.SubscriberProfile) {
+	for range profile.FullNssai {
+		metrics.QuotaRejection(models.QuotaSliceRegisteredUEs)
+	}
+}
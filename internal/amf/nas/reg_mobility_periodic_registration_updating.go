@@ -54,7 +54,8 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ctx context.Context, amfInsta
 	if !subscriberProfile.Allow5G {
 		metrics.RegistrationAttempt(metrics.RAT5G, registrationTypeName(conn.RegistrationType5GS), metrics.ResultReject)
 
-		logger.From(ctx, logger.AmfLog).Info("registration update rejected: 5G not allowed for subscriber")
+		logger.From(ctx, logger.AmfLog).Info("registration update rejected: 5G not allowed for subscriber",
+			zap.String("state", string(subscriberProfile.State)))
 
 		amf.SendRegistrationReject(ctx, ueConn, fgs.GMMCauseServicesNotAllowed)
 		ue.Deregister(ctx)
@@ -62,10 +63,14 @@ func HandleMobilityAndPeriodicRegistrationUpdating(ctx context.Context, amfInsta
 		return
 	}
 
+	amfInstance.AdmitRegistration(ctx, ue.Supi(), subscriberProfile)
+
+	recordFullNssai(subscriberProfile)
+
 	if len(subscriberProfile.AllowedNssai) == 0 {
 		metrics.RegistrationAttempt(metrics.RAT5G, registrationTypeName(conn.RegistrationType5GS), metrics.ResultReject)
 
-		amf.SendRegistrationReject(ctx, ueConn, fgs.GMMCauseServicesNotAllowed)
+		sendNoAllowedNssaiReject(ctx, ueConn, subscriberProfile)
 		ue.Deregister(ctx)
 
 		return
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/amf"
@@ -68,6 +69,14 @@ func (fdb *failingSubscriberDB) ListPoliciesByProfile(_ context.Context, _ strin
 	return []db.Policy{{ID: "policy-1", Name: "TestPolicy", ProfileID: "profile-1", SliceID: "slice-1", DataNetworkID: "dn-1"}}, nil
 }
 
+func (fdb *failingSubscriberDB) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return nil, nil
+}
+
+func (fdb *failingSubscriberDB) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateActive, nil
+}
+
 func (fdb *failingSubscriberDB) NodeID() int { return 0 }
 
 func decryptAndDecodeNasPdu(t *testing.T, ue *amf.UeContext, nasPdu []byte, dlCountOffset uint32) []byte {
@@ -781,6 +790,14 @@ func (m *multiSliceDB) ListPoliciesByProfile(_ context.Context, _ string) ([]db.
 	}, nil
 }
 
+func (m *multiSliceDB) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return nil, nil
+}
+
+func (m *multiSliceDB) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateActive, nil
+}
+
 func (m *multiSliceDB) NodeID() int { return 0 }
 
 func TestMobilityReg_MultiSlice_AllowedNssaiContainsAllSlices(t *testing.T) {
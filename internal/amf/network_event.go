@@ -7,7 +7,9 @@ import (
 	"context"
 	"net"
 
+	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/internal/sctp"
 )
 
@@ -50,3 +52,58 @@ func (a *AMF) LogNetworkEvent(ctx context.Context, conn NGAPWriter, messageType
 func (a *AMF) logOutboundNGAP(ctx context.Context, conn NGAPWriter, msgType NGAPProcedure, packet []byte) {
 	a.LogNetworkEvent(ctx, conn, msgType, logger.DirectionOutbound, packet)
 }
+
+// UEStatus is a UE as the AMF holds it after a transition.
+type UEStatus struct {
+	Registered bool
+	Connected  bool
+	Location   models.UserLocation
+}
+
+// UEObserver is told of the transitions of the UEs the AMF serves: a
+// registration or deregistration, a registered UE gaining or losing its
+// connection, and a registered UE reporting another serving cell. It is
+// called with the UE's lock held, in the order the transitions happen, so
+// it must neither block nor call back into the AMF.
+type UEObserver interface {
+	UEChanged(supi etsi.SUPI, status UEStatus)
+}
+
+// notifyLocked tells the observer of the UE as it now stands. The caller
+// holds ue.mu.
+func (ue *UeContext) notifyLocked() {
+	if ue.observer == nil || !ue.supi.IsValid() {
+		return
+	}
+
+	ue.observer.UEChanged(ue.supi, UEStatus{
+		Registered: ue.state == Registered,
+		Connected:  ue.active.Load() != nil,
+		Location:   ue.Location,
+	})
+}
+
+// connectionChanged tells the observer a registered UE gained or lost its
+// connection.
+func (ue *UeContext) connectionChanged() {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	if ue.state == Registered {
+		ue.notifyLocked()
+	}
+}
+
+// AnnounceUE tells the observer of the UE with supi as it stands, as if it
+// had just changed, so a new watcher of it learns where it starts from.
+func (a *AMF) AnnounceUE(supi etsi.SUPI) {
+	ue, ok := a.LookupUeBySupi(supi)
+	if !ok {
+		return
+	}
+
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	ue.notifyLocked()
+}
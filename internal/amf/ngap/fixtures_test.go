@@ -6,6 +6,7 @@ package ngap
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/amf"
@@ -272,6 +273,14 @@ func (fdb *fakeDBInstance) ListPoliciesByProfile(_ context.Context, _ string) ([
 	return []db.Policy{{ID: "policy-1", Name: "TestPolicy", ProfileID: "profile-1", SliceID: "slice-1", DataNetworkID: "dn-1"}}, nil
 }
 
+func (fdb *fakeDBInstance) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return nil, nil
+}
+
+func (fdb *fakeDBInstance) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateActive, nil
+}
+
 func (fdb *fakeDBInstance) NodeID() int { return 0 }
 
 // fakeNGAPSender records the NGAP messages the AMF sends, standing in for an
@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ngap
+
+import (
+	"context"
+
+	"github.com/ellanetworks/core/internal/amf"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/ngap"
+)
+
+// HandleUEContextModificationFailure records that the NG-RAN node refused a
+// new UE-AMBR (TS 38.413 §8.3.4.3). The UE context is left as it was: the AMF
+// holds the new value and hands it to the NG-RAN node at the next Initial
+// Context Setup.
+func HandleUEContextModificationFailure(ctx context.Context, amfInstance *amf.AMF, ran *amf.Radio, msg *ngap.UEContextModificationFailure) {
+	// The Cause is mandatory but ignore criticality, so it may be absent.
+	cause := "absent"
+	if msg.Cause != nil {
+		cause = msg.Cause.String()
+	}
+
+	ueConn, ok := resolveUEIDs(ctx, amfInstance, ran, msg.AMFUENGAPID, msg.RANUENGAPID)
+	if !ok {
+		return
+	}
+
+	reportDiagnostics(ctx, ran, ngap.ProcUEContextModification, ngap.TriggeringUnsuccessfulOutcome, ueAssociated(*msg.AMFUENGAPID, *msg.RANUENGAPID), msg.Diagnostics())
+
+	ueConn.TouchLastSeen()
+	logger.WithTrace(ctx, ueConn.Log).Warn("NG-RAN node did not modify the UE context", logger.Cause(cause))
+}
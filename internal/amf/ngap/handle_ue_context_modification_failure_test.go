@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ngap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/amf"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/ngap"
+)
+
+func TestUEContextModificationFailure_UnknownAmfUeNgapID(t *testing.T) {
+	amfInstance := newTestAMF()
+	ran := newTestRadio(amfInstance)
+	sender := ran.Conn.(*fakeNGAPSender)
+
+	HandleUEContextModificationFailure(context.Background(), amfInstance, ran, &ngap.UEContextModificationFailure{
+		AMFUENGAPID: ngap.Ptr(ngap.AMFUENGAPID(999)),
+		RANUENGAPID: ngap.Ptr(ngap.RANUENGAPID(99)),
+		Cause:       &ngap.Cause{Group: ngap.CauseGroupRadioNetwork, Value: ngap.CauseRadioNetworkUnspecified},
+	})
+
+	errInd := assertSingleErrorIndication(t, sender, ngap.CauseRadioNetworkUnknownLocalUENGAPID)
+	assertErrorIndicationEchoesIDs(t, errInd, 999, 99)
+}
+
+// A refusal leaves the UE context in place: the connection is not released.
+func TestUEContextModificationFailure_KeepsUEContext(t *testing.T) {
+	amfInstance := newTestAMF()
+	ran := newTestRadio(amfInstance)
+	sender := ran.Conn.(*fakeNGAPSender)
+
+	amf.NewUeConnForTest(ran, 1, 10, logger.AmfLog)
+
+	HandleUEContextModificationFailure(context.Background(), amfInstance, ran, &ngap.UEContextModificationFailure{
+		AMFUENGAPID: ngap.Ptr(ngap.AMFUENGAPID(10)),
+		RANUENGAPID: ngap.Ptr(ngap.RANUENGAPID(1)),
+	})
+
+	if len(sender.SentUEContextReleaseCommands) != 0 {
+		t.Fatalf("expected no UEContextReleaseCommand, got %d", len(sender.SentUEContextReleaseCommands))
+	}
+
+	if len(sender.SentErrorIndications) != 0 {
+		t.Fatalf("expected no ErrorIndication, got %d", len(sender.SentErrorIndications))
+	}
+}
@@ -0,0 +1,33 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ngap
+
+import (
+	"context"
+
+	"github.com/ellanetworks/core/internal/amf"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/ngap"
+	"go.uber.org/zap"
+)
+
+// HandleUEContextModificationResponse records that the NG-RAN node applied a
+// new UE-AMBR (TS 38.413 §8.3.4.2).
+func HandleUEContextModificationResponse(ctx context.Context, amfInstance *amf.AMF, ran *amf.Radio, msg *ngap.UEContextModificationResponse) {
+	// Both identities are mandatory but ignore criticality, so an absent one
+	// still reaches the handler and leaves nothing to resolve by (§10.3.5).
+	ueConn, ok := resolveUEIDs(ctx, amfInstance, ran, msg.AMFUENGAPID, msg.RANUENGAPID)
+	if !ok {
+		return
+	}
+
+	reportDiagnostics(ctx, ran, ngap.ProcUEContextModification, ngap.TriggeringSuccessfulOutcome, ueAssociated(*msg.AMFUENGAPID, *msg.RANUENGAPID), msg.Diagnostics())
+
+	if msg.UserLocationInformation != nil {
+		ueConn.UpdateLocation(ctx, *msg.UserLocationInformation)
+	}
+
+	ueConn.TouchLastSeen()
+	logger.WithTrace(ctx, ueConn.Log).Debug("Handle UEContextModificationResponse", zap.Uint64("amf-ue-id", uint64(ueConn.AmfUeNgapID)))
+}
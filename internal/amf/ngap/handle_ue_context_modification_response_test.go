@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ngap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ellanetworks/core/ngap"
+)
+
+// Both UE NGAP IDs are mandatory but ignore criticality, so an absent one
+// reaches the handler, which has nothing to resolve by (TS 38.413 §10.3.5).
+func TestUEContextModificationResponse_BothIDsNil(t *testing.T) {
+	amfInstance := newTestAMF()
+	ran := newTestRadio(amfInstance)
+	sender := ran.Conn.(*fakeNGAPSender)
+
+	HandleUEContextModificationResponse(context.Background(), amfInstance, ran, &ngap.UEContextModificationResponse{})
+
+	if len(sender.SentErrorIndications) != 0 {
+		t.Fatalf("expected no ErrorIndication, got %d", len(sender.SentErrorIndications))
+	}
+}
+
+func TestUEContextModificationResponse_UnknownAmfUeNgapID(t *testing.T) {
+	amfInstance := newTestAMF()
+	ran := newTestRadio(amfInstance)
+	sender := ran.Conn.(*fakeNGAPSender)
+
+	HandleUEContextModificationResponse(context.Background(), amfInstance, ran, &ngap.UEContextModificationResponse{
+		AMFUENGAPID: ngap.Ptr(ngap.AMFUENGAPID(999)),
+		RANUENGAPID: ngap.Ptr(ngap.RANUENGAPID(99)),
+	})
+
+	errInd := assertSingleErrorIndication(t, sender, ngap.CauseRadioNetworkUnknownLocalUENGAPID)
+	assertErrorIndicationEchoesIDs(t, errInd, 999, 99)
+}
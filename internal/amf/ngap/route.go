@@ -47,6 +47,8 @@ const (
 	pathSwitchRequestMessageType                  amf.NGAPProcedure = "PathSwitchRequest"
 	locationReportMessageType                     amf.NGAPProcedure = "LocationReport"
 	uplinkNRPPaTransportMessageType               amf.NGAPProcedure = "UplinkUEAssociatedNRPPaTransport"
+	ueContextModificationResponseMessageType      amf.NGAPProcedure = "UEContextModificationResponse"
+	ueContextModificationFailureMessageType       amf.NGAPProcedure = "UEContextModificationFailure"
 
 	uplinkRANConfigurationTransferMessageType amf.NGAPProcedure = "UplinkRANConfigurationTransfer"
 )
@@ -170,6 +172,8 @@ func routeSuccessful(ctx context.Context, amfInstance *amf.AMF, ran *amf.Radio,
 		receivePDUSessionResourceReleaseResponse(ctx, amfInstance, ran, msg, so, span)
 	case ngap.ProcPDUSessionResourceModify:
 		receivePDUSessionResourceModifyResponse(ctx, amfInstance, ran, msg, so, span)
+	case ngap.ProcUEContextModification:
+		receiveUEContextModificationResponse(ctx, amfInstance, ran, msg, so, span)
 	default:
 		return false
 	}
@@ -187,6 +191,8 @@ func routeUnsuccessful(ctx context.Context, amfInstance *amf.AMF, ran *amf.Radio
 		receiveHandoverFailure(ctx, amfInstance, ran, msg, uo, span)
 	case ngap.ProcInitialContextSetup:
 		receiveInitialContextSetupFailure(ctx, amfInstance, ran, msg, uo, span)
+	case ngap.ProcUEContextModification:
+		receiveUEContextModificationFailure(ctx, amfInstance, ran, msg, uo, span)
 	default:
 		return false
 	}
@@ -516,6 +522,32 @@ func receivePDUSessionResourceModifyResponse(ctx context.Context, amfInstance *a
 	HandlePDUSessionResourceModifyResponse(ctx, amfInstance, ran, resp)
 }
 
+func receiveUEContextModificationResponse(ctx context.Context, amfInstance *amf.AMF, ran *amf.Radio, msg []byte, so *ngap.SuccessfulOutcome, span trace.Span) {
+	traceMessage(ctx, amfInstance, ran, msg, ueContextModificationResponseMessageType, span)
+
+	resp, err := ngap.ParseUEContextModificationResponse(so.Value)
+	if err != nil {
+		logger.WithTrace(ctx, ran.Log).Warn("failed to decode UE Context Modification Response", zap.Error(err))
+
+		return
+	}
+
+	HandleUEContextModificationResponse(ctx, amfInstance, ran, resp)
+}
+
+func receiveUEContextModificationFailure(ctx context.Context, amfInstance *amf.AMF, ran *amf.Radio, msg []byte, uo *ngap.UnsuccessfulOutcome, span trace.Span) {
+	traceMessage(ctx, amfInstance, ran, msg, ueContextModificationFailureMessageType, span)
+
+	fail, err := ngap.ParseUEContextModificationFailure(uo.Value)
+	if err != nil {
+		logger.WithTrace(ctx, ran.Log).Warn("failed to decode UE Context Modification Failure", zap.Error(err))
+
+		return
+	}
+
+	HandleUEContextModificationFailure(ctx, amfInstance, ran, fail)
+}
+
 // receivePDUSessionResourceModifyIndication parses and handles a PDU SESSION
 // RESOURCE MODIFY INDICATION (TS 38.413 §10.3.5).
 func receivePDUSessionResourceModifyIndication(ctx context.Context, amfInstance *amf.AMF, ran *amf.Radio, msg []byte, im *ngap.InitiatingMessage, span trace.Span) {
@@ -39,6 +39,7 @@ const (
 	NGAPProcedureUEContextReleaseCommand          NGAPProcedure = "UEContextReleaseCommand"
 	NGAPProcedureDownlinkNRPPaTransport           NGAPProcedure = "DownlinkNRPPaTransport"
 	NGAPProcedureDownlinkRANStatusTransfer        NGAPProcedure = "DownlinkRANStatusTransfer"
+	NGAPProcedureUEContextModificationRequest     NGAPProcedure = "UEContextModificationRequest"
 )
 
 func GetSCTPStreamID(msgType NGAPProcedure) (uint16, error) {
@@ -58,7 +59,7 @@ func GetSCTPStreamID(msgType NGAPProcedure) (uint16, error) {
 		NGAPProcedurePDUSessionResourceModifyConfirm, NGAPProcedureHandoverCancelAcknowledge,
 		NGAPProcedureLocationReportingControl, NGAPProcedurePathSwitchRequestFailure,
 		NGAPProcedureDownlinkNRPPaTransport,
-		NGAPProcedureDownlinkRANStatusTransfer:
+		NGAPProcedureDownlinkRANStatusTransfer, NGAPProcedureUEContextModificationRequest:
 		return 1, nil
 	default:
 		return 0, fmt.Errorf("NGAP message type (%s) not supported", msgType)
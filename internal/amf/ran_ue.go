@@ -159,8 +159,8 @@ func (ueConn *UeConn) Release() {
 	}
 
 	a.mu.Lock()
-	if ue := ueConn.ue.Load(); ue != nil {
-		ue.active.CompareAndSwap(ueConn, nil)
+	if ue := ueConn.ue.Load(); ue != nil && ue.active.CompareAndSwap(ueConn, nil) {
+		ue.connectionChanged()
 	}
 	a.mu.Unlock()
 }
@@ -196,7 +196,18 @@ func SendRegistrationReject(ctx context.Context, ue *UeConn, cause5GMM fgs.GMMCa
 		[]attribute.KeyValue{attribute.Int("cause", int(cause5GMM))},
 		registrationRejectSHT(ue),
 		func(_ *UeContext) ([]byte, error) {
-			return BuildRegistrationReject(int(ue.amf.T3502Value.Seconds()), cause5GMM)
+			return BuildRegistrationReject(int(ue.amf.T3502Value.Seconds()), cause5GMM, 0)
+		})
+}
+
+// SendRegistrationRejectCongestion rejects with cause #22 and T3346, keeping the
+// UE from retrying until the back-off expires (TS 24.501 §5.5.1.2.5).
+func SendRegistrationRejectCongestion(ctx context.Context, ue *UeConn, t3346 time.Duration) {
+	sendGmm(ctx, ue, "nas/send_registration_reject",
+		[]attribute.KeyValue{attribute.Int("cause", int(fgs.GMMCauseCongestion))},
+		registrationRejectSHT(ue),
+		func(_ *UeContext) ([]byte, error) {
+			return BuildRegistrationReject(int(ue.amf.T3502Value.Seconds()), fgs.GMMCauseCongestion, t3346)
 		})
 }
 
@@ -1276,3 +1287,31 @@ func (ueConn *UeConn) SendLocationReportingControl(ctx context.Context, eventTyp
 
 	return nil
 }
+
+// ueContextModificationBytes builds a UE CONTEXT MODIFICATION REQUEST carrying
+// only a new UE Aggregate Maximum Bit Rate (TS 38.413 §9.2.2.7).
+func ueContextModificationBytes(amfID ngap.AMFUENGAPID, ranID ngap.RANUENGAPID, ambrUp, ambrDown models.BitRate) ([]byte, error) {
+	msg := &ngap.UEContextModificationRequest{
+		AMFUENGAPID: amfID,
+		RANUENGAPID: ranID,
+		UEAggregateMaximumBitRate: &ngap.UEAggregateMaximumBitRate{
+			DL: ngap.BitRate(ambrDown.Bps()),
+			UL: ngap.BitRate(ambrUp.Bps()),
+		},
+	}
+
+	return msg.Marshal()
+}
+
+// SendUEContextModificationRequest asks the NG-RAN node to enforce a new
+// UE-AMBR for this UE (TS 38.413 §8.3.4).
+func (ueConn *UeConn) SendUEContextModificationRequest(ctx context.Context, ambrUp, ambrDown models.BitRate) error {
+	pkt, err := ueContextModificationBytes(ngap.AMFUENGAPID(ueConn.AmfUeNgapID), ngap.RANUENGAPID(ueConn.RanUeNgapID), ambrUp, ambrDown)
+	if err != nil {
+		return fmt.Errorf("build UEContextModificationRequest: %w", err)
+	}
+
+	ueConn.SendNGAP(ctx, NGAPProcedureUEContextModificationRequest, pkt)
+
+	return nil
+}
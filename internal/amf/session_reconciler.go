@@ -127,6 +127,11 @@ func (r *SessionReconciler) Reconcile() {
 }
 
 func (r *SessionReconciler) reconcileUE(ue *UeContext) {
+	if r.amf.ReconcileSubscriberState(context.Background(), ue) {
+		return
+	}
+
+	r.amf.ReconcileUeAmbr(context.Background(), ue)
 	r.amf.ReconcileSessionsForUE(context.Background(), ue)
 }
 
@@ -177,7 +182,8 @@ func (amf *AMF) ReconcileSessionsForUE(ctx context.Context, ue *UeContext) {
 func permanentPolicyFailure(err error) bool {
 	return errors.Is(err, smf.ErrNoPolicyMatch) ||
 		errors.Is(err, smf.ErrDNNNotFound) ||
-		errors.Is(err, smf.ErrDNNNotInSlice)
+		errors.Is(err, smf.ErrDNNNotInSlice) ||
+		errors.Is(err, smf.ErrDataBarred)
 }
 
 // fetchSessionPolicy reads the latest policy for a session from the DB.
@@ -229,6 +235,7 @@ func (amf *AMF) fetchSessionPolicy(smContextRef string) (*models.SessionPolicyDe
 		MTU:                 policy.MTU,
 		IPv4Pool:            policy.IPv4Pool,
 		IPv6Pool:            policy.IPv6Pool,
+		RulesPolicyID:       policy.PolicyID,
 	}
 	if policy.QosData.Arp != nil {
 		delta.Arp = policy.QosData.Arp.PriorityLevel
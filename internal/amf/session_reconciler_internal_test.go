@@ -23,6 +23,7 @@ func TestPermanentPolicyFailure(t *testing.T) {
 		{"no matching policy", smf.ErrNoPolicyMatch, true},
 		{"data network gone", smf.ErrDNNNotFound, true},
 		{"data network unbound from slice", smf.ErrDNNNotInSlice, true},
+		{"subscriber barred from data", smf.ErrDataBarred, true},
 		{"wrapped", fmt.Errorf("get session policy: %w", smf.ErrDNNNotInSlice), true},
 		{"transient infrastructure error", errors.New("raft: propose timeout"), false},
 		{"nil", nil, false},
@@ -101,6 +101,12 @@ func (ue *UeContext) setStateLocked(target StateType) {
 	} else {
 		ue.regStep = RegStepNone
 	}
+
+	// A registration update passes through RegistrationInitiated without the
+	// UE losing its registration, so only the ends of the graph are told.
+	if target == Registered || target == Deregistered {
+		ue.notifyLocked()
+	}
 }
 
 // RegStep returns the phase within the registration procedure (meaningful only in
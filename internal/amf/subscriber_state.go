@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package amf
+
+import (
+	"context"
+	"time"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ReconcileSubscriberState deregisters a UE whose subscriber may no longer
+// register, being suspended or expired, and reports whether it did. A
+// subscriber barred from data stays registered: its PDU sessions are released
+// by the session reconciliation, which no longer resolves a policy for them.
+func (amf *AMF) ReconcileSubscriberState(ctx context.Context, ue *UeContext) bool {
+	if ue == nil {
+		return false
+	}
+
+	supi := ue.Supi()
+	if !supi.IsIMSI() {
+		return false
+	}
+
+	state, err := amf.DBInstance.SubscriberStateAt(ctx, supi.IMSI(), time.Now())
+	if err != nil {
+		logger.AmfLog.Warn("failed to get subscriber state; deferring to the next sweep",
+			logger.SUPI(supi.String()), zap.Error(err))
+
+		return false
+	}
+
+	if state.AllowsRegistration() {
+		return false
+	}
+
+	logger.AmfLog.Info("deregistering UE: subscriber may no longer register",
+		logger.SUPI(supi.String()), zap.String("state", string(state)))
+
+	amf.DeregisterSubscriber(ctx, supi)
+
+	return true
+}
@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package amf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/amf"
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestReconcileSubscriberState(t *testing.T) {
+	for _, tc := range []struct {
+		state      db.SubscriberState
+		deregister bool
+	}{
+		{db.SubscriberStateActive, false},
+		{db.SubscriberStateBarredData, false},
+		{db.SubscriberStateSuspended, true},
+		{db.SubscriberStateExpired, true},
+	} {
+		t.Run(string(tc.state), func(t *testing.T) {
+			amfInstance := amf.New(&configTestDB{state: tc.state}, nil, nil)
+
+			ue := addTestUE(t, amfInstance, "001010000000001", func(ue *amf.UeContext) {
+				ue.ForceStateForTest(amf.Registered)
+			})
+
+			if got := amfInstance.ReconcileSubscriberState(context.Background(), ue); got != tc.deregister {
+				t.Fatalf("deregistered = %v, want %v", got, tc.deregister)
+			}
+
+			if _, ok := amfInstance.LookupUeBySupi(ue.Supi()); ok == tc.deregister {
+				t.Fatalf("UE context kept = %v, want %v", ok, !tc.deregister)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package amf
+
+import (
+	"context"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ReconcileUeAmbr re-resolves a UE's UE-AMBR, which a subscriber AMBR override
+// changes while the UE stays registered. A connected UE's NG-RAN node is told
+// with a UE Context Modification (TS 23.502 §4.2.2.2.2); an idle UE carries the
+// new value into its next Initial Context Setup.
+func (amf *AMF) ReconcileUeAmbr(ctx context.Context, ue *UeContext) {
+	if ue == nil {
+		return
+	}
+
+	ul, dl, ok := ue.AmbrRates()
+	if !ok {
+		// Not yet set: registration resolves it.
+		return
+	}
+
+	supi := ue.Supi()
+
+	profile, err := amf.SubscriberProfile(ctx, supi)
+	if err != nil {
+		logger.AmfLog.Warn("couldn't resolve UE-AMBR, skipping reconciliation",
+			logger.SUPI(supi.String()), zap.Error(err))
+
+		return
+	}
+
+	if profile.Ambr.Uplink.Bps() == ul.Bps() && profile.Ambr.Downlink.Bps() == dl.Bps() {
+		return
+	}
+
+	ue.SetAmbr(profile.Ambr)
+
+	ueConn := ue.Conn()
+	if ueConn == nil || ueConn.ICS() != ICSCompleted {
+		return
+	}
+
+	if err := ueConn.SendUEContextModificationRequest(ctx, profile.Ambr.Uplink, profile.Ambr.Downlink); err != nil {
+		logger.AmfLog.Warn("couldn't send UE-AMBR to NG-RAN",
+			logger.SUPI(supi.String()), zap.Error(err))
+
+		return
+	}
+
+	logger.AmfLog.Info("UE-AMBR changed",
+		logger.SUPI(supi.String()),
+		zap.String("uplink", profile.Ambr.Uplink.String()),
+		zap.String("downlink", profile.Ambr.Downlink.String()))
+}
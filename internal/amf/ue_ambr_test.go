@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package amf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/amf"
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+	"go.uber.org/zap"
+)
+
+func ambrTestDB() *configTestDB {
+	return &configTestDB{
+		subscriber: &db.Subscriber{ID: "sub-1", Imsi: "001010000000050", ProfileID: "profile-10"},
+	}
+}
+
+// The profile's UE-AMBR, as configTestDB serves it.
+func profileAmbr() *models.Ambr {
+	return &models.Ambr{Uplink: models.MustParseBitRate("100 Mbps"), Downlink: models.MustParseBitRate("200 Mbps")}
+}
+
+// A connected UE whose UE-AMBR changed has its NG-RAN node told.
+func TestReconcileUeAmbr_ConnectedUE_SendsUEContextModification(t *testing.T) {
+	fakeDB := ambrTestDB()
+	fakeDB.override = &db.SubscriberAmbrOverride{IMSI: "001010000000050", UeAmbrUplink: "1 Gbps", UeAmbrDownlink: "2 Gbps"}
+
+	sender := &fakeNGAPSender{}
+	amfInstance := amf.New(fakeDB, nil, &fakeSmf{})
+
+	ue := addUE(t, amfInstance, "001010000000050", func(u *amf.UeContext) {
+		u.ForceStateForTest(amf.Registered)
+	})
+	ue.SetAmbr(profileAmbr())
+
+	radio := &amf.Radio{Conn: sender}
+	radio.BindAMFForTest(amfInstance)
+	ueConn := amf.NewUeConnForTest(radio, 1, 1, zap.NewNop())
+	ueConn.AMFForTest().AttachUeConn(ue, ueConn)
+	ueConn.MarkICSCompleted()
+
+	amfInstance.ReconcileUeAmbr(context.Background(), ue)
+
+	if sender.ueContextModificationCalls != 1 {
+		t.Fatalf("UEContextModification calls = %d, want 1", sender.ueContextModificationCalls)
+	}
+
+	ul, dl, _ := ue.AmbrRates()
+	if ul.String() != "1 Gbps" || dl.String() != "2 Gbps" {
+		t.Fatalf("UE-AMBR = %s/%s, want the override's", ul, dl)
+	}
+
+	// Nothing changed since: nothing is sent.
+	amfInstance.ReconcileUeAmbr(context.Background(), ue)
+
+	if sender.ueContextModificationCalls != 1 {
+		t.Fatalf("UEContextModification calls = %d, want still 1", sender.ueContextModificationCalls)
+	}
+}
+
+// An idle UE keeps the new value for its next Initial Context Setup.
+func TestReconcileUeAmbr_IdleUE_StoresOnly(t *testing.T) {
+	fakeDB := ambrTestDB()
+	fakeDB.override = &db.SubscriberAmbrOverride{IMSI: "001010000000051", UeAmbrUplink: "1 Mbps", UeAmbrDownlink: "1 Mbps"}
+
+	amfInstance := amf.New(fakeDB, nil, &fakeSmf{})
+
+	ue := addUE(t, amfInstance, "001010000000051", func(u *amf.UeContext) {
+		u.ForceStateForTest(amf.Registered)
+	})
+	ue.SetAmbr(profileAmbr())
+
+	if conn := ue.Conn(); conn != nil {
+		conn.Release()
+	}
+
+	amfInstance.ReconcileUeAmbr(context.Background(), ue)
+
+	ul, dl, _ := ue.AmbrRates()
+	if ul.String() != "1 Mbps" || dl.String() != "1 Mbps" {
+		t.Fatalf("UE-AMBR = %s/%s, want the override's", ul, dl)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net"
 	"net/http"
 	"net/netip"
+	"slices"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -23,10 +24,12 @@ import (
 	"github.com/ellanetworks/core/internal/cluster/listener"
 	"github.com/ellanetworks/core/internal/config"
 	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/dns"
 	"github.com/ellanetworks/core/internal/kernel"
 	"github.com/ellanetworks/core/internal/lmf"
 	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/internal/netutil"
 	"github.com/ellanetworks/core/internal/smf"
 	"go.uber.org/zap"
@@ -99,6 +102,7 @@ type UpgradeConfig struct {
 	RegisterExtraRoutes func(*http.ServeMux)
 	ClusterListener     *listener.Listener
 	DatapathAttachMode  func() string
+	DatapathFeatures    func() models.DatapathFeatures
 }
 
 // StartDiscovery creates and starts the HTTP server with only the routes
@@ -243,6 +247,7 @@ func (s *Server) Upgrade(ctx context.Context, opts UpgradeConfig) error {
 		LMF:                opts.LMF,
 		BcryptCost:         bcrypt.DefaultCost,
 		DatapathAttachMode: opts.DatapathAttachMode,
+		DatapathFeatures:   opts.DatapathFeatures,
 		Ready:              &s.ready,
 		ReconcileRoutes: func(rcCtx context.Context) error {
 			return routeReconciler(rcCtx, opts.DB, kernelInt)
@@ -318,9 +323,156 @@ func (s *Server) Upgrade(ctx context.Context, opts UpgradeConfig) error {
 		}()
 	}
 
+	dnsWakeup, stopDNSWakeup := opts.DB.Changefeed().Wakeup(
+		db.TopicDNSResolvers,
+		db.TopicDataNetworks,
+		db.TopicIPLeases,
+	)
+
+	dnsService := dns.NewService(&dnsStoreAdapter{db: opts.DB}, dnsWakeup)
+	dnsService.Start()
+
+	go func() {
+		<-ctx.Done()
+		dnsService.Stop()
+		stopDNSWakeup()
+	}()
+
 	return nil
 }
 
+// dnsStoreAdapter adapts *db.Database to dns.Store. Local records naming a
+// subscriber resolve to the IPv4 addresses the subscriber holds on the
+// record's data network.
+type dnsStoreAdapter struct {
+	db *db.Database
+}
+
+func (a *dnsStoreAdapter) ListResolvers(ctx context.Context) ([]dns.Resolver, error) {
+	rows, err := a.db.ListAllDataNetworkDNSResolvers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	records, err := a.db.ListAllDNSLocalRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	leases, err := a.db.ListActiveLeases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]dns.Resolver, 0, len(rows))
+
+	for _, row := range rows {
+		dn, err := a.db.GetDataNetworkByID(ctx, row.DataNetworkID)
+		if err != nil {
+			return nil, fmt.Errorf("data network %s: %w", row.DataNetworkID, err)
+		}
+
+		listen, err := netip.ParseAddr(row.ListenAddress)
+		if err != nil {
+			logger.DNSLog.Warn("skipping DNS resolver with invalid listen address",
+				zap.String("data_network", dn.Name), zap.String("listen", row.ListenAddress))
+
+			continue
+		}
+
+		r := dns.Resolver{
+			DataNetwork: dn.Name,
+			Listen:      listen,
+			LocalZones:  row.LocalZoneList(),
+			LogQueries:  row.LogQueries,
+		}
+
+		for _, pool := range []string{dn.IPv4Pool, dn.IPv6Pool} {
+			if prefix, err := netip.ParsePrefix(pool); err == nil {
+				r.Pools = append(r.Pools, prefix.Masked())
+			}
+		}
+
+		for _, u := range row.UpstreamList() {
+			if addr, err := netip.ParseAddrPort(u); err == nil {
+				r.Upstreams = append(r.Upstreams, addr)
+			} else if addr, err := netip.ParseAddr(u); err == nil {
+				r.Upstreams = append(r.Upstreams, netip.AddrPortFrom(addr, dns.Port))
+			}
+		}
+
+		addrsByIMSI, err := a.subscriberAddresses(ctx, dn.ID, leases)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rec := range records {
+			if rec.DataNetworkID != dn.ID {
+				continue
+			}
+
+			record := dns.Record{Name: rec.Name}
+
+			if rec.IMSI != "" {
+				record.Addresses = addrsByIMSI[rec.IMSI]
+			} else if addr, err := netip.ParseAddr(rec.Address); err == nil {
+				record.Addresses = []netip.Addr{addr}
+			}
+
+			r.Records = append(r.Records, record)
+		}
+
+		resolvers = append(resolvers, r)
+	}
+
+	return resolvers, nil
+}
+
+// subscriberAddresses maps IMSIs to the IPv4 addresses they hold on the
+// data network: their static addresses and the ones of live sessions.
+// IPv6 leases are delegated prefixes, which name no single host.
+func (a *dnsStoreAdapter) subscriberAddresses(ctx context.Context, dataNetworkID string, active []db.IPLease) (map[string][]netip.Addr, error) {
+	statics, err := a.db.ListStaticLeasesByDataNetwork(ctx, dataNetworkID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]netip.Addr)
+
+	for _, l := range append(statics, active...) {
+		if l.PoolID != dataNetworkID {
+			continue
+		}
+
+		addr := l.Address()
+		if !addr.Is4() || slices.Contains(out[l.IMSI], addr) {
+			continue
+		}
+
+		out[l.IMSI] = append(out[l.IMSI], addr)
+	}
+
+	return out, nil
+}
+
+func (a *dnsStoreAdapter) ListUEs(ctx context.Context) ([]dns.UE, error) {
+	leases, err := a.db.ListActiveLeases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ues := make([]dns.UE, 0, len(leases))
+	for _, l := range leases {
+		ues = append(ues, dns.UE{IMSI: l.IMSI, Address: l.Address()})
+	}
+
+	return ues, nil
+}
+
 // bgpSettingsStoreAdapter adapts *db.Database to bgp.SettingsStore,
 // converting from the DB row types into the BGP service's own types
 // so the bgp package does not depend on db.
@@ -447,7 +599,14 @@ func ReconcileKernelRouting(ctx context.Context, dbInstance *db.Database, kernel
 
 	desired := make(map[routeKey]struct{}, len(expectedRoutes))
 
+	var scopedRoutes []db.Route
+
 	for _, route := range expectedRoutes {
+		if route.DataNetworkID != "" {
+			scopedRoutes = append(scopedRoutes, route)
+			continue
+		}
+
 		destPrefix, err := netip.ParsePrefix(route.Destination)
 		if err != nil {
 			return fmt.Errorf("couldn't parse destination: %v", err)
@@ -522,6 +681,10 @@ func ReconcileKernelRouting(ctx context.Context, dbInstance *db.Database, kernel
 		}
 	}
 
+	if err := reconcileDataNetworkTables(ctx, dbInstance, kernelInt, scopedRoutes); err != nil {
+		return err
+	}
+
 	for _, netIf := range interfaceDBKernelMap {
 		err := kernelInt.EnsureGatewaysOnInterfaceInNeighTable(netIf)
 		if err != nil {
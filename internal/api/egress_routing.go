@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/kernel"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// dataNetworkTable is the kernel view of one data network with its own
+// routing table: the table id, the device its routes leave through and the
+// UE pools steered into it.
+type dataNetworkTable struct {
+	name   string
+	table  int
+	device string
+	pools  []netip.Prefix
+}
+
+func listDataNetworkTables(ctx context.Context, dbInstance *db.Database, kernelInt kernel.Kernel) (map[string]dataNetworkTable, error) {
+	egress, err := dbInstance.ListAllDataNetworkEgress(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list data network egress: %v", err)
+	}
+
+	if len(egress) == 0 {
+		return map[string]dataNetworkTable{}, nil
+	}
+
+	dataNetworks, err := dbInstance.ListAllDataNetworks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list data networks: %v", err)
+	}
+
+	byID := make(map[string]db.DataNetwork, len(dataNetworks))
+	for _, dn := range dataNetworks {
+		byID[dn.ID] = dn
+	}
+
+	tables := make(map[string]dataNetworkTable, len(egress))
+
+	for _, e := range egress {
+		if e.RoutingTable == 0 {
+			continue
+		}
+
+		dn, ok := byID[e.DataNetworkID]
+		if !ok {
+			continue
+		}
+
+		device, err := kernelInt.ResolveEgressDevice(e.InterfaceName, e.VlanID)
+		if err != nil {
+			logger.APILog.Warn("couldn't resolve data network egress device, skipping its routing table",
+				zap.String("data_network", dn.Name),
+				zap.String("interface", e.InterfaceName),
+				zap.Int("vlan_id", e.VlanID),
+				zap.Error(err))
+
+			continue
+		}
+
+		t := dataNetworkTable{name: dn.Name, table: e.RoutingTable, device: device}
+
+		for _, pool := range []string{dn.IPv4Pool, dn.IPv6Pool} {
+			if pool == "" {
+				continue
+			}
+
+			if prefix, err := netip.ParsePrefix(pool); err == nil {
+				t.pools = append(t.pools, prefix.Masked())
+			}
+		}
+
+		tables[dn.ID] = t
+	}
+
+	return tables, nil
+}
+
+// reconcileDataNetworkTables drives the per-data-network routing tables:
+// routes scoped to a data network land in its table via its egress device,
+// and a "from <pool> lookup <table>" rule steers the data network's UEs
+// into it. Ella-owned table routes and rules without a DB counterpart are
+// removed.
+func reconcileDataNetworkTables(ctx context.Context, dbInstance *db.Database, kernelInt kernel.Kernel, scopedRoutes []db.Route) error {
+	tables, err := listDataNetworkTables(ctx, dbInstance, kernelInt)
+	if err != nil {
+		return err
+	}
+
+	type tableRouteKey struct {
+		destination string
+		gateway     string
+		priority    int
+		device      string
+		table       int
+	}
+
+	keyOf := func(r kernel.TableRoute) tableRouteKey {
+		return tableRouteKey{
+			destination: kernel.UnmapPrefix(r.Destination).String(),
+			gateway:     r.Gateway.Unmap().String(),
+			priority:    r.Priority,
+			device:      r.Device,
+			table:       r.Table,
+		}
+	}
+
+	managedRoutes, err := kernelInt.ListManagedTableRoutes()
+	if err != nil {
+		return fmt.Errorf("couldn't list managed table routes: %v", err)
+	}
+
+	present := make(map[tableRouteKey]struct{}, len(managedRoutes))
+	for _, r := range managedRoutes {
+		present[keyOf(r)] = struct{}{}
+	}
+
+	desired := make(map[tableRouteKey]struct{}, len(scopedRoutes))
+
+	for _, route := range scopedRoutes {
+		t, ok := tables[route.DataNetworkID]
+		if !ok {
+			logger.APILog.Warn("route is scoped to a data network without a routing table, skipping",
+				zap.Int64("route_id", route.ID),
+				zap.String("data_network_id", route.DataNetworkID))
+
+			continue
+		}
+
+		destPrefix, err := netip.ParsePrefix(route.Destination)
+		if err != nil {
+			return fmt.Errorf("couldn't parse destination: %v", err)
+		}
+
+		gwAddr, err := netip.ParseAddr(route.Gateway)
+		if err != nil {
+			return fmt.Errorf("invalid gateway: %v", route.Gateway)
+		}
+
+		tr := kernel.TableRoute{
+			Destination: kernel.UnmapPrefix(destPrefix),
+			Gateway:     gwAddr.Unmap(),
+			Priority:    route.Metric,
+			Device:      t.device,
+			Table:       t.table,
+		}
+
+		key := keyOf(tr)
+		desired[key] = struct{}{}
+
+		if _, ok := present[key]; ok {
+			continue
+		}
+
+		if err := kernelInt.ReplaceTableRoute(tr); err != nil {
+			return fmt.Errorf("couldn't create route in table %d for data network %s: %v", t.table, t.name, err)
+		}
+	}
+
+	for _, r := range managedRoutes {
+		if _, ok := desired[keyOf(r)]; ok {
+			continue
+		}
+
+		if err := kernelInt.DeleteTableRoute(r); err != nil {
+			logger.APILog.Warn("couldn't delete stale table route",
+				zap.String("destination", r.Destination.String()),
+				zap.Int("table", r.Table),
+				zap.Error(err))
+		}
+	}
+
+	managedRules, err := kernelInt.ListManagedSourceRules()
+	if err != nil {
+		return fmt.Errorf("couldn't list managed source rules: %v", err)
+	}
+
+	presentRules := make(map[kernel.SourceRule]struct{}, len(managedRules))
+	for _, r := range managedRules {
+		presentRules[r] = struct{}{}
+	}
+
+	desiredRules := make(map[kernel.SourceRule]struct{})
+
+	for _, t := range tables {
+		for _, pool := range t.pools {
+			rule := kernel.SourceRule{Source: pool, Table: t.table}
+			desiredRules[rule] = struct{}{}
+
+			if _, ok := presentRules[rule]; ok {
+				continue
+			}
+
+			if err := kernelInt.AddSourceRule(rule); err != nil {
+				return fmt.Errorf("couldn't add source rule for data network %s: %v", t.name, err)
+			}
+		}
+	}
+
+	for _, r := range managedRules {
+		if _, ok := desiredRules[r]; ok {
+			continue
+		}
+
+		if err := kernelInt.DeleteSourceRule(r); err != nil {
+			logger.APILog.Warn("couldn't delete stale source rule",
+				zap.String("source", r.Source.String()),
+				zap.Int("table", r.Table),
+				zap.Error(err))
+		}
+	}
+
+	return nil
+}
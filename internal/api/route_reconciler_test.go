@@ -5,8 +5,10 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"net/netip"
 	"path/filepath"
+	"slices"
 	"sync"
 	"testing"
 
@@ -31,6 +33,10 @@ type recordingKernel struct {
 	forwardingOn   bool
 	enableCalled   bool
 	existsCheckErr error
+	tableRoutes    []kernel.TableRoute
+	rules          []kernel.SourceRule
+	tableDeleted   []kernel.TableRoute
+	rulesDeleted   []kernel.SourceRule
 }
 
 type routeOp struct {
@@ -142,6 +148,70 @@ func (k *recordingKernel) EnsureGatewaysOnInterfaceInNeighTable(_ kernel.Network
 	return nil
 }
 
+func (k *recordingKernel) ResolveEgressDevice(name string, vlanID int) (string, error) {
+	if name == "" {
+		name = "n6"
+	}
+
+	if vlanID != 0 {
+		return fmt.Sprintf("%s.%d", name, vlanID), nil
+	}
+
+	return name, nil
+}
+
+func (k *recordingKernel) ReplaceTableRoute(route kernel.TableRoute) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.tableRoutes = append(k.tableRoutes, route)
+
+	return nil
+}
+
+func (k *recordingKernel) DeleteTableRoute(route kernel.TableRoute) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.tableDeleted = append(k.tableDeleted, route)
+	k.tableRoutes = slices.DeleteFunc(k.tableRoutes, func(r kernel.TableRoute) bool { return r == route })
+
+	return nil
+}
+
+func (k *recordingKernel) ListManagedTableRoutes() ([]kernel.TableRoute, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return slices.Clone(k.tableRoutes), nil
+}
+
+func (k *recordingKernel) AddSourceRule(rule kernel.SourceRule) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.rules = append(k.rules, rule)
+
+	return nil
+}
+
+func (k *recordingKernel) DeleteSourceRule(rule kernel.SourceRule) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.rulesDeleted = append(k.rulesDeleted, rule)
+	k.rules = slices.DeleteFunc(k.rules, func(r kernel.SourceRule) bool { return r == rule })
+
+	return nil
+}
+
+func (k *recordingKernel) ListManagedSourceRules() ([]kernel.SourceRule, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return slices.Clone(k.rules), nil
+}
+
 func newReconcileTestDB(t *testing.T) *db.Database {
 	t.Helper()
 
@@ -347,3 +417,65 @@ func TestReconcileKernelRouting_V4MappedDBDestination(t *testing.T) {
 		t.Errorf("expected no CreateRoute, got %d (%v)", got, k.created)
 	}
 }
+
+func TestReconcileKernelRouting_DataNetworkTable(t *testing.T) {
+	ctx := context.Background()
+	dbInstance := newReconcileTestDB(t)
+	k := newRecordingKernel()
+
+	dn := &db.DataNetwork{Name: "enterprise", IPv4Pool: "10.46.0.0/16", DNS: "8.8.8.8", MTU: 1400}
+	if err := dbInstance.CreateDataNetworkWithEgress(ctx, dn, &db.DataNetworkEgress{InterfaceName: "eth2", VlanID: 100, RoutingTable: 100}); err != nil {
+		t.Fatalf("seed data network: %v", err)
+	}
+
+	if _, err := dbInstance.CreateRoute(ctx, &db.Route{
+		Destination:   "0.0.0.0/0",
+		Gateway:       "192.168.100.1",
+		Interface:     db.N6,
+		Metric:        10,
+		DataNetworkID: dn.ID,
+	}); err != nil {
+		t.Fatalf("seed route: %v", err)
+	}
+
+	staleRule := kernel.SourceRule{Source: netip.MustParsePrefix("10.99.0.0/16"), Table: 99}
+	k.rules = append(k.rules, staleRule)
+
+	if err := ReconcileKernelRouting(ctx, dbInstance, k); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if got := len(k.created); got != 0 {
+		t.Fatalf("a data network route must stay out of the main table, got %v", k.created)
+	}
+
+	want := kernel.TableRoute{
+		Destination: netip.MustParsePrefix("0.0.0.0/0"),
+		Gateway:     netip.MustParseAddr("192.168.100.1"),
+		Priority:    10,
+		Device:      "eth2.100",
+		Table:       100,
+	}
+
+	if len(k.tableRoutes) != 1 || k.tableRoutes[0] != want {
+		t.Fatalf("expected %+v in table 100, got %+v", want, k.tableRoutes)
+	}
+
+	wantRule := kernel.SourceRule{Source: netip.MustParsePrefix("10.46.0.0/16"), Table: 100}
+	if len(k.rules) != 1 || k.rules[0] != wantRule {
+		t.Fatalf("expected rule %+v, got %+v", wantRule, k.rules)
+	}
+
+	if len(k.rulesDeleted) != 1 || k.rulesDeleted[0] != staleRule {
+		t.Fatalf("expected stale rule to be removed, got %+v", k.rulesDeleted)
+	}
+
+	// A second pass is a no-op.
+	if err := ReconcileKernelRouting(ctx, dbInstance, k); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(k.tableRoutes) != 1 || len(k.tableDeleted) != 0 {
+		t.Fatalf("expected a stable table, got routes=%+v deleted=%+v", k.tableRoutes, k.tableDeleted)
+	}
+}
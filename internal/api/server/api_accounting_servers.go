@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const (
+	// MaxNumAccountingServers bounds the servers every session is reported
+	// to.
+	MaxNumAccountingServers = 8
+	// MinInterimInterval and MaxInterimInterval bound the Interim-Update
+	// interval in seconds; RFC 2869 §2.1 asks for at least a minute.
+	MinInterimInterval = 60
+	MaxInterimInterval = 86400
+)
+
+// CreateAccountingServerParams configures a RADIUS accounting server.
+// Secret is write-only: it is never returned.
+type CreateAccountingServerParams struct {
+	Name            string `json:"name"`
+	Address         string `json:"address"`
+	Secret          string `json:"secret"`
+	InterimInterval int    `json:"interim_interval"`
+}
+
+// UpdateAccountingServerParams changes a server. Omitting Secret keeps the
+// current one.
+type UpdateAccountingServerParams struct {
+	Address         string `json:"address"`
+	Secret          string `json:"secret,omitempty"`
+	InterimInterval int    `json:"interim_interval"`
+}
+
+type AccountingServerResponse struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	// InterimInterval is in seconds; 0 sends no Interim-Updates.
+	InterimInterval int `json:"interim_interval"`
+}
+
+type ListAccountingServersResponse struct {
+	Items []AccountingServerResponse `json:"items"`
+}
+
+const (
+	CreateAccountingServerAction = "create_accounting_server"
+	UpdateAccountingServerAction = "update_accounting_server"
+	DeleteAccountingServerAction = "delete_accounting_server"
+)
+
+// validateAccountingServer checks the address and interim interval,
+// returning the address to store and the error as the response message.
+func validateAccountingServer(address string, interimInterval int) (string, error) {
+	addr, normalized, err := parseServerAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid address: %w", err)
+	}
+
+	if !addr.IsGlobalUnicast() && !addr.IsLoopback() {
+		return "", errors.New("invalid address, must be a unicast address")
+	}
+
+	if interimInterval != 0 && (interimInterval < MinInterimInterval || interimInterval > MaxInterimInterval) {
+		return "", fmt.Errorf("interim_interval must be 0 or between %d and %d seconds", MinInterimInterval, MaxInterimInterval)
+	}
+
+	return normalized, nil
+}
+
+func toAccountingServerResponse(s *db.AccountingServer) AccountingServerResponse {
+	return AccountingServerResponse{
+		Name:            s.Name,
+		Address:         s.Address,
+		InterimInterval: s.InterimInterval,
+	}
+}
+
+func ListAccountingServers(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servers, err := dbInstance.ListAccountingServers(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list accounting servers", err, logger.APILog)
+			return
+		}
+
+		items := make([]AccountingServerResponse, 0, len(servers))
+		for i := range servers {
+			items = append(items, toAccountingServerResponse(&servers[i]))
+		}
+
+		writeResponse(r.Context(), w, ListAccountingServersResponse{Items: items}, http.StatusOK, logger.APILog)
+	})
+}
+
+func GetAccountingServer(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		server, err := dbInstance.GetAccountingServer(r.Context(), name)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Accounting server not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve accounting server", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, toAccountingServerResponse(server), http.StatusOK, logger.APILog)
+	})
+}
+
+func CreateAccountingServer(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		var params CreateAccountingServerParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if params.Name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "name is missing", nil, logger.APILog)
+			return
+		}
+
+		if !isResourceNameValid(params.Name) {
+			writeError(r.Context(), w, http.StatusBadRequest, "invalid name format - must be less than 256 characters", nil, logger.APILog)
+			return
+		}
+
+		address, err := validateAccountingServer(params.Address, params.InterimInterval)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		if params.Secret == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "secret is missing", nil, logger.APILog)
+			return
+		}
+
+		servers, err := dbInstance.ListAccountingServers(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create accounting server", err, logger.APILog)
+			return
+		}
+
+		if len(servers) >= MaxNumAccountingServers {
+			writeError(r.Context(), w, http.StatusBadRequest, fmt.Sprintf("Maximum number of accounting servers reached (%d)", MaxNumAccountingServers), nil, logger.APILog)
+			return
+		}
+
+		server := &db.AccountingServer{
+			Name:            params.Name,
+			Address:         address,
+			Secret:          params.Secret,
+			InterimInterval: params.InterimInterval,
+		}
+
+		if err := dbInstance.CreateAccountingServer(r.Context(), server); err != nil {
+			if errors.Is(err, db.ErrAlreadyExists) {
+				writeError(r.Context(), w, http.StatusConflict, "Accounting server already exists", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create accounting server", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Accounting server created successfully"}, http.StatusCreated, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), CreateAccountingServerAction, email, getClientIP(r), "User created accounting server: "+params.Name)
+	})
+}
+
+func UpdateAccountingServer(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params UpdateAccountingServerParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		address, err := validateAccountingServer(params.Address, params.InterimInterval)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		current, err := dbInstance.GetAccountingServer(r.Context(), name)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Accounting server not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update accounting server", err, logger.APILog)
+
+			return
+		}
+
+		secret := params.Secret
+		if secret == "" {
+			secret = current.Secret
+		}
+
+		server := &db.AccountingServer{
+			Name:            name,
+			Address:         address,
+			Secret:          secret,
+			InterimInterval: params.InterimInterval,
+		}
+
+		if err := dbInstance.UpdateAccountingServer(r.Context(), server); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Accounting server not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update accounting server", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Accounting server updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateAccountingServerAction, email, getClientIP(r), "User updated accounting server: "+name)
+	})
+}
+
+func DeleteAccountingServer(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeleteAccountingServer(r.Context(), name); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Accounting server not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete accounting server", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Accounting server deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteAccountingServerAction, email, getClientIP(r), "User deleted accounting server: "+name)
+	})
+}
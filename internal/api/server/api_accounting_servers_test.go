@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type AccountingServerParams struct {
+	Name            string `json:"name,omitempty"`
+	Address         string `json:"address"`
+	Secret          string `json:"secret,omitempty"`
+	InterimInterval int    `json:"interim_interval"`
+}
+
+type AccountingServer struct {
+	Name            string `json:"name"`
+	Address         string `json:"address"`
+	Secret          string `json:"secret,omitempty"`
+	InterimInterval int    `json:"interim_interval"`
+}
+
+type GetAccountingServerResponse struct {
+	Result AccountingServer `json:"result"`
+	Error  string           `json:"error,omitempty"`
+}
+
+type ListAccountingServersResponse struct {
+	Result struct {
+		Items []AccountingServer `json:"items"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+type AccountingServerMessageResponse struct {
+	Result struct {
+		Message string `json:"message"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func doAccountingServerRequest(url string, client *http.Client, token, method, path string, data any, out any) (int, error) {
+	body := strings.NewReader("")
+
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return 0, err
+		}
+
+		body = strings.NewReader(string(b))
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url+"/api/v1/networking/accounting-servers"+path, body)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return 0, err
+	}
+
+	return res.StatusCode, nil
+}
+
+func TestAccountingServersCRUD(t *testing.T) {
+	env, err := setupServer(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	invalid := []struct {
+		name   string
+		params AccountingServerParams
+	}{
+		{"missing name", AccountingServerParams{Address: "192.0.2.10", Secret: "s"}},
+		{"missing secret", AccountingServerParams{Name: "aaa", Address: "192.0.2.10"}},
+		{"hostname", AccountingServerParams{Name: "aaa", Address: "aaa.example.com", Secret: "s"}},
+		{"multicast", AccountingServerParams{Name: "aaa", Address: "224.0.0.1", Secret: "s"}},
+		{"interim too short", AccountingServerParams{Name: "aaa", Address: "192.0.2.10", Secret: "s", InterimInterval: 30}},
+	}
+
+	for _, tc := range invalid {
+		var resp AccountingServerMessageResponse
+
+		status, err := doAccountingServerRequest(env.Server.URL, client, token, http.MethodPost, "", &tc.params, &resp)
+		if err != nil {
+			t.Fatalf("%s: couldn't create accounting server: %s", tc.name, err)
+		}
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, http.StatusBadRequest, status)
+		}
+	}
+
+	create := &AccountingServerParams{Name: "aaa", Address: "192.0.2.10", Secret: "testing123", InterimInterval: 300}
+
+	var createResp AccountingServerMessageResponse
+
+	status, err := doAccountingServerRequest(env.Server.URL, client, token, http.MethodPost, "", create, &createResp)
+	if err != nil {
+		t.Fatalf("couldn't create accounting server: %s", err)
+	}
+
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, status, createResp.Error)
+	}
+
+	status, err = doAccountingServerRequest(env.Server.URL, client, token, http.MethodPost, "", create, &createResp)
+	if err != nil {
+		t.Fatalf("couldn't create accounting server: %s", err)
+	}
+
+	if status != http.StatusConflict {
+		t.Fatalf("expected status %d for a duplicate, got %d", http.StatusConflict, status)
+	}
+
+	var getResp GetAccountingServerResponse
+
+	status, err = doAccountingServerRequest(env.Server.URL, client, token, http.MethodGet, "/aaa", nil, &getResp)
+	if err != nil {
+		t.Fatalf("couldn't get accounting server: %s", err)
+	}
+
+	want := AccountingServer{Name: "aaa", Address: "192.0.2.10", InterimInterval: 300}
+	if status != http.StatusOK || getResp.Result != want {
+		t.Fatalf("got %+v (status %d), want %+v without the secret", getResp.Result, status, want)
+	}
+
+	var updateResp AccountingServerMessageResponse
+
+	status, err = doAccountingServerRequest(env.Server.URL, client, token, http.MethodPut, "/aaa", &AccountingServerParams{Address: "192.0.2.11:1646"}, &updateResp)
+	if err != nil {
+		t.Fatalf("couldn't update accounting server: %s", err)
+	}
+
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusOK, status, updateResp.Error)
+	}
+
+	stored, err := env.DB.GetAccountingServer(context.Background(), "aaa")
+	if err != nil {
+		t.Fatalf("couldn't read accounting server: %s", err)
+	}
+
+	if stored.Address != "192.0.2.11:1646" || stored.Secret != "testing123" || stored.InterimInterval != 0 {
+		t.Fatalf("stored %+v, want the new address and no interims with the secret kept", stored)
+	}
+
+	var listResp ListAccountingServersResponse
+
+	status, err = doAccountingServerRequest(env.Server.URL, client, token, http.MethodGet, "", nil, &listResp)
+	if err != nil {
+		t.Fatalf("couldn't list accounting servers: %s", err)
+	}
+
+	if status != http.StatusOK || len(listResp.Result.Items) != 1 {
+		t.Fatalf("expected one accounting server, got %+v (status %d)", listResp.Result.Items, status)
+	}
+
+	var deleteResp AccountingServerMessageResponse
+
+	status, err = doAccountingServerRequest(env.Server.URL, client, token, http.MethodDelete, "/aaa", nil, &deleteResp)
+	if err != nil {
+		t.Fatalf("couldn't delete accounting server: %s", err)
+	}
+
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusOK, status, deleteResp.Error)
+	}
+
+	status, err = doAccountingServerRequest(env.Server.URL, client, token, http.MethodGet, "/aaa", nil, &getResp)
+	if err != nil {
+		t.Fatalf("couldn't get accounting server: %s", err)
+	}
+
+	if status != http.StatusNotFound {
+		t.Fatalf("expected status %d after delete, got %d", http.StatusNotFound, status)
+	}
+}
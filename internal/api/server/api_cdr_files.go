@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ellanetworks/core/internal/cdr"
+	"github.com/ellanetworks/core/internal/config"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+// CDRFileResponse describes a closed CDR file. Format is "csv" or "ber".
+type CDRFileResponse struct {
+	Name       string `json:"name"`
+	Format     string `json:"format"`
+	Size       int64  `json:"size"`
+	ModifiedAt string `json:"modified_at"`
+}
+
+type ListCDRFilesResponse struct {
+	Items []CDRFileResponse `json:"items"`
+}
+
+const DownloadCDRFileAction = "download_cdr_file"
+
+// ListCDRFiles lists the closed CDR files of the node serving the request.
+// Files still being written are not listed.
+func ListCDRFiles(appCfg config.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		files, err := cdr.ListFiles(cdr.Dir(appCfg.DB.Path))
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list CDR files", err, logger.APILog)
+			return
+		}
+
+		items := make([]CDRFileResponse, 0, len(files))
+		for _, f := range files {
+			items = append(items, CDRFileResponse{
+				Name:       f.Name,
+				Format:     strings.TrimPrefix(filepath.Ext(f.Name), "."),
+				Size:       f.Size,
+				ModifiedAt: f.ModTime.UTC().Format(time.RFC3339),
+			})
+		}
+
+		writeResponse(r.Context(), w, ListCDRFilesResponse{Items: items}, http.StatusOK, logger.APILog)
+	})
+}
+
+// DownloadCDRFile serves a closed CDR file of the node serving the request.
+func DownloadCDRFile(appCfg config.Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		file, err := cdr.OpenFile(cdr.Dir(appCfg.DB.Path), name)
+		if err != nil {
+			if errors.Is(err, cdr.ErrInvalidName) {
+				writeError(r.Context(), w, http.StatusBadRequest, "Invalid CDR file name", nil, logger.APILog)
+				return
+			}
+
+			if errors.Is(err, os.ErrNotExist) {
+				writeError(r.Context(), w, http.StatusNotFound, "CDR file not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to open CDR file", err, logger.APILog)
+
+			return
+		}
+
+		defer func() {
+			_ = file.Close()
+		}()
+
+		info, err := file.Stat()
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to open CDR file", err, logger.APILog)
+			return
+		}
+
+		contentType := "application/octet-stream"
+		if filepath.Ext(name) == cdr.ExtCSV {
+			contentType = "text/csv"
+		}
+
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+name+"\"")
+		w.Header().Set("Content-Type", contentType)
+		http.ServeContent(w, r, "", info.ModTime(), file)
+
+		logger.LogAuditEvent(r.Context(), DownloadCDRFileAction, email, getClientIP(r), "User downloaded CDR file: "+name)
+	})
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/cdr"
+)
+
+type CDRFile struct {
+	Name       string `json:"name"`
+	Format     string `json:"format"`
+	Size       int64  `json:"size"`
+	ModifiedAt string `json:"modified_at"`
+}
+
+type ListCDRFilesResponse struct {
+	Result struct {
+		Items []CDRFile `json:"items"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func getCDRFile(url string, client *http.Client, token, name string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/api/v1/charging/cdr-files/"+name, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return res.StatusCode, body, nil
+}
+
+func TestCDRFiles(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	dir := cdr.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"ella-core-1_20260304T050000Z_000000.csv":      "local_sequence_number\n1\n",
+		"ella-core-1_20260304T050000Z_000000.ber":      "\xbf\x4f\x00",
+		"ella-core-1_20260304T051500Z_000001.csv.open": "local_sequence_number\n",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, env.Server.URL+"/api/v1/charging/cdr-files", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("couldn't list CDR files: %s", err)
+	}
+
+	var listResp ListCDRFilesResponse
+
+	err = json.NewDecoder(res.Body).Decode(&listResp)
+	_ = res.Body.Close()
+
+	if err != nil {
+		t.Fatalf("couldn't decode CDR files: %s", err)
+	}
+
+	if res.StatusCode != http.StatusOK || len(listResp.Result.Items) != 2 {
+		t.Fatalf("got %+v (status %d), want the two closed files", listResp.Result.Items, res.StatusCode)
+	}
+
+	if got := listResp.Result.Items[0]; got.Name != "ella-core-1_20260304T050000Z_000000.ber" || got.Format != "ber" || got.Size != 3 {
+		t.Fatalf("first file = %+v", got)
+	}
+
+	status, body, err := getCDRFile(env.Server.URL, client, token, "ella-core-1_20260304T050000Z_000000.csv")
+	if err != nil {
+		t.Fatalf("couldn't download CDR file: %s", err)
+	}
+
+	if status != http.StatusOK || string(body) != files["ella-core-1_20260304T050000Z_000000.csv"] {
+		t.Fatalf("download = %q (status %d)", body, status)
+	}
+
+	cases := []struct {
+		name   string
+		status int
+	}{
+		{"ella-core-1_20260304T051500Z_000001.csv.open", http.StatusBadRequest},
+		{"..%2Fdb.sqlite3", http.StatusBadRequest},
+		{"ella-core-1_20260304T051500Z_000009.csv", http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		status, _, err := getCDRFile(env.Server.URL, client, token, tc.name)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+
+		if status != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, status, tc.status)
+		}
+	}
+}
@@ -262,6 +262,13 @@ func RemoveClusterMember(dbInstance *db.Database) http.Handler {
 				zap.Int("nodeId", nodeID), zap.Error(err))
 		}
 
+		// The removed node's registrations and sessions no longer
+		// count against the admission quotas.
+		if err := dbInstance.DeleteQuotaAdmissionsByNode(r.Context(), nodeID); err != nil {
+			logger.APILog.Warn("Failed to purge admission records for removed cluster member; they count against quotas until manually cleaned",
+				zap.Int("nodeId", nodeID), zap.Error(err))
+		}
+
 		// Drop the removed node's pin from cluster_node_certs. The
 		// row deletion replicates through Raft; once committed,
 		// peer listeners refuse the removed node's handshakes. We
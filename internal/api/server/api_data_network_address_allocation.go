@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const UpdateDataNetworkAddressAllocationAction = "update_data_network_address_allocation"
+
+// AddressAllocationPool is the mode of a data network that allocates from
+// its own pool.
+const AddressAllocationPool = "pool"
+
+// DataNetworkAddressAllocation is where a data network's UE IPv4 addresses
+// come from. Secret is write-only: it is never returned, and omitting it
+// on update keeps the current one.
+type DataNetworkAddressAllocation struct {
+	Mode           string `json:"mode"`
+	Server         string `json:"server,omitempty"`
+	RelayAddress   string `json:"relay_address,omitempty"`
+	Secret         string `json:"secret,omitempty"`
+	FallbackToPool bool   `json:"fallback_to_pool"`
+}
+
+func addressAllocationFromDB(alloc *db.DataNetworkAddressAllocation) DataNetworkAddressAllocation {
+	if alloc == nil {
+		return DataNetworkAddressAllocation{Mode: AddressAllocationPool}
+	}
+
+	return DataNetworkAddressAllocation{
+		Mode:           alloc.Mode,
+		Server:         alloc.Server,
+		RelayAddress:   alloc.RelayAddress,
+		FallbackToPool: alloc.FallbackToPool,
+	}
+}
+
+// parseServerAddress accepts an address, or an address and port, and
+// returns it in canonical form.
+func parseServerAddress(raw string) (netip.Addr, string, error) {
+	if ap, err := netip.ParseAddrPort(raw); err == nil && ap.Port() != 0 {
+		addr := ap.Addr().Unmap()
+		return addr, netip.AddrPortFrom(addr, ap.Port()).String(), nil
+	}
+
+	addr, err := netip.ParseAddr(raw)
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, "", errors.New("must be an address or address:port")
+	}
+
+	addr = addr.Unmap()
+
+	return addr, addr.String(), nil
+}
+
+// validateAddressAllocation checks an external allocation and returns the
+// row to store. current is the stored allocation, whose secret an update
+// without one keeps.
+func validateAddressAllocation(ctx context.Context, dbInstance *db.Database, dn *db.DataNetwork, current *db.DataNetworkAddressAllocation, params *DataNetworkAddressAllocation) (*db.DataNetworkAddressAllocation, int, string) {
+	serverAddr, server, err := parseServerAddress(params.Server)
+	if err != nil {
+		return nil, http.StatusBadRequest, "invalid server: " + err.Error()
+	}
+
+	if !serverAddr.IsGlobalUnicast() && !serverAddr.IsLoopback() {
+		return nil, http.StatusBadRequest, "invalid server, must be a unicast address"
+	}
+
+	row := &db.DataNetworkAddressAllocation{
+		DataNetworkID:  dn.ID,
+		Mode:           params.Mode,
+		Server:         server,
+		FallbackToPool: params.FallbackToPool,
+	}
+
+	switch params.Mode {
+	case db.AddressAllocationDHCP:
+		if params.Secret != "" {
+			return nil, http.StatusBadRequest, "secret is only used in radius mode"
+		}
+
+		if !serverAddr.Is4() {
+			return nil, http.StatusBadRequest, "invalid server, DHCPv4 servers have IPv4 addresses"
+		}
+
+		relay, err := netip.ParseAddr(params.RelayAddress)
+		if err != nil || !relay.Unmap().Is4() {
+			return nil, http.StatusBadRequest, "invalid relay_address, must be an IPv4 address"
+		}
+
+		relay = relay.Unmap()
+
+		if !relay.IsGlobalUnicast() && !relay.IsLoopback() {
+			return nil, http.StatusBadRequest, "invalid relay_address, must be a unicast address"
+		}
+
+		// The server answers the relay address, which must stay reachable
+		// on N6 rather than route back into a UE pool.
+		if pool, err := ueAddressInPools(ctx, dbInstance, relay); err != nil {
+			return nil, http.StatusInternalServerError, "Failed to list data networks"
+		} else if pool != "" {
+			return nil, http.StatusConflict, fmt.Sprintf("relay_address %s is inside the UE pool of data network %q", relay, pool)
+		}
+
+		row.RelayAddress = relay.String()
+	case db.AddressAllocationRADIUS:
+		if params.RelayAddress != "" {
+			return nil, http.StatusBadRequest, "relay_address is only used in dhcp mode"
+		}
+
+		row.Secret = params.Secret
+		if row.Secret == "" && current != nil && current.Mode == db.AddressAllocationRADIUS {
+			row.Secret = current.Secret
+		}
+
+		if row.Secret == "" {
+			return nil, http.StatusBadRequest, "secret is required in radius mode"
+		}
+	default:
+		return nil, http.StatusBadRequest, "invalid mode, must be one of pool, dhcp, radius"
+	}
+
+	return row, 0, ""
+}
+
+func GetDataNetworkAddressAllocation(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		alloc, err := dbInstance.GetDataNetworkAddressAllocation(r.Context(), dn.ID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network address allocation", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, addressAllocationFromDB(alloc), http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateDataNetworkAddressAllocation(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params DataNetworkAddressAllocation
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if params.Mode == AddressAllocationPool {
+			if params.Server != "" || params.RelayAddress != "" || params.Secret != "" || params.FallbackToPool {
+				writeError(r.Context(), w, http.StatusBadRequest, "server, relay_address, secret and fallback_to_pool must be omitted in pool mode", nil, logger.APILog)
+				return
+			}
+
+			if err := dbInstance.ClearDataNetworkAddressAllocation(r.Context(), dn.ID); err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network address allocation", err, logger.APILog)
+				return
+			}
+
+			writeResponse(r.Context(), w, SuccessResponse{Message: "Data network address allocation updated successfully"}, http.StatusOK, logger.APILog)
+
+			logger.LogAuditEvent(r.Context(), UpdateDataNetworkAddressAllocationAction, email, getClientIP(r), "User set data network "+name+" to allocate from its pool")
+
+			return
+		}
+
+		current, err := dbInstance.GetDataNetworkAddressAllocation(r.Context(), dn.ID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network address allocation", err, logger.APILog)
+			return
+		}
+
+		row, status, msg := validateAddressAllocation(r.Context(), dbInstance, dn, current, &params)
+		if status != 0 {
+			writeError(r.Context(), w, status, msg, nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.SetDataNetworkAddressAllocation(r.Context(), row); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network address allocation", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network address allocation updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateDataNetworkAddressAllocationAction, email, getClientIP(r), fmt.Sprintf("User set data network %s to allocate from %s server %s", name, row.Mode, row.Server))
+	})
+}
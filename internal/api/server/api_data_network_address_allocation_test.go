@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+const addressAllocationDN = "enterprise"
+
+type dataNetworkAddressAllocationResponse struct {
+	Result struct {
+		Mode           string `json:"mode"`
+		Server         string `json:"server"`
+		RelayAddress   string `json:"relay_address"`
+		Secret         string `json:"secret"`
+		FallbackToPool bool   `json:"fallback_to_pool"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIDataNetworkAddressAllocationEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: addressAllocationDN, IPv4Pool: "10.74.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	allocURL := url + "/api/v1/networking/data-networks/" + addressAllocationDN + "/address-allocation"
+
+	get := func(t *testing.T) dataNetworkAddressAllocationResponse {
+		t.Helper()
+
+		var resp dataNetworkAddressAllocationResponse
+
+		code, err := doNATRequest(client, "GET", allocURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		return resp
+	}
+
+	t.Run("pool is the default", func(t *testing.T) {
+		if resp := get(t); resp.Result.Mode != "pool" {
+			t.Fatalf("unexpected address allocation: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+			code int
+		}{
+			{"unknown mode", map[string]any{"mode": "slaac", "server": "10.100.0.2"}, http.StatusBadRequest},
+			{"missing server", map[string]any{"mode": "dhcp", "relay_address": "10.100.0.1"}, http.StatusBadRequest},
+			{"missing relay", map[string]any{"mode": "dhcp", "server": "10.100.0.2"}, http.StatusBadRequest},
+			{"IPv6 DHCP server", map[string]any{"mode": "dhcp", "server": "2001:db8::2", "relay_address": "10.100.0.1"}, http.StatusBadRequest},
+			{"secret in dhcp mode", map[string]any{"mode": "dhcp", "server": "10.100.0.2", "relay_address": "10.100.0.1", "secret": "s"}, http.StatusBadRequest},
+			{"relay in a UE pool", map[string]any{"mode": "dhcp", "server": "10.100.0.2", "relay_address": "10.74.0.1"}, http.StatusConflict},
+			{"missing secret", map[string]any{"mode": "radius", "server": "10.100.0.3"}, http.StatusBadRequest},
+			{"relay in radius mode", map[string]any{"mode": "radius", "server": "10.100.0.3", "secret": "s", "relay_address": "10.100.0.1"}, http.StatusBadRequest},
+			{"settings in pool mode", map[string]any{"mode": "pool", "server": "10.100.0.2"}, http.StatusBadRequest},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", allocURL, token, tc.body, &resp)
+			if err != nil || code != tc.code {
+				t.Fatalf("%s: expected %d, got %d (%v, %s)", tc.name, tc.code, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("dhcp, radius and back to pool", func(t *testing.T) {
+		var msg messageResponse
+
+		body := map[string]any{"mode": "dhcp", "server": "10.100.0.2:6767", "relay_address": "10.100.0.1", "fallback_to_pool": true}
+
+		code, err := doNATRequest(client, "PUT", allocURL, token, body, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		r := get(t).Result
+		if r.Mode != "dhcp" || r.Server != "10.100.0.2:6767" || r.RelayAddress != "10.100.0.1" || !r.FallbackToPool {
+			t.Fatalf("unexpected address allocation: %+v", r)
+		}
+
+		code, err = doNATRequest(client, "PUT", allocURL, token, map[string]any{"mode": "radius", "server": "10.100.0.3", "secret": "testing123"}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		r = get(t).Result
+		if r.Mode != "radius" || r.Server != "10.100.0.3" || r.Secret != "" || r.FallbackToPool {
+			t.Fatalf("unexpected address allocation: %+v", r)
+		}
+
+		// The secret is kept when an update leaves it out.
+		code, err = doNATRequest(client, "PUT", allocURL, token, map[string]any{"mode": "radius", "server": "10.100.0.4:1812"}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		code, err = doNATRequest(client, "PUT", allocURL, token, map[string]any{"mode": "pool"}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		if r := get(t).Result; r.Mode != "pool" || r.Server != "" {
+			t.Fatalf("expected pool allocation, got %+v", r)
+		}
+	})
+
+	t.Run("unknown data network", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "GET", url+"/api/v1/networking/data-networks/missing/address-allocation", token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
@@ -0,0 +1,462 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const (
+	UpdateDataNetworkDNSResolverAction = "update_data_network_dns_resolver"
+	CreateDNSRecordAction              = "create_dns_record"
+	DeleteDNSRecordAction              = "delete_dns_record"
+)
+
+const (
+	// MaxDNSUpstreams bounds the servers a forwarder fails over between.
+	MaxDNSUpstreams = 4
+	// MaxDNSLocalZones bounds the domains a forwarder answers alone.
+	MaxDNSLocalZones = 16
+)
+
+// DataNetworkDNSResolver is a data network's embedded DNS forwarder. While
+// it is enabled, UEs are handed ListenAddress as their DNS server.
+type DataNetworkDNSResolver struct {
+	Enabled       bool     `json:"enabled"`
+	ListenAddress string   `json:"listen_address,omitempty"`
+	Upstreams     []string `json:"upstreams"`
+	LocalZones    []string `json:"local_zones"`
+	LogQueries    bool     `json:"log_queries"`
+}
+
+type DNSRecord struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	IMSI    string `json:"imsi,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+type DNSRecordList struct {
+	Items      []DNSRecord `json:"items"`
+	Page       int         `json:"page"`
+	PerPage    int         `json:"per_page"`
+	TotalCount int         `json:"total_count"`
+}
+
+type CreateDNSRecordParams struct {
+	Name    string `json:"name"`
+	IMSI    string `json:"imsi,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+func dnsResolverFromDB(resolver *db.DataNetworkDNSResolver) DataNetworkDNSResolver {
+	if resolver == nil {
+		return DataNetworkDNSResolver{Upstreams: []string{}, LocalZones: []string{}}
+	}
+
+	return DataNetworkDNSResolver{
+		Enabled:       true,
+		ListenAddress: resolver.ListenAddress,
+		Upstreams:     resolver.UpstreamList(),
+		LocalZones:    resolver.LocalZoneList(),
+		LogQueries:    resolver.LogQueries,
+	}
+}
+
+func dnsRecordFromDB(rec db.DNSLocalRecord) DNSRecord {
+	return DNSRecord{
+		ID:      rec.ID,
+		Name:    rec.Name,
+		IMSI:    rec.IMSI,
+		Address: rec.Address,
+	}
+}
+
+// parseDNSUpstreams validates upstream servers, each an address or
+// address:port, and returns them in canonical form.
+func parseDNSUpstreams(raw []string, listen netip.Addr) ([]string, error) {
+	if len(raw) == 0 || len(raw) > MaxDNSUpstreams {
+		return nil, fmt.Errorf("between 1 and %d upstreams are required", MaxDNSUpstreams)
+	}
+
+	seen := make(map[string]struct{}, len(raw))
+	out := make([]string, 0, len(raw))
+
+	for _, s := range raw {
+		var addr netip.Addr
+
+		canonical := ""
+
+		if ap, err := netip.ParseAddrPort(s); err == nil && ap.Port() != 0 {
+			addr = ap.Addr().Unmap()
+			canonical = netip.AddrPortFrom(addr, ap.Port()).String()
+		} else if a, err := netip.ParseAddr(s); err == nil {
+			addr = a.Unmap()
+			canonical = addr.String()
+		} else {
+			return nil, fmt.Errorf("upstream %q is not an address or address:port", s)
+		}
+
+		if addr.IsUnspecified() || addr.IsMulticast() {
+			return nil, fmt.Errorf("upstream %s is not a unicast address", addr)
+		}
+
+		// The forwarder asking itself would loop until every query times out.
+		if addr == listen {
+			return nil, fmt.Errorf("upstream %s is the listen address", addr)
+		}
+
+		if _, ok := seen[canonical]; ok {
+			return nil, fmt.Errorf("duplicate upstream %s", canonical)
+		}
+
+		seen[canonical] = struct{}{}
+		out = append(out, canonical)
+	}
+
+	return out, nil
+}
+
+// parseDNSName validates a zone or record name and returns it in lower case
+// without the trailing dot.
+func parseDNSName(raw string) (string, error) {
+	if strings.HasPrefix(raw, "*.") {
+		return "", errors.New("wildcards are not allowed")
+	}
+
+	if err := validateFQDN(raw); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.ToLower(raw), "."), nil
+}
+
+func parseDNSLocalZones(raw []string) ([]string, error) {
+	if len(raw) > MaxDNSLocalZones {
+		return nil, fmt.Errorf("at most %d local zones are allowed", MaxDNSLocalZones)
+	}
+
+	seen := make(map[string]struct{}, len(raw))
+	out := make([]string, 0, len(raw))
+
+	for _, s := range raw {
+		zone, err := parseDNSName(s)
+		if err != nil {
+			return nil, fmt.Errorf("local zone %q: %v", s, err)
+		}
+
+		if _, ok := seen[zone]; ok {
+			return nil, fmt.Errorf("duplicate local zone %s", zone)
+		}
+
+		seen[zone] = struct{}{}
+		out = append(out, zone)
+	}
+
+	return out, nil
+}
+
+// validateDNSResolver checks an enabled resolver and returns the row to
+// store. The listen address must be one UEs reach through the core, so it
+// cannot be inside a UE pool.
+func validateDNSResolver(ctx context.Context, dbInstance *db.Database, dn *db.DataNetwork, params *DataNetworkDNSResolver) (*db.DataNetworkDNSResolver, int, string) {
+	listen, err := netip.ParseAddr(params.ListenAddress)
+	if err != nil || listen.Zone() != "" {
+		return nil, http.StatusBadRequest, "invalid listen_address, must be an IP address"
+	}
+
+	listen = listen.Unmap()
+
+	if !listen.IsGlobalUnicast() {
+		return nil, http.StatusBadRequest, "invalid listen_address, must be a unicast address"
+	}
+
+	upstreams, err := parseDNSUpstreams(params.Upstreams, listen)
+	if err != nil {
+		return nil, http.StatusBadRequest, "invalid upstreams: " + err.Error()
+	}
+
+	zones, err := parseDNSLocalZones(params.LocalZones)
+	if err != nil {
+		return nil, http.StatusBadRequest, "invalid local_zones: " + err.Error()
+	}
+
+	if pool, err := ueAddressInPools(ctx, dbInstance, listen); err != nil {
+		return nil, http.StatusInternalServerError, "Failed to list data networks"
+	} else if pool != "" {
+		return nil, http.StatusConflict, fmt.Sprintf("listen_address %s is inside the UE pool of data network %q", listen, pool)
+	}
+
+	return &db.DataNetworkDNSResolver{
+		DataNetworkID: dn.ID,
+		ListenAddress: listen.String(),
+		Upstreams:     strings.Join(upstreams, ","),
+		LocalZones:    strings.Join(zones, ","),
+		LogQueries:    params.LogQueries,
+	}, 0, ""
+}
+
+func GetDataNetworkDNSResolver(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		resolver, err := dbInstance.GetDataNetworkDNSResolver(r.Context(), dn.ID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network DNS resolver", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, dnsResolverFromDB(resolver), http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateDataNetworkDNSResolver(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params DataNetworkDNSResolver
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if !params.Enabled {
+			if params.ListenAddress != "" || len(params.Upstreams) != 0 || len(params.LocalZones) != 0 {
+				writeError(r.Context(), w, http.StatusBadRequest, "listen_address, upstreams and local_zones must be omitted when the resolver is disabled", nil, logger.APILog)
+				return
+			}
+
+			if err := dbInstance.ClearDataNetworkDNSResolver(r.Context(), dn.ID); err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network DNS resolver", err, logger.APILog)
+				return
+			}
+
+			writeResponse(r.Context(), w, SuccessResponse{Message: "Data network DNS resolver updated successfully"}, http.StatusOK, logger.APILog)
+
+			logger.LogAuditEvent(r.Context(), UpdateDataNetworkDNSResolverAction, email, getClientIP(r), "User disabled the DNS resolver of data network "+name)
+
+			return
+		}
+
+		row, status, msg := validateDNSResolver(r.Context(), dbInstance, dn, &params)
+		if status != 0 {
+			writeError(r.Context(), w, status, msg, nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.SetDataNetworkDNSResolver(r.Context(), row); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network DNS resolver", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network DNS resolver updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateDataNetworkDNSResolverAction, email, getClientIP(r), fmt.Sprintf("User enabled the DNS resolver of data network %s on %s", name, row.ListenAddress))
+	})
+}
+
+func ListDataNetworkDNSRecords(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		rows, err := dbInstance.ListDNSLocalRecordsByDataNetwork(r.Context(), dn.ID)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list DNS records", err, logger.APILog)
+			return
+		}
+
+		items := make([]DNSRecord, 0, len(rows))
+		for _, row := range rows {
+			items = append(items, dnsRecordFromDB(row))
+		}
+
+		writeResponse(r.Context(), w, DNSRecordList{
+			Items:      items,
+			Page:       1,
+			PerPage:    len(items),
+			TotalCount: len(items),
+		}, http.StatusOK, logger.APILog)
+	})
+}
+
+// validateDNSRecord checks a new record. A record naming a subscriber
+// resolves to the IPv4 addresses the subscriber holds on the data network.
+func validateDNSRecord(ctx context.Context, dbInstance *db.Database, params *CreateDNSRecordParams) (int, string) {
+	recordName, err := parseDNSName(params.Name)
+	if err != nil {
+		return http.StatusBadRequest, "invalid name: " + err.Error()
+	}
+
+	params.Name = recordName
+
+	if (params.IMSI == "") == (params.Address == "") {
+		return http.StatusBadRequest, "exactly one of imsi and address is required"
+	}
+
+	if params.Address != "" {
+		addr, err := netip.ParseAddr(params.Address)
+		if err != nil || addr.Zone() != "" || addr.IsUnspecified() || addr.IsMulticast() {
+			return http.StatusBadRequest, "invalid address, must be a unicast IP address"
+		}
+
+		params.Address = addr.Unmap().String()
+
+		return 0, ""
+	}
+
+	if _, err := dbInstance.GetSubscriber(ctx, params.IMSI); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return http.StatusNotFound, "Subscriber not found"
+		}
+
+		return http.StatusInternalServerError, "Failed to get subscriber"
+	}
+
+	return 0, ""
+}
+
+func CreateDataNetworkDNSRecord(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params CreateDNSRecordParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if status, msg := validateDNSRecord(r.Context(), dbInstance, &params); status != 0 {
+			writeError(r.Context(), w, status, msg, nil, logger.APILog)
+			return
+		}
+
+		row := &db.DNSLocalRecord{
+			DataNetworkID: dn.ID,
+			Name:          params.Name,
+			IMSI:          params.IMSI,
+			Address:       params.Address,
+		}
+
+		if err := dbInstance.CreateDNSLocalRecord(r.Context(), row); err != nil {
+			if errors.Is(err, db.ErrAlreadyExists) {
+				writeError(r.Context(), w, http.StatusConflict, "the data network already has a record with this name", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create DNS record", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, dnsRecordFromDB(*row), http.StatusCreated, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), CreateDNSRecordAction, email, getClientIP(r), fmt.Sprintf("User created DNS record %s on data network %s", params.Name, name))
+	})
+}
+
+func DeleteDataNetworkDNSRecord(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		id := r.PathValue("id")
+
+		if name == "" || id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name or id parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		rec, err := dbInstance.GetDNSLocalRecord(r.Context(), id)
+		if err != nil || rec.DataNetworkID != dn.ID {
+			writeError(r.Context(), w, http.StatusNotFound, "DNS record not found", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeleteDNSLocalRecord(r.Context(), id); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "DNS record not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete DNS record", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "DNS record deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteDNSRecordAction, email, getClientIP(r), fmt.Sprintf("User removed DNS record %s on data network %s", rec.Name, name))
+	})
+}
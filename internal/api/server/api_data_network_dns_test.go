@@ -0,0 +1,242 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+const dnsResolverDN = "dns-dn"
+
+type dataNetworkDNSResolverResponse struct {
+	Result struct {
+		Enabled       bool     `json:"enabled"`
+		ListenAddress string   `json:"listen_address"`
+		Upstreams     []string `json:"upstreams"`
+		LocalZones    []string `json:"local_zones"`
+		LogQueries    bool     `json:"log_queries"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+type dnsRecordResponse struct {
+	Result struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		IMSI    string `json:"imsi"`
+		Address string `json:"address"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+type dnsRecordListResponse struct {
+	Result struct {
+		Items []struct {
+			ID      string `json:"id"`
+			Name    string `json:"name"`
+			Address string `json:"address"`
+		} `json:"items"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIDataNetworkDNSResolverEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: dnsResolverDN, IPv4Pool: "10.73.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	resolverURL := url + "/api/v1/networking/data-networks/" + dnsResolverDN + "/dns-resolver"
+
+	t.Run("resolver is off by default", func(t *testing.T) {
+		var resp dataNetworkDNSResolverResponse
+
+		code, err := doNATRequest(client, "GET", resolverURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		if resp.Result.Enabled {
+			t.Fatalf("unexpected DNS resolver: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+			code int
+		}{
+			{"missing listen address", map[string]any{"enabled": true, "upstreams": []string{"1.1.1.1"}}, http.StatusBadRequest},
+			{"loopback listen address", map[string]any{"enabled": true, "listen_address": "127.0.0.1", "upstreams": []string{"1.1.1.1"}}, http.StatusBadRequest},
+			{"no upstreams", map[string]any{"enabled": true, "listen_address": "192.168.50.1"}, http.StatusBadRequest},
+			{"upstream is the listen address", map[string]any{"enabled": true, "listen_address": "192.168.50.1", "upstreams": []string{"192.168.50.1:53"}}, http.StatusBadRequest},
+			{"too many upstreams", map[string]any{"enabled": true, "listen_address": "192.168.50.1", "upstreams": []string{"1.1.1.1", "1.0.0.1", "8.8.8.8", "8.8.4.4", "9.9.9.9"}}, http.StatusBadRequest},
+			{"invalid local zone", map[string]any{"enabled": true, "listen_address": "192.168.50.1", "upstreams": []string{"1.1.1.1"}, "local_zones": []string{"*.corp.example"}}, http.StatusBadRequest},
+			{"listen address in a UE pool", map[string]any{"enabled": true, "listen_address": "10.73.0.1", "upstreams": []string{"1.1.1.1"}}, http.StatusConflict},
+			{"settings while disabled", map[string]any{"enabled": false, "listen_address": "192.168.50.1"}, http.StatusBadRequest},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", resolverURL, token, tc.body, &resp)
+			if err != nil || code != tc.code {
+				t.Fatalf("%s: expected %d, got %d (%v, %s)", tc.name, tc.code, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("enable, read back and disable", func(t *testing.T) {
+		var msg messageResponse
+
+		body := map[string]any{
+			"enabled":        true,
+			"listen_address": "192.168.50.1",
+			"upstreams":      []string{"1.1.1.1", "9.9.9.9:5353"},
+			"local_zones":    []string{"Corp.Example."},
+			"log_queries":    true,
+		}
+
+		code, err := doNATRequest(client, "PUT", resolverURL, token, body, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		var resp dataNetworkDNSResolverResponse
+
+		if _, err := doNATRequest(client, "GET", resolverURL, token, nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		r := resp.Result
+		if !r.Enabled || r.ListenAddress != "192.168.50.1" || len(r.Upstreams) != 2 || r.Upstreams[1] != "9.9.9.9:5353" ||
+			len(r.LocalZones) != 1 || r.LocalZones[0] != "corp.example" || !r.LogQueries {
+			t.Fatalf("unexpected DNS resolver: %+v", r)
+		}
+
+		code, err = doNATRequest(client, "PUT", resolverURL, token, map[string]any{"enabled": false}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		resp = dataNetworkDNSResolverResponse{}
+
+		if _, err := doNATRequest(client, "GET", resolverURL, token, nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Result.Enabled {
+			t.Fatalf("expected the resolver to be off, got %+v", resp.Result)
+		}
+	})
+}
+
+func TestAPIDataNetworkDNSRecordsEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: dnsResolverDN, IPv4Pool: "10.73.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	recordsURL := url + "/api/v1/networking/data-networks/" + dnsResolverDN + "/dns-records"
+
+	t.Run("invalid records are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+			code int
+		}{
+			{"neither imsi nor address", map[string]any{"name": "printer.corp.example"}, http.StatusBadRequest},
+			{"both imsi and address", map[string]any{"name": "printer.corp.example", "imsi": "001019756139935", "address": "10.0.0.5"}, http.StatusBadRequest},
+			{"invalid name", map[string]any{"name": "printer", "address": "10.0.0.5"}, http.StatusBadRequest},
+			{"invalid address", map[string]any{"name": "printer.corp.example", "address": "224.0.0.1"}, http.StatusBadRequest},
+			{"unknown subscriber", map[string]any{"name": "camera.corp.example", "imsi": "001019756139935"}, http.StatusNotFound},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "POST", recordsURL, token, tc.body, &resp)
+			if err != nil || code != tc.code {
+				t.Fatalf("%s: expected %d, got %d (%v, %s)", tc.name, tc.code, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("create, list and delete", func(t *testing.T) {
+		var created dnsRecordResponse
+
+		code, err := doNATRequest(client, "POST", recordsURL, token, map[string]any{"name": "Printer.Corp.Example", "address": "10.0.0.5"}, &created)
+		if err != nil || code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d (%v, %s)", code, err, created.Error)
+		}
+
+		if created.Result.ID == "" || created.Result.Name != "printer.corp.example" {
+			t.Fatalf("unexpected record: %+v", created.Result)
+		}
+
+		var dup messageResponse
+
+		code, err = doNATRequest(client, "POST", recordsURL, token, map[string]any{"name": "printer.corp.example", "address": "10.0.0.6"}, &dup)
+		if err != nil || code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d (%v, %s)", code, err, dup.Error)
+		}
+
+		var list dnsRecordListResponse
+
+		if _, err := doNATRequest(client, "GET", recordsURL, token, nil, &list); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(list.Result.Items) != 1 || list.Result.Items[0].Address != "10.0.0.5" {
+			t.Fatalf("unexpected records: %+v", list.Result.Items)
+		}
+
+		var msg messageResponse
+
+		code, err = doNATRequest(client, "DELETE", recordsURL+"/"+created.Result.ID, token, nil, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		code, err = doNATRequest(client, "DELETE", recordsURL+"/"+created.Result.ID, token, nil, &msg)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, msg.Error)
+		}
+	})
+}
@@ -0,0 +1,525 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+const (
+	UpdateDataNetworkNATAction = "update_data_network_nat"
+	CreatePortForwardAction    = "create_port_forward"
+	DeletePortForwardAction    = "delete_port_forward"
+)
+
+// MaxNATPoolAddresses matches the datapath's per-pool address slots.
+const MaxNATPoolAddresses = 16
+
+// DataNetworkNAT is a data network's public source pool. An empty Addresses
+// list means the data network masquerades on its egress address.
+type DataNetworkNAT struct {
+	Addresses []string `json:"addresses"`
+	Mapping   string   `json:"mapping"`
+	PortMin   int      `json:"port_min,omitempty"`
+	PortMax   int      `json:"port_max,omitempty"`
+}
+
+type PortForward struct {
+	ID            string `json:"id"`
+	Protocol      string `json:"protocol"`
+	PublicAddress string `json:"public_address"`
+	PublicPort    int    `json:"public_port"`
+	IMSI          string `json:"imsi,omitempty"`
+	UEAddress     string `json:"ue_address,omitempty"`
+	UEPort        int    `json:"ue_port"`
+}
+
+type PortForwardList struct {
+	Items      []PortForward `json:"items"`
+	Page       int           `json:"page"`
+	PerPage    int           `json:"per_page"`
+	TotalCount int           `json:"total_count"`
+}
+
+type CreatePortForwardParams struct {
+	Protocol      string `json:"protocol"`
+	PublicAddress string `json:"public_address"`
+	PublicPort    int    `json:"public_port"`
+	IMSI          string `json:"imsi,omitempty"`
+	UEAddress     string `json:"ue_address,omitempty"`
+	UEPort        int    `json:"ue_port"`
+}
+
+func natFromDB(nat *db.DataNetworkNAT) DataNetworkNAT {
+	if nat == nil {
+		return DataNetworkNAT{Addresses: []string{}, Mapping: db.NATMappingDeterministic}
+	}
+
+	return DataNetworkNAT{
+		Addresses: nat.AddressList(),
+		Mapping:   nat.Mapping,
+		PortMin:   nat.PortMin,
+		PortMax:   nat.PortMax,
+	}
+}
+
+func portForwardFromDB(f db.NATPortForward) PortForward {
+	return PortForward{
+		ID:            f.ID,
+		Protocol:      f.Protocol,
+		PublicAddress: f.PublicAddress,
+		PublicPort:    f.PublicPort,
+		IMSI:          f.IMSI,
+		UEAddress:     f.UEAddress,
+		UEPort:        f.UEPort,
+	}
+}
+
+func isPortValid(port int) bool {
+	return port >= 1 && port <= 65535
+}
+
+// parseNATAddresses validates a pool's public addresses: IPv4 only, at most
+// MaxNATPoolAddresses, no duplicates. It returns them in canonical form.
+func parseNATAddresses(raw []string) ([]string, error) {
+	if len(raw) > MaxNATPoolAddresses {
+		return nil, fmt.Errorf("at most %d NAT addresses are allowed", MaxNATPoolAddresses)
+	}
+
+	seen := make(map[netip.Addr]struct{}, len(raw))
+	out := make([]string, 0, len(raw))
+
+	for _, s := range raw {
+		addr, err := netip.ParseAddr(s)
+		if err != nil || !addr.Is4() {
+			return nil, fmt.Errorf("%q is not an IPv4 address", s)
+		}
+
+		if !addr.IsGlobalUnicast() {
+			return nil, fmt.Errorf("%s is not a unicast address", addr)
+		}
+
+		if _, ok := seen[addr]; ok {
+			return nil, fmt.Errorf("duplicate NAT address %s", addr)
+		}
+
+		seen[addr] = struct{}{}
+		out = append(out, addr.String())
+	}
+
+	return out, nil
+}
+
+// natAddressOwner returns the name of the data network, other than
+// excludeID, whose NAT pool holds addr, or "" when none does.
+func natAddressOwner(ctx context.Context, dbInstance *db.Database, addr string, excludeID string) (string, error) {
+	pools, err := dbInstance.ListAllDataNetworkNAT(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for i := range pools {
+		if pools[i].DataNetworkID == excludeID {
+			continue
+		}
+
+		for _, a := range pools[i].AddressList() {
+			if a != addr {
+				continue
+			}
+
+			dns, err := dbInstance.ListAllDataNetworks(ctx)
+			if err != nil {
+				return "", err
+			}
+
+			for _, dn := range dns {
+				if dn.ID == pools[i].DataNetworkID {
+					return dn.Name, nil
+				}
+			}
+
+			return pools[i].DataNetworkID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// ueAddressInPools reports whether addr lies inside any data network's UE
+// pool. A public address there would be routed back to a UE.
+func ueAddressInPools(ctx context.Context, dbInstance *db.Database, addr netip.Addr) (string, error) {
+	dns, err := dbInstance.ListAllDataNetworks(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, dn := range dns {
+		if p, err := netip.ParsePrefix(dn.IPv4Pool); err == nil && p.Contains(addr) {
+			return dn.Name, nil
+		}
+	}
+
+	return "", nil
+}
+
+func GetDataNetworkNAT(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		nat, err := dbInstance.GetDataNetworkNAT(r.Context(), dn.ID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network NAT", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, natFromDB(nat), http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateDataNetworkNAT(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params DataNetworkNAT
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if params.Mapping == "" {
+			params.Mapping = db.NATMappingDeterministic
+		}
+
+		if params.Mapping != db.NATMappingDeterministic && params.Mapping != db.NATMappingRoundRobin {
+			writeError(r.Context(), w, http.StatusBadRequest, "invalid mapping, must be deterministic or round_robin", nil, logger.APILog)
+			return
+		}
+
+		if params.PortMin != 0 || params.PortMax != 0 {
+			if params.PortMin < 1024 || params.PortMax > 65535 || params.PortMin > params.PortMax {
+				writeError(r.Context(), w, http.StatusBadRequest, "invalid port range, must satisfy 1024 <= port_min <= port_max <= 65535", nil, logger.APILog)
+				return
+			}
+		}
+
+		addresses, err := parseNATAddresses(params.Addresses)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		// Without the pool maps the data network would keep masquerading
+		// on the egress address.
+		if len(addresses) > 0 && !datapath().NATPools {
+			writeError(r.Context(), w, http.StatusBadRequest, "NAT pools are not supported by this node's datapath", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if len(addresses) > 0 && dn.IPv4Pool == "" {
+			writeError(r.Context(), w, http.StatusConflict, "data network has no IPv4 pool to translate", nil, logger.APILog)
+			return
+		}
+
+		for _, a := range addresses {
+			owner, err := natAddressOwner(r.Context(), dbInstance, a, dn.ID)
+			if err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list data network NAT", err, logger.APILog)
+				return
+			}
+
+			if owner != "" {
+				writeError(r.Context(), w, http.StatusConflict, fmt.Sprintf("NAT address %s is already used by data network %q", a, owner), nil, logger.APILog)
+				return
+			}
+
+			pool, err := ueAddressInPools(r.Context(), dbInstance, netip.MustParseAddr(a))
+			if err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list data networks", err, logger.APILog)
+				return
+			}
+
+			if pool != "" {
+				writeError(r.Context(), w, http.StatusConflict, fmt.Sprintf("NAT address %s is inside the UE pool of data network %q", a, pool), nil, logger.APILog)
+				return
+			}
+		}
+
+		row := &db.DataNetworkNAT{
+			DataNetworkID: dn.ID,
+			Addresses:     strings.Join(addresses, ","),
+			Mapping:       params.Mapping,
+			PortMin:       params.PortMin,
+			PortMax:       params.PortMax,
+		}
+
+		if err := dbInstance.SetDataNetworkNAT(r.Context(), row); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network NAT", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network NAT updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateDataNetworkNATAction, email, getClientIP(r), fmt.Sprintf("User set %d NAT address(es) on data network %s", len(addresses), name))
+	})
+}
+
+func ListDataNetworkPortForwards(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		rows, err := dbInstance.ListNATPortForwardsByDataNetwork(r.Context(), dn.ID)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list port forwards", err, logger.APILog)
+			return
+		}
+
+		items := make([]PortForward, 0, len(rows))
+		for _, row := range rows {
+			items = append(items, portForwardFromDB(row))
+		}
+
+		writeResponse(r.Context(), w, PortForwardList{
+			Items:      items,
+			Page:       1,
+			PerPage:    len(items),
+			TotalCount: len(items),
+		}, http.StatusOK, logger.APILog)
+	})
+}
+
+// validatePortForward checks a new forward against the data network and its
+// existing forwards. The datapath sources a UE's traffic from its forwards'
+// public address, so every forward to one UE must share it.
+func validatePortForward(ctx context.Context, dbInstance *db.Database, dn *db.DataNetwork, params *CreatePortForwardParams) (int, string) {
+	if params.Protocol != "tcp" && params.Protocol != "udp" {
+		return http.StatusBadRequest, "invalid protocol, must be tcp or udp"
+	}
+
+	public, err := netip.ParseAddr(params.PublicAddress)
+	if err != nil || !public.Is4() || !public.IsGlobalUnicast() {
+		return http.StatusBadRequest, "invalid public_address, must be a unicast IPv4 address"
+	}
+
+	params.PublicAddress = public.String()
+
+	if !isPortValid(params.PublicPort) {
+		return http.StatusBadRequest, "invalid public_port, must be between 1 and 65535"
+	}
+
+	if !isPortValid(params.UEPort) {
+		return http.StatusBadRequest, "invalid ue_port, must be between 1 and 65535"
+	}
+
+	if (params.IMSI == "") == (params.UEAddress == "") {
+		return http.StatusBadRequest, "exactly one of imsi and ue_address is required"
+	}
+
+	if dn.IPv4Pool == "" {
+		return http.StatusConflict, "data network has no IPv4 pool"
+	}
+
+	if params.UEAddress != "" {
+		ue, err := netip.ParseAddr(params.UEAddress)
+		if err != nil || !ue.Is4() {
+			return http.StatusBadRequest, "invalid ue_address, must be an IPv4 address"
+		}
+
+		pool, err := netip.ParsePrefix(dn.IPv4Pool)
+		if err != nil || !pool.Contains(ue) {
+			return http.StatusBadRequest, "ue_address is outside the data network's IPv4 pool"
+		}
+
+		params.UEAddress = ue.String()
+	} else if _, err := dbInstance.GetSubscriber(ctx, params.IMSI); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return http.StatusNotFound, "Subscriber not found"
+		}
+
+		return http.StatusInternalServerError, "Failed to get subscriber"
+	}
+
+	owner, err := natAddressOwner(ctx, dbInstance, params.PublicAddress, dn.ID)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to list data network NAT"
+	}
+
+	if owner != "" {
+		return http.StatusConflict, fmt.Sprintf("public_address %s belongs to the NAT pool of data network %q", params.PublicAddress, owner)
+	}
+
+	if pool, err := ueAddressInPools(ctx, dbInstance, public); err != nil {
+		return http.StatusInternalServerError, "Failed to list data networks"
+	} else if pool != "" {
+		return http.StatusConflict, fmt.Sprintf("public_address %s is inside the UE pool of data network %q", params.PublicAddress, pool)
+	}
+
+	existing, err := dbInstance.ListNATPortForwardsByDataNetwork(ctx, dn.ID)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to list port forwards"
+	}
+
+	for _, f := range existing {
+		sameUE := (params.IMSI != "" && f.IMSI == params.IMSI) || (params.UEAddress != "" && f.UEAddress == params.UEAddress)
+		if sameUE && f.PublicAddress != params.PublicAddress {
+			return http.StatusConflict, fmt.Sprintf("the UE is already forwarded from %s; all of a UE's forwards must share one public address", f.PublicAddress)
+		}
+	}
+
+	return 0, ""
+}
+
+func CreateDataNetworkPortForward(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params CreatePortForwardParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if !datapath().NATPools {
+			writeError(r.Context(), w, http.StatusBadRequest, "port forwards are not supported by this node's datapath", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if status, msg := validatePortForward(r.Context(), dbInstance, dn, &params); status != 0 {
+			writeError(r.Context(), w, status, msg, nil, logger.APILog)
+			return
+		}
+
+		row := &db.NATPortForward{
+			DataNetworkID: dn.ID,
+			Protocol:      params.Protocol,
+			PublicAddress: params.PublicAddress,
+			PublicPort:    params.PublicPort,
+			IMSI:          params.IMSI,
+			UEAddress:     params.UEAddress,
+			UEPort:        params.UEPort,
+		}
+
+		if err := dbInstance.CreateNATPortForward(r.Context(), row); err != nil {
+			if errors.Is(err, db.ErrAlreadyExists) {
+				writeError(r.Context(), w, http.StatusConflict, "the public address and port are already forwarded", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create port forward", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, portForwardFromDB(*row), http.StatusCreated, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), CreatePortForwardAction, email, getClientIP(r), fmt.Sprintf("User forwarded %s %s:%d on data network %s", params.Protocol, params.PublicAddress, params.PublicPort, name))
+	})
+}
+
+func DeleteDataNetworkPortForward(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		id := r.PathValue("id")
+
+		if name == "" || id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name or id parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		fwd, err := dbInstance.GetNATPortForward(r.Context(), id)
+		if err != nil || fwd.DataNetworkID != dn.ID {
+			writeError(r.Context(), w, http.StatusNotFound, "Port forward not found", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeleteNATPortForward(r.Context(), id); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Port forward not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete port forward", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Port forward deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeletePortForwardAction, email, getClientIP(r), fmt.Sprintf("User removed port forward %s %s:%d on data network %s", fwd.Protocol, fwd.PublicAddress, fwd.PublicPort, name))
+	})
+}
@@ -0,0 +1,320 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+const natDN = "nat-dn"
+
+type dataNetworkNATResponse struct {
+	Result struct {
+		Addresses []string `json:"addresses"`
+		Mapping   string   `json:"mapping"`
+		PortMin   int      `json:"port_min"`
+		PortMax   int      `json:"port_max"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+type portForwardItem struct {
+	ID            string `json:"id"`
+	Protocol      string `json:"protocol"`
+	PublicAddress string `json:"public_address"`
+	PublicPort    int    `json:"public_port"`
+	IMSI          string `json:"imsi"`
+	UEAddress     string `json:"ue_address"`
+	UEPort        int    `json:"ue_port"`
+}
+
+type portForwardResponse struct {
+	Result portForwardItem `json:"result"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type portForwardListResponse struct {
+	Result struct {
+		Items      []portForwardItem `json:"items"`
+		TotalCount int               `json:"total_count"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func doNATRequest(client *http.Client, method, path, token string, body any, out any) (int, error) {
+	var reader *strings.Reader
+
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+
+		reader = strings.NewReader(string(raw))
+	} else {
+		reader = strings.NewReader("")
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, path, reader)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return 0, err
+	}
+
+	return res.StatusCode, nil
+}
+
+func TestAPIDataNetworkNATEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: natDN, IPv4Pool: "10.70.0.0/24", DNS: DNS, MTU: MTU})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	natURL := url + "/api/v1/networking/data-networks/" + natDN + "/nat"
+	forwardsURL := url + "/api/v1/networking/data-networks/" + natDN + "/port-forwards"
+
+	t.Run("unset pool reads as empty", func(t *testing.T) {
+		var resp dataNetworkNATResponse
+
+		code, err := doNATRequest(client, "GET", natURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		if len(resp.Result.Addresses) != 0 || resp.Result.Mapping != "deterministic" {
+			t.Fatalf("unexpected NAT config: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid pools are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+			want int
+		}{
+			{"ipv6 address", map[string]any{"addresses": []string{"2001:db8::1"}}, http.StatusBadRequest},
+			{"duplicate address", map[string]any{"addresses": []string{"203.0.113.1", "203.0.113.1"}}, http.StatusBadRequest},
+			{"bad mapping", map[string]any{"addresses": []string{"203.0.113.1"}, "mapping": "random"}, http.StatusBadRequest},
+			{"inverted ports", map[string]any{"addresses": []string{"203.0.113.1"}, "port_min": 30000, "port_max": 20000}, http.StatusBadRequest},
+			{"inside a UE pool", map[string]any{"addresses": []string{"10.70.0.9"}}, http.StatusConflict},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", natURL, token, tc.body, &resp)
+			if err != nil || code != tc.want {
+				t.Fatalf("%s: expected %d, got %d (%v, %s)", tc.name, tc.want, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("set and read back a pool", func(t *testing.T) {
+		var msg messageResponse
+
+		body := map[string]any{"addresses": []string{"203.0.113.10", "203.0.113.11"}, "mapping": "round_robin", "port_min": 20000, "port_max": 29999}
+
+		code, err := doNATRequest(client, "PUT", natURL, token, body, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		var resp dataNetworkNATResponse
+
+		if _, err := doNATRequest(client, "GET", natURL, token, nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(resp.Result.Addresses) != 2 || resp.Result.Mapping != "round_robin" || resp.Result.PortMin != 20000 || resp.Result.PortMax != 29999 {
+			t.Fatalf("unexpected NAT config: %+v", resp.Result)
+		}
+	})
+
+	t.Run("a pool address cannot be shared across data networks", func(t *testing.T) {
+		sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: "nat-other-dn", IPv4Pool: "10.71.0.0/24", DNS: DNS, MTU: MTU})
+		if err != nil || sc != http.StatusCreated {
+			t.Fatalf("couldn't create data network: %d %v", sc, err)
+		}
+
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", url+"/api/v1/networking/data-networks/nat-other-dn/nat", token, map[string]any{"addresses": []string{"203.0.113.11"}}, &msg)
+		if err != nil || code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d (%v, %s)", code, err, msg.Error)
+		}
+	})
+
+	var forwardID string
+
+	t.Run("create and list a port forward", func(t *testing.T) {
+		var resp portForwardResponse
+
+		body := map[string]any{"protocol": "tcp", "public_address": "203.0.113.10", "public_port": 8080, "ue_address": "10.70.0.20", "ue_port": 80}
+
+		code, err := doNATRequest(client, "POST", forwardsURL, token, body, &resp)
+		if err != nil || code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		if resp.Result.ID == "" {
+			t.Fatal("expected an id in the response")
+		}
+
+		forwardID = resp.Result.ID
+
+		var list portForwardListResponse
+
+		if _, err := doNATRequest(client, "GET", forwardsURL, token, nil, &list); err != nil {
+			t.Fatal(err)
+		}
+
+		if list.Result.TotalCount != 1 || list.Result.Items[0].UEAddress != "10.70.0.20" || list.Result.Items[0].PublicPort != 8080 {
+			t.Fatalf("unexpected port forwards: %+v", list.Result)
+		}
+	})
+
+	t.Run("invalid port forwards are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+			want int
+		}{
+			{"bad protocol", map[string]any{"protocol": "sctp", "public_address": "203.0.113.10", "public_port": 9000, "ue_address": "10.70.0.21", "ue_port": 80}, http.StatusBadRequest},
+			{"no target", map[string]any{"protocol": "tcp", "public_address": "203.0.113.10", "public_port": 9000, "ue_port": 80}, http.StatusBadRequest},
+			{"both targets", map[string]any{"protocol": "tcp", "public_address": "203.0.113.10", "public_port": 9000, "imsi": Imsi, "ue_address": "10.70.0.21", "ue_port": 80}, http.StatusBadRequest},
+			{"ue outside pool", map[string]any{"protocol": "tcp", "public_address": "203.0.113.10", "public_port": 9000, "ue_address": "10.99.0.1", "ue_port": 80}, http.StatusBadRequest},
+			{"unknown subscriber", map[string]any{"protocol": "tcp", "public_address": "203.0.113.10", "public_port": 9000, "imsi": "001010100000042", "ue_port": 80}, http.StatusNotFound},
+			{"reused public port", map[string]any{"protocol": "tcp", "public_address": "203.0.113.10", "public_port": 8080, "ue_address": "10.70.0.21", "ue_port": 80}, http.StatusConflict},
+			{"second public address for a UE", map[string]any{"protocol": "udp", "public_address": "203.0.113.11", "public_port": 5000, "ue_address": "10.70.0.20", "ue_port": 5000}, http.StatusConflict},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "POST", forwardsURL, token, tc.body, &resp)
+			if err != nil || code != tc.want {
+				t.Fatalf("%s: expected %d, got %d (%v, %s)", tc.name, tc.want, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("delete a port forward", func(t *testing.T) {
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "DELETE", forwardsURL+"/"+forwardID, token, nil, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		code, err = doNATRequest(client, "DELETE", forwardsURL+"/"+forwardID, token, nil, &msg)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, msg.Error)
+		}
+	})
+
+	t.Run("clearing the addresses removes the pool", func(t *testing.T) {
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", natURL, token, map[string]any{"addresses": []string{}}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		var resp dataNetworkNATResponse
+
+		if _, err := doNATRequest(client, "GET", natURL, token, nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(resp.Result.Addresses) != 0 {
+			t.Fatalf("expected no addresses, got %+v", resp.Result)
+		}
+	})
+}
+
+func TestAPIDataNetworkNATUnsupportedDatapath(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServerWithDatapath(dbPath, models.DatapathFeatures{})
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: natDN, IPv4Pool: "10.70.0.0/24", DNS: DNS, MTU: MTU})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	natURL := url + "/api/v1/networking/data-networks/" + natDN + "/nat"
+	forwardsURL := url + "/api/v1/networking/data-networks/" + natDN + "/port-forwards"
+
+	var msg messageResponse
+
+	code, err := doNATRequest(client, "PUT", natURL, token, map[string]any{"addresses": []string{"203.0.113.10"}}, &msg)
+	if err != nil || code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a pool, got %d (%v, %s)", code, err, msg.Error)
+	}
+
+	code, err = doNATRequest(client, "PUT", natURL, token, map[string]any{"addresses": []string{}}, &msg)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected clearing the pool to succeed, got %d (%v, %s)", code, err, msg.Error)
+	}
+
+	var resp portForwardResponse
+
+	body := map[string]any{"protocol": "tcp", "public_address": "203.0.113.10", "public_port": 8080, "ue_address": "10.70.0.20", "ue_port": 80}
+
+	code, err = doNATRequest(client, "POST", forwardsURL, token, body, &resp)
+	if err != nil || code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a port forward, got %d (%v, %s)", code, err, resp.Error)
+	}
+}
@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const UpdateDataNetworkOnlineChargingAction = "update_data_network_online_charging"
+
+// DataNetworkOnlineCharging is whether sessions on a data network are held
+// to credit granted by an online charging system, and how it is reached.
+type DataNetworkOnlineCharging struct {
+	Enabled         bool   `json:"enabled"`
+	Protocol        string `json:"protocol,omitempty"`
+	Server          string `json:"server,omitempty"`
+	Realm           string `json:"realm,omitempty"`
+	RatingGroup     int64  `json:"rating_group,omitempty"`
+	FailureHandling string `json:"failure_handling,omitempty"`
+}
+
+func GetDataNetworkOnlineCharging(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		oc, err := dbInstance.GetDataNetworkOnlineCharging(r.Context(), dn.ID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeResponse(r.Context(), w, DataNetworkOnlineCharging{}, http.StatusOK, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network online charging", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, DataNetworkOnlineCharging{
+			Enabled:         true,
+			Protocol:        oc.Protocol,
+			Server:          oc.Server,
+			Realm:           oc.Realm,
+			RatingGroup:     oc.RatingGroup,
+			FailureHandling: oc.FailureHandling,
+		}, http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateDataNetworkOnlineCharging(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params DataNetworkOnlineCharging
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if !params.Enabled {
+			if params != (DataNetworkOnlineCharging{}) {
+				writeError(r.Context(), w, http.StatusBadRequest, "settings must be omitted when disabled", nil, logger.APILog)
+				return
+			}
+
+			if err := dbInstance.ClearDataNetworkOnlineCharging(r.Context(), dn.ID); err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network online charging", err, logger.APILog)
+				return
+			}
+
+			writeResponse(r.Context(), w, SuccessResponse{Message: "Data network online charging updated successfully"}, http.StatusOK, logger.APILog)
+
+			logger.LogAuditEvent(r.Context(), UpdateDataNetworkOnlineChargingAction, email, getClientIP(r), "User turned off online charging for data network "+name)
+
+			return
+		}
+
+		if params.FailureHandling == "" {
+			params.FailureHandling = db.OnlineChargingTerminate
+		}
+
+		if msg := validateOnlineCharging(&params); msg != "" {
+			writeError(r.Context(), w, http.StatusBadRequest, msg, nil, logger.APILog)
+			return
+		}
+
+		row := &db.DataNetworkOnlineCharging{
+			DataNetworkID:   dn.ID,
+			Protocol:        params.Protocol,
+			Server:          params.Server,
+			Realm:           params.Realm,
+			RatingGroup:     params.RatingGroup,
+			FailureHandling: params.FailureHandling,
+		}
+
+		if err := dbInstance.SetDataNetworkOnlineCharging(r.Context(), row); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network online charging", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network online charging updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateDataNetworkOnlineChargingAction, email, getClientIP(r), "User set data network "+name+" to charge sessions online against "+params.Server)
+	})
+}
+
+// validateOnlineCharging checks enabled online charging settings and
+// returns why they are refused, or "".
+func validateOnlineCharging(params *DataNetworkOnlineCharging) string {
+	switch params.Protocol {
+	case db.OnlineChargingDiameter:
+		host, port, err := net.SplitHostPort(params.Server)
+		if err != nil || host == "" {
+			return "invalid server, must be host:port for diameter"
+		}
+
+		if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+			return "invalid server port"
+		}
+
+		if params.Realm == "" {
+			return "realm is required for diameter"
+		}
+	case db.OnlineChargingNchf:
+		u, err := url.Parse(params.Server)
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return "invalid server, must be an http or https URL for nchf"
+		}
+
+		if params.Realm != "" {
+			return "realm only applies to diameter"
+		}
+	default:
+		return "protocol must be diameter or nchf"
+	}
+
+	if params.RatingGroup < 1 || params.RatingGroup > 0xFFFFFFFF {
+		return "rating_group must be between 1 and 4294967295"
+	}
+
+	if params.FailureHandling != db.OnlineChargingTerminate && params.FailureHandling != db.OnlineChargingContinue {
+		return "failure_handling must be terminate or continue"
+	}
+
+	return ""
+}
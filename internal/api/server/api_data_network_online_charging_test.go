@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+const onlineChargingDN = "prepaid"
+
+type dataNetworkOnlineChargingResponse struct {
+	Result struct {
+		Enabled         bool   `json:"enabled"`
+		Protocol        string `json:"protocol"`
+		Server          string `json:"server"`
+		Realm           string `json:"realm"`
+		RatingGroup     int64  `json:"rating_group"`
+		FailureHandling string `json:"failure_handling"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIDataNetworkOnlineChargingEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: onlineChargingDN, IPv4Pool: "10.76.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	ocURL := url + "/api/v1/networking/data-networks/" + onlineChargingDN + "/online-charging"
+
+	get := func(t *testing.T) dataNetworkOnlineChargingResponse {
+		t.Helper()
+
+		var resp dataNetworkOnlineChargingResponse
+
+		code, err := doNATRequest(client, "GET", ocURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		return resp
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		if resp := get(t); resp.Result.Enabled {
+			t.Fatalf("unexpected online charging: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"unknown protocol", map[string]any{"enabled": true, "protocol": "radius", "server": "ocs:3868", "realm": "example.org", "rating_group": 1}},
+			{"diameter without port", map[string]any{"enabled": true, "protocol": "diameter", "server": "ocs.example.org", "realm": "example.org", "rating_group": 1}},
+			{"diameter without realm", map[string]any{"enabled": true, "protocol": "diameter", "server": "ocs.example.org:3868", "rating_group": 1}},
+			{"nchf without scheme", map[string]any{"enabled": true, "protocol": "nchf", "server": "chf.example.org:8080", "rating_group": 1}},
+			{"nchf with realm", map[string]any{"enabled": true, "protocol": "nchf", "server": "http://chf.example.org", "realm": "example.org", "rating_group": 1}},
+			{"missing rating group", map[string]any{"enabled": true, "protocol": "nchf", "server": "http://chf.example.org"}},
+			{"unknown failure handling", map[string]any{"enabled": true, "protocol": "nchf", "server": "http://chf.example.org", "rating_group": 1, "failure_handling": "retry"}},
+			{"settings when disabled", map[string]any{"enabled": false, "protocol": "nchf"}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", ocURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("enable, change and disable", func(t *testing.T) {
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", ocURL, token, map[string]any{
+			"enabled": true, "protocol": "diameter", "server": "ocs.example.org:3868", "realm": "example.org", "rating_group": 10,
+		}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		r := get(t).Result
+		if !r.Enabled || r.Protocol != "diameter" || r.Server != "ocs.example.org:3868" || r.Realm != "example.org" || r.RatingGroup != 10 || r.FailureHandling != "terminate" {
+			t.Fatalf("unexpected online charging: %+v", r)
+		}
+
+		code, err = doNATRequest(client, "PUT", ocURL, token, map[string]any{
+			"enabled": true, "protocol": "nchf", "server": "https://chf.example.org/", "rating_group": 20, "failure_handling": "continue",
+		}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		if r := get(t).Result; r.Protocol != "nchf" || r.Realm != "" || r.RatingGroup != 20 || r.FailureHandling != "continue" {
+			t.Fatalf("unexpected online charging: %+v", r)
+		}
+
+		code, err = doNATRequest(client, "PUT", ocURL, token, map[string]any{"enabled": false}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		if r := get(t).Result; r.Enabled || r.Server != "" {
+			t.Fatalf("expected online charging off, got %+v", r)
+		}
+	})
+
+	t.Run("unknown data network", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "GET", url+"/api/v1/networking/data-networks/missing/online-charging", token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
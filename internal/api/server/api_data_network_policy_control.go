@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const UpdateDataNetworkPolicyControlAction = "update_data_network_policy_control"
+
+// DataNetworkPolicyControl is whether an external policy decision point
+// sets the QoS and network rules of 5G sessions on a data network, and
+// where its Npcf_SMPolicyControl API is served.
+type DataNetworkPolicyControl struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url,omitempty"`
+}
+
+func GetDataNetworkPolicyControl(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		pc, err := dbInstance.GetDataNetworkPolicyControl(r.Context(), dn.ID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeResponse(r.Context(), w, DataNetworkPolicyControl{}, http.StatusOK, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network policy control", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, DataNetworkPolicyControl{Enabled: true, URL: pc.URL}, http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateDataNetworkPolicyControl(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params DataNetworkPolicyControl
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if !params.Enabled {
+			if params.URL != "" {
+				writeError(r.Context(), w, http.StatusBadRequest, "url must be omitted when disabled", nil, logger.APILog)
+				return
+			}
+
+			if err := dbInstance.ClearDataNetworkPolicyControl(r.Context(), dn.ID); err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network policy control", err, logger.APILog)
+				return
+			}
+
+			writeResponse(r.Context(), w, SuccessResponse{Message: "Data network policy control updated successfully"}, http.StatusOK, logger.APILog)
+
+			logger.LogAuditEvent(r.Context(), UpdateDataNetworkPolicyControlAction, email, getClientIP(r), "User turned off external policy control for data network "+name)
+
+			return
+		}
+
+		u, err := url.Parse(params.URL)
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "invalid url, must be an http or https URL", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.SetDataNetworkPolicyControl(r.Context(), &db.DataNetworkPolicyControl{DataNetworkID: dn.ID, URL: params.URL}); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network policy control", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network policy control updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateDataNetworkPolicyControlAction, email, getClientIP(r), "User set data network "+name+" to take session policies from "+params.URL)
+	})
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+const policyControlDN = "work-orders"
+
+type dataNetworkPolicyControlResponse struct {
+	Result struct {
+		Enabled bool   `json:"enabled"`
+		URL     string `json:"url"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIDataNetworkPolicyControlEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: policyControlDN, IPv4Pool: "10.77.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	pcURL := url + "/api/v1/networking/data-networks/" + policyControlDN + "/policy-control"
+
+	get := func(t *testing.T) dataNetworkPolicyControlResponse {
+		t.Helper()
+
+		var resp dataNetworkPolicyControlResponse
+
+		code, err := doNATRequest(client, "GET", pcURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		return resp
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		if resp := get(t); resp.Result.Enabled {
+			t.Fatalf("unexpected policy control: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"missing url", map[string]any{"enabled": true}},
+			{"url without scheme", map[string]any{"enabled": true, "url": "pcf.example.org:8080"}},
+			{"unsupported scheme", map[string]any{"enabled": true, "url": "ftp://pcf.example.org"}},
+			{"url when disabled", map[string]any{"enabled": false, "url": "http://pcf.example.org"}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", pcURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("enable and disable", func(t *testing.T) {
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", pcURL, token, map[string]any{"enabled": true, "url": "https://pcf.example.org/"}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		if r := get(t).Result; !r.Enabled || r.URL != "https://pcf.example.org/" {
+			t.Fatalf("unexpected policy control: %+v", r)
+		}
+
+		code, err = doNATRequest(client, "PUT", pcURL, token, map[string]any{"enabled": false}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		if r := get(t).Result; r.Enabled || r.URL != "" {
+			t.Fatalf("expected policy control off, got %+v", r)
+		}
+	})
+
+	t.Run("unknown data network", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "GET", url+"/api/v1/networking/data-networks/missing/policy-control", token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const (
+	UpdateDataNetworkQuotaAction = "update_data_network_quota"
+	DeleteDataNetworkQuotaAction = "delete_data_network_quota"
+)
+
+// DataNetworkQuotaParams caps the sessions of a data network. A zero limit is
+// no limit, and a zero BackOffTimer sends the default back-off.
+type DataNetworkQuotaParams struct {
+	MaxSessions  int `json:"max_sessions"`
+	BackOffTimer int `json:"back_off_timer"`
+}
+
+// DataNetworkQuotaResponse is a data network's quota alongside the sessions
+// the cluster currently counts against it.
+type DataNetworkQuotaResponse struct {
+	MaxSessions  int `json:"max_sessions"`
+	BackOffTimer int `json:"back_off_timer"`
+	Sessions     int `json:"sessions"`
+}
+
+func GetDataNetworkQuota(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		var resp DataNetworkQuotaResponse
+
+		quota, err := dbInstance.GetDataNetworkQuota(r.Context(), dn.ID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network quota", err, logger.APILog)
+			return
+		}
+
+		if quota != nil {
+			resp.MaxSessions = quota.MaxSessions
+			resp.BackOffTimer = quota.BackOffTimer
+		}
+
+		resp.Sessions, err = dbInstance.CountAdmittedSessionsByDataNetwork(r.Context(), dn.Name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to count data network sessions", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, resp, http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateDataNetworkQuota(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params DataNetworkQuotaParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if params.MaxSessions < 0 {
+			writeError(r.Context(), w, http.StatusBadRequest, "max_sessions must not be negative", nil, logger.APILog)
+			return
+		}
+
+		if err := validateBackOffTimer(params.BackOffTimer); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		quota := &db.DataNetworkQuota{
+			DataNetworkID: dn.ID,
+			MaxSessions:   params.MaxSessions,
+			BackOffTimer:  params.BackOffTimer,
+		}
+
+		if err := dbInstance.SetDataNetworkQuota(r.Context(), quota); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network quota", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network quota updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateDataNetworkQuotaAction, email, getClientIP(r),
+			"User set the quota of data network "+name)
+	})
+}
+
+func DeleteDataNetworkQuota(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.ClearDataNetworkQuota(r.Context(), dn.ID); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete data network quota", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network quota deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteDataNetworkQuotaAction, email, getClientIP(r),
+			"User deleted the quota of data network "+name)
+	})
+}
@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const UpdateDataNetworkSecondaryAuthAction = "update_data_network_secondary_auth"
+
+// DataNetworkSecondaryAuth is whether a data network authenticates users
+// against a DN-AAA RADIUS server. Secret is write-only: it is never
+// returned, and omitting it on update keeps the current one.
+type DataNetworkSecondaryAuth struct {
+	Enabled bool   `json:"enabled"`
+	Server  string `json:"server,omitempty"`
+	Secret  string `json:"secret,omitempty"`
+}
+
+func GetDataNetworkSecondaryAuth(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		auth, err := dbInstance.GetDataNetworkSecondaryAuth(r.Context(), dn.ID)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeResponse(r.Context(), w, DataNetworkSecondaryAuth{}, http.StatusOK, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network secondary authentication", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, DataNetworkSecondaryAuth{Enabled: true, Server: auth.Server}, http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateDataNetworkSecondaryAuth(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params DataNetworkSecondaryAuth
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if !params.Enabled {
+			if params.Server != "" || params.Secret != "" {
+				writeError(r.Context(), w, http.StatusBadRequest, "server and secret must be omitted when disabled", nil, logger.APILog)
+				return
+			}
+
+			if err := dbInstance.ClearDataNetworkSecondaryAuth(r.Context(), dn.ID); err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network secondary authentication", err, logger.APILog)
+				return
+			}
+
+			writeResponse(r.Context(), w, SuccessResponse{Message: "Data network secondary authentication updated successfully"}, http.StatusOK, logger.APILog)
+
+			logger.LogAuditEvent(r.Context(), UpdateDataNetworkSecondaryAuthAction, email, getClientIP(r), "User turned off secondary authentication for data network "+name)
+
+			return
+		}
+
+		serverAddr, server, err := parseServerAddress(params.Server)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "invalid server: "+err.Error(), nil, logger.APILog)
+			return
+		}
+
+		if !serverAddr.IsGlobalUnicast() && !serverAddr.IsLoopback() {
+			writeError(r.Context(), w, http.StatusBadRequest, "invalid server, must be a unicast address", nil, logger.APILog)
+			return
+		}
+
+		secret := params.Secret
+		if secret == "" {
+			current, err := dbInstance.GetDataNetworkSecondaryAuth(r.Context(), dn.ID)
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network secondary authentication", err, logger.APILog)
+				return
+			}
+
+			if current != nil {
+				secret = current.Secret
+			}
+		}
+
+		if secret == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "secret is required", nil, logger.APILog)
+			return
+		}
+
+		row := &db.DataNetworkSecondaryAuth{DataNetworkID: dn.ID, Server: server, Secret: secret}
+
+		if err := dbInstance.SetDataNetworkSecondaryAuth(r.Context(), row); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network secondary authentication", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network secondary authentication updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateDataNetworkSecondaryAuthAction, email, getClientIP(r), "User set data network "+name+" to authenticate users against "+server)
+	})
+}
@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+const secondaryAuthDN = "corporate"
+
+type dataNetworkSecondaryAuthResponse struct {
+	Result struct {
+		Enabled bool   `json:"enabled"`
+		Server  string `json:"server"`
+		Secret  string `json:"secret"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIDataNetworkSecondaryAuthEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: secondaryAuthDN, IPv4Pool: "10.75.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	authURL := url + "/api/v1/networking/data-networks/" + secondaryAuthDN + "/secondary-authentication"
+
+	get := func(t *testing.T) dataNetworkSecondaryAuthResponse {
+		t.Helper()
+
+		var resp dataNetworkSecondaryAuthResponse
+
+		code, err := doNATRequest(client, "GET", authURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		return resp
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		if resp := get(t); resp.Result.Enabled {
+			t.Fatalf("unexpected secondary authentication: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid settings are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"missing server", map[string]any{"enabled": true, "secret": "s"}},
+			{"hostname server", map[string]any{"enabled": true, "server": "aaa.example.com", "secret": "s"}},
+			{"missing secret", map[string]any{"enabled": true, "server": "10.100.0.3"}},
+			{"settings when disabled", map[string]any{"enabled": false, "server": "10.100.0.3"}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", authURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("enable, keep secret and disable", func(t *testing.T) {
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", authURL, token, map[string]any{"enabled": true, "server": "10.100.0.3", "secret": "testing123"}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		r := get(t).Result
+		if !r.Enabled || r.Server != "10.100.0.3" || r.Secret != "" {
+			t.Fatalf("unexpected secondary authentication: %+v", r)
+		}
+
+		// The secret is kept when an update leaves it out.
+		code, err = doNATRequest(client, "PUT", authURL, token, map[string]any{"enabled": true, "server": "10.100.0.4:1812"}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		if r := get(t).Result; r.Server != "10.100.0.4:1812" {
+			t.Fatalf("unexpected secondary authentication: %+v", r)
+		}
+
+		code, err = doNATRequest(client, "PUT", authURL, token, map[string]any{"enabled": false}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		if r := get(t).Result; r.Enabled || r.Server != "" {
+			t.Fatalf("expected secondary authentication off, got %+v", r)
+		}
+	})
+
+	t.Run("unknown data network", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "GET", url+"/api/v1/networking/data-networks/missing/secondary-authentication", token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+const UpdateDataNetworkTCPMSSAction = "update_data_network_tcp_mss"
+
+// MinTCPMSS is the smallest MSS every IPv4 host must accept (RFC 879).
+const MinTCPMSS = 536
+
+// DataNetworkTCPMSS is a data network's TCP MSS clamp. A zero MSS clamps to
+// the data network's MTU less the GTP-U, IP and TCP headers.
+type DataNetworkTCPMSS struct {
+	Enabled bool `json:"enabled"`
+	MSS     int  `json:"mss,omitempty"`
+}
+
+func tcpMSSFromDB(clamp *db.DataNetworkTCPMSS) DataNetworkTCPMSS {
+	if clamp == nil {
+		return DataNetworkTCPMSS{}
+	}
+
+	return DataNetworkTCPMSS{Enabled: true, MSS: clamp.MSS}
+}
+
+func GetDataNetworkTCPMSS(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		clamp, err := dbInstance.GetDataNetworkTCPMSS(r.Context(), dn.ID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network TCP MSS clamp", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, tcpMSSFromDB(clamp), http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateDataNetworkTCPMSS(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params DataNetworkTCPMSS
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		dn, err := dbInstance.GetDataNetwork(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+			return
+		}
+
+		if !params.Enabled {
+			if params.MSS != 0 {
+				writeError(r.Context(), w, http.StatusBadRequest, "mss must be omitted when clamping is disabled", nil, logger.APILog)
+				return
+			}
+
+			if err := dbInstance.ClearDataNetworkTCPMSS(r.Context(), dn.ID); err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network TCP MSS clamp", err, logger.APILog)
+				return
+			}
+
+			writeResponse(r.Context(), w, SuccessResponse{Message: "Data network TCP MSS clamp updated successfully"}, http.StatusOK, logger.APILog)
+
+			logger.LogAuditEvent(r.Context(), UpdateDataNetworkTCPMSSAction, email, getClientIP(r), "User disabled TCP MSS clamping on data network "+name)
+
+			return
+		}
+
+		// Without the clamp maps handshakes would pass unchanged.
+		if !datapath().TCPMSSClamp {
+			writeError(r.Context(), w, http.StatusBadRequest, "TCP MSS clamping is not supported by this node's datapath", nil, logger.APILog)
+			return
+		}
+
+		// An MSS above MTU less the IP and TCP headers never clamps anything.
+		maxMSS := int(dn.MTU) - 40
+		if params.MSS != 0 && (params.MSS < MinTCPMSS || params.MSS > maxMSS) {
+			writeError(r.Context(), w, http.StatusBadRequest, fmt.Sprintf("invalid mss, must be 0 or between %d and %d", MinTCPMSS, maxMSS), nil, logger.APILog)
+			return
+		}
+
+		row := &db.DataNetworkTCPMSS{
+			DataNetworkID: dn.ID,
+			MSS:           params.MSS,
+		}
+
+		if err := dbInstance.SetDataNetworkTCPMSS(r.Context(), row); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update data network TCP MSS clamp", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Data network TCP MSS clamp updated successfully"}, http.StatusOK, logger.APILog)
+
+		detail := fmt.Sprintf("User enabled TCP MSS clamping on data network %s, derived from MTU %d", name, dn.MTU)
+		if params.MSS != 0 {
+			detail = fmt.Sprintf("User enabled TCP MSS clamping on data network %s at %d", name, params.MSS)
+		}
+
+		logger.LogAuditEvent(r.Context(), UpdateDataNetworkTCPMSSAction, email, getClientIP(r), detail)
+	})
+}
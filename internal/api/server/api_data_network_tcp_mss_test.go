@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+const tcpMSSDN = "mss-dn"
+
+type dataNetworkTCPMSSResponse struct {
+	Result struct {
+		Enabled bool `json:"enabled"`
+		MSS     int  `json:"mss"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIDataNetworkTCPMSSEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: tcpMSSDN, IPv4Pool: "10.72.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	mssURL := url + "/api/v1/networking/data-networks/" + tcpMSSDN + "/tcp-mss-clamp"
+
+	t.Run("clamping is off by default", func(t *testing.T) {
+		var resp dataNetworkTCPMSSResponse
+
+		code, err := doNATRequest(client, "GET", mssURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		if resp.Result.Enabled {
+			t.Fatalf("unexpected TCP MSS clamp: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"below the minimum", map[string]any{"enabled": true, "mss": 500}},
+			{"above the MTU", map[string]any{"enabled": true, "mss": 1400}},
+			{"mss while disabled", map[string]any{"enabled": false, "mss": 1200}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", mssURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("enable, read back and disable", func(t *testing.T) {
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", mssURL, token, map[string]any{"enabled": true, "mss": 1300}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		var resp dataNetworkTCPMSSResponse
+
+		if _, err := doNATRequest(client, "GET", mssURL, token, nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if !resp.Result.Enabled || resp.Result.MSS != 1300 {
+			t.Fatalf("unexpected TCP MSS clamp: %+v", resp.Result)
+		}
+
+		code, err = doNATRequest(client, "PUT", mssURL, token, map[string]any{"enabled": false}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		resp = dataNetworkTCPMSSResponse{}
+
+		if _, err := doNATRequest(client, "GET", mssURL, token, nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Result.Enabled {
+			t.Fatalf("expected clamping to be off, got %+v", resp.Result)
+		}
+	})
+}
+
+func TestAPIDataNetworkTCPMSSUnsupportedDatapath(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServerWithDatapath(dbPath, models.DatapathFeatures{})
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: tcpMSSDN, IPv4Pool: "10.72.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	mssURL := url + "/api/v1/networking/data-networks/" + tcpMSSDN + "/tcp-mss-clamp"
+
+	var msg messageResponse
+
+	code, err := doNATRequest(client, "PUT", mssURL, token, map[string]any{"enabled": true}, &msg)
+	if err != nil || code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for enabling clamping, got %d (%v, %s)", code, err, msg.Error)
+	}
+
+	code, err = doNATRequest(client, "PUT", mssURL, token, map[string]any{"enabled": false}, &msg)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected disabling clamping to succeed, got %d (%v, %s)", code, err, msg.Error)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/netip"
 	"regexp"
@@ -17,16 +18,27 @@ import (
 	"github.com/ellanetworks/core/internal/db"
 	"github.com/ellanetworks/core/internal/ipam"
 	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/internal/smf"
 	"go.uber.org/zap"
 )
 
+// DataNetworkEgress selects where a data network's uplink leaves the node.
+// An empty Interface means interfaces.n6. RoutingTable, when set, is the
+// kernel table that holds the routes scoped to the data network.
+type DataNetworkEgress struct {
+	Interface    string `json:"interface,omitempty"`
+	VlanID       int    `json:"vlan_id,omitempty"`
+	RoutingTable int    `json:"routing_table,omitempty"`
+}
+
 type CreateDataNetworkParams struct {
-	Name     string `json:"name"`
-	IPv4Pool string `json:"ipv4_pool"`
-	IPv6Pool string `json:"ipv6_pool,omitempty"`
-	DNS      string `json:"dns"`
-	MTU      int32  `json:"mtu"`
+	Name     string             `json:"name"`
+	IPv4Pool string             `json:"ipv4_pool"`
+	IPv6Pool string             `json:"ipv6_pool,omitempty"`
+	DNS      string             `json:"dns"`
+	MTU      int32              `json:"mtu"`
+	Egress   *DataNetworkEgress `json:"egress,omitempty"`
 }
 
 type UpdateDataNetworkParams struct {
@@ -34,6 +46,8 @@ type UpdateDataNetworkParams struct {
 	IPv6Pool string `json:"ipv6_pool,omitempty"`
 	DNS      string `json:"dns"`
 	MTU      int32  `json:"mtu"`
+	// Egress is left untouched when omitted; an empty object clears it.
+	Egress *DataNetworkEgress `json:"egress,omitempty"`
 }
 
 type DataNetworkStatus struct {
@@ -55,6 +69,7 @@ type DataNetwork struct {
 	Status         DataNetworkStatus        `json:"status"`
 	IPAllocation   *DataNetworkIPAllocation `json:"ip_allocation,omitempty"`
 	IPv6Allocation *DataNetworkIPAllocation `json:"ipv6_allocation,omitempty"`
+	Egress         *DataNetworkEgress       `json:"egress,omitempty"`
 }
 
 type IPAllocationItem struct {
@@ -112,6 +127,12 @@ func ListDataNetworks(dbInstance *db.Database, sessions smf.SessionQuerier) http
 			return
 		}
 
+		egressByID, err := dataNetworkEgressByID(ctx, dbInstance)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list data network egress", err, logger.APILog)
+			return
+		}
+
 		items := make([]DataNetwork, 0, len(dbDataNetworks))
 
 		for _, dbDataNetwork := range dbDataNetworks {
@@ -129,6 +150,7 @@ func ListDataNetworks(dbInstance *db.Database, sessions smf.SessionQuerier) http
 				Status: DataNetworkStatus{
 					Sessions: sessionCount,
 				},
+				Egress: egressByID[dbDataNetwork.ID],
 			})
 		}
 
@@ -173,6 +195,14 @@ func GetDataNetwork(dbInstance *db.Database, sessions smf.SessionQuerier) http.H
 			},
 		}
 
+		egress, err := dbInstance.GetDataNetworkEgress(r.Context(), dbDataNetwork.ID)
+		if err == nil {
+			dataNetwork.Egress = egressFromDB(egress)
+		} else if !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network egress", err, logger.APILog)
+			return
+		}
+
 		pool, poolErr := ipam.NewPool(dbDataNetwork.ID, dbDataNetwork.IPv4Pool)
 		if poolErr != nil {
 			logger.APILog.Warn("failed to parse IP pool for allocation stats", zap.String("data_network", name), zap.Error(poolErr))
@@ -367,6 +397,17 @@ func DeleteDataNetwork(dbInstance *db.Database) http.Handler {
 			return
 		}
 
+		scopedRoutes, err := listDataNetworkRoutes(r.Context(), dbInstance, name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to check routes", err, logger.APILog)
+			return
+		}
+
+		if len(scopedRoutes) > 0 {
+			writeError(r.Context(), w, http.StatusConflict, "Data Network has routes", nil, logger.APILog)
+			return
+		}
+
 		if err := dbInstance.DeleteDataNetwork(r.Context(), name); err != nil {
 			if errors.Is(err, db.ErrNotFound) {
 				writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
@@ -384,7 +425,7 @@ func DeleteDataNetwork(dbInstance *db.Database) http.Handler {
 	})
 }
 
-func CreateDataNetwork(dbInstance *db.Database) http.Handler {
+func CreateDataNetwork(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		email, ok := r.Context().Value(contextKeyEmail).(string)
 		if !ok {
@@ -403,6 +444,11 @@ func CreateDataNetwork(dbInstance *db.Database) http.Handler {
 			return
 		}
 
+		if err := validateDataNetworkEgress(r.Context(), dbInstance, datapath(), createDataNetworkParams.Egress, ""); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
 		if createDataNetworkParams.IPv4Pool != "" {
 			if err := validateNoOverlap(r.Context(), dbInstance, createDataNetworkParams.IPv4Pool, ""); err != nil {
 				writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
@@ -436,7 +482,15 @@ func CreateDataNetwork(dbInstance *db.Database) http.Handler {
 			MTU:      createDataNetworkParams.MTU,
 		}
 
-		if err := dbInstance.CreateDataNetwork(r.Context(), dbDataNetwork); err != nil {
+		// A data network without an egress override goes through the
+		// baseline op so nodes still on the baseline schema can apply it.
+		if egress := egressToDB(createDataNetworkParams.Egress); egress.IsZero() {
+			err = dbInstance.CreateDataNetwork(r.Context(), dbDataNetwork)
+		} else {
+			err = dbInstance.CreateDataNetworkWithEgress(r.Context(), dbDataNetwork, egress)
+		}
+
+		if err != nil {
 			if errors.Is(err, db.ErrAlreadyExists) {
 				writeError(r.Context(), w, http.StatusConflict, "Data Network already exists", nil, logger.APILog)
 				return
@@ -453,7 +507,7 @@ func CreateDataNetwork(dbInstance *db.Database) http.Handler {
 	})
 }
 
-func UpdateDataNetwork(dbInstance *db.Database) http.Handler {
+func UpdateDataNetwork(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		email, ok := r.Context().Value(contextKeyEmail).(string)
 		if !ok {
@@ -479,6 +533,11 @@ func UpdateDataNetwork(dbInstance *db.Database) http.Handler {
 			return
 		}
 
+		if err := validateDataNetworkEgress(r.Context(), dbInstance, datapath(), updateDataNetworkParams.Egress, name); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
 		if updateDataNetworkParams.IPv4Pool != "" {
 			if err := validateNoOverlap(r.Context(), dbInstance, updateDataNetworkParams.IPv4Pool, name); err != nil {
 				writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
@@ -501,7 +560,15 @@ func UpdateDataNetwork(dbInstance *db.Database) http.Handler {
 			MTU:      updateDataNetworkParams.MTU,
 		}
 
-		if err := dbInstance.UpdateDataNetwork(r.Context(), dn); err != nil {
+		var err error
+
+		if updateDataNetworkParams.Egress == nil {
+			err = dbInstance.UpdateDataNetwork(r.Context(), dn)
+		} else {
+			err = dbInstance.UpdateDataNetworkWithEgress(r.Context(), dn, egressToDB(updateDataNetworkParams.Egress))
+		}
+
+		if err != nil {
 			if errors.Is(err, db.ErrNotFound) {
 				writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
 				return
@@ -600,6 +667,108 @@ func validateUpdateDataNetworkParams(p UpdateDataNetworkParams) error {
 	return nil
 }
 
+// linuxIfNameMaxLen is IFNAMSIZ minus the trailing NUL.
+const linuxIfNameMaxLen = 15
+
+func isInterfaceNameValid(name string) bool {
+	if name == "" || len(name) > linuxIfNameMaxLen || name == "." || name == ".." {
+		return false
+	}
+
+	return !strings.ContainsAny(name, "/: \t\n")
+}
+
+// isRoutingTableValid rejects the kernel's reserved tables (default, main,
+// local); 0 means "no table of its own".
+func isRoutingTableValid(table int) bool {
+	return table >= 0 && table <= math.MaxUint32 && table != 253 && table != 254 && table != 255
+}
+
+func validateDataNetworkEgress(ctx context.Context, dbInstance *db.Database, features models.DatapathFeatures, egress *DataNetworkEgress, excludeName string) error {
+	if egress == nil {
+		return nil
+	}
+
+	// Stored anyway, the data network's traffic would silently keep
+	// leaving through n6.
+	if !egressToDB(egress).IsZero() && !features.DataNetworkEgress {
+		return errors.New("egress is not supported by this node's datapath")
+	}
+
+	switch {
+	case egress.Interface != "" && !isInterfaceNameValid(egress.Interface):
+		return errors.New("invalid egress.interface, must be a valid interface name")
+	case egress.VlanID < 0 || egress.VlanID > 4094:
+		return errors.New("invalid egress.vlan_id, must be an integer between 0 and 4094")
+	case !isRoutingTableValid(egress.RoutingTable):
+		return errors.New("invalid egress.routing_table, must be between 1 and 4294967295 and not 253, 254 or 255")
+	}
+
+	if egress.RoutingTable == 0 {
+		return nil
+	}
+
+	dataNetworks, err := dbInstance.ListAllDataNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list data networks: %w", err)
+	}
+
+	egressByID, err := dataNetworkEgressByID(ctx, dbInstance)
+	if err != nil {
+		return fmt.Errorf("failed to list data network egress: %w", err)
+	}
+
+	for _, dn := range dataNetworks {
+		if dn.Name == excludeName {
+			continue
+		}
+
+		if other, ok := egressByID[dn.ID]; ok && other.RoutingTable == egress.RoutingTable {
+			return fmt.Errorf("routing table %d is already used by data network %q", egress.RoutingTable, dn.Name)
+		}
+	}
+
+	return nil
+}
+
+func egressFromDB(egress *db.DataNetworkEgress) *DataNetworkEgress {
+	if egress.IsZero() {
+		return nil
+	}
+
+	return &DataNetworkEgress{
+		Interface:    egress.InterfaceName,
+		VlanID:       egress.VlanID,
+		RoutingTable: egress.RoutingTable,
+	}
+}
+
+func egressToDB(egress *DataNetworkEgress) *db.DataNetworkEgress {
+	if egress == nil {
+		return nil
+	}
+
+	return &db.DataNetworkEgress{
+		InterfaceName: egress.Interface,
+		VlanID:        egress.VlanID,
+		RoutingTable:  egress.RoutingTable,
+	}
+}
+
+func dataNetworkEgressByID(ctx context.Context, dbInstance *db.Database) (map[string]*DataNetworkEgress, error) {
+	rows, err := dbInstance.ListAllDataNetworkEgress(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*DataNetworkEgress, len(rows))
+	for i := range rows {
+		byID[rows[i].DataNetworkID] = egressFromDB(&rows[i])
+	}
+
+	return byID, nil
+}
+
 func validateNoOverlap(ctx context.Context, dbInstance *db.Database, cidr string, excludeName string) error {
 	newPrefix, err := netip.ParsePrefix(cidr)
 	if err != nil {
@@ -12,6 +12,8 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
 )
 
 const (
@@ -31,6 +33,12 @@ type DataNetworkIPAllocation struct {
 	Available int `json:"available"`
 }
 
+type DataNetworkEgress struct {
+	Interface    string `json:"interface,omitempty"`
+	VlanID       int    `json:"vlan_id,omitempty"`
+	RoutingTable int    `json:"routing_table,omitempty"`
+}
+
 type DataNetwork struct {
 	Name           string                   `json:"name"`
 	IPv4Pool       string                   `json:"ipv4_pool,omitempty"`
@@ -39,6 +47,7 @@ type DataNetwork struct {
 	MTU            int32                    `json:"mtu,omitempty"`
 	IPAllocation   *DataNetworkIPAllocation `json:"ip_allocation,omitempty"`
 	IPv6Allocation *DataNetworkIPAllocation `json:"ipv6_allocation,omitempty"`
+	Egress         *DataNetworkEgress       `json:"egress,omitempty"`
 }
 
 type GetDataNetworkResponse struct {
@@ -47,11 +56,12 @@ type GetDataNetworkResponse struct {
 }
 
 type CreateDataNetworkParams struct {
-	Name     string `json:"name"`
-	IPv4Pool string `json:"ipv4_pool,omitempty"`
-	IPv6Pool string `json:"ipv6_pool,omitempty"`
-	DNS      string `json:"dns,omitempty"`
-	MTU      int32  `json:"mtu,omitempty"`
+	Name     string             `json:"name"`
+	IPv4Pool string             `json:"ipv4_pool,omitempty"`
+	IPv6Pool string             `json:"ipv6_pool,omitempty"`
+	DNS      string             `json:"dns,omitempty"`
+	MTU      int32              `json:"mtu,omitempty"`
+	Egress   *DataNetworkEgress `json:"egress,omitempty"`
 }
 
 type CreateDataNetworkResponse struct {
@@ -60,10 +70,11 @@ type CreateDataNetworkResponse struct {
 }
 
 type UpdateDataNetworkParams struct {
-	IPv4Pool string `json:"ipv4_pool,omitempty"`
-	IPv6Pool string `json:"ipv6_pool,omitempty"`
-	DNS      string `json:"dns,omitempty"`
-	MTU      int32  `json:"mtu,omitempty"`
+	IPv4Pool string             `json:"ipv4_pool,omitempty"`
+	IPv6Pool string             `json:"ipv6_pool,omitempty"`
+	DNS      string             `json:"dns,omitempty"`
+	MTU      int32              `json:"mtu,omitempty"`
+	Egress   *DataNetworkEgress `json:"egress,omitempty"`
 }
 
 type DeleteDataNetworkResponseResult struct {
@@ -1439,3 +1450,186 @@ func TestUpdateDataNetworkIPv6PoolOverlap(t *testing.T) {
 		}
 	})
 }
+
+func TestDataNetworkEgress(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	egress := &DataNetworkEgress{Interface: "eth2", VlanID: 100, RoutingTable: 100}
+
+	statusCode, response, err := createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: "enterprise", IPv4Pool: "10.70.0.0/24", DNS: DNS, MTU: MTU, Egress: egress,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if statusCode != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, statusCode, response.Error)
+	}
+
+	t.Run("get returns egress", func(t *testing.T) {
+		_, getResponse, err := getDataNetwork(env.Server.URL, client, token, "enterprise")
+		if err != nil {
+			t.Fatalf("couldn't get data network: %s", err)
+		}
+
+		if getResponse.Result.Egress == nil || *getResponse.Result.Egress != *egress {
+			t.Fatalf("expected egress %+v, got %+v", egress, getResponse.Result.Egress)
+		}
+	})
+
+	t.Run("list returns egress", func(t *testing.T) {
+		_, listResponse, err := listDataNetworks(env.Server.URL, client, token)
+		if err != nil {
+			t.Fatalf("couldn't list data networks: %s", err)
+		}
+
+		for _, dn := range listResponse.Result.Items {
+			if dn.Name == "enterprise" && (dn.Egress == nil || *dn.Egress != *egress) {
+				t.Fatalf("expected egress %+v, got %+v", egress, dn.Egress)
+			}
+		}
+	})
+
+	t.Run("routing table must be unique", func(t *testing.T) {
+		statusCode, response, err := createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+			Name: "other", IPv4Pool: "10.71.0.0/24", DNS: DNS, MTU: MTU, Egress: &DataNetworkEgress{RoutingTable: 100},
+		})
+		if err != nil {
+			t.Fatalf("couldn't create data network: %s", err)
+		}
+
+		if statusCode != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, statusCode)
+		}
+
+		if !strings.Contains(response.Error, "routing table 100 is already used") {
+			t.Fatalf("unexpected error: %s", response.Error)
+		}
+	})
+
+	t.Run("invalid egress", func(t *testing.T) {
+		for _, e := range []*DataNetworkEgress{
+			{VlanID: 4095},
+			{RoutingTable: 254},
+			{Interface: "an-interface-name-too-long"},
+			{Interface: "eth/0"},
+		} {
+			statusCode, _, err := createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+				Name: "invalid", IPv4Pool: "10.72.0.0/24", DNS: DNS, MTU: MTU, Egress: e,
+			})
+			if err != nil {
+				t.Fatalf("couldn't create data network: %s", err)
+			}
+
+			if statusCode != http.StatusBadRequest {
+				t.Fatalf("expected status %d for %+v, got %d", http.StatusBadRequest, e, statusCode)
+			}
+		}
+	})
+
+	t.Run("update without egress keeps it", func(t *testing.T) {
+		statusCode, _, err := editDataNetwork(env.Server.URL, client, "enterprise", token, &UpdateDataNetworkParams{
+			IPv4Pool: "10.70.0.0/24", DNS: DNS, MTU: 1400,
+		})
+		if err != nil || statusCode != http.StatusOK {
+			t.Fatalf("couldn't update data network: %d %v", statusCode, err)
+		}
+
+		_, getResponse, err := getDataNetwork(env.Server.URL, client, token, "enterprise")
+		if err != nil {
+			t.Fatalf("couldn't get data network: %s", err)
+		}
+
+		if getResponse.Result.Egress == nil || *getResponse.Result.Egress != *egress {
+			t.Fatalf("expected egress to be kept, got %+v", getResponse.Result.Egress)
+		}
+	})
+
+	t.Run("update with empty egress clears it", func(t *testing.T) {
+		statusCode, _, err := editDataNetwork(env.Server.URL, client, "enterprise", token, &UpdateDataNetworkParams{
+			IPv4Pool: "10.70.0.0/24", DNS: DNS, MTU: 1400, Egress: &DataNetworkEgress{},
+		})
+		if err != nil || statusCode != http.StatusOK {
+			t.Fatalf("couldn't update data network: %d %v", statusCode, err)
+		}
+
+		_, getResponse, err := getDataNetwork(env.Server.URL, client, token, "enterprise")
+		if err != nil {
+			t.Fatalf("couldn't get data network: %s", err)
+		}
+
+		if getResponse.Result.Egress != nil {
+			t.Fatalf("expected egress to be cleared, got %+v", getResponse.Result.Egress)
+		}
+	})
+}
+
+func TestDataNetworkEgressUnsupportedDatapath(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServerWithDatapath(dbPath, models.DatapathFeatures{})
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	statusCode, response, err := createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: "enterprise", IPv4Pool: "10.70.0.0/24", DNS: DNS, MTU: MTU, Egress: &DataNetworkEgress{Interface: "eth2"},
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if statusCode != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, statusCode)
+	}
+
+	if !strings.Contains(response.Error, "not supported by this node's datapath") {
+		t.Fatalf("unexpected error: %s", response.Error)
+	}
+
+	statusCode, response, err = createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: "enterprise", IPv4Pool: "10.70.0.0/24", DNS: DNS, MTU: MTU, Egress: &DataNetworkEgress{},
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if statusCode != http.StatusCreated {
+		t.Fatalf("expected an empty egress to be accepted, got %d (error: %s)", statusCode, response.Error)
+	}
+
+	statusCode, _, err = editDataNetwork(env.Server.URL, client, "enterprise", token, &UpdateDataNetworkParams{
+		IPv4Pool: "10.70.0.0/24", DNS: DNS, MTU: MTU, Egress: &DataNetworkEgress{RoutingTable: 100},
+	})
+	if err != nil {
+		t.Fatalf("couldn't update data network: %s", err)
+	}
+
+	if statusCode != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, statusCode)
+	}
+}
@@ -72,6 +72,34 @@ func (fk FakeKernel) EnsureGatewaysOnInterfaceInNeighTable(ifKey kernel.NetworkI
 	return nil
 }
 
+func (fk FakeKernel) ResolveEgressDevice(name string, vlanID int) (string, error) {
+	return name, nil
+}
+
+func (fk FakeKernel) ReplaceTableRoute(route kernel.TableRoute) error {
+	return nil
+}
+
+func (fk FakeKernel) DeleteTableRoute(route kernel.TableRoute) error {
+	return nil
+}
+
+func (fk FakeKernel) ListManagedTableRoutes() ([]kernel.TableRoute, error) {
+	return nil, nil
+}
+
+func (fk FakeKernel) AddSourceRule(rule kernel.SourceRule) error {
+	return nil
+}
+
+func (fk FakeKernel) DeleteSourceRule(rule kernel.SourceRule) error {
+	return nil
+}
+
+func (fk FakeKernel) ListManagedSourceRules() ([]kernel.SourceRule, error) {
+	return nil, nil
+}
+
 type dummyFS struct{}
 
 func (dummyFS) Open(name string) (fs.File, error) {
@@ -94,7 +122,18 @@ func setupServer(filepath string) (testEnv, error) {
 		return testEnv{}, err
 	}
 
-	return buildTestEnv(testdb)
+	return buildTestEnv(testdb, nil)
+}
+
+// setupServerWithDatapath is setupServer on a node whose datapath carries
+// only the given optional features.
+func setupServerWithDatapath(filepath string, features models.DatapathFeatures) (testEnv, error) {
+	testdb, err := db.NewDatabaseWithoutRaft(context.Background(), filepath)
+	if err != nil {
+		return testEnv{}, err
+	}
+
+	return buildTestEnv(testdb, func() models.DatapathFeatures { return features })
 }
 
 // setupServerWithRaft is the slow path for tests that exercise cluster /
@@ -105,10 +144,10 @@ func setupServerWithRaft(filepath string) (testEnv, error) {
 		return testEnv{}, err
 	}
 
-	return buildTestEnv(testdb)
+	return buildTestEnv(testdb, nil)
 }
 
-func buildTestEnv(testdb *db.Database) (testEnv, error) {
+func buildTestEnv(testdb *db.Database, datapath func() models.DatapathFeatures) (testEnv, error) {
 	logger.SetDb(testdb)
 
 	// Initialize SMF context with test stubs
@@ -118,6 +157,7 @@ func buildTestEnv(testdb *db.Database) (testEnv, error) {
 	dummyfs := dummyFS{}
 
 	cfg := config.Config{
+		DB: config.DB{Path: testdb.Path()},
 		Interfaces: config.Interfaces{
 			N2: config.N2Interface{
 				Address: "12.12.12.12",
@@ -138,15 +178,16 @@ func buildTestEnv(testdb *db.Database) (testEnv, error) {
 	amfInstance := amf.New(testdb, nil, smfInstance)
 	lmfInstance := lmf.New(amfInstance, nil, nil)
 	ts := httptest.NewTLSServer(server.NewHandler(server.HandlerConfig{
-		DB:           testdb,
-		Config:       cfg,
-		JWTSecret:    jwtSecret,
-		SecureCookie: false,
-		FrontendFS:   dummyfs,
-		Sessions:     smfInstance,
-		AMF:          amfInstance,
-		LMF:          lmfInstance,
-		BcryptCost:   bcrypt.MinCost,
+		DB:               testdb,
+		Config:           cfg,
+		JWTSecret:        jwtSecret,
+		SecureCookie:     false,
+		FrontendFS:       dummyfs,
+		Sessions:         smfInstance,
+		AMF:              amfInstance,
+		LMF:              lmfInstance,
+		BcryptCost:       bcrypt.MinCost,
+		DatapathFeatures: datapath,
 	}))
 
 	supportbundle.ConfigProvider = func(ctx context.Context) ([]byte, error) {
@@ -252,6 +293,10 @@ func (f *fakeSessionStore) AllocateIP(_ context.Context, _ string, _ uint8) (net
 	return netip.Addr{}, fmt.Errorf("not implemented in test")
 }
 
+func (f *fakeSessionStore) ReserveIP(_ context.Context, _ string, _ uint8, _ netip.Addr) (netip.Addr, error) {
+	return netip.Addr{}, fmt.Errorf("not implemented in test")
+}
+
 func (f *fakeSessionStore) ReleaseIP(_ context.Context, _ string, _ uint8) (netip.Addr, error) {
 	return netip.Addr{}, fmt.Errorf("not implemented in test")
 }
@@ -268,6 +313,10 @@ func (f *fakeSessionStore) IncrementDailyUsage(_ context.Context, _ string, _, _
 	return nil
 }
 
+func (f *fakeSessionStore) IncrementRatingGroupUsage(_ context.Context, _ string, _ models.RatingGroupUsage) error {
+	return nil
+}
+
 func (f *fakeSessionStore) InsertFlowReports(_ context.Context, _ []*models.FlowReportRequest) error {
 	return nil
 }
@@ -296,6 +345,8 @@ func (f *fakeUPFClient) DeleteSession(ctx context.Context, remoteSEID uint64) er
 
 func (f *fakeUPFClient) FlushUsage(ctx context.Context, remoteSEID uint64) {}
 
+func (f *fakeUPFClient) SetUsageThreshold(ctx context.Context, remoteSEID uint64, bytes uint64) {}
+
 func (f *fakeUPFClient) SuppressDownlinkDataNotification(ctx context.Context, remoteSEID uint64) {}
 
 func (f *fakeUPFClient) ClearDownlinkDataNotification(ctx context.Context, remoteSEID uint64) {}
@@ -308,7 +359,7 @@ func (f *fakeUPFClient) RegisterIPv6Session(ctx context.Context, reg *models.IPv
 	return nil
 }
 
-func (f *fakeUPFClient) UnregisterIPv6Session(ctx context.Context, ulTEID uint32) error {
+func (f *fakeUPFClient) UnregisterIPv6Session(ctx context.Context, seid uint64, ulTEID uint32) error {
 	return nil
 }
 
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/monitoringevent"
+	"github.com/google/uuid"
+)
+
+const (
+	CreateMonitoringSubscriptionAction = "create_monitoring_subscription"
+	DeleteMonitoringSubscriptionAction = "delete_monitoring_subscription"
+)
+
+const (
+	// MinMonitoringSubscriptionDuration and
+	// MaxMonitoringSubscriptionDuration bound, in seconds, how long a
+	// subscription given a duration may last.
+	MinMonitoringSubscriptionDuration = 60
+	MaxMonitoringSubscriptionDuration = 30 * 24 * 60 * 60
+)
+
+// CreateMonitoringSubscriptionParams subscribes to an event of the UE of
+// subscriber IMSI, reported to NotificationURL. A zero MaxReports reports
+// until the subscription is deleted or expires; a zero Duration never
+// expires it.
+type CreateMonitoringSubscriptionParams struct {
+	IMSI            string `json:"imsi"`
+	Event           string `json:"event"`
+	NotificationURL string `json:"notification_url"`
+	MaxReports      int    `json:"max_reports,omitempty"`
+	Duration        int64  `json:"duration,omitempty"`
+}
+
+type MonitoringSubscriptionResponse struct {
+	ID              string `json:"id"`
+	IMSI            string `json:"imsi"`
+	Event           string `json:"event"`
+	NotificationURL string `json:"notification_url"`
+	MaxReports      int    `json:"max_reports,omitempty"`
+	Reports         int    `json:"reports"`
+	CreatedAt       string `json:"created_at"`
+	ExpiresAt       string `json:"expires_at,omitempty"`
+}
+
+type ListMonitoringSubscriptionsResponse struct {
+	Items []MonitoringSubscriptionResponse `json:"items"`
+}
+
+func monitoringSubscriptionFromDB(sub *db.MonitoringSubscription) MonitoringSubscriptionResponse {
+	resp := MonitoringSubscriptionResponse{
+		ID:              sub.ID,
+		IMSI:            sub.IMSI,
+		Event:           sub.Event,
+		NotificationURL: sub.NotificationURL,
+		MaxReports:      sub.MaxReports,
+		Reports:         sub.Reports,
+		CreatedAt:       time.Unix(sub.CreatedAt, 0).UTC().Format(time.RFC3339),
+	}
+
+	if sub.ExpiresAt > 0 {
+		resp.ExpiresAt = time.Unix(sub.ExpiresAt, 0).UTC().Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+func ListMonitoringSubscriptions(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rows, err := dbInstance.ListMonitoringSubscriptions(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list monitoring subscriptions", err, logger.APILog)
+			return
+		}
+
+		items := make([]MonitoringSubscriptionResponse, 0, len(rows))
+		for i := range rows {
+			items = append(items, monitoringSubscriptionFromDB(&rows[i]))
+		}
+
+		writeResponse(r.Context(), w, ListMonitoringSubscriptionsResponse{Items: items}, http.StatusOK, logger.APILog)
+	})
+}
+
+func GetMonitoringSubscription(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing id parameter", nil, logger.APILog)
+			return
+		}
+
+		sub, err := dbInstance.GetMonitoringSubscription(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Monitoring subscription not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve monitoring subscription", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, monitoringSubscriptionFromDB(sub), http.StatusOK, logger.APILog)
+	})
+}
+
+func CreateMonitoringSubscription(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		var params CreateMonitoringSubscriptionParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if err := validateMonitoringSubscription(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		if _, err := dbInstance.GetSubscriber(r.Context(), params.IMSI); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Subscriber not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get subscriber", err, logger.APILog)
+
+			return
+		}
+
+		existing, err := dbInstance.ListMonitoringSubscriptions(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create monitoring subscription", err, logger.APILog)
+			return
+		}
+
+		if len(existing) >= db.MaxMonitoringSubscriptions {
+			writeError(r.Context(), w, http.StatusBadRequest,
+				fmt.Sprintf("Maximum number of monitoring subscriptions (%d) reached", db.MaxMonitoringSubscriptions), nil, logger.APILog)
+
+			return
+		}
+
+		id, err := uuid.NewV7()
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to generate monitoring subscription id", err, logger.APILog)
+			return
+		}
+
+		now := time.Now().Unix()
+		sub := &db.MonitoringSubscription{
+			ID:              id.String(),
+			IMSI:            params.IMSI,
+			Event:           params.Event,
+			NotificationURL: params.NotificationURL,
+			MaxReports:      params.MaxReports,
+			CreatedAt:       now,
+		}
+
+		if params.Duration > 0 {
+			sub.ExpiresAt = now + params.Duration
+		}
+
+		if err := dbInstance.CreateMonitoringSubscription(r.Context(), sub); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create monitoring subscription", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, monitoringSubscriptionFromDB(sub), http.StatusCreated, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), CreateMonitoringSubscriptionAction, email, getClientIP(r),
+			fmt.Sprintf("User subscribed to %s of subscriber %s as %s", sub.Event, sub.IMSI, sub.ID))
+	})
+}
+
+func DeleteMonitoringSubscription(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		id := r.PathValue("id")
+		if id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing id parameter", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeleteMonitoringSubscription(r.Context(), id); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Monitoring subscription not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete monitoring subscription", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Monitoring subscription deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteMonitoringSubscriptionAction, email, getClientIP(r), "User deleted monitoring subscription "+id)
+	})
+}
+
+func validateMonitoringSubscription(p *CreateMonitoringSubscriptionParams) error {
+	if p.IMSI == "" {
+		return errors.New("imsi is missing")
+	}
+
+	if !slices.Contains(monitoringevent.Events, p.Event) {
+		return fmt.Errorf("invalid event - must be one of %v", monitoringevent.Events)
+	}
+
+	u, err := url.Parse(p.NotificationURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return errors.New("invalid notification_url, must be an http or https URL")
+	}
+
+	if p.MaxReports < 0 {
+		return errors.New("max_reports must not be negative")
+	}
+
+	if p.Duration != 0 && (p.Duration < MinMonitoringSubscriptionDuration || p.Duration > MaxMonitoringSubscriptionDuration) {
+		return fmt.Errorf("duration must be between %d and %d seconds", MinMonitoringSubscriptionDuration, MaxMonitoringSubscriptionDuration)
+	}
+
+	return nil
+}
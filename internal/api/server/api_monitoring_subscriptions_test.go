@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+type monitoringSubscription struct {
+	ID              string `json:"id"`
+	IMSI            string `json:"imsi"`
+	Event           string `json:"event"`
+	NotificationURL string `json:"notification_url"`
+	MaxReports      int    `json:"max_reports"`
+	Reports         int    `json:"reports"`
+	CreatedAt       string `json:"created_at"`
+	ExpiresAt       string `json:"expires_at"`
+}
+
+type monitoringSubscriptionResponse struct {
+	Result monitoringSubscription `json:"result"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+type listMonitoringSubscriptionsResponse struct {
+	Result struct {
+		Items []monitoringSubscription `json:"items"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func validMonitoringSubscriptionBody() map[string]any {
+	return map[string]any{
+		"imsi":             Imsi,
+		"event":            "UE_REACHABILITY",
+		"notification_url": "https://app.example.com/ue-events",
+		"max_reports":      1,
+		"duration":         3600,
+	}
+}
+
+func TestAPIMonitoringSubscriptionsEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	code, _, err := createProfile(url, client, token, &CreateProfileParams{Name: TestProfileName, UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps"})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create profile: %d (%v)", code, err)
+	}
+
+	code, _, err = createPolicy(url, client, token, &CreatePolicyParams{
+		Name:                PolicyName,
+		ProfileName:         TestProfileName,
+		SliceName:           DefaultSliceName,
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "100 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkName:     "internet",
+	})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create policy: %d (%v)", code, err)
+	}
+
+	code, resp, err := createSubscriber(url, client, token, &CreateSubscriberParams{Imsi: Imsi, Key: Key, Opc: Opc, SequenceNumber: SequenceNumber, ProfileName: TestProfileName})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create subscriber: %d (%v, %s)", code, err, resp.Error)
+	}
+
+	subsURL := url + "/api/v1/monitoring-subscriptions"
+
+	var created monitoringSubscriptionResponse
+
+	t.Run("create", func(t *testing.T) {
+		code, err := doNATRequest(client, "POST", subsURL, token, validMonitoringSubscriptionBody(), &created)
+		if err != nil || code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d (%v, %s)", code, err, created.Error)
+		}
+
+		r := created.Result
+		if r.ID == "" || r.IMSI != Imsi || r.Event != "UE_REACHABILITY" || r.MaxReports != 1 || r.Reports != 0 || r.ExpiresAt <= r.CreatedAt {
+			t.Fatalf("unexpected subscription: %+v", r)
+		}
+	})
+
+	t.Run("list and get", func(t *testing.T) {
+		var list listMonitoringSubscriptionsResponse
+
+		code, err := doNATRequest(client, "GET", subsURL, token, nil, &list)
+		if err != nil || code != http.StatusOK || len(list.Result.Items) != 1 || list.Result.Items[0] != created.Result {
+			t.Fatalf("expected the created subscription, got %d (%v, %+v)", code, err, list.Result.Items)
+		}
+
+		var one monitoringSubscriptionResponse
+
+		code, err = doNATRequest(client, "GET", subsURL+"/"+created.Result.ID, token, nil, &one)
+		if err != nil || code != http.StatusOK || one.Result != created.Result {
+			t.Fatalf("expected the created subscription, got %d (%v, %+v)", code, err, one.Result)
+		}
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		cases := []struct {
+			name  string
+			field string
+			value any
+		}{
+			{"no IMSI", "imsi", nil},
+			{"unknown event", "event", "BATTERY_LOW"},
+			{"notification URL not http", "notification_url", "ftp://app.example.com"},
+			{"negative max reports", "max_reports", -1},
+			{"duration too short", "duration", 10},
+		}
+
+		for _, tc := range cases {
+			body := validMonitoringSubscriptionBody()
+			if tc.value == nil {
+				delete(body, tc.field)
+			} else {
+				body[tc.field] = tc.value
+			}
+
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "POST", subsURL, token, body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("unknown subscriber", func(t *testing.T) {
+		body := validMonitoringSubscriptionBody()
+		body["imsi"] = "001019999999999"
+
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "POST", subsURL, token, body, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "DELETE", subsURL+"/"+created.Result.ID, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		code, err = doNATRequest(client, "GET", subsURL+"/"+created.Result.ID, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		code, err = doNATRequest(client, "DELETE", subsURL+"/"+created.Result.ID, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
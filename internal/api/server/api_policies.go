@@ -16,12 +16,18 @@ import (
 
 	"github.com/ellanetworks/core/internal/db"
 	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
 )
 
 const (
 	MaxNumNetworkRulesPerDirection = 12
 	DirectionUplink                = "uplink"
 	DirectionDownlink              = "downlink"
+
+	// Rate-limit scopes: a bucket per session, or one per rule shared by
+	// every session of the policy.
+	RateLimitScopeSession = "session"
+	RateLimitScopeRule    = "rule"
 )
 
 type PolicyRule struct {
@@ -31,6 +37,32 @@ type PolicyRule struct {
 	PortLow      int32   `json:"port_low"`
 	PortHigh     int32   `json:"port_high"`
 	Action       string  `json:"action"`
+	FQDN         string  `json:"fqdn,omitempty"`
+	MatchSNI     bool    `json:"match_sni,omitempty"`
+	// RateLimit is the bitrate a "rate_limit" rule polices matching traffic
+	// to, per RateLimitScope.
+	RateLimit      string `json:"rate_limit,omitempty"`
+	RateLimitScope string `json:"rate_limit_scope,omitempty"`
+	// Schedule names a schedule outside whose windows the rule is not
+	// installed.
+	Schedule string `json:"schedule,omitempty"`
+	// Breakout is where an uplink "breakout" rule's traffic leaves the node
+	// instead of the data network's egress.
+	Breakout *RuleBreakout `json:"breakout,omitempty"`
+	// RatingGroup, when set, counts the traffic the rule passes apart in
+	// the subscriber's usage. ZeroRated keeps it out of the subscriber's
+	// total.
+	RatingGroup uint32 `json:"rating_group,omitempty"`
+	ZeroRated   bool   `json:"zero_rated,omitempty"`
+}
+
+// RuleBreakout is a local egress beside the data network's, such as the
+// interface facing an edge compute subnet. RoutingTable, when set, is the
+// kernel table the destination is looked up in.
+type RuleBreakout struct {
+	Interface    string `json:"interface"`
+	VlanID       int    `json:"vlan_id,omitempty"`
+	RoutingTable int    `json:"routing_table,omitempty"`
 }
 
 type PolicyRules struct {
@@ -38,7 +70,16 @@ type PolicyRules struct {
 	Downlink []PolicyRule `json:"downlink,omitempty"`
 }
 
-func toDBPolicyRules(rules *PolicyRules) *db.PolicyRulesInput {
+// ScheduledAmbr replaces a policy's Session-AMBR while Schedule is active.
+type ScheduledAmbr struct {
+	Schedule            string `json:"schedule"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
+}
+
+// toDBPolicyRules converts rules, resolving schedule names through
+// scheduleIDs.
+func toDBPolicyRules(rules *PolicyRules, scheduleIDs map[string]string) *db.PolicyRulesInput {
 	if rules == nil {
 		return nil
 	}
@@ -46,14 +87,29 @@ func toDBPolicyRules(rules *PolicyRules) *db.PolicyRulesInput {
 	convert := func(in []PolicyRule) []db.PolicyRuleInput {
 		out := make([]db.PolicyRuleInput, 0, len(in))
 		for _, rule := range in {
-			out = append(out, db.PolicyRuleInput{
-				Description:  rule.Description,
-				RemotePrefix: rule.RemotePrefix,
-				Protocol:     rule.Protocol,
-				PortLow:      rule.PortLow,
-				PortHigh:     rule.PortHigh,
-				Action:       rule.Action,
-			})
+			in := db.PolicyRuleInput{
+				Description:     rule.Description,
+				RemotePrefix:    rule.RemotePrefix,
+				Protocol:        rule.Protocol,
+				PortLow:         rule.PortLow,
+				PortHigh:        rule.PortHigh,
+				Action:          rule.Action,
+				FQDN:            strings.TrimSuffix(strings.ToLower(rule.FQDN), "."),
+				MatchSNI:        rule.MatchSNI,
+				RateLimit:       rule.RateLimit,
+				RateLimitShared: rule.RateLimitScope == RateLimitScopeRule,
+				ScheduleID:      scheduleIDs[rule.Schedule],
+				RatingGroup:     rule.RatingGroup,
+				ZeroRated:       rule.ZeroRated,
+			}
+
+			if rule.Breakout != nil {
+				in.BreakoutInterface = rule.Breakout.Interface
+				in.BreakoutVlanID = rule.Breakout.VlanID
+				in.BreakoutRoutingTable = rule.Breakout.RoutingTable
+			}
+
+			out = append(out, in)
 		}
 
 		return out
@@ -65,42 +121,92 @@ func toDBPolicyRules(rules *PolicyRules) *db.PolicyRulesInput {
 	}
 }
 
+func toDBPolicySchedule(scheduled *ScheduledAmbr, scheduleIDs map[string]string) *db.PolicySchedule {
+	if scheduled == nil {
+		return nil
+	}
+
+	return &db.PolicySchedule{
+		ScheduleID:          scheduleIDs[scheduled.Schedule],
+		SessionAmbrUplink:   scheduled.SessionAmbrUplink,
+		SessionAmbrDownlink: scheduled.SessionAmbrDownlink,
+	}
+}
+
+// policyScheduleIDs maps the schedule names rules and scheduled refer to
+// onto their IDs, failing on the first name that does not exist.
+func policyScheduleIDs(ctx context.Context, dbInstance *db.Database, rules *PolicyRules, scheduled *ScheduledAmbr) (map[string]string, error) {
+	var names []string
+
+	if rules != nil {
+		for _, rule := range slices.Concat(rules.Uplink, rules.Downlink) {
+			if rule.Schedule != "" {
+				names = append(names, rule.Schedule)
+			}
+		}
+	}
+
+	if scheduled != nil {
+		names = append(names, scheduled.Schedule)
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	ids, err := scheduleIDsByName(ctx, dbInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if _, ok := ids[name]; !ok {
+			return nil, fmt.Errorf("%w: %q", errUnknownSchedule, name)
+		}
+	}
+
+	return ids, nil
+}
+
 type CreatePolicyParams struct {
-	Name                string       `json:"name"`
-	ProfileName         string       `json:"profile_name"`
-	SliceName           string       `json:"slice_name"`
-	DataNetworkName     string       `json:"data_network_name"`
-	SessionAmbrUplink   string       `json:"session_ambr_uplink"`
-	SessionAmbrDownlink string       `json:"session_ambr_downlink"`
-	Var5qi              int32        `json:"var5qi"`
-	Arp                 int32        `json:"arp"`
-	Rules               *PolicyRules `json:"rules,omitempty"`
-	Default             *bool        `json:"default,omitempty"`
+	Name                string         `json:"name"`
+	ProfileName         string         `json:"profile_name"`
+	SliceName           string         `json:"slice_name"`
+	DataNetworkName     string         `json:"data_network_name"`
+	SessionAmbrUplink   string         `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string         `json:"session_ambr_downlink"`
+	Var5qi              int32          `json:"var5qi"`
+	Arp                 int32          `json:"arp"`
+	Rules               *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr       *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
+	Default             *bool          `json:"default,omitempty"`
 }
 
 type UpdatePolicyParams struct {
-	ProfileName         string       `json:"profile_name"`
-	SliceName           string       `json:"slice_name"`
-	DataNetworkName     string       `json:"data_network_name"`
-	SessionAmbrUplink   string       `json:"session_ambr_uplink"`
-	SessionAmbrDownlink string       `json:"session_ambr_downlink"`
-	Var5qi              int32        `json:"var5qi"`
-	Arp                 int32        `json:"arp"`
-	Rules               *PolicyRules `json:"rules,omitempty"`
-	Default             *bool        `json:"default,omitempty"`
+	ProfileName         string         `json:"profile_name"`
+	SliceName           string         `json:"slice_name"`
+	DataNetworkName     string         `json:"data_network_name"`
+	SessionAmbrUplink   string         `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string         `json:"session_ambr_downlink"`
+	Var5qi              int32          `json:"var5qi"`
+	Arp                 int32          `json:"arp"`
+	Rules               *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr       *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
+	Default             *bool          `json:"default,omitempty"`
 }
 
 type Policy struct {
-	Name                string       `json:"name"`
-	ProfileName         string       `json:"profile_name"`
-	SliceName           string       `json:"slice_name"`
-	DataNetworkName     string       `json:"data_network_name"`
-	SessionAmbrUplink   string       `json:"session_ambr_uplink"`
-	SessionAmbrDownlink string       `json:"session_ambr_downlink"`
-	Var5qi              int32        `json:"var5qi"`
-	Arp                 int32        `json:"arp"`
-	Rules               *PolicyRules `json:"rules,omitempty"`
-	Default             bool         `json:"default"`
+	Name                string         `json:"name"`
+	ProfileName         string         `json:"profile_name"`
+	SliceName           string         `json:"slice_name"`
+	DataNetworkName     string         `json:"data_network_name"`
+	SessionAmbrUplink   string         `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string         `json:"session_ambr_downlink"`
+	Var5qi              int32          `json:"var5qi"`
+	Arp                 int32          `json:"arp"`
+	Rules               *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr       *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
+	Default             bool           `json:"default"`
 }
 
 var qciCompatible5Qi = []int32{1, 2, 3, 4, 5, 6, 7, 8, 9, 65, 66, 67, 69, 70, 75, 79, 80, 82, 83}
@@ -174,6 +280,40 @@ func validateRemotePrefix(prefix *string) error {
 	return nil
 }
 
+// validateFQDN accepts a domain name of at least two labels, optionally
+// prefixed with "*." to match every name below it.
+func validateFQDN(fqdn string) error {
+	name := strings.TrimSuffix(strings.ToLower(fqdn), ".")
+	name = strings.TrimPrefix(name, "*.")
+
+	if len(name) == 0 || len(name) > 253 {
+		return errors.New("must be 1 to 253 characters")
+	}
+
+	labels := strings.Split(name, ".")
+	if len(labels) < 2 {
+		return errors.New("must have at least two labels")
+	}
+
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 {
+			return errors.New("labels must be 1 to 63 characters")
+		}
+
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return errors.New("labels must not start or end with '-'")
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+				return errors.New("only letters, digits, '-' and '_' are allowed, with '*.' as an optional prefix")
+			}
+		}
+	}
+
+	return nil
+}
+
 func validatePorts(portLow, portHigh int32) error {
 	if portLow < 0 || portHigh < 0 {
 		return errors.New("port values must be >= 0")
@@ -199,14 +339,103 @@ func validateProtocol(protocol int32) error {
 }
 
 func validateAction(action string) error {
-	if action != "allow" && action != "deny" {
-		return errors.New("action must be 'allow' or 'deny'")
+	switch action {
+	case "allow", "deny", db.RuleActionRateLimit, db.RuleActionBreakout:
+	default:
+		return errors.New("action must be 'allow', 'deny', 'rate_limit' or 'breakout'")
 	}
 
 	return nil
 }
 
-func validatePolicyRule(rule PolicyRule) error {
+// validateRateLimit checks the bitrate and scope of a "rate_limit" rule, and
+// that no other action carries them. The datapath holds the rate in kbps in
+// 32 bits, which the 5G UE-AMBR ceiling stays within.
+func validateRateLimit(rule PolicyRule) error {
+	if rule.Action != db.RuleActionRateLimit {
+		if rule.RateLimit != "" || rule.RateLimitScope != "" {
+			return errors.New("rate_limit and rate_limit_scope require action 'rate_limit'")
+		}
+
+		return nil
+	}
+
+	if !isValidBitrate(rule.RateLimit) {
+		return errors.New("rate_limit must be a bitrate such as \"5 Mbps\"")
+	}
+
+	if bps, ok := bitrateToBps(rule.RateLimit); !ok || bps > MaxUeAmbrBpsFor5G {
+		return fmt.Errorf("rate_limit must not exceed %d bps", uint64(MaxUeAmbrBpsFor5G))
+	}
+
+	switch rule.RateLimitScope {
+	case "", RateLimitScopeSession, RateLimitScopeRule:
+		return nil
+	default:
+		return errors.New("rate_limit_scope must be 'session' or 'rule'")
+	}
+}
+
+// validateBreakout checks the local egress of a "breakout" rule, and that no
+// other action carries one. Only uplink traffic breaks out: downlink from the
+// edge reaches the UE like any other N6 traffic.
+func validateBreakout(rule PolicyRule, direction string) error {
+	if rule.Action != db.RuleActionBreakout {
+		if rule.Breakout != nil {
+			return errors.New("breakout requires action 'breakout'")
+		}
+
+		return nil
+	}
+
+	switch {
+	case direction != DirectionUplink:
+		return errors.New("action 'breakout' is only valid on uplink rules")
+	case rule.Breakout == nil:
+		return errors.New("breakout is missing")
+	case !isInterfaceNameValid(rule.Breakout.Interface):
+		return errors.New("breakout.interface must be a valid interface name")
+	case rule.Breakout.VlanID < 0 || rule.Breakout.VlanID > 4094:
+		return errors.New("breakout.vlan_id must be an integer between 0 and 4094")
+	case !isRoutingTableValid(rule.Breakout.RoutingTable):
+		return errors.New("breakout.routing_table must be between 1 and 4294967295 and not 253, 254 or 255")
+	}
+
+	return nil
+}
+
+// validateRating checks that only rules that pass traffic are rated.
+func validateRating(rule PolicyRule) error {
+	if rule.Action == "deny" && (rule.RatingGroup != 0 || rule.ZeroRated) {
+		return errors.New("rating_group and zero_rated are not valid on a 'deny' rule")
+	}
+
+	return nil
+}
+
+// validateRuleDatapath refuses a rule the local datapath would not enforce:
+// stored anyway, it would pass the traffic it was meant to single out.
+func validateRuleDatapath(rule PolicyRule, features models.DatapathFeatures) error {
+	if rule.FQDN != "" && !features.FQDNRules {
+		return errors.New("rule fqdn is not supported by this node's datapath")
+	}
+
+	if rule.Action == db.RuleActionRateLimit && !features.RateLimitRules {
+		return errors.New("action 'rate_limit' is not supported by this node's datapath")
+	}
+
+	if rule.Action == db.RuleActionBreakout && !features.BreakoutRules {
+		return errors.New("action 'breakout' is not supported by this node's datapath")
+	}
+
+	if (rule.RatingGroup != 0 || rule.ZeroRated) && !features.RatedRules {
+		return errors.New("rating_group and zero_rated are not supported by this node's datapath")
+	}
+
+	return nil
+}
+
+func validatePolicyRule(rule PolicyRule, direction string) error {
 	if rule.Description == "" {
 		return errors.New("rule description is missing")
 	}
@@ -216,13 +445,37 @@ func validatePolicyRule(rule PolicyRule) error {
 	}
 
 	if err := validateAction(rule.Action); err != nil {
-		return errors.New("rule action must be 'allow' or 'deny'")
+		return fmt.Errorf("rule %w", err)
+	}
+
+	if err := validateRateLimit(rule); err != nil {
+		return fmt.Errorf("invalid rule rate limit: %w", err)
+	}
+
+	if err := validateBreakout(rule, direction); err != nil {
+		return fmt.Errorf("invalid rule breakout: %w", err)
+	}
+
+	if err := validateRating(rule); err != nil {
+		return fmt.Errorf("invalid rule rating: %w", err)
 	}
 
 	if err := validateRemotePrefix(rule.RemotePrefix); err != nil {
 		return fmt.Errorf("invalid rule remote_prefix: %w", err)
 	}
 
+	if rule.FQDN != "" {
+		if rule.RemotePrefix != nil && *rule.RemotePrefix != "" {
+			return errors.New("rule fqdn and remote_prefix are mutually exclusive")
+		}
+
+		if err := validateFQDN(rule.FQDN); err != nil {
+			return fmt.Errorf("invalid rule fqdn: %w", err)
+		}
+	} else if rule.MatchSNI {
+		return errors.New("rule match_sni requires fqdn")
+	}
+
 	if err := validateProtocol(rule.Protocol); err != nil {
 		return fmt.Errorf("invalid rule protocol: %w", err)
 	}
@@ -234,7 +487,24 @@ func validatePolicyRule(rule PolicyRule) error {
 	return nil
 }
 
-func validatePolicyRules(rules *PolicyRules) error {
+// validateScheduledAmbr checks the form of an alternate Session-AMBR; the
+// schedule's existence is checked against the database.
+func validateScheduledAmbr(scheduled *ScheduledAmbr) error {
+	switch {
+	case scheduled == nil:
+		return nil
+	case scheduled.Schedule == "":
+		return errors.New("scheduled_ambr.schedule is missing")
+	case !isValidBitrate(scheduled.SessionAmbrUplink):
+		return errors.New("invalid scheduled_ambr.session_ambr_uplink format - must be in the format `<number> <unit>`, allowed units are Mbps, Gbps")
+	case !isValidBitrate(scheduled.SessionAmbrDownlink):
+		return errors.New("invalid scheduled_ambr.session_ambr_downlink format - must be in the format `<number> <unit>`, allowed units are Mbps, Gbps")
+	}
+
+	return nil
+}
+
+func validatePolicyRules(rules *PolicyRules, features models.DatapathFeatures) error {
 	if rules == nil {
 		return nil
 	}
@@ -248,13 +518,21 @@ func validatePolicyRules(rules *PolicyRules) error {
 	}
 
 	for i, rule := range rules.Uplink {
-		if err := validatePolicyRule(rule); err != nil {
+		if err := validatePolicyRule(rule, DirectionUplink); err != nil {
+			return fmt.Errorf("uplink rule %d: %w", i, err)
+		}
+
+		if err := validateRuleDatapath(rule, features); err != nil {
 			return fmt.Errorf("uplink rule %d: %w", i, err)
 		}
 	}
 
 	for i, rule := range rules.Downlink {
-		if err := validatePolicyRule(rule); err != nil {
+		if err := validatePolicyRule(rule, DirectionDownlink); err != nil {
+			return fmt.Errorf("downlink rule %d: %w", i, err)
+		}
+
+		if err := validateRuleDatapath(rule, features); err != nil {
 			return fmt.Errorf("downlink rule %d: %w", i, err)
 		}
 	}
@@ -359,6 +637,39 @@ func getPolicyRulesForPolicy(ctx context.Context, dbInstance *db.Database, polic
 		return nil, nil
 	}
 
+	fqdns, err := dbInstance.ListNetworkRuleFQDNsByPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := dbInstance.ListNetworkRuleRateLimitsByPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	breakouts, err := dbInstance.ListNetworkRuleBreakoutsByPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings, err := dbInstance.ListNetworkRuleRatingsByPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSchedules, err := dbInstance.ListNetworkRuleSchedulesByPolicy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduleNames map[string]string
+
+	if len(ruleSchedules) > 0 {
+		if scheduleNames, err = scheduleNamesByID(ctx, dbInstance); err != nil {
+			return nil, err
+		}
+	}
+
 	policyRules := &PolicyRules{
 		Uplink:   []PolicyRule{},
 		Downlink: []PolicyRule{},
@@ -374,6 +685,37 @@ func getPolicyRulesForPolicy(ctx context.Context, dbInstance *db.Database, polic
 			Action:       rule.Action,
 		}
 
+		if f, ok := fqdns[rule.ID]; ok {
+			apiRule.FQDN = f.FQDN
+			apiRule.MatchSNI = f.MatchSNI
+		}
+
+		if l, ok := limits[rule.ID]; ok {
+			apiRule.RateLimit = l.Bitrate
+			apiRule.RateLimitScope = RateLimitScopeSession
+
+			if l.Shared {
+				apiRule.RateLimitScope = RateLimitScopeRule
+			}
+		}
+
+		if b, ok := breakouts[rule.ID]; ok {
+			apiRule.Breakout = &RuleBreakout{
+				Interface:    b.InterfaceName,
+				VlanID:       b.VlanID,
+				RoutingTable: b.RoutingTable,
+			}
+		}
+
+		if rt, ok := ratings[rule.ID]; ok {
+			apiRule.RatingGroup = uint32(rt.RatingGroup)
+			apiRule.ZeroRated = rt.ZeroRated
+		}
+
+		if sched, ok := ruleSchedules[rule.ID]; ok {
+			apiRule.Schedule = scheduleNames[sched.ScheduleID]
+		}
+
 		switch rule.Direction {
 		case DirectionUplink:
 			policyRules.Uplink = append(policyRules.Uplink, apiRule)
@@ -389,6 +731,30 @@ func getPolicyRulesForPolicy(ctx context.Context, dbInstance *db.Database, polic
 	return policyRules, nil
 }
 
+// getScheduledAmbrForPolicy returns nil when the policy has no alternate
+// Session-AMBR.
+func getScheduledAmbrForPolicy(ctx context.Context, dbInstance *db.Database, policyID string) (*ScheduledAmbr, error) {
+	scheduled, err := dbInstance.GetPolicySchedule(ctx, policyID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	names, err := scheduleNamesByID(ctx, dbInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduledAmbr{
+		Schedule:            names[scheduled.ScheduleID],
+		SessionAmbrUplink:   scheduled.SessionAmbrUplink,
+		SessionAmbrDownlink: scheduled.SessionAmbrDownlink,
+	}, nil
+}
+
 func GetPolicy(dbInstance *db.Database) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		name := r.PathValue("name")
@@ -433,6 +799,12 @@ func GetPolicy(dbInstance *db.Database) http.Handler {
 			return
 		}
 
+		scheduledAmbr, err := getScheduledAmbrForPolicy(r.Context(), dbInstance, dbPolicy.ID)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve policy schedule", err, logger.APILog)
+			return
+		}
+
 		policy := Policy{
 			Name:                dbPolicy.Name,
 			ProfileName:         profile.Name,
@@ -443,6 +815,7 @@ func GetPolicy(dbInstance *db.Database) http.Handler {
 			Var5qi:              dbPolicy.Var5qi,
 			Arp:                 dbPolicy.Arp,
 			Rules:               rules,
+			ScheduledAmbr:       scheduledAmbr,
 			Default:             dbPolicy.IsDefault,
 		}
 		writeResponse(r.Context(), w, policy, http.StatusOK, logger.APILog)
@@ -480,6 +853,24 @@ func DeletePolicy(dbInstance *db.Database) http.Handler {
 	})
 }
 
+// sessionAmbrsToCheck lists a policy's Session-AMBR values, alternate pair
+// included, for the encodability checks.
+func sessionAmbrsToCheck(uplink, downlink string, scheduled *ScheduledAmbr) []struct{ label, value string } {
+	ambrs := []struct{ label, value string }{
+		{"session_ambr_uplink", uplink},
+		{"session_ambr_downlink", downlink},
+	}
+
+	if scheduled != nil {
+		ambrs = append(ambrs,
+			struct{ label, value string }{"scheduled_ambr.session_ambr_uplink", scheduled.SessionAmbrUplink},
+			struct{ label, value string }{"scheduled_ambr.session_ambr_downlink", scheduled.SessionAmbrDownlink},
+		)
+	}
+
+	return ambrs
+}
+
 func checkPolicyBindingFree(ctx context.Context, dbInstance *db.Database, profile *db.Profile, sliceID, dataNetworkID, dataNetworkName, excludeName string) error {
 	if profile.Allow4G {
 		policies, err := dbInstance.ListPoliciesByProfile(ctx, profile.ID)
@@ -514,7 +905,7 @@ func checkPolicyBindingFree(ctx context.Context, dbInstance *db.Database, profil
 	return fmt.Errorf("policy %q already binds this slice to data network %q", existing.Name, dataNetworkName)
 }
 
-func CreatePolicy(dbInstance *db.Database) http.Handler {
+func CreatePolicy(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		email, ok := r.Context().Value(contextKeyEmail).(string)
 		if !ok {
@@ -528,7 +919,7 @@ func CreatePolicy(dbInstance *db.Database) http.Handler {
 			return
 		}
 
-		if err := validatePolicyParams(createPolicyParams); err != nil {
+		if err := validatePolicyParams(createPolicyParams, datapath()); err != nil {
 			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
 			return
 		}
@@ -547,10 +938,7 @@ func CreatePolicy(dbInstance *db.Database) http.Handler {
 			return
 		}
 
-		for _, ambr := range []struct{ label, value string }{
-			{"session_ambr_uplink", createPolicyParams.SessionAmbrUplink},
-			{"session_ambr_downlink", createPolicyParams.SessionAmbrDownlink},
-		} {
+		for _, ambr := range sessionAmbrsToCheck(createPolicyParams.SessionAmbrUplink, createPolicyParams.SessionAmbrDownlink, createPolicyParams.ScheduledAmbr) {
 			if err := checkSessionAmbrEncodable(profile.Allow4G, profile.Allow5G, ambr.label, ambr.value); err != nil {
 				writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
 				return
@@ -585,6 +973,18 @@ func CreatePolicy(dbInstance *db.Database) http.Handler {
 			return
 		}
 
+		scheduleIDs, err := policyScheduleIDs(r.Context(), dbInstance, createPolicyParams.Rules, createPolicyParams.ScheduledAmbr)
+		if err != nil {
+			if errors.Is(err, errUnknownSchedule) {
+				writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list schedules", err, logger.APILog)
+
+			return
+		}
+
 		dbPolicy := &db.Policy{
 			Name:                createPolicyParams.Name,
 			SessionAmbrDownlink: createPolicyParams.SessionAmbrDownlink,
@@ -596,8 +996,11 @@ func CreatePolicy(dbInstance *db.Database) http.Handler {
 			SliceID:             slice.ID,
 		}
 
-		if createPolicyParams.Rules != nil {
-			if err := dbInstance.CreatePolicyWithRules(r.Context(), dbPolicy, toDBPolicyRules(createPolicyParams.Rules)); err != nil {
+		if createPolicyParams.Rules != nil || createPolicyParams.ScheduledAmbr != nil {
+			rules := toDBPolicyRules(createPolicyParams.Rules, scheduleIDs)
+			scheduled := toDBPolicySchedule(createPolicyParams.ScheduledAmbr, scheduleIDs)
+
+			if err := dbInstance.CreatePolicyWithRules(r.Context(), dbPolicy, rules, scheduled); err != nil {
 				if errors.Is(err, db.ErrAlreadyExists) {
 					writeError(r.Context(), w, http.StatusConflict, "Policy already exists", nil, logger.APILog)
 					return
@@ -633,7 +1036,7 @@ func CreatePolicy(dbInstance *db.Database) http.Handler {
 	})
 }
 
-func UpdatePolicy(dbInstance *db.Database) http.Handler {
+func UpdatePolicy(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		email, ok := r.Context().Value(contextKeyEmail).(string)
 		if !ok {
@@ -654,7 +1057,7 @@ func UpdatePolicy(dbInstance *db.Database) http.Handler {
 			return
 		}
 
-		if err := validateUpdatePolicyParams(updatePolicyParams); err != nil {
+		if err := validateUpdatePolicyParams(updatePolicyParams, datapath()); err != nil {
 			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
 			return
 		}
@@ -665,6 +1068,13 @@ func UpdatePolicy(dbInstance *db.Database) http.Handler {
 			return
 		}
 
+		if updatePolicyParams.Rules != nil {
+			if err := checkCaptivePortalRoom(r.Context(), dbInstance, policy.ID, len(updatePolicyParams.Rules.Uplink)); err != nil {
+				writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+				return
+			}
+		}
+
 		profile, err := dbInstance.GetProfile(r.Context(), updatePolicyParams.ProfileName)
 		if err != nil {
 			writeError(r.Context(), w, http.StatusNotFound, "Profile not found", nil, logger.APILog)
@@ -679,10 +1089,7 @@ func UpdatePolicy(dbInstance *db.Database) http.Handler {
 			return
 		}
 
-		for _, ambr := range []struct{ label, value string }{
-			{"session_ambr_uplink", updatePolicyParams.SessionAmbrUplink},
-			{"session_ambr_downlink", updatePolicyParams.SessionAmbrDownlink},
-		} {
+		for _, ambr := range sessionAmbrsToCheck(updatePolicyParams.SessionAmbrUplink, updatePolicyParams.SessionAmbrDownlink, updatePolicyParams.ScheduledAmbr) {
 			if err := checkSessionAmbrEncodable(profile.Allow4G, profile.Allow5G, ambr.label, ambr.value); err != nil {
 				writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
 				return
@@ -706,6 +1113,18 @@ func UpdatePolicy(dbInstance *db.Database) http.Handler {
 			return
 		}
 
+		scheduleIDs, err := policyScheduleIDs(r.Context(), dbInstance, updatePolicyParams.Rules, updatePolicyParams.ScheduledAmbr)
+		if err != nil {
+			if errors.Is(err, errUnknownSchedule) {
+				writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list schedules", err, logger.APILog)
+
+			return
+		}
+
 		policy.Name = policyName
 		policy.SessionAmbrDownlink = updatePolicyParams.SessionAmbrDownlink
 		policy.SessionAmbrUplink = updatePolicyParams.SessionAmbrUplink
@@ -715,7 +1134,10 @@ func UpdatePolicy(dbInstance *db.Database) http.Handler {
 		policy.SliceID = slice.ID
 		policy.DataNetworkID = dataNetwork.ID
 
-		if err := dbInstance.UpdatePolicyWithRules(r.Context(), policy, toDBPolicyRules(updatePolicyParams.Rules)); err != nil {
+		rules := toDBPolicyRules(updatePolicyParams.Rules, scheduleIDs)
+		scheduled := toDBPolicySchedule(updatePolicyParams.ScheduledAmbr, scheduleIDs)
+
+		if err := dbInstance.UpdatePolicyWithRules(r.Context(), policy, rules, scheduled); err != nil {
 			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update policy", err, logger.APILog)
 			return
 		}
@@ -732,7 +1154,7 @@ func UpdatePolicy(dbInstance *db.Database) http.Handler {
 	})
 }
 
-func validatePolicyParams(p CreatePolicyParams) error {
+func validatePolicyParams(p CreatePolicyParams, features models.DatapathFeatures) error {
 	switch {
 	case p.Name == "":
 		return errors.New("name is missing")
@@ -762,14 +1184,18 @@ func validatePolicyParams(p CreatePolicyParams) error {
 		return errors.New("invalid arp format - must be an integer between 1 and 15")
 	}
 
-	if err := validatePolicyRules(p.Rules); err != nil {
+	if err := validatePolicyRules(p.Rules, features); err != nil {
+		return err
+	}
+
+	if err := validateScheduledAmbr(p.ScheduledAmbr); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func validateUpdatePolicyParams(p UpdatePolicyParams) error {
+func validateUpdatePolicyParams(p UpdatePolicyParams, features models.DatapathFeatures) error {
 	switch {
 	case p.ProfileName == "":
 		return errors.New("profile_name is missing")
@@ -795,7 +1221,11 @@ func validateUpdatePolicyParams(p UpdatePolicyParams) error {
 		return errors.New("invalid arp format - must be an integer between 1 and 15")
 	}
 
-	if err := validatePolicyRules(p.Rules); err != nil {
+	if err := validatePolicyRules(p.Rules, features); err != nil {
+		return err
+	}
+
+	if err := validateScheduledAmbr(p.ScheduledAmbr); err != nil {
 		return err
 	}
 
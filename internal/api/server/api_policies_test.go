@@ -12,6 +12,8 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
 )
 
 const (
@@ -30,12 +32,32 @@ type CreatePolicyResponseResult struct {
 }
 
 type PolicyRule struct {
-	Description  string  `json:"description"`
-	RemotePrefix *string `json:"remote_prefix,omitempty"`
-	Protocol     int32   `json:"protocol"`
-	PortLow      int32   `json:"port_low"`
-	PortHigh     int32   `json:"port_high"`
-	Action       string  `json:"action"`
+	Description    string        `json:"description"`
+	RemotePrefix   *string       `json:"remote_prefix,omitempty"`
+	FQDN           string        `json:"fqdn,omitempty"`
+	MatchSNI       bool          `json:"match_sni,omitempty"`
+	Protocol       int32         `json:"protocol"`
+	PortLow        int32         `json:"port_low"`
+	PortHigh       int32         `json:"port_high"`
+	Action         string        `json:"action"`
+	RateLimit      string        `json:"rate_limit,omitempty"`
+	RateLimitScope string        `json:"rate_limit_scope,omitempty"`
+	Schedule       string        `json:"schedule,omitempty"`
+	Breakout       *RuleBreakout `json:"breakout,omitempty"`
+	RatingGroup    uint32        `json:"rating_group,omitempty"`
+	ZeroRated      bool          `json:"zero_rated,omitempty"`
+}
+
+type RuleBreakout struct {
+	Interface    string `json:"interface"`
+	VlanID       int    `json:"vlan_id,omitempty"`
+	RoutingTable int    `json:"routing_table,omitempty"`
+}
+
+type ScheduledAmbr struct {
+	Schedule            string `json:"schedule"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
 }
 
 type PolicyRules struct {
@@ -44,15 +66,16 @@ type PolicyRules struct {
 }
 
 type Policy struct {
-	Name                string       `json:"name"`
-	ProfileName         string       `json:"profile_name,omitempty"`
-	SliceName           string       `json:"slice_name,omitempty"`
-	SessionAmbrUplink   string       `json:"session_ambr_uplink,omitempty"`
-	SessionAmbrDownlink string       `json:"session_ambr_downlink,omitempty"`
-	Var5qi              int32        `json:"var5qi,omitempty"`
-	Arp                 int32        `json:"arp,omitempty"`
-	DataNetworkName     string       `json:"data_network_name,omitempty"`
-	Rules               *PolicyRules `json:"rules,omitempty"`
+	Name                string         `json:"name"`
+	ProfileName         string         `json:"profile_name,omitempty"`
+	SliceName           string         `json:"slice_name,omitempty"`
+	SessionAmbrUplink   string         `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string         `json:"session_ambr_downlink,omitempty"`
+	Var5qi              int32          `json:"var5qi,omitempty"`
+	Arp                 int32          `json:"arp,omitempty"`
+	DataNetworkName     string         `json:"data_network_name,omitempty"`
+	Rules               *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr       *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
 }
 
 type GetPolicyResponse struct {
@@ -61,15 +84,16 @@ type GetPolicyResponse struct {
 }
 
 type CreatePolicyParams struct {
-	Name                string       `json:"name"`
-	ProfileName         string       `json:"profile_name"`
-	SliceName           string       `json:"slice_name"`
-	SessionAmbrUplink   string       `json:"session_ambr_uplink,omitempty"`
-	SessionAmbrDownlink string       `json:"session_ambr_downlink,omitempty"`
-	Var5qi              int32        `json:"var5qi,omitempty"`
-	Arp                 int32        `json:"arp,omitempty"`
-	DataNetworkName     string       `json:"data_network_name,omitempty"`
-	Rules               *PolicyRules `json:"rules,omitempty"`
+	Name                string         `json:"name"`
+	ProfileName         string         `json:"profile_name"`
+	SliceName           string         `json:"slice_name"`
+	SessionAmbrUplink   string         `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string         `json:"session_ambr_downlink,omitempty"`
+	Var5qi              int32          `json:"var5qi,omitempty"`
+	Arp                 int32          `json:"arp,omitempty"`
+	DataNetworkName     string         `json:"data_network_name,omitempty"`
+	Rules               *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr       *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
 }
 
 type CreatePolicyResponse struct {
@@ -78,14 +102,15 @@ type CreatePolicyResponse struct {
 }
 
 type UpdatePolicyParams struct {
-	ProfileName         string       `json:"profile_name"`
-	SliceName           string       `json:"slice_name"`
-	SessionAmbrUplink   string       `json:"session_ambr_uplink,omitempty"`
-	SessionAmbrDownlink string       `json:"session_ambr_downlink,omitempty"`
-	Var5qi              int32        `json:"var5qi,omitempty"`
-	Arp                 int32        `json:"arp,omitempty"`
-	DataNetworkName     string       `json:"data_network_name,omitempty"`
-	Rules               *PolicyRules `json:"rules,omitempty"`
+	ProfileName         string         `json:"profile_name"`
+	SliceName           string         `json:"slice_name"`
+	SessionAmbrUplink   string         `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string         `json:"session_ambr_downlink,omitempty"`
+	Var5qi              int32          `json:"var5qi,omitempty"`
+	Arp                 int32          `json:"arp,omitempty"`
+	DataNetworkName     string         `json:"data_network_name,omitempty"`
+	Rules               *PolicyRules   `json:"rules,omitempty"`
+	ScheduledAmbr       *ScheduledAmbr `json:"scheduled_ambr,omitempty"`
 }
 
 type DeletePolicyResponseResult struct {
@@ -1545,3 +1570,479 @@ func TestUpdatePolicyAllZeroRulePersists(t *testing.T) {
 		t.Fatalf("allow-all rule (protocol=0, port_low=0, port_high=0) not found in GET response after PUT: %+v", getResp.Result.Rules.Uplink)
 	}
 }
+
+func TestPolicyFQDNRules(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	_, _, err = createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	_, _, err = createProfile(env.Server.URL, client, token, &CreateProfileParams{
+		Name: "fqdn-profile", UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	params := func(rules ...PolicyRule) *CreatePolicyParams {
+		return &CreatePolicyParams{
+			Name:                "fqdn-policy",
+			ProfileName:         "fqdn-profile",
+			SliceName:           DefaultSliceName,
+			SessionAmbrUplink:   "100 Mbps",
+			SessionAmbrDownlink: "100 Mbps",
+			Var5qi:              9,
+			Arp:                 1,
+			DataNetworkName:     DataNetworkName,
+			Rules:               &PolicyRules{Uplink: rules},
+		}
+	}
+
+	cidr := "10.0.0.0/8"
+
+	invalid := []struct {
+		name string
+		rule PolicyRule
+	}{
+		{"prefix and fqdn", PolicyRule{Description: "both", RemotePrefix: &cidr, FQDN: "api.vendor-cloud.example", Action: "allow"}},
+		{"single label", PolicyRule{Description: "short", FQDN: "localhost", Action: "allow"}},
+		{"inner wildcard", PolicyRule{Description: "wild", FQDN: "api.*.example", Action: "allow"}},
+		{"sni without fqdn", PolicyRule{Description: "sni", MatchSNI: true, Action: "allow"}},
+	}
+
+	for _, tc := range invalid {
+		status, _, err := createPolicy(env.Server.URL, client, token, params(tc.rule))
+		if err != nil {
+			t.Fatalf("%s: couldn't create policy: %s", tc.name, err)
+		}
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, http.StatusBadRequest, status)
+		}
+	}
+
+	status, resp, err := createPolicy(env.Server.URL, client, token, params(
+		PolicyRule{Description: "vendor cloud", Protocol: 6, PortLow: 443, PortHigh: 443, FQDN: "*.Vendor-Cloud.example.", MatchSNI: true, Action: "allow"},
+		PolicyRule{Description: "everything else", Action: "deny"},
+	))
+	if err != nil {
+		t.Fatalf("couldn't create policy: %s", err)
+	}
+
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, status, resp.Error)
+	}
+
+	_, getResp, err := getPolicy(env.Server.URL, client, token, "fqdn-policy")
+	if err != nil {
+		t.Fatalf("couldn't get policy: %s", err)
+	}
+
+	if getResp.Result.Rules == nil || len(getResp.Result.Rules.Uplink) != 2 {
+		t.Fatalf("expected 2 uplink rules, got %+v", getResp.Result.Rules)
+	}
+
+	got := getResp.Result.Rules.Uplink[0]
+	if got.FQDN != "*.vendor-cloud.example" || !got.MatchSNI || got.RemotePrefix != nil {
+		t.Fatalf("unexpected FQDN rule: %+v", got)
+	}
+
+	if getResp.Result.Rules.Uplink[1].FQDN != "" {
+		t.Fatalf("expected the second rule to carry no FQDN, got %+v", getResp.Result.Rules.Uplink[1])
+	}
+}
+
+func TestPolicyRateLimitRules(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	_, _, err = createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	_, _, err = createProfile(env.Server.URL, client, token, &CreateProfileParams{
+		Name: "ratelimit-profile", UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	params := func(rules ...PolicyRule) *CreatePolicyParams {
+		return &CreatePolicyParams{
+			Name:                "ratelimit-policy",
+			ProfileName:         "ratelimit-profile",
+			SliceName:           DefaultSliceName,
+			SessionAmbrUplink:   "100 Mbps",
+			SessionAmbrDownlink: "100 Mbps",
+			Var5qi:              9,
+			Arp:                 1,
+			DataNetworkName:     DataNetworkName,
+			Rules:               &PolicyRules{Downlink: rules},
+		}
+	}
+
+	invalid := []struct {
+		name string
+		rule PolicyRule
+	}{
+		{"missing rate", PolicyRule{Description: "r", Action: "rate_limit"}},
+		{"malformed rate", PolicyRule{Description: "r", Action: "rate_limit", RateLimit: "fast"}},
+		{"rate too high", PolicyRule{Description: "r", Action: "rate_limit", RateLimit: "5000 Gbps"}},
+		{"unknown scope", PolicyRule{Description: "r", Action: "rate_limit", RateLimit: "2 Mbps", RateLimitScope: "global"}},
+		{"rate on allow", PolicyRule{Description: "r", Action: "allow", RateLimit: "2 Mbps"}},
+		{"unknown action", PolicyRule{Description: "r", Action: "shape"}},
+	}
+
+	for _, tc := range invalid {
+		status, _, err := createPolicy(env.Server.URL, client, token, params(tc.rule))
+		if err != nil {
+			t.Fatalf("%s: couldn't create policy: %s", tc.name, err)
+		}
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, http.StatusBadRequest, status)
+		}
+	}
+
+	status, resp, err := createPolicy(env.Server.URL, client, token, params(
+		PolicyRule{Description: "software updates", Protocol: 6, PortLow: 443, PortHigh: 443, Action: "rate_limit", RateLimit: "2 Mbps", RateLimitScope: "rule"},
+		PolicyRule{Description: "per session", Action: "rate_limit", RateLimit: "50 Mbps"},
+	))
+	if err != nil {
+		t.Fatalf("couldn't create policy: %s", err)
+	}
+
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, status, resp.Error)
+	}
+
+	_, getResp, err := getPolicy(env.Server.URL, client, token, "ratelimit-policy")
+	if err != nil {
+		t.Fatalf("couldn't get policy: %s", err)
+	}
+
+	if getResp.Result.Rules == nil || len(getResp.Result.Rules.Downlink) != 2 {
+		t.Fatalf("expected 2 downlink rules, got %+v", getResp.Result.Rules)
+	}
+
+	shared := getResp.Result.Rules.Downlink[0]
+	if shared.Action != "rate_limit" || shared.RateLimit != "2 Mbps" || shared.RateLimitScope != "rule" {
+		t.Fatalf("unexpected shared rate limit rule: %+v", shared)
+	}
+
+	perSession := getResp.Result.Rules.Downlink[1]
+	if perSession.RateLimit != "50 Mbps" || perSession.RateLimitScope != "session" {
+		t.Fatalf("expected the scope to default to session, got %+v", perSession)
+	}
+}
+
+func TestPolicyBreakoutRules(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	_, _, err = createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	_, _, err = createProfile(env.Server.URL, client, token, &CreateProfileParams{
+		Name: "breakout-profile", UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	params := func(rules *PolicyRules) *CreatePolicyParams {
+		return &CreatePolicyParams{
+			Name:                "breakout-policy",
+			ProfileName:         "breakout-profile",
+			SliceName:           DefaultSliceName,
+			SessionAmbrUplink:   "100 Mbps",
+			SessionAmbrDownlink: "100 Mbps",
+			Var5qi:              9,
+			Arp:                 1,
+			DataNetworkName:     DataNetworkName,
+			Rules:               rules,
+		}
+	}
+
+	edge := "192.168.50.0/24"
+
+	invalid := []struct {
+		name  string
+		rules *PolicyRules
+	}{
+		{"missing egress", &PolicyRules{Uplink: []PolicyRule{{Description: "r", Action: "breakout"}}}},
+		{"bad interface", &PolicyRules{Uplink: []PolicyRule{{Description: "r", Action: "breakout", Breakout: &RuleBreakout{Interface: "eth 2"}}}}},
+		{"bad vlan", &PolicyRules{Uplink: []PolicyRule{{Description: "r", Action: "breakout", Breakout: &RuleBreakout{Interface: "eth2", VlanID: 5000}}}}},
+		{"reserved table", &PolicyRules{Uplink: []PolicyRule{{Description: "r", Action: "breakout", Breakout: &RuleBreakout{Interface: "eth2", RoutingTable: 254}}}}},
+		{"egress on allow", &PolicyRules{Uplink: []PolicyRule{{Description: "r", Action: "allow", Breakout: &RuleBreakout{Interface: "eth2"}}}}},
+		{"downlink breakout", &PolicyRules{Downlink: []PolicyRule{{Description: "r", Action: "breakout", Breakout: &RuleBreakout{Interface: "eth2"}}}}},
+	}
+
+	for _, tc := range invalid {
+		status, _, err := createPolicy(env.Server.URL, client, token, params(tc.rules))
+		if err != nil {
+			t.Fatalf("%s: couldn't create policy: %s", tc.name, err)
+		}
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, http.StatusBadRequest, status)
+		}
+	}
+
+	status, resp, err := createPolicy(env.Server.URL, client, token, params(&PolicyRules{Uplink: []PolicyRule{
+		{Description: "edge video", RemotePrefix: &edge, Action: "breakout", Breakout: &RuleBreakout{Interface: "eth2", VlanID: 30, RoutingTable: 100}},
+		{Description: "everything else", Action: "allow"},
+	}}))
+	if err != nil {
+		t.Fatalf("couldn't create policy: %s", err)
+	}
+
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, status, resp.Error)
+	}
+
+	_, getResp, err := getPolicy(env.Server.URL, client, token, "breakout-policy")
+	if err != nil {
+		t.Fatalf("couldn't get policy: %s", err)
+	}
+
+	if getResp.Result.Rules == nil || len(getResp.Result.Rules.Uplink) != 2 {
+		t.Fatalf("expected 2 uplink rules, got %+v", getResp.Result.Rules)
+	}
+
+	got := getResp.Result.Rules.Uplink[0]
+	if got.Action != "breakout" || got.Breakout == nil || *got.Breakout != (RuleBreakout{Interface: "eth2", VlanID: 30, RoutingTable: 100}) {
+		t.Fatalf("unexpected breakout rule: %+v", got)
+	}
+
+	if getResp.Result.Rules.Uplink[1].Breakout != nil {
+		t.Fatalf("expected the allow rule to carry no breakout, got %+v", getResp.Result.Rules.Uplink[1])
+	}
+}
+
+func TestPolicyRatedRules(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	_, _, err = createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	_, _, err = createProfile(env.Server.URL, client, token, &CreateProfileParams{
+		Name: "rated-profile", UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	params := func(rules *PolicyRules) *CreatePolicyParams {
+		return &CreatePolicyParams{
+			Name:                "rated-policy",
+			ProfileName:         "rated-profile",
+			SliceName:           DefaultSliceName,
+			SessionAmbrUplink:   "100 Mbps",
+			SessionAmbrDownlink: "100 Mbps",
+			Var5qi:              9,
+			Arp:                 1,
+			DataNetworkName:     DataNetworkName,
+			Rules:               rules,
+		}
+	}
+
+	for _, rule := range []PolicyRule{
+		{Description: "r", Action: "deny", RatingGroup: 10},
+		{Description: "r", Action: "deny", ZeroRated: true},
+	} {
+		status, _, err := createPolicy(env.Server.URL, client, token, params(&PolicyRules{Downlink: []PolicyRule{rule}}))
+		if err != nil {
+			t.Fatalf("couldn't create policy: %s", err)
+		}
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("rated deny rule %+v: expected status %d, got %d", rule, http.StatusBadRequest, status)
+		}
+	}
+
+	portal := "198.51.100.0/24"
+
+	status, resp, err := createPolicy(env.Server.URL, client, token, params(&PolicyRules{Downlink: []PolicyRule{
+		{Description: "self-care portal", RemotePrefix: &portal, Action: "allow", RatingGroup: 10, ZeroRated: true},
+		{Description: "everything else", Action: "allow"},
+	}}))
+	if err != nil {
+		t.Fatalf("couldn't create policy: %s", err)
+	}
+
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, status, resp.Error)
+	}
+
+	_, getResp, err := getPolicy(env.Server.URL, client, token, "rated-policy")
+	if err != nil {
+		t.Fatalf("couldn't get policy: %s", err)
+	}
+
+	if getResp.Result.Rules == nil || len(getResp.Result.Rules.Downlink) != 2 {
+		t.Fatalf("expected 2 downlink rules, got %+v", getResp.Result.Rules)
+	}
+
+	if got := getResp.Result.Rules.Downlink[0]; got.RatingGroup != 10 || !got.ZeroRated {
+		t.Fatalf("unexpected rated rule: %+v", got)
+	}
+
+	if got := getResp.Result.Rules.Downlink[1]; got.RatingGroup != 0 || got.ZeroRated {
+		t.Fatalf("expected the second rule to be unrated, got %+v", got)
+	}
+}
+
+// TestPolicyRulesUnsupportedDatapath checks that rules the local datapath
+// would not enforce are refused rather than stored.
+func TestPolicyRulesUnsupportedDatapath(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServerWithDatapath(dbPath, models.DatapathFeatures{})
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	_, _, err = createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	_, _, err = createProfile(env.Server.URL, client, token, &CreateProfileParams{
+		Name: "legacy-profile", UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	params := func(rules ...PolicyRule) *CreatePolicyParams {
+		return &CreatePolicyParams{
+			Name:                "legacy-policy",
+			ProfileName:         "legacy-profile",
+			SliceName:           DefaultSliceName,
+			SessionAmbrUplink:   "100 Mbps",
+			SessionAmbrDownlink: "100 Mbps",
+			Var5qi:              9,
+			Arp:                 1,
+			DataNetworkName:     DataNetworkName,
+			Rules:               &PolicyRules{Uplink: rules},
+		}
+	}
+
+	unsupported := []struct {
+		name string
+		rule PolicyRule
+	}{
+		{"fqdn", PolicyRule{Description: "vendor cloud", FQDN: "api.vendor-cloud.example", Action: "deny"}},
+		{"rate_limit", PolicyRule{Description: "video", Action: "rate_limit", RateLimit: "5 Mbps"}},
+		{"breakout", PolicyRule{Description: "edge", Action: "breakout", Breakout: &RuleBreakout{Interface: "eth2"}}},
+		{"rating_group", PolicyRule{Description: "video", Action: "allow", RatingGroup: 100}},
+		{"zero_rated", PolicyRule{Description: "portal", Action: "allow", ZeroRated: true}},
+	}
+
+	for _, tc := range unsupported {
+		status, resp, err := createPolicy(env.Server.URL, client, token, params(tc.rule))
+		if err != nil {
+			t.Fatalf("%s: couldn't create policy: %s", tc.name, err)
+		}
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, http.StatusBadRequest, status)
+		}
+
+		if !strings.Contains(resp.Error, "not supported by this node's datapath") {
+			t.Fatalf("%s: unexpected error: %s", tc.name, resp.Error)
+		}
+	}
+
+	status, resp, err := createPolicy(env.Server.URL, client, token, params(
+		PolicyRule{Description: "everything", Action: "deny"},
+	))
+	if err != nil {
+		t.Fatalf("couldn't create policy: %s", err)
+	}
+
+	if status != http.StatusCreated {
+		t.Fatalf("expected a plain rule to be accepted, got %d (error: %s)", status, resp.Error)
+	}
+}
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const UpdatePolicyDecisionAction = "update_policy_decision"
+
+// PolicyAssociationResponse is a session whose policy an external policy
+// decision point controls, with the decision in force. Zero 5QI and ARP,
+// empty Session-AMBR values and an empty rules policy keep those of the
+// session's local policy.
+type PolicyAssociationResponse struct {
+	ID                  string `json:"id"`
+	DataNetwork         string `json:"data_network"`
+	IMSI                string `json:"imsi"`
+	PDUSessionID        int    `json:"pdu_session_id"`
+	Var5qi              int32  `json:"var5qi,omitempty"`
+	Arp                 int32  `json:"arp,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink,omitempty"`
+	RulesPolicy         string `json:"rules_policy,omitempty"`
+}
+
+type ListPolicyAssociationsResponse struct {
+	Items []PolicyAssociationResponse `json:"items"`
+}
+
+// UpdatePolicyDecisionParams is a new decision for a session. RulesPolicy
+// names a policy of the session's data network whose network rules apply
+// in place of the subscriber's.
+type UpdatePolicyDecisionParams struct {
+	Var5qi              int32  `json:"var5qi,omitempty"`
+	Arp                 int32  `json:"arp,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink,omitempty"`
+	RulesPolicy         string `json:"rules_policy,omitempty"`
+}
+
+// policyAssociationNames resolves the data network and policy IDs of
+// associations to names, caching what it looked up.
+type policyAssociationNames struct {
+	db           *db.Database
+	dataNetworks map[string]string
+	policies     map[string]string
+}
+
+func (n *policyAssociationNames) response(ctx context.Context, a *db.SMPolicyAssociation) (PolicyAssociationResponse, error) {
+	resp := PolicyAssociationResponse{
+		ID:                  a.ID,
+		IMSI:                a.IMSI,
+		PDUSessionID:        a.PDUSessionID,
+		Var5qi:              a.Var5qi,
+		Arp:                 a.Arp,
+		SessionAmbrUplink:   a.SessionAmbrUplink,
+		SessionAmbrDownlink: a.SessionAmbrDownlink,
+	}
+
+	name, ok := n.dataNetworks[a.DataNetworkID]
+	if !ok {
+		dn, err := n.db.GetDataNetworkByID(ctx, a.DataNetworkID)
+		if err != nil {
+			return PolicyAssociationResponse{}, err
+		}
+
+		name = dn.Name
+		n.dataNetworks[a.DataNetworkID] = name
+	}
+
+	resp.DataNetwork = name
+
+	if a.RulesPolicyID == nil {
+		return resp, nil
+	}
+
+	name, ok = n.policies[*a.RulesPolicyID]
+	if !ok {
+		policy, err := n.db.GetPolicyByID(ctx, *a.RulesPolicyID)
+		if err != nil {
+			return PolicyAssociationResponse{}, err
+		}
+
+		name = policy.Name
+		n.policies[*a.RulesPolicyID] = name
+	}
+
+	resp.RulesPolicy = name
+
+	return resp, nil
+}
+
+func newPolicyAssociationNames(dbInstance *db.Database) *policyAssociationNames {
+	return &policyAssociationNames{db: dbInstance, dataNetworks: make(map[string]string), policies: make(map[string]string)}
+}
+
+func ListPolicyAssociations(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assocs, err := dbInstance.ListAllSMPolicyAssociations(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list policy associations", err, logger.APILog)
+			return
+		}
+
+		names := newPolicyAssociationNames(dbInstance)
+		items := make([]PolicyAssociationResponse, 0, len(assocs))
+
+		for i := range assocs {
+			item, err := names.response(r.Context(), &assocs[i])
+			if err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list policy associations", err, logger.APILog)
+				return
+			}
+
+			items = append(items, item)
+		}
+
+		writeResponse(r.Context(), w, ListPolicyAssociationsResponse{Items: items}, http.StatusOK, logger.APILog)
+	})
+}
+
+func GetPolicyAssociation(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing id parameter", nil, logger.APILog)
+			return
+		}
+
+		assoc, err := dbInstance.GetSMPolicyAssociation(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Policy association not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve policy association", err, logger.APILog)
+
+			return
+		}
+
+		resp, err := newPolicyAssociationNames(dbInstance).response(r.Context(), assoc)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve policy association", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, resp, http.StatusOK, logger.APILog)
+	})
+}
+
+// UpdatePolicyDecision is the callback through which the decision point
+// changes its decision for a session. The node serving the session puts it
+// in force.
+func UpdatePolicyDecision(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		id := r.PathValue("id")
+		if id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing id parameter", nil, logger.APILog)
+			return
+		}
+
+		var params UpdatePolicyDecisionParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if err := validatePolicyDecision(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		assoc, err := dbInstance.GetSMPolicyAssociation(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Policy association not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update policy decision", err, logger.APILog)
+
+			return
+		}
+
+		assoc.Var5qi = params.Var5qi
+		assoc.Arp = params.Arp
+		assoc.SessionAmbrUplink = params.SessionAmbrUplink
+		assoc.SessionAmbrDownlink = params.SessionAmbrDownlink
+		assoc.RulesPolicyID = nil
+
+		if params.RulesPolicy != "" {
+			policy, err := dbInstance.GetPolicy(r.Context(), params.RulesPolicy)
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update policy decision", err, logger.APILog)
+				return
+			}
+
+			if err != nil || policy.DataNetworkID != assoc.DataNetworkID {
+				writeError(r.Context(), w, http.StatusBadRequest, "rules_policy must be a policy of the session's data network", nil, logger.APILog)
+				return
+			}
+
+			assoc.RulesPolicyID = &policy.ID
+		}
+
+		if err := dbInstance.UpdateSMPolicyDecision(r.Context(), assoc); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Policy association not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update policy decision", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Policy decision updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdatePolicyDecisionAction, email, getClientIP(r), "User updated the policy decision of association "+id)
+	})
+}
+
+func validatePolicyDecision(p *UpdatePolicyDecisionParams) error {
+	switch {
+	case p.Var5qi != 0 && !isValid5Qi(p.Var5qi):
+		return errors.New("invalid Var5qi format - must be an integer associated with a non-GBR 5QI")
+	case p.Arp != 0 && !isValidArp(p.Arp):
+		return errors.New("invalid arp format - must be an integer between 1 and 15")
+	case (p.SessionAmbrUplink == "") != (p.SessionAmbrDownlink == ""):
+		return errors.New("session_ambr_uplink and session_ambr_downlink must be set together")
+	case p.SessionAmbrUplink != "" && !isValidBitrate(p.SessionAmbrUplink):
+		return errors.New("invalid session_ambr_uplink format - must be in the format `<number> <unit>`, allowed units are Mbps, Gbps")
+	case p.SessionAmbrDownlink != "" && !isValidBitrate(p.SessionAmbrDownlink):
+		return errors.New("invalid session_ambr_downlink format - must be in the format `<number> <unit>`, allowed units are Mbps, Gbps")
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+type policyAssociation struct {
+	ID                  string `json:"id"`
+	DataNetwork         string `json:"data_network"`
+	IMSI                string `json:"imsi"`
+	PDUSessionID        int    `json:"pdu_session_id"`
+	Var5qi              int32  `json:"var5qi"`
+	Arp                 int32  `json:"arp"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
+	RulesPolicy         string `json:"rules_policy"`
+}
+
+type policyAssociationResponse struct {
+	Result policyAssociation `json:"result"`
+	Error  string            `json:"error,omitempty"`
+}
+
+type listPolicyAssociationsResponse struct {
+	Result struct {
+		Items []policyAssociation `json:"items"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIPolicyAssociationsEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	if err := createDataNetworkAndPolicy(url, client, token); err != nil {
+		t.Fatalf("couldn't create data network and policy: %s", err)
+	}
+
+	ctx := context.Background()
+
+	dn, err := env.DB.GetDataNetwork(ctx, DataNetworkName)
+	if err != nil {
+		t.Fatalf("couldn't get data network: %s", err)
+	}
+
+	// The node serving the session records the association when the
+	// decision point first decides its policy.
+	if err := env.DB.CreateSMPolicyAssociation(ctx, &db.SMPolicyAssociation{
+		ID: "sm-1", DataNetworkID: dn.ID, IMSI: "001010100007487", PDUSessionID: 1, Var5qi: 7,
+	}); err != nil {
+		t.Fatalf("couldn't create policy association: %s", err)
+	}
+
+	assocURL := url + "/api/v1/policy-control/associations"
+
+	t.Run("list and get", func(t *testing.T) {
+		var list listPolicyAssociationsResponse
+
+		code, err := doNATRequest(client, "GET", assocURL, token, nil, &list)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, list.Error)
+		}
+
+		want := policyAssociation{ID: "sm-1", DataNetwork: DataNetworkName, IMSI: "001010100007487", PDUSessionID: 1, Var5qi: 7}
+		if len(list.Result.Items) != 1 || list.Result.Items[0] != want {
+			t.Fatalf("unexpected associations: %+v", list.Result.Items)
+		}
+
+		var one policyAssociationResponse
+
+		code, err = doNATRequest(client, "GET", assocURL+"/sm-1", token, nil, &one)
+		if err != nil || code != http.StatusOK || one.Result != want {
+			t.Fatalf("expected 200 with %+v, got %d (%v, %+v)", want, code, err, one)
+		}
+	})
+
+	t.Run("invalid decisions are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"GBR 5QI", map[string]any{"var5qi": 1}},
+			{"ARP out of range", map[string]any{"arp": 16}},
+			{"uplink AMBR only", map[string]any{"session_ambr_uplink": "10 Mbps"}},
+			{"malformed AMBR", map[string]any{"session_ambr_uplink": "fast", "session_ambr_downlink": "10 Mbps"}},
+			{"unknown rules policy", map[string]any{"rules_policy": "missing"}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", assocURL+"/sm-1/decision", token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("update decision", func(t *testing.T) {
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", assocURL+"/sm-1/decision", token, map[string]any{
+			"var5qi": 8, "arp": 3, "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "50 Mbps", "rules_policy": PolicyName,
+		}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		var one policyAssociationResponse
+
+		code, err = doNATRequest(client, "GET", assocURL+"/sm-1", token, nil, &one)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, one.Error)
+		}
+
+		r := one.Result
+		if r.Var5qi != 8 || r.Arp != 3 || r.SessionAmbrUplink != "5 Mbps" || r.SessionAmbrDownlink != "50 Mbps" || r.RulesPolicy != PolicyName {
+			t.Fatalf("unexpected decision: %+v", r)
+		}
+
+		code, err = doNATRequest(client, "PUT", assocURL+"/sm-1/decision", token, map[string]any{}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		var cleared policyAssociationResponse
+
+		code, err = doNATRequest(client, "GET", assocURL+"/sm-1", token, nil, &cleared)
+		if err != nil || code != http.StatusOK || cleared.Result.Var5qi != 0 || cleared.Result.RulesPolicy != "" {
+			t.Fatalf("expected the local policy back, got %d (%v, %+v)", code, err, cleared.Result)
+		}
+	})
+
+	t.Run("unknown association", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "GET", assocURL+"/missing", token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		code, err = doNATRequest(client, "PUT", assocURL+"/missing/decision", token, map[string]any{"var5qi": 9}, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+const UpdatePolicyCaptivePortalAction = "update_policy_captive_portal"
+
+// CaptivePortalRules is how many uplink rules the UPF places ahead of a
+// policy's own in redirect mode, out of MaxNumNetworkRulesPerDirection.
+const CaptivePortalRules = 4
+
+// PolicyCaptivePortal is a policy's redirect mode. While enabled, the
+// policy's subscribers have DNS and web traffic sent to PortalAddress and
+// everything else dropped, until the redirect is lifted for them.
+type PolicyCaptivePortal struct {
+	Enabled       bool   `json:"enabled"`
+	PortalAddress string `json:"portal_address,omitempty"`
+}
+
+func captivePortalFromDB(portal *db.PolicyCaptivePortal) PolicyCaptivePortal {
+	if portal == nil {
+		return PolicyCaptivePortal{}
+	}
+
+	return PolicyCaptivePortal{Enabled: true, PortalAddress: portal.PortalAddress}
+}
+
+func validatePortalAddress(s string) error {
+	addr, err := netip.ParseAddr(s)
+	if err != nil || !addr.Is4() {
+		return errors.New("invalid portal_address, must be an IPv4 address")
+	}
+
+	if addr.IsUnspecified() || addr.IsLoopback() || addr.IsMulticast() || addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return errors.New("invalid portal_address, must be a unicast address")
+	}
+
+	return nil
+}
+
+// checkCaptivePortalRoom reports whether uplinkRules uplink rules fit beside
+// the captive portal's, when the policy is in redirect mode.
+func checkCaptivePortalRoom(ctx context.Context, dbInstance *db.Database, policyID string, uplinkRules int) error {
+	if _, err := dbInstance.GetPolicyCaptivePortal(ctx, policyID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if maxRules := MaxNumNetworkRulesPerDirection - CaptivePortalRules; uplinkRules > maxRules {
+		return fmt.Errorf("uplink rules exceed maximum of %d while the captive portal is enabled", maxRules)
+	}
+
+	return nil
+}
+
+func GetPolicyCaptivePortal(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		policy, err := dbInstance.GetPolicy(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Policy not found", nil, logger.APILog)
+			return
+		}
+
+		portal, err := dbInstance.GetPolicyCaptivePortal(r.Context(), policy.ID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get policy captive portal", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, captivePortalFromDB(portal), http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdatePolicyCaptivePortal(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params PolicyCaptivePortal
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		policy, err := dbInstance.GetPolicy(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Policy not found", nil, logger.APILog)
+			return
+		}
+
+		if !params.Enabled {
+			if params.PortalAddress != "" {
+				writeError(r.Context(), w, http.StatusBadRequest, "portal_address must be omitted when the captive portal is disabled", nil, logger.APILog)
+				return
+			}
+
+			if err := dbInstance.ClearPolicyCaptivePortal(r.Context(), policy.ID); err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update policy captive portal", err, logger.APILog)
+				return
+			}
+
+			writeResponse(r.Context(), w, SuccessResponse{Message: "Policy captive portal updated successfully"}, http.StatusOK, logger.APILog)
+
+			logger.LogAuditEvent(r.Context(), UpdatePolicyCaptivePortalAction, email, getClientIP(r), "User disabled the captive portal on policy "+name)
+
+			return
+		}
+
+		if !datapath().CaptivePortal {
+			writeError(r.Context(), w, http.StatusBadRequest, "captive portals are not supported by this node's datapath", nil, logger.APILog)
+			return
+		}
+
+		if err := validatePortalAddress(params.PortalAddress); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		rules, err := dbInstance.ListRulesForPolicy(r.Context(), policy.ID)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list policy rules", err, logger.APILog)
+			return
+		}
+
+		uplinkRules := 0
+
+		for _, rule := range rules {
+			if rule.Direction == DirectionUplink {
+				uplinkRules++
+			}
+		}
+
+		if maxRules := MaxNumNetworkRulesPerDirection - CaptivePortalRules; uplinkRules > maxRules {
+			writeError(r.Context(), w, http.StatusBadRequest, fmt.Sprintf("the captive portal needs %d uplink rules, so the policy may have at most %d", CaptivePortalRules, maxRules), nil, logger.APILog)
+			return
+		}
+
+		row := &db.PolicyCaptivePortal{
+			PolicyID:      policy.ID,
+			PortalAddress: params.PortalAddress,
+		}
+
+		if err := dbInstance.SetPolicyCaptivePortal(r.Context(), row); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update policy captive portal", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Policy captive portal updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdatePolicyCaptivePortalAction, email, getClientIP(r), fmt.Sprintf("User enabled the captive portal on policy %s at %s", name, params.PortalAddress))
+	})
+}
@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/api/server"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+type policyCaptivePortalResponse struct {
+	Result struct {
+		Enabled       bool   `json:"enabled"`
+		PortalAddress string `json:"portal_address"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIPolicyCaptivePortalEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	if _, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS}); err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if _, _, err := createProfile(url, client, token, &CreateProfileParams{Name: "portal-profile", UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps"}); err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	uplinkRules := func(n int) *PolicyRules {
+		rules := &PolicyRules{}
+		for range n {
+			rules.Uplink = append(rules.Uplink, PolicyRule{Description: "r", Action: "allow"})
+		}
+
+		return rules
+	}
+
+	status, _, err := createPolicy(url, client, token, &CreatePolicyParams{
+		Name:                "portal-policy",
+		ProfileName:         "portal-profile",
+		SliceName:           DefaultSliceName,
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "100 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkName:     DataNetworkName,
+		Rules:               uplinkRules(10),
+	})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("couldn't create policy: %d %v", status, err)
+	}
+
+	portalURL := url + "/api/v1/policies/portal-policy/captive-portal"
+
+	t.Run("redirect mode is off by default", func(t *testing.T) {
+		var resp policyCaptivePortalResponse
+
+		code, err := doNATRequest(client, "GET", portalURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		if resp.Result.Enabled {
+			t.Fatalf("unexpected captive portal: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"missing address", map[string]any{"enabled": true}},
+			{"IPv6 address", map[string]any{"enabled": true, "portal_address": "2001:db8::1"}},
+			{"multicast address", map[string]any{"enabled": true, "portal_address": "224.0.0.1"}},
+			{"address while disabled", map[string]any{"enabled": false, "portal_address": "192.0.2.10"}},
+			{"too many uplink rules", map[string]any{"enabled": true, "portal_address": "192.0.2.10"}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", portalURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	update := func(n int) int {
+		status, _, err := editPolicy(url, client, "portal-policy", token, &UpdatePolicyParams{
+			ProfileName:         "portal-profile",
+			SliceName:           DefaultSliceName,
+			SessionAmbrUplink:   "100 Mbps",
+			SessionAmbrDownlink: "100 Mbps",
+			Var5qi:              9,
+			Arp:                 1,
+			DataNetworkName:     DataNetworkName,
+			Rules:               uplinkRules(n),
+		})
+		if err != nil {
+			t.Fatalf("couldn't update policy: %s", err)
+		}
+
+		return status
+	}
+
+	t.Run("enable, read back and disable", func(t *testing.T) {
+		if status := update(server.MaxNumNetworkRulesPerDirection - server.CaptivePortalRules); status != http.StatusOK {
+			t.Fatalf("couldn't trim the policy's rules: %d", status)
+		}
+
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", portalURL, token, map[string]any{"enabled": true, "portal_address": "192.0.2.10"}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		var resp policyCaptivePortalResponse
+
+		if _, err := doNATRequest(client, "GET", portalURL, token, nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if !resp.Result.Enabled || resp.Result.PortalAddress != "192.0.2.10" {
+			t.Fatalf("unexpected captive portal: %+v", resp.Result)
+		}
+
+		if status := update(server.MaxNumNetworkRulesPerDirection - server.CaptivePortalRules + 1); status != http.StatusBadRequest {
+			t.Fatalf("expected 400 for rules that leave no room for the portal's, got %d", status)
+		}
+
+		code, err = doNATRequest(client, "PUT", portalURL, token, map[string]any{"enabled": false}, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		resp = policyCaptivePortalResponse{}
+
+		if _, err := doNATRequest(client, "GET", portalURL, token, nil, &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Result.Enabled {
+			t.Fatalf("expected redirect mode to be off, got %+v", resp.Result)
+		}
+	})
+}
+
+func TestAPIPolicyCaptivePortalUnsupportedDatapath(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServerWithDatapath(dbPath, models.DatapathFeatures{})
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	if _, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS}); err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if _, _, err := createProfile(url, client, token, &CreateProfileParams{Name: "portal-profile", UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps"}); err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	status, _, err := createPolicy(url, client, token, &CreatePolicyParams{
+		Name:                "portal-policy",
+		ProfileName:         "portal-profile",
+		SliceName:           DefaultSliceName,
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "100 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkName:     DataNetworkName,
+	})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("couldn't create policy: %d %v", status, err)
+	}
+
+	portalURL := url + "/api/v1/policies/portal-policy/captive-portal"
+
+	var msg messageResponse
+
+	code, err := doNATRequest(client, "PUT", portalURL, token, map[string]any{"enabled": true, "portal_address": "192.0.2.10"}, &msg)
+	if err != nil || code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for enabling redirect mode, got %d (%v, %s)", code, err, msg.Error)
+	}
+
+	code, err = doNATRequest(client, "PUT", portalURL, token, map[string]any{"enabled": false}, &msg)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected disabling redirect mode to succeed, got %d (%v, %s)", code, err, msg.Error)
+	}
+}
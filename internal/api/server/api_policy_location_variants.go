@@ -0,0 +1,271 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const (
+	CreatePolicyLocationVariantAction = "create_policy_location_variant"
+	DeletePolicyLocationVariantAction = "delete_policy_location_variant"
+)
+
+// PolicyLocationVariant replaces a policy's Session-AMBR while the
+// subscriber is served in a tracking area or cell, and with RulesPolicy its
+// network rules too. A cell variant wins over a tracking area variant;
+// elsewhere the policy applies unchanged.
+type PolicyLocationVariant struct {
+	ID                  string `json:"id"`
+	Name                string `json:"name"`
+	TAC                 string `json:"tac,omitempty"`
+	CellID              string `json:"cell_id,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
+	RulesPolicy         string `json:"rules_policy,omitempty"`
+}
+
+type PolicyLocationVariantList struct {
+	Items      []PolicyLocationVariant `json:"items"`
+	Page       int                     `json:"page"`
+	PerPage    int                     `json:"per_page"`
+	TotalCount int                     `json:"total_count"`
+}
+
+// CreatePolicyLocationVariantParams is a new variant. RulesPolicy names a
+// policy of the same data network whose network rules apply in the area;
+// empty keeps the policy's own.
+type CreatePolicyLocationVariantParams struct {
+	Name                string `json:"name"`
+	TAC                 string `json:"tac,omitempty"`
+	CellID              string `json:"cell_id,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
+	RulesPolicy         string `json:"rules_policy,omitempty"`
+}
+
+func policyLocationVariantFromDB(v db.PolicyLocationVariant, rulesPolicy string) PolicyLocationVariant {
+	return PolicyLocationVariant{
+		ID:                  v.ID,
+		Name:                v.Name,
+		TAC:                 v.TAC,
+		CellID:              v.CellID,
+		SessionAmbrUplink:   v.SessionAmbrUplink,
+		SessionAmbrDownlink: v.SessionAmbrDownlink,
+		RulesPolicy:         rulesPolicy,
+	}
+}
+
+// isValidCellID accepts an E-UTRA cell identity (7 hex digits) or an NR
+// cell identity (9 hex digits), as reported in the user location.
+func isValidCellID(s string) bool {
+	if len(s) != 7 && len(s) != 9 {
+		return false
+	}
+
+	_, err := strconv.ParseUint(s, 16, 64)
+
+	return err == nil
+}
+
+func validatePolicyLocationVariant(p *CreatePolicyLocationVariantParams) error {
+	p.TAC = strings.ToLower(p.TAC)
+	p.CellID = strings.ToLower(p.CellID)
+
+	switch {
+	case !isResourceNameValid(p.Name):
+		return errors.New("invalid name format, must be less than 256 characters")
+	case (p.TAC == "") == (p.CellID == ""):
+		return errors.New("exactly one of tac and cell_id is required")
+	case p.TAC != "" && !isValidTac(p.TAC):
+		return errors.New("invalid tac, must be 6 hexadecimal digits")
+	case p.CellID != "" && !isValidCellID(p.CellID):
+		return errors.New("invalid cell_id, must be 7 (E-UTRA) or 9 (NR) hexadecimal digits")
+	case !isValidBitrate(p.SessionAmbrUplink):
+		return errors.New("invalid session_ambr_uplink format, must be in the format `<number> <unit>`, where <unit> is one of Kbps, Mbps or Gbps")
+	case !isValidBitrate(p.SessionAmbrDownlink):
+		return errors.New("invalid session_ambr_downlink format, must be in the format `<number> <unit>`, where <unit> is one of Kbps, Mbps or Gbps")
+	}
+
+	return nil
+}
+
+func ListPolicyLocationVariants(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		policy, err := dbInstance.GetPolicy(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Policy not found", nil, logger.APILog)
+			return
+		}
+
+		rows, err := dbInstance.ListPolicyLocationVariants(r.Context(), policy.ID)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list policy location variants", err, logger.APILog)
+			return
+		}
+
+		names := map[string]string{}
+		items := make([]PolicyLocationVariant, 0, len(rows))
+
+		for _, row := range rows {
+			var rulesPolicy string
+
+			if row.RulesPolicyID != nil {
+				name, ok := names[*row.RulesPolicyID]
+				if !ok {
+					p, err := dbInstance.GetPolicyByID(r.Context(), *row.RulesPolicyID)
+					if err != nil {
+						writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list policy location variants", err, logger.APILog)
+						return
+					}
+
+					name = p.Name
+					names[*row.RulesPolicyID] = name
+				}
+
+				rulesPolicy = name
+			}
+
+			items = append(items, policyLocationVariantFromDB(row, rulesPolicy))
+		}
+
+		writeResponse(r.Context(), w, PolicyLocationVariantList{
+			Items:      items,
+			Page:       1,
+			PerPage:    len(items),
+			TotalCount: len(items),
+		}, http.StatusOK, logger.APILog)
+	})
+}
+
+func CreatePolicyLocationVariant(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params CreatePolicyLocationVariantParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if err := validatePolicyLocationVariant(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		policy, err := dbInstance.GetPolicy(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Policy not found", nil, logger.APILog)
+			return
+		}
+
+		row := &db.PolicyLocationVariant{
+			PolicyID:            policy.ID,
+			Name:                params.Name,
+			TAC:                 params.TAC,
+			CellID:              params.CellID,
+			SessionAmbrUplink:   params.SessionAmbrUplink,
+			SessionAmbrDownlink: params.SessionAmbrDownlink,
+		}
+
+		if params.RulesPolicy != "" {
+			rulesPolicy, err := dbInstance.GetPolicy(r.Context(), params.RulesPolicy)
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create policy location variant", err, logger.APILog)
+				return
+			}
+
+			if err != nil || rulesPolicy.DataNetworkID != policy.DataNetworkID {
+				writeError(r.Context(), w, http.StatusBadRequest, "rules_policy must be a policy of the same data network", nil, logger.APILog)
+				return
+			}
+
+			row.RulesPolicyID = &rulesPolicy.ID
+		}
+
+		if err := dbInstance.CreatePolicyLocationVariant(r.Context(), row); err != nil {
+			if errors.Is(err, db.ErrAlreadyExists) {
+				writeError(r.Context(), w, http.StatusConflict, "the policy already has a location variant with this name or area", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create policy location variant", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, policyLocationVariantFromDB(*row, params.RulesPolicy), http.StatusCreated, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), CreatePolicyLocationVariantAction, email, getClientIP(r), fmt.Sprintf("User created location variant %s on policy %s", params.Name, name))
+	})
+}
+
+func DeletePolicyLocationVariant(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		name := r.PathValue("name")
+		id := r.PathValue("id")
+
+		if name == "" || id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name or id parameter", nil, logger.APILog)
+			return
+		}
+
+		policy, err := dbInstance.GetPolicy(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Policy not found", nil, logger.APILog)
+			return
+		}
+
+		variant, err := dbInstance.GetPolicyLocationVariant(r.Context(), id)
+		if err != nil || variant.PolicyID != policy.ID {
+			writeError(r.Context(), w, http.StatusNotFound, "Policy location variant not found", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeletePolicyLocationVariant(r.Context(), id); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Policy location variant not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete policy location variant", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Policy location variant deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeletePolicyLocationVariantAction, email, getClientIP(r), fmt.Sprintf("User removed location variant %s from policy %s", variant.Name, name))
+	})
+}
@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+type policyLocationVariantResponse struct {
+	Result struct {
+		ID                  string `json:"id"`
+		Name                string `json:"name"`
+		TAC                 string `json:"tac"`
+		CellID              string `json:"cell_id"`
+		SessionAmbrUplink   string `json:"session_ambr_uplink"`
+		SessionAmbrDownlink string `json:"session_ambr_downlink"`
+		RulesPolicy         string `json:"rules_policy"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+type policyLocationVariantListResponse struct {
+	Result struct {
+		Items []struct {
+			ID          string `json:"id"`
+			Name        string `json:"name"`
+			TAC         string `json:"tac"`
+			CellID      string `json:"cell_id"`
+			RulesPolicy string `json:"rules_policy"`
+		} `json:"items"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPIPolicyLocationVariantsEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	if _, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS}); err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if _, _, err := createProfile(url, client, token, &CreateProfileParams{Name: "site-profile", UeAmbrUplink: "200 Mbps", UeAmbrDownlink: "200 Mbps"}); err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	status, _, err := createPolicy(url, client, token, &CreatePolicyParams{
+		Name:                "site-policy",
+		ProfileName:         "site-profile",
+		SliceName:           DefaultSliceName,
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "100 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkName:     DataNetworkName,
+	})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("couldn't create policy: %d %v", status, err)
+	}
+
+	// The policy whose network rules apply at the loading dock.
+	if _, _, err := createProfile(url, client, token, &CreateProfileParams{Name: "dock-profile", UeAmbrUplink: "200 Mbps", UeAmbrDownlink: "200 Mbps"}); err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	status, _, err = createPolicy(url, client, token, &CreatePolicyParams{
+		Name:                "dock-rules",
+		ProfileName:         "dock-profile",
+		SliceName:           DefaultSliceName,
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "100 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkName:     DataNetworkName,
+	})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("couldn't create policy: %d %v", status, err)
+	}
+
+	variantsURL := url + "/api/v1/policies/site-policy/location-variants"
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"no area", map[string]any{"name": "v", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "5 Mbps"}},
+			{"both areas", map[string]any{"name": "v", "tac": "000001", "cell_id": "0000101", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "5 Mbps"}},
+			{"short tac", map[string]any{"name": "v", "tac": "01", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "5 Mbps"}},
+			{"bad cell id", map[string]any{"name": "v", "cell_id": "00001zz", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "5 Mbps"}},
+			{"cell id length", map[string]any{"name": "v", "cell_id": "00001010", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "5 Mbps"}},
+			{"bad bitrate", map[string]any{"name": "v", "tac": "000001", "session_ambr_uplink": "fast", "session_ambr_downlink": "5 Mbps"}},
+			{"missing name", map[string]any{"tac": "000001", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "5 Mbps"}},
+			{"unknown rules policy", map[string]any{"name": "v", "tac": "000001", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "5 Mbps", "rules_policy": "missing"}},
+		}
+
+		for _, tc := range cases {
+			var resp policyLocationVariantResponse
+
+			code, err := doNATRequest(client, "POST", variantsURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("unknown policy is not found", func(t *testing.T) {
+		var resp policyLocationVariantListResponse
+
+		code, err := doNATRequest(client, "GET", url+"/api/v1/policies/missing/location-variants", token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+
+	var parkingID string
+
+	t.Run("create variants", func(t *testing.T) {
+		var resp policyLocationVariantResponse
+
+		code, err := doNATRequest(client, "POST", variantsURL, token, map[string]any{
+			"name": "parking-lot", "tac": "0000AB", "session_ambr_uplink": "5 Mbps", "session_ambr_downlink": "10 Mbps",
+		}, &resp)
+		if err != nil || code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		if resp.Result.ID == "" || resp.Result.TAC != "0000ab" || resp.Result.SessionAmbrDownlink != "10 Mbps" {
+			t.Fatalf("unexpected variant: %+v", resp.Result)
+		}
+
+		parkingID = resp.Result.ID
+
+		code, err = doNATRequest(client, "POST", variantsURL, token, map[string]any{
+			"name": "loading-dock", "cell_id": "000000101", "session_ambr_uplink": "50 Mbps", "session_ambr_downlink": "50 Mbps", "rules_policy": "dock-rules",
+		}, &resp)
+		if err != nil || code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		if resp.Result.RulesPolicy != "dock-rules" {
+			t.Fatalf("rules_policy = %q, want dock-rules", resp.Result.RulesPolicy)
+		}
+	})
+
+	t.Run("duplicate area conflicts", func(t *testing.T) {
+		var resp policyLocationVariantResponse
+
+		code, err := doNATRequest(client, "POST", variantsURL, token, map[string]any{
+			"name": "other", "tac": "0000ab", "session_ambr_uplink": "1 Mbps", "session_ambr_downlink": "1 Mbps",
+		}, &resp)
+		if err != nil || code != http.StatusConflict {
+			t.Fatalf("expected 409, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+
+	t.Run("list variants", func(t *testing.T) {
+		var resp policyLocationVariantListResponse
+
+		code, err := doNATRequest(client, "GET", variantsURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		if len(resp.Result.Items) != 2 || resp.Result.Items[0].Name != "loading-dock" || resp.Result.Items[1].Name != "parking-lot" {
+			t.Fatalf("unexpected variants: %+v", resp.Result.Items)
+		}
+
+		if resp.Result.Items[0].RulesPolicy != "dock-rules" || resp.Result.Items[1].RulesPolicy != "" {
+			t.Fatalf("unexpected rules policies: %+v", resp.Result.Items)
+		}
+	})
+
+	t.Run("delete variant", func(t *testing.T) {
+		var resp policyLocationVariantResponse
+
+		code, err := doNATRequest(client, "DELETE", variantsURL+"/"+parkingID, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		code, err = doNATRequest(client, "DELETE", variantsURL+"/"+parkingID, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
@@ -303,12 +303,15 @@ func UpdateProfile(dbInstance *db.Database) http.Handler {
 					return
 				}
 
+				scheduled, err := getScheduledAmbrForPolicy(r.Context(), dbInstance, p.ID)
+				if err != nil {
+					writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve policy schedule", err, logger.APILog)
+					return
+				}
+
 				// Only the 4G bound is new: the policy's 5G encodability was
 				// settled when it was written.
-				for _, ambr := range []struct{ label, value string }{
-					{"session_ambr_uplink", p.SessionAmbrUplink},
-					{"session_ambr_downlink", p.SessionAmbrDownlink},
-				} {
+				for _, ambr := range sessionAmbrsToCheck(p.SessionAmbrUplink, p.SessionAmbrDownlink, scheduled) {
 					if err := checkSessionAmbrEncodable(true, false, ambr.label, ambr.value); err != nil {
 						writeError(r.Context(), w, http.StatusBadRequest,
 							fmt.Sprintf("cannot enable 4G: policy %q has a %s EPS cannot carry: %s", p.Name, ambr.label, err),
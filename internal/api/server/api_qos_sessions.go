@@ -0,0 +1,323 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	CreateQosSessionAction = "create_qos_session"
+	DeleteQosSessionAction = "delete_qos_session"
+)
+
+const (
+	// MinQosSessionDuration and MaxQosSessionDuration bound, in seconds,
+	// how long a QoS session may last.
+	MinQosSessionDuration = 60
+	MaxQosSessionDuration = 24 * 60 * 60
+)
+
+// gbr5Qis are the standardized 5QIs of guaranteed bit rate QoS flows
+// (TS 23.501 Table 5.7.4-1), the ones a QoS session may request.
+var gbr5Qis = []int32{1, 2, 3, 4, 65, 66, 67, 71, 72, 73, 74, 76}
+
+// CreateQosSessionParams asks for a dedicated QoS flow carrying the traffic
+// a UE, named by exactly one of IMSI and UEIP, exchanges with a remote
+// party, for Duration seconds. An empty RemotePrefix, a zero Protocol and
+// zero ports match any. The application is told how the request fares at
+// NotificationURL.
+type CreateQosSessionParams struct {
+	IMSI            string `json:"imsi,omitempty"`
+	UEIP            string `json:"ue_ip,omitempty"`
+	RemotePrefix    string `json:"remote_prefix,omitempty"`
+	Protocol        int32  `json:"protocol,omitempty"`
+	PortLow         int32  `json:"port_low,omitempty"`
+	PortHigh        int32  `json:"port_high,omitempty"`
+	Var5qi          int32  `json:"var5qi"`
+	GBRUplink       string `json:"gbr_uplink"`
+	GBRDownlink     string `json:"gbr_downlink"`
+	MBRUplink       string `json:"mbr_uplink"`
+	MBRDownlink     string `json:"mbr_downlink"`
+	Duration        int64  `json:"duration"`
+	NotificationURL string `json:"notification_url"`
+}
+
+// QosSessionResponse is a QoS session and how it fares: requested until the
+// node serving the UE takes it, installing while the flow is added, active
+// once established, and failed with a Reason.
+type QosSessionResponse struct {
+	ID              string `json:"id"`
+	IMSI            string `json:"imsi,omitempty"`
+	UEIP            string `json:"ue_ip,omitempty"`
+	RemotePrefix    string `json:"remote_prefix,omitempty"`
+	Protocol        int    `json:"protocol,omitempty"`
+	PortLow         int    `json:"port_low,omitempty"`
+	PortHigh        int    `json:"port_high,omitempty"`
+	Var5qi          int32  `json:"var5qi"`
+	GBRUplink       string `json:"gbr_uplink"`
+	GBRDownlink     string `json:"gbr_downlink"`
+	MBRUplink       string `json:"mbr_uplink"`
+	MBRDownlink     string `json:"mbr_downlink"`
+	NotificationURL string `json:"notification_url"`
+	CreatedAt       string `json:"created_at"`
+	ExpiresAt       string `json:"expires_at"`
+	Status          string `json:"status"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+type ListQosSessionsResponse struct {
+	Items []QosSessionResponse `json:"items"`
+}
+
+func qosSessionFromDB(qs *db.QosSession) QosSessionResponse {
+	return QosSessionResponse{
+		ID:              qs.ID,
+		IMSI:            qs.IMSI,
+		UEIP:            qs.UEIP,
+		RemotePrefix:    qs.RemotePrefix,
+		Protocol:        qs.Protocol,
+		PortLow:         qs.PortLow,
+		PortHigh:        qs.PortHigh,
+		Var5qi:          qs.Var5qi,
+		GBRUplink:       qs.GBRUplink,
+		GBRDownlink:     qs.GBRDownlink,
+		MBRUplink:       qs.MBRUplink,
+		MBRDownlink:     qs.MBRDownlink,
+		NotificationURL: qs.NotificationURL,
+		CreatedAt:       time.Unix(qs.CreatedAt, 0).UTC().Format(time.RFC3339),
+		ExpiresAt:       time.Unix(qs.ExpiresAt, 0).UTC().Format(time.RFC3339),
+		Status:          qs.Status,
+		Reason:          qs.Reason,
+	}
+}
+
+func ListQosSessions(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rows, err := dbInstance.ListQosSessions(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list QoS sessions", err, logger.APILog)
+			return
+		}
+
+		items := make([]QosSessionResponse, 0, len(rows))
+		for i := range rows {
+			items = append(items, qosSessionFromDB(&rows[i]))
+		}
+
+		writeResponse(r.Context(), w, ListQosSessionsResponse{Items: items}, http.StatusOK, logger.APILog)
+	})
+}
+
+func GetQosSession(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing id parameter", nil, logger.APILog)
+			return
+		}
+
+		qs, err := dbInstance.GetQosSession(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "QoS session not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve QoS session", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, qosSessionFromDB(qs), http.StatusOK, logger.APILog)
+	})
+}
+
+// CreateQosSession records an application's request. The node serving the
+// UE adds the flow and notifies the application.
+func CreateQosSession(dbInstance *db.Database, datapath func() models.DatapathFeatures) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		var params CreateQosSessionParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if err := validateQosSession(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		if !datapath().QoSFlows {
+			writeError(r.Context(), w, http.StatusBadRequest, "QoS flows are not supported by this node's datapath", nil, logger.APILog)
+			return
+		}
+
+		if params.IMSI != "" {
+			if _, err := dbInstance.GetSubscriber(r.Context(), params.IMSI); err != nil {
+				if errors.Is(err, db.ErrNotFound) {
+					writeError(r.Context(), w, http.StatusNotFound, "Subscriber not found", nil, logger.APILog)
+					return
+				}
+
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get subscriber", err, logger.APILog)
+
+				return
+			}
+		}
+
+		existing, err := dbInstance.ListQosSessions(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create QoS session", err, logger.APILog)
+			return
+		}
+
+		if len(existing) >= db.MaxQosSessions {
+			writeError(r.Context(), w, http.StatusBadRequest,
+				fmt.Sprintf("Maximum number of QoS sessions (%d) reached", db.MaxQosSessions), nil, logger.APILog)
+
+			return
+		}
+
+		id, err := uuid.NewV7()
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to generate QoS session id", err, logger.APILog)
+			return
+		}
+
+		now := time.Now().Unix()
+		qs := &db.QosSession{
+			ID:              id.String(),
+			IMSI:            params.IMSI,
+			UEIP:            params.UEIP,
+			RemotePrefix:    params.RemotePrefix,
+			Protocol:        int(params.Protocol),
+			PortLow:         int(params.PortLow),
+			PortHigh:        int(params.PortHigh),
+			Var5qi:          params.Var5qi,
+			GBRUplink:       params.GBRUplink,
+			GBRDownlink:     params.GBRDownlink,
+			MBRUplink:       params.MBRUplink,
+			MBRDownlink:     params.MBRDownlink,
+			NotificationURL: params.NotificationURL,
+			CreatedAt:       now,
+			ExpiresAt:       now + params.Duration,
+			Status:          db.QosSessionRequested,
+		}
+
+		if err := dbInstance.CreateQosSession(r.Context(), qs); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create QoS session", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, qosSessionFromDB(qs), http.StatusCreated, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), CreateQosSessionAction, email, getClientIP(r), "User created QoS session "+qs.ID)
+	})
+}
+
+// DeleteQosSession ends a QoS session. The node serving the UE removes the
+// flow.
+func DeleteQosSession(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		id := r.PathValue("id")
+		if id == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing id parameter", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeleteQosSession(r.Context(), id); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "QoS session not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete QoS session", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "QoS session deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteQosSessionAction, email, getClientIP(r), "User deleted QoS session "+id)
+	})
+}
+
+// validateQosSession checks a request and puts its addresses in canonical
+// form.
+func validateQosSession(p *CreateQosSessionParams) error {
+	if (p.IMSI == "") == (p.UEIP == "") {
+		return errors.New("exactly one of imsi and ue_ip is required")
+	}
+
+	if p.UEIP != "" {
+		addr, err := netip.ParseAddr(p.UEIP)
+		if err != nil || addr.Zone() != "" || addr.IsUnspecified() || addr.IsMulticast() {
+			return errors.New("invalid ue_ip, must be a unicast IP address")
+		}
+
+		p.UEIP = addr.Unmap().String()
+	}
+
+	if p.RemotePrefix != "" {
+		prefix, err := netip.ParsePrefix(p.RemotePrefix)
+		if err != nil {
+			return fmt.Errorf("invalid remote_prefix: %w", err)
+		}
+
+		p.RemotePrefix = prefix.Masked().String()
+	}
+
+	if err := validateProtocol(p.Protocol); err != nil {
+		return err
+	}
+
+	if err := validatePorts(p.PortLow, p.PortHigh); err != nil {
+		return err
+	}
+
+	if !slices.Contains(gbr5Qis, p.Var5qi) {
+		return errors.New("invalid var5qi - must be a standardized GBR 5QI")
+	}
+
+	for _, dir := range []struct{ name, gbr, mbr string }{
+		{"uplink", p.GBRUplink, p.MBRUplink},
+		{"downlink", p.GBRDownlink, p.MBRDownlink},
+	} {
+		if !isValidBitrate(dir.gbr) || !isValidBitrate(dir.mbr) {
+			return fmt.Errorf("invalid %s bit rate format - must be in the format `<number> <unit>`, allowed units are Kbps, Mbps, Gbps", dir.name)
+		}
+
+		if models.MustParseBitRate(dir.gbr).Kbps() > models.MustParseBitRate(dir.mbr).Kbps() {
+			return fmt.Errorf("gbr_%s must not exceed mbr_%s", dir.name, dir.name)
+		}
+	}
+
+	if p.Duration < MinQosSessionDuration || p.Duration > MaxQosSessionDuration {
+		return fmt.Errorf("duration must be between %d and %d seconds", MinQosSessionDuration, MaxQosSessionDuration)
+	}
+
+	u, err := url.Parse(p.NotificationURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return errors.New("invalid notification_url, must be an http or https URL")
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+type qosSession struct {
+	ID              string `json:"id"`
+	UEIP            string `json:"ue_ip"`
+	RemotePrefix    string `json:"remote_prefix"`
+	Protocol        int    `json:"protocol"`
+	Var5qi          int32  `json:"var5qi"`
+	GBRUplink       string `json:"gbr_uplink"`
+	MBRDownlink     string `json:"mbr_downlink"`
+	NotificationURL string `json:"notification_url"`
+	CreatedAt       string `json:"created_at"`
+	ExpiresAt       string `json:"expires_at"`
+	Status          string `json:"status"`
+}
+
+type qosSessionResponse struct {
+	Result qosSession `json:"result"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type listQosSessionsResponse struct {
+	Result struct {
+		Items []qosSession `json:"items"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func validQosSessionBody() map[string]any {
+	return map[string]any{
+		"ue_ip":            "10.45.0.3",
+		"remote_prefix":    "198.51.100.7/24",
+		"protocol":         17,
+		"port_low":         5000,
+		"port_high":        5010,
+		"var5qi":           2,
+		"gbr_uplink":       "2 Mbps",
+		"gbr_downlink":     "2 Mbps",
+		"mbr_uplink":       "4 Mbps",
+		"mbr_downlink":     "4 Mbps",
+		"duration":         600,
+		"notification_url": "https://app.example.com/qos-events",
+	}
+}
+
+func TestAPIQosSessionsEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sessionsURL := url + "/api/v1/qos-sessions"
+
+	var created qosSessionResponse
+
+	t.Run("create", func(t *testing.T) {
+		code, err := doNATRequest(client, "POST", sessionsURL, token, validQosSessionBody(), &created)
+		if err != nil || code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d (%v, %s)", code, err, created.Error)
+		}
+
+		r := created.Result
+		if r.ID == "" || r.Status != db.QosSessionRequested || r.RemotePrefix != "198.51.100.0/24" || r.Var5qi != 2 || r.ExpiresAt <= r.CreatedAt {
+			t.Fatalf("unexpected QoS session: %+v", r)
+		}
+	})
+
+	t.Run("list and get", func(t *testing.T) {
+		var list listQosSessionsResponse
+
+		code, err := doNATRequest(client, "GET", sessionsURL, token, nil, &list)
+		if err != nil || code != http.StatusOK || len(list.Result.Items) != 1 || list.Result.Items[0] != created.Result {
+			t.Fatalf("expected the created QoS session, got %d (%v, %+v)", code, err, list.Result.Items)
+		}
+
+		var one qosSessionResponse
+
+		code, err = doNATRequest(client, "GET", sessionsURL+"/"+created.Result.ID, token, nil, &one)
+		if err != nil || code != http.StatusOK || one.Result != created.Result {
+			t.Fatalf("expected the created QoS session, got %d (%v, %+v)", code, err, one.Result)
+		}
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		cases := []struct {
+			name  string
+			field string
+			value any
+		}{
+			{"no UE", "ue_ip", nil},
+			{"both UE identities", "imsi", "001010100007487"},
+			{"non-GBR 5QI", "var5qi", 9},
+			{"GBR above MBR", "gbr_uplink", "8 Mbps"},
+			{"malformed bit rate", "mbr_downlink", "fast"},
+			{"reversed ports", "port_low", 6000},
+			{"duration too short", "duration", 10},
+			{"notification URL not http", "notification_url", "ftp://app.example.com"},
+			{"malformed remote prefix", "remote_prefix", "198.51.100.7"},
+		}
+
+		for _, tc := range cases {
+			body := validQosSessionBody()
+			if tc.value == nil {
+				delete(body, tc.field)
+			} else {
+				body[tc.field] = tc.value
+			}
+
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "POST", sessionsURL, token, body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("unknown subscriber", func(t *testing.T) {
+		body := validQosSessionBody()
+		delete(body, "ue_ip")
+		body["imsi"] = "001019999999999"
+
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "POST", sessionsURL, token, body, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "DELETE", sessionsURL+"/"+created.Result.ID, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		code, err = doNATRequest(client, "GET", sessionsURL+"/"+created.Result.ID, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		code, err = doNATRequest(client, "DELETE", sessionsURL+"/"+created.Result.ID, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
+
+func TestAPIQosSessionsUnsupportedDatapath(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServerWithDatapath(dbPath, models.DatapathFeatures{})
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	var resp messageResponse
+
+	code, err := doNATRequest(client, "POST", url+"/api/v1/qos-sessions", token, validQosSessionBody(), &resp)
+	if err != nil || code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d (%v, %s)", code, err, resp.Error)
+	}
+}
@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+type sliceQuotaResponse struct {
+	Result struct {
+		MaxRegisteredUEs         int `json:"max_registered_ues"`
+		MaxSessions              int `json:"max_sessions"`
+		MaxSessionsPerSubscriber int `json:"max_sessions_per_subscriber"`
+		BackOffTimer             int `json:"back_off_timer"`
+		RegisteredUEs            int `json:"registered_ues"`
+		Sessions                 int `json:"sessions"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+type dataNetworkQuotaResponse struct {
+	Result struct {
+		MaxSessions  int `json:"max_sessions"`
+		BackOffTimer int `json:"back_off_timer"`
+		Sessions     int `json:"sessions"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPISliceQuotaEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createSlice(url, client, token, &CreateSliceParams{Name: "tenant-a", Sst: 1, Sd: "102030"})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create slice: %d %v", sc, err)
+	}
+
+	quotaURL := url + "/api/v1/slices/tenant-a/quota"
+
+	get := func(t *testing.T) sliceQuotaResponse {
+		t.Helper()
+
+		var resp sliceQuotaResponse
+
+		code, err := doNATRequest(client, "GET", quotaURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		return resp
+	}
+
+	t.Run("no limit by default", func(t *testing.T) {
+		resp := get(t)
+		if resp.Result.MaxRegisteredUEs != 0 || resp.Result.MaxSessions != 0 || resp.Result.MaxSessionsPerSubscriber != 0 {
+			t.Fatalf("unexpected quota: %+v", resp.Result)
+		}
+	})
+
+	t.Run("invalid quotas are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"negative registered UEs", map[string]any{"max_registered_ues": -1}},
+			{"negative sessions", map[string]any{"max_sessions": -1}},
+			{"negative sessions per subscriber", map[string]any{"max_sessions_per_subscriber": -1}},
+			{"unencodable back-off", map[string]any{"max_sessions": 10, "back_off_timer": 90}},
+			{"back-off too long", map[string]any{"max_sessions": 10, "back_off_timer": 4 * 3600}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", quotaURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400, got %d (%v)", tc.name, code, err)
+			}
+		}
+	})
+
+	t.Run("set, read and delete", func(t *testing.T) {
+		body := map[string]any{"max_registered_ues": 100, "max_sessions": 200, "max_sessions_per_subscriber": 2, "back_off_timer": 120}
+
+		var msg messageResponse
+
+		code, err := doNATRequest(client, "PUT", quotaURL, token, body, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		resp := get(t)
+		if resp.Result.MaxRegisteredUEs != 100 || resp.Result.MaxSessions != 200 || resp.Result.MaxSessionsPerSubscriber != 2 || resp.Result.BackOffTimer != 120 {
+			t.Fatalf("unexpected quota: %+v", resp.Result)
+		}
+
+		if resp.Result.RegisteredUEs != 0 || resp.Result.Sessions != 0 {
+			t.Fatalf("unexpected live counters: %+v", resp.Result)
+		}
+
+		code, err = doNATRequest(client, "DELETE", quotaURL, token, nil, &msg)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+		}
+
+		if resp := get(t); resp.Result.MaxSessions != 0 {
+			t.Fatalf("quota not deleted: %+v", resp.Result)
+		}
+	})
+
+	t.Run("unknown slice", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "GET", url+"/api/v1/slices/nope/quota", token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v)", code, err)
+		}
+	})
+}
+
+func TestAPIDataNetworkQuotaEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	sc, _, err := createDataNetwork(url, client, token, &CreateDataNetworkParams{Name: "tenant-dn", IPv4Pool: "10.76.0.0/24", DNS: DNS, MTU: 1400})
+	if err != nil || sc != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v", sc, err)
+	}
+
+	quotaURL := url + "/api/v1/networking/data-networks/tenant-dn/quota"
+
+	var msg messageResponse
+
+	code, err := doNATRequest(client, "PUT", quotaURL, token, map[string]any{"max_sessions": -5}, &msg)
+	if err != nil || code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d (%v)", code, err)
+	}
+
+	code, err = doNATRequest(client, "PUT", quotaURL, token, map[string]any{"max_sessions": 50, "back_off_timer": 60}, &msg)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+	}
+
+	var resp dataNetworkQuotaResponse
+
+	code, err = doNATRequest(client, "GET", quotaURL, token, nil, &resp)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+	}
+
+	if resp.Result.MaxSessions != 50 || resp.Result.BackOffTimer != 60 || resp.Result.Sessions != 0 {
+		t.Fatalf("unexpected quota: %+v", resp.Result)
+	}
+
+	code, err = doNATRequest(client, "DELETE", quotaURL, token, nil, &msg)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+	}
+
+	resp = dataNetworkQuotaResponse{}
+
+	code, err = doNATRequest(client, "GET", quotaURL, token, nil, &resp)
+	if err != nil || code != http.StatusOK || resp.Result.MaxSessions != 0 {
+		t.Fatalf("quota not deleted: %d %+v (%v)", code, resp.Result, err)
+	}
+}
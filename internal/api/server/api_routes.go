@@ -6,6 +6,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/netip"
@@ -21,6 +22,7 @@ type CreateRouteParams struct {
 	Gateway     string `json:"gateway"`
 	Interface   string `json:"interface"`
 	Metric      int    `json:"metric"`
+	DataNetwork string `json:"data_network,omitempty"`
 }
 
 type Route struct {
@@ -30,6 +32,7 @@ type Route struct {
 	Interface   string `json:"interface"`
 	Metric      int    `json:"metric"`
 	Source      string `json:"source"`
+	DataNetwork string `json:"data_network,omitempty"`
 }
 
 type ListRoutesResponse struct {
@@ -66,6 +69,47 @@ var interfaceDBMap = map[string]db.NetworkInterface{
 	"n6": db.N6,
 }
 
+// dataNetworkNamesByID maps data network ids to names so routes scoped to
+// a data network can be reported by name.
+func dataNetworkNamesByID(ctx context.Context, dbInstance *db.Database) (map[string]string, error) {
+	dataNetworks, err := dbInstance.ListAllDataNetworks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(dataNetworks))
+	for _, dn := range dataNetworks {
+		names[dn.ID] = dn.Name
+	}
+
+	return names, nil
+}
+
+// listDataNetworkRoutes returns the static routes scoped to one data
+// network. The route table is capped at MaxNumRoutes, so the whole set is
+// read and filtered here.
+func listDataNetworkRoutes(ctx context.Context, dbInstance *db.Database, name string) ([]db.Route, error) {
+	dn, err := dbInstance.GetDataNetwork(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := dbInstance.ListAllRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := make([]db.Route, 0, len(all))
+
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].DataNetworkID == dn.ID {
+			routes = append(routes, all[i])
+		}
+	}
+
+	return routes, nil
+}
+
 func ListRoutes(dbInstance *db.Database, bgpService *bgp.BGPService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
@@ -82,15 +126,50 @@ func ListRoutes(dbInstance *db.Database, bgpService *bgp.BGPService) http.Handle
 			return
 		}
 
-		dbRoutes, total, err := dbInstance.ListRoutesPage(r.Context(), page, perPage)
-		if err != nil {
-			writeError(r.Context(), w, http.StatusInternalServerError, "Routes not found", err, logger.APILog)
-			return
+		var (
+			dbRoutes []db.Route
+			total    int
+			learned  []bgp.LearnedRoute
+		)
+
+		if dataNetwork := q.Get("data_network"); dataNetwork != "" {
+			// BGP-learned routes live in the main table, so a data network
+			// scoped view only lists its static routes.
+			scoped, err := listDataNetworkRoutes(r.Context(), dbInstance, dataNetwork)
+			if err != nil {
+				if errors.Is(err, db.ErrNotFound) {
+					writeError(r.Context(), w, http.StatusNotFound, "Data Network not found", nil, logger.APILog)
+					return
+				}
+
+				writeError(r.Context(), w, http.StatusInternalServerError, "Routes not found", err, logger.APILog)
+
+				return
+			}
+
+			total = len(scoped)
+
+			start := min((page-1)*perPage, total)
+			end := min(start+perPage, total)
+			dbRoutes = scoped[start:end]
+		} else {
+			var err error
+
+			dbRoutes, total, err = dbInstance.ListRoutesPage(r.Context(), page, perPage)
+			if err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Routes not found", err, logger.APILog)
+				return
+			}
+
+			if bgpService != nil && bgpService.IsRunning() {
+				learned = bgpService.GetLearnedRoutes()
+			}
 		}
 
-		var learned []bgp.LearnedRoute
-		if bgpService != nil && bgpService.IsRunning() {
-			learned = bgpService.GetLearnedRoutes()
+		dnNames, err := dataNetworkNamesByID(r.Context(), dbInstance)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list data networks", err, logger.APILog)
+			return
 		}
 
 		items := make([]Route, 0, len(dbRoutes)+len(learned))
@@ -113,6 +192,7 @@ func ListRoutes(dbInstance *db.Database, bgpService *bgp.BGPService) http.Handle
 				Interface:   dbRoute.Interface.String(),
 				Metric:      dbRoute.Metric,
 				Source:      "static",
+				DataNetwork: dnNames[dbRoute.DataNetworkID],
 			})
 		}
 
@@ -158,6 +238,16 @@ func GetRoute(dbInstance *db.Database) http.Handler {
 			Source:      "static",
 		}
 
+		if dbRoute.DataNetworkID != "" {
+			dnNames, err := dataNetworkNamesByID(r.Context(), dbInstance)
+			if err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list data networks", err, logger.APILog)
+				return
+			}
+
+			routeResponse.DataNetwork = dnNames[dbRoute.DataNetworkID]
+		}
+
 		writeResponse(r.Context(), w, routeResponse, http.StatusOK, logger.APILog)
 	})
 }
@@ -227,6 +317,40 @@ func CreateRoute(dbInstance *db.Database, reconcileRoutes func(context.Context)
 			return
 		}
 
+		var dataNetworkID string
+
+		if createRouteParams.DataNetwork != "" {
+			if dbNetworkInterface != db.N6 {
+				writeError(r.Context(), w, http.StatusBadRequest, "data_network routes must use the n6 interface", nil, logger.APILog)
+				return
+			}
+
+			dn, err := dbInstance.GetDataNetwork(r.Context(), createRouteParams.DataNetwork)
+			if err != nil {
+				if errors.Is(err, db.ErrNotFound) {
+					writeError(r.Context(), w, http.StatusBadRequest, "data network not found", nil, logger.APILog)
+					return
+				}
+
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network", err, logger.APILog)
+
+				return
+			}
+
+			egress, err := dbInstance.GetDataNetworkEgress(r.Context(), dn.ID)
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get data network egress", err, logger.APILog)
+				return
+			}
+
+			if egress == nil || egress.RoutingTable == 0 {
+				writeError(r.Context(), w, http.StatusBadRequest, "data network has no routing table: set egress.routing_table first", nil, logger.APILog)
+				return
+			}
+
+			dataNetworkID = dn.ID
+		}
+
 		// Hard cap on total static routes; bounds the cost of the
 		// reconciler's DB read on every tick.
 		existing, _, err := dbInstance.ListRoutesPage(r.Context(), 1, MaxNumRoutes+1)
@@ -244,17 +368,19 @@ func CreateRoute(dbInstance *db.Database, reconcileRoutes func(context.Context)
 			if existingRoute.Destination == createRouteParams.Destination &&
 				existingRoute.Gateway == createRouteParams.Gateway &&
 				existingRoute.Metric == createRouteParams.Metric &&
-				existingRoute.Interface == dbNetworkInterface {
+				existingRoute.Interface == dbNetworkInterface &&
+				existingRoute.DataNetworkID == dataNetworkID {
 				writeError(r.Context(), w, http.StatusBadRequest, "Route already exists", nil, logger.APILog)
 				return
 			}
 		}
 
 		dbRoute := &db.Route{
-			Destination: createRouteParams.Destination,
-			Gateway:     createRouteParams.Gateway,
-			Interface:   dbNetworkInterface,
-			Metric:      createRouteParams.Metric,
+			Destination:   createRouteParams.Destination,
+			Gateway:       createRouteParams.Gateway,
+			Interface:     dbNetworkInterface,
+			Metric:        createRouteParams.Metric,
+			DataNetworkID: dataNetworkID,
 		}
 
 		routeID, err := dbInstance.CreateRoute(r.Context(), dbRoute)
@@ -31,6 +31,7 @@ type Route struct {
 	Gateway     string `json:"gateway"`
 	Interface   string `json:"interface"`
 	Metric      int    `json:"metric"`
+	DataNetwork string `json:"data_network,omitempty"`
 }
 
 type GetRouteResponse struct {
@@ -43,6 +44,7 @@ type CreateRouteParams struct {
 	Gateway     string `json:"gateway"`
 	Interface   string `json:"interface"`
 	Metric      int    `json:"metric"`
+	DataNetwork string `json:"data_network,omitempty"`
 }
 
 type CreateRouteResponse struct {
@@ -728,3 +730,108 @@ func TestCreateTooManyRoutes(t *testing.T) {
 		t.Fatalf("expected error %q, got %q", "Maximum number of routes reached (12)", response.Error)
 	}
 }
+
+func TestCreateDataNetworkScopedRoute(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	if statusCode, response, err := createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: "no-table", IPv4Pool: "10.80.0.0/24", DNS: DNS, MTU: MTU,
+	}); err != nil || statusCode != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v %v", statusCode, err, response)
+	}
+
+	if statusCode, response, err := createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: "enterprise", IPv4Pool: "10.81.0.0/24", DNS: DNS, MTU: MTU, Egress: &DataNetworkEgress{RoutingTable: 100},
+	}); err != nil || statusCode != http.StatusCreated {
+		t.Fatalf("couldn't create data network: %d %v %v", statusCode, err, response)
+	}
+
+	t.Run("data network without a routing table", func(t *testing.T) {
+		statusCode, _, err := createRoute(env.Server.URL, client, token, &CreateRouteParams{
+			Destination: "0.0.0.0/0", Gateway: "192.168.100.1", Interface: "n6", Metric: 10, DataNetwork: "no-table",
+		})
+		if err != nil {
+			t.Fatalf("couldn't create route: %s", err)
+		}
+
+		if statusCode != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, statusCode)
+		}
+	})
+
+	t.Run("data network route must use n6", func(t *testing.T) {
+		statusCode, _, err := createRoute(env.Server.URL, client, token, &CreateRouteParams{
+			Destination: "0.0.0.0/0", Gateway: "192.168.100.1", Interface: "n3", Metric: 10, DataNetwork: "enterprise",
+		})
+		if err != nil {
+			t.Fatalf("couldn't create route: %s", err)
+		}
+
+		if statusCode != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, statusCode)
+		}
+	})
+
+	statusCode, response, err := createRoute(env.Server.URL, client, token, &CreateRouteParams{
+		Destination: "0.0.0.0/0", Gateway: "192.168.100.1", Interface: "n6", Metric: 10, DataNetwork: "enterprise",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create route: %s", err)
+	}
+
+	if statusCode != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, statusCode, response.Error)
+	}
+
+	t.Run("list filtered by data network", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", env.Server.URL+"/api/v1/networking/routes?data_network=enterprise", nil)
+		if err != nil {
+			t.Fatalf("couldn't build request: %s", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("couldn't list routes: %s", err)
+		}
+
+		defer func() {
+			_ = res.Body.Close()
+		}()
+
+		var listResponse ListRouteResponse
+		if err := json.NewDecoder(res.Body).Decode(&listResponse); err != nil {
+			t.Fatalf("couldn't decode response: %s", err)
+		}
+
+		if len(listResponse.Result.Items) != 1 || listResponse.Result.Items[0].DataNetwork != "enterprise" {
+			t.Fatalf("expected one route scoped to enterprise, got %+v", listResponse.Result.Items)
+		}
+	})
+
+	t.Run("data network with routes cannot be deleted", func(t *testing.T) {
+		statusCode, _, err := deleteDataNetwork(env.Server.URL, client, token, "enterprise")
+		if err != nil {
+			t.Fatalf("couldn't delete data network: %s", err)
+		}
+
+		if statusCode != http.StatusConflict {
+			t.Fatalf("expected status %d, got %d", http.StatusConflict, statusCode)
+		}
+	})
+}
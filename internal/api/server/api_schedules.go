@@ -0,0 +1,297 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// MaxNumScheduleWindows bounds the windows of one schedule.
+const MaxNumScheduleWindows = 32
+
+var errUnknownSchedule = errors.New("unknown schedule")
+
+type CreateScheduleParams struct {
+	Name     string                  `json:"name"`
+	TimeZone string                  `json:"time_zone"`
+	Windows  []models.ScheduleWindow `json:"windows"`
+}
+
+type UpdateScheduleParams struct {
+	TimeZone string                  `json:"time_zone"`
+	Windows  []models.ScheduleWindow `json:"windows"`
+}
+
+type ScheduleResponse struct {
+	Name     string                  `json:"name"`
+	TimeZone string                  `json:"time_zone"`
+	Windows  []models.ScheduleWindow `json:"windows"`
+	// Active reports whether the schedule is in a window now.
+	Active bool `json:"active"`
+}
+
+type ListSchedulesResponse struct {
+	Items []ScheduleResponse `json:"items"`
+}
+
+const (
+	CreateScheduleAction = "create_schedule"
+	UpdateScheduleAction = "update_schedule"
+	DeleteScheduleAction = "delete_schedule"
+)
+
+// validateSchedule checks the time zone and windows, returning the error
+// as the response message.
+func validateSchedule(timeZone string, windows []models.ScheduleWindow) error {
+	if timeZone == "" {
+		return errors.New("time_zone is missing")
+	}
+
+	if len(windows) > MaxNumScheduleWindows {
+		return fmt.Errorf("windows exceed the maximum of %d", MaxNumScheduleWindows)
+	}
+
+	if _, err := models.ParseSchedule(timeZone, windows); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func toScheduleResponse(s *db.Schedule, now time.Time) (ScheduleResponse, error) {
+	windows, err := s.WindowList()
+	if err != nil {
+		return ScheduleResponse{}, err
+	}
+
+	parsed, err := s.Parse()
+	if err != nil {
+		return ScheduleResponse{}, err
+	}
+
+	return ScheduleResponse{
+		Name:     s.Name,
+		TimeZone: s.TimeZone,
+		Windows:  windows,
+		Active:   parsed.ActiveAt(now),
+	}, nil
+}
+
+// scheduleIDsByName maps every schedule name to its ID.
+func scheduleIDsByName(ctx context.Context, dbInstance *db.Database) (map[string]string, error) {
+	schedules, err := dbInstance.ListSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string, len(schedules))
+	for _, s := range schedules {
+		ids[s.Name] = s.ID
+	}
+
+	return ids, nil
+}
+
+// scheduleNamesByID maps every schedule ID to its name.
+func scheduleNamesByID(ctx context.Context, dbInstance *db.Database) (map[string]string, error) {
+	schedules, err := dbInstance.ListSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(schedules))
+	for _, s := range schedules {
+		names[s.ID] = s.Name
+	}
+
+	return names, nil
+}
+
+func ListSchedules(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		schedules, err := dbInstance.ListSchedules(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list schedules", err, logger.APILog)
+			return
+		}
+
+		now := time.Now()
+		items := make([]ScheduleResponse, 0, len(schedules))
+
+		for i := range schedules {
+			item, err := toScheduleResponse(&schedules[i], now)
+			if err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to read schedule", err, logger.APILog)
+				return
+			}
+
+			items = append(items, item)
+		}
+
+		writeResponse(r.Context(), w, ListSchedulesResponse{Items: items}, http.StatusOK, logger.APILog)
+	})
+}
+
+func GetSchedule(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		schedule, err := dbInstance.GetSchedule(r.Context(), name)
+		if err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Schedule not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve schedule", err, logger.APILog)
+
+			return
+		}
+
+		resp, err := toScheduleResponse(schedule, time.Now())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to read schedule", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, resp, http.StatusOK, logger.APILog)
+	})
+}
+
+func CreateSchedule(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		var params CreateScheduleParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if params.Name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "name is missing", nil, logger.APILog)
+			return
+		}
+
+		if !isResourceNameValid(params.Name) {
+			writeError(r.Context(), w, http.StatusBadRequest, "invalid name format - must be less than 256 characters", nil, logger.APILog)
+			return
+		}
+
+		if err := validateSchedule(params.TimeZone, params.Windows); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "invalid schedule: "+err.Error(), nil, logger.APILog)
+			return
+		}
+
+		schedule := &db.Schedule{Name: params.Name, TimeZone: params.TimeZone}
+		if err := schedule.SetWindows(params.Windows); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create schedule", err, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.CreateSchedule(r.Context(), schedule); err != nil {
+			if errors.Is(err, db.ErrAlreadyExists) {
+				writeError(r.Context(), w, http.StatusConflict, "Schedule already exists", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to create schedule", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Schedule created successfully"}, http.StatusCreated, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), CreateScheduleAction, email, getClientIP(r), "User created schedule: "+params.Name)
+	})
+}
+
+func UpdateSchedule(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params UpdateScheduleParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if err := validateSchedule(params.TimeZone, params.Windows); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "invalid schedule: "+err.Error(), nil, logger.APILog)
+			return
+		}
+
+		schedule := &db.Schedule{Name: name, TimeZone: params.TimeZone}
+		if err := schedule.SetWindows(params.Windows); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update schedule", err, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.UpdateSchedule(r.Context(), schedule); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Schedule not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update schedule", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Schedule updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateScheduleAction, email, getClientIP(r), "User updated schedule: "+name)
+	})
+}
+
+func DeleteSchedule(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeleteSchedule(r.Context(), name); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Schedule not found", nil, logger.APILog)
+				return
+			}
+
+			if errors.Is(err, db.ErrScheduleInUse) {
+				writeError(r.Context(), w, http.StatusConflict, "Schedule is used by network rules or policies", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete schedule", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Schedule deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteScheduleAction, email, getClientIP(r), "User deleted schedule: "+name)
+	})
+}
@@ -0,0 +1,360 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type ScheduleWindow struct {
+	Days  []string `json:"days"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+type CreateScheduleParams struct {
+	Name     string           `json:"name"`
+	TimeZone string           `json:"time_zone"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+type Schedule struct {
+	Name     string           `json:"name"`
+	TimeZone string           `json:"time_zone"`
+	Windows  []ScheduleWindow `json:"windows"`
+	Active   bool             `json:"active"`
+}
+
+type GetScheduleResponse struct {
+	Result Schedule `json:"result"`
+	Error  string   `json:"error,omitempty"`
+}
+
+type ListSchedulesResponse struct {
+	Result struct {
+		Items []Schedule `json:"items"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+type ScheduleMessageResponse struct {
+	Result struct {
+		Message string `json:"message"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func doScheduleRequest(url string, client *http.Client, token, method, path string, data any, out any) (int, error) {
+	var body *strings.Reader
+
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return 0, err
+		}
+
+		body = strings.NewReader(string(b))
+	} else {
+		body = strings.NewReader("")
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url+"/api/v1/schedules"+path, body)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return 0, err
+	}
+
+	return res.StatusCode, nil
+}
+
+func createSchedule(url string, client *http.Client, token string, data *CreateScheduleParams) (int, *ScheduleMessageResponse, error) {
+	var resp ScheduleMessageResponse
+
+	status, err := doScheduleRequest(url, client, token, http.MethodPost, "", data, &resp)
+
+	return status, &resp, err
+}
+
+func getSchedule(url string, client *http.Client, token, name string) (int, *GetScheduleResponse, error) {
+	var resp GetScheduleResponse
+
+	status, err := doScheduleRequest(url, client, token, http.MethodGet, "/"+name, nil, &resp)
+
+	return status, &resp, err
+}
+
+func deleteSchedule(url string, client *http.Client, token, name string) (int, *ScheduleMessageResponse, error) {
+	var resp ScheduleMessageResponse
+
+	status, err := doScheduleRequest(url, client, token, http.MethodDelete, "/"+name, nil, &resp)
+
+	return status, &resp, err
+}
+
+var everyDay = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+func TestSchedulesCRUD(t *testing.T) {
+	env, err := setupServer(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	invalid := []struct {
+		name   string
+		params CreateScheduleParams
+	}{
+		{"missing name", CreateScheduleParams{TimeZone: "UTC", Windows: []ScheduleWindow{{Days: everyDay, Start: "08:00", End: "09:00"}}}},
+		{"missing time zone", CreateScheduleParams{Name: "s", Windows: []ScheduleWindow{{Days: everyDay, Start: "08:00", End: "09:00"}}}},
+		{"unknown time zone", CreateScheduleParams{Name: "s", TimeZone: "Mars/Olympus", Windows: []ScheduleWindow{{Days: everyDay, Start: "08:00", End: "09:00"}}}},
+		{"no windows", CreateScheduleParams{Name: "s", TimeZone: "UTC"}},
+		{"unknown day", CreateScheduleParams{Name: "s", TimeZone: "UTC", Windows: []ScheduleWindow{{Days: []string{"someday"}, Start: "08:00", End: "09:00"}}}},
+		{"malformed time", CreateScheduleParams{Name: "s", TimeZone: "UTC", Windows: []ScheduleWindow{{Days: everyDay, Start: "8am", End: "09:00"}}}},
+	}
+
+	for _, tc := range invalid {
+		status, _, err := createSchedule(env.Server.URL, client, token, &tc.params)
+		if err != nil {
+			t.Fatalf("%s: couldn't create schedule: %s", tc.name, err)
+		}
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, http.StatusBadRequest, status)
+		}
+	}
+
+	always := &CreateScheduleParams{
+		Name:     "always",
+		TimeZone: "America/Toronto",
+		Windows:  []ScheduleWindow{{Days: everyDay, Start: "00:00", End: "24:00"}},
+	}
+
+	status, resp, err := createSchedule(env.Server.URL, client, token, always)
+	if err != nil {
+		t.Fatalf("couldn't create schedule: %s", err)
+	}
+
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, status, resp.Error)
+	}
+
+	status, _, err = createSchedule(env.Server.URL, client, token, always)
+	if err != nil {
+		t.Fatalf("couldn't create schedule: %s", err)
+	}
+
+	if status != http.StatusConflict {
+		t.Fatalf("expected status %d for a duplicate, got %d", http.StatusConflict, status)
+	}
+
+	status, getResp, err := getSchedule(env.Server.URL, client, token, "always")
+	if err != nil {
+		t.Fatalf("couldn't get schedule: %s", err)
+	}
+
+	if status != http.StatusOK || getResp.Result.TimeZone != "America/Toronto" || len(getResp.Result.Windows) != 1 {
+		t.Fatalf("unexpected schedule (status %d): %+v", status, getResp.Result)
+	}
+
+	if !getResp.Result.Active {
+		t.Fatal("expected an all-day, every-day schedule to be active")
+	}
+
+	updateResp := &ScheduleMessageResponse{}
+
+	status, err = doScheduleRequest(env.Server.URL, client, token, http.MethodPut, "/always", &CreateScheduleParams{
+		TimeZone: "UTC",
+		Windows:  []ScheduleWindow{{Days: []string{"mon"}, Start: "01:00", End: "02:00"}},
+	}, updateResp)
+	if err != nil {
+		t.Fatalf("couldn't update schedule: %s", err)
+	}
+
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusOK, status, updateResp.Error)
+	}
+
+	var listResp ListSchedulesResponse
+
+	status, err = doScheduleRequest(env.Server.URL, client, token, http.MethodGet, "", nil, &listResp)
+	if err != nil {
+		t.Fatalf("couldn't list schedules: %s", err)
+	}
+
+	if status != http.StatusOK || len(listResp.Result.Items) != 1 || listResp.Result.Items[0].TimeZone != "UTC" {
+		t.Fatalf("unexpected schedule list (status %d): %+v", status, listResp.Result.Items)
+	}
+
+	status, _, err = deleteSchedule(env.Server.URL, client, token, "always")
+	if err != nil {
+		t.Fatalf("couldn't delete schedule: %s", err)
+	}
+
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+
+	status, _, err = getSchedule(env.Server.URL, client, token, "always")
+	if err != nil {
+		t.Fatalf("couldn't get schedule: %s", err)
+	}
+
+	if status != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestPolicyScheduledRulesAndAmbr(t *testing.T) {
+	env, err := setupServer(filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't initialize and login: %s", err)
+	}
+
+	_, _, err = createDataNetwork(env.Server.URL, client, token, &CreateDataNetworkParams{
+		Name: DataNetworkName, MTU: MTU, IPv4Pool: IPv4Pool, DNS: DNS,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	_, _, err = createProfile(env.Server.URL, client, token, &CreateProfileParams{
+		Name: "schedule-profile", UeAmbrUplink: "1 Gbps", UeAmbrDownlink: "1 Gbps",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create profile: %s", err)
+	}
+
+	status, _, err := createSchedule(env.Server.URL, client, token, &CreateScheduleParams{
+		Name:     "class-hours",
+		TimeZone: "America/Toronto",
+		Windows:  []ScheduleWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:30", End: "15:00"}},
+	})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("couldn't create schedule: status %d, err %v", status, err)
+	}
+
+	socialMedia := "157.240.0.0/16"
+
+	params := func(schedule string, scheduled *ScheduledAmbr) *CreatePolicyParams {
+		return &CreatePolicyParams{
+			Name:                "school-policy",
+			ProfileName:         "schedule-profile",
+			SliceName:           DefaultSliceName,
+			SessionAmbrUplink:   "100 Mbps",
+			SessionAmbrDownlink: "100 Mbps",
+			Var5qi:              9,
+			Arp:                 1,
+			DataNetworkName:     DataNetworkName,
+			Rules: &PolicyRules{Uplink: []PolicyRule{
+				{Description: "social media", RemotePrefix: &socialMedia, Action: "deny", Schedule: schedule},
+			}},
+			ScheduledAmbr: scheduled,
+		}
+	}
+
+	invalid := []struct {
+		name   string
+		params *CreatePolicyParams
+	}{
+		{"unknown rule schedule", params("weekends", nil)},
+		{"unknown ambr schedule", params("class-hours", &ScheduledAmbr{Schedule: "weekends", SessionAmbrUplink: "1 Mbps", SessionAmbrDownlink: "1 Mbps"})},
+		{"missing ambr schedule", params("", &ScheduledAmbr{SessionAmbrUplink: "1 Mbps", SessionAmbrDownlink: "1 Mbps"})},
+		{"malformed scheduled ambr", params("", &ScheduledAmbr{Schedule: "class-hours", SessionAmbrUplink: "fast", SessionAmbrDownlink: "1 Mbps"})},
+	}
+
+	for _, tc := range invalid {
+		status, _, err := createPolicy(env.Server.URL, client, token, tc.params)
+		if err != nil {
+			t.Fatalf("%s: couldn't create policy: %s", tc.name, err)
+		}
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, http.StatusBadRequest, status)
+		}
+	}
+
+	status, resp, err := createPolicy(env.Server.URL, client, token, params("class-hours", &ScheduledAmbr{
+		Schedule: "class-hours", SessionAmbrUplink: "5 Mbps", SessionAmbrDownlink: "10 Mbps",
+	}))
+	if err != nil {
+		t.Fatalf("couldn't create policy: %s", err)
+	}
+
+	if status != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusCreated, status, resp.Error)
+	}
+
+	_, getResp, err := getPolicy(env.Server.URL, client, token, "school-policy")
+	if err != nil {
+		t.Fatalf("couldn't get policy: %s", err)
+	}
+
+	if getResp.Result.Rules == nil || len(getResp.Result.Rules.Uplink) != 1 || getResp.Result.Rules.Uplink[0].Schedule != "class-hours" {
+		t.Fatalf("expected the rule to carry its schedule, got %+v", getResp.Result.Rules)
+	}
+
+	scheduled := getResp.Result.ScheduledAmbr
+	if scheduled == nil || scheduled.Schedule != "class-hours" || scheduled.SessionAmbrUplink != "5 Mbps" || scheduled.SessionAmbrDownlink != "10 Mbps" {
+		t.Fatalf("unexpected scheduled Session-AMBR: %+v", scheduled)
+	}
+
+	status, _, err = deleteSchedule(env.Server.URL, client, token, "class-hours")
+	if err != nil {
+		t.Fatalf("couldn't delete schedule: %s", err)
+	}
+
+	if status != http.StatusConflict {
+		t.Fatalf("expected status %d for a schedule in use, got %d", http.StatusConflict, status)
+	}
+
+	status, _, err = deletePolicy(env.Server.URL, client, token, "school-policy")
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("couldn't delete policy: status %d, err %v", status, err)
+	}
+
+	status, _, err = deleteSchedule(env.Server.URL, client, token, "class-hours")
+	if err != nil {
+		t.Fatalf("couldn't delete schedule: %s", err)
+	}
+
+	if status != http.StatusOK {
+		t.Fatalf("expected status %d once unused, got %d", http.StatusOK, status)
+	}
+}
@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/nas"
+)
+
+const (
+	UpdateSliceQuotaAction = "update_slice_quota"
+	DeleteSliceQuotaAction = "delete_slice_quota"
+)
+
+// SliceQuotaParams caps a slice. A zero limit is no limit, and a zero
+// BackOffTimer sends the default back-off.
+type SliceQuotaParams struct {
+	MaxRegisteredUEs         int `json:"max_registered_ues"`
+	MaxSessions              int `json:"max_sessions"`
+	MaxSessionsPerSubscriber int `json:"max_sessions_per_subscriber"`
+	BackOffTimer             int `json:"back_off_timer"`
+}
+
+// SliceQuotaResponse is a slice's quota alongside the UEs and sessions the
+// cluster currently counts against it.
+type SliceQuotaResponse struct {
+	MaxRegisteredUEs         int `json:"max_registered_ues"`
+	MaxSessions              int `json:"max_sessions"`
+	MaxSessionsPerSubscriber int `json:"max_sessions_per_subscriber"`
+	BackOffTimer             int `json:"back_off_timer"`
+	RegisteredUEs            int `json:"registered_ues"`
+	Sessions                 int `json:"sessions"`
+}
+
+func GetSliceQuota(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		slice, err := dbInstance.GetNetworkSlice(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Slice not found", nil, logger.APILog)
+			return
+		}
+
+		var resp SliceQuotaResponse
+
+		quota, err := dbInstance.GetNetworkSliceQuota(r.Context(), slice.ID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get slice quota", err, logger.APILog)
+			return
+		}
+
+		if quota != nil {
+			resp.MaxRegisteredUEs = quota.MaxRegisteredUEs
+			resp.MaxSessions = quota.MaxSessions
+			resp.MaxSessionsPerSubscriber = quota.MaxSessionsPerSubscriber
+			resp.BackOffTimer = quota.BackOffTimer
+		}
+
+		sd := ""
+		if slice.Sd != nil {
+			sd = *slice.Sd
+		}
+
+		snssai := models.Snssai{Sst: slice.Sst, Sd: sd}
+
+		resp.RegisteredUEs, err = dbInstance.CountSliceRegistrations(r.Context(), snssai)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to count slice registrations", err, logger.APILog)
+			return
+		}
+
+		resp.Sessions, err = dbInstance.CountAdmittedSessionsBySlice(r.Context(), snssai)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to count slice sessions", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, resp, http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateSliceQuota(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		var params SliceQuotaParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		switch {
+		case params.MaxRegisteredUEs < 0:
+			writeError(r.Context(), w, http.StatusBadRequest, "max_registered_ues must not be negative", nil, logger.APILog)
+			return
+		case params.MaxSessions < 0:
+			writeError(r.Context(), w, http.StatusBadRequest, "max_sessions must not be negative", nil, logger.APILog)
+			return
+		case params.MaxSessionsPerSubscriber < 0:
+			writeError(r.Context(), w, http.StatusBadRequest, "max_sessions_per_subscriber must not be negative", nil, logger.APILog)
+			return
+		}
+
+		if err := validateBackOffTimer(params.BackOffTimer); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		slice, err := dbInstance.GetNetworkSlice(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Slice not found", nil, logger.APILog)
+			return
+		}
+
+		quota := &db.NetworkSliceQuota{
+			NetworkSliceID:           slice.ID,
+			MaxRegisteredUEs:         params.MaxRegisteredUEs,
+			MaxSessions:              params.MaxSessions,
+			MaxSessionsPerSubscriber: params.MaxSessionsPerSubscriber,
+			BackOffTimer:             params.BackOffTimer,
+		}
+
+		if err := dbInstance.SetNetworkSliceQuota(r.Context(), quota); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update slice quota", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Slice quota updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateSliceQuotaAction, email, getClientIP(r),
+			"User set the quota of slice "+name)
+	})
+}
+
+func DeleteSliceQuota(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		name := r.PathValue("name")
+		if name == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing name parameter", nil, logger.APILog)
+			return
+		}
+
+		slice, err := dbInstance.GetNetworkSlice(r.Context(), name)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Slice not found", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.ClearNetworkSliceQuota(r.Context(), slice.ID); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete slice quota", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Slice quota deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteSliceQuotaAction, email, getClientIP(r),
+			"User deleted the quota of slice "+name)
+	})
+}
+
+// validateBackOffTimer checks that a quota's back-off, in seconds, fits both
+// the GPRS timer 2 of T3346 and the GPRS timer 3 of T3396 and the 5GSM
+// back-off timer (TS 24.008 §10.5.7.4, §10.5.7.4a).
+func validateBackOffTimer(seconds int) error {
+	if seconds < 0 {
+		return errors.New("back_off_timer must not be negative")
+	}
+
+	if seconds == 0 {
+		return nil
+	}
+
+	d := time.Duration(seconds) * time.Second
+
+	_, err2 := nas.GPRSTimer2FromDuration(d)
+	_, err3 := nas.GPRSTimer3FromDuration(d)
+
+	if err2 != nil || err3 != nil {
+		return errors.New("back_off_timer must be a multiple of 2 seconds up to 62, of 1 minute up to 31 minutes, or of 30 minutes up to 180 minutes")
+	}
+
+	return nil
+}
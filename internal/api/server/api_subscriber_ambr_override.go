@@ -0,0 +1,234 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const (
+	SetSubscriberAmbrOverrideAction    = "set_subscriber_ambr_override"
+	DeleteSubscriberAmbrOverrideAction = "delete_subscriber_ambr_override"
+)
+
+const (
+	// MinAmbrOverrideDuration and MaxAmbrOverrideDuration bound, in seconds,
+	// how long an override given a duration may last.
+	MinAmbrOverrideDuration = 60
+	MaxAmbrOverrideDuration = 30 * 24 * 60 * 60
+)
+
+// SubscriberAmbrOverrideParams replaces a subscriber's UE-AMBR, Session-AMBR
+// or both, each given as an uplink and downlink pair. A zero Duration keeps
+// the override until it is deleted.
+type SubscriberAmbrOverrideParams struct {
+	UeAmbrUplink        string `json:"ue_ambr_uplink,omitempty"`
+	UeAmbrDownlink      string `json:"ue_ambr_downlink,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink,omitempty"`
+	Duration            int64  `json:"duration,omitempty"`
+}
+
+type SubscriberAmbrOverrideResponse struct {
+	UeAmbrUplink        string `json:"ue_ambr_uplink,omitempty"`
+	UeAmbrDownlink      string `json:"ue_ambr_downlink,omitempty"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink,omitempty"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink,omitempty"`
+	CreatedAt           string `json:"created_at"`
+	ExpiresAt           string `json:"expires_at,omitempty"`
+}
+
+func subscriberAmbrOverrideFromDB(o *db.SubscriberAmbrOverride) SubscriberAmbrOverrideResponse {
+	resp := SubscriberAmbrOverrideResponse{
+		UeAmbrUplink:        o.UeAmbrUplink,
+		UeAmbrDownlink:      o.UeAmbrDownlink,
+		SessionAmbrUplink:   o.SessionAmbrUplink,
+		SessionAmbrDownlink: o.SessionAmbrDownlink,
+		CreatedAt:           time.Unix(o.CreatedAt, 0).UTC().Format(time.RFC3339),
+	}
+
+	if o.ExpiresAt > 0 {
+		resp.ExpiresAt = time.Unix(o.ExpiresAt, 0).UTC().Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+func GetSubscriberAmbrOverride(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		imsi := r.PathValue("imsi")
+		if imsi == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing imsi parameter", nil, logger.APILog)
+			return
+		}
+
+		if _, err := dbInstance.GetSubscriber(r.Context(), imsi); err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Subscriber not found", nil, logger.APILog)
+			return
+		}
+
+		// An expired override awaiting the revert job no longer applies.
+		o, err := dbInstance.ActiveSubscriberAmbrOverride(r.Context(), imsi, time.Now())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get subscriber AMBR override", err, logger.APILog)
+			return
+		}
+
+		if o == nil {
+			writeError(r.Context(), w, http.StatusNotFound, "AMBR override not found", nil, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, subscriberAmbrOverrideFromDB(o), http.StatusOK, logger.APILog)
+	})
+}
+
+func SetSubscriberAmbrOverride(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		imsi := r.PathValue("imsi")
+		if imsi == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing imsi parameter", nil, logger.APILog)
+			return
+		}
+
+		var params SubscriberAmbrOverrideParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if err := validateAmbrOverride(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		subscriber, err := dbInstance.GetSubscriber(r.Context(), imsi)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Subscriber not found", nil, logger.APILog)
+			return
+		}
+
+		profile, err := dbInstance.GetProfileByID(r.Context(), subscriber.ProfileID)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get subscriber profile", err, logger.APILog)
+			return
+		}
+
+		// The override must reach the radio on every RAT the profile allows,
+		// just as the profile's and policies' own bitrates must.
+		if err := checkAmbrOverrideEncodable(profile, &params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		now := time.Now().Unix()
+		o := &db.SubscriberAmbrOverride{
+			IMSI:                imsi,
+			UeAmbrUplink:        params.UeAmbrUplink,
+			UeAmbrDownlink:      params.UeAmbrDownlink,
+			SessionAmbrUplink:   params.SessionAmbrUplink,
+			SessionAmbrDownlink: params.SessionAmbrDownlink,
+			CreatedAt:           now,
+		}
+
+		if params.Duration > 0 {
+			o.ExpiresAt = now + params.Duration
+		}
+
+		if err := dbInstance.SetSubscriberAmbrOverride(r.Context(), o); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to set subscriber AMBR override", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, subscriberAmbrOverrideFromDB(o), http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), SetSubscriberAmbrOverrideAction, email, getClientIP(r),
+			fmt.Sprintf("User set the AMBR override of subscriber %s", imsi))
+	})
+}
+
+func DeleteSubscriberAmbrOverride(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		imsi := r.PathValue("imsi")
+		if imsi == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing imsi parameter", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeleteSubscriberAmbrOverride(r.Context(), imsi); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "AMBR override not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete subscriber AMBR override", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Subscriber AMBR override deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteSubscriberAmbrOverrideAction, email, getClientIP(r),
+			"User deleted the AMBR override of subscriber "+imsi)
+	})
+}
+
+func validateAmbrOverride(p *SubscriberAmbrOverrideParams) error {
+	ue := p.UeAmbrUplink != "" || p.UeAmbrDownlink != ""
+	session := p.SessionAmbrUplink != "" || p.SessionAmbrDownlink != ""
+
+	switch {
+	case !ue && !session:
+		return errors.New("an AMBR override needs a UE-AMBR, a Session-AMBR or both")
+	case ue && !isValidBitrate(p.UeAmbrUplink):
+		return errors.New("invalid ue_ambr_uplink format - must be in the format `<number> <unit>`, allowed units are Mbps, Gbps")
+	case ue && !isValidBitrate(p.UeAmbrDownlink):
+		return errors.New("invalid ue_ambr_downlink format - must be in the format `<number> <unit>`, allowed units are Mbps, Gbps")
+	case session && !isValidBitrate(p.SessionAmbrUplink):
+		return errors.New("invalid session_ambr_uplink format - must be in the format `<number> <unit>`, allowed units are Mbps, Gbps")
+	case session && !isValidBitrate(p.SessionAmbrDownlink):
+		return errors.New("invalid session_ambr_downlink format - must be in the format `<number> <unit>`, allowed units are Mbps, Gbps")
+	}
+
+	if p.Duration != 0 && (p.Duration < MinAmbrOverrideDuration || p.Duration > MaxAmbrOverrideDuration) {
+		return fmt.Errorf("duration must be between %d and %d seconds", MinAmbrOverrideDuration, MaxAmbrOverrideDuration)
+	}
+
+	return nil
+}
+
+func checkAmbrOverrideEncodable(profile *db.Profile, p *SubscriberAmbrOverrideParams) error {
+	if p.UeAmbrUplink != "" {
+		if err := checkUeAmbrEncodable(profile.Allow4G, profile.Allow5G, "ue_ambr_uplink", p.UeAmbrUplink); err != nil {
+			return err
+		}
+
+		if err := checkUeAmbrEncodable(profile.Allow4G, profile.Allow5G, "ue_ambr_downlink", p.UeAmbrDownlink); err != nil {
+			return err
+		}
+	}
+
+	if p.SessionAmbrUplink != "" {
+		if err := checkSessionAmbrEncodable(profile.Allow4G, profile.Allow5G, "session_ambr_uplink", p.SessionAmbrUplink); err != nil {
+			return err
+		}
+
+		if err := checkSessionAmbrEncodable(profile.Allow4G, profile.Allow5G, "session_ambr_downlink", p.SessionAmbrDownlink); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
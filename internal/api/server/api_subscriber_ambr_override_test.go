@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+type subscriberAmbrOverride struct {
+	UeAmbrUplink        string `json:"ue_ambr_uplink"`
+	UeAmbrDownlink      string `json:"ue_ambr_downlink"`
+	SessionAmbrUplink   string `json:"session_ambr_uplink"`
+	SessionAmbrDownlink string `json:"session_ambr_downlink"`
+	CreatedAt           string `json:"created_at"`
+	ExpiresAt           string `json:"expires_at"`
+}
+
+type subscriberAmbrOverrideResponse struct {
+	Result subscriberAmbrOverride `json:"result"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+func TestAPISubscriberAmbrOverrideEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	code, _, err := createProfile(url, client, token, &CreateProfileParams{Name: TestProfileName, UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps"})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create profile: %d (%v)", code, err)
+	}
+
+	code, _, err = createPolicy(url, client, token, &CreatePolicyParams{
+		Name:                PolicyName,
+		ProfileName:         TestProfileName,
+		SliceName:           DefaultSliceName,
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "100 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkName:     "internet",
+	})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create policy: %d (%v)", code, err)
+	}
+
+	code, resp, err := createSubscriber(url, client, token, &CreateSubscriberParams{Imsi: Imsi, Key: Key, Opc: Opc, SequenceNumber: SequenceNumber, ProfileName: TestProfileName})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create subscriber: %d (%v, %s)", code, err, resp.Error)
+	}
+
+	overrideURL := url + "/api/v1/subscribers/" + Imsi + "/ambr-override"
+
+	t.Run("none at first", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "GET", overrideURL, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+
+	t.Run("set and get", func(t *testing.T) {
+		body := map[string]any{
+			"session_ambr_uplink":   "50 Mbps",
+			"session_ambr_downlink": "500 Mbps",
+			"duration":              7200,
+		}
+
+		var set subscriberAmbrOverrideResponse
+
+		code, err := doNATRequest(client, "PUT", overrideURL, token, body, &set)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, set.Error)
+		}
+
+		r := set.Result
+		if r.SessionAmbrDownlink != "500 Mbps" || r.UeAmbrUplink != "" || r.ExpiresAt <= r.CreatedAt {
+			t.Fatalf("unexpected override: %+v", r)
+		}
+
+		var got subscriberAmbrOverrideResponse
+
+		code, err = doNATRequest(client, "GET", overrideURL, token, nil, &got)
+		if err != nil || code != http.StatusOK || got.Result != set.Result {
+			t.Fatalf("expected the override set, got %d (%v, %+v)", code, err, got.Result)
+		}
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"nothing overridden", map[string]any{"duration": 3600}},
+			{"half a pair", map[string]any{"ue_ambr_uplink": "10 Mbps"}},
+			{"not a bitrate", map[string]any{"ue_ambr_uplink": "fast", "ue_ambr_downlink": "10 Mbps"}},
+			{"beyond the 4G UE-AMBR ceiling", map[string]any{"ue_ambr_uplink": "20 Gbps", "ue_ambr_downlink": "20 Gbps"}},
+			{"duration too short", map[string]any{"ue_ambr_uplink": "10 Mbps", "ue_ambr_downlink": "10 Mbps", "duration": 10}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", overrideURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("unknown subscriber", func(t *testing.T) {
+		body := map[string]any{"ue_ambr_uplink": "10 Mbps", "ue_ambr_downlink": "10 Mbps"}
+
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "PUT", url+"/api/v1/subscribers/001019999999999/ambr-override", token, body, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "DELETE", overrideURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		code, err = doNATRequest(client, "GET", overrideURL, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		code, err = doNATRequest(client, "DELETE", overrideURL, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
@@ -0,0 +1,662 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	ImportSubscribersAction = "import_subscribers"
+	ExportSubscribersAction = "export_subscribers"
+)
+
+const (
+	// MaxSubscriberImportRows bounds one import. A SIM vendor batch of 5,000
+	// cards fits in one request, and so in one changeset.
+	MaxSubscriberImportRows = 5000
+	// exportSubscribersBatchSize is how many subscribers an export reads
+	// between flushes to the client.
+	exportSubscribersBatchSize = 500
+)
+
+// Subscriber import modes. All-or-nothing writes nothing unless every row
+// is valid; best-effort writes the valid rows and reports the others.
+const (
+	SubscriberImportAllOrNothing = "all_or_nothing"
+	SubscriberImportBestEffort   = "best_effort"
+)
+
+// subscriberImportColumns are the CSV columns of an import or export, named
+// after the fields of CreateSubscriberParams. opc may be left out of an
+// import, in which case it is derived from the operator code.
+var subscriberImportColumns = []string{"imsi", "key", "opc", "sequenceNumber", "profile_name"}
+
+// SubscriberImportRowError is why one row of an import was rejected. Row is
+// the 1-based position of the row, not counting the CSV header.
+type SubscriberImportRowError struct {
+	Row   int    `json:"row"`
+	Imsi  string `json:"imsi,omitempty"`
+	Error string `json:"error"`
+}
+
+type SubscriberImportReport struct {
+	Mode     string                     `json:"mode"`
+	DryRun   bool                       `json:"dry_run"`
+	Total    int                        `json:"total"`
+	Valid    int                        `json:"valid"`
+	Imported int                        `json:"imported"`
+	Errors   []SubscriberImportRowError `json:"errors"`
+}
+
+// SubscriberExportRecord is one exported subscriber. Credentials are only
+// filled in when requested, and the record then reads back as an import
+// row.
+type SubscriberExportRecord struct {
+	Imsi           string `json:"imsi"`
+	Key            string `json:"key,omitempty"`
+	Opc            string `json:"opc,omitempty"`
+	SequenceNumber string `json:"sequenceNumber,omitempty"`
+	ProfileName    string `json:"profile_name"`
+}
+
+// readSubscriberImport decodes an import body, CSV with a header row or a
+// JSON array of CreateSubscriberParams, depending on its content type.
+func readSubscriberImport(r *http.Request) ([]CreateSubscriberParams, error) {
+	mediaType := "application/json"
+
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content type: %w", err)
+		}
+
+		mediaType = parsed
+	}
+
+	var (
+		rows []CreateSubscriberParams
+		err  error
+	)
+
+	switch mediaType {
+	case "text/csv":
+		rows, err = readSubscriberImportCSV(r.Body)
+	case "application/json":
+		err = json.NewDecoder(r.Body).Decode(&rows)
+	default:
+		return nil, fmt.Errorf("unsupported content type %q, must be text/csv or application/json", mediaType)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no subscribers to import")
+	}
+
+	if len(rows) > MaxSubscriberImportRows {
+		return nil, fmt.Errorf("too many subscribers, an import takes at most %d", MaxSubscriberImportRows)
+	}
+
+	return rows, nil
+}
+
+func readSubscriberImportCSV(body io.Reader) ([]CreateSubscriberParams, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing CSV header")
+		}
+
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+
+	for i, name := range header {
+		// Spreadsheets often save CSV with a byte order mark.
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+
+		if !slices.Contains(subscriberImportColumns, name) {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+
+		if _, dup := columns[name]; dup {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+
+		columns[name] = i
+	}
+
+	for _, name := range []string{"imsi", "key", "sequenceNumber", "profile_name"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing CSV column %q", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []CreateSubscriberParams
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		if len(rows) == MaxSubscriberImportRows {
+			return nil, fmt.Errorf("too many subscribers, an import takes at most %d", MaxSubscriberImportRows)
+		}
+
+		rows = append(rows, CreateSubscriberParams{
+			Imsi:           field(record, "imsi"),
+			Key:            field(record, "key"),
+			Opc:            field(record, "opc"),
+			SequenceNumber: field(record, "sequenceNumber"),
+			ProfileName:    field(record, "profile_name"),
+		})
+	}
+
+	return rows, nil
+}
+
+// subscriberImportValidator applies the checks of CreateSubscriber to every
+// row of an import, looking the operator and each profile up once.
+type subscriberImportValidator struct {
+	db       *db.Database
+	mcc      string
+	mnc      string
+	opCode   []byte
+	profiles map[string]importProfile
+	seen     map[string]struct{}
+}
+
+// importProfile is a profile named by an import: its ID, or why rows
+// cannot be assigned to it.
+type importProfile struct {
+	id  string
+	err string
+}
+
+func newSubscriberImportValidator(ctx context.Context, dbInstance *db.Database) (*subscriberImportValidator, error) {
+	operator, err := dbInstance.GetOperator(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get operator: %w", err)
+	}
+
+	operatorCode, err := dbInstance.GetOperatorCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get operator code: %w", err)
+	}
+
+	opCode, err := hex.DecodeString(operatorCode)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode operator code: %w", err)
+	}
+
+	return &subscriberImportValidator{
+		db:       dbInstance,
+		mcc:      operator.Mcc,
+		mnc:      operator.Mnc,
+		opCode:   opCode,
+		profiles: make(map[string]importProfile),
+		seen:     make(map[string]struct{}),
+	}, nil
+}
+
+func (v *subscriberImportValidator) profile(ctx context.Context, name string) (importProfile, error) {
+	if p, ok := v.profiles[name]; ok {
+		return p, nil
+	}
+
+	var p importProfile
+
+	profile, err := v.db.GetProfile(ctx, name)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			return p, fmt.Errorf("couldn't get profile: %w", err)
+		}
+
+		p.err = "Profile not found"
+	} else {
+		policyCount, err := v.db.CountPoliciesInProfile(ctx, profile.ID)
+		if err != nil {
+			return p, fmt.Errorf("couldn't check policies: %w", err)
+		}
+
+		if policyCount < 1 {
+			p.err = "Profile has no policy; create a policy for this profile before assigning subscribers"
+		} else {
+			p.id = profile.ID
+		}
+	}
+
+	v.profiles[name] = p
+
+	return p, nil
+}
+
+// validate returns the subscriber a row creates, or why it cannot be
+// created. The error is only set when the check itself failed.
+func (v *subscriberImportValidator) validate(ctx context.Context, params CreateSubscriberParams) (*db.Subscriber, string, error) {
+	switch {
+	case params.Imsi == "":
+		return nil, "Missing imsi parameter", nil
+	case params.ProfileName == "":
+		return nil, "Missing profile_name parameter", nil
+	case params.SequenceNumber == "":
+		return nil, "Missing sequenceNumber parameter", nil
+	}
+
+	if _, err := etsi.NewSUPIFromIMSI(params.Imsi); err != nil || !imsiInHomeNetwork(params.Imsi, v.mcc, v.mnc) {
+		return nil, "Invalid IMSI format. Must be a string of 6 to 15 digits starting with `<mcc><mnc>`.", nil
+	}
+
+	switch {
+	case !isSequenceNumberValid(params.SequenceNumber):
+		return nil, "Invalid sequenceNumber. Must be a 6-byte hexadecimal string.", nil
+	case !isHexOfLength(params.Key, 16):
+		return nil, "Invalid key format. Must be a 32-character hexadecimal string.", nil
+	case params.Opc != "" && !isHexOfLength(params.Opc, 16):
+		return nil, "Invalid OPC format. Must be a 32-character hexadecimal string.", nil
+	}
+
+	if _, dup := v.seen[params.Imsi]; dup {
+		return nil, "Duplicate imsi in import", nil
+	}
+
+	v.seen[params.Imsi] = struct{}{}
+
+	profile, err := v.profile(ctx, params.ProfileName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if profile.err != "" {
+		return nil, profile.err, nil
+	}
+
+	if _, err := v.db.GetSubscriber(ctx, params.Imsi); err == nil {
+		return nil, "Subscriber already exists", nil
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return nil, "", fmt.Errorf("couldn't get subscriber: %w", err)
+	}
+
+	opcHex := params.Opc
+	if opcHex == "" {
+		keyBytes, _ := hex.DecodeString(params.Key)
+		derivedOPC, _ := deriveOPc(keyBytes, v.opCode)
+		opcHex = hex.EncodeToString(derivedOPC)
+	}
+
+	return &db.Subscriber{
+		Imsi:           params.Imsi,
+		SequenceNumber: params.SequenceNumber,
+		PermanentKey:   params.Key,
+		Opc:            opcHex,
+		ProfileID:      profile.id,
+	}, "", nil
+}
+
+func ImportSubscribers(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			mode = SubscriberImportAllOrNothing
+		}
+
+		if mode != SubscriberImportAllOrNothing && mode != SubscriberImportBestEffort {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid mode, must be all_or_nothing or best_effort", nil, logger.APILog)
+			return
+		}
+
+		dryRun := false
+
+		if raw := r.URL.Query().Get("dry_run"); raw != "" {
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				writeError(r.Context(), w, http.StatusBadRequest, "Invalid dry_run, must be true or false", nil, logger.APILog)
+				return
+			}
+
+			dryRun = parsed
+		}
+
+		rows, err := readSubscriberImport(r)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				writeError(r.Context(), w, http.StatusRequestEntityTooLarge, "Import is too large", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data: "+err.Error(), nil, logger.APILog)
+
+			return
+		}
+
+		validator, err := newSubscriberImportValidator(r.Context(), dbInstance)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to prepare import", err, logger.APILog)
+			return
+		}
+
+		numSubscribers, err := dbInstance.CountSubscribers(r.Context())
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to count subscribers", err, logger.APILog)
+			return
+		}
+
+		report := SubscriberImportReport{
+			Mode:   mode,
+			DryRun: dryRun,
+			Total:  len(rows),
+			Errors: []SubscriberImportRowError{},
+		}
+
+		valid := make([]db.Subscriber, 0, len(rows))
+		rowOf := make(map[string]int, len(rows))
+
+		for i, params := range rows {
+			sub, reason, err := validator.validate(r.Context(), params)
+			if err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to validate import", err, logger.APILog)
+				return
+			}
+
+			if reason == "" && numSubscribers+len(valid) >= MaxNumSubscribers {
+				reason = "Maximum number of subscribers reached (" + strconv.Itoa(MaxNumSubscribers) + ")"
+			}
+
+			if reason != "" {
+				report.Errors = append(report.Errors, SubscriberImportRowError{Row: i + 1, Imsi: params.Imsi, Error: reason})
+				continue
+			}
+
+			valid = append(valid, *sub)
+			rowOf[sub.Imsi] = i + 1
+		}
+
+		report.Valid = len(valid)
+
+		if dryRun || len(valid) == 0 || (mode == SubscriberImportAllOrNothing && len(report.Errors) > 0) {
+			writeResponse(r.Context(), w, report, http.StatusOK, logger.APILog)
+			return
+		}
+
+		result, err := dbInstance.ImportSubscribers(r.Context(), valid, mode == SubscriberImportBestEffort)
+		if err != nil {
+			if errors.Is(err, db.ErrAlreadyExists) {
+				writeError(r.Context(), w, http.StatusConflict, "A subscriber in the import was created meanwhile; nothing was imported", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to import subscribers", err, logger.APILog)
+
+			return
+		}
+
+		for _, imsi := range result.Existing {
+			report.Errors = append(report.Errors, SubscriberImportRowError{Row: rowOf[imsi], Imsi: imsi, Error: "Subscriber already exists"})
+		}
+
+		slices.SortFunc(report.Errors, func(a, b SubscriberImportRowError) int { return a.Row - b.Row })
+
+		report.Imported = len(result.Created)
+
+		writeResponse(r.Context(), w, report, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), ImportSubscribersAction, email, getClientIP(r), fmt.Sprintf("User imported %d of %d subscribers (%s)", report.Imported, report.Total, mode))
+	})
+}
+
+// subscriberExportWriter writes an export in one of its formats.
+type subscriberExportWriter interface {
+	write(rec SubscriberExportRecord) error
+	// flush completes whatever write left buffered.
+	flush() error
+	// close ends the export.
+	close() error
+}
+
+type csvSubscriberExport struct {
+	w           *csv.Writer
+	credentials bool
+}
+
+func newCSVSubscriberExport(w io.Writer, credentials bool) (*csvSubscriberExport, error) {
+	e := &csvSubscriberExport{w: csv.NewWriter(w), credentials: credentials}
+
+	header := []string{"imsi", "profile_name"}
+	if credentials {
+		header = subscriberImportColumns
+	}
+
+	if err := e.w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *csvSubscriberExport) write(rec SubscriberExportRecord) error {
+	if !e.credentials {
+		return e.w.Write([]string{rec.Imsi, rec.ProfileName})
+	}
+
+	return e.w.Write([]string{rec.Imsi, rec.Key, rec.Opc, rec.SequenceNumber, rec.ProfileName})
+}
+
+func (e *csvSubscriberExport) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvSubscriberExport) close() error {
+	return e.flush()
+}
+
+// jsonSubscriberExport writes a JSON array, one element at a time.
+type jsonSubscriberExport struct {
+	w io.Writer
+	n int
+}
+
+func newJSONSubscriberExport(w io.Writer) (*jsonSubscriberExport, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+
+	return &jsonSubscriberExport{w: w}, nil
+}
+
+func (e *jsonSubscriberExport) write(rec SubscriberExportRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if e.n > 0 {
+		b = append([]byte(","), b...)
+	}
+
+	e.n++
+
+	_, err = e.w.Write(b)
+
+	return err
+}
+
+func (e *jsonSubscriberExport) flush() error {
+	return nil
+}
+
+func (e *jsonSubscriberExport) close() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+func ExportSubscribers(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+
+		if format != "csv" && format != "json" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid format, must be csv or json", nil, logger.APILog)
+			return
+		}
+
+		credentials := false
+
+		if raw := r.URL.Query().Get("include_credentials"); raw != "" {
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				writeError(r.Context(), w, http.StatusBadRequest, "Invalid include_credentials, must be true or false", nil, logger.APILog)
+				return
+			}
+
+			credentials = parsed
+		}
+
+		if credentials && !hasPermission(r, PermReadSubscriberCredentials) {
+			writeError(r.Context(), w, http.StatusForbidden, "Forbidden", errors.New("permission denied"), logger.APILog)
+			return
+		}
+
+		profiles, _, err := dbInstance.ListProfilesPage(r.Context(), 1, 1000)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to list profiles", err, logger.APILog)
+			return
+		}
+
+		profileNames := make(map[string]string, len(profiles))
+		for _, p := range profiles {
+			profileNames[p.ID] = p.Name
+		}
+
+		// Audited however the export ends, with how far it got: a client
+		// that drops the connection part way has still read what was sent.
+		exported := 0
+		completed := false
+
+		defer func() {
+			what := "subscribers"
+			if credentials {
+				what += " with credentials"
+			}
+
+			details := fmt.Sprintf("User exported %d %s", exported, what)
+			if !completed {
+				details = fmt.Sprintf("User's export of %s failed after %d subscribers", what, exported)
+			}
+
+			logger.LogAuditEvent(r.Context(), ExportSubscribersAction, email, getClientIP(r), details)
+		}()
+
+		w.Header().Set("Content-Disposition", "attachment; filename=\"subscribers."+format+"\"")
+
+		var export subscriberExportWriter
+
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			export, err = newCSVSubscriberExport(w, credentials)
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			export, err = newJSONSubscriberExport(w)
+		}
+
+		// Once the first byte is out the status is sent, so a failure from
+		// here on can only cut the export short.
+		log := logger.WithTrace(r.Context(), logger.APILog)
+		if err != nil {
+			log.Warn("Failed to write subscriber export", zap.Error(err))
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		after := ""
+
+		for {
+			page, err := dbInstance.ListSubscribersAfter(r.Context(), after, exportSubscribersBatchSize)
+			if err != nil {
+				log.Error("Failed to list subscribers for export", zap.Error(err))
+				return
+			}
+
+			if len(page) == 0 {
+				break
+			}
+
+			for _, sub := range page {
+				rec := SubscriberExportRecord{Imsi: sub.Imsi, ProfileName: profileNames[sub.ProfileID]}
+				if credentials {
+					rec.Key = sub.PermanentKey
+					rec.Opc = sub.Opc
+					rec.SequenceNumber = sub.SequenceNumber
+				}
+
+				if err := export.write(rec); err != nil {
+					log.Warn("Failed to write subscriber export", zap.Error(err))
+					return
+				}
+
+				exported++
+			}
+
+			after = page[len(page)-1].Imsi
+
+			if err := export.flush(); err != nil {
+				log.Warn("Failed to write subscriber export", zap.Error(err))
+				return
+			}
+
+			_ = rc.Flush()
+		}
+
+		if err := export.close(); err != nil {
+			log.Warn("Failed to write subscriber export", zap.Error(err))
+			return
+		}
+
+		completed = true
+	})
+}
@@ -0,0 +1,313 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type subscriberImportReportResponse struct {
+	Result struct {
+		Mode     string `json:"mode"`
+		DryRun   bool   `json:"dry_run"`
+		Total    int    `json:"total"`
+		Valid    int    `json:"valid"`
+		Imported int    `json:"imported"`
+		Errors   []struct {
+			Row   int    `json:"row"`
+			Imsi  string `json:"imsi"`
+			Error string `json:"error"`
+		} `json:"errors"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+type subscriberExportRecord struct {
+	Imsi           string `json:"imsi"`
+	Key            string `json:"key"`
+	Opc            string `json:"opc"`
+	SequenceNumber string `json:"sequenceNumber"`
+	ProfileName    string `json:"profile_name"`
+}
+
+func doRawRequest(client *http.Client, method, url, token, contentType, body string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	raw, err := io.ReadAll(res.Body)
+
+	return res.StatusCode, raw, err
+}
+
+func importSubscribers(client *http.Client, url, token, query, contentType, body string) (int, *subscriberImportReportResponse, error) {
+	code, raw, err := doRawRequest(client, "POST", url+"/api/v1/subscribers/import"+query, token, contentType, body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var resp subscriberImportReportResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return code, nil, fmt.Errorf("couldn't decode %q: %w", raw, err)
+	}
+
+	return code, &resp, nil
+}
+
+func bulkImsi(i int) string {
+	return fmt.Sprintf("00101%010d", i)
+}
+
+func TestAPISubscriberImportExportEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	row := func(i int, key, profile string) string {
+		return fmt.Sprintf("%s,%s,,%s,%s\n", bulkImsi(i), key, SequenceNumber, profile)
+	}
+
+	batch := "imsi,key,opc,sequenceNumber,profile_name\n" +
+		row(1, Key, DefaultProfileName) +
+		row(2, Key, DefaultProfileName) +
+		row(3, "not-a-key", DefaultProfileName) +
+		row(1, Key, DefaultProfileName) +
+		row(4, Key, "missing-profile") +
+		row(5, Key, DefaultProfileName)
+
+	countSubscribers := func(t *testing.T) int {
+		t.Helper()
+
+		var resp ListSubscriberResponse
+
+		code, err := doNATRequest(client, "GET", url+"/api/v1/subscribers?page=1&per_page=100", token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("couldn't list subscribers: %d %v", code, err)
+		}
+
+		return resp.Result.TotalCount
+	}
+
+	t.Run("malformed imports are rejected", func(t *testing.T) {
+		cases := []struct {
+			name, query, contentType, body string
+		}{
+			{"unknown mode", "?mode=sometimes", "text/csv", batch},
+			{"bad dry_run", "?dry_run=maybe", "text/csv", batch},
+			{"unknown column", "", "text/csv", "imsi,key,sequenceNumber,profile_name,iccid\n"},
+			{"missing column", "", "text/csv", "imsi,key,profile_name\n"},
+			{"no rows", "", "text/csv", "imsi,key,sequenceNumber,profile_name\n"},
+			{"not an array", "", "application/json", `{"imsi": "001010000000001"}`},
+			{"unsupported content type", "", "application/xml", "<subscribers/>"},
+			{"too many rows", "", "text/csv", "imsi,key,sequenceNumber,profile_name\n" + strings.Repeat(bulkImsi(1)+","+Key+","+SequenceNumber+",default\n", 5001)},
+		}
+
+		for _, tc := range cases {
+			code, raw, err := doRawRequest(client, "POST", url+"/api/v1/subscribers/import"+tc.query, token, tc.contentType, tc.body)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, raw)
+			}
+		}
+	})
+
+	t.Run("dry run reports every row and writes nothing", func(t *testing.T) {
+		code, resp, err := importSubscribers(client, url, token, "?dry_run=true&mode=best_effort", "text/csv", batch)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v)", code, err)
+		}
+
+		r := resp.Result
+		if !r.DryRun || r.Total != 6 || r.Valid != 3 || r.Imported != 0 || len(r.Errors) != 3 {
+			t.Fatalf("unexpected report: %+v", r)
+		}
+
+		want := map[int]string{
+			3: "Invalid key format. Must be a 32-character hexadecimal string.",
+			4: "Duplicate imsi in import",
+			5: "Profile not found",
+		}
+
+		for _, e := range r.Errors {
+			if want[e.Row] != e.Error {
+				t.Fatalf("row %d: unexpected error %q", e.Row, e.Error)
+			}
+		}
+
+		if n := countSubscribers(t); n != 0 {
+			t.Fatalf("dry run created %d subscribers", n)
+		}
+	})
+
+	t.Run("all or nothing writes nothing when a row is invalid", func(t *testing.T) {
+		code, resp, err := importSubscribers(client, url, token, "", "text/csv", batch)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v)", code, err)
+		}
+
+		if resp.Result.Mode != "all_or_nothing" || resp.Result.Imported != 0 || len(resp.Result.Errors) != 3 {
+			t.Fatalf("unexpected report: %+v", resp.Result)
+		}
+
+		if n := countSubscribers(t); n != 0 {
+			t.Fatalf("all-or-nothing import created %d subscribers", n)
+		}
+	})
+
+	t.Run("best effort imports the valid rows", func(t *testing.T) {
+		code, resp, err := importSubscribers(client, url, token, "?mode=best_effort", "text/csv; charset=utf-8", "\ufeff"+batch)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v)", code, err)
+		}
+
+		if resp.Result.Imported != 3 || len(resp.Result.Errors) != 3 {
+			t.Fatalf("unexpected report: %+v", resp.Result)
+		}
+
+		if n := countSubscribers(t); n != 3 {
+			t.Fatalf("expected 3 subscribers, got %d", n)
+		}
+	})
+
+	t.Run("json import reports existing subscribers", func(t *testing.T) {
+		body := fmt.Sprintf(`[
+			{"imsi": %q, "key": %q, "opc": %q, "sequenceNumber": %q, "profile_name": "default"},
+			{"imsi": %q, "key": %q, "sequenceNumber": %q, "profile_name": "default"}
+		]`, bulkImsi(6), Key, Opc, SequenceNumber, bulkImsi(2), Key, SequenceNumber)
+
+		code, resp, err := importSubscribers(client, url, token, "", "application/json", body)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v)", code, err)
+		}
+
+		if resp.Result.Imported != 0 || len(resp.Result.Errors) != 1 || resp.Result.Errors[0].Error != "Subscriber already exists" {
+			t.Fatalf("unexpected report: %+v", resp.Result)
+		}
+
+		body = fmt.Sprintf(`[{"imsi": %q, "key": %q, "opc": %q, "sequenceNumber": %q, "profile_name": "default"}]`, bulkImsi(6), Key, Opc, SequenceNumber)
+
+		code, resp, err = importSubscribers(client, url, token, "", "application/json", body)
+		if err != nil || code != http.StatusOK || resp.Result.Imported != 1 {
+			t.Fatalf("expected 1 import, got %d (%v, %+v)", code, err, resp)
+		}
+	})
+
+	t.Run("csv export with credentials reads back as an import", func(t *testing.T) {
+		code, raw, err := doRawRequest(client, "GET", url+"/api/v1/subscribers/export?include_credentials=true", token, "", "")
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, raw)
+		}
+
+		records, err := csv.NewReader(strings.NewReader(string(raw))).ReadAll()
+		if err != nil {
+			t.Fatalf("couldn't parse export: %v", err)
+		}
+
+		if len(records) != 5 || strings.Join(records[0], ",") != "imsi,key,opc,sequenceNumber,profile_name" {
+			t.Fatalf("unexpected export: %q", records)
+		}
+
+		wantImsis := []string{bulkImsi(1), bulkImsi(2), bulkImsi(5), bulkImsi(6)}
+		for i, rec := range records[1:] {
+			if rec[0] != wantImsis[i] || rec[1] != Key || rec[2] == "" || rec[3] != SequenceNumber || rec[4] != DefaultProfileName {
+				t.Fatalf("unexpected record %d: %q", i, rec)
+			}
+		}
+
+		if records[4][2] != Opc {
+			t.Fatalf("expected the imported OPc, got %q", records[4][2])
+		}
+
+		_, auditResp, err := listAuditLogs(url, client, token, 1, 100)
+		if err != nil {
+			t.Fatalf("couldn't list audit logs: %s", err)
+		}
+
+		found := false
+
+		for _, entry := range auditResp.Result.Items {
+			if entry.Action == "export_subscribers" && entry.Details == "User exported 4 subscribers with credentials" {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Fatalf("no audit entry for the export in %+v", auditResp.Result.Items)
+		}
+	})
+
+	t.Run("json export leaves credentials out by default", func(t *testing.T) {
+		code, raw, err := doRawRequest(client, "GET", url+"/api/v1/subscribers/export?format=json", token, "", "")
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, raw)
+		}
+
+		var records []subscriberExportRecord
+		if err := json.Unmarshal(raw, &records); err != nil {
+			t.Fatalf("couldn't parse export %q: %v", raw, err)
+		}
+
+		if len(records) != 4 || records[0].Imsi != bulkImsi(1) || records[0].ProfileName != DefaultProfileName || records[0].Key != "" {
+			t.Fatalf("unexpected export: %+v", records)
+		}
+	})
+
+	t.Run("read-only users export without credentials only", func(t *testing.T) {
+		readOnlyToken, err := createUserAndLogin(url, token, "readonly@ellanetworks.com", RoleReadOnly, client)
+		if err != nil {
+			t.Fatalf("couldn't create read-only user: %v", err)
+		}
+
+		code, _, err := doRawRequest(client, "GET", url+"/api/v1/subscribers/export", readOnlyToken, "", "")
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v)", code, err)
+		}
+
+		code, _, err = doRawRequest(client, "GET", url+"/api/v1/subscribers/export?include_credentials=true", readOnlyToken, "", "")
+		if err != nil || code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d (%v)", code, err)
+		}
+
+		code, _, err = doRawRequest(client, "POST", url+"/api/v1/subscribers/import", readOnlyToken, "text/csv", batch)
+		if err != nil || code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d (%v)", code, err)
+		}
+	})
+}
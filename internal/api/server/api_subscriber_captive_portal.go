@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const UpdateSubscriberCaptivePortalAction = "update_subscriber_captive_portal"
+
+// SubscriberCaptivePortal is whether a subscriber was let past the captive
+// portal of its policy. It has no effect on a policy not in redirect mode.
+type SubscriberCaptivePortal struct {
+	Lifted bool `json:"lifted"`
+}
+
+func GetSubscriberCaptivePortal(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		imsi := r.PathValue("imsi")
+		if imsi == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing imsi parameter", nil, logger.APILog)
+			return
+		}
+
+		if _, err := dbInstance.GetSubscriber(r.Context(), imsi); err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Subscriber not found", nil, logger.APILog)
+			return
+		}
+
+		lifted, err := dbInstance.IsCaptivePortalLifted(r.Context(), imsi)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get subscriber captive portal", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SubscriberCaptivePortal{Lifted: lifted}, http.StatusOK, logger.APILog)
+	})
+}
+
+func UpdateSubscriberCaptivePortal(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := r.Context().Value(contextKeyEmail).(string)
+		if !ok {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get email", errors.New("missing email in context"), logger.APILog)
+			return
+		}
+
+		imsi := r.PathValue("imsi")
+		if imsi == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing imsi parameter", nil, logger.APILog)
+			return
+		}
+
+		var params SubscriberCaptivePortal
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		if _, err := dbInstance.GetSubscriber(r.Context(), imsi); err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Subscriber not found", nil, logger.APILog)
+			return
+		}
+
+		var (
+			err    error
+			detail string
+		)
+
+		if params.Lifted {
+			err = dbInstance.LiftCaptivePortal(r.Context(), imsi)
+			detail = "User lifted the captive portal for subscriber " + imsi
+		} else {
+			err = dbInstance.RestoreCaptivePortal(r.Context(), imsi)
+			detail = "User restored the captive portal for subscriber " + imsi
+		}
+
+		if err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to update subscriber captive portal", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Subscriber captive portal updated successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), UpdateSubscriberCaptivePortalAction, email, getClientIP(r), detail)
+	})
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+type subscriberCaptivePortalResponse struct {
+	Result struct {
+		Lifted bool `json:"lifted"`
+	} `json:"result"`
+	Error string `json:"error,omitempty"`
+}
+
+func TestAPISubscriberCaptivePortalEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	status, _, err := createSubscriber(url, client, token, &CreateSubscriberParams{
+		Imsi:           Imsi,
+		Key:            Key,
+		Opc:            Opc,
+		SequenceNumber: SequenceNumber,
+		ProfileName:    DefaultProfileName,
+	})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("couldn't create subscriber: %d %v", status, err)
+	}
+
+	portalURL := url + "/api/v1/subscribers/" + Imsi + "/captive-portal"
+
+	lifted := func() bool {
+		var resp subscriberCaptivePortalResponse
+
+		code, err := doNATRequest(client, "GET", portalURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		return resp.Result.Lifted
+	}
+
+	if lifted() {
+		t.Fatal("expected the captive portal to apply by default")
+	}
+
+	var msg messageResponse
+
+	code, err := doNATRequest(client, "PUT", portalURL, token, map[string]any{"lifted": true}, &msg)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+	}
+
+	if !lifted() {
+		t.Fatal("expected the captive portal to be lifted")
+	}
+
+	code, err = doNATRequest(client, "PUT", portalURL, token, map[string]any{"lifted": false}, &msg)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%v, %s)", code, err, msg.Error)
+	}
+
+	if lifted() {
+		t.Fatal("expected the captive portal to apply again")
+	}
+
+	code, err = doNATRequest(client, "PUT", url+"/api/v1/subscribers/001019999999999/captive-portal", token, map[string]any{"lifted": true}, &msg)
+	if err != nil || code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown subscriber, got %d (%v)", code, err)
+	}
+}
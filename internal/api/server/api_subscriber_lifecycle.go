@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+const (
+	SetSubscriberLifecycleAction    = "set_subscriber_lifecycle"
+	DeleteSubscriberLifecycleAction = "delete_subscriber_lifecycle"
+)
+
+// SubscriberLifecycleParams puts a subscriber in State and, with ValidFrom or
+// ValidUntil (RFC 3339), bounds the time it may use the network. An empty
+// State is active.
+type SubscriberLifecycleParams struct {
+	State      string `json:"state,omitempty"`
+	ValidFrom  string `json:"valid_from,omitempty"`
+	ValidUntil string `json:"valid_until,omitempty"`
+}
+
+// SubscriberLifecycleResponse reports the configured lifecycle and, as
+// EffectiveState, the state it puts the subscriber in now.
+type SubscriberLifecycleResponse struct {
+	State          string `json:"state"`
+	EffectiveState string `json:"effective_state"`
+	ValidFrom      string `json:"valid_from,omitempty"`
+	ValidUntil     string `json:"valid_until,omitempty"`
+	UpdatedAt      string `json:"updated_at,omitempty"`
+}
+
+func subscriberLifecycleFromDB(l *db.SubscriberLifecycle, now time.Time) SubscriberLifecycleResponse {
+	if l == nil {
+		return SubscriberLifecycleResponse{
+			State:          string(db.SubscriberStateActive),
+			EffectiveState: string(db.SubscriberStateActive),
+		}
+	}
+
+	resp := SubscriberLifecycleResponse{
+		State:          l.State,
+		EffectiveState: string(l.StateAt(now)),
+		UpdatedAt:      time.Unix(l.UpdatedAt, 0).UTC().Format(time.RFC3339),
+	}
+
+	if l.ValidFrom > 0 {
+		resp.ValidFrom = time.Unix(l.ValidFrom, 0).UTC().Format(time.RFC3339)
+	}
+
+	if l.ValidUntil > 0 {
+		resp.ValidUntil = time.Unix(l.ValidUntil, 0).UTC().Format(time.RFC3339)
+	}
+
+	return resp
+}
+
+func GetSubscriberLifecycle(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		imsi := r.PathValue("imsi")
+		if imsi == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing imsi parameter", nil, logger.APILog)
+			return
+		}
+
+		if _, err := dbInstance.GetSubscriber(r.Context(), imsi); err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Subscriber not found", nil, logger.APILog)
+			return
+		}
+
+		// A subscriber without a lifecycle is active.
+		l, err := dbInstance.GetSubscriberLifecycle(r.Context(), imsi)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to get subscriber lifecycle", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, subscriberLifecycleFromDB(l, time.Now()), http.StatusOK, logger.APILog)
+	})
+}
+
+func SetSubscriberLifecycle(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		imsi := r.PathValue("imsi")
+		if imsi == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing imsi parameter", nil, logger.APILog)
+			return
+		}
+
+		var params SubscriberLifecycleParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid request data", err, logger.APILog)
+			return
+		}
+
+		now := time.Now()
+
+		l, err := subscriberLifecycleFromParams(imsi, &params, now)
+		if err != nil {
+			writeError(r.Context(), w, http.StatusBadRequest, err.Error(), nil, logger.APILog)
+			return
+		}
+
+		if _, err := dbInstance.GetSubscriber(r.Context(), imsi); err != nil {
+			writeError(r.Context(), w, http.StatusNotFound, "Subscriber not found", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.SetSubscriberLifecycle(r.Context(), l); err != nil {
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to set subscriber lifecycle", err, logger.APILog)
+			return
+		}
+
+		writeResponse(r.Context(), w, subscriberLifecycleFromDB(l, now), http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), SetSubscriberLifecycleAction, email, getClientIP(r),
+			fmt.Sprintf("User set the lifecycle of subscriber %s to %s", imsi, l.State))
+	})
+}
+
+func DeleteSubscriberLifecycle(dbInstance *db.Database) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email := getEmailFromContext(r)
+
+		imsi := r.PathValue("imsi")
+		if imsi == "" {
+			writeError(r.Context(), w, http.StatusBadRequest, "Missing imsi parameter", nil, logger.APILog)
+			return
+		}
+
+		if err := dbInstance.DeleteSubscriberLifecycle(r.Context(), imsi); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(r.Context(), w, http.StatusNotFound, "Subscriber lifecycle not found", nil, logger.APILog)
+				return
+			}
+
+			writeError(r.Context(), w, http.StatusInternalServerError, "Failed to delete subscriber lifecycle", err, logger.APILog)
+
+			return
+		}
+
+		writeResponse(r.Context(), w, SuccessResponse{Message: "Subscriber lifecycle deleted successfully"}, http.StatusOK, logger.APILog)
+
+		logger.LogAuditEvent(r.Context(), DeleteSubscriberLifecycleAction, email, getClientIP(r),
+			"User deleted the lifecycle of subscriber "+imsi)
+	})
+}
+
+func subscriberLifecycleFromParams(imsi string, p *SubscriberLifecycleParams, now time.Time) (*db.SubscriberLifecycle, error) {
+	state := db.SubscriberState(p.State)
+	if state == "" {
+		state = db.SubscriberStateActive
+	}
+
+	if !state.Valid() {
+		return nil, errors.New("invalid state - must be one of active, suspended, barred_data, expired")
+	}
+
+	l := &db.SubscriberLifecycle{
+		IMSI:      imsi,
+		State:     string(state),
+		UpdatedAt: now.Unix(),
+	}
+
+	if p.ValidFrom != "" {
+		t, err := time.Parse(time.RFC3339, p.ValidFrom)
+		if err != nil {
+			return nil, errors.New("invalid valid_from - must be an RFC 3339 date-time")
+		}
+
+		l.ValidFrom = t.Unix()
+	}
+
+	if p.ValidUntil != "" {
+		t, err := time.Parse(time.RFC3339, p.ValidUntil)
+		if err != nil {
+			return nil, errors.New("invalid valid_until - must be an RFC 3339 date-time")
+		}
+
+		l.ValidUntil = t.Unix()
+	}
+
+	if l.ValidFrom > 0 && l.ValidUntil > 0 && l.ValidUntil <= l.ValidFrom {
+		return nil, errors.New("valid_until must be after valid_from")
+	}
+
+	return l, nil
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type subscriberLifecycle struct {
+	State          string `json:"state"`
+	EffectiveState string `json:"effective_state"`
+	ValidFrom      string `json:"valid_from"`
+	ValidUntil     string `json:"valid_until"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+type subscriberLifecycleResponse struct {
+	Result subscriberLifecycle `json:"result"`
+	Error  string              `json:"error,omitempty"`
+}
+
+func TestAPISubscriberLifecycleEndToEnd(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+	url := env.Server.URL
+
+	token, err := initializeAndRefresh(url, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	code, _, err := createProfile(url, client, token, &CreateProfileParams{Name: TestProfileName, UeAmbrUplink: "100 Mbps", UeAmbrDownlink: "100 Mbps"})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create profile: %d (%v)", code, err)
+	}
+
+	code, _, err = createPolicy(url, client, token, &CreatePolicyParams{
+		Name:                PolicyName,
+		ProfileName:         TestProfileName,
+		SliceName:           DefaultSliceName,
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "100 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkName:     "internet",
+	})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create policy: %d (%v)", code, err)
+	}
+
+	code, resp, err := createSubscriber(url, client, token, &CreateSubscriberParams{Imsi: Imsi, Key: Key, Opc: Opc, SequenceNumber: SequenceNumber, ProfileName: TestProfileName})
+	if err != nil || code != http.StatusCreated {
+		t.Fatalf("couldn't create subscriber: %d (%v, %s)", code, err, resp.Error)
+	}
+
+	lifecycleURL := url + "/api/v1/subscribers/" + Imsi + "/lifecycle"
+
+	t.Run("active at first", func(t *testing.T) {
+		var got subscriberLifecycleResponse
+
+		code, err := doNATRequest(client, "GET", lifecycleURL, token, nil, &got)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, got.Error)
+		}
+
+		if got.Result.State != "active" || got.Result.EffectiveState != "active" {
+			t.Fatalf("unexpected lifecycle: %+v", got.Result)
+		}
+	})
+
+	t.Run("validity window", func(t *testing.T) {
+		from := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+		until := from.Add(30 * 24 * time.Hour)
+
+		body := map[string]any{
+			"valid_from":  from.Format(time.RFC3339),
+			"valid_until": until.Format(time.RFC3339),
+		}
+
+		var set subscriberLifecycleResponse
+
+		code, err := doNATRequest(client, "PUT", lifecycleURL, token, body, &set)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, set.Error)
+		}
+
+		// Not valid yet: the subscriber is held suspended until valid_from.
+		r := set.Result
+		if r.State != "active" || r.EffectiveState != "suspended" || r.ValidFrom != from.Format(time.RFC3339) || r.ValidUntil != until.Format(time.RFC3339) {
+			t.Fatalf("unexpected lifecycle: %+v", r)
+		}
+
+		var got subscriberLifecycleResponse
+
+		code, err = doNATRequest(client, "GET", lifecycleURL, token, nil, &got)
+		if err != nil || code != http.StatusOK || got.Result != set.Result {
+			t.Fatalf("expected the lifecycle set, got %d (%v, %+v)", code, err, got.Result)
+		}
+	})
+
+	t.Run("barred from data", func(t *testing.T) {
+		var set subscriberLifecycleResponse
+
+		code, err := doNATRequest(client, "PUT", lifecycleURL, token, map[string]any{"state": "barred_data"}, &set)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, set.Error)
+		}
+
+		if set.Result.EffectiveState != "barred_data" || set.Result.ValidFrom != "" {
+			t.Fatalf("unexpected lifecycle: %+v", set.Result)
+		}
+	})
+
+	t.Run("invalid requests are rejected", func(t *testing.T) {
+		cases := []struct {
+			name string
+			body map[string]any
+		}{
+			{"unknown state", map[string]any{"state": "dormant"}},
+			{"not a date-time", map[string]any{"valid_until": "next week"}},
+			{"window ends before it starts", map[string]any{"valid_from": "2026-03-01T00:00:00Z", "valid_until": "2026-02-01T00:00:00Z"}},
+		}
+
+		for _, tc := range cases {
+			var resp messageResponse
+
+			code, err := doNATRequest(client, "PUT", lifecycleURL, token, tc.body, &resp)
+			if err != nil || code != http.StatusBadRequest {
+				t.Fatalf("%s: expected 400, got %d (%v, %s)", tc.name, code, err, resp.Error)
+			}
+		}
+	})
+
+	t.Run("unknown subscriber", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "PUT", url+"/api/v1/subscribers/001019999999999/lifecycle", token, map[string]any{"state": "suspended"}, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		var resp messageResponse
+
+		code, err := doNATRequest(client, "DELETE", lifecycleURL, token, nil, &resp)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("expected 200, got %d (%v, %s)", code, err, resp.Error)
+		}
+
+		var got subscriberLifecycleResponse
+
+		code, err = doNATRequest(client, "GET", lifecycleURL, token, nil, &got)
+		if err != nil || code != http.StatusOK || got.Result.EffectiveState != "active" {
+			t.Fatalf("expected active again, got %d (%v, %+v)", code, err, got.Result)
+		}
+
+		code, err = doNATRequest(client, "DELETE", lifecycleURL, token, nil, &resp)
+		if err != nil || code != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d (%v, %s)", code, err, resp.Error)
+		}
+	})
+}
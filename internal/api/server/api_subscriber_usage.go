@@ -127,9 +127,31 @@ func GetSubscriberUsage(dbInstance *db.Database) http.Handler {
 
 			writeResponse(r.Context(), w, response, http.StatusOK, logger.APILog)
 
+			return
+		case "rating_group":
+			ratingGroupUsage, err := dbInstance.GetUsagePerRatingGroup(ctx, subscriber, startDate, endDate)
+			if err != nil {
+				writeError(r.Context(), w, http.StatusInternalServerError, "Failed to retrieve subscriber usage", err, logger.APILog)
+				return
+			}
+
+			response := make([]map[string]SubscriberUsage, len(ratingGroupUsage))
+
+			for i, usage := range ratingGroupUsage {
+				response[i] = map[string]SubscriberUsage{
+					strconv.FormatInt(usage.RatingGroup, 10): {
+						UplinkBytes:   usage.BytesUplink,
+						DownlinkBytes: usage.BytesDownlink,
+						TotalBytes:    usage.BytesUplink + usage.BytesDownlink,
+					},
+				}
+			}
+
+			writeResponse(r.Context(), w, response, http.StatusOK, logger.APILog)
+
 			return
 		default:
-			writeError(r.Context(), w, http.StatusBadRequest, "Invalid group_by parameter", errors.New("group_by must be either 'day' or 'subscriber'"), logger.APILog)
+			writeError(r.Context(), w, http.StatusBadRequest, "Invalid group_by parameter", errors.New("group_by must be 'day', 'subscriber' or 'rating_group'"), logger.APILog)
 			return
 		}
 	})
@@ -61,8 +61,9 @@ type DeleteSubscriberUsageResponse struct {
 type GroupBy string
 
 const (
-	GroupByDay        GroupBy = "day"
-	GroupBySubscriber GroupBy = "subscriber"
+	GroupByDay         GroupBy = "day"
+	GroupBySubscriber  GroupBy = "subscriber"
+	GroupByRatingGroup GroupBy = "rating_group"
 )
 
 func getSubscriberUsage(url string, client *http.Client, token string, startDate string, endDate string, subscriber string, groupBy GroupBy) (int, *GetSubscriberUsageResponse, error) {
@@ -655,6 +656,76 @@ func TestAPISubscriberUsagePerSubscriberEndToEnd(t *testing.T) {
 	})
 }
 
+func TestAPISubscriberUsagePerRatingGroup(t *testing.T) {
+	tempDir := t.TempDir()
+	dbPath := filepath.Join(tempDir, "db.sqlite3")
+
+	env, err := setupServer(dbPath)
+	if err != nil {
+		t.Fatalf("couldn't create test server: %s", err)
+	}
+	defer env.Server.Close()
+
+	client := newTestClient(env.Server)
+
+	token, err := initializeAndRefresh(env.Server.URL, client)
+	if err != nil {
+		t.Fatalf("couldn't create first user and login: %s", err)
+	}
+
+	imsi := "001010100007487"
+
+	if err := createDataNetworkAndPolicy(env.Server.URL, client, token); err != nil {
+		t.Fatalf("couldn't create data network and policy: %s", err)
+	}
+
+	_, _, err = createSubscriber(env.Server.URL, client, token, &CreateSubscriberParams{
+		Imsi:           imsi,
+		Key:            Key,
+		Opc:            Opc,
+		SequenceNumber: SequenceNumber,
+		ProfileName:    TestProfileName,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create subscriber: %s", err)
+	}
+
+	day, err := time.Parse("2006-01-02", "2025-11-14")
+	if err != nil {
+		t.Fatalf("couldn't parse date: %s", err)
+	}
+
+	for _, u := range []db.DailyRatingGroupUsage{
+		{EpochDay: db.DaysSinceEpoch(day), IMSI: imsi, RatingGroup: 20, BytesDownlink: 300},
+		{EpochDay: db.DaysSinceEpoch(day), IMSI: imsi, RatingGroup: 10, BytesUplink: 100, BytesDownlink: 200},
+	} {
+		if err := env.DB.IncrementRatingGroupUsage(context.Background(), u); err != nil {
+			t.Fatalf("couldn't increment rating group usage: %s", err)
+		}
+	}
+
+	statusCode, response, err := getSubscriberUsage(env.Server.URL, client, token, "2025-11-14", "2025-11-14", imsi, GroupByRatingGroup)
+	if err != nil {
+		t.Fatalf("couldn't get subscriber usage per rating group: %s", err)
+	}
+
+	if statusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d (error: %s)", http.StatusOK, statusCode, response.Error)
+	}
+
+	if len(response.Result) != 2 {
+		t.Fatalf("expected 2 usage data entries, got %d entries", len(response.Result))
+	}
+
+	if got := response.Result[0]["10"]; got.UplinkBytes != 100 || got.DownlinkBytes != 200 || got.TotalBytes != 300 {
+		t.Fatalf("unexpected usage for rating group 10: %+v", response.Result[0])
+	}
+
+	if got := response.Result[1]["20"]; got.DownlinkBytes != 300 || got.TotalBytes != 300 {
+		t.Fatalf("unexpected usage for rating group 20: %+v", response.Result[1])
+	}
+}
+
 func TestAPISubscriberUsageRetentionPolicyEndToEnd(t *testing.T) {
 	tempDir := t.TempDir()
 	dbPath := filepath.Join(tempDir, "db.sqlite3")
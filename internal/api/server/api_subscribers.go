@@ -117,12 +117,15 @@ func isImsiValid(ctx context.Context, imsi string, dbInstance *db.Database) bool
 		return false
 	}
 
-	Mcc := network.Mcc
-	Mnc := network.Mnc
+	return imsiInHomeNetwork(imsi, network.Mcc, network.Mnc)
+}
 
-	mncLength := len(Mnc)
+// imsiInHomeNetwork reports whether a well-formed IMSI starts with the home
+// network's MCC and MNC and carries a subscriber number after them.
+func imsiInHomeNetwork(imsi, mcc, mnc string) bool {
+	mncLength := len(mnc)
 
-	if imsi[:3] != Mcc || imsi[3:3+mncLength] != Mnc {
+	if imsi[:3] != mcc || imsi[3:3+mncLength] != mnc {
 		return false
 	}
 
@@ -25,17 +25,28 @@ var PermissionsByRole = map[RoleID][]string{
 		PermReadMyUser, PermUpdateMyUserPassword,
 		PermListMyAPITokens, PermCreateMyAPIToken, PermDeleteMyAPIToken,
 		PermReadOperator,
-		PermListSubscribers, PermReadSubscriber,
+		PermListSubscribers, PermReadSubscriber, PermExportSubscribers,
 		PermListDataNetworks, PermReadDataNetwork, PermListDataNetworkStaticIPs, PermListDataNetworkFramedRoutes,
-		PermListPolicies, PermReadPolicy,
+		PermReadDataNetworkNAT, PermListDataNetworkPortForwards, PermReadDataNetworkTCPMSS,
+		PermReadDataNetworkDNSResolver, PermListDataNetworkDNSRecords,
+		PermReadDataNetworkAddressAllocation, PermReadDataNetworkSecondaryAuth, PermReadDataNetworkOnlineCharging,
+		PermReadDataNetworkPolicyControl, PermReadDataNetworkQuota,
+		PermListPolicies, PermReadPolicy, PermReadPolicyCaptivePortal, PermReadSubscriberCaptivePortal,
+		PermListPolicyLocationVariants,
+		PermReadSubscriberAmbrOverride, PermReadSubscriberLifecycle,
 		PermListProfiles, PermReadProfile,
-		PermListSlices, PermReadSlice,
+		PermListSchedules, PermReadSchedule,
+		PermListSlices, PermReadSlice, PermReadSliceQuota,
 		PermListRoutes, PermReadRoute,
 		PermListRadios, PermReadRadio,
 		PermGetNATInfo,
 		PermReadBGP,
 		PermGetFlowAccountingInfo,
 		PermGetLocalSwitchInfo,
+		PermListAccountingServers, PermReadAccountingServer,
+		PermListPolicyAssociations, PermReadPolicyAssociation,
+		PermListQosSessions, PermReadQosSession,
+		PermListMonitoringSubscriptions, PermReadMonitoringSubscription,
 		PermGetSubscriberUsageRetentionPolicy, PermGetSubscriberUsage,
 		PermListRadioEvents, PermGetRadioEventRetentionPolicy, PermGetRadioEvent,
 		PermGetFlowReportsRetentionPolicy, PermListFlowReports,
@@ -52,16 +63,38 @@ var PermissionsByRole = map[RoleID][]string{
 		PermListDataNetworks, PermCreateDataNetwork, PermUpdateDataNetwork, PermReadDataNetwork, PermDeleteDataNetwork,
 		PermListDataNetworkStaticIPs, PermCreateDataNetworkStaticIP, PermUpdateDataNetworkStaticIP, PermDeleteDataNetworkStaticIP,
 		PermListDataNetworkFramedRoutes, PermCreateDataNetworkFramedRoute, PermUpdateDataNetworkFramedRoute, PermDeleteDataNetworkFramedRoute,
+		PermReadDataNetworkNAT, PermUpdateDataNetworkNAT,
+		PermReadDataNetworkTCPMSS, PermUpdateDataNetworkTCPMSS,
+		PermReadDataNetworkDNSResolver, PermUpdateDataNetworkDNSResolver,
+		PermListDataNetworkDNSRecords, PermCreateDataNetworkDNSRecord, PermDeleteDataNetworkDNSRecord,
+		PermReadDataNetworkAddressAllocation, PermUpdateDataNetworkAddressAllocation,
+		PermReadDataNetworkSecondaryAuth, PermUpdateDataNetworkSecondaryAuth,
+		PermReadDataNetworkOnlineCharging, PermUpdateDataNetworkOnlineCharging,
+		PermReadDataNetworkPolicyControl, PermUpdateDataNetworkPolicyControl,
+		PermReadDataNetworkQuota, PermUpdateDataNetworkQuota,
+		PermListDataNetworkPortForwards, PermCreateDataNetworkPortForward, PermDeleteDataNetworkPortForward,
 		PermListSubscribers, PermCreateSubscriber, PermUpdateSubscriber, PermReadSubscriber, PermDeleteSubscriber, PermReadSubscriberCredentials,
+		PermImportSubscribers, PermExportSubscribers,
 		PermListPolicies, PermCreatePolicy, PermUpdatePolicy, PermReadPolicy, PermDeletePolicy,
+		PermReadPolicyCaptivePortal, PermUpdatePolicyCaptivePortal, PermReadSubscriberCaptivePortal, PermUpdateSubscriberCaptivePortal,
+		PermListPolicyLocationVariants, PermCreatePolicyLocationVariant, PermDeletePolicyLocationVariant,
+		PermReadSubscriberAmbrOverride, PermUpdateSubscriberAmbrOverride,
+		PermReadSubscriberLifecycle, PermUpdateSubscriberLifecycle,
 		PermListProfiles, PermCreateProfile, PermUpdateProfile, PermReadProfile, PermDeleteProfile,
+		PermListSchedules, PermCreateSchedule, PermUpdateSchedule, PermReadSchedule, PermDeleteSchedule,
 		PermListSlices, PermCreateSlice, PermUpdateSlice, PermReadSlice, PermDeleteSlice,
+		PermReadSliceQuota, PermUpdateSliceQuota,
 		PermListRoutes, PermCreateRoute, PermReadRoute, PermDeleteRoute,
 		PermListRadios, PermReadRadio,
 		PermGetNATInfo, PermUpdateNATInfo,
 		PermReadBGP, PermUpdateBGP,
 		PermGetFlowAccountingInfo, PermUpdateFlowAccountingInfo,
 		PermGetLocalSwitchInfo, PermUpdateLocalSwitchInfo,
+		PermListAccountingServers, PermCreateAccountingServer, PermUpdateAccountingServer, PermReadAccountingServer, PermDeleteAccountingServer,
+		PermListPolicyAssociations, PermReadPolicyAssociation, PermUpdatePolicyDecision,
+		PermListQosSessions, PermCreateQosSession, PermReadQosSession, PermDeleteQosSession,
+		PermListMonitoringSubscriptions, PermCreateMonitoringSubscription, PermReadMonitoringSubscription, PermDeleteMonitoringSubscription,
+		PermListCDRFiles, PermReadCDRFile,
 		PermListRadioEvents, PermGetRadioEventRetentionPolicy, PermSetRadioEventRetentionPolicy, PermClearRadioEvents, PermGetRadioEvent,
 		PermGetFlowReportsRetentionPolicy, PermSetFlowReportsRetentionPolicy, PermListFlowReports, PermClearFlowReports,
 		PermSupportBundle,
@@ -111,6 +144,44 @@ const (
 	PermUpdateDataNetworkFramedRoute = "data_network:update_framed_route"
 	PermDeleteDataNetworkFramedRoute = "data_network:delete_framed_route"
 
+	// NAT pool and port forward permissions (data network sub-resources)
+	PermReadDataNetworkNAT           = "data_network:read_nat"
+	PermUpdateDataNetworkNAT         = "data_network:update_nat"
+	PermListDataNetworkPortForwards  = "data_network:list_port_forwards"
+	PermCreateDataNetworkPortForward = "data_network:create_port_forward"
+	PermDeleteDataNetworkPortForward = "data_network:delete_port_forward"
+
+	// TCP MSS clamp permissions (data network sub-resource)
+	PermReadDataNetworkTCPMSS   = "data_network:read_tcp_mss"
+	PermUpdateDataNetworkTCPMSS = "data_network:update_tcp_mss"
+
+	// DNS resolver and local record permissions (data network sub-resources)
+	PermReadDataNetworkDNSResolver   = "data_network:read_dns_resolver"
+	PermUpdateDataNetworkDNSResolver = "data_network:update_dns_resolver"
+	PermListDataNetworkDNSRecords    = "data_network:list_dns_records"
+	PermCreateDataNetworkDNSRecord   = "data_network:create_dns_record"
+	PermDeleteDataNetworkDNSRecord   = "data_network:delete_dns_record"
+
+	// External address allocation permissions (data network sub-resource)
+	PermReadDataNetworkAddressAllocation   = "data_network:read_address_allocation"
+	PermUpdateDataNetworkAddressAllocation = "data_network:update_address_allocation"
+
+	// Secondary authentication permissions (data network sub-resource)
+	PermReadDataNetworkSecondaryAuth   = "data_network:read_secondary_auth"
+	PermUpdateDataNetworkSecondaryAuth = "data_network:update_secondary_auth"
+
+	// Online charging permissions (data network sub-resource)
+	PermReadDataNetworkOnlineCharging   = "data_network:read_online_charging"
+	PermUpdateDataNetworkOnlineCharging = "data_network:update_online_charging"
+
+	// External policy control permissions (data network sub-resource)
+	PermReadDataNetworkPolicyControl   = "data_network:read_policy_control"
+	PermUpdateDataNetworkPolicyControl = "data_network:update_policy_control"
+
+	// Admission quota permissions (data network sub-resource)
+	PermReadDataNetworkQuota   = "data_network:read_quota"
+	PermUpdateDataNetworkQuota = "data_network:update_quota"
+
 	// Operator permissions
 	PermReadOperator              = "operator:read"
 	PermUpdateOperatorTracking    = "operator:update_tracking"
@@ -128,6 +199,22 @@ const (
 	PermReadSubscriber            = "subscriber:read"
 	PermDeleteSubscriber          = "subscriber:delete"
 	PermReadSubscriberCredentials = "subscriber:read_credentials"
+	PermImportSubscribers         = "subscriber:import"
+	PermExportSubscribers         = "subscriber:export"
+
+	// Captive portal permissions (policy and subscriber sub-resources)
+	PermReadPolicyCaptivePortal       = "policy:read_captive_portal"
+	PermUpdatePolicyCaptivePortal     = "policy:update_captive_portal"
+	PermReadSubscriberCaptivePortal   = "subscriber:read_captive_portal"
+	PermUpdateSubscriberCaptivePortal = "subscriber:update_captive_portal"
+
+	// Subscriber AMBR override permissions
+	PermReadSubscriberAmbrOverride   = "subscriber:read_ambr_override"
+	PermUpdateSubscriberAmbrOverride = "subscriber:update_ambr_override"
+
+	// Subscriber lifecycle permissions
+	PermReadSubscriberLifecycle   = "subscriber:read_lifecycle"
+	PermUpdateSubscriberLifecycle = "subscriber:update_lifecycle"
 
 	// Subscriber Usage permissions
 	PermGetSubscriberUsageRetentionPolicy = "subscriber_usage:get_retention"
@@ -142,6 +229,11 @@ const (
 	PermReadPolicy   = "policy:read"
 	PermDeletePolicy = "policy:delete"
 
+	// Policy location variant permissions (policy sub-resource)
+	PermListPolicyLocationVariants  = "policy:list_location_variants"
+	PermCreatePolicyLocationVariant = "policy:create_location_variant"
+	PermDeletePolicyLocationVariant = "policy:delete_location_variant"
+
 	// Profile permissions
 	PermListProfiles  = "profile:list"
 	PermCreateProfile = "profile:create"
@@ -149,6 +241,13 @@ const (
 	PermReadProfile   = "profile:read"
 	PermDeleteProfile = "profile:delete"
 
+	// Schedule permissions
+	PermListSchedules  = "schedule:list"
+	PermCreateSchedule = "schedule:create"
+	PermUpdateSchedule = "schedule:update"
+	PermReadSchedule   = "schedule:read"
+	PermDeleteSchedule = "schedule:delete"
+
 	// Slice permissions
 	PermListSlices  = "slice:list"
 	PermCreateSlice = "slice:create"
@@ -156,6 +255,10 @@ const (
 	PermReadSlice   = "slice:read"
 	PermDeleteSlice = "slice:delete"
 
+	// Admission quota permissions (slice sub-resource)
+	PermReadSliceQuota   = "slice:read_quota"
+	PermUpdateSliceQuota = "slice:update_quota"
+
 	// Route permissions
 	PermListRoutes  = "route:list"
 	PermCreateRoute = "route:create"
@@ -178,6 +281,34 @@ const (
 	PermGetLocalSwitchInfo    = "local_switch:get"
 	PermUpdateLocalSwitchInfo = "local_switch:update"
 
+	// Accounting server permissions
+	PermListAccountingServers  = "accounting_server:list"
+	PermCreateAccountingServer = "accounting_server:create"
+	PermUpdateAccountingServer = "accounting_server:update"
+	PermReadAccountingServer   = "accounting_server:read"
+	PermDeleteAccountingServer = "accounting_server:delete"
+
+	// Policy association permissions
+	PermListPolicyAssociations = "policy_association:list"
+	PermReadPolicyAssociation  = "policy_association:read"
+	PermUpdatePolicyDecision   = "policy_association:update_decision"
+
+	// QoS session permissions
+	PermListQosSessions  = "qos_session:list"
+	PermCreateQosSession = "qos_session:create"
+	PermReadQosSession   = "qos_session:read"
+	PermDeleteQosSession = "qos_session:delete"
+
+	// Monitoring subscription permissions
+	PermListMonitoringSubscriptions  = "monitoring_subscription:list"
+	PermCreateMonitoringSubscription = "monitoring_subscription:create"
+	PermReadMonitoringSubscription   = "monitoring_subscription:read"
+	PermDeleteMonitoringSubscription = "monitoring_subscription:delete"
+
+	// Charging data record permissions
+	PermListCDRFiles = "cdr_file:list"
+	PermReadCDRFile  = "cdr_file:read"
+
 	// Interface permissions
 	PermListNetworkInterfaces = "network_interface:list"
 	PermUpdateN3Interface     = "network_interface:update_n3"
@@ -246,14 +377,33 @@ func Authorize(permission string, next http.Handler) http.Handler {
 			return
 		}
 
-		allowedPerms := PermissionsByRole[roleID]
-		for _, p := range allowedPerms {
-			if p == permission || p == "*" {
-				next.ServeHTTP(w, r)
-				return
-			}
+		if roleHasPermission(roleID, permission) {
+			next.ServeHTTP(w, r)
+			return
 		}
 
 		writeError(r.Context(), w, http.StatusForbidden, "Forbidden", errors.New("permission denied"), logger.APILog)
 	})
 }
+
+// hasPermission reports whether the authenticated user's role grants
+// permission, for handlers whose behaviour depends on more than the
+// permission their route requires.
+func hasPermission(r *http.Request, permission string) bool {
+	roleID, ok := r.Context().Value(contextKeyRoleID).(RoleID)
+	if !ok {
+		return false
+	}
+
+	return roleHasPermission(roleID, permission)
+}
+
+func roleHasPermission(roleID RoleID, permission string) bool {
+	for _, p := range PermissionsByRole[roleID] {
+		if p == permission || p == "*" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -10,13 +10,22 @@ import (
 // DefaultMaxBodySize is the maximum request body size for most API endpoints (1 MB).
 const DefaultMaxBodySize = 1 << 20
 
+// MaxSubscriberImportBodySize is the maximum request body size for a
+// subscriber import (8 MB), room for MaxSubscriberImportRows as JSON.
+const MaxSubscriberImportBodySize = 8 << 20
+
 // MaxBodySizeMiddleware limits the size of incoming request bodies using
-// http.MaxBytesReader. Most endpoints are limited to DefaultMaxBodySize.
-// The restore endpoint is exempt because database backups have no
-// predictable upper bound and the endpoint already requires admin auth.
+// http.MaxBytesReader. Most endpoints are limited to DefaultMaxBodySize and
+// subscriber imports to MaxSubscriberImportBodySize. The restore endpoint
+// is exempt because database backups have no predictable upper bound and
+// the endpoint already requires admin auth.
 func MaxBodySizeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/api/v1/restore" {
+		switch r.URL.Path {
+		case "/api/v1/restore":
+		case "/api/v1/subscribers/import":
+			r.Body = http.MaxBytesReader(w, r.Body, MaxSubscriberImportBodySize)
+		default:
 			r.Body = http.MaxBytesReader(w, r.Body, DefaultMaxBodySize)
 		}
 
@@ -80,6 +80,36 @@ func TestMaxBodySizeMiddleware_RestoreAllowsLargeBody(t *testing.T) {
 	}
 }
 
+func TestMaxBodySizeMiddleware_SubscriberImportLimit(t *testing.T) {
+	handler := MaxBodySizeMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		size int
+		want int
+	}{
+		{2 << 20, http.StatusOK},
+		{MaxSubscriberImportBodySize + 1, http.StatusRequestEntityTooLarge},
+	} {
+		body := strings.NewReader(strings.Repeat("x", tc.size))
+		req := httptest.NewRequestWithContext(context.Background(), http.MethodPost, "/api/v1/subscribers/import", body)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Fatalf("expected %d for a %d byte import, got %d", tc.want, tc.size, rec.Code)
+		}
+	}
+}
+
 func TestMaxBodySizeMiddleware_GETRequestUnaffected(t *testing.T) {
 	handler := MaxBodySizeMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
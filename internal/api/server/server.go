@@ -19,6 +19,7 @@ import (
 	"github.com/ellanetworks/core/internal/lmf"
 	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/internal/smf"
 	"go.uber.org/zap"
 )
@@ -40,6 +41,8 @@ type HandlerConfig struct {
 	ClusterListener     *listener.Listener
 	LMF                 *lmf.LMF
 	DatapathAttachMode  func() string
+	// DatapathFeatures is nil in tests, where every feature is assumed.
+	DatapathFeatures func() models.DatapathFeatures
 }
 
 func NewHandler(cfg HandlerConfig) http.Handler {
@@ -57,6 +60,11 @@ func NewHandler(cfg HandlerConfig) http.Handler {
 	registerExtraRoutes := cfg.RegisterExtraRoutes
 	lmfInstance := cfg.LMF
 
+	datapath := cfg.DatapathFeatures
+	if datapath == nil {
+		datapath = allDatapathFeatures
+	}
+
 	mux := http.NewServeMux()
 
 	// Status (Unauthenticated)
@@ -109,9 +117,19 @@ func NewHandler(cfg HandlerConfig) http.Handler {
 	// Subscribers (Authenticated)
 	mux.HandleFunc("GET /api/v1/subscribers", Authenticate(jwtSecret, dbInstance, Authorize(PermListSubscribers, ListSubscribers(dbInstance, amfInstance, mmeInstance))).ServeHTTP)
 	mux.HandleFunc("POST /api/v1/subscribers", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateSubscriber, CreateSubscriber(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/subscribers/import", Authenticate(jwtSecret, dbInstance, Authorize(PermImportSubscribers, ImportSubscribers(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/subscribers/export", Authenticate(jwtSecret, dbInstance, Authorize(PermExportSubscribers, ExportSubscribers(dbInstance))).ServeHTTP)
 	mux.HandleFunc("PUT /api/v1/subscribers/{imsi}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSubscriber, UpdateSubscriber(dbInstance))).ServeHTTP)
 	mux.HandleFunc("GET /api/v1/subscribers/{imsi}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadSubscriber, GetSubscriber(dbInstance, amfInstance, mmeInstance))).ServeHTTP)
 	mux.HandleFunc("GET /api/v1/subscribers/{imsi}/credentials", Authenticate(jwtSecret, dbInstance, Authorize(PermReadSubscriberCredentials, GetSubscriberCredentials(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/subscribers/{imsi}/captive-portal", Authenticate(jwtSecret, dbInstance, Authorize(PermReadSubscriberCaptivePortal, GetSubscriberCaptivePortal(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/subscribers/{imsi}/captive-portal", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSubscriberCaptivePortal, UpdateSubscriberCaptivePortal(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/subscribers/{imsi}/ambr-override", Authenticate(jwtSecret, dbInstance, Authorize(PermReadSubscriberAmbrOverride, GetSubscriberAmbrOverride(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/subscribers/{imsi}/ambr-override", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSubscriberAmbrOverride, SetSubscriberAmbrOverride(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/subscribers/{imsi}/ambr-override", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSubscriberAmbrOverride, DeleteSubscriberAmbrOverride(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/subscribers/{imsi}/lifecycle", Authenticate(jwtSecret, dbInstance, Authorize(PermReadSubscriberLifecycle, GetSubscriberLifecycle(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/subscribers/{imsi}/lifecycle", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSubscriberLifecycle, SetSubscriberLifecycle(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/subscribers/{imsi}/lifecycle", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSubscriberLifecycle, DeleteSubscriberLifecycle(dbInstance))).ServeHTTP)
 	mux.HandleFunc("DELETE /api/v1/subscribers/{imsi}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteSubscriber, DeleteSubscriber(dbInstance, amfInstance, mmeInstance))).ServeHTTP)
 
 	// Subscriber Usage (Authenticated)
@@ -120,12 +138,21 @@ func NewHandler(cfg HandlerConfig) http.Handler {
 	mux.HandleFunc("DELETE /api/v1/subscriber-usage", Authenticate(jwtSecret, dbInstance, Authorize(PermClearSubscriberUsage, ClearSubscriberUsage(dbInstance))).ServeHTTP)
 	mux.HandleFunc("GET /api/v1/subscriber-usage", Authenticate(jwtSecret, dbInstance, Authorize(PermGetSubscriberUsage, GetSubscriberUsage(dbInstance))).ServeHTTP)
 
+	// Charging Data Records (Authenticated)
+	mux.HandleFunc("GET /api/v1/charging/cdr-files", Authenticate(jwtSecret, dbInstance, Authorize(PermListCDRFiles, ListCDRFiles(appCfg))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/charging/cdr-files/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadCDRFile, DownloadCDRFile(appCfg))).ServeHTTP)
+
 	// Policies (Authenticated)
 	mux.HandleFunc("GET /api/v1/policies", Authenticate(jwtSecret, dbInstance, Authorize(PermListPolicies, ListPolicies(dbInstance))).ServeHTTP)
-	mux.HandleFunc("POST /api/v1/policies", Authenticate(jwtSecret, dbInstance, Authorize(PermCreatePolicy, CreatePolicy(dbInstance))).ServeHTTP)
-	mux.HandleFunc("PUT /api/v1/policies/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdatePolicy, UpdatePolicy(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/policies", Authenticate(jwtSecret, dbInstance, Authorize(PermCreatePolicy, CreatePolicy(dbInstance, datapath))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/policies/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdatePolicy, UpdatePolicy(dbInstance, datapath))).ServeHTTP)
 	mux.HandleFunc("GET /api/v1/policies/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadPolicy, GetPolicy(dbInstance))).ServeHTTP)
 	mux.HandleFunc("DELETE /api/v1/policies/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeletePolicy, DeletePolicy(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/policies/{name}/captive-portal", Authenticate(jwtSecret, dbInstance, Authorize(PermReadPolicyCaptivePortal, GetPolicyCaptivePortal(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/policies/{name}/captive-portal", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdatePolicyCaptivePortal, UpdatePolicyCaptivePortal(dbInstance, datapath))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/policies/{name}/location-variants", Authenticate(jwtSecret, dbInstance, Authorize(PermListPolicyLocationVariants, ListPolicyLocationVariants(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/policies/{name}/location-variants", Authenticate(jwtSecret, dbInstance, Authorize(PermCreatePolicyLocationVariant, CreatePolicyLocationVariant(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/policies/{name}/location-variants/{id}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeletePolicyLocationVariant, DeletePolicyLocationVariant(dbInstance))).ServeHTTP)
 
 	// Profiles (Authenticated)
 	mux.HandleFunc("GET /api/v1/profiles", Authenticate(jwtSecret, dbInstance, Authorize(PermListProfiles, ListProfiles(dbInstance))).ServeHTTP)
@@ -134,12 +161,22 @@ func NewHandler(cfg HandlerConfig) http.Handler {
 	mux.HandleFunc("GET /api/v1/profiles/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadProfile, GetProfile(dbInstance))).ServeHTTP)
 	mux.HandleFunc("DELETE /api/v1/profiles/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteProfile, DeleteProfile(dbInstance))).ServeHTTP)
 
+	// Schedules (Authenticated)
+	mux.HandleFunc("GET /api/v1/schedules", Authenticate(jwtSecret, dbInstance, Authorize(PermListSchedules, ListSchedules(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/schedules", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateSchedule, CreateSchedule(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/schedules/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSchedule, UpdateSchedule(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/schedules/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadSchedule, GetSchedule(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/schedules/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteSchedule, DeleteSchedule(dbInstance))).ServeHTTP)
+
 	// Slices (Authenticated)
 	mux.HandleFunc("GET /api/v1/slices", Authenticate(jwtSecret, dbInstance, Authorize(PermListSlices, ListSlices(dbInstance))).ServeHTTP)
 	mux.HandleFunc("POST /api/v1/slices", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateSlice, CreateSlice(dbInstance))).ServeHTTP)
 	mux.HandleFunc("PUT /api/v1/slices/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSlice, UpdateSlice(dbInstance))).ServeHTTP)
 	mux.HandleFunc("GET /api/v1/slices/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadSlice, GetSlice(dbInstance))).ServeHTTP)
 	mux.HandleFunc("DELETE /api/v1/slices/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteSlice, DeleteSlice(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/slices/{name}/quota", Authenticate(jwtSecret, dbInstance, Authorize(PermReadSliceQuota, GetSliceQuota(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/slices/{name}/quota", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSliceQuota, UpdateSliceQuota(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/slices/{name}/quota", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateSliceQuota, DeleteSliceQuota(dbInstance))).ServeHTTP)
 
 	// Operator (Authenticated)
 	mux.HandleFunc("GET /api/v1/operator", Authenticate(jwtSecret, dbInstance, Authorize(PermReadOperator, GetOperator(dbInstance))).ServeHTTP)
@@ -154,8 +191,8 @@ func NewHandler(cfg HandlerConfig) http.Handler {
 
 	// Data Networks (Authenticated)
 	mux.HandleFunc("GET /api/v1/networking/data-networks", Authenticate(jwtSecret, dbInstance, Authorize(PermListDataNetworks, ListDataNetworks(dbInstance, sessions))).ServeHTTP)
-	mux.HandleFunc("POST /api/v1/networking/data-networks", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateDataNetwork, CreateDataNetwork(dbInstance))).ServeHTTP)
-	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetwork, UpdateDataNetwork(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/networking/data-networks", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateDataNetwork, CreateDataNetwork(dbInstance, datapath))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetwork, UpdateDataNetwork(dbInstance, datapath))).ServeHTTP)
 	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetwork, GetDataNetwork(dbInstance, sessions))).ServeHTTP)
 	mux.HandleFunc("DELETE /api/v1/networking/data-networks/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteDataNetwork, DeleteDataNetwork(dbInstance))).ServeHTTP)
 	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/ipv4-allocations", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetwork, ListIPv4Allocations(dbInstance))).ServeHTTP)
@@ -170,6 +207,30 @@ func NewHandler(cfg HandlerConfig) http.Handler {
 	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/framed-routes/{imsi}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkFramedRoute, UpdateDataNetworkFramedRoute(dbInstance))).ServeHTTP)
 	mux.HandleFunc("DELETE /api/v1/networking/data-networks/{name}/framed-routes/{imsi}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteDataNetworkFramedRoute, DeleteDataNetworkFramedRoute(dbInstance))).ServeHTTP)
 
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/nat", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetworkNAT, GetDataNetworkNAT(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/nat", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkNAT, UpdateDataNetworkNAT(dbInstance, datapath))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/port-forwards", Authenticate(jwtSecret, dbInstance, Authorize(PermListDataNetworkPortForwards, ListDataNetworkPortForwards(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/networking/data-networks/{name}/port-forwards", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateDataNetworkPortForward, CreateDataNetworkPortForward(dbInstance, datapath))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/networking/data-networks/{name}/port-forwards/{id}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteDataNetworkPortForward, DeleteDataNetworkPortForward(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/tcp-mss-clamp", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetworkTCPMSS, GetDataNetworkTCPMSS(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/tcp-mss-clamp", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkTCPMSS, UpdateDataNetworkTCPMSS(dbInstance, datapath))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/dns-resolver", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetworkDNSResolver, GetDataNetworkDNSResolver(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/dns-resolver", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkDNSResolver, UpdateDataNetworkDNSResolver(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/dns-records", Authenticate(jwtSecret, dbInstance, Authorize(PermListDataNetworkDNSRecords, ListDataNetworkDNSRecords(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/networking/data-networks/{name}/dns-records", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateDataNetworkDNSRecord, CreateDataNetworkDNSRecord(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/networking/data-networks/{name}/dns-records/{id}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteDataNetworkDNSRecord, DeleteDataNetworkDNSRecord(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/address-allocation", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetworkAddressAllocation, GetDataNetworkAddressAllocation(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/address-allocation", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkAddressAllocation, UpdateDataNetworkAddressAllocation(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/secondary-authentication", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetworkSecondaryAuth, GetDataNetworkSecondaryAuth(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/secondary-authentication", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkSecondaryAuth, UpdateDataNetworkSecondaryAuth(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/online-charging", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetworkOnlineCharging, GetDataNetworkOnlineCharging(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/online-charging", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkOnlineCharging, UpdateDataNetworkOnlineCharging(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/policy-control", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetworkPolicyControl, GetDataNetworkPolicyControl(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/policy-control", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkPolicyControl, UpdateDataNetworkPolicyControl(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/data-networks/{name}/quota", Authenticate(jwtSecret, dbInstance, Authorize(PermReadDataNetworkQuota, GetDataNetworkQuota(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/data-networks/{name}/quota", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkQuota, UpdateDataNetworkQuota(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/networking/data-networks/{name}/quota", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateDataNetworkQuota, DeleteDataNetworkQuota(dbInstance))).ServeHTTP)
+
 	// Routes (Authenticated)
 	mux.HandleFunc("GET /api/v1/networking/routes", Authenticate(jwtSecret, dbInstance, Authorize(PermListRoutes, ListRoutes(dbInstance, bgpService))).ServeHTTP)
 	mux.HandleFunc("POST /api/v1/networking/routes", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateRoute, CreateRoute(dbInstance, reconcileRoutes))).ServeHTTP)
@@ -199,6 +260,30 @@ func NewHandler(cfg HandlerConfig) http.Handler {
 	mux.HandleFunc("GET /api/v1/networking/local-switch", Authenticate(jwtSecret, dbInstance, Authorize(PermGetLocalSwitchInfo, GetLocalSwitchInfo(dbInstance))).ServeHTTP)
 	mux.HandleFunc("PUT /api/v1/networking/local-switch", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateLocalSwitchInfo, UpdateLocalSwitchInfo(dbInstance))).ServeHTTP)
 
+	// Accounting Servers (Authenticated)
+	mux.HandleFunc("GET /api/v1/networking/accounting-servers", Authenticate(jwtSecret, dbInstance, Authorize(PermListAccountingServers, ListAccountingServers(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/networking/accounting-servers", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateAccountingServer, CreateAccountingServer(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/networking/accounting-servers/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateAccountingServer, UpdateAccountingServer(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/networking/accounting-servers/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadAccountingServer, GetAccountingServer(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/networking/accounting-servers/{name}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteAccountingServer, DeleteAccountingServer(dbInstance))).ServeHTTP)
+
+	// Policy associations
+	mux.HandleFunc("GET /api/v1/policy-control/associations", Authenticate(jwtSecret, dbInstance, Authorize(PermListPolicyAssociations, ListPolicyAssociations(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/policy-control/associations/{id}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadPolicyAssociation, GetPolicyAssociation(dbInstance))).ServeHTTP)
+	mux.HandleFunc("PUT /api/v1/policy-control/associations/{id}/decision", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdatePolicyDecision, UpdatePolicyDecision(dbInstance))).ServeHTTP)
+
+	// QoS sessions
+	mux.HandleFunc("GET /api/v1/qos-sessions", Authenticate(jwtSecret, dbInstance, Authorize(PermListQosSessions, ListQosSessions(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/qos-sessions", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateQosSession, CreateQosSession(dbInstance, datapath))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/qos-sessions/{id}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadQosSession, GetQosSession(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/qos-sessions/{id}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteQosSession, DeleteQosSession(dbInstance))).ServeHTTP)
+
+	// Monitoring event subscriptions
+	mux.HandleFunc("GET /api/v1/monitoring-subscriptions", Authenticate(jwtSecret, dbInstance, Authorize(PermListMonitoringSubscriptions, ListMonitoringSubscriptions(dbInstance))).ServeHTTP)
+	mux.HandleFunc("POST /api/v1/monitoring-subscriptions", Authenticate(jwtSecret, dbInstance, Authorize(PermCreateMonitoringSubscription, CreateMonitoringSubscription(dbInstance))).ServeHTTP)
+	mux.HandleFunc("GET /api/v1/monitoring-subscriptions/{id}", Authenticate(jwtSecret, dbInstance, Authorize(PermReadMonitoringSubscription, GetMonitoringSubscription(dbInstance))).ServeHTTP)
+	mux.HandleFunc("DELETE /api/v1/monitoring-subscriptions/{id}", Authenticate(jwtSecret, dbInstance, Authorize(PermDeleteMonitoringSubscription, DeleteMonitoringSubscription(dbInstance))).ServeHTTP)
+
 	// Interfaces (Authenticated)
 	mux.HandleFunc("GET /api/v1/networking/interfaces", Authenticate(jwtSecret, dbInstance, Authorize(PermListNetworkInterfaces, ListNetworkInterfaces(dbInstance, appCfg))).ServeHTTP)
 	mux.HandleFunc("PUT /api/v1/networking/interfaces/n3", Authenticate(jwtSecret, dbInstance, Authorize(PermUpdateN3Interface, UpdateN3Interface(dbInstance))).ServeHTTP)
@@ -290,6 +375,21 @@ func NewHandler(cfg HandlerConfig) http.Handler {
 	return handler
 }
 
+// allDatapathFeatures stands in for the datapath probe where there is none.
+func allDatapathFeatures() models.DatapathFeatures {
+	return models.DatapathFeatures{
+		DataNetworkEgress: true,
+		NATPools:          true,
+		FQDNRules:         true,
+		RateLimitRules:    true,
+		BreakoutRules:     true,
+		TCPMSSClamp:       true,
+		RatedRules:        true,
+		CaptivePortal:     true,
+		QoSFlows:          true,
+	}
+}
+
 // DiscoveryHandlerConfig holds the dependencies for the discovery-phase
 // HTTP handler that runs before cluster formation.
 type DiscoveryHandlerConfig struct {
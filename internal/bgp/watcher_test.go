@@ -82,6 +82,14 @@ func (fk *fakeKernel) EnsureGatewaysOnInterfaceInNeighTable(_ kernel.NetworkInte
 	return nil
 }
 
+func (fk *fakeKernel) ResolveEgressDevice(name string, _ int) (string, error) { return name, nil }
+func (fk *fakeKernel) ReplaceTableRoute(_ kernel.TableRoute) error            { return nil }
+func (fk *fakeKernel) DeleteTableRoute(_ kernel.TableRoute) error             { return nil }
+func (fk *fakeKernel) ListManagedTableRoutes() ([]kernel.TableRoute, error)   { return nil, nil }
+func (fk *fakeKernel) AddSourceRule(_ kernel.SourceRule) error                { return nil }
+func (fk *fakeKernel) DeleteSourceRule(_ kernel.SourceRule) error             { return nil }
+func (fk *fakeKernel) ListManagedSourceRules() ([]kernel.SourceRule, error)   { return nil, nil }
+
 // fakeImportStore returns configurable import prefix entries per peer.
 type fakeImportStore struct {
 	entries map[int][]bgp.ImportPrefixEntry
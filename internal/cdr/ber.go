@@ -0,0 +1,361 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cdr
+
+import (
+	"encoding/hex"
+	"net/netip"
+	"time"
+
+	"github.com/ellanetworks/core/internal/accounting"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/nas"
+)
+
+// ASN.1 identifier octet bits (X.690 §8.1.2).
+const (
+	classUniversal   = 0x00
+	classContext     = 0x80
+	constructed      = 0x20
+	tagEnumerated    = 0x0a
+	tagSequence      = 0x10
+	highTagNumber    = 0x1f
+	pgwRecordTag     = 79 // GPRSRecord pGWRecord
+	recordTypePGW    = 85
+	changeQoS        = 0 // ChangeCondition qoSChange
+	changeClosure    = 2 // ChangeCondition recordClosure
+	servingNodeSGW   = 2 // ServingNodeType gTPSGW
+	pdnTypeOrg       = 0xf1
+	pdnTypeIPv4      = 0x21
+	pdnTypeIPv6      = 0x57
+	pdnTypeIPv4v6    = 0x8d
+	uliFlagTAI       = 0x08
+	uliFlagECGI      = 0x10
+	normalChargeChar = 0x0800 // Charging Characteristics, normal profile
+)
+
+// PGWRecord fields (TS 32.298 §5.2.2.1).
+const (
+	fieldRecordType              = 0
+	fieldServedIMSI              = 3
+	fieldPGWAddress              = 4
+	fieldChargingID              = 5
+	fieldServingNodeAddress      = 6
+	fieldAccessPointNameNI       = 7
+	fieldPDPPDNType              = 8
+	fieldServedPDPPDNAddress     = 9
+	fieldListOfTrafficVolumes    = 12
+	fieldRecordOpeningTime       = 13
+	fieldDuration                = 14
+	fieldCauseForRecClosing      = 15
+	fieldRecordSequenceNumber    = 17
+	fieldNodeID                  = 18
+	fieldLocalSequenceNumber     = 20
+	fieldChargingCharacteristics = 23
+	fieldServedIMEISV            = 29
+	fieldRATType                 = 30
+	fieldUserLocationInformation = 32
+	fieldServingNodeType         = 35
+	fieldStartTime               = 38
+	fieldStopTime                = 39
+	fieldServedPDPPDNAddressExt  = 45
+)
+
+// ChangeOfCharCondition and EPCQoSInformation fields.
+const (
+	containerUplink     = 3
+	containerDownlink   = 4
+	containerCondition  = 5
+	containerTime       = 6
+	containerQoS        = 9
+	qosQCI              = 1
+	qosAPNAMBRUplink    = 7
+	qosAPNAMBRDownlink  = 8
+	ipBinV4Address      = 0
+	ipBinV6Address      = 1
+	pdpAddressIPAddress = 0
+	timeStampLen        = 9
+)
+
+// tbcdFiller pads a TBCD-STRING of odd length.
+const tbcdFiller byte = 0x0f
+
+// AppendBER appends r encoded as a GPRSRecord carrying a PGWRecord. Files
+// of records are these encodings back to back.
+func (r *Record) AppendBER(b []byte) []byte {
+	var f []byte
+
+	f = appendInt(f, classContext, fieldRecordType, recordTypePGW)
+
+	if imsi, ok := tbcd(r.IMSI); ok {
+		f = appendTLV(f, classContext, fieldServedIMSI, imsi)
+	}
+
+	f = appendTLV(f, classContext|constructed, fieldPGWAddress, gsnAddress(r.Address))
+	f = appendInt(f, classContext, fieldChargingID, uint64(r.ChargingID))
+	f = appendTLV(f, classContext|constructed, fieldServingNodeAddress, gsnAddress(r.Address))
+	f = appendTLV(f, classContext, fieldAccessPointNameNI, []byte(r.DNN))
+	f = appendTLV(f, classContext, fieldPDPPDNType, r.pdnType())
+
+	switch {
+	case r.IPv4.IsValid():
+		f = appendTLV(f, classContext|constructed, fieldServedPDPPDNAddress, pdpAddress(r.IPv4))
+	case r.IPv6Prefix.IsValid():
+		f = appendTLV(f, classContext|constructed, fieldServedPDPPDNAddress, pdpAddress(r.IPv6Prefix.Addr()))
+	}
+
+	f = appendTLV(f, classContext|constructed, fieldListOfTrafficVolumes, r.trafficVolume())
+	f = appendTLV(f, classContext, fieldRecordOpeningTime, timeStamp(r.Opened))
+	f = appendInt(f, classContext, fieldDuration, uint64(r.Closed.Sub(r.Opened)/time.Second))
+	f = appendInt(f, classContext, fieldCauseForRecClosing, uint64(r.Cause))
+
+	if r.Sequence > 0 {
+		f = appendInt(f, classContext, fieldRecordSequenceNumber, uint64(r.Sequence))
+	}
+
+	f = appendTLV(f, classContext, fieldNodeID, []byte(r.NodeID))
+	f = appendInt(f, classContext, fieldLocalSequenceNumber, r.LocalSequence)
+	f = appendTLV(f, classContext, fieldChargingCharacteristics, []byte{normalChargeChar >> 8, normalChargeChar & 0xff})
+
+	if imei, ok := tbcd(r.IMEI); ok {
+		f = appendTLV(f, classContext, fieldServedIMEISV, imei)
+	}
+
+	f = appendInt(f, classContext, fieldRATType, uint64(r.RAT))
+
+	if uli := userLocationInformation(r.Location); uli != nil {
+		f = appendTLV(f, classContext, fieldUserLocationInformation, uli)
+	}
+
+	if r.RAT == accounting.RATEUTRAN {
+		f = appendTLV(f, classContext|constructed, fieldServingNodeType, appendInt(nil, classUniversal, tagEnumerated, servingNodeSGW))
+	}
+
+	f = appendTLV(f, classContext, fieldStartTime, timeStamp(r.SessionStart))
+
+	if !r.SessionStop.IsZero() {
+		f = appendTLV(f, classContext, fieldStopTime, timeStamp(r.SessionStop))
+	}
+
+	if r.IPv4.IsValid() && r.IPv6Prefix.IsValid() {
+		f = appendTLV(f, classContext|constructed, fieldServedPDPPDNAddressExt, pdpAddress(r.IPv6Prefix.Addr()))
+	}
+
+	return appendTLV(b, classContext|constructed, pgwRecordTag, f)
+}
+
+func (r *Record) pdnType() []byte {
+	switch {
+	case r.IPv4.IsValid() && r.IPv6Prefix.IsValid():
+		return []byte{pdnTypeOrg, pdnTypeIPv4v6}
+	case r.IPv6Prefix.IsValid():
+		return []byte{pdnTypeOrg, pdnTypeIPv6}
+	default:
+		return []byte{pdnTypeOrg, pdnTypeIPv4}
+	}
+}
+
+// trafficVolume encodes the record's single ChangeOfCharCondition: the
+// volumes it carried and the QoS they were carried under.
+func (r *Record) trafficVolume() []byte {
+	condition := uint64(changeClosure)
+	if r.Cause == CauseMaxChangeCond {
+		condition = changeQoS
+	}
+
+	var qos []byte
+
+	qos = appendInt(qos, classContext, qosQCI, uint64(r.QoS.FiveQI))
+	qos = appendInt(qos, classContext, qosAPNAMBRUplink, r.QoS.Ambr.Uplink.Bps())
+	qos = appendInt(qos, classContext, qosAPNAMBRDownlink, r.QoS.Ambr.Downlink.Bps())
+
+	var c []byte
+
+	c = appendInt(c, classContext, containerUplink, r.Uplink)
+	c = appendInt(c, classContext, containerDownlink, r.Downlink)
+	c = appendInt(c, classContext, containerCondition, condition)
+	c = appendTLV(c, classContext, containerTime, timeStamp(r.Closed))
+	c = appendTLV(c, classContext|constructed, containerQoS, qos)
+
+	return appendTLV(nil, classUniversal|constructed, tagSequence, c)
+}
+
+// gsnAddress encodes an IPBinaryAddress, empty when addr is unknown.
+func gsnAddress(addr netip.Addr) []byte {
+	switch {
+	case addr.Is4():
+		a := addr.As4()
+		return appendTLV(nil, classContext, ipBinV4Address, a[:])
+	case addr.Is6():
+		a := addr.As16()
+		return appendTLV(nil, classContext, ipBinV6Address, a[:])
+	default:
+		return nil
+	}
+}
+
+// pdpAddress encodes a PDPAddress holding an IP address.
+func pdpAddress(addr netip.Addr) []byte {
+	return appendTLV(nil, classContext|constructed, pdpAddressIPAddress, gsnAddress(addr))
+}
+
+// timeStamp encodes a TimeStamp: YYMMDDhhmmss in BCD, then the UTC offset
+// as an ASCII sign and hhmm in BCD. Times are written in UTC.
+func timeStamp(t time.Time) []byte {
+	t = t.UTC()
+	b := make([]byte, 0, timeStampLen)
+
+	for _, v := range []int{t.Year() % 100, int(t.Month()), t.Day(), t.Hour(), t.Minute(), t.Second()} {
+		b = append(b, byte(v/10)<<4|byte(v%10))
+	}
+
+	return append(b, '+', 0, 0)
+}
+
+// tbcd encodes a digit string as a TBCD-STRING (TS 29.002 §17.7.8),
+// reporting false when s is empty or holds anything but digits.
+func tbcd(s string) ([]byte, bool) {
+	if s == "" {
+		return nil, false
+	}
+
+	b := make([]byte, 0, (len(s)+1)/2)
+
+	for i := 0; i < len(s); i += 2 {
+		lo := s[i]
+		if lo < '0' || lo > '9' {
+			return nil, false
+		}
+
+		hi := tbcdFiller
+
+		if i+1 < len(s) {
+			if s[i+1] < '0' || s[i+1] > '9' {
+				return nil, false
+			}
+
+			hi = s[i+1] - '0'
+		}
+
+		b = append(b, hi<<4|(lo-'0'))
+	}
+
+	return b, true
+}
+
+// userLocationInformation encodes an E-UTRAN location as the User Location
+// Information IE of TS 29.274 §8.21 with a TAI and an ECGI. It returns nil
+// for other locations, which that encoding cannot carry.
+func userLocationInformation(loc models.UserLocation) []byte {
+	eutra := loc.EutraLocation
+	if eutra == nil || eutra.Tai == nil || eutra.Ecgi == nil {
+		return nil
+	}
+
+	tai, ok := plmnAndHex(eutra.Tai.PlmnID, eutra.Tai.Tac, 2)
+	if !ok {
+		return nil
+	}
+
+	ecgi, ok := plmnAndHex(eutra.Ecgi.PlmnID, eutra.Ecgi.EutraCellID, 4)
+	if !ok {
+		return nil
+	}
+
+	return append(append([]byte{uliFlagTAI | uliFlagECGI}, tai...), ecgi...)
+}
+
+// plmnAndHex encodes a PLMN followed by a hexadecimal identity in idLen
+// octets.
+func plmnAndHex(plmn *models.PlmnID, id string, idLen int) ([]byte, bool) {
+	if plmn == nil {
+		return nil, false
+	}
+
+	b, err := nas.PLMN{MCC: plmn.Mcc, MNC: plmn.Mnc}.AppendBinary(nil)
+	if err != nil {
+		return nil, false
+	}
+
+	if len(id)%2 == 1 {
+		id = "0" + id
+	}
+
+	raw, err := hex.DecodeString(id)
+	if err != nil || len(raw) > idLen {
+		return nil, false
+	}
+
+	b = append(b, make([]byte, idLen-len(raw))...)
+
+	return append(b, raw...), true
+}
+
+// appendInt appends an INTEGER or ENUMERATED v with the given tag.
+func appendInt(b []byte, class byte, tag int, v uint64) []byte {
+	var content [9]byte
+
+	n := 1
+	for v>>(8*n) != 0 && n < 8 {
+		n++
+	}
+
+	// A set top bit would read as negative: prefix a zero octet.
+	if v>>(8*n-1)&1 == 1 {
+		n++
+	}
+
+	for i := range n {
+		if shift := 8 * (n - 1 - i); shift < 64 {
+			content[i] = byte(v >> shift)
+		}
+	}
+
+	return appendTLV(b, class, tag, content[:n])
+}
+
+// appendTLV appends an identifier, a definite length and content.
+func appendTLV(b []byte, class byte, tag int, content []byte) []byte {
+	if tag < highTagNumber {
+		b = append(b, class|byte(tag))
+	} else {
+		b = append(b, class|highTagNumber)
+
+		var stack [5]byte
+
+		n := 0
+		for t := tag; ; t >>= 7 {
+			stack[n] = byte(t & 0x7f)
+			n++
+
+			if t < 0x80 {
+				break
+			}
+		}
+
+		for i := n - 1; i >= 0; i-- {
+			octet := stack[i]
+			if i > 0 {
+				octet |= 0x80
+			}
+
+			b = append(b, octet)
+		}
+	}
+
+	l := len(content)
+
+	switch {
+	case l < 0x80:
+		b = append(b, byte(l))
+	case l <= 0xff:
+		b = append(b, 0x81, byte(l))
+	case l <= 0xffff:
+		b = append(b, 0x82, byte(l>>8), byte(l))
+	default:
+		b = append(b, 0x83, byte(l>>16), byte(l>>8), byte(l))
+	}
+
+	return append(b, content...)
+}
@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cdr
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/accounting"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// berFields splits a PGWRecord into its fields by context tag.
+func berFields(t *testing.T, b []byte) map[int]asn1.RawValue {
+	t.Helper()
+
+	var record asn1.RawValue
+
+	rest, err := asn1.Unmarshal(b, &record)
+	if err != nil {
+		t.Fatalf("unmarshal record: %v", err)
+	}
+
+	if len(rest) != 0 {
+		t.Fatalf("%d trailing bytes", len(rest))
+	}
+
+	if record.Class != asn1.ClassContextSpecific || record.Tag != pgwRecordTag || !record.IsCompound {
+		t.Fatalf("record is class %d tag %d, want [%d] constructed", record.Class, record.Tag, pgwRecordTag)
+	}
+
+	fields := make(map[int]asn1.RawValue)
+
+	for body := record.Bytes; len(body) > 0; {
+		var field asn1.RawValue
+
+		body, err = asn1.Unmarshal(body, &field)
+		if err != nil {
+			t.Fatalf("unmarshal field: %v", err)
+		}
+
+		if _, dup := fields[field.Tag]; dup {
+			t.Fatalf("field [%d] repeated", field.Tag)
+		}
+
+		fields[field.Tag] = field
+	}
+
+	return fields
+}
+
+func TestAppendBER(t *testing.T) {
+	plmn := &models.PlmnID{Mcc: "001", Mnc: "01"}
+	opened := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	r := &Record{
+		LocalSequence: 7,
+		NodeID:        "ella-core-1",
+		Address:       netip.MustParseAddr("192.0.2.1"),
+		ChargingID:    0x80000001,
+		Sequence:      2,
+		IMSI:          "001010000000001",
+		IMEI:          "356938035643809",
+		DNN:           "internet",
+		RAT:           accounting.RATEUTRAN,
+		IPv4:          netip.MustParseAddr("10.45.0.2"),
+		IPv6Prefix:    netip.MustParsePrefix("2001:db8:1::/64"),
+		Location: models.UserLocation{EutraLocation: &models.EutraLocation{
+			Tai:  &models.Tai{PlmnID: plmn, Tac: "0001"},
+			Ecgi: &models.Ecgi{PlmnID: plmn, EutraCellID: "0000101"},
+		}},
+		QoS:          QoS{FiveQI: 9, Ambr: models.Ambr{Uplink: models.MustParseBitRate("100 Mbps"), Downlink: models.MustParseBitRate("200 Mbps")}},
+		SessionStart: opened,
+		Opened:       opened,
+		Closed:       opened.Add(90 * time.Second),
+		Uplink:       1000,
+		Downlink:     5 << 32,
+		Cause:        CauseVolumeLimit,
+	}
+
+	fields := berFields(t, r.AppendBER(nil))
+
+	want := map[int][]byte{
+		fieldRecordType:              {recordTypePGW},
+		fieldServedIMSI:              {0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0xf1},
+		fieldChargingID:              {0x00, 0x80, 0x00, 0x00, 0x01},
+		fieldAccessPointNameNI:       []byte("internet"),
+		fieldPDPPDNType:              {pdnTypeOrg, pdnTypeIPv4v6},
+		fieldRecordOpeningTime:       {0x26, 0x03, 0x04, 0x05, 0x06, 0x07, '+', 0, 0},
+		fieldDuration:                {90},
+		fieldCauseForRecClosing:      {byte(CauseVolumeLimit)},
+		fieldRecordSequenceNumber:    {2},
+		fieldNodeID:                  []byte("ella-core-1"),
+		fieldLocalSequenceNumber:     {7},
+		fieldServedIMEISV:            {0x53, 0x96, 0x83, 0x30, 0x65, 0x34, 0x08, 0xf9},
+		fieldRATType:                 {byte(accounting.RATEUTRAN)},
+		fieldUserLocationInformation: {0x18, 0x00, 0xf1, 0x10, 0x00, 0x01, 0x00, 0xf1, 0x10, 0x00, 0x00, 0x01, 0x01},
+		fieldPGWAddress:              {0x80, 4, 192, 0, 2, 1},
+		fieldServedPDPPDNAddress:     {0xa0, 6, 0x80, 4, 10, 45, 0, 2},
+	}
+
+	for tag, content := range want {
+		field, ok := fields[tag]
+		if !ok {
+			t.Errorf("field [%d] missing", tag)
+			continue
+		}
+
+		if !bytes.Equal(field.Bytes, content) {
+			t.Errorf("field [%d] = % x, want % x", tag, field.Bytes, content)
+		}
+	}
+
+	if _, ok := fields[fieldStopTime]; ok {
+		t.Error("partial record carries a stop time")
+	}
+
+	if _, ok := fields[fieldServedPDPPDNAddressExt]; !ok {
+		t.Error("dual-stack record lacks the IPv6 address")
+	}
+
+	var container asn1.RawValue
+	if _, err := asn1.Unmarshal(fields[fieldListOfTrafficVolumes].Bytes, &container); err != nil {
+		t.Fatalf("unmarshal traffic volume: %v", err)
+	}
+
+	var downlink asn1.RawValue
+
+	rest, err := asn1.Unmarshal(container.Bytes, &downlink)
+	if err == nil {
+		_, err = asn1.Unmarshal(rest, &downlink)
+	}
+
+	if err != nil || downlink.Tag != containerDownlink || !bytes.Equal(downlink.Bytes, []byte{0x05, 0, 0, 0, 0}) {
+		t.Fatalf("downlink volume = %+v (%v), want 5<<32", downlink, err)
+	}
+}
+
+func TestAppendBER_FinalNRRecord(t *testing.T) {
+	start := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	r := &Record{
+		NodeID:       "ella-core-1",
+		IMSI:         "001010000000001",
+		DNN:          "internet",
+		RAT:          accounting.RATNR,
+		IPv6Prefix:   netip.MustParsePrefix("2001:db8:1::/64"),
+		SessionStart: start,
+		SessionStop:  start.Add(time.Minute),
+		Opened:       start,
+		Closed:       start.Add(time.Minute),
+	}
+
+	fields := berFields(t, r.AppendBER(nil))
+
+	for _, tag := range []int{fieldRecordSequenceNumber, fieldServedIMEISV, fieldUserLocationInformation, fieldServingNodeType, fieldServedPDPPDNAddressExt} {
+		if _, ok := fields[tag]; ok {
+			t.Errorf("field [%d] present", tag)
+		}
+	}
+
+	if got := fields[fieldPDPPDNType].Bytes; !bytes.Equal(got, []byte{pdnTypeOrg, pdnTypeIPv6}) {
+		t.Errorf("PDN type = % x, want IPv6", got)
+	}
+
+	if _, ok := fields[fieldStopTime]; !ok {
+		t.Error("final record lacks a stop time")
+	}
+}
+
+func TestAppendTLV_HighTagAndLongLength(t *testing.T) {
+	content := bytes.Repeat([]byte{0xaa}, 300)
+
+	b := appendTLV(nil, classContext|constructed, pgwRecordTag, content)
+	if !bytes.Equal(b[:5], []byte{0xbf, 0x4f, 0x82, 0x01, 0x2c}) {
+		t.Fatalf("header = % x, want bf 4f 82 01 2c", b[:5])
+	}
+
+	b = appendTLV(nil, classContext, 200, nil)
+	if !bytes.Equal(b, []byte{0x9f, 0x81, 0x48, 0x00}) {
+		t.Fatalf("tag 200 = % x, want 9f 81 48 00", b)
+	}
+}
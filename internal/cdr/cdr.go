@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package cdr writes offline charging data records for the sessions a node
+// serves. A session yields a record when it ends, and partial records
+// before that when its traffic or duration crosses a threshold or its QoS
+// changes. Records go to files under the data directory in two formats:
+// CSV, and PGW-CDRs encoded in ASN.1 BER as in TS 32.298. Files rotate on
+// size and age; closed files are left for a billing system to collect.
+package cdr
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/ellanetworks/core/internal/accounting"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// Session describes a session when it starts.
+type Session struct {
+	// Ref identifies the session to the caller; later calls name it.
+	Ref        string
+	IMSI       string
+	DNN        string
+	Snssai     models.Snssai
+	RAT        accounting.RAT
+	IPv4       netip.Addr
+	IPv6Prefix netip.Prefix
+	QoS        QoS
+}
+
+// QoS is what a session is charged under.
+type QoS struct {
+	FiveQI int32
+	Ambr   models.Ambr
+}
+
+// Cause is why a record was closed, valued as CauseForRecClosing (TS 32.298
+// §5.1.2.2.15).
+type Cause int
+
+const (
+	CauseNormalRelease   Cause = 0
+	CauseAbnormalRelease Cause = 4
+	CauseVolumeLimit     Cause = 16
+	CauseTimeLimit       Cause = 17
+	CauseMaxChangeCond   Cause = 19
+)
+
+func (c Cause) String() string {
+	switch c {
+	case CauseNormalRelease:
+		return "normalRelease"
+	case CauseAbnormalRelease:
+		return "abnormalRelease"
+	case CauseVolumeLimit:
+		return "volumeLimit"
+	case CauseTimeLimit:
+		return "timeLimit"
+	case CauseMaxChangeCond:
+		return "maxChangeCond"
+	default:
+		return "unknown"
+	}
+}
+
+// Record is one charging data record: a whole session, or the part of it
+// between two closing conditions.
+type Record struct {
+	// LocalSequence numbers the records of a node.
+	LocalSequence uint64
+	NodeID        string
+	// Address is the gateway address records name as P-GW and serving
+	// node; invalid when unknown.
+	Address    netip.Addr
+	ChargingID uint32
+	// Sequence numbers the records of a session that was split; 0 when a
+	// single record covers the session.
+	Sequence int
+	IMSI     string
+	IMEI     string
+	DNN      string
+	Snssai   models.Snssai
+	RAT      accounting.RAT
+	IPv4     netip.Addr
+	// IPv6Prefix is the prefix delegated to the UE.
+	IPv6Prefix netip.Prefix
+	Location   models.UserLocation
+	QoS        QoS
+	// SessionStart is when the session began; SessionStop is zero on
+	// partial records.
+	SessionStart time.Time
+	SessionStop  time.Time
+	Opened       time.Time
+	Closed       time.Time
+	// Uplink and Downlink are the octets carried while the record was open.
+	Uplink   uint64
+	Downlink uint64
+	Cause    Cause
+}
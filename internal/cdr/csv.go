@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cdr
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ellanetworks/core/internal/accounting"
+)
+
+// csvHeader names the columns of CSV files, in the order CSVFields returns
+// them.
+var csvHeader = []string{
+	"local_sequence_number",
+	"node_id",
+	"charging_id",
+	"record_sequence_number",
+	"imsi",
+	"imei",
+	"dnn",
+	"sst",
+	"sd",
+	"rat_type",
+	"ipv4_address",
+	"ipv6_prefix",
+	"mcc",
+	"mnc",
+	"tac",
+	"cell_id",
+	"five_qi",
+	"ambr_uplink_bps",
+	"ambr_downlink_bps",
+	"session_start_time",
+	"session_stop_time",
+	"record_opening_time",
+	"record_closing_time",
+	"duration",
+	"uplink_bytes",
+	"downlink_bytes",
+	"cause_for_record_closing",
+}
+
+// CSVFields returns r as a CSV row. Times are RFC 3339 in UTC; values that
+// are not known are left empty.
+func (r *Record) CSVFields() []string {
+	rat := "NR"
+	if r.RAT == accounting.RATEUTRAN {
+		rat = "EUTRA"
+	}
+
+	var ipv4, ipv6 string
+
+	if r.IPv4.IsValid() {
+		ipv4 = r.IPv4.String()
+	}
+
+	if r.IPv6Prefix.IsValid() {
+		ipv6 = r.IPv6Prefix.String()
+	}
+
+	var sequence string
+	if r.Sequence > 0 {
+		sequence = strconv.Itoa(r.Sequence)
+	}
+
+	mcc, mnc, tac, cell := r.cell()
+
+	return []string{
+		strconv.FormatUint(r.LocalSequence, 10),
+		r.NodeID,
+		strconv.FormatUint(uint64(r.ChargingID), 10),
+		sequence,
+		r.IMSI,
+		r.IMEI,
+		r.DNN,
+		strconv.Itoa(int(r.Snssai.Sst)),
+		r.Snssai.Sd,
+		rat,
+		ipv4,
+		ipv6,
+		mcc,
+		mnc,
+		tac,
+		cell,
+		strconv.Itoa(int(r.QoS.FiveQI)),
+		strconv.FormatUint(r.QoS.Ambr.Uplink.Bps(), 10),
+		strconv.FormatUint(r.QoS.Ambr.Downlink.Bps(), 10),
+		csvTime(r.SessionStart),
+		csvTime(r.SessionStop),
+		csvTime(r.Opened),
+		csvTime(r.Closed),
+		strconv.FormatInt(int64(r.Closed.Sub(r.Opened)/time.Second), 10),
+		strconv.FormatUint(r.Uplink, 10),
+		strconv.FormatUint(r.Downlink, 10),
+		r.Cause.String(),
+	}
+}
+
+// cell returns the serving PLMN, TAC and cell identity, NR or E-UTRAN.
+func (r *Record) cell() (mcc, mnc, tac, cell string) {
+	switch loc := r.Location; {
+	case loc.NrLocation != nil && loc.NrLocation.Tai != nil && loc.NrLocation.Ncgi != nil:
+		if plmn := loc.NrLocation.Tai.PlmnID; plmn != nil {
+			mcc, mnc = plmn.Mcc, plmn.Mnc
+		}
+
+		return mcc, mnc, loc.NrLocation.Tai.Tac, loc.NrLocation.Ncgi.NrCellID
+	case loc.EutraLocation != nil && loc.EutraLocation.Tai != nil && loc.EutraLocation.Ecgi != nil:
+		if plmn := loc.EutraLocation.Tai.PlmnID; plmn != nil {
+			mcc, mnc = plmn.Mcc, plmn.Mnc
+		}
+
+		return mcc, mnc, loc.EutraLocation.Tai.Tac, loc.EutraLocation.Ecgi.EutraCellID
+	default:
+		return "", "", "", ""
+	}
+}
+
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
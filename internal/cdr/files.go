@@ -0,0 +1,283 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cdr
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ExtCSV and ExtBER name the two files a rotation produces.
+	ExtCSV = ".csv"
+	ExtBER = ".ber"
+	// openSuffix marks a file still being written. Collectors see a file
+	// only once it is closed and renamed.
+	openSuffix = ".open"
+)
+
+// Dir is where a node keeps its CDR files: beside its database.
+func Dir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "cdr")
+}
+
+// ErrInvalidName reports a file name that does not name a closed CDR file.
+var ErrInvalidName = errors.New("invalid CDR file name")
+
+// File is a closed CDR file.
+type File struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// ListFiles lists the closed CDR files in dir, oldest first. A missing dir
+// holds none.
+func ListFiles(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	files := make([]File, 0, len(entries))
+
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !closedName(e.Name()) {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		files = append(files, File{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+
+	// Names carry a zero-padded sequence, so they sort in write order.
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(a.Name, b.Name) })
+
+	return files, nil
+}
+
+// OpenFile opens the closed CDR file name in dir. Names that are not a
+// plain closed-file name are refused with ErrInvalidName.
+func OpenFile(dir, name string) (*os.File, error) {
+	if filepath.Base(name) != name || !closedName(name) {
+		return nil, ErrInvalidName
+	}
+
+	return os.Open(filepath.Join(dir, name))
+}
+
+func closedName(name string) bool {
+	return !strings.HasPrefix(name, ".") && (strings.HasSuffix(name, ExtCSV) || strings.HasSuffix(name, ExtBER))
+}
+
+// fileSet writes records to a CSV and a BER file opened together and
+// rotated together. It is not safe for concurrent use.
+type fileSet struct {
+	dir     string
+	prefix  string
+	maxSize int64
+	maxAge  time.Duration
+
+	seq     uint64
+	base    string
+	opened  time.Time
+	size    int64
+	records int
+	csv     *os.File
+	ber     *os.File
+}
+
+// recover closes files a previous run left open, as they were found, and
+// picks the next file sequence number.
+func (f *fileSet) recover() error {
+	if err := os.MkdirAll(f.dir, 0o750); err != nil {
+		return fmt.Errorf("create CDR directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return fmt.Errorf("read CDR directory: %w", err)
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+
+		if closed, ok := strings.CutSuffix(name, openSuffix); ok {
+			if err := os.Rename(filepath.Join(f.dir, name), filepath.Join(f.dir, closed)); err != nil {
+				return fmt.Errorf("close CDR file left open: %w", err)
+			}
+
+			name = closed
+		}
+
+		if seq, ok := f.sequenceOf(name); ok && seq >= f.seq {
+			f.seq = seq + 1
+		}
+	}
+
+	return nil
+}
+
+// sequenceOf reads the sequence number from a file this set would write:
+// "<prefix>_<time>_<seq>.<ext>".
+func (f *fileSet) sequenceOf(name string) (uint64, bool) {
+	rest, ok := strings.CutPrefix(name, f.prefix+"_")
+	if !ok {
+		return 0, false
+	}
+
+	rest = strings.TrimSuffix(strings.TrimSuffix(rest, ExtCSV), ExtBER)
+
+	i := strings.LastIndexByte(rest, '_')
+	if i < 0 {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(rest[i+1:], 10, 64)
+
+	return seq, err == nil
+}
+
+// write appends r to both files, opening them first if needed, and rotates
+// once the BER file reaches the size limit.
+func (f *fileSet) write(r *Record, now time.Time) error {
+	if f.csv == nil {
+		if err := f.open(now); err != nil {
+			return err
+		}
+	}
+
+	var row bytes.Buffer
+
+	w := csv.NewWriter(&row)
+	if err := w.Write(r.CSVFields()); err != nil {
+		return err
+	}
+
+	w.Flush()
+
+	if _, err := f.csv.Write(row.Bytes()); err != nil {
+		return fmt.Errorf("write CSV record: %w", err)
+	}
+
+	ber := r.AppendBER(nil)
+	if _, err := f.ber.Write(ber); err != nil {
+		return fmt.Errorf("write BER record: %w", err)
+	}
+
+	f.records++
+	f.size += int64(len(ber))
+
+	if f.size >= f.maxSize {
+		return f.close()
+	}
+
+	return nil
+}
+
+// rotate closes files that have records and have been open for the age
+// limit. Empty files are kept open rather than shipped.
+func (f *fileSet) rotate(now time.Time) error {
+	if f.csv == nil || f.records == 0 || now.Sub(f.opened) < f.maxAge {
+		return nil
+	}
+
+	return f.close()
+}
+
+func (f *fileSet) open(now time.Time) error {
+	base := fmt.Sprintf("%s_%s_%06d", f.prefix, now.UTC().Format("20060102T150405Z"), f.seq)
+
+	csvFile, err := os.OpenFile(filepath.Join(f.dir, base+ExtCSV+openSuffix), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("open CSV file: %w", err)
+	}
+
+	berFile, err := os.OpenFile(filepath.Join(f.dir, base+ExtBER+openSuffix), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		_ = csvFile.Close()
+		return fmt.Errorf("open BER file: %w", err)
+	}
+
+	w := csv.NewWriter(csvFile)
+	if err := w.Write(csvHeader); err != nil {
+		_ = csvFile.Close()
+		_ = berFile.Close()
+
+		return fmt.Errorf("write CSV header: %w", err)
+	}
+
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		_ = csvFile.Close()
+		_ = berFile.Close()
+
+		return fmt.Errorf("write CSV header: %w", err)
+	}
+
+	f.seq++
+	f.base = base
+	f.opened = now
+	f.size = 0
+	f.records = 0
+	f.csv = csvFile
+	f.ber = berFile
+
+	return nil
+}
+
+// close syncs, closes and renames the open files, making them visible to
+// collectors.
+func (f *fileSet) close() error {
+	if f.csv == nil {
+		return nil
+	}
+
+	var errs []error
+
+	for _, file := range []*os.File{f.csv, f.ber} {
+		if err := file.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+
+		if err := file.Close(); err != nil {
+			errs = append(errs, err)
+		}
+
+		name := file.Name()
+		if err := os.Rename(name, strings.TrimSuffix(name, openSuffix)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	f.csv = nil
+	f.ber = nil
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("close CDR files %s: %w", f.base, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cdr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/accounting"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// VolumeLimit and TimeLimit close a partial record once a session has
+	// carried that many octets, or run that long, since its last record.
+	VolumeLimit = 1 << 30
+	TimeLimit   = time.Hour
+	// FileMaxSize and FileMaxAge rotate the files: once the BER file holds
+	// that many octets, or has been open that long with records in it.
+	FileMaxSize = 8 << 20
+	FileMaxAge  = 15 * time.Minute
+	// tick is how often time limits are checked; it bounds how late a
+	// time-limited record or a rotation can be.
+	tick = 10 * time.Second
+)
+
+// Service tracks the sessions of this node and writes their charging data
+// records.
+type Service struct {
+	nodeID  string
+	address netip.Addr
+	ues     accounting.UEDirectory
+	now     func() time.Time
+
+	mu         sync.Mutex
+	files      fileSet
+	sessions   map[string]*session
+	chargingID uint32
+	localSeq   uint64
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// session is an open session and its current record.
+type session struct {
+	Session
+	chargingID uint32
+	ue         accounting.UE
+	started    time.Time
+	opened     time.Time
+	sequence   int
+	uplink     uint64
+	downlink   uint64
+}
+
+// NewService writes records to files in dir, naming nodeID as the node
+// and address as its gateway address. ues resolves the IMEI and location
+// records carry; nil leaves them out. Start must be called explicitly.
+func NewService(dir, nodeID string, address netip.Addr, ues accounting.UEDirectory) *Service {
+	var seed [4]byte
+
+	_, _ = rand.Read(seed[:])
+
+	return &Service{
+		nodeID:     nodeID,
+		address:    address,
+		ues:        ues,
+		now:        time.Now,
+		files:      fileSet{dir: dir, prefix: nodeID, maxSize: FileMaxSize, maxAge: FileMaxAge},
+		sessions:   make(map[string]*session),
+		chargingID: binary.BigEndian.Uint32(seed[:]),
+	}
+}
+
+// Start closes files a previous run left open and launches the loop that
+// enforces time limits and rotates files. Calls without a paired Stop are
+// no-ops.
+func (s *Service) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	if err := s.files.recover(); err != nil {
+		logger.ChargingLog.Warn("couldn't recover CDR files", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx, s.done)
+}
+
+// Stop ends the loop. Sessions still open get a final record with cause
+// abnormalRelease and the files are closed. Safe to call when not started.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	done := s.done
+	s.cancel = nil
+	s.done = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	for ref, sess := range s.sessions {
+		s.writeRecord(sess, CauseAbnormalRelease, now, true)
+		delete(s.sessions, ref)
+	}
+
+	if err := s.files.close(); err != nil {
+		logger.ChargingLog.Warn("couldn't close CDR files", zap.Error(err))
+	}
+}
+
+// SessionStarted opens the first record of sess.
+func (s *Service) SessionStarted(sess Session) {
+	var ue accounting.UE
+	if s.ues != nil {
+		ue, _ = s.ues.LookupUE(sess.IMSI, sess.RAT)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chargingID++
+	if s.chargingID == 0 {
+		s.chargingID++
+	}
+
+	now := s.now()
+	s.sessions[sess.Ref] = &session{
+		Session:    sess,
+		chargingID: s.chargingID,
+		ue:         ue,
+		started:    now,
+		opened:     now,
+	}
+}
+
+// SessionUsage adds traffic to a session's current record, closing it as
+// partial when the volume limit is reached. uplink and downlink are
+// increments in octets.
+func (s *Service) SessionUsage(ref string, uplink, downlink uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[ref]
+	if !ok {
+		return
+	}
+
+	sess.uplink += uplink
+	sess.downlink += downlink
+
+	if sess.uplink+sess.downlink >= VolumeLimit {
+		s.writeRecord(sess, CauseVolumeLimit, s.now(), false)
+	}
+}
+
+// SessionModified closes a session's current record as partial when its
+// QoS changed; the next record is charged under qos.
+func (s *Service) SessionModified(ref string, qos QoS) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[ref]
+	if !ok || sess.QoS.FiveQI == qos.FiveQI &&
+		sess.QoS.Ambr.Uplink.Equal(qos.Ambr.Uplink) && sess.QoS.Ambr.Downlink.Equal(qos.Ambr.Downlink) {
+		return
+	}
+
+	s.writeRecord(sess, CauseMaxChangeCond, s.now(), false)
+	sess.QoS = qos
+}
+
+// SessionStopped writes a session's final record.
+func (s *Service) SessionStopped(ref string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[ref]
+	if !ok {
+		return
+	}
+
+	delete(s.sessions, ref)
+	s.writeRecord(sess, CauseNormalRelease, s.now(), true)
+}
+
+// checkLimits closes records that reached the time limit and rotates
+// files that reached the age limit.
+func (s *Service) checkLimits() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	for _, sess := range s.sessions {
+		if now.Sub(sess.opened) >= TimeLimit {
+			s.writeRecord(sess, CauseTimeLimit, now, false)
+		}
+	}
+
+	if err := s.files.rotate(now); err != nil {
+		logger.ChargingLog.Warn("couldn't rotate CDR files", zap.Error(err))
+	}
+}
+
+// writeRecord closes sess's current record and writes it. A partial record
+// opens the next one; a final record gets the session's stop time. s.mu
+// must be held.
+func (s *Service) writeRecord(sess *session, cause Cause, now time.Time, final bool) {
+	if s.ues != nil {
+		if ue, ok := s.ues.LookupUE(sess.IMSI, sess.RAT); ok {
+			sess.ue = ue
+		}
+	}
+
+	if !final || sess.sequence > 0 {
+		sess.sequence++
+	}
+
+	s.localSeq++
+
+	r := &Record{
+		LocalSequence: s.localSeq,
+		NodeID:        s.nodeID,
+		Address:       s.address,
+		ChargingID:    sess.chargingID,
+		Sequence:      sess.sequence,
+		IMSI:          sess.IMSI,
+		IMEI:          sess.ue.IMEI,
+		DNN:           sess.DNN,
+		Snssai:        sess.Snssai,
+		RAT:           sess.RAT,
+		IPv4:          sess.IPv4,
+		IPv6Prefix:    sess.IPv6Prefix,
+		Location:      sess.ue.Location,
+		QoS:           sess.QoS,
+		SessionStart:  sess.started,
+		Opened:        sess.opened,
+		Closed:        now,
+		Uplink:        sess.uplink,
+		Downlink:      sess.downlink,
+		Cause:         cause,
+	}
+
+	if final {
+		r.SessionStop = now
+	}
+
+	if err := s.files.write(r, now); err != nil {
+		logger.ChargingLog.Warn("couldn't write charging data record",
+			zap.String("imsi", sess.IMSI), zap.Uint32("chargingID", sess.chargingID), zap.Error(err))
+	}
+
+	sess.opened = now
+	sess.uplink = 0
+	sess.downlink = 0
+}
+
+func (s *Service) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkLimits()
+		}
+	}
+}
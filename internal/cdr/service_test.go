@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cdr
+
+import (
+	"encoding/asn1"
+	"encoding/csv"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/accounting"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+type fakeUEs struct{}
+
+func (fakeUEs) LookupUE(string, accounting.RAT) (accounting.UE, bool) {
+	return accounting.UE{IMEI: "356938035643809"}, true
+}
+
+// fakeClock is advanced by hand.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestService(t *testing.T) (*Service, *fakeClock, string) {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "cdr")
+	clock := &fakeClock{t: time.Date(2026, 3, 4, 5, 0, 0, 0, time.UTC)}
+
+	s := NewService(dir, "ella-core-1", netip.MustParseAddr("192.0.2.1"), fakeUEs{})
+	s.now = clock.now
+
+	if err := s.files.recover(); err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+
+	return s, clock, dir
+}
+
+// readCSV reads the rows of every closed CSV file in dir, headers dropped.
+func readCSV(t *testing.T, dir string) [][]string {
+	t.Helper()
+
+	files, err := ListFiles(dir)
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+
+	var rows [][]string
+
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name, ExtCSV) {
+			continue
+		}
+
+		file, err := OpenFile(dir, f.Name)
+		if err != nil {
+			t.Fatalf("OpenFile: %v", err)
+		}
+
+		records, err := csv.NewReader(file).ReadAll()
+		_ = file.Close()
+
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+
+		if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+			t.Fatalf("%s lacks the header", f.Name)
+		}
+
+		rows = append(rows, records[1:]...)
+	}
+
+	return rows
+}
+
+func column(name string) int {
+	for i, h := range csvHeader {
+		if h == name {
+			return i
+		}
+	}
+
+	panic("no column " + name)
+}
+
+func TestService_PartialAndFinalRecords(t *testing.T) {
+	s, clock, dir := newTestService(t)
+
+	qos := QoS{FiveQI: 9, Ambr: models.Ambr{Uplink: models.MustParseBitRate("100 Mbps"), Downlink: models.MustParseBitRate("100 Mbps")}}
+
+	s.SessionStarted(Session{
+		Ref:    "ref-1",
+		IMSI:   "001010000000001",
+		DNN:    "internet",
+		Snssai: models.Snssai{Sst: 1, Sd: "102030"},
+		RAT:    accounting.RATNR,
+		IPv4:   netip.MustParseAddr("10.45.0.2"),
+		QoS:    qos,
+	})
+
+	clock.t = clock.t.Add(time.Minute)
+	s.SessionUsage("ref-1", VolumeLimit/2, VolumeLimit/2)
+
+	clock.t = clock.t.Add(time.Minute)
+	s.SessionUsage("ref-1", 100, 200)
+	s.SessionModified("ref-1", qos)
+
+	upgraded := qos
+	upgraded.Ambr.Downlink = models.MustParseBitRate("1 Gbps")
+	s.SessionModified("ref-1", upgraded)
+
+	clock.t = clock.t.Add(TimeLimit)
+	s.checkLimits()
+
+	clock.t = clock.t.Add(time.Minute)
+	s.SessionUsage("ref-1", 1, 2)
+	s.SessionStopped("ref-1")
+	s.SessionStopped("ref-1")
+
+	if err := s.files.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	rows := readCSV(t, dir)
+
+	want := []struct {
+		cause    Cause
+		sequence string
+		uplink   string
+		ambrDL   string
+	}{
+		{CauseVolumeLimit, "1", "536870912", "100000000"},
+		{CauseMaxChangeCond, "2", "100", "100000000"},
+		{CauseTimeLimit, "3", "0", "1000000000"},
+		{CauseNormalRelease, "4", "1", "1000000000"},
+	}
+
+	if len(rows) != len(want) {
+		t.Fatalf("got %d records, want %d: %v", len(rows), len(want), rows)
+	}
+
+	chargingID := rows[0][column("charging_id")]
+
+	for i, w := range want {
+		row := rows[i]
+
+		if row[column("cause_for_record_closing")] != w.cause.String() ||
+			row[column("record_sequence_number")] != w.sequence ||
+			row[column("uplink_bytes")] != w.uplink ||
+			row[column("ambr_downlink_bps")] != w.ambrDL {
+			t.Errorf("record %d = %v, want %+v", i, row, w)
+		}
+
+		if row[column("charging_id")] != chargingID || row[column("sd")] != "102030" || row[column("imei")] != "356938035643809" {
+			t.Errorf("record %d lost session data: %v", i, row)
+		}
+
+		hasStop := row[column("session_stop_time")] != ""
+		if hasStop != (w.cause == CauseNormalRelease) {
+			t.Errorf("record %d session_stop_time = %q", i, row[column("session_stop_time")])
+		}
+	}
+}
+
+func TestService_SingleRecordHasNoSequence(t *testing.T) {
+	s, _, dir := newTestService(t)
+
+	s.SessionStarted(Session{Ref: "ref-1", IMSI: "001010000000001", DNN: "internet", RAT: accounting.RATEUTRAN})
+	s.SessionUsage("ref-1", 10, 20)
+	s.Stop()
+
+	rows := readCSV(t, dir)
+	if len(rows) != 1 {
+		t.Fatalf("got %d records, want 1", len(rows))
+	}
+
+	if got := rows[0][column("record_sequence_number")]; got != "" {
+		t.Errorf("record_sequence_number = %q, want none", got)
+	}
+
+	if got := rows[0][column("cause_for_record_closing")]; got != CauseAbnormalRelease.String() {
+		t.Errorf("cause = %q, want abnormalRelease on shutdown", got)
+	}
+
+	files, err := ListFiles(dir)
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name, ExtBER) {
+			continue
+		}
+
+		b, err := os.ReadFile(filepath.Join(dir, f.Name))
+		if err != nil {
+			t.Fatalf("read BER file: %v", err)
+		}
+
+		var record asn1.RawValue
+		if rest, err := asn1.Unmarshal(b, &record); err != nil || len(rest) != 0 {
+			t.Fatalf("BER file is not one record: %v, %d trailing bytes", err, len(rest))
+		}
+	}
+}
+
+func TestFileSet_RotationAndRecovery(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 4, 5, 0, 0, 0, time.UTC)
+	f := &fileSet{dir: dir, prefix: "ella-core-1", maxSize: 1 << 20, maxAge: time.Minute}
+
+	if err := f.recover(); err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+
+	r := &Record{IMSI: "001010000000001", Opened: now, Closed: now}
+
+	if err := f.write(r, now); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if files, _ := ListFiles(dir); len(files) != 0 {
+		t.Fatalf("open files listed: %v", files)
+	}
+
+	if err := f.rotate(now.Add(30 * time.Second)); err != nil || f.csv == nil {
+		t.Fatalf("rotated before the age limit: %v", err)
+	}
+
+	if err := f.rotate(now.Add(time.Minute)); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	if files, _ := ListFiles(dir); len(files) != 2 {
+		t.Fatalf("got %v, want a CSV and a BER file", files)
+	}
+
+	if err := f.rotate(now.Add(time.Hour)); err != nil || f.csv != nil {
+		t.Fatalf("empty set opened by rotate: %v", err)
+	}
+
+	// A crash leaves the next pair open; a new set closes it and carries on
+	// the sequence.
+	if err := f.write(r, now.Add(time.Hour)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	_ = f.csv.Close()
+	_ = f.ber.Close()
+
+	restarted := &fileSet{dir: dir, prefix: "ella-core-1", maxSize: 1 << 20, maxAge: time.Minute}
+	if err := restarted.recover(); err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+
+	if restarted.seq != 2 {
+		t.Fatalf("seq = %d, want 2", restarted.seq)
+	}
+
+	files, err := ListFiles(dir)
+	if err != nil || len(files) != 4 {
+		t.Fatalf("got %v (%v), want both pairs closed", files, err)
+	}
+
+	if !strings.HasSuffix(files[0].Name, "_000000.ber") || !strings.HasSuffix(files[3].Name, "_000001.csv") {
+		t.Fatalf("files not in write order: %v", files)
+	}
+}
+
+func TestOpenFile_RefusesOtherPaths(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"../db.sqlite3", "a/b.csv", "x.csv.open", ".hidden.csv", "notes.txt"} {
+		if _, err := OpenFile(dir, name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("OpenFile(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+
+	if _, err := OpenFile(dir, "missing.csv"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenFile(missing) = %v, want not exist", err)
+	}
+
+}
@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package charging holds sessions to credit granted by an online charging
+// system (TS 32.251 §5.2.2). A session asks for quota per rating group
+// when it starts, asks for more as it uses it up, and is terminated or
+// redirected when the charging system has no more to give. The charging
+// system is reached over Diameter Gy (TS 32.299) or Nchf_ConvergedCharging
+// (TS 32.291).
+package charging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/ellanetworks/core/internal/accounting"
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// ErrCreditDenied is returned when the charging system refuses a session
+// credit.
+var ErrCreditDenied = errors.New("credit denied by the online charging system")
+
+// Protocol is how the charging system is reached.
+type Protocol string
+
+const (
+	ProtocolDiameter Protocol = db.OnlineChargingDiameter
+	ProtocolNchf     Protocol = db.OnlineChargingNchf
+)
+
+// Settings are a data network's online charging settings. They are
+// comparable, so sessions charged alike share one client.
+type Settings struct {
+	Protocol Protocol
+	// Server is a Diameter peer as host:port, or the Nchf API root.
+	Server string
+	// Realm is the Destination-Realm of Diameter requests.
+	Realm string
+	// RatingGroup charges the traffic no network rule rates on its own.
+	RatingGroup uint32
+	// Continue lets sessions carry on unmetered when the charging system
+	// does not answer, rather than terminating them.
+	Continue bool
+}
+
+// Session describes a session when it starts.
+type Session struct {
+	// Ref identifies the session to the caller; later calls name it.
+	Ref        string
+	IMSI       string
+	DNN        string
+	Snssai     models.Snssai
+	RAT        accounting.RAT
+	IPv4       netip.Addr
+	IPv6Prefix netip.Prefix
+	PolicyID   string
+}
+
+// RequestType is the CC-Request-Type of a request (RFC 4006 §8.3).
+type RequestType int
+
+const (
+	RequestInitial RequestType = iota
+	RequestUpdate
+	RequestTerminate
+)
+
+// Request asks the charging system for credit and reports what was used.
+type Request struct {
+	Type RequestType
+	// Ref is the charging system's name for the session, from the answer
+	// to the initial request; empty in it.
+	Ref string
+	// Number counts the requests of the session, from 0.
+	Number  uint32
+	Session *Session
+	Units   []Units
+}
+
+// Units is the quota asked for one rating group and what was used of the
+// last grant.
+type Units struct {
+	RatingGroup uint32
+	// Request asks for a new grant.
+	Request  bool
+	Uplink   uint64
+	Downlink uint64
+	Time     time.Duration
+}
+
+// Result is the outcome of a request as a whole.
+type Result int
+
+const (
+	ResultSuccess Result = iota
+	// ResultDenied ends the session: the subscriber is unknown, barred or
+	// out of credit.
+	ResultDenied
+	// ResultNotApplicable leaves the session out of credit control.
+	ResultNotApplicable
+)
+
+// Answer is the charging system's answer to a Request.
+type Answer struct {
+	Result Result
+	// Ref names the session in later requests; set in the answer to the
+	// initial request.
+	Ref    string
+	Grants []Grant
+}
+
+// FinalAction is what happens when the final grant of a rating group is
+// used up (RFC 4006 §8.35).
+type FinalAction int
+
+const (
+	FinalTerminate FinalAction = iota
+	FinalRedirect
+	FinalRestrict
+)
+
+// Grant is the quota granted for one rating group. A zero Octets leaves the
+// volume unmetered, and a zero Time the duration.
+type Grant struct {
+	RatingGroup uint32
+	// Denied refuses the rating group any quota.
+	Denied bool
+	Octets uint64
+	Time   time.Duration
+	// Validity is how long the grant may be used before asking again.
+	Validity time.Duration
+	// Threshold is the remaining volume at which to ask for more; zero
+	// leaves it to the service.
+	Threshold uint64
+	// Final marks the last grant: once it is used up, FinalAction applies
+	// rather than another request.
+	Final       bool
+	FinalAction FinalAction
+}
+
+// OCS is a client of an online charging system.
+type OCS interface {
+	Exchange(ctx context.Context, req *Request) (*Answer, error)
+	Close() error
+}
+
+func newOCS(settings Settings, nodeID string) (OCS, error) {
+	switch settings.Protocol {
+	case ProtocolDiameter:
+		return newGy(settings, nodeID)
+	case ProtocolNchf:
+		return newNchf(settings, nodeID)
+	default:
+		return nil, fmt.Errorf("unknown online charging protocol %q", settings.Protocol)
+	}
+}
+
+// Enforcer acts on the sessions the service charges. The SMF implements
+// it.
+type Enforcer interface {
+	// SetUsageThreshold makes the user plane report a session's usage as
+	// soon as it has carried bytes octets since its last report; zero
+	// returns it to periodic reports.
+	SetUsageThreshold(ctx context.Context, ref string, bytes uint64) error
+	// TerminateSession releases a session that has run out of credit.
+	TerminateSession(ctx context.Context, ref string) error
+}
+
+// Store is the part of the database the service uses. *db.Database
+// satisfies it.
+type Store interface {
+	GetDataNetwork(ctx context.Context, name string) (*db.DataNetwork, error)
+	GetDataNetworkOnlineCharging(ctx context.Context, dataNetworkID string) (*db.DataNetworkOnlineCharging, error)
+	ListNetworkRuleRatingsByPolicy(ctx context.Context, policyID string) (map[string]db.NetworkRuleRating, error)
+	GetPolicyCaptivePortal(ctx context.Context, policyID string) (*db.PolicyCaptivePortal, error)
+	IsCaptivePortalLifted(ctx context.Context, imsi string) (bool, error)
+	LiftCaptivePortal(ctx context.Context, imsi string) error
+	RestoreCaptivePortal(ctx context.Context, imsi string) error
+}
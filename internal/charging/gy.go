@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package charging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ellanetworks/core/internal/diameter"
+)
+
+// serviceContextPS is the Service-Context-Id of PS charging (TS 32.251
+// §6.1.2).
+const serviceContextPS = "32251@3gpp.org"
+
+// gy is a Diameter Gy client (TS 32.299 §6.4).
+type gy struct {
+	client *diameter.Client
+	host   string
+	realm  string
+	// boot and seq make Session-Ids unique (RFC 6733 §8.8).
+	boot uint32
+	seq  atomic.Uint32
+}
+
+// newGy returns a Gy client for settings. Origin-Host is the node's name in
+// the charging system's realm.
+func newGy(settings Settings, nodeID string) (*gy, error) {
+	if settings.Realm == "" {
+		return nil, errors.New("diameter realm is required")
+	}
+
+	host := nodeID + "." + settings.Realm
+
+	c, err := diameter.NewClient(diameter.Config{
+		Server:      settings.Server,
+		OriginHost:  host,
+		OriginRealm: settings.Realm,
+		Application: diameter.AppCreditControl,
+		ProductName: "Ella Core",
+		Timeout:     requestTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &gy{client: c, host: host, realm: settings.Realm, boot: uint32(time.Now().Unix())}, nil
+}
+
+func (g *gy) Close() error {
+	return g.client.Close()
+}
+
+// Exchange sends req as a Credit-Control-Request. Result codes that refuse
+// credit are answers, not errors.
+func (g *gy) Exchange(ctx context.Context, req *Request) (*Answer, error) {
+	ccr := g.ccr(req)
+
+	cca, err := g.client.Exchange(ctx, ccr)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &Answer{Ref: ccr.String(diameter.AVPSessionID)}
+
+	rc, ok := cca.ResultCode()
+	if !ok {
+		return nil, errors.New("credit-control answer without a result code")
+	}
+
+	switch result, ok := gyResult(rc); {
+	case !ok:
+		return nil, &diameter.ResultError{Code: rc, Message: cca.String(diameter.AVPErrorMessage)}
+	case result != ResultSuccess:
+		ans.Result = result
+		return ans, nil
+	}
+
+	for _, a := range cca.AVPs {
+		if a.Code != diameter.AVPMultipleServicesCC || a.Vendor != 0 {
+			continue
+		}
+
+		grant, err := parseMSCC(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Multiple-Services-Credit-Control: %w", err)
+		}
+
+		ans.Grants = append(ans.Grants, grant)
+	}
+
+	return ans, nil
+}
+
+// ccr builds the Credit-Control-Request of req.
+func (g *gy) ccr(req *Request) *diameter.Message {
+	sessionID := req.Ref
+	if req.Type == RequestInitial {
+		sessionID = fmt.Sprintf("%s;%d;%d", g.host, g.boot, g.seq.Add(1))
+	}
+
+	requestType := map[RequestType]uint32{
+		RequestInitial:   diameter.RequestInitial,
+		RequestUpdate:    diameter.RequestUpdate,
+		RequestTerminate: diameter.RequestTermination,
+	}[req.Type]
+
+	m := &diameter.Message{
+		Flags:       diameter.FlagProxiable,
+		Command:     diameter.CommandCreditControl,
+		Application: diameter.AppCreditControl,
+	}
+
+	m.Add(diameter.StringAVP(diameter.AVPSessionID, 0, sessionID))
+	m.AVPs = append(m.AVPs, g.client.Origin()...)
+	m.Add(diameter.StringAVP(diameter.AVPDestinationRealm, 0, g.realm))
+	m.Add(diameter.Uint32AVP(diameter.AVPAuthApplicationID, 0, diameter.AppCreditControl))
+	m.Add(diameter.StringAVP(diameter.AVPServiceContextID, 0, serviceContextPS))
+	m.Add(diameter.Uint32AVP(diameter.AVPCCRequestType, 0, requestType))
+	m.Add(diameter.Uint32AVP(diameter.AVPCCRequestNumber, 0, req.Number))
+	m.Add(diameter.Group(diameter.AVPSubscriptionID, 0,
+		diameter.Uint32AVP(diameter.AVPSubscriptionIDType, 0, diameter.SubscriptionIDIMSI),
+		diameter.StringAVP(diameter.AVPSubscriptionIDData, 0, req.Session.IMSI),
+	))
+
+	if req.Type == RequestTerminate {
+		m.Add(diameter.Uint32AVP(diameter.AVPTerminationCause, 0, diameter.TerminationLogout))
+	}
+
+	m.Add(diameter.Uint32AVP(diameter.AVPMultipleServicesIndicator, 0, 1))
+
+	for _, u := range req.Units {
+		var members []diameter.AVP
+
+		if u.Request {
+			members = append(members, diameter.Group(diameter.AVPRequestedServiceUnit, 0))
+		}
+
+		if u.Uplink != 0 || u.Downlink != 0 || u.Time != 0 {
+			members = append(members, diameter.Group(diameter.AVPUsedServiceUnit, 0,
+				diameter.Uint32AVP(diameter.AVPCCTime, 0, uint32(u.Time/time.Second)),
+				diameter.Uint64AVP(diameter.AVPCCTotalOctets, 0, u.Uplink+u.Downlink),
+				diameter.Uint64AVP(diameter.AVPCCInputOctets, 0, u.Uplink),
+				diameter.Uint64AVP(diameter.AVPCCOutputOctets, 0, u.Downlink),
+			))
+		}
+
+		members = append(members, diameter.Uint32AVP(diameter.AVPRatingGroup, 0, u.RatingGroup))
+
+		m.Add(diameter.Group(diameter.AVPMultipleServicesCC, 0, members...))
+	}
+
+	ps := []diameter.AVP{
+		diameter.StringAVP(diameter.AVPCalledStationID, 0, req.Session.DNN),
+		{Code: diameter.AVP3GPPRATType, Vendor: diameter.VendorID3GPP, Data: []byte{byte(req.Session.RAT)}},
+	}
+
+	if req.Session.IPv4.IsValid() {
+		ps = append(ps, diameter.AddressAVP(diameter.AVPPDPAddress, diameter.VendorID3GPP, req.Session.IPv4))
+	}
+
+	m.Add(diameter.Group(diameter.AVPServiceInformation, diameter.VendorID3GPP,
+		diameter.Group(diameter.AVPPSInformation, diameter.VendorID3GPP, ps...),
+	))
+
+	return m
+}
+
+// gyResult maps a Result-Code to a Result; false for codes that are
+// neither a grant nor a refusal.
+func gyResult(rc uint32) (Result, bool) {
+	switch rc {
+	case diameter.ResultSuccess, diameter.ResultLimitedSuccess:
+		return ResultSuccess, true
+	case diameter.ResultEndUserServiceDenied, diameter.ResultCreditLimitReached,
+		diameter.ResultUserUnknown, diameter.ResultRatingFailed:
+		return ResultDenied, true
+	case diameter.ResultCreditControlNotApplicable:
+		return ResultNotApplicable, true
+	default:
+		return 0, false
+	}
+}
+
+// parseMSCC reads the grant of one Multiple-Services-Credit-Control.
+func parseMSCC(mscc diameter.AVP) (Grant, error) {
+	members, err := mscc.Members()
+	if err != nil {
+		return Grant{}, err
+	}
+
+	var (
+		g     Grant
+		hasRG bool
+	)
+
+	for _, a := range members {
+		switch {
+		case a.Code == diameter.AVPRatingGroup && a.Vendor == 0:
+			g.RatingGroup, hasRG = a.Uint32()
+		case a.Code == diameter.AVPResultCode && a.Vendor == 0:
+			rc, _ := a.Uint32()
+			if result, ok := gyResult(rc); !ok || result != ResultSuccess {
+				g.Denied = true
+			}
+		case a.Code == diameter.AVPGrantedServiceUnit && a.Vendor == 0:
+			if err := parseGSU(a, &g); err != nil {
+				return Grant{}, err
+			}
+		case a.Code == diameter.AVPValidityTime && a.Vendor == 0:
+			v, _ := a.Uint32()
+			g.Validity = time.Duration(v) * time.Second
+		case a.Code == diameter.AVPVolumeQuotaThreshold && a.Vendor == diameter.VendorID3GPP:
+			v, _ := a.Uint32()
+			g.Threshold = uint64(v)
+		case a.Code == diameter.AVPFinalUnitIndication && a.Vendor == 0:
+			fui, err := a.Members()
+			if err != nil {
+				return Grant{}, err
+			}
+
+			g.Final = true
+
+			for _, f := range fui {
+				if f.Code == diameter.AVPFinalUnitAction && f.Vendor == 0 {
+					v, _ := f.Uint32()
+					g.FinalAction = map[uint32]FinalAction{
+						diameter.FinalUnitRedirect:       FinalRedirect,
+						diameter.FinalUnitRestrictAccess: FinalRestrict,
+					}[v]
+				}
+			}
+		}
+	}
+
+	if !hasRG {
+		return Grant{}, errors.New("no Rating-Group")
+	}
+
+	return g, nil
+}
+
+// parseGSU reads a Granted-Service-Unit. A volume granted per direction
+// counts as its sum.
+func parseGSU(gsu diameter.AVP, g *Grant) error {
+	members, err := gsu.Members()
+	if err != nil {
+		return err
+	}
+
+	var total, input, output uint64
+
+	for _, a := range members {
+		if a.Vendor != 0 {
+			continue
+		}
+
+		switch a.Code {
+		case diameter.AVPCCTotalOctets:
+			total, _ = a.Uint64()
+		case diameter.AVPCCInputOctets:
+			input, _ = a.Uint64()
+		case diameter.AVPCCOutputOctets:
+			output, _ = a.Uint64()
+		case diameter.AVPCCTime:
+			v, _ := a.Uint32()
+			g.Time = time.Duration(v) * time.Second
+		}
+	}
+
+	g.Octets = total
+	if g.Octets == 0 {
+		g.Octets = input + output
+	}
+
+	return nil
+}
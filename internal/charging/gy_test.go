@@ -0,0 +1,201 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package charging
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/diameter"
+	"github.com/ellanetworks/core/internal/diameter/diametertest"
+)
+
+// gyOCS is a stand-in charging system: it grants 5000 octets per rating
+// group, final once a subscriber has used 8000.
+type gyOCS struct {
+	mu       sync.Mutex
+	used     uint64
+	requests []*diameter.Message
+}
+
+func (o *gyOCS) handle(req *diameter.Message) *diameter.Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.requests = append(o.requests, req)
+
+	ans := req.Answer()
+	ans.Add(diameter.StringAVP(diameter.AVPSessionID, 0, req.String(diameter.AVPSessionID)))
+
+	if imsi := subscriptionID(req); imsi != "001010000000001" {
+		ans.Add(diameter.Uint32AVP(diameter.AVPResultCode, 0, diameter.ResultUserUnknown))
+		return ans
+	}
+
+	ans.Add(diameter.Uint32AVP(diameter.AVPResultCode, 0, diameter.ResultSuccess))
+
+	for _, a := range req.AVPs {
+		if a.Code != diameter.AVPMultipleServicesCC {
+			continue
+		}
+
+		members, _ := a.Members()
+
+		var (
+			rg        uint32
+			requested bool
+		)
+
+		for _, m := range members {
+			switch m.Code {
+			case diameter.AVPRatingGroup:
+				rg, _ = m.Uint32()
+			case diameter.AVPRequestedServiceUnit:
+				requested = true
+			case diameter.AVPUsedServiceUnit:
+				usu, _ := m.Members()
+				for _, u := range usu {
+					if u.Code == diameter.AVPCCTotalOctets {
+						v, _ := u.Uint64()
+						o.used += v
+					}
+				}
+			}
+		}
+
+		if !requested {
+			continue
+		}
+
+		grant := []diameter.AVP{
+			diameter.Uint32AVP(diameter.AVPRatingGroup, 0, rg),
+			diameter.Group(diameter.AVPGrantedServiceUnit, 0, diameter.Uint64AVP(diameter.AVPCCTotalOctets, 0, 5000)),
+			diameter.Uint32AVP(diameter.AVPValidityTime, 0, 3600),
+			diameter.Uint32AVP(diameter.AVPVolumeQuotaThreshold, diameter.VendorID3GPP, 500),
+		}
+
+		if o.used >= 8000 {
+			grant = append(grant, diameter.Group(diameter.AVPFinalUnitIndication, 0,
+				diameter.Uint32AVP(diameter.AVPFinalUnitAction, 0, diameter.FinalUnitRedirect)))
+		}
+
+		ans.Add(diameter.Group(diameter.AVPMultipleServicesCC, 0, grant...))
+	}
+
+	return ans
+}
+
+func subscriptionID(m *diameter.Message) string {
+	sub, ok := m.Find(diameter.AVPSubscriptionID, 0)
+	if !ok {
+		return ""
+	}
+
+	members, _ := sub.Members()
+	for _, a := range members {
+		if a.Code == diameter.AVPSubscriptionIDData {
+			return string(a.Data)
+		}
+	}
+
+	return ""
+}
+
+func newGyOCS(t *testing.T) (*gyOCS, *gy) {
+	t.Helper()
+
+	ocs := &gyOCS{}
+
+	srv, err := diametertest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), "ocs.example.org", "example.org", ocs.handle)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	t.Cleanup(func() { _ = srv.Close() })
+
+	c, err := newGy(Settings{Protocol: ProtocolDiameter, Server: srv.Addr().String(), Realm: "example.org", RatingGroup: 1}, "ella-core-1")
+	if err != nil {
+		t.Fatalf("newGy: %v", err)
+	}
+
+	t.Cleanup(func() { _ = c.Close() })
+
+	return ocs, c
+}
+
+func TestGyCreditControl(t *testing.T) {
+	ocs, c := newGyOCS(t)
+	ctx := context.Background()
+	sess := &Session{IMSI: "001010000000001", DNN: "internet", IPv4: netip.MustParseAddr("10.45.0.2")}
+
+	ans, err := c.Exchange(ctx, &Request{Type: RequestInitial, Session: sess, Units: []Units{{RatingGroup: 1, Request: true}}})
+	if err != nil {
+		t.Fatalf("CCR-I: %v", err)
+	}
+
+	if ans.Result != ResultSuccess || ans.Ref == "" || len(ans.Grants) != 1 {
+		t.Fatalf("CCA-I = %+v", ans)
+	}
+
+	want := Grant{RatingGroup: 1, Octets: 5000, Validity: time.Hour, Threshold: 500}
+	if ans.Grants[0] != want {
+		t.Fatalf("grant = %+v, want %+v", ans.Grants[0], want)
+	}
+
+	ans, err = c.Exchange(ctx, &Request{Type: RequestUpdate, Ref: ans.Ref, Number: 1, Session: sess,
+		Units: []Units{{RatingGroup: 1, Request: true, Uplink: 3000, Downlink: 5000}}})
+	if err != nil {
+		t.Fatalf("CCR-U: %v", err)
+	}
+
+	if g := ans.Grants[0]; !g.Final || g.FinalAction != FinalRedirect {
+		t.Fatalf("grant after 8000 octets = %+v, want a final one", g)
+	}
+
+	if _, err := c.Exchange(ctx, &Request{Type: RequestTerminate, Ref: ans.Ref, Number: 2, Session: sess,
+		Units: []Units{{RatingGroup: 1, Downlink: 100}}}); err != nil {
+		t.Fatalf("CCR-T: %v", err)
+	}
+
+	ocs.mu.Lock()
+	defer ocs.mu.Unlock()
+
+	if len(ocs.requests) != 3 {
+		t.Fatalf("%d requests reached the OCS", len(ocs.requests))
+	}
+
+	for i, req := range ocs.requests {
+		typ, _ := req.Uint32(diameter.AVPCCRequestType)
+		num, _ := req.Uint32(diameter.AVPCCRequestNumber)
+
+		if typ != uint32(i+1) || num != uint32(i) || req.String(diameter.AVPSessionID) != ans.Ref ||
+			req.String(diameter.AVPDestinationRealm) != "example.org" || req.String(diameter.AVPServiceContextID) != serviceContextPS {
+			t.Fatalf("request %d: type %d, number %d, Session-Id %q", i, typ, num, req.String(diameter.AVPSessionID))
+		}
+	}
+
+	if ocs.used != 8100 {
+		t.Fatalf("OCS counted %d octets, want 8100", ocs.used)
+	}
+}
+
+func TestGyDenied(t *testing.T) {
+	_, c := newGyOCS(t)
+
+	ans, err := c.Exchange(context.Background(), &Request{
+		Type:    RequestInitial,
+		Session: &Session{IMSI: "001010000000002"},
+		Units:   []Units{{RatingGroup: 1, Request: true}},
+	})
+	if err != nil {
+		t.Fatalf("CCR-I: %v", err)
+	}
+
+	if ans.Result != ResultDenied {
+		t.Fatalf("result = %v, want denied", ans.Result)
+	}
+}
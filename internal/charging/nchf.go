@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package charging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ellanetworks/core/internal/accounting"
+)
+
+const nchfChargingData = "/nchf-convergedcharging/v3/chargingdata"
+
+// nchf is an Nchf_ConvergedCharging client (TS 32.291 §6.1).
+type nchf struct {
+	base   string
+	nodeID string
+	http   *http.Client
+}
+
+func newNchf(settings Settings, nodeID string) (*nchf, error) {
+	u, err := url.Parse(settings.Server)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid charging function URL %q", settings.Server)
+	}
+
+	return &nchf{
+		base:   strings.TrimSuffix(settings.Server, "/"),
+		nodeID: nodeID,
+		http:   &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+func (n *nchf) Close() error {
+	n.http.CloseIdleConnections()
+	return nil
+}
+
+// Charging data types of TS 32.291 §6.1.6.2, the parts this client uses.
+type (
+	chargingDataRequest struct {
+		SubscriberIdentifier     string                  `json:"subscriberIdentifier"`
+		NfConsumerID             nfIdentification        `json:"nfConsumerIdentification"`
+		InvocationTimeStamp      string                  `json:"invocationTimeStamp"`
+		InvocationSequenceNumber uint32                  `json:"invocationSequenceNumber"`
+		MultipleUnitUsage        []multipleUnitUsage     `json:"multipleUnitUsage,omitempty"`
+		PDUSessionInformation    *pduSessionChargingInfo `json:"pDUSessionChargingInformation,omitempty"`
+	}
+
+	nfIdentification struct {
+		NodeFunctionality string `json:"nodeFunctionality"`
+		NFName            string `json:"nFName,omitempty"`
+	}
+
+	multipleUnitUsage struct {
+		RatingGroup       uint32              `json:"ratingGroup"`
+		RequestedUnit     *struct{}           `json:"requestedUnit,omitempty"`
+		UsedUnitContainer []usedUnitContainer `json:"usedUnitContainer,omitempty"`
+	}
+
+	usedUnitContainer struct {
+		Time           uint32 `json:"time,omitempty"`
+		TotalVolume    uint64 `json:"totalVolume"`
+		UplinkVolume   uint64 `json:"uplinkVolume"`
+		DownlinkVolume uint64 `json:"downlinkVolume"`
+	}
+
+	pduSessionChargingInfo struct {
+		PDUSessionInformation pduSessionInformation `json:"pduSessionInformation"`
+	}
+
+	pduSessionInformation struct {
+		DNNID        string      `json:"dnnId"`
+		RATType      string      `json:"ratType,omitempty"`
+		PDUAddress   *pduAddress `json:"pduAddress,omitempty"`
+		NetworkSlice struct {
+			SNSSAI struct {
+				SST int32  `json:"sst"`
+				SD  string `json:"sd,omitempty"`
+			} `json:"sNSSAI"`
+		} `json:"networkSlicingInfo"`
+	}
+
+	pduAddress struct {
+		PDUIPv4Address string `json:"pduIPv4Address"`
+	}
+
+	chargingDataResponse struct {
+		MultipleUnitInformation []multipleUnitInformation `json:"multipleUnitInformation"`
+	}
+
+	multipleUnitInformation struct {
+		ResultCode           string               `json:"resultCode"`
+		RatingGroup          uint32               `json:"ratingGroup"`
+		GrantedUnit          *grantedUnit         `json:"grantedUnit"`
+		ValidityTime         uint32               `json:"validityTime"`
+		VolumeQuotaThreshold uint64               `json:"volumeQuotaThreshold"`
+		FinalUnitIndication  *finalUnitIndication `json:"finalUnitIndication"`
+	}
+
+	grantedUnit struct {
+		Time           uint32 `json:"time"`
+		TotalVolume    uint64 `json:"totalVolume"`
+		UplinkVolume   uint64 `json:"uplinkVolume"`
+		DownlinkVolume uint64 `json:"downlinkVolume"`
+	}
+
+	finalUnitIndication struct {
+		FinalUnitAction string `json:"finalUnitAction"`
+	}
+)
+
+// Exchange sends req as a charging data request: a create for the initial
+// request, then updates and a release on the resource it returned.
+func (n *nchf) Exchange(ctx context.Context, req *Request) (*Answer, error) {
+	target, want := n.base+nchfChargingData, http.StatusCreated
+
+	switch req.Type {
+	case RequestUpdate:
+		target, want = n.base+nchfChargingData+"/"+url.PathEscape(req.Ref)+"/update", http.StatusOK
+	case RequestTerminate:
+		target, want = n.base+nchfChargingData+"/"+url.PathEscape(req.Ref)+"/release", http.StatusNoContent
+	}
+
+	body, err := json.Marshal(n.chargingData(req))
+	if err != nil {
+		return nil, err
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	hreq.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.http.Do(hreq) // #nosec G107 -- the charging function URL is operator-configured
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case want:
+	case http.StatusForbidden:
+		return &Answer{Result: ResultDenied}, nil
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("charging function answered %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	ans := &Answer{Ref: req.Ref}
+
+	if req.Type == RequestInitial {
+		loc := resp.Header.Get("Location")
+		if loc == "" {
+			return nil, errors.New("charging function created no resource")
+		}
+
+		ans.Ref = path.Base(loc)
+	}
+
+	if req.Type == RequestTerminate {
+		return ans, nil
+	}
+
+	var data chargingDataResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&data); err != nil {
+		return nil, fmt.Errorf("invalid charging data response: %w", err)
+	}
+
+	for _, mui := range data.MultipleUnitInformation {
+		ans.Grants = append(ans.Grants, nchfGrant(mui))
+	}
+
+	return ans, nil
+}
+
+func (n *nchf) chargingData(req *Request) *chargingDataRequest {
+	sess := req.Session
+
+	cd := &chargingDataRequest{
+		SubscriberIdentifier:     "imsi-" + sess.IMSI,
+		NfConsumerID:             nfIdentification{NodeFunctionality: "SMF", NFName: n.nodeID},
+		InvocationTimeStamp:      time.Now().UTC().Format(time.RFC3339),
+		InvocationSequenceNumber: req.Number,
+	}
+
+	for _, u := range req.Units {
+		muu := multipleUnitUsage{RatingGroup: u.RatingGroup}
+
+		if u.Request {
+			muu.RequestedUnit = &struct{}{}
+		}
+
+		if u.Uplink != 0 || u.Downlink != 0 || u.Time != 0 {
+			muu.UsedUnitContainer = []usedUnitContainer{{
+				Time:           uint32(u.Time / time.Second),
+				TotalVolume:    u.Uplink + u.Downlink,
+				UplinkVolume:   u.Uplink,
+				DownlinkVolume: u.Downlink,
+			}}
+		}
+
+		cd.MultipleUnitUsage = append(cd.MultipleUnitUsage, muu)
+	}
+
+	if req.Type == RequestInitial {
+		info := pduSessionInformation{DNNID: sess.DNN, RATType: "NR"}
+		if sess.RAT == accounting.RATEUTRAN {
+			info.RATType = "EUTRA"
+		}
+
+		if sess.IPv4.IsValid() {
+			info.PDUAddress = &pduAddress{PDUIPv4Address: sess.IPv4.String()}
+		}
+
+		info.NetworkSlice.SNSSAI.SST = sess.Snssai.Sst
+		info.NetworkSlice.SNSSAI.SD = sess.Snssai.Sd
+
+		cd.PDUSessionInformation = &pduSessionChargingInfo{PDUSessionInformation: info}
+	}
+
+	return cd
+}
+
+func nchfGrant(mui multipleUnitInformation) Grant {
+	g := Grant{
+		RatingGroup: mui.RatingGroup,
+		Denied:      mui.ResultCode != "" && mui.ResultCode != "SUCCESS",
+		Validity:    time.Duration(mui.ValidityTime) * time.Second,
+		Threshold:   mui.VolumeQuotaThreshold,
+	}
+
+	if gu := mui.GrantedUnit; gu != nil {
+		g.Time = time.Duration(gu.Time) * time.Second
+
+		g.Octets = gu.TotalVolume
+		if g.Octets == 0 {
+			g.Octets = gu.UplinkVolume + gu.DownlinkVolume
+		}
+	}
+
+	if fui := mui.FinalUnitIndication; fui != nil {
+		g.Final = true
+
+		switch fui.FinalUnitAction {
+		case "REDIRECT":
+			g.FinalAction = FinalRedirect
+		case "RESTRICT_ACCESS":
+			g.FinalAction = FinalRestrict
+		}
+	}
+
+	return g
+}
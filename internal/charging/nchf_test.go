@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package charging
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCHF is a stand-in charging function: it grants 2000 octets per rating
+// group to one subscriber and refuses the others.
+func newCHF(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var calls []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+
+		var req chargingDataRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if req.SubscriberIdentifier != "imsi-001010000000001" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch {
+		case r.URL.Path == nchfChargingData:
+			w.Header().Set("Location", "http://"+r.Host+nchfChargingData+"/cd-42")
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasSuffix(r.URL.Path, "/release"):
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var rsp chargingDataResponse
+
+		for _, u := range req.MultipleUnitUsage {
+			if u.RequestedUnit == nil {
+				continue
+			}
+
+			rsp.MultipleUnitInformation = append(rsp.MultipleUnitInformation, multipleUnitInformation{
+				ResultCode:   "SUCCESS",
+				RatingGroup:  u.RatingGroup,
+				GrantedUnit:  &grantedUnit{TotalVolume: 2000},
+				ValidityTime: 600,
+			})
+		}
+
+		_ = json.NewEncoder(w).Encode(rsp)
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestNchfConvergedCharging(t *testing.T) {
+	srv, calls := newCHF(t)
+
+	c, err := newNchf(Settings{Protocol: ProtocolNchf, Server: srv.URL + "/"}, "ella-core-1")
+	if err != nil {
+		t.Fatalf("newNchf: %v", err)
+	}
+
+	ctx := context.Background()
+	sess := &Session{IMSI: "001010000000001", DNN: "internet"}
+
+	ans, err := c.Exchange(ctx, &Request{Type: RequestInitial, Session: sess, Units: []Units{{RatingGroup: 1, Request: true}}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	want := Grant{RatingGroup: 1, Octets: 2000, Validity: 10 * time.Minute}
+	if ans.Ref != "cd-42" || len(ans.Grants) != 1 || ans.Grants[0] != want {
+		t.Fatalf("answer = %+v", ans)
+	}
+
+	if _, err := c.Exchange(ctx, &Request{Type: RequestUpdate, Ref: ans.Ref, Number: 1, Session: sess,
+		Units: []Units{{RatingGroup: 1, Request: true, Uplink: 1600}}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	if _, err := c.Exchange(ctx, &Request{Type: RequestTerminate, Ref: ans.Ref, Number: 2, Session: sess,
+		Units: []Units{{RatingGroup: 1, Uplink: 10}}}); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+
+	wantCalls := []string{
+		"POST " + nchfChargingData,
+		"POST " + nchfChargingData + "/cd-42/update",
+		"POST " + nchfChargingData + "/cd-42/release",
+	}
+	if strings.Join(*calls, "\n") != strings.Join(wantCalls, "\n") {
+		t.Fatalf("calls = %q, want %q", *calls, wantCalls)
+	}
+}
+
+func TestNchfDenied(t *testing.T) {
+	srv, _ := newCHF(t)
+
+	c, err := newNchf(Settings{Protocol: ProtocolNchf, Server: srv.URL}, "ella-core-1")
+	if err != nil {
+		t.Fatalf("newNchf: %v", err)
+	}
+
+	ans, err := c.Exchange(context.Background(), &Request{Type: RequestInitial, Session: &Session{IMSI: "001010000000002"}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if ans.Result != ResultDenied {
+		t.Fatalf("result = %v, want denied", ans.Result)
+	}
+}
+
+func TestNchfRejectsInvalidURL(t *testing.T) {
+	if _, err := newNchf(Settings{Protocol: ProtocolNchf, Server: "chf.example.org:8080"}, "ella-core-1"); err == nil {
+		t.Fatal("expected a URL without a scheme to be rejected")
+	}
+}
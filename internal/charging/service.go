@@ -0,0 +1,846 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package charging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// tick is how often time quotas and validity are checked; it bounds
+	// how late a time-based request can be.
+	tick = time.Second
+	// reauthPercent is the share of a grant used before more is asked for,
+	// when the charging system sets no threshold.
+	reauthPercent = 80
+	// retryInterval is how often a redirected session asks for credit again
+	// when its last grant set no validity, and how often a termination the
+	// SMF could not carry out is retried.
+	retryInterval = time.Minute
+	// requestTimeout bounds one exchange with the charging system.
+	requestTimeout = 5 * time.Second
+)
+
+// Service asks online charging systems for the credit of this node's
+// sessions and enforces it.
+type Service struct {
+	store  Store
+	nodeID string
+	now    func() time.Time
+	newOCS func(settings Settings, nodeID string) (OCS, error)
+
+	mu       sync.Mutex
+	enforcer Enforcer
+	datapath func() models.DatapathFeatures
+	clients  map[Settings]*client
+	sessions map[string]*session
+	wake     chan struct{}
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	// exchanges tracks requests sent outside the loop, so Stop can wait
+	// for them.
+	exchanges sync.WaitGroup
+}
+
+// client is a charging system client shared by the sessions charged with
+// the same settings.
+type client struct {
+	settings Settings
+	ocs      OCS
+	refs     int
+}
+
+// session is a session under credit control. Its fields are guarded by
+// Service.mu; exch serializes its exchanges and is held without it.
+type session struct {
+	Session
+	settings Settings
+	client   *client
+	exch     sync.Mutex
+
+	// ref is the charging system's name for the session and number the
+	// next CC-Request-Number.
+	ref    string
+	number uint32
+
+	credits   map[uint32]*credit
+	threshold uint64 // the usage threshold last set on the user plane
+
+	updating bool // an update is in flight
+	// redirected sessions are held at the captive portal after their final
+	// grant; restored records that the portal was put back for this.
+	redirected bool
+	restored   bool
+	lift       bool // the portal is to be lifted again
+	retryAt    time.Time
+	// end asks the loop to terminate the session, no sooner than endAt.
+	end   bool
+	endAt time.Time
+}
+
+// credit is the current grant of one rating group and what was used of it.
+type credit struct {
+	Grant
+	granted time.Time
+	// used counts the octets since the grant; sent those of it already
+	// reported in the request in flight.
+	used     uint64
+	sent     uint64
+	uplink   uint64 // since the last report
+	downlink uint64
+}
+
+// NewService charges sessions on the data networks store configures for
+// online charging, naming the node nodeID toward the charging systems.
+// Start must be called before sessions are authorized.
+func NewService(store Store, nodeID string) *Service {
+	return &Service{
+		store:    store,
+		nodeID:   nodeID,
+		now:      time.Now,
+		newOCS:   newOCS,
+		clients:  make(map[Settings]*client),
+		sessions: make(map[string]*session),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Start launches the loop that asks for credit and enforces it through
+// enforcer, redirecting to captive portals only where datapath has them.
+// Calls without a paired Stop are no-ops.
+func (s *Service) Start(enforcer Enforcer, datapath func() models.DatapathFeatures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	s.enforcer = enforcer
+	s.datapath = datapath
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx, s.done)
+}
+
+// Stop ends the loop, reports the final usage of the sessions still
+// charged and closes the clients. Safe to call when not started.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	done := s.done
+	s.cancel = nil
+	s.done = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.mu.Lock()
+	for ref, sess := range s.sessions {
+		delete(s.sessions, ref)
+		s.exchanges.Go(func() { s.terminate(sess) })
+	}
+	s.mu.Unlock()
+
+	s.exchanges.Wait()
+}
+
+// Authorize asks for the first grant of a session. It returns the usage
+// threshold to set on the user plane, zero when the session is not charged
+// by volume, and ErrCreditDenied when the session may not start.
+func (s *Service) Authorize(ctx context.Context, sess Session) (uint64, error) {
+	settings, err := s.settings(ctx, sess.DNN)
+	if err != nil {
+		return 0, err
+	}
+
+	if settings == nil {
+		return 0, nil
+	}
+
+	cl, err := s.acquire(*settings)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't set up online charging client: %w", err)
+	}
+
+	ss := &session{
+		Session:  sess,
+		settings: *settings,
+		client:   cl,
+		credits:  make(map[uint32]*credit),
+	}
+
+	now := s.now()
+	for _, rg := range s.ratingGroups(ctx, *settings, sess.PolicyID) {
+		ss.credits[rg] = &credit{Grant: Grant{RatingGroup: rg}, granted: now}
+	}
+
+	// ss is not shared until it is added to the sessions, so its requests
+	// need no lock before then.
+	ans, err := s.exchange(ctx, ss, s.request(ss, RequestInitial))
+	if err != nil {
+		s.release(cl)
+
+		if settings.Continue {
+			logger.ChargingLog.Warn("online charging system unavailable; session continues unmetered",
+				zap.String("imsi", sess.IMSI), zap.String("dnn", sess.DNN), zap.Error(err))
+
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("online charging system unavailable: %w", err)
+	}
+
+	switch ans.Result {
+	case ResultDenied:
+		s.release(cl)
+		return 0, ErrCreditDenied
+	case ResultNotApplicable:
+		s.release(cl)
+		return 0, nil
+	}
+
+	ss.ref = ans.Ref
+
+	for _, g := range ans.Grants {
+		if g.Denied {
+			// The session cannot carry the traffic of every rating group
+			// it may use: end it on the charging system too.
+			s.terminate(ss)
+			return 0, ErrCreditDenied
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.applyLocked(ss, ans, now)
+	ss.threshold = ss.thresholdLocked()
+	s.sessions[sess.Ref] = ss
+
+	return ss.threshold, nil
+}
+
+// SessionUsage counts traffic against a session's grants. uplink and
+// downlink are increments in octets over all of the session's traffic, and
+// groups break out that of rated network rules.
+func (s *Service) SessionUsage(ref string, uplink, downlink uint64, groups []models.RatingGroupUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[ref]
+	if !ok || sess.redirected {
+		return
+	}
+
+	for _, g := range groups {
+		c, ok := sess.credits[g.RatingGroup]
+		if !ok || g.RatingGroup == sess.settings.RatingGroup {
+			continue
+		}
+
+		c.add(g.UplinkVolume, g.DownlinkVolume)
+
+		uplink -= min(uplink, g.UplinkVolume)
+		downlink -= min(downlink, g.DownlinkVolume)
+	}
+
+	if c, ok := sess.credits[sess.settings.RatingGroup]; ok {
+		c.add(uplink, downlink)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SessionStopped reports the final usage of a session and ends it on the
+// charging system. It returns at once.
+func (s *Service) SessionStopped(ref string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[ref]
+	if !ok {
+		return
+	}
+
+	delete(s.sessions, ref)
+
+	s.exchanges.Go(func() { s.terminate(sess) })
+}
+
+// settings returns the online charging settings of a data network, nil
+// when it is not charged online.
+func (s *Service) settings(ctx context.Context, dnn string) (*Settings, error) {
+	dn, err := s.store.GetDataNetwork(ctx, dnn)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("couldn't get data network: %w", err)
+	}
+
+	oc, err := s.store.GetDataNetworkOnlineCharging(ctx, dn.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("couldn't get online charging settings: %w", err)
+	}
+
+	return &Settings{
+		Protocol:    Protocol(oc.Protocol),
+		Server:      oc.Server,
+		Realm:       oc.Realm,
+		RatingGroup: uint32(oc.RatingGroup),
+		Continue:    oc.FailureHandling == db.OnlineChargingContinue,
+	}, nil
+}
+
+// ratingGroups lists the rating groups a session is charged under: the
+// data network's, and those of the policy's rated network rules that are
+// not zero-rated.
+func (s *Service) ratingGroups(ctx context.Context, settings Settings, policyID string) []uint32 {
+	groups := []uint32{settings.RatingGroup}
+
+	if policyID == "" {
+		return groups
+	}
+
+	ratings, err := s.store.ListNetworkRuleRatingsByPolicy(ctx, policyID)
+	if err != nil {
+		logger.ChargingLog.Warn("couldn't list the policy's rating groups; charging under the data network's",
+			zap.String("policy_id", policyID), zap.Error(err))
+
+		return groups
+	}
+
+	for _, r := range ratings {
+		rg := uint32(r.RatingGroup)
+		if r.ZeroRated || rg == 0 || slices.Contains(groups, rg) {
+			continue
+		}
+
+		groups = append(groups, rg)
+	}
+
+	slices.Sort(groups)
+
+	return groups
+}
+
+func (s *Service) acquire(settings Settings) (*client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cl, ok := s.clients[settings]; ok {
+		cl.refs++
+		return cl, nil
+	}
+
+	ocs, err := s.newOCS(settings, s.nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := &client{settings: settings, ocs: ocs, refs: 1}
+	s.clients[settings] = cl
+
+	return cl, nil
+}
+
+// release drops a session's hold on its client, closing it after the last.
+func (s *Service) release(cl *client) {
+	s.mu.Lock()
+	cl.refs--
+	last := cl.refs == 0
+
+	if last {
+		delete(s.clients, cl.settings)
+	}
+	s.mu.Unlock()
+
+	if !last {
+		return
+	}
+
+	if err := cl.ocs.Close(); err != nil {
+		logger.ChargingLog.Debug("couldn't close online charging client", zap.Error(err))
+	}
+}
+
+// exchange sends req on the session's client. sess.exch must be held.
+func (s *Service) exchange(ctx context.Context, sess *session, req *Request) (*Answer, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	return sess.client.ocs.Exchange(ctx, req)
+}
+
+// request builds the next request of a session and counts it. Its usage
+// is marked as sent. s.mu, or sole ownership of sess, must be held.
+func (s *Service) request(sess *session, typ RequestType) *Request {
+	req := &Request{Type: typ, Ref: sess.ref, Number: sess.number, Session: &sess.Session}
+	sess.number++
+
+	now := s.now()
+
+	for _, rg := range sess.groups() {
+		c := sess.credits[rg]
+		u := Units{RatingGroup: rg, Request: typ != RequestTerminate}
+
+		if !sess.redirected && typ != RequestInitial {
+			u.Uplink, u.Downlink = c.uplink, c.downlink
+			if c.Time > 0 {
+				u.Time = now.Sub(c.granted)
+			}
+		}
+
+		c.uplink, c.downlink = 0, 0
+		c.sent = c.used
+		req.Units = append(req.Units, u)
+	}
+
+	return req
+}
+
+// applyLocked replaces a session's grants with those of ans. Usage counted
+// while the request was in flight carries over to the new grant.
+func (s *Service) applyLocked(sess *session, ans *Answer, now time.Time) {
+	for _, g := range ans.Grants {
+		old, ok := sess.credits[g.RatingGroup]
+		if !ok {
+			continue
+		}
+
+		sess.credits[g.RatingGroup] = &credit{
+			Grant:    g,
+			granted:  now,
+			used:     old.used - old.sent,
+			uplink:   old.uplink,
+			downlink: old.downlink,
+		}
+	}
+}
+
+// terminate ends a session on the charging system with its final usage.
+func (s *Service) terminate(sess *session) {
+	sess.exch.Lock()
+	defer sess.exch.Unlock()
+
+	s.mu.Lock()
+	req := s.request(sess, RequestTerminate)
+	s.mu.Unlock()
+
+	if _, err := s.exchange(context.Background(), sess, req); err != nil {
+		logger.ChargingLog.Warn("couldn't report the final usage of a session",
+			zap.String("imsi", sess.IMSI), zap.String("ref", sess.Ref), zap.Error(err))
+	}
+
+	s.release(sess.client)
+}
+
+// update asks for new grants for a session.
+func (s *Service) update(sess *session) {
+	sess.exch.Lock()
+	defer sess.exch.Unlock()
+
+	s.mu.Lock()
+	if s.sessions[sess.Ref] != sess {
+		s.mu.Unlock()
+		return
+	}
+
+	req := s.request(sess, RequestUpdate)
+	s.mu.Unlock()
+
+	ans, err := s.exchange(context.Background(), sess, req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess.updating = false
+
+	if s.sessions[sess.Ref] != sess {
+		return
+	}
+
+	defer func() {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}()
+
+	now := s.now()
+
+	switch {
+	case err != nil && sess.settings.Continue, err == nil && ans.Result == ResultNotApplicable:
+		// The session leaves credit control; the loop clears its
+		// threshold and lifts the portal it was held at.
+		logger.ChargingLog.Warn("session leaves online charging and continues unmetered",
+			zap.String("imsi", sess.IMSI), zap.String("ref", sess.Ref), zap.Error(err))
+
+		sess.credits = nil
+		sess.lift = sess.restored
+
+		return
+	case sess.redirected && (err != nil || ans.Result == ResultDenied):
+		// Still no credit: the subscriber stays at the portal, where they
+		// may top up.
+		sess.retryAt = now.Add(retryInterval)
+		return
+	case err != nil:
+		logger.ChargingLog.Warn("online charging system unavailable; terminating session",
+			zap.String("imsi", sess.IMSI), zap.String("ref", sess.Ref), zap.Error(err))
+
+		sess.end = true
+
+		return
+	case ans.Result == ResultDenied:
+		sess.end = true
+		return
+	}
+
+	s.applyLocked(sess, ans, now)
+
+	if !sess.redirected {
+		return
+	}
+
+	for _, c := range sess.credits {
+		if c.Denied {
+			sess.retryAt = now.Add(orDefault(c.Validity, retryInterval))
+			return
+		}
+	}
+
+	sess.redirected = false
+	sess.lift = sess.restored
+	sess.restored = false
+}
+
+func (s *Service) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+
+		s.check(ctx)
+	}
+}
+
+// action is what the loop does for a session outside the lock.
+type action struct {
+	sess      *session
+	kind      actionKind
+	threshold uint64
+}
+
+type actionKind int
+
+const (
+	actionThreshold actionKind = iota
+	actionUpdate
+	actionTerminate
+	actionRedirect
+	actionLift
+	actionDrop
+)
+
+// check decides what each session needs and does it.
+func (s *Service) check(ctx context.Context) {
+	s.mu.Lock()
+
+	now := s.now()
+	enforcer := s.enforcer
+	datapath := s.datapath
+
+	var actions []action
+
+	for ref, sess := range s.sessions {
+		if sess.lift {
+			sess.lift = false
+			actions = append(actions, action{sess: sess, kind: actionLift})
+		}
+
+		if sess.credits == nil {
+			delete(s.sessions, ref)
+			actions = append(actions, action{sess: sess, kind: actionDrop})
+
+			continue
+		}
+
+		if kind, ok := sess.nextLocked(now); ok {
+			if kind == actionUpdate {
+				sess.updating = true
+			}
+
+			actions = append(actions, action{sess: sess, kind: kind})
+		}
+
+		if th := sess.thresholdLocked(); th != sess.threshold && !sess.end {
+			sess.threshold = th
+			actions = append(actions, action{sess: sess, kind: actionThreshold, threshold: th})
+		}
+	}
+
+	s.mu.Unlock()
+
+	for _, a := range actions {
+		s.do(ctx, enforcer, datapath, a, now)
+	}
+}
+
+// nextLocked is the step a session is due for at now, if any.
+func (sess *session) nextLocked(now time.Time) (actionKind, bool) {
+	switch {
+	case sess.updating:
+		return 0, false
+	case sess.end:
+		if now.Before(sess.endAt) {
+			return 0, false
+		}
+
+		sess.endAt = now.Add(retryInterval)
+
+		return actionTerminate, true
+	case sess.redirected:
+		return actionUpdate, !now.Before(sess.retryAt)
+	}
+
+	due := false
+
+	for _, c := range sess.credits {
+		if c.exhausted(now) {
+			if c.Denied || c.Final {
+				if c.Denied || c.FinalAction == FinalTerminate {
+					sess.end = true
+					sess.endAt = now.Add(retryInterval)
+
+					return actionTerminate, true
+				}
+
+				sess.redirected = true
+				sess.retryAt = now.Add(orDefault(c.Validity, retryInterval))
+
+				return actionRedirect, true
+			}
+
+			due = true
+		} else if c.due(now) {
+			due = true
+		}
+	}
+
+	return actionUpdate, due
+}
+
+// thresholdLocked is the usage threshold to set for a session: the octets
+// it may carry before its next request or the end of a final grant. Zero
+// leaves its usage to periodic reports.
+func (sess *session) thresholdLocked() uint64 {
+	if sess.redirected || sess.end {
+		return 0
+	}
+
+	var th uint64
+
+	for _, c := range sess.credits {
+		if c.Octets == 0 || c.Denied {
+			continue
+		}
+
+		var left uint64
+
+		switch point := c.reauthAt(); {
+		case !c.Final && !sess.updating && c.used < point:
+			left = point - c.used
+		case c.used < c.Octets:
+			left = c.Octets - c.used
+		default:
+			left = 1
+		}
+
+		if th == 0 || left < th {
+			th = left
+		}
+	}
+
+	return th
+}
+
+func (s *Service) do(ctx context.Context, enforcer Enforcer, datapath func() models.DatapathFeatures, a action, now time.Time) {
+	sess := a.sess
+
+	switch a.kind {
+	case actionUpdate:
+		s.exchanges.Go(func() { s.update(sess) })
+	case actionThreshold:
+		if err := enforcer.SetUsageThreshold(ctx, sess.Ref, a.threshold); err != nil {
+			logger.ChargingLog.Debug("couldn't set usage threshold",
+				zap.String("ref", sess.Ref), zap.Uint64("bytes", a.threshold), zap.Error(err))
+		}
+	case actionDrop:
+		if err := enforcer.SetUsageThreshold(ctx, sess.Ref, 0); err != nil {
+			logger.ChargingLog.Debug("couldn't clear usage threshold", zap.String("ref", sess.Ref), zap.Error(err))
+		}
+
+		s.release(sess.client)
+	case actionTerminate:
+		logger.ChargingLog.Info("terminating session out of credit",
+			zap.String("imsi", sess.IMSI), zap.String("ref", sess.Ref))
+
+		if err := enforcer.TerminateSession(ctx, sess.Ref); err != nil {
+			logger.ChargingLog.Warn("couldn't terminate session out of credit; retrying later",
+				zap.String("imsi", sess.IMSI), zap.String("ref", sess.Ref), zap.Error(err))
+		}
+	case actionRedirect:
+		s.redirect(ctx, enforcer, datapath, sess, now)
+	case actionLift:
+		if err := s.store.LiftCaptivePortal(ctx, sess.IMSI); err != nil {
+			logger.ChargingLog.Warn("couldn't lift captive portal after new credit",
+				zap.String("imsi", sess.IMSI), zap.Error(err))
+		}
+	}
+}
+
+// errCaptivePortalUnsupported is why a session is terminated rather than
+// redirected on a datapath without portal maps.
+var errCaptivePortalUnsupported = errors.New("captive portals are not supported by this node's datapath")
+
+// redirect holds a session at its policy's captive portal once its final
+// grant is used up. A policy without one has nowhere to redirect to, and a
+// datapath without portal maps would let the session's traffic through, so
+// in both cases the session is terminated instead.
+func (s *Service) redirect(ctx context.Context, enforcer Enforcer, datapath func() models.DatapathFeatures, sess *session, now time.Time) {
+	_, err := s.store.GetPolicyCaptivePortal(ctx, sess.PolicyID)
+	if err == nil && (datapath == nil || !datapath().CaptivePortal) {
+		err = errCaptivePortalUnsupported
+	}
+
+	if err == nil {
+		var lifted bool
+
+		lifted, err = s.store.IsCaptivePortalLifted(ctx, sess.IMSI)
+		if err == nil && lifted {
+			err = s.store.RestoreCaptivePortal(ctx, sess.IMSI)
+		}
+
+		if err == nil {
+			logger.ChargingLog.Info("redirecting session out of credit to captive portal",
+				zap.String("imsi", sess.IMSI), zap.String("ref", sess.Ref))
+
+			s.mu.Lock()
+			sess.restored = sess.restored || lifted
+			s.mu.Unlock()
+
+			return
+		}
+	}
+
+	if !errors.Is(err, db.ErrNotFound) {
+		logger.ChargingLog.Warn("couldn't redirect session to captive portal; terminating it",
+			zap.String("imsi", sess.IMSI), zap.Error(err))
+	}
+
+	s.mu.Lock()
+	sess.redirected = false
+	sess.end = true
+	sess.endAt = now.Add(retryInterval)
+	s.mu.Unlock()
+
+	if err := enforcer.TerminateSession(ctx, sess.Ref); err != nil {
+		logger.ChargingLog.Warn("couldn't terminate session out of credit; retrying later",
+			zap.String("imsi", sess.IMSI), zap.String("ref", sess.Ref), zap.Error(err))
+	}
+}
+
+// groups lists a session's rating groups in order.
+func (sess *session) groups() []uint32 {
+	groups := make([]uint32, 0, len(sess.credits))
+	for rg := range sess.credits {
+		groups = append(groups, rg)
+	}
+
+	slices.Sort(groups)
+
+	return groups
+}
+
+func (c *credit) add(uplink, downlink uint64) {
+	c.uplink += uplink
+	c.downlink += downlink
+	c.used += uplink + downlink
+}
+
+// reauthAt is the usage at which more volume is asked for.
+func (c *credit) reauthAt() uint64 {
+	if c.Threshold > 0 {
+		return c.Octets - min(c.Threshold, c.Octets)
+	}
+
+	return c.Octets / 100 * reauthPercent
+}
+
+// exhausted reports whether the grant is used up.
+func (c *credit) exhausted(now time.Time) bool {
+	return c.Denied ||
+		c.Octets > 0 && c.used >= c.Octets ||
+		c.Time > 0 && now.Sub(c.granted) >= c.Time
+}
+
+// due reports whether more should be asked for before the grant runs out.
+func (c *credit) due(now time.Time) bool {
+	elapsed := now.Sub(c.granted)
+
+	if c.Validity > 0 && elapsed >= c.Validity {
+		return true
+	}
+
+	if c.Final {
+		return false
+	}
+
+	return c.Octets > 0 && c.used >= c.reauthAt() ||
+		c.Time > 0 && elapsed >= c.Time/100*reauthPercent
+}
+
+// orDefault returns d, or fallback when d is zero.
+func orDefault(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+
+	return fallback
+}
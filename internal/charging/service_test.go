@@ -0,0 +1,499 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package charging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// fakeOCS answers each request with the next of its answers, or grants
+// 1000 octets once they run out.
+type fakeOCS struct {
+	mu       sync.Mutex
+	answers  []*Answer
+	err      error
+	requests []*Request
+	closed   bool
+}
+
+func (o *fakeOCS) Exchange(_ context.Context, req *Request) (*Answer, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.requests = append(o.requests, req)
+
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	if len(o.answers) > 0 {
+		ans := o.answers[0]
+		o.answers = o.answers[1:]
+
+		return ans, nil
+	}
+
+	ans := &Answer{Ref: "ocs-1"}
+	for _, u := range req.Units {
+		ans.Grants = append(ans.Grants, Grant{RatingGroup: u.RatingGroup, Octets: 1000})
+	}
+
+	return ans, nil
+}
+
+func (o *fakeOCS) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.closed = true
+
+	return nil
+}
+
+func (o *fakeOCS) sent() []*Request {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]*Request(nil), o.requests...)
+}
+
+type fakeStore struct {
+	mu          sync.Mutex
+	charging    *db.DataNetworkOnlineCharging
+	ratings     map[string]db.NetworkRuleRating
+	portal      bool
+	lifted      bool
+	restores    int
+	liftedAgain int
+}
+
+func (f *fakeStore) GetDataNetwork(_ context.Context, name string) (*db.DataNetwork, error) {
+	return &db.DataNetwork{ID: "dn-" + name, Name: name}, nil
+}
+
+func (f *fakeStore) GetDataNetworkOnlineCharging(context.Context, string) (*db.DataNetworkOnlineCharging, error) {
+	if f.charging == nil {
+		return nil, db.ErrNotFound
+	}
+
+	return f.charging, nil
+}
+
+func (f *fakeStore) ListNetworkRuleRatingsByPolicy(context.Context, string) (map[string]db.NetworkRuleRating, error) {
+	return f.ratings, nil
+}
+
+func (f *fakeStore) GetPolicyCaptivePortal(_ context.Context, policyID string) (*db.PolicyCaptivePortal, error) {
+	if !f.portal {
+		return nil, db.ErrNotFound
+	}
+
+	return &db.PolicyCaptivePortal{PolicyID: policyID, PortalAddress: "10.0.0.80"}, nil
+}
+
+func (f *fakeStore) IsCaptivePortalLifted(context.Context, string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.lifted, nil
+}
+
+func (f *fakeStore) LiftCaptivePortal(context.Context, string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lifted = true
+	f.liftedAgain++
+
+	return nil
+}
+
+func (f *fakeStore) RestoreCaptivePortal(context.Context, string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lifted = false
+	f.restores++
+
+	return nil
+}
+
+type fakeEnforcer struct {
+	mu         sync.Mutex
+	thresholds map[string]uint64
+	terminated []string
+}
+
+func (e *fakeEnforcer) SetUsageThreshold(_ context.Context, ref string, bytes uint64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.thresholds[ref] = bytes
+
+	return nil
+}
+
+func (e *fakeEnforcer) TerminateSession(_ context.Context, ref string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.terminated = append(e.terminated, ref)
+
+	return nil
+}
+
+// fakeClock is advanced by hand.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+type harness struct {
+	svc      *Service
+	ocs      *fakeOCS
+	store    *fakeStore
+	enforcer *fakeEnforcer
+	clock    *fakeClock
+	features models.DatapathFeatures
+}
+
+func newHarness(t *testing.T, failureHandling string) *harness {
+	t.Helper()
+
+	h := &harness{
+		ocs: &fakeOCS{},
+		store: &fakeStore{charging: &db.DataNetworkOnlineCharging{
+			Protocol:        db.OnlineChargingDiameter,
+			Server:          "127.0.0.1:3868",
+			Realm:           "example.org",
+			RatingGroup:     1,
+			FailureHandling: failureHandling,
+		}},
+		enforcer: &fakeEnforcer{thresholds: make(map[string]uint64)},
+		clock:    &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+		features: models.DatapathFeatures{CaptivePortal: true},
+	}
+
+	h.svc = NewService(h.store, "ella-core-1")
+	h.svc.now = h.clock.now
+	h.svc.newOCS = func(Settings, string) (OCS, error) { return h.ocs, nil }
+	// The loop is not started: tests drive it with step.
+	h.svc.enforcer = h.enforcer
+	h.svc.datapath = func() models.DatapathFeatures { return h.features }
+
+	return h
+}
+
+// step runs the loop once and waits for the requests it sent.
+func (h *harness) step() {
+	h.svc.check(context.Background())
+	h.svc.exchanges.Wait()
+}
+
+var testSession = Session{Ref: "imsi-001010000000001#1", IMSI: "001010000000001", DNN: "internet", PolicyID: "policy-1"}
+
+func TestAuthorizeWithoutOnlineCharging(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.store.charging = nil
+
+	th, err := h.svc.Authorize(context.Background(), testSession)
+	if err != nil || th != 0 {
+		t.Fatalf("Authorize = %d, %v; want 0, nil", th, err)
+	}
+
+	if len(h.ocs.sent()) != 0 {
+		t.Fatal("a session without online charging reached the charging system")
+	}
+}
+
+func TestAuthorizeRequestsEveryRatingGroup(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.store.ratings = map[string]db.NetworkRuleRating{
+		"video": {RatingGroup: 10},
+		"free":  {RatingGroup: 20, ZeroRated: true},
+		"same":  {RatingGroup: 1},
+	}
+
+	th, err := h.svc.Authorize(context.Background(), testSession)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	// 80% of the smaller grant.
+	if th != 800 {
+		t.Fatalf("threshold = %d, want 800", th)
+	}
+
+	req := h.ocs.sent()[0]
+	if req.Type != RequestInitial || req.Number != 0 || len(req.Units) != 2 ||
+		req.Units[0].RatingGroup != 1 || req.Units[1].RatingGroup != 10 || !req.Units[0].Request {
+		t.Fatalf("initial request = %+v", req)
+	}
+}
+
+func TestAuthorizeDenied(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.ocs.answers = []*Answer{{Result: ResultDenied}}
+
+	if _, err := h.svc.Authorize(context.Background(), testSession); !errors.Is(err, ErrCreditDenied) {
+		t.Fatalf("Authorize = %v, want ErrCreditDenied", err)
+	}
+
+	if !h.ocs.closed {
+		t.Fatal("client of a refused session left open")
+	}
+}
+
+func TestAuthorizeWhenUnreachable(t *testing.T) {
+	for _, tt := range []struct {
+		handling string
+		wantErr  bool
+	}{
+		{db.OnlineChargingTerminate, true},
+		{db.OnlineChargingContinue, false},
+	} {
+		t.Run(tt.handling, func(t *testing.T) {
+			h := newHarness(t, tt.handling)
+			h.ocs.err = errors.New("connection refused")
+
+			th, err := h.svc.Authorize(context.Background(), testSession)
+			if (err != nil) != tt.wantErr || th != 0 {
+				t.Fatalf("Authorize = %d, %v", th, err)
+			}
+		})
+	}
+}
+
+func TestUsageRequestsMoreCredit(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.store.ratings = map[string]db.NetworkRuleRating{"video": {RatingGroup: 10}}
+
+	if _, err := h.svc.Authorize(context.Background(), testSession); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	// 1100 octets, 300 of them rated 10; 50 more are zero-rated. The
+	// default group reaches its threshold and both are reported.
+	h.svc.SessionUsage(testSession.Ref, 400, 700, []models.RatingGroupUsage{
+		{RatingGroup: 10, UplinkVolume: 100, DownlinkVolume: 200},
+		{RatingGroup: 20, UplinkVolume: 50},
+	})
+	h.step()
+
+	reqs := h.ocs.sent()
+	if len(reqs) != 2 {
+		t.Fatalf("%d requests, want an initial and an update", len(reqs))
+	}
+
+	update := reqs[1]
+	if update.Type != RequestUpdate || update.Ref != "ocs-1" || update.Number != 1 {
+		t.Fatalf("update = %+v", update)
+	}
+
+	want := []Units{
+		{RatingGroup: 1, Request: true, Uplink: 300, Downlink: 500},
+		{RatingGroup: 10, Request: true, Uplink: 100, Downlink: 200},
+	}
+	for i, u := range update.Units {
+		if u != want[i] {
+			t.Fatalf("units[%d] = %+v, want %+v", i, u, want[i])
+		}
+	}
+
+	// The fresh grants start unused, so the threshold is back at 80%.
+	h.step()
+
+	if th := h.enforcer.thresholds[testSession.Ref]; th != 800 {
+		t.Fatalf("threshold = %d, want 800", th)
+	}
+}
+
+func TestFinalGrantTerminates(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.ocs.answers = []*Answer{{Ref: "ocs-1", Grants: []Grant{{RatingGroup: 1, Octets: 1000, Final: true}}}}
+
+	th, err := h.svc.Authorize(context.Background(), testSession)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	if th != 1000 {
+		t.Fatalf("threshold of a final grant = %d, want all of it", th)
+	}
+
+	h.svc.SessionUsage(testSession.Ref, 600, 400, nil)
+	h.step()
+
+	if len(h.enforcer.terminated) != 1 || len(h.ocs.sent()) != 1 {
+		t.Fatalf("terminated %v after %d requests", h.enforcer.terminated, len(h.ocs.sent()))
+	}
+
+	h.svc.SessionStopped(testSession.Ref)
+	h.svc.exchanges.Wait()
+
+	reqs := h.ocs.sent()
+	last := reqs[len(reqs)-1]
+
+	if last.Type != RequestTerminate || last.Units[0].Uplink != 600 || last.Units[0].Downlink != 400 || last.Units[0].Request {
+		t.Fatalf("termination = %+v", last)
+	}
+
+	if !h.ocs.closed {
+		t.Fatal("client left open after the last session")
+	}
+}
+
+func TestFinalGrantRedirectsToPortal(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.store.portal = true
+	h.store.lifted = true
+	h.ocs.answers = []*Answer{
+		{Ref: "ocs-1", Grants: []Grant{{RatingGroup: 1, Octets: 1000, Final: true, FinalAction: FinalRedirect, Validity: 30 * time.Second}}},
+		{Result: ResultDenied},
+	}
+
+	if _, err := h.svc.Authorize(context.Background(), testSession); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	h.svc.SessionUsage(testSession.Ref, 0, 1000, nil)
+	h.step()
+
+	if h.store.restores != 1 || len(h.enforcer.terminated) != 0 {
+		t.Fatalf("restores = %d, terminated %v", h.store.restores, h.enforcer.terminated)
+	}
+
+	// Still out of credit when the validity ends: the session stays.
+	h.clock.t = h.clock.t.Add(30 * time.Second)
+	h.step()
+
+	if len(h.ocs.sent()) != 2 || len(h.enforcer.terminated) != 0 {
+		t.Fatalf("%d requests, terminated %v", len(h.ocs.sent()), h.enforcer.terminated)
+	}
+
+	// Topped up: the next request grants credit and lifts the portal.
+	h.clock.t = h.clock.t.Add(retryInterval)
+	h.step()
+	h.step()
+
+	if h.store.liftedAgain != 1 || !h.store.lifted {
+		t.Fatalf("portal not lifted after new credit: %+v", h.store)
+	}
+}
+
+func TestFinalGrantWithoutPortalTerminates(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.ocs.answers = []*Answer{{Ref: "ocs-1", Grants: []Grant{{RatingGroup: 1, Octets: 1000, Final: true, FinalAction: FinalRedirect}}}}
+
+	if _, err := h.svc.Authorize(context.Background(), testSession); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	h.svc.SessionUsage(testSession.Ref, 0, 1000, nil)
+	h.step()
+
+	if len(h.enforcer.terminated) != 1 {
+		t.Fatalf("terminated %v, want the session", h.enforcer.terminated)
+	}
+}
+
+func TestFinalGrantWithoutPortalDatapathTerminates(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.store.portal = true
+	h.features = models.DatapathFeatures{}
+	h.ocs.answers = []*Answer{{Ref: "ocs-1", Grants: []Grant{{RatingGroup: 1, Octets: 1000, Final: true, FinalAction: FinalRedirect}}}}
+
+	if _, err := h.svc.Authorize(context.Background(), testSession); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	h.svc.SessionUsage(testSession.Ref, 0, 1000, nil)
+	h.step()
+
+	if len(h.enforcer.terminated) != 1 || h.store.restores != 0 {
+		t.Fatalf("terminated %v, restores %d; want the session terminated", h.enforcer.terminated, h.store.restores)
+	}
+}
+
+func TestUpdateFailure(t *testing.T) {
+	for _, tt := range []struct {
+		handling      string
+		wantTerminate bool
+	}{
+		{db.OnlineChargingTerminate, true},
+		{db.OnlineChargingContinue, false},
+	} {
+		t.Run(tt.handling, func(t *testing.T) {
+			h := newHarness(t, tt.handling)
+
+			if _, err := h.svc.Authorize(context.Background(), testSession); err != nil {
+				t.Fatalf("Authorize: %v", err)
+			}
+
+			h.ocs.err = errors.New("connection reset")
+
+			h.svc.SessionUsage(testSession.Ref, 900, 0, nil)
+			h.step()
+			h.step()
+
+			if got := len(h.enforcer.terminated) == 1; got != tt.wantTerminate {
+				t.Fatalf("terminated %v", h.enforcer.terminated)
+			}
+
+			h.svc.mu.Lock()
+			_, charged := h.svc.sessions[testSession.Ref]
+			h.svc.mu.Unlock()
+
+			if charged == !tt.wantTerminate {
+				t.Fatalf("session still charged = %v", charged)
+			}
+		})
+	}
+}
+
+func TestTimeGrant(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+	h.ocs.answers = []*Answer{{Ref: "ocs-1", Grants: []Grant{{RatingGroup: 1, Time: 100 * time.Second}}}}
+
+	th, err := h.svc.Authorize(context.Background(), testSession)
+	if err != nil || th != 0 {
+		t.Fatalf("Authorize = %d, %v; a time grant sets no threshold", th, err)
+	}
+
+	h.clock.t = h.clock.t.Add(79 * time.Second)
+	h.step()
+
+	if len(h.ocs.sent()) != 1 {
+		t.Fatal("asked for more time too early")
+	}
+
+	h.clock.t = h.clock.t.Add(time.Second)
+	h.step()
+
+	reqs := h.ocs.sent()
+	if len(reqs) != 2 || reqs[1].Units[0].Time != 80*time.Second {
+		t.Fatalf("update = %+v", reqs[len(reqs)-1])
+	}
+}
+
+func TestStopTerminatesSessions(t *testing.T) {
+	h := newHarness(t, db.OnlineChargingTerminate)
+
+	if _, err := h.svc.Authorize(context.Background(), testSession); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	h.svc.Stop()
+
+	reqs := h.ocs.sent()
+	if reqs[len(reqs)-1].Type != RequestTerminate || !h.ocs.closed {
+		t.Fatalf("requests %+v, closed %v", reqs, h.ocs.closed)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"os"
 	"strings"
 	"time"
@@ -40,6 +41,11 @@ const (
 	DefaultNGAPPort = 38412
 	// DefaultS1APPort is the standard 4G S1-MME / S1AP SCTP port (3GPP TS 36.412).
 	DefaultS1APPort = 36412
+	// DefaultEchoInterval is the GTP-U echo period; TS 29.281 §7.2.1 forbids
+	// probing a path more often than every 60 seconds.
+	DefaultEchoInterval = 60 * time.Second
+	// DefaultPFCPPort is the registered N4 / PFCP UDP port (TS 29.244 §4.2.2).
+	DefaultPFCPPort = 8805
 )
 
 // ErrNoInterfaceIP is returned when an interface exists but currently has no
@@ -100,6 +106,34 @@ type N2InterfaceYaml struct {
 }
 
 type N3InterfaceYaml struct {
+	Name           string             `yaml:"name"`
+	Address        string             `yaml:"address"`
+	PathManagement PathManagementYaml `yaml:"path-management"`
+}
+
+type PathManagementYaml struct {
+	EchoInterval    string `yaml:"echo-interval"`
+	ReleaseSessions bool   `yaml:"release-sessions"`
+}
+
+// N4InterfaceYaml is the PFCP endpoint toward remote user planes, or
+// toward the control planes this node serves as a user plane.
+type N4InterfaceYaml struct {
+	Address string `yaml:"address"`
+	Port    int    `yaml:"port"`
+}
+
+// UserPlaneYaml is a remote UPF controlled over N4. Sessions on its data
+// networks are anchored there instead of on the embedded datapath.
+type UserPlaneYaml struct {
+	Name         string   `yaml:"name"`
+	Address      string   `yaml:"address"`
+	DataNetworks []string `yaml:"data-networks"`
+}
+
+// ControlPlaneYaml is a remote SMF allowed to drive the embedded UPF over
+// N4, with this node acting as its user plane.
+type ControlPlaneYaml struct {
 	Name    string `yaml:"name"`
 	Address string `yaml:"address"`
 }
@@ -118,6 +152,7 @@ type APIInterfaceYaml struct {
 type InterfacesYaml struct {
 	N2  N2InterfaceYaml  `yaml:"n2"`
 	N3  N3InterfaceYaml  `yaml:"n3"`
+	N4  N4InterfaceYaml  `yaml:"n4"`
 	N6  N6InterfaceYaml  `yaml:"n6"`
 	API APIInterfaceYaml `yaml:"api"`
 }
@@ -169,13 +204,15 @@ type ClusterYaml struct {
 }
 
 type ConfigYAML struct {
-	Logging    LoggingYaml    `yaml:"logging"`
-	DB         DBYaml         `yaml:"db"`
-	Interfaces InterfacesYaml `yaml:"interfaces"`
-	XDP        XDPYaml        `yaml:"xdp"`
-	Datapath   DatapathYaml   `yaml:"datapath"`
-	Telemetry  TelemetryYaml  `yaml:"telemetry"`
-	Cluster    ClusterYaml    `yaml:"cluster"`
+	Logging       LoggingYaml        `yaml:"logging"`
+	DB            DBYaml             `yaml:"db"`
+	Interfaces    InterfacesYaml     `yaml:"interfaces"`
+	XDP           XDPYaml            `yaml:"xdp"`
+	Datapath      DatapathYaml       `yaml:"datapath"`
+	Telemetry     TelemetryYaml      `yaml:"telemetry"`
+	Cluster       ClusterYaml        `yaml:"cluster"`
+	UserPlanes    []UserPlaneYaml    `yaml:"user-planes"`
+	ControlPlanes []ControlPlaneYaml `yaml:"control-planes"`
 }
 
 type N2Interface struct {
@@ -186,9 +223,42 @@ type N2Interface struct {
 }
 
 type N3Interface struct {
-	Name       string
-	Address    string
-	VlanConfig *VlanConfig
+	Name           string
+	Address        string
+	VlanConfig     *VlanConfig
+	PathManagement PathManagement
+}
+
+// PathManagement controls GTP-U path supervision of the radios' N3
+// endpoints (TS 29.281 §7.2.1).
+type PathManagement struct {
+	// EchoInterval is the period between Echo Requests to each peer; zero
+	// disables echo.
+	EchoInterval time.Duration
+	// ReleaseSessions releases the sessions tunnelled to a peer once its
+	// path is declared failed.
+	ReleaseSessions bool
+}
+
+// N4Interface is the address the PFCP endpoint binds to, which is also its
+// PFCP Node ID. Invalid when no remote user plane or control plane is
+// configured.
+type N4Interface struct {
+	Address netip.AddrPort
+}
+
+// UserPlane is a remote UPF and the data networks it serves.
+type UserPlane struct {
+	Name         string
+	Address      netip.AddrPort
+	DataNetworks []string
+}
+
+// ControlPlane is a remote SMF, recognised by the source IP of its PFCP
+// requests.
+type ControlPlane struct {
+	Name    string
+	Address netip.Addr
 }
 
 type N6Interface struct {
@@ -206,6 +276,7 @@ type APIInterface struct {
 type Interfaces struct {
 	N2  N2Interface
 	N3  N3Interface
+	N4  N4Interface
 	N6  N6Interface
 	API APIInterface
 }
@@ -255,12 +326,14 @@ type Cluster struct {
 }
 
 type Config struct {
-	Logging    Logging
-	DB         DB
-	Interfaces Interfaces
-	Datapath   Datapath
-	Telemetry  Telemetry
-	Cluster    Cluster
+	Logging       Logging
+	DB            DB
+	Interfaces    Interfaces
+	Datapath      Datapath
+	Telemetry     Telemetry
+	Cluster       Cluster
+	UserPlanes    []UserPlane
+	ControlPlanes []ControlPlane
 }
 
 type VlanConfig struct {
@@ -432,6 +505,11 @@ func Validate(filePath string) (Config, error) {
 	config.DB.Path = c.DB.Path
 	config.Interfaces.N3.Name = n3InterfaceName
 	config.Interfaces.N3.Address = n3Address
+
+	config.Interfaces.N3.PathManagement, err = validatePathManagement(c.Interfaces.N3.PathManagement)
+	if err != nil {
+		return Config{}, err
+	}
 	config.Interfaces.N6.Name = c.Interfaces.N6.Name
 
 	if c.Interfaces.N2.Name != "" {
@@ -487,6 +565,22 @@ func Validate(filePath string) (Config, error) {
 
 	config.Cluster = cluster
 
+	if len(c.UserPlanes) > 0 && len(c.ControlPlanes) > 0 {
+		return Config{}, errors.New("user-planes and control-planes cannot both be configured: the node is either the control plane or the user plane of N4")
+	}
+
+	config.Interfaces.N4, config.UserPlanes, err = validateUserPlanes(c.Interfaces.N4, c.UserPlanes)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if len(c.ControlPlanes) > 0 {
+		config.Interfaces.N4, config.ControlPlanes, err = validateControlPlanes(c.Interfaces.N4, c.ControlPlanes)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
 	return config, nil
 }
 
@@ -645,6 +739,171 @@ func resolveAttachMode(x XDPYaml, d DatapathYaml) (string, error) {
 	return "", errors.New("xdp.attach-mode is invalid. Allowed values are: native, generic")
 }
 
+// validatePathManagement resolves interfaces.n3.path-management. An unset
+// echo-interval is DefaultEchoInterval; "0s" turns echo off.
+func validatePathManagement(p PathManagementYaml) (PathManagement, error) {
+	interval := DefaultEchoInterval
+
+	if p.EchoInterval != "" {
+		d, err := time.ParseDuration(p.EchoInterval)
+		if err != nil {
+			return PathManagement{}, fmt.Errorf("interfaces.n3.path-management.echo-interval %q: %w", p.EchoInterval, err)
+		}
+
+		if d != 0 && d < DefaultEchoInterval {
+			return PathManagement{}, fmt.Errorf("interfaces.n3.path-management.echo-interval must be 0s or at least %s", DefaultEchoInterval)
+		}
+
+		interval = d
+	}
+
+	if interval == 0 && p.ReleaseSessions {
+		return PathManagement{}, errors.New("interfaces.n3.path-management.release-sessions requires echo to be enabled")
+	}
+
+	return PathManagement{EchoInterval: interval, ReleaseSessions: p.ReleaseSessions}, nil
+}
+
+// validateUserPlanes resolves interfaces.n4 and user-planes. The N4 address
+// is only required once a user plane is configured, and each data network
+// is served by at most one of them.
+func validateUserPlanes(n4 N4InterfaceYaml, ups []UserPlaneYaml) (N4Interface, []UserPlane, error) {
+	if len(ups) == 0 {
+		return N4Interface{}, nil, nil
+	}
+
+	n4If, err := validateN4(n4, "user-planes")
+	if err != nil {
+		return N4Interface{}, nil, err
+	}
+
+	names := make(map[string]bool, len(ups))
+	addresses := make(map[netip.Addr]bool, len(ups))
+	servedBy := make(map[string]string)
+	out := make([]UserPlane, 0, len(ups))
+
+	for i, up := range ups {
+		if up.Name == "" {
+			return N4Interface{}, nil, fmt.Errorf("user-planes[%d].name is empty", i)
+		}
+
+		if names[up.Name] {
+			return N4Interface{}, nil, fmt.Errorf("user-planes: duplicate name %q", up.Name)
+		}
+
+		names[up.Name] = true
+
+		upAddr, err := parseUserPlaneAddress(up.Address)
+		if err != nil {
+			return N4Interface{}, nil, fmt.Errorf("user-planes[%s].address: %w", up.Name, err)
+		}
+
+		// PFCP peers are told apart by source IP.
+		if addresses[upAddr.Addr()] {
+			return N4Interface{}, nil, fmt.Errorf("user-planes[%s].address: %s is already used by another user plane", up.Name, upAddr.Addr())
+		}
+
+		addresses[upAddr.Addr()] = true
+
+		if len(up.DataNetworks) == 0 {
+			return N4Interface{}, nil, fmt.Errorf("user-planes[%s].data-networks is empty", up.Name)
+		}
+
+		for _, dn := range up.DataNetworks {
+			if other, ok := servedBy[dn]; ok {
+				return N4Interface{}, nil, fmt.Errorf("user-planes: data network %q is assigned to both %s and %s", dn, other, up.Name)
+			}
+
+			servedBy[dn] = up.Name
+		}
+
+		out = append(out, UserPlane{Name: up.Name, Address: upAddr, DataNetworks: up.DataNetworks})
+	}
+
+	return n4If, out, nil
+}
+
+// validateN4 resolves interfaces.n4, required once peers configured under
+// key are.
+func validateN4(n4 N4InterfaceYaml, key string) (N4Interface, error) {
+	if n4.Address == "" {
+		return N4Interface{}, fmt.Errorf("interfaces.n4.address is required when %s are configured", key)
+	}
+
+	addr, err := netip.ParseAddr(n4.Address)
+	if err != nil || addr.IsUnspecified() {
+		return N4Interface{}, fmt.Errorf("interfaces.n4.address %q must be a specific IP address", n4.Address)
+	}
+
+	port := n4.Port
+	if port == 0 {
+		port = DefaultPFCPPort
+	}
+
+	if port < 1 || port > 65535 {
+		return N4Interface{}, errors.New("interfaces.n4.port must be between 1 and 65535")
+	}
+
+	return N4Interface{Address: netip.AddrPortFrom(addr, uint16(port))}, nil // #nosec: G115 -- range checked above
+}
+
+// validateControlPlanes resolves interfaces.n4 and control-planes. A
+// control plane is told apart by the source IP of its requests, so each
+// needs its own.
+func validateControlPlanes(n4 N4InterfaceYaml, cps []ControlPlaneYaml) (N4Interface, []ControlPlane, error) {
+	n4If, err := validateN4(n4, "control-planes")
+	if err != nil {
+		return N4Interface{}, nil, err
+	}
+
+	names := make(map[string]bool, len(cps))
+	addresses := make(map[netip.Addr]bool, len(cps))
+	out := make([]ControlPlane, 0, len(cps))
+
+	for i, cp := range cps {
+		if cp.Name == "" {
+			return N4Interface{}, nil, fmt.Errorf("control-planes[%d].name is empty", i)
+		}
+
+		if names[cp.Name] {
+			return N4Interface{}, nil, fmt.Errorf("control-planes: duplicate name %q", cp.Name)
+		}
+
+		names[cp.Name] = true
+
+		addr, err := netip.ParseAddr(cp.Address)
+		if err != nil || addr.IsUnspecified() {
+			return N4Interface{}, nil, fmt.Errorf("control-planes[%s].address: %q must be a specific IP address", cp.Name, cp.Address)
+		}
+
+		addr = addr.Unmap()
+
+		if addresses[addr] {
+			return N4Interface{}, nil, fmt.Errorf("control-planes[%s].address: %s is already used by another control plane", cp.Name, addr)
+		}
+
+		addresses[addr] = true
+
+		out = append(out, ControlPlane{Name: cp.Name, Address: addr})
+	}
+
+	return n4If, out, nil
+}
+
+// parseUserPlaneAddress accepts an IP, taking the PFCP port, or an IP:port.
+func parseUserPlaneAddress(s string) (netip.AddrPort, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.AddrPortFrom(addr, DefaultPFCPPort), nil
+	}
+
+	ap, err := netip.ParseAddrPort(s)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("%q is neither an IP address nor an IP:port", s)
+	}
+
+	return ap, nil
+}
+
 var GetVLANConfigForInterfaceFunc = func(name string) (*VlanConfig, error) {
 	link, err := netlink.LinkByName(name)
 	if err != nil {
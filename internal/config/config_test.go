@@ -5,10 +5,13 @@ package config_test
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ellanetworks/core/internal/config"
 )
@@ -443,3 +446,273 @@ interfaces:
 		})
 	}
 }
+
+func TestPathManagementResolution(t *testing.T) {
+	config.CheckInterfaceExistsFunc = func(name string) (bool, error) { return true, nil }
+	config.GetInterfaceNameFunc = func(name string) (string, error) { return InterfaceName, nil }
+	config.GetVLANConfigForInterfaceFunc = func(name string) (*config.VlanConfig, error) { return nil, nil }
+
+	const tmpl = `logging:
+  system:
+    level: "info"
+    output: "stdout"
+  audit:
+    output: "stdout"
+db:
+  path: "test"
+interfaces:
+  n2:
+    address: "0.0.0.0"
+  n3:
+    address: "33.33.33.3"
+%s
+  n6:
+    name: "enp6s0"
+  api:
+    address: "0.0.0.0"
+    port: 5002
+`
+
+	cases := []struct {
+		name         string
+		block        string
+		wantInterval time.Duration
+		wantRelease  bool
+		wantErrParts string
+	}{
+		{"defaults when omitted", "", config.DefaultEchoInterval, false, ""},
+		{"explicit interval and release", "    path-management:\n      echo-interval: \"2m\"\n      release-sessions: true", 2 * time.Minute, true, ""},
+		{"echo disabled", "    path-management:\n      echo-interval: \"0s\"", 0, false, ""},
+		{"interval below the spec minimum", "    path-management:\n      echo-interval: \"10s\"", 0, false, "at least 1m0s"},
+		{"unparsable interval", "    path-management:\n      echo-interval: \"often\"", 0, false, "echo-interval \"often\""},
+		{"release without echo", "    path-management:\n      echo-interval: \"0s\"\n      release-sessions: true", 0, false, "requires echo"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "core.yaml")
+			if err := os.WriteFile(path, []byte(fmt.Sprintf(tmpl, tc.block)), 0o600); err != nil {
+				t.Fatalf("write config: %s", err)
+			}
+
+			cfg, err := config.Validate(path)
+			if tc.wantErrParts != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrParts) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErrParts, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			pm := cfg.Interfaces.N3.PathManagement
+			if pm.EchoInterval != tc.wantInterval {
+				t.Errorf("echo interval = %s, want %s", pm.EchoInterval, tc.wantInterval)
+			}
+
+			if pm.ReleaseSessions != tc.wantRelease {
+				t.Errorf("release sessions = %v, want %v", pm.ReleaseSessions, tc.wantRelease)
+			}
+		})
+	}
+}
+
+func TestUserPlaneResolution(t *testing.T) {
+	config.CheckInterfaceExistsFunc = func(name string) (bool, error) { return true, nil }
+	config.GetInterfaceNameFunc = func(name string) (string, error) { return InterfaceName, nil }
+	config.GetVLANConfigForInterfaceFunc = func(name string) (*config.VlanConfig, error) { return nil, nil }
+
+	const tmpl = `logging:
+  system:
+    level: "info"
+    output: "stdout"
+  audit:
+    output: "stdout"
+db:
+  path: "test"
+interfaces:
+  n2:
+    address: "0.0.0.0"
+  n3:
+    address: "33.33.33.3"
+%s
+  n6:
+    name: "enp6s0"
+  api:
+    address: "0.0.0.0"
+    port: 5002
+%s`
+
+	const n4 = "  n4:\n    address: \"10.0.4.1\""
+
+	cases := []struct {
+		name         string
+		n4           string
+		userPlanes   string
+		wantN4       string
+		wantPlanes   []config.UserPlane
+		wantErrParts string
+	}{
+		{"none configured", "", "", "invalid AddrPort", nil, ""},
+		{
+			"default ports", n4,
+			"user-planes:\n  - name: \"edge\"\n    address: \"10.0.4.2\"\n    data-networks: [\"edge-internet\", \"iot\"]\n",
+			"10.0.4.1:8805",
+			[]config.UserPlane{{Name: "edge", Address: netip.MustParseAddrPort("10.0.4.2:8805"), DataNetworks: []string{"edge-internet", "iot"}}},
+			"",
+		},
+		{
+			"explicit ports", "  n4:\n    address: \"10.0.4.1\"\n    port: 18805",
+			"user-planes:\n  - name: \"edge\"\n    address: \"10.0.4.2:9805\"\n    data-networks: [\"iot\"]\n",
+			"10.0.4.1:18805",
+			[]config.UserPlane{{Name: "edge", Address: netip.MustParseAddrPort("10.0.4.2:9805"), DataNetworks: []string{"iot"}}},
+			"",
+		},
+		{"user planes without n4", "", "user-planes:\n  - name: \"edge\"\n    address: \"10.0.4.2\"\n    data-networks: [\"iot\"]\n", "", nil, "interfaces.n4.address is required"},
+		{"unspecified n4 address", "  n4:\n    address: \"0.0.0.0\"", "user-planes:\n  - name: \"edge\"\n    address: \"10.0.4.2\"\n    data-networks: [\"iot\"]\n", "", nil, "specific IP"},
+		{"bad user plane address", n4, "user-planes:\n  - name: \"edge\"\n    address: \"upf.example\"\n    data-networks: [\"iot\"]\n", "", nil, "user-planes[edge].address"},
+		{"no data networks", n4, "user-planes:\n  - name: \"edge\"\n    address: \"10.0.4.2\"\n", "", nil, "data-networks is empty"},
+		{
+			"duplicate name", n4,
+			"user-planes:\n  - name: \"edge\"\n    address: \"10.0.4.2\"\n    data-networks: [\"a\"]\n  - name: \"edge\"\n    address: \"10.0.4.3\"\n    data-networks: [\"b\"]\n",
+			"", nil, "duplicate name",
+		},
+		{
+			"shared address", n4,
+			"user-planes:\n  - name: \"a\"\n    address: \"10.0.4.2\"\n    data-networks: [\"a\"]\n  - name: \"b\"\n    address: \"10.0.4.2:9805\"\n    data-networks: [\"b\"]\n",
+			"", nil, "already used",
+		},
+		{
+			"data network on two user planes", n4,
+			"user-planes:\n  - name: \"a\"\n    address: \"10.0.4.2\"\n    data-networks: [\"iot\"]\n  - name: \"b\"\n    address: \"10.0.4.3\"\n    data-networks: [\"iot\"]\n",
+			"", nil, "assigned to both a and b",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "core.yaml")
+			if err := os.WriteFile(path, []byte(fmt.Sprintf(tmpl, tc.n4, tc.userPlanes)), 0o600); err != nil {
+				t.Fatalf("write config: %s", err)
+			}
+
+			cfg, err := config.Validate(path)
+			if tc.wantErrParts != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrParts) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErrParts, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := cfg.Interfaces.N4.Address.String(); got != tc.wantN4 {
+				t.Errorf("n4 address = %s, want %s", got, tc.wantN4)
+			}
+
+			if !reflect.DeepEqual(cfg.UserPlanes, tc.wantPlanes) {
+				t.Errorf("user planes = %+v, want %+v", cfg.UserPlanes, tc.wantPlanes)
+			}
+		})
+	}
+}
+
+func TestControlPlaneResolution(t *testing.T) {
+	config.CheckInterfaceExistsFunc = func(name string) (bool, error) { return true, nil }
+	config.GetInterfaceNameFunc = func(name string) (string, error) { return InterfaceName, nil }
+	config.GetVLANConfigForInterfaceFunc = func(name string) (*config.VlanConfig, error) { return nil, nil }
+
+	const tmpl = `logging:
+  system:
+    level: "info"
+    output: "stdout"
+  audit:
+    output: "stdout"
+db:
+  path: "test"
+interfaces:
+  n2:
+    address: "0.0.0.0"
+  n3:
+    address: "33.33.33.3"
+%s
+  n6:
+    name: "enp6s0"
+  api:
+    address: "0.0.0.0"
+    port: 5002
+%s`
+
+	const n4 = "  n4:\n    address: \"10.0.4.2\""
+
+	cases := []struct {
+		name         string
+		n4           string
+		planes       string
+		wantN4       string
+		wantPlanes   []config.ControlPlane
+		wantErrParts string
+	}{
+		{
+			"default port", n4,
+			"control-planes:\n  - name: \"core\"\n    address: \"10.0.4.1\"\n",
+			"10.0.4.2:8805",
+			[]config.ControlPlane{{Name: "core", Address: netip.MustParseAddr("10.0.4.1")}},
+			"",
+		},
+		{"control planes without n4", "", "control-planes:\n  - name: \"core\"\n    address: \"10.0.4.1\"\n", "", nil, "interfaces.n4.address is required when control-planes"},
+		{"bad address", n4, "control-planes:\n  - name: \"core\"\n    address: \"smf.example\"\n", "", nil, "control-planes[core].address"},
+		{"no name", n4, "control-planes:\n  - address: \"10.0.4.1\"\n", "", nil, "control-planes[0].name is empty"},
+		{
+			"duplicate name", n4,
+			"control-planes:\n  - name: \"core\"\n    address: \"10.0.4.1\"\n  - name: \"core\"\n    address: \"10.0.4.3\"\n",
+			"", nil, "duplicate name",
+		},
+		{
+			"shared address", n4,
+			"control-planes:\n  - name: \"a\"\n    address: \"10.0.4.1\"\n  - name: \"b\"\n    address: \"10.0.4.1\"\n",
+			"", nil, "already used",
+		},
+		{
+			"with user planes", n4,
+			"control-planes:\n  - name: \"core\"\n    address: \"10.0.4.1\"\nuser-planes:\n  - name: \"edge\"\n    address: \"10.0.4.3\"\n    data-networks: [\"iot\"]\n",
+			"", nil, "cannot both be configured",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "core.yaml")
+			if err := os.WriteFile(path, []byte(fmt.Sprintf(tmpl, tc.n4, tc.planes)), 0o600); err != nil {
+				t.Fatalf("write config: %s", err)
+			}
+
+			cfg, err := config.Validate(path)
+			if tc.wantErrParts != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrParts) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErrParts, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := cfg.Interfaces.N4.Address.String(); got != tc.wantN4 {
+				t.Errorf("n4 address = %s, want %s", got, tc.wantN4)
+			}
+
+			if !reflect.DeepEqual(cfg.ControlPlanes, tc.wantPlanes) {
+				t.Errorf("control planes = %+v, want %+v", cfg.ControlPlanes, tc.wantPlanes)
+			}
+		})
+	}
+}
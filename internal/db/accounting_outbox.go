@@ -0,0 +1,277 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AccountingOutboxTableName is local to each node: the sessions a node
+// serves produce its records, and only that node sends them.
+const AccountingOutboxTableName = "accounting_outbox"
+
+const (
+	insertAccountingRecordStmt          = "INSERT INTO %s (server_id, created_at, attributes) VALUES ($AccountingRecord.server_id, $AccountingRecord.created_at, $AccountingRecord.attributes)"
+	listAccountingRecordsStmt           = "SELECT &AccountingRecord.* FROM %s WHERE server_id==$AccountingRecord.server_id ORDER BY id LIMIT $ListArgs.limit"
+	deleteAccountingRecordStmt          = "DELETE FROM %s WHERE id==$AccountingRecord.id"
+	deleteAccountingRecordsByServerStmt = "DELETE FROM %s WHERE server_id==$AccountingRecord.server_id"
+	listAccountingRecordServersStmt     = "SELECT DISTINCT server_id AS &AccountingRecord.server_id FROM %s"
+	trimAccountingRecordsStmt           = "DELETE FROM %s WHERE server_id==$AccountingRecord.server_id AND id NOT IN (SELECT id FROM %s WHERE server_id==$AccountingRecord.server_id ORDER BY id DESC LIMIT $ListArgs.limit)"
+)
+
+// AccountingRecord is an accounting request waiting to be acknowledged by
+// the server ServerID names. Attributes holds the encoded RADIUS attributes;
+// the header and authenticator are computed on each attempt, since the
+// Acct-Delay-Time that goes with them changes.
+type AccountingRecord struct {
+	ID         int64  `db:"id"`
+	ServerID   string `db:"server_id"`  // accounting_servers.id, not a foreign key: the table is local
+	CreatedAt  int64  `db:"created_at"` // Unix seconds
+	Attributes []byte `db:"attributes"`
+}
+
+// EnqueueAccountingRecords appends records to the outbox.
+func (db *Database) EnqueueAccountingRecords(ctx context.Context, records []AccountingRecord) error {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", AccountingOutboxTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", AccountingOutboxTableName),
+			attribute.Int("batch_size", len(records)),
+		),
+	)
+	defer span.End()
+
+	if err := db.checkOpSchema(accountingSchema); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "schema pending")
+
+		return err
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingOutboxTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingOutboxTableName, "insert").Inc()
+
+	for i := range records {
+		if err := db.conn().Query(ctx, db.insertAccountingRecordStmt, records[i]).Run(); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "query failed")
+
+			return fmt.Errorf("query failed: %w", err)
+		}
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+// ListAccountingRecords returns up to limit of the oldest records for a
+// server, oldest first.
+func (db *Database) ListAccountingRecords(ctx context.Context, serverID string, limit int) ([]AccountingRecord, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", AccountingOutboxTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", AccountingOutboxTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(accountingSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingOutboxTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingOutboxTableName, "select").Inc()
+
+	var rows []AccountingRecord
+
+	err := db.conn().Query(ctx, db.listAccountingRecordsStmt, AccountingRecord{ServerID: serverID}, ListArgs{Limit: limit}).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return nil, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
+
+// DeleteAccountingRecord removes a record its server acknowledged.
+func (db *Database) DeleteAccountingRecord(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", AccountingOutboxTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", AccountingOutboxTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingOutboxTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingOutboxTableName, "delete").Inc()
+
+	if err := db.conn().Query(ctx, db.deleteAccountingRecordStmt, AccountingRecord{ID: id}).Run(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+// TrimAccountingRecords keeps only the newest keep records for a server,
+// bounding the outbox while the server is unreachable.
+func (db *Database) TrimAccountingRecords(ctx context.Context, serverID string, keep int) error {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", AccountingOutboxTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", AccountingOutboxTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(accountingSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingOutboxTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingOutboxTableName, "delete").Inc()
+
+	if err := db.conn().Query(ctx, db.trimAccountingRecordsStmt, AccountingRecord{ServerID: serverID}, ListArgs{Limit: keep}).Run(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+// ListAccountingRecordServers returns the IDs of the servers the outbox
+// holds records for.
+func (db *Database) ListAccountingRecordServers(ctx context.Context) ([]string, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", AccountingOutboxTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", AccountingOutboxTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(accountingSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingOutboxTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingOutboxTableName, "select").Inc()
+
+	var rows []AccountingRecord
+
+	err := db.conn().Query(ctx, db.listAccountingRecordServersStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return nil, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ServerID)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return ids, nil
+}
+
+// DeleteAccountingRecordsForServer drops every record held for a server
+// that was removed.
+func (db *Database) DeleteAccountingRecordsForServer(ctx context.Context, serverID string) error {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", AccountingOutboxTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", AccountingOutboxTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingOutboxTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingOutboxTableName, "delete").Inc()
+
+	if err := db.conn().Query(ctx, db.deleteAccountingRecordsByServerStmt, AccountingRecord{ServerID: serverID}).Run(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
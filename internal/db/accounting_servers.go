@@ -0,0 +1,289 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/canonical/sqlair"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const AccountingServersTableName = "accounting_servers"
+
+// accountingSchema is the migration that introduced accounting_servers and
+// accounting_outbox. Below it no server is configured, so no accounting
+// record is produced.
+const accountingSchema = 30
+
+const (
+	createAccountingServerStmt = "INSERT INTO %s (id, name, address, secret, interim_interval) VALUES ($AccountingServer.id, $AccountingServer.name, $AccountingServer.address, $AccountingServer.secret, $AccountingServer.interim_interval)"
+	updateAccountingServerStmt = "UPDATE %s SET address=$AccountingServer.address, secret=$AccountingServer.secret, interim_interval=$AccountingServer.interim_interval WHERE name==$AccountingServer.name"
+	deleteAccountingServerStmt = "DELETE FROM %s WHERE name==$AccountingServer.name"
+	getAccountingServerStmt    = "SELECT &AccountingServer.* FROM %s WHERE name==$AccountingServer.name"
+	listAccountingServersStmt  = "SELECT &AccountingServer.* FROM %s ORDER BY name"
+)
+
+// AccountingServer is a RADIUS accounting server (RFC 2866). Every server
+// receives the Start and Stop record of each session; InterimInterval, in
+// seconds, is how often it also receives an Interim-Update, 0 for never.
+type AccountingServer struct {
+	ID              string `db:"id"` // UUIDv7
+	Name            string `db:"name"`
+	Address         string `db:"address"` // host:port
+	Secret          string `db:"secret"`
+	InterimInterval int    `db:"interim_interval"`
+}
+
+func (db *Database) CreateAccountingServer(ctx context.Context, server *AccountingServer) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", AccountingServersTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", AccountingServersTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingServersTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingServersTableName, "insert").Inc()
+
+	if server.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("generate accounting server id: %w", err)
+		}
+
+		server.ID = id.String()
+	}
+
+	_, err := opCreateAccountingServer.Invoke(db, server)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreateAccountingServer(ctx context.Context, server *AccountingServer) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.createAccountingServerStmt, server).Run(); err != nil {
+		if isUniqueNameError(err) {
+			return nil, ErrAlreadyExists
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// UpdateAccountingServer replaces the address, secret and interim interval
+// of the server named server.Name.
+func (db *Database) UpdateAccountingServer(ctx context.Context, server *AccountingServer) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", AccountingServersTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", AccountingServersTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingServersTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingServersTableName, "update").Inc()
+
+	_, err := opUpdateAccountingServer.Invoke(db, server)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyUpdateAccountingServer(ctx context.Context, server *AccountingServer) (any, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, db.updateAccountingServerStmt, server).Get(&outcome); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// DeleteAccountingServer removes a server. Each node drops the records it
+// still held for it on its next reconcile.
+func (db *Database) DeleteAccountingServer(ctx context.Context, name string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", AccountingServersTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", AccountingServersTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingServersTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingServersTableName, "delete").Inc()
+
+	_, err := opDeleteAccountingServer.Invoke(db, &stringPayload{Value: name})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteAccountingServer(ctx context.Context, p *stringPayload) (any, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, db.deleteAccountingServerStmt, AccountingServer{Name: p.Value}).Get(&outcome); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// GetAccountingServer returns ErrNotFound for an unknown name.
+func (db *Database) GetAccountingServer(ctx context.Context, name string) (*AccountingServer, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", AccountingServersTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", AccountingServersTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(accountingSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingServersTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingServersTableName, "select").Inc()
+
+	row := AccountingServer{Name: name}
+
+	err := db.conn().Query(ctx, db.getAccountingServerStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// ListAccountingServers returns every server ordered by name.
+func (db *Database) ListAccountingServers(ctx context.Context) ([]AccountingServer, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", AccountingServersTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", AccountingServersTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(accountingSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []AccountingServer{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AccountingServersTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AccountingServersTableName, "select").Inc()
+
+	var rows []AccountingServer
+
+	err := db.conn().Query(ctx, db.listAccountingServersStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []AccountingServer{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func newAccountingTestDB(t *testing.T) *db.Database {
+	t.Helper()
+
+	database, err := db.NewDatabaseWithoutRaft(context.Background(), filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	})
+
+	return database
+}
+
+func TestAccountingServersEndToEnd(t *testing.T) {
+	database := newAccountingTestDB(t)
+	ctx := context.Background()
+
+	server := &db.AccountingServer{Name: "billing", Address: "10.100.0.2:1813", Secret: "testing123", InterimInterval: 300}
+
+	if err := database.CreateAccountingServer(ctx, server); err != nil {
+		t.Fatalf("couldn't create accounting server: %s", err)
+	}
+
+	if server.ID == "" {
+		t.Fatal("expected an ID to be assigned")
+	}
+
+	if err := database.CreateAccountingServer(ctx, &db.AccountingServer{Name: "billing", Address: "10.100.0.3:1813", Secret: "x"}); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a duplicate name, got %v", err)
+	}
+
+	server.Address = "10.100.0.4:1813"
+	server.InterimInterval = 0
+
+	if err := database.UpdateAccountingServer(ctx, server); err != nil {
+		t.Fatalf("couldn't update accounting server: %s", err)
+	}
+
+	got, err := database.GetAccountingServer(ctx, "billing")
+	if err != nil {
+		t.Fatalf("couldn't get accounting server: %s", err)
+	}
+
+	if *got != *server {
+		t.Fatalf("accounting server = %+v, want %+v", got, server)
+	}
+
+	if err := database.UpdateAccountingServer(ctx, &db.AccountingServer{Name: "missing"}); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound updating an unknown server, got %v", err)
+	}
+
+	if err := database.CreateAccountingServer(ctx, &db.AccountingServer{Name: "firewall", Address: "10.100.0.5:1813", Secret: "y"}); err != nil {
+		t.Fatalf("couldn't create second accounting server: %s", err)
+	}
+
+	servers, err := database.ListAccountingServers(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list accounting servers: %s", err)
+	}
+
+	if len(servers) != 2 || servers[0].Name != "billing" || servers[1].Name != "firewall" {
+		t.Fatalf("accounting servers = %+v, want billing and firewall", servers)
+	}
+
+	if err := database.DeleteAccountingServer(ctx, "billing"); err != nil {
+		t.Fatalf("couldn't delete accounting server: %s", err)
+	}
+
+	if _, err := database.GetAccountingServer(ctx, "billing"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	if err := database.DeleteAccountingServer(ctx, "billing"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestAccountingOutbox(t *testing.T) {
+	database := newAccountingTestDB(t)
+	ctx := context.Background()
+
+	var records []db.AccountingRecord
+	for i := range 5 {
+		records = append(records, db.AccountingRecord{ServerID: "a", CreatedAt: int64(100 + i), Attributes: []byte{byte(i)}})
+	}
+
+	records = append(records, db.AccountingRecord{ServerID: "b", CreatedAt: 200, Attributes: []byte{0xff}})
+
+	if err := database.EnqueueAccountingRecords(ctx, records); err != nil {
+		t.Fatalf("couldn't enqueue records: %s", err)
+	}
+
+	got, err := database.ListAccountingRecords(ctx, "a", 2)
+	if err != nil {
+		t.Fatalf("couldn't list records: %s", err)
+	}
+
+	if len(got) != 2 || got[0].Attributes[0] != 0 || got[1].Attributes[0] != 1 {
+		t.Fatalf("records = %+v, want the two oldest", got)
+	}
+
+	if err := database.DeleteAccountingRecord(ctx, got[0].ID); err != nil {
+		t.Fatalf("couldn't delete record: %s", err)
+	}
+
+	if err := database.TrimAccountingRecords(ctx, "a", 2); err != nil {
+		t.Fatalf("couldn't trim records: %s", err)
+	}
+
+	got, err = database.ListAccountingRecords(ctx, "a", 10)
+	if err != nil {
+		t.Fatalf("couldn't list records: %s", err)
+	}
+
+	if len(got) != 2 || got[0].Attributes[0] != 3 || got[1].Attributes[0] != 4 {
+		t.Fatalf("records after trim = %+v, want the two newest", got)
+	}
+
+	servers, err := database.ListAccountingRecordServers(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list record servers: %s", err)
+	}
+
+	if len(servers) != 2 {
+		t.Fatalf("record servers = %v, want a and b", servers)
+	}
+
+	if err := database.DeleteAccountingRecordsForServer(ctx, "b"); err != nil {
+		t.Fatalf("couldn't purge records: %s", err)
+	}
+
+	if got, _ := database.ListAccountingRecords(ctx, "b", 10); len(got) != 0 {
+		t.Fatalf("records for a purged server = %+v, want none", got)
+	}
+}
@@ -355,6 +355,12 @@ func (db *Database) applyClearDailyUsage(ctx context.Context) error {
 		return fmt.Errorf("query failed: %w", err)
 	}
 
+	if db.cachedAppliedSchema() >= networkRuleRatingsSchema {
+		if err := db.runner(ctx).Query(ctx, db.deleteAllRatingGroupUsageStmt).Run(); err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -364,6 +370,12 @@ func (db *Database) applyDeleteOldDailyUsage(ctx context.Context, p *int64Payloa
 		return fmt.Errorf("query failed: %w", err)
 	}
 
+	if db.cachedAppliedSchema() >= networkRuleRatingsSchema {
+		if err := db.runner(ctx).Query(ctx, db.deleteOldRatingGroupUsageStmt, cutoffDaysArgs{CutoffDays: p.Value}).Run(); err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const CaptivePortalLiftsTableName = "captive_portal_lifts"
+
+const (
+	insertCaptivePortalLiftStmt   = "INSERT INTO %s (imsi) VALUES ($CaptivePortalLift.imsi) ON CONFLICT(imsi) DO NOTHING"
+	deleteCaptivePortalLiftStmt   = "DELETE FROM %s WHERE imsi==$CaptivePortalLift.imsi"
+	getCaptivePortalLiftStmt      = "SELECT &CaptivePortalLift.* FROM %s WHERE imsi==$CaptivePortalLift.imsi"
+	listAllCaptivePortalLiftsStmt = "SELECT &CaptivePortalLift.* FROM %s ORDER BY imsi"
+)
+
+// CaptivePortalLift lets a subscriber past the captive portal of its
+// policy, to the policy's own network rules.
+type CaptivePortalLift struct {
+	IMSI string `db:"imsi"` // FK to subscribers.imsi
+}
+
+// LiftCaptivePortal lets a subscriber past the captive portal. Lifting it
+// twice is not an error.
+func (db *Database) LiftCaptivePortal(ctx context.Context, imsi string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", CaptivePortalLiftsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", CaptivePortalLiftsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(CaptivePortalLiftsTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(CaptivePortalLiftsTableName, "insert").Inc()
+
+	_, err := opLiftCaptivePortal.Invoke(db, &CaptivePortalLift{IMSI: imsi})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyLiftCaptivePortal(ctx context.Context, lift *CaptivePortalLift) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.insertCaptivePortalLiftStmt, lift).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// RestoreCaptivePortal puts a subscriber back behind the captive portal.
+// Restoring one that was never lifted is not an error.
+func (db *Database) RestoreCaptivePortal(ctx context.Context, imsi string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", CaptivePortalLiftsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", CaptivePortalLiftsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(CaptivePortalLiftsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(CaptivePortalLiftsTableName, "delete").Inc()
+
+	_, err := opRestoreCaptivePortal.Invoke(db, &CaptivePortalLift{IMSI: imsi})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyRestoreCaptivePortal(ctx context.Context, lift *CaptivePortalLift) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteCaptivePortalLiftStmt, lift).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// IsCaptivePortalLifted reports whether the subscriber was let past the
+// captive portal.
+func (db *Database) IsCaptivePortalLifted(ctx context.Context, imsi string) (bool, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", CaptivePortalLiftsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", CaptivePortalLiftsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(captivePortalSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return false, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(CaptivePortalLiftsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(CaptivePortalLiftsTableName, "select").Inc()
+
+	row := CaptivePortalLift{IMSI: imsi}
+
+	err := db.conn().Query(ctx, db.getCaptivePortalLiftStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return false, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return false, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return true, nil
+}
+
+func (db *Database) ListAllCaptivePortalLifts(ctx context.Context) ([]CaptivePortalLift, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", CaptivePortalLiftsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", CaptivePortalLiftsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(captivePortalSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []CaptivePortalLift{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(CaptivePortalLiftsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(CaptivePortalLiftsTableName, "select").Inc()
+
+	var rows []CaptivePortalLift
+
+	err := db.conn().Query(ctx, db.listAllCaptivePortalLiftsStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []CaptivePortalLift{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
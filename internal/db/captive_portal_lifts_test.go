@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestCaptivePortalLiftsEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	imsi := "001010000000101"
+	createSubscriber(t, database, imsi)
+
+	if lifted, err := database.IsCaptivePortalLifted(ctx, imsi); err != nil || lifted {
+		t.Fatalf("IsCaptivePortalLifted = %v, %v before any lift, want false", lifted, err)
+	}
+
+	for range 2 {
+		if err := database.LiftCaptivePortal(ctx, imsi); err != nil {
+			t.Fatalf("couldn't lift the captive portal: %s", err)
+		}
+	}
+
+	if lifted, err := database.IsCaptivePortalLifted(ctx, imsi); err != nil || !lifted {
+		t.Fatalf("IsCaptivePortalLifted = %v, %v after a lift, want true", lifted, err)
+	}
+
+	if err := database.RestoreCaptivePortal(ctx, imsi); err != nil {
+		t.Fatalf("couldn't restore the captive portal: %s", err)
+	}
+
+	if err := database.RestoreCaptivePortal(ctx, imsi); err != nil {
+		t.Fatalf("restoring twice should not fail: %s", err)
+	}
+
+	if lifted, err := database.IsCaptivePortalLifted(ctx, imsi); err != nil || lifted {
+		t.Fatalf("IsCaptivePortalLifted = %v, %v after a restore, want false", lifted, err)
+	}
+
+	if err := database.LiftCaptivePortal(ctx, imsi); err != nil {
+		t.Fatalf("couldn't lift the captive portal: %s", err)
+	}
+
+	if err := database.DeleteSubscriber(ctx, imsi); err != nil {
+		t.Fatalf("couldn't delete subscriber: %s", err)
+	}
+
+	lifts, err := database.ListAllCaptivePortalLifts(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list lifts: %s", err)
+	}
+
+	if len(lifts) != 0 {
+		t.Fatalf("expected the lift to be deleted with its subscriber, got %+v", lifts)
+	}
+}
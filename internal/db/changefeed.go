@@ -18,19 +18,32 @@ const (
 	// the full set of replicated tables that drive runtime state.
 	// Adding a new reconciler is two lines: declare the topic, mark
 	// the ops that touch it via AffectsTopic in operations_register.go.
-	TopicNATSettings            Topic = "nat_settings"
-	TopicFlowAccountingSettings Topic = "flow_accounting_settings"
-	TopicLocalSwitchSettings    Topic = "local_switch_settings"
-	TopicN3Settings             Topic = "n3_settings"
-	TopicPolicies               Topic = "policies"
-	TopicNetworkRules           Topic = "network_rules"
-	TopicBGPSettings            Topic = "bgp_settings"
-	TopicBGPPeers               Topic = "bgp_peers"
-	TopicDataNetworks           Topic = "data_networks"
-	TopicIPLeases               Topic = "ip_leases"
-	TopicClusterNodeCerts       Topic = "cluster_node_certs"
-	TopicSessionReconcile       Topic = "session_reconcile"
-	TopicFramedRoutes           Topic = "subscriber_framed_routes"
+	TopicNATSettings             Topic = "nat_settings"
+	TopicFlowAccountingSettings  Topic = "flow_accounting_settings"
+	TopicLocalSwitchSettings     Topic = "local_switch_settings"
+	TopicN3Settings              Topic = "n3_settings"
+	TopicPolicies                Topic = "policies"
+	TopicNetworkRules            Topic = "network_rules"
+	TopicBGPSettings             Topic = "bgp_settings"
+	TopicBGPPeers                Topic = "bgp_peers"
+	TopicDataNetworks            Topic = "data_networks"
+	TopicIPLeases                Topic = "ip_leases"
+	TopicClusterNodeCerts        Topic = "cluster_node_certs"
+	TopicSessionReconcile        Topic = "session_reconcile"
+	TopicFramedRoutes            Topic = "subscriber_framed_routes"
+	TopicDataNetworkEgress       Topic = "data_network_egress"
+	TopicDataNetworkNAT          Topic = "data_network_nat"
+	TopicDataNetworkTCPMSS       Topic = "data_network_tcp_mss"
+	TopicSchedules               Topic = "schedules"
+	TopicCaptivePortals          Topic = "captive_portals"
+	TopicDNSResolvers            Topic = "dns_resolvers"
+	TopicAddressAllocation       Topic = "address_allocation"
+	TopicSecondaryAuth           Topic = "secondary_auth"
+	TopicAccountingServers       Topic = "accounting_servers"
+	TopicOnlineCharging          Topic = "online_charging"
+	TopicPolicyControl           Topic = "policy_control"
+	TopicQosSessions             Topic = "qos_sessions"
+	TopicMonitoringSubscriptions Topic = "monitoring_subscriptions"
 )
 
 // Event is published once per (topic, applied-index) and carries no
@@ -45,8 +45,39 @@ var replicatedChangesetTables = []string{
 	PoliciesTableName,
 	ProfilesTableName,
 	DataNetworksTableName,
+	DataNetworkEgressTableName,
+	DataNetworkNATTableName,
+	NATPortForwardsTableName,
+	DataNetworkTCPMSSTableName,
+	DataNetworkDNSResolversTableName,
+	DNSLocalRecordsTableName,
+	DataNetworkAddressAllocationTableName,
+	ExternalIPLeasesTableName,
+	DataNetworkSecondaryAuthTableName,
+	AccountingServersTableName,
+	DataNetworkOnlineChargingTableName,
+	DataNetworkPolicyControlTableName,
+	SMPolicyAssociationsTableName,
+	QosSessionsTableName,
+	MonitoringSubscriptionsTableName,
+	SubscriberAmbrOverridesTableName,
+	NetworkSliceQuotasTableName,
+	DataNetworkQuotasTableName,
+	SubscriberLifecyclesTableName,
+	PolicyLocationVariantsTableName,
+	SliceRegistrationsTableName,
+	AdmittedSessionsTableName,
 	NetworkSlicesTableName,
 	NetworkRulesTableName,
+	NetworkRuleFQDNsTableName,
+	NetworkRuleRateLimitsTableName,
+	NetworkRuleBreakoutsTableName,
+	NetworkRuleRatingsTableName,
+	SchedulesTableName,
+	NetworkRuleSchedulesTableName,
+	PolicySchedulesTableName,
+	PolicyCaptivePortalsTableName,
+	CaptivePortalLiftsTableName,
 	FramedRoutesTableName,
 	IPLeasesTableName,
 	AuditLogsTableName,
@@ -62,6 +93,7 @@ var replicatedChangesetTables = []string{
 	ClusterJoinTokensTableName,
 	ClusterJoinHMACTableName,
 	DailyUsageTableName,
+	DailyRatingGroupUsageTableName,
 	CellPositionsTableName,
 	"schema_version",
 }
@@ -93,6 +125,7 @@ var localOnlyTables = []string{
 	FlowAccountingSettingsTableName,
 	LocalSwitchSettingsTableName,
 	PositioningSessionsTableName,
+	AccountingOutboxTableName,
 }
 
 // fsmInternalTables are managed directly by the FSM layer, not through
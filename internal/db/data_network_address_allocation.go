@@ -0,0 +1,429 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DataNetworkAddressAllocationTableName = "data_network_address_allocation"
+	ExternalIPLeasesTableName             = "external_ip_leases"
+)
+
+// addressAllocationSchema is the migration that introduced both tables.
+// Reads below it report no external allocation, so UEs keep taking
+// addresses from the data network's pool.
+const addressAllocationSchema = 28
+
+// Address allocation modes. A data network without a row allocates from its
+// own pool.
+const (
+	AddressAllocationDHCP   = "dhcp"
+	AddressAllocationRADIUS = "radius"
+)
+
+const (
+	upsertDataNetworkAddressAllocationStmt = "INSERT INTO %s (dataNetworkID, mode, server, relayAddress, secret, fallbackToPool) VALUES ($DataNetworkAddressAllocation.dataNetworkID, $DataNetworkAddressAllocation.mode, $DataNetworkAddressAllocation.server, $DataNetworkAddressAllocation.relayAddress, $DataNetworkAddressAllocation.secret, $DataNetworkAddressAllocation.fallbackToPool) ON CONFLICT(dataNetworkID) DO UPDATE SET mode=excluded.mode, server=excluded.server, relayAddress=excluded.relayAddress, secret=excluded.secret, fallbackToPool=excluded.fallbackToPool"
+	deleteDataNetworkAddressAllocationStmt = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkAddressAllocation.dataNetworkID"
+	getDataNetworkAddressAllocationStmt    = "SELECT &DataNetworkAddressAllocation.* FROM %s WHERE dataNetworkID==$DataNetworkAddressAllocation.dataNetworkID"
+
+	createExternalIPLeaseStmt       = "INSERT INTO %s (leaseID, source, serverID, clientID, renewAt, expiresAt) VALUES ($ExternalIPLease.leaseID, $ExternalIPLease.source, $ExternalIPLease.serverID, $ExternalIPLease.clientID, $ExternalIPLease.renewAt, $ExternalIPLease.expiresAt)"
+	getExternalIPLeaseStmt          = "SELECT &ExternalIPLease.* FROM %s WHERE leaseID==$ExternalIPLease.leaseID"
+	updateExternalIPLeaseTimersStmt = "UPDATE %s SET serverID=$ExternalIPLease.serverID, renewAt=$ExternalIPLease.renewAt, expiresAt=$ExternalIPLease.expiresAt WHERE leaseID==$ExternalIPLease.leaseID"
+	listExternalIPLeasesByNodeStmt  = "SELECT &ExternalIPLease.* FROM %s WHERE leaseID IN (SELECT id FROM %s WHERE nodeID==$IPLease.nodeID AND sessionID IS NOT NULL) ORDER BY renewAt"
+	getLeaseByAddressStmt           = "SELECT &IPLease.* FROM %s WHERE poolID==$IPLease.poolID AND poolType==$IPLease.poolType AND addressBin==$IPLease.addressBin"
+)
+
+// DataNetworkAddressAllocation makes a data network take UE IPv4 addresses
+// from an external server instead of its pool. In dhcp mode Server is the
+// DHCPv4 server and RelayAddress the N6 address Ella Core relays from; in
+// radius mode Server is the RADIUS authentication server and Secret its
+// shared secret. FallbackToPool lets sessions use the pool when the server
+// does not answer.
+type DataNetworkAddressAllocation struct {
+	DataNetworkID  string `db:"dataNetworkID"` // FK to data_networks.id
+	Mode           string `db:"mode"`
+	Server         string `db:"server"`
+	RelayAddress   string `db:"relayAddress"`
+	Secret         string `db:"secret"`
+	FallbackToPool bool   `db:"fallbackToPool"`
+}
+
+// ExternalIPLease records what the external server said about a dynamic
+// lease it handed out. RenewAt and ExpiresAt are Unix seconds; both are zero
+// for RADIUS addresses, which are held for the whole session.
+type ExternalIPLease struct {
+	LeaseID   string `db:"leaseID"` // FK to ip_leases.id
+	Source    string `db:"source"`
+	ServerID  string `db:"serverID"`
+	ClientID  string `db:"clientID"`
+	RenewAt   int64  `db:"renewAt"`
+	ExpiresAt int64  `db:"expiresAt"`
+}
+
+type createExternalLeasePayload struct {
+	Lease    IPLease         `json:"lease"`
+	External ExternalIPLease `json:"external"`
+}
+
+// SetDataNetworkAddressAllocation makes a data network allocate from an
+// external server, or changes its settings.
+func (db *Database) SetDataNetworkAddressAllocation(ctx context.Context, alloc *DataNetworkAddressAllocation) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", DataNetworkAddressAllocationTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", DataNetworkAddressAllocationTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkAddressAllocationTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkAddressAllocationTableName, "upsert").Inc()
+
+	_, err := opSetDataNetworkAddressAllocation.Invoke(db, alloc)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetDataNetworkAddressAllocation(ctx context.Context, alloc *DataNetworkAddressAllocation) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkAddressAllocationStmt, alloc).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearDataNetworkAddressAllocation returns a data network to its pool.
+// Addresses already leased from the external server are kept until their
+// sessions end. Clearing a data network that uses its pool is not an error.
+func (db *Database) ClearDataNetworkAddressAllocation(ctx context.Context, dataNetworkID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", DataNetworkAddressAllocationTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", DataNetworkAddressAllocationTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkAddressAllocationTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkAddressAllocationTableName, "delete").Inc()
+
+	_, err := opClearDataNetworkAddressAllocation.Invoke(db, &DataNetworkAddressAllocation{DataNetworkID: dataNetworkID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearDataNetworkAddressAllocation(ctx context.Context, alloc *DataNetworkAddressAllocation) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkAddressAllocationStmt, alloc).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDataNetworkAddressAllocation returns ErrNotFound when the data network
+// allocates from its pool.
+func (db *Database) GetDataNetworkAddressAllocation(ctx context.Context, dataNetworkID string) (*DataNetworkAddressAllocation, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkAddressAllocationTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkAddressAllocationTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(addressAllocationSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkAddressAllocationTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkAddressAllocationTableName, "select").Inc()
+
+	row := DataNetworkAddressAllocation{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkAddressAllocationStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// CreateExternalLease records an address handed out by an external server
+// as a dynamic lease bound to the session, together with the server's
+// lease details, and sets lease.ID. Retrying for a session that already
+// holds the same address is not an error. An address held by anyone else
+// returns ErrAlreadyExists.
+func (db *Database) CreateExternalLease(ctx context.Context, lease *IPLease, address netip.Addr, external *ExternalIPLease) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", ExternalIPLeasesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", ExternalIPLeasesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(ExternalIPLeasesTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(ExternalIPLeasesTableName, "insert").Inc()
+
+	b := address.As16()
+	lease.AddressBin = b[:]
+	lease.Type = "dynamic"
+
+	if lease.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("generate lease id: %w", err)
+		}
+
+		lease.ID = id.String()
+	}
+
+	external.LeaseID = lease.ID
+
+	leaseID, err := opCreateExternalLease.Invoke(db, &createExternalLeasePayload{Lease: *lease, External: *external})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	lease.ID = leaseID
+	external.LeaseID = leaseID
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+// applyCreateExternalLease inserts both rows under the same changeset so a
+// follower never sees an external address without its server details.
+func (db *Database) applyCreateExternalLease(ctx context.Context, p *createExternalLeasePayload) (any, error) {
+	runner := db.runner(ctx)
+
+	existing := IPLease{PoolID: p.Lease.PoolID, PoolType: p.Lease.PoolType, AddressBin: p.Lease.AddressBin}
+
+	err := runner.Query(ctx, db.getLeaseByAddressStmt, existing).Get(&existing)
+
+	switch {
+	case err == nil:
+		if existing.Type == "dynamic" && existing.IMSI == p.Lease.IMSI && existing.SessionID != nil &&
+			p.Lease.SessionID != nil && *existing.SessionID == *p.Lease.SessionID {
+			return existing.ID, nil
+		}
+
+		return nil, ErrAlreadyExists
+	case errors.Is(err, sql.ErrNoRows):
+	default:
+		return nil, fmt.Errorf("get lease by address: %w", err)
+	}
+
+	lease := p.Lease
+	if _, err := db.applyCreateLease(ctx, &lease); err != nil {
+		return nil, err
+	}
+
+	external := p.External
+	external.LeaseID = lease.ID
+
+	if err := runner.Query(ctx, db.createExternalIPLeaseStmt, external).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return lease.ID, nil
+}
+
+// GetExternalLease returns ErrNotFound for a lease taken from a pool.
+func (db *Database) GetExternalLease(ctx context.Context, leaseID string) (*ExternalIPLease, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", ExternalIPLeasesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", ExternalIPLeasesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(addressAllocationSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(ExternalIPLeasesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(ExternalIPLeasesTableName, "select").Inc()
+
+	row := ExternalIPLease{LeaseID: leaseID}
+
+	err := db.conn().Query(ctx, db.getExternalIPLeaseStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// ListExternalLeasesByNode returns the external details of the active
+// leases nodeID holds, soonest renewal first.
+func (db *Database) ListExternalLeasesByNode(ctx context.Context, nodeID int) ([]ExternalIPLease, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", ExternalIPLeasesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", ExternalIPLeasesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(addressAllocationSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []ExternalIPLease{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(ExternalIPLeasesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(ExternalIPLeasesTableName, "select").Inc()
+
+	var rows []ExternalIPLease
+
+	err := db.conn().Query(ctx, db.listExternalIPLeasesByNodeStmt, IPLease{NodeID: nodeID}).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []ExternalIPLease{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
+
+// UpdateExternalLeaseTimers stores the server, renewal and expiry times of
+// a renewed external lease.
+func (db *Database) UpdateExternalLeaseTimers(ctx context.Context, external *ExternalIPLease) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", ExternalIPLeasesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", ExternalIPLeasesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(ExternalIPLeasesTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(ExternalIPLeasesTableName, "update").Inc()
+
+	_, err := opUpdateExternalLeaseTimers.Invoke(db, external)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyUpdateExternalLeaseTimers(ctx context.Context, external *ExternalIPLease) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.updateExternalIPLeaseTimersStmt, external).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
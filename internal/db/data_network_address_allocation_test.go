@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkAddressAllocationEndToEnd(t *testing.T) {
+	database, poolID, _ := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	if _, err := database.GetDataNetworkAddressAllocation(ctx, poolID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before allocation is set, got %v", err)
+	}
+
+	alloc := &db.DataNetworkAddressAllocation{
+		DataNetworkID:  poolID,
+		Mode:           db.AddressAllocationDHCP,
+		Server:         "10.100.0.2",
+		RelayAddress:   "10.100.0.1",
+		FallbackToPool: true,
+	}
+
+	if err := database.SetDataNetworkAddressAllocation(ctx, alloc); err != nil {
+		t.Fatalf("couldn't set allocation: %s", err)
+	}
+
+	alloc.Mode = db.AddressAllocationRADIUS
+	alloc.Secret = "testing123"
+
+	if err := database.SetDataNetworkAddressAllocation(ctx, alloc); err != nil {
+		t.Fatalf("couldn't update allocation: %s", err)
+	}
+
+	got, err := database.GetDataNetworkAddressAllocation(ctx, poolID)
+	if err != nil {
+		t.Fatalf("couldn't get allocation: %s", err)
+	}
+
+	if *got != *alloc {
+		t.Fatalf("allocation = %+v, want %+v", got, alloc)
+	}
+
+	if err := database.ClearDataNetworkAddressAllocation(ctx, poolID); err != nil {
+		t.Fatalf("couldn't clear allocation: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkAddressAllocation(ctx, poolID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+
+	if err := database.ClearDataNetworkAddressAllocation(ctx, poolID); err != nil {
+		t.Fatalf("clearing twice should not fail: %s", err)
+	}
+}
+
+func TestExternalLeaseLifecycle(t *testing.T) {
+	database, poolID, imsi := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	sessionID := 5
+	now := time.Now().Unix()
+
+	newLease := func() *db.IPLease {
+		return &db.IPLease{
+			PoolID:    poolID,
+			PoolType:  "ipv4",
+			IMSI:      imsi,
+			SessionID: &sessionID,
+			CreatedAt: now,
+			NodeID:    database.NodeID(),
+		}
+	}
+
+	lease := newLease()
+	external := &db.ExternalIPLease{
+		Source:    db.AddressAllocationDHCP,
+		ServerID:  "10.100.0.2",
+		ClientID:  "imsi-" + imsi + "-5",
+		RenewAt:   now + 1800,
+		ExpiresAt: now + 3600,
+	}
+
+	// Outside the data network's own pool.
+	if err := database.CreateExternalLease(ctx, lease, addr("172.20.0.50"), external); err != nil {
+		t.Fatalf("CreateExternalLease: %s", err)
+	}
+
+	if lease.ID == "" || external.LeaseID != lease.ID {
+		t.Fatalf("expected ids to be set, got lease %q external %q", lease.ID, external.LeaseID)
+	}
+
+	retry := newLease()
+	if err := database.CreateExternalLease(ctx, retry, addr("172.20.0.50"), &db.ExternalIPLease{Source: db.AddressAllocationDHCP}); err != nil {
+		t.Fatalf("retrying for the same session should not fail: %s", err)
+	}
+
+	if retry.ID != lease.ID {
+		t.Fatalf("retry created lease %q, want %q", retry.ID, lease.ID)
+	}
+
+	otherSession := 6
+	other := newLease()
+	other.SessionID = &otherSession
+
+	if err := database.CreateExternalLease(ctx, other, addr("172.20.0.50"), &db.ExternalIPLease{Source: db.AddressAllocationDHCP}); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a held address, got %v", err)
+	}
+
+	// Allocation for the same session returns the external address.
+	got, err := database.AllocateIPLease(ctx, poolID, "ipv4", imsi, sessionID, database.NodeID())
+	if err != nil {
+		t.Fatalf("AllocateIPLease: %s", err)
+	}
+
+	if got != addr("172.20.0.50") {
+		t.Fatalf("AllocateIPLease = %s, want the external address", got)
+	}
+
+	leases, err := database.ListExternalLeasesByNode(ctx, database.NodeID())
+	if err != nil {
+		t.Fatalf("ListExternalLeasesByNode: %s", err)
+	}
+
+	if len(leases) != 1 || leases[0] != *external {
+		t.Fatalf("unexpected external leases %+v", leases)
+	}
+
+	external.RenewAt = now + 5400
+	external.ExpiresAt = now + 7200
+
+	if err := database.UpdateExternalLeaseTimers(ctx, external); err != nil {
+		t.Fatalf("UpdateExternalLeaseTimers: %s", err)
+	}
+
+	stored, err := database.GetExternalLease(ctx, lease.ID)
+	if err != nil {
+		t.Fatalf("GetExternalLease: %s", err)
+	}
+
+	if *stored != *external {
+		t.Fatalf("external lease = %+v, want %+v", stored, external)
+	}
+
+	if _, err := database.ReleaseIPLease(ctx, poolID, "ipv4", imsi, sessionID, database.NodeID()); err != nil {
+		t.Fatalf("ReleaseIPLease: %s", err)
+	}
+
+	if _, err := database.GetExternalLease(ctx, lease.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected the external details to go with the lease, got %v", err)
+	}
+}
@@ -0,0 +1,472 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/canonical/sqlair"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DataNetworkDNSResolversTableName = "data_network_dns_resolvers"
+	DNSLocalRecordsTableName         = "dns_local_records"
+)
+
+// dataNetworkDNSSchema is the migration that introduced both tables. Reads
+// below it report no resolver and no records, so UEs keep the data
+// network's own DNS server.
+const dataNetworkDNSSchema = 27
+
+const (
+	upsertDataNetworkDNSResolverStmt   = "INSERT INTO %s (dataNetworkID, listenAddress, upstreams, localZones, logQueries) VALUES ($DataNetworkDNSResolver.dataNetworkID, $DataNetworkDNSResolver.listenAddress, $DataNetworkDNSResolver.upstreams, $DataNetworkDNSResolver.localZones, $DataNetworkDNSResolver.logQueries) ON CONFLICT(dataNetworkID) DO UPDATE SET listenAddress=excluded.listenAddress, upstreams=excluded.upstreams, localZones=excluded.localZones, logQueries=excluded.logQueries"
+	deleteDataNetworkDNSResolverStmt   = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkDNSResolver.dataNetworkID"
+	getDataNetworkDNSResolverStmt      = "SELECT &DataNetworkDNSResolver.* FROM %s WHERE dataNetworkID==$DataNetworkDNSResolver.dataNetworkID"
+	listAllDataNetworkDNSResolversStmt = "SELECT &DataNetworkDNSResolver.* FROM %s ORDER BY dataNetworkID"
+
+	createDNSLocalRecordStmt    = "INSERT INTO %s (id, dataNetworkID, name, imsi, address) VALUES ($DNSLocalRecord.id, $DNSLocalRecord.dataNetworkID, $DNSLocalRecord.name, $DNSLocalRecord.imsi, $DNSLocalRecord.address)"
+	getDNSLocalRecordStmt       = "SELECT &DNSLocalRecord.* FROM %s WHERE id==$DNSLocalRecord.id"
+	deleteDNSLocalRecordStmt    = "DELETE FROM %s WHERE id==$DNSLocalRecord.id"
+	listDNSLocalRecordsByDNStmt = "SELECT &DNSLocalRecord.* FROM %s WHERE dataNetworkID==$DNSLocalRecord.dataNetworkID ORDER BY name"
+	listAllDNSLocalRecordsStmt  = "SELECT &DNSLocalRecord.* FROM %s ORDER BY dataNetworkID, name"
+)
+
+// DataNetworkDNSResolver turns on the embedded DNS forwarder of a data
+// network. UEs are handed ListenAddress as their DNS server. Upstreams is a
+// comma-separated list of servers tried in order, each an address or
+// address:port. LocalZones is a comma-separated list of domains answered
+// from the data network's local records alone.
+type DataNetworkDNSResolver struct {
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	ListenAddress string `db:"listenAddress"`
+	Upstreams     string `db:"upstreams"`
+	LocalZones    string `db:"localZones"`
+	LogQueries    bool   `db:"logQueries"`
+}
+
+// UpstreamList splits Upstreams.
+func (r *DataNetworkDNSResolver) UpstreamList() []string {
+	return splitList(r.Upstreams)
+}
+
+// LocalZoneList splits LocalZones.
+func (r *DataNetworkDNSResolver) LocalZoneList() []string {
+	return splitList(r.LocalZones)
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, ",")
+}
+
+// DNSLocalRecord names a UE or host in a data network's local zones. The
+// name resolves either to the static addresses of the subscriber IMSI in
+// the data network, or to a fixed Address.
+type DNSLocalRecord struct {
+	ID            string `db:"id"`            // UUIDv7
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	Name          string `db:"name"`
+	IMSI          string `db:"imsi"`
+	Address       string `db:"address"`
+}
+
+// SetDataNetworkDNSResolver turns on a data network's DNS forwarder, or
+// changes its settings.
+func (db *Database) SetDataNetworkDNSResolver(ctx context.Context, resolver *DataNetworkDNSResolver) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", DataNetworkDNSResolversTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", DataNetworkDNSResolversTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkDNSResolversTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkDNSResolversTableName, "upsert").Inc()
+
+	_, err := opSetDataNetworkDNSResolver.Invoke(db, resolver)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetDataNetworkDNSResolver(ctx context.Context, resolver *DataNetworkDNSResolver) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkDNSResolverStmt, resolver).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearDataNetworkDNSResolver turns a data network's DNS forwarder off. Its
+// local records are kept. Clearing a data network without a forwarder is
+// not an error.
+func (db *Database) ClearDataNetworkDNSResolver(ctx context.Context, dataNetworkID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", DataNetworkDNSResolversTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", DataNetworkDNSResolversTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkDNSResolversTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkDNSResolversTableName, "delete").Inc()
+
+	_, err := opClearDataNetworkDNSResolver.Invoke(db, &DataNetworkDNSResolver{DataNetworkID: dataNetworkID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearDataNetworkDNSResolver(ctx context.Context, resolver *DataNetworkDNSResolver) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkDNSResolverStmt, resolver).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDataNetworkDNSResolver returns ErrNotFound when the data network's DNS
+// forwarder is off.
+func (db *Database) GetDataNetworkDNSResolver(ctx context.Context, dataNetworkID string) (*DataNetworkDNSResolver, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkDNSResolversTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkDNSResolversTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkDNSSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkDNSResolversTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkDNSResolversTableName, "select").Inc()
+
+	row := DataNetworkDNSResolver{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkDNSResolverStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+func (db *Database) ListAllDataNetworkDNSResolvers(ctx context.Context) ([]DataNetworkDNSResolver, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkDNSResolversTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkDNSResolversTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkDNSSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []DataNetworkDNSResolver{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkDNSResolversTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkDNSResolversTableName, "select").Inc()
+
+	var rows []DataNetworkDNSResolver
+
+	err := db.conn().Query(ctx, db.listAllDataNetworkDNSResolversStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []DataNetworkDNSResolver{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
+
+// EffectiveDNS returns the DNS server UEs of dn are handed: the embedded
+// forwarder's address while it is on, the data network's own otherwise.
+func (db *Database) EffectiveDNS(ctx context.Context, dn *DataNetwork) (string, error) {
+	resolver, err := db.GetDataNetworkDNSResolver(ctx, dn.ID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return dn.DNS, nil
+		}
+
+		return "", err
+	}
+
+	return resolver.ListenAddress, nil
+}
+
+// CreateDNSLocalRecord stores a local record and sets its ID. A name the
+// data network already has returns ErrAlreadyExists.
+func (db *Database) CreateDNSLocalRecord(ctx context.Context, record *DNSLocalRecord) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", DNSLocalRecordsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", DNSLocalRecordsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DNSLocalRecordsTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DNSLocalRecordsTableName, "insert").Inc()
+
+	if record.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("generate dns record id: %w", err)
+		}
+
+		record.ID = id.String()
+	}
+
+	_, err := opCreateDNSLocalRecord.Invoke(db, record)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreateDNSLocalRecord(ctx context.Context, record *DNSLocalRecord) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.createDNSLocalRecordStmt, record).Run(); err != nil {
+		if isUniqueNameError(err) {
+			return nil, ErrAlreadyExists
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDNSLocalRecord returns ErrNotFound for an unknown id.
+func (db *Database) GetDNSLocalRecord(ctx context.Context, id string) (*DNSLocalRecord, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DNSLocalRecordsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DNSLocalRecordsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkDNSSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DNSLocalRecordsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DNSLocalRecordsTableName, "select").Inc()
+
+	row := DNSLocalRecord{ID: id}
+
+	err := db.conn().Query(ctx, db.getDNSLocalRecordStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// DeleteDNSLocalRecord returns ErrNotFound for an unknown id.
+func (db *Database) DeleteDNSLocalRecord(ctx context.Context, id string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", DNSLocalRecordsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", DNSLocalRecordsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DNSLocalRecordsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DNSLocalRecordsTableName, "delete").Inc()
+
+	_, err := opDeleteDNSLocalRecord.Invoke(db, &stringPayload{Value: id})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteDNSLocalRecord(ctx context.Context, p *stringPayload) (any, error) {
+	var outcome sqlair.Outcome
+
+	err := db.runner(ctx).Query(ctx, db.deleteDNSLocalRecordStmt, DNSLocalRecord{ID: p.Value}).Get(&outcome)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+func (db *Database) ListDNSLocalRecordsByDataNetwork(ctx context.Context, dataNetworkID string) ([]DNSLocalRecord, error) {
+	return db.listDNSLocalRecords(ctx, db.listDNSLocalRecordsByDNStmt, DNSLocalRecord{DataNetworkID: dataNetworkID})
+}
+
+func (db *Database) ListAllDNSLocalRecords(ctx context.Context) ([]DNSLocalRecord, error) {
+	return db.listDNSLocalRecords(ctx, db.listAllDNSLocalRecordsStmt)
+}
+
+func (db *Database) listDNSLocalRecords(ctx context.Context, stmt *sqlair.Statement, params ...any) ([]DNSLocalRecord, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DNSLocalRecordsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DNSLocalRecordsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkDNSSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []DNSLocalRecord{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DNSLocalRecordsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DNSLocalRecordsTableName, "select").Inc()
+
+	var rows []DNSLocalRecord
+
+	err := db.conn().Query(ctx, stmt, params...).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []DNSLocalRecord{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkDNSResolverEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	dn := &db.DataNetwork{Name: "factory", IPv4Pool: "10.48.0.0/16", DNS: "8.8.8.8", MTU: 1400}
+
+	if err := database.CreateDataNetworkWithEgress(ctx, dn, nil); err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if dns, err := database.EffectiveDNS(ctx, dn); err != nil || dns != "8.8.8.8" {
+		t.Fatalf("EffectiveDNS = %q, %v before the forwarder is on, want the data network's", dns, err)
+	}
+
+	resolver := &db.DataNetworkDNSResolver{
+		DataNetworkID: dn.ID,
+		ListenAddress: "10.48.0.1",
+		Upstreams:     "1.1.1.1,9.9.9.9:5353",
+		LocalZones:    "factory.local",
+		LogQueries:    true,
+	}
+
+	if err := database.SetDataNetworkDNSResolver(ctx, resolver); err != nil {
+		t.Fatalf("couldn't turn on the forwarder: %s", err)
+	}
+
+	got, err := database.GetDataNetworkDNSResolver(ctx, dn.ID)
+	if err != nil {
+		t.Fatalf("couldn't get the forwarder: %s", err)
+	}
+
+	if *got != *resolver {
+		t.Fatalf("forwarder = %+v, want %+v", got, resolver)
+	}
+
+	if upstreams := got.UpstreamList(); len(upstreams) != 2 || upstreams[1] != "9.9.9.9:5353" {
+		t.Fatalf("unexpected upstreams %v", upstreams)
+	}
+
+	if dns, err := database.EffectiveDNS(ctx, dn); err != nil || dns != "10.48.0.1" {
+		t.Fatalf("EffectiveDNS = %q, %v, want the forwarder's address", dns, err)
+	}
+
+	record := &db.DNSLocalRecord{DataNetworkID: dn.ID, Name: "plc1.factory.local", IMSI: "001010100007487"}
+
+	if err := database.CreateDNSLocalRecord(ctx, record); err != nil {
+		t.Fatalf("couldn't create record: %s", err)
+	}
+
+	if record.ID == "" {
+		t.Fatal("expected the record to be given an id")
+	}
+
+	dup := &db.DNSLocalRecord{DataNetworkID: dn.ID, Name: "plc1.factory.local", Address: "10.48.0.20"}
+	if err := database.CreateDNSLocalRecord(ctx, dup); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a duplicate name, got %v", err)
+	}
+
+	if err := database.ClearDataNetworkDNSResolver(ctx, dn.ID); err != nil {
+		t.Fatalf("couldn't turn off the forwarder: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkDNSResolver(ctx, dn.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+
+	records, err := database.ListDNSLocalRecordsByDataNetwork(ctx, dn.ID)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected the record to outlive the forwarder, got %+v, %v", records, err)
+	}
+
+	if err := database.DeleteDNSLocalRecord(ctx, record.ID); err != nil {
+		t.Fatalf("couldn't delete record: %s", err)
+	}
+
+	if err := database.DeleteDNSLocalRecord(ctx, record.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	if err := database.SetDataNetworkDNSResolver(ctx, resolver); err != nil {
+		t.Fatalf("couldn't turn on the forwarder: %s", err)
+	}
+
+	if err := database.CreateDNSLocalRecord(ctx, &db.DNSLocalRecord{DataNetworkID: dn.ID, Name: "hmi.factory.local", Address: "10.48.0.30"}); err != nil {
+		t.Fatalf("couldn't create record: %s", err)
+	}
+
+	if err := database.DeleteDataNetwork(ctx, "factory"); err != nil {
+		t.Fatalf("couldn't delete data network: %s", err)
+	}
+
+	resolvers, err := database.ListAllDataNetworkDNSResolvers(ctx)
+	if err != nil || len(resolvers) != 0 {
+		t.Fatalf("expected the forwarder to be deleted with its data network, got %+v, %v", resolvers, err)
+	}
+
+	records, err = database.ListAllDNSLocalRecords(ctx)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("expected the records to be deleted with their data network, got %+v, %v", records, err)
+	}
+}
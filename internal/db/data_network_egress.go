@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DataNetworkEgressTableName = "data_network_egress"
+
+// dataNetworkEgressSchema is the migration that introduced the table. Reads
+// below it report "no override" so callers on a node still at the baseline
+// schema keep sending every data network out of interfaces.n6.
+const dataNetworkEgressSchema = 18
+
+const (
+	upsertDataNetworkEgressStmt  = "INSERT INTO %s (dataNetworkID, interfaceName, vlanID, routingTable) VALUES ($DataNetworkEgress.dataNetworkID, $DataNetworkEgress.interfaceName, $DataNetworkEgress.vlanID, $DataNetworkEgress.routingTable) ON CONFLICT(dataNetworkID) DO UPDATE SET interfaceName=excluded.interfaceName, vlanID=excluded.vlanID, routingTable=excluded.routingTable"
+	deleteDataNetworkEgressStmt  = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkEgress.dataNetworkID"
+	getDataNetworkEgressStmt     = "SELECT &DataNetworkEgress.* FROM %s WHERE dataNetworkID==$DataNetworkEgress.dataNetworkID"
+	listAllDataNetworkEgressStmt = "SELECT &DataNetworkEgress.* FROM %s ORDER BY dataNetworkID"
+)
+
+// DataNetworkEgress overrides where a data network's uplink leaves the node.
+// InterfaceName names the egress netdev (empty: interfaces.n6). A non-zero
+// VlanID tags on top of that interface, resolved to its VLAN sub-interface.
+// A non-zero RoutingTable sends the data network's UE pools to that kernel
+// table, which holds the routes scoped to the data network.
+type DataNetworkEgress struct {
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	InterfaceName string `db:"interfaceName"`
+	VlanID        int    `db:"vlanID"`
+	RoutingTable  int    `db:"routingTable"`
+}
+
+// IsZero reports whether the override leaves every setting at its default,
+// in which case no row is stored.
+func (e *DataNetworkEgress) IsZero() bool {
+	return e == nil || (e.InterfaceName == "" && e.VlanID == 0 && e.RoutingTable == 0)
+}
+
+type dataNetworkWithEgressPayload struct {
+	DataNetwork DataNetwork        `json:"data_network"`
+	Egress      *DataNetworkEgress `json:"egress,omitempty"`
+}
+
+// CreateDataNetworkWithEgress creates a data network and its egress override
+// in one changeset. A nil or zero egress stores no override.
+func (db *Database) CreateDataNetworkWithEgress(ctx context.Context, dataNetwork *DataNetwork, egress *DataNetworkEgress) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", DataNetworksTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", DataNetworksTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworksTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworksTableName, "insert").Inc()
+
+	if dataNetwork.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("generate data network id: %w", err)
+		}
+
+		dataNetwork.ID = id.String()
+	}
+
+	_, err := opCreateDataNetworkWithEgress.Invoke(db, &dataNetworkWithEgressPayload{DataNetwork: *dataNetwork, Egress: egress})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+// UpdateDataNetworkWithEgress updates a data network by name. A nil egress
+// leaves the stored override untouched; a zero one removes it.
+func (db *Database) UpdateDataNetworkWithEgress(ctx context.Context, dataNetwork *DataNetwork, egress *DataNetworkEgress) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", DataNetworksTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", DataNetworksTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworksTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworksTableName, "update").Inc()
+
+	_, err := opUpdateDataNetworkWithEgress.Invoke(db, &dataNetworkWithEgressPayload{DataNetwork: *dataNetwork, Egress: egress})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreateDataNetworkWithEgress(ctx context.Context, payload *dataNetworkWithEgressPayload) (any, error) {
+	dn := payload.DataNetwork
+
+	if _, err := db.applyCreateDataNetwork(ctx, &dn); err != nil {
+		return nil, err
+	}
+
+	if err := db.applyDataNetworkEgress(ctx, dn.ID, payload.Egress); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (db *Database) applyUpdateDataNetworkWithEgress(ctx context.Context, payload *dataNetworkWithEgressPayload) (any, error) {
+	dn := payload.DataNetwork
+
+	if _, err := db.applyUpdateDataNetwork(ctx, &dn); err != nil {
+		return nil, err
+	}
+
+	if payload.Egress == nil {
+		return nil, nil
+	}
+
+	// The update is keyed by name; the override is keyed by id.
+	if err := db.runner(ctx).Query(ctx, db.getDataNetworkStmt, dn).Get(&dn); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	if err := db.applyDataNetworkEgress(ctx, dn.ID, payload.Egress); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (db *Database) applyDataNetworkEgress(ctx context.Context, dataNetworkID string, egress *DataNetworkEgress) error {
+	if egress.IsZero() {
+		row := DataNetworkEgress{DataNetworkID: dataNetworkID}
+		if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkEgressStmt, row).Run(); err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+
+		return nil
+	}
+
+	row := *egress
+	row.DataNetworkID = dataNetworkID
+
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkEgressStmt, row).Run(); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil
+}
+
+// GetDataNetworkEgress returns ErrNotFound when the data network has no
+// override.
+func (db *Database) GetDataNetworkEgress(ctx context.Context, dataNetworkID string) (*DataNetworkEgress, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkEgressTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkEgressTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkEgressSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkEgressTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkEgressTableName, "select").Inc()
+
+	row := DataNetworkEgress{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkEgressStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+func (db *Database) ListAllDataNetworkEgress(ctx context.Context) ([]DataNetworkEgress, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkEgressTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkEgressTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkEgressSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []DataNetworkEgress{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkEgressTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkEgressTableName, "select").Inc()
+
+	var rows []DataNetworkEgress
+
+	err := db.conn().Query(ctx, db.listAllDataNetworkEgressStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []DataNetworkEgress{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
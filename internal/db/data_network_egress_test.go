@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkEgressEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	dn := &db.DataNetwork{
+		Name:     "ot",
+		IPv4Pool: "10.50.0.0/24",
+		DNS:      "8.8.8.8",
+		MTU:      1400,
+	}
+
+	egress := &db.DataNetworkEgress{
+		InterfaceName: "eth2",
+		VlanID:        100,
+		RoutingTable:  100,
+	}
+
+	if err := database.CreateDataNetworkWithEgress(ctx, dn, egress); err != nil {
+		t.Fatalf("couldn't create data network with egress: %s", err)
+	}
+
+	got, err := database.GetDataNetworkEgress(ctx, dn.ID)
+	if err != nil {
+		t.Fatalf("couldn't get egress: %s", err)
+	}
+
+	if got.InterfaceName != "eth2" || got.VlanID != 100 || got.RoutingTable != 100 {
+		t.Fatalf("unexpected egress: %+v", got)
+	}
+
+	// A nil override leaves the stored one untouched.
+	if err := database.UpdateDataNetworkWithEgress(ctx, &db.DataNetwork{Name: "ot", IPv4Pool: dn.IPv4Pool, DNS: dn.DNS, MTU: 1300}, nil); err != nil {
+		t.Fatalf("couldn't update data network: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkEgress(ctx, dn.ID); err != nil {
+		t.Fatalf("expected egress to survive an update without one: %s", err)
+	}
+
+	if err := database.UpdateDataNetworkWithEgress(ctx, &db.DataNetwork{Name: "ot", IPv4Pool: dn.IPv4Pool, DNS: dn.DNS, MTU: 1300}, &db.DataNetworkEgress{RoutingTable: 200}); err != nil {
+		t.Fatalf("couldn't update egress: %s", err)
+	}
+
+	got, err = database.GetDataNetworkEgress(ctx, dn.ID)
+	if err != nil {
+		t.Fatalf("couldn't get egress: %s", err)
+	}
+
+	if got.InterfaceName != "" || got.VlanID != 0 || got.RoutingTable != 200 {
+		t.Fatalf("expected egress to be replaced, got %+v", got)
+	}
+
+	all, err := database.ListAllDataNetworkEgress(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list egress: %s", err)
+	}
+
+	if len(all) != 1 || all[0].DataNetworkID != dn.ID {
+		t.Fatalf("expected one egress row for %s, got %+v", dn.ID, all)
+	}
+
+	// A zero override removes the row.
+	if err := database.UpdateDataNetworkWithEgress(ctx, &db.DataNetwork{Name: "ot", IPv4Pool: dn.IPv4Pool, DNS: dn.DNS, MTU: 1300}, &db.DataNetworkEgress{}); err != nil {
+		t.Fatalf("couldn't clear egress: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkEgress(ctx, dn.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+
+	if err := database.UpdateDataNetworkWithEgress(ctx, &db.DataNetwork{Name: "missing", IPv4Pool: "10.60.0.0/24", DNS: "8.8.8.8", MTU: 1400}, &db.DataNetworkEgress{RoutingTable: 300}); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound updating a missing data network, got %v", err)
+	}
+}
+
+func TestDataNetworkEgressDeletedWithDataNetwork(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	dn := &db.DataNetwork{Name: "it", IPv4Pool: "10.51.0.0/24", DNS: "8.8.8.8", MTU: 1400}
+
+	if err := database.CreateDataNetworkWithEgress(ctx, dn, &db.DataNetworkEgress{InterfaceName: "eth3"}); err != nil {
+		t.Fatalf("couldn't create data network with egress: %s", err)
+	}
+
+	if err := database.DeleteDataNetwork(ctx, "it"); err != nil {
+		t.Fatalf("couldn't delete data network: %s", err)
+	}
+
+	all, err := database.ListAllDataNetworkEgress(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list egress: %s", err)
+	}
+
+	if len(all) != 0 {
+		t.Fatalf("expected egress to be deleted with its data network, got %+v", all)
+	}
+}
+
+func TestRouteDataNetworkScope(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	id, err := database.CreateRoute(ctx, &db.Route{
+		Destination:   "0.0.0.0/0",
+		Gateway:       "192.168.100.1",
+		Interface:     db.N6,
+		Metric:        10,
+		DataNetworkID: "dn-1",
+	})
+	if err != nil {
+		t.Fatalf("couldn't create route: %s", err)
+	}
+
+	route, err := database.GetRoute(ctx, id)
+	if err != nil {
+		t.Fatalf("couldn't get route: %s", err)
+	}
+
+	if route.DataNetworkID != "dn-1" {
+		t.Fatalf("expected route scoped to dn-1, got %q", route.DataNetworkID)
+	}
+}
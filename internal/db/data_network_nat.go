@@ -0,0 +1,422 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/canonical/sqlair"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DataNetworkNATTableName  = "data_network_nat"
+	NATPortForwardsTableName = "nat_port_forwards"
+)
+
+// dataNetworkNATSchema is the migration that introduced both tables. Reads
+// below it report no pool and no forwards, so every data network keeps
+// masquerading on its egress address.
+const dataNetworkNATSchema = 19
+
+// NAT pool mappings. Deterministic maps a UE to the same public address
+// every time; round robin spreads UEs over the pool as they first send.
+const (
+	NATMappingDeterministic = "deterministic"
+	NATMappingRoundRobin    = "round_robin"
+)
+
+const (
+	upsertDataNetworkNATStmt  = "INSERT INTO %s (dataNetworkID, addresses, mapping, portMin, portMax) VALUES ($DataNetworkNAT.dataNetworkID, $DataNetworkNAT.addresses, $DataNetworkNAT.mapping, $DataNetworkNAT.portMin, $DataNetworkNAT.portMax) ON CONFLICT(dataNetworkID) DO UPDATE SET addresses=excluded.addresses, mapping=excluded.mapping, portMin=excluded.portMin, portMax=excluded.portMax"
+	deleteDataNetworkNATStmt  = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkNAT.dataNetworkID"
+	getDataNetworkNATStmt     = "SELECT &DataNetworkNAT.* FROM %s WHERE dataNetworkID==$DataNetworkNAT.dataNetworkID"
+	listAllDataNetworkNATStmt = "SELECT &DataNetworkNAT.* FROM %s ORDER BY dataNetworkID"
+
+	createNATPortForwardStmt    = "INSERT INTO %s (id, dataNetworkID, protocol, publicAddress, publicPort, imsi, ueAddress, uePort) VALUES ($NATPortForward.id, $NATPortForward.dataNetworkID, $NATPortForward.protocol, $NATPortForward.publicAddress, $NATPortForward.publicPort, $NATPortForward.imsi, $NATPortForward.ueAddress, $NATPortForward.uePort)"
+	getNATPortForwardStmt       = "SELECT &NATPortForward.* FROM %s WHERE id==$NATPortForward.id"
+	deleteNATPortForwardStmt    = "DELETE FROM %s WHERE id==$NATPortForward.id"
+	listNATPortForwardsByDNStmt = "SELECT &NATPortForward.* FROM %s WHERE dataNetworkID==$NATPortForward.dataNetworkID ORDER BY publicAddress, protocol, publicPort"
+	listAllNATPortForwardsStmt  = "SELECT &NATPortForward.* FROM %s ORDER BY dataNetworkID, publicAddress, protocol, publicPort"
+)
+
+// DataNetworkNAT is a data network's public source pool. Addresses is a
+// comma-separated list of IPv4 addresses. A zero PortMin and PortMax keep
+// the datapath's default source port range.
+type DataNetworkNAT struct {
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	Addresses     string `db:"addresses"`
+	Mapping       string `db:"mapping"`
+	PortMin       int    `db:"portMin"`
+	PortMax       int    `db:"portMax"`
+}
+
+// AddressList splits Addresses.
+func (n *DataNetworkNAT) AddressList() []string {
+	if n == nil || n.Addresses == "" {
+		return nil
+	}
+
+	return strings.Split(n.Addresses, ",")
+}
+
+// NATPortForward exposes UEPort of a subscriber's UE on PublicAddress and
+// PublicPort. The UE is named either by IMSI, resolved through its lease in
+// the data network, or by a fixed UEAddress.
+type NATPortForward struct {
+	ID            string `db:"id"`            // UUIDv7
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	Protocol      string `db:"protocol"`
+	PublicAddress string `db:"publicAddress"`
+	PublicPort    int    `db:"publicPort"`
+	IMSI          string `db:"imsi"`
+	UEAddress     string `db:"ueAddress"`
+	UEPort        int    `db:"uePort"`
+}
+
+// SetDataNetworkNAT stores a data network's NAT pool. An empty address list
+// removes it.
+func (db *Database) SetDataNetworkNAT(ctx context.Context, nat *DataNetworkNAT) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", DataNetworkNATTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", DataNetworkNATTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkNATTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkNATTableName, "upsert").Inc()
+
+	_, err := opSetDataNetworkNAT.Invoke(db, nat)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetDataNetworkNAT(ctx context.Context, nat *DataNetworkNAT) (any, error) {
+	if nat.Addresses == "" {
+		if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkNATStmt, nat).Run(); err != nil {
+			return nil, fmt.Errorf("query failed: %w", err)
+		}
+
+		return nil, nil
+	}
+
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkNATStmt, nat).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDataNetworkNAT returns ErrNotFound when the data network has no pool.
+func (db *Database) GetDataNetworkNAT(ctx context.Context, dataNetworkID string) (*DataNetworkNAT, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkNATTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkNATTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkNATSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkNATTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkNATTableName, "select").Inc()
+
+	row := DataNetworkNAT{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkNATStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+func (db *Database) ListAllDataNetworkNAT(ctx context.Context) ([]DataNetworkNAT, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkNATTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkNATTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkNATSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []DataNetworkNAT{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkNATTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkNATTableName, "select").Inc()
+
+	var rows []DataNetworkNAT
+
+	err := db.conn().Query(ctx, db.listAllDataNetworkNATStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []DataNetworkNAT{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
+
+// CreateNATPortForward stores a port forward and sets its ID. A forward
+// reusing another's protocol, public address and port returns
+// ErrAlreadyExists.
+func (db *Database) CreateNATPortForward(ctx context.Context, fwd *NATPortForward) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", NATPortForwardsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", NATPortForwardsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NATPortForwardsTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NATPortForwardsTableName, "insert").Inc()
+
+	if fwd.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("generate port forward id: %w", err)
+		}
+
+		fwd.ID = id.String()
+	}
+
+	_, err := opCreateNATPortForward.Invoke(db, fwd)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreateNATPortForward(ctx context.Context, fwd *NATPortForward) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.createNATPortForwardStmt, fwd).Run(); err != nil {
+		if isUniqueNameError(err) {
+			return nil, ErrAlreadyExists
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetNATPortForward returns ErrNotFound for an unknown id.
+func (db *Database) GetNATPortForward(ctx context.Context, id string) (*NATPortForward, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NATPortForwardsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NATPortForwardsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkNATSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NATPortForwardsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NATPortForwardsTableName, "select").Inc()
+
+	row := NATPortForward{ID: id}
+
+	err := db.conn().Query(ctx, db.getNATPortForwardStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// DeleteNATPortForward returns ErrNotFound for an unknown id.
+func (db *Database) DeleteNATPortForward(ctx context.Context, id string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", NATPortForwardsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", NATPortForwardsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NATPortForwardsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NATPortForwardsTableName, "delete").Inc()
+
+	_, err := opDeleteNATPortForward.Invoke(db, &stringPayload{Value: id})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteNATPortForward(ctx context.Context, p *stringPayload) (any, error) {
+	var outcome sqlair.Outcome
+
+	err := db.runner(ctx).Query(ctx, db.deleteNATPortForwardStmt, NATPortForward{ID: p.Value}).Get(&outcome)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+func (db *Database) ListNATPortForwardsByDataNetwork(ctx context.Context, dataNetworkID string) ([]NATPortForward, error) {
+	return db.listNATPortForwards(ctx, db.listNATPortForwardsByDNStmt, NATPortForward{DataNetworkID: dataNetworkID})
+}
+
+func (db *Database) ListAllNATPortForwards(ctx context.Context) ([]NATPortForward, error) {
+	return db.listNATPortForwards(ctx, db.listAllNATPortForwardsStmt)
+}
+
+func (db *Database) listNATPortForwards(ctx context.Context, stmt *sqlair.Statement, params ...any) ([]NATPortForward, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NATPortForwardsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NATPortForwardsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkNATSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []NATPortForward{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NATPortForwardsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NATPortForwardsTableName, "select").Inc()
+
+	var rows []NATPortForward
+
+	err := db.conn().Query(ctx, stmt, params...).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []NATPortForward{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkNATEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	dn := &db.DataNetwork{Name: "enterprise", IPv4Pool: "10.47.0.0/16", DNS: "8.8.8.8", MTU: 1400}
+
+	if err := database.CreateDataNetworkWithEgress(ctx, dn, nil); err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkNAT(ctx, dn.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before a pool is set, got %v", err)
+	}
+
+	nat := &db.DataNetworkNAT{
+		DataNetworkID: dn.ID,
+		Addresses:     "203.0.113.10,203.0.113.11",
+		Mapping:       db.NATMappingRoundRobin,
+		PortMin:       20000,
+		PortMax:       29999,
+	}
+
+	if err := database.SetDataNetworkNAT(ctx, nat); err != nil {
+		t.Fatalf("couldn't set NAT pool: %s", err)
+	}
+
+	got, err := database.GetDataNetworkNAT(ctx, dn.ID)
+	if err != nil {
+		t.Fatalf("couldn't get NAT pool: %s", err)
+	}
+
+	if *got != *nat {
+		t.Fatalf("unexpected NAT pool: %+v", got)
+	}
+
+	if addrs := got.AddressList(); len(addrs) != 2 || addrs[1] != "203.0.113.11" {
+		t.Fatalf("unexpected address list: %v", addrs)
+	}
+
+	fwd := &db.NATPortForward{
+		DataNetworkID: dn.ID,
+		Protocol:      "tcp",
+		PublicAddress: "203.0.113.10",
+		PublicPort:    8080,
+		IMSI:          "001010100007487",
+		UEPort:        80,
+	}
+
+	if err := database.CreateNATPortForward(ctx, fwd); err != nil {
+		t.Fatalf("couldn't create port forward: %s", err)
+	}
+
+	if fwd.ID == "" {
+		t.Fatal("expected port forward id to be set")
+	}
+
+	dup := *fwd
+	dup.ID = ""
+	dup.IMSI = "001010100007488"
+
+	if err := database.CreateNATPortForward(ctx, &dup); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a reused public port, got %v", err)
+	}
+
+	forwards, err := database.ListNATPortForwardsByDataNetwork(ctx, dn.ID)
+	if err != nil {
+		t.Fatalf("couldn't list port forwards: %s", err)
+	}
+
+	if len(forwards) != 1 || forwards[0] != *fwd {
+		t.Fatalf("unexpected port forwards: %+v", forwards)
+	}
+
+	// Clearing the addresses removes the pool.
+	if err := database.SetDataNetworkNAT(ctx, &db.DataNetworkNAT{DataNetworkID: dn.ID}); err != nil {
+		t.Fatalf("couldn't clear NAT pool: %s", err)
+	}
+
+	all, err := database.ListAllDataNetworkNAT(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list NAT pools: %s", err)
+	}
+
+	if len(all) != 0 {
+		t.Fatalf("expected no NAT pools after clearing, got %+v", all)
+	}
+
+	if err := database.DeleteNATPortForward(ctx, fwd.ID); err != nil {
+		t.Fatalf("couldn't delete port forward: %s", err)
+	}
+
+	if err := database.DeleteNATPortForward(ctx, fwd.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestNATPortForwardsDeletedWithDataNetwork(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	dn := &db.DataNetwork{Name: "iot", IPv4Pool: "10.46.0.0/24", DNS: "8.8.8.8", MTU: 1400}
+
+	if err := database.CreateDataNetworkWithEgress(ctx, dn, nil); err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if err := database.SetDataNetworkNAT(ctx, &db.DataNetworkNAT{DataNetworkID: dn.ID, Addresses: "203.0.113.20", Mapping: db.NATMappingDeterministic}); err != nil {
+		t.Fatalf("couldn't set NAT pool: %s", err)
+	}
+
+	if err := database.CreateNATPortForward(ctx, &db.NATPortForward{DataNetworkID: dn.ID, Protocol: "udp", PublicAddress: "203.0.113.20", PublicPort: 5000, UEAddress: "10.46.0.7", UEPort: 5000}); err != nil {
+		t.Fatalf("couldn't create port forward: %s", err)
+	}
+
+	if err := database.DeleteDataNetwork(ctx, "iot"); err != nil {
+		t.Fatalf("couldn't delete data network: %s", err)
+	}
+
+	pools, err := database.ListAllDataNetworkNAT(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list NAT pools: %s", err)
+	}
+
+	forwards, err := database.ListAllNATPortForwards(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list port forwards: %s", err)
+	}
+
+	if len(pools) != 0 || len(forwards) != 0 {
+		t.Fatalf("expected NAT config to be deleted with its data network, got %+v and %+v", pools, forwards)
+	}
+}
@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DataNetworkOnlineChargingTableName = "data_network_online_charging"
+
+// onlineChargingSchema is the migration that introduced the table. Reads
+// below it report no online charging, so sessions are not held to credit.
+const onlineChargingSchema = 31
+
+const (
+	upsertDataNetworkOnlineChargingStmt = "INSERT INTO %s (dataNetworkID, protocol, server, realm, ratingGroup, failureHandling) VALUES ($DataNetworkOnlineCharging.dataNetworkID, $DataNetworkOnlineCharging.protocol, $DataNetworkOnlineCharging.server, $DataNetworkOnlineCharging.realm, $DataNetworkOnlineCharging.ratingGroup, $DataNetworkOnlineCharging.failureHandling) ON CONFLICT(dataNetworkID) DO UPDATE SET protocol=excluded.protocol, server=excluded.server, realm=excluded.realm, ratingGroup=excluded.ratingGroup, failureHandling=excluded.failureHandling"
+	deleteDataNetworkOnlineChargingStmt = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkOnlineCharging.dataNetworkID"
+	getDataNetworkOnlineChargingStmt    = "SELECT &DataNetworkOnlineCharging.* FROM %s WHERE dataNetworkID==$DataNetworkOnlineCharging.dataNetworkID"
+)
+
+// Online charging protocols.
+const (
+	OnlineChargingDiameter = "diameter" // Diameter Gy (RFC 4006, TS 32.299)
+	OnlineChargingNchf     = "nchf"     // Nchf_ConvergedCharging (TS 32.291)
+)
+
+// What sessions do when the online charging system cannot be reached.
+const (
+	OnlineChargingTerminate = "terminate"
+	OnlineChargingContinue  = "continue"
+)
+
+// DataNetworkOnlineCharging makes sessions on a data network ask an online
+// charging system for credit before and while passing traffic. Server is a
+// Diameter peer as host:port, or the Nchf API root as a URL. Realm is the
+// Destination-Realm for Diameter. RatingGroup charges the traffic that no
+// policy rule rates on its own. FailureHandling says whether sessions end
+// or carry on unmetered when the charging system does not answer.
+type DataNetworkOnlineCharging struct {
+	DataNetworkID   string `db:"dataNetworkID"` // FK to data_networks.id
+	Protocol        string `db:"protocol"`
+	Server          string `db:"server"`
+	Realm           string `db:"realm"`
+	RatingGroup     int64  `db:"ratingGroup"`
+	FailureHandling string `db:"failureHandling"`
+}
+
+// SetDataNetworkOnlineCharging turns online charging on for a data network,
+// or changes its settings. Sessions pick up the change when they next start.
+func (db *Database) SetDataNetworkOnlineCharging(ctx context.Context, auth *DataNetworkOnlineCharging) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", DataNetworkOnlineChargingTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", DataNetworkOnlineChargingTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkOnlineChargingTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkOnlineChargingTableName, "upsert").Inc()
+
+	_, err := opSetDataNetworkOnlineCharging.Invoke(db, auth)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetDataNetworkOnlineCharging(ctx context.Context, auth *DataNetworkOnlineCharging) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkOnlineChargingStmt, auth).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearDataNetworkOnlineCharging turns online charging off for a data
+// network. Clearing a data network without it is not an error.
+func (db *Database) ClearDataNetworkOnlineCharging(ctx context.Context, dataNetworkID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", DataNetworkOnlineChargingTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", DataNetworkOnlineChargingTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkOnlineChargingTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkOnlineChargingTableName, "delete").Inc()
+
+	_, err := opClearDataNetworkOnlineCharging.Invoke(db, &DataNetworkOnlineCharging{DataNetworkID: dataNetworkID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearDataNetworkOnlineCharging(ctx context.Context, auth *DataNetworkOnlineCharging) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkOnlineChargingStmt, auth).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDataNetworkOnlineCharging returns ErrNotFound when the data network does
+// not use online charging.
+func (db *Database) GetDataNetworkOnlineCharging(ctx context.Context, dataNetworkID string) (*DataNetworkOnlineCharging, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkOnlineChargingTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkOnlineChargingTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(onlineChargingSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkOnlineChargingTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkOnlineChargingTableName, "select").Inc()
+
+	row := DataNetworkOnlineCharging{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkOnlineChargingStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
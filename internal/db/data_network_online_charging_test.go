@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkOnlineChargingEndToEnd(t *testing.T) {
+	database, dnID, _ := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	if _, err := database.GetDataNetworkOnlineCharging(ctx, dnID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before online charging is set, got %v", err)
+	}
+
+	oc := &db.DataNetworkOnlineCharging{
+		DataNetworkID:   dnID,
+		Protocol:        db.OnlineChargingDiameter,
+		Server:          "10.100.0.2:3868",
+		Realm:           "ocs.example.org",
+		RatingGroup:     1,
+		FailureHandling: db.OnlineChargingTerminate,
+	}
+
+	if err := database.SetDataNetworkOnlineCharging(ctx, oc); err != nil {
+		t.Fatalf("couldn't set online charging: %s", err)
+	}
+
+	oc.Protocol = db.OnlineChargingNchf
+	oc.Server = "https://chf.example.org"
+	oc.Realm = ""
+	oc.FailureHandling = db.OnlineChargingContinue
+
+	if err := database.SetDataNetworkOnlineCharging(ctx, oc); err != nil {
+		t.Fatalf("couldn't update online charging: %s", err)
+	}
+
+	got, err := database.GetDataNetworkOnlineCharging(ctx, dnID)
+	if err != nil {
+		t.Fatalf("couldn't get online charging: %s", err)
+	}
+
+	if *got != *oc {
+		t.Fatalf("online charging = %+v, want %+v", got, oc)
+	}
+
+	bad := *oc
+	bad.RatingGroup = 0
+
+	if err := database.SetDataNetworkOnlineCharging(ctx, &bad); err == nil {
+		t.Fatal("expected a zero rating group to be rejected")
+	}
+
+	if err := database.ClearDataNetworkOnlineCharging(ctx, dnID); err != nil {
+		t.Fatalf("couldn't clear online charging: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkOnlineCharging(ctx, dnID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+}
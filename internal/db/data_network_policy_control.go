@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DataNetworkPolicyControlTableName = "data_network_policy_control"
+
+// policyControlSchema is the migration that introduced the table. Reads
+// below it report no policy control, so sessions take the local policy.
+const policyControlSchema = 32
+
+const (
+	upsertDataNetworkPolicyControlStmt = "INSERT INTO %s (dataNetworkID, url) VALUES ($DataNetworkPolicyControl.dataNetworkID, $DataNetworkPolicyControl.url) ON CONFLICT(dataNetworkID) DO UPDATE SET url=excluded.url"
+	deleteDataNetworkPolicyControlStmt = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkPolicyControl.dataNetworkID"
+	getDataNetworkPolicyControlStmt    = "SELECT &DataNetworkPolicyControl.* FROM %s WHERE dataNetworkID==$DataNetworkPolicyControl.dataNetworkID"
+)
+
+// DataNetworkPolicyControl makes the SMF ask an external policy decision
+// point for the QoS and rules of sessions on a data network. URL is the API
+// root of its Npcf_SMPolicyControl service.
+type DataNetworkPolicyControl struct {
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	URL           string `db:"url"`
+}
+
+// SetDataNetworkPolicyControl turns external policy control on for a data
+// network, or changes its endpoint. Sessions pick up the change when they
+// next start.
+func (db *Database) SetDataNetworkPolicyControl(ctx context.Context, pc *DataNetworkPolicyControl) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", DataNetworkPolicyControlTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", DataNetworkPolicyControlTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkPolicyControlTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkPolicyControlTableName, "upsert").Inc()
+
+	_, err := opSetDataNetworkPolicyControl.Invoke(db, pc)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetDataNetworkPolicyControl(ctx context.Context, pc *DataNetworkPolicyControl) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkPolicyControlStmt, pc).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearDataNetworkPolicyControl turns external policy control off for a data
+// network. Clearing a data network without it is not an error.
+func (db *Database) ClearDataNetworkPolicyControl(ctx context.Context, dataNetworkID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", DataNetworkPolicyControlTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", DataNetworkPolicyControlTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkPolicyControlTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkPolicyControlTableName, "delete").Inc()
+
+	_, err := opClearDataNetworkPolicyControl.Invoke(db, &DataNetworkPolicyControl{DataNetworkID: dataNetworkID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearDataNetworkPolicyControl(ctx context.Context, pc *DataNetworkPolicyControl) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkPolicyControlStmt, pc).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDataNetworkPolicyControl returns ErrNotFound when the data network does
+// not use external policy control.
+func (db *Database) GetDataNetworkPolicyControl(ctx context.Context, dataNetworkID string) (*DataNetworkPolicyControl, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkPolicyControlTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkPolicyControlTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(policyControlSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkPolicyControlTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkPolicyControlTableName, "select").Inc()
+
+	row := DataNetworkPolicyControl{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkPolicyControlStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkPolicyControlEndToEnd(t *testing.T) {
+	database, dnID, _ := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	if _, err := database.GetDataNetworkPolicyControl(ctx, dnID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before policy control is set, got %v", err)
+	}
+
+	pc := &db.DataNetworkPolicyControl{DataNetworkID: dnID, URL: "http://pcf.example.org:8080"}
+
+	if err := database.SetDataNetworkPolicyControl(ctx, pc); err != nil {
+		t.Fatalf("couldn't set policy control: %s", err)
+	}
+
+	pc.URL = "https://pcf.example.org"
+
+	if err := database.SetDataNetworkPolicyControl(ctx, pc); err != nil {
+		t.Fatalf("couldn't update policy control: %s", err)
+	}
+
+	got, err := database.GetDataNetworkPolicyControl(ctx, dnID)
+	if err != nil {
+		t.Fatalf("couldn't get policy control: %s", err)
+	}
+
+	if *got != *pc {
+		t.Fatalf("policy control = %+v, want %+v", got, pc)
+	}
+
+	if err := database.ClearDataNetworkPolicyControl(ctx, dnID); err != nil {
+		t.Fatalf("couldn't clear policy control: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkPolicyControl(ctx, dnID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+}
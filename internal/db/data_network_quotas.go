@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	upsertDataNetworkQuotaStmt  = "INSERT INTO %s (dataNetworkID, maxSessions, backOffTimer) VALUES ($DataNetworkQuota.dataNetworkID, $DataNetworkQuota.maxSessions, $DataNetworkQuota.backOffTimer) ON CONFLICT(dataNetworkID) DO UPDATE SET maxSessions=excluded.maxSessions, backOffTimer=excluded.backOffTimer"
+	deleteDataNetworkQuotaStmt  = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkQuota.dataNetworkID"
+	getDataNetworkQuotaStmt     = "SELECT &DataNetworkQuota.* FROM %s WHERE dataNetworkID==$DataNetworkQuota.dataNetworkID"
+	listAllDataNetworkQuotaStmt = "SELECT &DataNetworkQuota.* FROM %s ORDER BY dataNetworkID"
+)
+
+// DataNetworkQuota caps the sessions open on a data network, across every
+// slice serving it. A zero limit is no limit. BackOffTimer, in seconds, is
+// how long a UE refused by the quota waits before trying again; zero means
+// DefaultQuotaBackOff.
+type DataNetworkQuota struct {
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	MaxSessions   int    `db:"maxSessions"`
+	BackOffTimer  int    `db:"backOffTimer"`
+}
+
+// BackOff is the back-off timer of a reject the quota causes.
+func (q *DataNetworkQuota) BackOff() time.Duration {
+	return backOff(q.BackOffTimer)
+}
+
+// SetDataNetworkQuota sets a data network's quota, replacing any previous
+// one.
+func (db *Database) SetDataNetworkQuota(ctx context.Context, quota *DataNetworkQuota) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", DataNetworkQuotasTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", DataNetworkQuotasTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkQuotasTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkQuotasTableName, "upsert").Inc()
+
+	_, err := opSetDataNetworkQuota.Invoke(db, quota)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetDataNetworkQuota(ctx context.Context, quota *DataNetworkQuota) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkQuotaStmt, quota).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearDataNetworkQuota lifts a data network's quota. Clearing a data
+// network without one is not an error.
+func (db *Database) ClearDataNetworkQuota(ctx context.Context, dataNetworkID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", DataNetworkQuotasTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", DataNetworkQuotasTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkQuotasTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkQuotasTableName, "delete").Inc()
+
+	_, err := opClearDataNetworkQuota.Invoke(db, &DataNetworkQuota{DataNetworkID: dataNetworkID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearDataNetworkQuota(ctx context.Context, quota *DataNetworkQuota) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkQuotaStmt, quota).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDataNetworkQuota returns ErrNotFound when the data network has no
+// quota.
+func (db *Database) GetDataNetworkQuota(ctx context.Context, dataNetworkID string) (*DataNetworkQuota, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkQuotasTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkQuotasTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkQuotasTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkQuotasTableName, "select").Inc()
+
+	row := DataNetworkQuota{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkQuotaStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+func (db *Database) ListAllDataNetworkQuotas(ctx context.Context) ([]DataNetworkQuota, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkQuotasTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkQuotasTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []DataNetworkQuota{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkQuotasTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkQuotasTableName, "select").Inc()
+
+	var rows []DataNetworkQuota
+
+	err := db.conn().Query(ctx, db.listAllDataNetworkQuotaStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []DataNetworkQuota{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkQuotaEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	dn := &db.DataNetwork{Name: "iot", IPv4Pool: "10.48.0.0/16", DNS: "8.8.8.8", MTU: 1400}
+
+	if err := database.CreateDataNetworkWithEgress(ctx, dn, nil); err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkQuota(ctx, dn.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before a quota is set, got %v", err)
+	}
+
+	if err := database.SetDataNetworkQuota(ctx, &db.DataNetworkQuota{DataNetworkID: dn.ID, MaxSessions: 500, BackOffTimer: 120}); err != nil {
+		t.Fatalf("couldn't set quota: %s", err)
+	}
+
+	got, err := database.GetDataNetworkQuota(ctx, dn.ID)
+	if err != nil {
+		t.Fatalf("couldn't get quota: %s", err)
+	}
+
+	if got.MaxSessions != 500 || got.BackOffTimer != 120 {
+		t.Fatalf("unexpected quota %+v", got)
+	}
+
+	if err := database.ClearDataNetworkQuota(ctx, dn.ID); err != nil {
+		t.Fatalf("couldn't clear quota: %s", err)
+	}
+
+	if err := database.ClearDataNetworkQuota(ctx, dn.ID); err != nil {
+		t.Fatalf("clearing a data network without a quota should not fail: %s", err)
+	}
+
+	if err := database.SetDataNetworkQuota(ctx, &db.DataNetworkQuota{DataNetworkID: dn.ID, MaxSessions: 1}); err != nil {
+		t.Fatalf("couldn't set quota: %s", err)
+	}
+
+	if err := database.DeleteDataNetwork(ctx, "iot"); err != nil {
+		t.Fatalf("couldn't delete data network: %s", err)
+	}
+
+	rows, err := database.ListAllDataNetworkQuotas(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list quotas: %s", err)
+	}
+
+	if len(rows) != 0 {
+		t.Fatalf("expected the quota to be deleted with its data network, got %+v", rows)
+	}
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DataNetworkSecondaryAuthTableName = "data_network_secondary_auth"
+
+// secondaryAuthSchema is the migration that introduced the table. Reads
+// below it report no secondary authentication, so sessions are set up on
+// primary authentication alone.
+const secondaryAuthSchema = 29
+
+const (
+	upsertDataNetworkSecondaryAuthStmt = "INSERT INTO %s (dataNetworkID, server, secret) VALUES ($DataNetworkSecondaryAuth.dataNetworkID, $DataNetworkSecondaryAuth.server, $DataNetworkSecondaryAuth.secret) ON CONFLICT(dataNetworkID) DO UPDATE SET server=excluded.server, secret=excluded.secret"
+	deleteDataNetworkSecondaryAuthStmt = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkSecondaryAuth.dataNetworkID"
+	getDataNetworkSecondaryAuthStmt    = "SELECT &DataNetworkSecondaryAuth.* FROM %s WHERE dataNetworkID==$DataNetworkSecondaryAuth.dataNetworkID"
+)
+
+// DataNetworkSecondaryAuth makes a data network authenticate users with EAP
+// against a DN-AAA RADIUS server (TS 23.501 §5.6.6). Server is the RADIUS
+// authentication server and Secret its shared secret.
+type DataNetworkSecondaryAuth struct {
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	Server        string `db:"server"`
+	Secret        string `db:"secret"`
+}
+
+// SetDataNetworkSecondaryAuth turns secondary authentication on for a data
+// network, or changes its server.
+func (db *Database) SetDataNetworkSecondaryAuth(ctx context.Context, auth *DataNetworkSecondaryAuth) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", DataNetworkSecondaryAuthTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", DataNetworkSecondaryAuthTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkSecondaryAuthTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkSecondaryAuthTableName, "upsert").Inc()
+
+	_, err := opSetDataNetworkSecondaryAuth.Invoke(db, auth)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetDataNetworkSecondaryAuth(ctx context.Context, auth *DataNetworkSecondaryAuth) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkSecondaryAuthStmt, auth).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearDataNetworkSecondaryAuth turns secondary authentication off for a
+// data network. Sessions already authenticated are kept. Clearing a data
+// network without it is not an error.
+func (db *Database) ClearDataNetworkSecondaryAuth(ctx context.Context, dataNetworkID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", DataNetworkSecondaryAuthTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", DataNetworkSecondaryAuthTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkSecondaryAuthTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkSecondaryAuthTableName, "delete").Inc()
+
+	_, err := opClearDataNetworkSecondaryAuth.Invoke(db, &DataNetworkSecondaryAuth{DataNetworkID: dataNetworkID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearDataNetworkSecondaryAuth(ctx context.Context, auth *DataNetworkSecondaryAuth) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkSecondaryAuthStmt, auth).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDataNetworkSecondaryAuth returns ErrNotFound when the data network does
+// not use secondary authentication.
+func (db *Database) GetDataNetworkSecondaryAuth(ctx context.Context, dataNetworkID string) (*DataNetworkSecondaryAuth, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkSecondaryAuthTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkSecondaryAuthTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(secondaryAuthSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkSecondaryAuthTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkSecondaryAuthTableName, "select").Inc()
+
+	row := DataNetworkSecondaryAuth{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkSecondaryAuthStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkSecondaryAuthEndToEnd(t *testing.T) {
+	database, dnID, _ := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	if _, err := database.GetDataNetworkSecondaryAuth(ctx, dnID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before secondary authentication is set, got %v", err)
+	}
+
+	auth := &db.DataNetworkSecondaryAuth{DataNetworkID: dnID, Server: "10.100.0.2:1812", Secret: "testing123"}
+
+	if err := database.SetDataNetworkSecondaryAuth(ctx, auth); err != nil {
+		t.Fatalf("couldn't set secondary authentication: %s", err)
+	}
+
+	auth.Server = "10.100.0.3:1812"
+
+	if err := database.SetDataNetworkSecondaryAuth(ctx, auth); err != nil {
+		t.Fatalf("couldn't update secondary authentication: %s", err)
+	}
+
+	got, err := database.GetDataNetworkSecondaryAuth(ctx, dnID)
+	if err != nil {
+		t.Fatalf("couldn't get secondary authentication: %s", err)
+	}
+
+	if *got != *auth {
+		t.Fatalf("secondary authentication = %+v, want %+v", got, auth)
+	}
+
+	if err := database.ClearDataNetworkSecondaryAuth(ctx, dnID); err != nil {
+		t.Fatalf("couldn't clear secondary authentication: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkSecondaryAuth(ctx, dnID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+
+	if err := database.ClearDataNetworkSecondaryAuth(ctx, dnID); err != nil {
+		t.Fatalf("clearing twice should not fail: %s", err)
+	}
+}
@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DataNetworkTCPMSSTableName = "data_network_tcp_mss"
+
+// dataNetworkTCPMSSSchema is the migration that introduced the table. Reads
+// below it report clamping off.
+const dataNetworkTCPMSSSchema = 24
+
+const (
+	upsertDataNetworkTCPMSSStmt  = "INSERT INTO %s (dataNetworkID, mss) VALUES ($DataNetworkTCPMSS.dataNetworkID, $DataNetworkTCPMSS.mss) ON CONFLICT(dataNetworkID) DO UPDATE SET mss=excluded.mss"
+	deleteDataNetworkTCPMSSStmt  = "DELETE FROM %s WHERE dataNetworkID==$DataNetworkTCPMSS.dataNetworkID"
+	getDataNetworkTCPMSSStmt     = "SELECT &DataNetworkTCPMSS.* FROM %s WHERE dataNetworkID==$DataNetworkTCPMSS.dataNetworkID"
+	listAllDataNetworkTCPMSSStmt = "SELECT &DataNetworkTCPMSS.* FROM %s ORDER BY dataNetworkID"
+)
+
+// DataNetworkTCPMSS turns on TCP MSS clamping for a data network. A non-zero
+// MSS is the limit; zero derives it from the data network's MTU less the
+// GTP-U and inner headers.
+type DataNetworkTCPMSS struct {
+	DataNetworkID string `db:"dataNetworkID"` // FK to data_networks.id
+	MSS           int    `db:"mss"`
+}
+
+// SetDataNetworkTCPMSS turns on clamping for a data network, or changes its
+// limit.
+func (db *Database) SetDataNetworkTCPMSS(ctx context.Context, clamp *DataNetworkTCPMSS) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", DataNetworkTCPMSSTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", DataNetworkTCPMSSTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkTCPMSSTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkTCPMSSTableName, "upsert").Inc()
+
+	_, err := opSetDataNetworkTCPMSS.Invoke(db, clamp)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetDataNetworkTCPMSS(ctx context.Context, clamp *DataNetworkTCPMSS) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertDataNetworkTCPMSSStmt, clamp).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearDataNetworkTCPMSS turns clamping off for a data network. Clearing a
+// data network without it is not an error.
+func (db *Database) ClearDataNetworkTCPMSS(ctx context.Context, dataNetworkID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", DataNetworkTCPMSSTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", DataNetworkTCPMSSTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkTCPMSSTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkTCPMSSTableName, "delete").Inc()
+
+	_, err := opClearDataNetworkTCPMSS.Invoke(db, &DataNetworkTCPMSS{DataNetworkID: dataNetworkID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearDataNetworkTCPMSS(ctx context.Context, clamp *DataNetworkTCPMSS) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteDataNetworkTCPMSSStmt, clamp).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetDataNetworkTCPMSS returns ErrNotFound when clamping is off for the data
+// network.
+func (db *Database) GetDataNetworkTCPMSS(ctx context.Context, dataNetworkID string) (*DataNetworkTCPMSS, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkTCPMSSTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkTCPMSSTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkTCPMSSSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkTCPMSSTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkTCPMSSTableName, "select").Inc()
+
+	row := DataNetworkTCPMSS{DataNetworkID: dataNetworkID}
+
+	err := db.conn().Query(ctx, db.getDataNetworkTCPMSSStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+func (db *Database) ListAllDataNetworkTCPMSS(ctx context.Context) ([]DataNetworkTCPMSS, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DataNetworkTCPMSSTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DataNetworkTCPMSSTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(dataNetworkTCPMSSSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []DataNetworkTCPMSS{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DataNetworkTCPMSSTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DataNetworkTCPMSSTableName, "select").Inc()
+
+	var rows []DataNetworkTCPMSS
+
+	err := db.conn().Query(ctx, db.listAllDataNetworkTCPMSSStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []DataNetworkTCPMSS{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
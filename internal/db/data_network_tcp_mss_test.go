@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestDataNetworkTCPMSSEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	dn := &db.DataNetwork{Name: "cpe", IPv4Pool: "10.48.0.0/16", DNS: "8.8.8.8", MTU: 1400}
+
+	if err := database.CreateDataNetworkWithEgress(ctx, dn, nil); err != nil {
+		t.Fatalf("couldn't create data network: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkTCPMSS(ctx, dn.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before clamping is turned on, got %v", err)
+	}
+
+	if err := database.SetDataNetworkTCPMSS(ctx, &db.DataNetworkTCPMSS{DataNetworkID: dn.ID}); err != nil {
+		t.Fatalf("couldn't turn on clamping: %s", err)
+	}
+
+	if err := database.SetDataNetworkTCPMSS(ctx, &db.DataNetworkTCPMSS{DataNetworkID: dn.ID, MSS: 1200}); err != nil {
+		t.Fatalf("couldn't change the MSS: %s", err)
+	}
+
+	got, err := database.GetDataNetworkTCPMSS(ctx, dn.ID)
+	if err != nil {
+		t.Fatalf("couldn't get clamp: %s", err)
+	}
+
+	if got.MSS != 1200 {
+		t.Fatalf("mss = %d, want 1200", got.MSS)
+	}
+
+	if err := database.ClearDataNetworkTCPMSS(ctx, dn.ID); err != nil {
+		t.Fatalf("couldn't turn off clamping: %s", err)
+	}
+
+	if _, err := database.GetDataNetworkTCPMSS(ctx, dn.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+
+	if err := database.SetDataNetworkTCPMSS(ctx, &db.DataNetworkTCPMSS{DataNetworkID: dn.ID}); err != nil {
+		t.Fatalf("couldn't turn on clamping: %s", err)
+	}
+
+	if err := database.DeleteDataNetwork(ctx, "cpe"); err != nil {
+		t.Fatalf("couldn't delete data network: %s", err)
+	}
+
+	rows, err := database.ListAllDataNetworkTCPMSS(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list clamps: %s", err)
+	}
+
+	if len(rows) != 0 {
+		t.Fatalf("expected the clamp to be deleted with its data network, got %+v", rows)
+	}
+}
@@ -56,6 +56,7 @@ type Database struct {
 	// Subscriber statements
 	listSubscribersStmt         *sqlair.Statement
 	listSubscribersByDNStmt     *sqlair.Statement
+	listSubscribersAfterStmt    *sqlair.Statement
 	countSubscribersStmt        *sqlair.Statement
 	getSubscriberStmt           *sqlair.Statement
 	createSubscriberStmt        *sqlair.Statement
@@ -193,6 +194,7 @@ type Database struct {
 	// Policies statements
 	listPoliciesStmt      *sqlair.Statement
 	getPolicyStmt         *sqlair.Statement
+	getPolicyByIDStmt     *sqlair.Statement
 	getPolicyByLookupStmt *sqlair.Statement
 
 	getPolicyByProfileAndSliceStmt *sqlair.Statement
@@ -245,6 +247,183 @@ type Database struct {
 	listFramedRoutesByDNStmt     *sqlair.Statement
 	listAllFramedRoutesStmt      *sqlair.Statement
 
+	// Data Network Egress statements
+	upsertDataNetworkEgressStmt  *sqlair.Statement
+	deleteDataNetworkEgressStmt  *sqlair.Statement
+	getDataNetworkEgressStmt     *sqlair.Statement
+	listAllDataNetworkEgressStmt *sqlair.Statement
+
+	// Data Network NAT statements
+	upsertDataNetworkNATStmt    *sqlair.Statement
+	deleteDataNetworkNATStmt    *sqlair.Statement
+	getDataNetworkNATStmt       *sqlair.Statement
+	listAllDataNetworkNATStmt   *sqlair.Statement
+	createNATPortForwardStmt    *sqlair.Statement
+	getNATPortForwardStmt       *sqlair.Statement
+	deleteNATPortForwardStmt    *sqlair.Statement
+	listNATPortForwardsByDNStmt *sqlair.Statement
+	listAllNATPortForwardsStmt  *sqlair.Statement
+
+	// Data Network TCP MSS statements
+	upsertDataNetworkTCPMSSStmt  *sqlair.Statement
+	deleteDataNetworkTCPMSSStmt  *sqlair.Statement
+	getDataNetworkTCPMSSStmt     *sqlair.Statement
+	listAllDataNetworkTCPMSSStmt *sqlair.Statement
+
+	// Data Network DNS statements
+	upsertDataNetworkDNSResolverStmt   *sqlair.Statement
+	deleteDataNetworkDNSResolverStmt   *sqlair.Statement
+	getDataNetworkDNSResolverStmt      *sqlair.Statement
+	listAllDataNetworkDNSResolversStmt *sqlair.Statement
+	createDNSLocalRecordStmt           *sqlair.Statement
+	getDNSLocalRecordStmt              *sqlair.Statement
+	deleteDNSLocalRecordStmt           *sqlair.Statement
+	listDNSLocalRecordsByDNStmt        *sqlair.Statement
+	listAllDNSLocalRecordsStmt         *sqlair.Statement
+
+	// External address allocation statements
+	upsertDataNetworkAddressAllocationStmt *sqlair.Statement
+	deleteDataNetworkAddressAllocationStmt *sqlair.Statement
+	getDataNetworkAddressAllocationStmt    *sqlair.Statement
+	createExternalIPLeaseStmt              *sqlair.Statement
+	getExternalIPLeaseStmt                 *sqlair.Statement
+	updateExternalIPLeaseTimersStmt        *sqlair.Statement
+	listExternalIPLeasesByNodeStmt         *sqlair.Statement
+	getLeaseByAddressStmt                  *sqlair.Statement
+
+	// Secondary authentication statements
+	upsertDataNetworkSecondaryAuthStmt *sqlair.Statement
+	deleteDataNetworkSecondaryAuthStmt *sqlair.Statement
+	getDataNetworkSecondaryAuthStmt    *sqlair.Statement
+
+	upsertDataNetworkOnlineChargingStmt *sqlair.Statement
+	deleteDataNetworkOnlineChargingStmt *sqlair.Statement
+	getDataNetworkOnlineChargingStmt    *sqlair.Statement
+
+	upsertDataNetworkPolicyControlStmt   *sqlair.Statement
+	deleteDataNetworkPolicyControlStmt   *sqlair.Statement
+	getDataNetworkPolicyControlStmt      *sqlair.Statement
+	insertSMPolicyAssociationStmt        *sqlair.Statement
+	updateSMPolicyDecisionStmt           *sqlair.Statement
+	deleteSMPolicyAssociationStmt        *sqlair.Statement
+	deleteSMPolicyAssociationsByNodeStmt *sqlair.Statement
+	getSMPolicyAssociationStmt           *sqlair.Statement
+	listSMPolicyAssociationsByNodeStmt   *sqlair.Statement
+	listAllSMPolicyAssociationsStmt      *sqlair.Statement
+
+	insertQosSessionStmt       *sqlair.Statement
+	claimQosSessionStmt        *sqlair.Statement
+	updateQosSessionStatusStmt *sqlair.Statement
+	updateQosSessionPolicyStmt *sqlair.Statement
+	deleteQosSessionStmt       *sqlair.Statement
+	getQosSessionStmt          *sqlair.Statement
+	listQosSessionsStmt        *sqlair.Statement
+
+	insertMonitoringSubscriptionStmt         *sqlair.Statement
+	deleteMonitoringSubscriptionStmt         *sqlair.Statement
+	countMonitoringReportStmt                *sqlair.Statement
+	deleteSpentMonitoringSubscriptionStmt    *sqlair.Statement
+	deleteExpiredMonitoringSubscriptionsStmt *sqlair.Statement
+	getMonitoringSubscriptionStmt            *sqlair.Statement
+	listMonitoringSubscriptionsStmt          *sqlair.Statement
+
+	upsertSubscriberAmbrOverrideStmt         *sqlair.Statement
+	deleteSubscriberAmbrOverrideStmt         *sqlair.Statement
+	deleteExpiredSubscriberAmbrOverridesStmt *sqlair.Statement
+	getSubscriberAmbrOverrideStmt            *sqlair.Statement
+	listSubscriberAmbrOverridesStmt          *sqlair.Statement
+
+	upsertNetworkSliceQuotaStmt  *sqlair.Statement
+	deleteNetworkSliceQuotaStmt  *sqlair.Statement
+	getNetworkSliceQuotaStmt     *sqlair.Statement
+	listAllNetworkSliceQuotaStmt *sqlair.Statement
+	upsertDataNetworkQuotaStmt   *sqlair.Statement
+	deleteDataNetworkQuotaStmt   *sqlair.Statement
+	getDataNetworkQuotaStmt      *sqlair.Statement
+	listAllDataNetworkQuotaStmt  *sqlair.Statement
+
+	upsertSubscriberLifecycleStmt  *sqlair.Statement
+	deleteSubscriberLifecycleStmt  *sqlair.Statement
+	expireSubscriberLifecyclesStmt *sqlair.Statement
+	getSubscriberLifecycleStmt     *sqlair.Statement
+	listSubscriberLifecyclesStmt   *sqlair.Statement
+
+	createPolicyLocationVariantStmt        *sqlair.Statement
+	getPolicyLocationVariantStmt           *sqlair.Statement
+	deletePolicyLocationVariantStmt        *sqlair.Statement
+	listPolicyLocationVariantsByPolicyStmt *sqlair.Statement
+
+	insertSliceRegistrationStmt            *sqlair.Statement
+	deleteSliceRegistrationsStmt           *sqlair.Statement
+	releaseSliceRegistrationsStmt          *sqlair.Statement
+	releaseSliceRegistrationStmt           *sqlair.Statement
+	deleteSliceRegistrationsByNodeStmt     *sqlair.Statement
+	countSliceRegistrationsStmt            *sqlair.Statement
+	countHeldSliceRegistrationsStmt        *sqlair.Statement
+	listSliceRegistrationsByNodeStmt       *sqlair.Statement
+	insertAdmittedSessionStmt              *sqlair.Statement
+	getAdmittedSessionStmt                 *sqlair.Statement
+	deleteAdmittedSessionStmt              *sqlair.Statement
+	countHeldAdmittedSessionsStmt          *sqlair.Statement
+	deleteAdmittedSessionsByNodeStmt       *sqlair.Statement
+	countAdmittedSessionsBySubscriberStmt  *sqlair.Statement
+	countAdmittedSessionsBySliceStmt       *sqlair.Statement
+	countAdmittedSessionsByDataNetworkStmt *sqlair.Statement
+	listAdmittedSessionsByNodeStmt         *sqlair.Statement
+
+	// RADIUS accounting statements
+	createAccountingServerStmt          *sqlair.Statement
+	updateAccountingServerStmt          *sqlair.Statement
+	deleteAccountingServerStmt          *sqlair.Statement
+	getAccountingServerStmt             *sqlair.Statement
+	listAccountingServersStmt           *sqlair.Statement
+	insertAccountingRecordStmt          *sqlair.Statement
+	listAccountingRecordsStmt           *sqlair.Statement
+	deleteAccountingRecordStmt          *sqlair.Statement
+	deleteAccountingRecordsByServerStmt *sqlair.Statement
+	listAccountingRecordServersStmt     *sqlair.Statement
+	trimAccountingRecordsStmt           *sqlair.Statement
+
+	// Captive portal statements
+	upsertPolicyCaptivePortalStmt   *sqlair.Statement
+	deletePolicyCaptivePortalStmt   *sqlair.Statement
+	getPolicyCaptivePortalStmt      *sqlair.Statement
+	listAllPolicyCaptivePortalsStmt *sqlair.Statement
+	insertCaptivePortalLiftStmt     *sqlair.Statement
+	deleteCaptivePortalLiftStmt     *sqlair.Statement
+	getCaptivePortalLiftStmt        *sqlair.Statement
+	listAllCaptivePortalLiftsStmt   *sqlair.Statement
+
+	createNetworkRuleFQDNStmt        *sqlair.Statement
+	listNetworkRuleFQDNsByPolicyStmt *sqlair.Statement
+
+	createNetworkRuleRateLimitStmt        *sqlair.Statement
+	listNetworkRuleRateLimitsByPolicyStmt *sqlair.Statement
+
+	createNetworkRuleBreakoutStmt        *sqlair.Statement
+	listNetworkRuleBreakoutsByPolicyStmt *sqlair.Statement
+
+	createNetworkRuleRatingStmt        *sqlair.Statement
+	listNetworkRuleRatingsByPolicyStmt *sqlair.Statement
+	incrementRatingGroupUsageStmt      *sqlair.Statement
+	getUsagePerRatingGroupStmt         *sqlair.Statement
+	deleteAllRatingGroupUsageStmt      *sqlair.Statement
+	deleteOldRatingGroupUsageStmt      *sqlair.Statement
+
+	createScheduleStmt                   *sqlair.Statement
+	updateScheduleStmt                   *sqlair.Statement
+	deleteScheduleStmt                   *sqlair.Statement
+	getScheduleStmt                      *sqlair.Statement
+	getScheduleByIDStmt                  *sqlair.Statement
+	listSchedulesStmt                    *sqlair.Statement
+	countNetworkRuleSchedulesStmt        *sqlair.Statement
+	countPolicySchedulesStmt             *sqlair.Statement
+	createNetworkRuleScheduleStmt        *sqlair.Statement
+	listNetworkRuleSchedulesByPolicyStmt *sqlair.Statement
+	upsertPolicyScheduleStmt             *sqlair.Statement
+	deletePolicyScheduleStmt             *sqlair.Statement
+	getPolicyScheduleStmt                *sqlair.Statement
+
 	// Retention Policy statements
 	selectRetentionPolicyStmt *sqlair.Statement
 	upsertRetentionPolicyStmt *sqlair.Statement
@@ -1284,6 +1463,7 @@ func (db *Database) PrepareStatements() error {
 		// Subscribers
 		{&db.listSubscribersStmt, fmt.Sprintf(listSubscribersPagedStmt, SubscribersTableName), []any{ListArgs{}, Subscriber{}, NumItems{}}},
 		{&db.listSubscribersByDNStmt, fmt.Sprintf(listSubscribersByDNStmt, SubscribersTableName, PoliciesTableName), []any{ListArgs{}, Subscriber{}, NumItems{}, Policy{}}},
+		{&db.listSubscribersAfterStmt, fmt.Sprintf(listSubscribersAfterStmt, SubscribersTableName), []any{ListArgs{}, Subscriber{}}},
 		{&db.countSubscribersStmt, fmt.Sprintf(countSubscribersStmt, SubscribersTableName), []any{NumItems{}}},
 		{&db.getSubscriberStmt, fmt.Sprintf(getSubscriberStmt, SubscribersTableName), []any{Subscriber{}}},
 		{&db.createSubscriberStmt, fmt.Sprintf(createSubscriberStmt, SubscribersTableName), []any{Subscriber{}}},
@@ -1421,6 +1601,7 @@ func (db *Database) PrepareStatements() error {
 		// Policies
 		{&db.listPoliciesStmt, fmt.Sprintf(listPoliciesPagedStmt, PoliciesTableName), []any{ListArgs{}, Policy{}, NumItems{}}},
 		{&db.getPolicyStmt, fmt.Sprintf(getPolicyStmt, PoliciesTableName), []any{Policy{}}},
+		{&db.getPolicyByIDStmt, fmt.Sprintf(getPolicyByIDStmt, PoliciesTableName), []any{Policy{}}},
 		{&db.getPolicyByLookupStmt, fmt.Sprintf(getPolicyByLookupStmt, PoliciesTableName), []any{Policy{}}},
 		{&db.getPolicyByProfileAndSliceStmt, fmt.Sprintf(getPolicyByProfileAndSliceStmt, PoliciesTableName), []any{Policy{}}},
 		{&db.getDefaultPolicyByProfileStmt, fmt.Sprintf(getDefaultPolicyByProfileStmt, PoliciesTableName), []any{Policy{}}},
@@ -1472,6 +1653,157 @@ func (db *Database) PrepareStatements() error {
 		{&db.listFramedRoutesByDNStmt, fmt.Sprintf(listFramedRoutesByDNStmt, FramedRoutesTableName), []any{SubscriberFramedRoute{}}},
 		{&db.listAllFramedRoutesStmt, fmt.Sprintf(listAllFramedRoutesStmt, FramedRoutesTableName), []any{SubscriberFramedRoute{}}},
 
+		// Data Network Egress
+		{&db.upsertDataNetworkEgressStmt, fmt.Sprintf(upsertDataNetworkEgressStmt, DataNetworkEgressTableName), []any{DataNetworkEgress{}}},
+		{&db.deleteDataNetworkEgressStmt, fmt.Sprintf(deleteDataNetworkEgressStmt, DataNetworkEgressTableName), []any{DataNetworkEgress{}}},
+		{&db.getDataNetworkEgressStmt, fmt.Sprintf(getDataNetworkEgressStmt, DataNetworkEgressTableName), []any{DataNetworkEgress{}}},
+		{&db.listAllDataNetworkEgressStmt, fmt.Sprintf(listAllDataNetworkEgressStmt, DataNetworkEgressTableName), []any{DataNetworkEgress{}}},
+
+		// Data Network NAT
+		{&db.upsertDataNetworkNATStmt, fmt.Sprintf(upsertDataNetworkNATStmt, DataNetworkNATTableName), []any{DataNetworkNAT{}}},
+		{&db.deleteDataNetworkNATStmt, fmt.Sprintf(deleteDataNetworkNATStmt, DataNetworkNATTableName), []any{DataNetworkNAT{}}},
+		{&db.getDataNetworkNATStmt, fmt.Sprintf(getDataNetworkNATStmt, DataNetworkNATTableName), []any{DataNetworkNAT{}}},
+		{&db.listAllDataNetworkNATStmt, fmt.Sprintf(listAllDataNetworkNATStmt, DataNetworkNATTableName), []any{DataNetworkNAT{}}},
+		{&db.upsertDataNetworkTCPMSSStmt, fmt.Sprintf(upsertDataNetworkTCPMSSStmt, DataNetworkTCPMSSTableName), []any{DataNetworkTCPMSS{}}},
+		{&db.deleteDataNetworkTCPMSSStmt, fmt.Sprintf(deleteDataNetworkTCPMSSStmt, DataNetworkTCPMSSTableName), []any{DataNetworkTCPMSS{}}},
+		{&db.getDataNetworkTCPMSSStmt, fmt.Sprintf(getDataNetworkTCPMSSStmt, DataNetworkTCPMSSTableName), []any{DataNetworkTCPMSS{}}},
+		{&db.listAllDataNetworkTCPMSSStmt, fmt.Sprintf(listAllDataNetworkTCPMSSStmt, DataNetworkTCPMSSTableName), []any{DataNetworkTCPMSS{}}},
+		{&db.upsertDataNetworkDNSResolverStmt, fmt.Sprintf(upsertDataNetworkDNSResolverStmt, DataNetworkDNSResolversTableName), []any{DataNetworkDNSResolver{}}},
+		{&db.deleteDataNetworkDNSResolverStmt, fmt.Sprintf(deleteDataNetworkDNSResolverStmt, DataNetworkDNSResolversTableName), []any{DataNetworkDNSResolver{}}},
+		{&db.getDataNetworkDNSResolverStmt, fmt.Sprintf(getDataNetworkDNSResolverStmt, DataNetworkDNSResolversTableName), []any{DataNetworkDNSResolver{}}},
+		{&db.listAllDataNetworkDNSResolversStmt, fmt.Sprintf(listAllDataNetworkDNSResolversStmt, DataNetworkDNSResolversTableName), []any{DataNetworkDNSResolver{}}},
+		{&db.createDNSLocalRecordStmt, fmt.Sprintf(createDNSLocalRecordStmt, DNSLocalRecordsTableName), []any{DNSLocalRecord{}}},
+		{&db.getDNSLocalRecordStmt, fmt.Sprintf(getDNSLocalRecordStmt, DNSLocalRecordsTableName), []any{DNSLocalRecord{}}},
+		{&db.deleteDNSLocalRecordStmt, fmt.Sprintf(deleteDNSLocalRecordStmt, DNSLocalRecordsTableName), []any{DNSLocalRecord{}}},
+		{&db.listDNSLocalRecordsByDNStmt, fmt.Sprintf(listDNSLocalRecordsByDNStmt, DNSLocalRecordsTableName), []any{DNSLocalRecord{}}},
+		{&db.listAllDNSLocalRecordsStmt, fmt.Sprintf(listAllDNSLocalRecordsStmt, DNSLocalRecordsTableName), []any{DNSLocalRecord{}}},
+		{&db.upsertDataNetworkAddressAllocationStmt, fmt.Sprintf(upsertDataNetworkAddressAllocationStmt, DataNetworkAddressAllocationTableName), []any{DataNetworkAddressAllocation{}}},
+		{&db.deleteDataNetworkAddressAllocationStmt, fmt.Sprintf(deleteDataNetworkAddressAllocationStmt, DataNetworkAddressAllocationTableName), []any{DataNetworkAddressAllocation{}}},
+		{&db.getDataNetworkAddressAllocationStmt, fmt.Sprintf(getDataNetworkAddressAllocationStmt, DataNetworkAddressAllocationTableName), []any{DataNetworkAddressAllocation{}}},
+		{&db.createExternalIPLeaseStmt, fmt.Sprintf(createExternalIPLeaseStmt, ExternalIPLeasesTableName), []any{ExternalIPLease{}}},
+		{&db.getExternalIPLeaseStmt, fmt.Sprintf(getExternalIPLeaseStmt, ExternalIPLeasesTableName), []any{ExternalIPLease{}}},
+		{&db.updateExternalIPLeaseTimersStmt, fmt.Sprintf(updateExternalIPLeaseTimersStmt, ExternalIPLeasesTableName), []any{ExternalIPLease{}}},
+		{&db.listExternalIPLeasesByNodeStmt, fmt.Sprintf(listExternalIPLeasesByNodeStmt, ExternalIPLeasesTableName, IPLeasesTableName), []any{ExternalIPLease{}, IPLease{}}},
+		{&db.getLeaseByAddressStmt, fmt.Sprintf(getLeaseByAddressStmt, IPLeasesTableName), []any{IPLease{}}},
+		{&db.upsertDataNetworkSecondaryAuthStmt, fmt.Sprintf(upsertDataNetworkSecondaryAuthStmt, DataNetworkSecondaryAuthTableName), []any{DataNetworkSecondaryAuth{}}},
+		{&db.deleteDataNetworkSecondaryAuthStmt, fmt.Sprintf(deleteDataNetworkSecondaryAuthStmt, DataNetworkSecondaryAuthTableName), []any{DataNetworkSecondaryAuth{}}},
+		{&db.getDataNetworkSecondaryAuthStmt, fmt.Sprintf(getDataNetworkSecondaryAuthStmt, DataNetworkSecondaryAuthTableName), []any{DataNetworkSecondaryAuth{}}},
+		{&db.upsertDataNetworkOnlineChargingStmt, fmt.Sprintf(upsertDataNetworkOnlineChargingStmt, DataNetworkOnlineChargingTableName), []any{DataNetworkOnlineCharging{}}},
+		{&db.deleteDataNetworkOnlineChargingStmt, fmt.Sprintf(deleteDataNetworkOnlineChargingStmt, DataNetworkOnlineChargingTableName), []any{DataNetworkOnlineCharging{}}},
+		{&db.getDataNetworkOnlineChargingStmt, fmt.Sprintf(getDataNetworkOnlineChargingStmt, DataNetworkOnlineChargingTableName), []any{DataNetworkOnlineCharging{}}},
+		{&db.upsertDataNetworkPolicyControlStmt, fmt.Sprintf(upsertDataNetworkPolicyControlStmt, DataNetworkPolicyControlTableName), []any{DataNetworkPolicyControl{}}},
+		{&db.deleteDataNetworkPolicyControlStmt, fmt.Sprintf(deleteDataNetworkPolicyControlStmt, DataNetworkPolicyControlTableName), []any{DataNetworkPolicyControl{}}},
+		{&db.getDataNetworkPolicyControlStmt, fmt.Sprintf(getDataNetworkPolicyControlStmt, DataNetworkPolicyControlTableName), []any{DataNetworkPolicyControl{}}},
+		{&db.insertSMPolicyAssociationStmt, fmt.Sprintf(insertSMPolicyAssociationStmt, SMPolicyAssociationsTableName), []any{SMPolicyAssociation{}}},
+		{&db.updateSMPolicyDecisionStmt, fmt.Sprintf(updateSMPolicyDecisionStmt, SMPolicyAssociationsTableName), []any{SMPolicyAssociation{}}},
+		{&db.deleteSMPolicyAssociationStmt, fmt.Sprintf(deleteSMPolicyAssociationStmt, SMPolicyAssociationsTableName), []any{SMPolicyAssociation{}}},
+		{&db.deleteSMPolicyAssociationsByNodeStmt, fmt.Sprintf(deleteSMPolicyAssociationsByNodeStmt, SMPolicyAssociationsTableName), []any{SMPolicyAssociation{}}},
+		{&db.getSMPolicyAssociationStmt, fmt.Sprintf(getSMPolicyAssociationStmt, SMPolicyAssociationsTableName), []any{SMPolicyAssociation{}}},
+		{&db.listSMPolicyAssociationsByNodeStmt, fmt.Sprintf(listSMPolicyAssociationsByNodeStmt, SMPolicyAssociationsTableName), []any{SMPolicyAssociation{}}},
+		{&db.listAllSMPolicyAssociationsStmt, fmt.Sprintf(listAllSMPolicyAssociationsStmt, SMPolicyAssociationsTableName), []any{SMPolicyAssociation{}}},
+		{&db.insertQosSessionStmt, fmt.Sprintf(insertQosSessionStmt, QosSessionsTableName), []any{QosSession{}}},
+		{&db.claimQosSessionStmt, fmt.Sprintf(claimQosSessionStmt, QosSessionsTableName), []any{QosSession{}}},
+		{&db.updateQosSessionStatusStmt, fmt.Sprintf(updateQosSessionStatusStmt, QosSessionsTableName), []any{QosSession{}}},
+		{&db.updateQosSessionPolicyStmt, fmt.Sprintf(updateQosSessionPolicyStmt, QosSessionsTableName), []any{QosSession{}}},
+		{&db.deleteQosSessionStmt, fmt.Sprintf(deleteQosSessionStmt, QosSessionsTableName), []any{QosSession{}}},
+		{&db.getQosSessionStmt, fmt.Sprintf(getQosSessionStmt, QosSessionsTableName), []any{QosSession{}}},
+		{&db.listQosSessionsStmt, fmt.Sprintf(listQosSessionsStmt, QosSessionsTableName), []any{QosSession{}}},
+		{&db.insertMonitoringSubscriptionStmt, fmt.Sprintf(insertMonitoringSubscriptionStmt, MonitoringSubscriptionsTableName), []any{MonitoringSubscription{}}},
+		{&db.deleteMonitoringSubscriptionStmt, fmt.Sprintf(deleteMonitoringSubscriptionStmt, MonitoringSubscriptionsTableName), []any{MonitoringSubscription{}}},
+		{&db.countMonitoringReportStmt, fmt.Sprintf(countMonitoringReportStmt, MonitoringSubscriptionsTableName), []any{MonitoringSubscription{}}},
+		{&db.deleteSpentMonitoringSubscriptionStmt, fmt.Sprintf(deleteSpentMonitoringSubscriptionStmt, MonitoringSubscriptionsTableName), []any{MonitoringSubscription{}}},
+		{&db.deleteExpiredMonitoringSubscriptionsStmt, fmt.Sprintf(deleteExpiredMonitoringSubscriptionsStmt, MonitoringSubscriptionsTableName), []any{MonitoringSubscription{}}},
+		{&db.getMonitoringSubscriptionStmt, fmt.Sprintf(getMonitoringSubscriptionStmt, MonitoringSubscriptionsTableName), []any{MonitoringSubscription{}}},
+		{&db.listMonitoringSubscriptionsStmt, fmt.Sprintf(listMonitoringSubscriptionsStmt, MonitoringSubscriptionsTableName), []any{MonitoringSubscription{}}},
+		{&db.upsertSubscriberAmbrOverrideStmt, fmt.Sprintf(upsertSubscriberAmbrOverrideStmt, SubscriberAmbrOverridesTableName), []any{SubscriberAmbrOverride{}}},
+		{&db.deleteSubscriberAmbrOverrideStmt, fmt.Sprintf(deleteSubscriberAmbrOverrideStmt, SubscriberAmbrOverridesTableName), []any{SubscriberAmbrOverride{}}},
+		{&db.deleteExpiredSubscriberAmbrOverridesStmt, fmt.Sprintf(deleteExpiredSubscriberAmbrOverridesStmt, SubscriberAmbrOverridesTableName), []any{SubscriberAmbrOverride{}}},
+		{&db.getSubscriberAmbrOverrideStmt, fmt.Sprintf(getSubscriberAmbrOverrideStmt, SubscriberAmbrOverridesTableName), []any{SubscriberAmbrOverride{}}},
+		{&db.listSubscriberAmbrOverridesStmt, fmt.Sprintf(listSubscriberAmbrOverridesStmt, SubscriberAmbrOverridesTableName), []any{SubscriberAmbrOverride{}}},
+		{&db.upsertNetworkSliceQuotaStmt, fmt.Sprintf(upsertNetworkSliceQuotaStmt, NetworkSliceQuotasTableName), []any{NetworkSliceQuota{}}},
+		{&db.deleteNetworkSliceQuotaStmt, fmt.Sprintf(deleteNetworkSliceQuotaStmt, NetworkSliceQuotasTableName), []any{NetworkSliceQuota{}}},
+		{&db.getNetworkSliceQuotaStmt, fmt.Sprintf(getNetworkSliceQuotaStmt, NetworkSliceQuotasTableName), []any{NetworkSliceQuota{}}},
+		{&db.listAllNetworkSliceQuotaStmt, fmt.Sprintf(listAllNetworkSliceQuotaStmt, NetworkSliceQuotasTableName), []any{NetworkSliceQuota{}}},
+		{&db.upsertDataNetworkQuotaStmt, fmt.Sprintf(upsertDataNetworkQuotaStmt, DataNetworkQuotasTableName), []any{DataNetworkQuota{}}},
+		{&db.deleteDataNetworkQuotaStmt, fmt.Sprintf(deleteDataNetworkQuotaStmt, DataNetworkQuotasTableName), []any{DataNetworkQuota{}}},
+		{&db.getDataNetworkQuotaStmt, fmt.Sprintf(getDataNetworkQuotaStmt, DataNetworkQuotasTableName), []any{DataNetworkQuota{}}},
+		{&db.listAllDataNetworkQuotaStmt, fmt.Sprintf(listAllDataNetworkQuotaStmt, DataNetworkQuotasTableName), []any{DataNetworkQuota{}}},
+		{&db.upsertSubscriberLifecycleStmt, fmt.Sprintf(upsertSubscriberLifecycleStmt, SubscriberLifecyclesTableName), []any{SubscriberLifecycle{}}},
+		{&db.deleteSubscriberLifecycleStmt, fmt.Sprintf(deleteSubscriberLifecycleStmt, SubscriberLifecyclesTableName), []any{SubscriberLifecycle{}}},
+		{&db.expireSubscriberLifecyclesStmt, fmt.Sprintf(expireSubscriberLifecyclesStmt, SubscriberLifecyclesTableName), []any{SubscriberLifecycle{}}},
+		{&db.getSubscriberLifecycleStmt, fmt.Sprintf(getSubscriberLifecycleStmt, SubscriberLifecyclesTableName), []any{SubscriberLifecycle{}}},
+		{&db.listSubscriberLifecyclesStmt, fmt.Sprintf(listSubscriberLifecyclesStmt, SubscriberLifecyclesTableName), []any{SubscriberLifecycle{}}},
+		{&db.createPolicyLocationVariantStmt, fmt.Sprintf(createPolicyLocationVariantStmt, PolicyLocationVariantsTableName), []any{PolicyLocationVariant{}}},
+		{&db.getPolicyLocationVariantStmt, fmt.Sprintf(getPolicyLocationVariantStmt, PolicyLocationVariantsTableName), []any{PolicyLocationVariant{}}},
+		{&db.deletePolicyLocationVariantStmt, fmt.Sprintf(deletePolicyLocationVariantStmt, PolicyLocationVariantsTableName), []any{PolicyLocationVariant{}}},
+		{&db.listPolicyLocationVariantsByPolicyStmt, fmt.Sprintf(listPolicyLocationVariantsByPolicyStmt, PolicyLocationVariantsTableName), []any{PolicyLocationVariant{}}},
+		{&db.insertSliceRegistrationStmt, fmt.Sprintf(insertSliceRegistrationStmt, SliceRegistrationsTableName), []any{SliceRegistration{}}},
+		{&db.deleteSliceRegistrationsStmt, fmt.Sprintf(deleteSliceRegistrationsStmt, SliceRegistrationsTableName), []any{SliceRegistration{}}},
+		{&db.releaseSliceRegistrationsStmt, fmt.Sprintf(releaseSliceRegistrationsStmt, SliceRegistrationsTableName), []any{SliceRegistration{}}},
+		{&db.releaseSliceRegistrationStmt, fmt.Sprintf(releaseSliceRegistrationStmt, SliceRegistrationsTableName), []any{SliceRegistration{}}},
+		{&db.deleteSliceRegistrationsByNodeStmt, fmt.Sprintf(deleteSliceRegistrationsByNodeStmt, SliceRegistrationsTableName), []any{SliceRegistration{}}},
+		{&db.countSliceRegistrationsStmt, fmt.Sprintf(countSliceRegistrationsStmt, SliceRegistrationsTableName), []any{SliceRegistration{}, NumItems{}}},
+		{&db.countHeldSliceRegistrationsStmt, fmt.Sprintf(countHeldSliceRegistrationsStmt, SliceRegistrationsTableName), []any{SliceRegistration{}, NumItems{}}},
+		{&db.listSliceRegistrationsByNodeStmt, fmt.Sprintf(listSliceRegistrationsByNodeStmt, SliceRegistrationsTableName), []any{SliceRegistration{}}},
+		{&db.insertAdmittedSessionStmt, fmt.Sprintf(insertAdmittedSessionStmt, AdmittedSessionsTableName), []any{AdmittedSession{}}},
+		{&db.getAdmittedSessionStmt, fmt.Sprintf(getAdmittedSessionStmt, AdmittedSessionsTableName), []any{AdmittedSession{}}},
+		{&db.deleteAdmittedSessionStmt, fmt.Sprintf(deleteAdmittedSessionStmt, AdmittedSessionsTableName), []any{AdmittedSession{}}},
+		{&db.countHeldAdmittedSessionsStmt, fmt.Sprintf(countHeldAdmittedSessionsStmt, AdmittedSessionsTableName), []any{AdmittedSession{}, NumItems{}}},
+		{&db.deleteAdmittedSessionsByNodeStmt, fmt.Sprintf(deleteAdmittedSessionsByNodeStmt, AdmittedSessionsTableName), []any{AdmittedSession{}}},
+		{&db.countAdmittedSessionsBySubscriberStmt, fmt.Sprintf(countAdmittedSessionsBySubscriberStmt, AdmittedSessionsTableName), []any{AdmittedSession{}, NumItems{}}},
+		{&db.countAdmittedSessionsBySliceStmt, fmt.Sprintf(countAdmittedSessionsBySliceStmt, AdmittedSessionsTableName), []any{AdmittedSession{}, NumItems{}}},
+		{&db.countAdmittedSessionsByDataNetworkStmt, fmt.Sprintf(countAdmittedSessionsByDataNetworkStmt, AdmittedSessionsTableName), []any{AdmittedSession{}, NumItems{}}},
+		{&db.listAdmittedSessionsByNodeStmt, fmt.Sprintf(listAdmittedSessionsByNodeStmt, AdmittedSessionsTableName), []any{AdmittedSession{}}},
+		{&db.createAccountingServerStmt, fmt.Sprintf(createAccountingServerStmt, AccountingServersTableName), []any{AccountingServer{}}},
+		{&db.updateAccountingServerStmt, fmt.Sprintf(updateAccountingServerStmt, AccountingServersTableName), []any{AccountingServer{}}},
+		{&db.deleteAccountingServerStmt, fmt.Sprintf(deleteAccountingServerStmt, AccountingServersTableName), []any{AccountingServer{}}},
+		{&db.getAccountingServerStmt, fmt.Sprintf(getAccountingServerStmt, AccountingServersTableName), []any{AccountingServer{}}},
+		{&db.listAccountingServersStmt, fmt.Sprintf(listAccountingServersStmt, AccountingServersTableName), []any{AccountingServer{}}},
+		{&db.insertAccountingRecordStmt, fmt.Sprintf(insertAccountingRecordStmt, AccountingOutboxTableName), []any{AccountingRecord{}}},
+		{&db.listAccountingRecordsStmt, fmt.Sprintf(listAccountingRecordsStmt, AccountingOutboxTableName), []any{AccountingRecord{}, ListArgs{}}},
+		{&db.deleteAccountingRecordStmt, fmt.Sprintf(deleteAccountingRecordStmt, AccountingOutboxTableName), []any{AccountingRecord{}}},
+		{&db.deleteAccountingRecordsByServerStmt, fmt.Sprintf(deleteAccountingRecordsByServerStmt, AccountingOutboxTableName), []any{AccountingRecord{}}},
+		{&db.listAccountingRecordServersStmt, fmt.Sprintf(listAccountingRecordServersStmt, AccountingOutboxTableName), []any{AccountingRecord{}}},
+		{&db.trimAccountingRecordsStmt, fmt.Sprintf(trimAccountingRecordsStmt, AccountingOutboxTableName, AccountingOutboxTableName), []any{AccountingRecord{}, ListArgs{}}},
+		{&db.upsertPolicyCaptivePortalStmt, fmt.Sprintf(upsertPolicyCaptivePortalStmt, PolicyCaptivePortalsTableName), []any{PolicyCaptivePortal{}}},
+		{&db.deletePolicyCaptivePortalStmt, fmt.Sprintf(deletePolicyCaptivePortalStmt, PolicyCaptivePortalsTableName), []any{PolicyCaptivePortal{}}},
+		{&db.getPolicyCaptivePortalStmt, fmt.Sprintf(getPolicyCaptivePortalStmt, PolicyCaptivePortalsTableName), []any{PolicyCaptivePortal{}}},
+		{&db.listAllPolicyCaptivePortalsStmt, fmt.Sprintf(listAllPolicyCaptivePortalsStmt, PolicyCaptivePortalsTableName), []any{PolicyCaptivePortal{}}},
+		{&db.insertCaptivePortalLiftStmt, fmt.Sprintf(insertCaptivePortalLiftStmt, CaptivePortalLiftsTableName), []any{CaptivePortalLift{}}},
+		{&db.deleteCaptivePortalLiftStmt, fmt.Sprintf(deleteCaptivePortalLiftStmt, CaptivePortalLiftsTableName), []any{CaptivePortalLift{}}},
+		{&db.getCaptivePortalLiftStmt, fmt.Sprintf(getCaptivePortalLiftStmt, CaptivePortalLiftsTableName), []any{CaptivePortalLift{}}},
+		{&db.listAllCaptivePortalLiftsStmt, fmt.Sprintf(listAllCaptivePortalLiftsStmt, CaptivePortalLiftsTableName), []any{CaptivePortalLift{}}},
+		{&db.createNATPortForwardStmt, fmt.Sprintf(createNATPortForwardStmt, NATPortForwardsTableName), []any{NATPortForward{}}},
+		{&db.getNATPortForwardStmt, fmt.Sprintf(getNATPortForwardStmt, NATPortForwardsTableName), []any{NATPortForward{}}},
+		{&db.deleteNATPortForwardStmt, fmt.Sprintf(deleteNATPortForwardStmt, NATPortForwardsTableName), []any{NATPortForward{}}},
+		{&db.listNATPortForwardsByDNStmt, fmt.Sprintf(listNATPortForwardsByDNStmt, NATPortForwardsTableName), []any{NATPortForward{}}},
+		{&db.listAllNATPortForwardsStmt, fmt.Sprintf(listAllNATPortForwardsStmt, NATPortForwardsTableName), []any{NATPortForward{}}},
+		{&db.createNetworkRuleFQDNStmt, fmt.Sprintf(createNetworkRuleFQDNStmt, NetworkRuleFQDNsTableName), []any{NetworkRuleFQDN{}}},
+		{&db.listNetworkRuleFQDNsByPolicyStmt, fmt.Sprintf(listNetworkRuleFQDNsByPolicyStmt, NetworkRuleFQDNsTableName), []any{NetworkRuleFQDN{}}},
+		{&db.createNetworkRuleRateLimitStmt, fmt.Sprintf(createNetworkRuleRateLimitStmt, NetworkRuleRateLimitsTableName), []any{NetworkRuleRateLimit{}}},
+		{&db.listNetworkRuleRateLimitsByPolicyStmt, fmt.Sprintf(listNetworkRuleRateLimitsByPolicyStmt, NetworkRuleRateLimitsTableName), []any{NetworkRuleRateLimit{}}},
+		{&db.createNetworkRuleBreakoutStmt, fmt.Sprintf(createNetworkRuleBreakoutStmt, NetworkRuleBreakoutsTableName), []any{NetworkRuleBreakout{}}},
+		{&db.listNetworkRuleBreakoutsByPolicyStmt, fmt.Sprintf(listNetworkRuleBreakoutsByPolicyStmt, NetworkRuleBreakoutsTableName), []any{NetworkRuleBreakout{}}},
+		{&db.createNetworkRuleRatingStmt, fmt.Sprintf(createNetworkRuleRatingStmt, NetworkRuleRatingsTableName), []any{NetworkRuleRating{}}},
+		{&db.listNetworkRuleRatingsByPolicyStmt, fmt.Sprintf(listNetworkRuleRatingsByPolicyStmt, NetworkRuleRatingsTableName), []any{NetworkRuleRating{}}},
+		{&db.incrementRatingGroupUsageStmt, fmt.Sprintf(incrementRatingGroupUsageStmt, DailyRatingGroupUsageTableName), []any{DailyRatingGroupUsage{}}},
+		{&db.getUsagePerRatingGroupStmt, fmt.Sprintf(getUsagePerRatingGroupStmt, DailyRatingGroupUsageTableName), []any{UsageFilters{}, UsagePerRatingGroup{}}},
+		{&db.deleteAllRatingGroupUsageStmt, fmt.Sprintf(deleteAllRatingGroupUsageStmt, DailyRatingGroupUsageTableName), nil},
+		{&db.deleteOldRatingGroupUsageStmt, fmt.Sprintf(deleteOldRatingGroupUsageStmt, DailyRatingGroupUsageTableName), []any{cutoffDaysArgs{}}},
+		{&db.createScheduleStmt, fmt.Sprintf(createScheduleStmt, SchedulesTableName), []any{Schedule{}}},
+		{&db.updateScheduleStmt, fmt.Sprintf(updateScheduleStmt, SchedulesTableName), []any{Schedule{}}},
+		{&db.deleteScheduleStmt, fmt.Sprintf(deleteScheduleStmt, SchedulesTableName), []any{Schedule{}}},
+		{&db.getScheduleStmt, fmt.Sprintf(getScheduleStmt, SchedulesTableName), []any{Schedule{}}},
+		{&db.getScheduleByIDStmt, fmt.Sprintf(getScheduleByIDStmt, SchedulesTableName), []any{Schedule{}}},
+		{&db.listSchedulesStmt, fmt.Sprintf(listSchedulesStmt, SchedulesTableName), []any{Schedule{}}},
+		{&db.countNetworkRuleSchedulesStmt, fmt.Sprintf(countNetworkRuleSchedulesStmt, NetworkRuleSchedulesTableName), []any{NumItems{}, NetworkRuleSchedule{}}},
+		{&db.countPolicySchedulesStmt, fmt.Sprintf(countPolicySchedulesStmt, PolicySchedulesTableName), []any{NumItems{}, PolicySchedule{}}},
+		{&db.createNetworkRuleScheduleStmt, fmt.Sprintf(createNetworkRuleScheduleStmt, NetworkRuleSchedulesTableName), []any{NetworkRuleSchedule{}}},
+		{&db.listNetworkRuleSchedulesByPolicyStmt, fmt.Sprintf(listNetworkRuleSchedulesByPolicyStmt, NetworkRuleSchedulesTableName), []any{NetworkRuleSchedule{}}},
+		{&db.upsertPolicyScheduleStmt, fmt.Sprintf(upsertPolicyScheduleStmt, PolicySchedulesTableName), []any{PolicySchedule{}}},
+		{&db.deletePolicyScheduleStmt, fmt.Sprintf(deletePolicyScheduleStmt, PolicySchedulesTableName), []any{PolicySchedule{}}},
+		{&db.getPolicyScheduleStmt, fmt.Sprintf(getPolicyScheduleStmt, PolicySchedulesTableName), []any{PolicySchedule{}}},
+
 		// Retention Policy
 		{&db.selectRetentionPolicyStmt, fmt.Sprintf(selectRetentionPolicyStmt, RetentionPolicyTableName), []any{RetentionPolicy{}}},
 		{&db.upsertRetentionPolicyStmt, fmt.Sprintf(upsertRetentionPolicyStmt, RetentionPolicyTableName), []any{RetentionPolicy{}}},
@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV18 creates the data_network_egress table, which lets a data network
+// leave through its own N6 interface, VLAN and kernel routing table instead of
+// the node-wide interfaces.n6, and scopes static routes to a data network.
+// An empty routes.dataNetworkID keeps a route in the main table, as before.
+func migrateV18(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		interfaceName TEXT NOT NULL DEFAULT '',
+		vlanID INTEGER NOT NULL DEFAULT 0,
+		routingTable INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkEgressTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_egress table: %w", err)
+	}
+
+	stmt = fmt.Sprintf("ALTER TABLE %s ADD COLUMN dataNetworkID TEXT NOT NULL DEFAULT ''", RoutesTableName)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to add dataNetworkID column to routes: %w", err)
+	}
+
+	return nil
+}
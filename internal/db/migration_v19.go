@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV19 creates the data_network_nat table, which gives a data network
+// its own pool of public IPv4 source addresses and port range instead of the
+// egress interface address, and the nat_port_forwards table, which exposes a
+// UE port on a public address and port.
+func migrateV19(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		addresses TEXT NOT NULL,
+		mapping TEXT NOT NULL DEFAULT 'deterministic',
+		portMin INTEGER NOT NULL DEFAULT 0,
+		portMax INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkNATTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_nat table: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		id TEXT PRIMARY KEY,
+		dataNetworkID TEXT NOT NULL,
+		protocol TEXT NOT NULL,
+		publicAddress TEXT NOT NULL,
+		publicPort INTEGER NOT NULL,
+		imsi TEXT NOT NULL DEFAULT '',
+		ueAddress TEXT NOT NULL DEFAULT '',
+		uePort INTEGER NOT NULL,
+		UNIQUE (protocol, publicAddress, publicPort),
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, NATPortForwardsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create nat_port_forwards table: %w", err)
+	}
+
+	stmt = fmt.Sprintf("CREATE INDEX idx_nat_port_forwards_dn ON %s (dataNetworkID)", NATPortForwardsTableName)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create nat_port_forwards index: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV20 creates the network_rule_fqdns table, which turns a network rule
+// into one matching the addresses a domain name resolves to instead of its
+// remote prefix.
+func migrateV20(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		network_rule_id TEXT PRIMARY KEY,
+		policy_id TEXT NOT NULL,
+		fqdn TEXT NOT NULL,
+		match_sni INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (network_rule_id) REFERENCES network_rules (id) ON DELETE CASCADE,
+		FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE
+	)`, NetworkRuleFQDNsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_rule_fqdns table: %w", err)
+	}
+
+	stmt = fmt.Sprintf("CREATE INDEX idx_network_rule_fqdns_policy ON %s (policy_id)", NetworkRuleFQDNsTableName)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_rule_fqdns index: %w", err)
+	}
+
+	return nil
+}
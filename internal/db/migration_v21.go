@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV21 creates the network_rule_rate_limits table, which holds the
+// bitrate of network rules whose action is "rate_limit".
+func migrateV21(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		network_rule_id TEXT PRIMARY KEY,
+		policy_id TEXT NOT NULL,
+		bitrate TEXT NOT NULL,
+		shared INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (network_rule_id) REFERENCES network_rules (id) ON DELETE CASCADE,
+		FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE
+	)`, NetworkRuleRateLimitsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_rule_rate_limits table: %w", err)
+	}
+
+	stmt = fmt.Sprintf("CREATE INDEX idx_network_rule_rate_limits_policy ON %s (policy_id)", NetworkRuleRateLimitsTableName)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_rule_rate_limits index: %w", err)
+	}
+
+	return nil
+}
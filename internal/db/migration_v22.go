@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV22 creates the schedules table and the two tables that attach a
+// schedule to a network rule or to a policy's alternate Session-AMBR.
+// Schedules are not cascaded: one in use cannot be deleted.
+func migrateV22(ctx context.Context, tx *sql.Tx) error {
+	stmts := []struct {
+		what, stmt string
+	}{
+		{"schedules table", fmt.Sprintf(`CREATE TABLE %s (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			time_zone TEXT NOT NULL,
+			windows TEXT NOT NULL
+		)`, SchedulesTableName)},
+		{"network_rule_schedules table", fmt.Sprintf(`CREATE TABLE %s (
+			network_rule_id TEXT PRIMARY KEY,
+			policy_id TEXT NOT NULL,
+			schedule_id TEXT NOT NULL,
+			FOREIGN KEY (network_rule_id) REFERENCES network_rules (id) ON DELETE CASCADE,
+			FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE,
+			FOREIGN KEY (schedule_id) REFERENCES schedules (id)
+		)`, NetworkRuleSchedulesTableName)},
+		{"network_rule_schedules policy index", fmt.Sprintf("CREATE INDEX idx_network_rule_schedules_policy ON %s (policy_id)", NetworkRuleSchedulesTableName)},
+		{"network_rule_schedules schedule index", fmt.Sprintf("CREATE INDEX idx_network_rule_schedules_schedule ON %s (schedule_id)", NetworkRuleSchedulesTableName)},
+		{"policy_schedules table", fmt.Sprintf(`CREATE TABLE %s (
+			policy_id TEXT PRIMARY KEY,
+			schedule_id TEXT NOT NULL,
+			session_ambr_uplink TEXT NOT NULL,
+			session_ambr_downlink TEXT NOT NULL,
+			FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE,
+			FOREIGN KEY (schedule_id) REFERENCES schedules (id)
+		)`, PolicySchedulesTableName)},
+		{"policy_schedules schedule index", fmt.Sprintf("CREATE INDEX idx_policy_schedules_schedule ON %s (schedule_id)", PolicySchedulesTableName)},
+	}
+
+	for _, s := range stmts {
+		if _, err := tx.ExecContext(ctx, s.stmt); err != nil {
+			return fmt.Errorf("failed to create %s: %w", s.what, err)
+		}
+	}
+
+	return nil
+}
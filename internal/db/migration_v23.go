@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV23 creates the network_rule_breakouts table, which holds the local
+// egress of uplink network rules whose action is "breakout".
+func migrateV23(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		network_rule_id TEXT PRIMARY KEY,
+		policy_id TEXT NOT NULL,
+		interface_name TEXT NOT NULL,
+		vlan_id INTEGER NOT NULL DEFAULT 0,
+		routing_table INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (network_rule_id) REFERENCES network_rules (id) ON DELETE CASCADE,
+		FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE
+	)`, NetworkRuleBreakoutsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_rule_breakouts table: %w", err)
+	}
+
+	stmt = fmt.Sprintf("CREATE INDEX idx_network_rule_breakouts_policy ON %s (policy_id)", NetworkRuleBreakoutsTableName)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_rule_breakouts index: %w", err)
+	}
+
+	return nil
+}
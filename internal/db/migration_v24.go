@@ -0,0 +1,27 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV24 creates the data_network_tcp_mss table. A row turns on TCP MSS
+// clamping for the data network's UEs; a zero mss derives the limit from
+// the data network's MTU.
+func migrateV24(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		mss INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkTCPMSSTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_tcp_mss table: %w", err)
+	}
+
+	return nil
+}
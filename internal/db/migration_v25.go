@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV25 creates the network_rule_ratings table, which holds the rating
+// group of network rules whose traffic is counted apart, and the
+// daily_usage_rating_groups table those counts land in.
+func migrateV25(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		network_rule_id TEXT PRIMARY KEY,
+		policy_id TEXT NOT NULL,
+		rating_group INTEGER NOT NULL DEFAULT 0,
+		zero_rated INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (network_rule_id) REFERENCES network_rules (id) ON DELETE CASCADE,
+		FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE
+	)`, NetworkRuleRatingsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_rule_ratings table: %w", err)
+	}
+
+	stmt = fmt.Sprintf("CREATE INDEX idx_network_rule_ratings_policy ON %s (policy_id)", NetworkRuleRatingsTableName)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_rule_ratings index: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		epoch_day INTEGER NOT NULL,
+		imsi TEXT NOT NULL,
+		rating_group INTEGER NOT NULL,
+		bytes_uplink INTEGER NOT NULL DEFAULT 0 CHECK (bytes_uplink >= 0),
+		bytes_downlink INTEGER NOT NULL DEFAULT 0 CHECK (bytes_downlink >= 0),
+		PRIMARY KEY (epoch_day, imsi, rating_group),
+		FOREIGN KEY (imsi) REFERENCES subscribers(imsi) ON DELETE CASCADE
+	)`, DailyRatingGroupUsageTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create daily_usage_rating_groups table: %w", err)
+	}
+
+	return nil
+}
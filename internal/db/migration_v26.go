@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV26 creates the policy_captive_portals table, whose rows put a
+// policy's subscribers behind a captive portal, and the captive_portal_lifts
+// table of subscribers let past it.
+func migrateV26(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		policyID TEXT PRIMARY KEY,
+		portalAddress TEXT NOT NULL,
+		FOREIGN KEY (policyID) REFERENCES policies(id) ON DELETE CASCADE
+	)`, PolicyCaptivePortalsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create policy_captive_portals table: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		imsi TEXT PRIMARY KEY,
+		FOREIGN KEY (imsi) REFERENCES subscribers(imsi) ON DELETE CASCADE
+	)`, CaptivePortalLiftsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create captive_portal_lifts table: %w", err)
+	}
+
+	return nil
+}
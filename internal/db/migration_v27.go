@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV27 creates the data_network_dns_resolvers table, whose rows turn on
+// the embedded DNS forwarder of a data network, and the dns_local_records
+// table, which names UEs and hosts inside the forwarder's local zones.
+func migrateV27(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		listenAddress TEXT NOT NULL,
+		upstreams TEXT NOT NULL,
+		localZones TEXT NOT NULL DEFAULT '',
+		logQueries BOOLEAN NOT NULL DEFAULT FALSE,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkDNSResolversTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_dns_resolvers table: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		id TEXT PRIMARY KEY,
+		dataNetworkID TEXT NOT NULL,
+		name TEXT NOT NULL,
+		imsi TEXT NOT NULL DEFAULT '',
+		address TEXT NOT NULL DEFAULT '',
+		UNIQUE (dataNetworkID, name),
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DNSLocalRecordsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create dns_local_records table: %w", err)
+	}
+
+	return nil
+}
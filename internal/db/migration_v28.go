@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV28 creates the data_network_address_allocation table, whose rows
+// make a data network take UE IPv4 addresses from an external DHCPv4 or
+// RADIUS server, and the external_ip_leases table, which keeps what the
+// server said about each address leased that way.
+func migrateV28(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		mode TEXT NOT NULL CHECK (mode IN ('dhcp', 'radius')),
+		server TEXT NOT NULL,
+		relayAddress TEXT NOT NULL DEFAULT '',
+		secret TEXT NOT NULL DEFAULT '',
+		fallbackToPool BOOLEAN NOT NULL DEFAULT FALSE,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkAddressAllocationTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_address_allocation table: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		leaseID TEXT PRIMARY KEY,
+		source TEXT NOT NULL CHECK (source IN ('dhcp', 'radius')),
+		serverID TEXT NOT NULL DEFAULT '',
+		clientID TEXT NOT NULL DEFAULT '',
+		renewAt INTEGER NOT NULL DEFAULT 0,
+		expiresAt INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (leaseID) REFERENCES ip_leases(id) ON DELETE CASCADE
+	)`, ExternalIPLeasesTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create external_ip_leases table: %w", err)
+	}
+
+	return nil
+}
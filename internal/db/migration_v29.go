@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV29 creates the data_network_secondary_auth table, whose rows make
+// a data network authenticate users against a DN-AAA RADIUS server before
+// their PDU sessions are set up.
+func migrateV29(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		server TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkSecondaryAuthTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_secondary_auth table: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV30 creates the RADIUS accounting tables: accounting_servers,
+// replicated, lists the servers that receive accounting records, and
+// accounting_outbox, local to each node, holds the records its sessions
+// produced until the server acknowledged them.
+func migrateV30(ctx context.Context, tx *sql.Tx) error {
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			address TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			interim_interval INTEGER NOT NULL DEFAULT 0
+		)`, AccountingServersTableName),
+		fmt.Sprintf(`CREATE TABLE %s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			server_id TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			attributes BLOB NOT NULL
+		)`, AccountingOutboxTableName),
+		fmt.Sprintf("CREATE INDEX idx_accounting_outbox_server ON %s(server_id, id)", AccountingOutboxTableName),
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create accounting tables: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV31 creates the data_network_online_charging table, whose rows make
+// sessions on a data network ask an online charging system for credit.
+func migrateV31(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		protocol TEXT NOT NULL CHECK (protocol IN ('diameter', 'nchf')),
+		server TEXT NOT NULL,
+		realm TEXT NOT NULL DEFAULT '',
+		ratingGroup INTEGER NOT NULL CHECK (ratingGroup >= 1),
+		failureHandling TEXT NOT NULL CHECK (failureHandling IN ('terminate', 'continue')),
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkOnlineChargingTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_online_charging table: %w", err)
+	}
+
+	return nil
+}
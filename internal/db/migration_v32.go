@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV32 creates the data_network_policy_control table, whose rows have
+// an external policy decision point set the policy of sessions on a data
+// network, and the sm_policy_associations table of the sessions it controls.
+func migrateV32(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkPolicyControlTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_policy_control table: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		id TEXT PRIMARY KEY,
+		dataNetworkID TEXT NOT NULL,
+		imsi TEXT NOT NULL,
+		pduSessionID INTEGER NOT NULL,
+		nodeID INTEGER NOT NULL,
+		var5qi INTEGER NOT NULL DEFAULT 0,
+		arp INTEGER NOT NULL DEFAULT 0,
+		sessionAmbrUplink TEXT NOT NULL DEFAULT '',
+		sessionAmbrDownlink TEXT NOT NULL DEFAULT '',
+		rulesPolicyID TEXT,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE,
+		FOREIGN KEY (rulesPolicyID) REFERENCES policies(id) ON DELETE SET NULL
+	)`, SMPolicyAssociationsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create sm_policy_associations table: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX idx_sm_policy_associations_node ON %s (nodeID)", SMPolicyAssociationsTableName)); err != nil {
+		return fmt.Errorf("failed to create sm_policy_associations index: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV33 creates the qos_sessions table, whose rows are the QoS requests
+// of applications: a dedicated QoS flow for the traffic a UE exchanges with
+// a remote party, for a limited time.
+func migrateV33(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		id TEXT PRIMARY KEY,
+		imsi TEXT NOT NULL DEFAULT '',
+		ueIP TEXT NOT NULL DEFAULT '',
+		remotePrefix TEXT NOT NULL DEFAULT '',
+		protocol INTEGER NOT NULL DEFAULT 0,
+		portLow INTEGER NOT NULL DEFAULT 0,
+		portHigh INTEGER NOT NULL DEFAULT 0,
+		var5qi INTEGER NOT NULL,
+		gbrUplink TEXT NOT NULL,
+		gbrDownlink TEXT NOT NULL,
+		mbrUplink TEXT NOT NULL,
+		mbrDownlink TEXT NOT NULL,
+		notificationURL TEXT NOT NULL DEFAULT '',
+		createdAt INTEGER NOT NULL,
+		expiresAt INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		nodeID INTEGER NOT NULL DEFAULT 0,
+		policyID TEXT,
+		FOREIGN KEY (policyID) REFERENCES policies(id) ON DELETE SET NULL
+	)`, QosSessionsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create qos_sessions table: %w", err)
+	}
+
+	return nil
+}
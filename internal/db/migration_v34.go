@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV34 creates the monitoring_subscriptions table, whose rows are the
+// subscriptions of applications to the events of a subscriber's UE.
+func migrateV34(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		id TEXT PRIMARY KEY,
+		imsi TEXT NOT NULL,
+		event TEXT NOT NULL,
+		notificationURL TEXT NOT NULL,
+		maxReports INTEGER NOT NULL DEFAULT 0,
+		reports INTEGER NOT NULL DEFAULT 0,
+		createdAt INTEGER NOT NULL,
+		expiresAt INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (imsi) REFERENCES subscribers(imsi) ON DELETE CASCADE
+	)`, MonitoringSubscriptionsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create monitoring_subscriptions table: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV35 creates the subscriber_ambr_overrides table, whose rows replace
+// the UE-AMBR of a subscriber's profile, the Session-AMBR of its policies, or
+// both, until they expire.
+func migrateV35(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		imsi TEXT PRIMARY KEY,
+		ueAmbrUplink TEXT NOT NULL DEFAULT '',
+		ueAmbrDownlink TEXT NOT NULL DEFAULT '',
+		sessionAmbrUplink TEXT NOT NULL DEFAULT '',
+		sessionAmbrDownlink TEXT NOT NULL DEFAULT '',
+		createdAt INTEGER NOT NULL,
+		expiresAt INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (imsi) REFERENCES subscribers(imsi) ON DELETE CASCADE
+	)`, SubscriberAmbrOverridesTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create subscriber_ambr_overrides table: %w", err)
+	}
+
+	return nil
+}
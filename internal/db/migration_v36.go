@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV36 creates the network_slice_quotas and data_network_quotas
+// tables, which cap the UEs registered to a slice and the sessions opened on
+// a slice or data network, and the slice_registrations and admitted_sessions
+// tables, whose rows are the UEs and sessions every node of the cluster
+// counts against them. A zero limit is no limit.
+func migrateV36(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		networkSliceID TEXT PRIMARY KEY,
+		maxRegisteredUEs INTEGER NOT NULL DEFAULT 0,
+		maxSessions INTEGER NOT NULL DEFAULT 0,
+		maxSessionsPerSubscriber INTEGER NOT NULL DEFAULT 0,
+		backOffTimer INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (networkSliceID) REFERENCES network_slices(id) ON DELETE CASCADE
+	)`, NetworkSliceQuotasTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create network_slice_quotas table: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		dataNetworkID TEXT PRIMARY KEY,
+		maxSessions INTEGER NOT NULL DEFAULT 0,
+		backOffTimer INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (dataNetworkID) REFERENCES data_networks(id) ON DELETE CASCADE
+	)`, DataNetworkQuotasTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create data_network_quotas table: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		imsi TEXT NOT NULL,
+		sst INTEGER NOT NULL,
+		sd TEXT NOT NULL DEFAULT '',
+		nodeID INTEGER NOT NULL,
+		createdAt INTEGER NOT NULL,
+		PRIMARY KEY (imsi, sst, sd)
+	)`, SliceRegistrationsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create slice_registrations table: %w", err)
+	}
+
+	stmt = fmt.Sprintf(`CREATE TABLE %s (
+		nodeID INTEGER NOT NULL,
+		ref TEXT NOT NULL,
+		imsi TEXT NOT NULL,
+		sst INTEGER,
+		sd TEXT NOT NULL DEFAULT '',
+		dataNetwork TEXT NOT NULL,
+		createdAt INTEGER NOT NULL,
+		PRIMARY KEY (nodeID, ref)
+	)`, AdmittedSessionsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create admitted_sessions table: %w", err)
+	}
+
+	indexes := []string{
+		fmt.Sprintf("CREATE INDEX idx_slice_registrations_slice ON %s (sst, sd)", SliceRegistrationsTableName),
+		fmt.Sprintf("CREATE INDEX idx_slice_registrations_node ON %s (nodeID)", SliceRegistrationsTableName),
+		fmt.Sprintf("CREATE INDEX idx_admitted_sessions_slice ON %s (sst, sd, imsi)", AdmittedSessionsTableName),
+		fmt.Sprintf("CREATE INDEX idx_admitted_sessions_data_network ON %s (dataNetwork)", AdmittedSessionsTableName),
+	}
+
+	for _, index := range indexes {
+		if _, err := tx.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("failed to create admission index: %w", err)
+		}
+	}
+
+	return nil
+}
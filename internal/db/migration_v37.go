@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV37 creates the subscriber_lifecycles table, whose rows put a
+// subscriber in a lifecycle state other than active, or bound the time it
+// may use the network.
+func migrateV37(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		imsi TEXT PRIMARY KEY,
+		state TEXT NOT NULL DEFAULT 'active',
+		validFrom INTEGER NOT NULL DEFAULT 0,
+		validUntil INTEGER NOT NULL DEFAULT 0,
+		updatedAt INTEGER NOT NULL,
+		FOREIGN KEY (imsi) REFERENCES subscribers(imsi) ON DELETE CASCADE
+	)`, SubscriberLifecyclesTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create subscriber_lifecycles table: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateV38 creates the policy_location_variants table, whose rows replace
+// a policy's Session-AMBR, and optionally its network rules, for UEs served
+// in one tracking area or cell.
+func migrateV38(ctx context.Context, tx *sql.Tx) error {
+	stmt := fmt.Sprintf(`CREATE TABLE %s (
+		id TEXT PRIMARY KEY,
+		policyID TEXT NOT NULL,
+		name TEXT NOT NULL,
+		tac TEXT NOT NULL DEFAULT '',
+		cellID TEXT NOT NULL DEFAULT '',
+		sessionAmbrUplink TEXT NOT NULL,
+		sessionAmbrDownlink TEXT NOT NULL,
+		rulesPolicyID TEXT,
+		UNIQUE (policyID, name),
+		UNIQUE (policyID, tac, cellID),
+		FOREIGN KEY (policyID) REFERENCES policies(id) ON DELETE CASCADE,
+		FOREIGN KEY (rulesPolicyID) REFERENCES policies(id) ON DELETE SET NULL
+	)`, PolicyLocationVariantsTableName)
+
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create policy_location_variants table: %w", err)
+	}
+
+	return nil
+}
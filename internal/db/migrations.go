@@ -39,6 +39,27 @@ var migrations = []migration{
 	{15, "add positioning_sessions and cell_positions tables for LMF", migrateV15},
 	{16, "add subscriber_framed_routes table", migrateV16},
 	{17, "add local_switch_settings table", migrateV17},
+	{18, "add data_network_egress table and routes.dataNetworkID", migrateV18},
+	{19, "add data_network_nat and nat_port_forwards tables", migrateV19},
+	{20, "add network_rule_fqdns table", migrateV20},
+	{21, "add network_rule_rate_limits table", migrateV21},
+	{22, "add schedules, network_rule_schedules and policy_schedules tables", migrateV22},
+	{23, "add network_rule_breakouts table", migrateV23},
+	{24, "add data_network_tcp_mss table", migrateV24},
+	{25, "add network_rule_ratings and daily_usage_rating_groups tables", migrateV25},
+	{26, "add captive portal tables", migrateV26},
+	{27, "add data network DNS resolver tables", migrateV27},
+	{28, "add external address allocation tables", migrateV28},
+	{29, "add data network secondary authentication table", migrateV29},
+	{30, "add RADIUS accounting tables", migrateV30},
+	{31, "add data network online charging table", migrateV31},
+	{32, "add external policy control tables", migrateV32},
+	{33, "add application QoS session table", migrateV33},
+	{34, "add monitoring event subscription table", migrateV34},
+	{35, "add subscriber AMBR override table", migrateV35},
+	{36, "add admission quota tables", migrateV36},
+	{37, "add subscriber lifecycle table", migrateV37},
+	{38, "add policy location variants table", migrateV38},
 }
 
 // baselineVersion is the highest migration that runs locally during
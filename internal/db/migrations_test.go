@@ -156,6 +156,39 @@ func TestRunMigrations_FreshDatabase(t *testing.T) {
 		AuditLogsTableName,
 		DailyUsageTableName,
 		DataNetworksTableName,
+		DataNetworkEgressTableName,
+		DataNetworkNATTableName,
+		NATPortForwardsTableName,
+		DataNetworkTCPMSSTableName,
+		DataNetworkDNSResolversTableName,
+		DNSLocalRecordsTableName,
+		DataNetworkAddressAllocationTableName,
+		ExternalIPLeasesTableName,
+		DataNetworkSecondaryAuthTableName,
+		AccountingServersTableName,
+		AccountingOutboxTableName,
+		DataNetworkOnlineChargingTableName,
+		DataNetworkPolicyControlTableName,
+		SMPolicyAssociationsTableName,
+		QosSessionsTableName,
+		MonitoringSubscriptionsTableName,
+		SubscriberAmbrOverridesTableName,
+		NetworkSliceQuotasTableName,
+		DataNetworkQuotasTableName,
+		SubscriberLifecyclesTableName,
+		PolicyLocationVariantsTableName,
+		SliceRegistrationsTableName,
+		AdmittedSessionsTableName,
+		NetworkRuleFQDNsTableName,
+		NetworkRuleRateLimitsTableName,
+		NetworkRuleBreakoutsTableName,
+		NetworkRuleRatingsTableName,
+		DailyRatingGroupUsageTableName,
+		SchedulesTableName,
+		NetworkRuleSchedulesTableName,
+		PolicySchedulesTableName,
+		PolicyCaptivePortalsTableName,
+		CaptivePortalLiftsTableName,
 		FlowAccountingSettingsTableName,
 		FlowReportsTableName,
 		HomeNetworkKeysTableName,
@@ -0,0 +1,339 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/canonical/sqlair"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const MonitoringSubscriptionsTableName = "monitoring_subscriptions"
+
+// MaxMonitoringSubscriptions caps the monitoring event subscriptions
+// recorded at once.
+const MaxMonitoringSubscriptions = 1024
+
+// monitoringSubscriptionsSchema is the migration that introduced the table.
+// Reads below it report no subscriptions.
+const monitoringSubscriptionsSchema = 34
+
+const (
+	insertMonitoringSubscriptionStmt         = "INSERT INTO %s (id, imsi, event, notificationURL, maxReports, reports, createdAt, expiresAt) VALUES ($MonitoringSubscription.id, $MonitoringSubscription.imsi, $MonitoringSubscription.event, $MonitoringSubscription.notificationURL, $MonitoringSubscription.maxReports, $MonitoringSubscription.reports, $MonitoringSubscription.createdAt, $MonitoringSubscription.expiresAt) ON CONFLICT(id) DO NOTHING"
+	deleteMonitoringSubscriptionStmt         = "DELETE FROM %s WHERE id==$MonitoringSubscription.id"
+	countMonitoringReportStmt                = "UPDATE %s SET reports=reports+1 WHERE id==$MonitoringSubscription.id AND (maxReports==0 OR reports<maxReports)"
+	deleteSpentMonitoringSubscriptionStmt    = "DELETE FROM %s WHERE id==$MonitoringSubscription.id AND maxReports>0 AND reports>=maxReports"
+	deleteExpiredMonitoringSubscriptionsStmt = "DELETE FROM %s WHERE expiresAt>0 AND expiresAt<=$MonitoringSubscription.expiresAt"
+	getMonitoringSubscriptionStmt            = "SELECT &MonitoringSubscription.* FROM %s WHERE id==$MonitoringSubscription.id"
+	listMonitoringSubscriptionsStmt          = "SELECT &MonitoringSubscription.* FROM %s ORDER BY createdAt, id"
+)
+
+// MonitoringSubscription is an application's subscription to an event of
+// the UE of subscriber IMSI, reported to NotificationURL. A subscription
+// with MaxReports set ends once that many reports were made; one with
+// ExpiresAt (Unix seconds) set ends then. Zero means no limit.
+type MonitoringSubscription struct {
+	ID              string `db:"id"`
+	IMSI            string `db:"imsi"` // FK to subscribers.imsi
+	Event           string `db:"event"`
+	NotificationURL string `db:"notificationURL"`
+	MaxReports      int    `db:"maxReports"`
+	Reports         int    `db:"reports"`
+	CreatedAt       int64  `db:"createdAt"`
+	ExpiresAt       int64  `db:"expiresAt"`
+}
+
+// CreateMonitoringSubscription records a new subscription. An ID already
+// recorded returns ErrAlreadyExists.
+func (db *Database) CreateMonitoringSubscription(ctx context.Context, sub *MonitoringSubscription) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", MonitoringSubscriptionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", MonitoringSubscriptionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(MonitoringSubscriptionsTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(MonitoringSubscriptionsTableName, "insert").Inc()
+
+	_, err := opCreateMonitoringSubscription.Invoke(db, sub)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreateMonitoringSubscription(ctx context.Context, sub *MonitoringSubscription) (any, error) {
+	rowsAffected, err := db.execMonitoringSubscription(ctx, db.insertMonitoringSubscriptionStmt, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrAlreadyExists
+	}
+
+	return nil, nil
+}
+
+// DeleteMonitoringSubscription returns ErrNotFound for an unknown id.
+func (db *Database) DeleteMonitoringSubscription(ctx context.Context, id string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", MonitoringSubscriptionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", MonitoringSubscriptionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(MonitoringSubscriptionsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(MonitoringSubscriptionsTableName, "delete").Inc()
+
+	_, err := opDeleteMonitoringSubscription.Invoke(db, &stringPayload{Value: id})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteMonitoringSubscription(ctx context.Context, p *stringPayload) (any, error) {
+	rowsAffected, err := db.execMonitoringSubscription(ctx, db.deleteMonitoringSubscriptionStmt, &MonitoringSubscription{ID: p.Value})
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// RecordMonitoringReport counts a report made for subscription id, and
+// deletes the subscription once it made its last. A subscription gone, or
+// one out of reports, returns ErrNotFound: the report must not be made.
+func (db *Database) RecordMonitoringReport(ctx context.Context, id string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", MonitoringSubscriptionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", MonitoringSubscriptionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(MonitoringSubscriptionsTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(MonitoringSubscriptionsTableName, "update").Inc()
+
+	_, err := opRecordMonitoringReport.Invoke(db, &stringPayload{Value: id})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyRecordMonitoringReport(ctx context.Context, p *stringPayload) (any, error) {
+	sub := &MonitoringSubscription{ID: p.Value}
+
+	rowsAffected, err := db.execMonitoringSubscription(ctx, db.countMonitoringReportStmt, sub)
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	if _, err := db.execMonitoringSubscription(ctx, db.deleteSpentMonitoringSubscriptionStmt, sub); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// DeleteExpiredMonitoringSubscriptions deletes the subscriptions expired by
+// now (Unix seconds).
+func (db *Database) DeleteExpiredMonitoringSubscriptions(ctx context.Context, now int64) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", MonitoringSubscriptionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", MonitoringSubscriptionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(MonitoringSubscriptionsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(MonitoringSubscriptionsTableName, "delete").Inc()
+
+	_, err := opDeleteExpiredMonitoringSubscriptions.Invoke(db, &int64Payload{Value: now})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteExpiredMonitoringSubscriptions(ctx context.Context, p *int64Payload) (any, error) {
+	_, err := db.execMonitoringSubscription(ctx, db.deleteExpiredMonitoringSubscriptionsStmt, &MonitoringSubscription{ExpiresAt: p.Value})
+
+	return nil, err
+}
+
+func (db *Database) execMonitoringSubscription(ctx context.Context, stmt *sqlair.Statement, sub *MonitoringSubscription) (int64, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, stmt, sub).Get(&outcome); err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// GetMonitoringSubscription returns ErrNotFound for an unknown id.
+func (db *Database) GetMonitoringSubscription(ctx context.Context, id string) (*MonitoringSubscription, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", MonitoringSubscriptionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", MonitoringSubscriptionsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(monitoringSubscriptionsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(MonitoringSubscriptionsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(MonitoringSubscriptionsTableName, "select").Inc()
+
+	row := MonitoringSubscription{ID: id}
+
+	err := db.conn().Query(ctx, db.getMonitoringSubscriptionStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// ListMonitoringSubscriptions returns every subscription, oldest first.
+func (db *Database) ListMonitoringSubscriptions(ctx context.Context) ([]MonitoringSubscription, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", MonitoringSubscriptionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", MonitoringSubscriptionsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(monitoringSubscriptionsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []MonitoringSubscription{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(MonitoringSubscriptionsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(MonitoringSubscriptionsTableName, "select").Inc()
+
+	var rows []MonitoringSubscription
+
+	err := db.conn().Query(ctx, db.listMonitoringSubscriptionsStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []MonitoringSubscription{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
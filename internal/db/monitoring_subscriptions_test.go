@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestMonitoringSubscriptionsEndToEnd(t *testing.T) {
+	database, _, imsi := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	subs := []*db.MonitoringSubscription{
+		{ID: "ms-1", IMSI: imsi, Event: "UE_REACHABILITY", NotificationURL: "https://app.example.com/n", MaxReports: 2, CreatedAt: 100},
+		{ID: "ms-2", IMSI: imsi, Event: "LOCATION_REPORTING", NotificationURL: "https://app.example.com/n", CreatedAt: 200, ExpiresAt: 500},
+		{ID: "ms-3", IMSI: imsi, Event: "ROAMING_STATUS", NotificationURL: "https://app.example.com/n", CreatedAt: 300},
+	}
+
+	for _, sub := range subs {
+		if err := database.CreateMonitoringSubscription(ctx, sub); err != nil {
+			t.Fatalf("couldn't create subscription %s: %s", sub.ID, err)
+		}
+	}
+
+	if err := database.CreateMonitoringSubscription(ctx, subs[0]); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a recorded id, got %v", err)
+	}
+
+	// ms-1 allows two reports: the second ends it, and a third is refused.
+	if err := database.RecordMonitoringReport(ctx, "ms-1"); err != nil {
+		t.Fatalf("couldn't record report: %s", err)
+	}
+
+	got, err := database.GetMonitoringSubscription(ctx, "ms-1")
+	if err != nil || got.Reports != 1 || got.Event != "UE_REACHABILITY" {
+		t.Fatalf("subscription = %+v (%v), want one report made", got, err)
+	}
+
+	if err := database.RecordMonitoringReport(ctx, "ms-1"); err != nil {
+		t.Fatalf("couldn't record last report: %s", err)
+	}
+
+	if err := database.RecordMonitoringReport(ctx, "ms-1"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound once out of reports, got %v", err)
+	}
+
+	if _, err := database.GetMonitoringSubscription(ctx, "ms-1"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected the spent subscription deleted, got %v", err)
+	}
+
+	// ms-2 expires at 500; ms-3 never does.
+	if err := database.DeleteExpiredMonitoringSubscriptions(ctx, 499); err != nil {
+		t.Fatalf("couldn't delete expired subscriptions: %s", err)
+	}
+
+	if all, _ := database.ListMonitoringSubscriptions(ctx); len(all) != 2 {
+		t.Fatalf("subscriptions = %+v, want ms-2 and ms-3 before expiry", all)
+	}
+
+	if err := database.DeleteExpiredMonitoringSubscriptions(ctx, 500); err != nil {
+		t.Fatalf("couldn't delete expired subscriptions: %s", err)
+	}
+
+	all, err := database.ListMonitoringSubscriptions(ctx)
+	if err != nil || len(all) != 1 || all[0].ID != "ms-3" {
+		t.Fatalf("subscriptions = %+v (%v), want only ms-3", all, err)
+	}
+
+	if err := database.DeleteMonitoringSubscription(ctx, "ms-2"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an expired subscription, got %v", err)
+	}
+
+	// Deleting the subscriber deletes its subscriptions.
+	if err := database.DeleteSubscriber(ctx, imsi); err != nil {
+		t.Fatalf("couldn't delete subscriber: %s", err)
+	}
+
+	if all, _ := database.ListMonitoringSubscriptions(ctx); len(all) != 0 {
+		t.Fatalf("subscriptions = %+v, want none after the subscriber was deleted", all)
+	}
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const NetworkRuleBreakoutsTableName = "network_rule_breakouts"
+
+// networkRuleBreakoutsSchema is the migration that introduced the table.
+// Below it no rule can carry the "breakout" action.
+const networkRuleBreakoutsSchema = 23
+
+// RuleActionBreakout is the uplink network rule action that sends matching
+// traffic out of the rule's NetworkRuleBreakout instead of the data
+// network's egress.
+const RuleActionBreakout = "breakout"
+
+const (
+	createNetworkRuleBreakoutStmt        = "INSERT INTO %s (network_rule_id, policy_id, interface_name, vlan_id, routing_table) VALUES ($NetworkRuleBreakout.network_rule_id, $NetworkRuleBreakout.policy_id, $NetworkRuleBreakout.interface_name, $NetworkRuleBreakout.vlan_id, $NetworkRuleBreakout.routing_table)"
+	listNetworkRuleBreakoutsByPolicyStmt = "SELECT &NetworkRuleBreakout.* FROM %s WHERE policy_id==$NetworkRuleBreakout.policy_id"
+)
+
+// NetworkRuleBreakout is the local egress of a "breakout" network rule, such
+// as the interface facing an edge compute subnet. Like DataNetworkEgress, a
+// non-zero RoutingTable is the kernel table the destination is looked up in,
+// and a non-zero VlanID tags the traffic on InterfaceName.
+type NetworkRuleBreakout struct {
+	NetworkRuleID string `db:"network_rule_id"` // FK to network_rules.id
+	PolicyID      string `db:"policy_id"`       // FK to policies.id
+	InterfaceName string `db:"interface_name"`
+	VlanID        int    `db:"vlan_id"`
+	RoutingTable  int    `db:"routing_table"`
+}
+
+// hasBreakoutRules reports whether any rule in the payload breaks out.
+func (r *PolicyRulesInput) hasBreakoutRules() bool {
+	if r == nil {
+		return false
+	}
+
+	for _, rule := range r.Uplink {
+		if rule.Action == RuleActionBreakout {
+			return true
+		}
+	}
+
+	for _, rule := range r.Downlink {
+		if rule.Action == RuleActionBreakout {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (db *Database) insertNetworkRuleBreakout(ctx context.Context, nr *NetworkRule, rule PolicyRuleInput) error {
+	row := &NetworkRuleBreakout{
+		NetworkRuleID: nr.ID,
+		PolicyID:      nr.PolicyID,
+		InterfaceName: rule.BreakoutInterface,
+		VlanID:        rule.BreakoutVlanID,
+		RoutingTable:  rule.BreakoutRoutingTable,
+	}
+
+	if err := db.runner(ctx).Query(ctx, db.createNetworkRuleBreakoutStmt, row).Run(); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil
+}
+
+// ListNetworkRuleBreakoutsByPolicy returns the local egress of a policy's
+// breakout rules, keyed by network rule ID.
+func (db *Database) ListNetworkRuleBreakoutsByPolicy(ctx context.Context, policyID string) (map[string]NetworkRuleBreakout, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NetworkRuleBreakoutsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NetworkRuleBreakoutsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(networkRuleBreakoutsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return map[string]NetworkRuleBreakout{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkRuleBreakoutsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkRuleBreakoutsTableName, "select").Inc()
+
+	var rows []NetworkRuleBreakout
+
+	err := db.conn().Query(ctx, db.listNetworkRuleBreakoutsByPolicyStmt, NetworkRuleBreakout{PolicyID: policyID}).GetAll(&rows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	out := make(map[string]NetworkRuleBreakout, len(rows))
+	for _, row := range rows {
+		out[row.NetworkRuleID] = row
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return out, nil
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestNetworkRuleBreakoutsFollowPolicyRules(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	if err := database.CreateDataNetwork(ctx, &db.DataNetwork{Name: "breakout-dnn", IPv4Pool: "10.50.0.0/24"}); err != nil {
+		t.Fatalf("Couldn't create data network: %s", err)
+	}
+
+	dataNetwork, err := database.GetDataNetwork(ctx, "breakout-dnn")
+	if err != nil {
+		t.Fatalf("Couldn't get data network: %s", err)
+	}
+
+	profileID, sliceID := createPolicyDeps(t, database, "breakout")
+
+	policy := &db.Policy{
+		Name:                "breakout-policy",
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "200 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkID:       dataNetwork.ID,
+		ProfileID:           profileID,
+		SliceID:             sliceID,
+	}
+
+	edge := "192.168.50.0/24"
+
+	rules := &db.PolicyRulesInput{
+		Uplink: []db.PolicyRuleInput{
+			{Description: "edge video", RemotePrefix: &edge, Action: db.RuleActionBreakout, BreakoutInterface: "eth2", BreakoutVlanID: 30, BreakoutRoutingTable: 100},
+			{Description: "everything else", Action: "allow"},
+		},
+	}
+
+	if err := database.CreatePolicyWithRules(ctx, policy, rules, nil); err != nil {
+		t.Fatalf("Couldn't create policy: %s", err)
+	}
+
+	breakouts, err := database.ListNetworkRuleBreakoutsByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list breakouts: %s", err)
+	}
+
+	dbRules, err := database.ListRulesForPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list rules: %s", err)
+	}
+
+	if len(breakouts) != 1 {
+		t.Fatalf("expected 1 breakout rule, got %d", len(breakouts))
+	}
+
+	got, ok := breakouts[dbRules[0].ID]
+	if !ok {
+		t.Fatalf("breakout not keyed by the first rule's ID")
+	}
+
+	if got.InterfaceName != "eth2" || got.VlanID != 30 || got.RoutingTable != 100 {
+		t.Fatalf("unexpected breakout row: %+v", got)
+	}
+
+	rules.Uplink = rules.Uplink[1:]
+
+	if err := database.UpdatePolicyWithRules(ctx, policy, rules, nil); err != nil {
+		t.Fatalf("Couldn't update policy: %s", err)
+	}
+
+	breakouts, err = database.ListNetworkRuleBreakoutsByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list breakouts: %s", err)
+	}
+
+	if len(breakouts) != 0 {
+		t.Fatalf("expected breakout rows to be deleted with their rules, got %d", len(breakouts))
+	}
+}
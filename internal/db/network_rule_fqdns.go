@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const NetworkRuleFQDNsTableName = "network_rule_fqdns"
+
+// networkRuleFQDNsSchema is the migration that introduced the table. Below
+// it no rule carries a domain name.
+const networkRuleFQDNsSchema = 20
+
+const (
+	createNetworkRuleFQDNStmt        = "INSERT INTO %s (network_rule_id, policy_id, fqdn, match_sni) VALUES ($NetworkRuleFQDN.network_rule_id, $NetworkRuleFQDN.policy_id, $NetworkRuleFQDN.fqdn, $NetworkRuleFQDN.match_sni)"
+	listNetworkRuleFQDNsByPolicyStmt = "SELECT &NetworkRuleFQDN.* FROM %s WHERE policy_id==$NetworkRuleFQDN.policy_id"
+)
+
+// NetworkRuleFQDN makes a network rule match the addresses FQDN resolves to
+// instead of its remote prefix. FQDN is a domain name, or "*." followed by one
+// to match every name below it. MatchSNI also learns addresses from the
+// server name of TLS connections, for resolvers the datapath cannot see.
+type NetworkRuleFQDN struct {
+	NetworkRuleID string `db:"network_rule_id"` // FK to network_rules.id
+	PolicyID      string `db:"policy_id"`       // FK to policies.id
+	FQDN          string `db:"fqdn"`
+	MatchSNI      bool   `db:"match_sni"`
+}
+
+// hasFQDNRules reports whether any rule in the payload names a domain.
+func (r *PolicyRulesInput) hasFQDNRules() bool {
+	if r == nil {
+		return false
+	}
+
+	for _, rule := range r.Uplink {
+		if rule.FQDN != "" {
+			return true
+		}
+	}
+
+	for _, rule := range r.Downlink {
+		if rule.FQDN != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (db *Database) insertNetworkRuleFQDN(ctx context.Context, nr *NetworkRule, rule PolicyRuleInput) error {
+	row := &NetworkRuleFQDN{
+		NetworkRuleID: nr.ID,
+		PolicyID:      nr.PolicyID,
+		FQDN:          rule.FQDN,
+		MatchSNI:      rule.MatchSNI,
+	}
+
+	if err := db.runner(ctx).Query(ctx, db.createNetworkRuleFQDNStmt, row).Run(); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil
+}
+
+// ListNetworkRuleFQDNsByPolicy returns the domain names of a policy's rules,
+// keyed by network rule ID.
+func (db *Database) ListNetworkRuleFQDNsByPolicy(ctx context.Context, policyID string) (map[string]NetworkRuleFQDN, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NetworkRuleFQDNsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NetworkRuleFQDNsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(networkRuleFQDNsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return map[string]NetworkRuleFQDN{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkRuleFQDNsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkRuleFQDNsTableName, "select").Inc()
+
+	var rows []NetworkRuleFQDN
+
+	err := db.conn().Query(ctx, db.listNetworkRuleFQDNsByPolicyStmt, NetworkRuleFQDN{PolicyID: policyID}).GetAll(&rows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	out := make(map[string]NetworkRuleFQDN, len(rows))
+	for _, row := range rows {
+		out[row.NetworkRuleID] = row
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return out, nil
+}
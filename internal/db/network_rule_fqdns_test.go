@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestNetworkRuleFQDNsFollowPolicyRules(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	if err := database.CreateDataNetwork(ctx, &db.DataNetwork{Name: "fqdn-dnn", IPv4Pool: "10.48.0.0/24"}); err != nil {
+		t.Fatalf("Couldn't create data network: %s", err)
+	}
+
+	dataNetwork, err := database.GetDataNetwork(ctx, "fqdn-dnn")
+	if err != nil {
+		t.Fatalf("Couldn't get data network: %s", err)
+	}
+
+	profileID, sliceID := createPolicyDeps(t, database, "fqdn")
+
+	policy := &db.Policy{
+		Name:                "fqdn-policy",
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "200 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkID:       dataNetwork.ID,
+		ProfileID:           profileID,
+		SliceID:             sliceID,
+	}
+
+	rules := &db.PolicyRulesInput{
+		Uplink: []db.PolicyRuleInput{
+			{Description: "vendor cloud", Protocol: 6, PortLow: 443, PortHigh: 443, Action: "allow", FQDN: "*.vendor-cloud.example", MatchSNI: true},
+			{Description: "everything else", Action: "deny"},
+		},
+	}
+
+	if err := database.CreatePolicyWithRules(ctx, policy, rules, nil); err != nil {
+		t.Fatalf("Couldn't create policy: %s", err)
+	}
+
+	fqdns, err := database.ListNetworkRuleFQDNsByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list FQDNs: %s", err)
+	}
+
+	if len(fqdns) != 1 {
+		t.Fatalf("expected 1 FQDN rule, got %d", len(fqdns))
+	}
+
+	dbRules, err := database.ListRulesForPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list rules: %s", err)
+	}
+
+	got, ok := fqdns[dbRules[0].ID]
+	if !ok {
+		t.Fatalf("FQDN not keyed by the first rule's ID")
+	}
+
+	if got.FQDN != "*.vendor-cloud.example" || !got.MatchSNI {
+		t.Fatalf("unexpected FQDN row: %+v", got)
+	}
+
+	if _, ok := fqdns[dbRules[1].ID]; ok {
+		t.Fatalf("prefix rule should carry no FQDN")
+	}
+
+	// Replacing the rules drops the old rows with the rules they named.
+	rules.Uplink = rules.Uplink[1:]
+
+	if err := database.UpdatePolicyWithRules(ctx, policy, rules, nil); err != nil {
+		t.Fatalf("Couldn't update policy: %s", err)
+	}
+
+	fqdns, err = database.ListNetworkRuleFQDNsByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list FQDNs: %s", err)
+	}
+
+	if len(fqdns) != 0 {
+		t.Fatalf("expected FQDN rows to be deleted with their rules, got %d", len(fqdns))
+	}
+}
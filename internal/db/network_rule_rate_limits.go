@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const NetworkRuleRateLimitsTableName = "network_rule_rate_limits"
+
+// networkRuleRateLimitsSchema is the migration that introduced the table.
+// Below it no rule can carry the "rate_limit" action.
+const networkRuleRateLimitsSchema = 21
+
+// RuleActionRateLimit is the network rule action that allows matching traffic
+// up to the rule's NetworkRuleRateLimit.
+const RuleActionRateLimit = "rate_limit"
+
+const (
+	createNetworkRuleRateLimitStmt        = "INSERT INTO %s (network_rule_id, policy_id, bitrate, shared) VALUES ($NetworkRuleRateLimit.network_rule_id, $NetworkRuleRateLimit.policy_id, $NetworkRuleRateLimit.bitrate, $NetworkRuleRateLimit.shared)"
+	listNetworkRuleRateLimitsByPolicyStmt = "SELECT &NetworkRuleRateLimit.* FROM %s WHERE policy_id==$NetworkRuleRateLimit.policy_id"
+)
+
+// NetworkRuleRateLimit is the bitrate a "rate_limit" network rule polices
+// matching traffic to, e.g. "5 Mbps". Each session gets its own bucket unless
+// Shared, in which case every session of the policy draws from one.
+type NetworkRuleRateLimit struct {
+	NetworkRuleID string `db:"network_rule_id"` // FK to network_rules.id
+	PolicyID      string `db:"policy_id"`       // FK to policies.id
+	Bitrate       string `db:"bitrate"`
+	Shared        bool   `db:"shared"`
+}
+
+// hasRateLimitRules reports whether any rule in the payload is rate limited.
+func (r *PolicyRulesInput) hasRateLimitRules() bool {
+	if r == nil {
+		return false
+	}
+
+	for _, rule := range r.Uplink {
+		if rule.Action == RuleActionRateLimit {
+			return true
+		}
+	}
+
+	for _, rule := range r.Downlink {
+		if rule.Action == RuleActionRateLimit {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (db *Database) insertNetworkRuleRateLimit(ctx context.Context, nr *NetworkRule, rule PolicyRuleInput) error {
+	row := &NetworkRuleRateLimit{
+		NetworkRuleID: nr.ID,
+		PolicyID:      nr.PolicyID,
+		Bitrate:       rule.RateLimit,
+		Shared:        rule.RateLimitShared,
+	}
+
+	if err := db.runner(ctx).Query(ctx, db.createNetworkRuleRateLimitStmt, row).Run(); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil
+}
+
+// ListNetworkRuleRateLimitsByPolicy returns the bitrates of a policy's
+// rate-limited rules, keyed by network rule ID.
+func (db *Database) ListNetworkRuleRateLimitsByPolicy(ctx context.Context, policyID string) (map[string]NetworkRuleRateLimit, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NetworkRuleRateLimitsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NetworkRuleRateLimitsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(networkRuleRateLimitsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return map[string]NetworkRuleRateLimit{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkRuleRateLimitsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkRuleRateLimitsTableName, "select").Inc()
+
+	var rows []NetworkRuleRateLimit
+
+	err := db.conn().Query(ctx, db.listNetworkRuleRateLimitsByPolicyStmt, NetworkRuleRateLimit{PolicyID: policyID}).GetAll(&rows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	out := make(map[string]NetworkRuleRateLimit, len(rows))
+	for _, row := range rows {
+		out[row.NetworkRuleID] = row
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return out, nil
+}
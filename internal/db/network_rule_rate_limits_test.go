@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestNetworkRuleRateLimitsFollowPolicyRules(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	if err := database.CreateDataNetwork(ctx, &db.DataNetwork{Name: "ratelimit-dnn", IPv4Pool: "10.49.0.0/24"}); err != nil {
+		t.Fatalf("Couldn't create data network: %s", err)
+	}
+
+	dataNetwork, err := database.GetDataNetwork(ctx, "ratelimit-dnn")
+	if err != nil {
+		t.Fatalf("Couldn't get data network: %s", err)
+	}
+
+	profileID, sliceID := createPolicyDeps(t, database, "ratelimit")
+
+	policy := &db.Policy{
+		Name:                "ratelimit-policy",
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "200 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkID:       dataNetwork.ID,
+		ProfileID:           profileID,
+		SliceID:             sliceID,
+	}
+
+	rules := &db.PolicyRulesInput{
+		Downlink: []db.PolicyRuleInput{
+			{Description: "software updates", Protocol: 6, PortLow: 443, PortHigh: 443, Action: db.RuleActionRateLimit, RateLimit: "2 Mbps", RateLimitShared: true},
+			{Description: "everything else", Action: "allow"},
+		},
+	}
+
+	if err := database.CreatePolicyWithRules(ctx, policy, rules, nil); err != nil {
+		t.Fatalf("Couldn't create policy: %s", err)
+	}
+
+	limits, err := database.ListNetworkRuleRateLimitsByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list rate limits: %s", err)
+	}
+
+	dbRules, err := database.ListRulesForPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list rules: %s", err)
+	}
+
+	if len(limits) != 1 {
+		t.Fatalf("expected 1 rate-limited rule, got %d", len(limits))
+	}
+
+	got, ok := limits[dbRules[0].ID]
+	if !ok {
+		t.Fatalf("rate limit not keyed by the first rule's ID")
+	}
+
+	if got.Bitrate != "2 Mbps" || !got.Shared {
+		t.Fatalf("unexpected rate limit row: %+v", got)
+	}
+
+	if dbRules[0].Action != db.RuleActionRateLimit {
+		t.Fatalf("expected action %q, got %q", db.RuleActionRateLimit, dbRules[0].Action)
+	}
+
+	rules.Downlink = rules.Downlink[1:]
+
+	if err := database.UpdatePolicyWithRules(ctx, policy, rules, nil); err != nil {
+		t.Fatalf("Couldn't update policy: %s", err)
+	}
+
+	limits, err = database.ListNetworkRuleRateLimitsByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list rate limits: %s", err)
+	}
+
+	if len(limits) != 0 {
+		t.Fatalf("expected rate limit rows to be deleted with their rules, got %d", len(limits))
+	}
+}
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const NetworkRuleRatingsTableName = "network_rule_ratings"
+
+// networkRuleRatingsSchema is the migration that introduced the
+// network_rule_ratings and daily_usage_rating_groups tables.
+const networkRuleRatingsSchema = 25
+
+const (
+	createNetworkRuleRatingStmt        = "INSERT INTO %s (network_rule_id, policy_id, rating_group, zero_rated) VALUES ($NetworkRuleRating.network_rule_id, $NetworkRuleRating.policy_id, $NetworkRuleRating.rating_group, $NetworkRuleRating.zero_rated)"
+	listNetworkRuleRatingsByPolicyStmt = "SELECT &NetworkRuleRating.* FROM %s WHERE policy_id==$NetworkRuleRating.policy_id"
+)
+
+// NetworkRuleRating is how the traffic a network rule passes is charged.
+// A non-zero RatingGroup counts it in daily_usage_rating_groups; ZeroRated
+// keeps it out of the subscriber's daily usage.
+type NetworkRuleRating struct {
+	NetworkRuleID string `db:"network_rule_id"` // FK to network_rules.id
+	PolicyID      string `db:"policy_id"`       // FK to policies.id
+	RatingGroup   int64  `db:"rating_group"`
+	ZeroRated     bool   `db:"zero_rated"`
+}
+
+func (rule PolicyRuleInput) isRated() bool {
+	return rule.RatingGroup != 0 || rule.ZeroRated
+}
+
+// hasRatedRules reports whether any rule in the payload carries a rating.
+func (r *PolicyRulesInput) hasRatedRules() bool {
+	if r == nil {
+		return false
+	}
+
+	for _, rule := range r.Uplink {
+		if rule.isRated() {
+			return true
+		}
+	}
+
+	for _, rule := range r.Downlink {
+		if rule.isRated() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (db *Database) insertNetworkRuleRating(ctx context.Context, nr *NetworkRule, rule PolicyRuleInput) error {
+	row := &NetworkRuleRating{
+		NetworkRuleID: nr.ID,
+		PolicyID:      nr.PolicyID,
+		RatingGroup:   int64(rule.RatingGroup),
+		ZeroRated:     rule.ZeroRated,
+	}
+
+	if err := db.runner(ctx).Query(ctx, db.createNetworkRuleRatingStmt, row).Run(); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil
+}
+
+// ListNetworkRuleRatingsByPolicy returns the ratings of a policy's rated
+// rules, keyed by network rule ID.
+func (db *Database) ListNetworkRuleRatingsByPolicy(ctx context.Context, policyID string) (map[string]NetworkRuleRating, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NetworkRuleRatingsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NetworkRuleRatingsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(networkRuleRatingsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return map[string]NetworkRuleRating{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkRuleRatingsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkRuleRatingsTableName, "select").Inc()
+
+	var rows []NetworkRuleRating
+
+	err := db.conn().Query(ctx, db.listNetworkRuleRatingsByPolicyStmt, NetworkRuleRating{PolicyID: policyID}).GetAll(&rows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	out := make(map[string]NetworkRuleRating, len(rows))
+	for _, row := range rows {
+		out[row.NetworkRuleID] = row
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return out, nil
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestNetworkRuleRatingsFollowPolicyRules(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	if err := database.CreateDataNetwork(ctx, &db.DataNetwork{Name: "rating-dnn", IPv4Pool: "10.51.0.0/24"}); err != nil {
+		t.Fatalf("Couldn't create data network: %s", err)
+	}
+
+	dataNetwork, err := database.GetDataNetwork(ctx, "rating-dnn")
+	if err != nil {
+		t.Fatalf("Couldn't get data network: %s", err)
+	}
+
+	profileID, sliceID := createPolicyDeps(t, database, "rating")
+
+	policy := &db.Policy{
+		Name:                "rating-policy",
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "200 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkID:       dataNetwork.ID,
+		ProfileID:           profileID,
+		SliceID:             sliceID,
+	}
+
+	portal := "198.51.100.0/24"
+	video := "203.0.113.0/24"
+
+	rules := &db.PolicyRulesInput{
+		Uplink: []db.PolicyRuleInput{
+			{Description: "self-care portal", RemotePrefix: &portal, Action: "allow", ZeroRated: true},
+			{Description: "video", RemotePrefix: &video, Action: "allow", RatingGroup: 20},
+			{Description: "everything else", Action: "allow"},
+		},
+	}
+
+	if err := database.CreatePolicyWithRules(ctx, policy, rules, nil); err != nil {
+		t.Fatalf("Couldn't create policy: %s", err)
+	}
+
+	ratings, err := database.ListNetworkRuleRatingsByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list ratings: %s", err)
+	}
+
+	dbRules, err := database.ListRulesForPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list rules: %s", err)
+	}
+
+	if len(ratings) != 2 {
+		t.Fatalf("expected 2 rated rules, got %d", len(ratings))
+	}
+
+	if got := ratings[dbRules[0].ID]; !got.ZeroRated || got.RatingGroup != 0 {
+		t.Fatalf("unexpected rating for the portal rule: %+v", got)
+	}
+
+	if got := ratings[dbRules[1].ID]; got.ZeroRated || got.RatingGroup != 20 {
+		t.Fatalf("unexpected rating for the video rule: %+v", got)
+	}
+
+	rules.Uplink = rules.Uplink[2:]
+
+	if err := database.UpdatePolicyWithRules(ctx, policy, rules, nil); err != nil {
+		t.Fatalf("Couldn't update policy: %s", err)
+	}
+
+	ratings, err = database.ListNetworkRuleRatingsByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list ratings: %s", err)
+	}
+
+	if len(ratings) != 0 {
+		t.Fatalf("expected rating rows to be deleted with their rules, got %d", len(ratings))
+	}
+}
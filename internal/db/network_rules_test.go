@@ -915,7 +915,7 @@ func TestUpdatePolicyWithRules_AllZeroRule_NoRaft(t *testing.T) {
 		},
 	}
 
-	if err := dbInstance.UpdatePolicyWithRules(ctx, policy, initial); err != nil {
+	if err := dbInstance.UpdatePolicyWithRules(ctx, policy, initial, nil); err != nil {
 		t.Fatalf("UpdatePolicyWithRules (initial): %v", err)
 	}
 
@@ -940,7 +940,7 @@ func TestUpdatePolicyWithRules_AllZeroRule_NoRaft(t *testing.T) {
 		},
 	}
 
-	if err := dbInstance.UpdatePolicyWithRules(ctx, policy, updated); err != nil {
+	if err := dbInstance.UpdatePolicyWithRules(ctx, policy, updated, nil); err != nil {
 		t.Fatalf("UpdatePolicyWithRules (with allow-all): %v", err)
 	}
 
@@ -1028,7 +1028,7 @@ func TestUpdatePolicyWithRules_AllZeroRule_Raft(t *testing.T) {
 		},
 	}
 
-	if err := database.UpdatePolicyWithRules(ctx, created, initial); err != nil {
+	if err := database.UpdatePolicyWithRules(ctx, created, initial, nil); err != nil {
 		t.Fatalf("UpdatePolicyWithRules (initial): %v", err)
 	}
 
@@ -1052,7 +1052,7 @@ func TestUpdatePolicyWithRules_AllZeroRule_Raft(t *testing.T) {
 		},
 	}
 
-	if err := database.UpdatePolicyWithRules(ctx, created, withAllZero); err != nil {
+	if err := database.UpdatePolicyWithRules(ctx, created, withAllZero, nil); err != nil {
 		t.Fatalf("UpdatePolicyWithRules (with allow-all): %v", err)
 	}
 
@@ -1138,7 +1138,7 @@ func TestUpdatePolicyWithRules_Raft_SurviveRestart(t *testing.T) {
 		}},
 	}
 
-	if err := database.UpdatePolicyWithRules(ctx, created, rules); err != nil {
+	if err := database.UpdatePolicyWithRules(ctx, created, rules, nil); err != nil {
 		t.Fatalf("UpdatePolicyWithRules: %v", err)
 	}
 
@@ -1266,7 +1266,7 @@ func TestUpdatePolicyWithRules_SnapshotRestore_SurviveRestart(t *testing.T) {
 		}},
 	}
 
-	if err := database.UpdatePolicyWithRules(ctx, created, rules); err != nil {
+	if err := database.UpdatePolicyWithRules(ctx, created, rules, nil); err != nil {
 		t.Fatalf("UpdatePolicyWithRules: %v", err)
 	}
 
@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	NetworkSliceQuotasTableName = "network_slice_quotas"
+	DataNetworkQuotasTableName  = "data_network_quotas"
+)
+
+// admissionQuotasSchema is the migration that introduced the quota tables
+// and the admission records. Reads below it report no quota, and admissions
+// go unrecorded.
+const admissionQuotasSchema = 36
+
+// DefaultQuotaBackOff is the back-off timer sent with a quota reject when the
+// quota does not set one.
+const DefaultQuotaBackOff = 5 * time.Minute
+
+const (
+	upsertNetworkSliceQuotaStmt  = "INSERT INTO %s (networkSliceID, maxRegisteredUEs, maxSessions, maxSessionsPerSubscriber, backOffTimer) VALUES ($NetworkSliceQuota.networkSliceID, $NetworkSliceQuota.maxRegisteredUEs, $NetworkSliceQuota.maxSessions, $NetworkSliceQuota.maxSessionsPerSubscriber, $NetworkSliceQuota.backOffTimer) ON CONFLICT(networkSliceID) DO UPDATE SET maxRegisteredUEs=excluded.maxRegisteredUEs, maxSessions=excluded.maxSessions, maxSessionsPerSubscriber=excluded.maxSessionsPerSubscriber, backOffTimer=excluded.backOffTimer"
+	deleteNetworkSliceQuotaStmt  = "DELETE FROM %s WHERE networkSliceID==$NetworkSliceQuota.networkSliceID"
+	getNetworkSliceQuotaStmt     = "SELECT &NetworkSliceQuota.* FROM %s WHERE networkSliceID==$NetworkSliceQuota.networkSliceID"
+	listAllNetworkSliceQuotaStmt = "SELECT &NetworkSliceQuota.* FROM %s ORDER BY networkSliceID"
+)
+
+// NetworkSliceQuota caps a network slice: the UEs registered to it, the
+// sessions open on it, and the sessions each subscriber may open on it. A
+// zero limit is no limit. BackOffTimer, in seconds, is how long a UE
+// refused by the quota waits before trying again; zero means
+// DefaultQuotaBackOff.
+type NetworkSliceQuota struct {
+	NetworkSliceID           string `db:"networkSliceID"` // FK to network_slices.id
+	MaxRegisteredUEs         int    `db:"maxRegisteredUEs"`
+	MaxSessions              int    `db:"maxSessions"`
+	MaxSessionsPerSubscriber int    `db:"maxSessionsPerSubscriber"`
+	BackOffTimer             int    `db:"backOffTimer"`
+}
+
+// BackOff is the back-off timer of a reject the quota causes.
+func (q *NetworkSliceQuota) BackOff() time.Duration {
+	return backOff(q.BackOffTimer)
+}
+
+func backOff(seconds int) time.Duration {
+	if seconds <= 0 {
+		return DefaultQuotaBackOff
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// SetNetworkSliceQuota sets a network slice's quota, replacing any previous
+// one.
+func (db *Database) SetNetworkSliceQuota(ctx context.Context, quota *NetworkSliceQuota) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", NetworkSliceQuotasTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", NetworkSliceQuotasTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkSliceQuotasTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkSliceQuotasTableName, "upsert").Inc()
+
+	_, err := opSetNetworkSliceQuota.Invoke(db, quota)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetNetworkSliceQuota(ctx context.Context, quota *NetworkSliceQuota) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertNetworkSliceQuotaStmt, quota).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearNetworkSliceQuota lifts a network slice's quota. Clearing a slice
+// without one is not an error.
+func (db *Database) ClearNetworkSliceQuota(ctx context.Context, networkSliceID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", NetworkSliceQuotasTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", NetworkSliceQuotasTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkSliceQuotasTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkSliceQuotasTableName, "delete").Inc()
+
+	_, err := opClearNetworkSliceQuota.Invoke(db, &NetworkSliceQuota{NetworkSliceID: networkSliceID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearNetworkSliceQuota(ctx context.Context, quota *NetworkSliceQuota) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteNetworkSliceQuotaStmt, quota).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetNetworkSliceQuota returns ErrNotFound when the network slice has no
+// quota.
+func (db *Database) GetNetworkSliceQuota(ctx context.Context, networkSliceID string) (*NetworkSliceQuota, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NetworkSliceQuotasTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NetworkSliceQuotasTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkSliceQuotasTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkSliceQuotasTableName, "select").Inc()
+
+	row := NetworkSliceQuota{NetworkSliceID: networkSliceID}
+
+	err := db.conn().Query(ctx, db.getNetworkSliceQuotaStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+func (db *Database) ListAllNetworkSliceQuotas(ctx context.Context) ([]NetworkSliceQuota, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NetworkSliceQuotasTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NetworkSliceQuotasTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []NetworkSliceQuota{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkSliceQuotasTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkSliceQuotasTableName, "select").Inc()
+
+	var rows []NetworkSliceQuota
+
+	err := db.conn().Query(ctx, db.listAllNetworkSliceQuotaStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []NetworkSliceQuota{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestNetworkSliceQuotaEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	slice := &db.NetworkSlice{Name: "tenant-a", Sst: 1}
+
+	if err := database.CreateNetworkSlice(ctx, slice); err != nil {
+		t.Fatalf("couldn't create network slice: %s", err)
+	}
+
+	if _, err := database.GetNetworkSliceQuota(ctx, slice.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before a quota is set, got %v", err)
+	}
+
+	if err := database.SetNetworkSliceQuota(ctx, &db.NetworkSliceQuota{NetworkSliceID: slice.ID, MaxRegisteredUEs: 100}); err != nil {
+		t.Fatalf("couldn't set quota: %s", err)
+	}
+
+	got, err := database.GetNetworkSliceQuota(ctx, slice.ID)
+	if err != nil {
+		t.Fatalf("couldn't get quota: %s", err)
+	}
+
+	if got.MaxRegisteredUEs != 100 || got.MaxSessions != 0 || got.BackOff() != db.DefaultQuotaBackOff {
+		t.Fatalf("unexpected quota %+v", got)
+	}
+
+	if err := database.SetNetworkSliceQuota(ctx, &db.NetworkSliceQuota{NetworkSliceID: slice.ID, MaxSessions: 200, MaxSessionsPerSubscriber: 2, BackOffTimer: 60}); err != nil {
+		t.Fatalf("couldn't replace quota: %s", err)
+	}
+
+	got, err = database.GetNetworkSliceQuota(ctx, slice.ID)
+	if err != nil {
+		t.Fatalf("couldn't get quota: %s", err)
+	}
+
+	if got.MaxRegisteredUEs != 0 || got.MaxSessions != 200 || got.MaxSessionsPerSubscriber != 2 || got.BackOff() != time.Minute {
+		t.Fatalf("expected the quota to be replaced, got %+v", got)
+	}
+
+	if err := database.ClearNetworkSliceQuota(ctx, slice.ID); err != nil {
+		t.Fatalf("couldn't clear quota: %s", err)
+	}
+
+	if _, err := database.GetNetworkSliceQuota(ctx, slice.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+
+	if err := database.SetNetworkSliceQuota(ctx, &db.NetworkSliceQuota{NetworkSliceID: slice.ID, MaxSessions: 10}); err != nil {
+		t.Fatalf("couldn't set quota: %s", err)
+	}
+
+	if err := database.DeleteNetworkSlice(ctx, "tenant-a"); err != nil {
+		t.Fatalf("couldn't delete network slice: %s", err)
+	}
+
+	rows, err := database.ListAllNetworkSliceQuotas(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list quotas: %s", err)
+	}
+
+	if len(rows) != 0 {
+		t.Fatalf("expected the quota to be deleted with its slice, got %+v", rows)
+	}
+}
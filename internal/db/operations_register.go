@@ -7,6 +7,7 @@
 package db
 
 import (
+	"github.com/ellanetworks/core/internal/models"
 	ellaraft "github.com/ellanetworks/core/internal/raft"
 )
 
@@ -16,6 +17,7 @@ var (
 	opUpdateSubscriberProfile = registerChangesetOp("UpdateSubscriberProfile", (*Database).applyUpdateSubscriberProfile, AffectsTopic(TopicSessionReconcile))
 	opEditSubscriberSeqNum    = registerChangesetOp("EditSubscriberSeqNum", (*Database).applyEditSubscriberSeqNum)
 	opDeleteSubscriber        = registerChangesetOp("DeleteSubscriber", (*Database).applyDeleteSubscriber)
+	opImportSubscribers       = registerChangesetOpReturning[importSubscribersPayload, SubscriberImportResult]("ImportSubscribers", (*Database).applyImportSubscribers)
 )
 
 // Daily usage
@@ -24,6 +26,11 @@ var (
 	opClearDailyUsage     = registerChangesetOp("ClearDailyUsage", (*Database).applyClearDailyUsageOp)
 )
 
+// Rating group usage. daily_usage_rating_groups table introduced in v25.
+var (
+	opIncrementRatingGroupUsage = registerChangesetOp("IncrementRatingGroupUsage", (*Database).applyIncrementRatingGroupUsage, RequireSchema(25))
+)
+
 // IP leases. ip_leases.nodeID added in v9.
 var (
 	opCreateLease               = registerChangesetOp("CreateLease", (*Database).applyCreateLease, RequireSchema(9), AffectsTopic(TopicIPLeases))
@@ -85,14 +92,153 @@ var (
 var (
 	opCreateDataNetwork = registerChangesetOp("CreateDataNetwork", (*Database).applyCreateDataNetwork, AffectsTopic(TopicDataNetworks), AffectsTopic(TopicSessionReconcile))
 	opUpdateDataNetwork = registerChangesetOp("UpdateDataNetwork", (*Database).applyUpdateDataNetwork, AffectsTopic(TopicDataNetworks), AffectsTopic(TopicSessionReconcile))
-	opDeleteDataNetwork = registerChangesetOp("DeleteDataNetwork", (*Database).applyDeleteDataNetwork, AffectsTopic(TopicDataNetworks), AffectsTopic(TopicSessionReconcile))
+	opDeleteDataNetwork = registerChangesetOp("DeleteDataNetwork", (*Database).applyDeleteDataNetwork, AffectsTopic(TopicDataNetworks), AffectsTopic(TopicSessionReconcile), AffectsTopic(TopicDataNetworkEgress), AffectsTopic(TopicDataNetworkNAT), AffectsTopic(TopicDataNetworkTCPMSS), AffectsTopic(TopicDNSResolvers), AffectsTopic(TopicAddressAllocation), AffectsTopic(TopicSecondaryAuth), AffectsTopic(TopicOnlineCharging), AffectsTopic(TopicPolicyControl))
+)
+
+// Data network egress. data_network_egress table introduced in v18.
+var (
+	opCreateDataNetworkWithEgress = registerChangesetOp("CreateDataNetworkWithEgress", (*Database).applyCreateDataNetworkWithEgress, RequireSchema(18), AffectsTopic(TopicDataNetworks), AffectsTopic(TopicSessionReconcile), AffectsTopic(TopicDataNetworkEgress))
+	opUpdateDataNetworkWithEgress = registerChangesetOp("UpdateDataNetworkWithEgress", (*Database).applyUpdateDataNetworkWithEgress, RequireSchema(18), AffectsTopic(TopicDataNetworks), AffectsTopic(TopicSessionReconcile), AffectsTopic(TopicDataNetworkEgress))
+)
+
+// Data network NAT pools and port forwards. Tables introduced in v19.
+var (
+	opSetDataNetworkNAT    = registerChangesetOp("SetDataNetworkNAT", (*Database).applySetDataNetworkNAT, RequireSchema(19), AffectsTopic(TopicDataNetworkNAT))
+	opCreateNATPortForward = registerChangesetOp("CreateNATPortForward", (*Database).applyCreateNATPortForward, RequireSchema(19), AffectsTopic(TopicDataNetworkNAT))
+	opDeleteNATPortForward = registerChangesetOp("DeleteNATPortForward", (*Database).applyDeleteNATPortForward, RequireSchema(19), AffectsTopic(TopicDataNetworkNAT))
+)
+
+// Data network TCP MSS clamping. data_network_tcp_mss table introduced in v24.
+var (
+	opSetDataNetworkTCPMSS   = registerChangesetOp("SetDataNetworkTCPMSS", (*Database).applySetDataNetworkTCPMSS, RequireSchema(24), AffectsTopic(TopicDataNetworkTCPMSS))
+	opClearDataNetworkTCPMSS = registerChangesetOp("ClearDataNetworkTCPMSS", (*Database).applyClearDataNetworkTCPMSS, RequireSchema(24), AffectsTopic(TopicDataNetworkTCPMSS))
+)
+
+// Data network DNS forwarders and local records. Tables introduced in v27.
+// A forwarder changes the DNS server sessions are handed.
+var (
+	opSetDataNetworkDNSResolver   = registerChangesetOp("SetDataNetworkDNSResolver", (*Database).applySetDataNetworkDNSResolver, RequireSchema(27), AffectsTopic(TopicDNSResolvers), AffectsTopic(TopicSessionReconcile))
+	opClearDataNetworkDNSResolver = registerChangesetOp("ClearDataNetworkDNSResolver", (*Database).applyClearDataNetworkDNSResolver, RequireSchema(27), AffectsTopic(TopicDNSResolvers), AffectsTopic(TopicSessionReconcile))
+	opCreateDNSLocalRecord        = registerChangesetOp("CreateDNSLocalRecord", (*Database).applyCreateDNSLocalRecord, RequireSchema(27), AffectsTopic(TopicDNSResolvers))
+	opDeleteDNSLocalRecord        = registerChangesetOp("DeleteDNSLocalRecord", (*Database).applyDeleteDNSLocalRecord, RequireSchema(27), AffectsTopic(TopicDNSResolvers))
+)
+
+// External address allocation. Tables introduced in v28. An external lease
+// is an ip_leases row plus its server details, so BGP and the lease views
+// see it like any other dynamic lease.
+var (
+	opSetDataNetworkAddressAllocation   = registerChangesetOp("SetDataNetworkAddressAllocation", (*Database).applySetDataNetworkAddressAllocation, RequireSchema(28), AffectsTopic(TopicAddressAllocation))
+	opClearDataNetworkAddressAllocation = registerChangesetOp("ClearDataNetworkAddressAllocation", (*Database).applyClearDataNetworkAddressAllocation, RequireSchema(28), AffectsTopic(TopicAddressAllocation))
+	opCreateExternalLease               = registerChangesetOpReturning[createExternalLeasePayload, string]("CreateExternalLease", (*Database).applyCreateExternalLease, RequireSchema(28), AffectsTopic(TopicIPLeases))
+	opUpdateExternalLeaseTimers         = registerChangesetOp("UpdateExternalLeaseTimers", (*Database).applyUpdateExternalLeaseTimers, RequireSchema(28))
+)
+
+// Secondary authentication. data_network_secondary_auth table introduced in
+// v29.
+var (
+	opSetDataNetworkSecondaryAuth   = registerChangesetOp("SetDataNetworkSecondaryAuth", (*Database).applySetDataNetworkSecondaryAuth, RequireSchema(29), AffectsTopic(TopicSecondaryAuth))
+	opClearDataNetworkSecondaryAuth = registerChangesetOp("ClearDataNetworkSecondaryAuth", (*Database).applyClearDataNetworkSecondaryAuth, RequireSchema(29), AffectsTopic(TopicSecondaryAuth))
+)
+
+// RADIUS accounting. accounting_servers table introduced in v30.
+var (
+	opCreateAccountingServer = registerChangesetOp("CreateAccountingServer", (*Database).applyCreateAccountingServer, RequireSchema(30), AffectsTopic(TopicAccountingServers))
+	opUpdateAccountingServer = registerChangesetOp("UpdateAccountingServer", (*Database).applyUpdateAccountingServer, RequireSchema(30), AffectsTopic(TopicAccountingServers))
+	opDeleteAccountingServer = registerChangesetOp("DeleteAccountingServer", (*Database).applyDeleteAccountingServer, RequireSchema(30), AffectsTopic(TopicAccountingServers))
+)
+
+// Online charging. data_network_online_charging table introduced in v31.
+var (
+	opSetDataNetworkOnlineCharging   = registerChangesetOp("SetDataNetworkOnlineCharging", (*Database).applySetDataNetworkOnlineCharging, RequireSchema(31), AffectsTopic(TopicOnlineCharging))
+	opClearDataNetworkOnlineCharging = registerChangesetOp("ClearDataNetworkOnlineCharging", (*Database).applyClearDataNetworkOnlineCharging, RequireSchema(31), AffectsTopic(TopicOnlineCharging))
+)
+
+// External policy control. data_network_policy_control and
+// sm_policy_associations tables introduced in v32.
+var (
+	opSetDataNetworkPolicyControl      = registerChangesetOp("SetDataNetworkPolicyControl", (*Database).applySetDataNetworkPolicyControl, RequireSchema(32), AffectsTopic(TopicPolicyControl))
+	opClearDataNetworkPolicyControl    = registerChangesetOp("ClearDataNetworkPolicyControl", (*Database).applyClearDataNetworkPolicyControl, RequireSchema(32), AffectsTopic(TopicPolicyControl))
+	opCreateSMPolicyAssociation        = registerChangesetOp("CreateSMPolicyAssociation", (*Database).applyCreateSMPolicyAssociation, RequireSchema(32), AffectsTopic(TopicPolicyControl))
+	opUpdateSMPolicyDecision           = registerChangesetOp("UpdateSMPolicyDecision", (*Database).applyUpdateSMPolicyDecision, RequireSchema(32), AffectsTopic(TopicPolicyControl))
+	opDeleteSMPolicyAssociation        = registerChangesetOp("DeleteSMPolicyAssociation", (*Database).applyDeleteSMPolicyAssociation, RequireSchema(32), AffectsTopic(TopicPolicyControl))
+	opDeleteSMPolicyAssociationsByNode = registerChangesetOp("DeleteSMPolicyAssociationsByNode", (*Database).applyDeleteSMPolicyAssociationsByNode, RequireSchema(32), AffectsTopic(TopicPolicyControl))
+)
+
+// Application QoS sessions. qos_sessions table introduced in v33.
+var (
+	opCreateQosSession       = registerChangesetOp("CreateQosSession", (*Database).applyCreateQosSession, RequireSchema(33), AffectsTopic(TopicQosSessions))
+	opClaimQosSession        = registerChangesetOp("ClaimQosSession", (*Database).applyClaimQosSession, RequireSchema(33), AffectsTopic(TopicQosSessions))
+	opUpdateQosSessionStatus = registerChangesetOp("UpdateQosSessionStatus", (*Database).applyUpdateQosSessionStatus, RequireSchema(33), AffectsTopic(TopicQosSessions))
+	opUpdateQosSessionPolicy = registerChangesetOp("UpdateQosSessionPolicy", (*Database).applyUpdateQosSessionPolicy, RequireSchema(33), AffectsTopic(TopicQosSessions))
+	opDeleteQosSession       = registerChangesetOp("DeleteQosSession", (*Database).applyDeleteQosSession, RequireSchema(33), AffectsTopic(TopicQosSessions))
+)
+
+// Monitoring event subscriptions. monitoring_subscriptions table introduced
+// in v34.
+var (
+	opCreateMonitoringSubscription         = registerChangesetOp("CreateMonitoringSubscription", (*Database).applyCreateMonitoringSubscription, RequireSchema(34), AffectsTopic(TopicMonitoringSubscriptions))
+	opDeleteMonitoringSubscription         = registerChangesetOp("DeleteMonitoringSubscription", (*Database).applyDeleteMonitoringSubscription, RequireSchema(34), AffectsTopic(TopicMonitoringSubscriptions))
+	opRecordMonitoringReport               = registerChangesetOp("RecordMonitoringReport", (*Database).applyRecordMonitoringReport, RequireSchema(34), AffectsTopic(TopicMonitoringSubscriptions))
+	opDeleteExpiredMonitoringSubscriptions = registerChangesetOp("DeleteExpiredMonitoringSubscriptions", (*Database).applyDeleteExpiredMonitoringSubscriptions, RequireSchema(34), AffectsTopic(TopicMonitoringSubscriptions))
+)
+
+// Subscriber AMBR overrides. subscriber_ambr_overrides table introduced in
+// v35. Every write changes the bit rates of live sessions.
+var (
+	opSetSubscriberAmbrOverride            = registerChangesetOp("SetSubscriberAmbrOverride", (*Database).applySetSubscriberAmbrOverride, RequireSchema(35), AffectsTopic(TopicSessionReconcile))
+	opDeleteSubscriberAmbrOverride         = registerChangesetOp("DeleteSubscriberAmbrOverride", (*Database).applyDeleteSubscriberAmbrOverride, RequireSchema(35), AffectsTopic(TopicSessionReconcile))
+	opDeleteExpiredSubscriberAmbrOverrides = registerChangesetOp("DeleteExpiredSubscriberAmbrOverrides", (*Database).applyDeleteExpiredSubscriberAmbrOverrides, RequireSchema(35), AffectsTopic(TopicSessionReconcile))
+)
+
+// Admission quotas. network_slice_quotas and data_network_quotas tables
+// introduced in v36. Quotas are read at admission, so writes notify no one.
+var (
+	opSetNetworkSliceQuota   = registerChangesetOp("SetNetworkSliceQuota", (*Database).applySetNetworkSliceQuota, RequireSchema(36))
+	opClearNetworkSliceQuota = registerChangesetOp("ClearNetworkSliceQuota", (*Database).applyClearNetworkSliceQuota, RequireSchema(36))
+	opSetDataNetworkQuota    = registerChangesetOp("SetDataNetworkQuota", (*Database).applySetDataNetworkQuota, RequireSchema(36))
+	opClearDataNetworkQuota  = registerChangesetOp("ClearDataNetworkQuota", (*Database).applyClearDataNetworkQuota, RequireSchema(36))
+)
+
+// Subscriber lifecycles. subscriber_lifecycles table introduced in v37. Every
+// write may end a subscriber's registrations or sessions.
+var (
+	opSetSubscriberLifecycle     = registerChangesetOp("SetSubscriberLifecycle", (*Database).applySetSubscriberLifecycle, RequireSchema(37), AffectsTopic(TopicSessionReconcile))
+	opDeleteSubscriberLifecycle  = registerChangesetOp("DeleteSubscriberLifecycle", (*Database).applyDeleteSubscriberLifecycle, RequireSchema(37), AffectsTopic(TopicSessionReconcile))
+	opExpireSubscriberLifecycles = registerChangesetOp("ExpireSubscriberLifecycles", (*Database).applyExpireSubscriberLifecycles, RequireSchema(37), AffectsTopic(TopicSessionReconcile))
+)
+
+// Policy location variants. policy_location_variants table introduced in
+// v38. Every write may change the Session-AMBR of live sessions.
+var (
+	opCreatePolicyLocationVariant = registerChangesetOp("CreatePolicyLocationVariant", (*Database).applyCreatePolicyLocationVariant, RequireSchema(38), AffectsTopic(TopicSessionReconcile))
+	opDeletePolicyLocationVariant = registerChangesetOp("DeletePolicyLocationVariant", (*Database).applyDeletePolicyLocationVariant, RequireSchema(38), AffectsTopic(TopicSessionReconcile))
+)
+
+// Admission records. slice_registrations and admitted_sessions tables
+// introduced in v39. They are counted at admission, so writes notify no one.
+var (
+	opAdmitSliceRegistrations     = registerChangesetOpReturning[admitSliceRegistrationsPayload, []int]("AdmitSliceRegistrations", (*Database).applyAdmitSliceRegistrations, RequireSchema(36))
+	opReleaseSliceRegistrations   = registerChangesetOp("ReleaseSliceRegistrations", (*Database).applyReleaseSliceRegistrations, RequireSchema(36))
+	opAdmitSession                = registerChangesetOpReturning[admitSessionPayload, models.Quota]("AdmitSession", (*Database).applyAdmitSession, RequireSchema(36))
+	opReleaseAdmittedSession      = registerChangesetOp("ReleaseAdmittedSession", (*Database).applyReleaseAdmittedSession, RequireSchema(36))
+	opReconcileQuotaAdmissions    = registerChangesetOp("ReconcileQuotaAdmissions", (*Database).applyReconcileQuotaAdmissions, RequireSchema(36))
+	opDeleteQuotaAdmissionsByNode = registerChangesetOp("DeleteQuotaAdmissionsByNode", (*Database).applyDeleteQuotaAdmissionsByNode, RequireSchema(36))
+)
+
+// Captive portals. policy_captive_portals and captive_portal_lifts tables
+// introduced in v26.
+var (
+	opSetPolicyCaptivePortal   = registerChangesetOp("SetPolicyCaptivePortal", (*Database).applySetPolicyCaptivePortal, RequireSchema(26), AffectsTopic(TopicCaptivePortals))
+	opClearPolicyCaptivePortal = registerChangesetOp("ClearPolicyCaptivePortal", (*Database).applyClearPolicyCaptivePortal, RequireSchema(26), AffectsTopic(TopicCaptivePortals))
+	opLiftCaptivePortal        = registerChangesetOp("LiftCaptivePortal", (*Database).applyLiftCaptivePortal, RequireSchema(26), AffectsTopic(TopicCaptivePortals))
+	opRestoreCaptivePortal     = registerChangesetOp("RestoreCaptivePortal", (*Database).applyRestoreCaptivePortal, RequireSchema(26), AffectsTopic(TopicCaptivePortals))
 )
 
 // Policies
 var (
 	opCreatePolicy          = registerChangesetOp("CreatePolicy", (*Database).applyCreatePolicy, AffectsTopic(TopicPolicies), AffectsTopic(TopicSessionReconcile))
 	opUpdatePolicy          = registerChangesetOp("UpdatePolicy", (*Database).applyUpdatePolicy, AffectsTopic(TopicPolicies), AffectsTopic(TopicSessionReconcile))
-	opDeletePolicy          = registerChangesetOp("DeletePolicy", (*Database).applyDeletePolicy, AffectsTopic(TopicPolicies), AffectsTopic(TopicSessionReconcile))
+	opDeletePolicy          = registerChangesetOp("DeletePolicy", (*Database).applyDeletePolicy, AffectsTopic(TopicPolicies), AffectsTopic(TopicSessionReconcile), AffectsTopic(TopicPolicyControl))
 	opSetDefaultPolicy      = registerChangesetOp("SetDefaultPolicy", (*Database).applySetDefaultPolicy, RequireSchema(14), AffectsTopic(TopicPolicies), AffectsTopic(TopicSessionReconcile))
 	opCreatePolicyWithRules = registerChangesetOp("CreatePolicyWithRules", (*Database).applyCreatePolicyWithRules, AffectsTopic(TopicPolicies), AffectsTopic(TopicNetworkRules), AffectsTopic(TopicSessionReconcile))
 	opUpdatePolicyWithRules = registerChangesetOp("UpdatePolicyWithRules", (*Database).applyUpdatePolicyWithRules, AffectsTopic(TopicPolicies), AffectsTopic(TopicNetworkRules), AffectsTopic(TopicSessionReconcile))
@@ -106,6 +252,13 @@ var (
 	opDeleteNetworkRulesByPolicy = registerChangesetOp("DeleteNetworkRulesByPolicy", (*Database).applyDeleteNetworkRulesByPolicy, AffectsTopic(TopicNetworkRules))
 )
 
+// Schedules
+var (
+	opCreateSchedule = registerChangesetOp("CreateSchedule", (*Database).applyCreateSchedule, RequireSchema(22), AffectsTopic(TopicSchedules))
+	opUpdateSchedule = registerChangesetOp("UpdateSchedule", (*Database).applyUpdateSchedule, RequireSchema(22), AffectsTopic(TopicSchedules), AffectsTopic(TopicSessionReconcile))
+	opDeleteSchedule = registerChangesetOp("DeleteSchedule", (*Database).applyDeleteSchedule, RequireSchema(22), AffectsTopic(TopicSchedules))
+)
+
 // Framed routes
 var (
 	opReplaceFramedRoutes = registerChangesetOp("ReplaceFramedRoutes", (*Database).applyReplaceFramedRoutes, RequireSchema(16), AffectsTopic(TopicSessionReconcile), AffectsTopic(TopicFramedRoutes))
@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const PolicyCaptivePortalsTableName = "policy_captive_portals"
+
+// captivePortalSchema is the migration that introduced the captive portal
+// tables. Reads below it report every policy out of redirect mode and no
+// redirect lifted.
+const captivePortalSchema = 26
+
+const (
+	upsertPolicyCaptivePortalStmt   = "INSERT INTO %s (policyID, portalAddress) VALUES ($PolicyCaptivePortal.policyID, $PolicyCaptivePortal.portalAddress) ON CONFLICT(policyID) DO UPDATE SET portalAddress=excluded.portalAddress"
+	deletePolicyCaptivePortalStmt   = "DELETE FROM %s WHERE policyID==$PolicyCaptivePortal.policyID"
+	getPolicyCaptivePortalStmt      = "SELECT &PolicyCaptivePortal.* FROM %s WHERE policyID==$PolicyCaptivePortal.policyID"
+	listAllPolicyCaptivePortalsStmt = "SELECT &PolicyCaptivePortal.* FROM %s ORDER BY policyID"
+)
+
+// PolicyCaptivePortal puts a policy in redirect mode: its subscribers'
+// DNS and web traffic goes to the portal at PortalAddress, an IPv4 address
+// in the data network, and the rest of their traffic is dropped until the
+// redirect is lifted for them.
+type PolicyCaptivePortal struct {
+	PolicyID      string `db:"policyID"` // FK to policies.id
+	PortalAddress string `db:"portalAddress"`
+}
+
+// SetPolicyCaptivePortal puts a policy in redirect mode, or changes its
+// portal.
+func (db *Database) SetPolicyCaptivePortal(ctx context.Context, portal *PolicyCaptivePortal) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", PolicyCaptivePortalsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", PolicyCaptivePortalsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicyCaptivePortalsTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicyCaptivePortalsTableName, "upsert").Inc()
+
+	_, err := opSetPolicyCaptivePortal.Invoke(db, portal)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetPolicyCaptivePortal(ctx context.Context, portal *PolicyCaptivePortal) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.upsertPolicyCaptivePortalStmt, portal).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ClearPolicyCaptivePortal takes a policy out of redirect mode. Clearing a
+// policy not in it is not an error.
+func (db *Database) ClearPolicyCaptivePortal(ctx context.Context, policyID string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", PolicyCaptivePortalsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", PolicyCaptivePortalsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicyCaptivePortalsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicyCaptivePortalsTableName, "delete").Inc()
+
+	_, err := opClearPolicyCaptivePortal.Invoke(db, &PolicyCaptivePortal{PolicyID: policyID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClearPolicyCaptivePortal(ctx context.Context, portal *PolicyCaptivePortal) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deletePolicyCaptivePortalStmt, portal).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetPolicyCaptivePortal returns ErrNotFound when the policy is not in
+// redirect mode.
+func (db *Database) GetPolicyCaptivePortal(ctx context.Context, policyID string) (*PolicyCaptivePortal, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", PolicyCaptivePortalsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", PolicyCaptivePortalsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(captivePortalSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicyCaptivePortalsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicyCaptivePortalsTableName, "select").Inc()
+
+	row := PolicyCaptivePortal{PolicyID: policyID}
+
+	err := db.conn().Query(ctx, db.getPolicyCaptivePortalStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+func (db *Database) ListAllPolicyCaptivePortals(ctx context.Context) ([]PolicyCaptivePortal, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", PolicyCaptivePortalsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", PolicyCaptivePortalsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(captivePortalSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []PolicyCaptivePortal{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicyCaptivePortalsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicyCaptivePortalsTableName, "select").Inc()
+
+	var rows []PolicyCaptivePortal
+
+	err := db.conn().Query(ctx, db.listAllPolicyCaptivePortalsStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []PolicyCaptivePortal{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
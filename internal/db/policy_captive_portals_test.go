@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestPolicyCaptivePortalEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	if err := database.CreateDataNetwork(ctx, &db.DataNetwork{Name: "portal-dnn", IPv4Pool: "10.52.0.0/24"}); err != nil {
+		t.Fatalf("Couldn't create data network: %s", err)
+	}
+
+	dataNetwork, err := database.GetDataNetwork(ctx, "portal-dnn")
+	if err != nil {
+		t.Fatalf("Couldn't get data network: %s", err)
+	}
+
+	profileID, sliceID := createPolicyDeps(t, database, "portal")
+
+	policy := &db.Policy{
+		Name:                "portal-policy",
+		SessionAmbrUplink:   "100 Mbps",
+		SessionAmbrDownlink: "200 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkID:       dataNetwork.ID,
+		ProfileID:           profileID,
+		SliceID:             sliceID,
+	}
+
+	if err := database.CreatePolicy(ctx, policy); err != nil {
+		t.Fatalf("Couldn't create policy: %s", err)
+	}
+
+	if _, err := database.GetPolicyCaptivePortal(ctx, policy.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before redirect mode is turned on, got %v", err)
+	}
+
+	if err := database.SetPolicyCaptivePortal(ctx, &db.PolicyCaptivePortal{PolicyID: policy.ID, PortalAddress: "192.0.2.10"}); err != nil {
+		t.Fatalf("couldn't turn on redirect mode: %s", err)
+	}
+
+	if err := database.SetPolicyCaptivePortal(ctx, &db.PolicyCaptivePortal{PolicyID: policy.ID, PortalAddress: "192.0.2.20"}); err != nil {
+		t.Fatalf("couldn't change the portal: %s", err)
+	}
+
+	got, err := database.GetPolicyCaptivePortal(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("couldn't get captive portal: %s", err)
+	}
+
+	if got.PortalAddress != "192.0.2.20" {
+		t.Fatalf("portal = %q, want 192.0.2.20", got.PortalAddress)
+	}
+
+	if err := database.ClearPolicyCaptivePortal(ctx, policy.ID); err != nil {
+		t.Fatalf("couldn't turn off redirect mode: %s", err)
+	}
+
+	if _, err := database.GetPolicyCaptivePortal(ctx, policy.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after clearing, got %v", err)
+	}
+
+	if err := database.SetPolicyCaptivePortal(ctx, &db.PolicyCaptivePortal{PolicyID: policy.ID, PortalAddress: "192.0.2.10"}); err != nil {
+		t.Fatalf("couldn't turn on redirect mode: %s", err)
+	}
+
+	if err := database.DeletePolicy(ctx, policy.Name); err != nil {
+		t.Fatalf("couldn't delete policy: %s", err)
+	}
+
+	rows, err := database.ListAllPolicyCaptivePortals(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list captive portals: %s", err)
+	}
+
+	if len(rows) != 0 {
+		t.Fatalf("expected the captive portal to be deleted with its policy, got %+v", rows)
+	}
+}
@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/canonical/sqlair"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	PolicyLocationVariantsTableName = "policy_location_variants"
+)
+
+// policyLocationVariantsSchema is the migration that introduced the table.
+// Reads below it report no variants, so every UE gets its policy's own
+// Session-AMBR.
+const policyLocationVariantsSchema = 38
+
+const (
+	createPolicyLocationVariantStmt        = "INSERT INTO %s (id, policyID, name, tac, cellID, sessionAmbrUplink, sessionAmbrDownlink, rulesPolicyID) VALUES ($PolicyLocationVariant.id, $PolicyLocationVariant.policyID, $PolicyLocationVariant.name, $PolicyLocationVariant.tac, $PolicyLocationVariant.cellID, $PolicyLocationVariant.sessionAmbrUplink, $PolicyLocationVariant.sessionAmbrDownlink, $PolicyLocationVariant.rulesPolicyID)"
+	getPolicyLocationVariantStmt           = "SELECT &PolicyLocationVariant.* FROM %s WHERE id==$PolicyLocationVariant.id"
+	deletePolicyLocationVariantStmt        = "DELETE FROM %s WHERE id==$PolicyLocationVariant.id"
+	listPolicyLocationVariantsByPolicyStmt = "SELECT &PolicyLocationVariant.* FROM %s WHERE policyID==$PolicyLocationVariant.policyID ORDER BY name"
+)
+
+// PolicyLocationVariant replaces a policy's Session-AMBR for UEs served in
+// one area: the tracking area TAC, or the single cell CellID. Exactly one of
+// the two is set, in the lower-case hex the AMF and MME record. A non-nil
+// RulesPolicyID also puts the network rules of that policy, one of the same
+// data network, in force there.
+type PolicyLocationVariant struct {
+	ID                  string  `db:"id"`       // UUIDv7
+	PolicyID            string  `db:"policyID"` // FK to policies.id
+	Name                string  `db:"name"`
+	TAC                 string  `db:"tac"`
+	CellID              string  `db:"cellID"`
+	SessionAmbrUplink   string  `db:"sessionAmbrUplink"`
+	SessionAmbrDownlink string  `db:"sessionAmbrDownlink"`
+	RulesPolicyID       *string `db:"rulesPolicyID"` // FK to policies.id
+}
+
+// variantForArea picks the variant that applies in area: the one for its
+// cell, else the one for its tracking area, else none.
+func variantForArea(variants []PolicyLocationVariant, area models.ServingArea) *PolicyLocationVariant {
+	var byTAC *PolicyLocationVariant
+
+	for i := range variants {
+		v := &variants[i]
+
+		switch {
+		case v.CellID != "" && v.CellID == area.CellID:
+			return v
+		case v.TAC != "" && v.TAC == area.TAC && byTAC == nil:
+			byTAC = v
+		}
+	}
+
+	return byTAC
+}
+
+// CreatePolicyLocationVariant stores a variant and sets its ID. A name or
+// area the policy already has a variant for returns ErrAlreadyExists.
+func (db *Database) CreatePolicyLocationVariant(ctx context.Context, variant *PolicyLocationVariant) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", PolicyLocationVariantsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", PolicyLocationVariantsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicyLocationVariantsTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicyLocationVariantsTableName, "insert").Inc()
+
+	if variant.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("generate location variant id: %w", err)
+		}
+
+		variant.ID = id.String()
+	}
+
+	_, err := opCreatePolicyLocationVariant.Invoke(db, variant)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreatePolicyLocationVariant(ctx context.Context, variant *PolicyLocationVariant) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.createPolicyLocationVariantStmt, variant).Run(); err != nil {
+		if isUniqueNameError(err) {
+			return nil, ErrAlreadyExists
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetPolicyLocationVariant returns ErrNotFound for an unknown id.
+func (db *Database) GetPolicyLocationVariant(ctx context.Context, id string) (*PolicyLocationVariant, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", PolicyLocationVariantsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", PolicyLocationVariantsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(policyLocationVariantsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicyLocationVariantsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicyLocationVariantsTableName, "select").Inc()
+
+	row := PolicyLocationVariant{ID: id}
+
+	err := db.conn().Query(ctx, db.getPolicyLocationVariantStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// DeletePolicyLocationVariant returns ErrNotFound for an unknown id.
+func (db *Database) DeletePolicyLocationVariant(ctx context.Context, id string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", PolicyLocationVariantsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", PolicyLocationVariantsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicyLocationVariantsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicyLocationVariantsTableName, "delete").Inc()
+
+	_, err := opDeletePolicyLocationVariant.Invoke(db, &stringPayload{Value: id})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeletePolicyLocationVariant(ctx context.Context, p *stringPayload) (any, error) {
+	var outcome sqlair.Outcome
+
+	err := db.runner(ctx).Query(ctx, db.deletePolicyLocationVariantStmt, PolicyLocationVariant{ID: p.Value}).Get(&outcome)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// ListPolicyLocationVariants returns a policy's variants ordered by name.
+func (db *Database) ListPolicyLocationVariants(ctx context.Context, policyID string) ([]PolicyLocationVariant, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", PolicyLocationVariantsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", PolicyLocationVariantsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(policyLocationVariantsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []PolicyLocationVariant{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicyLocationVariantsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicyLocationVariantsTableName, "select").Inc()
+
+	var rows []PolicyLocationVariant
+
+	err := db.conn().Query(ctx, db.listPolicyLocationVariantsByPolicyStmt, PolicyLocationVariant{PolicyID: policyID}).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []PolicyLocationVariant{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
+
+// PolicyInArea returns policy as it applies to a UE served in area: a copy
+// carrying the Session-AMBR of the variant for the area, or policy itself
+// when no variant matches. rulesPolicyID is the policy whose network rules
+// are in force there: the variant's, else policy's own.
+func (db *Database) PolicyInArea(ctx context.Context, policy *Policy, area models.ServingArea) (located *Policy, rulesPolicyID string, err error) {
+	if area == (models.ServingArea{}) {
+		return policy, policy.ID, nil
+	}
+
+	variants, err := db.ListPolicyLocationVariants(ctx, policy.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	v := variantForArea(variants, area)
+	if v == nil {
+		return policy, policy.ID, nil
+	}
+
+	inArea := *policy
+	inArea.SessionAmbrUplink, inArea.SessionAmbrDownlink = v.SessionAmbrUplink, v.SessionAmbrDownlink
+
+	if v.RulesPolicyID != nil {
+		return &inArea, *v.RulesPolicyID, nil
+	}
+
+	return &inArea, policy.ID, nil
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func TestPolicyLocationVariantsEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	if err := database.CreateDataNetwork(ctx, &db.DataNetwork{Name: "site-dnn", IPv4Pool: "10.53.0.0/24"}); err != nil {
+		t.Fatalf("Couldn't create data network: %s", err)
+	}
+
+	dataNetwork, err := database.GetDataNetwork(ctx, "site-dnn")
+	if err != nil {
+		t.Fatalf("Couldn't get data network: %s", err)
+	}
+
+	profileID, sliceID := createPolicyDeps(t, database, "site")
+
+	policy := &db.Policy{
+		Name:                "site-policy",
+		SessionAmbrUplink:   "500 Mbps",
+		SessionAmbrDownlink: "1 Gbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkID:       dataNetwork.ID,
+		ProfileID:           profileID,
+		SliceID:             sliceID,
+	}
+
+	if err := database.CreatePolicy(ctx, policy); err != nil {
+		t.Fatalf("Couldn't create policy: %s", err)
+	}
+
+	// A policy of the same data network whose rules apply at the gate.
+	rulesProfileID, rulesSliceID := createPolicyDeps(t, database, "site-rules")
+
+	rulesPolicy := &db.Policy{
+		Name:                "site-rules",
+		SessionAmbrUplink:   "1 Mbps",
+		SessionAmbrDownlink: "1 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkID:       dataNetwork.ID,
+		ProfileID:           rulesProfileID,
+		SliceID:             rulesSliceID,
+	}
+
+	if err := database.CreatePolicy(ctx, rulesPolicy); err != nil {
+		t.Fatalf("Couldn't create rules policy: %s", err)
+	}
+
+	parkingLot := &db.PolicyLocationVariant{PolicyID: policy.ID, Name: "parking-lot", TAC: "000002", SessionAmbrUplink: "5 Mbps", SessionAmbrDownlink: "10 Mbps"}
+	if err := database.CreatePolicyLocationVariant(ctx, parkingLot); err != nil {
+		t.Fatalf("couldn't create TAC variant: %s", err)
+	}
+
+	gate := &db.PolicyLocationVariant{PolicyID: policy.ID, Name: "gate", CellID: "0000021", SessionAmbrUplink: "1 Mbps", SessionAmbrDownlink: "2 Mbps", RulesPolicyID: &rulesPolicy.ID}
+	if err := database.CreatePolicyLocationVariant(ctx, gate); err != nil {
+		t.Fatalf("couldn't create cell variant: %s", err)
+	}
+
+	if parkingLot.ID == "" || gate.ID == "" {
+		t.Fatalf("expected IDs to be set")
+	}
+
+	duplicate := &db.PolicyLocationVariant{PolicyID: policy.ID, Name: "other", TAC: "000002", SessionAmbrUplink: "1 Mbps", SessionAmbrDownlink: "1 Mbps"}
+	if err := database.CreatePolicyLocationVariant(ctx, duplicate); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a second variant of the area, got %v", err)
+	}
+
+	variants, err := database.ListPolicyLocationVariants(ctx, policy.ID)
+	if err != nil || len(variants) != 2 || variants[0].Name != "gate" {
+		t.Fatalf("unexpected variants: %+v (%v)", variants, err)
+	}
+
+	if variants[0].RulesPolicyID == nil || *variants[0].RulesPolicyID != rulesPolicy.ID || variants[1].RulesPolicyID != nil {
+		t.Fatalf("listed rules policies = %v/%v, want %q for the gate only", variants[0].RulesPolicyID, variants[1].RulesPolicyID, rulesPolicy.ID)
+	}
+
+	stored, err := database.GetPolicyLocationVariant(ctx, gate.ID)
+	if err != nil || stored.RulesPolicyID == nil || *stored.RulesPolicyID != rulesPolicy.ID {
+		t.Fatalf("stored gate variant = %+v (%v), want rules policy %q", stored, err, rulesPolicy.ID)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		area      models.ServingArea
+		wantUL    string
+		wantDL    string
+		wantRules string
+		wantSame  bool
+	}{
+		{"unknown location", models.ServingArea{}, "500 Mbps", "1 Gbps", policy.ID, true},
+		{"other tracking area", models.ServingArea{TAC: "000001", CellID: "0000011"}, "500 Mbps", "1 Gbps", policy.ID, true},
+		{"tracking area", models.ServingArea{TAC: "000002", CellID: "0000022"}, "5 Mbps", "10 Mbps", policy.ID, false},
+		{"cell wins over its tracking area", models.ServingArea{TAC: "000002", CellID: "0000021"}, "1 Mbps", "2 Mbps", rulesPolicy.ID, false},
+	} {
+		got, rulesPolicyID, err := database.PolicyInArea(ctx, policy, tc.area)
+		if err != nil {
+			t.Fatalf("%s: couldn't resolve policy: %s", tc.name, err)
+		}
+
+		if got.SessionAmbrUplink != tc.wantUL || got.SessionAmbrDownlink != tc.wantDL {
+			t.Fatalf("%s: Session-AMBR = %s/%s, want %s/%s", tc.name, got.SessionAmbrUplink, got.SessionAmbrDownlink, tc.wantUL, tc.wantDL)
+		}
+
+		if rulesPolicyID != tc.wantRules {
+			t.Fatalf("%s: rules policy = %q, want %q", tc.name, rulesPolicyID, tc.wantRules)
+		}
+
+		if (got == policy) != tc.wantSame {
+			t.Fatalf("%s: expected the stored policy to be left untouched", tc.name)
+		}
+	}
+
+	// Deleting the rules policy leaves the variant on its policy's rules.
+	if err := database.DeletePolicy(ctx, rulesPolicy.Name); err != nil {
+		t.Fatalf("couldn't delete rules policy: %s", err)
+	}
+
+	stored, err = database.GetPolicyLocationVariant(ctx, gate.ID)
+	if err != nil || stored.RulesPolicyID != nil {
+		t.Fatalf("gate variant after its rules policy was deleted = %+v (%v), want no rules policy", stored, err)
+	}
+
+	if err := database.DeletePolicyLocationVariant(ctx, gate.ID); err != nil {
+		t.Fatalf("couldn't delete variant: %s", err)
+	}
+
+	if _, err := database.GetPolicyLocationVariant(ctx, gate.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	if err := database.DeletePolicyLocationVariant(ctx, gate.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	// Variants go with their policy.
+	if err := database.DeletePolicy(ctx, policy.Name); err != nil {
+		t.Fatalf("couldn't delete policy: %s", err)
+	}
+
+	if _, err := database.GetPolicyLocationVariant(ctx, parkingLot.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected the variant to be deleted with its policy, got %v", err)
+	}
+}
@@ -18,6 +18,25 @@ type PolicyRuleInput struct {
 	PortLow      int32   `json:"port_low" db:"port_low"`
 	PortHigh     int32   `json:"port_high" db:"port_high"`
 	Action       string  `json:"action" db:"action"`
+	// FQDN and MatchSNI are stored in network_rule_fqdns; see NetworkRuleFQDN.
+	FQDN     string `json:"fqdn,omitempty"`
+	MatchSNI bool   `json:"match_sni,omitempty"`
+	// RateLimit and RateLimitShared are stored in network_rule_rate_limits
+	// when Action is RuleActionRateLimit; see NetworkRuleRateLimit.
+	RateLimit       string `json:"rate_limit,omitempty"`
+	RateLimitShared bool   `json:"rate_limit_shared,omitempty"`
+	// BreakoutInterface, BreakoutVlanID and BreakoutRoutingTable are stored
+	// in network_rule_breakouts when Action is RuleActionBreakout; see
+	// NetworkRuleBreakout.
+	BreakoutInterface    string `json:"breakout_interface,omitempty"`
+	BreakoutVlanID       int    `json:"breakout_vlan_id,omitempty"`
+	BreakoutRoutingTable int    `json:"breakout_routing_table,omitempty"`
+	// ScheduleID is stored in network_rule_schedules; see NetworkRuleSchedule.
+	ScheduleID string `json:"schedule_id,omitempty"`
+	// RatingGroup and ZeroRated are stored in network_rule_ratings; see
+	// NetworkRuleRating.
+	RatingGroup uint32 `json:"rating_group,omitempty"`
+	ZeroRated   bool   `json:"zero_rated,omitempty"`
 }
 
 type PolicyRulesInput struct {
@@ -28,9 +47,14 @@ type PolicyRulesInput struct {
 type policyWithRulesPayload struct {
 	Policy Policy            `json:"policy"`
 	Rules  *PolicyRulesInput `json:"rules,omitempty"`
+	// ScheduledAmbr is the policy's alternate Session-AMBR; nil removes it.
+	ScheduledAmbr *PolicySchedule `json:"scheduled_ambr,omitempty"`
 }
 
-func (db *Database) CreatePolicyWithRules(ctx context.Context, policy *Policy, rules *PolicyRulesInput) error {
+// CreatePolicyWithRules writes policy, its rules and its alternate
+// Session-AMBR in one changeset. A nil scheduled leaves the policy without
+// one.
+func (db *Database) CreatePolicyWithRules(ctx context.Context, policy *Policy, rules *PolicyRulesInput, scheduled *PolicySchedule) error {
 	if policy.ID == "" {
 		id, err := uuid.NewV7()
 		if err != nil {
@@ -40,13 +64,76 @@ func (db *Database) CreatePolicyWithRules(ctx context.Context, policy *Policy, r
 		policy.ID = id.String()
 	}
 
-	_, err := opCreatePolicyWithRules.Invoke(db, &policyWithRulesPayload{Policy: *policy, Rules: rules})
+	if rules.hasFQDNRules() {
+		if err := db.checkOpSchema(networkRuleFQDNsSchema); err != nil {
+			return err
+		}
+	}
+
+	if rules.hasRateLimitRules() {
+		if err := db.checkOpSchema(networkRuleRateLimitsSchema); err != nil {
+			return err
+		}
+	}
+
+	if rules.hasBreakoutRules() {
+		if err := db.checkOpSchema(networkRuleBreakoutsSchema); err != nil {
+			return err
+		}
+	}
+
+	if rules.hasRatedRules() {
+		if err := db.checkOpSchema(networkRuleRatingsSchema); err != nil {
+			return err
+		}
+	}
+
+	if scheduled != nil || rules.hasScheduledRules() {
+		if err := db.checkOpSchema(schedulesSchema); err != nil {
+			return err
+		}
+	}
+
+	_, err := opCreatePolicyWithRules.Invoke(db, &policyWithRulesPayload{Policy: *policy, Rules: rules, ScheduledAmbr: scheduled})
 
 	return err
 }
 
-func (db *Database) UpdatePolicyWithRules(ctx context.Context, policy *Policy, rules *PolicyRulesInput) error {
-	_, err := opUpdatePolicyWithRules.Invoke(db, &policyWithRulesPayload{Policy: *policy, Rules: rules})
+// UpdatePolicyWithRules writes policy, its rules and its alternate
+// Session-AMBR in one changeset. A nil scheduled leaves the policy without
+// one.
+func (db *Database) UpdatePolicyWithRules(ctx context.Context, policy *Policy, rules *PolicyRulesInput, scheduled *PolicySchedule) error {
+	if rules.hasFQDNRules() {
+		if err := db.checkOpSchema(networkRuleFQDNsSchema); err != nil {
+			return err
+		}
+	}
+
+	if rules.hasRateLimitRules() {
+		if err := db.checkOpSchema(networkRuleRateLimitsSchema); err != nil {
+			return err
+		}
+	}
+
+	if rules.hasBreakoutRules() {
+		if err := db.checkOpSchema(networkRuleBreakoutsSchema); err != nil {
+			return err
+		}
+	}
+
+	if rules.hasRatedRules() {
+		if err := db.checkOpSchema(networkRuleRatingsSchema); err != nil {
+			return err
+		}
+	}
+
+	if scheduled != nil || rules.hasScheduledRules() {
+		if err := db.checkOpSchema(schedulesSchema); err != nil {
+			return err
+		}
+	}
+
+	_, err := opUpdatePolicyWithRules.Invoke(db, &policyWithRulesPayload{Policy: *policy, Rules: rules, ScheduledAmbr: scheduled})
 
 	return err
 }
@@ -74,6 +161,10 @@ func (db *Database) applyCreatePolicyWithRules(ctx context.Context, payload *pol
 		return nil, err
 	}
 
+	if err := db.applyPolicySchedule(ctx, policy.ID, payload.ScheduledAmbr); err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -86,6 +177,10 @@ func (db *Database) applyUpdatePolicyWithRules(ctx context.Context, payload *pol
 		return nil, err
 	}
 
+	if err := db.applyPolicySchedule(ctx, payload.Policy.ID, payload.ScheduledAmbr); err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -142,6 +237,36 @@ func (db *Database) insertPolicyRules(ctx context.Context, policyID, direction s
 
 			return fmt.Errorf("query failed: %w", err)
 		}
+
+		if rule.FQDN != "" {
+			if err := db.insertNetworkRuleFQDN(ctx, nr, rule); err != nil {
+				return err
+			}
+		}
+
+		if rule.Action == RuleActionRateLimit {
+			if err := db.insertNetworkRuleRateLimit(ctx, nr, rule); err != nil {
+				return err
+			}
+		}
+
+		if rule.Action == RuleActionBreakout {
+			if err := db.insertNetworkRuleBreakout(ctx, nr, rule); err != nil {
+				return err
+			}
+		}
+
+		if rule.ScheduleID != "" {
+			if err := db.insertNetworkRuleSchedule(ctx, nr, rule); err != nil {
+				return err
+			}
+		}
+
+		if rule.isRated() {
+			if err := db.insertNetworkRuleRating(ctx, nr, rule); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,385 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/canonical/sqlair"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const QosSessionsTableName = "qos_sessions"
+
+// MaxQosSessions caps the QoS sessions recorded at once. The filters of
+// each in force take datapath filter slots beside those of the policies.
+const MaxQosSessions = 16
+
+// qosSessionsSchema is the migration that introduced the table. Reads below
+// it report no QoS sessions.
+const qosSessionsSchema = 33
+
+// The states of a QoS session. A requested session waits for the node
+// serving its UE to claim it; installing, the node is adding its QoS flow;
+// active, the flow is established. A failed session keeps its reason until
+// it expires.
+const (
+	QosSessionRequested  = "requested"
+	QosSessionInstalling = "installing"
+	QosSessionActive     = "active"
+	QosSessionFailed     = "failed"
+)
+
+const (
+	insertQosSessionStmt       = "INSERT INTO %s (id, imsi, ueIP, remotePrefix, protocol, portLow, portHigh, var5qi, gbrUplink, gbrDownlink, mbrUplink, mbrDownlink, notificationURL, createdAt, expiresAt, status, reason, nodeID, policyID) VALUES ($QosSession.id, $QosSession.imsi, $QosSession.ueIP, $QosSession.remotePrefix, $QosSession.protocol, $QosSession.portLow, $QosSession.portHigh, $QosSession.var5qi, $QosSession.gbrUplink, $QosSession.gbrDownlink, $QosSession.mbrUplink, $QosSession.mbrDownlink, $QosSession.notificationURL, $QosSession.createdAt, $QosSession.expiresAt, $QosSession.status, $QosSession.reason, $QosSession.nodeID, $QosSession.policyID) ON CONFLICT(id) DO NOTHING"
+	claimQosSessionStmt        = "UPDATE %s SET status='installing', nodeID=$QosSession.nodeID, policyID=$QosSession.policyID WHERE id==$QosSession.id AND status=='requested'"
+	updateQosSessionStatusStmt = "UPDATE %s SET status=$QosSession.status, reason=$QosSession.reason WHERE id==$QosSession.id"
+	updateQosSessionPolicyStmt = "UPDATE %s SET policyID=$QosSession.policyID WHERE id==$QosSession.id"
+	deleteQosSessionStmt       = "DELETE FROM %s WHERE id==$QosSession.id"
+	getQosSessionStmt          = "SELECT &QosSession.* FROM %s WHERE id==$QosSession.id"
+	listQosSessionsStmt        = "SELECT &QosSession.* FROM %s ORDER BY createdAt, id"
+)
+
+// QosSession is an application's request for a dedicated QoS flow carrying
+// the traffic a UE exchanges with a remote party until ExpiresAt (Unix
+// seconds). The UE is named by exactly one of IMSI and UEIP. An empty
+// RemotePrefix, a zero Protocol and zero ports match any. NodeID is the
+// cluster node serving the UE's session and PolicyID the policy whose
+// network rules the session had when the node claimed the request.
+type QosSession struct {
+	ID              string  `db:"id"`
+	IMSI            string  `db:"imsi"`
+	UEIP            string  `db:"ueIP"`
+	RemotePrefix    string  `db:"remotePrefix"`
+	Protocol        int     `db:"protocol"`
+	PortLow         int     `db:"portLow"`
+	PortHigh        int     `db:"portHigh"`
+	Var5qi          int32   `db:"var5qi"`
+	GBRUplink       string  `db:"gbrUplink"`
+	GBRDownlink     string  `db:"gbrDownlink"`
+	MBRUplink       string  `db:"mbrUplink"`
+	MBRDownlink     string  `db:"mbrDownlink"`
+	NotificationURL string  `db:"notificationURL"`
+	CreatedAt       int64   `db:"createdAt"`
+	ExpiresAt       int64   `db:"expiresAt"`
+	Status          string  `db:"status"`
+	Reason          string  `db:"reason"`
+	NodeID          int     `db:"nodeID"`
+	PolicyID        *string `db:"policyID"` // FK to policies.id
+}
+
+// CreateQosSession records a new request. An ID already recorded returns
+// ErrAlreadyExists.
+func (db *Database) CreateQosSession(ctx context.Context, qs *QosSession) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", QosSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", QosSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(QosSessionsTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(QosSessionsTableName, "insert").Inc()
+
+	_, err := opCreateQosSession.Invoke(db, qs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreateQosSession(ctx context.Context, qs *QosSession) (any, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, db.insertQosSessionStmt, qs).Get(&outcome); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrAlreadyExists
+	}
+
+	return nil, nil
+}
+
+// ClaimQosSession moves requested session qs.ID to installing on node
+// qs.NodeID, over the network rules of policy qs.PolicyID. A session another
+// node claimed first, or one no longer requested, returns ErrNotFound.
+func (db *Database) ClaimQosSession(ctx context.Context, qs *QosSession) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", QosSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", QosSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(QosSessionsTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(QosSessionsTableName, "update").Inc()
+
+	_, err := opClaimQosSession.Invoke(db, qs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyClaimQosSession(ctx context.Context, qs *QosSession) (any, error) {
+	return nil, db.updateQosSession(ctx, db.claimQosSessionStmt, qs)
+}
+
+// UpdateQosSessionStatus sets the status and reason of session qs.ID. An
+// unknown ID returns ErrNotFound.
+func (db *Database) UpdateQosSessionStatus(ctx context.Context, qs *QosSession) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", QosSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", QosSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(QosSessionsTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(QosSessionsTableName, "update").Inc()
+
+	_, err := opUpdateQosSessionStatus.Invoke(db, qs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyUpdateQosSessionStatus(ctx context.Context, qs *QosSession) (any, error) {
+	return nil, db.updateQosSession(ctx, db.updateQosSessionStatusStmt, qs)
+}
+
+// UpdateQosSessionPolicy sets the policy whose network rules session qs.ID
+// carries, as when its PDU session moves to another policy. An unknown ID
+// returns ErrNotFound.
+func (db *Database) UpdateQosSessionPolicy(ctx context.Context, qs *QosSession) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", QosSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", QosSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(QosSessionsTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(QosSessionsTableName, "update").Inc()
+
+	_, err := opUpdateQosSessionPolicy.Invoke(db, qs)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyUpdateQosSessionPolicy(ctx context.Context, qs *QosSession) (any, error) {
+	return nil, db.updateQosSession(ctx, db.updateQosSessionPolicyStmt, qs)
+}
+
+func (db *Database) updateQosSession(ctx context.Context, stmt *sqlair.Statement, qs *QosSession) error {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, stmt, qs).Get(&outcome); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteQosSession returns ErrNotFound for an unknown id.
+func (db *Database) DeleteQosSession(ctx context.Context, id string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", QosSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", QosSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(QosSessionsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(QosSessionsTableName, "delete").Inc()
+
+	_, err := opDeleteQosSession.Invoke(db, &stringPayload{Value: id})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteQosSession(ctx context.Context, p *stringPayload) (any, error) {
+	return nil, db.updateQosSession(ctx, db.deleteQosSessionStmt, &QosSession{ID: p.Value})
+}
+
+// GetQosSession returns ErrNotFound for an unknown id.
+func (db *Database) GetQosSession(ctx context.Context, id string) (*QosSession, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", QosSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", QosSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(qosSessionsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(QosSessionsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(QosSessionsTableName, "select").Inc()
+
+	row := QosSession{ID: id}
+
+	err := db.conn().Query(ctx, db.getQosSessionStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// ListQosSessions returns every QoS session, oldest first.
+func (db *Database) ListQosSessions(ctx context.Context) ([]QosSession, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", QosSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", QosSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(qosSessionsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []QosSession{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(QosSessionsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(QosSessionsTableName, "select").Inc()
+
+	var rows []QosSession
+
+	err := db.conn().Query(ctx, db.listQosSessionsStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []QosSession{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestQosSessionsEndToEnd(t *testing.T) {
+	database, _, imsi := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	policy, err := database.GetPolicy(ctx, "test-policy")
+	if err != nil {
+		t.Fatalf("GetPolicy: %s", err)
+	}
+
+	qs := &db.QosSession{
+		ID:           "qs-1",
+		IMSI:         imsi,
+		RemotePrefix: "198.51.100.7/32",
+		Protocol:     17,
+		Var5qi:       2,
+		GBRUplink:    "4 Mbps",
+		GBRDownlink:  "1 Mbps",
+		MBRUplink:    "8 Mbps",
+		MBRDownlink:  "2 Mbps",
+		CreatedAt:    100,
+		ExpiresAt:    400,
+		Status:       db.QosSessionRequested,
+	}
+
+	if err := database.CreateQosSession(ctx, qs); err != nil {
+		t.Fatalf("couldn't create QoS session: %s", err)
+	}
+
+	if err := database.CreateQosSession(ctx, qs); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a recorded id, got %v", err)
+	}
+
+	claim := &db.QosSession{ID: "qs-1", NodeID: 2, PolicyID: &policy.ID}
+
+	if err := database.ClaimQosSession(ctx, claim); err != nil {
+		t.Fatalf("couldn't claim QoS session: %s", err)
+	}
+
+	// Only a requested session can be claimed, so a second node loses.
+	if err := database.ClaimQosSession(ctx, &db.QosSession{ID: "qs-1", NodeID: 3}); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound claiming a claimed session, got %v", err)
+	}
+
+	if err := database.UpdateQosSessionStatus(ctx, &db.QosSession{ID: "qs-1", Status: db.QosSessionActive}); err != nil {
+		t.Fatalf("couldn't update QoS session: %s", err)
+	}
+
+	got, err := database.GetQosSession(ctx, "qs-1")
+	if err != nil {
+		t.Fatalf("couldn't get QoS session: %s", err)
+	}
+
+	if got.Status != db.QosSessionActive || got.NodeID != 2 || got.PolicyID == nil || *got.PolicyID != policy.ID || got.MBRUplink != "8 Mbps" || got.Protocol != 17 {
+		t.Fatalf("QoS session = %+v, want qs-1 active on node 2", got)
+	}
+
+	// The PDU session moved to a policy without network rules.
+	if err := database.UpdateQosSessionPolicy(ctx, &db.QosSession{ID: "qs-1"}); err != nil {
+		t.Fatalf("couldn't update QoS session policy: %s", err)
+	}
+
+	if got, err := database.GetQosSession(ctx, "qs-1"); err != nil || got.PolicyID != nil || got.Status != db.QosSessionActive {
+		t.Fatalf("QoS session = %+v (%v), want qs-1 active without a policy", got, err)
+	}
+
+	if err := database.UpdateQosSessionStatus(ctx, &db.QosSession{ID: "unknown", Status: db.QosSessionFailed}); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown id, got %v", err)
+	}
+
+	if err := database.CreateQosSession(ctx, &db.QosSession{ID: "qs-0", UEIP: "10.45.0.3", Var5qi: 1, GBRUplink: "1 Mbps", GBRDownlink: "1 Mbps", MBRUplink: "1 Mbps", MBRDownlink: "1 Mbps", CreatedAt: 100, ExpiresAt: 200, Status: db.QosSessionRequested}); err != nil {
+		t.Fatalf("couldn't create QoS session: %s", err)
+	}
+
+	all, err := database.ListQosSessions(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list QoS sessions: %s", err)
+	}
+
+	if len(all) != 2 || all[0].ID != "qs-0" || all[1].ID != "qs-1" {
+		t.Fatalf("QoS sessions = %+v, want qs-0 then qs-1", all)
+	}
+
+	if err := database.DeleteQosSession(ctx, "qs-1"); err != nil {
+		t.Fatalf("couldn't delete QoS session: %s", err)
+	}
+
+	if err := database.DeleteQosSession(ctx, "qs-1"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	if _, err := database.GetQosSession(ctx, "qs-1"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
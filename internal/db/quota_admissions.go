@@ -0,0 +1,664 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/canonical/sqlair"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	SliceRegistrationsTableName = "slice_registrations"
+	AdmittedSessionsTableName   = "admitted_sessions"
+)
+
+const (
+	insertSliceRegistrationStmt            = "INSERT INTO %s (imsi, sst, sd, nodeID, createdAt) VALUES ($SliceRegistration.imsi, $SliceRegistration.sst, $SliceRegistration.sd, $SliceRegistration.nodeID, $SliceRegistration.createdAt) ON CONFLICT(imsi, sst, sd) DO NOTHING"
+	deleteSliceRegistrationsStmt           = "DELETE FROM %s WHERE imsi==$SliceRegistration.imsi"
+	releaseSliceRegistrationsStmt          = "DELETE FROM %s WHERE imsi==$SliceRegistration.imsi AND nodeID==$SliceRegistration.nodeID"
+	releaseSliceRegistrationStmt           = "DELETE FROM %s WHERE imsi==$SliceRegistration.imsi AND sst==$SliceRegistration.sst AND sd==$SliceRegistration.sd AND nodeID==$SliceRegistration.nodeID"
+	deleteSliceRegistrationsByNodeStmt     = "DELETE FROM %s WHERE nodeID==$SliceRegistration.nodeID"
+	countSliceRegistrationsStmt            = "SELECT COUNT(*) AS &NumItems.count FROM %s WHERE sst==$SliceRegistration.sst AND sd==$SliceRegistration.sd AND imsi!=$SliceRegistration.imsi"
+	countHeldSliceRegistrationsStmt        = "SELECT COUNT(*) AS &NumItems.count FROM %s WHERE imsi==$SliceRegistration.imsi AND nodeID==$SliceRegistration.nodeID"
+	listSliceRegistrationsByNodeStmt       = "SELECT &SliceRegistration.* FROM %s WHERE nodeID==$SliceRegistration.nodeID ORDER BY imsi, sst, sd"
+	insertAdmittedSessionStmt              = "INSERT INTO %s (nodeID, ref, imsi, sst, sd, dataNetwork, createdAt) VALUES ($AdmittedSession.nodeID, $AdmittedSession.ref, $AdmittedSession.imsi, $AdmittedSession.sst, $AdmittedSession.sd, $AdmittedSession.dataNetwork, $AdmittedSession.createdAt) ON CONFLICT(nodeID, ref) DO NOTHING"
+	getAdmittedSessionStmt                 = "SELECT &AdmittedSession.* FROM %s WHERE nodeID==$AdmittedSession.nodeID AND ref==$AdmittedSession.ref"
+	deleteAdmittedSessionStmt              = "DELETE FROM %s WHERE nodeID==$AdmittedSession.nodeID AND ref==$AdmittedSession.ref"
+	countHeldAdmittedSessionsStmt          = "SELECT COUNT(*) AS &NumItems.count FROM %s WHERE nodeID==$AdmittedSession.nodeID AND ref==$AdmittedSession.ref"
+	deleteAdmittedSessionsByNodeStmt       = "DELETE FROM %s WHERE nodeID==$AdmittedSession.nodeID"
+	countAdmittedSessionsBySubscriberStmt  = "SELECT COUNT(*) AS &NumItems.count FROM %s WHERE sst==$AdmittedSession.sst AND sd==$AdmittedSession.sd AND imsi==$AdmittedSession.imsi"
+	countAdmittedSessionsBySliceStmt       = "SELECT COUNT(*) AS &NumItems.count FROM %s WHERE sst==$AdmittedSession.sst AND sd==$AdmittedSession.sd"
+	countAdmittedSessionsByDataNetworkStmt = "SELECT COUNT(*) AS &NumItems.count FROM %s WHERE dataNetwork==$AdmittedSession.dataNetwork"
+	listAdmittedSessionsByNodeStmt         = "SELECT &AdmittedSession.* FROM %s WHERE nodeID==$AdmittedSession.nodeID ORDER BY ref"
+)
+
+// SliceRegistration is a UE registered to a network slice, counted against
+// the slice's maximum of registered UEs on every node. NodeID is the node
+// whose AMF holds the registration. Sd is normalised by models.NormalizeSD.
+type SliceRegistration struct {
+	IMSI      string `db:"imsi"`
+	Sst       int32  `db:"sst"`
+	Sd        string `db:"sd"`
+	NodeID    int    `db:"nodeID"`
+	CreatedAt int64  `db:"createdAt"`
+}
+
+// AdmittedSession is a session counted against the quotas of its slice and
+// data network on every node. Ref is the session's name at NodeID's SMF; a
+// nil Sst is a session on no slice. Sd is normalised by models.NormalizeSD.
+type AdmittedSession struct {
+	NodeID      int    `db:"nodeID"`
+	Ref         string `db:"ref"`
+	IMSI        string `db:"imsi"`
+	Sst         *int32 `db:"sst"`
+	Sd          string `db:"sd"`
+	DataNetwork string `db:"dataNetwork"` // data network name
+	CreatedAt   int64  `db:"createdAt"`
+}
+
+// SliceAdmission is a slice a registering UE asks for and the most UEs the
+// slice may hold. A zero MaxRegisteredUEs is no limit.
+type SliceAdmission struct {
+	Snssai           models.Snssai
+	MaxRegisteredUEs int
+}
+
+// SessionLimits is the most sessions a new session may join: of its
+// subscriber on its slice, on its slice, and on its data network. A zero
+// limit is no limit.
+type SessionLimits struct {
+	MaxSessionsPerSubscriber int
+	MaxSliceSessions         int
+	MaxDataNetworkSessions   int
+}
+
+type admitSliceRegistrationsPayload struct {
+	IMSI      string           `json:"imsi"`
+	NodeID    int              `json:"nodeId"`
+	CreatedAt int64            `json:"createdAt"`
+	Slices    []SliceAdmission `json:"slices"`
+}
+
+type admitSessionPayload struct {
+	Session AdmittedSession `json:"session"`
+	Limits  SessionLimits   `json:"limits"`
+}
+
+type releaseSliceRegistrationsPayload struct {
+	IMSI   string `json:"imsi"`
+	NodeID int    `json:"nodeId"`
+}
+
+type reconcileQuotaAdmissionsPayload struct {
+	NodeID              int                 `json:"nodeId"`
+	AddRegistrations    []SliceRegistration `json:"addRegistrations"`
+	RemoveRegistrations []SliceRegistration `json:"removeRegistrations"`
+	AddSessions         []AdmittedSession   `json:"addSessions"`
+	RemoveSessions      []string            `json:"removeSessions"`
+}
+
+// QuotaAdmissionChanges is what a node's AMF and SMF hold that its admission
+// records miss, and the records they no longer hold. Removals name rows of
+// the reconciling node only; sessions are removed by Ref.
+type QuotaAdmissionChanges struct {
+	AddRegistrations    []SliceRegistration
+	RemoveRegistrations []SliceRegistration
+	AddSessions         []AdmittedSession
+	RemoveSessions      []string
+}
+
+// Empty reports whether c changes nothing.
+func (c *QuotaAdmissionChanges) Empty() bool {
+	return len(c.AddRegistrations) == 0 && len(c.RemoveRegistrations) == 0 &&
+		len(c.AddSessions) == 0 && len(c.RemoveSessions) == 0
+}
+
+// AdmitSliceRegistrations registers imsi, served by this node, to the slices
+// that have room for it, replacing its previous registrations on any node,
+// and returns the indexes in slices of those that are full. The UE's own
+// registrations do not count against it, so a registration update keeps its
+// slices. Below the schema that introduced the table every slice is
+// admitted unrecorded.
+func (db *Database) AdmitSliceRegistrations(ctx context.Context, imsi string, slices []SliceAdmission, createdAt int64) ([]int, error) {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s (admit)", "INSERT", SliceRegistrationsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", SliceRegistrationsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SliceRegistrationsTableName, "admit"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SliceRegistrationsTableName, "admit").Inc()
+
+	refused, err := opAdmitSliceRegistrations.Invoke(db, &admitSliceRegistrationsPayload{
+		IMSI:      imsi,
+		NodeID:    db.NodeID(),
+		CreatedAt: createdAt,
+		Slices:    slices,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return refused, nil
+}
+
+func (db *Database) applyAdmitSliceRegistrations(ctx context.Context, p *admitSliceRegistrationsPayload) (any, error) {
+	runner := db.runner(ctx)
+
+	refused := []int{}
+	kept := make([]SliceRegistration, 0, len(p.Slices))
+
+	for i, s := range p.Slices {
+		row := SliceRegistration{
+			IMSI:      p.IMSI,
+			Sst:       s.Snssai.Sst,
+			Sd:        models.NormalizeSD(s.Snssai.Sd),
+			NodeID:    p.NodeID,
+			CreatedAt: p.CreatedAt,
+		}
+
+		if s.MaxRegisteredUEs > 0 {
+			var count NumItems
+
+			if err := runner.Query(ctx, db.countSliceRegistrationsStmt, row).Get(&count); err != nil {
+				return nil, fmt.Errorf("count slice registrations: %w", err)
+			}
+
+			if count.Count >= s.MaxRegisteredUEs {
+				refused = append(refused, i)
+				continue
+			}
+		}
+
+		kept = append(kept, row)
+	}
+
+	if err := runner.Query(ctx, db.deleteSliceRegistrationsStmt, SliceRegistration{IMSI: p.IMSI}).Run(); err != nil {
+		return nil, fmt.Errorf("delete slice registrations: %w", err)
+	}
+
+	for _, row := range kept {
+		if err := runner.Query(ctx, db.insertSliceRegistrationStmt, row).Run(); err != nil {
+			return nil, fmt.Errorf("insert slice registration: %w", err)
+		}
+	}
+
+	return refused, nil
+}
+
+// ReleaseSliceRegistrations removes the slice registrations of imsi this
+// node holds. Registrations another node took over are left alone. A UE
+// with no registration recorded, as on slices without a quota, is released
+// without a write.
+func (db *Database) ReleaseSliceRegistrations(ctx context.Context, imsi string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", SliceRegistrationsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", SliceRegistrationsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SliceRegistrationsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	held, err := db.countQuotaAdmissions(ctx, SliceRegistrationsTableName, db.countHeldSliceRegistrationsStmt, SliceRegistration{IMSI: imsi, NodeID: db.NodeID()})
+	if err == nil && held == 0 {
+		span.SetStatus(codes.Ok, "none held")
+		return nil
+	}
+
+	DBQueriesTotal.WithLabelValues(SliceRegistrationsTableName, "delete").Inc()
+
+	_, err = opReleaseSliceRegistrations.Invoke(db, &releaseSliceRegistrationsPayload{IMSI: imsi, NodeID: db.NodeID()})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyReleaseSliceRegistrations(ctx context.Context, p *releaseSliceRegistrationsPayload) (any, error) {
+	row := SliceRegistration{IMSI: p.IMSI, NodeID: p.NodeID}
+
+	if err := db.runner(ctx).Query(ctx, db.releaseSliceRegistrationsStmt, row).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// AdmitSession records session, served by this node, when it fits limits,
+// and otherwise returns the quota it would exceed. A session already
+// recorded is admitted again, so a retried admission does not count twice.
+// Below the schema that introduced the table every session is admitted
+// unrecorded.
+func (db *Database) AdmitSession(ctx context.Context, session *AdmittedSession, limits SessionLimits) (models.Quota, error) {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s (admit)", "INSERT", AdmittedSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", AdmittedSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return "", nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AdmittedSessionsTableName, "admit"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AdmittedSessionsTableName, "admit").Inc()
+
+	row := *session
+	row.NodeID = db.NodeID()
+	row.Sd = models.NormalizeSD(row.Sd)
+
+	quota, err := opAdmitSession.Invoke(db, &admitSessionPayload{Session: row, Limits: limits})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return "", err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return quota, nil
+}
+
+func (db *Database) applyAdmitSession(ctx context.Context, p *admitSessionPayload) (any, error) {
+	runner := db.runner(ctx)
+	row := p.Session
+
+	existing := AdmittedSession{NodeID: row.NodeID, Ref: row.Ref}
+
+	err := runner.Query(ctx, db.getAdmittedSessionStmt, existing).Get(&existing)
+	if err == nil {
+		return models.Quota(""), nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get admitted session: %w", err)
+	}
+
+	checks := []struct {
+		quota models.Quota
+		limit int
+		stmt  *sqlair.Statement
+	}{
+		{models.QuotaSubscriberSessions, p.Limits.MaxSessionsPerSubscriber, db.countAdmittedSessionsBySubscriberStmt},
+		{models.QuotaSliceSessions, p.Limits.MaxSliceSessions, db.countAdmittedSessionsBySliceStmt},
+		{models.QuotaDataNetworkSessions, p.Limits.MaxDataNetworkSessions, db.countAdmittedSessionsByDataNetworkStmt},
+	}
+
+	for _, check := range checks {
+		if check.limit <= 0 || (row.Sst == nil && check.quota != models.QuotaDataNetworkSessions) {
+			continue
+		}
+
+		var count NumItems
+
+		if err := runner.Query(ctx, check.stmt, row).Get(&count); err != nil {
+			return nil, fmt.Errorf("count admitted sessions: %w", err)
+		}
+
+		if count.Count >= check.limit {
+			return check.quota, nil
+		}
+	}
+
+	if err := runner.Query(ctx, db.insertAdmittedSessionStmt, row).Run(); err != nil {
+		return nil, fmt.Errorf("insert admitted session: %w", err)
+	}
+
+	return models.Quota(""), nil
+}
+
+// ReleaseAdmittedSession removes the session this node recorded under ref.
+// A session not recorded, as one without a quota, is released without a
+// write.
+func (db *Database) ReleaseAdmittedSession(ctx context.Context, ref string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", AdmittedSessionsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", AdmittedSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AdmittedSessionsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	held, err := db.countQuotaAdmissions(ctx, AdmittedSessionsTableName, db.countHeldAdmittedSessionsStmt, AdmittedSession{NodeID: db.NodeID(), Ref: ref})
+	if err == nil && held == 0 {
+		span.SetStatus(codes.Ok, "none held")
+		return nil
+	}
+
+	DBQueriesTotal.WithLabelValues(AdmittedSessionsTableName, "delete").Inc()
+
+	_, err = opReleaseAdmittedSession.Invoke(db, &AdmittedSession{NodeID: db.NodeID(), Ref: ref})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyReleaseAdmittedSession(ctx context.Context, p *AdmittedSession) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteAdmittedSessionStmt, p).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// ReconcileQuotaAdmissions brings this node's admission records in line with
+// the registrations and sessions it holds. Added records never displace
+// another node's.
+func (db *Database) ReconcileQuotaAdmissions(ctx context.Context, changes *QuotaAdmissionChanges) error {
+	_, span := tracer.Start(
+		ctx,
+		"ReconcileQuotaAdmissions",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", AdmittedSessionsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil || changes.Empty() {
+		span.SetStatus(codes.Ok, "nothing to reconcile")
+		return nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AdmittedSessionsTableName, "reconcile"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AdmittedSessionsTableName, "reconcile").Inc()
+
+	_, err := opReconcileQuotaAdmissions.Invoke(db, &reconcileQuotaAdmissionsPayload{
+		NodeID:              db.NodeID(),
+		AddRegistrations:    changes.AddRegistrations,
+		RemoveRegistrations: changes.RemoveRegistrations,
+		AddSessions:         changes.AddSessions,
+		RemoveSessions:      changes.RemoveSessions,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyReconcileQuotaAdmissions(ctx context.Context, p *reconcileQuotaAdmissionsPayload) (any, error) {
+	runner := db.runner(ctx)
+
+	for _, row := range p.RemoveRegistrations {
+		row.NodeID = p.NodeID
+
+		if err := runner.Query(ctx, db.releaseSliceRegistrationStmt, row).Run(); err != nil {
+			return nil, fmt.Errorf("delete slice registration: %w", err)
+		}
+	}
+
+	for _, row := range p.AddRegistrations {
+		row.NodeID = p.NodeID
+
+		if err := runner.Query(ctx, db.insertSliceRegistrationStmt, row).Run(); err != nil {
+			return nil, fmt.Errorf("insert slice registration: %w", err)
+		}
+	}
+
+	for _, ref := range p.RemoveSessions {
+		if err := runner.Query(ctx, db.deleteAdmittedSessionStmt, AdmittedSession{NodeID: p.NodeID, Ref: ref}).Run(); err != nil {
+			return nil, fmt.Errorf("delete admitted session: %w", err)
+		}
+	}
+
+	for _, row := range p.AddSessions {
+		row.NodeID = p.NodeID
+
+		if err := runner.Query(ctx, db.insertAdmittedSessionStmt, row).Run(); err != nil {
+			return nil, fmt.Errorf("insert admitted session: %w", err)
+		}
+	}
+
+	return nil, nil
+}
+
+// DeleteQuotaAdmissionsByNode removes the registrations and sessions a node
+// recorded, which do not survive its removal from the cluster.
+func (db *Database) DeleteQuotaAdmissionsByNode(ctx context.Context, nodeID int) error {
+	_, span := tracer.Start(
+		ctx,
+		"DeleteQuotaAdmissionsByNode",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", AdmittedSessionsTableName),
+			attribute.Int("node_id", nodeID),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(AdmittedSessionsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(AdmittedSessionsTableName, "delete").Inc()
+
+	_, err := opDeleteQuotaAdmissionsByNode.Invoke(db, &intPayload{Value: nodeID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteQuotaAdmissionsByNode(ctx context.Context, p *intPayload) (any, error) {
+	runner := db.runner(ctx)
+
+	if err := runner.Query(ctx, db.deleteSliceRegistrationsByNodeStmt, SliceRegistration{NodeID: p.Value}).Run(); err != nil {
+		return nil, fmt.Errorf("delete slice registrations: %w", err)
+	}
+
+	if err := runner.Query(ctx, db.deleteAdmittedSessionsByNodeStmt, AdmittedSession{NodeID: p.Value}).Run(); err != nil {
+		return nil, fmt.Errorf("delete admitted sessions: %w", err)
+	}
+
+	return nil, nil
+}
+
+// CountSliceRegistrations returns the UEs registered to snssai across the
+// cluster.
+func (db *Database) CountSliceRegistrations(ctx context.Context, snssai models.Snssai) (int, error) {
+	row := SliceRegistration{Sst: snssai.Sst, Sd: models.NormalizeSD(snssai.Sd)}
+
+	return db.countQuotaAdmissions(ctx, SliceRegistrationsTableName, db.countSliceRegistrationsStmt, row)
+}
+
+// CountAdmittedSessionsBySlice returns the sessions open on snssai across
+// the cluster.
+func (db *Database) CountAdmittedSessionsBySlice(ctx context.Context, snssai models.Snssai) (int, error) {
+	row := AdmittedSession{Sst: &snssai.Sst, Sd: models.NormalizeSD(snssai.Sd)}
+
+	return db.countQuotaAdmissions(ctx, AdmittedSessionsTableName, db.countAdmittedSessionsBySliceStmt, row)
+}
+
+// CountAdmittedSessionsByDataNetwork returns the sessions open on the data
+// network named dnn across the cluster.
+func (db *Database) CountAdmittedSessionsByDataNetwork(ctx context.Context, dnn string) (int, error) {
+	row := AdmittedSession{DataNetwork: dnn}
+
+	return db.countQuotaAdmissions(ctx, AdmittedSessionsTableName, db.countAdmittedSessionsByDataNetworkStmt, row)
+}
+
+func (db *Database) countQuotaAdmissions(ctx context.Context, table string, stmt *sqlair.Statement, params ...any) (int, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", table),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", table),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return 0, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(table, "count"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(table, "count").Inc()
+
+	var count NumItems
+
+	if err := db.conn().Query(ctx, stmt, params...).Get(&count); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return count.Count, nil
+}
+
+func (db *Database) ListSliceRegistrationsByNode(ctx context.Context, nodeID int) ([]SliceRegistration, error) {
+	return listQuotaAdmissions[SliceRegistration](ctx, db, SliceRegistrationsTableName, db.listSliceRegistrationsByNodeStmt, SliceRegistration{NodeID: nodeID})
+}
+
+func (db *Database) ListAdmittedSessionsByNode(ctx context.Context, nodeID int) ([]AdmittedSession, error) {
+	return listQuotaAdmissions[AdmittedSession](ctx, db, AdmittedSessionsTableName, db.listAdmittedSessionsByNodeStmt, AdmittedSession{NodeID: nodeID})
+}
+
+func listQuotaAdmissions[T any](ctx context.Context, db *Database, table string, stmt *sqlair.Statement, params ...any) ([]T, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", table),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", table),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(admissionQuotasSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []T{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(table, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(table, "select").Inc()
+
+	var rows []T
+
+	err := db.conn().Query(ctx, stmt, params...).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []T{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
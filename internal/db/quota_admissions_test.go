@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func newQuotaAdmissionsTestDB(t *testing.T) *db.Database {
+	t.Helper()
+
+	database, err := db.NewDatabaseWithoutRaft(context.Background(), filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	})
+
+	return database
+}
+
+func TestAdmitSliceRegistrations(t *testing.T) {
+	ctx := context.Background()
+	database := newQuotaAdmissionsTestDB(t)
+
+	full := models.Snssai{Sst: 1, Sd: "00000A"}
+	open := models.Snssai{Sst: 2}
+	admissions := []db.SliceAdmission{{Snssai: full, MaxRegisteredUEs: 1}, {Snssai: open}}
+
+	refused, err := database.AdmitSliceRegistrations(ctx, "001010000000001", admissions, 100)
+	if err != nil {
+		t.Fatalf("couldn't admit first UE: %s", err)
+	}
+
+	if len(refused) != 0 {
+		t.Fatalf("expected the first UE to be admitted everywhere, refused %v", refused)
+	}
+
+	// The UE's own registration does not count against its update.
+	refused, err = database.AdmitSliceRegistrations(ctx, "001010000000001", admissions, 200)
+	if err != nil || len(refused) != 0 {
+		t.Fatalf("expected a registration update to keep its slices, refused %v, err %v", refused, err)
+	}
+
+	refused, err = database.AdmitSliceRegistrations(ctx, "001010000000002", admissions, 300)
+	if err != nil {
+		t.Fatalf("couldn't admit second UE: %s", err)
+	}
+
+	if !slices.Equal(refused, []int{0}) {
+		t.Fatalf("expected the full slice to be refused, refused %v", refused)
+	}
+
+	count, err := database.CountSliceRegistrations(ctx, models.Snssai{Sst: 1, Sd: "00000a"})
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 UE on the full slice, got %d, err %v", count, err)
+	}
+
+	count, err = database.CountSliceRegistrations(ctx, open)
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 UEs on the open slice, got %d, err %v", count, err)
+	}
+
+	if err := database.ReleaseSliceRegistrations(ctx, "001010000000001"); err != nil {
+		t.Fatalf("couldn't release first UE: %s", err)
+	}
+
+	refused, err = database.AdmitSliceRegistrations(ctx, "001010000000002", admissions, 400)
+	if err != nil || len(refused) != 0 {
+		t.Fatalf("expected the released room to admit the second UE, refused %v, err %v", refused, err)
+	}
+}
+
+func TestAdmitSession(t *testing.T) {
+	ctx := context.Background()
+	database := newQuotaAdmissionsTestDB(t)
+
+	sst := int32(1)
+	session := func(ref, imsi string) *db.AdmittedSession {
+		return &db.AdmittedSession{Ref: ref, IMSI: imsi, Sst: &sst, DataNetwork: "internet", CreatedAt: 100}
+	}
+
+	limits := db.SessionLimits{MaxSessionsPerSubscriber: 1, MaxSliceSessions: 2, MaxDataNetworkSessions: 3}
+
+	tests := []struct {
+		name    string
+		session *db.AdmittedSession
+		limits  db.SessionLimits
+		want    models.Quota
+	}{
+		{"first session", session("a#1", "001010000000001"), limits, ""},
+		{"retried admission", session("a#1", "001010000000001"), limits, ""},
+		{"second session of subscriber", session("a#2", "001010000000001"), limits, models.QuotaSubscriberSessions},
+		{"second subscriber", session("b#1", "001010000000002"), limits, ""},
+		{"slice full", session("c#1", "001010000000003"), limits, models.QuotaSliceSessions},
+		{"data network full", &db.AdmittedSession{Ref: "d#1", IMSI: "001010000000004", DataNetwork: "internet"}, db.SessionLimits{MaxDataNetworkSessions: 2}, models.QuotaDataNetworkSessions},
+		{"no limits", session("e#1", "001010000000005"), db.SessionLimits{}, ""},
+	}
+
+	for _, tc := range tests {
+		got, err := database.AdmitSession(ctx, tc.session, tc.limits)
+		if err != nil {
+			t.Fatalf("%s: couldn't admit: %s", tc.name, err)
+		}
+
+		if got != tc.want {
+			t.Fatalf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+
+	count, err := database.CountAdmittedSessionsBySlice(ctx, models.Snssai{Sst: 1})
+	if err != nil || count != 3 {
+		t.Fatalf("expected 3 sessions on the slice, got %d, err %v", count, err)
+	}
+
+	if err := database.ReleaseAdmittedSession(ctx, "a#1"); err != nil {
+		t.Fatalf("couldn't release session: %s", err)
+	}
+
+	count, err = database.CountAdmittedSessionsByDataNetwork(ctx, "internet")
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 sessions on the data network, got %d, err %v", count, err)
+	}
+}
+
+func TestReconcileQuotaAdmissions(t *testing.T) {
+	ctx := context.Background()
+	database := newQuotaAdmissionsTestDB(t)
+
+	if _, err := database.AdmitSession(ctx, &db.AdmittedSession{Ref: "stale", IMSI: "001010000000001", DataNetwork: "internet"}, db.SessionLimits{}); err != nil {
+		t.Fatalf("couldn't admit session: %s", err)
+	}
+
+	if _, err := database.AdmitSliceRegistrations(ctx, "001010000000001", []db.SliceAdmission{{Snssai: models.Snssai{Sst: 1}}}, 100); err != nil {
+		t.Fatalf("couldn't admit registration: %s", err)
+	}
+
+	err := database.ReconcileQuotaAdmissions(ctx, &db.QuotaAdmissionChanges{
+		AddRegistrations:    []db.SliceRegistration{{IMSI: "001010000000002", Sst: 1, CreatedAt: 200}},
+		RemoveRegistrations: []db.SliceRegistration{{IMSI: "001010000000001", Sst: 1}},
+		AddSessions:         []db.AdmittedSession{{Ref: "live", IMSI: "001010000000002", DataNetwork: "internet", CreatedAt: 200}},
+		RemoveSessions:      []string{"stale"},
+	})
+	if err != nil {
+		t.Fatalf("couldn't reconcile: %s", err)
+	}
+
+	registrations, err := database.ListSliceRegistrationsByNode(ctx, database.NodeID())
+	if err != nil {
+		t.Fatalf("couldn't list registrations: %s", err)
+	}
+
+	if len(registrations) != 1 || registrations[0].IMSI != "001010000000002" {
+		t.Fatalf("expected only the live registration, got %+v", registrations)
+	}
+
+	sessions, err := database.ListAdmittedSessionsByNode(ctx, database.NodeID())
+	if err != nil {
+		t.Fatalf("couldn't list sessions: %s", err)
+	}
+
+	if len(sessions) != 1 || sessions[0].Ref != "live" {
+		t.Fatalf("expected only the live session, got %+v", sessions)
+	}
+
+	if err := database.DeleteQuotaAdmissionsByNode(ctx, database.NodeID()); err != nil {
+		t.Fatalf("couldn't delete the node's admissions: %s", err)
+	}
+
+	count, err := database.CountSliceRegistrations(ctx, models.Snssai{Sst: 1})
+	if err != nil || count != 0 {
+		t.Fatalf("expected no registrations after deleting the node's, got %d, err %v", count, err)
+	}
+}
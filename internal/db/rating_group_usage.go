@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DailyRatingGroupUsageTableName = "daily_usage_rating_groups"
+
+const (
+	incrementRatingGroupUsageStmt = "INSERT INTO %s (epoch_day, imsi, rating_group, bytes_uplink, bytes_downlink) VALUES ($DailyRatingGroupUsage.epoch_day, $DailyRatingGroupUsage.imsi, $DailyRatingGroupUsage.rating_group, $DailyRatingGroupUsage.bytes_uplink, $DailyRatingGroupUsage.bytes_downlink) ON CONFLICT(epoch_day, imsi, rating_group) DO UPDATE SET bytes_uplink = bytes_uplink + $DailyRatingGroupUsage.bytes_uplink, bytes_downlink = bytes_downlink + $DailyRatingGroupUsage.bytes_downlink"
+	deleteOldRatingGroupUsageStmt = "DELETE FROM %s WHERE epoch_day < $cutoffDaysArgs.cutoff_days"
+	deleteAllRatingGroupUsageStmt = "DELETE FROM %s"
+)
+
+const (
+	getUsagePerRatingGroupStmt = `
+SELECT
+    rating_group AS &UsagePerRatingGroup.rating_group,
+    COALESCE(SUM(bytes_uplink), 0)   AS &UsagePerRatingGroup.bytes_uplink,
+    COALESCE(SUM(bytes_downlink), 0) AS &UsagePerRatingGroup.bytes_downlink
+FROM %s
+WHERE
+    epoch_day >= $UsageFilters.start_date
+    AND epoch_day <= $UsageFilters.end_date
+    AND ($UsageFilters.imsi IS NULL OR imsi = $UsageFilters.imsi)
+GROUP BY rating_group
+ORDER BY rating_group ASC`
+)
+
+// DailyRatingGroupUsage is one subscriber's traffic against one rating
+// group on one day. It is counted beside DailyUsage, which leaves out the
+// zero-rated part.
+type DailyRatingGroupUsage struct {
+	EpochDay      int64  `db:"epoch_day"`
+	IMSI          string `db:"imsi"`
+	RatingGroup   int64  `db:"rating_group"`
+	BytesUplink   int64  `db:"bytes_uplink"`
+	BytesDownlink int64  `db:"bytes_downlink"`
+}
+
+type UsagePerRatingGroup struct {
+	RatingGroup   int64 `db:"rating_group"`
+	BytesUplink   int64 `db:"bytes_uplink"`
+	BytesDownlink int64 `db:"bytes_downlink"`
+}
+
+func (db *Database) IncrementRatingGroupUsage(ctx context.Context, usage DailyRatingGroupUsage) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", DailyRatingGroupUsageTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", DailyRatingGroupUsageTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DailyRatingGroupUsageTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DailyRatingGroupUsageTableName, "insert").Inc()
+
+	_, err := opIncrementRatingGroupUsage.Invoke(db, &usage)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+// GetUsagePerRatingGroup sums usage per rating group between startDate and
+// endDate, for one subscriber or, with an empty imsi, for all of them.
+func (db *Database) GetUsagePerRatingGroup(ctx context.Context, imsi string, startDate time.Time, endDate time.Time) ([]UsagePerRatingGroup, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", DailyRatingGroupUsageTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", DailyRatingGroupUsageTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(networkRuleRatingsSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(DailyRatingGroupUsageTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(DailyRatingGroupUsageTableName, "select").Inc()
+
+	filters := UsageFilters{
+		StartDate: DaysSinceEpoch(startDate),
+		EndDate:   DaysSinceEpoch(endDate),
+		Limit:     NoUsageLimit,
+	}
+
+	if imsi != "" {
+		filters.IMSI = &imsi
+	}
+
+	var usage []UsagePerRatingGroup
+
+	err := db.conn().Query(ctx, db.getUsagePerRatingGroupStmt, filters).GetAll(&usage)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return nil, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return usage, nil
+}
+
+func (db *Database) applyIncrementRatingGroupUsage(ctx context.Context, u *DailyRatingGroupUsage) (any, error) {
+	err := db.runner(ctx).Query(ctx, db.incrementRatingGroupUsageStmt, u).Run()
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
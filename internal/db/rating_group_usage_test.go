@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestRatingGroupUsage(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	testImsi := "001010100007487"
+
+	if _, err := createDataNetworkPolicyAndSubscriber(database, testImsi); err != nil {
+		t.Fatalf("Couldn't complete createDataNetworkPolicyAndSubscriber: %s", err)
+	}
+
+	oldDate := time.Now().AddDate(0, 0, -10)
+	newDate := time.Now()
+
+	for _, u := range []db.DailyRatingGroupUsage{
+		{EpochDay: db.DaysSinceEpoch(oldDate), IMSI: testImsi, RatingGroup: 10, BytesUplink: 1},
+		{EpochDay: db.DaysSinceEpoch(newDate), IMSI: testImsi, RatingGroup: 10, BytesUplink: 100, BytesDownlink: 200},
+		{EpochDay: db.DaysSinceEpoch(newDate), IMSI: testImsi, RatingGroup: 10, BytesUplink: 10, BytesDownlink: 20},
+		{EpochDay: db.DaysSinceEpoch(newDate), IMSI: testImsi, RatingGroup: 20, BytesDownlink: 5},
+	} {
+		if err := database.IncrementRatingGroupUsage(ctx, u); err != nil {
+			t.Fatalf("couldn't increment rating group usage: %s", err)
+		}
+	}
+
+	usage, err := database.GetUsagePerRatingGroup(ctx, testImsi, newDate, newDate)
+	if err != nil {
+		t.Fatalf("couldn't get rating group usage: %s", err)
+	}
+
+	want := []db.UsagePerRatingGroup{
+		{RatingGroup: 10, BytesUplink: 110, BytesDownlink: 220},
+		{RatingGroup: 20, BytesDownlink: 5},
+	}
+
+	if len(usage) != len(want) || usage[0] != want[0] || usage[1] != want[1] {
+		t.Fatalf("unexpected usage: got %+v, want %+v", usage, want)
+	}
+
+	if err := database.DeleteOldDailyUsage(ctx, 5); err != nil {
+		t.Fatalf("couldn't delete old daily usage: %s", err)
+	}
+
+	usage, err = database.GetUsagePerRatingGroup(ctx, testImsi, oldDate, oldDate)
+	if err != nil {
+		t.Fatalf("couldn't get rating group usage: %s", err)
+	}
+
+	if len(usage) != 0 {
+		t.Fatalf("expected old rating group usage to be deleted, got %+v", usage)
+	}
+
+	if err := database.ClearDailyUsage(ctx); err != nil {
+		t.Fatalf("couldn't clear daily usage: %s", err)
+	}
+
+	usage, err = database.GetUsagePerRatingGroup(ctx, "", newDate, newDate)
+	if err != nil {
+		t.Fatalf("couldn't get rating group usage: %s", err)
+	}
+
+	if len(usage) != 0 {
+		t.Fatalf("expected rating group usage to be cleared, got %+v", usage)
+	}
+}
@@ -25,7 +25,7 @@ const (
 	listRoutesPageStmt = "SELECT &Route.*, COUNT(*) OVER() AS &NumItems.count FROM %s ORDER BY id DESC LIMIT $ListArgs.limit OFFSET $ListArgs.offset"
 	listAllRoutesStmt  = "SELECT &Route.* FROM %s ORDER BY id ASC"
 	getRouteStmt       = "SELECT &Route.* FROM %s WHERE id==$Route.id"
-	createRouteStmt    = "INSERT INTO %s (destination, gateway, interface, metric) VALUES ($Route.destination, $Route.gateway, $Route.interface, $Route.metric)"
+	createRouteStmt    = "INSERT INTO %s (destination, gateway, interface, metric, dataNetworkID) VALUES ($Route.destination, $Route.gateway, $Route.interface, $Route.metric, $Route.dataNetworkID)"
 	deleteRouteStmt    = "DELETE FROM %s WHERE id==$Route.id"
 	countRoutesStmt    = "SELECT COUNT(*) AS &NumItems.count FROM %s"
 )
@@ -49,13 +49,16 @@ func (ni NetworkInterface) String() string {
 	}
 }
 
-// Route represents a route record.
+// Route represents a route record. A route with a DataNetworkID is installed
+// in that data network's routing table (see DataNetworkEgress) rather than
+// the main table.
 type Route struct {
-	ID          int64            `db:"id"`
-	Destination string           `db:"destination"`
-	Gateway     string           `db:"gateway"`
-	Interface   NetworkInterface `db:"interface"`
-	Metric      int              `db:"metric"`
+	ID            int64            `db:"id"`
+	Destination   string           `db:"destination"`
+	Gateway       string           `db:"gateway"`
+	Interface     NetworkInterface `db:"interface"`
+	Metric        int              `db:"metric"`
+	DataNetworkID string           `db:"dataNetworkID"`
 }
 
 func (db *Database) ListRoutesPage(ctx context.Context, page int, perPage int) ([]Route, int, error) {
@@ -0,0 +1,587 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/canonical/sqlair"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	SchedulesTableName            = "schedules"
+	NetworkRuleSchedulesTableName = "network_rule_schedules"
+	PolicySchedulesTableName      = "policy_schedules"
+)
+
+// schedulesSchema is the migration that introduced the three tables. Below
+// it there are no schedules, so every rule and Session-AMBR is always on.
+const schedulesSchema = 22
+
+// ErrScheduleInUse is returned when deleting a schedule a network rule or
+// policy still refers to.
+var ErrScheduleInUse = errors.New("schedule is in use")
+
+const (
+	createScheduleStmt            = "INSERT INTO %s (id, name, time_zone, windows) VALUES ($Schedule.id, $Schedule.name, $Schedule.time_zone, $Schedule.windows)"
+	updateScheduleStmt            = "UPDATE %s SET time_zone=$Schedule.time_zone, windows=$Schedule.windows WHERE name==$Schedule.name"
+	deleteScheduleStmt            = "DELETE FROM %s WHERE name==$Schedule.name"
+	getScheduleStmt               = "SELECT &Schedule.* FROM %s WHERE name==$Schedule.name"
+	getScheduleByIDStmt           = "SELECT &Schedule.* FROM %s WHERE id==$Schedule.id"
+	listSchedulesStmt             = "SELECT &Schedule.* FROM %s ORDER BY name"
+	countNetworkRuleSchedulesStmt = "SELECT COUNT(*) AS &NumItems.count FROM %s WHERE schedule_id==$NetworkRuleSchedule.schedule_id"
+	countPolicySchedulesStmt      = "SELECT COUNT(*) AS &NumItems.count FROM %s WHERE schedule_id==$PolicySchedule.schedule_id"
+
+	createNetworkRuleScheduleStmt        = "INSERT INTO %s (network_rule_id, policy_id, schedule_id) VALUES ($NetworkRuleSchedule.network_rule_id, $NetworkRuleSchedule.policy_id, $NetworkRuleSchedule.schedule_id)"
+	listNetworkRuleSchedulesByPolicyStmt = "SELECT &NetworkRuleSchedule.* FROM %s WHERE policy_id==$NetworkRuleSchedule.policy_id"
+
+	upsertPolicyScheduleStmt = "INSERT INTO %s (policy_id, schedule_id, session_ambr_uplink, session_ambr_downlink) VALUES ($PolicySchedule.policy_id, $PolicySchedule.schedule_id, $PolicySchedule.session_ambr_uplink, $PolicySchedule.session_ambr_downlink) ON CONFLICT(policy_id) DO UPDATE SET schedule_id=excluded.schedule_id, session_ambr_uplink=excluded.session_ambr_uplink, session_ambr_downlink=excluded.session_ambr_downlink"
+	deletePolicyScheduleStmt = "DELETE FROM %s WHERE policy_id==$PolicySchedule.policy_id"
+	getPolicyScheduleStmt    = "SELECT &PolicySchedule.* FROM %s WHERE policy_id==$PolicySchedule.policy_id"
+)
+
+// Schedule is a named set of weekly windows. Windows holds the JSON encoding
+// of []models.ScheduleWindow; see Parse.
+type Schedule struct {
+	ID       string `db:"id"` // UUIDv7
+	Name     string `db:"name"`
+	TimeZone string `db:"time_zone"`
+	Windows  string `db:"windows"`
+}
+
+// NetworkRuleSchedule limits a network rule to the windows of a schedule.
+// Outside them the rule is not installed and traffic falls through to the
+// next rule.
+type NetworkRuleSchedule struct {
+	NetworkRuleID string `db:"network_rule_id"` // FK to network_rules.id
+	PolicyID      string `db:"policy_id"`       // FK to policies.id
+	ScheduleID    string `db:"schedule_id"`     // FK to schedules.id
+}
+
+// PolicySchedule replaces a policy's Session-AMBR with an alternate pair
+// while its schedule is active.
+type PolicySchedule struct {
+	PolicyID            string `db:"policy_id"`   // FK to policies.id
+	ScheduleID          string `db:"schedule_id"` // FK to schedules.id
+	SessionAmbrUplink   string `db:"session_ambr_uplink"`
+	SessionAmbrDownlink string `db:"session_ambr_downlink"`
+}
+
+// SetWindows encodes windows into Windows.
+func (s *Schedule) SetWindows(windows []models.ScheduleWindow) error {
+	b, err := json.Marshal(windows)
+	if err != nil {
+		return fmt.Errorf("encode schedule windows: %w", err)
+	}
+
+	s.Windows = string(b)
+
+	return nil
+}
+
+// WindowList decodes Windows.
+func (s *Schedule) WindowList() ([]models.ScheduleWindow, error) {
+	var windows []models.ScheduleWindow
+	if err := json.Unmarshal([]byte(s.Windows), &windows); err != nil {
+		return nil, fmt.Errorf("decode schedule %s windows: %w", s.Name, err)
+	}
+
+	return windows, nil
+}
+
+// Parse returns the schedule in a form that can be evaluated.
+func (s *Schedule) Parse() (*models.Schedule, error) {
+	windows, err := s.WindowList()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := models.ParseSchedule(s.TimeZone, windows)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", s.Name, err)
+	}
+
+	return parsed, nil
+}
+
+// hasScheduledRules reports whether any rule in the payload has a schedule.
+func (r *PolicyRulesInput) hasScheduledRules() bool {
+	if r == nil {
+		return false
+	}
+
+	for _, rule := range r.Uplink {
+		if rule.ScheduleID != "" {
+			return true
+		}
+	}
+
+	for _, rule := range r.Downlink {
+		if rule.ScheduleID != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (db *Database) CreateSchedule(ctx context.Context, schedule *Schedule) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", SchedulesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", SchedulesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SchedulesTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SchedulesTableName, "insert").Inc()
+
+	if schedule.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("generate schedule id: %w", err)
+		}
+
+		schedule.ID = id.String()
+	}
+
+	_, err := opCreateSchedule.Invoke(db, schedule)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreateSchedule(ctx context.Context, schedule *Schedule) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.createScheduleStmt, schedule).Run(); err != nil {
+		if isUniqueNameError(err) {
+			return nil, ErrAlreadyExists
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// UpdateSchedule replaces the time zone and windows of the schedule named
+// schedule.Name.
+func (db *Database) UpdateSchedule(ctx context.Context, schedule *Schedule) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", SchedulesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", SchedulesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SchedulesTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SchedulesTableName, "update").Inc()
+
+	_, err := opUpdateSchedule.Invoke(db, schedule)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyUpdateSchedule(ctx context.Context, schedule *Schedule) (any, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, db.updateScheduleStmt, schedule).Get(&outcome); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// DeleteSchedule returns ErrScheduleInUse while a network rule or policy
+// still refers to the schedule.
+func (db *Database) DeleteSchedule(ctx context.Context, name string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", SchedulesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", SchedulesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SchedulesTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SchedulesTableName, "delete").Inc()
+
+	_, err := opDeleteSchedule.Invoke(db, &stringPayload{Value: name})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteSchedule(ctx context.Context, p *stringPayload) (any, error) {
+	schedule := Schedule{Name: p.Value}
+
+	if err := db.runner(ctx).Query(ctx, db.getScheduleStmt, schedule).Get(&schedule); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	// The check runs inside the apply so a rule attached concurrently on
+	// another node cannot be left pointing at nothing.
+	var rules, policies NumItems
+
+	if err := db.runner(ctx).Query(ctx, db.countNetworkRuleSchedulesStmt, NetworkRuleSchedule{ScheduleID: schedule.ID}).Get(&rules); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	if err := db.runner(ctx).Query(ctx, db.countPolicySchedulesStmt, PolicySchedule{ScheduleID: schedule.ID}).Get(&policies); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	if rules.Count > 0 || policies.Count > 0 {
+		return nil, ErrScheduleInUse
+	}
+
+	if err := db.runner(ctx).Query(ctx, db.deleteScheduleStmt, schedule).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetSchedule returns ErrNotFound for an unknown name.
+func (db *Database) GetSchedule(ctx context.Context, name string) (*Schedule, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", SchedulesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SchedulesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(schedulesSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SchedulesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SchedulesTableName, "select").Inc()
+
+	row := Schedule{Name: name}
+
+	err := db.conn().Query(ctx, db.getScheduleStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// ListSchedules returns every schedule ordered by name.
+func (db *Database) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", SchedulesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SchedulesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(schedulesSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []Schedule{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SchedulesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SchedulesTableName, "select").Inc()
+
+	var rows []Schedule
+
+	err := db.conn().Query(ctx, db.listSchedulesStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []Schedule{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
+
+// ActiveSchedules evaluates every schedule at now and returns the IDs of
+// those in a window. A schedule that fails to parse is inactive; its error is
+// joined into the returned one alongside the partial result.
+func (db *Database) ActiveSchedules(ctx context.Context, now time.Time) (map[string]bool, error) {
+	schedules, err := db.ListSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[string]bool, len(schedules))
+
+	var errs []error
+
+	for _, s := range schedules {
+		parsed, err := s.Parse()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if parsed.ActiveAt(now) {
+			active[s.ID] = true
+		}
+	}
+
+	return active, errors.Join(errs...)
+}
+
+func (db *Database) insertNetworkRuleSchedule(ctx context.Context, nr *NetworkRule, rule PolicyRuleInput) error {
+	row := &NetworkRuleSchedule{
+		NetworkRuleID: nr.ID,
+		PolicyID:      nr.PolicyID,
+		ScheduleID:    rule.ScheduleID,
+	}
+
+	if err := db.runner(ctx).Query(ctx, db.createNetworkRuleScheduleStmt, row).Run(); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil
+}
+
+// ListNetworkRuleSchedulesByPolicy returns the schedules of a policy's
+// rules, keyed by network rule ID. Rules without one are always on.
+func (db *Database) ListNetworkRuleSchedulesByPolicy(ctx context.Context, policyID string) (map[string]NetworkRuleSchedule, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", NetworkRuleSchedulesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", NetworkRuleSchedulesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(schedulesSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return map[string]NetworkRuleSchedule{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(NetworkRuleSchedulesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(NetworkRuleSchedulesTableName, "select").Inc()
+
+	var rows []NetworkRuleSchedule
+
+	err := db.conn().Query(ctx, db.listNetworkRuleSchedulesByPolicyStmt, NetworkRuleSchedule{PolicyID: policyID}).GetAll(&rows)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	out := make(map[string]NetworkRuleSchedule, len(rows))
+	for _, row := range rows {
+		out[row.NetworkRuleID] = row
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return out, nil
+}
+
+// applyPolicySchedule replaces the policy's alternate Session-AMBR with
+// scheduled, or removes it when scheduled is nil.
+func (db *Database) applyPolicySchedule(ctx context.Context, policyID string, scheduled *PolicySchedule) error {
+	if scheduled == nil {
+		// Below the schema there is no row to remove; the write gate keeps a
+		// non-nil value from reaching this point.
+		if db.checkOpSchema(schedulesSchema) != nil {
+			return nil
+		}
+
+		if err := db.runner(ctx).Query(ctx, db.deletePolicyScheduleStmt, PolicySchedule{PolicyID: policyID}).Run(); err != nil {
+			return fmt.Errorf("query failed: %w", err)
+		}
+
+		return nil
+	}
+
+	row := *scheduled
+	row.PolicyID = policyID
+
+	if err := db.runner(ctx).Query(ctx, db.upsertPolicyScheduleStmt, row).Run(); err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil
+}
+
+// GetPolicySchedule returns ErrNotFound when the policy has no alternate
+// Session-AMBR.
+func (db *Database) GetPolicySchedule(ctx context.Context, policyID string) (*PolicySchedule, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", PolicySchedulesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", PolicySchedulesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(schedulesSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(PolicySchedulesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(PolicySchedulesTableName, "select").Inc()
+
+	row := PolicySchedule{PolicyID: policyID}
+
+	err := db.conn().Query(ctx, db.getPolicyScheduleStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// EffectiveSessionAmbr returns the Session-AMBR policy grants at now: the
+// alternate pair while its schedule is active, the policy's own otherwise.
+func (db *Database) EffectiveSessionAmbr(ctx context.Context, policy *Policy, now time.Time) (uplink, downlink string, err error) {
+	scheduled, err := db.GetPolicySchedule(ctx, policy.ID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return policy.SessionAmbrUplink, policy.SessionAmbrDownlink, nil
+		}
+
+		return "", "", err
+	}
+
+	schedule := Schedule{ID: scheduled.ScheduleID}
+
+	if err := db.conn().Query(ctx, db.getScheduleByIDStmt, schedule).Get(&schedule); err != nil {
+		return "", "", fmt.Errorf("get schedule %s: %w", scheduled.ScheduleID, err)
+	}
+
+	parsed, err := schedule.Parse()
+	if err != nil {
+		return "", "", err
+	}
+
+	if parsed.ActiveAt(now) {
+		return scheduled.SessionAmbrUplink, scheduled.SessionAmbrDownlink, nil
+	}
+
+	return policy.SessionAmbrUplink, policy.SessionAmbrDownlink, nil
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func TestSchedulesAttachToRulesAndSessionAmbr(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "db.sqlite3"))
+	if err != nil {
+		t.Fatalf("Couldn't complete NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't complete Close: %s", err)
+		}
+	}()
+
+	schedule := &db.Schedule{Name: "night", TimeZone: "UTC"}
+	if err := schedule.SetWindows([]models.ScheduleWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}, Start: "22:00", End: "06:00"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.CreateSchedule(ctx, schedule); err != nil {
+		t.Fatalf("Couldn't create schedule: %s", err)
+	}
+
+	if err := database.CreateSchedule(ctx, &db.Schedule{Name: "night", TimeZone: "UTC", Windows: "[]"}); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a duplicate name, got %v", err)
+	}
+
+	if err := database.CreateDataNetwork(ctx, &db.DataNetwork{Name: "schedule-dnn", IPv4Pool: "10.50.0.0/24"}); err != nil {
+		t.Fatalf("Couldn't create data network: %s", err)
+	}
+
+	dataNetwork, err := database.GetDataNetwork(ctx, "schedule-dnn")
+	if err != nil {
+		t.Fatalf("Couldn't get data network: %s", err)
+	}
+
+	profileID, sliceID := createPolicyDeps(t, database, "schedule")
+
+	policy := &db.Policy{
+		Name:                "schedule-policy",
+		SessionAmbrUplink:   "10 Mbps",
+		SessionAmbrDownlink: "20 Mbps",
+		Var5qi:              9,
+		Arp:                 1,
+		DataNetworkID:       dataNetwork.ID,
+		ProfileID:           profileID,
+		SliceID:             sliceID,
+	}
+
+	rules := &db.PolicyRulesInput{
+		Uplink: []db.PolicyRuleInput{
+			{Description: "bulk sync", Protocol: 6, PortLow: 873, PortHigh: 873, Action: "allow", ScheduleID: schedule.ID},
+			{Description: "everything else", Action: "deny"},
+		},
+	}
+
+	scheduled := &db.PolicySchedule{ScheduleID: schedule.ID, SessionAmbrUplink: "1 Gbps", SessionAmbrDownlink: "1 Gbps"}
+
+	if err := database.CreatePolicyWithRules(ctx, policy, rules, scheduled); err != nil {
+		t.Fatalf("Couldn't create policy: %s", err)
+	}
+
+	ruleSchedules, err := database.ListNetworkRuleSchedulesByPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list rule schedules: %s", err)
+	}
+
+	dbRules, err := database.ListRulesForPolicy(ctx, policy.ID)
+	if err != nil {
+		t.Fatalf("Couldn't list rules: %s", err)
+	}
+
+	if len(ruleSchedules) != 1 || ruleSchedules[dbRules[0].ID].ScheduleID != schedule.ID {
+		t.Fatalf("expected only the first rule to carry the schedule, got %+v", ruleSchedules)
+	}
+
+	night := time.Date(2026, 3, 11, 23, 0, 0, 0, time.UTC)
+	day := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC)
+
+	ul, dl, err := database.EffectiveSessionAmbr(ctx, policy, night)
+	if err != nil {
+		t.Fatalf("EffectiveSessionAmbr: %s", err)
+	}
+
+	if ul != "1 Gbps" || dl != "1 Gbps" {
+		t.Fatalf("expected the alternate Session-AMBR at night, got %s/%s", ul, dl)
+	}
+
+	ul, dl, err = database.EffectiveSessionAmbr(ctx, policy, day)
+	if err != nil {
+		t.Fatalf("EffectiveSessionAmbr: %s", err)
+	}
+
+	if ul != "10 Mbps" || dl != "20 Mbps" {
+		t.Fatalf("expected the policy's Session-AMBR by day, got %s/%s", ul, dl)
+	}
+
+	active, err := database.ActiveSchedules(ctx, night)
+	if err != nil || !active[schedule.ID] {
+		t.Fatalf("expected the schedule active at night, got %v (%v)", active, err)
+	}
+
+	if err := database.DeleteSchedule(ctx, "night"); !errors.Is(err, db.ErrScheduleInUse) {
+		t.Fatalf("expected ErrScheduleInUse, got %v", err)
+	}
+
+	if err := database.UpdatePolicyWithRules(ctx, policy, &db.PolicyRulesInput{Uplink: rules.Uplink[1:]}, nil); err != nil {
+		t.Fatalf("Couldn't update policy: %s", err)
+	}
+
+	if _, err := database.GetPolicySchedule(ctx, policy.ID); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected the alternate Session-AMBR removed, got %v", err)
+	}
+
+	if err := database.DeleteSchedule(ctx, "night"); err != nil {
+		t.Fatalf("Couldn't delete unused schedule: %s", err)
+	}
+
+	if err := database.DeleteSchedule(ctx, "night"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/canonical/sqlair"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const SMPolicyAssociationsTableName = "sm_policy_associations"
+
+const (
+	insertSMPolicyAssociationStmt        = "INSERT INTO %s (id, dataNetworkID, imsi, pduSessionID, nodeID, var5qi, arp, sessionAmbrUplink, sessionAmbrDownlink, rulesPolicyID) VALUES ($SMPolicyAssociation.id, $SMPolicyAssociation.dataNetworkID, $SMPolicyAssociation.imsi, $SMPolicyAssociation.pduSessionID, $SMPolicyAssociation.nodeID, $SMPolicyAssociation.var5qi, $SMPolicyAssociation.arp, $SMPolicyAssociation.sessionAmbrUplink, $SMPolicyAssociation.sessionAmbrDownlink, $SMPolicyAssociation.rulesPolicyID) ON CONFLICT(id) DO NOTHING"
+	updateSMPolicyDecisionStmt           = "UPDATE %s SET var5qi=$SMPolicyAssociation.var5qi, arp=$SMPolicyAssociation.arp, sessionAmbrUplink=$SMPolicyAssociation.sessionAmbrUplink, sessionAmbrDownlink=$SMPolicyAssociation.sessionAmbrDownlink, rulesPolicyID=$SMPolicyAssociation.rulesPolicyID WHERE id==$SMPolicyAssociation.id"
+	deleteSMPolicyAssociationStmt        = "DELETE FROM %s WHERE id==$SMPolicyAssociation.id"
+	deleteSMPolicyAssociationsByNodeStmt = "DELETE FROM %s WHERE nodeID==$SMPolicyAssociation.nodeID"
+	getSMPolicyAssociationStmt           = "SELECT &SMPolicyAssociation.* FROM %s WHERE id==$SMPolicyAssociation.id"
+	listSMPolicyAssociationsByNodeStmt   = "SELECT &SMPolicyAssociation.* FROM %s WHERE nodeID==$SMPolicyAssociation.nodeID ORDER BY id"
+	listAllSMPolicyAssociationsStmt      = "SELECT &SMPolicyAssociation.* FROM %s ORDER BY imsi, pduSessionID"
+)
+
+// SMPolicyAssociation is a session whose policy an external policy decision
+// point controls, and the decision in force. ID is the association's name at
+// the decision point. NodeID is the cluster node serving the session, which
+// applies decision changes. Zero Var5qi and Arp, empty Session-AMBR values
+// and a nil RulesPolicyID leave the session's local policy in place; a
+// RulesPolicyID puts the network rules of that policy on the session.
+type SMPolicyAssociation struct {
+	ID                  string  `db:"id"`
+	DataNetworkID       string  `db:"dataNetworkID"` // FK to data_networks.id
+	IMSI                string  `db:"imsi"`
+	PDUSessionID        int     `db:"pduSessionID"`
+	NodeID              int     `db:"nodeID"`
+	Var5qi              int32   `db:"var5qi"`
+	Arp                 int32   `db:"arp"`
+	SessionAmbrUplink   string  `db:"sessionAmbrUplink"`
+	SessionAmbrDownlink string  `db:"sessionAmbrDownlink"`
+	RulesPolicyID       *string `db:"rulesPolicyID"` // FK to policies.id
+}
+
+// CreateSMPolicyAssociation records a new association with its first
+// decision. An ID already recorded returns ErrAlreadyExists.
+func (db *Database) CreateSMPolicyAssociation(ctx context.Context, assoc *SMPolicyAssociation) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "INSERT", SMPolicyAssociationsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", SMPolicyAssociationsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SMPolicyAssociationsTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SMPolicyAssociationsTableName, "insert").Inc()
+
+	_, err := opCreateSMPolicyAssociation.Invoke(db, assoc)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyCreateSMPolicyAssociation(ctx context.Context, assoc *SMPolicyAssociation) (any, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, db.insertSMPolicyAssociationStmt, assoc).Get(&outcome); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrAlreadyExists
+	}
+
+	return nil, nil
+}
+
+// UpdateSMPolicyDecision replaces the decision in force for association
+// assoc.ID; the session fields are left as recorded. An unknown ID returns
+// ErrNotFound.
+func (db *Database) UpdateSMPolicyDecision(ctx context.Context, assoc *SMPolicyAssociation) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", SMPolicyAssociationsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", SMPolicyAssociationsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SMPolicyAssociationsTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SMPolicyAssociationsTableName, "update").Inc()
+
+	_, err := opUpdateSMPolicyDecision.Invoke(db, assoc)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyUpdateSMPolicyDecision(ctx context.Context, assoc *SMPolicyAssociation) (any, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, db.updateSMPolicyDecisionStmt, assoc).Get(&outcome); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// DeleteSMPolicyAssociation returns ErrNotFound for an unknown id.
+func (db *Database) DeleteSMPolicyAssociation(ctx context.Context, id string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", SMPolicyAssociationsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", SMPolicyAssociationsTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SMPolicyAssociationsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SMPolicyAssociationsTableName, "delete").Inc()
+
+	_, err := opDeleteSMPolicyAssociation.Invoke(db, &stringPayload{Value: id})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteSMPolicyAssociation(ctx context.Context, p *stringPayload) (any, error) {
+	var outcome sqlair.Outcome
+
+	err := db.runner(ctx).Query(ctx, db.deleteSMPolicyAssociationStmt, SMPolicyAssociation{ID: p.Value}).Get(&outcome)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// DeleteSMPolicyAssociationsByNode removes the associations of the sessions
+// a node served, which do not survive its restart.
+func (db *Database) DeleteSMPolicyAssociationsByNode(ctx context.Context, nodeID int) error {
+	_, span := tracer.Start(
+		ctx,
+		"DeleteSMPolicyAssociationsByNode",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", SMPolicyAssociationsTableName),
+			attribute.Int("node_id", nodeID),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SMPolicyAssociationsTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SMPolicyAssociationsTableName, "delete").Inc()
+
+	_, err := opDeleteSMPolicyAssociationsByNode.Invoke(db, &intPayload{Value: nodeID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteSMPolicyAssociationsByNode(ctx context.Context, p *intPayload) (any, error) {
+	if err := db.runner(ctx).Query(ctx, db.deleteSMPolicyAssociationsByNodeStmt, SMPolicyAssociation{NodeID: p.Value}).Run(); err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return nil, nil
+}
+
+// GetSMPolicyAssociation returns ErrNotFound for an unknown id.
+func (db *Database) GetSMPolicyAssociation(ctx context.Context, id string) (*SMPolicyAssociation, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", SMPolicyAssociationsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SMPolicyAssociationsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(policyControlSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SMPolicyAssociationsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SMPolicyAssociationsTableName, "select").Inc()
+
+	row := SMPolicyAssociation{ID: id}
+
+	err := db.conn().Query(ctx, db.getSMPolicyAssociationStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+func (db *Database) ListSMPolicyAssociationsByNode(ctx context.Context, nodeID int) ([]SMPolicyAssociation, error) {
+	return db.listSMPolicyAssociations(ctx, db.listSMPolicyAssociationsByNodeStmt, SMPolicyAssociation{NodeID: nodeID})
+}
+
+func (db *Database) ListAllSMPolicyAssociations(ctx context.Context) ([]SMPolicyAssociation, error) {
+	return db.listSMPolicyAssociations(ctx, db.listAllSMPolicyAssociationsStmt)
+}
+
+func (db *Database) listSMPolicyAssociations(ctx context.Context, stmt *sqlair.Statement, params ...any) ([]SMPolicyAssociation, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", SMPolicyAssociationsTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SMPolicyAssociationsTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(policyControlSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []SMPolicyAssociation{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SMPolicyAssociationsTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SMPolicyAssociationsTableName, "select").Inc()
+
+	var rows []SMPolicyAssociation
+
+	err := db.conn().Query(ctx, stmt, params...).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []SMPolicyAssociation{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestSMPolicyAssociationsEndToEnd(t *testing.T) {
+	database, dnID, imsi := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	policy, err := database.GetPolicy(ctx, "test-policy")
+	if err != nil {
+		t.Fatalf("GetPolicy: %s", err)
+	}
+
+	assoc := &db.SMPolicyAssociation{
+		ID:            "assoc-1",
+		DataNetworkID: dnID,
+		IMSI:          imsi,
+		PDUSessionID:  1,
+		NodeID:        1,
+		Var5qi:        7,
+	}
+
+	if err := database.CreateSMPolicyAssociation(ctx, assoc); err != nil {
+		t.Fatalf("couldn't create association: %s", err)
+	}
+
+	if err := database.CreateSMPolicyAssociation(ctx, assoc); !errors.Is(err, db.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for a recorded id, got %v", err)
+	}
+
+	if err := database.CreateSMPolicyAssociation(ctx, &db.SMPolicyAssociation{ID: "assoc-2", DataNetworkID: dnID, IMSI: imsi, PDUSessionID: 2, NodeID: 2}); err != nil {
+		t.Fatalf("couldn't create association: %s", err)
+	}
+
+	decision := &db.SMPolicyAssociation{
+		ID:                  "assoc-1",
+		Var5qi:              5,
+		Arp:                 2,
+		SessionAmbrUplink:   "10 Mbps",
+		SessionAmbrDownlink: "50 Mbps",
+		RulesPolicyID:       &policy.ID,
+	}
+
+	if err := database.UpdateSMPolicyDecision(ctx, decision); err != nil {
+		t.Fatalf("couldn't update decision: %s", err)
+	}
+
+	got, err := database.GetSMPolicyAssociation(ctx, "assoc-1")
+	if err != nil {
+		t.Fatalf("couldn't get association: %s", err)
+	}
+
+	if byID, err := database.GetPolicyByID(ctx, policy.ID); err != nil || byID.Name != policy.Name {
+		t.Fatalf("GetPolicyByID = %+v, %v, want %s", byID, err, policy.Name)
+	}
+
+	if got.IMSI != imsi || got.PDUSessionID != 1 || got.Var5qi != 5 || got.Arp != 2 || got.SessionAmbrDownlink != "50 Mbps" || got.RulesPolicyID == nil || *got.RulesPolicyID != policy.ID {
+		t.Fatalf("association = %+v, want the session of assoc-1 with the new decision", got)
+	}
+
+	if err := database.UpdateSMPolicyDecision(ctx, &db.SMPolicyAssociation{ID: "unknown"}); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound updating an unknown association, got %v", err)
+	}
+
+	mine, err := database.ListSMPolicyAssociationsByNode(ctx, 1)
+	if err != nil {
+		t.Fatalf("couldn't list associations: %s", err)
+	}
+
+	if len(mine) != 1 || mine[0].ID != "assoc-1" {
+		t.Fatalf("associations of node 1 = %+v, want assoc-1 only", mine)
+	}
+
+	if err := database.DeleteSMPolicyAssociation(ctx, "assoc-1"); err != nil {
+		t.Fatalf("couldn't delete association: %s", err)
+	}
+
+	if err := database.DeleteSMPolicyAssociation(ctx, "assoc-1"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	if err := database.DeleteSMPolicyAssociationsByNode(ctx, 2); err != nil {
+		t.Fatalf("couldn't delete the associations of node 2: %s", err)
+	}
+
+	all, err := database.ListAllSMPolicyAssociations(ctx)
+	if err != nil {
+		t.Fatalf("couldn't list associations: %s", err)
+	}
+
+	if len(all) != 0 {
+		t.Fatalf("associations = %+v, want none", all)
+	}
+}
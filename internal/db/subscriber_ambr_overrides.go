@@ -0,0 +1,320 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/canonical/sqlair"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const SubscriberAmbrOverridesTableName = "subscriber_ambr_overrides"
+
+// subscriberAmbrOverridesSchema is the migration that introduced the table.
+// Reads below it report no overrides.
+const subscriberAmbrOverridesSchema = 35
+
+const (
+	upsertSubscriberAmbrOverrideStmt         = "INSERT INTO %s (imsi, ueAmbrUplink, ueAmbrDownlink, sessionAmbrUplink, sessionAmbrDownlink, createdAt, expiresAt) VALUES ($SubscriberAmbrOverride.imsi, $SubscriberAmbrOverride.ueAmbrUplink, $SubscriberAmbrOverride.ueAmbrDownlink, $SubscriberAmbrOverride.sessionAmbrUplink, $SubscriberAmbrOverride.sessionAmbrDownlink, $SubscriberAmbrOverride.createdAt, $SubscriberAmbrOverride.expiresAt) ON CONFLICT(imsi) DO UPDATE SET ueAmbrUplink=excluded.ueAmbrUplink, ueAmbrDownlink=excluded.ueAmbrDownlink, sessionAmbrUplink=excluded.sessionAmbrUplink, sessionAmbrDownlink=excluded.sessionAmbrDownlink, createdAt=excluded.createdAt, expiresAt=excluded.expiresAt"
+	deleteSubscriberAmbrOverrideStmt         = "DELETE FROM %s WHERE imsi==$SubscriberAmbrOverride.imsi"
+	deleteExpiredSubscriberAmbrOverridesStmt = "DELETE FROM %s WHERE expiresAt>0 AND expiresAt<=$SubscriberAmbrOverride.expiresAt"
+	getSubscriberAmbrOverrideStmt            = "SELECT &SubscriberAmbrOverride.* FROM %s WHERE imsi==$SubscriberAmbrOverride.imsi"
+	listSubscriberAmbrOverridesStmt          = "SELECT &SubscriberAmbrOverride.* FROM %s ORDER BY imsi"
+)
+
+// SubscriberAmbrOverride replaces, for subscriber IMSI, the UE-AMBR of its
+// profile and the Session-AMBR of its policies. An empty pair keeps the
+// configured one. An override with ExpiresAt (Unix seconds) set stops
+// applying then; zero never expires it.
+type SubscriberAmbrOverride struct {
+	IMSI                string `db:"imsi"` // FK to subscribers.imsi
+	UeAmbrUplink        string `db:"ueAmbrUplink"`
+	UeAmbrDownlink      string `db:"ueAmbrDownlink"`
+	SessionAmbrUplink   string `db:"sessionAmbrUplink"`
+	SessionAmbrDownlink string `db:"sessionAmbrDownlink"`
+	CreatedAt           int64  `db:"createdAt"`
+	ExpiresAt           int64  `db:"expiresAt"`
+}
+
+// ActiveAt reports whether the override applies at now.
+func (o *SubscriberAmbrOverride) ActiveAt(now time.Time) bool {
+	return o.ExpiresAt == 0 || now.Unix() < o.ExpiresAt
+}
+
+// UeAmbr returns the UE-AMBR the override grants in place of the profile's
+// uplink and downlink. A nil override grants the profile's.
+func (o *SubscriberAmbrOverride) UeAmbr(uplink, downlink string) (string, string) {
+	if o == nil || o.UeAmbrUplink == "" {
+		return uplink, downlink
+	}
+
+	return o.UeAmbrUplink, o.UeAmbrDownlink
+}
+
+// SessionAmbr returns the Session-AMBR the override grants in place of the
+// policy's uplink and downlink. A nil override grants the policy's.
+func (o *SubscriberAmbrOverride) SessionAmbr(uplink, downlink string) (string, string) {
+	if o == nil || o.SessionAmbrUplink == "" {
+		return uplink, downlink
+	}
+
+	return o.SessionAmbrUplink, o.SessionAmbrDownlink
+}
+
+// SetSubscriberAmbrOverride records the override of a subscriber, replacing
+// any it had.
+func (db *Database) SetSubscriberAmbrOverride(ctx context.Context, o *SubscriberAmbrOverride) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", SubscriberAmbrOverridesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", SubscriberAmbrOverridesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberAmbrOverridesTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberAmbrOverridesTableName, "upsert").Inc()
+
+	_, err := opSetSubscriberAmbrOverride.Invoke(db, o)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetSubscriberAmbrOverride(ctx context.Context, o *SubscriberAmbrOverride) (any, error) {
+	_, err := db.execSubscriberAmbrOverride(ctx, db.upsertSubscriberAmbrOverrideStmt, o)
+
+	return nil, err
+}
+
+// DeleteSubscriberAmbrOverride returns ErrNotFound for a subscriber without
+// an override.
+func (db *Database) DeleteSubscriberAmbrOverride(ctx context.Context, imsi string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", SubscriberAmbrOverridesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", SubscriberAmbrOverridesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberAmbrOverridesTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberAmbrOverridesTableName, "delete").Inc()
+
+	_, err := opDeleteSubscriberAmbrOverride.Invoke(db, &stringPayload{Value: imsi})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteSubscriberAmbrOverride(ctx context.Context, p *stringPayload) (any, error) {
+	rowsAffected, err := db.execSubscriberAmbrOverride(ctx, db.deleteSubscriberAmbrOverrideStmt, &SubscriberAmbrOverride{IMSI: p.Value})
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// DeleteExpiredSubscriberAmbrOverrides deletes the overrides expired by now
+// (Unix seconds).
+func (db *Database) DeleteExpiredSubscriberAmbrOverrides(ctx context.Context, now int64) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", SubscriberAmbrOverridesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", SubscriberAmbrOverridesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberAmbrOverridesTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberAmbrOverridesTableName, "delete").Inc()
+
+	_, err := opDeleteExpiredSubscriberAmbrOverrides.Invoke(db, &int64Payload{Value: now})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteExpiredSubscriberAmbrOverrides(ctx context.Context, p *int64Payload) (any, error) {
+	_, err := db.execSubscriberAmbrOverride(ctx, db.deleteExpiredSubscriberAmbrOverridesStmt, &SubscriberAmbrOverride{ExpiresAt: p.Value})
+
+	return nil, err
+}
+
+func (db *Database) execSubscriberAmbrOverride(ctx context.Context, stmt *sqlair.Statement, o *SubscriberAmbrOverride) (int64, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, stmt, o).Get(&outcome); err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// GetSubscriberAmbrOverride returns the override of a subscriber, expired
+// or not, and ErrNotFound for a subscriber without one.
+func (db *Database) GetSubscriberAmbrOverride(ctx context.Context, imsi string) (*SubscriberAmbrOverride, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", SubscriberAmbrOverridesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SubscriberAmbrOverridesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(subscriberAmbrOverridesSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberAmbrOverridesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberAmbrOverridesTableName, "select").Inc()
+
+	row := SubscriberAmbrOverride{IMSI: imsi}
+
+	err := db.conn().Query(ctx, db.getSubscriberAmbrOverrideStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// ActiveSubscriberAmbrOverride returns the override that applies to a
+// subscriber at now, or nil when it has none or it expired.
+func (db *Database) ActiveSubscriberAmbrOverride(ctx context.Context, imsi string, now time.Time) (*SubscriberAmbrOverride, error) {
+	o, err := db.GetSubscriberAmbrOverride(ctx, imsi)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if !o.ActiveAt(now) {
+		return nil, nil
+	}
+
+	return o, nil
+}
+
+// ListSubscriberAmbrOverrides returns every override, expired or not, by
+// IMSI.
+func (db *Database) ListSubscriberAmbrOverrides(ctx context.Context) ([]SubscriberAmbrOverride, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", SubscriberAmbrOverridesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SubscriberAmbrOverridesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(subscriberAmbrOverridesSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []SubscriberAmbrOverride{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberAmbrOverridesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberAmbrOverridesTableName, "select").Inc()
+
+	var rows []SubscriberAmbrOverride
+
+	err := db.conn().Query(ctx, db.listSubscriberAmbrOverridesStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []SubscriberAmbrOverride{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestSubscriberAmbrOverridesEndToEnd(t *testing.T) {
+	database, _, imsi := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	if o, err := database.ActiveSubscriberAmbrOverride(ctx, imsi, time.Unix(100, 0)); err != nil || o != nil {
+		t.Fatalf("override = %+v (%v), want none", o, err)
+	}
+
+	boost := &db.SubscriberAmbrOverride{
+		IMSI:                imsi,
+		SessionAmbrUplink:   "50 Mbps",
+		SessionAmbrDownlink: "500 Mbps",
+		CreatedAt:           100,
+		ExpiresAt:           500,
+	}
+
+	if err := database.SetSubscriberAmbrOverride(ctx, boost); err != nil {
+		t.Fatalf("couldn't set override: %s", err)
+	}
+
+	o, err := database.ActiveSubscriberAmbrOverride(ctx, imsi, time.Unix(499, 0))
+	if err != nil || o == nil {
+		t.Fatalf("override = %+v (%v), want the boost", o, err)
+	}
+
+	// Only the Session-AMBR is overridden: the profile's UE-AMBR stands.
+	if ul, dl := o.SessionAmbr("10 Mbps", "100 Mbps"); ul != "50 Mbps" || dl != "500 Mbps" {
+		t.Errorf("Session-AMBR = %s/%s, want the override's", ul, dl)
+	}
+
+	if ul, dl := o.UeAmbr("20 Mbps", "200 Mbps"); ul != "20 Mbps" || dl != "200 Mbps" {
+		t.Errorf("UE-AMBR = %s/%s, want the profile's", ul, dl)
+	}
+
+	if o, err := database.ActiveSubscriberAmbrOverride(ctx, imsi, time.Unix(500, 0)); err != nil || o != nil {
+		t.Fatalf("override = %+v (%v), want none once expired", o, err)
+	}
+
+	// Setting again replaces the override.
+	throttle := &db.SubscriberAmbrOverride{IMSI: imsi, UeAmbrUplink: "1 Mbps", UeAmbrDownlink: "1 Mbps", CreatedAt: 200}
+	if err := database.SetSubscriberAmbrOverride(ctx, throttle); err != nil {
+		t.Fatalf("couldn't replace override: %s", err)
+	}
+
+	got, err := database.GetSubscriberAmbrOverride(ctx, imsi)
+	if err != nil || *got != *throttle {
+		t.Fatalf("override = %+v (%v), want %+v", got, err, throttle)
+	}
+
+	// An override without expiry outlives the sweep.
+	if err := database.DeleteExpiredSubscriberAmbrOverrides(ctx, 1_000_000); err != nil {
+		t.Fatalf("couldn't delete expired overrides: %s", err)
+	}
+
+	list, err := database.ListSubscriberAmbrOverrides(ctx)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("overrides = %+v (%v), want the throttle kept", list, err)
+	}
+
+	if err := database.DeleteSubscriberAmbrOverride(ctx, imsi); err != nil {
+		t.Fatalf("couldn't delete override: %s", err)
+	}
+
+	if err := database.DeleteSubscriberAmbrOverride(ctx, imsi); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a deleted override, got %v", err)
+	}
+
+	// An expired override is swept.
+	boost.ExpiresAt = 300
+	if err := database.SetSubscriberAmbrOverride(ctx, boost); err != nil {
+		t.Fatalf("couldn't set override: %s", err)
+	}
+
+	if err := database.DeleteExpiredSubscriberAmbrOverrides(ctx, 300); err != nil {
+		t.Fatalf("couldn't delete expired overrides: %s", err)
+	}
+
+	if _, err := database.GetSubscriberAmbrOverride(ctx, imsi); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected the expired override deleted, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const listSubscribersAfterStmt = "SELECT &Subscriber.* FROM %s WHERE imsi > $Subscriber.imsi ORDER BY imsi LIMIT $ListArgs.limit"
+
+// SubscriberImportResult is the outcome of ImportSubscribers: the IMSIs it
+// created and, when existing subscribers are skipped, those it left alone.
+type SubscriberImportResult struct {
+	Created  []string `json:"created"`
+	Existing []string `json:"existing,omitempty"`
+}
+
+type importSubscribersPayload struct {
+	Subscribers  []Subscriber `json:"subscribers"`
+	SkipExisting bool         `json:"skip_existing"`
+}
+
+// ImportSubscribers creates subscribers in one changeset. When skipExisting
+// is false, an IMSI that is already provisioned fails the whole import and
+// nothing is written; otherwise that row is skipped and reported.
+func (db *Database) ImportSubscribers(ctx context.Context, subscribers []Subscriber, skipExisting bool) (*SubscriberImportResult, error) {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s (import)", "INSERT", SubscribersTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("INSERT"),
+			attribute.String("db.collection", SubscribersTableName),
+			attribute.Int("rows", len(subscribers)),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscribersTableName, "insert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscribersTableName, "insert").Inc()
+
+	for i := range subscribers {
+		if subscribers[i].ID != "" {
+			continue
+		}
+
+		id, err := uuid.NewV7()
+		if err != nil {
+			return nil, fmt.Errorf("generate subscriber id: %w", err)
+		}
+
+		subscribers[i].ID = id.String()
+	}
+
+	result, err := opImportSubscribers.Invoke(db, &importSubscribersPayload{Subscribers: subscribers, SkipExisting: skipExisting})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &result, nil
+}
+
+func (db *Database) applyImportSubscribers(ctx context.Context, p *importSubscribersPayload) (any, error) {
+	result := SubscriberImportResult{Created: make([]string, 0, len(p.Subscribers))}
+
+	for i := range p.Subscribers {
+		s := &p.Subscribers[i]
+
+		err := db.runner(ctx).Query(ctx, db.createSubscriberStmt, s).Run()
+		if err == nil {
+			result.Created = append(result.Created, s.Imsi)
+			continue
+		}
+
+		if !isUniqueNameError(err) {
+			return nil, fmt.Errorf("query failed: %w", err)
+		}
+
+		if !p.SkipExisting {
+			return nil, fmt.Errorf("subscriber %s: %w", s.Imsi, ErrAlreadyExists)
+		}
+
+		result.Existing = append(result.Existing, s.Imsi)
+	}
+
+	return result, nil
+}
+
+// ListSubscribersAfter returns up to limit subscribers ordered by IMSI,
+// starting after afterImsi. An empty afterImsi starts from the first
+// subscriber. Unlike offset paging, walking the table this way neither
+// skips nor repeats rows when subscribers are added or removed meanwhile.
+func (db *Database) ListSubscribersAfter(ctx context.Context, afterImsi string, limit int) ([]Subscriber, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s (after)", "SELECT", SubscribersTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SubscribersTableName),
+			attribute.Int("limit", limit),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscribersTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscribersTableName, "select").Inc()
+
+	var subs []Subscriber
+
+	err := db.conn().Query(ctx, db.listSubscribersAfterStmt, Subscriber{Imsi: afterImsi}, ListArgs{Limit: limit}).GetAll(&subs)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return nil, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return subs, nil
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+	ellaraft "github.com/ellanetworks/core/internal/raft"
+)
+
+func importTestSubscribers(profileID string, first, n int) []db.Subscriber {
+	subs := make([]db.Subscriber, 0, n)
+	for i := first; i < first+n; i++ {
+		subs = append(subs, db.Subscriber{
+			Imsi:           fmt.Sprintf("00101%010d", i),
+			SequenceNumber: "000000000001",
+			PermanentKey:   "6f30087629feb0b089783c81d0ae09b5",
+			Opc:            "21a7e1897dfb481d62439142cdf1b6ee",
+			ProfileID:      profileID,
+		})
+	}
+
+	return subs
+}
+
+func TestImportSubscribers(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabase(ctx, filepath.Join(t.TempDir(), "db.sqlite3"), ellaraft.ClusterConfig{})
+	if err != nil {
+		t.Fatalf("Couldn't initialize NewDatabase: %s", err)
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Fatalf("Couldn't close database: %s", err)
+		}
+	}()
+
+	profileID, err := createDataNetworkAndPolicy(database)
+	if err != nil {
+		t.Fatalf("Couldn't create data network and policy: %s", err)
+	}
+
+	t.Run("a vendor batch lands in one changeset", func(t *testing.T) {
+		result, err := database.ImportSubscribers(ctx, importTestSubscribers(profileID, 0, 5000), false)
+		if err != nil {
+			t.Fatalf("ImportSubscribers: %v", err)
+		}
+
+		if len(result.Created) != 5000 || len(result.Existing) != 0 {
+			t.Fatalf("unexpected result: %d created, %d existing", len(result.Created), len(result.Existing))
+		}
+
+		count, err := database.CountSubscribers(ctx)
+		if err != nil || count != 5000 {
+			t.Fatalf("expected 5000 subscribers, got %d (%v)", count, err)
+		}
+	})
+
+	t.Run("an existing IMSI rolls back the whole import", func(t *testing.T) {
+		_, err := database.ImportSubscribers(ctx, importTestSubscribers(profileID, 4999, 10), false)
+		if !errors.Is(err, db.ErrAlreadyExists) {
+			t.Fatalf("expected ErrAlreadyExists, got %v", err)
+		}
+
+		if _, err := database.GetSubscriber(ctx, "001010000005000"); !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("expected no partial import, got %v", err)
+		}
+	})
+
+	t.Run("skipping existing IMSIs imports the rest", func(t *testing.T) {
+		result, err := database.ImportSubscribers(ctx, importTestSubscribers(profileID, 4999, 3), true)
+		if err != nil {
+			t.Fatalf("ImportSubscribers: %v", err)
+		}
+
+		if len(result.Created) != 2 || len(result.Existing) != 1 || result.Existing[0] != "001010000004999" {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("listing after an IMSI walks the table in order", func(t *testing.T) {
+		var (
+			after string
+			seen  int
+		)
+
+		for {
+			page, err := database.ListSubscribersAfter(ctx, after, 1000)
+			if err != nil {
+				t.Fatalf("ListSubscribersAfter: %v", err)
+			}
+
+			if len(page) == 0 {
+				break
+			}
+
+			for _, s := range page {
+				if s.Imsi <= after {
+					t.Fatalf("IMSI %s out of order after %s", s.Imsi, after)
+				}
+
+				after = s.Imsi
+				seen++
+			}
+		}
+
+		if seen != 5002 {
+			t.Fatalf("expected 5002 subscribers, walked %d", seen)
+		}
+	})
+}
@@ -0,0 +1,345 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/canonical/sqlair"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const SubscriberLifecyclesTableName = "subscriber_lifecycles"
+
+// subscriberLifecyclesSchema is the migration that introduced the table.
+// Reads below it report every subscriber active.
+const subscriberLifecyclesSchema = 37
+
+const (
+	upsertSubscriberLifecycleStmt  = "INSERT INTO %s (imsi, state, validFrom, validUntil, updatedAt) VALUES ($SubscriberLifecycle.imsi, $SubscriberLifecycle.state, $SubscriberLifecycle.validFrom, $SubscriberLifecycle.validUntil, $SubscriberLifecycle.updatedAt) ON CONFLICT(imsi) DO UPDATE SET state=excluded.state, validFrom=excluded.validFrom, validUntil=excluded.validUntil, updatedAt=excluded.updatedAt"
+	deleteSubscriberLifecycleStmt  = "DELETE FROM %s WHERE imsi==$SubscriberLifecycle.imsi"
+	expireSubscriberLifecyclesStmt = "UPDATE %s SET state='expired', updatedAt=$SubscriberLifecycle.updatedAt WHERE state!='expired' AND validUntil>0 AND validUntil<=$SubscriberLifecycle.validUntil"
+	getSubscriberLifecycleStmt     = "SELECT &SubscriberLifecycle.* FROM %s WHERE imsi==$SubscriberLifecycle.imsi"
+	listSubscriberLifecyclesStmt   = "SELECT &SubscriberLifecycle.* FROM %s ORDER BY imsi"
+)
+
+// SubscriberState is where a subscriber stands in its lifecycle. A
+// subscriber without a lifecycle row is active.
+type SubscriberState string
+
+const (
+	SubscriberStateActive SubscriberState = "active"
+	// SubscriberStateSuspended refuses the subscriber's registrations.
+	SubscriberStateSuspended SubscriberState = "suspended"
+	// SubscriberStateBarredData admits the subscriber's registrations but
+	// none of its PDU sessions.
+	SubscriberStateBarredData SubscriberState = "barred_data"
+	// SubscriberStateExpired refuses the subscriber's registrations for
+	// good: a subscriber lands here when its validity window closes.
+	SubscriberStateExpired SubscriberState = "expired"
+)
+
+// Valid reports whether s is a known state.
+func (s SubscriberState) Valid() bool {
+	switch s {
+	case SubscriberStateActive, SubscriberStateSuspended, SubscriberStateBarredData, SubscriberStateExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// AllowsRegistration reports whether a subscriber in state s may register.
+func (s SubscriberState) AllowsRegistration() bool {
+	return s == SubscriberStateActive || s == SubscriberStateBarredData
+}
+
+// AllowsData reports whether a subscriber in state s may hold PDU sessions.
+func (s SubscriberState) AllowsData() bool {
+	return s == SubscriberStateActive
+}
+
+// SubscriberLifecycle puts subscriber IMSI in State, and bounds, when
+// ValidFrom or ValidUntil (Unix seconds) is set, the time it may use the
+// network. Zero leaves the bound open.
+type SubscriberLifecycle struct {
+	IMSI       string `db:"imsi"` // FK to subscribers.imsi
+	State      string `db:"state"`
+	ValidFrom  int64  `db:"validFrom"`
+	ValidUntil int64  `db:"validUntil"`
+	UpdatedAt  int64  `db:"updatedAt"`
+}
+
+// StateAt returns the state that applies at now: expired from ValidUntil on,
+// suspended before ValidFrom, and State in between. A nil lifecycle is
+// active.
+func (l *SubscriberLifecycle) StateAt(now time.Time) SubscriberState {
+	if l == nil {
+		return SubscriberStateActive
+	}
+
+	state := SubscriberState(l.State)
+
+	switch {
+	case state == SubscriberStateExpired:
+		return state
+	case l.ValidUntil > 0 && now.Unix() >= l.ValidUntil:
+		return SubscriberStateExpired
+	case l.ValidFrom > 0 && now.Unix() < l.ValidFrom:
+		return SubscriberStateSuspended
+	default:
+		return state
+	}
+}
+
+// SetSubscriberLifecycle records the lifecycle of a subscriber, replacing
+// any it had.
+func (db *Database) SetSubscriberLifecycle(ctx context.Context, l *SubscriberLifecycle) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPSERT", SubscriberLifecyclesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPSERT"),
+			attribute.String("db.collection", SubscriberLifecyclesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberLifecyclesTableName, "upsert"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberLifecyclesTableName, "upsert").Inc()
+
+	_, err := opSetSubscriberLifecycle.Invoke(db, l)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applySetSubscriberLifecycle(ctx context.Context, l *SubscriberLifecycle) (any, error) {
+	_, err := db.execSubscriberLifecycle(ctx, db.upsertSubscriberLifecycleStmt, l)
+
+	return nil, err
+}
+
+// DeleteSubscriberLifecycle makes a subscriber active again, with no
+// validity window. It returns ErrNotFound for a subscriber without a
+// lifecycle.
+func (db *Database) DeleteSubscriberLifecycle(ctx context.Context, imsi string) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "DELETE", SubscriberLifecyclesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("DELETE"),
+			attribute.String("db.collection", SubscriberLifecyclesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberLifecyclesTableName, "delete"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberLifecyclesTableName, "delete").Inc()
+
+	_, err := opDeleteSubscriberLifecycle.Invoke(db, &stringPayload{Value: imsi})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyDeleteSubscriberLifecycle(ctx context.Context, p *stringPayload) (any, error) {
+	rowsAffected, err := db.execSubscriberLifecycle(ctx, db.deleteSubscriberLifecycleStmt, &SubscriberLifecycle{IMSI: p.Value})
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nil, nil
+}
+
+// ExpireSubscriberLifecycles moves to expired every subscriber whose
+// validity window closed by now (Unix seconds).
+func (db *Database) ExpireSubscriberLifecycles(ctx context.Context, now int64) error {
+	_, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "UPDATE", SubscriberLifecyclesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("UPDATE"),
+			attribute.String("db.collection", SubscriberLifecyclesTableName),
+		),
+	)
+	defer span.End()
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberLifecyclesTableName, "update"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberLifecyclesTableName, "update").Inc()
+
+	_, err := opExpireSubscriberLifecycles.Invoke(db, &int64Payload{Value: now})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return nil
+}
+
+func (db *Database) applyExpireSubscriberLifecycles(ctx context.Context, p *int64Payload) (any, error) {
+	_, err := db.execSubscriberLifecycle(ctx, db.expireSubscriberLifecyclesStmt, &SubscriberLifecycle{UpdatedAt: p.Value, ValidUntil: p.Value})
+
+	return nil, err
+}
+
+func (db *Database) execSubscriberLifecycle(ctx context.Context, stmt *sqlair.Statement, l *SubscriberLifecycle) (int64, error) {
+	var outcome sqlair.Outcome
+
+	if err := db.runner(ctx).Query(ctx, stmt, l).Get(&outcome); err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+
+	rowsAffected, err := outcome.Result().RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// GetSubscriberLifecycle returns the lifecycle of a subscriber, and
+// ErrNotFound for a subscriber without one.
+func (db *Database) GetSubscriberLifecycle(ctx context.Context, imsi string) (*SubscriberLifecycle, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", SubscriberLifecyclesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SubscriberLifecyclesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(subscriberLifecyclesSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return nil, ErrNotFound
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberLifecyclesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberLifecyclesTableName, "select").Inc()
+
+	row := SubscriberLifecycle{IMSI: imsi}
+
+	err := db.conn().Query(ctx, db.getSubscriberLifecycleStmt, row).Get(&row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "not found")
+			return nil, ErrNotFound
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return &row, nil
+}
+
+// SubscriberStateAt returns the state that applies to a subscriber at now.
+func (db *Database) SubscriberStateAt(ctx context.Context, imsi string, now time.Time) (SubscriberState, error) {
+	l, err := db.GetSubscriberLifecycle(ctx, imsi)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return SubscriberStateActive, nil
+		}
+
+		return "", err
+	}
+
+	return l.StateAt(now), nil
+}
+
+// ListSubscriberLifecycles returns every lifecycle by IMSI.
+func (db *Database) ListSubscriberLifecycles(ctx context.Context) ([]SubscriberLifecycle, error) {
+	ctx, span := tracer.Start(
+		ctx,
+		fmt.Sprintf("%s %s", "SELECT", SubscriberLifecyclesTableName),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNameSQLite,
+			semconv.DBOperationName("SELECT"),
+			attribute.String("db.collection", SubscriberLifecyclesTableName),
+		),
+	)
+	defer span.End()
+
+	if db.checkOpSchema(subscriberLifecyclesSchema) != nil {
+		span.SetStatus(codes.Ok, "schema pending")
+		return []SubscriberLifecycle{}, nil
+	}
+
+	timer := prometheus.NewTimer(DBQueryDuration.WithLabelValues(SubscriberLifecyclesTableName, "select"))
+	defer timer.ObserveDuration()
+
+	DBQueriesTotal.WithLabelValues(SubscriberLifecyclesTableName, "select").Inc()
+
+	var rows []SubscriberLifecycle
+
+	err := db.conn().Query(ctx, db.listSubscriberLifecyclesStmt).GetAll(&rows)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetStatus(codes.Ok, "no rows")
+			return []SubscriberLifecycle{}, nil
+		}
+
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query failed")
+
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	return rows, nil
+}
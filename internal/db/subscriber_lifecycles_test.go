@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestSubscriberLifecyclesEndToEnd(t *testing.T) {
+	database, _, imsi := setupLeaseTestDB(t)
+	ctx := context.Background()
+
+	if state, err := database.SubscriberStateAt(ctx, imsi, time.Unix(100, 0)); err != nil || state != db.SubscriberStateActive {
+		t.Fatalf("state = %q (%v), want active without a lifecycle", state, err)
+	}
+
+	contractor := &db.SubscriberLifecycle{
+		IMSI:       imsi,
+		State:      string(db.SubscriberStateActive),
+		ValidFrom:  200,
+		ValidUntil: 500,
+		UpdatedAt:  100,
+	}
+
+	if err := database.SetSubscriberLifecycle(ctx, contractor); err != nil {
+		t.Fatalf("couldn't set lifecycle: %s", err)
+	}
+
+	for _, tc := range []struct {
+		at   int64
+		want db.SubscriberState
+	}{
+		{199, db.SubscriberStateSuspended},
+		{200, db.SubscriberStateActive},
+		{499, db.SubscriberStateActive},
+		{500, db.SubscriberStateExpired},
+	} {
+		state, err := database.SubscriberStateAt(ctx, imsi, time.Unix(tc.at, 0))
+		if err != nil || state != tc.want {
+			t.Errorf("state at %d = %q (%v), want %q", tc.at, state, err, tc.want)
+		}
+	}
+
+	// The sweep leaves a window still open alone...
+	if err := database.ExpireSubscriberLifecycles(ctx, 499); err != nil {
+		t.Fatalf("couldn't expire lifecycles: %s", err)
+	}
+
+	got, err := database.GetSubscriberLifecycle(ctx, imsi)
+	if err != nil || got.State != string(db.SubscriberStateActive) {
+		t.Fatalf("lifecycle = %+v (%v), want still active", got, err)
+	}
+
+	// ...and records the expiry of a closed one.
+	if err := database.ExpireSubscriberLifecycles(ctx, 500); err != nil {
+		t.Fatalf("couldn't expire lifecycles: %s", err)
+	}
+
+	got, err = database.GetSubscriberLifecycle(ctx, imsi)
+	if err != nil || got.State != string(db.SubscriberStateExpired) || got.UpdatedAt != 500 {
+		t.Fatalf("lifecycle = %+v (%v), want expired at 500", got, err)
+	}
+
+	list, err := database.ListSubscriberLifecycles(ctx)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("lifecycles = %+v (%v), want one", list, err)
+	}
+
+	if err := database.DeleteSubscriberLifecycle(ctx, imsi); err != nil {
+		t.Fatalf("couldn't delete lifecycle: %s", err)
+	}
+
+	if err := database.DeleteSubscriberLifecycle(ctx, imsi); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a deleted lifecycle, got %v", err)
+	}
+}
+
+func TestSubscriberStateAllows(t *testing.T) {
+	for _, tc := range []struct {
+		state              db.SubscriberState
+		registration, data bool
+	}{
+		{db.SubscriberStateActive, true, true},
+		{db.SubscriberStateBarredData, true, false},
+		{db.SubscriberStateSuspended, false, false},
+		{db.SubscriberStateExpired, false, false},
+	} {
+		if got := tc.state.AllowsRegistration(); got != tc.registration {
+			t.Errorf("%s AllowsRegistration = %v, want %v", tc.state, got, tc.registration)
+		}
+
+		if got := tc.state.AllowsData(); got != tc.data {
+			t.Errorf("%s AllowsData = %v, want %v", tc.state, got, tc.data)
+		}
+	}
+}
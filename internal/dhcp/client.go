@@ -0,0 +1,341 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dhcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	// ServerPort is where servers listen and relays receive replies.
+	ServerPort = 67
+
+	defaultTimeout  = 2 * time.Second
+	defaultAttempts = 3
+)
+
+var (
+	// ErrNak is returned when the server refuses the address.
+	ErrNak = errors.New("DHCP server sent a NAK")
+	// ErrNoAnswer is returned when the server does not answer any attempt.
+	ErrNoAnswer = errors.New("DHCP server did not answer")
+)
+
+// Config sets up a Client.
+type Config struct {
+	// Server is the DHCPv4 server, on ServerPort unless given.
+	Server netip.AddrPort
+	// Relay is the N6 address used as giaddr. The server must route the
+	// leased range's replies back to it.
+	Relay netip.Addr
+	// Listen is where replies are read. The zero value is Relay on
+	// ServerPort.
+	Listen netip.AddrPort
+	// Timeout bounds each attempt, Attempts counts them.
+	Timeout  time.Duration
+	Attempts int
+}
+
+// Request identifies the UE a lease is for. ClientID is sent as option 61
+// and names the lease on the server; CircuitID and RemoteID go in option 82.
+type Request struct {
+	ClientID  string
+	CircuitID string
+	RemoteID  string
+}
+
+// Lease is an address the server handed out. RenewAt is when the lease
+// should be renewed (T1), ExpiresAt when it ends. Both are zero for an
+// infinite lease.
+type Lease struct {
+	Address   netip.Addr
+	ServerID  netip.Addr
+	RenewAt   time.Time
+	ExpiresAt time.Time
+}
+
+// Client relays requests for one server. It is safe for concurrent use.
+type Client struct {
+	conn     *net.UDPConn
+	server   *net.UDPAddr
+	relay    netip.Addr
+	timeout  time.Duration
+	attempts int
+
+	mu      sync.Mutex
+	pending map[uint32]chan *message
+
+	wg sync.WaitGroup
+}
+
+// NewClient binds the reply socket and starts reading from it.
+func NewClient(cfg Config) (*Client, error) {
+	if !cfg.Relay.Is4() {
+		return nil, fmt.Errorf("relay address %s is not IPv4", cfg.Relay)
+	}
+
+	server := cfg.Server
+	if server.Port() == 0 {
+		server = netip.AddrPortFrom(server.Addr(), ServerPort)
+	}
+
+	listen := cfg.Listen
+	if !listen.IsValid() {
+		listen = netip.AddrPortFrom(cfg.Relay, ServerPort)
+	}
+
+	conn, err := net.ListenUDP("udp4", net.UDPAddrFromAddrPort(listen))
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", listen, err)
+	}
+
+	c := &Client{
+		conn:     conn,
+		server:   net.UDPAddrFromAddrPort(server),
+		relay:    cfg.Relay,
+		timeout:  cfg.Timeout,
+		attempts: cfg.Attempts,
+		pending:  map[uint32]chan *message{},
+	}
+
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+
+	if c.attempts <= 0 {
+		c.attempts = defaultAttempts
+	}
+
+	c.wg.Go(c.read)
+
+	return c, nil
+}
+
+// Close stops the client. Exchanges in flight fail.
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	c.wg.Wait()
+
+	return err
+}
+
+func (c *Client) read() {
+	buf := make([]byte, 1500)
+
+	for {
+		n, _, err := c.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		m, err := parseMessage(buf[:n])
+		if err != nil || m.op != opBootReply {
+			continue
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[m.xid]
+		c.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// Acquire runs DISCOVER, OFFER, REQUEST, ACK for a new address.
+func (c *Client) Acquire(ctx context.Context, req Request) (*Lease, error) {
+	xid := rand.Uint32()
+	chaddr := hardwareAddress(req.ClientID)
+
+	discover := c.newRequest(MsgDiscover, xid, chaddr, req)
+
+	offer, err := c.exchange(ctx, discover, MsgOffer)
+	if err != nil {
+		return nil, err
+	}
+
+	if !offer.yiaddr.IsValid() {
+		return nil, fmt.Errorf("offer without an address")
+	}
+
+	serverID := offer.addr(optServerID)
+
+	request := c.newRequest(MsgRequest, xid, chaddr, req)
+	request.setOption(optRequestedIP, addrOption(offer.yiaddr))
+
+	if serverID.IsValid() {
+		request.setOption(optServerID, addrOption(serverID))
+	}
+
+	ack, err := c.exchange(ctx, request, MsgAck)
+	if err != nil {
+		return nil, err
+	}
+
+	if ack.yiaddr != offer.yiaddr {
+		return nil, fmt.Errorf("ack for %s after an offer of %s", ack.yiaddr, offer.yiaddr)
+	}
+
+	return leaseFrom(ack, serverID, time.Now()), nil
+}
+
+// Renew asks the server to extend a lease (RFC 2131 §4.3.2, RENEWING).
+func (c *Client) Renew(ctx context.Context, req Request, address netip.Addr) (*Lease, error) {
+	request := c.newRequest(MsgRequest, rand.Uint32(), hardwareAddress(req.ClientID), req)
+	request.ciaddr = address
+
+	ack, err := c.exchange(ctx, request, MsgAck)
+	if err != nil {
+		return nil, err
+	}
+
+	lease := leaseFrom(ack, netip.Addr{}, time.Now())
+	if !lease.Address.IsValid() {
+		lease.Address = address
+	}
+
+	return lease, nil
+}
+
+// Release gives an address back. The server does not answer, so delivery
+// is best effort.
+func (c *Client) Release(req Request, address netip.Addr, serverID netip.Addr) error {
+	release := c.newRequest(MsgRelease, rand.Uint32(), hardwareAddress(req.ClientID), req)
+	release.ciaddr = address
+
+	if serverID.IsValid() {
+		release.setOption(optServerID, addrOption(serverID))
+	}
+
+	b, err := release.marshal()
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.conn.WriteToUDP(b, c.server); err != nil {
+		return fmt.Errorf("send release: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) newRequest(msgType uint8, xid uint32, chaddr [hlenEthernet]byte, req Request) *message {
+	m := newRequest(msgType, xid, chaddr)
+	m.hops = 1
+	m.giaddr = c.relay
+	m.setOption(optClientID, clientIDOption(req.ClientID))
+
+	if msgType != MsgRelease {
+		m.setOption(optParamRequest, []byte{optSubnetMask, optRouter, optDNS, optLeaseTime, optServerID, optRenewalTime, optRebindingTime})
+	}
+
+	if info := relayAgentInfo(req.CircuitID, req.RemoteID); len(info) > 0 {
+		m.setOption(optRelayAgentInfo, info)
+	}
+
+	return m
+}
+
+// exchange sends m until a reply of type want, or a NAK, arrives.
+func (c *Client) exchange(ctx context.Context, m *message, want uint8) (*message, error) {
+	b, err := m.marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *message, 1)
+
+	c.mu.Lock()
+	c.pending[m.xid] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, m.xid)
+		c.mu.Unlock()
+	}()
+
+	for range c.attempts {
+		if _, err := c.conn.WriteToUDP(b, c.server); err != nil {
+			return nil, fmt.Errorf("send to %s: %w", c.server, err)
+		}
+
+		timer := time.NewTimer(c.timeout)
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+				break wait
+			case reply := <-ch:
+				switch reply.msgType {
+				case want:
+					timer.Stop()
+					return reply, nil
+				case MsgNak:
+					timer.Stop()
+					return nil, ErrNak
+				}
+			}
+		}
+	}
+
+	return nil, ErrNoAnswer
+}
+
+func leaseFrom(ack *message, serverID netip.Addr, now time.Time) *Lease {
+	l := &Lease{Address: ack.yiaddr, ServerID: ack.addr(optServerID)}
+	if !l.ServerID.IsValid() {
+		l.ServerID = serverID
+	}
+
+	leaseTime := ack.duration(optLeaseTime)
+	if leaseTime == 0 {
+		return l
+	}
+
+	t1 := ack.duration(optRenewalTime)
+	if t1 == 0 || t1 >= leaseTime {
+		t1 = leaseTime / 2
+	}
+
+	l.RenewAt = now.Add(t1)
+	l.ExpiresAt = now.Add(leaseTime)
+
+	return l
+}
+
+// hardwareAddress derives a stable, locally administered unicast MAC from
+// the client id, for servers that key leases on chaddr.
+func hardwareAddress(clientID string) [hlenEthernet]byte {
+	sum := sha256.Sum256([]byte(clientID))
+
+	var mac [hlenEthernet]byte
+
+	copy(mac[:], sum[:])
+	mac[0] = (mac[0] | 0x02) &^ 0x01
+
+	return mac
+}
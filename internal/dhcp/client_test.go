@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dhcp_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/dhcp"
+	"github.com/ellanetworks/core/internal/dhcp/dhcptest"
+)
+
+var loopback = netip.MustParseAddr("127.0.0.1")
+
+func newPair(t *testing.T, leaseTime time.Duration) (*dhcp.Client, *dhcptest.Server) {
+	t.Helper()
+
+	srv, err := dhcptest.NewServer(netip.AddrPortFrom(loopback, 0), netip.MustParsePrefix("172.20.0.0/29"), leaseTime)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	t.Cleanup(func() { _ = srv.Close() })
+
+	c, err := dhcp.NewClient(dhcp.Config{
+		Server:  srv.Addr(),
+		Relay:   loopback,
+		Listen:  netip.AddrPortFrom(loopback, 0),
+		Timeout: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c, srv
+}
+
+func TestAcquireRenewRelease(t *testing.T) {
+	c, srv := newPair(t, time.Hour)
+	ctx := context.Background()
+
+	req := dhcp.Request{ClientID: "imsi-001010000000001-1", CircuitID: "internet", RemoteID: "001010000000001"}
+
+	lease, err := c.Acquire(ctx, req)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	if lease.Address != netip.MustParseAddr("172.20.0.1") || lease.ServerID != loopback {
+		t.Fatalf("unexpected lease %+v", lease)
+	}
+
+	if lease.RenewAt.IsZero() || !lease.RenewAt.Before(lease.ExpiresAt) {
+		t.Fatalf("unexpected timers %+v", lease)
+	}
+
+	if got := srv.RemoteID(req.ClientID); got != req.RemoteID {
+		t.Fatalf("server saw remote id %q, want %q", got, req.RemoteID)
+	}
+
+	second, err := c.Acquire(ctx, dhcp.Request{ClientID: "imsi-001010000000002-1"})
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	if second.Address == lease.Address {
+		t.Fatalf("two clients were given %s", lease.Address)
+	}
+
+	renewed, err := c.Renew(ctx, req, lease.Address)
+	if err != nil {
+		t.Fatalf("Renew: %v", err)
+	}
+
+	if renewed.Address != lease.Address {
+		t.Fatalf("renewal moved the address to %s", renewed.Address)
+	}
+
+	if _, err := c.Renew(ctx, dhcp.Request{ClientID: "imsi-001010000000003-1"}, lease.Address); !errors.Is(err, dhcp.ErrNak) {
+		t.Fatalf("renewing someone else's address: expected ErrNak, got %v", err)
+	}
+
+	if err := c.Release(req, lease.Address, lease.ServerID); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, held := srv.Leases()[req.ClientID]; !held {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("server kept the released lease")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAcquireWithoutServer(t *testing.T) {
+	c, err := dhcp.NewClient(dhcp.Config{
+		Server:   netip.AddrPortFrom(loopback, 9),
+		Relay:    loopback,
+		Listen:   netip.AddrPortFrom(loopback, 0),
+		Timeout:  50 * time.Millisecond,
+		Attempts: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	defer func() { _ = c.Close() }()
+
+	if _, err := c.Acquire(context.Background(), dhcp.Request{ClientID: "imsi-001010000000001-1"}); !errors.Is(err, dhcp.ErrNoAnswer) {
+		t.Fatalf("expected ErrNoAnswer, got %v", err)
+	}
+}
+
+func TestNewClientRejectsIPv6Relay(t *testing.T) {
+	if _, err := dhcp.NewClient(dhcp.Config{Relay: netip.MustParseAddr("2001:db8::1")}); err == nil {
+		t.Fatal("expected an error for an IPv6 relay address")
+	}
+}
@@ -0,0 +1,316 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package dhcptest provides a minimal DHCPv4 server that stands in for the
+// external server of the dhcp package's relay in tests and labs. It decodes
+// messages on its own, so a mistake in the relay's codec is not mirrored on
+// the server side.
+package dhcptest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/dhcp"
+)
+
+const (
+	opBootRequest = 1
+	opBootReply   = 2
+
+	// headerLen is the fixed BOOTP header up to the magic cookie.
+	headerLen = 236
+	minLen    = headerLen + 4
+
+	optPad            = 0
+	optLeaseTime      = 51
+	optMessageType    = 53
+	optServerID       = 54
+	optRequestedIP    = 50
+	optClientID       = 61
+	optRelayAgentInfo = 82
+	optEnd            = 255
+	subOptRemoteID    = 2
+)
+
+var magicCookie = [4]byte{99, 130, 83, 99}
+
+// Server leases addresses from one range to relayed clients, keyed on the
+// client id. It answers the relay the request came from.
+type Server struct {
+	conn      *net.UDPConn
+	pool      netip.Prefix
+	serverID  netip.Addr
+	leaseTime time.Duration
+
+	mu     sync.Mutex
+	leases map[string]netip.Addr
+	relays map[string]string
+
+	wg sync.WaitGroup
+}
+
+// NewServer listens on listen and leases from pool, skipping its network
+// address.
+func NewServer(listen netip.AddrPort, pool netip.Prefix, leaseTime time.Duration) (*Server, error) {
+	if !pool.Addr().Is4() {
+		return nil, fmt.Errorf("pool %s is not IPv4", pool)
+	}
+
+	conn, err := net.ListenUDP("udp4", net.UDPAddrFromAddrPort(listen))
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", listen, err)
+	}
+
+	s := &Server{
+		conn:      conn,
+		pool:      pool.Masked(),
+		serverID:  listen.Addr(),
+		leaseTime: leaseTime,
+		leases:    map[string]netip.Addr{},
+		relays:    map[string]string{},
+	}
+
+	s.wg.Go(s.serve)
+
+	return s, nil
+}
+
+// Addr is where the server listens.
+func (s *Server) Addr() netip.AddrPort {
+	return s.conn.LocalAddr().(*net.UDPAddr).AddrPort()
+}
+
+// Leases returns the addresses held, by client id.
+func (s *Server) Leases() map[string]netip.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.leases)
+}
+
+// RemoteID returns the option 82 remote id last relayed for a client id.
+func (s *Server) RemoteID(clientID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.relays[clientID]
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	err := s.conn.Close()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	buf := make([]byte, 1500)
+
+	for {
+		n, from, err := s.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		req, ok := parseRequest(buf[:n])
+		if !ok {
+			continue
+		}
+
+		reply := s.handle(req)
+		if reply == nil {
+			continue
+		}
+
+		_, _ = s.conn.WriteToUDPAddrPort(reply, from)
+	}
+}
+
+// request is the part of a relayed BOOTREQUEST the server answers from.
+type request struct {
+	header  []byte
+	ciaddr  netip.Addr
+	msgType uint8
+	options map[uint8][]byte
+}
+
+func parseRequest(b []byte) (*request, bool) {
+	if len(b) < minLen || [4]byte(b[headerLen:minLen]) != magicCookie || b[0] != opBootRequest {
+		return nil, false
+	}
+
+	req := &request{
+		header:  b[:headerLen],
+		ciaddr:  netip.AddrFrom4([4]byte(b[12:16])),
+		options: map[uint8][]byte{},
+	}
+
+	for opts := b[minLen:]; len(opts) > 0 && opts[0] != optEnd; {
+		if opts[0] == optPad {
+			opts = opts[1:]
+			continue
+		}
+
+		if len(opts) < 2 || len(opts) < 2+int(opts[1]) {
+			return nil, false
+		}
+
+		req.options[opts[0]] = opts[2 : 2+int(opts[1])]
+		opts = opts[2+int(opts[1]):]
+	}
+
+	v := req.options[optMessageType]
+	if len(v) != 1 {
+		return nil, false
+	}
+
+	req.msgType = v[0]
+
+	return req, true
+}
+
+func (r *request) addr(code uint8) netip.Addr {
+	v := r.options[code]
+	if len(v) != 4 {
+		return netip.Addr{}
+	}
+
+	return netip.AddrFrom4([4]byte(v))
+}
+
+func (s *Server) handle(req *request) []byte {
+	clientID := string(req.options[optClientID])
+	if len(clientID) > 0 {
+		clientID = clientID[1:]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if remoteID, ok := subOption(req.options[optRelayAgentInfo], subOptRemoteID); ok {
+		s.relays[clientID] = remoteID
+	}
+
+	switch req.msgType {
+	case dhcp.MsgDiscover:
+		addr, ok := s.leases[clientID]
+		if !ok {
+			addr, ok = s.free()
+			if !ok {
+				return nil
+			}
+		}
+
+		return s.reply(req, dhcp.MsgOffer, addr)
+	case dhcp.MsgRequest:
+		requested := req.addr(optRequestedIP)
+		if !requested.IsValid() || requested.IsUnspecified() {
+			requested = req.ciaddr
+		}
+
+		if held, ok := s.leases[clientID]; ok && held != requested {
+			return s.reply(req, dhcp.MsgNak, netip.Addr{})
+		}
+
+		if !s.pool.Contains(requested) || s.heldByOther(clientID, requested) {
+			return s.reply(req, dhcp.MsgNak, netip.Addr{})
+		}
+
+		s.leases[clientID] = requested
+
+		return s.reply(req, dhcp.MsgAck, requested)
+	case dhcp.MsgRelease:
+		if s.leases[clientID] == req.ciaddr {
+			delete(s.leases, clientID)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) free() (netip.Addr, bool) {
+	for a := s.pool.Addr().Next(); s.pool.Contains(a); a = a.Next() {
+		if !s.heldByOther("", a) {
+			return a, true
+		}
+	}
+
+	return netip.Addr{}, false
+}
+
+func (s *Server) heldByOther(clientID string, a netip.Addr) bool {
+	for id, held := range s.leases {
+		if held == a && id != clientID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reply builds the BOOTREPLY to req: its header with yiaddr set and the
+// client's and next server's addresses and the boot file cleared, then the
+// message type, server id, lease time and the relay agent information echoed
+// back (RFC 3046 §2.2).
+func (s *Server) reply(req *request, msgType uint8, yiaddr netip.Addr) []byte {
+	b := make([]byte, minLen, 300)
+	copy(b, req.header)
+	b[0] = opBootReply
+	clear(b[12:24])
+	clear(b[44:headerLen])
+
+	if yiaddr.Is4() {
+		v := yiaddr.As4()
+		copy(b[16:20], v[:])
+	}
+
+	copy(b[headerLen:], magicCookie[:])
+
+	serverID := s.serverID.As4()
+
+	b = append(b, optMessageType, 1, msgType)
+	b = append(b, optServerID, 4)
+	b = append(b, serverID[:]...)
+
+	if msgType != dhcp.MsgNak && s.leaseTime > 0 {
+		b = append(b, optLeaseTime, 4)
+		b = binary.BigEndian.AppendUint32(b, uint32(s.leaseTime/time.Second))
+	}
+
+	if info, ok := req.options[optRelayAgentInfo]; ok {
+		b = append(b, optRelayAgentInfo, uint8(len(info)))
+		b = append(b, info...)
+	}
+
+	b = append(b, optEnd)
+
+	for len(b) < 300 {
+		b = append(b, optPad)
+	}
+
+	return b
+}
+
+func subOption(info []byte, code uint8) (string, bool) {
+	for len(info) >= 2 && len(info) >= 2+int(info[1]) {
+		if info[0] == code {
+			return string(info[2 : 2+int(info[1])]), true
+		}
+
+		info = info[2+int(info[1]):]
+	}
+
+	return "", false
+}
@@ -0,0 +1,271 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package dhcp leases UE IPv4 addresses from an external DHCPv4 server.
+// Ella Core acts as a relay agent on N6 (RFC 2131 §4.1, RFC 3046): it
+// talks to the server on the UE's behalf, with its relay address as giaddr
+// and the data network and IMSI in the relay agent information option.
+package dhcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Message types (RFC 2132 §9.6).
+const (
+	MsgDiscover uint8 = 1
+	MsgOffer    uint8 = 2
+	MsgRequest  uint8 = 3
+	MsgDecline  uint8 = 4
+	MsgAck      uint8 = 5
+	MsgNak      uint8 = 6
+	MsgRelease  uint8 = 7
+)
+
+const (
+	opBootRequest = 1
+	opBootReply   = 2
+
+	htypeEthernet = 1
+	hlenEthernet  = 6
+
+	// headerLen is the fixed BOOTP header up to the magic cookie.
+	headerLen = 236
+	minLen    = headerLen + 4
+	// maxLen is what a client may send without option 57 (RFC 2131 §2).
+	maxLen = 576
+)
+
+var magicCookie = [4]byte{99, 130, 83, 99}
+
+// Option codes used by the relay (RFC 2132, RFC 3046).
+const (
+	optPad             = 0
+	optSubnetMask      = 1
+	optRouter          = 3
+	optDNS             = 6
+	optRequestedIP     = 50
+	optLeaseTime       = 51
+	optMessageType     = 53
+	optServerID        = 54
+	optParamRequest    = 55
+	optRenewalTime     = 58
+	optRebindingTime   = 59
+	optClientID        = 61
+	optRelayAgentInfo  = 82
+	optEnd             = 255
+	subOptCircuitID    = 1
+	subOptRemoteID     = 2
+	clientIDTypeOpaque = 0
+)
+
+var errMalformed = errors.New("malformed DHCP message")
+
+// message is a DHCPv4 message. Options keeps the raw value of each option
+// seen, last one wins.
+type message struct {
+	op      uint8
+	hops    uint8
+	xid     uint32
+	flags   uint16
+	ciaddr  netip.Addr
+	yiaddr  netip.Addr
+	giaddr  netip.Addr
+	chaddr  [hlenEthernet]byte
+	msgType uint8
+	options map[uint8][]byte
+	// order is the order options are written in; option 82 always goes
+	// last (RFC 3046 §2.1).
+	order []uint8
+}
+
+func newRequest(msgType uint8, xid uint32, chaddr [hlenEthernet]byte) *message {
+	return &message{
+		op:      opBootRequest,
+		xid:     xid,
+		chaddr:  chaddr,
+		msgType: msgType,
+		options: map[uint8][]byte{},
+	}
+}
+
+func (m *message) setOption(code uint8, value []byte) {
+	if _, ok := m.options[code]; !ok {
+		m.order = append(m.order, code)
+	}
+
+	m.options[code] = value
+}
+
+func (m *message) addr(code uint8) netip.Addr {
+	v := m.options[code]
+	if len(v) != 4 {
+		return netip.Addr{}
+	}
+
+	return netip.AddrFrom4([4]byte(v))
+}
+
+func (m *message) duration(code uint8) time.Duration {
+	v := m.options[code]
+	if len(v) != 4 {
+		return 0
+	}
+
+	return time.Duration(binary.BigEndian.Uint32(v)) * time.Second
+}
+
+func putAddr(b []byte, a netip.Addr) {
+	if a.Is4() {
+		v := a.As4()
+		copy(b, v[:])
+	}
+}
+
+func getAddr(b []byte) netip.Addr {
+	a := netip.AddrFrom4([4]byte(b[:4]))
+	if a.IsUnspecified() {
+		return netip.Addr{}
+	}
+
+	return a
+}
+
+func (m *message) marshal() ([]byte, error) {
+	b := make([]byte, minLen, maxLen)
+	b[0] = m.op
+	b[1] = htypeEthernet
+	b[2] = hlenEthernet
+	b[3] = m.hops
+	binary.BigEndian.PutUint32(b[4:], m.xid)
+	binary.BigEndian.PutUint16(b[10:], m.flags)
+	putAddr(b[12:], m.ciaddr)
+	putAddr(b[16:], m.yiaddr)
+	putAddr(b[24:], m.giaddr)
+	copy(b[28:], m.chaddr[:])
+	copy(b[headerLen:], magicCookie[:])
+
+	b = append(b, optMessageType, 1, m.msgType)
+
+	appendOption := func(code uint8, v []byte) error {
+		if len(v) > 255 {
+			return fmt.Errorf("option %d is %d bytes long", code, len(v))
+		}
+
+		b = append(b, code, uint8(len(v)))
+		b = append(b, v...)
+
+		return nil
+	}
+
+	for _, code := range m.order {
+		if code == optRelayAgentInfo {
+			continue
+		}
+
+		if err := appendOption(code, m.options[code]); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := m.options[optRelayAgentInfo]; ok {
+		if err := appendOption(optRelayAgentInfo, v); err != nil {
+			return nil, err
+		}
+	}
+
+	b = append(b, optEnd)
+
+	if len(b) > maxLen {
+		return nil, fmt.Errorf("message is %d bytes long", len(b))
+	}
+
+	// Pad to the BOOTP minimum of 300 bytes some servers insist on.
+	for len(b) < 300 {
+		b = append(b, optPad)
+	}
+
+	return b, nil
+}
+
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < minLen || [4]byte(b[headerLen:minLen]) != magicCookie {
+		return nil, errMalformed
+	}
+
+	if b[1] != htypeEthernet || b[2] != hlenEthernet {
+		return nil, fmt.Errorf("%w: hardware type %d length %d", errMalformed, b[1], b[2])
+	}
+
+	m := &message{
+		op:      b[0],
+		hops:    b[3],
+		xid:     binary.BigEndian.Uint32(b[4:]),
+		flags:   binary.BigEndian.Uint16(b[10:]),
+		ciaddr:  getAddr(b[12:]),
+		yiaddr:  getAddr(b[16:]),
+		giaddr:  getAddr(b[24:]),
+		options: map[uint8][]byte{},
+	}
+	copy(m.chaddr[:], b[28:])
+
+	opts := b[minLen:]
+	for len(opts) > 0 {
+		code := opts[0]
+
+		switch code {
+		case optPad:
+			opts = opts[1:]
+			continue
+		case optEnd:
+			opts = nil
+			continue
+		}
+
+		if len(opts) < 2 || len(opts) < 2+int(opts[1]) {
+			return nil, fmt.Errorf("%w: option %d overruns the message", errMalformed, code)
+		}
+
+		m.setOption(code, opts[2:2+int(opts[1])])
+		opts = opts[2+int(opts[1]):]
+	}
+
+	v := m.options[optMessageType]
+	if len(v) != 1 {
+		return nil, fmt.Errorf("%w: no message type", errMalformed)
+	}
+
+	m.msgType = v[0]
+
+	return m, nil
+}
+
+// relayAgentInfo builds option 82 with the circuit and remote ids.
+func relayAgentInfo(circuitID, remoteID string) []byte {
+	var v []byte
+
+	if circuitID != "" {
+		v = append(v, subOptCircuitID, uint8(min(len(circuitID), 64)))
+		v = append(v, circuitID[:min(len(circuitID), 64)]...)
+	}
+
+	if remoteID != "" {
+		v = append(v, subOptRemoteID, uint8(min(len(remoteID), 64)))
+		v = append(v, remoteID[:min(len(remoteID), 64)]...)
+	}
+
+	return v
+}
+
+func clientIDOption(clientID string) []byte {
+	return append([]byte{clientIDTypeOpaque}, clientID...)
+}
+
+func addrOption(a netip.Addr) []byte {
+	v := a.As4()
+	return v[:]
+}
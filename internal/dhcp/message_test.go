@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dhcp
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := newRequest(MsgRequest, 0xdeadbeef, hardwareAddress("imsi-001010000000001-1"))
+	m.hops = 1
+	m.giaddr = netip.MustParseAddr("10.100.0.1")
+	m.setOption(optRelayAgentInfo, relayAgentInfo("internet", "001010000000001"))
+	m.setOption(optClientID, clientIDOption("imsi-001010000000001-1"))
+	m.setOption(optRequestedIP, addrOption(netip.MustParseAddr("172.20.0.9")))
+
+	b, err := m.marshal()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if len(b) < 300 {
+		t.Fatalf("message is %d bytes, want at least the BOOTP minimum", len(b))
+	}
+
+	// Option 82 goes last, right before the end option.
+	end := bytes.IndexByte(b[minLen:], optEnd)
+	info := relayAgentInfo("internet", "001010000000001")
+
+	if !bytes.HasSuffix(b[:minLen+end], info) {
+		t.Fatal("relay agent information is not the last option")
+	}
+
+	got, err := parseMessage(b)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if got.xid != m.xid || got.hops != 1 || got.giaddr != m.giaddr || got.chaddr != m.chaddr || got.msgType != MsgRequest {
+		t.Fatalf("header did not round-trip: %+v", got)
+	}
+
+	if got.addr(optRequestedIP) != netip.MustParseAddr("172.20.0.9") {
+		t.Fatalf("requested address = %s", got.addr(optRequestedIP))
+	}
+
+	if !bytes.Equal(got.options[optRelayAgentInfo], info) {
+		t.Fatalf("relay agent information = %x, want %x", got.options[optRelayAgentInfo], info)
+	}
+}
+
+func TestParseMessageRejectsMalformed(t *testing.T) {
+	m := newRequest(MsgDiscover, 1, [hlenEthernet]byte{})
+
+	b, err := m.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][]byte{
+		"short":           b[:100],
+		"bad cookie":      append(append(append([]byte{}, b[:headerLen]...), 1, 2, 3, 4), b[minLen:]...),
+		"overrun option":  append(append([]byte{}, b[:minLen]...), optServerID, 4, 10),
+		"no message type": append(append([]byte{}, b[:minLen]...), optEnd),
+	}
+
+	for name, raw := range cases {
+		if _, err := parseMessage(raw); !errors.Is(err, errMalformed) {
+			t.Errorf("%s: expected errMalformed, got %v", name, err)
+		}
+	}
+}
+
+func TestLeaseTimers(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	ack := &message{options: map[uint8][]byte{optLeaseTime: {0, 0, 0x0e, 0x10}}}
+
+	l := leaseFrom(ack, netip.Addr{}, now)
+	if !l.ExpiresAt.Equal(now.Add(time.Hour)) || !l.RenewAt.Equal(now.Add(30*time.Minute)) {
+		t.Fatalf("unexpected timers: renew %s expire %s", l.RenewAt, l.ExpiresAt)
+	}
+
+	infinite := leaseFrom(&message{options: map[uint8][]byte{}}, netip.Addr{}, now)
+	if !infinite.RenewAt.IsZero() || !infinite.ExpiresAt.IsZero() {
+		t.Fatalf("expected no timers for an infinite lease, got %+v", infinite)
+	}
+}
+
+func TestHardwareAddressIsLocalUnicast(t *testing.T) {
+	a := hardwareAddress("imsi-001010000000001-1")
+	if a[0]&0x02 == 0 || a[0]&0x01 != 0 {
+		t.Fatalf("%x is not a locally administered unicast address", a)
+	}
+
+	if a != hardwareAddress("imsi-001010000000001-1") {
+		t.Fatal("hardware address is not stable")
+	}
+}
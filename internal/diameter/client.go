@@ -0,0 +1,459 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package diameter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPort is the IANA port (RFC 6733 §2.1).
+	DefaultPort = 3868
+
+	defaultTimeout = 5 * time.Second
+	// watchdogInterval is Tw, how long a quiet connection waits before
+	// probing the peer with a DWR (RFC 3539 §3.4.1).
+	watchdogInterval = 30 * time.Second
+)
+
+// ErrClosed is returned by calls on a closed Client.
+var ErrClosed = errors.New("diameter client closed")
+
+// ErrConnectionLost is returned for requests whose connection failed before
+// their answer arrived. The request may or may not have been processed.
+var ErrConnectionLost = errors.New("diameter connection lost")
+
+// Config sets up a Client.
+type Config struct {
+	// Server is the peer's host:port.
+	Server      string
+	OriginHost  string
+	OriginRealm string
+	// Application is the Auth-Application-Id advertised in the capabilities
+	// exchange.
+	Application uint32
+	ProductName string
+	// Timeout bounds connecting, the capabilities exchange, and each
+	// watchdog.
+	Timeout time.Duration
+}
+
+// Client holds one connection to a Diameter peer, set up on first use and
+// again after it fails. It answers the peer's watchdogs and probes a quiet
+// connection with its own. It is safe for concurrent use.
+type Client struct {
+	cfg     Config
+	stateID uint32
+
+	mu       sync.Mutex
+	conn     *peerConn
+	closed   bool
+	hopByHop uint32
+	endToEnd uint32
+}
+
+// peerConn is one transport connection and the requests awaiting an answer
+// on it.
+type peerConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	pending  map[uint32]chan *Message
+	lastRecv time.Time
+	err      error
+
+	done chan struct{}
+}
+
+// NewClient returns a client for cfg.Server. It does not connect.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("server is required")
+	}
+
+	if cfg.OriginHost == "" || cfg.OriginRealm == "" {
+		return nil, errors.New("origin host and realm are required")
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	var seed [8]byte
+
+	_, _ = rand.Read(seed[:])
+
+	now := uint32(time.Now().Unix())
+
+	return &Client{
+		cfg:      cfg,
+		stateID:  now,
+		hopByHop: binary.BigEndian.Uint32(seed[:4]),
+		// The high 12 bits come from the clock, the rest are random (RFC
+		// 6733 §3).
+		endToEnd: now<<20 | binary.BigEndian.Uint32(seed[4:])&0xfffff,
+	}, nil
+}
+
+// Exchange sends req and waits for its answer. It sets the identifiers and
+// the R bit; the caller sets everything else, Origin-Host and Origin-Realm
+// included (see Origin).
+func (c *Client) Exchange(ctx context.Context, req *Message) (*Message, error) {
+	pc, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.hopByHop++
+	c.endToEnd++
+	req.HopByHop = c.hopByHop
+	req.EndToEnd = c.endToEnd
+	c.mu.Unlock()
+
+	req.Flags |= FlagRequest
+
+	ans, err := pc.exchange(ctx, req)
+	if errors.Is(err, ErrConnectionLost) {
+		c.drop(pc)
+	}
+
+	return ans, err
+}
+
+// Origin returns the Origin-Host and Origin-Realm AVPs of this client.
+func (c *Client) Origin() []AVP {
+	return []AVP{
+		StringAVP(AVPOriginHost, 0, c.cfg.OriginHost),
+		StringAVP(AVPOriginRealm, 0, c.cfg.OriginRealm),
+	}
+}
+
+// Close sends a DPR on an open connection and closes it.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	pc := c.conn
+	c.conn = nil
+	c.closed = true
+	c.mu.Unlock()
+
+	if pc == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	dpr := &Message{Flags: FlagRequest, Command: CommandDisconnectPeer}
+	dpr.AVPs = append(dpr.AVPs, c.Origin()...)
+	dpr.Add(Uint32AVP(AVPDisconnectCause, 0, disconnectRebooting))
+
+	c.mu.Lock()
+	c.hopByHop++
+	c.endToEnd++
+	dpr.HopByHop, dpr.EndToEnd = c.hopByHop, c.endToEnd
+	c.mu.Unlock()
+
+	_, _ = pc.exchange(ctx, dpr)
+
+	return pc.close(nil)
+}
+
+// connect returns the open connection, or sets one up.
+func (c *Client) connect(ctx context.Context) (*peerConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	if c.conn != nil {
+		return c.conn, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", c.cfg.Server)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", c.cfg.Server, err)
+	}
+
+	if err := c.capabilitiesExchange(ctx, conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("capabilities exchange with %s: %w", c.cfg.Server, err)
+	}
+
+	pc := &peerConn{
+		conn:     conn,
+		pending:  make(map[uint32]chan *Message),
+		lastRecv: time.Now(),
+		done:     make(chan struct{}),
+	}
+
+	go pc.read(c.Origin())
+	go c.watchdog(pc)
+
+	c.conn = pc
+
+	return pc, nil
+}
+
+// capabilitiesExchange sends the CER on a new connection and checks the CEA
+// (RFC 6733 §5.3), before anything else is read from it.
+func (c *Client) capabilitiesExchange(ctx context.Context, conn net.Conn) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+
+		defer func() { _ = conn.SetDeadline(time.Time{}) }()
+	}
+
+	cer := &Message{Flags: FlagRequest, Command: CommandCapabilitiesExchange}
+	cer.AVPs = append(cer.AVPs, c.Origin()...)
+
+	if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok {
+		cer.Add(AddressAVP(AVPHostIPAddress, 0, addr.AddrPort().Addr()))
+	}
+
+	cer.Add(Uint32AVP(AVPVendorID, 0, 0))
+	cer.Add(StringAVP(AVPProductName, 0, c.cfg.ProductName))
+	cer.Add(Uint32AVP(AVPOriginStateID, 0, c.stateID))
+	cer.Add(Uint32AVP(AVPSupportedVendorID, 0, VendorID3GPP))
+	cer.Add(Uint32AVP(AVPAuthApplicationID, 0, c.cfg.Application))
+
+	// connect holds c.mu.
+	c.hopByHop++
+	c.endToEnd++
+	cer.HopByHop, cer.EndToEnd = c.hopByHop, c.endToEnd
+
+	if _, err := conn.Write(cer.Encode()); err != nil {
+		return err
+	}
+
+	cea, err := ReadMessage(conn)
+	if err != nil {
+		return err
+	}
+
+	if cea.Command != CommandCapabilitiesExchange || cea.IsRequest() {
+		return fmt.Errorf("expected a CEA, got command %d", cea.Command)
+	}
+
+	if rc, _ := cea.ResultCode(); rc != ResultSuccess {
+		return &ResultError{Code: rc, Message: cea.String(AVPErrorMessage)}
+	}
+
+	return nil
+}
+
+// drop forgets pc, so the next exchange sets up a new connection.
+func (c *Client) drop(pc *peerConn) {
+	c.mu.Lock()
+	if c.conn == pc {
+		c.conn = nil
+	}
+	c.mu.Unlock()
+}
+
+// watchdog probes pc with a DWR when it has been quiet for Tw and closes it
+// when the probe goes unanswered (RFC 3539 §3.4).
+func (c *Client) watchdog(pc *peerConn) {
+	ticker := time.NewTicker(watchdogInterval / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pc.done:
+			c.drop(pc)
+			return
+		case <-ticker.C:
+		}
+
+		pc.mu.Lock()
+		quiet := time.Since(pc.lastRecv) >= watchdogInterval
+		pc.mu.Unlock()
+
+		if !quiet {
+			continue
+		}
+
+		dwr := &Message{Flags: FlagRequest, Command: CommandDeviceWatchdog}
+		dwr.AVPs = append(dwr.AVPs, c.Origin()...)
+		dwr.Add(Uint32AVP(AVPOriginStateID, 0, c.stateID))
+
+		c.mu.Lock()
+		c.hopByHop++
+		c.endToEnd++
+		dwr.HopByHop, dwr.EndToEnd = c.hopByHop, c.endToEnd
+		c.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+		_, err := pc.exchange(ctx, dwr)
+
+		cancel()
+
+		if err != nil {
+			_ = pc.close(fmt.Errorf("%w: watchdog unanswered", ErrConnectionLost))
+		}
+	}
+}
+
+func (pc *peerConn) exchange(ctx context.Context, req *Message) (*Message, error) {
+	ch := make(chan *Message, 1)
+
+	pc.mu.Lock()
+	if pc.err != nil {
+		err := pc.err
+		pc.mu.Unlock()
+
+		return nil, err
+	}
+
+	pc.pending[req.HopByHop] = ch
+	pc.mu.Unlock()
+
+	defer func() {
+		pc.mu.Lock()
+		delete(pc.pending, req.HopByHop)
+		pc.mu.Unlock()
+	}()
+
+	if err := pc.write(req); err != nil {
+		_ = pc.close(fmt.Errorf("%w: %v", ErrConnectionLost, err))
+		return nil, fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	}
+
+	select {
+	case ans := <-ch:
+		return ans, nil
+	case <-pc.done:
+		pc.mu.Lock()
+		err := pc.err
+		pc.mu.Unlock()
+
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (pc *peerConn) write(m *Message) error {
+	pc.writeMu.Lock()
+	defer pc.writeMu.Unlock()
+
+	_, err := pc.conn.Write(m.Encode())
+
+	return err
+}
+
+// read delivers answers to their exchanges and answers the peer's own
+// requests until the connection fails.
+func (pc *peerConn) read(origin []AVP) {
+	for {
+		m, err := ReadMessage(pc.conn)
+		if err != nil {
+			_ = pc.close(fmt.Errorf("%w: %v", ErrConnectionLost, err))
+			return
+		}
+
+		pc.mu.Lock()
+		pc.lastRecv = time.Now()
+		ch := pc.pending[m.HopByHop]
+		pc.mu.Unlock()
+
+		if !m.IsRequest() {
+			if ch != nil {
+				ch <- m
+			}
+
+			continue
+		}
+
+		ans := m.Answer()
+		ans.AVPs = append(ans.AVPs, origin...)
+
+		switch m.Command {
+		case CommandDeviceWatchdog, CommandDisconnectPeer:
+			ans.Add(Uint32AVP(AVPResultCode, 0, ResultSuccess))
+		default:
+			ans.Flags |= FlagError
+			ans.Add(Uint32AVP(AVPResultCode, 0, ResultCommandUnsupported))
+		}
+
+		if err := pc.write(ans); err != nil || m.Command == CommandDisconnectPeer {
+			_ = pc.close(fmt.Errorf("%w: peer disconnected", ErrConnectionLost))
+			return
+		}
+	}
+}
+
+// close ends the connection once, failing the exchanges still waiting
+// with err.
+func (pc *peerConn) close(err error) error {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if pc.err != nil {
+		return nil
+	}
+
+	if err == nil {
+		err = fmt.Errorf("%w: closed", ErrConnectionLost)
+	}
+
+	pc.err = err
+	close(pc.done)
+
+	return pc.conn.Close()
+}
+
+// ReadMessage reads one whole message from a stream.
+func ReadMessage(r io.Reader) (*Message, error) {
+	hdr := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+
+	n, err := messageLen(hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, n)
+	copy(b, hdr)
+
+	if _, err := io.ReadFull(r, b[headerLen:]); err != nil {
+		return nil, err
+	}
+
+	return Parse(b)
+}
+
+// ResultError is an answer whose result is not a success.
+type ResultError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("diameter result %d: %s", e.Code, e.Message)
+	}
+
+	return fmt.Sprintf("diameter result %d", e.Code)
+}
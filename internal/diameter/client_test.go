@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package diameter_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/diameter"
+	"github.com/ellanetworks/core/internal/diameter/diametertest"
+)
+
+func newServer(t *testing.T, handler diametertest.Handler) *diametertest.Server {
+	t.Helper()
+
+	srv, err := diametertest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), "ocs.example.org", "example.org", handler)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	t.Cleanup(func() { _ = srv.Close() })
+
+	return srv
+}
+
+func newClient(t *testing.T, server string) *diameter.Client {
+	t.Helper()
+
+	c, err := diameter.NewClient(diameter.Config{
+		Server:      server,
+		OriginHost:  "ella-core-1.example.org",
+		OriginRealm: "example.org",
+		Application: diameter.AppCreditControl,
+		ProductName: "Ella Core",
+		Timeout:     time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+func ccr(c *diameter.Client, sessionID string) *diameter.Message {
+	req := &diameter.Message{Command: diameter.CommandCreditControl, Application: diameter.AppCreditControl}
+	req.Add(diameter.StringAVP(diameter.AVPSessionID, 0, sessionID))
+	req.AVPs = append(req.AVPs, c.Origin()...)
+
+	return req
+}
+
+func TestExchange(t *testing.T) {
+	srv := newServer(t, func(req *diameter.Message) *diameter.Message {
+		ans := req.Answer()
+		ans.Add(diameter.StringAVP(diameter.AVPSessionID, 0, req.String(diameter.AVPSessionID)))
+		ans.Add(diameter.Uint32AVP(diameter.AVPResultCode, 0, diameter.ResultSuccess))
+
+		return ans
+	})
+
+	c := newClient(t, srv.Addr().String())
+
+	for _, id := range []string{"ella;1;1", "ella;1;2"} {
+		ans, err := c.Exchange(context.Background(), ccr(c, id))
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+
+		if ans.IsRequest() || ans.String(diameter.AVPSessionID) != id {
+			t.Fatalf("answer = %+v", ans)
+		}
+
+		if rc, _ := ans.ResultCode(); rc != diameter.ResultSuccess {
+			t.Fatalf("Result-Code = %d", rc)
+		}
+
+		if ans.String(diameter.AVPOriginHost) != "ocs.example.org" {
+			t.Fatalf("Origin-Host = %q", ans.String(diameter.AVPOriginHost))
+		}
+	}
+}
+
+func TestExchangeReconnects(t *testing.T) {
+	var seen atomic.Int32
+
+	srv := newServer(t, func(req *diameter.Message) *diameter.Message {
+		ans := req.Answer()
+		ans.Add(diameter.Uint32AVP(diameter.AVPResultCode, 0, diameter.ResultSuccess))
+
+		return ans
+	})
+
+	c := newClient(t, srv.Addr().String())
+
+	if _, err := c.Exchange(context.Background(), ccr(c, "ella;1;1")); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	// A new server on the same port: the old connection is gone, and the
+	// client must notice and set up another.
+	addr := srv.Addr()
+	_ = srv.Close()
+
+	srv2, err := diametertest.NewServer(addr, "ocs.example.org", "example.org", func(req *diameter.Message) *diameter.Message {
+		seen.Add(1)
+
+		ans := req.Answer()
+		ans.Add(diameter.Uint32AVP(diameter.AVPResultCode, 0, diameter.ResultSuccess))
+
+		return ans
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	defer func() { _ = srv2.Close() }()
+
+	var lastErr error
+
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, lastErr = c.Exchange(ctx, ccr(c, "ella;1;2"))
+
+		cancel()
+
+		if lastErr == nil {
+			break
+		}
+
+		if !errors.Is(lastErr, diameter.ErrConnectionLost) {
+			t.Fatalf("Exchange on a dead connection = %v, want ErrConnectionLost", lastErr)
+		}
+	}
+
+	if lastErr != nil || seen.Load() != 1 {
+		t.Fatalf("no exchange over a new connection: %v, %d seen", lastErr, seen.Load())
+	}
+}
+
+func TestExchangeHonoursContext(t *testing.T) {
+	srv := newServer(t, func(*diameter.Message) *diameter.Message { return nil })
+
+	c := newClient(t, srv.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.Exchange(ctx, ccr(c, "ella;1;1")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end the exchange, got %v", err)
+	}
+}
+
+func TestExchangeAfterClose(t *testing.T) {
+	c := newClient(t, "127.0.0.1:9")
+	_ = c.Close()
+
+	if _, err := c.Exchange(context.Background(), ccr(c, "ella;1;1")); !errors.Is(err, diameter.ErrClosed) {
+		t.Fatalf("got %v, want ErrClosed", err)
+	}
+}
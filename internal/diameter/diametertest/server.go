@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package diametertest provides a minimal Diameter peer that stands in for
+// an online charging system in tests and labs.
+package diametertest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/ellanetworks/core/internal/diameter"
+)
+
+// Handler answers one application request, such as a CCR. The server adds
+// the identifiers, Origin-Host and Origin-Realm. A nil answer drops the
+// request.
+type Handler func(req *diameter.Message) *diameter.Message
+
+// Server answers capabilities exchanges and watchdogs itself and hands other
+// requests to a Handler.
+type Server struct {
+	ln      net.Listener
+	origin  []diameter.AVP
+	handler Handler
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// NewServer listens on listen as host in realm.
+func NewServer(listen netip.AddrPort, host, realm string, handler Handler) (*Server, error) {
+	ln, err := net.Listen("tcp", listen.String())
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", listen, err)
+	}
+
+	s := &Server{
+		ln:      ln,
+		origin:  []diameter.AVP{diameter.StringAVP(diameter.AVPOriginHost, 0, host), diameter.StringAVP(diameter.AVPOriginRealm, 0, realm)},
+		handler: handler,
+		conns:   make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Go(s.serve)
+
+	return s, nil
+}
+
+// Addr is where the server listens.
+func (s *Server) Addr() netip.AddrPort {
+	return s.ln.Addr().(*net.TCPAddr).AddrPort()
+}
+
+// Close stops the server and drops its connections.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Go(func() {
+			s.serveConn(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		})
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	for {
+		req, err := diameter.ReadMessage(conn)
+		if err != nil || !req.IsRequest() {
+			return
+		}
+
+		var ans *diameter.Message
+
+		switch req.Command {
+		case diameter.CommandCapabilitiesExchange, diameter.CommandDeviceWatchdog, diameter.CommandDisconnectPeer:
+			ans = req.Answer()
+			ans.Add(diameter.Uint32AVP(diameter.AVPResultCode, 0, diameter.ResultSuccess))
+		default:
+			ans = s.handler(req)
+			if ans == nil {
+				continue
+			}
+
+			ans.Flags &^= diameter.FlagRequest
+			ans.Command = req.Command
+			ans.Application = req.Application
+			ans.HopByHop = req.HopByHop
+			ans.EndToEnd = req.EndToEnd
+		}
+
+		ans.AVPs = append(ans.AVPs, s.origin...)
+
+		if _, err := conn.Write(ans.Encode()); err != nil || req.Command == diameter.CommandDisconnectPeer {
+			return
+		}
+	}
+}
@@ -0,0 +1,456 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package diameter is the Diameter client Ella Core uses to reach external
+// charging systems (RFC 6733, RFC 4006). It covers the message and AVP
+// formats, capabilities exchange and watchdogs over TCP, and the AVPs of
+// the Gy credit-control application (TS 32.299).
+package diameter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Version is the only protocol version (RFC 6733 §3).
+const Version = 1
+
+// Command codes (RFC 6733 §3.1, RFC 4006 §3).
+const (
+	CommandCapabilitiesExchange uint32 = 257
+	CommandCreditControl        uint32 = 272
+	CommandDeviceWatchdog       uint32 = 280
+	CommandDisconnectPeer       uint32 = 282
+)
+
+// Application identifiers (RFC 6733 §2.4, RFC 4006 §1.3).
+const (
+	AppCommon        uint32 = 0
+	AppCreditControl uint32 = 4
+)
+
+// Command flags (RFC 6733 §3).
+const (
+	FlagRequest       uint8 = 0x80
+	FlagProxiable     uint8 = 0x40
+	FlagError         uint8 = 0x20
+	FlagRetransmitted uint8 = 0x10
+)
+
+// AVP flags (RFC 6733 §4.1).
+const (
+	AVPFlagVendor    uint8 = 0x80
+	AVPFlagMandatory uint8 = 0x40
+)
+
+// VendorID3GPP is the vendor of the 3GPP AVPs (TS 29.230 §5).
+const VendorID3GPP uint32 = 10415
+
+// AVP codes of the base protocol (RFC 6733 §4.5), of credit control (RFC
+// 4006 §8) and of NAS (RFC 7155 §4.2).
+const (
+	AVPCalledStationID           uint32 = 30
+	AVPHostIPAddress             uint32 = 257
+	AVPAuthApplicationID         uint32 = 258
+	AVPSessionID                 uint32 = 263
+	AVPOriginHost                uint32 = 264
+	AVPSupportedVendorID         uint32 = 265
+	AVPVendorID                  uint32 = 266
+	AVPResultCode                uint32 = 268
+	AVPProductName               uint32 = 269
+	AVPDisconnectCause           uint32 = 273
+	AVPOriginStateID             uint32 = 278
+	AVPErrorMessage              uint32 = 281
+	AVPDestinationRealm          uint32 = 283
+	AVPTerminationCause          uint32 = 295
+	AVPOriginRealm               uint32 = 296
+	AVPExperimentalResult        uint32 = 297
+	AVPExperimentalResultCode    uint32 = 298
+	AVPCCInputOctets             uint32 = 412
+	AVPCCOutputOctets            uint32 = 414
+	AVPCCRequestNumber           uint32 = 415
+	AVPCCRequestType             uint32 = 416
+	AVPCCTime                    uint32 = 420
+	AVPCCTotalOctets             uint32 = 421
+	AVPFinalUnitIndication       uint32 = 430
+	AVPGrantedServiceUnit        uint32 = 431
+	AVPRatingGroup               uint32 = 432
+	AVPRequestedServiceUnit      uint32 = 437
+	AVPSubscriptionID            uint32 = 443
+	AVPSubscriptionIDData        uint32 = 444
+	AVPUsedServiceUnit           uint32 = 446
+	AVPValidityTime              uint32 = 448
+	AVPFinalUnitAction           uint32 = 449
+	AVPSubscriptionIDType        uint32 = 450
+	AVPMultipleServicesIndicator uint32 = 455
+	AVPMultipleServicesCC        uint32 = 456
+	AVPServiceContextID          uint32 = 461
+)
+
+// AVP codes of the 3GPP vendor (TS 29.061 §16.4.7, TS 32.299 §7.2).
+const (
+	AVP3GPPRATType          uint32 = 21
+	AVPVolumeQuotaThreshold uint32 = 869
+	AVPServiceInformation   uint32 = 873
+	AVPPSInformation        uint32 = 874
+	AVPPDPAddress           uint32 = 1227
+)
+
+// Result-Code values (RFC 6733 §7.1, RFC 4006 §9).
+const (
+	ResultSuccess                    uint32 = 2001
+	ResultLimitedSuccess             uint32 = 2002
+	ResultCommandUnsupported         uint32 = 3001
+	ResultEndUserServiceDenied       uint32 = 4010
+	ResultCreditControlNotApplicable uint32 = 4011
+	ResultCreditLimitReached         uint32 = 4012
+	ResultUserUnknown                uint32 = 5030
+	ResultRatingFailed               uint32 = 5031
+)
+
+// CC-Request-Type values (RFC 4006 §8.3).
+const (
+	RequestInitial     uint32 = 1
+	RequestUpdate      uint32 = 2
+	RequestTermination uint32 = 3
+)
+
+// Final-Unit-Action values (RFC 4006 §8.35).
+const (
+	FinalUnitTerminate      uint32 = 0
+	FinalUnitRedirect       uint32 = 1
+	FinalUnitRestrictAccess uint32 = 2
+)
+
+// SubscriptionIDIMSI is the Subscription-Id-Type of an IMSI (RFC 4006
+// §8.47).
+const SubscriptionIDIMSI uint32 = 1
+
+// TerminationLogout is the Termination-Cause of a session the user ended
+// (RFC 6733 §8.15).
+const TerminationLogout uint32 = 1
+
+// disconnectRebooting is the Disconnect-Cause of a peer going away (RFC
+// 6733 §5.4.3).
+const disconnectRebooting uint32 = 0
+
+const (
+	headerLen = 20
+	// maxLen bounds what a peer may make us buffer.
+	maxLen = 1 << 20
+)
+
+var errMalformed = errors.New("malformed Diameter message")
+
+// AVP is one attribute-value pair with its raw data. Grouped AVPs carry
+// their members encoded in Data; see Group and Members.
+type AVP struct {
+	Code   uint32
+	Flags  uint8
+	Vendor uint32
+	Data   []byte
+}
+
+// Message is a Diameter message.
+type Message struct {
+	Flags       uint8
+	Command     uint32
+	Application uint32
+	HopByHop    uint32
+	EndToEnd    uint32
+	AVPs        []AVP
+}
+
+// IsRequest reports whether the R bit is set.
+func (m *Message) IsRequest() bool {
+	return m.Flags&FlagRequest != 0
+}
+
+// Add appends an AVP. The M bit is set, as every AVP this package sends is
+// one the peer must understand.
+func (m *Message) Add(a AVP) {
+	m.AVPs = append(m.AVPs, a)
+}
+
+// Find returns the first AVP with code and vendor.
+func (m *Message) Find(code, vendor uint32) (AVP, bool) {
+	return find(m.AVPs, code, vendor)
+}
+
+// Uint32 returns the value of the first Unsigned32 or Enumerated AVP with
+// code, of the base vendor.
+func (m *Message) Uint32(code uint32) (uint32, bool) {
+	a, ok := m.Find(code, 0)
+	if !ok {
+		return 0, false
+	}
+
+	return a.Uint32()
+}
+
+// String returns the value of the first UTF8String or DiameterIdentity AVP
+// with code, of the base vendor.
+func (m *Message) String(code uint32) string {
+	a, ok := m.Find(code, 0)
+	if !ok {
+		return ""
+	}
+
+	return string(a.Data)
+}
+
+// ResultCode is the Result-Code of an answer, or the Experimental-Result-Code
+// when the answer carries that instead.
+func (m *Message) ResultCode() (uint32, bool) {
+	if rc, ok := m.Uint32(AVPResultCode); ok {
+		return rc, true
+	}
+
+	exp, ok := m.Find(AVPExperimentalResult, 0)
+	if !ok {
+		return 0, false
+	}
+
+	members, err := exp.Members()
+	if err != nil {
+		return 0, false
+	}
+
+	code, ok := find(members, AVPExperimentalResultCode, 0)
+	if !ok {
+		return 0, false
+	}
+
+	return code.Uint32()
+}
+
+// Answer returns an answer to m, with the identifiers copied and the R bit
+// cleared.
+func (m *Message) Answer() *Message {
+	return &Message{
+		Flags:       m.Flags &^ (FlagRequest | FlagRetransmitted | FlagError),
+		Command:     m.Command,
+		Application: m.Application,
+		HopByHop:    m.HopByHop,
+		EndToEnd:    m.EndToEnd,
+	}
+}
+
+// Encode returns the wire format of m.
+func (m *Message) Encode() []byte {
+	b := make([]byte, headerLen, 256)
+
+	for _, a := range m.AVPs {
+		b = a.append(b)
+	}
+
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(b)))
+	b[0] = Version
+	binary.BigEndian.PutUint32(b[4:8], m.Command)
+	b[4] = m.Flags
+	binary.BigEndian.PutUint32(b[8:12], m.Application)
+	binary.BigEndian.PutUint32(b[12:16], m.HopByHop)
+	binary.BigEndian.PutUint32(b[16:20], m.EndToEnd)
+
+	return b
+}
+
+// Parse decodes a whole message.
+func Parse(b []byte) (*Message, error) {
+	if len(b) < headerLen || b[0] != Version {
+		return nil, errMalformed
+	}
+
+	n := int(binary.BigEndian.Uint32(b[0:4]) & 0xffffff)
+	if n != len(b) || n%4 != 0 {
+		return nil, errMalformed
+	}
+
+	m := &Message{
+		Flags:       b[4],
+		Command:     binary.BigEndian.Uint32(b[4:8]) & 0xffffff,
+		Application: binary.BigEndian.Uint32(b[8:12]),
+		HopByHop:    binary.BigEndian.Uint32(b[12:16]),
+		EndToEnd:    binary.BigEndian.Uint32(b[16:20]),
+	}
+
+	avps, err := parseAVPs(b[headerLen:])
+	if err != nil {
+		return nil, err
+	}
+
+	m.AVPs = avps
+
+	return m, nil
+}
+
+// messageLen reads the length of the message whose header starts hdr.
+func messageLen(hdr []byte) (int, error) {
+	if hdr[0] != Version {
+		return 0, fmt.Errorf("%w: version %d", errMalformed, hdr[0])
+	}
+
+	n := int(binary.BigEndian.Uint32(hdr[0:4]) & 0xffffff)
+	if n < headerLen || n > maxLen || n%4 != 0 {
+		return 0, fmt.Errorf("%w: length %d", errMalformed, n)
+	}
+
+	return n, nil
+}
+
+func (a AVP) append(b []byte) []byte {
+	hdr := 8
+	if a.Vendor != 0 {
+		hdr = 12
+	}
+
+	n := hdr + len(a.Data)
+	flags := a.Flags | AVPFlagMandatory
+
+	if a.Vendor != 0 {
+		flags |= AVPFlagVendor
+	} else {
+		flags &^= AVPFlagVendor
+	}
+
+	b = binary.BigEndian.AppendUint32(b, a.Code)
+	b = binary.BigEndian.AppendUint32(b, uint32(n))
+	b[len(b)-4] = flags
+
+	if a.Vendor != 0 {
+		b = binary.BigEndian.AppendUint32(b, a.Vendor)
+	}
+
+	b = append(b, a.Data...)
+
+	for n%4 != 0 {
+		b = append(b, 0)
+		n++
+	}
+
+	return b
+}
+
+func parseAVPs(b []byte) ([]AVP, error) {
+	var avps []AVP
+
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, errMalformed
+		}
+
+		a := AVP{
+			Code:  binary.BigEndian.Uint32(b[0:4]),
+			Flags: b[4],
+		}
+
+		n := int(binary.BigEndian.Uint32(b[4:8]) & 0xffffff)
+		hdr := 8
+
+		if a.Flags&AVPFlagVendor != 0 {
+			if len(b) < 12 {
+				return nil, errMalformed
+			}
+
+			a.Vendor = binary.BigEndian.Uint32(b[8:12])
+			hdr = 12
+		}
+
+		if n < hdr || n > len(b) {
+			return nil, errMalformed
+		}
+
+		a.Data = b[hdr:n]
+		avps = append(avps, a)
+
+		padded := (n + 3) &^ 3
+		if padded > len(b) {
+			padded = len(b)
+		}
+
+		b = b[padded:]
+	}
+
+	return avps, nil
+}
+
+func find(avps []AVP, code, vendor uint32) (AVP, bool) {
+	for _, a := range avps {
+		if a.Code == code && a.Vendor == vendor {
+			return a, true
+		}
+	}
+
+	return AVP{}, false
+}
+
+// Uint32 decodes an Unsigned32 or Enumerated AVP.
+func (a AVP) Uint32() (uint32, bool) {
+	if len(a.Data) != 4 {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint32(a.Data), true
+}
+
+// Uint64 decodes an Unsigned64 AVP.
+func (a AVP) Uint64() (uint64, bool) {
+	if len(a.Data) != 8 {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint64(a.Data), true
+}
+
+// Members decodes the AVPs a Grouped AVP holds.
+func (a AVP) Members() ([]AVP, error) {
+	return parseAVPs(a.Data)
+}
+
+// Uint32AVP returns an Unsigned32 or Enumerated AVP.
+func Uint32AVP(code, vendor, v uint32) AVP {
+	return AVP{Code: code, Vendor: vendor, Data: binary.BigEndian.AppendUint32(nil, v)}
+}
+
+// Uint64AVP returns an Unsigned64 AVP.
+func Uint64AVP(code, vendor uint32, v uint64) AVP {
+	return AVP{Code: code, Vendor: vendor, Data: binary.BigEndian.AppendUint64(nil, v)}
+}
+
+// StringAVP returns a UTF8String, OctetString or DiameterIdentity AVP.
+func StringAVP(code, vendor uint32, s string) AVP {
+	return AVP{Code: code, Vendor: vendor, Data: []byte(s)}
+}
+
+// AddressAVP returns an Address AVP (RFC 6733 §4.3.1).
+func AddressAVP(code, vendor uint32, addr netip.Addr) AVP {
+	family := uint16(1)
+	if addr.Is6() && !addr.Is4In6() {
+		family = 2
+	}
+
+	data := binary.BigEndian.AppendUint16(nil, family)
+
+	return AVP{Code: code, Vendor: vendor, Data: append(data, addr.Unmap().AsSlice()...)}
+}
+
+// Group returns a Grouped AVP holding members.
+func Group(code, vendor uint32, members ...AVP) AVP {
+	var data []byte
+	for _, m := range members {
+		data = m.append(data)
+	}
+
+	return AVP{Code: code, Vendor: vendor, Data: data}
+}
+
+// ntpEpoch is the origin of the Time format (RFC 6733 §4.3.1).
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TimeAVP returns a Time AVP.
+func TimeAVP(code, vendor uint32, t time.Time) AVP {
+	return Uint32AVP(code, vendor, uint32(t.Sub(ntpEpoch)/time.Second))
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package diameter
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+)
+
+func TestAVPEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		avp  AVP
+		want []byte
+	}{
+		{
+			name: "unsigned32",
+			avp:  Uint32AVP(AVPResultCode, 0, ResultSuccess),
+			want: []byte{0, 0, 0x01, 0x0c, 0x40, 0, 0, 12, 0, 0, 0x07, 0xd1},
+		},
+		{
+			name: "vendor string with padding",
+			avp:  StringAVP(7, VendorID3GPP, "abc"),
+			want: []byte{0, 0, 0, 7, 0xc0, 0, 0, 15, 0, 0, 0x28, 0xaf, 'a', 'b', 'c', 0},
+		},
+		{
+			name: "IPv4 address",
+			avp:  AddressAVP(AVPHostIPAddress, 0, netip.MustParseAddr("192.0.2.1")),
+			want: []byte{0, 0, 0x01, 0x01, 0x40, 0, 0, 14, 0, 1, 192, 0, 2, 1, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.avp.append(nil); !bytes.Equal(got, tt.want) {
+				t.Fatalf("got % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := &Message{
+		Flags:       FlagRequest | FlagProxiable,
+		Command:     CommandCreditControl,
+		Application: AppCreditControl,
+		HopByHop:    0x11223344,
+		EndToEnd:    0x55667788,
+	}
+
+	m.Add(StringAVP(AVPSessionID, 0, "ella;1;2"))
+	m.Add(Group(AVPMultipleServicesCC, 0,
+		Uint32AVP(AVPRatingGroup, 0, 10),
+		Group(AVPUsedServiceUnit, 0, Uint64AVP(AVPCCTotalOctets, 0, 5<<32)),
+	))
+
+	b := m.Encode()
+	if len(b)%4 != 0 || int(b[3]) != len(b)&0xff {
+		t.Fatalf("length field does not match %d octets", len(b))
+	}
+
+	got, err := Parse(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if !got.IsRequest() || got.Command != CommandCreditControl || got.Application != AppCreditControl ||
+		got.HopByHop != m.HopByHop || got.EndToEnd != m.EndToEnd {
+		t.Fatalf("header = %+v", got)
+	}
+
+	if got.String(AVPSessionID) != "ella;1;2" {
+		t.Fatalf("Session-Id = %q", got.String(AVPSessionID))
+	}
+
+	mscc, ok := got.Find(AVPMultipleServicesCC, 0)
+	if !ok {
+		t.Fatal("MSCC missing")
+	}
+
+	members, err := mscc.Members()
+	if err != nil || len(members) != 2 {
+		t.Fatalf("MSCC members = %v, %v", members, err)
+	}
+
+	if rg, _ := members[0].Uint32(); rg != 10 {
+		t.Fatalf("Rating-Group = %d", rg)
+	}
+
+	used, err := members[1].Members()
+	if err != nil || len(used) != 1 {
+		t.Fatalf("USU members = %v, %v", used, err)
+	}
+
+	if octets, _ := used[0].Uint64(); octets != 5<<32 {
+		t.Fatalf("CC-Total-Octets = %d", octets)
+	}
+}
+
+func TestResultCodeFromExperimentalResult(t *testing.T) {
+	m := &Message{Command: CommandCreditControl}
+	m.Add(Group(AVPExperimentalResult, 0,
+		Uint32AVP(AVPVendorID, 0, VendorID3GPP),
+		Uint32AVP(AVPExperimentalResultCode, 0, ResultCreditLimitReached),
+	))
+
+	got, err := Parse(m.Encode())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if rc, ok := got.ResultCode(); !ok || rc != ResultCreditLimitReached {
+		t.Fatalf("ResultCode = %d, %v", rc, ok)
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	good := (&Message{Command: CommandDeviceWatchdog}).Encode()
+
+	bad := map[string][]byte{
+		"short":          good[:10],
+		"wrong version":  append([]byte{2}, good[1:]...),
+		"length too big": append(append([]byte{}, good...), 0, 0, 0, 0),
+		"truncated AVP":  append(append([]byte{1, 0, 0, 28}, good[4:20]...), 0, 0, 1, 0x0c, 0x40, 0, 0, 12),
+	}
+
+	for name, b := range bad {
+		if _, err := Parse(b); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// cacheSize bounds the answers one forwarder keeps.
+	cacheSize = 4096
+	// cacheMaxTTL caps how long an answer is served from cache, whatever
+	// its records say.
+	cacheMaxTTL = time.Hour
+	// cacheNegativeMaxTTL caps NXDOMAIN and empty answers (RFC 2308).
+	cacheNegativeMaxTTL = 5 * time.Minute
+)
+
+type cacheKey struct {
+	name  string
+	typ   dnsmessage.Type
+	class dnsmessage.Class
+}
+
+type cacheEntry struct {
+	msg     dnsmessage.Message
+	stored  time.Time
+	expires time.Time
+}
+
+// cache holds upstream answers until the shortest TTL among their records
+// runs out.
+type cache struct {
+	mu      sync.Mutex
+	entries map[cacheKey]*cacheEntry
+	size    int
+}
+
+func newCache(size int) *cache {
+	return &cache{entries: make(map[cacheKey]*cacheEntry), size: size}
+}
+
+// put stores msg, an upstream answer to key, if it may be cached.
+func (c *cache) put(key cacheKey, msg *dnsmessage.Message, now time.Time) {
+	ttl, ok := cacheTTL(msg)
+	if !ok || ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.size {
+		c.evictLocked(now)
+	}
+
+	c.entries[key] = &cacheEntry{msg: *msg, stored: now, expires: now.Add(ttl)}
+}
+
+// get returns the cached answer to key with its TTLs aged to now.
+func (c *cache) get(key cacheKey, now time.Time) (*dnsmessage.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !now.Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	age := uint32(now.Sub(e.stored) / time.Second)
+
+	msg := e.msg
+	msg.Answers = agedResources(e.msg.Answers, age)
+	msg.Authorities = agedResources(e.msg.Authorities, age)
+	msg.Additionals = agedResources(e.msg.Additionals, age)
+
+	return &msg, true
+}
+
+// evictLocked makes room for one entry: the expired ones go first, and
+// failing that an arbitrary one.
+func (c *cache) evictLocked(now time.Time) {
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if len(c.entries) < c.size {
+		return
+	}
+
+	for k := range c.entries {
+		delete(c.entries, k)
+		return
+	}
+}
+
+func agedResources(rrs []dnsmessage.Resource, age uint32) []dnsmessage.Resource {
+	if len(rrs) == 0 {
+		return nil
+	}
+
+	out := make([]dnsmessage.Resource, len(rrs))
+	copy(out, rrs)
+
+	for i := range out {
+		// The OPT pseudo-record's TTL carries EDNS flags, not a lifetime.
+		if out[i].Header.Type == dnsmessage.TypeOPT {
+			continue
+		}
+
+		if out[i].Header.TTL > age {
+			out[i].Header.TTL -= age
+		} else {
+			out[i].Header.TTL = 0
+		}
+	}
+
+	return out
+}
+
+// cacheTTL is how long msg may be served from cache: the shortest TTL of
+// its answers, or for a negative answer the SOA's negative TTL.
+func cacheTTL(msg *dnsmessage.Message) (time.Duration, bool) {
+	if msg.Truncated {
+		return 0, false
+	}
+
+	switch {
+	case msg.RCode == dnsmessage.RCodeSuccess && len(msg.Answers) > 0:
+		ttl := msg.Answers[0].Header.TTL
+		for _, rr := range msg.Answers[1:] {
+			ttl = min(ttl, rr.Header.TTL)
+		}
+
+		return min(time.Duration(ttl)*time.Second, cacheMaxTTL), true
+	case msg.RCode == dnsmessage.RCodeSuccess || msg.RCode == dnsmessage.RCodeNameError:
+		for _, rr := range msg.Authorities {
+			soa, ok := rr.Body.(*dnsmessage.SOAResource)
+			if !ok {
+				continue
+			}
+
+			ttl := min(rr.Header.TTL, soa.MinTTL)
+
+			return min(time.Duration(ttl)*time.Second, cacheNegativeMaxTTL), true
+		}
+
+		// Without an SOA a negative answer is not cached (RFC 2308 §5).
+		return 0, false
+	default:
+		return 0, false
+	}
+}
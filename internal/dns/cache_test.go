@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dns
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func answerA(name string, ttl uint32) *dnsmessage.Message {
+	n := dnsmessage.MustNewName(name)
+
+	return &dnsmessage.Message{
+		Header:    dnsmessage.Header{Response: true},
+		Questions: []dnsmessage.Question{{Name: n, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+		Answers: []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: n, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+			Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+		}},
+	}
+}
+
+func TestCache_AgesTTLAndExpires(t *testing.T) {
+	c := newCache(8)
+	key := cacheKey{name: "example.com.", typ: dnsmessage.TypeA, class: dnsmessage.ClassINET}
+	now := time.Unix(1000, 0)
+
+	c.put(key, answerA("example.com.", 30), now)
+
+	got, ok := c.get(key, now.Add(10*time.Second))
+	if !ok {
+		t.Fatal("expected a cached answer")
+	}
+
+	if ttl := got.Answers[0].Header.TTL; ttl != 20 {
+		t.Fatalf("expected TTL 20 after 10s, got %d", ttl)
+	}
+
+	if _, ok := c.get(key, now.Add(30*time.Second)); ok {
+		t.Fatal("expected the answer to expire with its TTL")
+	}
+}
+
+func TestCache_NegativeAnswers(t *testing.T) {
+	n := dnsmessage.MustNewName("missing.example.com.")
+	zone := dnsmessage.MustNewName("example.com.")
+
+	nx := &dnsmessage.Message{
+		Header:    dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeNameError},
+		Questions: []dnsmessage.Question{{Name: n, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+
+	if _, ok := cacheTTL(nx); ok {
+		t.Fatal("expected NXDOMAIN without an SOA not to be cached")
+	}
+
+	nx.Authorities = []dnsmessage.Resource{{
+		Header: dnsmessage.ResourceHeader{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600},
+		Body: &dnsmessage.SOAResource{
+			NS: zone, MBox: zone, Serial: 1, Refresh: 1, Retry: 1, Expire: 1, MinTTL: 7200,
+		},
+	}}
+
+	ttl, ok := cacheTTL(nx)
+	if !ok || ttl != cacheNegativeMaxTTL {
+		t.Fatalf("expected negative TTL capped at %s, got %s (%v)", cacheNegativeMaxTTL, ttl, ok)
+	}
+
+	fail := &dnsmessage.Message{Header: dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeServerFailure}}
+	if _, ok := cacheTTL(fail); ok {
+		t.Fatal("expected SERVFAIL not to be cached")
+	}
+}
+
+func TestCache_EvictsWhenFull(t *testing.T) {
+	c := newCache(2)
+	now := time.Unix(1000, 0)
+
+	for _, name := range []string{"a.example.", "b.example.", "c.example."} {
+		c.put(cacheKey{name: name, typ: dnsmessage.TypeA, class: dnsmessage.ClassINET}, answerA(name, 60), now)
+	}
+
+	if len(c.entries) != 2 {
+		t.Fatalf("expected the cache to hold 2 entries, got %d", len(c.entries))
+	}
+}
@@ -0,0 +1,653 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// upstreamTimeout bounds one exchange with one upstream server.
+	upstreamTimeout = 2 * time.Second
+	// upstreamHoldDown is how long a server that failed is tried only
+	// after the others.
+	upstreamHoldDown = 30 * time.Second
+	// localTTL is the TTL of answers from local records, kept short so a
+	// UE whose static address moves is found again soon.
+	localTTL = 60
+	// tcpIdleTimeout closes TCP connections that send no query.
+	tcpIdleTimeout = 10 * time.Second
+	// minUDPSize is what a client without EDNS accepts (RFC 1035).
+	minUDPSize = 512
+	// maxInflight bounds the queries a forwarder works on at once, per
+	// transport. Queries beyond it are dropped and the client retries.
+	maxInflight = 256
+)
+
+var errNoUpstream = errors.New("no upstream server answered")
+
+// zoneConfig is the part of a forwarder's settings a query reads.
+type zoneConfig struct {
+	dataNetwork string
+	pools       []netip.Prefix
+	upstreams   []netip.AddrPort
+	zones       []string
+	records     map[string][]netip.Addr
+	logQueries  bool
+}
+
+func newZoneConfig(r Resolver) *zoneConfig {
+	c := &zoneConfig{
+		dataNetwork: r.DataNetwork,
+		pools:       slices.Clone(r.Pools),
+		upstreams:   slices.Clone(r.Upstreams),
+		records:     make(map[string][]netip.Addr, len(r.Records)),
+		logQueries:  r.LogQueries,
+	}
+
+	for _, z := range r.LocalZones {
+		c.zones = append(c.zones, canonicalName(z))
+	}
+
+	for _, rec := range r.Records {
+		name := canonicalName(rec.Name)
+		c.records[name] = append(c.records[name], rec.Addresses...)
+	}
+
+	return c
+}
+
+// inLocalZone reports whether name, in canonical form, is one of the local
+// zones or below one.
+func (c *zoneConfig) inLocalZone(name string) bool {
+	for _, z := range c.zones {
+		if name == z || strings.HasSuffix(name, "."+z) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// servesClient reports whether client holds an address from one of the
+// data network's UE pools.
+func (c *zoneConfig) servesClient(client netip.Addr) bool {
+	return slices.ContainsFunc(c.pools, func(p netip.Prefix) bool { return p.Contains(client) })
+}
+
+// forwarder answers one data network's queries on its listen address.
+type forwarder struct {
+	listen netip.Addr
+
+	mu    sync.RWMutex
+	cfg   *zoneConfig
+	cache *cache
+
+	ues func(netip.Addr) (string, bool)
+	now func() time.Time
+
+	downMu sync.Mutex
+	down   map[netip.AddrPort]time.Time
+
+	udp      *net.UDPConn
+	tcp      *net.TCPListener
+	inflight chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newForwarder(r Resolver, ues func(netip.Addr) (string, bool)) *forwarder {
+	return &forwarder{
+		listen:   r.Listen,
+		cfg:      newZoneConfig(r),
+		cache:    newCache(cacheSize),
+		ues:      ues,
+		now:      time.Now,
+		down:     make(map[netip.AddrPort]time.Time),
+		inflight: make(chan struct{}, maxInflight),
+	}
+}
+
+// configure applies new settings to a running forwarder. Cached answers
+// are dropped when the upstream servers change.
+func (f *forwarder) configure(r Resolver) {
+	cfg := newZoneConfig(r)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !slices.Equal(f.cfg.upstreams, cfg.upstreams) {
+		f.cache = newCache(cacheSize)
+	}
+
+	f.cfg = cfg
+}
+
+func (f *forwarder) config() (*zoneConfig, *cache) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.cfg, f.cache
+}
+
+// start listens on the forwarder's address, over UDP and TCP.
+func (f *forwarder) start(port int) error {
+	addr := netip.AddrPortFrom(f.listen, uint16(port))
+
+	udp, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(addr))
+	if err != nil {
+		return fmt.Errorf("listen udp %s: %w", addr, err)
+	}
+
+	tcp, err := net.ListenTCP("tcp", net.TCPAddrFromAddrPort(addr))
+	if err != nil {
+		_ = udp.Close()
+		return fmt.Errorf("listen tcp %s: %w", addr, err)
+	}
+
+	f.udp = udp
+	f.tcp = tcp
+
+	f.wg.Add(2)
+
+	go f.serveUDP()
+	go f.serveTCP()
+
+	return nil
+}
+
+func (f *forwarder) close() {
+	if f.udp != nil {
+		_ = f.udp.Close()
+	}
+
+	if f.tcp != nil {
+		_ = f.tcp.Close()
+	}
+
+	f.wg.Wait()
+}
+
+func (f *forwarder) serveUDP() {
+	defer f.wg.Done()
+
+	buf := make([]byte, 65535)
+
+	for {
+		n, src, err := f.udp.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		select {
+		case f.inflight <- struct{}{}:
+		default:
+			continue
+		}
+
+		query := slices.Clone(buf[:n])
+
+		f.wg.Go(func() {
+			defer func() { <-f.inflight }()
+
+			reply := f.handle(context.Background(), query, src.Addr().Unmap(), false)
+			if reply != nil {
+				_, _ = f.udp.WriteToUDPAddrPort(reply, src)
+			}
+		})
+	}
+}
+
+func (f *forwarder) serveTCP() {
+	defer f.wg.Done()
+
+	for {
+		conn, err := f.tcp.AcceptTCP()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		select {
+		case f.inflight <- struct{}{}:
+		default:
+			_ = conn.Close()
+			continue
+		}
+
+		f.wg.Go(func() {
+			defer func() { <-f.inflight }()
+			defer func() { _ = conn.Close() }()
+
+			f.serveTCPConn(conn)
+		})
+	}
+}
+
+// serveTCPConn answers the length-prefixed queries of one connection
+// (RFC 7766) until the client stops sending.
+func (f *forwarder) serveTCPConn(conn *net.TCPConn) {
+	src := netip.MustParseAddrPort(conn.RemoteAddr().String()).Addr().Unmap()
+
+	for {
+		if err := conn.SetDeadline(time.Now().Add(tcpIdleTimeout)); err != nil {
+			return
+		}
+
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			return
+		}
+
+		reply := f.handle(context.Background(), query, src, true)
+		if reply == nil {
+			return
+		}
+
+		if err := writeTCPMessage(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// handle answers one query from client. It returns nil when the query is
+// not worth an answer.
+func (f *forwarder) handle(ctx context.Context, query []byte, client netip.Addr, overTCP bool) []byte {
+	var p dnsmessage.Parser
+
+	hdr, err := p.Start(query)
+	if err != nil || hdr.Response {
+		return nil
+	}
+
+	q, err := p.Question()
+	if err != nil {
+		return packReply(errorReply(hdr, nil, dnsmessage.RCodeFormatError))
+	}
+
+	if hdr.OpCode != 0 {
+		return packReply(errorReply(hdr, &q, dnsmessage.RCodeNotImplemented))
+	}
+
+	cfg, answers := f.config()
+
+	// The forwarder is reachable from anywhere its listen address is
+	// routed; only the data network's UEs are served.
+	if !cfg.servesClient(client) {
+		return packReply(errorReply(hdr, &q, dnsmessage.RCodeRefused))
+	}
+
+	start := f.now()
+	name := canonicalName(q.Name.String())
+	key := cacheKey{name: name, typ: q.Type, class: q.Class}
+
+	var (
+		reply  *dnsmessage.Message
+		source string
+	)
+
+	if local, ok := cfg.answerLocal(hdr, q, name); ok {
+		reply, source = local, "local"
+	} else if cached, ok := answers.get(key, start); ok {
+		reply, source = cached, "cache"
+	} else if answer, upstream, err := f.forward(ctx, cfg.upstreams, query, q, overTCP); err == nil {
+		answers.put(key, answer, f.now())
+		reply, source = answer, upstream.String()
+	} else {
+		reply, source = errorReply(hdr, &q, dnsmessage.RCodeServerFailure), "none"
+	}
+
+	reply.ID = hdr.ID
+	reply.RecursionDesired = hdr.RecursionDesired
+
+	out := packReply(reply)
+
+	if !overTCP && len(out) > udpLimit(&p) {
+		reply = &dnsmessage.Message{Header: reply.Header, Questions: reply.Questions}
+		reply.Truncated = true
+		out = packReply(reply)
+	}
+
+	if cfg.logQueries {
+		f.logQuery(cfg, client, q, name, reply.RCode, source, f.now().Sub(start))
+	}
+
+	return out
+}
+
+// answerLocal answers queries for local records and local zones. Other
+// names are left to the upstream servers.
+func (c *zoneConfig) answerLocal(hdr dnsmessage.Header, q dnsmessage.Question, name string) (*dnsmessage.Message, bool) {
+	addrs, hasRecord := c.records[name]
+	if !hasRecord && !c.inLocalZone(name) {
+		return nil, false
+	}
+
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 hdr.ID,
+			Response:           true,
+			Authoritative:      true,
+			RecursionAvailable: true,
+		},
+		Questions: []dnsmessage.Question{q},
+	}
+
+	if !hasRecord {
+		msg.RCode = dnsmessage.RCodeNameError
+		return msg, true
+	}
+
+	if q.Class != dnsmessage.ClassINET && q.Class != dnsmessage.ClassANY {
+		return msg, true
+	}
+
+	for _, addr := range addrs {
+		rr := dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: localTTL},
+		}
+
+		switch {
+		case addr.Is4() && (q.Type == dnsmessage.TypeA || q.Type == dnsmessage.TypeALL):
+			rr.Header.Type = dnsmessage.TypeA
+			rr.Body = &dnsmessage.AResource{A: addr.As4()}
+		case addr.Is6() && (q.Type == dnsmessage.TypeAAAA || q.Type == dnsmessage.TypeALL):
+			rr.Header.Type = dnsmessage.TypeAAAA
+			rr.Body = &dnsmessage.AAAAResource{AAAA: addr.As16()}
+		default:
+			continue
+		}
+
+		msg.Answers = append(msg.Answers, rr)
+	}
+
+	return msg, true
+}
+
+// forward sends query to the upstream servers in order, those that failed
+// recently last, and returns the first answer. A server failure or refusal
+// is returned only when no server does better.
+func (f *forwarder) forward(ctx context.Context, upstreams []netip.AddrPort, query []byte, q dnsmessage.Question, overTCP bool) (*dnsmessage.Message, netip.AddrPort, error) {
+	var (
+		errs         []error
+		fallback     *dnsmessage.Message
+		fallbackFrom netip.AddrPort
+	)
+
+	for _, upstream := range f.upstreamOrder(upstreams) {
+		msg, err := exchange(ctx, upstream, query, q, overTCP)
+		if err != nil {
+			f.markDown(upstream)
+			errs = append(errs, err)
+
+			continue
+		}
+
+		f.markUp(upstream)
+
+		if msg.RCode == dnsmessage.RCodeServerFailure || msg.RCode == dnsmessage.RCodeRefused {
+			if fallback == nil {
+				fallback, fallbackFrom = msg, upstream
+			}
+
+			continue
+		}
+
+		return msg, upstream, nil
+	}
+
+	if fallback != nil {
+		return fallback, fallbackFrom, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, netip.AddrPort{}, errNoUpstream
+	}
+
+	return nil, netip.AddrPort{}, errors.Join(errs...)
+}
+
+func (f *forwarder) upstreamOrder(upstreams []netip.AddrPort) []netip.AddrPort {
+	f.downMu.Lock()
+	defer f.downMu.Unlock()
+
+	now := f.now()
+	healthy := make([]netip.AddrPort, 0, len(upstreams))
+
+	var held []netip.AddrPort
+
+	for _, u := range upstreams {
+		if until, ok := f.down[u]; ok && now.Before(until) {
+			held = append(held, u)
+			continue
+		}
+
+		healthy = append(healthy, u)
+	}
+
+	return append(healthy, held...)
+}
+
+func (f *forwarder) markDown(upstream netip.AddrPort) {
+	f.downMu.Lock()
+	defer f.downMu.Unlock()
+
+	f.down[upstream] = f.now().Add(upstreamHoldDown)
+}
+
+func (f *forwarder) markUp(upstream netip.AddrPort) {
+	f.downMu.Lock()
+	defer f.downMu.Unlock()
+
+	delete(f.down, upstream)
+}
+
+func (f *forwarder) logQuery(cfg *zoneConfig, client netip.Addr, q dnsmessage.Question, name string, rcode dnsmessage.RCode, source string, latency time.Duration) {
+	imsi, _ := f.ues(client)
+
+	logger.DNSLog.Info("DNS query",
+		zap.String("data_network", cfg.dataNetwork),
+		zap.String("imsi", imsi),
+		zap.String("client", client.String()),
+		zap.String("name", name),
+		zap.String("type", strings.TrimPrefix(q.Type.String(), "Type")),
+		zap.String("rcode", strings.TrimPrefix(rcode.String(), "RCode")),
+		zap.String("source", source),
+		zap.Duration("latency", latency),
+	)
+}
+
+// exchange sends query to upstream under a fresh ID and returns its answer
+// to q.
+func exchange(ctx context.Context, upstream netip.AddrPort, query []byte, q dnsmessage.Question, overTCP bool) (*dnsmessage.Message, error) {
+	ctx, cancel := context.WithTimeout(ctx, upstreamTimeout)
+	defer cancel()
+
+	network := "udp"
+	if overTCP {
+		network = "tcp"
+	}
+
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, network, upstream.String())
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", upstream, err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	id := uint16(rand.Uint32())
+	out := slices.Clone(query)
+	binary.BigEndian.PutUint16(out, id)
+
+	var raw []byte
+
+	if overTCP {
+		if err := writeTCPMessage(conn, out); err != nil {
+			return nil, fmt.Errorf("query %s: %w", upstream, err)
+		}
+
+		raw, err = readTCPMessage(conn)
+		if err != nil {
+			return nil, fmt.Errorf("answer from %s: %w", upstream, err)
+		}
+	} else {
+		if _, err := conn.Write(out); err != nil {
+			return nil, fmt.Errorf("query %s: %w", upstream, err)
+		}
+
+		buf := make([]byte, 65535)
+
+		// Skip datagrams that do not answer this query, such as a late
+		// answer to one that timed out.
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, fmt.Errorf("answer from %s: %w", upstream, err)
+			}
+
+			if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+				raw = buf[:n]
+				break
+			}
+		}
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("answer from %s: %w", upstream, err)
+	}
+
+	if msg.ID != id || !msg.Response || len(msg.Questions) != 1 || !sameQuestion(msg.Questions[0], q) {
+		return nil, fmt.Errorf("answer from %s does not match the query", upstream)
+	}
+
+	return &msg, nil
+}
+
+func sameQuestion(a, b dnsmessage.Question) bool {
+	return a.Type == b.Type && a.Class == b.Class && strings.EqualFold(a.Name.String(), b.Name.String())
+}
+
+func errorReply(hdr dnsmessage.Header, q *dnsmessage.Question, rcode dnsmessage.RCode) *dnsmessage.Message {
+	msg := &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 hdr.ID,
+			Response:           true,
+			OpCode:             hdr.OpCode,
+			RecursionDesired:   hdr.RecursionDesired,
+			RecursionAvailable: true,
+			RCode:              rcode,
+		},
+	}
+
+	if q != nil {
+		msg.Questions = []dnsmessage.Question{*q}
+	}
+
+	return msg
+}
+
+func packReply(msg *dnsmessage.Message) []byte {
+	out, err := msg.Pack()
+	if err == nil {
+		return out
+	}
+
+	// A record that does not pack leaves the client with a failure rather
+	// than silence.
+	fail := errorReply(msg.Header, nil, dnsmessage.RCodeServerFailure)
+	fail.Questions = msg.Questions
+
+	out, err = fail.Pack()
+	if err != nil {
+		return nil
+	}
+
+	return out
+}
+
+// udpLimit is the largest UDP answer the client of the query p has read
+// the question of accepts: its EDNS buffer size (RFC 6891), or 512 bytes.
+func udpLimit(p *dnsmessage.Parser) int {
+	if p.SkipAllQuestions() != nil || p.SkipAllAnswers() != nil || p.SkipAllAuthorities() != nil {
+		return minUDPSize
+	}
+
+	for {
+		h, err := p.AdditionalHeader()
+		if err != nil {
+			return minUDPSize
+		}
+
+		if h.Type == dnsmessage.TypeOPT {
+			return max(int(h.Class), minUDPSize)
+		}
+
+		if err := p.SkipAdditional(); err != nil {
+			return minUDPSize
+		}
+	}
+}
+
+func canonicalName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name
+}
+
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	out := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(out, uint16(len(msg)))
+	copy(out[2:], msg)
+
+	_, err := w.Write(out)
+
+	return err
+}
@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeUpstream answers every A query with 192.0.2.1, or with rcode when it
+// is set, and counts the queries it saw.
+type fakeUpstream struct {
+	conn    *net.UDPConn
+	queries atomic.Int32
+	rcode   dnsmessage.RCode
+}
+
+func startFakeUpstream(t *testing.T, rcode dnsmessage.RCode) *fakeUpstream {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	u := &fakeUpstream{conn: conn, rcode: rcode}
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 65535)
+
+		for {
+			n, src, err := conn.ReadFromUDPAddrPort(buf)
+			if err != nil {
+				return
+			}
+
+			u.queries.Add(1)
+
+			var q dnsmessage.Message
+			if err := q.Unpack(buf[:n]); err != nil {
+				continue
+			}
+
+			reply := answerA(q.Questions[0].Name.String(), 300)
+			reply.ID = q.ID
+			reply.RCode = u.rcode
+
+			if u.rcode != dnsmessage.RCodeSuccess {
+				reply.Answers = nil
+			}
+
+			out, err := reply.Pack()
+			if err != nil {
+				continue
+			}
+
+			_, _ = conn.WriteToUDPAddrPort(out, src)
+		}
+	}()
+
+	return u
+}
+
+func (u *fakeUpstream) addr() netip.AddrPort {
+	return netip.MustParseAddrPort(u.conn.LocalAddr().String())
+}
+
+// deadUpstream is an address nothing answers on.
+func deadUpstream(t *testing.T) netip.AddrPort {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	addr := netip.MustParseAddrPort(conn.LocalAddr().String())
+	_ = conn.Close()
+
+	return addr
+}
+
+// uePools holds the address query sends from.
+var uePools = []netip.Prefix{netip.MustParsePrefix("10.45.0.0/16")}
+
+func query(t *testing.T, f *forwarder, name string, typ dnsmessage.Type) *dnsmessage.Message {
+	t.Helper()
+
+	return queryFrom(t, f, netip.MustParseAddr("10.45.0.2"), name, typ)
+}
+
+func queryFrom(t *testing.T, f *forwarder, client netip.Addr, name string, typ dnsmessage.Type) *dnsmessage.Message {
+	t.Helper()
+
+	q := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 4242, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET}},
+	}
+
+	raw, err := q.Pack()
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+
+	out := f.handle(context.Background(), raw, client, false)
+	if out == nil {
+		t.Fatal("expected an answer")
+	}
+
+	var reply dnsmessage.Message
+	if err := reply.Unpack(out); err != nil {
+		t.Fatalf("unpack: %v", err)
+	}
+
+	if reply.ID != 4242 {
+		t.Fatalf("expected the client's ID, got %d", reply.ID)
+	}
+
+	return &reply
+}
+
+func noUEs(netip.Addr) (string, bool) { return "", false }
+
+func TestForwarder_LocalRecordsAndZones(t *testing.T) {
+	upstream := startFakeUpstream(t, dnsmessage.RCodeSuccess)
+
+	f := newForwarder(Resolver{
+		DataNetwork: "internet",
+		Pools:       uePools,
+		Upstreams:   []netip.AddrPort{upstream.addr()},
+		LocalZones:  []string{"ue.internal"},
+		Records: []Record{
+			{Name: "Camera.UE.Internal", Addresses: []netip.Addr{netip.MustParseAddr("10.45.0.9")}},
+		},
+	}, noUEs)
+
+	reply := query(t, f, "camera.ue.internal.", dnsmessage.TypeA)
+	if reply.RCode != dnsmessage.RCodeSuccess || len(reply.Answers) != 1 || !reply.Authoritative {
+		t.Fatalf("expected one authoritative answer, got %+v", reply)
+	}
+
+	if a := reply.Answers[0].Body.(*dnsmessage.AResource).A; netip.AddrFrom4(a) != netip.MustParseAddr("10.45.0.9") {
+		t.Fatalf("unexpected address %v", a)
+	}
+
+	reply = query(t, f, "camera.ue.internal.", dnsmessage.TypeAAAA)
+	if reply.RCode != dnsmessage.RCodeSuccess || len(reply.Answers) != 0 {
+		t.Fatalf("expected NODATA for AAAA, got %+v", reply)
+	}
+
+	reply = query(t, f, "other.ue.internal.", dnsmessage.TypeA)
+	if reply.RCode != dnsmessage.RCodeNameError {
+		t.Fatalf("expected NXDOMAIN inside the local zone, got %v", reply.RCode)
+	}
+
+	if n := upstream.queries.Load(); n != 0 {
+		t.Fatalf("expected local names never to reach upstream, got %d queries", n)
+	}
+}
+
+func TestForwarder_CachesUpstreamAnswers(t *testing.T) {
+	upstream := startFakeUpstream(t, dnsmessage.RCodeSuccess)
+
+	f := newForwarder(Resolver{
+		DataNetwork: "internet",
+		Pools:       uePools,
+		Upstreams:   []netip.AddrPort{upstream.addr()},
+	}, noUEs)
+
+	for range 3 {
+		reply := query(t, f, "example.com.", dnsmessage.TypeA)
+		if reply.RCode != dnsmessage.RCodeSuccess || len(reply.Answers) != 1 {
+			t.Fatalf("expected an answer, got %+v", reply)
+		}
+	}
+
+	if n := upstream.queries.Load(); n != 1 {
+		t.Fatalf("expected 1 upstream query, got %d", n)
+	}
+}
+
+func TestForwarder_FailsOver(t *testing.T) {
+	refusing := startFakeUpstream(t, dnsmessage.RCodeRefused)
+	working := startFakeUpstream(t, dnsmessage.RCodeSuccess)
+
+	f := newForwarder(Resolver{
+		DataNetwork: "internet",
+		Pools:       uePools,
+		Upstreams:   []netip.AddrPort{refusing.addr(), working.addr()},
+	}, noUEs)
+
+	reply := query(t, f, "example.com.", dnsmessage.TypeA)
+	if reply.RCode != dnsmessage.RCodeSuccess || len(reply.Answers) != 1 {
+		t.Fatalf("expected the second upstream's answer, got %+v", reply)
+	}
+
+	if refusing.queries.Load() != 1 || working.queries.Load() != 1 {
+		t.Fatalf("expected one query to each upstream, got %d and %d",
+			refusing.queries.Load(), working.queries.Load())
+	}
+}
+
+func TestForwarder_HoldsDownFailedUpstream(t *testing.T) {
+	dead := deadUpstream(t)
+	working := startFakeUpstream(t, dnsmessage.RCodeSuccess)
+
+	f := newForwarder(Resolver{
+		DataNetwork: "internet",
+		Pools:       uePools,
+		Upstreams:   []netip.AddrPort{dead, working.addr()},
+	}, noUEs)
+
+	f.markDown(dead)
+
+	order := f.upstreamOrder([]netip.AddrPort{dead, working.addr()})
+	if order[0] != working.addr() || order[1] != dead {
+		t.Fatalf("expected the failed upstream last, got %v", order)
+	}
+
+	reply := query(t, f, "example.com.", dnsmessage.TypeA)
+	if reply.RCode != dnsmessage.RCodeSuccess {
+		t.Fatalf("expected an answer from the working upstream, got %v", reply.RCode)
+	}
+}
+
+func TestForwarder_ServerFailureWhenNoUpstreamAnswers(t *testing.T) {
+	f := newForwarder(Resolver{DataNetwork: "internet", Pools: uePools}, noUEs)
+
+	reply := query(t, f, "example.com.", dnsmessage.TypeA)
+	if reply.RCode != dnsmessage.RCodeServerFailure {
+		t.Fatalf("expected SERVFAIL, got %v", reply.RCode)
+	}
+}
+
+func TestForwarder_RefusesSourcesOutsideTheUEPools(t *testing.T) {
+	upstream := startFakeUpstream(t, dnsmessage.RCodeSuccess)
+
+	f := newForwarder(Resolver{
+		DataNetwork: "internet",
+		Pools:       append([]netip.Prefix{netip.MustParsePrefix("2001:db8:1::/48")}, uePools...),
+		Upstreams:   []netip.AddrPort{upstream.addr()},
+	}, noUEs)
+
+	for _, client := range []string{"10.45.0.2", "2001:db8:1:2::abcd"} {
+		if reply := queryFrom(t, f, netip.MustParseAddr(client), "example.com.", dnsmessage.TypeA); reply.RCode != dnsmessage.RCodeSuccess {
+			t.Errorf("query from UE %s: expected an answer, got %v", client, reply.RCode)
+		}
+	}
+
+	for _, client := range []string{"203.0.113.7", "10.46.0.2", "2001:db8:2::1"} {
+		if reply := queryFrom(t, f, netip.MustParseAddr(client), "example.com.", dnsmessage.TypeA); reply.RCode != dnsmessage.RCodeRefused {
+			t.Errorf("query from %s: expected REFUSED, got %v", client, reply.RCode)
+		}
+	}
+
+	if n := upstream.queries.Load(); n != 1 {
+		t.Fatalf("expected refused queries never to reach upstream, got %d queries", n)
+	}
+}
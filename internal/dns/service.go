@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// reconcileBackstop is the sweep that runs when no wakeup fired. It also
+// retries forwarders whose address could not be bound.
+const reconcileBackstop = time.Minute
+
+// Service runs one forwarder per data network with a resolver, and keeps
+// the set in line with the store.
+type Service struct {
+	store    Store
+	wakeup   <-chan struct{}
+	backstop time.Duration
+	port     int
+
+	mu         sync.Mutex
+	forwarders map[string]*forwarder
+	cancel     context.CancelFunc
+	done       chan struct{}
+
+	ues atomic.Pointer[ueIndex]
+}
+
+// NewService wires a service over store. wakeup is signalled when a
+// resolver, local record or lease changed; nil leaves only the backstop
+// sweep. Start must be called explicitly.
+func NewService(store Store, wakeup <-chan struct{}) *Service {
+	s := &Service{
+		store:      store,
+		wakeup:     wakeup,
+		backstop:   reconcileBackstop,
+		port:       Port,
+		forwarders: make(map[string]*forwarder),
+	}
+
+	s.ues.Store(&ueIndex{})
+
+	return s
+}
+
+// Start launches the reconcile loop. Calls without a paired Stop are
+// no-ops.
+func (s *Service) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx, s.done)
+}
+
+// Stop ends the reconcile loop and closes every forwarder. Safe to call
+// when not started.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	done := s.done
+	s.cancel = nil
+	s.done = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, f := range s.forwarders {
+		f.close()
+		delete(s.forwarders, name)
+	}
+}
+
+// Reconcile reads the resolvers and UE addresses and applies them.
+func (s *Service) Reconcile(ctx context.Context) error {
+	ues, err := s.store.ListUEs(ctx)
+	if err != nil {
+		return fmt.Errorf("list UEs: %w", err)
+	}
+
+	s.ues.Store(newUEIndex(ues))
+
+	resolvers, err := s.store.ListResolvers(ctx)
+	if err != nil {
+		return fmt.Errorf("list resolvers: %w", err)
+	}
+
+	return s.apply(resolvers)
+}
+
+// apply starts, updates and stops forwarders to match resolvers. A
+// forwarder whose listen address changed is restarted; any other change
+// applies in place.
+func (s *Service) apply(resolvers []Resolver) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	desired := make(map[string]Resolver, len(resolvers))
+	for _, r := range resolvers {
+		desired[r.DataNetwork] = r
+	}
+
+	// Stop first, so an address another data network gave up is free.
+	for name, f := range s.forwarders {
+		if r, ok := desired[name]; !ok || r.Listen != f.listen {
+			f.close()
+			delete(s.forwarders, name)
+		}
+	}
+
+	var errs []error
+
+	for name, r := range desired {
+		if f, ok := s.forwarders[name]; ok {
+			f.configure(r)
+			continue
+		}
+
+		f := newForwarder(r, s.lookupUE)
+		if err := f.start(s.port); err != nil {
+			errs = append(errs, fmt.Errorf("data network %s: %w", name, err))
+			continue
+		}
+
+		s.forwarders[name] = f
+
+		logger.DNSLog.Info("DNS forwarder started",
+			zap.String("data_network", name), zap.String("listen", r.Listen.String()))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Service) lookupUE(addr netip.Addr) (string, bool) {
+	return s.ues.Load().lookup(addr)
+}
+
+func (s *Service) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	if err := s.Reconcile(ctx); err != nil {
+		logger.DNSLog.Warn("initial DNS reconcile failed", zap.Error(err))
+	}
+
+	backstop := time.NewTicker(s.backstop)
+	defer backstop.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wakeup:
+		case <-backstop.C:
+		}
+
+		if err := s.Reconcile(ctx); err != nil {
+			logger.DNSLog.Warn("DNS reconcile failed", zap.Error(err))
+		}
+	}
+}
+
+// ueIndex maps UE addresses to IMSIs for query logs.
+type ueIndex struct {
+	v4 map[netip.Addr]string
+	v6 map[netip.Prefix]string
+}
+
+func newUEIndex(ues []UE) *ueIndex {
+	idx := &ueIndex{
+		v4: make(map[netip.Addr]string),
+		v6: make(map[netip.Prefix]string),
+	}
+
+	for _, ue := range ues {
+		addr := ue.Address.Unmap()
+
+		switch {
+		case addr.Is4():
+			idx.v4[addr] = ue.IMSI
+		case addr.Is6():
+			idx.v6[netip.PrefixFrom(addr, 64).Masked()] = ue.IMSI
+		}
+	}
+
+	return idx
+}
+
+func (idx *ueIndex) lookup(addr netip.Addr) (string, bool) {
+	addr = addr.Unmap()
+
+	if addr.Is4() {
+		imsi, ok := idx.v4[addr]
+		return imsi, ok
+	}
+
+	imsi, ok := idx.v6[netip.PrefixFrom(addr, 64).Masked()]
+
+	return imsi, ok
+}
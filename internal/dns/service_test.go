@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package dns
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+)
+
+type fakeStore struct {
+	resolvers []Resolver
+	ues       []UE
+}
+
+func (f *fakeStore) ListResolvers(context.Context) ([]Resolver, error) { return f.resolvers, nil }
+
+func (f *fakeStore) ListUEs(context.Context) ([]UE, error) { return f.ues, nil }
+
+func TestService_ReconcileStartsUpdatesAndStops(t *testing.T) {
+	loopback := netip.MustParseAddr("127.0.0.1")
+	store := &fakeStore{
+		resolvers: []Resolver{{DataNetwork: "internet", Listen: loopback}},
+		ues:       []UE{{IMSI: "001010000000001", Address: netip.MustParseAddr("10.45.0.2")}},
+	}
+
+	s := NewService(store, nil)
+	s.port = 0
+
+	t.Cleanup(s.Stop)
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	first := s.forwarders["internet"]
+	if first == nil {
+		t.Fatal("expected a forwarder for internet")
+	}
+
+	if imsi, ok := s.lookupUE(netip.MustParseAddr("10.45.0.2")); !ok || imsi != "001010000000001" {
+		t.Fatalf("expected the UE to be known, got %q", imsi)
+	}
+
+	store.resolvers[0].LocalZones = []string{"ue.internal"}
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if s.forwarders["internet"] != first {
+		t.Fatal("expected a settings change to keep the running forwarder")
+	}
+
+	if cfg, _ := first.config(); !cfg.inLocalZone("a.ue.internal.") {
+		t.Fatal("expected the new local zone to apply")
+	}
+
+	store.resolvers = nil
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(s.forwarders) != 0 {
+		t.Fatalf("expected no forwarders, got %d", len(s.forwarders))
+	}
+}
+
+func TestUEIndex_IPv6ByPrefix(t *testing.T) {
+	idx := newUEIndex([]UE{
+		{IMSI: "001010000000001", Address: netip.MustParseAddr("10.45.0.2")},
+		{IMSI: "001010000000002", Address: netip.MustParseAddr("2001:db8:1:2::")},
+	})
+
+	if imsi, ok := idx.lookup(netip.MustParseAddr("::ffff:10.45.0.2")); !ok || imsi != "001010000000001" {
+		t.Fatalf("expected the IPv4 UE, got %q", imsi)
+	}
+
+	if imsi, ok := idx.lookup(netip.MustParseAddr("2001:db8:1:2::abcd")); !ok || imsi != "001010000000002" {
+		t.Fatalf("expected the IPv6 UE by its /64, got %q", imsi)
+	}
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package dns runs the embedded DNS forwarders of data networks. A
+// forwarder answers names in its local zones from local records, and
+// forwards every other query to its upstream servers, caching the answers.
+package dns
+
+import (
+	"context"
+	"net/netip"
+)
+
+// Port is the port forwarders listen on, over UDP and TCP.
+const Port = 53
+
+// Resolver is one data network's forwarder.
+type Resolver struct {
+	DataNetwork string
+	Listen      netip.Addr
+	// Pools are the data network's UE address pools. Queries from any
+	// other source are refused.
+	Pools []netip.Prefix
+	// Upstreams are tried in order; one that fails is skipped for a while.
+	Upstreams []netip.AddrPort
+	// LocalZones are domains answered from Records alone: a name inside
+	// one without a record does not exist.
+	LocalZones []string
+	Records    []Record
+	LogQueries bool
+}
+
+// Record is a local name and the addresses it resolves to.
+type Record struct {
+	Name      string
+	Addresses []netip.Addr
+}
+
+// UE is a subscriber's address, which query logs are tied to. An IPv6
+// address stands for the UE's /64.
+type UE struct {
+	IMSI    string
+	Address netip.Addr
+}
+
+// Store is the narrow view the service needs over the replicated tables.
+// The api layer satisfies it with an adapter that resolves local records
+// to addresses, so this package stays free of the db dependency.
+type Store interface {
+	ListResolvers(ctx context.Context) ([]Resolver, error)
+	ListUEs(ctx context.Context) ([]UE, error)
+}
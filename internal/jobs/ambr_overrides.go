@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+const ambrOverrideTickInterval = 30 * time.Second
+
+// RunAmbrOverrideWorker reverts subscriber AMBR overrides once they expire.
+// Deleting an override wakes the session reconcilers on every node, which
+// put the profile and policy bitrates back on the live sessions. Runs on the
+// leader only (gated by guard).
+func RunAmbrOverrideWorker(ctx context.Context, database *db.Database, guard *LeaderGuard) {
+	ticker := time.NewTicker(ambrOverrideTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.EllaLog.Info("AMBR override worker stopped")
+			return
+		case <-ticker.C:
+		}
+
+		if !guard.IsLeader() {
+			continue
+		}
+
+		if _, err := revertExpiredAmbrOverrides(ctx, database, time.Now()); err != nil {
+			logger.EllaLog.Warn("AMBR override: revert expired overrides failed", zap.Error(err))
+		}
+	}
+}
+
+// revertExpiredAmbrOverrides deletes the overrides expired at now, and reports
+// whether there were any. Nothing is written when none expired, so an idle
+// tick does not wake the reconcilers.
+func revertExpiredAmbrOverrides(ctx context.Context, database *db.Database, now time.Time) (bool, error) {
+	overrides, err := database.ListSubscriberAmbrOverrides(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	expired := false
+
+	for _, o := range overrides {
+		if !o.ActiveAt(now) {
+			expired = true
+			break
+		}
+	}
+
+	if !expired {
+		return false, nil
+	}
+
+	if err := database.DeleteExpiredSubscriberAmbrOverrides(ctx, now.Unix()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestRevertExpiredAmbrOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "ella.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = database.Close() }()
+
+	profile := &db.Profile{Name: "tablets", UeAmbrUplink: "10 Mbps", UeAmbrDownlink: "10 Mbps"}
+	if err := database.CreateProfile(ctx, profile); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := database.GetProfile(ctx, profile.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imsi := "001010123456789"
+	if err := database.CreateSubscriber(ctx, &db.Subscriber{
+		Imsi:           imsi,
+		SequenceNumber: "000000000001",
+		PermanentKey:   "6f30087629feb0b089783c81d0ae09b5",
+		Opc:            "21a7e1897dfb481d62439142cdf1b6ee",
+		ProfileID:      created.ID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	if err := database.SetSubscriberAmbrOverride(ctx, &db.SubscriberAmbrOverride{
+		IMSI:           imsi,
+		UeAmbrUplink:   "100 Mbps",
+		UeAmbrDownlink: "100 Mbps",
+		CreatedAt:      now.Unix(),
+		ExpiresAt:      now.Add(2 * time.Hour).Unix(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if reverted, err := revertExpiredAmbrOverrides(ctx, database, now.Add(time.Hour)); err != nil || reverted {
+		t.Fatalf("reverted = %v (%v), want nothing before expiry", reverted, err)
+	}
+
+	wakeup, stop := database.Changefeed().Wakeup(db.TopicSessionReconcile)
+	defer stop()
+
+	if reverted, err := revertExpiredAmbrOverrides(ctx, database, now.Add(2*time.Hour)); err != nil || !reverted {
+		t.Fatalf("reverted = %v (%v), want the expired override reverted", reverted, err)
+	}
+
+	if _, err := database.GetSubscriberAmbrOverride(ctx, imsi); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("expected the override deleted, got %v", err)
+	}
+
+	select {
+	case <-wakeup:
+	case <-time.After(time.Second):
+		t.Fatal("session reconcilers were not woken")
+	}
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package jobs
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+const scheduleTickInterval = time.Minute
+
+// RunScheduleWorker re-evaluates schedules every minute and, when one
+// enters or leaves a window, wakes the reconcilers that apply scheduled
+// network rules and Session-AMBR values. Schedules are evaluated locally,
+// so the worker runs on every node rather than only on the leader. It
+// blocks until ctx is cancelled, so callers should invoke it in a
+// goroutine.
+func RunScheduleWorker(ctx context.Context, database *db.Database) {
+	ticker := time.NewTicker(scheduleTickInterval)
+	defer ticker.Stop()
+
+	active, _ := evaluateSchedules(ctx, database, time.Now(), nil)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.EllaLog.Info("Schedule worker stopped")
+			return
+		case <-ticker.C:
+		}
+
+		active, _ = evaluateSchedules(ctx, database, time.Now(), active)
+	}
+}
+
+// evaluateSchedules computes the schedules active at now and, if the set
+// differs from prev, publishes the schedule and session reconcile topics.
+// It returns the new set and whether anything was published.
+func evaluateSchedules(ctx context.Context, database *db.Database, now time.Time, prev map[string]bool) (map[string]bool, bool) {
+	active, err := database.ActiveSchedules(ctx, now)
+	if err != nil {
+		logger.EllaLog.Warn("error evaluating schedules", zap.Error(err))
+
+		if active == nil {
+			return prev, false
+		}
+	}
+
+	if prev == nil || maps.Equal(active, prev) {
+		return active, false
+	}
+
+	index := database.RaftAppliedIndex()
+	database.Changefeed().Publish(db.TopicSchedules, index)
+	database.Changefeed().Publish(db.TopicSessionReconcile, index)
+
+	return active, true
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package jobs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func TestEvaluateSchedules_PublishesOnWindowEdge(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "ella.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = database.Close() }()
+
+	schedule := &db.Schedule{Name: "class-hours", TimeZone: "UTC"}
+	if err := schedule.SetWindows([]models.ScheduleWindow{{Days: []string{"wed"}, Start: "08:00", End: "15:00"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.CreateSchedule(ctx, schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	wakeup, stop := database.Changefeed().Wakeup(db.TopicSessionReconcile)
+	defer stop()
+
+	before := time.Date(2026, 3, 11, 7, 59, 0, 0, time.UTC)
+	during := time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC)
+
+	active, published := evaluateSchedules(ctx, database, before, nil)
+	if published {
+		t.Fatal("the first evaluation should only record the state")
+	}
+
+	active, published = evaluateSchedules(ctx, database, before.Add(30*time.Second), active)
+	if published {
+		t.Fatal("expected no publish while nothing changed")
+	}
+
+	if _, published = evaluateSchedules(ctx, database, during, active); !published {
+		t.Fatal("expected a publish when the window opened")
+	}
+
+	select {
+	case <-wakeup:
+	case <-time.After(time.Second):
+		t.Fatal("session reconcilers were not woken")
+	}
+}
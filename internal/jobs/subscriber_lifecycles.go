@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+const subscriberLifecycleTickInterval = 30 * time.Second
+
+// RunSubscriberLifecycleWorker expires subscribers once their validity
+// window closes. Recording the expiry wakes the session reconcilers on every
+// node, which deregister the subscriber's UEs. Runs on the leader only (gated
+// by guard).
+func RunSubscriberLifecycleWorker(ctx context.Context, database *db.Database, guard *LeaderGuard) {
+	ticker := time.NewTicker(subscriberLifecycleTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.EllaLog.Info("subscriber lifecycle worker stopped")
+			return
+		case <-ticker.C:
+		}
+
+		if !guard.IsLeader() {
+			continue
+		}
+
+		if _, err := expireSubscribers(ctx, database, time.Now()); err != nil {
+			logger.EllaLog.Warn("subscriber lifecycle: expire subscribers failed", zap.Error(err))
+		}
+	}
+}
+
+// expireSubscribers records the expiry of the subscribers whose validity
+// window closed by now, and reports whether there were any. Nothing is
+// written when none closed, so an idle tick does not wake the reconcilers.
+func expireSubscribers(ctx context.Context, database *db.Database, now time.Time) (bool, error) {
+	lifecycles, err := database.ListSubscriberLifecycles(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	due := false
+
+	for _, l := range lifecycles {
+		if l.State != string(db.SubscriberStateExpired) && l.StateAt(now) == db.SubscriberStateExpired {
+			due = true
+			break
+		}
+	}
+
+	if !due {
+		return false, nil
+	}
+
+	if err := database.ExpireSubscriberLifecycles(ctx, now.Unix()); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package jobs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+func TestExpireSubscribers(t *testing.T) {
+	ctx := context.Background()
+
+	database, err := db.NewDatabaseWithoutRaft(ctx, filepath.Join(t.TempDir(), "ella.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = database.Close() }()
+
+	profile := &db.Profile{Name: "contractors", UeAmbrUplink: "10 Mbps", UeAmbrDownlink: "10 Mbps"}
+	if err := database.CreateProfile(ctx, profile); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := database.GetProfile(ctx, profile.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imsi := "001010123456789"
+	if err := database.CreateSubscriber(ctx, &db.Subscriber{
+		Imsi:           imsi,
+		SequenceNumber: "000000000001",
+		PermanentKey:   "6f30087629feb0b089783c81d0ae09b5",
+		Opc:            "21a7e1897dfb481d62439142cdf1b6ee",
+		ProfileID:      created.ID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	if err := database.SetSubscriberLifecycle(ctx, &db.SubscriberLifecycle{
+		IMSI:       imsi,
+		State:      string(db.SubscriberStateActive),
+		ValidUntil: now.Add(2 * time.Hour).Unix(),
+		UpdatedAt:  now.Unix(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expired, err := expireSubscribers(ctx, database, now.Add(time.Hour)); err != nil || expired {
+		t.Fatalf("expired = %v (%v), want nothing before the window closes", expired, err)
+	}
+
+	wakeup, stop := database.Changefeed().Wakeup(db.TopicSessionReconcile)
+	defer stop()
+
+	if expired, err := expireSubscribers(ctx, database, now.Add(2*time.Hour)); err != nil || !expired {
+		t.Fatalf("expired = %v (%v), want the subscriber expired", expired, err)
+	}
+
+	l, err := database.GetSubscriberLifecycle(ctx, imsi)
+	if err != nil || l.State != string(db.SubscriberStateExpired) {
+		t.Fatalf("lifecycle = %+v (%v), want expired", l, err)
+	}
+
+	select {
+	case <-wakeup:
+	case <-time.After(time.Second):
+		t.Fatal("session reconcilers were not woken")
+	}
+
+	// Once recorded, the expiry is not written again.
+	if expired, err := expireSubscribers(ctx, database, now.Add(3*time.Hour)); err != nil || expired {
+		t.Fatalf("expired = %v (%v), want nothing left to expire", expired, err)
+	}
+}
@@ -50,6 +50,13 @@ type Kernel interface {
 	InterfaceExists(ifKey NetworkInterface) (bool, error)
 	RouteExists(destination netip.Prefix, gateway netip.Addr, priority int, ifKey NetworkInterface) (bool, error)
 	EnsureGatewaysOnInterfaceInNeighTable(ifKey NetworkInterface) error
+	ResolveEgressDevice(name string, vlanID int) (string, error)
+	ReplaceTableRoute(route TableRoute) error
+	DeleteTableRoute(route TableRoute) error
+	ListManagedTableRoutes() ([]TableRoute, error)
+	AddSourceRule(rule SourceRule) error
+	DeleteSourceRule(rule SourceRule) error
+	ListManagedSourceRules() ([]SourceRule, error)
 }
 
 // RealKernel is the production implementation of the Kernel interface.
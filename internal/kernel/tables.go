@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package kernel
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/vishvananda/netlink"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+// sourceRulePriority places Ella's per-data-network policy rules ahead of
+// the default "lookup main" rule (32766) while leaving room below for
+// operator rules that must win.
+const sourceRulePriority = 1000
+
+// TableRoute describes one Ella-owned route in a data network's routing
+// table. Device is the resolved egress netdev (VLAN sub-interface
+// included).
+type TableRoute struct {
+	Destination netip.Prefix
+	Gateway     netip.Addr
+	Priority    int
+	Device      string
+	Table       int
+}
+
+// SourceRule sends traffic sourced from a UE pool to a routing table.
+type SourceRule struct {
+	Source netip.Prefix
+	Table  int
+}
+
+// ResolveEgressDevice returns the netdev a data network egresses through.
+// An empty name means the N6 interface. A non-zero vlanID selects the VLAN
+// sub-interface of that vid whose parent is the named interface.
+func (rk *RealKernel) ResolveEgressDevice(name string, vlanID int) (string, error) {
+	if name == "" {
+		name = rk.ifMapping[N6]
+	}
+
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to find network interface %q: %v", name, err)
+	}
+
+	if vlanID == 0 {
+		return name, nil
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		return "", fmt.Errorf("failed to list links: %v", err)
+	}
+
+	for _, l := range links {
+		vlan, ok := l.(*netlink.Vlan)
+		if !ok {
+			continue
+		}
+
+		if vlan.Attrs().ParentIndex == link.Attrs().Index && vlan.VlanId == vlanID {
+			return vlan.Attrs().Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no VLAN %d sub-interface on %q", vlanID, name)
+}
+
+func tableRouteToNetlink(route TableRoute) (*netlink.Route, netlink.Link, error) {
+	link, err := netlink.LinkByName(route.Device)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find network interface %q: %v", route.Device, err)
+	}
+
+	gw, via := gwOrVia(route.Destination, route.Gateway)
+
+	nlRoute := &netlink.Route{
+		Dst:       prefixToIPNet(route.Destination),
+		Gw:        gw,
+		LinkIndex: link.Attrs().Index,
+		Priority:  route.Priority,
+		Table:     route.Table,
+		Protocol:  rtProtoElla,
+	}
+	if via != nil {
+		nlRoute.Via = via
+	}
+
+	return nlRoute, link, nil
+}
+
+// ReplaceTableRoute creates or updates a route in a data network's table.
+func (rk *RealKernel) ReplaceTableRoute(route TableRoute) error {
+	nlRoute, link, err := tableRouteToNetlink(route)
+	if err != nil {
+		return err
+	}
+
+	if err := netlink.RouteReplace(nlRoute); err != nil {
+		return fmt.Errorf("failed to replace route in table %d: %v", route.Table, err)
+	}
+
+	logger.EllaLog.Debug("Replaced table route", zap.String("destination", route.Destination.String()), zap.String("gateway", route.Gateway.String()), zap.Int("table", route.Table), zap.String("interface", route.Device))
+
+	if nlRoute.Gw != nil {
+		return addNeighbourForLink(nlRoute.Gw, link)
+	}
+
+	if via, ok := nlRoute.Via.(*netlink.Via); ok && via != nil {
+		return addNeighbourForLink(via.Addr, link)
+	}
+
+	return nil
+}
+
+// DeleteTableRoute removes an Ella-owned route from a data network's table.
+func (rk *RealKernel) DeleteTableRoute(route TableRoute) error {
+	nlRoute, _, err := tableRouteToNetlink(route)
+	if err != nil {
+		return err
+	}
+
+	if err := netlink.RouteDel(nlRoute); err != nil {
+		return fmt.Errorf("failed to delete route from table %d: %v", route.Table, err)
+	}
+
+	return nil
+}
+
+// ListManagedTableRoutes returns every Ella-owned route outside the main
+// table.
+func (rk *RealKernel) ListManagedTableRoutes() ([]TableRoute, error) {
+	filter := netlink.Route{
+		Table:    unix.RT_TABLE_UNSPEC,
+		Protocol: rtProtoElla,
+	}
+
+	var result []TableRoute
+
+	for _, af := range []int{unix.AF_INET, unix.AF_INET6} {
+		routes, err := netlink.RouteListFiltered(af, &filter, netlink.RT_FILTER_TABLE|netlink.RT_FILTER_PROTOCOL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list routes: %v", err)
+		}
+
+		for _, r := range routes {
+			if r.Table == unix.RT_TABLE_MAIN || r.Dst == nil {
+				continue
+			}
+
+			dst, ok := prefixFromIPNet(r.Dst)
+			if !ok {
+				continue
+			}
+
+			var gw netip.Addr
+			if r.Gw != nil {
+				gw, _ = addrFromNetIP(r.Gw)
+			} else if via, ok := r.Via.(*netlink.Via); ok && via != nil {
+				gw, _ = addrFromNetIP(via.Addr)
+			}
+
+			var device string
+			if link, err := netlink.LinkByIndex(r.LinkIndex); err == nil {
+				device = link.Attrs().Name
+			}
+
+			result = append(result, TableRoute{
+				Destination: dst,
+				Gateway:     gw,
+				Priority:    r.Priority,
+				Device:      device,
+				Table:       r.Table,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func sourceRuleToNetlink(rule SourceRule) *netlink.Rule {
+	nlRule := netlink.NewRule()
+	nlRule.Src = prefixToIPNet(rule.Source)
+	nlRule.Table = rule.Table
+	nlRule.Priority = sourceRulePriority
+	nlRule.Protocol = uint8(rtProtoElla)
+
+	nlRule.Family = unix.AF_INET
+	if !rule.Source.Addr().Unmap().Is4() {
+		nlRule.Family = unix.AF_INET6
+	}
+
+	return nlRule
+}
+
+// AddSourceRule installs "from <source> lookup <table>". Callers diff
+// against ListManagedSourceRules first; the kernel rejects duplicates.
+func (rk *RealKernel) AddSourceRule(rule SourceRule) error {
+	if err := netlink.RuleAdd(sourceRuleToNetlink(rule)); err != nil {
+		return fmt.Errorf("failed to add rule from %s lookup %d: %v", rule.Source, rule.Table, err)
+	}
+
+	logger.EllaLog.Debug("Added source rule", zap.String("source", rule.Source.String()), zap.Int("table", rule.Table))
+
+	return nil
+}
+
+// DeleteSourceRule removes an Ella-owned source rule.
+func (rk *RealKernel) DeleteSourceRule(rule SourceRule) error {
+	if err := netlink.RuleDel(sourceRuleToNetlink(rule)); err != nil {
+		return fmt.Errorf("failed to delete rule from %s lookup %d: %v", rule.Source, rule.Table, err)
+	}
+
+	return nil
+}
+
+// ListManagedSourceRules returns every policy rule carrying Ella's
+// protocol marker.
+func (rk *RealKernel) ListManagedSourceRules() ([]SourceRule, error) {
+	var result []SourceRule
+
+	for _, af := range []int{unix.AF_INET, unix.AF_INET6} {
+		rules, err := netlink.RuleList(af)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list rules: %v", err)
+		}
+
+		for _, r := range rules {
+			if r.Protocol != uint8(rtProtoElla) || r.Src == nil {
+				continue
+			}
+
+			src, ok := prefixFromIPNet(r.Src)
+			if !ok {
+				continue
+			}
+
+			result = append(result, SourceRule{Source: src, Table: r.Table})
+		}
+	}
+
+	return result, nil
+}
@@ -32,6 +32,11 @@ var (
 	NetworkLog  *zap.Logger
 	RaftLog     *zap.Logger
 	LmfLog      *zap.Logger
+	DNSLog      *zap.Logger
+	AcctLog     *zap.Logger
+	ChargingLog *zap.Logger
+	PolicyLog   *zap.Logger
+	ExposureLog *zap.Logger
 
 	atomicLevel zap.AtomicLevel
 
@@ -99,6 +104,11 @@ func ConfigureLogging(systemLevel, systemOutput, systemFilePath, auditOutput, au
 	SessionsLog = log.With(zap.String("component", "Sessions"))
 	RaftLog = log.With(zap.String("component", "Raft"))
 	LmfLog = log.With(zap.String("component", "LMF"))
+	DNSLog = log.With(zap.String("component", "DNS"))
+	AcctLog = log.With(zap.String("component", "Accounting"))
+	ChargingLog = log.With(zap.String("component", "Charging"))
+	PolicyLog = log.With(zap.String("component", "PolicyControl"))
+	ExposureLog = log.With(zap.String("component", "Exposure"))
 
 	return nil
 }
@@ -220,6 +230,9 @@ type NetworkProtocol string
 const (
 	NGAPNetworkProtocol NetworkProtocol = "NGAP"
 	S1APNetworkProtocol NetworkProtocol = "S1AP"
+	// GTPUNetworkProtocol marks N3 / S1-U path events: path failures and
+	// recoveries seen through echo, and Error Indications from a radio.
+	GTPUNetworkProtocol NetworkProtocol = "GTP-U"
 )
 
 func LogNetworkEvent(
@@ -244,14 +257,14 @@ func LogNetworkEvent(
 	}
 
 	// Count every signaling message independently of whether network event logging
-	// is configured. NGAP is 5G, S1AP is 4G.
-	rat := metrics.RAT5G
-	if protocol == S1APNetworkProtocol {
-		rat = metrics.RAT4G
+	// is configured. NGAP is 5G, S1AP is 4G; GTP-U path events are not signaling.
+	switch protocol {
+	case NGAPNetworkProtocol:
+		metrics.SignalingMessage(metrics.RAT5G, string(dir), messageType)
+	case S1APNetworkProtocol:
+		metrics.SignalingMessage(metrics.RAT4G, string(dir), messageType)
 	}
 
-	metrics.SignalingMessage(rat, string(dir), messageType)
-
 	if NetworkLog == nil {
 		return
 	}
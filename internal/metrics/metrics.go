@@ -6,7 +6,12 @@
 // (5G) and the MME (4G). Single-NF metrics stay in their own packages.
 package metrics
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"strconv"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 // RAT label values distinguishing the radio access technology a metric belongs to.
 const (
@@ -23,6 +28,7 @@ const (
 var (
 	signalingMessages    *prometheus.CounterVec
 	registrationAttempts *prometheus.CounterVec
+	quotaRejections      *prometheus.CounterVec
 )
 
 // RegisterMetrics registers the cross-NF metrics. Called once at startup.
@@ -37,7 +43,12 @@ func RegisterMetrics() {
 		Help: "Total UE registration (5G) and attach/tracking-area-update (4G) attempts by RAT, type, and result.",
 	}, []string{"rat", "type", "result"})
 
-	prometheus.MustRegister(signalingMessages, registrationAttempts)
+	quotaRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "app_quota_rejections_total",
+		Help: "Total registrations and sessions refused by an admission quota, by quota.",
+	}, []string{"quota"})
+
+	prometheus.MustRegister(signalingMessages, registrationAttempts, quotaRejections)
 }
 
 // RegisterRadioGauges registers the connected-radio and registered-subscriber
@@ -70,6 +81,38 @@ func RegisterRadioGauges(radios5G, subscribers5G, radios4G, subscribers4G func()
 	}))
 }
 
+// RegisterSliceGauges registers the registered-subscriber gauge broken down by
+// network slice. The callback tallies the UEs the AMF allows on each slice; nil
+// reports none.
+func RegisterSliceGauges(subscribers func() map[models.Snssai]int) {
+	subscribersDesc := prometheus.NewDesc(
+		"app_slice_registered_subscribers",
+		"Number of subscribers currently registered in Ella Core on each network slice.",
+		[]string{"sst", "sd"},
+		nil,
+	)
+
+	prometheus.MustRegister(prometheus.CollectorFunc(func(ch chan<- prometheus.Metric) {
+		if subscribers == nil {
+			return
+		}
+
+		for snssai, n := range subscribers() {
+			ch <- prometheus.MustNewConstMetric(subscribersDesc, prometheus.GaugeValue, float64(n), strconv.Itoa(int(snssai.Sst)), snssai.Sd)
+		}
+	}))
+}
+
+// QuotaRejection records one registration or session refused by quota. Safe to
+// call before RegisterMetrics (no-op).
+func QuotaRejection(quota models.Quota) {
+	if quotaRejections == nil {
+		return
+	}
+
+	quotaRejections.WithLabelValues(string(quota)).Inc()
+}
+
 func countOrZero(f func() int) int {
 	if f == nil {
 		return 0
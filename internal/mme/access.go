@@ -6,11 +6,16 @@ package mme
 import (
 	"context"
 	"fmt"
+	"time"
 )
 
+// Access is what a subscriber may use: the RATs its profile allows, unless
+// its lifecycle state refuses registration, and whether it is barred from
+// holding PDN connections.
 type Access struct {
-	Allow4G bool
-	Allow5G bool
+	Allow4G    bool
+	Allow5G    bool
+	DataBarred bool
 }
 
 func ResolveAccess(ctx context.Context, m *MME, imsi string) (Access, error) {
@@ -24,7 +29,18 @@ func ResolveAccess(ctx context.Context, m *MME, imsi string) (Access, error) {
 		return Access{}, fmt.Errorf("get profile: %w", err)
 	}
 
-	return Access{Allow4G: profile.Allow4G, Allow5G: profile.Allow5G}, nil
+	state, err := m.Bearer.SubscriberStateAt(ctx, imsi, time.Now())
+	if err != nil {
+		return Access{}, fmt.Errorf("get subscriber state: %w", err)
+	}
+
+	registers := state.AllowsRegistration()
+
+	return Access{
+		Allow4G:    profile.Allow4G && registers,
+		Allow5G:    profile.Allow5G && registers,
+		DataBarred: !state.AllowsData(),
+	}, nil
 }
 
 func (ue *UeContext) SetAccess(a Access) {
@@ -65,7 +65,7 @@ func (m *MME) DeactivateBearer(ctx context.Context, ue *UeContext, p *PdnConnect
 
 	if releaseOnly {
 		write = func(wire []byte) error {
-			m.sendERABRelease(ctx, ueConn, p, wire)
+			m.sendERABRelease(ctx, ueConn, p.Ebi, wire)
 
 			return nil
 		}
@@ -109,13 +109,13 @@ func (m *MME) DisconnectBearer(ctx context.Context, ue *UeContext, p *PdnConnect
 	m.DeactivateBearer(ctx, ue, p, esmCause, pti, true)
 }
 
-// sendERABRelease releases a UE's E-RAB at the eNB while the UE stays connected,
-// carrying the DEACTIVATE EPS BEARER CONTEXT REQUEST in the NAS-PDU so the eNB
-// both releases the radio bearer and delivers the NAS (TS 36.413 §8.2.3).
-func (m *MME) sendERABRelease(ctx context.Context, ueConn *UeConn, p *PdnConnection, naspdu []byte) {
+// sendERABRelease releases a UE's E-RAB ebi at the eNB while the UE stays
+// connected, carrying the DEACTIVATE EPS BEARER CONTEXT REQUEST in the NAS-PDU
+// so the eNB both releases the radio bearer and delivers the NAS (TS 36.413 §8.2.3).
+func (m *MME) sendERABRelease(ctx context.Context, ueConn *UeConn, ebi uint8, naspdu []byte) {
 	cmd := &s1ap.ERABReleaseCommand{
 		ERABToBeReleased: []s1ap.ERABItem{{
-			ERABID: s1ap.ERABID(p.Ebi),
+			ERABID: s1ap.ERABID(ebi),
 			Cause:  CauseNASNormalRelease,
 		}},
 		NASPDU: s1ap.NASPDU(naspdu),
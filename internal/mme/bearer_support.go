@@ -6,21 +6,27 @@ package mme
 import (
 	"context"
 	"slices"
+	"time"
 
 	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/nas/eps"
 	"github.com/ellanetworks/core/s1ap"
 )
 
 // ActiveEBIs returns the EPS bearer identities of the UE's established PDN
-// connections, sorted.
+// connections and of the dedicated bearers whose E-RAB is up, sorted.
 func (ue *UeContext) ActiveEBIs() []uint8 {
 	ue.mu.Lock()
 	defer ue.mu.Unlock()
 
 	out := make([]uint8, 0, len(ue.Pdns))
-	for ebi := range ue.Pdns {
+	for ebi, p := range ue.Pdns {
 		out = append(out, ebi)
+
+		if p.Dedicated != nil && p.Dedicated.RadioUp {
+			out = append(out, p.Dedicated.Ebi)
+		}
 	}
 
 	slices.Sort(out)
@@ -41,6 +47,20 @@ type bearerStore interface {
 	GetDataNetworkByID(ctx context.Context, id string) (*db.DataNetwork, error)
 	GetNetworkSliceByID(ctx context.Context, id string) (*db.NetworkSlice, error)
 	GetOperator(ctx context.Context) (*db.Operator, error)
+	// PolicyInArea applies the policy's location variant for the area the UE
+	// is served in, if any, and names the policy whose network rules apply
+	// there.
+	PolicyInArea(ctx context.Context, policy *db.Policy, area models.ServingArea) (located *db.Policy, rulesPolicyID string, err error)
+	// EffectiveSessionAmbr applies the policy's Session-AMBR schedule, if any.
+	EffectiveSessionAmbr(ctx context.Context, policy *db.Policy, now time.Time) (uplink, downlink string, err error)
+	// EffectiveDNS is the data network's embedded DNS forwarder when it has
+	// one, its own DNS server otherwise.
+	EffectiveDNS(ctx context.Context, dn *db.DataNetwork) (string, error)
+	// ActiveSubscriberAmbrOverride is the subscriber's unexpired AMBR
+	// override, nil when it has none.
+	ActiveSubscriberAmbrOverride(ctx context.Context, imsi string, now time.Time) (*db.SubscriberAmbrOverride, error)
+	// SubscriberStateAt is the subscriber's lifecycle state at now.
+	SubscriberStateAt(ctx context.Context, imsi string, now time.Time) (db.SubscriberState, error)
 	// NodeID is the cluster node identity, used to make each HA node's MME Code
 	// (and hence its GUMMEI) distinct.
 	NodeID() int
@@ -9,6 +9,7 @@ import (
 	"net/netip"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/db"
@@ -68,14 +69,18 @@ type fakeSessionManager struct {
 	ambrUpdated     bool
 	ambrUplink      models.BitRate // records the last UpdateEPSSessionAMBR uplink value
 	ambrDownlink    models.BitRate
-	ambrErr         error // when set, UpdateEPSSessionAMBR fails with it
-	framedChanged   bool  // FramedRoutesChanged returns this
-	framedErr       error // when set, FramedRoutesChanged fails with it
-	staticIPChanged bool  // StaticIPChanged returns this
-	staticIPErr     error // when set, StaticIPChanged fails with it
+	ambrErr         error  // when set, UpdateEPSSessionAMBR fails with it
+	rulesPolicyID   string // records the last UpdateEPSSessionRules policy
+	framedChanged   bool   // FramedRoutesChanged returns this
+	framedErr       error  // when set, FramedRoutesChanged fails with it
+	staticIPChanged bool   // StaticIPChanged returns this
+	staticIPErr     error  // when set, StaticIPChanged fails with it
 
 	suppressCalls         int // counts HandleEPSPagingFailure calls
 	clearSuppressionCalls int // counts ClearEPSPagingSuppression calls
+
+	dedicatedUp       []models.FTEID // records the eNB F-TEID of each DedicatedBearerUp
+	dedicatedReleased []string       // records the reason of each DedicatedBearerReleased
 }
 
 type idleEPSTransfer struct {
@@ -137,6 +142,12 @@ func (f *fakeSessionManager) ModifyEPSSession(_ context.Context, _ string, _ uin
 	return nil
 }
 
+func (f *fakeSessionManager) UpdateEPSSessionRules(_ context.Context, _ string, policyID string) error {
+	f.rulesPolicyID = policyID
+
+	return nil
+}
+
 func (f *fakeSessionManager) UpdateEPSSessionAMBR(_ context.Context, _ string, ambrUplink, ambrDownlink models.BitRate) error {
 	if f.ambrErr != nil {
 		return f.ambrErr
@@ -179,6 +190,16 @@ func (f *fakeSessionManager) StaticIPChanged(_ context.Context, _ string) (bool,
 	return f.staticIPChanged, f.staticIPErr
 }
 
+func (f *fakeSessionManager) DedicatedBearerUp(_ context.Context, _ string, enb models.FTEID) error {
+	f.dedicatedUp = append(f.dedicatedUp, enb)
+
+	return nil
+}
+
+func (f *fakeSessionManager) DedicatedBearerReleased(_ context.Context, _, reason string) {
+	f.dedicatedReleased = append(f.dedicatedReleased, reason)
+}
+
 // fakeBearerStore resolves a fixed default-bearer QoS (QCI 9, APN "internet",
 // 1 Gbps UE-AMBR) for any subscriber.
 type fakeBearerStore struct{}
@@ -195,6 +216,26 @@ func (fakeBearerStore) GetDefaultPolicyByProfile(_ context.Context, _ string) (*
 	return &db.Policy{Var5qi: 9, Arp: 15, SliceID: "test-slice", DataNetworkID: "test-dn", IsDefault: true, SessionAmbrUplink: "100 Mbps", SessionAmbrDownlink: "200 Mbps"}, nil
 }
 
+func (fakeBearerStore) PolicyInArea(_ context.Context, pol *db.Policy, _ models.ServingArea) (*db.Policy, string, error) {
+	return pol, pol.ID, nil
+}
+
+func (fakeBearerStore) EffectiveSessionAmbr(_ context.Context, pol *db.Policy, _ time.Time) (string, string, error) {
+	return pol.SessionAmbrUplink, pol.SessionAmbrDownlink, nil
+}
+
+func (fakeBearerStore) EffectiveDNS(_ context.Context, dn *db.DataNetwork) (string, error) {
+	return dn.DNS, nil
+}
+
+func (fakeBearerStore) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return nil, nil
+}
+
+func (fakeBearerStore) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateActive, nil
+}
+
 func (fakeBearerStore) ListPoliciesByProfile(_ context.Context, _ string) ([]db.Policy, error) {
 	return []db.Policy{
 		{Var5qi: 9, Arp: 15, SliceID: "test-slice", DataNetworkID: "test-dn", IsDefault: true, SessionAmbrUplink: "100 Mbps", SessionAmbrDownlink: "200 Mbps"},
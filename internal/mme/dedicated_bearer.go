@@ -0,0 +1,460 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package mme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ellanetworks/core/internal/guard"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/nas/eps"
+	"github.com/ellanetworks/core/s1ap"
+	"go.uber.org/zap"
+)
+
+// DedicatedBearer is a dedicated EPS bearer the network activated on a PDN
+// connection for the dedicated QoS flow of its session (TS 23.401 §5.4.1).
+// Its uplink shares the default bearer's S-GW endpoint; the UPF tells the
+// flow's traffic apart by its packet filter, as the UE does by the TFT.
+type DedicatedBearer struct {
+	Ebi      uint8
+	EnbFTEID models.FTEID // eNB S1-U endpoint of its E-RAB
+
+	// RadioUp and Accepted record the E-RAB setup and the UE's ACTIVATE
+	// DEDICATED EPS BEARER CONTEXT ACCEPT, which arrive in either order. The
+	// bearer carries traffic once both are in.
+	RadioUp  bool
+	Accepted bool
+	// Deactivating is set while a deactivation is in flight.
+	Deactivating bool
+
+	// guard supervises the bearer's outstanding activation or deactivation
+	// (T3485/T3495).
+	guard guard.Guard
+}
+
+// dedicatedBearerQCI is the QCI of a GBR 5QI that has a standardized EPS
+// counterpart (TS 23.203 table 6.1.7).
+func dedicatedBearerQCI(fiveQI int32) (uint8, bool) {
+	switch fiveQI {
+	case 1, 2, 3, 4, 65, 66, 67, 75:
+		return uint8(fiveQI), true
+	default:
+		return 0, false
+	}
+}
+
+// dedicatedLocked returns the PDN connection whose dedicated bearer has EPS
+// bearer identity ebi, nil if none has. Caller holds ue.mu.
+func (ue *UeContext) dedicatedLocked(ebi uint8) *PdnConnection {
+	for _, p := range ue.Pdns {
+		if p.Dedicated != nil && p.Dedicated.Ebi == ebi {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// LookupDedicatedBearer returns the PDN connection and the dedicated bearer
+// with EPS bearer identity ebi under the lock, nils if the UE has none.
+func (m *MME) LookupDedicatedBearer(ue *UeContext, ebi uint8) (*PdnConnection, *DedicatedBearer) {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	p := ue.dedicatedLocked(ebi)
+	if p == nil {
+		return nil, nil
+	}
+
+	return p, p.Dedicated
+}
+
+// ActivateDedicatedBearer adds a dedicated bearer for flow to the PDN
+// connection of EPS session ref, whose default bearer is ebi (TS 24.301
+// §6.4.2). The E-RAB Setup Request carries the ACTIVATE DEDICATED EPS BEARER
+// CONTEXT REQUEST; the SMF is told the bearer is up once the eNB has set it
+// up and the UE accepted it, and that it is released if either refuses.
+func (m *MME) ActivateDedicatedBearer(ctx context.Context, imsi string, ebi uint8, ref string, flow models.GBRQosFlow) error {
+	qci, ok := dedicatedBearerQCI(flow.Var5qi)
+	if !ok {
+		return fmt.Errorf("5QI %d has no EPS bearer QCI", flow.Var5qi)
+	}
+
+	rates, err := eps.GBRBitRates(flow.MFBRUplink.Bps(), flow.MFBRDownlink.Bps(), flow.GFBRUplink.Bps(), flow.GFBRDownlink.Bps())
+	if err != nil {
+		return err
+	}
+
+	pf, err := eps.RemoteTFTPacketFilter(1, eps.TFTBidirectional, 1, flow.Filter.RemotePrefix, flow.Filter.Protocol, flow.Filter.PortLow, flow.Filter.PortHigh)
+	if err != nil {
+		return err
+	}
+
+	tft, err := eps.NewTFT(pf)
+	if err != nil {
+		return err
+	}
+
+	ue, ok := m.LookupUeByIMSI(imsi)
+	if !ok {
+		return fmt.Errorf("no UE context for IMSI %s", imsi)
+	}
+
+	ueConn := ue.Conn()
+	if ueConn == nil {
+		return fmt.Errorf("UE %s has no S1 connection", imsi)
+	}
+
+	ue.mu.Lock()
+
+	p, held := ue.Pdns[ebi]
+
+	switch {
+	case !held || p.SessionRef != ref:
+		ue.mu.Unlock()
+		return fmt.Errorf("UE %s holds no PDN connection for session %q", imsi, ref)
+	case p.Deactivating || p.Dedicated != nil:
+		ue.mu.Unlock()
+		return fmt.Errorf("PDN connection of session %q cannot take a dedicated bearer now", ref)
+	}
+
+	d := &DedicatedBearer{Ebi: ue.allocateEBI()}
+	if d.Ebi == 0 {
+		ue.mu.Unlock()
+		return errors.New("no free EPS bearer identity")
+	}
+
+	p.Dedicated = d
+	sgw, sgwIPv6 := p.SgwFTEID, p.SgwN3IPv6
+	arp := p.Arp
+	ue.mu.Unlock()
+
+	if flow.Arp != nil {
+		arp = uint8(flow.Arp.PriorityLevel)
+	}
+
+	abandon := func(err error) error {
+		ue.mu.Lock()
+		if p.Dedicated == d {
+			p.Dedicated = nil
+		}
+		ue.mu.Unlock()
+
+		return err
+	}
+
+	plain, err := (&eps.ActivateDedicatedEPSBearerContextRequest{
+		EPSBearerIdentity:       eps.EPSBearerIdentity(d.Ebi),
+		LinkedEPSBearerIdentity: eps.EPSBearerIdentity(ebi),
+		EPSQoS:                  eps.EPSQoS{QCI: qci, BitRates: rates},
+		TFT:                     tft,
+	}).MarshalBinary()
+	if err != nil {
+		return abandon(fmt.Errorf("build Activate Dedicated EPS Bearer Context Request: %w", err))
+	}
+
+	sgwTLA, err := models.EncodeTransportLayerAddress(sgw.Addr, sgwIPv6)
+	if err != nil {
+		return abandon(fmt.Errorf("failed to encode S-GW transport layer address: %w", err))
+	}
+
+	req := &s1ap.ERABSetupRequest{
+		ERABToBeSetup: []s1ap.ERABToBeSetupItemBearerSUReq{{
+			ERABID: s1ap.ERABID(d.Ebi),
+			QoS: s1ap.ERABLevelQoSParameters{
+				QCI: s1ap.QCI(qci),
+				ARP: BearerARP(arp),
+				GBR: &s1ap.GBRQosInformation{
+					MaximumBitrateDL:    s1ap.BitRate(flow.MFBRDownlink.Bps()),
+					MaximumBitrateUL:    s1ap.BitRate(flow.MFBRUplink.Bps()),
+					GuaranteedBitrateDL: s1ap.BitRate(flow.GFBRDownlink.Bps()),
+					GuaranteedBitrateUL: s1ap.BitRate(flow.GFBRUplink.Bps()),
+				},
+			},
+			TransportLayerAddress: s1ap.TransportLayerAddress(sgwTLA),
+			GTPTEID:               s1ap.GTPTEID(sgw.TEID),
+		}},
+	}
+
+	var writeErr error
+
+	if err := ueConn.SendProtected(plain, eps.SHTIntegrityProtectedCiphered, func(wire []byte) error {
+		req.ERABToBeSetup[0].NASPDU = s1ap.NASPDU(wire)
+		writeErr = ueConn.SendERABSetup(ctx, req)
+
+		return writeErr
+	}); err != nil {
+		if writeErr == nil {
+			ReportProtectFailure(ctx, ueConn, "Activate Dedicated EPS Bearer Context Request", err)
+		}
+
+		return abandon(fmt.Errorf("send E-RAB Setup Request: %w", err))
+	}
+
+	m.armESMGuardOn(ue, &d.guard, "Activate Dedicated EPS Bearer Context Request", plain, eps.SHTIntegrityProtectedCiphered, func() {
+		m.DeactivateDedicatedBearerOf(context.Background(), ue, p, d, "the UE did not answer the bearer activation")
+	})
+
+	logger.From(ctx, logger.MmeLog).Info("activating dedicated EPS bearer",
+		zap.String("imsi", imsi), zap.Uint8("ebi", d.Ebi), zap.Uint8("linked-ebi", ebi), zap.Uint8("qci", qci))
+
+	return nil
+}
+
+// DeactivateDedicatedBearer removes the dedicated bearer of the PDN
+// connection of EPS session ref, whose default bearer is ebi. A session
+// without one is left alone.
+func (m *MME) DeactivateDedicatedBearer(ctx context.Context, imsi string, ebi uint8, ref string) error {
+	ue, ok := m.LookupUeByIMSI(imsi)
+	if !ok {
+		return nil
+	}
+
+	ue.mu.Lock()
+	p, held := ue.Pdns[ebi]
+
+	var d *DedicatedBearer
+	if held && p.SessionRef == ref {
+		d = p.Dedicated
+	}
+	ue.mu.Unlock()
+
+	if d == nil {
+		return nil
+	}
+
+	m.DeactivateDedicatedBearerOf(ctx, ue, p, d, "")
+
+	return nil
+}
+
+// DeactivateDedicatedBearerOf asks the UE to deactivate dedicated bearer d
+// of p and the eNB to release its E-RAB (TS 24.301 §6.4.4). A non-empty
+// reason reports the bearer released to the SMF at once; the SMF starting
+// the deactivation passes none. An idle UE loses the bearer locally.
+func (m *MME) DeactivateDedicatedBearerOf(ctx context.Context, ue *UeContext, p *PdnConnection, d *DedicatedBearer, reason string) {
+	ueConn := ue.Conn()
+
+	ue.mu.Lock()
+	if p.Dedicated != d || d.Deactivating {
+		ue.mu.Unlock()
+		return
+	}
+
+	d.Deactivating = true
+	ref := p.SessionRef
+	ue.mu.Unlock()
+
+	if reason != "" {
+		m.Session.DedicatedBearerReleased(ctx, ref, reason)
+	}
+
+	if ueConn == nil {
+		m.dropDedicatedBearer(ctx, ue, p, d, "")
+		return
+	}
+
+	plain, err := (&eps.DeactivateEPSBearerContextRequest{
+		EPSBearerIdentity: eps.EPSBearerIdentity(d.Ebi),
+		Cause:             eps.ESMCauseRegularDeactivation,
+	}).MarshalBinary()
+	if err != nil {
+		logger.From(ctx, logger.MmeLog).Error("failed to build Deactivate EPS Bearer Context Request",
+			zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", d.Ebi), zap.Error(err))
+		m.dropDedicatedBearer(ctx, ue, p, d, "")
+
+		return
+	}
+
+	if err := ueConn.SendProtected(plain, eps.SHTIntegrityProtectedCiphered, func(wire []byte) error {
+		m.sendERABRelease(ctx, ueConn, d.Ebi, wire)
+
+		return nil
+	}); err != nil {
+		ReportProtectFailure(ctx, ueConn, "Deactivate EPS Bearer Context Request", err)
+		m.dropDedicatedBearer(ctx, ue, p, d, "")
+
+		return
+	}
+
+	m.armESMGuardOn(ue, &d.guard, "Deactivate EPS Bearer Context Request", plain, eps.SHTIntegrityProtectedCiphered, func() {
+		m.dropDedicatedBearer(context.Background(), ue, p, d, "")
+	})
+
+	logger.From(ctx, logger.MmeLog).Info("deactivating dedicated EPS bearer",
+		zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", d.Ebi))
+}
+
+// dropDedicatedBearer removes dedicated bearer d from p without signalling.
+// A non-empty reason reports it released to the SMF, unless a deactivation
+// already did.
+func (m *MME) dropDedicatedBearer(ctx context.Context, ue *UeContext, p *PdnConnection, d *DedicatedBearer, reason string) {
+	ue.mu.Lock()
+	if p.Dedicated != d {
+		ue.mu.Unlock()
+		return
+	}
+
+	p.Dedicated = nil
+	report := reason != "" && !d.Deactivating
+	ref := p.SessionRef
+	ue.mu.Unlock()
+
+	d.guard.Stop()
+
+	if report {
+		m.Session.DedicatedBearerReleased(ctx, ref, reason)
+	}
+}
+
+// stopDedicatedBearer stops the procedure on p's dedicated bearer, for a PDN
+// connection being released with its session.
+func (m *MME) stopDedicatedBearer(ue *UeContext, p *PdnConnection) {
+	ue.mu.Lock()
+	d := p.Dedicated
+	p.Dedicated = nil
+	ue.mu.Unlock()
+
+	if d != nil {
+		d.guard.Stop()
+	}
+}
+
+// dropAllDedicatedBearers removes the dedicated bearers of a UE going idle:
+// the next service request sets up only the default bearers, and the UE
+// drops the EPS bearer contexts left without a radio bearer.
+func (m *MME) dropAllDedicatedBearers(ctx context.Context, ue *UeContext) {
+	for _, p := range m.SnapshotPDNs(ue) {
+		ue.mu.Lock()
+		d := p.Dedicated
+		ue.mu.Unlock()
+
+		if d != nil {
+			m.dropDedicatedBearer(ctx, ue, p, d, "the UE went idle")
+		}
+	}
+}
+
+// DedicatedEBIs returns the EPS bearer identities of the dedicated bearers
+// the UE accepted, sorted.
+func (m *MME) DedicatedEBIs(ue *UeContext) []uint8 {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	var out []uint8
+
+	for _, p := range ue.Pdns {
+		if p.Dedicated != nil && p.Dedicated.Accepted {
+			out = append(out, p.Dedicated.Ebi)
+		}
+	}
+
+	slices.Sort(out)
+
+	return out
+}
+
+// DedicatedBearerAccepted records the UE's ACTIVATE DEDICATED EPS BEARER
+// CONTEXT ACCEPT for bearer ebi, reporting false if no activation awaits it.
+func (m *MME) DedicatedBearerAccepted(ctx context.Context, ue *UeContext, ebi uint8) bool {
+	p, d := m.LookupDedicatedBearer(ue, ebi)
+	if d == nil {
+		return false
+	}
+
+	ue.mu.Lock()
+	d.Accepted = true
+	radioUp, deactivating := d.RadioUp, d.Deactivating
+	ue.mu.Unlock()
+
+	if deactivating {
+		return true
+	}
+
+	d.guard.Stop()
+
+	if radioUp {
+		m.dedicatedBearerUp(ctx, ue, p, d)
+	}
+
+	return true
+}
+
+// DedicatedBearerRejected abandons bearer ebi, which the UE refused: the eNB
+// releases its E-RAB and the SMF is told (TS 24.301 §6.4.2.4).
+func (m *MME) DedicatedBearerRejected(ctx context.Context, ue *UeContext, ebi uint8, cause eps.ESMCause) {
+	p, d := m.LookupDedicatedBearer(ue, ebi)
+	if d == nil {
+		return
+	}
+
+	if ueConn := ue.Conn(); ueConn != nil {
+		cmd := &s1ap.ERABReleaseCommand{
+			ERABToBeReleased: []s1ap.ERABItem{{ERABID: s1ap.ERABID(ebi), Cause: CauseNASNormalRelease}},
+		}
+
+		if err := ueConn.SendERABRelease(ctx, cmd); err != nil {
+			logger.From(ctx, logger.MmeLog).Error("failed to send E-RAB Release Command", zap.Error(err))
+		}
+	}
+
+	m.dropDedicatedBearer(ctx, ue, p, d, fmt.Sprintf("the UE rejected the bearer with ESM cause %s", cause))
+}
+
+// DedicatedBearerDeactivated completes the deactivation of bearer ebi,
+// reporting false if the UE has no such bearer.
+func (m *MME) DedicatedBearerDeactivated(ctx context.Context, ue *UeContext, ebi uint8) bool {
+	p, d := m.LookupDedicatedBearer(ue, ebi)
+	if d == nil {
+		return false
+	}
+
+	m.dropDedicatedBearer(ctx, ue, p, d, "the UE deactivated the bearer")
+
+	return true
+}
+
+// dedicatedRadioUp records the eNB endpoint of the E-RAB of dedicated bearer
+// d, set up or switched to a new eNB.
+func (m *MME) dedicatedRadioUp(ctx context.Context, ue *UeContext, p *PdnConnection, d *DedicatedBearer, enb models.FTEID) {
+	ue.mu.Lock()
+	d.RadioUp = true
+	d.EnbFTEID = enb
+	accepted := d.Accepted
+	ue.mu.Unlock()
+
+	if accepted {
+		m.dedicatedBearerUp(ctx, ue, p, d)
+	}
+}
+
+// dedicatedBearerUp moves the downlink of the dedicated flow onto bearer d;
+// a bearer the SMF cannot use is deactivated.
+func (m *MME) dedicatedBearerUp(ctx context.Context, ue *UeContext, p *PdnConnection, d *DedicatedBearer) {
+	ue.mu.Lock()
+	live := p.Dedicated == d && !d.Deactivating
+	ref, enb := p.SessionRef, d.EnbFTEID
+	ue.mu.Unlock()
+
+	if !live {
+		return
+	}
+
+	if err := m.Session.DedicatedBearerUp(ctx, ref, enb); err != nil {
+		logger.From(ctx, logger.MmeLog).Warn("failed to move a dedicated flow onto its EPS bearer; deactivating it",
+			zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", d.Ebi), zap.Error(err))
+
+		m.DeactivateDedicatedBearerOf(ctx, ue, p, d, "the user plane could not carry the bearer")
+
+		return
+	}
+
+	logger.From(ctx, logger.MmeLog).Info("dedicated EPS bearer active",
+		zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", d.Ebi))
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package mme
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func dedicatedTestFlow() models.GBRQosFlow {
+	return models.GBRQosFlow{
+		QosData:      models.QosData{Var5qi: 1},
+		GFBRUplink:   models.MustParseBitRate("64 Kbps"),
+		GFBRDownlink: models.MustParseBitRate("64 Kbps"),
+		MFBRUplink:   models.MustParseBitRate("128 Kbps"),
+		MFBRDownlink: models.MustParseBitRate("128 Kbps"),
+		Filter:       models.QosFlowFilter{RemotePrefix: netip.MustParsePrefix("198.51.100.0/24"), Protocol: 17},
+	}
+}
+
+// TS 23.401 §5.4.1
+func TestDedicatedBearer(t *testing.T) {
+	const ref = "imsi-001010000000001-5#1"
+
+	setup := func(t *testing.T) (*MME, *UeContext, *PdnConnection, *captureConn) {
+		t.Helper()
+
+		m := newTestMME(t)
+		ue, cc := securedUE(t, m)
+
+		p := testPDN(ue)
+		p.SessionRef = ref
+		p.SgwFTEID = models.FTEID{TEID: 0x10, Addr: netip.MustParseAddr("203.0.113.1")}
+
+		t.Cleanup(func() { m.stopDedicatedBearer(ue, p) })
+
+		return m, ue, p, cc
+	}
+
+	t.Run("the bearer is up once the eNB set it up and the UE accepted it", func(t *testing.T) {
+		m, ue, p, cc := setup(t)
+		sm := m.Session.(*fakeSessionManager)
+
+		sent := cc.count()
+
+		if err := m.ActivateDedicatedBearer(context.Background(), ue.IMSI(), DefaultERABID, ref, dedicatedTestFlow()); err != nil {
+			t.Fatalf("ActivateDedicatedBearer: %v", err)
+		}
+
+		if cc.count() != sent+1 {
+			t.Fatalf("sent %d S1AP messages, want the E-RAB Setup Request", cc.count()-sent)
+		}
+
+		if p.Dedicated == nil || p.Dedicated.Ebi != DefaultERABID+1 {
+			t.Fatalf("dedicated bearer = %+v, want EBI %d", p.Dedicated, DefaultERABID+1)
+		}
+
+		ebi := p.Dedicated.Ebi
+		enb := models.FTEID{TEID: 0x66, Addr: netip.MustParseAddr("203.0.113.3")}
+
+		m.dedicatedRadioUp(context.Background(), ue, p, p.Dedicated, enb)
+
+		if len(sm.dedicatedUp) != 0 {
+			t.Fatal("the SMF was told the bearer is up before the UE accepted it")
+		}
+
+		if !m.DedicatedBearerAccepted(context.Background(), ue, ebi) {
+			t.Fatal("the accept found no activation")
+		}
+
+		if len(sm.dedicatedUp) != 1 || sm.dedicatedUp[0] != enb {
+			t.Errorf("DedicatedBearerUp calls = %+v, want one toward the eNB", sm.dedicatedUp)
+		}
+
+		if got := ue.ActiveEBIs(); len(got) != 2 || got[1] != ebi {
+			t.Errorf("active EBIs = %v, want the default and the dedicated bearer", got)
+		}
+	})
+
+	t.Run("the UE rejecting the bearer reports it released", func(t *testing.T) {
+		m, ue, p, _ := setup(t)
+		sm := m.Session.(*fakeSessionManager)
+
+		if err := m.ActivateDedicatedBearer(context.Background(), ue.IMSI(), DefaultERABID, ref, dedicatedTestFlow()); err != nil {
+			t.Fatalf("ActivateDedicatedBearer: %v", err)
+		}
+
+		m.DedicatedBearerRejected(context.Background(), ue, p.Dedicated.Ebi, 0)
+
+		if p.Dedicated != nil {
+			t.Error("the rejected bearer was kept")
+		}
+
+		if len(sm.dedicatedReleased) != 1 {
+			t.Errorf("DedicatedBearerReleased calls = %v, want one", sm.dedicatedReleased)
+		}
+	})
+
+	t.Run("the SMF releasing the bearer is not reported back", func(t *testing.T) {
+		m, ue, p, cc := setup(t)
+		sm := m.Session.(*fakeSessionManager)
+
+		if err := m.ActivateDedicatedBearer(context.Background(), ue.IMSI(), DefaultERABID, ref, dedicatedTestFlow()); err != nil {
+			t.Fatalf("ActivateDedicatedBearer: %v", err)
+		}
+
+		ebi := p.Dedicated.Ebi
+		sent := cc.count()
+
+		if err := m.DeactivateDedicatedBearer(context.Background(), ue.IMSI(), DefaultERABID, ref); err != nil {
+			t.Fatalf("DeactivateDedicatedBearer: %v", err)
+		}
+
+		if cc.count() != sent+1 {
+			t.Fatalf("sent %d S1AP messages, want the E-RAB Release Command", cc.count()-sent)
+		}
+
+		if !m.DedicatedBearerDeactivated(context.Background(), ue, ebi) {
+			t.Fatal("the deactivation accept found no bearer")
+		}
+
+		if p.Dedicated != nil || len(sm.dedicatedReleased) != 0 {
+			t.Errorf("dedicated = %+v, released reports = %v, want the bearer gone unreported", p.Dedicated, sm.dedicatedReleased)
+		}
+	})
+
+	t.Run("a 5QI with no EPS counterpart is refused", func(t *testing.T) {
+		m, ue, p, _ := setup(t)
+
+		flow := dedicatedTestFlow()
+		flow.Var5qi = 82
+
+		if err := m.ActivateDedicatedBearer(context.Background(), ue.IMSI(), DefaultERABID, ref, flow); err == nil {
+			t.Error("a delay-critical 5QI was given an EPS bearer")
+		}
+
+		if p.Dedicated != nil {
+			t.Error("a refused activation left a bearer behind")
+		}
+	})
+}
@@ -25,6 +25,11 @@ func (m *MME) DetachUEAfterPathSwitchFailure(ctx context.Context, ue *UeContext)
 }
 
 func (m *MME) DetachSubscriber(ctx context.Context, imsi string) {
+	m.detachSubscriber(ctx, imsi, "subscriber deleted")
+}
+
+// detachSubscriber detaches the subscriber's UE, if attached, for reason.
+func (m *MME) detachSubscriber(ctx context.Context, imsi, reason string) {
 	ue, ok := m.LookupUeByIMSI(imsi)
 	if !ok {
 		return
@@ -33,7 +38,7 @@ func (m *MME) DetachSubscriber(ctx context.Context, imsi string) {
 	ueConn := ue.Conn()
 	if ueConn == nil || !m.UeConnected(ue) {
 		ue.TransitionTo(EMMDeregistered)
-		logger.From(ctx, logger.MmeLog).Info("releasing idle UE on subscriber deletion", zap.String("imsi", imsi))
+		logger.From(ctx, logger.MmeLog).Info("releasing idle UE", zap.String("imsi", imsi), zap.String("reason", reason))
 		m.ReleaseAllSessions(ctx, ue)
 		m.RemoveUe(ue)
 
@@ -41,17 +46,17 @@ func (m *MME) DetachSubscriber(ctx context.Context, imsi string) {
 	}
 
 	if !ue.Secured() {
-		logger.From(ctx, logger.MmeLog).Info("local detach of connected-but-unsecured UE on subscriber deletion",
-			zap.String("imsi", imsi))
-		m.ReleaseUEContextLocally(ue, "subscriber deleted")
+		logger.From(ctx, logger.MmeLog).Info("local detach of connected-but-unsecured UE",
+			zap.String("imsi", imsi), zap.String("reason", reason))
+		m.ReleaseUEContextLocally(ue, reason)
 
 		return
 	}
 
 	ue.TransitionTo(EMMDeregistrationInitiated)
 
-	logger.From(ctx, ueConn.Log).Info("network-initiated detach (subscriber deleted)",
-		zap.String("imsi", imsi))
+	logger.From(ctx, ueConn.Log).Info("network-initiated detach",
+		zap.String("imsi", imsi), zap.String("reason", reason))
 
 	plain, err := (&eps.DetachRequestNetwork{TypeOfDetach: eps.DetachTypeReattachNotRequired}).MarshalBinary()
 	if err != nil {
@@ -4,6 +4,7 @@
 package mme
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -39,9 +40,42 @@ func (c *UeConn) UpdateLocation(cgi s1ap.EUTRANCGI, tai s1ap.TAI) {
 
 	if c.ue != nil {
 		c.ue.mu.Lock()
+		previous := c.ue.Location.ServingArea()
 		c.ue.Location = c.Location
+
+		if c.ue.emmState == EMMRegistered {
+			c.ue.notifyLocked()
+		}
+
 		c.ue.mu.Unlock()
+
+		if previous != (models.ServingArea{}) && previous != c.Location.ServingArea() {
+			c.servingAreaChanged()
+		}
+	}
+}
+
+// servingAreaChanged reconciles the bearers of a UE that moved to another
+// tracking area or cell, whose policy may carry a location variant there.
+// It runs apart from the S1AP handler that reported the move, which it would
+// otherwise hold up on the database and the UE's answer.
+func (c *UeConn) servingAreaChanged() {
+	if c.m == nil || c.m.Session == nil {
+		return
 	}
+
+	go c.m.ReconcileUE(context.Background(), c.ue)
+}
+
+// servingArea is the area of the last location reported for the UE with
+// imsi, the zero area for an unknown UE.
+func (m *MME) servingArea(imsi string) models.ServingArea {
+	ue, ok := m.LookupUeByIMSI(imsi)
+	if !ok {
+		return models.ServingArea{}
+	}
+
+	return ue.GetUserLocation().ServingArea()
 }
 
 // GetUserLocation returns a copy of the UE's user location.
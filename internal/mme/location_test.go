@@ -4,9 +4,13 @@
 package mme
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/udm"
 	"github.com/ellanetworks/core/s1ap"
 )
 
@@ -144,3 +148,53 @@ func TestMMELocationAccessors(t *testing.T) {
 		t.Fatal("IsUERegistered should be false for an unknown SUPI")
 	}
 }
+
+// areaBearerStore caps the Session-AMBR of every policy in tracking area
+// 000007, and puts the rules of policy "site-rules" in force there.
+type areaBearerStore struct {
+	fakeBearerStore
+}
+
+func (areaBearerStore) PolicyInArea(_ context.Context, pol *db.Policy, area models.ServingArea) (*db.Policy, string, error) {
+	if area.TAC != "000007" {
+		return pol, pol.ID, nil
+	}
+
+	capped := *pol
+	capped.SessionAmbrUplink, capped.SessionAmbrDownlink = "5 Mbps", "10 Mbps"
+
+	return &capped, "site-rules", nil
+}
+
+func TestResolveQoSFollowsServingArea(t *testing.T) {
+	m := New(udm.New(newFakeCredStore(), noopKeyResolver), areaBearerStore{}, &fakeSessionManager{})
+	ue := m.NewUe(&captureConn{}, 7)
+	m.RegisterUEForTest(ue, testSubscriber.IMSI)
+
+	qos, err := ResolveQoS(context.Background(), m, testSubscriber.IMSI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qos.SessAmbrDL.String() != "200 Mbps" {
+		t.Fatalf("Session-AMBR downlink with no location = %s, want the policy's 200 Mbps", qos.SessAmbrDL)
+	}
+
+	policyID := qos.PolicyID
+
+	cgi, tai := testCGIAndTAI()
+	ue.Conn().UpdateLocation(cgi, tai)
+
+	qos, err = ResolveQoS(context.Background(), m, testSubscriber.IMSI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if qos.SessAmbrUL.String() != "5 Mbps" || qos.SessAmbrDL.String() != "10 Mbps" {
+		t.Fatalf("Session-AMBR in TAC 000007 = %s/%s, want the variant's 5 Mbps/10 Mbps", qos.SessAmbrUL, qos.SessAmbrDL)
+	}
+
+	if qos.PolicyID != "site-rules" || policyID == "site-rules" {
+		t.Fatalf("rules policy in TAC 000007 = %q (was %q), want the variant's site-rules", qos.PolicyID, policyID)
+	}
+}
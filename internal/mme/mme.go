@@ -34,12 +34,21 @@ type epsSessionManager interface {
 	TransferIdleToEPS(ctx context.Context, supi etsi.SUPI, pduSessionID, epsBearerIdentity uint8, dnn string, snssai *models.Snssai) (models.EPSBearer, error)
 	ModifyEPSSession(ctx context.Context, ref string, ebi uint8, enb models.FTEID) error
 	UpdateEPSSessionAMBR(ctx context.Context, ref string, ambrUplink, ambrDownlink models.BitRate) error
+	// UpdateEPSSessionRules binds session ref to the network rules of policy
+	// policyID, unless it is on them already.
+	UpdateEPSSessionRules(ctx context.Context, ref string, policyID string) error
 	DeactivateEPSSession(ctx context.Context, ref string) error
 	HandleEPSPagingFailure(ctx context.Context, imsi string, ebi uint8) error
 	ClearEPSPagingSuppression(ctx context.Context, imsi string, ebi uint8) error
 	ReleaseEPSSession(ctx context.Context, ref string) error
 	FramedRoutesChanged(ctx context.Context, ref string) (bool, error)
 	StaticIPChanged(ctx context.Context, ref string) (bool, error)
+	// DedicatedBearerUp moves the dedicated QoS flow of session ref onto the
+	// dedicated bearer whose E-RAB ends at enb.
+	DedicatedBearerUp(ctx context.Context, ref string, enb models.FTEID) error
+	// DedicatedBearerReleased reports that the dedicated bearer of session
+	// ref is gone.
+	DedicatedBearerReleased(ctx context.Context, ref string, reason string)
 }
 
 type credentialProvider interface {
@@ -85,6 +94,9 @@ type MME struct {
 	NAS     NASHandler
 	FiveGS  interworking.FiveGSPeer
 
+	// Observer is told of the UEs' transitions; nil tells no one.
+	Observer UEObserver
+
 	// EPSNetworkFeatureSupport is advertised in Attach/TAU Accept (TS 24.301
 	// §9.9.3.12A); nil falls back to the default.
 	EPSNetworkFeatureSupport *eps.NetworkFeatureSupport
@@ -102,6 +102,10 @@ type PdnConnection struct {
 	PendingQCI           uint8
 	PendingARP           uint8
 
+	// Dedicated is the dedicated bearer carrying the session's dedicated QoS
+	// flow, nil when it has none.
+	Dedicated *DedicatedBearer
+
 	// guard supervises this bearer's outstanding ESM procedure (Modify/Deactivate,
 	// T3486/T3495). It is per-bearer because a UE with several PDN connections can
 	// have an ESM procedure outstanding on each at once; the guard invalidates a
@@ -202,6 +206,8 @@ type UeContext struct {
 	// lppaBuf holds an LPPa message for delivery when the UE answers a page.
 	lppaBufMu sync.RWMutex
 	lppaBuf   *LPPaBuffered
+
+	observer UEObserver
 }
 
 // TouchLastSeen records the current time as the UE's most recent uplink NAS
@@ -259,6 +265,9 @@ func (m *MME) CommitUEIdentity(ctx context.Context, ue *UeContext, _ AuthProof)
 	}
 
 	m.UEs[supi] = ue
+	ue.mu.Lock()
+	ue.observer = m.Observer
+	ue.mu.Unlock()
 	m.mu.Unlock()
 
 	// TS 24.301 §5.5.1.2.7 f): a genuine re-attach supersedes the old context and
@@ -349,11 +358,11 @@ func (m *MME) DefaultPDN(ue *UeContext) *PdnConnection {
 }
 
 // allocateEBI returns the lowest free EPS bearer identity in [5,15] for a new
-// PDN connection's default bearer, or 0 if all are in use (TS 24.301: EBI 0-4
-// are reserved, 5-15 are assignable).
+// PDN connection's default bearer or a dedicated bearer, or 0 if all are in
+// use (TS 24.301: EBI 0-4 are reserved, 5-15 are assignable).
 func (ue *UeContext) allocateEBI() uint8 {
 	for ebi := DefaultERABID; ebi <= 15; ebi++ {
-		if _, ok := ue.Pdns[ebi]; !ok {
+		if _, ok := ue.Pdns[ebi]; !ok && ue.dedicatedLocked(ebi) == nil {
 			return ebi
 		}
 	}
@@ -646,6 +655,10 @@ func (m *MME) attachUeConnLocked(ue *UeContext, c *UeConn) (superseded *UeConn)
 	// Becoming connected is activity; refresh liveness at the bind point.
 	ue.TouchLastSeen()
 
+	if superseded == nil {
+		ue.connectionChanged()
+	}
+
 	return superseded
 }
 
@@ -716,6 +729,7 @@ func (m *MME) freeUeConnLocked(ue *UeContext) {
 
 	if old := m.detachConnLocked(ue); old != nil {
 		m.releaseConnIDLocked(uint32(old.MMEUES1APID))
+		ue.connectionChanged()
 	}
 }
 
@@ -65,6 +65,16 @@ func activateDefaultBearer(ctx context.Context, m *mme.MME, ue *mme.UeContext, u
 		return
 	}
 
+	// Every attach opens a PDN connection, which barring refuses
+	// (TS 24.301 §6.5.1.4, ESM cause #8).
+	if access.DataBarred {
+		logger.From(ctx, logger.MmeLog).Info("attach rejected: subscriber barred from data",
+			zap.String("imsi", ue.IMSI()))
+		rejectAttachESM(ctx, m, ue, ueConn, uint8(ue.RequestedPTI), eps.ESMCauseOperatorDeterminedBarring)
+
+		return
+	}
+
 	ue.SetAccess(access)
 
 	qos, err := mme.ResolveAttachQoS(ctx, m, ue)
@@ -110,7 +120,7 @@ func activateDefaultBearer(ctx context.Context, m *mme.MME, ue *mme.UeContext, u
 	if err != nil {
 		logger.From(ctx, logger.MmeLog).Info("attach rejected: default bearer setup failed",
 			zap.String("imsi", ue.IMSI()), zap.Error(err))
-		rejectAttachESM(ctx, m, ue, ueConn, uint8(ue.RequestedPTI), attachBearerRejectCause(ue.RequestedType, err))
+		rejectAttachESMBackoff(ctx, m, ue, ueConn, uint8(ue.RequestedPTI), attachBearerRejectCause(ue.RequestedType, err), attachBearerT3396(err))
 
 		return
 	}
@@ -9,6 +9,7 @@ import (
 	"net/netip"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/db"
@@ -148,6 +149,10 @@ func (f *fakeSessionManager) UpdateEPSSessionAMBR(_ context.Context, _ string, _
 	return nil
 }
 
+func (f *fakeSessionManager) UpdateEPSSessionRules(_ context.Context, _ string, _ string) error {
+	return nil
+}
+
 func (f *fakeSessionManager) DeactivateEPSSession(_ context.Context, _ string) error {
 	f.deactivated = true
 
@@ -176,6 +181,12 @@ func (f *fakeSessionManager) StaticIPChanged(_ context.Context, _ string) (bool,
 	return false, nil
 }
 
+func (f *fakeSessionManager) DedicatedBearerUp(_ context.Context, _ string, _ models.FTEID) error {
+	return nil
+}
+
+func (f *fakeSessionManager) DedicatedBearerReleased(_ context.Context, _, _ string) {}
+
 // fakeBearerStore resolves a fixed default-bearer QoS for any subscriber.
 type erroringSessionManager struct{ fakeSessionManager }
 
@@ -189,6 +200,12 @@ func (barredBearerStore) GetProfileByID(_ context.Context, id string) (*db.Profi
 	return &db.Profile{ID: id, UeAmbrDownlink: "1 Gbps", UeAmbrUplink: "1 Gbps", Allow4G: false, Allow5G: true}, nil
 }
 
+type dataBarredBearerStore struct{ fakeBearerStore }
+
+func (dataBarredBearerStore) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateBarredData, nil
+}
+
 type fakeBearerStore struct{}
 
 func (fakeBearerStore) GetSubscriber(_ context.Context, imsi string) (*db.Subscriber, error) {
@@ -203,6 +220,26 @@ func (fakeBearerStore) GetDefaultPolicyByProfile(_ context.Context, _ string) (*
 	return &db.Policy{Var5qi: 9, Arp: 15, SliceID: "test-slice", DataNetworkID: "test-dn", IsDefault: true, SessionAmbrUplink: "100 Mbps", SessionAmbrDownlink: "200 Mbps"}, nil
 }
 
+func (fakeBearerStore) PolicyInArea(_ context.Context, pol *db.Policy, _ models.ServingArea) (*db.Policy, string, error) {
+	return pol, pol.ID, nil
+}
+
+func (fakeBearerStore) EffectiveSessionAmbr(_ context.Context, pol *db.Policy, _ time.Time) (string, string, error) {
+	return pol.SessionAmbrUplink, pol.SessionAmbrDownlink, nil
+}
+
+func (fakeBearerStore) EffectiveDNS(_ context.Context, dn *db.DataNetwork) (string, error) {
+	return dn.DNS, nil
+}
+
+func (fakeBearerStore) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return nil, nil
+}
+
+func (fakeBearerStore) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateActive, nil
+}
+
 func (fakeBearerStore) ListPoliciesByProfile(_ context.Context, _ string) ([]db.Policy, error) {
 	return []db.Policy{
 		{Var5qi: 9, Arp: 15, SliceID: "test-slice", DataNetworkID: "test-dn", IsDefault: true, SessionAmbrUplink: "100 Mbps", SessionAmbrDownlink: "200 Mbps"},
@@ -224,7 +224,13 @@ func rejectAttach(ctx context.Context, m *mme.MME, ue *mme.UeContext, ueConn *mm
 }
 
 func rejectAttachESM(ctx context.Context, m *mme.MME, ue *mme.UeContext, ueConn *mme.UeConn, pti uint8, esmCause eps.ESMCause) {
-	esm, err := (&eps.PDNConnectivityReject{PTI: nas.ProcedureTransactionIdentity(pti), Cause: esmCause}).MarshalBinary()
+	rejectAttachESMBackoff(ctx, m, ue, ueConn, pti, esmCause, nil)
+}
+
+// rejectAttachESMBackoff rejects the attach with a PDN Connectivity Reject
+// carrying the T3396 back-off; nil omits it.
+func rejectAttachESMBackoff(ctx context.Context, m *mme.MME, ue *mme.UeContext, ueConn *mme.UeConn, pti uint8, esmCause eps.ESMCause, t3396 *nas.GPRSTimer3) {
+	esm, err := (&eps.PDNConnectivityReject{PTI: nas.ProcedureTransactionIdentity(pti), Cause: esmCause, T3396: t3396}).MarshalBinary()
 	if err != nil {
 		logger.From(ctx, logger.MmeLog).Error("failed to build the PDN Connectivity Reject carried by an Attach Reject",
 			zap.String("imsi", ue.IMSI()), zap.Error(err))
@@ -15,6 +15,13 @@ import (
 
 // handleDeactivateBearerAccept finalises an EPS bearer deactivation (TS 24.301 §6.4.4.3).
 func handleDeactivateBearerAccept(ctx context.Context, m *mme.MME, ue *mme.UeContext, accept *eps.DeactivateEPSBearerContextAccept) nasreply.Disposition {
+	if m.DedicatedBearerDeactivated(ctx, ue, uint8(accept.EPSBearerIdentity)) {
+		logger.From(ctx, logger.MmeLog).Info("dedicated EPS bearer deactivated",
+			zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", uint8(accept.EPSBearerIdentity)))
+
+		return nasreply.Handled()
+	}
+
 	p := m.LookupPDN(ue, uint8(accept.EPSBearerIdentity))
 
 	if p == nil {
@@ -0,0 +1,38 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package nas
+
+import (
+	"context"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/internal/nasreply"
+	"github.com/ellanetworks/core/nas/eps"
+	"go.uber.org/zap"
+)
+
+// handleActivateDedicatedBearerAccept records the UE's acceptance of a
+// dedicated bearer (TS 24.301 §6.4.2.3).
+func handleActivateDedicatedBearerAccept(ctx context.Context, m *mme.MME, ue *mme.UeContext, accept *eps.ActivateDedicatedEPSBearerContextAccept) nasreply.Disposition {
+	if !m.DedicatedBearerAccepted(ctx, ue, uint8(accept.EPSBearerIdentity)) {
+		logger.From(ctx, logger.MmeLog).Warn("Activate Dedicated Accept for an unknown EPS bearer",
+			zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", uint8(accept.EPSBearerIdentity)))
+
+		return nasreply.Silent(nasreply.ReasonNoContext)
+	}
+
+	return nasreply.Handled()
+}
+
+// handleActivateDedicatedBearerReject abandons a dedicated bearer the UE
+// refused (TS 24.301 §6.4.2.4).
+func handleActivateDedicatedBearerReject(ctx context.Context, m *mme.MME, ue *mme.UeContext, rej *eps.ActivateDedicatedEPSBearerContextReject) nasreply.Disposition {
+	logger.From(ctx, logger.MmeLog).Info("UE rejected a dedicated EPS bearer",
+		zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", uint8(rej.EPSBearerIdentity)), zap.Stringer("esm-cause", rej.Cause))
+
+	m.DedicatedBearerRejected(ctx, ue, uint8(rej.EPSBearerIdentity), rej.Cause)
+
+	return nasreply.Handled()
+}
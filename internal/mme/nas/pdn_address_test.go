@@ -169,6 +169,36 @@ func TestActivateDefaultBearerRejectsWhen4GNotAllowed(t *testing.T) {
 	parseUEContextReleaseCommand(t, cc.sent[1])
 }
 
+// TestActivateDefaultBearerRejectsWhenDataBarred checks that a subscriber barred
+// from data is refused the attach's default bearer with ESM cause #8 "operator
+// determined barring" (TS 24.301 §6.5.1.4).
+func TestActivateDefaultBearerRejectsWhenDataBarred(t *testing.T) {
+	m := mme.New(udm.New(newFakeCredStore(), noopKeyResolver), dataBarredBearerStore{}, &fakeSessionManager{})
+	ue, cc := securedUE(t, m)
+
+	activateDefaultBearer(context.Background(), m, ue, ue.Conn())
+
+	if len(cc.sent) != 2 {
+		t.Fatalf("expected Attach Reject + UE Context Release Command, got %d", len(cc.sent))
+	}
+
+	rej, err := eps.ParseAttachReject(decodeProtectedDownlink(t, ue, cc.sent[0]))
+	if err != nil {
+		t.Fatalf("not an Attach Reject: %v", err)
+	}
+
+	esm, err := eps.ParsePDNConnectivityReject(rej.ESMMessageContainer)
+	if err != nil {
+		t.Fatalf("ESM message container is not a PDN Connectivity Reject: %v", err)
+	}
+
+	if esm.Cause != eps.ESMCauseOperatorDeterminedBarring {
+		t.Fatalf("carried ESM cause = %d, want %d (operator determined barring)", esm.Cause, eps.ESMCauseOperatorDeterminedBarring)
+	}
+
+	parseUEContextReleaseCommand(t, cc.sent[1])
+}
+
 // TestActivateDefaultBearerRejectsOnSessionFailure checks that when the anchor
 // cannot establish the default bearer, the attach is rejected with EMM cause
 // #19 "ESM failure" and the S1 context is released (TS 24.301 §5.5.1.2.5).
@@ -118,6 +118,22 @@ func openPDNConnection(ctx context.Context, m *mme.MME, ue *mme.UeContext, ueCon
 		return nasreply.Handled()
 	}
 
+	access, err := mme.ResolveAccess(ctx, m, ue.IMSI())
+	if err != nil {
+		logger.From(ctx, logger.MmeLog).Warn("failed to resolve the subscriber's access for additional PDN", zap.String("apn", apn), zap.Error(err))
+		rejectPDNConnectivity(ctx, ueConn, uint8(pti), eps.ESMCauseRequestRejectedUnspecified)
+
+		return nasreply.Handled()
+	}
+
+	if access.DataBarred {
+		logger.From(ctx, logger.MmeLog).Info("PDN connectivity rejected: subscriber barred from data",
+			zap.String("imsi", ue.IMSI()), zap.String("apn", apn))
+		rejectPDNConnectivity(ctx, ueConn, uint8(pti), eps.ESMCauseOperatorDeterminedBarring)
+
+		return nasreply.Handled()
+	}
+
 	qos, err := mme.ResolveQoSByAPN(ctx, m, ue.IMSI(), apn)
 	if errors.Is(err, mme.ErrUnknownAPN) {
 		logger.From(ctx, logger.MmeLog).Info("PDN connectivity rejected: APN not in subscriber profile",
@@ -163,7 +179,7 @@ func openPDNConnection(ctx context.Context, m *mme.MME, ue *mme.UeContext, ueCon
 		logger.From(ctx, logger.MmeLog).Info("PDN connectivity rejected: session setup failed",
 			zap.String("imsi", ue.IMSI()), zap.String("apn", apn), zap.Error(err))
 		m.DropPDN(ue, p.Ebi)
-		rejectPDNConnectivity(ctx, ueConn, uint8(pti), attachBearerRejectCause(ue.RequestedType, err))
+		rejectPDNConnectivityBackoff(ctx, ueConn, uint8(pti), attachBearerRejectCause(ue.RequestedType, err), attachBearerT3396(err))
 
 		return nasreply.Handled()
 	}
@@ -341,8 +357,15 @@ func handleActivateDefaultBearerReject(ctx context.Context, m *mme.MME, ue *mme.
 // rejectPDNConnectivity refuses a PDN CONNECTIVITY REQUEST with an ESM cause
 // (TS 24.301 §6.5.1.4).
 func rejectPDNConnectivity(ctx context.Context, ueConn *mme.UeConn, pti uint8, cause eps.ESMCause) {
+	rejectPDNConnectivityBackoff(ctx, ueConn, pti, cause, nil)
+}
+
+// rejectPDNConnectivityBackoff rejects with a T3396 back-off, which keeps the UE
+// from retrying the APN until it expires; nil omits it.
+func rejectPDNConnectivityBackoff(ctx context.Context, ueConn *mme.UeConn, pti uint8, cause eps.ESMCause, t3396 *nas.GPRSTimer3) {
 	ueConn.SendDownlinkProtected(ctx, &eps.PDNConnectivityReject{
 		PTI:   nas.ProcedureTransactionIdentity(pti),
 		Cause: cause,
+		T3396: t3396,
 	})
 }
@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/nas"
 	"github.com/ellanetworks/core/nas/eps"
 )
 
@@ -41,5 +42,29 @@ func attachBearerRejectCause(t eps.RequestType, err error) eps.ESMCause {
 		return pdnType.Cause
 	}
 
+	if quota, ok := errors.AsType[*models.QuotaExceededError](err); ok {
+		if quota.Quota == models.QuotaSubscriberSessions {
+			return eps.ESMCauseMaxEPSBearersReached
+		}
+
+		return eps.ESMCauseInsufficientResources
+	}
+
 	return eps.ESMCauseRequestRejectedUnspecified
 }
+
+// attachBearerT3396 is the T3396 back-off of a session refused by an admission
+// quota, nil for any other refusal (TS 24.301 §6.5.1.4.3).
+func attachBearerT3396(err error) *nas.GPRSTimer3 {
+	quota, ok := errors.AsType[*models.QuotaExceededError](err)
+	if !ok || quota.BackOff <= 0 {
+		return nil
+	}
+
+	timer, timerErr := nas.GPRSTimer3FromDuration(quota.BackOff)
+	if timerErr != nil {
+		return nil
+	}
+
+	return &timer
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package nas
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/nas/eps"
+)
+
+func TestAttachBearerQuotaReject(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		err       error
+		wantCause eps.ESMCause
+		wantT3396 time.Duration
+	}{
+		{
+			name:      "slice sessions",
+			err:       fmt.Errorf("create: %w", &models.QuotaExceededError{Quota: models.QuotaSliceSessions, Limit: 10, BackOff: 5 * time.Minute}),
+			wantCause: eps.ESMCauseInsufficientResources,
+			wantT3396: 5 * time.Minute,
+		},
+		{
+			name:      "data network sessions without back-off",
+			err:       &models.QuotaExceededError{Quota: models.QuotaDataNetworkSessions, Limit: 10},
+			wantCause: eps.ESMCauseInsufficientResources,
+		},
+		{
+			name:      "sessions per subscriber",
+			err:       &models.QuotaExceededError{Quota: models.QuotaSubscriberSessions, Limit: 2},
+			wantCause: eps.ESMCauseMaxEPSBearersReached,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := attachBearerRejectCause(eps.RequestTypeInitialRequest, tc.err); got != tc.wantCause {
+				t.Errorf("cause %v, want %v", got, tc.wantCause)
+			}
+
+			var got time.Duration
+
+			if timer := attachBearerT3396(tc.err); timer != nil {
+				got, _ = timer.Duration()
+			}
+
+			if got != tc.wantT3396 {
+				t.Errorf("T3396 %v, want %v", got, tc.wantT3396)
+			}
+		})
+	}
+}
@@ -288,6 +288,16 @@ func buildTrackingAreaUpdateAccept(ctx context.Context, m *mme.MME, ue *mme.UeCo
 }
 
 func reconcileBearerContextStatus(ctx context.Context, m *mme.MME, ue *mme.UeContext, ueStatus nas.EPSBearerContextStatus) {
+	for _, ebi := range m.DedicatedEBIs(ue) {
+		if ebi < uint8(len(ueStatus.Active)) && ueStatus.Active[ebi] {
+			continue
+		}
+
+		logger.MmeLog.Info("dropping dedicated EPS bearer reported inactive by the UE",
+			zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", ebi))
+		m.DedicatedBearerDeactivated(ctx, ue, ebi)
+	}
+
 	pdns := m.SnapshotPDNs(ue)
 	remaining := len(pdns)
 
@@ -322,5 +332,11 @@ func bearerContextStatus(m *mme.MME, ue *mme.UeContext) nas.EPSBearerContextStat
 		}
 	}
 
+	for _, ebi := range m.DedicatedEBIs(ue) {
+		if ebi < uint8(len(status.Active)) {
+			status.Active[ebi] = true
+		}
+	}
+
 	return status
 }
@@ -6,6 +6,7 @@ package mme
 import (
 	"context"
 
+	"github.com/ellanetworks/core/internal/guard"
 	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/nas/eps"
 	"go.uber.org/zap"
@@ -116,6 +117,12 @@ func (m *MME) ArmESMGuardAbortOnly(ue *UeContext, p *PdnConnection, name string,
 }
 
 func (m *MME) armESMGuardMode(ue *UeContext, p *PdnConnection, name string, plain []byte, sht eps.SecurityHeaderType, onAbort func()) {
+	m.armESMGuardOn(ue, &p.guard, name, plain, sht, onAbort)
+}
+
+// armESMGuardOn supervises an ESM procedure with g, the guard of the bearer
+// it runs on.
+func (m *MME) armESMGuardOn(ue *UeContext, g *guard.Guard, name string, plain []byte, sht eps.SecurityHeaderType, onAbort func()) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -124,7 +131,7 @@ func (m *MME) armESMGuardMode(ue *UeContext, p *PdnConnection, name string, plai
 		return
 	}
 
-	p.guard.ArmWith(
+	g.ArmWith(
 		m.esmGuardCfg,
 		func(attempt int32) { conn.retransmitNASGuard(ue, name, plain, sht, attempt) },
 		func() { conn.expireNASGuard(ue, name, onAbort) },
@@ -7,7 +7,9 @@ import (
 	"context"
 	"net"
 
+	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/internal/sctp"
 )
 
@@ -56,3 +58,58 @@ func (m *MME) LogNetworkEvent(ctx context.Context, conn S1APWriter, messageType
 func (m *MME) LogOutboundS1AP(ctx context.Context, conn S1APWriter, messageType S1APProcedure, raw []byte) {
 	m.LogNetworkEvent(ctx, conn, messageType, logger.DirectionOutbound, raw)
 }
+
+// UEStatus is a UE as the MME holds it after a transition.
+type UEStatus struct {
+	Registered bool
+	Connected  bool
+	Location   models.UserLocation
+}
+
+// UEObserver is told of the transitions of the UEs the MME serves: an
+// attach or detach, a registered UE entering or leaving ECM-IDLE, and a
+// registered UE reporting another serving cell. It is called with the UE's
+// lock held, in the order the transitions happen, so it must neither block
+// nor call back into the MME.
+type UEObserver interface {
+	UEChanged(supi etsi.SUPI, status UEStatus)
+}
+
+// notifyLocked tells the observer of the UE as it now stands. The caller
+// holds ue.mu.
+func (ue *UeContext) notifyLocked() {
+	if ue.observer == nil || !ue.supi.IsIMSI() {
+		return
+	}
+
+	ue.observer.UEChanged(ue.supi, UEStatus{
+		Registered: ue.emmState == EMMRegistered,
+		Connected:  ue.active.Load() != nil,
+		Location:   ue.Location,
+	})
+}
+
+// connectionChanged tells the observer a registered UE entered or left
+// ECM-IDLE.
+func (ue *UeContext) connectionChanged() {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	if ue.emmState == EMMRegistered {
+		ue.notifyLocked()
+	}
+}
+
+// AnnounceUE tells the observer of the UE with supi as it stands, as if it
+// had just changed, so a new watcher of it learns where it starts from.
+func (m *MME) AnnounceUE(supi etsi.SUPI) {
+	ue, ok := m.LookupUeBySupi(supi)
+	if !ok {
+		return
+	}
+
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	ue.notifyLocked()
+}
@@ -4,8 +4,11 @@
 package mme
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 
+	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/s1ap"
 )
 
@@ -60,3 +63,54 @@ func TestS1APMessageType(t *testing.T) {
 		})
 	}
 }
+
+type recordingObserver struct {
+	seen []UEStatus
+}
+
+func (r *recordingObserver) UEChanged(_ etsi.SUPI, status UEStatus) {
+	r.seen = append(r.seen, status)
+}
+
+func TestUEObserver_ToldOfRegistrationAndConnection(t *testing.T) {
+	obs := &recordingObserver{}
+	ue := &UeContext{supi: mustSUPI(testSubscriber.IMSI), observer: obs}
+
+	ue.TransitionTo(EMMRegistrationInitiated)
+	ue.TransitionTo(EMMRegistered)
+
+	// A tracking area update keeps the registration.
+	ue.TransitionTo(EMMRegistrationInitiated)
+	ue.TransitionTo(EMMRegistered)
+
+	ue.active.Store(&UeConn{})
+	ue.connectionChanged()
+	ue.active.Store(nil)
+	ue.connectionChanged()
+
+	ue.TransitionTo(EMMDeregistered)
+
+	got := make([]string, 0, len(obs.seen))
+	for _, st := range obs.seen {
+		got = append(got, fmt.Sprintf("registered=%t connected=%t", st.Registered, st.Connected))
+	}
+
+	want := []string{
+		"registered=true connected=false",
+		"registered=true connected=false",
+		"registered=true connected=true",
+		"registered=true connected=false",
+		"registered=false connected=false",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("observed %v, want %v", got, want)
+	}
+
+	// A UE that is not registered has no connection to tell of.
+	ue.active.Store(&UeConn{})
+	ue.connectionChanged()
+
+	if len(obs.seen) != len(want) {
+		t.Fatalf("observed %d transitions, want none for an unregistered UE", len(obs.seen)-len(want))
+	}
+}
@@ -6,6 +6,7 @@ package mme
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/ellanetworks/core/internal/db"
 	"github.com/ellanetworks/core/internal/models"
@@ -14,7 +15,7 @@ import (
 
 // EpsQoS is the default-bearer QoS resolved from a subscriber's profile/policy.
 type EpsQoS struct {
-	PolicyID string // policy DB ID, so the UPF binds the session to its network rules
+	PolicyID string // DB ID of the policy whose network rules the UPF binds the session to
 	QCI      byte
 	ARP      byte // priority level (1-15)
 	APN      string
@@ -55,7 +56,40 @@ func ResolveQoS(ctx context.Context, m *MME, imsi string) (*EpsQoS, error) {
 		return nil, fmt.Errorf("get default policy: %w", err)
 	}
 
-	return qosForPolicy(ctx, m, profile, pol)
+	return qosForPolicy(ctx, m, imsi, profile, pol)
+}
+
+// ResolveUeAmbr resolves the subscriber's UE-AMBR: the profile's, unless an
+// AMBR override replaces it.
+func ResolveUeAmbr(ctx context.Context, m *MME, imsi string) (uplink, downlink models.BitRate, err error) {
+	sub, err := m.Bearer.GetSubscriber(ctx, imsi)
+	if err != nil {
+		return models.BitRate{}, models.BitRate{}, fmt.Errorf("get subscriber: %w", err)
+	}
+
+	profile, err := m.Bearer.GetProfileByID(ctx, sub.ProfileID)
+	if err != nil {
+		return models.BitRate{}, models.BitRate{}, fmt.Errorf("get profile: %w", err)
+	}
+
+	override, err := m.Bearer.ActiveSubscriberAmbrOverride(ctx, imsi, time.Now())
+	if err != nil {
+		return models.BitRate{}, models.BitRate{}, fmt.Errorf("get AMBR override: %w", err)
+	}
+
+	ul, dl := override.UeAmbr(profile.UeAmbrUplink, profile.UeAmbrDownlink)
+
+	uplink, err = models.ParseBitRate(ul)
+	if err != nil {
+		return models.BitRate{}, models.BitRate{}, fmt.Errorf("UE-AMBR uplink: %w", err)
+	}
+
+	downlink, err = models.ParseBitRate(dl)
+	if err != nil {
+		return models.BitRate{}, models.BitRate{}, fmt.Errorf("UE-AMBR downlink: %w", err)
+	}
+
+	return uplink, downlink, nil
 }
 
 // ErrUnknownAPN reports that the subscriber's profile has no policy bound to a
@@ -90,14 +124,14 @@ func ResolveQoSByAPN(ctx context.Context, m *MME, imsi, apn string) (*EpsQoS, er
 		}
 
 		if dn.Name == apn {
-			return qosForPolicy(ctx, m, profile, &policies[i])
+			return qosForPolicy(ctx, m, imsi, profile, &policies[i])
 		}
 	}
 
 	return nil, ErrUnknownAPN
 }
 
-func qosForPolicy(ctx context.Context, m *MME, profile *db.Profile, pol *db.Policy) (*EpsQoS, error) {
+func qosForPolicy(ctx context.Context, m *MME, imsi string, profile *db.Profile, pol *db.Policy) (*EpsQoS, error) {
 	dn, err := m.Bearer.GetDataNetworkByID(ctx, pol.DataNetworkID)
 	if err != nil {
 		return nil, fmt.Errorf("get data network: %w", err)
@@ -108,7 +142,51 @@ func qosForPolicy(ctx context.Context, m *MME, profile *db.Profile, pol *db.Poli
 		return nil, err
 	}
 
-	return qosForPolicyDN(profile, pol, dn, snssai)
+	// A location variant replaces the policy's Session-AMBR, and maybe its
+	// network rules, where the UE is served; UpdateLocation reconciles its
+	// bearers when it moves.
+	located, rulesPolicyID, err := m.Bearer.PolicyInArea(ctx, pol, m.servingArea(imsi))
+	if err != nil {
+		return nil, fmt.Errorf("resolve location variant: %w", err)
+	}
+
+	// A scheduled alternate Session-AMBR replaces that while its window is
+	// open; the session reconciler re-resolves when it flips.
+	effective := *located
+
+	effective.SessionAmbrUplink, effective.SessionAmbrDownlink, err = m.Bearer.EffectiveSessionAmbr(ctx, located, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("resolve scheduled Session-AMBR: %w", err)
+	}
+
+	// A subscriber's own override, while it lasts, wins over the profile's
+	// UE-AMBR and the policy's Session-AMBR.
+	override, err := m.Bearer.ActiveSubscriberAmbrOverride(ctx, imsi, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("get AMBR override: %w", err)
+	}
+
+	effectiveProfile := *profile
+
+	effectiveProfile.UeAmbrUplink, effectiveProfile.UeAmbrDownlink = override.UeAmbr(profile.UeAmbrUplink, profile.UeAmbrDownlink)
+	effective.SessionAmbrUplink, effective.SessionAmbrDownlink = override.SessionAmbr(effective.SessionAmbrUplink, effective.SessionAmbrDownlink)
+
+	// UEs are handed the embedded DNS forwarder while it is on.
+	effectiveDN := *dn
+
+	effectiveDN.DNS, err = m.Bearer.EffectiveDNS(ctx, dn)
+	if err != nil {
+		return nil, fmt.Errorf("resolve DNS server: %w", err)
+	}
+
+	qos, err := qosForPolicyDN(&effectiveProfile, &effective, &effectiveDN, snssai)
+	if err != nil {
+		return nil, err
+	}
+
+	qos.PolicyID = rulesPolicyID
+
+	return qos, nil
 }
 
 func snssaiForPolicy(ctx context.Context, m *MME, pol *db.Policy) (*models.Snssai, error) {
@@ -43,6 +43,8 @@ func (m *MME) ReconcileUE(ctx context.Context, ue *UeContext) {
 		return
 	}
 
+	m.reconcileUeAmbr(ctx, ue, ueConn)
+
 	for _, p := range m.SnapshotPDNs(ue) {
 		m.reconcileBearer(ctx, ue, ueConn, p)
 	}
@@ -135,6 +137,13 @@ func (m *MME) reconcileBearer(ctx context.Context, ue *UeContext, ueConn *UeConn
 		return
 	}
 
+	// The network rules of a location variant need only the UPF, so they
+	// change without signalling the UE.
+	if err := m.Session.UpdateEPSSessionRules(ctx, p.SessionRef, qos.PolicyID); err != nil {
+		logger.From(ctx, logger.MmeLog).Warn("reconcile: failed to update network rules; deferring to next sweep",
+			zap.String("imsi", ue.IMSI()), zap.String("apn", p.Apn), zap.Error(err))
+	}
+
 	newFingerprint := qos.DnFingerprint()
 	dnChanged := newFingerprint != curDNConfig
 
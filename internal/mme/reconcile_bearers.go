@@ -40,6 +40,14 @@ func (m *MME) ReconcileBearersToRAN(ctx context.Context, ue *UeContext, want RAN
 	for _, b := range want.Present {
 		named[b.Ebi] = struct{}{}
 
+		if p, d := m.LookupDedicatedBearer(ue, b.Ebi); d != nil {
+			m.dedicatedRadioUp(ctx, ue, p, d, b.EnbFTEID)
+
+			result.Applied = append(result.Applied, b.Ebi)
+
+			continue
+		}
+
 		p := m.LookupPDN(ue, b.Ebi)
 		if p == nil {
 			logger.From(ctx, logger.MmeLog).Warn("RAN reports an E-RAB the core does not know; not switched",
@@ -70,6 +78,14 @@ func (m *MME) ReconcileBearersToRAN(ctx context.Context, ue *UeContext, want RAN
 	for _, ebi := range want.Rejected {
 		named[ebi] = struct{}{}
 
+		if p, d := m.LookupDedicatedBearer(ue, ebi); d != nil {
+			m.dropDedicatedBearer(ctx, ue, p, d, "the eNB could not set up the bearer")
+
+			result.Released = append(result.Released, ebi)
+
+			continue
+		}
+
 		if p := m.LookupPDN(ue, ebi); p != nil {
 			m.ReleasePDN(ctx, ue, p)
 
@@ -79,6 +95,19 @@ func (m *MME) ReconcileBearersToRAN(ctx context.Context, ue *UeContext, want RAN
 
 	if want.Authoritative {
 		for _, p := range m.SnapshotPDNs(ue) {
+			ue.mu.Lock()
+			d := p.Dedicated
+			ue.mu.Unlock()
+
+			if d != nil {
+				if _, ok := named[d.Ebi]; !ok {
+					logger.From(ctx, logger.MmeLog).Info("dropping a dedicated E-RAB the RAN did not report",
+						zap.String("imsi", ue.IMSI()), zap.Uint8("e-rab-id", d.Ebi))
+
+					m.dropDedicatedBearer(ctx, ue, p, d, "the bearer did not follow the UE to the new eNB")
+				}
+			}
+
 			if _, ok := named[p.Ebi]; ok {
 				continue
 			}
@@ -279,6 +279,10 @@ func TestReconcileDataNetworkModifiesSessionAMBR(t *testing.T) {
 		t.Fatalf("UPF Session-AMBR not updated to %s/%s, got %+v", qos.SessAmbrUL, qos.SessAmbrDL, fsm)
 	}
 
+	if fsm.rulesPolicyID != qos.PolicyID {
+		t.Fatalf("UPF session bound to the rules of %q, want %q", fsm.rulesPolicyID, qos.PolicyID)
+	}
+
 	if len(cc.sent) != 1 {
 		t.Fatalf("expected one Modify EPS Bearer Context Request, got %d", len(cc.sent))
 	}
@@ -63,7 +63,7 @@ func (m *MME) ForwardRelocation(ctx context.Context, req interworking.ForwardRel
 		return none, fmt.Errorf("mme: resolve the subscriber's access: %w", err)
 	}
 
-	if !access.Allow4G {
+	if !access.Allow4G || access.DataBarred {
 		return none, interworking.TargetRefusal{Cause: s1ap.Cause{
 			Group: s1ap.CauseGroupRadioNetwork,
 			Value: s1ap.CauseRadioNetworkHOTargetNotAllowed,
@@ -9,6 +9,7 @@ import (
 	"net/netip"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/db"
@@ -164,6 +165,10 @@ func (f *fakeSessionManager) UpdateEPSSessionAMBR(_ context.Context, _ string, _
 	return nil
 }
 
+func (f *fakeSessionManager) UpdateEPSSessionRules(_ context.Context, _ string, _ string) error {
+	return nil
+}
+
 func (f *fakeSessionManager) DeactivateEPSSession(_ context.Context, _ string) error {
 	f.deactivated = true
 
@@ -193,6 +198,12 @@ func (f *fakeSessionManager) StaticIPChanged(_ context.Context, _ string) (bool,
 	return false, nil
 }
 
+func (f *fakeSessionManager) DedicatedBearerUp(_ context.Context, _ string, _ models.FTEID) error {
+	return nil
+}
+
+func (f *fakeSessionManager) DedicatedBearerReleased(_ context.Context, _, _ string) {}
+
 // fakeBearerStore resolves a fixed default-bearer QoS for any subscriber.
 type fakeBearerStore struct{}
 
@@ -208,6 +219,26 @@ func (fakeBearerStore) GetDefaultPolicyByProfile(_ context.Context, _ string) (*
 	return &db.Policy{Var5qi: 9, Arp: 15, DataNetworkID: "test-dn", IsDefault: true, SessionAmbrUplink: "100 Mbps", SessionAmbrDownlink: "200 Mbps"}, nil
 }
 
+func (fakeBearerStore) PolicyInArea(_ context.Context, pol *db.Policy, _ models.ServingArea) (*db.Policy, string, error) {
+	return pol, pol.ID, nil
+}
+
+func (fakeBearerStore) EffectiveSessionAmbr(_ context.Context, pol *db.Policy, _ time.Time) (string, string, error) {
+	return pol.SessionAmbrUplink, pol.SessionAmbrDownlink, nil
+}
+
+func (fakeBearerStore) EffectiveDNS(_ context.Context, dn *db.DataNetwork) (string, error) {
+	return dn.DNS, nil
+}
+
+func (fakeBearerStore) ActiveSubscriberAmbrOverride(context.Context, string, time.Time) (*db.SubscriberAmbrOverride, error) {
+	return nil, nil
+}
+
+func (fakeBearerStore) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return db.SubscriberStateActive, nil
+}
+
 func (fakeBearerStore) ListPoliciesByProfile(_ context.Context, _ string) ([]db.Policy, error) {
 	return []db.Policy{
 		{Var5qi: 9, Arp: 15, DataNetworkID: "test-dn", IsDefault: true, SessionAmbrUplink: "100 Mbps", SessionAmbrDownlink: "200 Mbps"},
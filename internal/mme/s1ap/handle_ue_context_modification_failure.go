@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package s1ap
+
+import (
+	"context"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/s1ap"
+	"go.uber.org/zap"
+)
+
+// handleUEContextModificationFailure records that the eNB refused a new
+// UE-AMBR (TS 36.413 §8.3.4.3). The UE context is left as it was; the MME holds
+// the new value and hands it to the eNB at the next Initial Context Setup.
+func handleUEContextModificationFailure(m *mme.MME, ctx context.Context, radio *mme.Radio, value []byte) {
+	msg, err := s1ap.ParseUEContextModificationFailure(value)
+	if err != nil {
+		handleParseError(m, radio.Conn, s1ap.ProcUEContextModification, err)
+		return
+	}
+
+	ue, ueConn, ok := resolveUEIDs(m, radio.Conn, msg.MMEUES1APID, msg.ENBUES1APID)
+	if !ok {
+		return
+	}
+
+	reportDiagnostics(m, ctx, radio.Conn, s1ap.ProcUEContextModification, s1ap.TriggeringUnsuccessfulOutcome, ueAssociated(ueConn.MMEUES1APID, ueConn.ENBUES1APID), msg.Diagnostics())
+
+	ue.TouchLastSeen()
+
+	fields := []zap.Field{zap.Uint32("mme-ue-id", uint32(*msg.MMEUES1APID))}
+	if msg.Cause != nil {
+		fields = append(fields, zap.String("cause", mme.S1apCauseName(msg.Cause)))
+	}
+
+	logger.MmeLog.Warn("UE Context Modification Failure", fields...)
+}
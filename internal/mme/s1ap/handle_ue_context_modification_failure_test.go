@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package s1ap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/s1ap"
+)
+
+// A refused UE-AMBR change leaves the UE context in place: nothing is sent
+// back and the UE stays connected.
+func TestHandleUEContextModificationFailureKeepsUE(t *testing.T) {
+	m := newTestMME(t)
+	conn := &captureConn{}
+	ue := m.NewUe(conn, 7)
+	m.RegisterUEForTest(ue, "001010000000001")
+
+	wire, err := (&s1ap.UEContextModificationFailure{
+		MMEUES1APID: s1ap.Ptr(ue.Conn().MMEUES1APID),
+		ENBUES1APID: s1ap.Ptr(s1ap.ENBUES1APID(7)),
+		Cause:       &s1ap.Cause{Group: s1ap.CauseGroupRadioNetwork, Value: s1ap.CauseRadioNetworkUnspecified},
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handleUEContextModificationFailure(m, context.Background(), mme.NewRadioForTest(conn), unsuccessfulValue(t, wire))
+
+	if conn.count() != 0 {
+		t.Fatalf("expected nothing sent, got %d messages", conn.count())
+	}
+
+	if ue.Conn() == nil {
+		t.Fatal("UE connection released after a refused modification")
+	}
+}
+
+func TestHandleUEContextModificationResponseMalformed(t *testing.T) {
+	m := newTestMME(t)
+
+	handleUEContextModificationResponse(m, context.Background(), mme.NewRadioForTest(&captureConn{}), []byte{0xff, 0xff, 0xff})
+}
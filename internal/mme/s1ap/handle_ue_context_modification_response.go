@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package s1ap
+
+import (
+	"context"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/s1ap"
+	"go.uber.org/zap"
+)
+
+// handleUEContextModificationResponse records that the eNB applied a new
+// UE-AMBR (TS 36.413 §8.3.4.2).
+func handleUEContextModificationResponse(m *mme.MME, ctx context.Context, radio *mme.Radio, value []byte) {
+	resp, err := s1ap.ParseUEContextModificationResponse(value)
+	if err != nil {
+		handleParseError(m, radio.Conn, s1ap.ProcUEContextModification, err)
+		return
+	}
+
+	// Both identities are mandatory but ignore criticality, so an absent one
+	// still reaches the handler.
+	ue, ueConn, ok := resolveUEIDs(m, radio.Conn, resp.MMEUES1APID, resp.ENBUES1APID)
+	if !ok {
+		return
+	}
+
+	reportDiagnostics(m, ctx, radio.Conn, s1ap.ProcUEContextModification, s1ap.TriggeringSuccessfulOutcome, ueAssociated(ueConn.MMEUES1APID, ueConn.ENBUES1APID), resp.Diagnostics())
+
+	ue.TouchLastSeen()
+
+	logger.From(ctx, radio.Log).Debug("UE Context Modification Response", zap.Uint32("mme-ue-id", uint32(*resp.MMEUES1APID)))
+}
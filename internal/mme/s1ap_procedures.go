@@ -56,6 +56,10 @@ const (
 	S1APProcedureERABModificationIndication  S1APProcedure = "E-RABModificationIndication"
 	S1APProcedureERABModificationConfirm     S1APProcedure = "E-RABModificationConfirm"
 
+	S1APProcedureUEContextModificationRequest  S1APProcedure = "UEContextModificationRequest"
+	S1APProcedureUEContextModificationResponse S1APProcedure = "UEContextModificationResponse"
+	S1APProcedureUEContextModificationFailure  S1APProcedure = "UEContextModificationFailure"
+
 	S1APProcedureDownlinkUEAssociatedLPPaTransport S1APProcedure = "DownlinkUEAssociatedLPPaTransport"
 	S1APProcedureUplinkUEAssociatedLPPaTransport   S1APProcedure = "UplinkUEAssociatedLPPaTransport"
 	S1APProcedureLocationReport                    S1APProcedure = "LocationReport"
@@ -137,6 +141,8 @@ func s1apInitiatingMessageType(code s1ap.ProcedureCode) S1APProcedure {
 		return S1APProcedureUplinkUEAssociatedLPPaTransport
 	case s1ap.ProcLocationReport:
 		return S1APProcedureLocationReport
+	case s1ap.ProcUEContextModification:
+		return S1APProcedureUEContextModificationRequest
 	default:
 		return S1APProcedureUnknown
 	}
@@ -170,6 +176,8 @@ func s1apSuccessfulOutcomeType(code s1ap.ProcedureCode) S1APProcedure {
 		return S1APProcedureHandoverRequestAck
 	case s1ap.ProcHandoverCancel:
 		return S1APProcedureHandoverCancelAcknowledge
+	case s1ap.ProcUEContextModification:
+		return S1APProcedureUEContextModificationResponse
 	default:
 		return S1APProcedureUnknown
 	}
@@ -189,6 +197,8 @@ func s1apUnsuccessfulOutcomeType(code s1ap.ProcedureCode) S1APProcedure {
 		return S1APProcedureHandoverPreparationFailure
 	case s1ap.ProcHandoverResourceAllocation:
 		return S1APProcedureHandoverFailure
+	case s1ap.ProcUEContextModification:
+		return S1APProcedureUEContextModificationFailure
 	default:
 		return S1APProcedureUnknown
 	}
@@ -265,3 +265,22 @@ func ReportProtectFailure(ctx context.Context, c *UeConn, what string, err error
 		SendUEContextRelease(ctx, c.m, c.Conn(), c.MMEUES1APID, c.ENBUES1APID, true, CauseNASNormalRelease)
 	}
 }
+
+// SendUEContextModification stamps the UE identities and sends the UE Context
+// Modification Request (TS 36.413 §8.3.4).
+func (c *UeConn) SendUEContextModification(ctx context.Context, req *s1ap.UEContextModificationRequest) error {
+	if c == nil {
+		return nil
+	}
+
+	req.MMEUES1APID, req.ENBUES1APID = c.MMEUES1APID, c.ENBUES1APID
+
+	b, err := req.Marshal()
+	if err != nil {
+		return fmt.Errorf("marshal UE Context Modification Request: %w", err)
+	}
+
+	c.SendS1AP(ctx, S1APProcedureUEContextModificationRequest, b)
+
+	return nil
+}
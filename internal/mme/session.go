@@ -5,8 +5,10 @@ package mme
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/nas/eps"
 	"go.uber.org/zap"
 )
 
@@ -104,6 +106,8 @@ func (m *MME) ReleaseAllSessions(ctx context.Context, ue *UeContext) {
 }
 
 func (m *MME) releaseAnchorSession(ctx context.Context, ue *UeContext, p *PdnConnection) {
+	m.stopDedicatedBearer(ue, p)
+
 	if err := m.Session.ReleaseEPSSession(ctx, p.SessionRef); err != nil {
 		logger.MmeLog.Warn("failed to release PDN connection session",
 			zap.String("imsi", ue.IMSI()), zap.Uint8("ebi", p.Ebi), zap.Error(err))
@@ -111,8 +115,11 @@ func (m *MME) releaseAnchorSession(ctx context.Context, ue *UeContext, p *PdnCon
 }
 
 // DeactivateAllSessions buffers every PDN connection's downlink so data for the
-// idle UE triggers paging (TS 23.401), without releasing the sessions.
+// idle UE triggers paging (TS 23.401), without releasing the sessions. The
+// dedicated bearers are dropped.
 func (m *MME) DeactivateAllSessions(ctx context.Context, ue *UeContext) {
+	m.dropAllDedicatedBearers(ctx, ue)
+
 	for _, p := range m.SnapshotPDNs(ue) {
 		if err := m.Session.DeactivateEPSSession(ctx, p.SessionRef); err != nil {
 			logger.MmeLog.Warn("failed to deactivate PDN connection session for paging",
@@ -136,6 +143,7 @@ func (m *MME) SessionDropped(ctx context.Context, imsi string, ebi uint8, ref st
 	}
 
 	m.StopESMGuard(p)
+	m.stopDedicatedBearer(ue, p)
 
 	logger.From(ctx, logger.MmeLog).Info("PDN connection moved to 5GS; dropping the EPS routing context",
 		zap.String("imsi", imsi), zap.Uint8("ebi", ebi), zap.Bool("last-pdn", last))
@@ -155,6 +163,36 @@ func (m *MME) SessionDropped(ctx context.Context, imsi string, ebi uint8, ref st
 	m.DeregisterEmptyUE(ctx, ue)
 }
 
+// DeactivateSession tears down the PDN connection of session ref on the
+// network's initiative, as when its credit runs out. A connected UE is asked
+// to deactivate the bearer; an idle one loses it locally and learns of it
+// from the EPS bearer context status of its next service request or tracking
+// area update (TS 24.301 §6.4.4.1).
+func (m *MME) DeactivateSession(ctx context.Context, imsi string, ebi uint8, ref string) error {
+	ue, ok := m.LookupUeByIMSI(imsi)
+	if !ok {
+		return fmt.Errorf("no UE context for IMSI %s", imsi)
+	}
+
+	ue.mu.Lock()
+	p, ok := ue.Pdns[ebi]
+	held := ok && p.SessionRef == ref
+	ue.mu.Unlock()
+
+	if !held {
+		return fmt.Errorf("UE %s holds no PDN connection for session %q", imsi, ref)
+	}
+
+	if ue.Conn() == nil {
+		m.ReleasePDN(ctx, ue, p)
+		return nil
+	}
+
+	m.DisconnectBearer(ctx, ue, p, eps.ESMCauseRegularDeactivation, 0)
+
+	return nil
+}
+
 func takePDNByRef(ue *UeContext, ebi uint8, ref string) (p *PdnConnection, last bool) {
 	ue.mu.Lock()
 	defer ue.mu.Unlock()
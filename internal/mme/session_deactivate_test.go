@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package mme
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeactivateSession(t *testing.T) {
+	t.Run("a connected UE is asked to deactivate the bearer", func(t *testing.T) {
+		m := newTestMME(t)
+		ue, cc := securedUE(t, m)
+
+		p := testPDN(ue)
+		p.SessionRef = "imsi-001010000000001-5#1"
+
+		sent := len(cc.sent)
+
+		if err := m.DeactivateSession(context.Background(), ue.IMSI(), DefaultERABID, p.SessionRef); err != nil {
+			t.Fatalf("DeactivateSession: %v", err)
+		}
+
+		if !p.Deactivating {
+			t.Error("the bearer is not being deactivated")
+		}
+
+		if len(cc.sent) == sent {
+			t.Error("no Deactivate EPS Bearer Context Request was sent")
+		}
+
+		if !m.Session.(*fakeSessionManager).released {
+			t.Error("the user plane was not released at the start of the deactivation")
+		}
+	})
+
+	t.Run("a session the UE no longer holds is refused", func(t *testing.T) {
+		m := newTestMME(t)
+		ue, _ := securedUE(t, m)
+
+		testPDN(ue).SessionRef = "imsi-001010000000001-5#2"
+
+		if err := m.DeactivateSession(context.Background(), ue.IMSI(), DefaultERABID, "imsi-001010000000001-5#1"); err == nil {
+			t.Fatal("a stale session reference deactivated the current PDN connection")
+		}
+	})
+}
@@ -79,7 +79,7 @@ func (r *SessionReconciler) Stop() {
 func (r *SessionReconciler) loop(ctx context.Context, done chan struct{}) {
 	defer close(done)
 
-	r.mme.ReconcileDataNetwork(ctx)
+	r.reconcile(ctx)
 
 	ticker := time.NewTicker(r.backstop)
 	defer ticker.Stop()
@@ -89,9 +89,16 @@ func (r *SessionReconciler) loop(ctx context.Context, done chan struct{}) {
 		case <-ctx.Done():
 			return
 		case <-r.wakeup:
-			r.mme.ReconcileDataNetwork(ctx)
+			r.reconcile(ctx)
 		case <-ticker.C:
-			r.mme.ReconcileDataNetwork(ctx)
+			r.reconcile(ctx)
 		}
 	}
 }
+
+// reconcile detaches the UEs whose subscriber may no longer hold a PDN
+// connection before reconciling the bearers of those that remain.
+func (r *SessionReconciler) reconcile(ctx context.Context) {
+	r.mme.ReconcileSubscriberStates(ctx)
+	r.mme.ReconcileDataNetwork(ctx)
+}
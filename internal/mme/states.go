@@ -111,6 +111,12 @@ func (ue *UeContext) setEMMStateLocked(target EMMState) {
 		ue.idleMobilityFrom5GS = false
 		ue.localBearerDeactivation = false
 	}
+
+	// A tracking area update passes through EMM-REGISTERED-INITIATED without
+	// the UE losing its registration, so only the ends of the graph are told.
+	if target == EMMRegistered || target == EMMDeregistered {
+		ue.notifyLocked()
+	}
 }
 
 // RegStep returns the sub-phase within the attach procedure (meaningful only in
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package mme
+
+import (
+	"context"
+	"time"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ReconcileSubscriberStates detaches every UE whose subscriber is no longer
+// active: suspended, expired or barred from data. A UE in EPS cannot stay
+// attached without a PDN connection, so barring data detaches it as well.
+func (m *MME) ReconcileSubscriberStates(ctx context.Context) {
+	now := time.Now()
+
+	for _, imsi := range m.registeredIMSIs() {
+		state, err := m.Bearer.SubscriberStateAt(ctx, imsi, now)
+		if err != nil {
+			logger.From(ctx, logger.MmeLog).Warn("reconcile: failed to get subscriber state; deferring to next sweep",
+				zap.String("imsi", imsi), zap.Error(err))
+
+			continue
+		}
+
+		if state.AllowsData() {
+			continue
+		}
+
+		m.detachSubscriber(ctx, imsi, "subscriber "+string(state))
+	}
+}
+
+// registeredIMSIs returns the IMSI of every EMM-REGISTERED UE.
+func (m *MME) registeredIMSIs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	imsis := make([]string, 0, len(m.UEs))
+
+	for supi, ue := range m.UEs {
+		if ue.EMMState() == EMMRegistered {
+			imsis = append(imsis, supi.IMSI())
+		}
+	}
+
+	return imsis
+}
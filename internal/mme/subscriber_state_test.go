@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package mme
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/udm"
+)
+
+// stateBearerStore puts every subscriber in state.
+type stateBearerStore struct {
+	fakeBearerStore
+	state db.SubscriberState
+}
+
+func (s stateBearerStore) SubscriberStateAt(context.Context, string, time.Time) (db.SubscriberState, error) {
+	return s.state, nil
+}
+
+func TestReconcileSubscriberStates(t *testing.T) {
+	for _, tc := range []struct {
+		state  db.SubscriberState
+		detach bool
+	}{
+		{db.SubscriberStateActive, false},
+		{db.SubscriberStateBarredData, true},
+		{db.SubscriberStateSuspended, true},
+		{db.SubscriberStateExpired, true},
+	} {
+		t.Run(string(tc.state), func(t *testing.T) {
+			m := New(udm.New(newFakeCredStore(), noopKeyResolver), stateBearerStore{state: tc.state}, &fakeSessionManager{})
+
+			ue, cc := securedUE(t, m)
+
+			m.ReconcileSubscriberStates(context.Background())
+
+			if detached := ue.EMMState() == EMMDeregistrationInitiated; detached != tc.detach {
+				t.Fatalf("detach initiated = %v, want %v", detached, tc.detach)
+			}
+
+			if sent := cc.count() > 0; sent != tc.detach {
+				t.Fatalf("Detach Request sent = %v, want %v", sent, tc.detach)
+			}
+		})
+	}
+}
+
+func TestResolveAccessFoldsSubscriberState(t *testing.T) {
+	for _, tc := range []struct {
+		state           db.SubscriberState
+		allow4G, barred bool
+	}{
+		{db.SubscriberStateActive, true, false},
+		{db.SubscriberStateBarredData, true, true},
+		{db.SubscriberStateSuspended, false, true},
+		{db.SubscriberStateExpired, false, true},
+	} {
+		m := New(udm.New(newFakeCredStore(), noopKeyResolver), stateBearerStore{state: tc.state}, &fakeSessionManager{})
+
+		access, err := ResolveAccess(context.Background(), m, testSubscriber.IMSI)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.state, err)
+		}
+
+		if access.Allow4G != tc.allow4G || access.Allow5G != tc.allow4G || access.DataBarred != tc.barred {
+			t.Errorf("%s: access = %+v, want 4G/5G %v, barred %v", tc.state, access, tc.allow4G, tc.barred)
+		}
+	}
+}
@@ -78,6 +78,14 @@ func (ue *UeContext) AmbrRates() (uplink, downlink models.BitRate) {
 	return ue.Ambr.Uplink, ue.Ambr.Downlink
 }
 
+// SetAmbr replaces the UE-AMBR.
+func (ue *UeContext) SetAmbr(ambr *models.Ambr) {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	ue.Ambr = ambr
+}
+
 // HasKASME reports whether K_ASME is present (the UE has authenticated).
 func (ue *UeContext) HasKASME() bool {
 	ue.mu.Lock()
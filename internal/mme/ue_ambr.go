@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package mme
+
+import (
+	"context"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/s1ap"
+	"go.uber.org/zap"
+)
+
+// reconcileUeAmbr re-resolves a connected UE's UE-AMBR, which a subscriber AMBR
+// override changes while the UE stays attached, and tells the eNB with a UE
+// Context Modification (TS 23.401 §5.4.2). Before the Initial Context Setup
+// completes only the stored value changes: the ICS or a later E-RAB Setup
+// carries it.
+func (m *MME) reconcileUeAmbr(ctx context.Context, ue *UeContext, ueConn *UeConn) {
+	curUL, curDL := ue.AmbrRates()
+	if curUL.Bps() == 0 && curDL.Bps() == 0 {
+		// Not yet set: the attach resolves it.
+		return
+	}
+
+	ul, dl, err := ResolveUeAmbr(ctx, m, ue.IMSI())
+	if err != nil {
+		logger.From(ctx, logger.MmeLog).Warn("reconcile: failed to resolve UE-AMBR; deferring to next sweep",
+			zap.String("imsi", ue.IMSI()), zap.Error(err))
+
+		return
+	}
+
+	if ul.Bps() == curUL.Bps() && dl.Bps() == curDL.Bps() {
+		return
+	}
+
+	ue.SetAmbr(&models.Ambr{Uplink: ul, Downlink: dl})
+
+	m.mu.RLock()
+	established := ueConn.ICS == ICSCompleted
+	m.mu.RUnlock()
+
+	if !established {
+		return
+	}
+
+	req := &s1ap.UEContextModificationRequest{
+		UEAggregateMaximumBitRate: &s1ap.UEAggregateMaximumBitRate{
+			DL: s1ap.BitRate(dl.Bps()),
+			UL: s1ap.BitRate(ul.Bps()),
+		},
+	}
+
+	if err := ueConn.SendUEContextModification(ctx, req); err != nil {
+		logger.From(ctx, ueConn.Log).Warn("couldn't send UE-AMBR to eNB",
+			zap.String("imsi", ue.IMSI()), zap.Error(err))
+
+		return
+	}
+
+	logger.From(ctx, ueConn.Log).Info("UE-AMBR changed",
+		zap.String("imsi", ue.IMSI()),
+		zap.String("uplink", ul.String()), zap.String("downlink", dl.String()))
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package mme
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/s1ap"
+)
+
+// A UE-AMBR that changed under a connected UE, as an AMBR override does, is
+// sent to the eNB in a UE Context Modification Request.
+func TestReconcileUESendsChangedUeAmbr(t *testing.T) {
+	m := newTestMME(t)
+	ue, cc := connectedBearerUE(t, m)
+
+	qos, err := ResolveQoS(context.Background(), m, ue.imsiOrEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testPDN(ue).DnConfig = qos.DnFingerprint()
+	ue.Conn().ICS = ICSCompleted
+	ue.SetAmbr(&models.Ambr{Uplink: models.MustParseBitRate("1 Mbps"), Downlink: models.MustParseBitRate("1 Mbps")})
+
+	m.ReconcileUE(context.Background(), ue)
+
+	if len(cc.sent) != 1 {
+		t.Fatalf("expected one UE Context Modification Request, got %d", len(cc.sent))
+	}
+
+	pdu, err := s1ap.Unmarshal(cc.sent[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im, ok := pdu.(*s1ap.InitiatingMessage)
+	if !ok || im.ProcedureCode != s1ap.ProcUEContextModification {
+		t.Fatalf("sent %T, want a UE Context Modification Request", pdu)
+	}
+
+	req, err := s1ap.ParseUEContextModificationRequest(im.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fakeBearerStore's profile UE-AMBR.
+	if req.UEAggregateMaximumBitRate == nil || req.UEAggregateMaximumBitRate.DL != 1_000_000_000 {
+		t.Fatalf("UE-AMBR = %+v, want 1 Gbps", req.UEAggregateMaximumBitRate)
+	}
+
+	if ul, dl := ue.AmbrRates(); ul.Bps() != 1_000_000_000 || dl.Bps() != 1_000_000_000 {
+		t.Fatalf("stored UE-AMBR = %s/%s, want 1 Gbps", ul, dl)
+	}
+
+	// Converged: nothing more is sent.
+	m.ReconcileUE(context.Background(), ue)
+
+	if len(cc.sent) != 1 {
+		t.Fatalf("expected no further signalling, got %d messages", len(cc.sent))
+	}
+}
+
+// Before the Initial Context Setup completes only the stored value changes.
+func TestReconcileUEStoresUeAmbrBeforeICS(t *testing.T) {
+	m := newTestMME(t)
+	ue, cc := connectedBearerUE(t, m)
+
+	qos, err := ResolveQoS(context.Background(), m, ue.imsiOrEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testPDN(ue).DnConfig = qos.DnFingerprint()
+	ue.Conn().ICS = ICSPending
+	ue.SetAmbr(&models.Ambr{Uplink: models.MustParseBitRate("1 Mbps"), Downlink: models.MustParseBitRate("1 Mbps")})
+
+	m.ReconcileUE(context.Background(), ue)
+
+	if len(cc.sent) != 0 {
+		t.Fatalf("expected no signalling before the ICS completes, got %d", len(cc.sent))
+	}
+
+	if ul, _ := ue.AmbrRates(); ul.Bps() != 1_000_000_000 {
+		t.Fatalf("stored UE-AMBR uplink = %s, want 1 Gbps", ul)
+	}
+}
@@ -0,0 +1,19 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package models
+
+// DatapathFeatures lists the optional features the loaded eBPF objects
+// enforce. Objects built before a feature's maps existed still load, so the
+// API checks here and refuses configuration the datapath would not apply.
+type DatapathFeatures struct {
+	DataNetworkEgress bool
+	NATPools          bool
+	FQDNRules         bool
+	RateLimitRules    bool
+	BreakoutRules     bool
+	TCPMSSClamp       bool
+	RatedRules        bool
+	CaptivePortal     bool
+	QoSFlows          bool
+}
@@ -42,22 +42,55 @@ type Action int
 const (
 	Allow Action = iota
 	Deny
+	// RateLimit allows matching traffic up to FilterRule.RateLimit.
+	RateLimit
+	// Breakout allows matching uplink traffic out of FilterRule.Breakout
+	// instead of the data network's egress.
+	Breakout
+	// Portal redirects matching uplink traffic to FilterRule.Portal, and
+	// PortalDrop drops it, unless the subscriber's redirect was lifted.
+	// Neither is a network rule action: the UPF places them ahead of the
+	// rules of a policy in captive portal mode.
+	Portal
+	PortalDrop
+	// QoSFlow carries matching traffic in the dedicated QoS flow
+	// FilterRule.QFI, up to FilterRule.RateLimit when set, if the rules
+	// after it let it through: it does not end evaluation. It is not a
+	// network rule action either: the UPF places it ahead of the rules of a
+	// session an application requested QoS for.
+	QoSFlow
 )
 
 func (a Action) String() string {
-	if a == Allow {
+	switch a {
+	case Allow:
 		return "allow"
+	case RateLimit:
+		return "rate_limit"
+	case Breakout:
+		return "breakout"
+	case Portal:
+		return "portal"
+	case PortalDrop:
+		return "portal_drop"
+	case QoSFlow:
+		return "qos_flow"
+	default:
+		return "deny"
 	}
-
-	return "deny"
 }
 
 func ActionFromString(s string) Action {
-	if s == "deny" {
+	switch s {
+	case "deny":
 		return Deny
+	case "rate_limit":
+		return RateLimit
+	case "breakout":
+		return Breakout
+	default:
+		return Allow
 	}
-
-	return Allow
 }
 
 type FilterRule struct {
@@ -66,4 +99,38 @@ type FilterRule struct {
 	PortLow      int32
 	PortHigh     int32
 	Action       Action
+	// FQDN, when set, replaces RemotePrefix with the addresses the name
+	// resolves to; "*." matches every name below it. MatchSNI also learns
+	// them from TLS server names.
+	FQDN     string
+	MatchSNI bool
+	// FQDNSet is the datapath's handle for FQDN, assigned by the UPF.
+	FQDNSet uint16
+	// RateLimit caps a RateLimit rule, per session unless RateLimitShared
+	// makes every session of the policy share it.
+	RateLimit       BitRate
+	RateLimitShared bool
+	// Breakout is where a Breakout rule's traffic leaves the node, and
+	// BreakoutIndex the datapath's handle for it, assigned by the UPF.
+	Breakout      BreakoutEgress
+	BreakoutIndex uint8
+	// RatingGroup, when non-zero, counts the traffic the rule passes
+	// separately in the session's usage. ZeroRated keeps it out of the
+	// session's usage.
+	RatingGroup uint32
+	ZeroRated   bool
+	// Portal is the IPv4 address a Portal rule redirects to.
+	Portal string
+	// QFI is the QoS flow a QoSFlow rule marks its downlink traffic with.
+	QFI uint8
+}
+
+// BreakoutEgress is a local egress beside the data network's, such as the
+// interface facing an edge compute subnet. A non-zero Table is the kernel
+// table the destination is looked up in; a non-zero VlanID tags the traffic
+// on Interface.
+type BreakoutEgress struct {
+	Interface string
+	VlanID    int
+	Table     int
 }
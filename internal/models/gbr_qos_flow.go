@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package models
+
+import "net/netip"
+
+// GBRQosFlow is a dedicated guaranteed bit rate QoS flow of a PDU session
+// (TS 23.501 §5.7.1.1), carrying the traffic Filter matches beside the
+// session's default flow.
+type GBRQosFlow struct {
+	QosData
+	GFBRUplink   BitRate
+	GFBRDownlink BitRate
+	MFBRUplink   BitRate
+	MFBRDownlink BitRate
+	Filter       QosFlowFilter
+}
+
+// QosFlowFilter matches the traffic a UE exchanges with a remote party. An
+// invalid RemotePrefix, a zero Protocol and a zero port range match any.
+type QosFlowFilter struct {
+	RemotePrefix netip.Prefix
+	Protocol     uint8
+	PortLow      uint16
+	PortHigh     uint16
+}
+
+// QosSessionFilterID is the ID the UPF installs the filters of an
+// application's QoS session under, in place of the policy ID of the PDU
+// session it applies to.
+func QosSessionFilterID(qosSessionID string) string {
+	return "qos-session:" + qosSessionID
+}
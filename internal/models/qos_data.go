@@ -5,6 +5,10 @@ package models
 
 const DefaultQFI uint8 = 1
 
+// DedicatedQFI is the QoS flow a session's dedicated GBR flow takes beside
+// its default flow.
+const DedicatedQFI uint8 = 2
+
 type QosData struct {
 	QFI    uint8
 	Var5qi int32
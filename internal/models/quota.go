@@ -0,0 +1,33 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Quota names an admission quota. The names are also the values of the
+// quota label of app_quota_rejections_total.
+type Quota string
+
+const (
+	QuotaSliceRegisteredUEs  Quota = "slice_registered_ues"
+	QuotaSliceSessions       Quota = "slice_sessions"
+	QuotaSubscriberSessions  Quota = "subscriber_sessions"
+	QuotaDataNetworkSessions Quota = "data_network_sessions"
+)
+
+// QuotaExceededError is a session refused because a quota is full. BackOff
+// is how long the UE should wait before asking again; zero when waiting
+// would not help, as with the subscriber's own limit.
+type QuotaExceededError struct {
+	Quota   Quota
+	Limit   int
+	BackOff time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota of %d reached", e.Quota, e.Limit)
+}
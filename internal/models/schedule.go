@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // schedules name IANA zones; do not depend on the host's zoneinfo
+)
+
+// ScheduleWindow is one recurring weekly interval. Days are three-letter
+// English day names ("mon" ... "sun"); Start and End are "HH:MM" wall-clock
+// times in the schedule's time zone. An End at or before Start runs past
+// midnight into the next day, and "24:00" ends a window at midnight.
+type ScheduleWindow struct {
+	Days  []string `json:"days"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+const minutesPerDay = 24 * 60
+
+// window is a parsed ScheduleWindow, in minutes since midnight.
+type window struct {
+	days       [7]bool
+	start, end int
+}
+
+// Schedule is a set of weekly windows in one time zone. The zero value is
+// never active.
+type Schedule struct {
+	loc     *time.Location
+	windows []window
+}
+
+// ParseSchedule validates a time zone and its windows. An empty zone is UTC.
+func ParseSchedule(timeZone string, windows []ScheduleWindow) (*Schedule, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("time zone %q: %w", timeZone, err)
+	}
+
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("schedule has no windows")
+	}
+
+	s := &Schedule{loc: loc, windows: make([]window, 0, len(windows))}
+
+	for i, w := range windows {
+		parsed, err := parseWindow(w)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i, err)
+		}
+
+		s.windows = append(s.windows, parsed)
+	}
+
+	return s, nil
+}
+
+func parseWindow(w ScheduleWindow) (window, error) {
+	var out window
+
+	if len(w.Days) == 0 {
+		return out, fmt.Errorf("no days")
+	}
+
+	for _, d := range w.Days {
+		wd, ok := weekdayNames[strings.ToLower(d)]
+		if !ok {
+			return out, fmt.Errorf("unknown day %q", d)
+		}
+
+		out.days[wd] = true
+	}
+
+	start, err := parseTimeOfDay(w.Start)
+	if err != nil || start == minutesPerDay {
+		return out, fmt.Errorf("invalid start %q", w.Start)
+	}
+
+	end, err := parseTimeOfDay(w.End)
+	if err != nil {
+		return out, fmt.Errorf("invalid end %q", w.End)
+	}
+
+	if end == start {
+		return out, fmt.Errorf("start and end are both %s", w.Start)
+	}
+
+	out.start, out.end = start, end
+
+	return out, nil
+}
+
+// parseTimeOfDay reads "HH:MM", allowing "24:00".
+func parseTimeOfDay(s string) (int, error) {
+	var h, m int
+
+	if len(s) != 5 || s[2] != ':' {
+		return 0, fmt.Errorf("time of day %q is not HH:MM", s)
+	}
+
+	if _, err := fmt.Sscanf(s, "%02d:%02d", &h, &m); err != nil {
+		return 0, fmt.Errorf("time of day %q is not HH:MM", s)
+	}
+
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("time of day %q out of range", s)
+	}
+
+	return h*60 + m, nil
+}
+
+// ActiveAt reports whether t falls in any window, read in the schedule's
+// time zone so a window follows daylight-saving changes.
+func (s *Schedule) ActiveAt(t time.Time) bool {
+	if s == nil {
+		return false
+	}
+
+	local := t.In(s.loc)
+	day := local.Weekday()
+	minute := local.Hour()*60 + local.Minute()
+	yesterday := (day + 6) % 7
+
+	for _, w := range s.windows {
+		if w.end > w.start {
+			if w.days[day] && minute >= w.start && minute < w.end {
+				return true
+			}
+
+			continue
+		}
+
+		// The window wraps: it covers [start, midnight) on a listed day and
+		// [midnight, end) on the day after.
+		if w.days[day] && minute >= w.start {
+			return true
+		}
+
+		if w.days[yesterday] && minute < w.end {
+			return true
+		}
+	}
+
+	return false
+}
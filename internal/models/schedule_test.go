@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func TestScheduleActiveAt(t *testing.T) {
+	s, err := models.ParseSchedule("America/Toronto", []models.ScheduleWindow{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:30", End: "15:00"},
+		{Days: []string{"Sat"}, Start: "22:00", End: "06:00"},
+	})
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+
+	loc, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"weekday in class", time.Date(2026, 3, 11, 9, 0, 0, 0, loc), true},
+		{"weekday at start", time.Date(2026, 3, 11, 8, 30, 0, 0, loc), true},
+		{"weekday at end", time.Date(2026, 3, 11, 15, 0, 0, 0, loc), false},
+		{"weekday evening", time.Date(2026, 3, 11, 19, 0, 0, 0, loc), false},
+		{"sunday daytime", time.Date(2026, 3, 15, 10, 0, 0, 0, loc), false},
+		{"saturday night", time.Date(2026, 3, 14, 23, 0, 0, 0, loc), true},
+		{"sunday early, carried from saturday", time.Date(2026, 3, 15, 5, 59, 0, 0, loc), true},
+		{"monday early, not carried from sunday", time.Date(2026, 3, 16, 5, 0, 0, 0, loc), false},
+		{"read in the schedule's zone", time.Date(2026, 3, 11, 13, 0, 0, 0, time.UTC), true},
+	} {
+		if got := s.ActiveAt(c.at); got != c.want {
+			t.Errorf("%s: ActiveAt(%s) = %v, want %v", c.name, c.at, got, c.want)
+		}
+	}
+}
+
+func TestScheduleEndOfDay(t *testing.T) {
+	s, err := models.ParseSchedule("", []models.ScheduleWindow{{Days: []string{"wed"}, Start: "00:00", End: "24:00"}})
+	if err != nil {
+		t.Fatalf("ParseSchedule: %v", err)
+	}
+
+	if !s.ActiveAt(time.Date(2026, 3, 11, 23, 59, 0, 0, time.UTC)) {
+		t.Errorf("expected a 00:00-24:00 window to cover the whole day")
+	}
+
+	if s.ActiveAt(time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected a 00:00-24:00 window to end at midnight")
+	}
+}
+
+func TestParseScheduleRejectsMalformed(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		zone    string
+		windows []models.ScheduleWindow
+	}{
+		{"unknown zone", "Mars/Olympus", []models.ScheduleWindow{{Days: []string{"mon"}, Start: "08:00", End: "09:00"}}},
+		{"no windows", "UTC", nil},
+		{"no days", "UTC", []models.ScheduleWindow{{Start: "08:00", End: "09:00"}}},
+		{"unknown day", "UTC", []models.ScheduleWindow{{Days: []string{"monday"}, Start: "08:00", End: "09:00"}}},
+		{"short time", "UTC", []models.ScheduleWindow{{Days: []string{"mon"}, Start: "8:00", End: "09:00"}}},
+		{"minute out of range", "UTC", []models.ScheduleWindow{{Days: []string{"mon"}, Start: "08:60", End: "09:00"}}},
+		{"start at 24:00", "UTC", []models.ScheduleWindow{{Days: []string{"mon"}, Start: "24:00", End: "09:00"}}},
+		{"past 24:00", "UTC", []models.ScheduleWindow{{Days: []string{"mon"}, Start: "08:00", End: "24:30"}}},
+		{"empty window", "UTC", []models.ScheduleWindow{{Days: []string{"mon"}, Start: "08:00", End: "08:00"}}},
+	} {
+		if _, err := models.ParseSchedule(c.zone, c.windows); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestNilScheduleIsNeverActive(t *testing.T) {
+	var s *models.Schedule
+	if s.ActiveAt(time.Now()) {
+		t.Fatal("nil schedule reported active")
+	}
+}
@@ -22,6 +22,7 @@ type EstablishRequest struct {
 	SEID         uint64
 	IMSI         string
 	PolicyID     string
+	DNN          string // selects the user plane serving the data network
 	PDRs         []PDR
 	FARs         []FAR
 	QERs         []QER
@@ -181,12 +182,23 @@ type URR struct {
 	URRID uint32
 }
 
+// S1UBearer is a dedicated EPS bearer of an S1-U session: downlink traffic
+// its QoS flow marks with QFI leaves the default bearer's FAR with the eNB
+// TEID of the bearer's E-RAB.
+type S1UBearer struct {
+	QFI  uint8
+	TEID uint32
+}
+
 type ModifyRequest struct {
 	SEID       uint64
 	PolicyID   string
 	UpdatePDRs []PDR
 	UpdateFARs []FAR
 	UpdateQERs []QER
+	// S1UBearers is every dedicated EPS bearer the session has; any the
+	// UPF holds that it does not list are removed.
+	S1UBearers []S1UBearer
 }
 
 // DeleteRequest asks the UPF to delete a session by its SEID.
@@ -207,6 +219,32 @@ type UsageReport struct {
 	SEID           uint64
 	UplinkVolume   uint64
 	DownlinkVolume uint64
+	// RatingGroups breaks out the traffic of rated network rules. Volumes
+	// of zero-rated rules appear here only, not in the totals above.
+	RatingGroups []RatingGroupUsage
+}
+
+// RatingGroupUsage is the traffic counted against one rating group.
+type RatingGroupUsage struct {
+	RatingGroup    uint32
+	UplinkVolume   uint64
+	DownlinkVolume uint64
+}
+
+// ErrorIndicationReport tells the SMF that an access node answered a
+// downlink G-PDU with a GTP-U Error Indication (TS 29.281 §7.3.1): the peer
+// no longer holds the session's tunnel.
+type ErrorIndicationReport struct {
+	SEID          uint64
+	RemoteTEID    uint32
+	RemoteAddress netip.Addr
+}
+
+// PathFailureReport tells the SMF that an N3 peer stopped answering GTP-U
+// Echo Requests, listing the sessions tunnelled to it.
+type PathFailureReport struct {
+	RemoteAddress netip.Addr
+	SEIDs         []uint64
 }
 
 // FlowReportRequest is sent by UPF to SMF with flow statistics.
@@ -228,6 +266,7 @@ type FlowReportRequest struct {
 // IPv6SessionRegistration carries the metadata the SMF provides to the UPF
 // so the RA responder can reply to Router Solicitations from IPv6 UEs.
 type IPv6SessionRegistration struct {
+	SEID         uint64       // the session, so the registration reaches its UPF
 	UplinkTEID   uint32       // UL TEID allocated by the UPF
 	DownlinkTEID uint32       // DL TEID provided by the gNB
 	GnbN3Addr    netip.Addr   // gNB's N3 transport address (IPv4 or IPv6)
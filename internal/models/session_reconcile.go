@@ -41,4 +41,5 @@ type SessionPolicyDelta struct {
 	MTU                 uint16 // MTU for the PDU session (from data network)
 	IPv4Pool            string // IPv4 pool CIDR (from data network)
 	IPv6Pool            string // IPv6 prefix delegation pool CIDR (from data network)
+	RulesPolicyID       string // policy whose network rules apply (a location variant may name another)
 }
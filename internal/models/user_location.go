@@ -8,3 +8,24 @@ type UserLocation struct {
 	NrLocation    *NrLocation
 	N3gaLocation  *N3gaLocation
 }
+
+// ServingArea is where a UE is served: its tracking area code and cell
+// identity, in the lower-case hex the AMF and MME record them in.
+type ServingArea struct {
+	TAC    string
+	CellID string
+}
+
+// ServingArea returns the tracking area and cell of the NR or E-UTRA
+// location. Any other location, such as an N3IWF one, has no cell and is the
+// zero area.
+func (l UserLocation) ServingArea() ServingArea {
+	switch {
+	case l.NrLocation != nil && l.NrLocation.Tai != nil && l.NrLocation.Ncgi != nil:
+		return ServingArea{TAC: l.NrLocation.Tai.Tac, CellID: l.NrLocation.Ncgi.NrCellID}
+	case l.EutraLocation != nil && l.EutraLocation.Tai != nil && l.EutraLocation.Ecgi != nil:
+		return ServingArea{TAC: l.EutraLocation.Tai.Tac, CellID: l.EutraLocation.Ecgi.EutraCellID}
+	default:
+		return ServingArea{}
+	}
+}
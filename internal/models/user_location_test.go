@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package models_test
+
+import (
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func TestUserLocationServingArea(t *testing.T) {
+	tai := &models.Tai{Tac: "000001"}
+
+	for _, tc := range []struct {
+		name string
+		loc  models.UserLocation
+		want models.ServingArea
+	}{
+		{"empty", models.UserLocation{}, models.ServingArea{}},
+		{
+			"NR",
+			models.UserLocation{NrLocation: &models.NrLocation{Tai: tai, Ncgi: &models.Ncgi{NrCellID: "000000010"}}},
+			models.ServingArea{TAC: "000001", CellID: "000000010"},
+		},
+		{
+			"E-UTRA",
+			models.UserLocation{EutraLocation: &models.EutraLocation{Tai: tai, Ecgi: &models.Ecgi{EutraCellID: "0000010"}}},
+			models.ServingArea{TAC: "000001", CellID: "0000010"},
+		},
+		{"N3IWF has no cell", models.UserLocation{N3gaLocation: &models.N3gaLocation{N3gppTai: tai}}, models.ServingArea{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.loc.ServingArea(); got != tc.want {
+				t.Errorf("ServingArea() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package monitoringevent reports the events of a subscriber's UE to the
+// applications that subscribed to them, in the manner of Nnef
+// MonitoringEvent (TS 29.122 §4.4.2). Subscriptions are recorded in the
+// monitoring_subscriptions table; each node reports the events of the UEs
+// it serves. Registration, connection and location are reported from the
+// transitions the AMF and the MME observe, in the order they make them;
+// PDN connectivity is reported as the SMF establishes and releases
+// sessions.
+package monitoringevent
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// The events an application may subscribe to, as TS 29.122 §5.3.2.4.3
+// names their monitoring types.
+const (
+	// EventUEReachability is reported when the UE moves from idle, or
+	// unregistered, to connected: it can be sent data at once.
+	EventUEReachability = "UE_REACHABILITY"
+	// EventLossOfConnectivity is reported when the UE deregisters, or the
+	// network deregisters it.
+	EventLossOfConnectivity = "LOSS_OF_CONNECTIVITY"
+	// EventLocationReporting is reported when the UE's serving cell or
+	// tracking area changes.
+	EventLocationReporting = "LOCATION_REPORTING"
+	// EventPDNConnectivityStatus is reported when a session of the UE is
+	// established or released.
+	EventPDNConnectivityStatus = "PDN_CONNECTIVITY_STATUS"
+	// EventRoamingStatus is reported when the UE registers, and when its
+	// serving PLMN changes.
+	EventRoamingStatus = "ROAMING_STATUS"
+)
+
+// Events lists the events an application may subscribe to.
+var Events = []string{
+	EventUEReachability,
+	EventLossOfConnectivity,
+	EventLocationReporting,
+	EventPDNConnectivityStatus,
+	EventRoamingStatus,
+}
+
+// The radio access types a UE is reported on.
+const (
+	RATNR    = "NR"
+	RATEUTRA = "EUTRA"
+)
+
+// UE is what the AMF or the MME knows of a registered UE.
+type UE struct {
+	RAT       string
+	Connected bool
+	Location  Location
+}
+
+// Location is the UE's last known serving cell.
+type Location struct {
+	PLMN   models.PlmnID
+	TAC    string
+	CellID string
+}
+
+// LocationOf returns the serving cell of a UE's location. A location
+// without a 3GPP cell, or an empty one, returns false.
+func LocationOf(loc models.UserLocation) (Location, bool) {
+	var (
+		tai    *models.Tai
+		cellID string
+	)
+
+	switch {
+	case loc.NrLocation != nil && loc.NrLocation.Ncgi != nil:
+		tai, cellID = loc.NrLocation.Tai, loc.NrLocation.Ncgi.NrCellID
+	case loc.EutraLocation != nil && loc.EutraLocation.Ecgi != nil:
+		tai, cellID = loc.EutraLocation.Tai, loc.EutraLocation.Ecgi.EutraCellID
+	default:
+		return Location{}, false
+	}
+
+	if tai == nil || tai.PlmnID == nil {
+		return Location{}, false
+	}
+
+	return Location{PLMN: *tai.PlmnID, TAC: tai.Tac, CellID: cellID}, true
+}
+
+// UEs are the AMF and the MME of this node, which observe the transitions
+// of their UEs to the service.
+type UEs interface {
+	// Announce has the AMF and the MME observe the UE with imsi as it
+	// stands, as if it had just changed.
+	Announce(imsi string)
+}
+
+// Session is an established session of a UE.
+type Session struct {
+	Ref        string
+	IMSI       string
+	DNN        string
+	RAT        string
+	IPv4       netip.Addr
+	IPv6Prefix netip.Prefix
+}
+
+// Store is the part of the database the service uses. *db.Database
+// satisfies it.
+type Store interface {
+	ListMonitoringSubscriptions(ctx context.Context) ([]db.MonitoringSubscription, error)
+	RecordMonitoringReport(ctx context.Context, id string) error
+	DeleteExpiredMonitoringSubscriptions(ctx context.Context, now int64) error
+}
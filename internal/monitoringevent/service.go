@@ -0,0 +1,639 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package monitoringevent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// expireInterval is how often expired subscriptions are ended.
+	expireInterval = time.Second
+	// reloadBackstop is the reload of subscriptions that runs when no
+	// wakeup fired.
+	reloadBackstop = 30 * time.Second
+	// notifyTimeout bounds one attempt to notify an application.
+	notifyTimeout = 5 * time.Second
+	// notifyAttempts is how many times a report is posted before it is
+	// dropped, the wait between attempts doubling from retryBackoff.
+	notifyAttempts = 5
+	retryBackoff   = 2 * time.Second
+)
+
+// Service reports the events of the UEs this node serves.
+type Service struct {
+	store   Store
+	wakeup  <-chan struct{}
+	now     func() time.Time
+	http    *http.Client
+	backoff time.Duration
+
+	mu  sync.Mutex
+	ues UEs
+	// subs holds the subscriptions by IMSI, tracked what the AMF and the
+	// MME told of each subscribed UE and sessions the established sessions
+	// by reference.
+	subs     map[string][]db.MonitoringSubscription
+	tracked  map[string]*tracked
+	sessions map[string]Session
+	// ctx ends the deliveries in flight on Stop.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// qmu guards the observations queued for the loop and the IMSIs they
+	// are queued for. The AMF and the MME queue with their UE's lock held,
+	// so nothing else is locked under it.
+	qmu      sync.Mutex
+	queue    []observation
+	watched  map[string]bool
+	observed chan struct{}
+
+	deliveries sync.WaitGroup
+}
+
+// view is a UE as the AMF and the MME have it. An unregistered UE has a
+// zero UE.
+type view struct {
+	registered bool
+	UE
+}
+
+// tracked is what the AMF and the MME told of a subscribed UE: its UE on
+// each access, nil when not registered there. Until it is seeded,
+// observations only bring it up to date; after, each is diffed against
+// last.
+type tracked struct {
+	nr, eutra *UE
+	seeded    bool
+	last      view
+}
+
+// view returns the UE on the access it is reported on: the one it is
+// connected to, 5G when neither, as while it moves between them it is
+// registered on both.
+func (t *tracked) view() view {
+	switch {
+	case t.eutra != nil && t.eutra.Connected && (t.nr == nil || !t.nr.Connected):
+		return view{registered: true, UE: *t.eutra}
+	case t.nr != nil:
+		return view{registered: true, UE: *t.nr}
+	case t.eutra != nil:
+		return view{registered: true, UE: *t.eutra}
+	default:
+		return view{}
+	}
+}
+
+// observation is a transition of a UE on one access, or, with seed set,
+// the mark that the UE's announcement is queued before it.
+type observation struct {
+	imsi       string
+	rat        string
+	registered bool
+	ue         UE
+	seed       bool
+}
+
+// NewService reports the events subscribed to in store. wakeup is
+// signalled when subscriptions changed; nil leaves only the backstop
+// reload.
+func NewService(store Store, wakeup <-chan struct{}) *Service {
+	return &Service{
+		store:    store,
+		wakeup:   wakeup,
+		now:      time.Now,
+		http:     &http.Client{Timeout: notifyTimeout},
+		backoff:  retryBackoff,
+		subs:     make(map[string][]db.MonitoringSubscription),
+		tracked:  make(map[string]*tracked),
+		sessions: make(map[string]Session),
+		watched:  make(map[string]bool),
+		observed: make(chan struct{}, 1),
+	}
+}
+
+// Start loads the subscriptions and launches the loop that reports what
+// ues observe of their UEs. Calls without a paired Stop are no-ops.
+func (s *Service) Start(ues UEs) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	s.ues = ues
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+
+	s.reloadLocked(s.ctx)
+
+	go s.loop(s.ctx, s.done)
+}
+
+// Stop ends the loop and the deliveries in flight. Safe to call when not
+// started.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	done := s.done
+	s.cancel = nil
+	s.done = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.deliveries.Wait()
+}
+
+func (s *Service) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	expire := time.NewTicker(expireInterval)
+	defer expire.Stop()
+
+	reload := time.NewTicker(reloadBackstop)
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wakeup:
+			s.reload(ctx)
+		case <-reload.C:
+			s.reload(ctx)
+		case <-s.observed:
+			s.report(ctx)
+		case <-expire.C:
+			s.Expire(ctx)
+		}
+	}
+}
+
+func (s *Service) reload(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reloadLocked(ctx)
+}
+
+// reloadLocked reads the subscriptions. A UE subscribed to for the first
+// time is announced by the AMF and the MME, so only what changes after is
+// reported.
+func (s *Service) reloadLocked(ctx context.Context) {
+	rows, err := s.store.ListMonitoringSubscriptions(ctx)
+	if err != nil {
+		logger.ExposureLog.Warn("couldn't list monitoring subscriptions", zap.Error(err))
+		return
+	}
+
+	subs := make(map[string][]db.MonitoringSubscription)
+	for _, sub := range rows {
+		subs[sub.IMSI] = append(subs[sub.IMSI], sub)
+	}
+
+	for imsi := range s.tracked {
+		if _, ok := subs[imsi]; !ok {
+			s.forgetLocked(imsi)
+		}
+	}
+
+	s.subs = subs
+
+	for imsi := range subs {
+		if _, ok := s.tracked[imsi]; !ok {
+			s.trackLocked(imsi)
+		}
+	}
+}
+
+// trackLocked starts queueing the observations of the UE with imsi and
+// has it announced. Whatever is queued before the seed that follows the
+// announcement is no newer than it, so the UE is seeded as it stood then.
+func (s *Service) trackLocked(imsi string) {
+	s.tracked[imsi] = &tracked{}
+
+	s.qmu.Lock()
+	s.watched[imsi] = true
+	s.qmu.Unlock()
+
+	if s.ues != nil {
+		s.ues.Announce(imsi)
+	}
+
+	s.enqueue(observation{imsi: imsi, seed: true})
+}
+
+func (s *Service) forgetLocked(imsi string) {
+	delete(s.tracked, imsi)
+
+	s.qmu.Lock()
+	delete(s.watched, imsi)
+	s.qmu.Unlock()
+}
+
+// Observe queues a transition the AMF or the MME made of the UE with imsi
+// on the access rat: ue is the UE as it stands after it, nil once it is
+// no longer registered there. Transitions of UEs no one subscribed to are
+// dropped. It neither blocks nor takes a lock but the queue's, so it may
+// be called with the UE's lock held.
+func (s *Service) Observe(imsi, rat string, ue *UE) {
+	o := observation{imsi: imsi, rat: rat}
+	if ue != nil {
+		o.registered, o.ue = true, *ue
+	}
+
+	s.enqueue(o)
+}
+
+func (s *Service) enqueue(o observation) {
+	s.qmu.Lock()
+	defer s.qmu.Unlock()
+
+	if !s.watched[o.imsi] {
+		return
+	}
+
+	s.queue = append(s.queue, o)
+
+	select {
+	case s.observed <- struct{}{}:
+	default:
+	}
+}
+
+// report reports the queued observations, in the order they were made.
+func (s *Service) report(ctx context.Context) {
+	type pending struct {
+		sub db.MonitoringSubscription
+		r   report
+	}
+
+	s.qmu.Lock()
+	queue := s.queue
+	s.queue = nil
+	s.qmu.Unlock()
+
+	var reports []pending
+
+	now := s.now()
+
+	s.mu.Lock()
+
+	for _, o := range queue {
+		t := s.tracked[o.imsi]
+		if t == nil {
+			continue
+		}
+
+		var ue *UE
+		if o.registered {
+			ue = &o.ue
+		}
+
+		switch o.rat {
+		case RATNR:
+			t.nr = ue
+		case RATEUTRA:
+			t.eutra = ue
+		}
+
+		cur := t.view()
+
+		if o.seed {
+			t.seeded, t.last = true, cur
+			continue
+		}
+
+		if !t.seeded {
+			continue
+		}
+
+		prev := t.last
+		t.last = cur
+
+		for _, sub := range s.subs[o.imsi] {
+			if r, ok := change(sub.Event, o.imsi, prev, cur, now); ok {
+				reports = append(reports, pending{sub, r})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for i := range reports {
+		s.dispatch(ctx, &reports[i].sub, reports[i].r)
+	}
+}
+
+// Expire ends the expired subscriptions.
+func (s *Service) Expire(ctx context.Context) {
+	now := s.now()
+
+	s.mu.Lock()
+	expired := s.expireLocked(now)
+	s.mu.Unlock()
+
+	if !expired {
+		return
+	}
+
+	if err := s.store.DeleteExpiredMonitoringSubscriptions(ctx, now.Unix()); err != nil {
+		logger.ExposureLog.Warn("couldn't delete expired monitoring subscriptions", zap.Error(err))
+	}
+}
+
+// expireLocked drops the subscriptions expired by now and reports whether
+// there were any.
+func (s *Service) expireLocked(now time.Time) bool {
+	expired := false
+
+	for imsi, subs := range s.subs {
+		kept := subs[:0]
+
+		for _, sub := range subs {
+			if sub.ExpiresAt > 0 && sub.ExpiresAt <= now.Unix() {
+				expired = true
+				continue
+			}
+
+			kept = append(kept, sub)
+		}
+
+		if len(kept) == 0 {
+			delete(s.subs, imsi)
+			s.forgetLocked(imsi)
+
+			continue
+		}
+
+		s.subs[imsi] = kept
+	}
+
+	return expired
+}
+
+// change returns the report event makes of a UE that was prev and is cur.
+func change(event, imsi string, prev, cur view, now time.Time) (report, bool) {
+	r := report{MonitoringType: event, IMSI: imsi, EventTime: now.UTC().Format(time.RFC3339)}
+
+	switch event {
+	case EventUEReachability:
+		if !cur.registered || !cur.Connected || prev.registered && prev.Connected {
+			return report{}, false
+		}
+
+		r.ReachabilityType = "DATA"
+		r.RatType = cur.RAT
+	case EventLossOfConnectivity:
+		if !prev.registered || cur.registered {
+			return report{}, false
+		}
+
+		r.LossOfConnectReason = "DEREGISTERED"
+	case EventLocationReporting:
+		if !cur.registered || cur.Location.CellID == "" || cur.Location == prev.Location {
+			return report{}, false
+		}
+
+		r.RatType = cur.RAT
+		r.LocationInfo = &locationInfo{
+			CellID:         cur.Location.CellID,
+			TrackingAreaID: cur.Location.TAC,
+			PlmnID:         plmnID(cur.Location.PLMN),
+		}
+	case EventRoamingStatus:
+		plmn := cur.Location.PLMN
+		if !cur.registered || plmn.Mcc == "" || prev.registered && prev.Location.PLMN == plmn {
+			return report{}, false
+		}
+
+		roaming := !strings.HasPrefix(imsi, plmn.Mcc+plmn.Mnc)
+		r.RoamingStatus = &roaming
+		p := plmnID(plmn)
+		r.PlmnID = &p
+	default:
+		return report{}, false
+	}
+
+	return r, true
+}
+
+// SessionStarted reports an established session to the subscriptions of
+// its UE. It returns at once.
+func (s *Service) SessionStarted(sess Session) {
+	s.mu.Lock()
+	s.sessions[sess.Ref] = sess
+	s.mu.Unlock()
+
+	s.pdnStatus(sess, "CREATED")
+}
+
+// SessionStopped reports a released session to the subscriptions of its
+// UE. It returns at once.
+func (s *Service) SessionStopped(ref string) {
+	s.mu.Lock()
+	sess, ok := s.sessions[ref]
+	delete(s.sessions, ref)
+	s.mu.Unlock()
+
+	if ok {
+		s.pdnStatus(sess, "RELEASED")
+	}
+}
+
+func (s *Service) pdnStatus(sess Session, status string) {
+	s.mu.Lock()
+	ctx := s.ctx
+
+	var subs []db.MonitoringSubscription
+
+	for _, sub := range s.subs[sess.IMSI] {
+		if sub.Event == EventPDNConnectivityStatus {
+			subs = append(subs, sub)
+		}
+	}
+	s.mu.Unlock()
+
+	if ctx == nil || len(subs) == 0 {
+		return
+	}
+
+	info := pdnConnInfo{Status: status, Apn: sess.DNN}
+
+	switch {
+	case sess.IPv4.IsValid() && sess.IPv6Prefix.IsValid():
+		info.PdnType = "IPV4V6"
+	case sess.IPv6Prefix.IsValid():
+		info.PdnType = "IPV6"
+	default:
+		info.PdnType = "IPV4"
+	}
+
+	if sess.IPv4.IsValid() {
+		info.Ipv4Addr = sess.IPv4.String()
+	}
+
+	if sess.IPv6Prefix.IsValid() {
+		info.Ipv6Prefixes = []string{sess.IPv6Prefix.String()}
+	}
+
+	r := report{
+		MonitoringType:  EventPDNConnectivityStatus,
+		IMSI:            sess.IMSI,
+		EventTime:       s.now().UTC().Format(time.RFC3339),
+		RatType:         sess.RAT,
+		PdnConnInfoList: []pdnConnInfo{info},
+	}
+
+	s.deliveries.Go(func() {
+		for i := range subs {
+			s.dispatch(ctx, &subs[i], r)
+		}
+	})
+}
+
+// dispatch counts a report against the reports sub allows, then delivers
+// it.
+func (s *Service) dispatch(ctx context.Context, sub *db.MonitoringSubscription, r report) {
+	if sub.MaxReports > 0 {
+		if err := s.store.RecordMonitoringReport(ctx, sub.ID); err != nil {
+			if !errors.Is(err, db.ErrNotFound) {
+				logger.ExposureLog.Warn("couldn't count monitoring report", zap.String("subscription", sub.ID), zap.Error(err))
+			}
+
+			return
+		}
+	}
+
+	body, err := json.Marshal(notification{
+		Subscription:           "/api/v1/monitoring-subscriptions/" + sub.ID,
+		MonitoringEventReports: []report{r},
+	})
+	if err != nil {
+		return
+	}
+
+	id, url := sub.ID, sub.NotificationURL
+
+	s.deliveries.Go(func() { s.deliver(ctx, id, url, body) })
+}
+
+// deliver posts a notification, trying again on failure until it is
+// accepted, refused, or out of attempts.
+func (s *Service) deliver(ctx context.Context, id, url string, body []byte) {
+	wait := s.backoff
+
+	for attempt := 1; ; attempt++ {
+		retry := s.post(ctx, id, url, body)
+		if !retry {
+			return
+		}
+
+		if attempt == notifyAttempts {
+			logger.ExposureLog.Warn("dropped monitoring report", zap.String("subscription", id), zap.Int("attempts", attempt))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		wait *= 2
+	}
+}
+
+// post makes one attempt at a notification and reports whether it is
+// worth another.
+func (s *Service) post(ctx context.Context, id, url string, body []byte) bool {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		logger.ExposureLog.Warn("invalid monitoring notification URL", zap.String("subscription", id), zap.Error(err))
+		return false
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		logger.ExposureLog.Debug("couldn't notify monitoring report", zap.String("subscription", id), zap.Error(err))
+		return true
+	}
+
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		return false
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	default:
+		logger.ExposureLog.Warn("monitoring notification refused", zap.String("subscription", id), zap.Int("status", resp.StatusCode))
+		return false
+	}
+}
+
+// notification and its parts follow MonitoringNotification (TS 29.122
+// §5.3.2.3.3), naming the UE by IMSI.
+type notification struct {
+	Subscription           string   `json:"subscription"`
+	MonitoringEventReports []report `json:"monitoringEventReports"`
+}
+
+type report struct {
+	MonitoringType      string        `json:"monitoringType"`
+	IMSI                string        `json:"imsi"`
+	EventTime           string        `json:"eventTime"`
+	RatType             string        `json:"ratType,omitempty"`
+	ReachabilityType    string        `json:"reachabilityType,omitempty"`
+	LossOfConnectReason string        `json:"lossOfConnectReason,omitempty"`
+	LocationInfo        *locationInfo `json:"locationInfo,omitempty"`
+	PdnConnInfoList     []pdnConnInfo `json:"pdnConnInfoList,omitempty"`
+	RoamingStatus       *bool         `json:"roamingStatus,omitempty"`
+	PlmnID              *plmnIDJSON   `json:"plmnId,omitempty"`
+}
+
+type locationInfo struct {
+	CellID         string     `json:"cellId"`
+	TrackingAreaID string     `json:"trackingAreaId"`
+	PlmnID         plmnIDJSON `json:"plmnId"`
+}
+
+type pdnConnInfo struct {
+	Status       string   `json:"status"`
+	Apn          string   `json:"apn"`
+	PdnType      string   `json:"pdnType"`
+	Ipv4Addr     string   `json:"ipv4Addr,omitempty"`
+	Ipv6Prefixes []string `json:"ipv6Prefixes,omitempty"`
+}
+
+type plmnIDJSON struct {
+	Mcc string `json:"mcc"`
+	Mnc string `json:"mnc"`
+}
+
+func plmnID(p models.PlmnID) plmnIDJSON {
+	return plmnIDJSON{Mcc: p.Mcc, Mnc: p.Mnc}
+}
@@ -0,0 +1,363 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package monitoringevent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+const testIMSI = "001010000000001"
+
+type fakeStore struct {
+	mu      sync.Mutex
+	rows    []db.MonitoringSubscription
+	expired int
+}
+
+func (f *fakeStore) ListMonitoringSubscriptions(context.Context) ([]db.MonitoringSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.rows), nil
+}
+
+func (f *fakeStore) RecordMonitoringReport(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.rows {
+		if f.rows[i].ID != id {
+			continue
+		}
+
+		f.rows[i].Reports++
+		if f.rows[i].Reports >= f.rows[i].MaxReports {
+			f.rows = slices.Delete(f.rows, i, i+1)
+		}
+
+		return nil
+	}
+
+	return db.ErrNotFound
+}
+
+func (f *fakeStore) DeleteExpiredMonitoringSubscriptions(_ context.Context, now int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.expired++
+	f.rows = slices.DeleteFunc(f.rows, func(sub db.MonitoringSubscription) bool {
+		return sub.ExpiresAt > 0 && sub.ExpiresAt <= now
+	})
+
+	return nil
+}
+
+// fakeUEs announces the UEs set on it, by RAT, as the AMF and the MME
+// would.
+type fakeUEs struct {
+	s   *Service
+	ues map[string]*UE
+}
+
+func (f *fakeUEs) Announce(imsi string) {
+	if imsi != testIMSI {
+		return
+	}
+
+	for _, rat := range []string{RATNR, RATEUTRA} {
+		f.s.Observe(imsi, rat, f.ues[rat])
+	}
+}
+
+// recorder answers notifications with the statuses queued in fail, then
+// 204, keeping the reports it accepted.
+type recorder struct {
+	mu       sync.Mutex
+	fail     []int
+	attempts int
+	reports  []report
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts++
+
+	if len(r.fail) > 0 {
+		w.WriteHeader(r.fail[0])
+		r.fail = r.fail[1:]
+
+		return
+	}
+
+	var n notification
+	if err := json.NewDecoder(req.Body).Decode(&n); err == nil {
+		r.reports = append(r.reports, n.MonitoringEventReports...)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *recorder) got() []report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.reports)
+}
+
+func (r *recorder) types() []string {
+	var out []string
+
+	for _, rep := range r.got() {
+		out = append(out, rep.MonitoringType)
+	}
+
+	slices.Sort(out)
+
+	return out
+}
+
+func newTestService(t *testing.T, subs ...db.MonitoringSubscription) (*Service, *fakeStore, *fakeUEs, *recorder) {
+	t.Helper()
+
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	for i := range subs {
+		subs[i].NotificationURL = srv.URL
+	}
+
+	store := &fakeStore{rows: subs}
+	s := NewService(store, nil)
+	s.now = func() time.Time { return time.Unix(1000, 0) }
+	s.backoff = time.Millisecond
+
+	ues := &fakeUEs{s: s, ues: make(map[string]*UE)}
+	s.ues = ues
+
+	return s, store, ues, rec
+}
+
+func sub(id, event string) db.MonitoringSubscription {
+	return db.MonitoringSubscription{ID: id, IMSI: testIMSI, Event: event}
+}
+
+func inCell(cell string, connected bool) *UE {
+	return &UE{
+		RAT:       RATNR,
+		Connected: connected,
+		Location:  Location{PLMN: models.PlmnID{Mcc: "001", Mnc: "01"}, TAC: "000001", CellID: cell},
+	}
+}
+
+// observe queues a transition of the test UE on 5G.
+func observe(s *Service, ue *UE) {
+	s.Observe(testIMSI, RATNR, ue)
+}
+
+// flush reports the queued transitions and waits for the reports.
+func flush(s *Service) {
+	s.report(context.Background())
+	s.deliveries.Wait()
+}
+
+func TestService_ReportsWhatChanges(t *testing.T) {
+	s, _, ues, rec := newTestService(t,
+		sub("reach", EventUEReachability),
+		sub("loss", EventLossOfConnectivity),
+		sub("loc", EventLocationReporting),
+		sub("roam", EventRoamingStatus),
+	)
+
+	// The UE is registered when the subscriptions are loaded: only what
+	// changes after is reported.
+	ues.ues[RATNR] = inCell("000000001", false)
+	s.reload(context.Background())
+	flush(s)
+
+	if got := rec.types(); len(got) != 0 {
+		t.Fatalf("reports = %v, want none for the UE as it was", got)
+	}
+
+	observe(s, inCell("000000001", true))
+	flush(s)
+
+	if got := rec.types(); !slices.Equal(got, []string{EventUEReachability}) {
+		t.Fatalf("reports = %v, want reachability once connected", got)
+	}
+
+	observe(s, inCell("000000002", true))
+	flush(s)
+
+	reports := rec.got()
+	if len(reports) != 2 || reports[1].LocationInfo == nil || reports[1].LocationInfo.CellID != "000000002" || reports[1].RatType != RATNR {
+		t.Fatalf("reports = %+v, want the new cell reported", reports)
+	}
+
+	observe(s, nil)
+	observe(s, inCell("000000002", false))
+	flush(s)
+
+	want := []string{EventLocationReporting, EventLocationReporting, EventLossOfConnectivity, EventRoamingStatus, EventUEReachability}
+	if got := rec.types(); !slices.Equal(got, want) {
+		t.Fatalf("reports = %v, want %v", got, want)
+	}
+
+	for _, r := range rec.got() {
+		if r.MonitoringType == EventRoamingStatus && (r.RoamingStatus == nil || *r.RoamingStatus || r.PlmnID == nil || r.PlmnID.Mcc != "001") {
+			t.Errorf("roaming report = %+v, want the UE at home in 001-01", r)
+		}
+	}
+}
+
+func TestService_ReportsEveryTransition(t *testing.T) {
+	s, _, ues, rec := newTestService(t, sub("reach", EventUEReachability), sub("loss", EventLossOfConnectivity))
+
+	ues.ues[RATNR] = inCell("000000001", false)
+	s.reload(context.Background())
+
+	// Transitions undone before the service gets to them are each reported.
+	observe(s, inCell("000000001", true))
+	observe(s, inCell("000000001", false))
+	observe(s, nil)
+	observe(s, inCell("000000001", true))
+	flush(s)
+
+	want := []string{EventLossOfConnectivity, EventUEReachability, EventUEReachability}
+	if got := rec.types(); !slices.Equal(got, want) {
+		t.Fatalf("reports = %v, want %v", got, want)
+	}
+}
+
+func TestService_MovesBetweenAccesses(t *testing.T) {
+	s, _, ues, rec := newTestService(t, sub("reach", EventUEReachability), sub("loss", EventLossOfConnectivity))
+
+	ues.ues[RATNR] = inCell("000000001", false)
+	s.reload(context.Background())
+
+	// The UE connects on 4G while its 5G registration is left behind, then
+	// the AMF lets that go: it stays reachable, on 4G.
+	onLTE := inCell("0000001", true)
+	onLTE.RAT = RATEUTRA
+	s.Observe(testIMSI, RATEUTRA, onLTE)
+	observe(s, nil)
+	flush(s)
+
+	reports := rec.got()
+	if len(reports) != 1 || reports[0].MonitoringType != EventUEReachability || reports[0].RatType != RATEUTRA {
+		t.Fatalf("reports = %+v, want reachability on EUTRA only", reports)
+	}
+}
+
+func TestService_MaxReportsAndExpiry(t *testing.T) {
+	once := sub("once", EventUEReachability)
+	once.MaxReports = 1
+	brief := sub("brief", EventLocationReporting)
+	brief.ExpiresAt = 1500
+
+	s, store, _, rec := newTestService(t, once, brief)
+	s.reload(context.Background())
+
+	observe(s, inCell("000000001", true))
+	flush(s)
+
+	if got := rec.types(); !slices.Equal(got, []string{EventLocationReporting, EventUEReachability}) {
+		t.Fatalf("reports = %v", got)
+	}
+
+	// The first report spent "once", which the reload drops.
+	s.reload(context.Background())
+
+	observe(s, nil)
+	observe(s, inCell("000000002", true))
+
+	s.now = func() time.Time { return time.Unix(1500, 0) }
+	s.Expire(context.Background())
+	flush(s)
+
+	if got := rec.types(); len(got) != 2 {
+		t.Fatalf("reports = %v, want none from spent or expired subscriptions", got)
+	}
+
+	if store.expired != 1 || len(store.rows) != 0 {
+		t.Errorf("store = %d expiries, rows %+v, want the expired subscription deleted", store.expired, store.rows)
+	}
+}
+
+func TestService_PDNConnectivityStatus(t *testing.T) {
+	s, _, ues, rec := newTestService(t, sub("pdn", EventPDNConnectivityStatus))
+	s.Start(ues)
+
+	s.SessionStarted(Session{
+		Ref:        "ref-1",
+		IMSI:       testIMSI,
+		DNN:        "internet",
+		RAT:        RATEUTRA,
+		IPv4:       netip.MustParseAddr("10.45.0.3"),
+		IPv6Prefix: netip.MustParsePrefix("2001:db8:1::/64"),
+	})
+	s.SessionStarted(Session{Ref: "ref-2", IMSI: "001019999999999", DNN: "internet"})
+	s.SessionStopped("ref-1")
+	s.SessionStopped("ref-unknown")
+	s.deliveries.Wait()
+	s.Stop()
+
+	reports := rec.got()
+	if len(reports) != 2 {
+		t.Fatalf("reports = %+v, want the subscribed UE's session created and released", reports)
+	}
+
+	var statuses []string
+
+	for _, r := range reports {
+		info := r.PdnConnInfoList[0]
+		if info.Apn != "internet" || info.PdnType != "IPV4V6" || info.Ipv4Addr != "10.45.0.3" || r.RatType != RATEUTRA {
+			t.Errorf("report = %+v", r)
+		}
+
+		statuses = append(statuses, info.Status)
+	}
+
+	slices.Sort(statuses)
+
+	if !slices.Equal(statuses, []string{"CREATED", "RELEASED"}) {
+		t.Errorf("statuses = %v", statuses)
+	}
+}
+
+func TestService_RetriesFailedDeliveries(t *testing.T) {
+	s, _, _, rec := newTestService(t, sub("reach", EventUEReachability), sub("loss", EventLossOfConnectivity))
+	s.reload(context.Background())
+
+	rec.fail = []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+	observe(s, inCell("000000001", true))
+	flush(s)
+
+	if got := rec.types(); !slices.Equal(got, []string{EventUEReachability}) || rec.attempts != 3 {
+		t.Fatalf("reports = %v after %d attempts, want it delivered on the third", got, rec.attempts)
+	}
+
+	// A refused report is not tried again.
+	rec.fail = []int{http.StatusBadRequest}
+	observe(s, nil)
+	flush(s)
+
+	if got := rec.types(); len(got) != 1 || rec.attempts != 4 {
+		t.Fatalf("reports = %v after %d attempts, want the refused one dropped", got, rec.attempts)
+	}
+}
@@ -0,0 +1,830 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultHeartbeatInterval is how often an associated user plane is
+	// probed, and how often an unassociated one is retried.
+	DefaultHeartbeatInterval = 10 * time.Second
+
+	// DefaultUsagePeriod is the Measurement Period of the URRs, matching the
+	// embedded UPF's usage report cadence.
+	DefaultUsagePeriod = 30 * time.Second
+)
+
+var ErrNotAssociated = errors.New("no PFCP association with the user plane")
+
+// ReportHandler receives what remote user planes report about sessions.
+// The SMF implements it.
+type ReportHandler interface {
+	HandleDownlinkDataReport(context.Context, *models.DownlinkDataReport) error
+	HandleUsageReport(context.Context, *models.UsageReport) error
+	HandleErrorIndicationReport(context.Context, *models.ErrorIndicationReport) error
+	HandlePathFailureReport(context.Context, *models.PathFailureReport) error
+}
+
+// Endpoint is the SMF's PFCP function: one UDP socket shared by every user
+// plane it controls.
+type Endpoint struct {
+	*transport
+
+	nodeID   netip.Addr
+	recovery uint32
+	handler  ReportHandler
+
+	heartbeatInterval time.Duration
+	usagePeriod       time.Duration
+
+	mu    sync.Mutex
+	peers map[netip.Addr]*Peer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Peer is a remote user plane: its association state, the sessions the
+// SMF established on it, keyed by the SMF's SEID, and the network rules
+// they are to enforce.
+type Peer struct {
+	e    *Endpoint
+	name string
+	addr netip.AddrPort
+
+	mu         sync.Mutex
+	associated bool
+	recovery   uint32
+	sessions   map[uint64]*session
+	filters    map[string]*policyRules
+	lifted     map[netip.Addr]bool
+}
+
+// session is a session established on a peer. Its fields are guarded by
+// Peer.mu; reqMu serializes the modifications that change its rule PDRs.
+type session struct {
+	reqMu sync.Mutex
+
+	cp           uint64
+	up           uint64
+	policyID     string
+	uplink       *models.PDR
+	downlink     []models.PDR
+	framedRoutes []netip.Prefix
+	tunnel       models.EstablishResponse
+	access       netip.Addr // the access node its downlink is tunnelled to
+	installed    sessionRules
+}
+
+func newSession(req *models.EstablishRequest) *session {
+	s := &session{cp: req.SEID, policyID: req.PolicyID, framedRoutes: req.FramedRoutes}
+
+	for i := range req.PDRs {
+		switch pdr := req.PDRs[i]; {
+		case pdr.PDI.LocalFTEID != nil:
+			s.uplink = &pdr
+		case pdr.PDI.UEIPAddress.IsValid():
+			s.downlink = append(s.downlink, pdr)
+		}
+	}
+
+	s.setAccess(req.FARs)
+
+	return s
+}
+
+// setAccess follows the access node the session's FARs tunnel to.
+func (s *session) setAccess(fars []models.FAR) {
+	for _, far := range fars {
+		if far.ForwardingParameters == nil || far.ForwardingParameters.OuterHeaderCreation == nil {
+			continue
+		}
+
+		ohc := far.ForwardingParameters.OuterHeaderCreation
+
+		ip := ohc.IPv4Address
+		if ohc.Description == models.OuterHeaderCreationGtpUUdpIpv6 {
+			ip = ohc.IPv6Address
+		}
+
+		if addr, ok := netip.AddrFromSlice(ip); ok {
+			s.access = addr.Unmap()
+		}
+	}
+}
+
+// Listen opens the N4 socket on addr, whose IP is also the SMF's Node ID.
+func Listen(addr netip.AddrPort, handler ReportHandler) (*Endpoint, error) {
+	if !addr.Addr().IsValid() || addr.Addr().IsUnspecified() {
+		return nil, fmt.Errorf("the N4 address must be a specific IP, got %q", addr.Addr())
+	}
+
+	t, err := listen(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Endpoint{
+		transport:         t,
+		nodeID:            addr.Addr().Unmap(),
+		recovery:          ToNTP(time.Now()),
+		handler:           handler,
+		heartbeatInterval: DefaultHeartbeatInterval,
+		usagePeriod:       DefaultUsagePeriod,
+		peers:             make(map[netip.Addr]*Peer),
+	}, nil
+}
+
+// AddPeer registers a user plane. Its requests are recognised by source IP,
+// so each user plane needs its own address. Call before Start.
+func (e *Endpoint) AddPeer(name string, addr netip.AddrPort) *Peer {
+	addr = netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+
+	p := &Peer{
+		e:        e,
+		name:     name,
+		addr:     addr,
+		sessions: make(map[uint64]*session),
+		filters:  make(map[string]*policyRules),
+		lifted:   make(map[netip.Addr]bool),
+	}
+
+	e.mu.Lock()
+	e.peers[addr.Addr()] = p
+	e.mu.Unlock()
+
+	return p
+}
+
+// Start serves the socket and keeps every peer associated until Close.
+func (e *Endpoint) Start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+
+	e.wg.Go(func() { e.serve(ctx) })
+
+	e.mu.Lock()
+	peers := slices.Collect(maps.Values(e.peers))
+	e.mu.Unlock()
+
+	for _, p := range peers {
+		e.wg.Go(func() { p.supervise(ctx) })
+	}
+}
+
+// Close stops supervision and closes the socket. Sessions on the user planes
+// are left in place: a restarted SMF cannot address them, and the user plane
+// drops them once it stops hearing from this Node ID.
+func (e *Endpoint) Close() {
+	if e.cancel != nil {
+		e.cancel()
+	}
+
+	_ = e.conn.Close()
+
+	e.wg.Wait()
+}
+
+// UpdateFilters replaces the network rules of a policy on every user plane.
+// Each keeps them even when its sessions could not be brought up to date,
+// so a later call retries only those.
+func (e *Endpoint) UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error {
+	var errs []error
+
+	for _, p := range e.sortedPeers() {
+		errs = append(errs, p.UpdateFilters(ctx, policyID, direction, rules))
+	}
+
+	return errors.Join(errs...)
+}
+
+// UpdatePortalLifted lets ues past their captive portal on every user
+// plane.
+func (e *Endpoint) UpdatePortalLifted(ctx context.Context, ues []netip.Addr) error {
+	var errs []error
+
+	for _, p := range e.sortedPeers() {
+		errs = append(errs, p.UpdatePortalLifted(ctx, ues))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *Endpoint) sortedPeers() []*Peer {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return slices.SortedFunc(maps.Values(e.peers), func(a, b *Peer) int { return strings.Compare(a.name, b.name) })
+}
+
+func (e *Endpoint) serve(ctx context.Context) {
+	e.transport.serve(ctx, logger.SmfLog, e.handleRequest)
+}
+
+func (e *Endpoint) peer(addr netip.Addr) *Peer {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.peers[addr]
+}
+
+func (e *Endpoint) handleRequest(ctx context.Context, from netip.AddrPort, msg *Message) {
+	var peerSEID uint64
+
+	reply := func(t uint8, ies ...IE) {
+		resp := &Message{Type: t, HasSEID: msg.HasSEID, SEID: peerSEID, Sequence: msg.Sequence, IEs: ies}
+		if err := e.send(from, resp); err != nil {
+			logger.SmfLog.Debug("failed to answer a PFCP request", zap.Error(err), zap.Stringer("peer", from))
+		}
+	}
+
+	if msg.Type == MsgHeartbeatRequest {
+		reply(MsgHeartbeatResponse, RecoveryTimeStampIE(e.recovery))
+		return
+	}
+
+	p := e.peer(from.Addr())
+	if p == nil {
+		logger.SmfLog.Debug("ignoring a PFCP request from an unknown user plane",
+			zap.Stringer("peer", from), zap.Uint8("type", msg.Type))
+
+		return
+	}
+
+	switch msg.Type {
+	case MsgAssociationSetupRequest:
+		p.associate(ctx, msg.IEs)
+		reply(MsgAssociationSetupResponse, NodeIDIE(e.nodeID), CauseIE(CauseRequestAccepted), RecoveryTimeStampIE(e.recovery))
+	case MsgAssociationReleaseRequest:
+		p.release(ctx, "the user plane released the association")
+		reply(MsgAssociationReleaseResponse, NodeIDIE(e.nodeID), CauseIE(CauseRequestAccepted))
+	case MsgNodeReportRequest:
+		failures, err := parseNodeReport(msg.IEs)
+		if err != nil {
+			reply(MsgNodeReportResponse, NodeIDIE(e.nodeID), CauseIE(CauseMandatoryIEMissing))
+			return
+		}
+
+		reply(MsgNodeReportResponse, NodeIDIE(e.nodeID), CauseIE(CauseRequestAccepted))
+
+		e.wg.Go(func() { e.dispatchPathFailures(ctx, p, failures) })
+	case MsgSessionReportRequest:
+		upSEID, ok := p.upSEID(msg.SEID)
+		if !ok {
+			reply(MsgSessionReportResponse, CauseIE(CauseSessionContextNotFound))
+			return
+		}
+
+		peerSEID = upSEID
+
+		report, err := parseSessionReport(msg.SEID, msg.IEs)
+		if err != nil {
+			reply(MsgSessionReportResponse, CauseIE(CauseMandatoryIEMissing))
+			return
+		}
+
+		reply(MsgSessionReportResponse, CauseIE(CauseRequestAccepted))
+
+		// Handled off the read loop: the SMF may answer a report with a
+		// modification, whose response this loop has to deliver.
+		e.wg.Go(func() { e.dispatch(ctx, p, report) })
+	default:
+		logger.SmfLog.Debug("ignoring an unsupported PFCP request",
+			zap.Stringer("peer", from), zap.Uint8("type", msg.Type))
+	}
+}
+
+func (e *Endpoint) dispatch(ctx context.Context, p *Peer, r *sessionReport) {
+	if r.downlinkData != nil {
+		if err := e.handler.HandleDownlinkDataReport(ctx, r.downlinkData); err != nil {
+			logger.SmfLog.Warn("failed to handle a downlink data report", zap.Error(err), zap.String("user-plane", p.name))
+		}
+	}
+
+	e.dispatchUsage(ctx, p, r.usage)
+
+	for _, ei := range r.errorIndications {
+		if err := e.handler.HandleErrorIndicationReport(ctx, ei); err != nil {
+			logger.SmfLog.Warn("failed to handle an error indication report", zap.Error(err), zap.String("user-plane", p.name))
+		}
+	}
+}
+
+func (e *Endpoint) dispatchUsage(ctx context.Context, p *Peer, usage []*models.UsageReport) {
+	for _, u := range usage {
+		if err := e.handler.HandleUsageReport(ctx, u); err != nil {
+			logger.SmfLog.Warn("failed to handle a usage report", zap.Error(err), zap.String("user-plane", p.name))
+		}
+	}
+}
+
+// dispatchPathFailures releases the sessions tunnelled to the access nodes
+// the user plane lost its GTP-U path to, as the embedded UPF reports them.
+func (e *Endpoint) dispatchPathFailures(ctx context.Context, p *Peer, failures []netip.Addr) {
+	for _, addr := range failures {
+		report := &models.PathFailureReport{RemoteAddress: addr, SEIDs: p.sessionsTo(addr)}
+
+		if err := e.handler.HandlePathFailureReport(ctx, report); err != nil {
+			logger.SmfLog.Warn("failed to handle a path failure report", zap.Error(err), zap.String("user-plane", p.name))
+		}
+	}
+}
+
+// supervise sets up the association and heartbeats it. A heartbeat that
+// goes unanswered drops the association until setup succeeds again; the
+// sessions survive unless the user plane comes back with a new Recovery
+// Time Stamp, meaning it restarted and lost them.
+func (p *Peer) supervise(ctx context.Context) {
+	ticker := time.NewTicker(p.e.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if p.isAssociated() {
+			p.heartbeat(ctx)
+		} else {
+			p.setup(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Peer) setup(ctx context.Context) {
+	resp, err := p.e.request(ctx, p.addr, &Message{
+		Type: MsgAssociationSetupRequest,
+		IEs:  []IE{NodeIDIE(p.e.nodeID), RecoveryTimeStampIE(p.e.recovery)},
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.SmfLog.Debug("PFCP association setup failed", zap.Error(err), zap.String("user-plane", p.name))
+		}
+
+		return
+	}
+
+	if err := checkCause(resp.IEs); err != nil {
+		logger.SmfLog.Warn("user plane refused the PFCP association", zap.Error(err), zap.String("user-plane", p.name))
+		return
+	}
+
+	p.associate(ctx, resp.IEs)
+}
+
+func (p *Peer) heartbeat(ctx context.Context) {
+	resp, err := p.e.request(ctx, p.addr, &Message{
+		Type: MsgHeartbeatRequest,
+		IEs:  []IE{RecoveryTimeStampIE(p.e.recovery)},
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			p.mu.Lock()
+			p.associated = false
+			p.mu.Unlock()
+
+			logger.SmfLog.Warn("user plane stopped answering PFCP heartbeats", zap.String("user-plane", p.name))
+		}
+
+		return
+	}
+
+	p.checkRecovery(ctx, resp.IEs)
+}
+
+// associate records an association the SMF or the user plane set up.
+func (p *Peer) associate(ctx context.Context, ies []IE) {
+	p.checkRecovery(ctx, ies)
+
+	p.mu.Lock()
+	was := p.associated
+	p.associated = true
+	p.mu.Unlock()
+
+	if !was {
+		logger.SmfLog.Info("PFCP association established", zap.String("user-plane", p.name), zap.Stringer("address", p.addr))
+	}
+}
+
+// checkRecovery compares the peer's Recovery Time Stamp with the last one
+// seen: a change means the user plane restarted without its sessions.
+func (p *Peer) checkRecovery(ctx context.Context, ies []IE) {
+	ie, ok := findIE(ies, IERecoveryTimeStamp)
+	if !ok {
+		return
+	}
+
+	ts, err := ie.uint32()
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	restarted := p.recovery != 0 && p.recovery != ts
+	p.recovery = ts
+	p.mu.Unlock()
+
+	if restarted {
+		p.release(ctx, "the user plane restarted")
+	}
+}
+
+// release forgets the peer's sessions and has the SMF release them, the
+// same way it handles an unreachable access node.
+func (p *Peer) release(ctx context.Context, reason string) {
+	p.mu.Lock()
+	seids := slices.Collect(maps.Keys(p.sessions))
+	clear(p.sessions)
+	p.associated = false
+	p.mu.Unlock()
+
+	logger.SmfLog.Warn("releasing the sessions of a user plane", zap.String("user-plane", p.name),
+		zap.String("reason", reason), zap.Int("sessions", len(seids)))
+
+	if len(seids) == 0 {
+		return
+	}
+
+	p.e.wg.Go(func() {
+		err := p.e.handler.HandlePathFailureReport(ctx, &models.PathFailureReport{RemoteAddress: p.addr.Addr(), SEIDs: seids})
+		if err != nil {
+			logger.SmfLog.Warn("failed to release the sessions of a user plane", zap.Error(err), zap.String("user-plane", p.name))
+		}
+	})
+}
+
+func (p *Peer) isAssociated() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.associated
+}
+
+func (p *Peer) upSEID(seid uint64) (uint64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.sessions[seid]
+	if !ok {
+		return 0, false
+	}
+
+	return s.up, true
+}
+
+func (p *Peer) lookup(seid uint64) *session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.sessions[seid]
+}
+
+// sessionsTo lists the sessions tunnelled to the access node addr.
+func (p *Peer) sessionsTo(addr netip.Addr) []uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var seids []uint64
+
+	for seid, s := range p.sessions {
+		if s.access == addr {
+			seids = append(seids, seid)
+		}
+	}
+
+	slices.Sort(seids)
+
+	return seids
+}
+
+// liftedLocked reports whether the captive portal of s was lifted.
+func (p *Peer) liftedLocked(s *session) bool {
+	for _, pdr := range s.downlink {
+		if p.lifted[portalKey(pdr.PDI.UEIPAddress)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Name is the configured name of the user plane.
+func (p *Peer) Name() string {
+	return p.name
+}
+
+// HasSession reports whether the session, by the SMF's SEID, lives on this
+// user plane.
+func (p *Peer) HasSession(seid uint64) bool {
+	_, ok := p.upSEID(seid)
+	return ok
+}
+
+// EstablishSession creates the session on the user plane, which allocates
+// the uplink tunnel, with the network rules of its policy.
+func (p *Peer) EstablishSession(ctx context.Context, req *models.EstablishRequest) (*models.EstablishResponse, error) {
+	if !p.isAssociated() {
+		return nil, fmt.Errorf("user plane %s: %w", p.name, ErrNotAssociated)
+	}
+
+	s := newSession(req)
+
+	p.mu.Lock()
+	rules, lifted := p.filters[req.PolicyID], p.liftedLocked(s)
+	p.mu.Unlock()
+
+	networkRules, installed := s.buildRules(rules, lifted, 0, true)
+
+	resp, err := p.e.request(ctx, p.addr, &Message{
+		Type:    MsgSessionEstablishmentRequest,
+		HasSEID: true,
+		IEs:     establishmentIEs(p.e.nodeID, req, p.e.usagePeriod, networkRules),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("user plane %s: %w", p.name, err)
+	}
+
+	upSEID, est, err := parseEstablishmentResponse(resp.IEs)
+	if err != nil {
+		return nil, fmt.Errorf("user plane %s: %w", p.name, err)
+	}
+
+	s.up, s.tunnel, s.installed = upSEID, *est, installed
+
+	p.mu.Lock()
+	p.sessions[req.SEID] = s
+	p.mu.Unlock()
+
+	// The rules may have changed while the session was being established,
+	// before UpdateFilters could see it.
+	if err := p.syncRules(ctx, s); err != nil {
+		logger.SmfLog.Warn("failed to update the network rules of a new session",
+			zap.Error(err), zap.String("user-plane", p.name), zap.Uint64("seid", req.SEID))
+	}
+
+	return est, nil
+}
+
+// ModifySession updates the session's forwarding and QoS rules, and its
+// network rules when its policy changes.
+func (p *Peer) ModifySession(ctx context.Context, req *models.ModifyRequest) error {
+	s := p.lookup(req.SEID)
+	if s == nil {
+		return models.ErrSessionNotFound
+	}
+
+	// A dedicated EPS bearer needs its own downlink FAR, which the
+	// modification IEs do not carry yet.
+	if len(req.S1UBearers) > 0 {
+		return fmt.Errorf("user plane %s: dedicated EPS bearers are not supported", p.name)
+	}
+
+	s.reqMu.Lock()
+	defer s.reqMu.Unlock()
+
+	p.mu.Lock()
+	if req.PolicyID != "" {
+		s.policyID = req.PolicyID
+	}
+
+	s.setAccess(req.UpdateFARs)
+	rules, next, ruleIEs := p.rulesChangeLocked(s)
+	p.mu.Unlock()
+
+	resp, err := p.e.request(ctx, p.addr, &Message{
+		Type:    MsgSessionModificationRequest,
+		HasSEID: true,
+		SEID:    s.up,
+		IEs:     append(modificationIEs(req), ruleIEs...),
+	})
+	if err != nil {
+		return fmt.Errorf("user plane %s: %w", p.name, err)
+	}
+
+	p.e.dispatchUsage(ctx, p, responseUsage(req.SEID, resp.IEs))
+
+	if err := checkCause(resp.IEs); err != nil {
+		return p.checkGone(req.SEID, err)
+	}
+
+	if rules {
+		p.mu.Lock()
+		s.installed = next
+		p.mu.Unlock()
+	}
+
+	return nil
+}
+
+// rulesChangeLocked returns the IEs that bring the rule PDRs of s up to
+// date, and what they install, if they are not.
+func (p *Peer) rulesChangeLocked(s *session) (bool, sessionRules, []IE) {
+	rules, lifted := p.filters[s.policyID], p.liftedLocked(s)
+	if s.installed.current(rules, lifted) {
+		return false, sessionRules{}, nil
+	}
+
+	create, next := s.buildRules(rules, lifted, 1-s.installed.bank, false)
+
+	return true, next, append(create, removeRulesIEs(s.installed)...)
+}
+
+// syncRules brings the rule PDRs of s up to date with its policy's rules.
+func (p *Peer) syncRules(ctx context.Context, s *session) error {
+	s.reqMu.Lock()
+	defer s.reqMu.Unlock()
+
+	p.mu.Lock()
+	changed, next, ies := p.rulesChangeLocked(s)
+	p.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+
+	resp, err := p.e.request(ctx, p.addr, &Message{
+		Type:    MsgSessionModificationRequest,
+		HasSEID: true,
+		SEID:    s.up,
+		IEs:     ies,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := checkCause(resp.IEs); err != nil {
+		return p.checkGone(s.cp, err)
+	}
+
+	p.mu.Lock()
+	s.installed = next
+	p.mu.Unlock()
+
+	return nil
+}
+
+// UpdateFilters replaces the network rules of a policy in one direction,
+// and brings the sessions on the policy up to date. Rules the user plane
+// cannot enforce are left out.
+func (p *Peer) UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error {
+	if n := unenforceable(rules); n > 0 {
+		logger.SmfLog.Warn("network rules matching by FQDN are not enforced on a remote user plane",
+			zap.String("user-plane", p.name), zap.String("policy", policyID),
+			zap.Stringer("direction", direction), zap.Int("rules", n))
+	}
+
+	p.mu.Lock()
+
+	next := &policyRules{}
+	if cur := p.filters[policyID]; cur != nil {
+		*next = *cur
+	}
+
+	if direction == models.DirectionUplink {
+		next.uplink = slices.Clone(rules)
+	} else {
+		next.downlink = slices.Clone(rules)
+	}
+
+	if len(next.uplink) == 0 && len(next.downlink) == 0 {
+		delete(p.filters, policyID)
+	} else {
+		p.filters[policyID] = next
+	}
+
+	var sessions []*session
+
+	for _, s := range p.sessions {
+		if s.policyID == policyID {
+			sessions = append(sessions, s)
+		}
+	}
+
+	p.mu.Unlock()
+
+	return p.syncSessions(ctx, sessions)
+}
+
+// UpdatePortalLifted makes the set of UEs let past their captive portal
+// match ues, and brings the sessions whose portal changed up to date.
+func (p *Peer) UpdatePortalLifted(ctx context.Context, ues []netip.Addr) error {
+	p.mu.Lock()
+
+	clear(p.lifted)
+
+	for _, ue := range ues {
+		p.lifted[portalKey(ue)] = true
+	}
+
+	var sessions []*session
+
+	for _, s := range p.sessions {
+		if s.installed.lifted != p.liftedLocked(s) {
+			sessions = append(sessions, s)
+		}
+	}
+
+	p.mu.Unlock()
+
+	return p.syncSessions(ctx, sessions)
+}
+
+// syncSessions brings the rule PDRs of sessions up to date. A user plane
+// that does not answer fails the rest, rather than holding the caller for
+// every session's retransmissions.
+func (p *Peer) syncSessions(ctx context.Context, sessions []*session) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	if !p.isAssociated() {
+		return fmt.Errorf("user plane %s: %w", p.name, ErrNotAssociated)
+	}
+
+	var errs []error
+
+	for _, s := range sessions {
+		err := p.syncRules(ctx, s)
+
+		var cause *CauseError
+
+		switch {
+		case err == nil || errors.Is(err, models.ErrSessionNotFound):
+		case errors.As(err, &cause):
+			errs = append(errs, err)
+		default:
+			return fmt.Errorf("user plane %s: %w", p.name, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DeleteSession removes the session. The final usage the user plane returns
+// is reported before this returns, as the embedded UPF does.
+func (p *Peer) DeleteSession(ctx context.Context, seid uint64) error {
+	upSEID, ok := p.upSEID(seid)
+	if !ok {
+		return models.ErrSessionNotFound
+	}
+
+	resp, err := p.e.request(ctx, p.addr, &Message{
+		Type:    MsgSessionDeletionRequest,
+		HasSEID: true,
+		SEID:    upSEID,
+	})
+	if err != nil {
+		return fmt.Errorf("user plane %s: %w", p.name, err)
+	}
+
+	p.e.dispatchUsage(ctx, p, responseUsage(seid, resp.IEs))
+
+	if err := checkCause(resp.IEs); err != nil {
+		return p.checkGone(seid, err)
+	}
+
+	p.mu.Lock()
+	delete(p.sessions, seid)
+	p.mu.Unlock()
+
+	return nil
+}
+
+// checkGone forgets a session the user plane no longer knows.
+func (p *Peer) checkGone(seid uint64, err error) error {
+	if errors.Is(err, models.ErrSessionNotFound) {
+		p.mu.Lock()
+		delete(p.sessions, seid)
+		p.mu.Unlock()
+	}
+
+	return fmt.Errorf("user plane %s: %w", p.name, err)
+}
+
+// responseUsage returns the usage reports of a response, skipping them if
+// they are malformed: the request itself still succeeded.
+func responseUsage(seid uint64, ies []IE) []*models.UsageReport {
+	r, err := parseSessionReport(seid, ies)
+	if err != nil {
+		logger.SmfLog.Debug("ignoring malformed usage reports", zap.Error(err), zap.Uint64("seid", seid))
+		return nil
+	}
+
+	return r.usage
+}
@@ -0,0 +1,384 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// fakeUPF is a minimal PFCP user plane: it accepts the association,
+// allocates a tunnel per session, and answers with usage on deletion.
+type fakeUPF struct {
+	t    *testing.T
+	conn *net.UDPConn
+
+	mu       sync.Mutex
+	recovery uint32
+	sessions map[uint64]uint64 // UP SEID -> CP SEID
+	requests []*Message
+	replies  map[uint32]chan *Message
+}
+
+func newFakeUPF(t *testing.T) *fakeUPF {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	u := &fakeUPF{t: t, conn: conn, recovery: 100, sessions: make(map[uint64]uint64), replies: make(map[uint32]chan *Message)}
+
+	go u.serve()
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return u
+}
+
+func (u *fakeUPF) addr() netip.AddrPort {
+	return u.conn.LocalAddr().(*net.UDPAddr).AddrPort()
+}
+
+func (u *fakeUPF) serve() {
+	buf := make([]byte, maxMessageSize)
+
+	for {
+		n, from, err := u.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			return
+		}
+
+		msg, err := ParseMessage(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			u.t.Errorf("fake UPF got a malformed message: %v", err)
+			continue
+		}
+
+		if msg.IsResponse() {
+			u.mu.Lock()
+			ch := u.replies[msg.Sequence]
+			u.mu.Unlock()
+
+			if ch != nil {
+				ch <- msg
+			}
+
+			continue
+		}
+
+		if resp := u.handle(msg); resp != nil {
+			resp.Sequence = msg.Sequence
+			_, _ = u.conn.WriteToUDPAddrPort(resp.Marshal(), from)
+		}
+	}
+}
+
+func (u *fakeUPF) handle(msg *Message) *Message {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.requests = append(u.requests, msg)
+
+	switch msg.Type {
+	case MsgAssociationSetupRequest:
+		return &Message{Type: MsgAssociationSetupResponse, IEs: []IE{
+			NodeIDIE(u.addr().Addr()), CauseIE(CauseRequestAccepted), RecoveryTimeStampIE(u.recovery),
+		}}
+	case MsgHeartbeatRequest:
+		return &Message{Type: MsgHeartbeatResponse, IEs: []IE{RecoveryTimeStampIE(u.recovery)}}
+	case MsgSessionEstablishmentRequest:
+		fseid, _ := findIE(msg.IEs, IEFSEID)
+		cpSEID, _ := parseFSEID(fseid)
+		upSEID := 1000 + cpSEID
+		u.sessions[upSEID] = cpSEID
+
+		return &Message{Type: MsgSessionEstablishmentResponse, HasSEID: true, SEID: cpSEID, IEs: []IE{
+			NodeIDIE(u.addr().Addr()),
+			CauseIE(CauseRequestAccepted),
+			FSEIDIE(upSEID, u.addr().Addr()),
+			NewGroupedIE(IECreatedPDR, uint16IE(IEPDRID, 1), NewIE(IEFTEID, []byte{fteidV4, 0, 0, 0, byte(cpSEID), 127, 0, 0, 1})),
+		}}
+	case MsgSessionModificationRequest, MsgSessionDeletionRequest:
+		cpSEID, ok := u.sessions[msg.SEID]
+		if !ok {
+			return &Message{Type: msg.Type + 1, HasSEID: true, IEs: []IE{CauseIE(CauseSessionContextNotFound)}}
+		}
+
+		if msg.Type == MsgSessionModificationRequest {
+			return &Message{Type: msg.Type + 1, HasSEID: true, SEID: cpSEID, IEs: []IE{CauseIE(CauseRequestAccepted)}}
+		}
+
+		delete(u.sessions, msg.SEID)
+
+		return &Message{Type: msg.Type + 1, HasSEID: true, SEID: cpSEID, IEs: []IE{
+			CauseIE(CauseRequestAccepted),
+			NewGroupedIE(IEUsageReportSessionDeletion, uint32IE(IEURRID, 1), volumeIE(volumeUplink|volumeDownlink, 3, 4)),
+		}}
+	}
+
+	return nil
+}
+
+// report sends a Session Report Request to the SMF and returns its answer.
+func (u *fakeUPF) report(t *testing.T, to netip.AddrPort, cpSEID uint64, seq uint32, ies ...IE) *Message {
+	t.Helper()
+
+	ch := make(chan *Message, 1)
+
+	u.mu.Lock()
+	u.replies[seq] = ch
+	u.mu.Unlock()
+
+	msg := &Message{Type: MsgSessionReportRequest, HasSEID: true, SEID: cpSEID, Sequence: seq, IEs: ies}
+	if _, err := u.conn.WriteToUDPAddrPort(msg.Marshal(), to); err != nil {
+		t.Fatalf("send report: %v", err)
+	}
+
+	select {
+	case resp := <-ch:
+		return resp
+	case <-time.After(2 * time.Second):
+		t.Fatal("no Session Report Response")
+		return nil
+	}
+}
+
+func (u *fakeUPF) countRequests(msgType uint8) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	n := 0
+
+	for _, m := range u.requests {
+		if m.Type == msgType {
+			n++
+		}
+	}
+
+	return n
+}
+
+type fakeReports struct {
+	mu           sync.Mutex
+	usage        []*models.UsageReport
+	downlinkData []*models.DownlinkDataReport
+	pathFailures []*models.PathFailureReport
+}
+
+func (f *fakeReports) HandleDownlinkDataReport(_ context.Context, r *models.DownlinkDataReport) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.downlinkData = append(f.downlinkData, r)
+
+	return nil
+}
+
+func (f *fakeReports) HandleUsageReport(_ context.Context, r *models.UsageReport) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.usage = append(f.usage, r)
+
+	return nil
+}
+
+func (f *fakeReports) HandleErrorIndicationReport(context.Context, *models.ErrorIndicationReport) error {
+	return nil
+}
+
+func (f *fakeReports) HandlePathFailureReport(_ context.Context, r *models.PathFailureReport) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pathFailures = append(f.pathFailures, r)
+
+	return nil
+}
+
+func startEndpoint(t *testing.T, upf *fakeUPF, reports *fakeReports) (*Endpoint, *Peer) {
+	t.Helper()
+
+	e, err := Listen(netip.MustParseAddrPort("127.0.0.1:0"), reports)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	e.heartbeatInterval = 20 * time.Millisecond
+	e.t1 = 50 * time.Millisecond
+
+	p := e.AddPeer("edge", upf.addr())
+
+	e.Start(context.Background())
+	t.Cleanup(e.Close)
+
+	waitFor(t, "association", p.isAssociated)
+
+	return e, p
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPeer_SessionLifecycle(t *testing.T) {
+	upf := newFakeUPF(t)
+	reports := &fakeReports{}
+	_, p := startEndpoint(t, upf, reports)
+
+	ctx := context.Background()
+
+	resp, err := p.EstablishSession(ctx, testEstablishRequest())
+	if err != nil {
+		t.Fatalf("establish: %v", err)
+	}
+
+	if resp.N3TEID != 42 || resp.N3IPv4 != netip.MustParseAddr("127.0.0.1") {
+		t.Fatalf("establish response = %+v, want the tunnel the user plane allocated", resp)
+	}
+
+	if err := p.ModifySession(ctx, &models.ModifyRequest{SEID: 42}); err != nil {
+		t.Fatalf("modify: %v", err)
+	}
+
+	if err := p.DeleteSession(ctx, 42); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	reports.mu.Lock()
+	usage := reports.usage
+	reports.mu.Unlock()
+
+	if len(usage) != 1 || usage[0].SEID != 42 || usage[0].UplinkVolume != 3 || usage[0].DownlinkVolume != 4 {
+		t.Fatalf("usage = %+v, want the final report of session 42", usage)
+	}
+
+	if err := p.DeleteSession(ctx, 42); !errors.Is(err, models.ErrSessionNotFound) {
+		t.Fatalf("second delete err = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestPeer_ForgetsSessionTheUserPlaneLost(t *testing.T) {
+	upf := newFakeUPF(t)
+	_, p := startEndpoint(t, upf, &fakeReports{})
+
+	ctx := context.Background()
+
+	if _, err := p.EstablishSession(ctx, testEstablishRequest()); err != nil {
+		t.Fatalf("establish: %v", err)
+	}
+
+	upf.mu.Lock()
+	clear(upf.sessions)
+	upf.mu.Unlock()
+
+	if err := p.ModifySession(ctx, &models.ModifyRequest{SEID: 42}); !errors.Is(err, models.ErrSessionNotFound) {
+		t.Fatalf("modify err = %v, want ErrSessionNotFound", err)
+	}
+
+	if _, ok := p.upSEID(42); ok {
+		t.Fatal("session still tracked after the user plane reported it gone")
+	}
+}
+
+func TestEndpoint_SessionReportReachesSMF(t *testing.T) {
+	upf := newFakeUPF(t)
+	reports := &fakeReports{}
+	e, p := startEndpoint(t, upf, reports)
+
+	if _, err := p.EstablishSession(context.Background(), testEstablishRequest()); err != nil {
+		t.Fatalf("establish: %v", err)
+	}
+
+	resp := upf.report(t, e.LocalAddr(), 42, 9000,
+		uint8IE(IEReportType, ReportTypeDLDR|ReportTypeUSAR),
+		NewGroupedIE(IEDownlinkDataReport, uint16IE(IEPDRID, 2)),
+		NewGroupedIE(IEUsageReportSessionReport, uint32IE(IEURRID, 2), volumeIE(volumeDownlink, 8)),
+	)
+
+	if err := checkCause(resp.IEs); err != nil || resp.SEID != 1042 {
+		t.Fatalf("report response SEID %d cause %v, want accepted toward the UP SEID", resp.SEID, err)
+	}
+
+	waitFor(t, "the reports", func() bool {
+		reports.mu.Lock()
+		defer reports.mu.Unlock()
+
+		return len(reports.downlinkData) == 1 && len(reports.usage) == 1
+	})
+
+	if resp := upf.report(t, e.LocalAddr(), 7, 9001, uint8IE(IEReportType, ReportTypeUSAR)); checkCause(resp.IEs) == nil {
+		t.Fatal("report for an unknown session accepted")
+	}
+}
+
+func TestPeer_RestartReleasesSessions(t *testing.T) {
+	upf := newFakeUPF(t)
+	reports := &fakeReports{}
+	_, p := startEndpoint(t, upf, reports)
+
+	if _, err := p.EstablishSession(context.Background(), testEstablishRequest()); err != nil {
+		t.Fatalf("establish: %v", err)
+	}
+
+	upf.mu.Lock()
+	upf.recovery++
+	upf.mu.Unlock()
+
+	waitFor(t, "the path failure report", func() bool {
+		reports.mu.Lock()
+		defer reports.mu.Unlock()
+
+		return len(reports.pathFailures) == 1
+	})
+
+	if r := reports.pathFailures[0]; len(r.SEIDs) != 1 || r.SEIDs[0] != 42 {
+		t.Fatalf("path failure = %+v, want session 42", r)
+	}
+
+	waitFor(t, "the association to come back", p.isAssociated)
+
+	if upf.countRequests(MsgAssociationSetupRequest) < 2 {
+		t.Fatal("association not set up again after the restart")
+	}
+}
+
+func TestPeer_EstablishRequiresAssociation(t *testing.T) {
+	e, err := Listen(netip.MustParseAddrPort("127.0.0.1:0"), &fakeReports{})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	defer e.Close()
+
+	p := e.AddPeer("edge", netip.MustParseAddrPort("127.0.0.1:1"))
+
+	if _, err := p.EstablishSession(context.Background(), testEstablishRequest()); !errors.Is(err, ErrNotAssociated) {
+		t.Fatalf("err = %v, want ErrNotAssociated", err)
+	}
+}
+
+func TestListen_RequiresSpecificAddress(t *testing.T) {
+	if _, err := Listen(netip.MustParseAddrPort("0.0.0.0:0"), &fakeReports{}); err == nil {
+		t.Fatal("expected an error for an unspecified Node ID")
+	}
+}
@@ -0,0 +1,561 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// Node ID types (TS 29.244 §8.2.38).
+const (
+	nodeIDIPv4 = 0
+	nodeIDIPv6 = 1
+)
+
+// F-TEID flags (TS 29.244 §8.2.3).
+const (
+	fteidV4   = 0x01
+	fteidV6   = 0x02
+	fteidCH   = 0x04
+	fteidCHID = 0x08
+)
+
+// F-SEID flags (TS 29.244 §8.2.37).
+const (
+	fseidV6 = 0x01
+	fseidV4 = 0x02
+)
+
+// UE IP Address flags (TS 29.244 §8.2.62).
+const (
+	ueIPV6 = 0x01
+	ueIPV4 = 0x02
+	ueIPSD = 0x04 // the address is the destination of the matched packets
+)
+
+// Apply Action flags (TS 29.244 §8.2.26).
+const (
+	applyDrop = 0x01
+	applyForw = 0x02
+	applyBuff = 0x04
+	applyNocp = 0x08
+	applyDupl = 0x10
+)
+
+// Remote GTP-U Peer flags (TS 29.244 §8.2.70).
+const (
+	remotePeerV6 = 0x01
+	remotePeerV4 = 0x02
+)
+
+// SDF Filter flags (TS 29.244 §8.2.5). Only a Flow Description is encoded.
+const sdfFD = 0x01
+
+// Volume Measurement flags (TS 29.244 §8.2.44).
+const (
+	volumeTotal    = 0x01
+	volumeUplink   = 0x02
+	volumeDownlink = 0x04
+)
+
+// Outer Header Creation description flags carrying an IPv6 address
+// (TS 29.244 §8.2.56); the IPv4 ones are the rest.
+const ohcIPv6Descriptions = 0x0200 | 0x0800
+
+const (
+	measurementMethodVolume = 0x02 // VOLUM
+	reportingTriggerPerio   = 0x01 // PERIO, first octet
+	usageTriggerTermr       = 0x08 // TERMR, second octet
+)
+
+func uint8IE(t uint16, v uint8) IE {
+	return NewIE(t, []byte{v})
+}
+
+func uint16IE(t uint16, v uint16) IE {
+	return NewIE(t, binary.BigEndian.AppendUint16(nil, v))
+}
+
+func uint32IE(t uint16, v uint32) IE {
+	return NewIE(t, binary.BigEndian.AppendUint32(nil, v))
+}
+
+func (ie IE) uint8() (uint8, error) {
+	if len(ie.Value) < 1 {
+		return 0, fmt.Errorf("IE type %d: %w", ie.Type, errTruncated)
+	}
+
+	return ie.Value[0], nil
+}
+
+func (ie IE) uint16() (uint16, error) {
+	if len(ie.Value) < 2 {
+		return 0, fmt.Errorf("IE type %d: %w", ie.Type, errTruncated)
+	}
+
+	return binary.BigEndian.Uint16(ie.Value), nil
+}
+
+func (ie IE) uint32() (uint32, error) {
+	if len(ie.Value) < 4 {
+		return 0, fmt.Errorf("IE type %d: %w", ie.Type, errTruncated)
+	}
+
+	return binary.BigEndian.Uint32(ie.Value), nil
+}
+
+// NodeIDIE encodes addr as a Node ID.
+func NodeIDIE(addr netip.Addr) IE {
+	if addr.Is4() {
+		return NewIE(IENodeID, append([]byte{nodeIDIPv4}, addr.AsSlice()...))
+	}
+
+	return NewIE(IENodeID, append([]byte{nodeIDIPv6}, addr.AsSlice()...))
+}
+
+// parseNodeID returns the address of an IPv4 or IPv6 Node ID; an FQDN is
+// not supported.
+func parseNodeID(ie IE) (netip.Addr, error) {
+	if len(ie.Value) < 1 {
+		return netip.Addr{}, fmt.Errorf("Node ID: %w", errTruncated)
+	}
+
+	addr, ok := netip.AddrFromSlice(ie.Value[1:])
+
+	switch {
+	case ie.Value[0] == nodeIDIPv4 && ok && addr.Is4():
+		return addr, nil
+	case ie.Value[0] == nodeIDIPv6 && ok && addr.Is6():
+		return addr, nil
+	default:
+		return netip.Addr{}, fmt.Errorf("unsupported Node ID %x", ie.Value)
+	}
+}
+
+// RecoveryTimeStampIE encodes the NTP seconds the function last started at.
+func RecoveryTimeStampIE(ntp uint32) IE {
+	return uint32IE(IERecoveryTimeStamp, ntp)
+}
+
+// CauseIE encodes a Cause.
+func CauseIE(cause uint8) IE {
+	return uint8IE(IECause, cause)
+}
+
+// FSEIDIE encodes a session endpoint: the SEID and the function's address.
+func FSEIDIE(seid uint64, addr netip.Addr) IE {
+	flags := byte(fseidV6)
+	if addr.Is4() {
+		flags = fseidV4
+	}
+
+	b := []byte{flags}
+	b = binary.BigEndian.AppendUint64(b, seid)
+
+	return NewIE(IEFSEID, append(b, addr.AsSlice()...))
+}
+
+// parseFSEID returns the SEID of an F-SEID; the address is not needed
+// because the SMF answers the association peer.
+func parseFSEID(ie IE) (uint64, error) {
+	if len(ie.Value) < 9 {
+		return 0, fmt.Errorf("F-SEID: %w", errTruncated)
+	}
+
+	return binary.BigEndian.Uint64(ie.Value[1:9]), nil
+}
+
+// fteidChooseIE asks the UPF to allocate the F-TEID. PDRs naming the same
+// chooseID share the one tunnel allocated for them.
+func fteidChooseIE(chooseID uint8) IE {
+	return NewIE(IEFTEID, []byte{fteidCH | fteidCHID | fteidV4, chooseID})
+}
+
+// fteidIE encodes an allocated tunnel.
+func fteidIE(teid uint32, v4, v6 netip.Addr) IE {
+	var flags byte
+
+	if v4.IsValid() {
+		flags |= fteidV4
+	}
+
+	if v6.IsValid() {
+		flags |= fteidV6
+	}
+
+	b := binary.BigEndian.AppendUint32([]byte{flags}, teid)
+
+	if v4.IsValid() {
+		b = append(b, v4.AsSlice()...)
+	}
+
+	if v6.IsValid() {
+		b = append(b, v6.AsSlice()...)
+	}
+
+	return NewIE(IEFTEID, b)
+}
+
+// localFTEID is the F-TEID of a PDI: a tunnel, or a request to allocate
+// one, shared by the PDRs with the same choose ID.
+type localFTEID struct {
+	choose      bool
+	chooseID    uint8
+	hasChooseID bool
+	teid        uint32
+	v4, v6      netip.Addr
+}
+
+func decodeFTEID(ie IE) (localFTEID, error) {
+	var f localFTEID
+
+	b := ie.Value
+	if len(b) < 1 {
+		return f, fmt.Errorf("F-TEID: %w", errTruncated)
+	}
+
+	flags := b[0]
+
+	if flags&fteidCH != 0 {
+		f.choose = true
+
+		if flags&fteidCHID != 0 {
+			if len(b) < 2 {
+				return f, fmt.Errorf("F-TEID: %w", errTruncated)
+			}
+
+			f.chooseID, f.hasChooseID = b[1], true
+		}
+
+		return f, nil
+	}
+
+	if len(b) < 5 {
+		return f, fmt.Errorf("F-TEID: %w", errTruncated)
+	}
+
+	f.teid = binary.BigEndian.Uint32(b[1:5])
+	b = b[5:]
+
+	if flags&fteidV4 != 0 {
+		if len(b) < 4 {
+			return f, fmt.Errorf("F-TEID: %w", errTruncated)
+		}
+
+		f.v4 = netip.AddrFrom4([4]byte(b[:4]))
+		b = b[4:]
+	}
+
+	if flags&fteidV6 != 0 {
+		if len(b) < 16 {
+			return f, fmt.Errorf("F-TEID: %w", errTruncated)
+		}
+
+		f.v6 = netip.AddrFrom16([16]byte(b[:16]))
+	}
+
+	return f, nil
+}
+
+// parseFTEID decodes an allocated F-TEID.
+func parseFTEID(ie IE) (teid uint32, v4, v6 netip.Addr, err error) {
+	f, err := decodeFTEID(ie)
+	if err != nil {
+		return 0, v4, v6, err
+	}
+
+	if f.choose {
+		return 0, v4, v6, errors.New("F-TEID: the UPF returned a CHOOSE request instead of a tunnel")
+	}
+
+	return f.teid, f.v4, f.v6, nil
+}
+
+// ueIPAddressIE encodes a UE address matched as the destination of
+// downlink packets.
+func ueIPAddressIE(addr netip.Addr) IE {
+	flags := byte(ueIPSD | ueIPV6)
+	if addr.Is4() {
+		flags = ueIPSD | ueIPV4
+	}
+
+	return NewIE(IEUEIPAddress, append([]byte{flags}, addr.AsSlice()...))
+}
+
+// parseUEIPAddress returns the address of a UE IP Address; the Ella SMF
+// names one family per PDR, so an IPv4 address wins over an IPv6 one.
+func parseUEIPAddress(ie IE) (netip.Addr, error) {
+	b := ie.Value
+	if len(b) < 1 {
+		return netip.Addr{}, fmt.Errorf("UE IP Address: %w", errTruncated)
+	}
+
+	flags := b[0]
+	b = b[1:]
+
+	if flags&ueIPV4 != 0 {
+		if len(b) < 4 {
+			return netip.Addr{}, fmt.Errorf("UE IP Address: %w", errTruncated)
+		}
+
+		return netip.AddrFrom4([4]byte(b[:4])), nil
+	}
+
+	if flags&ueIPV6 != 0 {
+		if len(b) < 16 {
+			return netip.Addr{}, fmt.Errorf("UE IP Address: %w", errTruncated)
+		}
+
+		return netip.AddrFrom16([16]byte(b[:16])), nil
+	}
+
+	return netip.Addr{}, errors.New("UE IP Address without an address")
+}
+
+func applyActionIE(a models.ApplyAction) IE {
+	var flags byte
+
+	for _, f := range []struct {
+		set  bool
+		flag byte
+	}{{a.Drop, applyDrop}, {a.Forw, applyForw}, {a.Buff, applyBuff}, {a.Nocp, applyNocp}, {a.Dupl, applyDupl}} {
+		if f.set {
+			flags |= f.flag
+		}
+	}
+
+	return NewIE(IEApplyAction, []byte{flags, 0})
+}
+
+func parseApplyAction(ie IE) (models.ApplyAction, error) {
+	flags, err := ie.uint8()
+	if err != nil {
+		return models.ApplyAction{}, err
+	}
+
+	return models.ApplyAction{
+		Drop: flags&applyDrop != 0,
+		Forw: flags&applyForw != 0,
+		Buff: flags&applyBuff != 0,
+		Nocp: flags&applyNocp != 0,
+		Dupl: flags&applyDupl != 0,
+	}, nil
+}
+
+// ohcIE encodes GTP-U encapsulation toward the access node.
+func ohcIE(ohc *models.OuterHeaderCreation) IE {
+	b := binary.BigEndian.AppendUint16(nil, ohc.Description)
+	b = binary.BigEndian.AppendUint32(b, ohc.TEID)
+
+	if ohc.Description&ohcIPv6Descriptions != 0 {
+		b = append(b, ohc.IPv6Address.To16()...)
+	} else {
+		b = append(b, ohc.IPv4Address.To4()...)
+	}
+
+	return NewIE(IEOuterHeaderCreation, b)
+}
+
+// parseOHC decodes GTP-U encapsulation toward the access node. S1U is left
+// to the 3GPP Interface Type beside it.
+func parseOHC(ie IE) (*models.OuterHeaderCreation, error) {
+	b := ie.Value
+	if len(b) < 6 {
+		return nil, fmt.Errorf("Outer Header Creation: %w", errTruncated)
+	}
+
+	ohc := &models.OuterHeaderCreation{
+		Description: binary.BigEndian.Uint16(b[0:2]),
+		TEID:        binary.BigEndian.Uint32(b[2:6]),
+	}
+	b = b[6:]
+
+	switch {
+	case ohc.Description&ohcIPv6Descriptions != 0:
+		if len(b) < 16 {
+			return nil, fmt.Errorf("Outer Header Creation: %w", errTruncated)
+		}
+
+		ohc.Description = models.OuterHeaderCreationGtpUUdpIpv6
+		ohc.IPv6Address = net.IP(slices.Clone(b[:16]))
+	case ohc.Description&models.OuterHeaderCreationGtpUUdpIpv4 != 0:
+		if len(b) < 4 {
+			return nil, fmt.Errorf("Outer Header Creation: %w", errTruncated)
+		}
+
+		ohc.Description = models.OuterHeaderCreationGtpUUdpIpv4
+		ohc.IPv4Address = net.IP(slices.Clone(b[:4]))
+	default:
+		return nil, fmt.Errorf("unsupported Outer Header Creation description %#04x", ohc.Description)
+	}
+
+	return ohc, nil
+}
+
+func gateStatusIE(ul, dl uint8) IE {
+	return uint8IE(IEGateStatus, ul<<2|dl)
+}
+
+func parseGateStatus(ie IE) (*models.GateStatus, error) {
+	v, err := ie.uint8()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.GateStatus{ULGate: v >> 2 & 0x03, DLGate: v & 0x03}, nil
+}
+
+// mbrIE encodes uplink and downlink rates in kbps, 5 octets each.
+func mbrIE(ul, dl uint64) IE {
+	b := make([]byte, 10)
+	putUint40(b[0:5], ul)
+	putUint40(b[5:10], dl)
+
+	return NewIE(IEMBR, b)
+}
+
+func parseMBR(ie IE) (*models.MBR, error) {
+	if len(ie.Value) < 10 {
+		return nil, fmt.Errorf("MBR: %w", errTruncated)
+	}
+
+	return &models.MBR{ULMBR: uint40(ie.Value[0:5]), DLMBR: uint40(ie.Value[5:10])}, nil
+}
+
+func putUint40(b []byte, v uint64) {
+	b[0] = byte(v >> 32)
+	binary.BigEndian.PutUint32(b[1:5], uint32(v)) // #nosec: G115 -- low 32 bits by design
+}
+
+func uint40(b []byte) uint64 {
+	return uint64(b[0])<<32 | uint64(binary.BigEndian.Uint32(b[1:5]))
+}
+
+// sdfFilterIE encodes a Flow Description (TS 29.212 §5.4.2).
+func sdfFilterIE(fd string) IE {
+	b := []byte{sdfFD, 0}
+	b = binary.BigEndian.AppendUint16(b, uint16(len(fd))) // #nosec: G115 -- a flow description is short
+
+	return NewIE(IESDFFilter, append(b, fd...))
+}
+
+// parseSDFFilter returns the Flow Description of an SDF Filter. Filters on
+// ToS, SPI or flow label cannot be enforced by the datapath and are refused.
+func parseSDFFilter(ie IE) (string, error) {
+	b := ie.Value
+	if len(b) < 2 {
+		return "", fmt.Errorf("SDF Filter: %w", errTruncated)
+	}
+
+	if b[0] != sdfFD {
+		return "", fmt.Errorf("unsupported SDF Filter flags %#02x", b[0])
+	}
+
+	if len(b) < 4 {
+		return "", fmt.Errorf("SDF Filter: %w", errTruncated)
+	}
+
+	n := int(binary.BigEndian.Uint16(b[2:4]))
+	if len(b) < 4+n {
+		return "", fmt.Errorf("SDF Filter: %w", errTruncated)
+	}
+
+	return string(b[4 : 4+n]), nil
+}
+
+// volumeMeasurementIE encodes the volumes of one report, split by
+// direction.
+func volumeMeasurementIE(ul, dl uint64) IE {
+	b := []byte{volumeTotal | volumeUplink | volumeDownlink}
+	b = binary.BigEndian.AppendUint64(b, ul+dl)
+	b = binary.BigEndian.AppendUint64(b, ul)
+	b = binary.BigEndian.AppendUint64(b, dl)
+
+	return NewIE(IEVolumeMeasurement, b)
+}
+
+// remoteGTPUPeerIE encodes the access node whose GTP-U path failed.
+func remoteGTPUPeerIE(addr netip.Addr) IE {
+	if addr.Is4() {
+		return NewIE(IERemoteGTPUPeer, append([]byte{remotePeerV4}, addr.AsSlice()...))
+	}
+
+	return NewIE(IERemoteGTPUPeer, append([]byte{remotePeerV6}, addr.AsSlice()...))
+}
+
+func parseRemoteGTPUPeer(ie IE) (netip.Addr, error) {
+	b := ie.Value
+	if len(b) < 1 {
+		return netip.Addr{}, fmt.Errorf("Remote GTP-U Peer: %w", errTruncated)
+	}
+
+	switch {
+	case b[0]&remotePeerV4 != 0 && len(b) >= 5:
+		return netip.AddrFrom4([4]byte(b[1:5])), nil
+	case b[0]&remotePeerV6 != 0 && len(b) >= 17:
+		return netip.AddrFrom16([16]byte(b[1:17])), nil
+	default:
+		return netip.Addr{}, fmt.Errorf("Remote GTP-U Peer: %w", errTruncated)
+	}
+}
+
+// parseVolumeMeasurement returns the volumes a usage report carries. Total
+// is reported only when the UPF did not split by direction.
+func parseVolumeMeasurement(ie IE) (total, ul, dl uint64, err error) {
+	b := ie.Value
+	if len(b) < 1 {
+		return 0, 0, 0, fmt.Errorf("Volume Measurement: %w", errTruncated)
+	}
+
+	flags := b[0]
+	b = b[1:]
+
+	next := func() (uint64, error) {
+		if len(b) < 8 {
+			return 0, fmt.Errorf("Volume Measurement: %w", errTruncated)
+		}
+
+		v := binary.BigEndian.Uint64(b[:8])
+		b = b[8:]
+
+		return v, nil
+	}
+
+	for _, f := range []struct {
+		flag byte
+		dst  *uint64
+	}{{volumeTotal, &total}, {volumeUplink, &ul}, {volumeDownlink, &dl}} {
+		if flags&f.flag == 0 {
+			continue
+		}
+
+		if *f.dst, err = next(); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+
+	return total, ul, dl, nil
+}
+
+// parseRemoteFTEID decodes the F-TEID of an Error Indication Report, which
+// names the access node's side of the tunnel.
+func parseRemoteFTEID(ie IE) (uint32, netip.Addr, error) {
+	teid, v4, v6, err := parseFTEID(ie)
+	if err != nil {
+		return 0, netip.Addr{}, err
+	}
+
+	if v4.IsValid() {
+		return teid, v4, nil
+	}
+
+	return teid, v6, nil
+}
@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	pfcpVersion = 1
+
+	headerFlagS = 0x01
+
+	nodeHeaderLen    = 8  // flags, type, length, sequence, spare
+	sessionHeaderLen = 16 // ... plus the 8-octet SEID
+	ieHeaderLen      = 4
+)
+
+var errTruncated = errors.New("truncated PFCP message")
+
+// Message is a PFCP message: the header fields and its top-level IEs.
+// HasSEID is set for session-related messages (types 50 and up), whose header
+// carries the SEID of the receiving function.
+type Message struct {
+	Type     uint8
+	HasSEID  bool
+	SEID     uint64
+	Sequence uint32 // 24 bits
+	IEs      []IE
+}
+
+// IE is a PFCP information element. A grouped IE keeps its children encoded
+// in Value; Children decodes them.
+type IE struct {
+	Type  uint16
+	Value []byte
+}
+
+// NewIE returns an IE carrying value.
+func NewIE(t uint16, value []byte) IE {
+	return IE{Type: t, Value: value}
+}
+
+// NewGroupedIE returns an IE whose value is the encoding of children.
+func NewGroupedIE(t uint16, children ...IE) IE {
+	return IE{Type: t, Value: appendIEs(nil, children)}
+}
+
+// Children decodes the value of a grouped IE.
+func (ie IE) Children() ([]IE, error) {
+	return ParseIEs(ie.Value)
+}
+
+// IsResponse tells responses from requests: up to the Association Release
+// the requests have odd message types, from the Node Report on (12 and
+// up) even types. 11 is the Version Not Supported Response.
+func (m *Message) IsResponse() bool {
+	switch {
+	case m.Type >= MsgNodeReportRequest:
+		return m.Type%2 == 1
+	case m.Type == msgVersionNotSupportedResponse:
+		return true
+	default:
+		return m.Type%2 == 0
+	}
+}
+
+// Marshal encodes m.
+func (m *Message) Marshal() []byte {
+	hdrLen := nodeHeaderLen
+	flags := byte(pfcpVersion << 5)
+
+	if m.HasSEID {
+		hdrLen = sessionHeaderLen
+		flags |= headerFlagS
+	}
+
+	b := make([]byte, hdrLen, hdrLen+64)
+	b[0] = flags
+	b[1] = m.Type
+
+	off := 4
+	if m.HasSEID {
+		binary.BigEndian.PutUint64(b[4:12], m.SEID)
+		off = 12
+	}
+
+	b[off] = byte(m.Sequence >> 16)
+	b[off+1] = byte(m.Sequence >> 8)
+	b[off+2] = byte(m.Sequence)
+
+	b = appendIEs(b, m.IEs)
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)-4)) // #nosec: G115 -- a UDP payload fits in 16 bits
+
+	return b
+}
+
+// ParseMessage decodes a PFCP message. Trailing bytes past the header's
+// length are ignored, as TS 29.244 §7.2.1 allows.
+func ParseMessage(b []byte) (*Message, error) {
+	if len(b) < nodeHeaderLen {
+		return nil, errTruncated
+	}
+
+	if v := b[0] >> 5; v != pfcpVersion {
+		return nil, fmt.Errorf("unsupported PFCP version %d", v)
+	}
+
+	length := int(binary.BigEndian.Uint16(b[2:4])) + 4
+	if length > len(b) {
+		return nil, errTruncated
+	}
+
+	b = b[:length]
+
+	m := &Message{Type: b[1], HasSEID: b[0]&headerFlagS != 0}
+
+	off := 4
+
+	if m.HasSEID {
+		if len(b) < sessionHeaderLen {
+			return nil, errTruncated
+		}
+
+		m.SEID = binary.BigEndian.Uint64(b[4:12])
+		off = 12
+	}
+
+	if len(b) < off+4 {
+		return nil, errTruncated
+	}
+
+	m.Sequence = uint32(b[off])<<16 | uint32(b[off+1])<<8 | uint32(b[off+2])
+
+	ies, err := ParseIEs(b[off+4:])
+	if err != nil {
+		return nil, err
+	}
+
+	m.IEs = ies
+
+	return m, nil
+}
+
+// ParseIEs decodes a run of IEs. Vendor-specific IEs keep their Enterprise ID
+// at the start of Value.
+func ParseIEs(b []byte) ([]IE, error) {
+	var ies []IE
+
+	for len(b) > 0 {
+		if len(b) < ieHeaderLen {
+			return nil, errTruncated
+		}
+
+		t := binary.BigEndian.Uint16(b[0:2])
+		l := int(binary.BigEndian.Uint16(b[2:4]))
+
+		if len(b) < ieHeaderLen+l {
+			return nil, fmt.Errorf("IE type %d: %w", t, errTruncated)
+		}
+
+		ies = append(ies, IE{Type: t, Value: b[ieHeaderLen : ieHeaderLen+l]})
+		b = b[ieHeaderLen+l:]
+	}
+
+	return ies, nil
+}
+
+func appendIEs(b []byte, ies []IE) []byte {
+	for _, ie := range ies {
+		b = binary.BigEndian.AppendUint16(b, ie.Type)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(ie.Value))) // #nosec: G115 -- a UDP payload fits in 16 bits
+		b = append(b, ie.Value...)
+	}
+
+	return b
+}
+
+// findIE returns the first IE of type t.
+func findIE(ies []IE, t uint16) (IE, bool) {
+	for _, ie := range ies {
+		if ie.Type == t {
+			return ie, true
+		}
+	}
+
+	return IE{}, false
+}
+
+// findIEs returns every IE of type t.
+func findIEs(ies []IE, t uint16) []IE {
+	var out []IE
+
+	for _, ie := range ies {
+		if ie.Type == t {
+			out = append(out, ie)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestMessage_SessionRoundTrip(t *testing.T) {
+	in := &Message{
+		Type:     MsgSessionModificationRequest,
+		HasSEID:  true,
+		SEID:     0x0102030405060708,
+		Sequence: 0xabcdef,
+		IEs: []IE{
+			CauseIE(CauseRequestAccepted),
+			NewGroupedIE(IEUpdateFAR, uint32IE(IEFARID, 2), NewIE(IEApplyAction, []byte{applyForw, 0})),
+		},
+	}
+
+	b := in.Marshal()
+
+	if b[0] != 0x21 {
+		t.Fatalf("flags = %#x, want version 1 with the S flag", b[0])
+	}
+
+	if got := int(binary.BigEndian.Uint16(b[2:4])); got != len(b)-4 {
+		t.Fatalf("length = %d, want %d", got, len(b)-4)
+	}
+
+	out, err := ParseMessage(b)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if out.Type != in.Type || !out.HasSEID || out.SEID != in.SEID || out.Sequence != in.Sequence {
+		t.Fatalf("header = %+v, want %+v", out, in)
+	}
+
+	if len(out.IEs) != 2 {
+		t.Fatalf("got %d IEs, want 2", len(out.IEs))
+	}
+
+	children, err := out.IEs[1].Children()
+	if err != nil {
+		t.Fatalf("children: %v", err)
+	}
+
+	if len(children) != 2 || children[0].Type != IEFARID || !bytes.Equal(children[1].Value, []byte{applyForw, 0}) {
+		t.Fatalf("grouped IE children = %+v", children)
+	}
+}
+
+func TestMessage_NodeHeaderHasNoSEID(t *testing.T) {
+	b := (&Message{Type: MsgHeartbeatRequest, Sequence: 7, IEs: []IE{RecoveryTimeStampIE(1)}}).Marshal()
+
+	if len(b) != nodeHeaderLen+ieHeaderLen+4 {
+		t.Fatalf("length = %d, want an 8-octet header and one IE", len(b))
+	}
+
+	out, err := ParseMessage(b)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if out.HasSEID || out.Sequence != 7 {
+		t.Fatalf("got %+v, want a node message with sequence 7", out)
+	}
+}
+
+func TestParseMessage_RejectsBadInput(t *testing.T) {
+	good := (&Message{Type: MsgHeartbeatRequest, IEs: []IE{RecoveryTimeStampIE(1)}}).Marshal()
+
+	truncatedIE := append([]byte(nil), good...)
+	binary.BigEndian.PutUint16(truncatedIE[nodeHeaderLen+2:], 40)
+
+	badVersion := append([]byte(nil), good...)
+	badVersion[0] = 0x40
+
+	for name, b := range map[string][]byte{
+		"short header":      good[:4],
+		"length past end":   good[:len(good)-1],
+		"IE past end":       truncatedIE,
+		"unknown version":   badVersion,
+		"session too short": {0x21, MsgSessionReportRequest, 0, 4, 0, 0, 0, 0},
+	} {
+		if _, err := ParseMessage(b); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMessage_IsResponse(t *testing.T) {
+	for msgType, want := range map[uint8]bool{
+		MsgHeartbeatRequest:            false,
+		MsgAssociationReleaseResponse:  true,
+		MsgNodeReportRequest:           false,
+		MsgNodeReportResponse:          true,
+		MsgSessionEstablishmentRequest: false,
+		MsgSessionReportResponse:       true,
+	} {
+		if got := (&Message{Type: msgType}).IsResponse(); got != want {
+			t.Errorf("IsResponse of type %d = %v, want %v", msgType, got, want)
+		}
+	}
+}
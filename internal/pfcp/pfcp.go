@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+//
+// Package pfcp implements the N4 / Sxb interface (TS 29.244) on both sides.
+// The Endpoint lets the SMF drive a user plane it does not embed: a third
+// party UPF, or a remote site breaking traffic out locally. The Server lets
+// an Ella node be that remote site, serving the sessions of another node's
+// SMF on its embedded datapath.
+//
+// Only the subset of PFCP the two need is encoded: node-level heartbeat,
+// association setup and path failure reports, and session establishment,
+// modification, deletion and reporting. Messages and information elements
+// are plain byte slices with no dependency on the in-process UPF.
+package pfcp
+
+import "time"
+
+// Message types (TS 29.244 §7.3).
+const (
+	MsgHeartbeatRequest             = 1
+	MsgHeartbeatResponse            = 2
+	MsgAssociationSetupRequest      = 5
+	MsgAssociationSetupResponse     = 6
+	MsgAssociationReleaseRequest    = 9
+	MsgAssociationReleaseResponse   = 10
+	msgVersionNotSupportedResponse  = 11
+	MsgNodeReportRequest            = 12
+	MsgNodeReportResponse           = 13
+	MsgSessionEstablishmentRequest  = 50
+	MsgSessionEstablishmentResponse = 51
+	MsgSessionModificationRequest   = 52
+	MsgSessionModificationResponse  = 53
+	MsgSessionDeletionRequest       = 54
+	MsgSessionDeletionResponse      = 55
+	MsgSessionReportRequest         = 56
+	MsgSessionReportResponse        = 57
+)
+
+// Information element types (TS 29.244 §8.1.2).
+const (
+	IECreatePDR                      = 1
+	IEPDI                            = 2
+	IECreateFAR                      = 3
+	IEForwardingParameters           = 4
+	IECreateURR                      = 6
+	IECreateQER                      = 7
+	IECreatedPDR                     = 8
+	IEUpdatePDR                      = 9
+	IEUpdateFAR                      = 10
+	IEUpdateForwardingParameters     = 11
+	IEUpdateQER                      = 14
+	IERemovePDR                      = 15
+	IERemoveFAR                      = 16
+	IERemoveURR                      = 17
+	IERemoveQER                      = 18
+	IECause                          = 19
+	IESourceInterface                = 20
+	IEFTEID                          = 21
+	IESDFFilter                      = 23
+	IEGateStatus                     = 25
+	IEMBR                            = 26
+	IEPrecedence                     = 29
+	IEReportingTriggers              = 37
+	IEReportType                     = 39
+	IEDestinationInterface           = 42
+	IEApplyAction                    = 44
+	IEPDRID                          = 56
+	IEFSEID                          = 57
+	IENodeID                         = 60
+	IEMeasurementMethod              = 62
+	IEUsageReportTrigger             = 63
+	IEMeasurementPeriod              = 64
+	IEVolumeMeasurement              = 66
+	IEUsageReportSessionModification = 78
+	IEUsageReportSessionDeletion     = 79
+	IEUsageReportSessionReport       = 80
+	IEURRID                          = 81
+	IEDownlinkDataReport             = 83
+	IEOuterHeaderCreation            = 84
+	IEUEIPAddress                    = 93
+	IEOuterHeaderRemoval             = 95
+	IERecoveryTimeStamp              = 96
+	IEErrorIndicationReport          = 99
+	IENodeReportType                 = 101
+	IEUserPlanePathFailureReport     = 102
+	IERemoteGTPUPeer                 = 103
+	IEURSEQN                         = 104
+	IEFARID                          = 108
+	IEQERID                          = 109
+	IEQFI                            = 124
+	IEFramedRoute                    = 153
+	IEFramedIPv6Route                = 155
+	IEInterfaceType                  = 160
+)
+
+// Cause values (TS 29.244 §8.2.1).
+const (
+	CauseRequestAccepted        = 1
+	CauseRequestRejected        = 64
+	CauseSessionContextNotFound = 65
+	CauseMandatoryIEMissing     = 66
+	CauseMandatoryIEIncorrect   = 69
+	CauseNoEstablishedPFCPAssoc = 72
+	CauseRuleCreationFailure    = 73
+)
+
+// Interface values of Source and Destination Interface (TS 29.244 §8.2.2).
+const (
+	InterfaceAccess = 0
+	InterfaceCore   = 1
+)
+
+// 3GPP Interface Type values (TS 29.244 §8.2.118) of the access side of a
+// downlink FAR: a 4G bearer's G-PDUs carry no PDU Session Container.
+const (
+	InterfaceTypeS1U = 0
+	InterfaceTypeN3  = 11
+)
+
+// Node Report Type flags (TS 29.244 §8.2.69).
+const NodeReportTypeUPFR = 1 << 0
+
+// Report Type flags (TS 29.244 §8.2.21).
+const (
+	ReportTypeDLDR = 1 << 0
+	ReportTypeUSAR = 1 << 1
+	ReportTypeERIR = 1 << 2
+)
+
+// Port is the registered PFCP UDP port (TS 29.244 §4.2.2).
+const Port = 8805
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) that
+// Recovery Time Stamp counts from and the Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// ToNTP converts t to the 32-bit NTP seconds of a Recovery Time Stamp.
+func ToNTP(t time.Time) uint32 {
+	return uint32(t.Unix() + ntpEpochOffset) // #nosec: G115 -- NTP era 0 wraps in 2036 by design
+}
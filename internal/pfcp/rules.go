@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"net/netip"
+	"slices"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// A remote user plane enforces the network rules of a session's policy as
+// PDRs of their own: one per rule and direction, matching the rule's SDF
+// Filter ahead of the session's PDRs, which match the rest at
+// pdrPrecedence. A rule PDR shares the FAR, QER and URR of the session PDR
+// it shadows, so buffering, the AMBR and usage apply to its traffic too; a
+// deny forwards to ruleFARDrop instead, and a rate limit adds a QER of its
+// own.
+//
+// What a flow description cannot carry is left to the embedded datapath:
+// FQDN rules are skipped, a breakout leaves through the user plane's own
+// data network, rating groups are counted in the session's usage, and a
+// shared rate limit applies per session. A captive portal cannot redirect,
+// so its rules drop instead until the subscriber's redirect is lifted.
+const (
+	// ruleChooseID is the CHOOSE ID of the uplink PDRs, so the rule PDRs
+	// match the tunnel allocated for the session's.
+	ruleChooseID = 1
+
+	// rulePrecedence is the precedence of a direction's first rule.
+	rulePrecedence = 1
+
+	// ruleFARDrop is the FAR deny rules forward to, created with the
+	// session.
+	ruleFARDrop uint32 = 0x8000
+
+	// ruleIDBank numbers the rule PDRs and QERs. A rule change creates the
+	// new ones in the other bank before the old ones are removed, so the
+	// two sets never share an ID in one modification.
+	ruleIDBank = 0x1000
+)
+
+// policyRules are the network rules of a policy, as the reconciler last
+// sent them. They are replaced, never changed, so a session compares them
+// by pointer.
+type policyRules struct {
+	uplink, downlink []models.FilterRule
+}
+
+// sessionRules is what a session's rule PDRs were built from, and the IDs
+// they were created with.
+type sessionRules struct {
+	rules  *policyRules
+	lifted bool
+	bank   int
+	pdrs   []uint16
+	qers   []uint32
+}
+
+func (r sessionRules) current(rules *policyRules, lifted bool) bool {
+	return r.rules == rules && r.lifted == lifted
+}
+
+// buildRules returns the Create PDRs and QERs of rules, numbered in bank.
+// At establishment the uplink rule PDRs ask for the session's tunnel by
+// CHOOSE ID; afterwards they name it.
+func (s *session) buildRules(rules *policyRules, lifted bool, bank int, establishing bool) ([]IE, sessionRules) {
+	built := sessionRules{rules: rules, lifted: lifted, bank: bank}
+	if rules == nil {
+		return nil, built
+	}
+
+	var ies []IE
+
+	nextPDR := uint16((bank + 1) * ruleIDBank) // #nosec: G115 -- two banks
+	nextQER := uint32((bank + 1) * ruleIDBank) // #nosec: G115 -- two banks
+
+	add := func(base models.PDR, precedence int, r models.FilterRule, fd string, pdi []IE) {
+		pdr := []IE{
+			uint16IE(IEPDRID, nextPDR),
+			uint32IE(IEPrecedence, uint32(precedence)), // #nosec: G115 -- bounded by the rule count
+			NewGroupedIE(IEPDI, append(pdi, sdfFilterIE(fd))...),
+		}
+
+		if base.OuterHeaderRemoval != nil {
+			pdr = append(pdr, uint8IE(IEOuterHeaderRemoval, *base.OuterHeaderRemoval))
+		}
+
+		if ruleDrops(r, lifted) {
+			pdr = append(pdr, uint32IE(IEFARID, ruleFARDrop))
+		} else {
+			pdr = append(pdr, uint32IE(IEFARID, base.FARID))
+
+			if base.URRID != 0 {
+				pdr = append(pdr, uint32IE(IEURRID, base.URRID))
+			}
+
+			if base.QERID != 0 {
+				pdr = append(pdr, uint32IE(IEQERID, base.QERID))
+			}
+
+			if r.Action == models.RateLimit && !r.RateLimit.IsZero() {
+				kbps := max(r.RateLimit.Kbps(), 1)
+
+				ies = append(ies, NewGroupedIE(IECreateQER,
+					uint32IE(IEQERID, nextQER),
+					gateStatusIE(models.GateOpen, models.GateOpen),
+					mbrIE(kbps, kbps),
+				))
+				pdr = append(pdr, uint32IE(IEQERID, nextQER))
+				built.qers = append(built.qers, nextQER)
+				nextQER++
+			}
+		}
+
+		ies = append(ies, NewGroupedIE(IECreatePDR, pdr...))
+		built.pdrs = append(built.pdrs, nextPDR)
+		nextPDR++
+	}
+
+	if s.uplink != nil {
+		tunnel := fteidIE(s.tunnel.N3TEID, s.tunnel.N3IPv4, s.tunnel.N3IPv6)
+		if establishing {
+			tunnel = fteidChooseIE(ruleChooseID)
+		}
+
+		for i, r := range rules.uplink {
+			fd, ok := ruleFlowDescription(r, lifted)
+			if !ok {
+				continue
+			}
+
+			add(*s.uplink, rulePrecedence+i, r, fd, []IE{uint8IE(IESourceInterface, InterfaceAccess), tunnel})
+		}
+	}
+
+	for _, base := range s.downlink {
+		ue := base.PDI.UEIPAddress
+
+		for i, r := range rules.downlink {
+			fd, ok := ruleFlowDescription(r, lifted)
+			if !ok || !sameFamily(r.RemotePrefix, ue) {
+				continue
+			}
+
+			add(base, rulePrecedence+i, r, fd, pdiIEs(base.PDI, s.framedRoutes))
+		}
+	}
+
+	return ies, built
+}
+
+// ruleFlowDescription is the flow description of a rule a remote user
+// plane can enforce. A QoS flow marking does not decide what passes, and
+// a lifted captive portal no longer applies.
+func ruleFlowDescription(r models.FilterRule, lifted bool) (string, bool) {
+	switch r.Action {
+	case models.QoSFlow:
+		return "", false
+	case models.Portal, models.PortalDrop:
+		if lifted {
+			return "", false
+		}
+	}
+
+	return flowDescription(r)
+}
+
+func ruleDrops(r models.FilterRule, lifted bool) bool {
+	switch r.Action {
+	case models.Deny:
+		return true
+	case models.Portal, models.PortalDrop:
+		return !lifted
+	default:
+		return false
+	}
+}
+
+// sameFamily reports whether a rule's remote prefix can match traffic of
+// the UE address ue; a rule on any address matches both families.
+func sameFamily(prefix string, ue netip.Addr) bool {
+	if prefix == "" {
+		return true
+	}
+
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return false
+	}
+
+	return p.Addr().Is4() == ue.Is4()
+}
+
+// removeRulesIEs removes the rule PDRs and QERs of r.
+func removeRulesIEs(r sessionRules) []IE {
+	ies := make([]IE, 0, len(r.pdrs)+len(r.qers))
+
+	for _, id := range r.pdrs {
+		ies = append(ies, NewGroupedIE(IERemovePDR, uint16IE(IEPDRID, id)))
+	}
+
+	for _, id := range r.qers {
+		ies = append(ies, NewGroupedIE(IERemoveQER, uint32IE(IEQERID, id)))
+	}
+
+	return ies
+}
+
+// unenforceable counts the rules a remote user plane cannot enforce.
+func unenforceable(rules []models.FilterRule) int {
+	return len(slices.DeleteFunc(slices.Clone(rules), func(r models.FilterRule) bool {
+		_, ok := flowDescription(r)
+		return ok || r.Action == models.QoSFlow
+	}))
+}
+
+// portalKey is the address a captive portal lift is recorded under: the
+// /64 of an IPv6 UE.
+func portalKey(ue netip.Addr) netip.Addr {
+	ue = ue.Unmap()
+	if ue.Is4() {
+		return ue
+	}
+
+	return netip.PrefixFrom(ue, 64).Masked().Addr()
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// flowDescription renders the match of r as the IPFilterRule of an SDF
+// Filter (TS 29.212 §5.4.2). It is written the downlink way, from the
+// remote end to the UE, for both directions: the user plane swaps source
+// and destination on uplink PDRs (TS 29.244 §5.2.1A), and a rule's ports
+// are always the remote end's. A rule matching by FQDN has no flow
+// description.
+func flowDescription(r models.FilterRule) (string, bool) {
+	if r.FQDN != "" {
+		return "", false
+	}
+
+	proto := "ip"
+	if r.Protocol != 0 {
+		proto = strconv.Itoa(int(r.Protocol))
+	}
+
+	remote := "any"
+	if r.RemotePrefix != "" {
+		remote = r.RemotePrefix
+	}
+
+	fd := "permit out " + proto + " from " + remote
+
+	switch {
+	case r.PortLow == 0 && r.PortHigh == 0:
+	case r.PortHigh <= r.PortLow:
+		fd += " " + strconv.Itoa(int(r.PortLow))
+	default:
+		fd += fmt.Sprintf(" %d-%d", r.PortLow, r.PortHigh)
+	}
+
+	return fd + " to assigned", true
+}
+
+// parseFlowDescription reads back the match of a flow description: the
+// protocol, the remote end and its ports. The UE end must be the assigned
+// address, any address, or an address of the UE, which the PDR already
+// matches on; its ports cannot be enforced and are refused.
+func parseFlowDescription(fd string) (models.FilterRule, error) {
+	var r models.FilterRule
+
+	f := strings.Fields(fd)
+	if len(f) < 6 || f[0] != "permit" || f[1] != "out" || f[3] != "from" {
+		return r, fmt.Errorf("unsupported flow description %q", fd)
+	}
+
+	if f[2] != "ip" {
+		proto, err := strconv.ParseUint(f[2], 10, 8)
+		if err != nil {
+			return r, fmt.Errorf("flow description %q: unsupported protocol %q", fd, f[2])
+		}
+
+		r.Protocol = int32(proto)
+	}
+
+	remote, err := parseFlowAddress(f[4])
+	if err != nil {
+		return r, fmt.Errorf("flow description %q: %w", fd, err)
+	}
+
+	r.RemotePrefix = remote
+
+	rest := f[5:]
+
+	if rest[0] != "to" {
+		if r.PortLow, r.PortHigh, err = parseFlowPorts(rest[0]); err != nil {
+			return r, fmt.Errorf("flow description %q: %w", fd, err)
+		}
+
+		rest = rest[1:]
+	}
+
+	if len(rest) != 2 || rest[0] != "to" {
+		return r, fmt.Errorf("unsupported flow description %q", fd)
+	}
+
+	if rest[1] != "assigned" && rest[1] != "any" {
+		if _, err := parseFlowAddress(rest[1]); err != nil {
+			return r, fmt.Errorf("flow description %q: %w", fd, err)
+		}
+	}
+
+	return r, nil
+}
+
+// parseFlowAddress returns the prefix an address field matches, empty for
+// any address.
+func parseFlowAddress(s string) (string, error) {
+	if s == "any" {
+		return "", nil
+	}
+
+	if p, err := netip.ParsePrefix(s); err == nil {
+		return p.Masked().String(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return "", fmt.Errorf("unsupported address %q", s)
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()).String(), nil
+}
+
+// parseFlowPorts reads a port or a port range; lists are not supported.
+func parseFlowPorts(s string) (int32, int32, error) {
+	lowS, highS, isRange := strings.Cut(s, "-")
+
+	low, err := strconv.ParseUint(lowS, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unsupported ports %q", s)
+	}
+
+	if !isRange {
+		return int32(low), int32(low), nil
+	}
+
+	high, err := strconv.ParseUint(highS, 10, 16)
+	if err != nil || high < low {
+		return 0, 0, fmt.Errorf("unsupported ports %q", s)
+	}
+
+	return int32(low), int32(high), nil
+}
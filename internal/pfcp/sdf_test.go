@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func TestFlowDescription_RoundTrip(t *testing.T) {
+	cases := []struct {
+		rule models.FilterRule
+		fd   string
+	}{
+		{models.FilterRule{}, "permit out ip from any to assigned"},
+		{models.FilterRule{Protocol: 17, RemotePrefix: "198.51.100.0/24"}, "permit out 17 from 198.51.100.0/24 to assigned"},
+		{models.FilterRule{Protocol: 6, RemotePrefix: "2001:db8::/32", PortLow: 443, PortHigh: 443}, "permit out 6 from 2001:db8::/32 443 to assigned"},
+		{models.FilterRule{Protocol: 6, PortLow: 8000, PortHigh: 8080}, "permit out 6 from any 8000-8080 to assigned"},
+	}
+
+	for _, tc := range cases {
+		fd, ok := flowDescription(tc.rule)
+		if !ok || fd != tc.fd {
+			t.Fatalf("flowDescription(%+v) = %q, want %q", tc.rule, fd, tc.fd)
+		}
+
+		got, err := parseFlowDescription(fd)
+		if err != nil {
+			t.Fatalf("parseFlowDescription(%q): %v", fd, err)
+		}
+
+		if got != tc.rule {
+			t.Fatalf("parseFlowDescription(%q) = %+v, want %+v", fd, got, tc.rule)
+		}
+	}
+}
+
+func TestFlowDescription_SkipsFQDN(t *testing.T) {
+	if _, ok := flowDescription(models.FilterRule{FQDN: "example.com"}); ok {
+		t.Fatal("a rule matching by FQDN has no flow description")
+	}
+}
+
+func TestParseFlowDescription_OtherSendersForms(t *testing.T) {
+	got, err := parseFlowDescription("permit out 6 from 203.0.113.9 80 to 10.45.0.2")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	want := models.FilterRule{Protocol: 6, RemotePrefix: "203.0.113.9/32", PortLow: 80, PortHigh: 80}
+	if got != want {
+		t.Fatalf("rule = %+v, want %+v", got, want)
+	}
+
+	for _, fd := range []string{
+		"deny out ip from any to assigned",
+		"permit in ip from any to assigned",
+		"permit out ip from any to assigned 5000",
+		"permit out tcp from any to assigned",
+		"permit out ip from any 80,443 to assigned",
+		"permit out ip from example.com to assigned",
+	} {
+		if _, err := parseFlowDescription(fd); err == nil {
+			t.Errorf("parseFlowDescription(%q) succeeded, want an error", fd)
+		}
+	}
+}
@@ -0,0 +1,965 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"go.uber.org/zap"
+)
+
+// UserPlane is the datapath a Server installs the sessions of its control
+// planes on: the embedded UPF, as the node's own SMF drives it.
+type UserPlane interface {
+	EstablishSession(ctx context.Context, req *models.EstablishRequest) (*models.EstablishResponse, error)
+	ModifySession(ctx context.Context, req *models.ModifyRequest) error
+	FlushUsage(ctx context.Context, seid uint64)
+	DeleteSession(ctx context.Context, seid uint64) error
+	UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error
+	RegisterIPv6Session(ctx context.Context, reg *models.IPv6SessionRegistration) error
+	UnregisterIPv6Session(ctx context.Context, seid uint64, ulTEID uint32) error
+}
+
+const (
+	// serverSEIDs marks the SEIDs a Server installs sessions under on the
+	// embedded UPF, apart from those of the node's own SMF, which count up
+	// from one.
+	serverSEIDs uint64 = 1 << 63
+
+	// controlPlaneTimeout is how long an associated control plane may go
+	// unheard, heartbeating every DefaultHeartbeatInterval, before its
+	// sessions are released.
+	controlPlaneTimeout = 6 * DefaultHeartbeatInterval
+)
+
+// Server lets the SMF of another node drive the embedded UPF over N4, as it
+// would a third party user plane. Each session is installed under a SEID of
+// its own, and the network rules it was sent as SDF-filtered PDRs become
+// the filters of a policy of its own.
+type Server struct {
+	*transport
+
+	nodeID   netip.Addr
+	recovery uint32
+	up       UserPlane
+	maxRules int
+
+	mu            sync.Mutex
+	controlPlanes map[netip.Addr]*controlPlane
+	sessions      map[uint64]*upSession
+	nextSEID      uint64
+	inflight      map[requestKey]bool
+	answered      map[requestKey]answer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// controlPlane is an SMF allowed to associate. Its fields are guarded by
+// Server.mu.
+type controlPlane struct {
+	name string
+	addr netip.Addr
+
+	from       netip.AddrPort
+	associated bool
+	recovery   uint32
+	lastHeard  time.Time
+}
+
+// requestKey identifies a request, so a retransmission is answered with
+// the response already sent rather than handled twice.
+type requestKey struct {
+	from     netip.AddrPort
+	sequence uint32
+}
+
+type answer struct {
+	resp *Message
+	at   time.Time
+}
+
+// upSession is a session a control plane established. reqMu serializes
+// its requests; the usage fields are guarded by usageMu, as the embedded
+// UPF reports from its own goroutines, and state and rules are changed
+// under both.
+type upSession struct {
+	reqMu sync.Mutex
+
+	seid   uint64
+	cpSEID uint64
+	cp     *controlPlane
+	tunnel models.EstablishResponse
+	state  ruleState
+	rules  translatedRules
+	ipv6   *models.IPv6SessionRegistration // the RA registration, if any
+
+	usageMu   sync.Mutex
+	pendingUL uint64
+	pendingDL uint64
+	sending   bool
+	deleting  bool
+	seqn      uint32
+}
+
+// NewServer opens the N4 socket on addr, whose IP is also the user plane's
+// Node ID. maxRules is how many network rules a session's policy may hold
+// per direction.
+func NewServer(addr netip.AddrPort, up UserPlane, maxRules int) (*Server, error) {
+	if !addr.Addr().IsValid() || addr.Addr().IsUnspecified() {
+		return nil, fmt.Errorf("the N4 address must be a specific IP, got %q", addr.Addr())
+	}
+
+	t, err := listen(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		transport:     t,
+		nodeID:        addr.Addr().Unmap(),
+		recovery:      ToNTP(time.Now()),
+		up:            up,
+		maxRules:      maxRules,
+		controlPlanes: make(map[netip.Addr]*controlPlane),
+		sessions:      make(map[uint64]*upSession),
+		nextSEID:      serverSEIDs,
+		inflight:      make(map[requestKey]bool),
+		answered:      make(map[requestKey]answer),
+	}, nil
+}
+
+// AddControlPlane allows the SMF at addr to associate. Call before Start.
+func (s *Server) AddControlPlane(name string, addr netip.Addr) {
+	addr = addr.Unmap()
+
+	s.mu.Lock()
+	s.controlPlanes[addr] = &controlPlane{name: name, addr: addr}
+	s.mu.Unlock()
+}
+
+// Start serves the socket until Close.
+func (s *Server) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Go(func() { s.transport.serve(ctx, logger.UpfLog, s.handleRequest) })
+	s.wg.Go(func() { s.supervise(ctx) })
+}
+
+// Close stops serving and releases every session of the control planes.
+func (s *Server) Close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	_ = s.conn.Close()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	cps := slices.Collect(maps.Values(s.controlPlanes))
+	s.mu.Unlock()
+
+	for _, cp := range cps {
+		s.purge(context.Background(), cp, "the user plane is shutting down")
+	}
+}
+
+// Owns reports whether seid is the SEID of a session a control plane
+// established, so its reports are sent back over N4.
+func (s *Server) Owns(seid uint64) bool {
+	return seid&serverSEIDs != 0
+}
+
+// policyID is the policy the network rules of a session are installed
+// under.
+func policyID(seid uint64) string {
+	return "pfcp/" + strconv.FormatUint(seid, 10)
+}
+
+// supervise releases the sessions of control planes gone silent, and
+// forgets the responses no retransmission can ask for anymore.
+func (s *Server) supervise(ctx context.Context) {
+	ticker := time.NewTicker(DefaultHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+
+		var silent []*controlPlane
+
+		s.mu.Lock()
+		for _, cp := range s.controlPlanes {
+			if cp.associated && now.Sub(cp.lastHeard) > controlPlaneTimeout {
+				silent = append(silent, cp)
+			}
+		}
+
+		maps.DeleteFunc(s.answered, func(_ requestKey, a answer) bool {
+			return now.Sub(a.at) > s.t1*time.Duration(s.n1+1)
+		})
+		s.mu.Unlock()
+
+		for _, cp := range silent {
+			s.purge(ctx, cp, "the control plane stopped heartbeating")
+		}
+	}
+}
+
+// handleRequest answers a retransmission from the responses already sent,
+// and handles anything else off the read loop: the embedded UPF may take a
+// while, and a session request may wait on the reports of another.
+func (s *Server) handleRequest(ctx context.Context, from netip.AddrPort, msg *Message) {
+	key := requestKey{from: from, sequence: msg.Sequence}
+
+	s.mu.Lock()
+	cp := s.controlPlanes[from.Addr()]
+
+	if cp != nil && cp.from == from {
+		cp.lastHeard = time.Now()
+	}
+
+	a, done := s.answered[key]
+	busy := s.inflight[key]
+
+	if !done && !busy {
+		s.inflight[key] = true
+	}
+	s.mu.Unlock()
+
+	switch {
+	case done:
+		_ = s.send(from, a.resp)
+		return
+	case busy:
+		return
+	}
+
+	s.wg.Go(func() {
+		resp := s.respond(ctx, cp, from, msg)
+
+		s.mu.Lock()
+		delete(s.inflight, key)
+
+		if resp != nil {
+			s.answered[key] = answer{resp: resp, at: time.Now()}
+		}
+		s.mu.Unlock()
+
+		if resp == nil {
+			return
+		}
+
+		if err := s.send(from, resp); err != nil {
+			logger.UpfLog.Debug("failed to answer a PFCP request", zap.Error(err), zap.Stringer("peer", from))
+		}
+	})
+}
+
+func (s *Server) respond(ctx context.Context, cp *controlPlane, from netip.AddrPort, msg *Message) *Message {
+	reply := func(t uint8, seid uint64, ies ...IE) *Message {
+		return &Message{Type: t, HasSEID: msg.HasSEID, SEID: seid, Sequence: msg.Sequence, IEs: ies}
+	}
+
+	if msg.Type == MsgHeartbeatRequest {
+		if cp != nil {
+			s.checkRecovery(ctx, cp, msg.IEs)
+		}
+
+		return reply(MsgHeartbeatResponse, 0, RecoveryTimeStampIE(s.recovery))
+	}
+
+	if cp == nil {
+		logger.UpfLog.Debug("ignoring a PFCP request from an unknown control plane",
+			zap.Stringer("peer", from), zap.Uint8("type", msg.Type))
+
+		if msg.Type == MsgAssociationSetupRequest {
+			return reply(MsgAssociationSetupResponse, 0, NodeIDIE(s.nodeID), CauseIE(CauseRequestRejected))
+		}
+
+		return nil
+	}
+
+	switch msg.Type {
+	case MsgAssociationSetupRequest:
+		s.associate(ctx, cp, from, msg.IEs)
+		return reply(MsgAssociationSetupResponse, 0, NodeIDIE(s.nodeID), CauseIE(CauseRequestAccepted), RecoveryTimeStampIE(s.recovery))
+	case MsgAssociationReleaseRequest:
+		s.purge(ctx, cp, "the control plane released the association")
+		return reply(MsgAssociationReleaseResponse, 0, NodeIDIE(s.nodeID), CauseIE(CauseRequestAccepted))
+	case MsgSessionEstablishmentRequest, MsgSessionModificationRequest, MsgSessionDeletionRequest:
+	default:
+		logger.UpfLog.Debug("ignoring an unsupported PFCP request",
+			zap.Stringer("peer", from), zap.Uint8("type", msg.Type))
+
+		return nil
+	}
+
+	if !s.isAssociated(cp, from) {
+		return reply(msg.Type+1, 0, CauseIE(CauseNoEstablishedPFCPAssoc))
+	}
+
+	if msg.Type == MsgSessionEstablishmentRequest {
+		cpSEID, ies := s.establish(ctx, cp, msg.IEs)
+		return reply(MsgSessionEstablishmentResponse, cpSEID, ies...)
+	}
+
+	sess := s.lookup(cp, msg.SEID)
+
+	switch msg.Type {
+	case MsgSessionModificationRequest:
+		if sess == nil {
+			return reply(MsgSessionModificationResponse, 0, CauseIE(CauseSessionContextNotFound))
+		}
+
+		return reply(MsgSessionModificationResponse, sess.cpSEID, s.modify(ctx, sess, msg.IEs)...)
+	default:
+		if sess == nil {
+			return reply(MsgSessionDeletionResponse, 0, CauseIE(CauseSessionContextNotFound))
+		}
+
+		return reply(MsgSessionDeletionResponse, sess.cpSEID, s.delete(ctx, sess)...)
+	}
+}
+
+// associate records an association, on the port the control plane sent
+// from: its reports go back there.
+func (s *Server) associate(ctx context.Context, cp *controlPlane, from netip.AddrPort, ies []IE) {
+	s.checkRecovery(ctx, cp, ies)
+
+	s.mu.Lock()
+	was := cp.associated
+	cp.associated, cp.from, cp.lastHeard = true, from, time.Now()
+	s.mu.Unlock()
+
+	if !was {
+		logger.UpfLog.Info("PFCP association established", zap.String("control-plane", cp.name), zap.Stringer("address", from))
+	}
+}
+
+// checkRecovery compares the control plane's Recovery Time Stamp with the
+// last one seen: a change means it restarted and cannot address its
+// sessions anymore.
+func (s *Server) checkRecovery(ctx context.Context, cp *controlPlane, ies []IE) {
+	ie, ok := findIE(ies, IERecoveryTimeStamp)
+	if !ok {
+		return
+	}
+
+	ts, err := ie.uint32()
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	restarted := cp.recovery != 0 && cp.recovery != ts
+	cp.recovery = ts
+	s.mu.Unlock()
+
+	if restarted {
+		s.purge(ctx, cp, "the control plane restarted")
+	}
+}
+
+func (s *Server) isAssociated(cp *controlPlane, from netip.AddrPort) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return cp.associated && cp.from == from
+}
+
+// purge drops the association of cp and removes its sessions from the
+// embedded UPF.
+func (s *Server) purge(ctx context.Context, cp *controlPlane, reason string) {
+	s.mu.Lock()
+	cp.associated = false
+
+	var sessions []*upSession
+
+	for _, sess := range s.sessions {
+		if sess.cp == cp {
+			sessions = append(sessions, sess)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(sessions) == 0 {
+		return
+	}
+
+	logger.UpfLog.Warn("releasing the sessions of a control plane", zap.String("control-plane", cp.name),
+		zap.String("reason", reason), zap.Int("sessions", len(sessions)))
+
+	for _, sess := range sessions {
+		_ = s.delete(ctx, sess)
+	}
+}
+
+func (s *Server) lookup(cp *controlPlane, seid uint64) *upSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess := s.sessions[seid]
+	if sess == nil || sess.cp != cp {
+		return nil
+	}
+
+	return sess
+}
+
+// establish installs a session and returns the control plane's SEID and
+// the response IEs.
+func (s *Server) establish(ctx context.Context, cp *controlPlane, ies []IE) (uint64, []IE) {
+	fseid, ok := findIE(ies, IEFSEID)
+	if !ok {
+		return 0, []IE{NodeIDIE(s.nodeID), CauseIE(CauseMandatoryIEMissing)}
+	}
+
+	cpSEID, err := parseFSEID(fseid)
+	if err != nil {
+		return 0, []IE{NodeIDIE(s.nodeID), CauseIE(CauseMandatoryIEIncorrect)}
+	}
+
+	reject := func(cause uint8, err error) (uint64, []IE) {
+		logger.UpfLog.Warn("refused a PFCP session establishment", zap.Error(err),
+			zap.String("control-plane", cp.name), zap.Uint64("cp-seid", cpSEID))
+
+		return cpSEID, []IE{NodeIDIE(s.nodeID), CauseIE(cause)}
+	}
+
+	state := newRuleState()
+	if err := state.apply(ies, true); err != nil {
+		return reject(CauseMandatoryIEIncorrect, err)
+	}
+
+	rules, err := state.translate(s.maxRules)
+	if err != nil {
+		return reject(CauseRuleCreationFailure, err)
+	}
+
+	for _, pdr := range state.pdrs {
+		if pdr.access && len(pdr.flows) == 0 && !pdr.fteid.choose {
+			return reject(CauseRuleCreationFailure, errors.New("the uplink PDR must let the user plane choose its F-TEID"))
+		}
+	}
+
+	s.mu.Lock()
+	s.nextSEID++
+	sess := &upSession{seid: s.nextSEID, cpSEID: cpSEID, cp: cp, state: state}
+	s.mu.Unlock()
+
+	if err := s.installFilters(ctx, sess.seid, translatedRules{}, rules); err != nil {
+		s.clearFilters(ctx, sess.seid, rules)
+		return reject(CauseRuleCreationFailure, err)
+	}
+
+	resp, err := s.up.EstablishSession(ctx, &models.EstablishRequest{
+		SEID:         sess.seid,
+		PolicyID:     policyID(sess.seid),
+		PDRs:         rules.pdrs,
+		FARs:         rules.fars,
+		QERs:         rules.qers,
+		URRs:         rules.urrs,
+		FramedRoutes: rules.framedRoutes,
+	})
+	if err != nil {
+		s.clearFilters(ctx, sess.seid, rules)
+		return reject(CauseRuleCreationFailure, err)
+	}
+
+	sess.tunnel, sess.rules = *resp, rules
+	s.registerIPv6(ctx, sess)
+
+	s.mu.Lock()
+	s.sessions[sess.seid] = sess
+	s.mu.Unlock()
+
+	return cpSEID, append([]IE{NodeIDIE(s.nodeID), CauseIE(CauseRequestAccepted), FSEIDIE(sess.seid, s.nodeID)}, sess.createdPDRs(ies)...)
+}
+
+// createdPDRs answers each Create PDR that let the user plane choose its
+// F-TEID with the session's tunnel.
+func (sess *upSession) createdPDRs(ies []IE) []IE {
+	var created []IE
+
+	for _, ie := range findIEs(ies, IECreatePDR) {
+		id, err := childUint16(ie, IEPDRID)
+		if err != nil {
+			continue
+		}
+
+		if pdr := sess.state.pdrs[id]; pdr.fteid != nil && pdr.fteid.choose {
+			created = append(created, NewGroupedIE(IECreatedPDR,
+				uint16IE(IEPDRID, id),
+				fteidIE(sess.tunnel.N3TEID, sess.tunnel.N3IPv4, sess.tunnel.N3IPv6),
+			))
+		}
+	}
+
+	return created
+}
+
+// modify applies a modification. The embedded UPF is sent every PDR, FAR
+// and QER the session keeps, and the filters of its policy when its rule
+// PDRs changed.
+func (s *Server) modify(ctx context.Context, sess *upSession, ies []IE) []IE {
+	sess.reqMu.Lock()
+	defer sess.reqMu.Unlock()
+
+	reject := func(cause uint8, err error) []IE {
+		logger.UpfLog.Warn("refused a PFCP session modification", zap.Error(err),
+			zap.String("control-plane", sess.cp.name), zap.Uint64("cp-seid", sess.cpSEID))
+
+		return []IE{CauseIE(cause)}
+	}
+
+	state := sess.state.clone()
+	if err := state.apply(ies, false); err != nil {
+		return reject(CauseMandatoryIEIncorrect, err)
+	}
+
+	rules, err := state.translate(s.maxRules)
+	if err != nil {
+		return reject(CauseRuleCreationFailure, err)
+	}
+
+	if err := sameTunnels(sess.rules.pdrs, rules.pdrs); err != nil {
+		return reject(CauseRuleCreationFailure, err)
+	}
+
+	if err := s.installFilters(ctx, sess.seid, sess.rules, rules); err != nil {
+		_ = s.installFilters(ctx, sess.seid, rules, sess.rules)
+		return reject(CauseRuleCreationFailure, err)
+	}
+
+	err = s.up.ModifySession(ctx, &models.ModifyRequest{
+		SEID:       sess.seid,
+		UpdatePDRs: rules.pdrs,
+		UpdateFARs: rules.fars,
+		UpdateQERs: rules.qers,
+	})
+	if err != nil {
+		_ = s.installFilters(ctx, sess.seid, rules, sess.rules)
+
+		if errors.Is(err, models.ErrSessionNotFound) {
+			s.forget(ctx, sess)
+			return []IE{CauseIE(CauseSessionContextNotFound)}
+		}
+
+		return reject(CauseRuleCreationFailure, err)
+	}
+
+	sess.usageMu.Lock()
+	sess.state, sess.rules = state, rules
+	sess.usageMu.Unlock()
+
+	s.registerIPv6(ctx, sess)
+
+	return append([]IE{CauseIE(CauseRequestAccepted)}, sess.createdPDRs(ies)...)
+}
+
+// sameTunnels refuses a modification that would change what the session's
+// PDRs match: the embedded UPF keeps a session's tunnel and addresses.
+func sameTunnels(cur, next []models.PDR) error {
+	if len(cur) != len(next) {
+		return errors.New("a modification cannot add or remove the PDRs of the session's tunnel and addresses")
+	}
+
+	for i := range cur {
+		if cur[i].PDRID != next[i].PDRID || (cur[i].PDI.LocalFTEID == nil) != (next[i].PDI.LocalFTEID == nil) ||
+			cur[i].PDI.UEIPAddress != next[i].PDI.UEIPAddress {
+			return fmt.Errorf("a modification cannot change what PDR %d matches", cur[i].PDRID)
+		}
+	}
+
+	return nil
+}
+
+// installFilters sends the network rules of next that differ from cur.
+func (s *Server) installFilters(ctx context.Context, seid uint64, cur, next translatedRules) error {
+	if !slices.Equal(cur.uplink, next.uplink) {
+		if err := s.up.UpdateFilters(ctx, policyID(seid), models.DirectionUplink, next.uplink); err != nil {
+			return fmt.Errorf("uplink network rules: %w", err)
+		}
+	}
+
+	if !slices.Equal(cur.downlink, next.downlink) {
+		if err := s.up.UpdateFilters(ctx, policyID(seid), models.DirectionDownlink, next.downlink); err != nil {
+			return fmt.Errorf("downlink network rules: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// clearFilters releases the filters of a session's policy.
+func (s *Server) clearFilters(ctx context.Context, seid uint64, rules translatedRules) {
+	if err := s.installFilters(ctx, seid, rules, translatedRules{}); err != nil {
+		logger.UpfLog.Warn("failed to release the network rules of a session", zap.Error(err), zap.Uint64("seid", seid))
+	}
+}
+
+// delete removes the session and returns the response IEs, with the usage
+// not reported yet.
+func (s *Server) delete(ctx context.Context, sess *upSession) []IE {
+	sess.reqMu.Lock()
+	defer sess.reqMu.Unlock()
+
+	sess.usageMu.Lock()
+	sess.deleting = true
+	sess.usageMu.Unlock()
+
+	// The final usage arrives through HandleUsageReport, which holds it
+	// for this response.
+	s.up.FlushUsage(ctx, sess.seid)
+
+	if err := s.up.DeleteSession(ctx, sess.seid); err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+		logger.UpfLog.Warn("failed to delete a session of a control plane", zap.Error(err),
+			zap.String("control-plane", sess.cp.name), zap.Uint64("seid", sess.seid))
+
+		return []IE{CauseIE(CauseRequestRejected)}
+	}
+
+	s.forget(ctx, sess)
+
+	sess.usageMu.Lock()
+	ul, dl := sess.pendingUL, sess.pendingDL
+	sess.pendingUL, sess.pendingDL = 0, 0
+	reports := sess.usageReportsLocked(IEUsageReportSessionDeletion, ul, dl, true)
+	sess.usageMu.Unlock()
+
+	return append([]IE{CauseIE(CauseRequestAccepted)}, reports...)
+}
+
+// forget releases what the session holds besides its rules on the embedded
+// UPF.
+func (s *Server) forget(ctx context.Context, sess *upSession) {
+	s.clearFilters(ctx, sess.seid, sess.rules)
+	s.unregisterIPv6(ctx, sess)
+
+	s.mu.Lock()
+	delete(s.sessions, sess.seid)
+	s.mu.Unlock()
+}
+
+// registerIPv6 has the embedded UPF answer the Router Solicitations of an
+// IPv6 UE once its downlink tunnel is known, as the node's own SMF does.
+// The control plane sends no MTU, so the Router Advertisement carries
+// none.
+func (s *Server) registerIPv6(ctx context.Context, sess *upSession) {
+	reg := sess.ipv6Registration()
+	if reg == nil || (sess.ipv6 != nil && *reg == *sess.ipv6) {
+		return
+	}
+
+	if err := s.up.RegisterIPv6Session(ctx, reg); err != nil {
+		logger.UpfLog.Warn("failed to register IPv6 session for RA", zap.Error(err), zap.Uint64("seid", sess.seid))
+		return
+	}
+
+	sess.ipv6 = reg
+}
+
+func (s *Server) unregisterIPv6(ctx context.Context, sess *upSession) {
+	if sess.ipv6 == nil {
+		return
+	}
+
+	if err := s.up.UnregisterIPv6Session(ctx, sess.seid, sess.ipv6.UplinkTEID); err != nil {
+		logger.UpfLog.Warn("failed to unregister IPv6 session for RA", zap.Error(err), zap.Uint64("seid", sess.seid))
+	}
+
+	sess.ipv6 = nil
+}
+
+func (sess *upSession) ipv6Registration() *models.IPv6SessionRegistration {
+	farByID := make(map[uint32]models.FAR, len(sess.rules.fars))
+	for _, far := range sess.rules.fars {
+		farByID[far.FARID] = far
+	}
+
+	qerByID := make(map[uint32]models.QER, len(sess.rules.qers))
+	for _, qer := range sess.rules.qers {
+		qerByID[qer.QERID] = qer
+	}
+
+	for _, pdr := range sess.rules.pdrs {
+		ue := pdr.PDI.UEIPAddress
+		if !ue.Is6() {
+			continue
+		}
+
+		fp := farByID[pdr.FARID].ForwardingParameters
+		if fp == nil || fp.OuterHeaderCreation == nil {
+			return nil
+		}
+
+		ohc := fp.OuterHeaderCreation
+
+		ip := ohc.IPv4Address
+		if ohc.Description == models.OuterHeaderCreationGtpUUdpIpv6 {
+			ip = ohc.IPv6Address
+		}
+
+		gnb, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			return nil
+		}
+
+		return &models.IPv6SessionRegistration{
+			SEID:         sess.seid,
+			UplinkTEID:   sess.tunnel.N3TEID,
+			DownlinkTEID: ohc.TEID,
+			GnbN3Addr:    gnb.Unmap(),
+			Prefix:       netip.PrefixFrom(ue, 64).Masked(),
+			QFI:          qerByID[pdr.QERID].QFI,
+			S1U:          ohc.S1U,
+		}
+	}
+
+	return nil
+}
+
+// usageReportsLocked splits ul and dl over the URRs of the session's
+// uplink and downlink PDRs, as the control plane measures them. The final
+// report covers every URR; a periodic one only those with traffic.
+func (sess *upSession) usageReportsLocked(t uint16, ul, dl uint64, final bool) []IE {
+	var ulURR, dlURR uint32
+
+	for _, pdr := range sess.rules.pdrs {
+		if pdr.PDI.LocalFTEID != nil {
+			ulURR = cmp.Or(ulURR, pdr.URRID)
+		} else {
+			dlURR = cmp.Or(dlURR, pdr.URRID)
+		}
+	}
+
+	trigger := []byte{reportingTriggerPerio, 0, 0}
+	if final {
+		trigger = []byte{0, usageTriggerTermr, 0}
+	}
+
+	var reports []IE
+
+	report := func(urrID uint32, ul, dl uint64) {
+		if urrID == 0 || (!final && ul == 0 && dl == 0) {
+			return
+		}
+
+		sess.seqn++
+
+		reports = append(reports, NewGroupedIE(t,
+			uint32IE(IEURRID, urrID),
+			uint32IE(IEURSEQN, sess.seqn),
+			NewIE(IEUsageReportTrigger, trigger),
+			volumeMeasurementIE(ul, dl),
+		))
+	}
+
+	if ulURR != 0 && dlURR != 0 && ulURR != dlURR {
+		report(ulURR, ul, 0)
+		report(dlURR, 0, dl)
+	} else {
+		report(cmp.Or(ulURR, dlURR), ul, dl)
+	}
+
+	return reports
+}
+
+func (s *Server) session(seid uint64) *upSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sessions[seid]
+}
+
+// HandleUsageReport sends the usage of a session to its control plane. A
+// report that does not go through is kept for the next one, or for the
+// session's deletion.
+func (s *Server) HandleUsageReport(ctx context.Context, r *models.UsageReport) error {
+	sess := s.session(r.SEID)
+	if sess == nil {
+		return fmt.Errorf("no session of a control plane with SEID %d", r.SEID)
+	}
+
+	sess.usageMu.Lock()
+	defer sess.usageMu.Unlock()
+
+	sess.pendingUL += r.UplinkVolume
+	sess.pendingDL += r.DownlinkVolume
+
+	if sess.deleting || sess.sending {
+		return nil
+	}
+
+	sess.sending = true
+
+	s.wg.Go(func() { s.sendUsage(ctx, sess) })
+
+	return nil
+}
+
+func (s *Server) sendUsage(ctx context.Context, sess *upSession) {
+	for {
+		sess.usageMu.Lock()
+
+		ul, dl := sess.pendingUL, sess.pendingDL
+		if sess.deleting || ul == 0 && dl == 0 {
+			sess.sending = false
+			sess.usageMu.Unlock()
+
+			return
+		}
+
+		sess.pendingUL, sess.pendingDL = 0, 0
+		reports := sess.usageReportsLocked(IEUsageReportSessionReport, ul, dl, false)
+		sess.usageMu.Unlock()
+
+		if len(reports) == 0 {
+			continue
+		}
+
+		err := s.report(ctx, sess, append([]IE{uint8IE(IEReportType, ReportTypeUSAR)}, reports...))
+		if err != nil {
+			sess.usageMu.Lock()
+			sess.pendingUL += ul
+			sess.pendingDL += dl
+			sess.sending = false
+			sess.usageMu.Unlock()
+
+			if ctx.Err() == nil {
+				logger.UpfLog.Debug("failed to report usage to a control plane", zap.Error(err),
+					zap.String("control-plane", sess.cp.name), zap.Uint64("seid", sess.seid))
+			}
+
+			return
+		}
+	}
+}
+
+// HandleDownlinkDataReport asks the control plane to page the UE.
+func (s *Server) HandleDownlinkDataReport(ctx context.Context, r *models.DownlinkDataReport) error {
+	sess := s.session(r.SEID)
+	if sess == nil {
+		return fmt.Errorf("no session of a control plane with SEID %d", r.SEID)
+	}
+
+	s.notify(ctx, sess, "downlink data", []IE{
+		uint8IE(IEReportType, ReportTypeDLDR),
+		NewGroupedIE(IEDownlinkDataReport, uint16IE(IEPDRID, r.PDRID)),
+	})
+
+	return nil
+}
+
+// HandleErrorIndicationReport tells the control plane the access node lost
+// the session's tunnel.
+func (s *Server) HandleErrorIndicationReport(ctx context.Context, r *models.ErrorIndicationReport) error {
+	sess := s.session(r.SEID)
+	if sess == nil {
+		return fmt.Errorf("no session of a control plane with SEID %d", r.SEID)
+	}
+
+	var v4, v6 netip.Addr
+	if r.RemoteAddress.Is4() {
+		v4 = r.RemoteAddress
+	} else {
+		v6 = r.RemoteAddress
+	}
+
+	s.notify(ctx, sess, "error indication", []IE{
+		uint8IE(IEReportType, ReportTypeERIR),
+		NewGroupedIE(IEErrorIndicationReport, fteidIE(r.RemoteTEID, v4, v6)),
+	})
+
+	return nil
+}
+
+// HandlePathFailureReport tells each control plane with sessions tunnelled
+// to the access node that its GTP-U path failed.
+func (s *Server) HandlePathFailureReport(ctx context.Context, r *models.PathFailureReport) error {
+	s.mu.Lock()
+
+	targets := make(map[*controlPlane]netip.AddrPort)
+
+	for _, seid := range r.SEIDs {
+		if sess := s.sessions[seid]; sess != nil && sess.cp.associated {
+			targets[sess.cp] = sess.cp.from
+		}
+	}
+	s.mu.Unlock()
+
+	for cp, to := range targets {
+		s.wg.Go(func() {
+			resp, err := s.request(ctx, to, &Message{
+				Type: MsgNodeReportRequest,
+				IEs: []IE{
+					NodeIDIE(s.nodeID),
+					uint8IE(IENodeReportType, NodeReportTypeUPFR),
+					NewGroupedIE(IEUserPlanePathFailureReport, remoteGTPUPeerIE(r.RemoteAddress)),
+				},
+			})
+			if err == nil {
+				err = checkCause(resp.IEs)
+			}
+
+			if err != nil && ctx.Err() == nil {
+				logger.UpfLog.Warn("failed to report a path failure to a control plane", zap.Error(err),
+					zap.String("control-plane", cp.name), zap.Stringer("access-node", r.RemoteAddress))
+			}
+		})
+	}
+
+	return nil
+}
+
+// notify sends a Session Report Request off the caller's goroutine: the
+// embedded UPF reports from its datapath loops.
+func (s *Server) notify(ctx context.Context, sess *upSession, what string, ies []IE) {
+	s.wg.Go(func() {
+		if err := s.report(ctx, sess, ies); err != nil && ctx.Err() == nil {
+			logger.UpfLog.Warn("failed to report to a control plane", zap.Error(err), zap.String("report", what),
+				zap.String("control-plane", sess.cp.name), zap.Uint64("seid", sess.seid))
+		}
+	})
+}
+
+func (s *Server) report(ctx context.Context, sess *upSession, ies []IE) error {
+	s.mu.Lock()
+	to, associated := sess.cp.from, sess.cp.associated
+	s.mu.Unlock()
+
+	if !associated {
+		return ErrNotAssociated
+	}
+
+	resp, err := s.request(ctx, to, &Message{
+		Type:    MsgSessionReportRequest,
+		HasSEID: true,
+		SEID:    sess.cpSEID,
+		IEs:     ies,
+	})
+	if err != nil {
+		return err
+	}
+
+	return checkCause(resp.IEs)
+}
@@ -0,0 +1,535 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// serverPDR is a PDR as the control plane sent it.
+type serverPDR struct {
+	id         uint16
+	precedence uint32
+	access     bool // Source Interface Access: an uplink PDR
+	fteid      *localFTEID
+	ue         netip.Addr
+	routes     []netip.Prefix
+	flows      []string
+	ohr        *uint8
+	farID      uint32
+	urrID      uint32
+	qerIDs     []uint32
+}
+
+// ruleState is the rules of a server session, kept as the control plane
+// last sent them: PFCP modifications carry only what changed, while the
+// embedded UPF takes a session's rules in full, as its own SMF sends them.
+type ruleState struct {
+	pdrs map[uint16]serverPDR
+	fars map[uint32]models.FAR
+	qers map[uint32]models.QER
+	urrs map[uint32]bool
+}
+
+func newRuleState() ruleState {
+	return ruleState{
+		pdrs: make(map[uint16]serverPDR),
+		fars: make(map[uint32]models.FAR),
+		qers: make(map[uint32]models.QER),
+		urrs: make(map[uint32]bool),
+	}
+}
+
+func (r ruleState) clone() ruleState {
+	return ruleState{pdrs: maps.Clone(r.pdrs), fars: maps.Clone(r.fars), qers: maps.Clone(r.qers), urrs: maps.Clone(r.urrs)}
+}
+
+// apply updates the state with the Create, Update and Remove IEs of an
+// establishment or modification request. URRs are only created with the
+// session: the embedded UPF measures a session's usage from its start.
+func (r ruleState) apply(ies []IE, establishing bool) error {
+	for _, ie := range ies {
+		var err error
+
+		switch ie.Type {
+		case IECreatePDR, IEUpdatePDR:
+			err = r.applyPDR(ie)
+		case IERemovePDR:
+			var id uint16
+
+			if id, err = childUint16(ie, IEPDRID); err == nil {
+				delete(r.pdrs, id)
+			}
+		case IECreateFAR, IEUpdateFAR:
+			err = r.applyFAR(ie)
+		case IERemoveFAR:
+			var id uint32
+
+			if id, err = childUint32(ie, IEFARID); err == nil {
+				delete(r.fars, id)
+			}
+		case IECreateQER, IEUpdateQER:
+			err = r.applyQER(ie)
+		case IERemoveQER:
+			var id uint32
+
+			if id, err = childUint32(ie, IEQERID); err == nil {
+				delete(r.qers, id)
+			}
+		case IECreateURR:
+			if !establishing {
+				return errors.New("URRs can only be created with the session")
+			}
+
+			var id uint32
+
+			if id, err = childUint32(ie, IEURRID); err == nil {
+				r.urrs[id] = true
+			}
+		case IERemoveURR:
+			return errors.New("URRs can only be removed with the session")
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyPDR creates a PDR, or updates the fields an Update PDR carries.
+func (r ruleState) applyPDR(ie IE) error {
+	children, err := ie.Children()
+	if err != nil {
+		return fmt.Errorf("PDR: %w", err)
+	}
+
+	id, err := requireUint16(children, IEPDRID)
+	if err != nil {
+		return err
+	}
+
+	pdr, ok := r.pdrs[id]
+	if !ok && ie.Type == IEUpdatePDR {
+		return fmt.Errorf("update of unknown PDR %d", id)
+	}
+
+	pdr.id = id
+
+	for _, c := range children {
+		switch c.Type {
+		case IEPrecedence:
+			pdr.precedence, err = c.uint32()
+		case IEPDI:
+			err = pdr.applyPDI(c)
+		case IEOuterHeaderRemoval:
+			var v uint8
+
+			if v, err = c.uint8(); err == nil {
+				pdr.ohr = &v
+			}
+		case IEFARID:
+			pdr.farID, err = c.uint32()
+		case IEURRID:
+			pdr.urrID, err = c.uint32()
+		}
+
+		if err != nil {
+			return fmt.Errorf("PDR %d: %w", id, err)
+		}
+	}
+
+	if qers := findIEs(children, IEQERID); len(qers) > 0 {
+		pdr.qerIDs = nil
+
+		for _, q := range qers {
+			qerID, err := q.uint32()
+			if err != nil {
+				return fmt.Errorf("PDR %d: %w", id, err)
+			}
+
+			pdr.qerIDs = append(pdr.qerIDs, qerID)
+		}
+	}
+
+	r.pdrs[id] = pdr
+
+	return nil
+}
+
+func (pdr *serverPDR) applyPDI(ie IE) error {
+	children, err := ie.Children()
+	if err != nil {
+		return fmt.Errorf("PDI: %w", err)
+	}
+
+	src, err := requireUint8(children, IESourceInterface)
+	if err != nil {
+		return err
+	}
+
+	*pdr = serverPDR{id: pdr.id, precedence: pdr.precedence, ohr: pdr.ohr, farID: pdr.farID, urrID: pdr.urrID, qerIDs: pdr.qerIDs}
+	pdr.access = src == InterfaceAccess
+
+	for _, c := range children {
+		switch c.Type {
+		case IEFTEID:
+			var f localFTEID
+
+			if f, err = decodeFTEID(c); err == nil {
+				pdr.fteid = &f
+			}
+		case IEUEIPAddress:
+			pdr.ue, err = parseUEIPAddress(c)
+		case IESDFFilter:
+			var fd string
+
+			if fd, err = parseSDFFilter(c); err == nil {
+				pdr.flows = append(pdr.flows, fd)
+			}
+		case IEFramedRoute, IEFramedIPv6Route:
+			var route netip.Prefix
+
+			if route, err = parseFramedRoute(c); err == nil {
+				pdr.routes = append(pdr.routes, route)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseFramedRoute reads the destination of a Framed-Route (RFC 2865
+// §5.22); the gateway and metrics after it are not needed.
+func parseFramedRoute(ie IE) (netip.Prefix, error) {
+	dest, _, _ := strings.Cut(string(ie.Value), " ")
+
+	p, err := netip.ParsePrefix(dest)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("framed route %q: %w", ie.Value, err)
+	}
+
+	return p.Masked(), nil
+}
+
+// applyFAR creates a FAR, or updates it. Forwarding parameters a FAR is
+// sent without are kept: a downlink that starts buffering keeps its tunnel
+// for when it forwards again.
+func (r ruleState) applyFAR(ie IE) error {
+	children, err := ie.Children()
+	if err != nil {
+		return fmt.Errorf("FAR: %w", err)
+	}
+
+	id, err := requireUint32(children, IEFARID)
+	if err != nil {
+		return err
+	}
+
+	far, ok := r.fars[id]
+	if !ok && ie.Type == IEUpdateFAR {
+		return fmt.Errorf("update of unknown FAR %d", id)
+	}
+
+	far.FARID = id
+
+	if c, ok := findIE(children, IEApplyAction); ok {
+		if far.ApplyAction, err = parseApplyAction(c); err != nil {
+			return fmt.Errorf("FAR %d: %w", id, err)
+		}
+	}
+
+	for _, t := range []uint16{IEForwardingParameters, IEUpdateForwardingParameters} {
+		c, ok := findIE(children, t)
+		if !ok {
+			continue
+		}
+
+		if far.ForwardingParameters, err = parseForwardingParameters(c); err != nil {
+			return fmt.Errorf("FAR %d: %w", id, err)
+		}
+	}
+
+	if far.ForwardingParameters == nil {
+		far.ForwardingParameters = &models.ForwardingParameters{}
+	}
+
+	r.fars[id] = far
+
+	return nil
+}
+
+func parseForwardingParameters(ie IE) (*models.ForwardingParameters, error) {
+	children, err := ie.Children()
+	if err != nil {
+		return nil, fmt.Errorf("Forwarding Parameters: %w", err)
+	}
+
+	fp := &models.ForwardingParameters{}
+
+	c, ok := findIE(children, IEOuterHeaderCreation)
+	if !ok {
+		return fp, nil
+	}
+
+	if fp.OuterHeaderCreation, err = parseOHC(c); err != nil {
+		return nil, err
+	}
+
+	if c, ok := findIE(children, IEInterfaceType); ok {
+		ifType, err := c.uint8()
+		if err != nil {
+			return nil, err
+		}
+
+		fp.OuterHeaderCreation.S1U = ifType&0x3f == InterfaceTypeS1U
+	}
+
+	return fp, nil
+}
+
+func (r ruleState) applyQER(ie IE) error {
+	children, err := ie.Children()
+	if err != nil {
+		return fmt.Errorf("QER: %w", err)
+	}
+
+	id, err := requireUint32(children, IEQERID)
+	if err != nil {
+		return err
+	}
+
+	qer, ok := r.qers[id]
+	if !ok && ie.Type == IEUpdateQER {
+		return fmt.Errorf("update of unknown QER %d", id)
+	}
+
+	qer.QERID = id
+
+	for _, c := range children {
+		switch c.Type {
+		case IEGateStatus:
+			qer.GateStatus, err = parseGateStatus(c)
+		case IEMBR:
+			qer.MBR, err = parseMBR(c)
+		case IEQFI:
+			qer.QFI, err = c.uint8()
+		}
+
+		if err != nil {
+			return fmt.Errorf("QER %d: %w", id, err)
+		}
+	}
+
+	r.qers[id] = qer
+
+	return nil
+}
+
+// translatedRules is a server session as the embedded UPF takes it: the PDRs
+// without an SDF Filter, and the FARs, QERs and URRs they use, plus the
+// network rules the SDF-filtered PDRs make up.
+type translatedRules struct {
+	pdrs         []models.PDR
+	fars         []models.FAR
+	qers         []models.QER
+	urrs         []models.URR
+	framedRoutes []netip.Prefix
+	uplink       []models.FilterRule
+	downlink     []models.FilterRule
+}
+
+// translate maps the state onto the session shape of the embedded UPF: one
+// uplink PDR on the tunnel it allocates, and a downlink PDR per UE address.
+// Each SDF-filtered PDR becomes a network rule in its direction, ordered by
+// precedence: a deny when its FAR drops, a rate limit when it has a QER of
+// its own with a maximum bit rate.
+func (r ruleState) translate(maxRules int) (translatedRules, error) {
+	var (
+		t        translatedRules
+		rulePDRs []serverPDR
+		uplinks  int
+	)
+
+	usedFARs := make(map[uint32]bool)
+	usedQERs := make(map[uint32]bool)
+
+	for _, id := range slices.Sorted(maps.Keys(r.pdrs)) {
+		pdr := r.pdrs[id]
+
+		if _, ok := r.fars[pdr.farID]; !ok {
+			return t, fmt.Errorf("PDR %d uses unknown FAR %d", id, pdr.farID)
+		}
+
+		for _, qerID := range pdr.qerIDs {
+			if _, ok := r.qers[qerID]; !ok {
+				return t, fmt.Errorf("PDR %d uses unknown QER %d", id, qerID)
+			}
+		}
+
+		if pdr.urrID != 0 && !r.urrs[pdr.urrID] {
+			return t, fmt.Errorf("PDR %d uses unknown URR %d", id, pdr.urrID)
+		}
+
+		if len(pdr.flows) > 0 {
+			rulePDRs = append(rulePDRs, pdr)
+			continue
+		}
+
+		base := models.PDR{PDRID: pdr.id, OuterHeaderRemoval: pdr.ohr, FARID: pdr.farID, URRID: pdr.urrID}
+		if len(pdr.qerIDs) > 0 {
+			base.QERID = pdr.qerIDs[0]
+			usedQERs[base.QERID] = true
+		}
+
+		switch {
+		case pdr.access && pdr.fteid != nil:
+			uplinks++
+			base.PDI.LocalFTEID = &models.FTEID{}
+		case pdr.access:
+			return t, fmt.Errorf("uplink PDR %d must match an F-TEID", id)
+		case pdr.ue.IsValid():
+			base.PDI.UEIPAddress = pdr.ue
+
+			for _, route := range pdr.routes {
+				if route.Addr().Is4() == pdr.ue.Is4() {
+					t.framedRoutes = append(t.framedRoutes, route)
+				}
+			}
+		default:
+			return t, fmt.Errorf("downlink PDR %d must match a UE IP address", id)
+		}
+
+		usedFARs[base.FARID] = true
+		t.pdrs = append(t.pdrs, base)
+	}
+
+	if uplinks > 1 {
+		return t, errors.New("a session has one uplink tunnel")
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(usedFARs)) {
+		t.fars = append(t.fars, r.fars[id])
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(usedQERs)) {
+		t.qers = append(t.qers, r.qers[id])
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(r.urrs)) {
+		t.urrs = append(t.urrs, models.URR{URRID: id})
+	}
+
+	slices.SortStableFunc(rulePDRs, func(a, b serverPDR) int {
+		return cmp.Or(cmp.Compare(a.precedence, b.precedence), cmp.Compare(a.id, b.id))
+	})
+
+	for _, pdr := range rulePDRs {
+		for _, fd := range pdr.flows {
+			rule, err := parseFlowDescription(fd)
+			if err != nil {
+				return t, fmt.Errorf("PDR %d: %w", pdr.id, err)
+			}
+
+			rule.Action = r.ruleAction(pdr, usedQERs, &rule)
+
+			if pdr.access {
+				t.uplink = appendRule(t.uplink, rule)
+			} else {
+				t.downlink = appendRule(t.downlink, rule)
+			}
+		}
+	}
+
+	if len(t.uplink) > maxRules || len(t.downlink) > maxRules {
+		return t, fmt.Errorf("the datapath enforces at most %d network rules per direction", maxRules)
+	}
+
+	return t, nil
+}
+
+// ruleAction is what a rule PDR does with the traffic its filter matches.
+func (r ruleState) ruleAction(pdr serverPDR, baseQERs map[uint32]bool, rule *models.FilterRule) models.Action {
+	if r.fars[pdr.farID].ApplyAction.Drop {
+		return models.Deny
+	}
+
+	for _, qerID := range pdr.qerIDs {
+		qer := r.qers[qerID]
+		if baseQERs[qerID] || qer.MBR == nil {
+			continue
+		}
+
+		kbps := qer.MBR.DLMBR
+		if pdr.access {
+			kbps = qer.MBR.ULMBR
+		}
+
+		rule.RateLimit = models.BitRateFromBps(kbps * 1000)
+
+		return models.RateLimit
+	}
+
+	return models.Allow
+}
+
+// appendRule adds a rule unless it repeats the last one: the control plane
+// sends a downlink rule once per UE address family, at the same
+// precedence.
+func appendRule(rules []models.FilterRule, r models.FilterRule) []models.FilterRule {
+	if len(rules) > 0 && rules[len(rules)-1] == r {
+		return rules
+	}
+
+	return append(rules, r)
+}
+
+func childUint16(ie IE, t uint16) (uint16, error) {
+	children, err := ie.Children()
+	if err != nil {
+		return 0, err
+	}
+
+	return requireUint16(children, t)
+}
+
+func childUint32(ie IE, t uint16) (uint32, error) {
+	children, err := ie.Children()
+	if err != nil {
+		return 0, err
+	}
+
+	return requireUint32(children, t)
+}
+
+func requireUint8(ies []IE, t uint16) (uint8, error) {
+	ie, ok := findIE(ies, t)
+	if !ok {
+		return 0, fmt.Errorf("missing IE type %d", t)
+	}
+
+	return ie.uint8()
+}
+
+func requireUint16(ies []IE, t uint16) (uint16, error) {
+	ie, ok := findIE(ies, t)
+	if !ok {
+		return 0, fmt.Errorf("missing IE type %d", t)
+	}
+
+	return ie.uint16()
+}
@@ -0,0 +1,405 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// fakeDatapath stands in for the embedded UPF behind a Server. Flushing a
+// session reports its usage synchronously, as the UPF does.
+type fakeDatapath struct {
+	server *Server
+
+	mu            sync.Mutex
+	establish     []*models.EstablishRequest
+	modify        []*models.ModifyRequest
+	deleted       []uint64
+	filters       map[string][]models.FilterRule
+	registrations []*models.IPv6SessionRegistration
+}
+
+func (d *fakeDatapath) EstablishSession(_ context.Context, req *models.EstablishRequest) (*models.EstablishResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.establish = append(d.establish, req)
+
+	return &models.EstablishResponse{N3TEID: 7, N3IPv4: netip.MustParseAddr("127.0.0.1")}, nil
+}
+
+func (d *fakeDatapath) ModifySession(_ context.Context, req *models.ModifyRequest) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.modify = append(d.modify, req)
+
+	return nil
+}
+
+func (d *fakeDatapath) FlushUsage(ctx context.Context, seid uint64) {
+	_ = d.server.HandleUsageReport(ctx, &models.UsageReport{SEID: seid, UplinkVolume: 3, DownlinkVolume: 4})
+}
+
+func (d *fakeDatapath) DeleteSession(_ context.Context, seid uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.deleted = append(d.deleted, seid)
+
+	return nil
+}
+
+func (d *fakeDatapath) UpdateFilters(_ context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := policyID + ":" + direction.String()
+	if len(rules) == 0 {
+		delete(d.filters, key)
+	} else {
+		d.filters[key] = rules
+	}
+
+	return nil
+}
+
+func (d *fakeDatapath) RegisterIPv6Session(_ context.Context, reg *models.IPv6SessionRegistration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.registrations = append(d.registrations, reg)
+
+	return nil
+}
+
+func (d *fakeDatapath) UnregisterIPv6Session(context.Context, uint64, uint32) error {
+	return nil
+}
+
+func (d *fakeDatapath) rules(seid uint64, direction models.Direction) []models.FilterRule {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.filters[policyID(seid)+":"+direction.String()]
+}
+
+// startServer serves N4 on loopback to an Ella SMF endpoint, and returns
+// the user plane as the SMF sees it.
+func startServer(t *testing.T, maxRules int) (*fakeDatapath, *fakeReports, *Endpoint, *Peer) {
+	t.Helper()
+
+	d := &fakeDatapath{filters: make(map[string][]models.FilterRule)}
+
+	s, err := NewServer(netip.MustParseAddrPort("127.0.0.1:0"), d, maxRules)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	d.server = s
+	s.t1 = 50 * time.Millisecond
+	s.AddControlPlane("core", netip.MustParseAddr("127.0.0.1"))
+	s.Start(context.Background())
+	t.Cleanup(s.Close)
+
+	reports := &fakeReports{}
+
+	e, err := Listen(netip.MustParseAddrPort("127.0.0.1:0"), reports)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	e.heartbeatInterval = 20 * time.Millisecond
+	e.t1 = 50 * time.Millisecond
+
+	p := e.AddPeer("ella-edge", s.LocalAddr())
+
+	e.Start(context.Background())
+	t.Cleanup(e.Close)
+
+	waitFor(t, "association", p.isAssociated)
+
+	return d, reports, e, p
+}
+
+func establishedSEID(t *testing.T, d *fakeDatapath) uint64 {
+	t.Helper()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.establish) != 1 {
+		t.Fatalf("%d sessions established on the datapath, want 1", len(d.establish))
+	}
+
+	return d.establish[0].SEID
+}
+
+func TestServer_InstallsSessionsWithTheirNetworkRules(t *testing.T) {
+	d, reports, _, p := startServer(t, 12)
+	ctx := context.Background()
+
+	uplink := []models.FilterRule{
+		{RemotePrefix: "198.51.100.0/24", Protocol: 6, PortLow: 443, PortHigh: 443, Action: models.Deny},
+		{Action: models.Allow},
+	}
+	downlink := []models.FilterRule{
+		{RemotePrefix: "203.0.113.0/24", Action: models.RateLimit, RateLimit: models.MustParseBitRate("1 Mbps")},
+	}
+
+	if err := p.UpdateFilters(ctx, "policy-1", models.DirectionUplink, uplink); err != nil {
+		t.Fatalf("uplink filters: %v", err)
+	}
+
+	if err := p.UpdateFilters(ctx, "policy-1", models.DirectionDownlink, downlink); err != nil {
+		t.Fatalf("downlink filters: %v", err)
+	}
+
+	req := testEstablishRequest()
+	req.PolicyID = "policy-1"
+
+	resp, err := p.EstablishSession(ctx, req)
+	if err != nil {
+		t.Fatalf("establish: %v", err)
+	}
+
+	if resp.N3TEID != 7 {
+		t.Fatalf("tunnel = %+v, want the one the datapath allocated", resp)
+	}
+
+	seid := establishedSEID(t, d)
+
+	est := d.establish[0]
+	if seid&serverSEIDs == 0 || est.PolicyID != policyID(seid) {
+		t.Fatalf("session %d on policy %q, want a server SEID on its own policy", seid, est.PolicyID)
+	}
+
+	if len(est.PDRs) != 2 || est.PDRs[0].PDI.LocalFTEID == nil || est.PDRs[1].PDI.UEIPAddress != netip.MustParseAddr("10.45.0.2") {
+		t.Fatalf("PDRs = %+v, want the uplink tunnel and the UE address only", est.PDRs)
+	}
+
+	if len(est.FARs) != 2 || len(est.QERs) != 1 || est.QERs[0].QFI != 9 || len(est.URRs) != 2 {
+		t.Fatalf("FARs %+v QERs %+v URRs %+v, want the SMF's", est.FARs, est.QERs, est.URRs)
+	}
+
+	if !slices.Equal(est.FramedRoutes, []netip.Prefix{netip.MustParsePrefix("192.168.10.0/24")}) {
+		t.Fatalf("framed routes = %v, want the IPv4 one", est.FramedRoutes)
+	}
+
+	if got := d.rules(seid, models.DirectionUplink); len(got) != 2 || got[0] != uplink[0] || got[1] != uplink[1] {
+		t.Fatalf("uplink rules = %+v, want %+v", got, uplink)
+	}
+
+	got := d.rules(seid, models.DirectionDownlink)
+	if len(got) != 1 || got[0].Action != models.RateLimit || got[0].RemotePrefix != "203.0.113.0/24" || !got[0].RateLimit.Equal(downlink[0].RateLimit) {
+		t.Fatalf("downlink rules = %+v, want %+v", got, downlink)
+	}
+
+	// A policy change reaches the running session.
+	if err := p.UpdateFilters(ctx, "policy-1", models.DirectionUplink, nil); err != nil {
+		t.Fatalf("clear uplink filters: %v", err)
+	}
+
+	if got := d.rules(seid, models.DirectionUplink); len(got) != 0 {
+		t.Fatalf("uplink rules = %+v after the policy dropped them", got)
+	}
+
+	if got := d.rules(seid, models.DirectionDownlink); len(got) != 1 {
+		t.Fatalf("downlink rules = %+v, want them kept", got)
+	}
+
+	if err := p.DeleteSession(ctx, req.SEID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	d.mu.Lock()
+	deleted := slices.Clone(d.deleted)
+	d.mu.Unlock()
+
+	if !slices.Equal(deleted, []uint64{seid}) || len(d.rules(seid, models.DirectionDownlink)) != 0 {
+		t.Fatalf("deleted %v, downlink rules %+v, want the session and its rules gone", deleted, d.rules(seid, models.DirectionDownlink))
+	}
+
+	reports.mu.Lock()
+	var ul, dl uint64
+
+	for _, u := range reports.usage {
+		ul += u.UplinkVolume
+		dl += u.DownlinkVolume
+	}
+	reports.mu.Unlock()
+
+	if ul != 3 || dl != 4 {
+		t.Fatalf("final usage %d/%d, want the usage flushed on deletion", ul, dl)
+	}
+
+	if err := p.DeleteSession(ctx, req.SEID); !errors.Is(err, models.ErrSessionNotFound) {
+		t.Fatalf("second delete err = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestServer_ReportsToTheControlPlane(t *testing.T) {
+	d, reports, _, p := startServer(t, 12)
+	ctx := context.Background()
+
+	req := testEstablishRequest()
+	if _, err := p.EstablishSession(ctx, req); err != nil {
+		t.Fatalf("establish: %v", err)
+	}
+
+	seid := establishedSEID(t, d)
+	access := netip.MustParseAddr("198.51.100.7")
+
+	err := p.ModifySession(ctx, &models.ModifyRequest{SEID: req.SEID, UpdateFARs: []models.FAR{{
+		FARID:       2,
+		ApplyAction: models.ApplyAction{Forw: true},
+		ForwardingParameters: &models.ForwardingParameters{OuterHeaderCreation: &models.OuterHeaderCreation{
+			Description: models.OuterHeaderCreationGtpUUdpIpv4, TEID: 9, IPv4Address: access.AsSlice(),
+		}},
+	}}})
+	if err != nil {
+		t.Fatalf("modify: %v", err)
+	}
+
+	d.mu.Lock()
+	mod := d.modify[0]
+	d.mu.Unlock()
+
+	if len(mod.UpdatePDRs) != 2 || len(mod.UpdateFARs) != 2 || mod.UpdateFARs[1].ForwardingParameters.OuterHeaderCreation.TEID != 9 {
+		t.Fatalf("modification = %+v, want every rule of the session", mod)
+	}
+
+	s := d.server
+
+	if err := s.HandleUsageReport(ctx, &models.UsageReport{SEID: seid, UplinkVolume: 5}); err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+
+	if err := s.HandleDownlinkDataReport(ctx, &models.DownlinkDataReport{SEID: seid, PDRID: 2}); err != nil {
+		t.Fatalf("downlink data: %v", err)
+	}
+
+	if err := s.HandlePathFailureReport(ctx, &models.PathFailureReport{RemoteAddress: access, SEIDs: []uint64{seid}}); err != nil {
+		t.Fatalf("path failure: %v", err)
+	}
+
+	waitFor(t, "the reports", func() bool {
+		reports.mu.Lock()
+		defer reports.mu.Unlock()
+
+		return len(reports.usage) == 1 && len(reports.downlinkData) == 1 && len(reports.pathFailures) == 1
+	})
+
+	reports.mu.Lock()
+	defer reports.mu.Unlock()
+
+	if u := reports.usage[0]; u.SEID != req.SEID || u.UplinkVolume != 5 {
+		t.Fatalf("usage = %+v, want 5 uplink octets of session %d", u, req.SEID)
+	}
+
+	if r := reports.downlinkData[0]; r.SEID != req.SEID || r.PDRID != 2 {
+		t.Fatalf("downlink data = %+v, want PDR 2 of session %d", r, req.SEID)
+	}
+
+	if r := reports.pathFailures[0]; r.RemoteAddress != access || !slices.Equal(r.SEIDs, []uint64{req.SEID}) {
+		t.Fatalf("path failure = %+v, want session %d tunnelled to %s", r, req.SEID, access)
+	}
+}
+
+func TestServer_RefusesMoreRulesThanTheDatapathHolds(t *testing.T) {
+	d, _, _, p := startServer(t, 1)
+	ctx := context.Background()
+
+	rules := []models.FilterRule{{Protocol: 6, Action: models.Deny}, {Protocol: 17, Action: models.Deny}}
+	if err := p.UpdateFilters(ctx, "policy-1", models.DirectionUplink, rules); err != nil {
+		t.Fatalf("filters: %v", err)
+	}
+
+	req := testEstablishRequest()
+	req.PolicyID = "policy-1"
+
+	var cause *CauseError
+	if _, err := p.EstablishSession(ctx, req); !errors.As(err, &cause) || cause.Cause != CauseRuleCreationFailure {
+		t.Fatalf("establish err = %v, want a rule creation failure", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.establish) != 0 || len(d.filters) != 0 {
+		t.Fatalf("datapath holds %d sessions and %d filters, want none", len(d.establish), len(d.filters))
+	}
+}
+
+func TestServer_AnswersRetransmissionsOnce(t *testing.T) {
+	d, _, e, p := startServer(t, 12)
+
+	msg := &Message{
+		Type:     MsgSessionEstablishmentRequest,
+		HasSEID:  true,
+		Sequence: 0x7fff00,
+		IEs:      establishmentIEs(e.nodeID, testEstablishRequest(), DefaultUsagePeriod, nil),
+	}
+
+	for range 3 {
+		if err := e.send(p.addr, msg); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+	}
+
+	waitFor(t, "the establishment", func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		return len(d.establish) > 0
+	})
+
+	time.Sleep(50 * time.Millisecond)
+
+	if seid := establishedSEID(t, d); seid == 0 {
+		t.Fatal("no session established")
+	}
+}
+
+func TestServer_RefusesUnknownControlPlanes(t *testing.T) {
+	s, err := NewServer(netip.MustParseAddrPort("127.0.0.1:0"), &fakeDatapath{}, 12)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	s.AddControlPlane("core", netip.MustParseAddr("192.0.2.1"))
+	s.Start(context.Background())
+	t.Cleanup(s.Close)
+
+	e, err := Listen(netip.MustParseAddrPort("127.0.0.1:0"), &fakeReports{})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	defer e.Close()
+
+	e.t1 = 50 * time.Millisecond
+	e.Start(context.Background())
+
+	resp, err := e.request(context.Background(), s.LocalAddr(), &Message{
+		Type: MsgAssociationSetupRequest,
+		IEs:  []IE{NodeIDIE(e.nodeID), RecoveryTimeStampIE(e.recovery)},
+	})
+	if err != nil {
+		t.Fatalf("association setup: %v", err)
+	}
+
+	var cause *CauseError
+	if err := checkCause(resp.IEs); !errors.As(err, &cause) || cause.Cause != CauseRequestRejected {
+		t.Fatalf("association setup cause = %v, want Request rejected", err)
+	}
+}
@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// pdrPrecedence is shared by every PDR of the SMF: they never overlap (one
+// uplink tunnel, one downlink rule per UE address family), so their order is
+// irrelevant. The network rules' PDRs come before them.
+const pdrPrecedence = 255
+
+// CauseError is a PFCP response whose Cause is not Request accepted. A
+// Session context not found also matches models.ErrSessionNotFound.
+type CauseError struct {
+	Cause uint8
+}
+
+func (e *CauseError) Error() string {
+	return fmt.Sprintf("user plane rejected the request with PFCP cause %d", e.Cause)
+}
+
+func (e *CauseError) Is(target error) bool {
+	return target == models.ErrSessionNotFound && e.Cause == CauseSessionContextNotFound
+}
+
+// checkCause returns the response's rejection, if any.
+func checkCause(ies []IE) error {
+	ie, ok := findIE(ies, IECause)
+	if !ok {
+		return errors.New("response without a Cause")
+	}
+
+	cause, err := ie.uint8()
+	if err != nil {
+		return err
+	}
+
+	if cause != CauseRequestAccepted {
+		return &CauseError{Cause: cause}
+	}
+
+	return nil
+}
+
+// establishmentIEs encodes the rules of req, followed by the PDRs and QERs
+// of its network rules. The SMF's SEID doubles as the CP F-SEID, so the
+// user plane addresses its reports with it.
+func establishmentIEs(nodeID netip.Addr, req *models.EstablishRequest, usagePeriod time.Duration, networkRules []IE) []IE {
+	ies := []IE{NodeIDIE(nodeID), FSEIDIE(req.SEID, nodeID)}
+
+	for _, pdr := range req.PDRs {
+		ies = append(ies, NewGroupedIE(IECreatePDR, pdrIEs(pdr, req.FramedRoutes, true)...))
+	}
+
+	for _, far := range req.FARs {
+		ies = append(ies, NewGroupedIE(IECreateFAR, farIEs(far, IEForwardingParameters)...))
+	}
+
+	ies = append(ies, NewGroupedIE(IECreateFAR, uint32IE(IEFARID, ruleFARDrop), applyActionIE(models.ApplyAction{Drop: true})))
+
+	for _, qer := range req.QERs {
+		ies = append(ies, NewGroupedIE(IECreateQER, qerIEs(qer)...))
+	}
+
+	ies = append(ies, networkRules...)
+
+	for _, urr := range req.URRs {
+		ies = append(ies, NewGroupedIE(IECreateURR,
+			uint32IE(IEURRID, urr.URRID),
+			uint8IE(IEMeasurementMethod, measurementMethodVolume),
+			NewIE(IEReportingTriggers, []byte{reportingTriggerPerio, 0}),
+			uint32IE(IEMeasurementPeriod, uint32(usagePeriod/time.Second)), // #nosec: G115 -- a configured period
+		))
+	}
+
+	return ies
+}
+
+// modificationIEs encodes the updated rules of req. The PDIs are left out:
+// the tunnel and UE addresses a session matches never change after
+// establishment, and resending the uplink CHOOSE would reallocate the TEID.
+func modificationIEs(req *models.ModifyRequest) []IE {
+	var ies []IE
+
+	for _, pdr := range req.UpdatePDRs {
+		ies = append(ies, NewGroupedIE(IEUpdatePDR, pdrIEs(pdr, nil, false)...))
+	}
+
+	for _, far := range req.UpdateFARs {
+		ies = append(ies, NewGroupedIE(IEUpdateFAR, farIEs(far, IEUpdateForwardingParameters)...))
+	}
+
+	for _, qer := range req.UpdateQERs {
+		ies = append(ies, NewGroupedIE(IEUpdateQER, qerIEs(qer)...))
+	}
+
+	return ies
+}
+
+func pdrIEs(pdr models.PDR, framedRoutes []netip.Prefix, withPDI bool) []IE {
+	ies := []IE{uint16IE(IEPDRID, pdr.PDRID), uint32IE(IEPrecedence, pdrPrecedence)}
+
+	if withPDI {
+		ies = append(ies, NewGroupedIE(IEPDI, pdiIEs(pdr.PDI, framedRoutes)...))
+	}
+
+	if pdr.OuterHeaderRemoval != nil {
+		ies = append(ies, uint8IE(IEOuterHeaderRemoval, *pdr.OuterHeaderRemoval))
+	}
+
+	if pdr.FARID != 0 {
+		ies = append(ies, uint32IE(IEFARID, pdr.FARID))
+	}
+
+	if pdr.URRID != 0 {
+		ies = append(ies, uint32IE(IEURRID, pdr.URRID))
+	}
+
+	if pdr.QERID != 0 {
+		ies = append(ies, uint32IE(IEQERID, pdr.QERID))
+	}
+
+	return ies
+}
+
+// pdiIEs matches uplink G-PDUs on the tunnel the UPF allocates, and
+// downlink packets on the UE address plus the framed routes of its family.
+func pdiIEs(pdi models.PDI, framedRoutes []netip.Prefix) []IE {
+	if pdi.LocalFTEID != nil {
+		return []IE{uint8IE(IESourceInterface, InterfaceAccess), fteidChooseIE(ruleChooseID)}
+	}
+
+	ies := []IE{uint8IE(IESourceInterface, InterfaceCore)}
+
+	if !pdi.UEIPAddress.IsValid() {
+		return ies
+	}
+
+	ies = append(ies, ueIPAddressIE(pdi.UEIPAddress))
+
+	for _, route := range framedRoutes {
+		if route.Addr().Is4() != pdi.UEIPAddress.Is4() {
+			continue
+		}
+
+		if route.Addr().Is4() {
+			ies = append(ies, NewIE(IEFramedRoute, []byte(route.String())))
+		} else {
+			ies = append(ies, NewIE(IEFramedIPv6Route, []byte(route.String())))
+		}
+	}
+
+	return ies
+}
+
+// farIEs sends Forwarding Parameters only with FORW: a buffering or dropping
+// FAR has nowhere to send to. Packets leaving in a GTP-U tunnel go to the
+// access side, over S1-U or N3, the rest to the data network.
+func farIEs(far models.FAR, fwdType uint16) []IE {
+	ies := []IE{uint32IE(IEFARID, far.FARID), applyActionIE(far.ApplyAction)}
+
+	if !far.ApplyAction.Forw || far.ForwardingParameters == nil {
+		return ies
+	}
+
+	ohc := far.ForwardingParameters.OuterHeaderCreation
+	if ohc == nil {
+		return append(ies, NewGroupedIE(fwdType, uint8IE(IEDestinationInterface, InterfaceCore)))
+	}
+
+	ifType := uint8(InterfaceTypeN3)
+	if ohc.S1U {
+		ifType = InterfaceTypeS1U
+	}
+
+	return append(ies, NewGroupedIE(fwdType, uint8IE(IEDestinationInterface, InterfaceAccess), ohcIE(ohc), uint8IE(IEInterfaceType, ifType)))
+}
+
+func qerIEs(qer models.QER) []IE {
+	ies := []IE{uint32IE(IEQERID, qer.QERID)}
+
+	if qer.GateStatus != nil {
+		ies = append(ies, gateStatusIE(qer.GateStatus.ULGate, qer.GateStatus.DLGate))
+	}
+
+	if qer.MBR != nil {
+		ies = append(ies, mbrIE(qer.MBR.ULMBR, qer.MBR.DLMBR))
+	}
+
+	if qer.QFI != 0 {
+		ies = append(ies, uint8IE(IEQFI, qer.QFI))
+	}
+
+	return ies
+}
+
+// parseEstablishmentResponse returns the UP SEID and the tunnel the UPF
+// allocated for the uplink PDR.
+func parseEstablishmentResponse(ies []IE) (uint64, *models.EstablishResponse, error) {
+	if err := checkCause(ies); err != nil {
+		return 0, nil, err
+	}
+
+	fseid, ok := findIE(ies, IEFSEID)
+	if !ok {
+		return 0, nil, errors.New("establishment response without an UP F-SEID")
+	}
+
+	upSEID, err := parseFSEID(fseid)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, created := range findIEs(ies, IECreatedPDR) {
+		children, err := created.Children()
+		if err != nil {
+			return 0, nil, fmt.Errorf("Created PDR: %w", err)
+		}
+
+		fteid, ok := findIE(children, IEFTEID)
+		if !ok {
+			continue
+		}
+
+		teid, v4, v6, err := parseFTEID(fteid)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		return upSEID, &models.EstablishResponse{N3TEID: teid, N3IPv4: v4, N3IPv6: v6}, nil
+	}
+
+	return 0, nil, errors.New("establishment response without an allocated F-TEID")
+}
+
+// parseNodeReport returns the access nodes of a User Plane Path Failure
+// Report; other node reports carry nothing the SMF acts on.
+func parseNodeReport(ies []IE) ([]netip.Addr, error) {
+	ie, ok := findIE(ies, IENodeReportType)
+	if !ok {
+		return nil, errors.New("node report without a Node Report Type")
+	}
+
+	reportType, err := ie.uint8()
+	if err != nil {
+		return nil, err
+	}
+
+	if reportType&NodeReportTypeUPFR == 0 {
+		return nil, nil
+	}
+
+	failure, ok := findIE(ies, IEUserPlanePathFailureReport)
+	if !ok {
+		return nil, errors.New("path failure node report without a User Plane Path Failure Report")
+	}
+
+	children, err := failure.Children()
+	if err != nil {
+		return nil, fmt.Errorf("User Plane Path Failure Report: %w", err)
+	}
+
+	var peers []netip.Addr
+
+	for _, peer := range findIEs(children, IERemoteGTPUPeer) {
+		addr, err := parseRemoteGTPUPeer(peer)
+		if err != nil {
+			return nil, err
+		}
+
+		peers = append(peers, addr)
+	}
+
+	return peers, nil
+}
+
+// sessionReport is what a Session Report Request or a deletion response
+// carries back to the SMF.
+type sessionReport struct {
+	downlinkData     *models.DownlinkDataReport
+	usage            []*models.UsageReport
+	errorIndications []*models.ErrorIndicationReport
+}
+
+// parseSessionReport decodes the reports of a Session Report Request, or the
+// usage returned in a modification or deletion response.
+func parseSessionReport(seid uint64, ies []IE) (*sessionReport, error) {
+	r := &sessionReport{}
+
+	if ie, ok := findIE(ies, IEDownlinkDataReport); ok {
+		children, err := ie.Children()
+		if err != nil {
+			return nil, fmt.Errorf("Downlink Data Report: %w", err)
+		}
+
+		r.downlinkData = &models.DownlinkDataReport{SEID: seid}
+
+		if pdrID, ok := findIE(children, IEPDRID); ok {
+			if r.downlinkData.PDRID, err = pdrID.uint16(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, t := range []uint16{IEUsageReportSessionModification, IEUsageReportSessionDeletion, IEUsageReportSessionReport} {
+		for _, ie := range findIEs(ies, t) {
+			usage, err := parseUsageReport(seid, ie)
+			if err != nil {
+				return nil, err
+			}
+
+			if usage != nil {
+				r.usage = append(r.usage, usage)
+			}
+		}
+	}
+
+	if ie, ok := findIE(ies, IEErrorIndicationReport); ok {
+		children, err := ie.Children()
+		if err != nil {
+			return nil, fmt.Errorf("Error Indication Report: %w", err)
+		}
+
+		for _, fteid := range findIEs(children, IEFTEID) {
+			teid, addr, err := parseRemoteFTEID(fteid)
+			if err != nil {
+				return nil, err
+			}
+
+			r.errorIndications = append(r.errorIndications, &models.ErrorIndicationReport{
+				SEID:          seid,
+				RemoteTEID:    teid,
+				RemoteAddress: addr,
+			})
+		}
+	}
+
+	return r, nil
+}
+
+// parseUsageReport maps one URR's volumes onto the session's counters. A
+// UPF that reports only a total is attributed by URR: the SMF measures the
+// uplink and the downlink with separate rules.
+func parseUsageReport(seid uint64, ie IE) (*models.UsageReport, error) {
+	children, err := ie.Children()
+	if err != nil {
+		return nil, fmt.Errorf("Usage Report: %w", err)
+	}
+
+	vol, ok := findIE(children, IEVolumeMeasurement)
+	if !ok {
+		return nil, nil
+	}
+
+	total, ul, dl, err := parseVolumeMeasurement(vol)
+	if err != nil {
+		return nil, err
+	}
+
+	if ul == 0 && dl == 0 && total != 0 {
+		urrID, err := requireUint32(children, IEURRID)
+		if err != nil {
+			return nil, err
+		}
+
+		if urrID == urrIDDownlink {
+			dl = total
+		} else {
+			ul = total
+		}
+	}
+
+	return &models.UsageReport{SEID: seid, UplinkVolume: ul, DownlinkVolume: dl}, nil
+}
+
+// urrIDDownlink is the URR the SMF attaches to its downlink PDRs.
+const urrIDDownlink = 2
+
+func requireUint32(ies []IE, t uint16) (uint32, error) {
+	ie, ok := findIE(ies, t)
+	if !ok {
+		return 0, fmt.Errorf("missing IE type %d", t)
+	}
+
+	return ie.uint32()
+}
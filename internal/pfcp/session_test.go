@@ -0,0 +1,210 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+func testEstablishRequest() *models.EstablishRequest {
+	ohr := models.OuterHeaderRemovalGtpUUdpIpv4
+
+	return &models.EstablishRequest{
+		SEID: 42,
+		PDRs: []models.PDR{
+			{PDRID: 1, OuterHeaderRemoval: &ohr, FARID: 1, QERID: 1, URRID: 1, PDI: models.PDI{LocalFTEID: &models.FTEID{}}},
+			{PDRID: 2, FARID: 2, QERID: 1, URRID: 2, PDI: models.PDI{UEIPAddress: netip.MustParseAddr("10.45.0.2")}},
+		},
+		FARs: []models.FAR{
+			{FARID: 1, ApplyAction: models.ApplyAction{Forw: true}, ForwardingParameters: &models.ForwardingParameters{}},
+			{FARID: 2, ApplyAction: models.ApplyAction{Buff: true, Nocp: true}, ForwardingParameters: &models.ForwardingParameters{}},
+		},
+		QERs: []models.QER{{
+			QERID:      1,
+			QFI:        9,
+			GateStatus: &models.GateStatus{ULGate: models.GateOpen, DLGate: models.GateClose},
+			MBR:        &models.MBR{ULMBR: 100000, DLMBR: 200000},
+		}},
+		URRs:         []models.URR{{URRID: 1}, {URRID: 2}},
+		FramedRoutes: []netip.Prefix{netip.MustParsePrefix("192.168.10.0/24"), netip.MustParsePrefix("2001:db8::/48")},
+	}
+}
+
+func children(t *testing.T, ie IE) []IE {
+	t.Helper()
+
+	c, err := ie.Children()
+	if err != nil {
+		t.Fatalf("children of IE %d: %v", ie.Type, err)
+	}
+
+	return c
+}
+
+func TestEstablishmentIEs_MapsRules(t *testing.T) {
+	smf := netip.MustParseAddr("192.0.2.1")
+	ies := establishmentIEs(smf, testEstablishRequest(), 30*time.Second, nil)
+
+	fseid, ok := findIE(ies, IEFSEID)
+	if seid, err := parseFSEID(fseid); !ok || err != nil || seid != 42 {
+		t.Fatalf("CP F-SEID = %d (%v), want the SMF's SEID", seid, err)
+	}
+
+	pdrs := findIEs(ies, IECreatePDR)
+	if len(pdrs) != 2 {
+		t.Fatalf("got %d Create PDRs, want 2", len(pdrs))
+	}
+
+	uplink, _ := findIE(children(t, pdrs[0]), IEPDI)
+	fteid, _ := findIE(children(t, uplink), IEFTEID)
+
+	if fteid.Value[0]&fteidCH == 0 {
+		t.Fatalf("uplink F-TEID = %x, want CHOOSE", fteid.Value)
+	}
+
+	downlink, _ := findIE(children(t, pdrs[1]), IEPDI)
+	pdi := children(t, downlink)
+
+	ueIP, _ := findIE(pdi, IEUEIPAddress)
+	if want := []byte{ueIPSD | ueIPV4, 10, 45, 0, 2}; string(ueIP.Value) != string(want) {
+		t.Fatalf("UE IP Address = %x, want %x", ueIP.Value, want)
+	}
+
+	routes := findIEs(pdi, IEFramedRoute)
+	if len(routes) != 1 || string(routes[0].Value) != "192.168.10.0/24" || len(findIEs(pdi, IEFramedIPv6Route)) != 0 {
+		t.Fatalf("framed routes = %+v, want only the IPv4 one on the IPv4 PDR", routes)
+	}
+
+	fars := findIEs(ies, IECreateFAR)
+	if _, ok := findIE(children(t, fars[0]), IEForwardingParameters); !ok {
+		t.Fatal("forwarding FAR without Forwarding Parameters")
+	}
+
+	if _, ok := findIE(children(t, fars[1]), IEForwardingParameters); ok {
+		t.Fatal("buffering FAR sent with Forwarding Parameters")
+	}
+
+	qer := children(t, findIEs(ies, IECreateQER)[0])
+
+	mbr, _ := findIE(qer, IEMBR)
+	if ul := uint64(mbr.Value[0])<<32 | uint64(binary.BigEndian.Uint32(mbr.Value[1:5])); ul != 100000 {
+		t.Fatalf("UL MBR = %d, want 100000", ul)
+	}
+
+	gate, _ := findIE(qer, IEGateStatus)
+	if gate.Value[0] != models.GateClose {
+		t.Fatalf("gate status = %#x, want only the downlink closed", gate.Value[0])
+	}
+
+	urr := children(t, findIEs(ies, IECreateURR)[1])
+	if period, err := requireUint32(urr, IEMeasurementPeriod); err != nil || period != 30 {
+		t.Fatalf("measurement period = %d (%v), want 30", period, err)
+	}
+}
+
+func TestModificationIEs_OmitsPDI(t *testing.T) {
+	req := testEstablishRequest()
+	req.FARs[1] = models.FAR{
+		FARID:       2,
+		ApplyAction: models.ApplyAction{Forw: true},
+		ForwardingParameters: &models.ForwardingParameters{OuterHeaderCreation: &models.OuterHeaderCreation{
+			Description: models.OuterHeaderCreationGtpUUdpIpv4,
+			TEID:        0x11223344,
+			IPv4Address: net.ParseIP("10.0.0.1"),
+		}},
+	}
+
+	ies := modificationIEs(&models.ModifyRequest{SEID: 42, UpdatePDRs: req.PDRs, UpdateFARs: req.FARs, UpdateQERs: req.QERs})
+
+	for _, pdr := range findIEs(ies, IEUpdatePDR) {
+		if _, ok := findIE(children(t, pdr), IEPDI); ok {
+			t.Fatal("Update PDR carries a PDI")
+		}
+	}
+
+	far := children(t, findIEs(ies, IEUpdateFAR)[1])
+
+	fwd, ok := findIE(far, IEUpdateForwardingParameters)
+	if !ok {
+		t.Fatal("forwarding FAR update without Update Forwarding Parameters")
+	}
+
+	params := children(t, fwd)
+
+	dest, _ := findIE(params, IEDestinationInterface)
+	ohc, _ := findIE(params, IEOuterHeaderCreation)
+
+	want := []byte{0x01, 0x00, 0x11, 0x22, 0x33, 0x44, 10, 0, 0, 1}
+	if dest.Value[0] != InterfaceAccess || string(ohc.Value) != string(want) {
+		t.Fatalf("destination %d OHC %x, want access and %x", dest.Value[0], ohc.Value, want)
+	}
+}
+
+func TestParseEstablishmentResponse(t *testing.T) {
+	fteid := []byte{fteidV4, 0, 0, 0x10, 0x01, 192, 0, 2, 9}
+
+	upSEID, resp, err := parseEstablishmentResponse([]IE{
+		CauseIE(CauseRequestAccepted),
+		FSEIDIE(77, netip.MustParseAddr("192.0.2.9")),
+		NewGroupedIE(IECreatedPDR, uint16IE(IEPDRID, 1), NewIE(IEFTEID, fteid)),
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if upSEID != 77 || resp.N3TEID != 0x1001 || resp.N3IPv4 != netip.MustParseAddr("192.0.2.9") || resp.N3IPv6.IsValid() {
+		t.Fatalf("got SEID %d %+v", upSEID, resp)
+	}
+
+	_, _, err = parseEstablishmentResponse([]IE{CauseIE(CauseSessionContextNotFound)})
+	if !errors.Is(err, models.ErrSessionNotFound) {
+		t.Fatalf("err = %v, want ErrSessionNotFound for a context-not-found cause", err)
+	}
+}
+
+func volumeIE(flags byte, values ...uint64) IE {
+	b := []byte{flags}
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint64(b, v)
+	}
+
+	return NewIE(IEVolumeMeasurement, b)
+}
+
+func TestParseSessionReport(t *testing.T) {
+	r, err := parseSessionReport(42, []IE{
+		uint8IE(IEReportType, ReportTypeDLDR|ReportTypeUSAR|ReportTypeERIR),
+		NewGroupedIE(IEDownlinkDataReport, uint16IE(IEPDRID, 2)),
+		NewGroupedIE(IEUsageReportSessionReport, uint32IE(IEURRID, 1), volumeIE(volumeTotal|volumeUplink|volumeDownlink, 30, 10, 20)),
+		NewGroupedIE(IEUsageReportSessionReport, uint32IE(IEURRID, 2), volumeIE(volumeTotal, 500)),
+		NewGroupedIE(IEErrorIndicationReport, NewIE(IEFTEID, []byte{fteidV4, 0, 0, 0, 7, 10, 0, 0, 1})),
+	})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if r.downlinkData == nil || r.downlinkData.SEID != 42 || r.downlinkData.PDRID != 2 {
+		t.Fatalf("downlink data = %+v", r.downlinkData)
+	}
+
+	if len(r.usage) != 2 || r.usage[0].UplinkVolume != 10 || r.usage[0].DownlinkVolume != 20 {
+		t.Fatalf("usage = %+v, want the split volumes first", r.usage)
+	}
+
+	if u := r.usage[1]; u.UplinkVolume != 0 || u.DownlinkVolume != 500 {
+		t.Fatalf("total-only usage on the downlink URR = %+v, want it counted downlink", u)
+	}
+
+	if len(r.errorIndications) != 1 || r.errorIndications[0].RemoteTEID != 7 ||
+		r.errorIndications[0].RemoteAddress != netip.MustParseAddr("10.0.0.1") {
+		t.Fatalf("error indications = %+v", r.errorIndications)
+	}
+}
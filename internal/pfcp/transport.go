@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package pfcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// T1 and N1 (TS 29.244 §6.4): a request is sent at most 1+N1 times, T1
+	// apart, before the peer is considered unreachable.
+	defaultT1 = 3 * time.Second
+	defaultN1 = 3
+
+	maxMessageSize = 65535
+)
+
+// transport is a PFCP socket: it retransmits the requests it sends and
+// matches their responses by sequence number.
+type transport struct {
+	conn *net.UDPConn
+
+	t1 time.Duration
+	n1 int
+
+	seq atomic.Uint32
+
+	pmu     sync.Mutex
+	pending map[uint32]chan *Message
+}
+
+func listen(addr netip.AddrPort) (*transport, error) {
+	conn, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(addr))
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+
+	return &transport{conn: conn, t1: defaultT1, n1: defaultN1, pending: make(map[uint32]chan *Message)}, nil
+}
+
+// LocalAddr is the address the socket listens on.
+func (t *transport) LocalAddr() netip.AddrPort {
+	return t.conn.LocalAddr().(*net.UDPAddr).AddrPort()
+}
+
+// serve reads the socket until it is closed, delivering responses to their
+// requests and handing requests to handle on the read loop.
+func (t *transport) serve(ctx context.Context, log *zap.Logger, handle func(context.Context, netip.AddrPort, *Message)) {
+	buf := make([]byte, maxMessageSize)
+
+	for {
+		n, from, err := t.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			log.Warn("failed to read from the N4 socket", zap.Error(err))
+
+			continue
+		}
+
+		msg, err := ParseMessage(buf[:n])
+		if err != nil {
+			log.Debug("dropping a malformed PFCP message", zap.Error(err), zap.Stringer("peer", from))
+			continue
+		}
+
+		from = netip.AddrPortFrom(from.Addr().Unmap(), from.Port())
+
+		if msg.IsResponse() {
+			t.deliver(msg)
+			continue
+		}
+
+		handle(ctx, from, msg)
+	}
+}
+
+func (t *transport) deliver(msg *Message) {
+	t.pmu.Lock()
+	ch, ok := t.pending[msg.Sequence]
+	delete(t.pending, msg.Sequence)
+	t.pmu.Unlock()
+
+	if ok {
+		ch <- msg
+	}
+}
+
+func (t *transport) send(to netip.AddrPort, msg *Message) error {
+	_, err := t.conn.WriteToUDPAddrPort(msg.Marshal(), to)
+	return err
+}
+
+// request sends msg and waits for its response, retransmitting N1 times.
+func (t *transport) request(ctx context.Context, to netip.AddrPort, msg *Message) (*Message, error) {
+	msg.Sequence = t.seq.Add(1) & 0xffffff
+	ch := make(chan *Message, 1)
+
+	t.pmu.Lock()
+	t.pending[msg.Sequence] = ch
+	t.pmu.Unlock()
+
+	defer func() {
+		t.pmu.Lock()
+		delete(t.pending, msg.Sequence)
+		t.pmu.Unlock()
+	}()
+
+	timer := time.NewTimer(t.t1)
+	defer timer.Stop()
+
+	for attempt := 0; ; attempt++ {
+		if err := t.send(to, msg); err != nil {
+			return nil, fmt.Errorf("send to %s: %w", to, err)
+		}
+
+		select {
+		case resp := <-ch:
+			return resp, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if attempt == t.n1 {
+			return nil, fmt.Errorf("no response from %s to PFCP message type %d", to, msg.Type)
+		}
+
+		timer.Reset(t.t1)
+	}
+}
@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package policycontrol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const npcfSMPolicies = "/npcf-smpolicycontrol/v1/sm-policies"
+
+// npcf is an Npcf_SMPolicyControl client (TS 29.512 §5).
+type npcf struct {
+	base string
+	http *http.Client
+}
+
+// NewNpcf returns a client of the decision point at base, the URL under
+// which it serves the Npcf_SMPolicyControl API.
+func NewNpcf(base string) (PDP, error) {
+	u, err := url.Parse(base)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid policy decision point URL %q", base)
+	}
+
+	return &npcf{
+		base: strings.TrimSuffix(base, "/"),
+		http: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Policy control data types of TS 29.512 §5.6.2, the parts this client
+// uses.
+type (
+	smPolicyContextData struct {
+		Supi         string      `json:"supi"`
+		PDUSessionID uint8       `json:"pduSessionId"`
+		DNN          string      `json:"dnn"`
+		SliceInfo    snssai      `json:"sliceInfo"`
+		AccessType   string      `json:"accessType"`
+		RATType      RAT         `json:"ratType"`
+		SubsSessAmbr *ambr       `json:"subsSessAmbr,omitempty"`
+		SubsDefQos   *defaultQos `json:"subsDefQos,omitempty"`
+	}
+
+	snssai struct {
+		SST int32  `json:"sst"`
+		SD  string `json:"sd,omitempty"`
+	}
+
+	ambr struct {
+		Uplink   string `json:"uplink"`
+		Downlink string `json:"downlink"`
+	}
+
+	defaultQos struct {
+		Var5qi int32 `json:"5qi"`
+		Arp    *arp  `json:"arp,omitempty"`
+	}
+
+	arp struct {
+		PriorityLevel int32 `json:"priorityLevel"`
+	}
+
+	smPolicyUpdateContextData struct {
+		RepPolicyCtrlReqTriggers []string `json:"repPolicyCtrlReqTriggers"`
+		RATType                  RAT      `json:"ratType"`
+	}
+
+	// smPolicyDecision is a decision or, in an update's answer, the changes
+	// to one: a null rule removes it.
+	smPolicyDecision struct {
+		SessRules map[string]*sessionRule `json:"sessRules"`
+		PccRules  map[string]*pccRule     `json:"pccRules"`
+	}
+
+	sessionRule struct {
+		AuthSessAmbr *ambr       `json:"authSessAmbr"`
+		AuthDefQos   *defaultQos `json:"authDefQos"`
+	}
+
+	pccRule struct {
+		PccRuleID string `json:"pccRuleId"`
+	}
+)
+
+// Create sets up a policy association. The decision point names the
+// association in the Location header of its answer.
+func (n *npcf) Create(ctx context.Context, sess *Session) (string, *Answer, error) {
+	data := smPolicyContextData{
+		Supi:         "imsi-" + sess.IMSI,
+		PDUSessionID: sess.PDUSessionID,
+		DNN:          sess.DNN,
+		SliceInfo:    snssai{SST: sess.Snssai.Sst, SD: sess.Snssai.Sd},
+		AccessType:   "3GPP_ACCESS",
+		RATType:      sess.RAT,
+	}
+
+	if sess.SessionAmbrUplink != "" {
+		data.SubsSessAmbr = &ambr{Uplink: sess.SessionAmbrUplink, Downlink: sess.SessionAmbrDownlink}
+	}
+
+	if sess.Var5qi != 0 {
+		data.SubsDefQos = &defaultQos{Var5qi: sess.Var5qi, Arp: &arp{PriorityLevel: sess.Arp}}
+	}
+
+	resp, err := n.post(ctx, n.base+npcfSMPolicies, data, http.StatusCreated)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		return "", nil, errors.New("policy decision point created no association")
+	}
+
+	decision, err := decodeDecision(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ans := &Answer{}
+	merge(ans, decision)
+
+	return path.Base(loc), ans, nil
+}
+
+// Update reports a change of radio access type. The decision point answers
+// with the changes to current, or with no content when there are none.
+func (n *npcf) Update(ctx context.Context, id string, rat RAT, current *Answer) (*Answer, error) {
+	data := smPolicyUpdateContextData{RepPolicyCtrlReqTriggers: []string{"RAT_TY_CH"}, RATType: rat}
+
+	resp, err := n.post(ctx, n.base+npcfSMPolicies+"/"+url.PathEscape(id)+"/update", data, http.StatusOK, http.StatusNoContent)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	ans := *current
+
+	if resp.StatusCode == http.StatusNoContent {
+		return &ans, nil
+	}
+
+	decision, err := decodeDecision(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	merge(&ans, decision)
+
+	return &ans, nil
+}
+
+// Delete ends a policy association.
+func (n *npcf) Delete(ctx context.Context, id string) error {
+	resp, err := n.post(ctx, n.base+npcfSMPolicies+"/"+url.PathEscape(id)+"/delete", struct{}{}, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func (n *npcf) post(ctx context.Context, target string, data any, want ...int) (*http.Response, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.http.Do(req) // #nosec G107 -- the decision point URL is operator-configured
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range want {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	return nil, fmt.Errorf("policy decision point answered %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+func decodeDecision(r io.Reader) (*smPolicyDecision, error) {
+	var decision smPolicyDecision
+	if err := json.NewDecoder(io.LimitReader(r, 1<<20)).Decode(&decision); err != nil {
+		return nil, fmt.Errorf("invalid policy decision: %w", err)
+	}
+
+	return &decision, nil
+}
+
+// merge applies a decision, or the changes to one, to ans. Sessions have a
+// single session rule, and predefined PCC rules name the policy whose
+// network rules apply; a removed rule restores the local policy's.
+func merge(ans *Answer, decision *smPolicyDecision) {
+	for _, rule := range decision.SessRules {
+		if rule == nil {
+			ans.Var5qi, ans.Arp = 0, 0
+			ans.SessionAmbrUplink, ans.SessionAmbrDownlink = "", ""
+
+			continue
+		}
+
+		if q := rule.AuthDefQos; q != nil {
+			ans.Var5qi = q.Var5qi
+			if q.Arp != nil {
+				ans.Arp = q.Arp.PriorityLevel
+			}
+		}
+
+		if a := rule.AuthSessAmbr; a != nil {
+			ans.SessionAmbrUplink, ans.SessionAmbrDownlink = a.Uplink, a.Downlink
+		}
+	}
+
+	for name, rule := range decision.PccRules {
+		switch {
+		case rule == nil:
+			if name == ans.RulesPolicy {
+				ans.RulesPolicy = ""
+			}
+		case rule.PccRuleID != "":
+			ans.RulesPolicy = rule.PccRuleID
+		default:
+			ans.RulesPolicy = name
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package policycontrol
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// newPCF is a stand-in decision point: it gives sessions 5QI 7 and the rules
+// of "work-order-42", and takes the rules away when a session moves to
+// E-UTRA.
+func newPCF(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var calls []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.URL.Path == npcfSMPolicies:
+			var data smPolicyContextData
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Supi != "imsi-001010000000001" || data.SubsDefQos == nil {
+				http.Error(w, "bad context data", http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Location", "http://"+r.Host+npcfSMPolicies+"/sm-7")
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{
+				"sessRules": {"s1": {"authDefQos": {"5qi": 7, "arp": {"priorityLevel": 2}},
+					"authSessAmbr": {"uplink": "10 Mbps", "downlink": "20 Mbps"}}},
+				"pccRules": {"work-order-42": {"pccRuleId": "work-order-42"}}
+			}`))
+		case strings.HasSuffix(r.URL.Path, "/update"):
+			var data smPolicyUpdateContextData
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if data.RATType != RATEUTRA {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"pccRules": {"work-order-42": null}}`))
+		case strings.HasSuffix(r.URL.Path, "/delete"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestNpcfSMPolicyControl(t *testing.T) {
+	srv, calls := newPCF(t)
+
+	c, err := NewNpcf(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("NewNpcf: %v", err)
+	}
+
+	ctx := context.Background()
+	sess := &Session{IMSI: "001010000000001", PDUSessionID: 1, DNN: "internet",
+		Snssai: models.Snssai{Sst: 1}, RAT: RATNR, Var5qi: 9, Arp: 1,
+		SessionAmbrUplink: "100 Mbps", SessionAmbrDownlink: "200 Mbps"}
+
+	id, ans, err := c.Create(ctx, sess)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	want := Answer{Var5qi: 7, Arp: 2, SessionAmbrUplink: "10 Mbps", SessionAmbrDownlink: "20 Mbps", RulesPolicy: "work-order-42"}
+	if id != "sm-7" || *ans != want {
+		t.Fatalf("create = %q, %+v, want sm-7, %+v", id, ans, want)
+	}
+
+	same, err := c.Update(ctx, id, RATNR, ans)
+	if err != nil || *same != want {
+		t.Fatalf("update without changes = %+v, %v, want %+v", same, err, want)
+	}
+
+	moved, err := c.Update(ctx, id, RATEUTRA, ans)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	want.RulesPolicy = ""
+	if *moved != want {
+		t.Fatalf("update = %+v, want the rules removed: %+v", moved, want)
+	}
+
+	if err := c.Delete(ctx, id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	wantCalls := []string{
+		"POST " + npcfSMPolicies,
+		"POST " + npcfSMPolicies + "/sm-7/update",
+		"POST " + npcfSMPolicies + "/sm-7/update",
+		"POST " + npcfSMPolicies + "/sm-7/delete",
+	}
+	if strings.Join(*calls, "\n") != strings.Join(wantCalls, "\n") {
+		t.Fatalf("calls = %q, want %q", *calls, wantCalls)
+	}
+}
+
+func TestNpcfRejected(t *testing.T) {
+	srv, _ := newPCF(t)
+
+	c, err := NewNpcf(srv.URL)
+	if err != nil {
+		t.Fatalf("NewNpcf: %v", err)
+	}
+
+	if _, _, err := c.Create(context.Background(), &Session{IMSI: "001010000000002"}); err == nil {
+		t.Fatal("create for an unknown subscriber succeeded")
+	}
+}
+
+func TestNewNpcfInvalidURL(t *testing.T) {
+	for _, u := range []string{"", "pcf.example.com", "ftp://pcf.example.com"} {
+		if _, err := NewNpcf(u); err == nil {
+			t.Errorf("NewNpcf(%q) succeeded", u)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package policycontrol has an external policy decision point set the QoS
+// and network rules of sessions, over Npcf_SMPolicyControl (TS 29.512). A
+// session asks for its policy when it starts and reports when it moves to
+// another access; the decision point may change its decision at any time
+// through the API, whose writes reach the node serving the session through
+// the sm_policy_associations table. Sessions whose decision point cannot be
+// reached keep their local policy.
+package policycontrol
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// ErrUnknownRules is returned when a decision activates rules that are not
+// a policy of the session's data network.
+var ErrUnknownRules = errors.New("rules are not a policy of the data network")
+
+// RAT is the radio access type of a session, as Npcf_SMPolicyControl names
+// it (TS 29.571 §5.4.3.2).
+type RAT string
+
+const (
+	RATNR    RAT = "NR"
+	RATEUTRA RAT = "EUTRA"
+)
+
+// Session describes a session when it starts. Its QoS and Session-AMBR are
+// those of the local policy, reported as the subscribed defaults.
+type Session struct {
+	// Ref identifies the session to the caller; later calls name it.
+	Ref                 string
+	IMSI                string
+	PDUSessionID        uint8
+	DNN                 string
+	Snssai              models.Snssai
+	RAT                 RAT
+	Var5qi              int32
+	Arp                 int32
+	SessionAmbrUplink   string
+	SessionAmbrDownlink string
+}
+
+// Decision is what the decision point sets for a session. Zero values keep
+// the local policy's: a 5QI, an ARP priority level, a Session-AMBR, and the
+// policy of the session's data network whose network rules apply in place
+// of the subscriber's.
+type Decision struct {
+	Var5qi              int32
+	Arp                 int32
+	SessionAmbrUplink   string
+	SessionAmbrDownlink string
+	// RulesPolicyID is the ID of the policy whose network rules apply.
+	RulesPolicyID string
+}
+
+// Answer is a decision as the decision point sends it, naming rules by
+// policy name.
+type Answer struct {
+	Var5qi              int32
+	Arp                 int32
+	SessionAmbrUplink   string
+	SessionAmbrDownlink string
+	RulesPolicy         string
+}
+
+// PDP is a client of a policy decision point.
+type PDP interface {
+	// Create sets up the policy association of a session and returns its ID
+	// and first decision.
+	Create(ctx context.Context, sess *Session) (string, *Answer, error)
+	// Update reports a session's move to another access and returns the
+	// decision in force after it.
+	Update(ctx context.Context, id string, rat RAT, current *Answer) (*Answer, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// Applier puts decisions in force on the sessions of this node.
+type Applier interface {
+	ApplyDecision(ctx context.Context, ref string, decision Decision) error
+}
+
+// Store is the part of the database the service uses. *db.Database
+// satisfies it.
+type Store interface {
+	GetDataNetwork(ctx context.Context, name string) (*db.DataNetwork, error)
+	GetDataNetworkPolicyControl(ctx context.Context, dataNetworkID string) (*db.DataNetworkPolicyControl, error)
+	GetPolicy(ctx context.Context, name string) (*db.Policy, error)
+	GetPolicyByID(ctx context.Context, id string) (*db.Policy, error)
+	CreateSMPolicyAssociation(ctx context.Context, assoc *db.SMPolicyAssociation) error
+	UpdateSMPolicyDecision(ctx context.Context, assoc *db.SMPolicyAssociation) error
+	DeleteSMPolicyAssociation(ctx context.Context, id string) error
+	DeleteSMPolicyAssociationsByNode(ctx context.Context, nodeID int) error
+	ListSMPolicyAssociationsByNode(ctx context.Context, nodeID int) ([]db.SMPolicyAssociation, error)
+}
+
+// DecisionOf returns the decision an association records.
+func DecisionOf(assoc *db.SMPolicyAssociation) Decision {
+	d := Decision{
+		Var5qi:              assoc.Var5qi,
+		Arp:                 assoc.Arp,
+		SessionAmbrUplink:   assoc.SessionAmbrUplink,
+		SessionAmbrDownlink: assoc.SessionAmbrDownlink,
+	}
+
+	if assoc.RulesPolicyID != nil {
+		d.RulesPolicyID = *assoc.RulesPolicyID
+	}
+
+	return d
+}
+
+// Record sets the decision columns of an association to d.
+func (d Decision) Record(assoc *db.SMPolicyAssociation) {
+	assoc.Var5qi = d.Var5qi
+	assoc.Arp = d.Arp
+	assoc.SessionAmbrUplink = d.SessionAmbrUplink
+	assoc.SessionAmbrDownlink = d.SessionAmbrDownlink
+	assoc.RulesPolicyID = nil
+
+	if d.RulesPolicyID != "" {
+		id := d.RulesPolicyID
+		assoc.RulesPolicyID = &id
+	}
+}
@@ -0,0 +1,489 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package policycontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// reconcileBackstop is the sweep that runs when no wakeup fired.
+	reconcileBackstop = time.Minute
+	// requestTimeout bounds one exchange with a decision point.
+	requestTimeout = 5 * time.Second
+)
+
+// default5Qis are the 5QIs a session's default QoS flow may take.
+var default5Qis = []int32{5, 6, 7, 8, 9, 69, 70, 79, 80}
+
+// Service keeps the policy associations of this node's sessions with the
+// decision points of their data networks, and puts the decisions recorded
+// for them in force.
+type Service struct {
+	store    Store
+	nodeID   int
+	wakeup   <-chan struct{}
+	backstop time.Duration
+	newPDP   func(url string) (PDP, error)
+
+	mu       sync.Mutex
+	applier  Applier
+	clients  map[string]PDP
+	sessions map[string]*association
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	// exchanges tracks requests sent outside the loop, so Stop can wait
+	// for them.
+	exchanges sync.WaitGroup
+}
+
+// association is the policy association of a session. applied is guarded
+// by Service.mu; exch serializes its exchanges and is held without it.
+type association struct {
+	id            string
+	dataNetworkID string
+	pdp           PDP
+	applied       Decision
+	exch          sync.Mutex
+}
+
+// NewService controls the policy of sessions on the data networks store
+// configures for it, recording their associations as node nodeID. wakeup is
+// signalled when decisions changed; nil leaves only the backstop sweep.
+// Start must be called before sessions are established.
+func NewService(store Store, nodeID int, wakeup <-chan struct{}) *Service {
+	return &Service{
+		store:    store,
+		nodeID:   nodeID,
+		wakeup:   wakeup,
+		backstop: reconcileBackstop,
+		newPDP:   NewNpcf,
+		clients:  make(map[string]PDP),
+		sessions: make(map[string]*association),
+	}
+}
+
+// Start ends the associations this node left behind when it last stopped,
+// whose sessions are gone, and launches the loop that puts changed
+// decisions in force through applier. Calls without a paired Stop are
+// no-ops.
+func (s *Service) Start(applier Applier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	s.applier = applier
+	s.clearStale(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx, s.done)
+}
+
+// Stop ends the loop and waits for the requests in flight. The
+// associations of sessions still up are kept, to be ended on the next
+// Start. Safe to call when not started.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	done := s.done
+	s.cancel = nil
+	s.done = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.exchanges.Wait()
+}
+
+// Create sets up the policy association of a session being established and
+// returns the decision for it. ok is false when the session's data network
+// has no external policy control.
+func (s *Service) Create(ctx context.Context, sess Session) (Decision, bool, error) {
+	dn, url, err := s.settings(ctx, sess.DNN)
+	if err != nil || url == "" {
+		return Decision{}, false, err
+	}
+
+	pdp, err := s.client(url)
+	if err != nil {
+		return Decision{}, false, err
+	}
+
+	id, ans, err := pdp.Create(ctx, &sess)
+	if err != nil {
+		return Decision{}, false, err
+	}
+
+	decision, err := s.resolve(ctx, dn.ID, ans)
+	if err != nil {
+		s.end(pdp, id)
+		return Decision{}, false, fmt.Errorf("invalid decision for association %q: %w", id, err)
+	}
+
+	assoc := &db.SMPolicyAssociation{
+		ID:            id,
+		DataNetworkID: dn.ID,
+		IMSI:          sess.IMSI,
+		PDUSessionID:  int(sess.PDUSessionID),
+		NodeID:        s.nodeID,
+	}
+	decision.Record(assoc)
+
+	if err := s.store.CreateSMPolicyAssociation(ctx, assoc); err != nil {
+		s.end(pdp, id)
+		return Decision{}, false, fmt.Errorf("couldn't record policy association: %w", err)
+	}
+
+	s.mu.Lock()
+	s.sessions[sess.Ref] = &association{id: id, dataNetworkID: dn.ID, pdp: pdp, applied: decision}
+	s.mu.Unlock()
+
+	return decision, true, nil
+}
+
+// SessionMoved reports a session's move to another radio access type and
+// records the decision in force after it, which the loop then applies. It
+// returns at once.
+func (s *Service) SessionMoved(ref string, rat RAT) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.sessions[ref]
+	if !ok {
+		return
+	}
+
+	current := a.applied
+
+	s.exchanges.Go(func() {
+		a.exch.Lock()
+		defer a.exch.Unlock()
+
+		if err := s.update(a, rat, current); err != nil {
+			logger.PolicyLog.Warn("couldn't report access change to the policy decision point",
+				zap.String("association", a.id), zap.String("ratType", string(rat)), zap.Error(err))
+		}
+	})
+}
+
+// SessionStopped ends the policy association of a session. It returns at
+// once.
+func (s *Service) SessionStopped(ref string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.sessions[ref]
+	if !ok {
+		return
+	}
+
+	delete(s.sessions, ref)
+
+	s.exchanges.Go(func() {
+		a.exch.Lock()
+		defer a.exch.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		if err := s.store.DeleteSMPolicyAssociation(ctx, a.id); err != nil && !errors.Is(err, db.ErrNotFound) {
+			logger.PolicyLog.Warn("couldn't delete policy association", zap.String("association", a.id), zap.Error(err))
+		}
+
+		if err := a.pdp.Delete(ctx, a.id); err != nil {
+			logger.PolicyLog.Warn("couldn't end policy association", zap.String("association", a.id), zap.Error(err))
+		}
+	})
+}
+
+// settings returns a data network and the URL of its decision point, empty
+// when it has no external policy control.
+func (s *Service) settings(ctx context.Context, dnn string) (*db.DataNetwork, string, error) {
+	dn, err := s.store.GetDataNetwork(ctx, dnn)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, "", nil
+		}
+
+		return nil, "", fmt.Errorf("couldn't get data network: %w", err)
+	}
+
+	pc, err := s.store.GetDataNetworkPolicyControl(ctx, dn.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, "", nil
+		}
+
+		return nil, "", fmt.Errorf("couldn't get policy control settings: %w", err)
+	}
+
+	return dn, pc.URL, nil
+}
+
+// client returns the client of the decision point at url, shared by the
+// data networks it serves.
+func (s *Service) client(url string) (PDP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pdp, ok := s.clients[url]; ok {
+		return pdp, nil
+	}
+
+	pdp, err := s.newPDP(url)
+	if err != nil {
+		return nil, err
+	}
+
+	s.clients[url] = pdp
+
+	return pdp, nil
+}
+
+// resolve checks an answer and names its rules by policy ID.
+func (s *Service) resolve(ctx context.Context, dataNetworkID string, ans *Answer) (Decision, error) {
+	if ans.Var5qi != 0 && !slices.Contains(default5Qis, ans.Var5qi) {
+		return Decision{}, fmt.Errorf("5QI %d cannot be that of a default QoS flow", ans.Var5qi)
+	}
+
+	if ans.Arp < 0 || ans.Arp > 15 {
+		return Decision{}, fmt.Errorf("ARP priority level %d is out of range", ans.Arp)
+	}
+
+	if (ans.SessionAmbrUplink == "") != (ans.SessionAmbrDownlink == "") {
+		return Decision{}, errors.New("session AMBR needs both an uplink and a downlink")
+	}
+
+	if ans.SessionAmbrUplink != "" {
+		for _, rate := range []string{ans.SessionAmbrUplink, ans.SessionAmbrDownlink} {
+			if _, err := models.ParseBitRate(rate); err != nil {
+				return Decision{}, err
+			}
+		}
+	}
+
+	d := Decision{
+		Var5qi:              ans.Var5qi,
+		Arp:                 ans.Arp,
+		SessionAmbrUplink:   ans.SessionAmbrUplink,
+		SessionAmbrDownlink: ans.SessionAmbrDownlink,
+	}
+
+	if ans.RulesPolicy == "" {
+		return d, nil
+	}
+
+	policy, err := s.store.GetPolicy(ctx, ans.RulesPolicy)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return Decision{}, fmt.Errorf("%w: %q", ErrUnknownRules, ans.RulesPolicy)
+		}
+
+		return Decision{}, fmt.Errorf("couldn't get policy: %w", err)
+	}
+
+	if policy.DataNetworkID != dataNetworkID {
+		return Decision{}, fmt.Errorf("%w: %q", ErrUnknownRules, ans.RulesPolicy)
+	}
+
+	d.RulesPolicyID = policy.ID
+
+	return d, nil
+}
+
+// update reports a change of radio access type and records the decision in
+// force after it.
+func (s *Service) update(a *association, rat RAT, current Decision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	ans := &Answer{
+		Var5qi:              current.Var5qi,
+		Arp:                 current.Arp,
+		SessionAmbrUplink:   current.SessionAmbrUplink,
+		SessionAmbrDownlink: current.SessionAmbrDownlink,
+	}
+
+	if current.RulesPolicyID != "" {
+		policy, err := s.store.GetPolicyByID(ctx, current.RulesPolicyID)
+		if err != nil {
+			return fmt.Errorf("couldn't get policy: %w", err)
+		}
+
+		ans.RulesPolicy = policy.Name
+	}
+
+	ans, err := a.pdp.Update(ctx, a.id, rat, ans)
+	if err != nil {
+		return err
+	}
+
+	decision, err := s.resolve(ctx, a.dataNetworkID, ans)
+	if err != nil {
+		return fmt.Errorf("invalid decision: %w", err)
+	}
+
+	if decision == current {
+		return nil
+	}
+
+	assoc := &db.SMPolicyAssociation{ID: a.id}
+	decision.Record(assoc)
+
+	return s.store.UpdateSMPolicyDecision(ctx, assoc)
+}
+
+// end ends an association the session will not use.
+func (s *Service) end(pdp PDP, id string) {
+	s.exchanges.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+
+		if err := pdp.Delete(ctx, id); err != nil {
+			logger.PolicyLog.Warn("couldn't end policy association", zap.String("association", id), zap.Error(err))
+		}
+	})
+}
+
+// clearStale ends the associations recorded for this node, best-effort.
+// Caller holds s.mu.
+func (s *Service) clearStale(ctx context.Context) {
+	stale, err := s.store.ListSMPolicyAssociationsByNode(ctx, s.nodeID)
+	if err != nil {
+		logger.PolicyLog.Warn("couldn't list stale policy associations", zap.Error(err))
+		return
+	}
+
+	if len(stale) == 0 {
+		return
+	}
+
+	urls := make(map[string]string)
+
+	for _, assoc := range stale {
+		url, ok := urls[assoc.DataNetworkID]
+		if !ok {
+			pc, err := s.store.GetDataNetworkPolicyControl(ctx, assoc.DataNetworkID)
+			if err == nil {
+				url = pc.URL
+			}
+
+			urls[assoc.DataNetworkID] = url
+		}
+
+		if url == "" {
+			continue
+		}
+
+		pdp, ok := s.clients[url]
+		if !ok {
+			if pdp, err = s.newPDP(url); err != nil {
+				continue
+			}
+
+			s.clients[url] = pdp
+		}
+
+		s.end(pdp, assoc.ID)
+	}
+
+	if err := s.store.DeleteSMPolicyAssociationsByNode(ctx, s.nodeID); err != nil {
+		logger.PolicyLog.Warn("couldn't delete stale policy associations", zap.Error(err))
+		return
+	}
+
+	logger.PolicyLog.Info("ended stale policy associations", zap.Int("count", len(stale)))
+}
+
+func (s *Service) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	backstop := time.NewTicker(s.backstop)
+	defer backstop.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wakeup:
+		case <-backstop.C:
+		}
+
+		if err := s.Reconcile(ctx); err != nil {
+			logger.PolicyLog.Warn("policy decision reconciliation failed", zap.Error(err))
+		}
+	}
+}
+
+// Reconcile puts the decisions recorded for this node's sessions in force
+// where they changed.
+func (s *Service) Reconcile(ctx context.Context) error {
+	rows, err := s.store.ListSMPolicyAssociationsByNode(ctx, s.nodeID)
+	if err != nil {
+		return fmt.Errorf("couldn't list policy associations: %w", err)
+	}
+
+	recorded := make(map[string]Decision, len(rows))
+	for i := range rows {
+		recorded[rows[i].ID] = DecisionOf(&rows[i])
+	}
+
+	type change struct {
+		ref      string
+		a        *association
+		decision Decision
+	}
+
+	s.mu.Lock()
+	applier := s.applier
+
+	var changes []change
+
+	for ref, a := range s.sessions {
+		d, ok := recorded[a.id]
+		if ok && d != a.applied {
+			changes = append(changes, change{ref: ref, a: a, decision: d})
+		}
+	}
+	s.mu.Unlock()
+
+	if applier == nil {
+		return nil
+	}
+
+	for _, c := range changes {
+		if err := applier.ApplyDecision(ctx, c.ref, c.decision); err != nil {
+			logger.PolicyLog.Warn("couldn't apply policy decision",
+				zap.String("association", c.a.id), zap.Error(err))
+
+			continue
+		}
+
+		s.mu.Lock()
+		c.a.applied = c.decision
+		s.mu.Unlock()
+	}
+
+	return nil
+}
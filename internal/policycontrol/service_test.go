@@ -0,0 +1,366 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package policycontrol
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/db"
+)
+
+type fakePDP struct {
+	mu      sync.Mutex
+	answer  Answer
+	moved   *Answer
+	err     error
+	created int
+	deleted []string
+}
+
+func (p *fakePDP) Create(context.Context, *Session) (string, *Answer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return "", nil, p.err
+	}
+
+	p.created++
+	ans := p.answer
+
+	return "sm-1", &ans, nil
+}
+
+func (p *fakePDP) Update(_ context.Context, _ string, _ RAT, current *Answer) (*Answer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.moved == nil {
+		return current, nil
+	}
+
+	ans := *p.moved
+
+	return &ans, nil
+}
+
+func (p *fakePDP) Delete(_ context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.deleted = append(p.deleted, id)
+
+	return nil
+}
+
+type fakeStore struct {
+	mu       sync.Mutex
+	url      string
+	policies map[string]*db.Policy
+	assocs   map[string]db.SMPolicyAssociation
+}
+
+func (f *fakeStore) GetDataNetwork(_ context.Context, name string) (*db.DataNetwork, error) {
+	return &db.DataNetwork{ID: "dn-" + name, Name: name}, nil
+}
+
+func (f *fakeStore) GetDataNetworkPolicyControl(_ context.Context, dataNetworkID string) (*db.DataNetworkPolicyControl, error) {
+	if f.url == "" {
+		return nil, db.ErrNotFound
+	}
+
+	return &db.DataNetworkPolicyControl{DataNetworkID: dataNetworkID, URL: f.url}, nil
+}
+
+func (f *fakeStore) GetPolicy(_ context.Context, name string) (*db.Policy, error) {
+	for _, p := range f.policies {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+
+	return nil, db.ErrNotFound
+}
+
+func (f *fakeStore) GetPolicyByID(_ context.Context, id string) (*db.Policy, error) {
+	if p, ok := f.policies[id]; ok {
+		return p, nil
+	}
+
+	return nil, db.ErrNotFound
+}
+
+func (f *fakeStore) CreateSMPolicyAssociation(_ context.Context, assoc *db.SMPolicyAssociation) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.assocs[assoc.ID] = *assoc
+
+	return nil
+}
+
+func (f *fakeStore) UpdateSMPolicyDecision(_ context.Context, assoc *db.SMPolicyAssociation) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	row, ok := f.assocs[assoc.ID]
+	if !ok {
+		return db.ErrNotFound
+	}
+
+	DecisionOf(assoc).Record(&row)
+	f.assocs[assoc.ID] = row
+
+	return nil
+}
+
+func (f *fakeStore) DeleteSMPolicyAssociation(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.assocs, id)
+
+	return nil
+}
+
+func (f *fakeStore) DeleteSMPolicyAssociationsByNode(_ context.Context, nodeID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for id, a := range f.assocs {
+		if a.NodeID == nodeID {
+			delete(f.assocs, id)
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeStore) ListSMPolicyAssociationsByNode(_ context.Context, nodeID int) ([]db.SMPolicyAssociation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var rows []db.SMPolicyAssociation
+
+	for _, a := range f.assocs {
+		if a.NodeID == nodeID {
+			rows = append(rows, a)
+		}
+	}
+
+	return rows, nil
+}
+
+type fakeApplier struct {
+	mu      sync.Mutex
+	applied map[string]Decision
+}
+
+func (a *fakeApplier) ApplyDecision(_ context.Context, ref string, decision Decision) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.applied[ref] = decision
+
+	return nil
+}
+
+type harness struct {
+	svc     *Service
+	pdp     *fakePDP
+	store   *fakeStore
+	applier *fakeApplier
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	h := &harness{
+		pdp: &fakePDP{},
+		store: &fakeStore{
+			url: "http://pcf.example.com",
+			policies: map[string]*db.Policy{
+				"policy-42": {ID: "policy-42", Name: "work-order-42", DataNetworkID: "dn-internet"},
+				"policy-99": {ID: "policy-99", Name: "other-dn", DataNetworkID: "dn-ims"},
+			},
+			assocs: make(map[string]db.SMPolicyAssociation),
+		},
+		applier: &fakeApplier{applied: make(map[string]Decision)},
+	}
+
+	h.svc = NewService(h.store, 1, nil)
+	h.svc.newPDP = func(string) (PDP, error) { return h.pdp, nil }
+	// The loop is not started: tests drive it with Reconcile.
+	h.svc.applier = h.applier
+
+	return h
+}
+
+var testSession = Session{Ref: "imsi-001010000000001#1", IMSI: "001010000000001", PDUSessionID: 1, DNN: "internet", RAT: RATNR}
+
+func TestCreateWithoutPolicyControl(t *testing.T) {
+	h := newHarness(t)
+	h.store.url = ""
+
+	if _, ok, err := h.svc.Create(context.Background(), testSession); ok || err != nil {
+		t.Fatalf("Create = %v, %v, want no policy control", ok, err)
+	}
+
+	if h.pdp.created != 0 {
+		t.Error("the decision point was asked for a data network it does not control")
+	}
+}
+
+func TestCreateRecordsDecision(t *testing.T) {
+	h := newHarness(t)
+	h.pdp.answer = Answer{Var5qi: 7, Arp: 2, SessionAmbrUplink: "10 Mbps", SessionAmbrDownlink: "20 Mbps", RulesPolicy: "work-order-42"}
+
+	d, ok, err := h.svc.Create(context.Background(), testSession)
+	if err != nil || !ok {
+		t.Fatalf("Create = %v, %v", ok, err)
+	}
+
+	want := Decision{Var5qi: 7, Arp: 2, SessionAmbrUplink: "10 Mbps", SessionAmbrDownlink: "20 Mbps", RulesPolicyID: "policy-42"}
+	if d != want {
+		t.Fatalf("decision = %+v, want %+v", d, want)
+	}
+
+	row, ok := h.store.assocs["sm-1"]
+	if !ok || row.NodeID != 1 || row.DataNetworkID != "dn-internet" || DecisionOf(&row) != want {
+		t.Fatalf("recorded association %+v", row)
+	}
+}
+
+func TestCreateRejectsInvalidDecision(t *testing.T) {
+	for name, ans := range map[string]Answer{
+		"GBR 5QI":          {Var5qi: 1},
+		"ARP out of range": {Arp: 16},
+		"half AMBR":        {SessionAmbrUplink: "10 Mbps"},
+		"malformed AMBR":   {SessionAmbrUplink: "fast", SessionAmbrDownlink: "10 Mbps"},
+		"unknown rules":    {RulesPolicy: "missing"},
+		"other DN's rules": {RulesPolicy: "other-dn"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := newHarness(t)
+			h.pdp.answer = ans
+
+			if _, ok, err := h.svc.Create(context.Background(), testSession); err == nil || ok {
+				t.Fatalf("Create = %v, %v, want an error", ok, err)
+			}
+
+			h.svc.exchanges.Wait()
+
+			if len(h.pdp.deleted) != 1 || len(h.store.assocs) != 0 {
+				t.Errorf("deleted %v, recorded %v: want the association ended and not recorded", h.pdp.deleted, h.store.assocs)
+			}
+		})
+	}
+}
+
+func TestCreateWhenUnreachable(t *testing.T) {
+	h := newHarness(t)
+	h.pdp.err = errors.New("connection refused")
+
+	if _, ok, err := h.svc.Create(context.Background(), testSession); err == nil || ok {
+		t.Fatalf("Create = %v, %v, want an error", ok, err)
+	}
+}
+
+func TestReconcileAppliesChangedDecision(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	if _, _, err := h.svc.Create(ctx, testSession); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := h.svc.Reconcile(ctx); err != nil || len(h.applier.applied) != 0 {
+		t.Fatalf("Reconcile = %v, applied %v: want nothing applied before a change", err, h.applier.applied)
+	}
+
+	// The decision point changes its decision through the API.
+	changed := Decision{Var5qi: 8, RulesPolicyID: "policy-42"}
+	row := db.SMPolicyAssociation{ID: "sm-1"}
+	changed.Record(&row)
+
+	if err := h.store.UpdateSMPolicyDecision(ctx, &row); err != nil {
+		t.Fatalf("UpdateSMPolicyDecision: %v", err)
+	}
+
+	if err := h.svc.Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if got := h.applier.applied[testSession.Ref]; got != changed {
+		t.Fatalf("applied %+v, want %+v", got, changed)
+	}
+
+	delete(h.applier.applied, testSession.Ref)
+
+	if err := h.svc.Reconcile(ctx); err != nil || len(h.applier.applied) != 0 {
+		t.Fatalf("Reconcile = %v, applied %v: want an applied decision left alone", err, h.applier.applied)
+	}
+}
+
+func TestSessionMovedRecordsDecision(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+	h.pdp.answer = Answer{RulesPolicy: "work-order-42"}
+	h.pdp.moved = &Answer{Var5qi: 9}
+
+	if _, _, err := h.svc.Create(ctx, testSession); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	h.svc.SessionMoved(testSession.Ref, RATEUTRA)
+	h.svc.exchanges.Wait()
+
+	row := h.store.assocs["sm-1"]
+	if got := DecisionOf(&row); got != (Decision{Var5qi: 9}) {
+		t.Fatalf("recorded %+v after the move", got)
+	}
+
+	if err := h.svc.Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if got := h.applier.applied[testSession.Ref]; got != (Decision{Var5qi: 9}) {
+		t.Fatalf("applied %+v after the move", got)
+	}
+}
+
+func TestSessionStoppedEndsAssociation(t *testing.T) {
+	h := newHarness(t)
+
+	if _, _, err := h.svc.Create(context.Background(), testSession); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	h.svc.SessionStopped(testSession.Ref)
+	h.svc.exchanges.Wait()
+
+	if len(h.pdp.deleted) != 1 || h.pdp.deleted[0] != "sm-1" || len(h.store.assocs) != 0 {
+		t.Fatalf("deleted %v, recorded %v: want the association ended", h.pdp.deleted, h.store.assocs)
+	}
+}
+
+func TestStartEndsStaleAssociations(t *testing.T) {
+	h := newHarness(t)
+	h.store.assocs["sm-old"] = db.SMPolicyAssociation{ID: "sm-old", DataNetworkID: "dn-internet", NodeID: 1}
+	h.store.assocs["sm-peer"] = db.SMPolicyAssociation{ID: "sm-peer", DataNetworkID: "dn-internet", NodeID: 2}
+
+	h.svc.Start(h.applier)
+	h.svc.Stop()
+
+	if len(h.pdp.deleted) != 1 || h.pdp.deleted[0] != "sm-old" {
+		t.Errorf("ended %v, want [sm-old]", h.pdp.deleted)
+	}
+
+	if _, ok := h.store.assocs["sm-peer"]; !ok || len(h.store.assocs) != 1 {
+		t.Errorf("recorded %v, want only the peer's association", h.store.assocs)
+	}
+}
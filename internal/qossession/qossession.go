@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package qossession gives the traffic an application exchanges with a UE a
+// guaranteed bit rate for a while, in the manner of Nnef_AFsessionWithQoS
+// (TS 29.122 §4.4.13). The application's request is recorded in the
+// qos_sessions table; the node serving the UE claims it, adds a dedicated
+// QoS flow to the UE's session, and removes the flow when the request
+// expires or is deleted. The application is notified of the outcome.
+package qossession
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// The events notified to an application, as Nnef_AFsessionWithQoS names
+// them (TS 29.122 §5.14.3.1).
+const (
+	EventResourcesAllocated = "SUCCESSFUL_RESOURCES_ALLOCATION"
+	EventAllocationFailed   = "FAILED_RESOURCES_ALLOCATION"
+	EventBearerReleased     = "RELEASE_OF_BEARER"
+	EventSessionTerminated  = "SESSION_TERMINATION"
+)
+
+// SMF is the part of the SMF the service uses. *smf.SMF satisfies it.
+type SMF interface {
+	QoSFlowSession(imsi string, ueIP netip.Addr) (ref string, policyID string, ok bool)
+	AddQoSFlow(ctx context.Context, ref string, flow models.GBRQosFlow, filterID string) error
+	ReleaseQoSFlow(ctx context.Context, ref string) error
+}
+
+// Filters installs the UPF filters of QoS sessions. *upf.SettingsReconciler
+// satisfies it.
+type Filters interface {
+	Reconcile(ctx context.Context) error
+	FiltersApplied(id string) bool
+}
+
+// Store is the part of the database the service uses. *db.Database
+// satisfies it.
+type Store interface {
+	ListQosSessions(ctx context.Context) ([]db.QosSession, error)
+	ClaimQosSession(ctx context.Context, qs *db.QosSession) error
+	UpdateQosSessionStatus(ctx context.Context, qs *db.QosSession) error
+	UpdateQosSessionPolicy(ctx context.Context, qs *db.QosSession) error
+	DeleteQosSession(ctx context.Context, id string) error
+}
+
+// FlowOf returns the dedicated QoS flow a session requests.
+func FlowOf(qs *db.QosSession) (models.GBRQosFlow, error) {
+	var (
+		flow models.GBRQosFlow
+		err  error
+	)
+
+	flow.Var5qi = qs.Var5qi
+
+	for _, r := range []struct {
+		dst *models.BitRate
+		src string
+	}{
+		{&flow.GFBRUplink, qs.GBRUplink},
+		{&flow.GFBRDownlink, qs.GBRDownlink},
+		{&flow.MFBRUplink, qs.MBRUplink},
+		{&flow.MFBRDownlink, qs.MBRDownlink},
+	} {
+		if *r.dst, err = models.ParseBitRate(r.src); err != nil {
+			return models.GBRQosFlow{}, err
+		}
+	}
+
+	if qs.RemotePrefix != "" {
+		if flow.Filter.RemotePrefix, err = netip.ParsePrefix(qs.RemotePrefix); err != nil {
+			return models.GBRQosFlow{}, err
+		}
+	}
+
+	flow.Filter.Protocol = uint8(qs.Protocol)
+	flow.Filter.PortLow = uint16(qs.PortLow)
+	flow.Filter.PortHigh = uint16(qs.PortHigh)
+
+	return flow, nil
+}
@@ -0,0 +1,581 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package qossession
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+	"go.uber.org/zap"
+)
+
+const (
+	// reconcileBackstop is the sweep that runs when no wakeup fired.
+	reconcileBackstop = 10 * time.Second
+	// claimTimeout is how long a request waits for the node serving its UE
+	// before it fails; twice that, for that node to install its filters.
+	claimTimeout = 30 * time.Second
+	// notifyTimeout bounds one notification to an application.
+	notifyTimeout = 5 * time.Second
+)
+
+// Service puts the QoS sessions of the UEs this node serves in force.
+type Service struct {
+	store    Store
+	nodeID   int
+	wakeup   <-chan struct{}
+	backstop time.Duration
+	now      func() time.Time
+	http     *http.Client
+
+	mu       sync.Mutex
+	sessions SMF
+	filters  Filters
+	datapath func() models.DatapathFeatures
+	// byID holds the QoS sessions whose flow this node added, byRef the
+	// same keyed by the PDU session carrying the flow.
+	byID   map[string]*held
+	byRef  map[string]string
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// notifications tracks the writes and notifications made outside the
+	// loop, so Stop can wait for them.
+	notifications sync.WaitGroup
+}
+
+// held is a QoS session whose flow this node added to session ref.
+type held struct {
+	ref             string
+	notificationURL string
+}
+
+// NewService puts the QoS sessions in store in force for the UEs served by
+// node nodeID. wakeup is signalled when QoS sessions changed; nil leaves
+// only the backstop sweep. Start must be called before sessions are
+// established.
+func NewService(store Store, nodeID int, wakeup <-chan struct{}) *Service {
+	return &Service{
+		store:    store,
+		nodeID:   nodeID,
+		wakeup:   wakeup,
+		backstop: reconcileBackstop,
+		now:      time.Now,
+		http:     &http.Client{Timeout: notifyTimeout},
+		byID:     make(map[string]*held),
+		byRef:    make(map[string]string),
+	}
+}
+
+// Start ends the QoS sessions this node served when it last stopped,
+// whose flows went with it, and launches the loop that adds and removes
+// flows through sessions once filters installed their filters. datapath
+// reports whether the user plane can enforce the flows. Calls without a
+// paired Stop are no-ops.
+func (s *Service) Start(sessions SMF, filters Filters, datapath func() models.DatapathFeatures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	s.sessions = sessions
+	s.filters = filters
+	s.datapath = datapath
+	s.clearStale(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx, s.done)
+}
+
+// Stop ends the loop and waits for the notifications in flight. The flows
+// of sessions still up are kept, and their QoS sessions ended on the next
+// Start. Safe to call when not started.
+func (s *Service) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	done := s.done
+	s.cancel = nil
+	s.done = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	s.notifications.Wait()
+}
+
+// QoSFlowEstablished marks the QoS session whose flow session ref carries
+// active. It returns at once.
+func (s *Service) QoSFlowEstablished(ref string) {
+	s.mu.Lock()
+	id, ok := s.byRef[ref]
+	h := s.byID[id]
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	s.notifications.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+
+		if err := s.store.UpdateQosSessionStatus(ctx, &db.QosSession{ID: id, Status: db.QosSessionActive}); err != nil {
+			logger.PolicyLog.Warn("couldn't mark QoS session active", zap.String("qosSession", id), zap.Error(err))
+			return
+		}
+
+		s.notify(h.notificationURL, id, EventResourcesAllocated, "")
+	})
+}
+
+// QoSFlowFailed fails the QoS session whose flow session ref could not
+// establish. It returns at once.
+func (s *Service) QoSFlowFailed(ref string, reason string) {
+	if id, h, ok := s.forgetRef(ref); ok {
+		s.notifications.Go(func() { s.fail(context.Background(), id, h.notificationURL, EventAllocationFailed, reason) })
+	}
+}
+
+// QoSFlowReleased fails the QoS session whose flow the network dropped
+// from session ref. It returns at once.
+func (s *Service) QoSFlowReleased(ref string) {
+	if id, h, ok := s.forgetRef(ref); ok {
+		s.notifications.Go(func() {
+			s.fail(context.Background(), id, h.notificationURL, EventBearerReleased, "the session moved to an access without dedicated QoS flows")
+		})
+	}
+}
+
+// QoSFlowPolicyChanged moves the QoS session whose flow session ref carries
+// to the network rules of policy policyID and rebuilds its filters from them.
+// It returns at once.
+func (s *Service) QoSFlowPolicyChanged(ref string, policyID string) {
+	s.mu.Lock()
+	id, ok := s.byRef[ref]
+	filters := s.filters
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	qs := &db.QosSession{ID: id}
+	if policyID != "" {
+		qs.PolicyID = &policyID
+	}
+
+	s.notifications.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+
+		if err := s.store.UpdateQosSessionPolicy(ctx, qs); err != nil {
+			logger.PolicyLog.Warn("couldn't move QoS session to the session's new policy", zap.String("qosSession", id), zap.Error(err))
+			return
+		}
+
+		if err := filters.Reconcile(ctx); err != nil {
+			logger.PolicyLog.Warn("couldn't install the filters of QoS sessions", zap.Error(err))
+		}
+	})
+}
+
+// SessionStopped ends the QoS session whose flow session ref carried. It
+// returns at once.
+func (s *Service) SessionStopped(ref string) {
+	if id, h, ok := s.forgetRef(ref); ok {
+		s.notifications.Go(func() { s.end(context.Background(), id, h.notificationURL) })
+	}
+}
+
+// forgetRef drops the QoS session whose flow session ref carries.
+func (s *Service) forgetRef(ref string) (string, *held, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.byRef[ref]
+	if !ok {
+		return "", nil, false
+	}
+
+	h := s.byID[id]
+	delete(s.byRef, ref)
+	delete(s.byID, id)
+
+	return id, h, true
+}
+
+// forget drops QoS session id and returns the session its flow was added
+// to.
+func (s *Service) forget(id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.byID[id]
+	if !ok {
+		return "", false
+	}
+
+	delete(s.byID, id)
+	delete(s.byRef, h.ref)
+
+	return h.ref, true
+}
+
+// clearStale ends the QoS sessions recorded as installed by this node,
+// best-effort. Caller holds s.mu.
+func (s *Service) clearStale(ctx context.Context) {
+	rows, err := s.store.ListQosSessions(ctx)
+	if err != nil {
+		logger.PolicyLog.Warn("couldn't list stale QoS sessions", zap.Error(err))
+		return
+	}
+
+	count := 0
+
+	for _, qs := range rows {
+		if qs.NodeID != s.nodeID || qs.Status != db.QosSessionInstalling && qs.Status != db.QosSessionActive {
+			continue
+		}
+
+		count++
+
+		s.end(ctx, qs.ID, qs.NotificationURL)
+	}
+
+	if count > 0 {
+		logger.PolicyLog.Info("ended stale QoS sessions", zap.Int("count", count))
+	}
+}
+
+func (s *Service) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	backstop := time.NewTicker(s.backstop)
+	defer backstop.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wakeup:
+		case <-backstop.C:
+		}
+
+		if err := s.Reconcile(ctx); err != nil {
+			logger.PolicyLog.Warn("QoS session reconciliation failed", zap.Error(err))
+		}
+	}
+}
+
+// Reconcile claims the requests for the UEs this node serves and adds
+// their flows, and removes the flows of QoS sessions that expired or were
+// deleted.
+func (s *Service) Reconcile(ctx context.Context) error {
+	s.mu.Lock()
+	sessions, filters := s.sessions, s.filters
+	s.mu.Unlock()
+
+	if sessions == nil {
+		return nil
+	}
+
+	rows, err := s.store.ListQosSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't list QoS sessions: %w", err)
+	}
+
+	now := s.now()
+	present := make(map[string]bool, len(rows))
+
+	for i := range rows {
+		qs := &rows[i]
+		present[qs.ID] = true
+
+		switch {
+		case now.Unix() >= qs.ExpiresAt:
+			s.expire(ctx, sessions, qs)
+		case qs.Status == db.QosSessionRequested:
+			s.claim(ctx, sessions, filters, qs, now)
+		case qs.Status == db.QosSessionInstalling && qs.NodeID == s.nodeID:
+			s.install(ctx, sessions, filters, qs, now)
+		}
+	}
+
+	// The flows of QoS sessions the application deleted.
+	var deleted []string
+
+	s.mu.Lock()
+	for id := range s.byID {
+		if !present[id] {
+			deleted = append(deleted, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range deleted {
+		if ref, ok := s.forget(id); ok {
+			if err := sessions.ReleaseQoSFlow(ctx, ref); err != nil {
+				logger.PolicyLog.Warn("couldn't release the flow of a deleted QoS session", zap.String("qosSession", id), zap.Error(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// expire removes the flow of an expired QoS session and deletes it. Only
+// the node serving the UE ends a session in force.
+func (s *Service) expire(ctx context.Context, sessions SMF, qs *db.QosSession) {
+	inForce := qs.Status == db.QosSessionInstalling || qs.Status == db.QosSessionActive
+	if inForce && qs.NodeID != s.nodeID {
+		return
+	}
+
+	if ref, ok := s.forget(qs.ID); ok {
+		if err := sessions.ReleaseQoSFlow(ctx, ref); err != nil {
+			logger.PolicyLog.Warn("couldn't release the flow of an expired QoS session", zap.String("qosSession", qs.ID), zap.Error(err))
+		}
+	}
+
+	if err := s.store.DeleteQosSession(ctx, qs.ID); err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			logger.PolicyLog.Warn("couldn't delete expired QoS session", zap.String("qosSession", qs.ID), zap.Error(err))
+		}
+
+		return
+	}
+
+	switch qs.Status {
+	case db.QosSessionRequested:
+		s.notify(qs.NotificationURL, qs.ID, EventAllocationFailed, "the request expired before a session of the UE was found")
+	case db.QosSessionInstalling, db.QosSessionActive:
+		s.notify(qs.NotificationURL, qs.ID, EventSessionTerminated, "")
+	}
+}
+
+// claim takes a request for a UE this node serves and installs it. A
+// request no node takes within claimTimeout fails.
+func (s *Service) claim(ctx context.Context, sessions SMF, filters Filters, qs *db.QosSession, now time.Time) {
+	_, policyID, ok := sessions.QoSFlowSession(qs.IMSI, ueAddr(qs.UEIP))
+	if !ok {
+		if now.Sub(time.Unix(qs.CreatedAt, 0)) < claimTimeout {
+			return
+		}
+
+		// Claimed first, so that one node alone reports the failure.
+		if s.store.ClaimQosSession(ctx, &db.QosSession{ID: qs.ID, NodeID: s.nodeID}) != nil {
+			return
+		}
+
+		s.fail(ctx, qs.ID, qs.NotificationURL, EventAllocationFailed, "no session of the UE is established")
+
+		return
+	}
+
+	claimed := &db.QosSession{ID: qs.ID, NodeID: s.nodeID}
+	if policyID != "" {
+		claimed.PolicyID = &policyID
+	}
+
+	if err := s.store.ClaimQosSession(ctx, claimed); err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			logger.PolicyLog.Warn("couldn't claim QoS session", zap.String("qosSession", qs.ID), zap.Error(err))
+		}
+
+		return
+	}
+
+	qs.Status, qs.NodeID, qs.PolicyID = db.QosSessionInstalling, claimed.NodeID, claimed.PolicyID
+
+	s.install(ctx, sessions, filters, qs, now)
+}
+
+// install adds the flow of a QoS session this node claimed, once the UPF
+// has its filters. The session fails when the flow cannot be added; a UE
+// that is idle or busy with another procedure is tried again on the next
+// pass.
+func (s *Service) install(ctx context.Context, sessions SMF, filters Filters, qs *db.QosSession, now time.Time) {
+	s.mu.Lock()
+	_, added := s.byID[qs.ID]
+	datapath := s.datapath
+	s.mu.Unlock()
+
+	if added {
+		return
+	}
+
+	failed := func(reason string) {
+		s.fail(ctx, qs.ID, qs.NotificationURL, EventAllocationFailed, reason)
+	}
+
+	ref, _, ok := sessions.QoSFlowSession(qs.IMSI, ueAddr(qs.UEIP))
+	if !ok {
+		failed("the session of the UE ended")
+		return
+	}
+
+	if qs.PolicyID == nil {
+		failed("the session of the UE has no policy")
+		return
+	}
+
+	if datapath == nil || !datapath().QoSFlows {
+		failed("the user plane cannot enforce dedicated QoS flows")
+		return
+	}
+
+	flow, err := FlowOf(qs)
+	if err != nil {
+		failed(err.Error())
+		return
+	}
+
+	filterID := models.QosSessionFilterID(qs.ID)
+
+	if !filters.FiltersApplied(filterID) {
+		if err := filters.Reconcile(ctx); err != nil {
+			logger.PolicyLog.Warn("couldn't install the filters of QoS sessions", zap.Error(err))
+		}
+
+		if !filters.FiltersApplied(filterID) {
+			if now.Sub(time.Unix(qs.CreatedAt, 0)) >= 2*claimTimeout {
+				failed("the user plane could not install the flow's filters")
+			}
+
+			return
+		}
+	}
+
+	// Recorded first: the UE may answer before AddQoSFlow returns.
+	s.mu.Lock()
+	s.byID[qs.ID] = &held{ref: ref, notificationURL: qs.NotificationURL}
+	s.byRef[ref] = qs.ID
+	s.mu.Unlock()
+
+	err = sessions.AddQoSFlow(ctx, ref, flow, filterID)
+	if err == nil {
+		return
+	}
+
+	s.forget(qs.ID)
+
+	if errors.Is(err, smf.ErrUENotReachable) || errors.Is(err, smf.ErrProcedureInFlight) {
+		return
+	}
+
+	failed(err.Error())
+}
+
+// fail marks a QoS session failed and notifies the application.
+func (s *Service) fail(ctx context.Context, id, notificationURL, event, reason string) {
+	qs := &db.QosSession{ID: id, Status: db.QosSessionFailed, Reason: reason}
+	if err := s.store.UpdateQosSessionStatus(ctx, qs); err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			logger.PolicyLog.Warn("couldn't mark QoS session failed", zap.String("qosSession", id), zap.Error(err))
+		}
+
+		return
+	}
+
+	logger.PolicyLog.Info("QoS session failed", zap.String("qosSession", id), zap.String("reason", reason))
+
+	s.notify(notificationURL, id, event, reason)
+}
+
+// end deletes a QoS session whose flow is gone and notifies the
+// application.
+func (s *Service) end(ctx context.Context, id, notificationURL string) {
+	if err := s.store.DeleteQosSession(ctx, id); err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			logger.PolicyLog.Warn("couldn't delete QoS session", zap.String("qosSession", id), zap.Error(err))
+		}
+
+		return
+	}
+
+	s.notify(notificationURL, id, EventSessionTerminated, "")
+}
+
+// notification is the body of a notification, after the
+// UserPlaneNotificationData of TS 29.122 §5.14.2.1.5.
+type notification struct {
+	Transaction  string        `json:"transaction"`
+	EventReports []eventReport `json:"eventReports"`
+}
+
+type eventReport struct {
+	Event  string `json:"event"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// notify posts event to the application's notification URL, best-effort.
+// It returns at once.
+func (s *Service) notify(url, id, event, reason string) {
+	s.notifications.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+
+		s.post(ctx, url, id, event, reason)
+	})
+}
+
+func (s *Service) post(ctx context.Context, url, id, event, reason string) {
+	body, err := json.Marshal(notification{
+		Transaction:  "/api/v1/qos-sessions/" + id,
+		EventReports: []eventReport{{Event: event, Reason: reason}},
+	})
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		logger.PolicyLog.Warn("invalid QoS session notification URL", zap.String("qosSession", id), zap.Error(err))
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		logger.PolicyLog.Warn("couldn't notify QoS session event", zap.String("qosSession", id), zap.String("event", event), zap.Error(err))
+		return
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		logger.PolicyLog.Warn("QoS session notification refused", zap.String("qosSession", id), zap.String("event", event), zap.Int("status", resp.StatusCode))
+	}
+}
+
+// ueAddr returns the UE address a QoS session names, invalid when it names
+// the UE by IMSI.
+func ueAddr(s string) netip.Addr {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return addr.Unmap()
+}
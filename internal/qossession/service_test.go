@@ -0,0 +1,443 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package qossession
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+)
+
+type fakeStore struct {
+	mu   sync.Mutex
+	rows map[string]db.QosSession
+}
+
+func (f *fakeStore) ListQosSessions(context.Context) ([]db.QosSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.QosSession, 0, len(f.rows))
+	for _, qs := range f.rows {
+		out = append(out, qs)
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) ClaimQosSession(_ context.Context, qs *db.QosSession) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	row, ok := f.rows[qs.ID]
+	if !ok || row.Status != db.QosSessionRequested {
+		return db.ErrNotFound
+	}
+
+	row.Status, row.NodeID, row.PolicyID = db.QosSessionInstalling, qs.NodeID, qs.PolicyID
+	f.rows[qs.ID] = row
+
+	return nil
+}
+
+func (f *fakeStore) UpdateQosSessionStatus(_ context.Context, qs *db.QosSession) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	row, ok := f.rows[qs.ID]
+	if !ok {
+		return db.ErrNotFound
+	}
+
+	row.Status, row.Reason = qs.Status, qs.Reason
+	f.rows[qs.ID] = row
+
+	return nil
+}
+
+func (f *fakeStore) UpdateQosSessionPolicy(_ context.Context, qs *db.QosSession) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	row, ok := f.rows[qs.ID]
+	if !ok {
+		return db.ErrNotFound
+	}
+
+	row.PolicyID = qs.PolicyID
+	f.rows[qs.ID] = row
+
+	return nil
+}
+
+func (f *fakeStore) DeleteQosSession(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.rows[id]; !ok {
+		return db.ErrNotFound
+	}
+
+	delete(f.rows, id)
+
+	return nil
+}
+
+func (f *fakeStore) row(id string) (db.QosSession, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	qs, ok := f.rows[id]
+
+	return qs, ok
+}
+
+type fakeSMF struct {
+	mu       sync.Mutex
+	imsi     string
+	addErr   error
+	added    map[string]string
+	released []string
+}
+
+func (f *fakeSMF) QoSFlowSession(imsi string, _ netip.Addr) (string, string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if imsi == "" || imsi != f.imsi {
+		return "", "", false
+	}
+
+	return "ref-1", "policy-1", true
+}
+
+func (f *fakeSMF) AddQoSFlow(_ context.Context, ref string, _ models.GBRQosFlow, filterID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.addErr != nil {
+		return f.addErr
+	}
+
+	f.added[ref] = filterID
+
+	return nil
+}
+
+func (f *fakeSMF) ReleaseQoSFlow(_ context.Context, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.released = append(f.released, ref)
+
+	return nil
+}
+
+type fakeFilters struct {
+	applied    bool
+	reconciles atomic.Int32
+}
+
+func (f *fakeFilters) Reconcile(context.Context) error {
+	f.reconciles.Add(1)
+	return nil
+}
+
+func (f *fakeFilters) FiltersApplied(string) bool { return f.applied }
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var n notification
+	if err := json.NewDecoder(req.Body).Decode(&n); err == nil && len(n.EventReports) == 1 {
+		r.mu.Lock()
+		r.events = append(r.events, n.EventReports[0].Event)
+		r.mu.Unlock()
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *recorder) got() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.events)
+}
+
+func newTestService(t *testing.T) (*Service, *fakeStore, *fakeSMF, *recorder, string) {
+	t.Helper()
+
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	store := &fakeStore{rows: make(map[string]db.QosSession)}
+
+	sessions := &fakeSMF{imsi: "001010000000001", added: make(map[string]string)}
+
+	s := NewService(store, 1, nil)
+	s.now = func() time.Time { return time.Unix(1000, 0) }
+	s.datapath = func() models.DatapathFeatures { return models.DatapathFeatures{QoSFlows: true} }
+
+	return s, store, sessions, rec, srv.URL
+}
+
+func testRow(id, url string) db.QosSession {
+	return db.QosSession{
+		ID:              id,
+		IMSI:            "001010000000001",
+		Var5qi:          2,
+		GBRUplink:       "1 Mbps",
+		GBRDownlink:     "1 Mbps",
+		MBRUplink:       "2 Mbps",
+		MBRDownlink:     "2 Mbps",
+		NotificationURL: url,
+		CreatedAt:       1000,
+		ExpiresAt:       2000,
+		Status:          db.QosSessionRequested,
+	}
+}
+
+func TestService_ClaimInstallEstablishAndExpire(t *testing.T) {
+	s, store, sessions, rec, url := newTestService(t)
+	store.rows["qs-1"] = testRow("qs-1", url)
+
+	s.sessions, s.filters = sessions, &fakeFilters{applied: true}
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if got := sessions.added["ref-1"]; got != models.QosSessionFilterID("qs-1") {
+		t.Fatalf("flow added with filters %q, want the QoS session's", got)
+	}
+
+	if qs, _ := store.row("qs-1"); qs.Status != db.QosSessionInstalling || qs.NodeID != 1 || qs.PolicyID == nil || *qs.PolicyID != "policy-1" {
+		t.Fatalf("row = %+v, want it installing on node 1 over policy-1", qs)
+	}
+
+	s.QoSFlowEstablished("ref-1")
+	s.notifications.Wait()
+
+	if qs, _ := store.row("qs-1"); qs.Status != db.QosSessionActive {
+		t.Fatalf("status = %q, want active", qs.Status)
+	}
+
+	s.now = func() time.Time { return time.Unix(2000, 0) }
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	s.notifications.Wait()
+
+	if _, ok := store.row("qs-1"); ok {
+		t.Error("expired QoS session still recorded")
+	}
+
+	if !slices.Equal(sessions.released, []string{"ref-1"}) {
+		t.Errorf("released = %v, want the flow released on expiry", sessions.released)
+	}
+
+	if got := rec.got(); !slices.Equal(got, []string{EventResourcesAllocated, EventSessionTerminated}) {
+		t.Errorf("notifications = %v", got)
+	}
+}
+
+func TestService_UnclaimedRequestFails(t *testing.T) {
+	s, store, sessions, rec, url := newTestService(t)
+
+	row := testRow("qs-1", url)
+	row.IMSI = "001019999999999"
+	store.rows["qs-1"] = row
+
+	s.sessions, s.filters = sessions, &fakeFilters{applied: true}
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if qs, _ := store.row("qs-1"); qs.Status != db.QosSessionRequested {
+		t.Fatalf("status = %q, want the request left for another node", qs.Status)
+	}
+
+	s.now = func() time.Time { return time.Unix(1000, 0).Add(claimTimeout) }
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	s.notifications.Wait()
+
+	if qs, _ := store.row("qs-1"); qs.Status != db.QosSessionFailed || qs.Reason == "" {
+		t.Fatalf("row = %+v, want it failed with a reason", qs)
+	}
+
+	if got := rec.got(); !slices.Equal(got, []string{EventAllocationFailed}) {
+		t.Errorf("notifications = %v", got)
+	}
+}
+
+func TestService_UnsupportedDatapathFails(t *testing.T) {
+	s, store, sessions, rec, url := newTestService(t)
+	store.rows["qs-1"] = testRow("qs-1", url)
+
+	s.sessions, s.filters = sessions, &fakeFilters{applied: true}
+	s.datapath = func() models.DatapathFeatures { return models.DatapathFeatures{} }
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	s.notifications.Wait()
+
+	if len(sessions.added) != 0 {
+		t.Errorf("flow added on a datapath that cannot enforce it: %v", sessions.added)
+	}
+
+	if qs, _ := store.row("qs-1"); qs.Status != db.QosSessionFailed || qs.Reason == "" {
+		t.Fatalf("row = %+v, want it failed with a reason", qs)
+	}
+
+	if got := rec.got(); !slices.Equal(got, []string{EventAllocationFailed}) {
+		t.Errorf("notifications = %v", got)
+	}
+}
+
+func TestService_IdleUEIsTriedAgain(t *testing.T) {
+	s, store, sessions, _, url := newTestService(t)
+	store.rows["qs-1"] = testRow("qs-1", url)
+
+	sessions.addErr = smf.ErrUENotReachable
+	s.sessions, s.filters = sessions, &fakeFilters{applied: true}
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if qs, _ := store.row("qs-1"); qs.Status != db.QosSessionInstalling {
+		t.Fatalf("status = %q, want installing", qs.Status)
+	}
+
+	sessions.addErr = nil
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if _, ok := sessions.added["ref-1"]; !ok {
+		t.Error("flow not added once the UE was reachable")
+	}
+}
+
+func TestService_PolicyChangeRebuildsFilters(t *testing.T) {
+	s, store, sessions, _, url := newTestService(t)
+	store.rows["qs-1"] = testRow("qs-1", url)
+
+	filters := &fakeFilters{applied: true}
+	s.sessions, s.filters = sessions, filters
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	s.QoSFlowPolicyChanged("ref-1", "policy-2")
+	s.notifications.Wait()
+
+	if qs, _ := store.row("qs-1"); qs.PolicyID == nil || *qs.PolicyID != "policy-2" {
+		t.Fatalf("row = %+v, want it over policy-2", qs)
+	}
+
+	if filters.reconciles.Load() == 0 {
+		t.Error("filters not rebuilt after the policy change")
+	}
+
+	// A session this node holds no flow for is left alone.
+	s.QoSFlowPolicyChanged("ref-2", "policy-3")
+	s.notifications.Wait()
+
+	if qs, _ := store.row("qs-1"); *qs.PolicyID != "policy-2" {
+		t.Errorf("row moved to %q by another session's change", *qs.PolicyID)
+	}
+}
+
+func TestService_DeletedAndStoppedSessions(t *testing.T) {
+	s, store, sessions, rec, url := newTestService(t)
+	store.rows["qs-1"] = testRow("qs-1", url)
+
+	s.sessions, s.filters = sessions, &fakeFilters{applied: true}
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	// The application deletes the QoS session: its flow is released.
+	delete(store.rows, "qs-1")
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	if !slices.Equal(sessions.released, []string{"ref-1"}) {
+		t.Fatalf("released = %v, want the deleted QoS session's flow released", sessions.released)
+	}
+
+	// The PDU session ends: its QoS session is deleted.
+	store.rows["qs-2"] = testRow("qs-2", url)
+
+	if err := s.Reconcile(context.Background()); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	s.SessionStopped("ref-1")
+	s.notifications.Wait()
+
+	if _, ok := store.row("qs-2"); ok {
+		t.Error("QoS session still recorded after its PDU session ended")
+	}
+
+	if got := rec.got(); !slices.Equal(got, []string{EventSessionTerminated}) {
+		t.Errorf("notifications = %v", got)
+	}
+}
+
+func TestService_StartEndsStaleSessions(t *testing.T) {
+	s, store, sessions, rec, url := newTestService(t)
+
+	stale := testRow("qs-1", url)
+	stale.Status, stale.NodeID = db.QosSessionActive, 1
+	other := testRow("qs-2", url)
+	other.Status, other.NodeID = db.QosSessionActive, 2
+	store.rows["qs-1"], store.rows["qs-2"] = stale, other
+
+	s.Start(sessions, &fakeFilters{applied: true}, s.datapath)
+	s.Stop()
+
+	if _, ok := store.row("qs-1"); ok {
+		t.Error("this node's QoS session survived the restart")
+	}
+
+	if _, ok := store.row("qs-2"); !ok {
+		t.Error("another node's QoS session was ended")
+	}
+
+	if got := rec.got(); !slices.Equal(got, []string{EventSessionTerminated}) {
+		t.Errorf("notifications = %v", got)
+	}
+}
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package radius
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	// AuthPort and AccountingPort are the IANA ports (RFC 2865, RFC 2866).
+	AuthPort       = 1812
+	AccountingPort = 1813
+
+	defaultTimeout  = 3 * time.Second
+	defaultAttempts = 3
+)
+
+// ErrNoAnswer is returned when the server does not answer any attempt.
+var ErrNoAnswer = errors.New("RADIUS server did not answer")
+
+// Config sets up a Client.
+type Config struct {
+	Server netip.AddrPort
+	Secret []byte
+	// Timeout bounds each attempt, Attempts counts them.
+	Timeout  time.Duration
+	Attempts int
+}
+
+// Client sends requests to one server. It is safe for concurrent use.
+type Client struct {
+	server   netip.AddrPort
+	secret   []byte
+	timeout  time.Duration
+	attempts int
+
+	nextID atomic.Uint32
+}
+
+// NewClient returns a client for cfg.Server.
+func NewClient(cfg Config) (*Client, error) {
+	if !cfg.Server.IsValid() {
+		return nil, fmt.Errorf("invalid server address %s", cfg.Server)
+	}
+
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("shared secret is required")
+	}
+
+	c := &Client{
+		server:   cfg.Server,
+		secret:   cfg.Secret,
+		timeout:  cfg.Timeout,
+		attempts: cfg.Attempts,
+	}
+
+	if c.timeout <= 0 {
+		c.timeout = defaultTimeout
+	}
+
+	if c.attempts <= 0 {
+		c.attempts = defaultAttempts
+	}
+
+	return c, nil
+}
+
+// Server is the address requests go to.
+func (c *Client) Server() netip.AddrPort {
+	return c.server
+}
+
+// Secret is the shared secret, which User-Password hiding needs.
+func (c *Client) Secret() []byte {
+	return c.secret
+}
+
+// Exchange sends req, retransmitting it unchanged, until an authentic
+// answer arrives (RFC 2865 §2.5). It sets req.Identifier.
+func (c *Client) Exchange(ctx context.Context, req *Packet) (*Packet, error) {
+	req.Identifier = uint8(c.nextID.Add(1))
+
+	raw, err := req.Encode(c.secret, [16]byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "udp", c.server.String())
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", c.server, err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	buf := make([]byte, maxLen)
+
+	for range c.attempts {
+		if _, err := conn.Write(raw); err != nil {
+			return nil, fmt.Errorf("send to %s: %w", c.server, err)
+		}
+
+		resp, err := c.await(ctx, conn, buf, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp != nil {
+			return resp, nil
+		}
+	}
+
+	return nil, ErrNoAnswer
+}
+
+// await reads until the answer to req arrives, or returns nil when the
+// attempt times out.
+func (c *Client) await(ctx context.Context, conn net.Conn, buf []byte, req *Packet) (*Packet, error) {
+	deadline := time.Now().Add(c.timeout)
+
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	for {
+		n, err := conn.Read(buf)
+
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			return nil, nil
+		case errors.Is(err, syscall.ECONNREFUSED):
+			// An ICMP unreachable from a server that is down: wait out
+			// the attempt as for a lost answer.
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Until(deadline)):
+				return nil, nil
+			}
+		case err != nil:
+			return nil, fmt.Errorf("read from %s: %w", c.server, err)
+		}
+
+		resp, err := Parse(buf[:n])
+		if err != nil || resp.Identifier != req.Identifier {
+			continue
+		}
+
+		if err := VerifyResponse(buf[:n], req.Authenticator, c.secret); err != nil {
+			continue
+		}
+
+		return resp, nil
+	}
+}
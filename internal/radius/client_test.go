@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package radius_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/radius"
+	"github.com/ellanetworks/core/internal/radius/radiustest"
+)
+
+var secret = []byte("testing123")
+
+func TestExchange(t *testing.T) {
+	var seen atomic.Int32
+
+	srv, err := radiustest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), secret, func(req *radius.Packet) *radius.Packet {
+		// Drop the first copy so the client has to retransmit.
+		if seen.Add(1) == 1 {
+			return nil
+		}
+
+		hidden, _ := req.Get(radius.TypeUserPassword)
+
+		password, err := radius.RevealPassword(hidden, secret, req.Authenticator)
+		if err != nil || string(password) != "secret-password" {
+			return &radius.Packet{Code: radius.CodeAccessReject}
+		}
+
+		resp := &radius.Packet{Code: radius.CodeAccessAccept}
+		resp.AddAddr(radius.TypeFramedIPAddress, netip.MustParseAddr("172.20.0.9"))
+		resp.Add(radius.TypeMessageAuthenticator, nil)
+
+		return resp
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	defer func() { _ = srv.Close() }()
+
+	c, err := radius.NewClient(radius.Config{Server: srv.Addr(), Secret: secret, Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req, err := radius.NewRequest(radius.CodeAccessRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.AddString(radius.TypeUserName, "001010000000001")
+	req.Add(radius.TypeUserPassword, radius.HidePassword([]byte("secret-password"), c.Secret(), req.Authenticator))
+	req.Add(radius.TypeMessageAuthenticator, nil)
+
+	resp, err := c.Exchange(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	if resp.Code != radius.CodeAccessAccept {
+		t.Fatalf("got %s, want Access-Accept", resp.Code)
+	}
+
+	if addr, ok := resp.Addr(radius.TypeFramedIPAddress); !ok || addr != netip.MustParseAddr("172.20.0.9") {
+		t.Fatalf("Framed-IP-Address = %s, %v", addr, ok)
+	}
+}
+
+func TestExchangeIgnoresForgedAnswers(t *testing.T) {
+	srv, err := radiustest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), []byte("another-secret"), func(*radius.Packet) *radius.Packet {
+		return &radius.Packet{Code: radius.CodeAccessAccept}
+	})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	defer func() { _ = srv.Close() }()
+
+	c, err := radius.NewClient(radius.Config{Server: srv.Addr(), Secret: secret, Timeout: 50 * time.Millisecond, Attempts: 2})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	req, err := radius.NewRequest(radius.CodeAccessRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Exchange(context.Background(), req); !errors.Is(err, radius.ErrNoAnswer) {
+		t.Fatalf("expected ErrNoAnswer, got %v", err)
+	}
+}
+
+func TestExchangeHonoursContext(t *testing.T) {
+	c, err := radius.NewClient(radius.Config{Server: netip.MustParseAddrPort("127.0.0.1:9"), Secret: secret, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req := &radius.Packet{Code: radius.CodeAccountingRequest}
+
+	if _, err := c.Exchange(ctx, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to end the exchange, got %v", err)
+	}
+}
@@ -0,0 +1,482 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package radius is the RADIUS client Ella Core uses to reach external AAA
+// servers (RFC 2865, RFC 2866, RFC 3579). It covers the packet format, the
+// authenticators, User-Password hiding and Message-Authenticator.
+package radius
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5" // #nosec G501 -- RADIUS authenticators are defined over MD5.
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// Code is the packet type (RFC 2865 §3, RFC 2866 §3).
+type Code uint8
+
+const (
+	CodeAccessRequest      Code = 1
+	CodeAccessAccept       Code = 2
+	CodeAccessReject       Code = 3
+	CodeAccountingRequest  Code = 4
+	CodeAccountingResponse Code = 5
+	CodeAccessChallenge    Code = 11
+)
+
+func (c Code) String() string {
+	switch c {
+	case CodeAccessRequest:
+		return "Access-Request"
+	case CodeAccessAccept:
+		return "Access-Accept"
+	case CodeAccessReject:
+		return "Access-Reject"
+	case CodeAccountingRequest:
+		return "Accounting-Request"
+	case CodeAccountingResponse:
+		return "Accounting-Response"
+	case CodeAccessChallenge:
+		return "Access-Challenge"
+	default:
+		return fmt.Sprintf("Code(%d)", uint8(c))
+	}
+}
+
+// Type is an attribute type (RFC 2865 §5).
+type Type uint8
+
+const (
+	TypeUserName             Type = 1
+	TypeUserPassword         Type = 2
+	TypeNASIPAddress         Type = 4
+	TypeServiceType          Type = 6
+	TypeFramedProtocol       Type = 7
+	TypeFramedIPAddress      Type = 8
+	TypeFilterID             Type = 11
+	TypeReplyMessage         Type = 18
+	TypeState                Type = 24
+	TypeClass                Type = 25
+	TypeVendorSpecific       Type = 26
+	TypeSessionTimeout       Type = 27
+	TypeCalledStationID      Type = 30
+	TypeCallingStationID     Type = 31
+	TypeNASIdentifier        Type = 32
+	TypeAcctStatusType       Type = 40
+	TypeAcctDelayTime        Type = 41
+	TypeAcctInputOctets      Type = 42
+	TypeAcctOutputOctets     Type = 43
+	TypeAcctSessionID        Type = 44
+	TypeAcctSessionTime      Type = 46
+	TypeAcctTerminateCause   Type = 49
+	TypeAcctInputGigawords   Type = 52
+	TypeAcctOutputGigawords  Type = 53
+	TypeEventTimestamp       Type = 55
+	TypeEAPMessage           Type = 79
+	TypeMessageAuthenticator Type = 80
+	TypeFramedIPv6Prefix     Type = 97
+)
+
+// Values of Acct-Status-Type (RFC 2866 §5.1).
+const (
+	AcctStatusStart         = 1
+	AcctStatusStop          = 2
+	AcctStatusInterimUpdate = 3
+)
+
+// Values of Acct-Terminate-Cause (RFC 2866 §5.10).
+const (
+	TerminateCauseUserRequest    = 1
+	TerminateCauseLostCarrier    = 2
+	TerminateCauseSessionTimeout = 5
+	TerminateCauseAdminReset     = 6
+	TerminateCauseNASRequest     = 10
+	TerminateCauseNASReboot      = 11
+)
+
+// VendorID3GPP is the SMI enterprise number of the 3GPP vendor-specific
+// attributes (TS 29.061 §16.4.7).
+const VendorID3GPP = 10415
+
+// Values of Service-Type and Framed-Protocol used for mobile sessions
+// (RFC 2865 §5.6, §5.7; TS 29.061 §16.4.7).
+const (
+	ServiceTypeFramed         = 2
+	FramedProtocolGPRSPDPCtxt = 7
+)
+
+const (
+	headerLen = 20
+	maxLen    = 4096
+	maxAttr   = 253
+)
+
+var errMalformed = errors.New("malformed RADIUS packet")
+
+// Attribute is one attribute with its raw value.
+type Attribute struct {
+	Type  Type
+	Value []byte
+}
+
+// Packet is a RADIUS packet. Authenticator is the request authenticator of
+// an Access-Request, and is computed on encoding for every other code.
+type Packet struct {
+	Code          Code
+	Identifier    uint8
+	Authenticator [16]byte
+	Attributes    []Attribute
+}
+
+// NewRequest returns a request with a random authenticator.
+func NewRequest(code Code) (*Packet, error) {
+	p := &Packet{Code: code}
+
+	if _, err := rand.Read(p.Authenticator[:]); err != nil {
+		return nil, fmt.Errorf("generate authenticator: %w", err)
+	}
+
+	return p, nil
+}
+
+// Add appends an attribute. Values longer than an attribute can carry are
+// split across several, as EAP-Message requires (RFC 3579 §3.1).
+func (p *Packet) Add(t Type, value []byte) {
+	for len(value) > maxAttr {
+		p.Attributes = append(p.Attributes, Attribute{Type: t, Value: value[:maxAttr]})
+		value = value[maxAttr:]
+	}
+
+	p.Attributes = append(p.Attributes, Attribute{Type: t, Value: value})
+}
+
+// AddString appends a text attribute.
+func (p *Packet) AddString(t Type, s string) {
+	p.Add(t, []byte(s))
+}
+
+// AddUint32 appends an integer attribute.
+func (p *Packet) AddUint32(t Type, v uint32) {
+	p.Add(t, binary.BigEndian.AppendUint32(nil, v))
+}
+
+// AddAddr appends an IPv4 address attribute.
+func (p *Packet) AddAddr(t Type, a netip.Addr) {
+	v := a.As4()
+	p.Add(t, v[:])
+}
+
+// AddVendor appends a Vendor-Specific attribute holding one sub-attribute
+// (RFC 2865 §5.26).
+func (p *Packet) AddVendor(vendor uint32, vendorType uint8, value []byte) {
+	v := binary.BigEndian.AppendUint32(nil, vendor)
+	v = append(v, vendorType, byte(2+len(value)))
+	p.Add(TypeVendorSpecific, append(v, value...))
+}
+
+// Vendor returns the first vendor sub-attribute of the given type.
+func (p *Packet) Vendor(vendor uint32, vendorType uint8) ([]byte, bool) {
+	for _, v := range p.GetAll(TypeVendorSpecific) {
+		if len(v) < 6 || binary.BigEndian.Uint32(v) != vendor {
+			continue
+		}
+
+		if v[4] == vendorType && int(v[5]) == len(v)-4 {
+			return v[6:], true
+		}
+	}
+
+	return nil, false
+}
+
+// Get returns the first attribute of type t.
+func (p *Packet) Get(t Type) ([]byte, bool) {
+	for _, a := range p.Attributes {
+		if a.Type == t {
+			return a.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// GetAll returns every attribute of type t, in order.
+func (p *Packet) GetAll(t Type) [][]byte {
+	var values [][]byte
+
+	for _, a := range p.Attributes {
+		if a.Type == t {
+			values = append(values, a.Value)
+		}
+	}
+
+	return values
+}
+
+// Concat joins every attribute of type t, as for EAP-Message.
+func (p *Packet) Concat(t Type) []byte {
+	return bytes.Join(p.GetAll(t), nil)
+}
+
+// String returns the first text attribute of type t.
+func (p *Packet) String(t Type) string {
+	v, _ := p.Get(t)
+	return string(v)
+}
+
+// Uint32 returns the first integer attribute of type t.
+func (p *Packet) Uint32(t Type) (uint32, bool) {
+	v, ok := p.Get(t)
+	if !ok || len(v) != 4 {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint32(v), true
+}
+
+// Addr returns the first IPv4 address attribute of type t.
+func (p *Packet) Addr(t Type) (netip.Addr, bool) {
+	v, ok := p.Get(t)
+	if !ok || len(v) != 4 {
+		return netip.Addr{}, false
+	}
+
+	return netip.AddrFrom4([4]byte(v)), true
+}
+
+// Encode serialises the packet. Requests carrying a Message-Authenticator
+// get it filled in; for responses requestAuth is the authenticator of the
+// request answered, and is ignored for requests.
+func (p *Packet) Encode(secret []byte, requestAuth [16]byte) ([]byte, error) {
+	b := make([]byte, headerLen, maxLen)
+	b[0] = byte(p.Code)
+	b[1] = p.Identifier
+
+	msgAuthAt := -1
+
+	for _, a := range p.Attributes {
+		if len(a.Value) > maxAttr {
+			return nil, fmt.Errorf("attribute %d is %d bytes long", a.Type, len(a.Value))
+		}
+
+		if a.Type == TypeMessageAuthenticator {
+			msgAuthAt = len(b) + 2
+			b = append(b, byte(a.Type), 18)
+			b = append(b, make([]byte, 16)...)
+
+			continue
+		}
+
+		b = append(b, byte(a.Type), byte(2+len(a.Value)))
+		b = append(b, a.Value...)
+	}
+
+	if len(b) > maxLen {
+		return nil, fmt.Errorf("packet is %d bytes long", len(b))
+	}
+
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+
+	switch p.Code {
+	case CodeAccessRequest:
+		copy(b[4:headerLen], p.Authenticator[:])
+	case CodeAccountingRequest:
+		// Zeroes, then the request authenticator (RFC 2866 §3).
+	default:
+		copy(b[4:headerLen], requestAuth[:])
+	}
+
+	if msgAuthAt >= 0 {
+		mac := hmac.New(md5.New, secret)
+		mac.Write(b)
+		copy(b[msgAuthAt:], mac.Sum(nil))
+	}
+
+	if p.Code != CodeAccessRequest {
+		sum := md5.Sum(append(append([]byte{}, b...), secret...)) // #nosec G401
+		copy(b[4:headerLen], sum[:])
+		copy(p.Authenticator[:], sum[:])
+	}
+
+	return b, nil
+}
+
+// Parse decodes a packet without checking its authenticators.
+func Parse(b []byte) (*Packet, error) {
+	if len(b) < headerLen {
+		return nil, errMalformed
+	}
+
+	length := int(binary.BigEndian.Uint16(b[2:]))
+	if length < headerLen || length > len(b) || length > maxLen {
+		return nil, fmt.Errorf("%w: length %d", errMalformed, length)
+	}
+
+	p := &Packet{Code: Code(b[0]), Identifier: b[1]}
+	copy(p.Authenticator[:], b[4:headerLen])
+
+	attrs, err := ParseAttributes(b[headerLen:length])
+	if err != nil {
+		return nil, err
+	}
+
+	p.Attributes = attrs
+
+	return p, nil
+}
+
+// AppendAttributes encodes attrs onto b as they appear in a packet, so a
+// request can be stored and sent later.
+func AppendAttributes(b []byte, attrs []Attribute) ([]byte, error) {
+	for _, a := range attrs {
+		if len(a.Value) > maxAttr {
+			return nil, fmt.Errorf("attribute %d is %d bytes long", a.Type, len(a.Value))
+		}
+
+		b = append(b, byte(a.Type), byte(2+len(a.Value)))
+		b = append(b, a.Value...)
+	}
+
+	return b, nil
+}
+
+// ParseAttributes decodes attributes encoded as in a packet.
+func ParseAttributes(b []byte) ([]Attribute, error) {
+	var attrs []Attribute
+
+	for len(b) > 0 {
+		if len(b) < 2 || b[1] < 2 || int(b[1]) > len(b) {
+			return nil, fmt.Errorf("%w: attribute overruns the packet", errMalformed)
+		}
+
+		attrs = append(attrs, Attribute{Type: Type(b[0]), Value: b[2:b[1]]})
+		b = b[b[1]:]
+	}
+
+	return attrs, nil
+}
+
+// VerifyResponse checks the response authenticator of raw, a reply to a
+// request with authenticator requestAuth, and its Message-Authenticator
+// when present (RFC 2865 §3, RFC 3579 §3.2).
+func VerifyResponse(raw []byte, requestAuth [16]byte, secret []byte) error {
+	if len(raw) < headerLen {
+		return errMalformed
+	}
+
+	b := append([]byte{}, raw[:binary.BigEndian.Uint16(raw[2:])]...)
+
+	var got [16]byte
+
+	copy(got[:], b[4:headerLen])
+	copy(b[4:headerLen], requestAuth[:])
+
+	want := md5.Sum(append(append([]byte{}, b...), secret...)) // #nosec G401
+	if !hmac.Equal(got[:], want[:]) {
+		return errors.New("response authenticator does not match")
+	}
+
+	return verifyMessageAuthenticator(b, secret)
+}
+
+// VerifyRequest checks the authenticator of an Accounting-Request, or the
+// Message-Authenticator of an Access-Request when present.
+func VerifyRequest(raw []byte, secret []byte) error {
+	if len(raw) < headerLen {
+		return errMalformed
+	}
+
+	b := append([]byte{}, raw[:binary.BigEndian.Uint16(raw[2:])]...)
+
+	if Code(b[0]) == CodeAccountingRequest {
+		var got [16]byte
+
+		copy(got[:], b[4:headerLen])
+		clear(b[4:headerLen])
+
+		want := md5.Sum(append(append([]byte{}, b...), secret...)) // #nosec G401
+		if !hmac.Equal(got[:], want[:]) {
+			return errors.New("request authenticator does not match")
+		}
+
+		copy(b[4:headerLen], got[:])
+	}
+
+	return verifyMessageAuthenticator(b, secret)
+}
+
+// verifyMessageAuthenticator checks b, whose authenticator field already
+// holds the value the HMAC was computed over.
+func verifyMessageAuthenticator(b []byte, secret []byte) error {
+	for at := headerLen; at+2 <= len(b) && b[at+1] >= 2; at += int(b[at+1]) {
+		if Type(b[at]) != TypeMessageAuthenticator {
+			continue
+		}
+
+		if b[at+1] != 18 || at+18 > len(b) {
+			return fmt.Errorf("%w: Message-Authenticator length", errMalformed)
+		}
+
+		var got [16]byte
+
+		copy(got[:], b[at+2:at+18])
+		clear(b[at+2 : at+18])
+
+		mac := hmac.New(md5.New, secret)
+		mac.Write(b)
+
+		if !hmac.Equal(got[:], mac.Sum(nil)) {
+			return errors.New("Message-Authenticator does not match")
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// HidePassword encrypts a User-Password value (RFC 2865 §5.2).
+func HidePassword(password []byte, secret []byte, requestAuth [16]byte) []byte {
+	padded := make([]byte, max(16, (len(password)+15)/16*16))
+	copy(padded, password)
+
+	out := make([]byte, len(padded))
+	prev := requestAuth[:]
+
+	for i := 0; i < len(padded); i += 16 {
+		sum := md5.Sum(append(append([]byte{}, secret...), prev...)) // #nosec G401
+		for j := range 16 {
+			out[i+j] = padded[i+j] ^ sum[j]
+		}
+
+		prev = out[i : i+16]
+	}
+
+	return out
+}
+
+// RevealPassword reverses HidePassword.
+func RevealPassword(hidden []byte, secret []byte, requestAuth [16]byte) ([]byte, error) {
+	if len(hidden) == 0 || len(hidden)%16 != 0 {
+		return nil, fmt.Errorf("%w: User-Password length %d", errMalformed, len(hidden))
+	}
+
+	out := make([]byte, len(hidden))
+	prev := requestAuth[:]
+
+	for i := 0; i < len(hidden); i += 16 {
+		sum := md5.Sum(append(append([]byte{}, secret...), prev...)) // #nosec G401
+		for j := range 16 {
+			out[i+j] = hidden[i+j] ^ sum[j]
+		}
+
+		prev = hidden[i : i+16]
+	}
+
+	return bytes.TrimRight(out, "\x00"), nil
+}
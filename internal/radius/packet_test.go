@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package radius
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+var testSecret = []byte("testing123")
+
+func TestAccessRequestRoundTrip(t *testing.T) {
+	req, err := NewRequest(CodeAccessRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Identifier = 7
+	req.AddString(TypeUserName, "001010000000001")
+	req.Add(TypeUserPassword, HidePassword([]byte("001010000000001"), testSecret, req.Authenticator))
+	req.AddUint32(TypeServiceType, ServiceTypeFramed)
+	req.Add(TypeMessageAuthenticator, nil)
+
+	raw, err := req.Encode(testSecret, [16]byte{})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	if err := VerifyRequest(raw, testSecret); err != nil {
+		t.Fatalf("VerifyRequest: %v", err)
+	}
+
+	if err := VerifyRequest(raw, []byte("wrong")); err == nil {
+		t.Fatal("expected a wrong secret to fail the Message-Authenticator")
+	}
+
+	got, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if got.Code != CodeAccessRequest || got.Identifier != 7 || got.Authenticator != req.Authenticator {
+		t.Fatalf("header did not round-trip: %+v", got)
+	}
+
+	if got.String(TypeUserName) != "001010000000001" {
+		t.Fatalf("User-Name = %q", got.String(TypeUserName))
+	}
+
+	if v, ok := got.Uint32(TypeServiceType); !ok || v != ServiceTypeFramed {
+		t.Fatalf("Service-Type = %d, %v", v, ok)
+	}
+
+	hidden, _ := got.Get(TypeUserPassword)
+
+	password, err := RevealPassword(hidden, testSecret, got.Authenticator)
+	if err != nil || string(password) != "001010000000001" {
+		t.Fatalf("RevealPassword = %q, %v", password, err)
+	}
+}
+
+func TestResponseAuthenticator(t *testing.T) {
+	reqAuth := [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	resp := &Packet{Code: CodeAccessAccept, Identifier: 3}
+	resp.AddAddr(TypeFramedIPAddress, netip.MustParseAddr("172.20.0.9"))
+	resp.Add(TypeMessageAuthenticator, nil)
+
+	raw, err := resp.Encode(testSecret, reqAuth)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	if err := VerifyResponse(raw, reqAuth, testSecret); err != nil {
+		t.Fatalf("VerifyResponse: %v", err)
+	}
+
+	if err := VerifyResponse(raw, [16]byte{}, testSecret); err == nil {
+		t.Fatal("expected a response to another request to fail")
+	}
+
+	tampered := bytes.Clone(raw)
+	tampered[len(tampered)-20] ^= 0xff
+
+	if err := VerifyResponse(tampered, reqAuth, testSecret); err == nil {
+		t.Fatal("expected a tampered response to fail")
+	}
+}
+
+func TestAccountingRequestAuthenticator(t *testing.T) {
+	req := &Packet{Code: CodeAccountingRequest, Identifier: 1}
+	req.AddString(TypeUserName, "001010000000001")
+
+	raw, err := req.Encode(testSecret, [16]byte{})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	if err := VerifyRequest(raw, testSecret); err != nil {
+		t.Fatalf("VerifyRequest: %v", err)
+	}
+
+	if err := VerifyRequest(raw, []byte("wrong")); err == nil {
+		t.Fatal("expected a wrong secret to fail")
+	}
+}
+
+func TestLongAttributesAreSplit(t *testing.T) {
+	p := &Packet{Code: CodeAccessRequest}
+	eap := bytes.Repeat([]byte{0xab}, 600)
+	p.Add(TypeEAPMessage, eap)
+
+	if n := len(p.GetAll(TypeEAPMessage)); n != 3 {
+		t.Fatalf("expected 3 EAP-Message attributes, got %d", n)
+	}
+
+	if !bytes.Equal(p.Concat(TypeEAPMessage), eap) {
+		t.Fatal("EAP-Message did not reassemble")
+	}
+}
+
+func TestVendorAttributesAndStoredAttributes(t *testing.T) {
+	p := &Packet{Code: CodeAccountingRequest}
+	p.AddUint32(TypeAcctStatusType, AcctStatusStart)
+	p.AddVendor(VendorID3GPP, 1, []byte("001010000000001"))
+
+	raw, err := AppendAttributes(nil, p.Attributes)
+	if err != nil {
+		t.Fatalf("AppendAttributes: %v", err)
+	}
+
+	attrs, err := ParseAttributes(raw)
+	if err != nil {
+		t.Fatalf("ParseAttributes: %v", err)
+	}
+
+	q := &Packet{Code: CodeAccountingRequest, Attributes: attrs}
+
+	if v, ok := q.Uint32(TypeAcctStatusType); !ok || v != AcctStatusStart {
+		t.Fatalf("Acct-Status-Type = %d, %v", v, ok)
+	}
+
+	if v, ok := q.Vendor(VendorID3GPP, 1); !ok || string(v) != "001010000000001" {
+		t.Fatalf("3GPP-IMSI = %q, %v", v, ok)
+	}
+
+	if _, ok := q.Vendor(VendorID3GPP, 20); ok {
+		t.Fatal("found a sub-attribute that was not added")
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	cases := map[string][]byte{
+		"short":         {1, 2, 0},
+		"bad length":    append([]byte{1, 1, 0, 200}, make([]byte, 16)...),
+		"zero attr len": append(append([]byte{1, 1, 0, 22}, make([]byte, 16)...), 1, 0),
+		"attr overrun":  append(append([]byte{1, 1, 0, 23}, make([]byte, 16)...), 1, 9, 0),
+	}
+
+	for name, raw := range cases {
+		if _, err := Parse(raw); !errors.Is(err, errMalformed) {
+			t.Errorf("%s: expected errMalformed, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+// Package radiustest provides a minimal RADIUS server that stands in for an
+// external AAA server in tests and labs.
+package radiustest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/ellanetworks/core/internal/radius"
+)
+
+// maxLen is the largest packet RADIUS allows (RFC 2865 §3).
+const maxLen = 4096
+
+// Handler answers one request, whose authenticators have been checked. A
+// nil answer drops the request.
+type Handler func(req *radius.Packet) *radius.Packet
+
+// Server hands requests to a Handler.
+type Server struct {
+	conn    *net.UDPConn
+	secret  []byte
+	handler Handler
+
+	wg sync.WaitGroup
+}
+
+// NewServer listens on listen.
+func NewServer(listen netip.AddrPort, secret []byte, handler Handler) (*Server, error) {
+	conn, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(listen))
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", listen, err)
+	}
+
+	s := &Server{conn: conn, secret: secret, handler: handler}
+
+	s.wg.Go(s.serve)
+
+	return s, nil
+}
+
+// Addr is where the server listens.
+func (s *Server) Addr() netip.AddrPort {
+	return s.conn.LocalAddr().(*net.UDPAddr).AddrPort()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	err := s.conn.Close()
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	buf := make([]byte, maxLen)
+
+	for {
+		n, from, err := s.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			continue
+		}
+
+		req, err := radius.Parse(buf[:n])
+		if err != nil || radius.VerifyRequest(buf[:n], s.secret) != nil {
+			continue
+		}
+
+		resp := s.handler(req)
+		if resp == nil {
+			continue
+		}
+
+		resp.Identifier = req.Identifier
+
+		raw, err := resp.Encode(s.secret, req.Authenticator)
+		if err != nil {
+			continue
+		}
+
+		_, _ = s.conn.WriteToUDPAddrPort(raw, from)
+	}
+}
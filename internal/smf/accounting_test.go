@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+)
+
+type fakeAccounting struct {
+	mu       sync.Mutex
+	started  []smf.AccountingSession
+	usage    map[string]uint64
+	modified []smf.AccountingQoS
+	stopped  []string
+}
+
+func (f *fakeAccounting) SessionStarted(sess smf.AccountingSession) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.started = append(f.started, sess)
+}
+
+func (f *fakeAccounting) SessionUsage(ref string, uplink, downlink uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.usage == nil {
+		f.usage = make(map[string]uint64)
+	}
+
+	f.usage[ref] += uplink + downlink
+}
+
+func (f *fakeAccounting) SessionModified(_ string, qos smf.AccountingQoS) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.modified = append(f.modified, qos)
+}
+
+func (f *fakeAccounting) SessionStopped(ref string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = append(f.stopped, ref)
+}
+
+func TestAccounting_SessionLifetime(t *testing.T) {
+	store, upf := epsTestSMF()
+	acct := &fakeAccounting{}
+	s := smf.New(&fakePCF{}, store, upf, &fakeAMF{}, smf.WithAccounting(acct))
+	ctx := context.Background()
+
+	bearer, err := s.CreateEPSSession(ctx, epsRequest(1))
+	if err != nil {
+		t.Fatalf("CreateEPSSession: %v", err)
+	}
+
+	if len(acct.started) != 1 {
+		t.Fatalf("started = %d, want 1", len(acct.started))
+	}
+
+	got := acct.started[0]
+	if got.Ref != bearer.Ref || got.IMSI != "001010000000001" || got.Dnn != "internet" || got.Access != smf.Access4G || got.IPv4 != bearer.IPv4 {
+		t.Errorf("started %+v, want the bearer's ref, IMSI, APN and address", got)
+	}
+
+	sc := s.GetSession(bearer.Ref)
+	if err := s.HandleUsageReport(ctx, &models.UsageReport{SEID: sc.PFCPContext.SEID, UplinkVolume: 10, DownlinkVolume: 20}); err != nil {
+		t.Fatalf("HandleUsageReport: %v", err)
+	}
+
+	if acct.usage[bearer.Ref] != 30 {
+		t.Errorf("usage = %d, want 30", acct.usage[bearer.Ref])
+	}
+
+	if err := s.UpdateEPSSessionAMBR(ctx, bearer.Ref, models.MustParseBitRate("100 Mbps"), models.MustParseBitRate("200 Mbps")); err != nil {
+		t.Fatalf("UpdateEPSSessionAMBR: %v", err)
+	}
+
+	if len(acct.modified) != 1 || !acct.modified[0].Ambr.Downlink.Equal(models.MustParseBitRate("200 Mbps")) {
+		t.Errorf("modified = %+v, want the new Session-AMBR once", acct.modified)
+	}
+
+	if err := s.ReleaseEPSSession(ctx, bearer.Ref); err != nil {
+		t.Fatalf("ReleaseEPSSession: %v", err)
+	}
+
+	if err := s.ReleaseEPSSession(ctx, bearer.Ref); err != nil {
+		t.Fatalf("second ReleaseEPSSession: %v", err)
+	}
+
+	if len(acct.stopped) != 1 || acct.stopped[0] != bearer.Ref {
+		t.Errorf("stopped = %v, want [%s] once", acct.stopped, bearer.Ref)
+	}
+}
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf
+
+import (
+	"context"
+	"time"
+
+	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"go.uber.org/zap"
+)
+
+// SessionQuotas resolves the admission quotas a new session counts against,
+// in the manner of network slice admission control (TS 23.501 §5.15.11), and
+// keeps the count of admitted sessions, which every node of a cluster shares.
+type SessionQuotas interface {
+	SessionQuota(ctx context.Context, snssai *models.Snssai, dnn string) (SessionQuota, error)
+	// AdmitSession counts session ref against q and returns the quota it
+	// would exceed, empty when it is admitted.
+	AdmitSession(ctx context.Context, ref string, supi etsi.SUPI, snssai *models.Snssai, dnn string, q SessionQuota) (models.Quota, error)
+	// ReleaseSession stops counting session ref.
+	ReleaseSession(ctx context.Context, ref string) error
+}
+
+// SessionQuota is the limits on the sessions of a slice and a data network.
+// A zero limit is no limit.
+type SessionQuota struct {
+	SliceMaxSessions              int
+	SliceMaxSessionsPerSubscriber int
+	SliceBackOff                  time.Duration
+	DataNetworkMaxSessions        int
+	DataNetworkBackOff            time.Duration
+}
+
+// WithSessionQuotas refuses sessions beyond the quotas q resolves.
+func WithSessionQuotas(q SessionQuotas) Option { return func(s *SMF) { s.quotas = q } }
+
+// sessionQuota resolves the quota of a session about to be established, nil
+// when no quotas are configured.
+func (s *SMF) sessionQuota(ctx context.Context, snssai *models.Snssai, dnn string) (*SessionQuota, error) {
+	if s.quotas == nil {
+		return nil, nil
+	}
+
+	q, err := s.quotas.SessionQuota(ctx, snssai, dnn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+// limited reports whether q limits any session count.
+func (q *SessionQuota) limited() bool {
+	return q.SliceMaxSessions > 0 || q.SliceMaxSessionsPerSubscriber > 0 || q.DataNetworkMaxSessions > 0
+}
+
+// admit counts sc against q, and returns the quota refusing it. The count
+// spans the cluster, so a quota holds however many nodes serve the slice.
+//
+// A session no quota limits goes uncounted, and one the count fails for, as
+// on a node cut off from the cluster leader, is admitted: the quotas must
+// not make the leader a dependency of every session. The quota reconciler
+// records both once it can.
+func (s *SMF) admit(ctx context.Context, q *SessionQuota, sc *SMContext) *models.QuotaExceededError {
+	if q == nil || !q.limited() {
+		return nil
+	}
+
+	quota, err := s.quotas.AdmitSession(ctx, sc.Ref, sc.Supi, sc.Snssai, sc.Dnn, *q)
+	if err != nil {
+		logger.SmfLog.Warn("couldn't count session against the quotas; admitting it until reconciled",
+			zap.String("smContextRef", sc.Ref), zap.Error(err))
+
+		return nil
+	}
+
+	switch quota {
+	case "":
+		return nil
+	case models.QuotaSubscriberSessions:
+		return &models.QuotaExceededError{Quota: quota, Limit: q.SliceMaxSessionsPerSubscriber}
+	case models.QuotaSliceSessions:
+		return &models.QuotaExceededError{Quota: quota, Limit: q.SliceMaxSessions, BackOff: q.SliceBackOff}
+	default:
+		return &models.QuotaExceededError{Quota: quota, Limit: q.DataNetworkMaxSessions, BackOff: q.DataNetworkBackOff}
+	}
+}
+
+// releaseAdmission stops counting sc against the quotas.
+func (s *SMF) releaseAdmission(sc *SMContext) {
+	if s.quotas == nil {
+		return
+	}
+
+	if err := s.quotas.ReleaseSession(context.Background(), sc.Ref); err != nil {
+		logger.SmfLog.Warn("couldn't release session admission; it counts against the quotas until reconciled",
+			zap.String("smContextRef", sc.Ref), zap.Error(err))
+	}
+}
+
+// QuotaSession is a session as the admission quotas count it.
+type QuotaSession struct {
+	Ref    string
+	Supi   etsi.SUPI
+	Snssai *models.Snssai
+	Dnn    string
+}
+
+// QuotaSessions returns the sessions of this node, for reconciling the
+// admission count.
+func (s *SMF) QuotaSessions() []QuotaSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]QuotaSession, 0, len(s.pool))
+
+	for _, sc := range s.pool {
+		out = append(out, QuotaSession{Ref: sc.Ref, Supi: sc.Supi, Snssai: sc.Snssai, Dnn: sc.Dnn})
+	}
+
+	return out
+}
+
+// SessionCountsBySlice returns the number of sessions on each slice.
+func (s *SMF) SessionCountsBySlice() map[models.Snssai]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[models.Snssai]int)
+
+	for _, sc := range s.pool {
+		if sc.Snssai != nil {
+			out[models.Snssai{Sst: sc.Snssai.Sst, Sd: models.NormalizeSD(sc.Snssai.Sd)}]++
+		}
+	}
+
+	return out
+}
+
+// SessionCountsByDNN returns the number of sessions on each data network.
+func (s *SMF) SessionCountsByDNN() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string]int)
+
+	for _, sc := range s.pool {
+		out[sc.Dnn]++
+	}
+
+	return out
+}
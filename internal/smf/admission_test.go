@@ -0,0 +1,301 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+// fakeSessionQuotas counts admitted sessions as the cluster store does,
+// including ones another node admitted.
+type fakeSessionQuotas struct {
+	quota    smf.SessionQuota
+	quotaErr error
+	admitErr error
+
+	mu       sync.Mutex
+	admitted map[string]smf.QuotaSession
+	admits   int
+}
+
+func (f *fakeSessionQuotas) SessionQuota(context.Context, *models.Snssai, string) (smf.SessionQuota, error) {
+	return f.quota, f.quotaErr
+}
+
+func (f *fakeSessionQuotas) AdmitSession(_ context.Context, ref string, supi etsi.SUPI, snssai *models.Snssai, dnn string, q smf.SessionQuota) (models.Quota, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.admits++
+
+	if f.admitErr != nil {
+		return "", f.admitErr
+	}
+
+	var onSlice, ofSubscriber, onDataNetwork int
+
+	for _, other := range f.admitted {
+		if snssai != nil && other.Snssai != nil && other.Snssai.Equal(*snssai) {
+			onSlice++
+
+			if other.Supi == supi {
+				ofSubscriber++
+			}
+		}
+
+		if other.Dnn == dnn {
+			onDataNetwork++
+		}
+	}
+
+	switch {
+	case q.SliceMaxSessionsPerSubscriber > 0 && ofSubscriber >= q.SliceMaxSessionsPerSubscriber:
+		return models.QuotaSubscriberSessions, nil
+	case q.SliceMaxSessions > 0 && onSlice >= q.SliceMaxSessions:
+		return models.QuotaSliceSessions, nil
+	case q.DataNetworkMaxSessions > 0 && onDataNetwork >= q.DataNetworkMaxSessions:
+		return models.QuotaDataNetworkSessions, nil
+	}
+
+	if f.admitted == nil {
+		f.admitted = make(map[string]smf.QuotaSession)
+	}
+
+	f.admitted[ref] = smf.QuotaSession{Ref: ref, Supi: supi, Snssai: snssai, Dnn: dnn}
+
+	return "", nil
+}
+
+func (f *fakeSessionQuotas) ReleaseSession(_ context.Context, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.admitted, ref)
+
+	return nil
+}
+
+func (f *fakeSessionQuotas) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.admitted)
+}
+
+func establishForQuota(t *testing.T, s *smf.SMF, supi etsi.SUPI, psi uint8) (*fgs.PDUSessionEstablishmentReject, error) {
+	t.Helper()
+
+	_, rsp, err := s.CreateSmContext(context.Background(), supi, psi, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+	if err == nil {
+		return nil, nil
+	}
+
+	reject, decodeErr := fgs.ParsePDUSessionEstablishmentReject(rsp)
+	if decodeErr != nil {
+		t.Fatalf("decode reject: %v", decodeErr)
+	}
+
+	return reject, err
+}
+
+func TestAdmission_QuotaRejects(t *testing.T) {
+	other, err := etsi.NewSUPIFromIMSI("001010000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		quota       smf.SessionQuota
+		second      etsi.SUPI
+		wantCause   fgs.GSMCause
+		wantBackoff time.Duration
+	}{
+		{
+			name:        "slice sessions",
+			quota:       smf.SessionQuota{SliceMaxSessions: 1, SliceBackOff: 5 * time.Minute},
+			second:      other,
+			wantCause:   fgs.GSMCauseInsufficientResourcesForSpecificSlice,
+			wantBackoff: 5 * time.Minute,
+		},
+		{
+			name:        "data network sessions",
+			quota:       smf.SessionQuota{DataNetworkMaxSessions: 1, DataNetworkBackOff: time.Minute},
+			second:      other,
+			wantCause:   fgs.GSMCauseInsufficientResources,
+			wantBackoff: time.Minute,
+		},
+		{
+			name:      "sessions per subscriber",
+			quota:     smf.SessionQuota{SliceMaxSessionsPerSubscriber: 1, SliceBackOff: 5 * time.Minute},
+			second:    testSUPI(),
+			wantCause: fgs.GSMCauseMaximumNumberOfPDUSessionsReached,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pcf, store, upf, amfCb := defaultFakes()
+			quotas := &fakeSessionQuotas{quota: tc.quota}
+			s := smf.New(pcf, store, upf, amfCb, smf.WithSessionQuotas(quotas))
+
+			if reject, err := establishForQuota(t, s, testSUPI(), 1); err != nil {
+				t.Fatalf("first session rejected with #%d: %v", reject.Cause, err)
+			}
+
+			reject, err := establishForQuota(t, s, tc.second, 2)
+			if err == nil {
+				t.Fatal("a session beyond the quota was accepted")
+			}
+
+			if reject.Cause != tc.wantCause {
+				t.Errorf("reject cause #%d, want #%d", reject.Cause, tc.wantCause)
+			}
+
+			var backoff time.Duration
+
+			if reject.BackoffTimer != nil {
+				backoff, _ = reject.BackoffTimer.Duration()
+			}
+
+			if backoff != tc.wantBackoff {
+				t.Errorf("back-off timer %v, want %v", backoff, tc.wantBackoff)
+			}
+
+			if n := s.SessionCountsBySlice()[*testSnssai]; n != 1 {
+				t.Errorf("sessions on the slice = %d, want the refused one never created", n)
+			}
+
+			if len(upf.deleteCalls) != 0 {
+				t.Errorf("UPF deletions = %d, want the refused session never programmed", len(upf.deleteCalls))
+			}
+
+			if n := quotas.count(); n != 1 {
+				t.Errorf("admitted sessions = %d, want only the first", n)
+			}
+		})
+	}
+}
+
+// TestAdmission_CountsOtherNodes checks that sessions another node admitted
+// count against the quota, and that a released session frees its room.
+func TestAdmission_CountsOtherNodes(t *testing.T) {
+	other, err := etsi.NewSUPIFromIMSI("001010000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcf, store, upf, amfCb := defaultFakes()
+	quotas := &fakeSessionQuotas{
+		quota:    smf.SessionQuota{SliceMaxSessions: 1, SliceBackOff: time.Minute},
+		admitted: map[string]smf.QuotaSession{"elsewhere": {Ref: "elsewhere", Supi: other, Snssai: testSnssai, Dnn: testDNN}},
+	}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithSessionQuotas(quotas))
+
+	reject, err := establishForQuota(t, s, testSUPI(), 1)
+	if err == nil {
+		t.Fatal("a session beyond the quota another node fills was accepted")
+	}
+
+	if reject.Cause != fgs.GSMCauseInsufficientResourcesForSpecificSlice {
+		t.Errorf("reject cause #%d, want #%d", reject.Cause, fgs.GSMCauseInsufficientResourcesForSpecificSlice)
+	}
+
+	if err := quotas.ReleaseSession(context.Background(), "elsewhere"); err != nil {
+		t.Fatal(err)
+	}
+
+	if reject, err := establishForQuota(t, s, testSUPI(), 1); err != nil {
+		t.Fatalf("session rejected with #%d after the other node released: %v", reject.Cause, err)
+	}
+
+	sessions := s.QuotaSessions()
+	if len(sessions) != 1 || quotas.count() != 1 {
+		t.Fatalf("pooled %d and admitted %d sessions, want 1 each", len(sessions), quotas.count())
+	}
+
+	s.RemoveSession(context.Background(), sessions[0].Ref)
+
+	if n := quotas.count(); n != 0 {
+		t.Errorf("admitted sessions = %d after removal, want the session released", n)
+	}
+}
+
+func TestAdmission_NoQuotaAdmits(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	s := smf.New(pcf, store, upf, amfCb, smf.WithSessionQuotas(&fakeSessionQuotas{}))
+
+	for psi := uint8(1); psi <= 3; psi++ {
+		if reject, err := establishForQuota(t, s, testSUPI(), psi); err != nil {
+			t.Fatalf("session %d rejected with #%d: %v", psi, reject.Cause, err)
+		}
+	}
+
+	if got := s.SessionCountsBySlice()[*testSnssai]; got != 3 {
+		t.Errorf("sessions on the slice = %d, want 3", got)
+	}
+
+	if got := s.SessionCountsByDNN()[testDNN]; got != 3 {
+		t.Errorf("sessions on the data network = %d, want 3", got)
+	}
+}
+
+// TestAdmission_NoQuotaSkipsCount checks that a session no quota limits is
+// not counted, so it does not wait on the cluster leader.
+func TestAdmission_NoQuotaSkipsCount(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	quotas := &fakeSessionQuotas{quota: smf.SessionQuota{SliceBackOff: time.Minute}}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithSessionQuotas(quotas))
+
+	if reject, err := establishForQuota(t, s, testSUPI(), 1); err != nil {
+		t.Fatalf("session rejected with #%d: %v", reject.Cause, err)
+	}
+
+	if quotas.admits != 0 {
+		t.Errorf("admission counts = %d, want none without a limit", quotas.admits)
+	}
+}
+
+// TestAdmission_CountFailureAdmits checks that a session whose count fails is
+// admitted rather than refused.
+func TestAdmission_CountFailureAdmits(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	quotas := &fakeSessionQuotas{
+		quota:    smf.SessionQuota{SliceMaxSessions: 1},
+		admitErr: errors.New("no leader"),
+	}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithSessionQuotas(quotas))
+
+	if reject, err := establishForQuota(t, s, testSUPI(), 1); err != nil {
+		t.Fatalf("session rejected with #%d: %v", reject.Cause, err)
+	}
+
+	if got := s.SessionCountsBySlice()[*testSnssai]; got != 1 {
+		t.Errorf("sessions on the slice = %d, want 1", got)
+	}
+}
+
+func TestAdmission_QuotaResolutionFailureRejects(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	quotas := &fakeSessionQuotas{quotaErr: errors.New("store unavailable")}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithSessionQuotas(quotas))
+
+	reject, err := establishForQuota(t, s, testSUPI(), 1)
+	if err == nil {
+		t.Fatal("a session whose quota could not be resolved was accepted")
+	}
+
+	if reject.Cause != fgs.GSMCauseNetworkFailure {
+		t.Errorf("reject cause #%d, want #%d", reject.Cause, fgs.GSMCauseNetworkFailure)
+	}
+}
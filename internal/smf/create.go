@@ -9,6 +9,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/logger"
@@ -173,15 +174,72 @@ func (s *SMF) CreateSmContext(ctx context.Context, supi etsi.SUPI, pduSessionID
 		return "", rsp, fmt.Errorf("parse PDU session request failed: %v", err)
 	}
 
-	sc, _, err := s.establishSession(ctx, SessionRequest{
-		Supi:     supi,
-		Identity: SessionIdentity{PDUSessionID: pduSessionID, EBI: epsBearerIdentity},
-		Dnn:      dnn,
-		Snssai:   snssai,
-		Access:   Access5G,
-		PDUType:  negotiatedType,
-		Policy:   policy,
-	})
+	est := &establishment{
+		session: SessionRequest{
+			Supi:     supi,
+			Identity: SessionIdentity{PDUSessionID: pduSessionID, EBI: epsBearerIdentity},
+			Dnn:      dnn,
+			Snssai:   snssai,
+			Access:   Access5G,
+			PDUType:  negotiatedType,
+			Policy:   policy,
+		},
+		pti:           reqPTI,
+		requestedType: requestedType,
+		pco:           pco,
+		alwaysOn:      alwaysOnIndication(req.AlwaysOnRequested),
+	}
+
+	// A data network that verifies the user, not just the SIM, has the DN-AAA
+	// server authenticate it before any resource is committed (TS 23.501
+	// §5.6.6). The establishment resumes when the server accepts.
+	required, err := s.secondaryAuthRequired(ctx, dnn)
+	if err != nil {
+		establishmentResult = metrics.ResultReject
+
+		rsp, buildErr := smfNas.BuildGSMPDUSessionEstablishmentReject(fgs.PDUSessionID(pduSessionID), reqPTI, fgs.GSMCauseRequestRejectedUnspecified)
+		if buildErr != nil {
+			return "", nil, fmt.Errorf("secondary authentication lookup failed: %v (build reject failed: %v)", err, buildErr)
+		}
+
+		return "", rsp, fmt.Errorf("secondary authentication lookup failed: %v", err)
+	}
+
+	if required {
+		ref, rsp, err := s.startSecondaryAuth(ctx, est)
+		if rsp != nil {
+			establishmentResult = metrics.ResultReject
+		}
+
+		return ref, rsp, err
+	}
+
+	return s.establish(ctx, est)
+}
+
+// establishment is a PDU session establishment request that passed its
+// checks, held while secondary authentication runs.
+type establishment struct {
+	session       SessionRequest
+	pti           nas.ProcedureTransactionIdentity
+	requestedType fgs.PDUSessionType
+	pco           *smfNas.ProtocolConfigurationOptions
+	alwaysOn      *bool
+	// eap is the EAP-Success of the DN-AAA server, for the accept.
+	eap []byte
+}
+
+// establish sets the session up and sends the PDU SESSION ESTABLISHMENT
+// ACCEPT, or returns the reject to send. It records the attempt's outcome.
+func (s *SMF) establish(ctx context.Context, est *establishment) (string, []byte, error) {
+	span := trace.SpanFromContext(ctx)
+	pduSessionID := est.session.Identity.PDUSessionID
+
+	var establishmentResult string
+
+	defer func() { recordSessionEstablishmentResult(metrics.RAT5G, establishmentResult) }()
+
+	sc, _, err := s.establishSession(ctx, est.session)
 	if err != nil {
 		establishmentResult = metrics.ResultReject
 
@@ -195,9 +253,29 @@ func (s *SMF) CreateSmContext(ctx context.Context, supi etsi.SUPI, pduSessionID
 			cause = fgs.GSMCauseInsufficientResources
 		case errors.Is(err, errSessionIdentity):
 			cause = fgs.GSMCauseInvalidPDUSessionIdentity
+		case errors.Is(err, errCreditDenied):
+			cause = fgs.GSMCauseUserAuthenticationOrAuthorizationFailed
+		case errors.Is(err, errAdmission):
+			cause = fgs.GSMCauseNetworkFailure
+		}
+
+		var backoff time.Duration
+
+		if quotaErr, ok := errors.AsType[*models.QuotaExceededError](err); ok {
+			cause, backoff = quotaRejectCause(quotaErr)
+		}
+
+		var (
+			rsp      []byte
+			buildErr error
+		)
+
+		if backoff > 0 {
+			rsp, buildErr = smfNas.BuildGSMPDUSessionEstablishmentRejectBackoff(fgs.PDUSessionID(pduSessionID), est.pti, cause, backoff)
+		} else {
+			rsp, buildErr = smfNas.BuildGSMPDUSessionEstablishmentReject(fgs.PDUSessionID(pduSessionID), est.pti, cause)
 		}
 
-		rsp, buildErr := smfNas.BuildGSMPDUSessionEstablishmentReject(fgs.PDUSessionID(pduSessionID), reqPTI, cause)
 		if buildErr != nil {
 			return "", nil, fmt.Errorf("failed to create SM Context: %v (build reject failed: %v)", err, buildErr)
 		}
@@ -209,7 +287,7 @@ func (s *SMF) CreateSmContext(ctx context.Context, supi etsi.SUPI, pduSessionID
 	// cause #50/#51 (TS 24.501 §6.4.1.3).
 	var cause *fgs.GSMCause
 
-	switch narrowPDUType(uint8(requestedType), sc.PDUSessionType) {
+	switch narrowPDUType(uint8(est.requestedType), sc.PDUSessionType) {
 	case narrowIPv4Only:
 		cause = new(fgs.GSMCausePDUSessionTypeIPv4OnlyAllowed)
 	case narrowIPv6Only:
@@ -226,7 +304,13 @@ func (s *SMF) CreateSmContext(ctx context.Context, supi etsi.SUPI, pduSessionID
 	// the N1N2 delivery below fails.
 	establishmentResult = metrics.ResultAccept
 
-	if err := s.sendPduSessionEstablishmentAccept(ctx, sc, policy, pco, addrs, uint8(reqPTI), cause, alwaysOnIndication(req.AlwaysOnRequested), sc.EBI); err != nil {
+	// The session's policy carries the policy decision point's decision, if
+	// any.
+	sc.Mutex.Lock()
+	policy := sc.PolicyData
+	sc.Mutex.Unlock()
+
+	if err := s.sendPduSessionEstablishmentAccept(ctx, sc, policy, est.pco, addrs, uint8(est.pti), cause, est.alwaysOn, sc.EBI, est.eap); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "failed to send PDU session establishment accept")
 
@@ -260,12 +344,28 @@ func (s *SMF) handlePduSessionContextReplacement(ctx context.Context, smCtxt *SM
 	s.dropSourceRouting(ctx, smCtxt.Ref, dropped)
 }
 
+// quotaRejectCause maps a refused admission to its 5GSM cause and back-off
+// timer: #69 for the slice, #26 for the data network and #65 for the
+// subscriber's own sessions, which no back-off would free (TS 24.501 §6.4.1.4.2).
+func quotaRejectCause(err *models.QuotaExceededError) (fgs.GSMCause, time.Duration) {
+	switch err.Quota {
+	case models.QuotaSliceSessions:
+		return fgs.GSMCauseInsufficientResourcesForSpecificSlice, err.BackOff
+	case models.QuotaDataNetworkSessions:
+		return fgs.GSMCauseInsufficientResources, err.BackOff
+	default:
+		return fgs.GSMCauseMaximumNumberOfPDUSessionsReached, 0
+	}
+}
+
 // establishmentRejectCause maps a session-policy lookup failure to the 5GSM
 // cause of the PDU Session Establishment Reject (TS 24.501 §9.11.4.2): #70 when
-// the slice is served but not the DNN, #27 when the DNN is unknown, and the
-// generic #31 otherwise.
+// the slice is served but not the DNN, #27 when the DNN is unknown, #8 when the
+// subscriber is barred from data, and the generic #31 otherwise.
 func establishmentRejectCause(err error) fgs.GSMCause {
 	switch {
+	case errors.Is(err, ErrDataBarred):
+		return fgs.GSMCauseOperatorDeterminedBarring
 	case errors.Is(err, ErrDNNNotInSlice):
 		return fgs.GSMCauseMissingOrUnknownDNNInASlice
 	case errors.Is(err, ErrDNNNotFound):
@@ -328,6 +428,7 @@ func (s *SMF) sendPduSessionEstablishmentAccept(
 	cause *fgs.GSMCause,
 	alwaysOn *bool,
 	epsBearerIdentity uint8,
+	eap []byte,
 ) error {
 	ctx, span := tracer.Start(ctx, "smf/send_pdu_session_establishment_accept",
 		trace.WithSpanKind(trace.SpanKindInternal),
@@ -339,7 +440,7 @@ func (s *SMF) sendPduSessionEstablishmentAccept(
 	smContext.establishmentOutstanding = true
 	smContext.Mutex.Unlock()
 
-	n1Msg, err := smfNas.BuildGSMPDUSessionEstablishmentAccept(&policy.Ambr, &policy.QosData, smContext.PDUSessionID, pti, smContext.Snssai, smContext.Dnn, pco, policy.DNS, policy.MTU, cause, addrs, alwaysOn, epsBearerIdentity)
+	n1Msg, err := smfNas.BuildGSMPDUSessionEstablishmentAccept(&policy.Ambr, &policy.QosData, smContext.PDUSessionID, pti, smContext.Snssai, smContext.Dnn, pco, policy.DNS, policy.MTU, cause, addrs, alwaysOn, epsBearerIdentity, eap)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "failed to build PDU session establishment accept")
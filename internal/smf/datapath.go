@@ -26,6 +26,10 @@ type dataPlane struct {
 	Downlink DownlinkState
 	QFI      uint8
 	AMBR     models.Ambr
+
+	// Dedicated is the eNB endpoint of the E-RAB of the dedicated EPS bearer
+	// carrying the session's dedicated QoS flow, unbound when it has none.
+	Dedicated AnchorBinding
 }
 
 const (
@@ -181,5 +185,18 @@ func (d dataPlane) modifyRequest(seid uint64, policyID string) *models.ModifyReq
 		UpdatePDRs: pdrs,
 		UpdateFARs: fars,
 		UpdateQERs: qers,
+		S1UBearers: d.s1uBearers(),
 	}
 }
+
+// s1uBearers lists the dedicated EPS bearer the downlink of the dedicated
+// QoS flow leaves through. The bearer counts only while the downlink
+// forwards to the eNB its E-RAB ends at; otherwise the flow's traffic takes
+// the default bearer.
+func (d dataPlane) s1uBearers() []models.S1UBearer {
+	if d.Access != Access4G || d.Downlink != DownlinkForwarding || !d.Dedicated.bound() || !d.Dedicated.sameNode(d.AN) {
+		return nil
+	}
+
+	return []models.S1UBearer{{QFI: models.DedicatedQFI, TEID: d.Dedicated.TEID}}
+}
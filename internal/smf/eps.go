@@ -169,6 +169,18 @@ func (s *SMF) bindEPSDownlink(ctx context.Context, smContext *SMContext, enb mod
 		return nil, fmt.Errorf("EPS session %q has no user plane", smContext.Ref)
 	}
 
+	dropped, err := s.bindDownlink(ctx, smContext, Access4G, anchorFromFTEID(enb))
+	if err != nil {
+		return nil, err
+	}
+
+	s.registerIPv6SessionIfNeeded(ctx, smContext, Access4G)
+
+	return dropped, nil
+}
+
+// anchorFromFTEID is the binding of the eNB S1-U endpoint enb.
+func anchorFromFTEID(enb models.FTEID) AnchorBinding {
 	enbIP := net.IP(enb.Addr.AsSlice())
 
 	an := AnchorBinding{TEID: enb.TEID}
@@ -178,14 +190,7 @@ func (s *SMF) bindEPSDownlink(ctx context.Context, smContext *SMContext, enb mod
 		an.IPv4 = enbIP
 	}
 
-	dropped, err := s.bindDownlink(ctx, smContext, Access4G, an)
-	if err != nil {
-		return nil, err
-	}
-
-	s.registerIPv6SessionIfNeeded(ctx, smContext, Access4G)
-
-	return dropped, nil
+	return an
 }
 
 func (s *SMF) UpdateEPSSessionAMBR(ctx context.Context, ref string, ambrUplink, ambrDownlink models.BitRate) error {
@@ -202,6 +207,21 @@ func (s *SMF) UpdateEPSSessionAMBR(ctx context.Context, ref string, ambrUplink,
 	smContext.Mutex.Lock()
 	defer smContext.Mutex.Unlock()
 
+	// A Session-AMBR the policy decision point set stays.
+	if smContext.policyDecision != nil && smContext.policyDecision.Ambr != nil {
+		return nil
+	}
+
+	if err := s.setEPSSessionAMBR(ctx, smContext, models.Ambr{Uplink: ambrUplink, Downlink: ambrDownlink}); err != nil {
+		return fmt.Errorf("update Session-AMBR for %q: %w", ref, err)
+	}
+
+	return nil
+}
+
+// setEPSSessionAMBR has the UPF enforce ambr on EPS session smContext.
+// Caller holds smContext.Mutex.
+func (s *SMF) setEPSSessionAMBR(ctx context.Context, smContext *SMContext, ambr models.Ambr) error {
 	var (
 		policyID string
 		qfi      uint8
@@ -212,20 +232,32 @@ func (s *SMF) UpdateEPSSessionAMBR(ctx context.Context, ref string, ambrUplink,
 		qfi = smContext.PolicyData.QosData.QFI
 	}
 
-	if err := s.applySessionQERs(ctx, smContext, policyID, qfi, ambrUplink, ambrDownlink); err != nil {
-		return fmt.Errorf("update Session-AMBR for %q: %w", ref, err)
+	if err := s.applySessionQERs(ctx, smContext, policyID, qfi, ambr.Uplink, ambr.Downlink); err != nil {
+		return err
 	}
 
 	if smContext.PolicyData != nil {
 		updated := *smContext.PolicyData
-		updated.Ambr.Uplink = ambrUplink
-		updated.Ambr.Downlink = ambrDownlink
+		updated.Ambr = ambr
 		smContext.PolicyData = &updated
+		s.policyCommitted(smContext)
 	}
 
 	return nil
 }
 
+// UpdateEPSSessionRules binds EPS session ref to the network rules of policy
+// policyID, those in force where the UE is served. A session already on
+// them is left alone.
+func (s *SMF) UpdateEPSSessionRules(ctx context.Context, ref string, policyID string) error {
+	smContext := s.GetSession(ref)
+	if smContext == nil {
+		return fmt.Errorf("no EPS session %q", ref)
+	}
+
+	return s.followLocalRules(ctx, smContext, policyID)
+}
+
 func (s *SMF) ReleaseEPSSession(ctx context.Context, ref string) error {
 	if s.dropHalf(ref, Access4G) {
 		return nil
@@ -5,6 +5,7 @@ package smf
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/nas/fgs"
@@ -25,6 +26,7 @@ func (s *SMF) handle5GSMStatus(ctx context.Context, smContext *SMContext, pti ui
 	smContext.stopProcedureTimer()
 	smContext.ClearPTIInUse(pti)
 	smContext.pendingPolicy = nil
+	s.qosFlowRejected(ctx, smContext, fmt.Sprintf("the UE answered 5GSM STATUS %s", cause))
 
 	establishmentMismatch := cause == fgs.GSMCausePTIMismatch &&
 		smContext.establishmentPTI != 0 && pti == smContext.establishmentPTI
@@ -57,7 +57,7 @@ func (s *SMF) UpdateSmContextN2HandoverPreparing(ctx context.Context, smContextR
 		return nil, fmt.Errorf("handle HandoverRequiredTransfer failed: %v", err)
 	}
 
-	n2Rsp, err := ngap.BuildHandoverRequestTransfer(&smContext.PolicyData.Ambr, &smContext.PolicyData.QosData, smContext.Tunnel.N3TEID, smContext.Tunnel.N3IPv4, smContext.Tunnel.N3IPv6, nasToNgapPDUSessionType(smContext.PDUSessionType), nil)
+	n2Rsp, err := ngap.BuildHandoverRequestTransfer(&smContext.PolicyData.Ambr, &smContext.PolicyData.QosData, smContext.Tunnel.N3TEID, smContext.Tunnel.N3IPv4, smContext.Tunnel.N3IPv6, nasToNgapPDUSessionType(smContext.PDUSessionType), nil, smContext.dedicatedQoSFlows()...)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "failed to build handover request transfer")
@@ -367,7 +367,7 @@ func handlePathSwitchRequestSetupFailedTransfer(b []byte) error {
 
 func (sc *SMContext) signalledQFI() uint8 {
 	if sc.pending != nil && sc.pending.policy != nil {
-		return transferPolicy(sc.PolicyData, sc.pending.policy).QosData.QFI
+		return sc.policyDecision.apply(transferPolicy(sc.PolicyData, sc.pending.policy)).QosData.QFI
 	}
 
 	if sc.PolicyData == nil {
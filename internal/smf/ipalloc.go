@@ -121,13 +121,23 @@ func narrowPDUType(requested, negotiated uint8) pduTypeNarrowing {
 	return narrowIPv6Only
 }
 
-func (s *SMF) allocateUEAddresses(ctx context.Context, dn DNNStore, sc *SMContext) (ueAddresses, error) {
+func (s *SMF) allocateUEAddresses(ctx context.Context, dn DNNStore, sc *SMContext, authorizedIPv4 netip.Addr) (ueAddresses, error) {
 	imsi := sc.Supi.IMSI()
 
 	var addrs ueAddresses
 
 	if fgs.PDUSessionType(sc.PDUSessionType) == fgs.PDUSessionTypeIPv4 || fgs.PDUSessionType(sc.PDUSessionType) == fgs.PDUSessionTypeIPv4v6 {
-		ipv4, err := dn.AllocateIP(ctx, imsi, sc.sessionKey())
+		var (
+			ipv4 netip.Addr
+			err  error
+		)
+
+		if authorizedIPv4.IsValid() {
+			ipv4, err = dn.ReserveIP(ctx, imsi, sc.sessionKey(), authorizedIPv4)
+		} else {
+			ipv4, err = dn.AllocateIP(ctx, imsi, sc.sessionKey())
+		}
+
 		if err != nil {
 			return ueAddresses{}, fmt.Errorf("allocate UE IPv4: %w", err)
 		}
@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/netip"
 	"os"
+	"reflect"
 	"slices"
 	"sync"
 	"testing"
@@ -441,6 +442,24 @@ func TestCreateSmContext_DNNNotFound(t *testing.T) {
 	}
 }
 
+// TestCreateSmContext_DataBarred verifies that a subscriber barred from data is
+// rejected with 5GSM cause #8 "operator determined barring" (TS 24.501).
+func TestCreateSmContext_DataBarred(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	pcf.policy = nil
+	pcf.err = fmt.Errorf("%w: subscriber is barred_data", smf.ErrDataBarred)
+	s := newTestSMF(pcf, store, upf, amfCb)
+
+	_, rejectN1, err := s.CreateSmContext(context.Background(), testSUPI(), 1, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+	if err == nil {
+		t.Fatal("expected error when the subscriber is barred from data")
+	}
+
+	if got := rejectCauseCode(t, rejectN1); fgs.GSMCause(got) != fgs.GSMCauseOperatorDeterminedBarring {
+		t.Fatalf("expected 5GSM cause %d (#8 operator determined barring), got %d", fgs.GSMCauseOperatorDeterminedBarring, got)
+	}
+}
+
 // TestCreateSmContext_DNNNotInSlice verifies that when the slice is served but
 // no policy provides the requested DNN, the SMF rejects with 5GSM cause #70
 // "missing or unknown DNN in a slice" (TS 24.501).
@@ -1066,6 +1085,70 @@ func TestHandleDownlinkDataReport(t *testing.T) {
 	amfCb.mu.Unlock()
 }
 
+func TestHandleErrorIndicationReport_DeactivatesUserPlane(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	s := newTestSMF(pcf, store, upf, amfCb)
+	ctx := context.Background()
+
+	smCtx, _ := setupSessionWithTunnel(t, s)
+	seid := smCtx.PFCPContext.SEID
+
+	// A TEID the session no longer uses is ignored.
+	if err := s.HandleErrorIndicationReport(ctx, &models.ErrorIndicationReport{SEID: seid, RemoteTEID: 1}); err != nil {
+		t.Fatalf("stale Error Indication: %v", err)
+	}
+
+	upf.mu.Lock()
+	if len(upf.modifyCalls) != 0 {
+		upf.mu.Unlock()
+		t.Fatalf("stale Error Indication modified the session: %d calls", len(upf.modifyCalls))
+	}
+	upf.mu.Unlock()
+
+	err := s.HandleErrorIndicationReport(ctx, &models.ErrorIndicationReport{
+		SEID:          seid,
+		RemoteTEID:    6000,
+		RemoteAddress: netip.MustParseAddr("10.0.0.100"),
+	})
+	if err != nil {
+		t.Fatalf("HandleErrorIndicationReport failed: %v", err)
+	}
+
+	upf.mu.Lock()
+	if len(upf.modifyCalls) != 1 {
+		upf.mu.Unlock()
+		t.Fatalf("expected 1 PFCP modify call, got %d", len(upf.modifyCalls))
+	}
+	upf.mu.Unlock()
+
+	if smCtx.Tunnel.Downlink != smf.DownlinkBuffering {
+		t.Fatalf("downlink = %v, want buffering", smCtx.Tunnel.Downlink)
+	}
+}
+
+func TestHandlePathFailureReport_ReleasesSession(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	s := newTestSMF(pcf, store, upf, amfCb)
+	ctx := context.Background()
+
+	smCtx, _ := setupSessionWithTunnel(t, s)
+
+	err := s.HandlePathFailureReport(ctx, &models.PathFailureReport{
+		RemoteAddress: netip.MustParseAddr("10.0.0.100"),
+		SEIDs:         []uint64{smCtx.PFCPContext.SEID},
+	})
+	if err != nil {
+		t.Fatalf("HandlePathFailureReport failed: %v", err)
+	}
+
+	amfCb.mu.Lock()
+	if len(amfCb.releaseCalls) != 1 {
+		amfCb.mu.Unlock()
+		t.Fatalf("expected 1 ReleaseSession call, got %d", len(amfCb.releaseCalls))
+	}
+	amfCb.mu.Unlock()
+}
+
 func TestReconcileSmContext_UsesNewPolicyForPFCPAndN1N2(t *testing.T) {
 	pcf, store, upf, amfCb := defaultFakes()
 	s := newTestSMF(pcf, store, upf, amfCb)
@@ -1738,6 +1821,39 @@ func TestHandleUsageReport(t *testing.T) {
 	}
 }
 
+func TestHandleUsageReportRatingGroups(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	s := newTestSMF(pcf, store, upf, amfCb)
+	ctx := context.Background()
+
+	smCtx, _ := setupSessionWithTunnel(t, s)
+
+	groups := []models.RatingGroupUsage{
+		{RatingGroup: 10, UplinkVolume: 200},
+		{RatingGroup: 20, UplinkVolume: 50},
+	}
+
+	err := s.HandleUsageReport(ctx, &models.UsageReport{
+		SEID:         smCtx.PFCPContext.SEID,
+		UplinkVolume: 100,
+		RatingGroups: groups,
+	})
+	if err != nil {
+		t.Fatalf("HandleUsageReport failed: %v", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if len(store.usageLog) != 1 || store.usageLog[0].uplinkBytes != 100 {
+		t.Fatalf("expected the totals to be stored as reported, got %+v", store.usageLog)
+	}
+
+	if !reflect.DeepEqual(store.ratingLog, groups) {
+		t.Fatalf("unexpected rating group usage: %+v", store.ratingLog)
+	}
+}
+
 // TestHandleDownlinkDataReportEPS checks that downlink data for a 4G EPS session
 // pages via the MME, not the AMF (TS 23.401 §5.3.4.3).
 func TestHandleDownlinkDataReportEPS(t *testing.T) {
@@ -4,7 +4,10 @@
 package smf
 
 import (
+	"strconv"
+
 	"github.com/ellanetworks/core/internal/metrics"
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -12,12 +15,15 @@ import (
 // ("4g"|"5g") and result ("accept"|"reject").
 var SessionEstablishmentAttempts *prometheus.CounterVec
 
-// sessionCounter reports active session counts split by RAT.
+// sessionCounter reports active session counts split by RAT, slice and data
+// network.
 type sessionCounter interface {
 	SessionCountByRAT() (fourG, fiveG int)
+	SessionCountsBySlice() map[models.Snssai]int
+	SessionCountsByDNN() map[string]int
 }
 
-// RegisterMetrics registers the SMF metrics. The sessions gauge reads per-RAT
+// RegisterMetrics registers the SMF metrics. The session gauges read their
 // counts from sessionCounter on each scrape; pass nil to report 0.
 func RegisterMetrics(sessions sessionCounter) {
 	SessionEstablishmentAttempts = prometheus.NewCounterVec(
@@ -35,6 +41,20 @@ func RegisterMetrics(sessions sessionCounter) {
 		nil,
 	)
 
+	sliceSessionsDesc := prometheus.NewDesc(
+		"app_slice_sessions",
+		"Number of active sessions on each network slice.",
+		[]string{"sst", "sd"},
+		nil,
+	)
+
+	dataNetworkSessionsDesc := prometheus.NewDesc(
+		"app_data_network_sessions",
+		"Number of active sessions on each data network.",
+		[]string{"data_network"},
+		nil,
+	)
+
 	prometheus.MustRegister(SessionEstablishmentAttempts)
 
 	prometheus.MustRegister(prometheus.CollectorFunc(func(ch chan<- prometheus.Metric) {
@@ -46,6 +66,18 @@ func RegisterMetrics(sessions sessionCounter) {
 		ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(fiveG), "5g")
 
 		ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(fourG), "4g")
+
+		if sessions == nil {
+			return
+		}
+
+		for snssai, n := range sessions.SessionCountsBySlice() {
+			ch <- prometheus.MustNewConstMetric(sliceSessionsDesc, prometheus.GaugeValue, float64(n), strconv.Itoa(int(snssai.Sst)), snssai.Sd)
+		}
+
+		for dnn, n := range sessions.SessionCountsByDNN() {
+			ch <- prometheus.MustNewConstMetric(dataNetworkSessionsDesc, prometheus.GaugeValue, float64(n), dnn)
+		}
 	}))
 }
 
@@ -0,0 +1,15 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package nas
+
+import (
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+// BuildGSMPDUSessionAuthenticationCommand builds a PDU SESSION AUTHENTICATION
+// COMMAND (TS 24.501 §8.3.4) relaying an EAP request of the DN-AAA server. The
+// procedure is network-initiated, so no PTI is assigned (§6.3.1.2).
+func BuildGSMPDUSessionAuthenticationCommand(pduSessionID fgs.PDUSessionID, eap []byte) ([]byte, error) {
+	return (&fgs.PDUSessionAuthenticationCommand{PDUSessionID: pduSessionID, EAP: eap}).MarshalBinary()
+}
@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package nas_test
+
+import (
+	"bytes"
+	"testing"
+
+	smfNas "github.com/ellanetworks/core/internal/smf/nas"
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+func TestBuildGSMPDUSessionAuthenticationCommand_RoundTrip(t *testing.T) {
+	eap := []byte{0x01, 0x07, 0x00, 0x05, 0x01} // EAP-Request/Identity
+
+	encoded, err := smfNas.BuildGSMPDUSessionAuthenticationCommand(3, eap)
+	if err != nil {
+		t.Fatalf("BuildGSMPDUSessionAuthenticationCommand failed: %v", err)
+	}
+
+	m, err := fgs.ParsePDUSessionAuthenticationCommand(encoded)
+	if err != nil {
+		t.Fatalf("ParsePDUSessionAuthenticationCommand failed: %v", err)
+	}
+
+	if m.PDUSessionID != 3 {
+		t.Errorf("PDU session ID = %d, want 3", m.PDUSessionID)
+	}
+
+	if m.PTI != 0 {
+		t.Errorf("PTI = %d, want 0 (network-initiated)", m.PTI)
+	}
+
+	if !bytes.Equal(m.EAP, eap) {
+		t.Errorf("EAP = %x, want %x", m.EAP, eap)
+	}
+}
+
+func TestBuildGSMPDUSessionEstablishmentRejectEAP_RoundTrip(t *testing.T) {
+	eap := []byte{0x04, 0x07, 0x00, 0x04} // EAP-Failure
+
+	encoded, err := smfNas.BuildGSMPDUSessionEstablishmentRejectEAP(3, 2, fgs.GSMCauseUserAuthenticationOrAuthorizationFailed, eap)
+	if err != nil {
+		t.Fatalf("BuildGSMPDUSessionEstablishmentRejectEAP failed: %v", err)
+	}
+
+	m, err := fgs.ParsePDUSessionEstablishmentReject(encoded)
+	if err != nil {
+		t.Fatalf("ParsePDUSessionEstablishmentReject failed: %v", err)
+	}
+
+	if m.PTI != 2 {
+		t.Errorf("PTI = %d, want 2", m.PTI)
+	}
+
+	if m.Cause != fgs.GSMCauseUserAuthenticationOrAuthorizationFailed {
+		t.Errorf("cause = %d, want #29", m.Cause)
+	}
+
+	if !bytes.Equal(m.EAP, eap) {
+		t.Errorf("EAP = %x, want %x", m.EAP, eap)
+	}
+}
@@ -54,6 +54,7 @@ func BuildGSMPDUSessionEstablishmentAccept(
 	addrs *PDUSessionAddresses,
 	alwaysOn *bool,
 	epsBearerIdentity uint8,
+	eap []byte,
 ) ([]byte, error) {
 	pduSessionType := fgs.PDUSessionTypeIPv4
 	if addrs != nil {
@@ -93,6 +94,7 @@ func BuildGSMPDUSessionEstablishmentAccept(
 		QoSFlowDescriptions: fgs.QoSFlowDescriptions{flow},
 		AlwaysOn:            alwaysOn,
 		DNN:                 dnnIE,
+		EAP:                 eap,
 	}
 
 	if addrs != nil {
@@ -4,6 +4,8 @@
 package nas
 
 import (
+	"time"
+
 	"github.com/ellanetworks/core/nas"
 	"github.com/ellanetworks/core/nas/fgs"
 )
@@ -13,3 +15,22 @@ import (
 func BuildGSMPDUSessionEstablishmentReject(pduSessionID fgs.PDUSessionID, pti nas.ProcedureTransactionIdentity, cause fgs.GSMCause) ([]byte, error) {
 	return (&fgs.PDUSessionEstablishmentReject{PDUSessionID: pduSessionID, PTI: pti, Cause: cause}).MarshalBinary()
 }
+
+// BuildGSMPDUSessionEstablishmentRejectEAP builds a PDU SESSION ESTABLISHMENT
+// REJECT carrying the EAP-Failure of a failed secondary authentication (TS
+// 24.501 §6.3.1.3).
+func BuildGSMPDUSessionEstablishmentRejectEAP(pduSessionID fgs.PDUSessionID, pti nas.ProcedureTransactionIdentity, cause fgs.GSMCause, eap []byte) ([]byte, error) {
+	return (&fgs.PDUSessionEstablishmentReject{PDUSessionID: pduSessionID, PTI: pti, Cause: cause, EAP: eap}).MarshalBinary()
+}
+
+// BuildGSMPDUSessionEstablishmentRejectBackoff builds a PDU SESSION
+// ESTABLISHMENT REJECT carrying a back-off timer, which keeps the UE from
+// retrying the slice or DNN for backoff (TS 24.501 §6.4.1.4.2).
+func BuildGSMPDUSessionEstablishmentRejectBackoff(pduSessionID fgs.PDUSessionID, pti nas.ProcedureTransactionIdentity, cause fgs.GSMCause, backoff time.Duration) ([]byte, error) {
+	timer, err := nas.GPRSTimer3FromDuration(backoff)
+	if err != nil {
+		return nil, err
+	}
+
+	return (&fgs.PDUSessionEstablishmentReject{PDUSessionID: pduSessionID, PTI: pti, Cause: cause, BackoffTimer: &timer}).MarshalBinary()
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package nas
+
+import (
+	"fmt"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+const (
+	// DedicatedQosRuleID is the QoS rule steering traffic into a session's
+	// dedicated QoS flow; a session carries at most one.
+	DedicatedQosRuleID uint8 = 2
+
+	// dedicatedQosRulePrecedence puts the dedicated rule ahead of the default
+	// rule, which sits at the lowest precedence (255).
+	dedicatedQosRulePrecedence uint8 = 10
+
+	dedicatedPacketFilterID uint8 = 1
+)
+
+// BuildQoSFlowAddCommand builds a PDU Session Modification Command creating
+// the dedicated GBR QoS flow and the QoS rule steering its traffic into it
+// (TS 24.501 §6.3.2).
+func BuildQoSFlowAddCommand(pduSessionID uint8, flow *models.GBRQosFlow) ([]byte, error) {
+	desc, err := fgs.GBRQoSFlow(flow.QFI, uint8(flow.Var5qi), fgs.QoSFlowOpCreate,
+		flow.GFBRUplink.Kbps(), flow.GFBRDownlink.Kbps(), flow.MFBRUplink.Kbps(), flow.MFBRDownlink.Kbps())
+	if err != nil {
+		return nil, fmt.Errorf("QoS flow description: %w", err)
+	}
+
+	f := flow.Filter
+	filter := fgs.RemotePacketFilter(dedicatedPacketFilterID, fgs.PacketFilterBidirectional, f.RemotePrefix, f.Protocol, f.PortLow, f.PortHigh)
+
+	m := &fgs.PDUSessionModificationCommand{
+		PDUSessionID:        fgs.PDUSessionID(pduSessionID),
+		QoSRules:            fgs.QoSRules{fgs.DedicatedQoSRule(DedicatedQosRuleID, flow.QFI, dedicatedQosRulePrecedence, filter)},
+		QoSFlowDescriptions: fgs.QoSFlowDescriptions{desc},
+	}
+
+	return m.MarshalBinary()
+}
+
+// BuildQoSFlowReleaseCommand builds a PDU Session Modification Command
+// deleting the dedicated QoS flow qfi and its QoS rule.
+func BuildQoSFlowReleaseCommand(pduSessionID uint8, qfi uint8) ([]byte, error) {
+	m := &fgs.PDUSessionModificationCommand{
+		PDUSessionID:        fgs.PDUSessionID(pduSessionID),
+		QoSRules:            fgs.QoSRules{fgs.DeletedQoSRule(DedicatedQosRuleID)},
+		QoSFlowDescriptions: fgs.QoSFlowDescriptions{fgs.DeletedQoSFlow(qfi)},
+	}
+
+	return m.MarshalBinary()
+}
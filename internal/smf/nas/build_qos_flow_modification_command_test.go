@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package nas_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	smfNas "github.com/ellanetworks/core/internal/smf/nas"
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+func TestBuildQoSFlowAddCommand(t *testing.T) {
+	flow := &models.GBRQosFlow{
+		QosData:      models.QosData{QFI: 2, Var5qi: 2, Arp: &models.Arp{PriorityLevel: 3}},
+		GFBRUplink:   models.MustParseBitRate("4 Mbps"),
+		GFBRDownlink: models.MustParseBitRate("1 Mbps"),
+		MFBRUplink:   models.MustParseBitRate("8 Mbps"),
+		MFBRDownlink: models.MustParseBitRate("2 Mbps"),
+		Filter:       models.QosFlowFilter{RemotePrefix: netip.MustParsePrefix("198.51.100.7/32"), Protocol: 17, PortLow: 5004, PortHigh: 5004},
+	}
+
+	encoded, err := smfNas.BuildQoSFlowAddCommand(1, flow)
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	m := decodeModCmd(t, encoded)
+
+	if len(m.QoSRules) != 1 {
+		t.Fatalf("QoS rules = %d, want 1", len(m.QoSRules))
+	}
+
+	rule := m.QoSRules[0]
+	if rule.Identifier != smfNas.DedicatedQosRuleID || rule.OperationCode != fgs.QoSRuleOpCreate || rule.DQR != 0 {
+		t.Errorf("rule = %+v, want a non-default created rule %d", rule, smfNas.DedicatedQosRuleID)
+	}
+
+	if rule.Parameters == nil || rule.Parameters.QFI != 2 || rule.Parameters.Precedence >= 255 {
+		t.Errorf("rule parameters = %+v, want QFI 2 ahead of the default rule", rule.Parameters)
+	}
+
+	if len(rule.Filters) != 1 || rule.Filters[0].Direction != fgs.PacketFilterBidirectional || len(rule.Filters[0].Components) != 3 {
+		t.Errorf("filters = %+v, want one bidirectional filter on address, protocol and port", rule.Filters)
+	}
+
+	if len(m.QoSFlowDescriptions) != 1 || m.QoSFlowDescriptions[0].QFI != 2 || m.QoSFlowDescriptions[0].OperationCode != fgs.QoSFlowOpCreate {
+		t.Fatalf("QoS flow descriptions = %+v, want flow 2 created", m.QoSFlowDescriptions)
+	}
+
+	// 5QI plus the four GBR and MBR rates.
+	if n := len(m.QoSFlowDescriptions[0].Parameters); n != 5 {
+		t.Errorf("QoS flow parameters = %d, want 5", n)
+	}
+
+	if m.SessionAMBR != nil {
+		t.Error("adding a QoS flow resent the session AMBR")
+	}
+}
+
+func TestBuildQoSFlowReleaseCommand(t *testing.T) {
+	encoded, err := smfNas.BuildQoSFlowReleaseCommand(1, 2)
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	m := decodeModCmd(t, encoded)
+
+	if len(m.QoSRules) != 1 || m.QoSRules[0].Identifier != smfNas.DedicatedQosRuleID || m.QoSRules[0].OperationCode != fgs.QoSRuleOpDelete {
+		t.Errorf("QoS rules = %+v, want the dedicated rule deleted", m.QoSRules)
+	}
+
+	if len(m.QoSFlowDescriptions) != 1 || m.QoSFlowDescriptions[0].QFI != 2 || m.QoSFlowDescriptions[0].OperationCode != fgs.QoSFlowOpDelete {
+		t.Errorf("QoS flow descriptions = %+v, want flow 2 deleted", m.QoSFlowDescriptions)
+	}
+}
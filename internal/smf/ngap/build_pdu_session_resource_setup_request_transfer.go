@@ -13,16 +13,23 @@ import (
 	libngap "github.com/ellanetworks/core/ngap"
 )
 
-func BuildPDUSessionResourceSetupRequestTransfer(ambr *models.Ambr, qosData *models.QosData, teid uint32, n3IPv4 netip.Addr, n3IPv6 netip.Addr, pduSessionType libngap.PDUSessionType) ([]byte, error) {
+func BuildPDUSessionResourceSetupRequestTransfer(ambr *models.Ambr, qosData *models.QosData, teid uint32, n3IPv4 netip.Addr, n3IPv6 netip.Addr, pduSessionType libngap.PDUSessionType, dedicated ...models.GBRQosFlow) ([]byte, error) {
 	transfer, err := pduSessionResourceSetupRequestTransfer(ambr, qosData, teid, n3IPv4, n3IPv6, pduSessionType)
 	if err != nil {
 		return nil, err
 	}
 
+	items, err := dedicatedQosFlowSetupRequestItems(dedicated)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.QosFlowSetupRequest = append(transfer.QosFlowSetupRequest, items...)
+
 	return marshalPDUSessionResourceSetupRequestTransfer(transfer)
 }
 
-func BuildHandoverRequestTransfer(ambr *models.Ambr, qosData *models.QosData, teid uint32, n3IPv4 netip.Addr, n3IPv6 netip.Addr, pduSessionType libngap.PDUSessionType, erabID *uint8) ([]byte, error) {
+func BuildHandoverRequestTransfer(ambr *models.Ambr, qosData *models.QosData, teid uint32, n3IPv4 netip.Addr, n3IPv6 netip.Addr, pduSessionType libngap.PDUSessionType, erabID *uint8, dedicated ...models.GBRQosFlow) ([]byte, error) {
 	transfer, err := pduSessionResourceSetupRequestTransfer(ambr, qosData, teid, n3IPv4, n3IPv6, pduSessionType)
 	if err != nil {
 		return nil, err
@@ -40,6 +47,14 @@ func BuildHandoverRequestTransfer(ambr *models.Ambr, qosData *models.QosData, te
 		}
 	}
 
+	// The E-RAB ID names the default bearer only; dedicated flows have none.
+	items, err := dedicatedQosFlowSetupRequestItems(dedicated)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer.QosFlowSetupRequest = append(transfer.QosFlowSetupRequest, items...)
+
 	return marshalPDUSessionResourceSetupRequestTransfer(transfer)
 }
 
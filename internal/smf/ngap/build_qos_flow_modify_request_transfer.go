@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ngap
+
+import (
+	"fmt"
+
+	"github.com/ellanetworks/core/internal/models"
+	libngap "github.com/ellanetworks/core/ngap"
+)
+
+// BuildQosFlowAddRequestTransfer builds the N2 SM Information of a PDU Session
+// Resource Modify Request adding a dedicated GBR QoS flow (TS 38.413).
+func BuildQosFlowAddRequestTransfer(flow *models.GBRQosFlow) ([]byte, error) {
+	params, err := gbrQosFlowLevelQosParameters(flow)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := libngap.PDUSessionResourceModifyRequestTransfer{
+		QosFlowAddOrModifyRequest: libngap.QosFlowAddOrModifyRequestList{{
+			QosFlowIdentifier:         libngap.QosFlowIdentifier(flow.QFI),
+			QosFlowLevelQosParameters: &params,
+		}},
+	}
+
+	buf, err := transfer.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("encode PDU Session Resource Modify Request Transfer: %w", err)
+	}
+
+	return buf, nil
+}
+
+// BuildQosFlowReleaseRequestTransfer builds the N2 SM Information of a PDU
+// Session Resource Modify Request releasing the dedicated QoS flow qfi.
+func BuildQosFlowReleaseRequestTransfer(qfi uint8) ([]byte, error) {
+	transfer := libngap.PDUSessionResourceModifyRequestTransfer{
+		QosFlowToRelease: libngap.QosFlowListWithCause{{
+			QosFlowIdentifier: libngap.QosFlowIdentifier(qfi),
+			Cause:             libngap.Cause{Group: libngap.CauseGroupNAS, Value: libngap.CauseNASNormalRelease},
+		}},
+	}
+
+	buf, err := transfer.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("encode PDU Session Resource Modify Request Transfer: %w", err)
+	}
+
+	return buf, nil
+}
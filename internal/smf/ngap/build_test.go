@@ -390,3 +390,101 @@ func TestBuildHandoverRequestTransferOmitsTheERABIDWithoutABearer(t *testing.T)
 		t.Error("an intra-5GS handover carried an E-RAB ID")
 	}
 }
+
+func testGBRQosFlow() models.GBRQosFlow {
+	return models.GBRQosFlow{
+		QosData:      models.QosData{Var5qi: 2, QFI: 2, Arp: &models.Arp{PriorityLevel: 3}},
+		GFBRUplink:   models.MustParseBitRate("4 Mbps"),
+		GFBRDownlink: models.MustParseBitRate("1 Mbps"),
+		MFBRUplink:   models.MustParseBitRate("8 Mbps"),
+		MFBRDownlink: models.MustParseBitRate("2 Mbps"),
+	}
+}
+
+func TestBuildQosFlowAddRequestTransfer(t *testing.T) {
+	flow := testGBRQosFlow()
+
+	buf, err := ngap.BuildQosFlowAddRequestTransfer(&flow)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	transfer, err := libngap.ParsePDUSessionResourceModifyRequestTransfer(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(transfer.QosFlowAddOrModifyRequest) != 1 {
+		t.Fatalf("got %d QoS flows, want 1", len(transfer.QosFlowAddOrModifyRequest))
+	}
+
+	item := transfer.QosFlowAddOrModifyRequest[0]
+	if item.QosFlowIdentifier != 2 || item.QosFlowLevelQosParameters == nil {
+		t.Fatalf("QFI = %d, parameters = %v, want QFI 2 with parameters", item.QosFlowIdentifier, item.QosFlowLevelQosParameters)
+	}
+
+	gbr := item.QosFlowLevelQosParameters.GBRQosInformation
+	if gbr == nil {
+		t.Fatal("no GBR QoS information on a GBR flow")
+	}
+
+	if gbr.GuaranteedFlowBitRateUL != 4_000_000 || gbr.MaximumFlowBitRateUL != 8_000_000 ||
+		gbr.GuaranteedFlowBitRateDL != 1_000_000 || gbr.MaximumFlowBitRateDL != 2_000_000 {
+		t.Errorf("GBR QoS information = %+v", *gbr)
+	}
+
+	if transfer.PDUSessionAggregateMaximumBitRate != nil {
+		t.Error("adding a GBR flow resent the session AMBR")
+	}
+}
+
+func TestBuildQosFlowReleaseRequestTransfer(t *testing.T) {
+	buf, err := ngap.BuildQosFlowReleaseRequestTransfer(2)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	transfer, err := libngap.ParsePDUSessionResourceModifyRequestTransfer(buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(transfer.QosFlowToRelease) != 1 || transfer.QosFlowToRelease[0].QosFlowIdentifier != 2 {
+		t.Fatalf("QoS flows to release = %+v, want QFI 2", transfer.QosFlowToRelease)
+	}
+
+	if cause := transfer.QosFlowToRelease[0].Cause; cause.Group != libngap.CauseGroupNAS || cause.Value != libngap.CauseNASNormalRelease {
+		t.Errorf("cause = %v, want nas normal-release", cause)
+	}
+}
+
+// A handover re-establishes the dedicated flow beside the default one, and
+// the E-RAB ID of the default bearer stays on the default flow.
+func TestBuildHandoverRequestTransferCarriesDedicatedFlows(t *testing.T) {
+	ambr := &models.Ambr{Uplink: models.MustParseBitRate("1 Mbps"), Downlink: models.MustParseBitRate("2 Mbps")}
+	qos := &models.QosData{Var5qi: 9, QFI: 1, Arp: &models.Arp{PriorityLevel: 1}}
+	ebi := uint8(5)
+
+	buf, err := ngap.BuildHandoverRequestTransfer(ambr, qos, 42, netip.MustParseAddr("1.2.3.4"), netip.Addr{}, libngap.PDUSessionTypeIPv4, &ebi, testGBRQosFlow())
+	if err != nil {
+		t.Fatalf("BuildHandoverRequestTransfer: %v", err)
+	}
+
+	transfer, err := libngap.ParsePDUSessionResourceSetupRequestTransfer(buf)
+	if err != nil {
+		t.Fatalf("parse the transfer: %v", err)
+	}
+
+	flows := transfer.QosFlowSetupRequest
+	if len(flows) != 2 || flows[0].QosFlowIdentifier != 1 || flows[1].QosFlowIdentifier != 2 {
+		t.Fatalf("QoS flows = %+v, want the default flow 1 then the dedicated flow 2", flows)
+	}
+
+	if flows[1].ERABID != nil {
+		t.Error("the dedicated flow carried the default bearer's E-RAB ID")
+	}
+
+	if flows[1].QosFlowLevelQosParameters.GBRQosInformation == nil {
+		t.Error("the dedicated flow lost its GBR QoS information")
+	}
+}
@@ -59,3 +59,41 @@ func sessionAMBR(ambr *models.Ambr) *libngap.PDUSessionAggregateMaximumBitRate {
 		UL: libngap.BitRate(ambr.Uplink.Bps()),
 	}
 }
+
+// gbrQosFlowLevelQosParameters maps a dedicated GBR flow onto the NGAP IE,
+// carrying its guaranteed and maximum flow bit rates (TS 38.413 §9.3.1.10).
+func gbrQosFlowLevelQosParameters(flow *models.GBRQosFlow) (libngap.QosFlowLevelQosParameters, error) {
+	params, err := qosFlowLevelQosParameters(&flow.QosData)
+	if err != nil {
+		return libngap.QosFlowLevelQosParameters{}, err
+	}
+
+	params.GBRQosInformation = &libngap.GBRQosInformation{
+		MaximumFlowBitRateDL:    libngap.BitRate(flow.MFBRDownlink.Bps()),
+		MaximumFlowBitRateUL:    libngap.BitRate(flow.MFBRUplink.Bps()),
+		GuaranteedFlowBitRateDL: libngap.BitRate(flow.GFBRDownlink.Bps()),
+		GuaranteedFlowBitRateUL: libngap.BitRate(flow.GFBRUplink.Bps()),
+	}
+
+	return params, nil
+}
+
+// dedicatedQosFlowSetupRequestItems lists the dedicated flows a setup or
+// handover request re-establishes beside the session's default flow.
+func dedicatedQosFlowSetupRequestItems(dedicated []models.GBRQosFlow) (libngap.QosFlowSetupRequestList, error) {
+	items := make(libngap.QosFlowSetupRequestList, 0, len(dedicated))
+
+	for i := range dedicated {
+		params, err := gbrQosFlowLevelQosParameters(&dedicated[i])
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, libngap.QosFlowSetupRequestItem{
+			QosFlowIdentifier:         libngap.QosFlowIdentifier(dedicated[i].QFI),
+			QosFlowLevelQosParameters: params,
+		})
+	}
+
+	return items, nil
+}
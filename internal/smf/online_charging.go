@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+// OnlineCharging holds sessions to credit granted by an online charging
+// system (TS 32.251 §5.2.2). SessionUsage and SessionStopped are called on
+// signalling paths and must return quickly.
+type OnlineCharging interface {
+	// Authorize asks for the credit a session starts with. An error refuses
+	// the session.
+	Authorize(ctx context.Context, sess ChargingSession) (ChargingGrant, error)
+	SessionUsage(ref string, uplinkBytes, downlinkBytes uint64, groups []models.RatingGroupUsage)
+	SessionStopped(ref string)
+}
+
+// ChargingSession describes a session to OnlineCharging when it starts.
+type ChargingSession struct {
+	Ref        string
+	IMSI       string
+	Dnn        string
+	Snssai     models.Snssai
+	Access     AccessType
+	IPv4       netip.Addr
+	IPv6Prefix netip.Prefix
+	PolicyID   string
+}
+
+// ChargingGrant is what OnlineCharging grants a session when it starts.
+type ChargingGrant struct {
+	// UsageThreshold is the volume after which the user plane reports the
+	// session's usage early; zero keeps periodic reports.
+	UsageThreshold uint64
+}
+
+// WithOnlineCharging charges sessions against the credit oc grants.
+func WithOnlineCharging(oc OnlineCharging) Option { return func(s *SMF) { s.charging = oc } }
+
+// authorizeCharging asks the online charging system for the credit of a
+// session whose user plane is set up, and arms its usage threshold.
+func (s *SMF) authorizeCharging(ctx context.Context, sc *SMContext, seid uint64, req SessionRequest, addrs ueAddresses) error {
+	sess := ChargingSession{
+		Ref:    sc.Ref,
+		IMSI:   req.Supi.IMSI(),
+		Dnn:    req.Dnn,
+		Access: req.Access,
+		IPv4:   addrs.IPv4,
+	}
+
+	if req.Snssai != nil {
+		sess.Snssai = *req.Snssai
+	}
+
+	if addrs.IPv6Prefix.IsValid() {
+		sess.IPv6Prefix = netip.PrefixFrom(addrs.IPv6Prefix, 64)
+	}
+
+	if req.Policy != nil {
+		sess.PolicyID = req.Policy.PolicyID
+	}
+
+	grant, err := s.charging.Authorize(ctx, sess)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errCreditDenied, err)
+	}
+
+	if grant.UsageThreshold != 0 {
+		s.upf.SetUsageThreshold(ctx, seid, grant.UsageThreshold)
+	}
+
+	return nil
+}
+
+// SetUsageThreshold makes the user plane report the usage of session ref
+// once it has carried bytes octets; zero returns it to periodic reports.
+func (s *SMF) SetUsageThreshold(ctx context.Context, ref string, bytes uint64) error {
+	sc := s.GetSession(ref)
+	if sc == nil {
+		return ErrSMContextNotFound
+	}
+
+	sc.Mutex.Lock()
+	pfcp := sc.PFCPContext
+	sc.Mutex.Unlock()
+
+	if pfcp == nil {
+		return fmt.Errorf("session %q has no UPF session", ref)
+	}
+
+	s.upf.SetUsageThreshold(ctx, pfcp.SEID, bytes)
+
+	return nil
+}
+
+// TerminateSession releases session ref on the network's initiative, as
+// when its credit is used up: a 5G session with a regular deactivation, a
+// 4G one by having the MME deactivate its bearer.
+func (s *SMF) TerminateSession(ctx context.Context, ref string) error {
+	sc := s.GetSession(ref)
+	if sc == nil {
+		return ErrSMContextNotFound
+	}
+
+	sc.Mutex.Lock()
+
+	if sc.releasing || sc.Tunnel == nil {
+		sc.Mutex.Unlock()
+		return nil
+	}
+
+	if sc.Access == Access4G {
+		imsi, ebi := sc.Supi.IMSI(), sc.EBI
+		sc.Mutex.Unlock()
+
+		if s.mme == nil {
+			return fmt.Errorf("no MME registered to deactivate EPS session %q", ref)
+		}
+
+		return s.mme.DeactivateSession(ctx, imsi, ebi, ref)
+	}
+
+	defer sc.Mutex.Unlock()
+
+	return s.startRelease(ctx, sc, 0, fgs.GSMCauseRegularDeactivation)
+}
@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+type fakeOnlineCharging struct {
+	mu         sync.Mutex
+	grant      smf.ChargingGrant
+	err        error
+	authorized []smf.ChargingSession
+	groups     []models.RatingGroupUsage
+	stopped    []string
+}
+
+func (f *fakeOnlineCharging) Authorize(_ context.Context, sess smf.ChargingSession) (smf.ChargingGrant, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.authorized = append(f.authorized, sess)
+
+	return f.grant, f.err
+}
+
+func (f *fakeOnlineCharging) SessionUsage(_ string, _, _ uint64, groups []models.RatingGroupUsage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.groups = append(f.groups, groups...)
+}
+
+func (f *fakeOnlineCharging) SessionStopped(ref string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = append(f.stopped, ref)
+}
+
+func TestOnlineCharging_SessionLifetime(t *testing.T) {
+	store, upf := epsTestSMF()
+	oc := &fakeOnlineCharging{grant: smf.ChargingGrant{UsageThreshold: 4000}}
+	mmeCb := &fakeMME{}
+	s := smf.New(&fakePCF{}, store, upf, &fakeAMF{}, smf.WithOnlineCharging(oc))
+	s.SetMME(mmeCb)
+
+	ctx := context.Background()
+
+	bearer, err := s.CreateEPSSession(ctx, epsRequest(1))
+	if err != nil {
+		t.Fatalf("CreateEPSSession: %v", err)
+	}
+
+	if len(oc.authorized) != 1 || oc.authorized[0].Ref != bearer.Ref || oc.authorized[0].IPv4 != bearer.IPv4 {
+		t.Fatalf("authorized %+v, want the bearer's session", oc.authorized)
+	}
+
+	seid := s.GetSession(bearer.Ref).PFCPContext.SEID
+	if upf.thresholds[seid] != 4000 {
+		t.Errorf("usage threshold = %d, want the granted 4000", upf.thresholds[seid])
+	}
+
+	rg := models.RatingGroupUsage{RatingGroup: 7, UplinkVolume: 5, DownlinkVolume: 6}
+	if err := s.HandleUsageReport(ctx, &models.UsageReport{SEID: seid, UplinkVolume: 10, DownlinkVolume: 20, RatingGroups: []models.RatingGroupUsage{rg}}); err != nil {
+		t.Fatalf("HandleUsageReport: %v", err)
+	}
+
+	if len(oc.groups) != 1 || oc.groups[0] != rg {
+		t.Errorf("rating group usage %+v, want the report's", oc.groups)
+	}
+
+	if err := s.TerminateSession(ctx, bearer.Ref); err != nil {
+		t.Fatalf("TerminateSession: %v", err)
+	}
+
+	if len(mmeCb.deactivatedCalls) != 1 || mmeCb.deactivatedCalls[0].ref != bearer.Ref || mmeCb.deactivatedCalls[0].ebi != epsTestEBI {
+		t.Errorf("MME deactivations %+v, want the bearer's", mmeCb.deactivatedCalls)
+	}
+
+	if err := s.ReleaseEPSSession(ctx, bearer.Ref); err != nil {
+		t.Fatalf("ReleaseEPSSession: %v", err)
+	}
+
+	if len(oc.stopped) != 1 || oc.stopped[0] != bearer.Ref {
+		t.Errorf("stopped = %v, want [%s]", oc.stopped, bearer.Ref)
+	}
+
+	if err := s.TerminateSession(ctx, bearer.Ref); !errors.Is(err, smf.ErrSMContextNotFound) {
+		t.Errorf("TerminateSession after release = %v, want ErrSMContextNotFound", err)
+	}
+}
+
+func TestOnlineCharging_CreditDenied(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	oc := &fakeOnlineCharging{err: errors.New("credit limit reached")}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithOnlineCharging(oc))
+
+	_, rsp, err := s.CreateSmContext(context.Background(), testSUPI(), 1, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+	if err == nil {
+		t.Fatal("CreateSmContext accepted a session without credit")
+	}
+
+	reject, err := fgs.ParsePDUSessionEstablishmentReject(rsp)
+	if err != nil {
+		t.Fatalf("decode reject: %v", err)
+	}
+
+	if reject.Cause != fgs.GSMCauseUserAuthenticationOrAuthorizationFailed {
+		t.Errorf("reject cause %d, want #29", reject.Cause)
+	}
+
+	if len(upf.deleteCalls) != 1 {
+		t.Errorf("UPF deletions = %d, want the established session rolled back", len(upf.deleteCalls))
+	}
+
+	if s.GetSessionBySEID(upf.lastEstablish.SEID) != nil {
+		t.Error("the refused session is still indexed")
+	}
+}
@@ -5,11 +5,13 @@ package smf
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/internal/smf/ngap"
+	"github.com/ellanetworks/core/nas/fgs"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -27,7 +29,7 @@ func (s *SMF) HandleDownlinkDataReport(ctx context.Context, report *models.Downl
 	smContext.Mutex.Lock()
 
 	onEPS := smContext.Access == Access4G
-	policy, tunnel := smContext.PolicyData, smContext.Tunnel
+	policy, tunnel, dedicated := smContext.PolicyData, smContext.Tunnel, smContext.dedicatedQoSFlows()
 	pduSessionType, supi, pduSessionID, snssai := smContext.PDUSessionType, smContext.Supi, smContext.PDUSessionID, smContext.Snssai
 
 	smContext.Mutex.Unlock()
@@ -45,7 +47,7 @@ func (s *SMF) HandleDownlinkDataReport(ctx context.Context, report *models.Downl
 		return fmt.Errorf("session for seid %d has no user plane to page for", report.SEID)
 	}
 
-	n2Pdu, err := ngap.BuildPDUSessionResourceSetupRequestTransfer(&policy.Ambr, &policy.QosData, tunnel.N3TEID, tunnel.N3IPv4, tunnel.N3IPv6, nasToNgapPDUSessionType(pduSessionType))
+	n2Pdu, err := ngap.BuildPDUSessionResourceSetupRequestTransfer(&policy.Ambr, &policy.QosData, tunnel.N3TEID, tunnel.N3IPv4, tunnel.N3IPv6, nasToNgapPDUSessionType(pduSessionType), dedicated...)
 	if err != nil {
 		return fmt.Errorf("failed to build PDUSessionResourceSetupRequestTransfer: %v", err)
 	}
@@ -70,6 +72,23 @@ func (s *SMF) HandleUsageReport(ctx context.Context, report *models.UsageReport)
 		return fmt.Errorf("failed to update data volume for imsi %s: %v", smContext.Supi.String(), err)
 	}
 
+	if s.accounting != nil {
+		s.accounting.SessionUsage(smContext.Ref, report.UplinkVolume, report.DownlinkVolume)
+	}
+
+	if s.charging != nil {
+		s.charging.SessionUsage(smContext.Ref, report.UplinkVolume, report.DownlinkVolume, report.RatingGroups)
+	}
+
+	// The totals are stored, so a failure here is not returned: the UPF
+	// would report them again.
+	for _, rg := range report.RatingGroups {
+		if err := s.store.IncrementRatingGroupUsage(ctx, smContext.Supi.IMSI(), rg); err != nil {
+			logger.WithTrace(ctx, logger.SmfLog).Warn("failed to update rating group usage",
+				logger.SUPI(smContext.Supi.String()), zap.Uint32("rating_group", rg.RatingGroup), zap.Error(err))
+		}
+	}
+
 	logger.WithTrace(ctx, logger.SmfLog).Debug(
 		"Processed usage report",
 		logger.SUPI(smContext.Supi.String()),
@@ -80,6 +99,92 @@ func (s *SMF) HandleUsageReport(ctx context.Context, report *models.UsageReport)
 	return nil
 }
 
+// HandleErrorIndicationReport deactivates the user plane of a session whose
+// access node no longer knows its downlink tunnel, typically because the radio
+// restarted (TS 29.281 §7.3.1). The UPF buffers from then on, so the next
+// downlink packet pages the UE or sets up a fresh tunnel instead of being
+// blackholed toward the stale one.
+func (s *SMF) HandleErrorIndicationReport(ctx context.Context, report *models.ErrorIndicationReport) error {
+	ctx, span := tracer.Start(ctx, "smf/handle_error_indication_report")
+	defer span.End()
+
+	smContext := s.GetSessionBySEID(report.SEID)
+	if smContext == nil {
+		return fmt.Errorf("failed to find SMContext for seid %d", report.SEID)
+	}
+
+	smContext.Mutex.Lock()
+
+	ref, access := smContext.Ref, smContext.Access
+	supi, pduSessionID := smContext.Supi, smContext.PDUSessionID
+	stale := smContext.Tunnel == nil || smContext.Tunnel.AN.TEID != report.RemoteTEID
+
+	smContext.Mutex.Unlock()
+
+	// The session moved to another tunnel since the rejected packet was sent.
+	if stale {
+		logger.WithTrace(ctx, logger.SmfLog).Debug("ignoring an Error Indication for a tunnel the session no longer uses",
+			logger.SUPI(supi.String()), logger.PDUSessionID(pduSessionID), zap.Uint32("teid", report.RemoteTEID))
+
+		return nil
+	}
+
+	logger.WithTrace(ctx, logger.SmfLog).Info("access node rejected the downlink tunnel, deactivating the user plane",
+		logger.SUPI(supi.String()), logger.PDUSessionID(pduSessionID),
+		zap.Uint32("teid", report.RemoteTEID), zap.String("peer", report.RemoteAddress.String()))
+
+	return s.deactivateSession(ctx, ref, access)
+}
+
+// HandlePathFailureReport releases the sessions tunnelled to an N3 peer whose
+// GTP-U path failed. 5G sessions go through the network-requested release so
+// the UE re-establishes them. The MME owns EPS bearer teardown, so a 4G
+// session only has its user plane deactivated and the UE is paged on the next
+// downlink packet.
+func (s *SMF) HandlePathFailureReport(ctx context.Context, report *models.PathFailureReport) error {
+	ctx, span := tracer.Start(ctx, "smf/handle_path_failure_report",
+		trace.WithAttributes(attribute.Int("sessions", len(report.SEIDs))),
+	)
+	defer span.End()
+
+	var errs []error
+
+	for _, seid := range report.SEIDs {
+		if err := s.releaseOnPathFailure(ctx, seid); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *SMF) releaseOnPathFailure(ctx context.Context, seid uint64) error {
+	smContext := s.GetSessionBySEID(seid)
+	if smContext == nil {
+		return fmt.Errorf("failed to find SMContext for seid %d", seid)
+	}
+
+	smContext.Mutex.Lock()
+
+	if smContext.Access == Access4G {
+		ref := smContext.Ref
+		smContext.Mutex.Unlock()
+
+		return s.deactivateSession(ctx, ref, Access4G)
+	}
+
+	defer smContext.Mutex.Unlock()
+
+	if smContext.Tunnel == nil || smContext.releasing {
+		return nil
+	}
+
+	logger.WithTrace(ctx, logger.SmfLog).Info("GTP-U path to the access node failed, releasing session",
+		logger.SUPI(smContext.Supi.String()), logger.PDUSessionID(smContext.PDUSessionID))
+
+	return s.startRelease(ctx, smContext, 0, fgs.GSMCauseNetworkFailure)
+}
+
 func (s *SMF) SendFlowReports(ctx context.Context, reqs []*models.FlowReportRequest) error {
 	ctx, span := tracer.Start(ctx, "smf/send_flow_reports",
 		trace.WithAttributes(attribute.Int("batch_size", len(reqs))),
@@ -0,0 +1,363 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"go.uber.org/zap"
+)
+
+// PolicyControl has an external policy decision point set the QoS and rules
+// of sessions (TS 23.502 §4.16.4, §4.16.5). On EPS the MME signals the QCI,
+// ARP and APN-AMBR of the subscription; the decided Session-AMBR and rules
+// are enforced by the UPF, and the decided 5QI and ARP wait for 5GS. SessionMoved and
+// SessionStopped are called on signalling paths and must return quickly.
+type PolicyControl interface {
+	// Decide asks for the policy of a session being established. ok is false
+	// when its data network has no external policy control; an error leaves
+	// the session on its local policy.
+	Decide(ctx context.Context, sess PolicySession) (decision PolicyDecision, ok bool, err error)
+	// SessionMoved reports that a session moved to another access.
+	SessionMoved(ref string, access AccessType)
+	SessionStopped(ref string)
+}
+
+// PolicySession describes a session to PolicyControl when it starts. Policy
+// is the local policy the decision overrides.
+type PolicySession struct {
+	Ref          string
+	IMSI         string
+	PDUSessionID uint8
+	Dnn          string
+	Snssai       models.Snssai
+	Access       AccessType
+	Policy       *Policy
+}
+
+// PolicyDecision is what a policy decision point sets for a session. Zero
+// values keep the local policy's.
+type PolicyDecision struct {
+	Var5qi int32
+	Arp    int32
+	Ambr   *models.Ambr
+	// Filter replaces the subscriber's network rules; nil restores them.
+	Filter *PolicyFilter
+}
+
+// WithPolicyControl has pc decide the policy of sessions on the data networks
+// it controls.
+func WithPolicyControl(pc PolicyControl) Option { return func(s *SMF) { s.policy = pc } }
+
+// apply returns policy with the decision's overrides.
+func (d *PolicyDecision) apply(policy *Policy) *Policy {
+	if d == nil || policy == nil {
+		return policy
+	}
+
+	decided := *policy
+
+	if d.Var5qi != 0 {
+		decided.QosData.Var5qi = d.Var5qi
+	}
+
+	if d.Arp != 0 {
+		arp := models.Arp{PriorityLevel: d.Arp}
+		if policy.QosData.Arp != nil {
+			arp.PreemptCap, arp.PreemptVuln = policy.QosData.Arp.PreemptCap, policy.QosData.Arp.PreemptVuln
+		}
+
+		decided.QosData.Arp = &arp
+	}
+
+	if d.Ambr != nil {
+		decided.Ambr = *d.Ambr
+	}
+
+	if d.Filter != nil {
+		decided.PolicyID = d.Filter.PolicyID
+		decided.NetworkRules = d.Filter.NetworkRules
+	}
+
+	return &decided
+}
+
+// applyDelta puts the decision's QoS and Session-AMBR overrides on a
+// reconciliation target.
+func (d *PolicyDecision) applyDelta(delta *models.SessionPolicyDelta) *models.SessionPolicyDelta {
+	if d == nil || delta == nil {
+		return delta
+	}
+
+	decided := *delta
+
+	if d.Var5qi != 0 {
+		decided.Var5qi = d.Var5qi
+	}
+
+	if d.Arp != 0 {
+		decided.Arp = d.Arp
+	}
+
+	if d.Ambr != nil {
+		decided.SessionAmbrUplink = d.Ambr.Uplink.String()
+		decided.SessionAmbrDownlink = d.Ambr.Downlink.String()
+	}
+
+	return &decided
+}
+
+// decidePolicy asks the policy decision point for the policy of a session
+// being established and returns it with the decision applied. An
+// unreachable decision point leaves the local policy. Caller holds
+// sc.Mutex.
+func (s *SMF) decidePolicy(ctx context.Context, sc *SMContext, req SessionRequest) *Policy {
+	sess := PolicySession{
+		Ref:          sc.Ref,
+		IMSI:         req.Supi.IMSI(),
+		PDUSessionID: req.Identity.PDUSessionID,
+		Dnn:          req.Dnn,
+		Access:       req.Access,
+		Policy:       req.Policy,
+	}
+
+	if req.Snssai != nil {
+		sess.Snssai = *req.Snssai
+	}
+
+	decision, ok, err := s.policy.Decide(ctx, sess)
+	if err != nil {
+		logger.WithTrace(ctx, logger.SmfLog).Warn("policy decision point unavailable; session takes the local policy",
+			logger.SUPI(req.Supi.String()), logger.PDUSessionID(req.Identity.PDUSessionID), zap.String("dnn", req.Dnn), zap.Error(err))
+
+		return req.Policy
+	}
+
+	if !ok {
+		return req.Policy
+	}
+
+	sc.policyDecision = &decision
+
+	if req.Access == Access4G {
+		eps := decision
+		eps.Var5qi, eps.Arp = 0, 0
+
+		return eps.apply(req.Policy)
+	}
+
+	return decision.apply(req.Policy)
+}
+
+// ApplyPolicyDecision puts a new decision of the policy decision point in
+// force on session ref: its rules at once, its QoS and Session-AMBR with a
+// network-requested modification, deferred while the UE is idle. An EPS
+// session takes only its Session-AMBR, at the UPF.
+func (s *SMF) ApplyPolicyDecision(ctx context.Context, ref string, decision PolicyDecision) error {
+	sc := s.GetSession(ref)
+	if sc == nil {
+		return ErrSMContextNotFound
+	}
+
+	sc.Mutex.Lock()
+	supi, snssai, dnn, access := sc.Supi, sc.Snssai, sc.Dnn, sc.Access
+	sc.Mutex.Unlock()
+
+	local, err := s.GetSessionPolicy(ctx, supi, snssai, dnn)
+	if err != nil {
+		return fmt.Errorf("no local policy for session %q: %w", ref, err)
+	}
+
+	if err := s.applyDecidedRules(ctx, sc, &decision, local); err != nil {
+		return err
+	}
+
+	if access == Access4G {
+		return s.applyDecidedEPSAMBR(ctx, sc, &decision, local)
+	}
+
+	return s.ReconcileSmContext(ctx, &models.SessionReconcileRequest{
+		SmContextRef: ref,
+		NewPolicy:    sessionPolicyDelta(local),
+		Reason:       models.ReconcilePolicyChange,
+	})
+}
+
+// applyDecidedEPSAMBR has the UPF enforce the decision's Session-AMBR on EPS
+// session sc, or the local policy's when it sets none.
+func (s *SMF) applyDecidedEPSAMBR(ctx context.Context, sc *SMContext, decision *PolicyDecision, local *Policy) error {
+	ambr := local.Ambr
+	if decision.Ambr != nil {
+		ambr = *decision.Ambr
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	if sc.releasing || sc.Tunnel == nil || sc.PolicyData == nil || sc.Access != Access4G {
+		return nil
+	}
+
+	if sc.PolicyData.Ambr.Uplink.Bps() == ambr.Uplink.Bps() && sc.PolicyData.Ambr.Downlink.Bps() == ambr.Downlink.Bps() {
+		return nil
+	}
+
+	if err := s.setEPSSessionAMBR(ctx, sc, ambr); err != nil {
+		return fmt.Errorf("failed to put the Session-AMBR of session %q in force: %w", sc.Ref, err)
+	}
+
+	return nil
+}
+
+// applyDecidedRules records the decision on sc and moves the session to the
+// network rules it sets, or back to the local policy's.
+func (s *SMF) applyDecidedRules(ctx context.Context, sc *SMContext, decision *PolicyDecision, local *Policy) error {
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	if sc.releasing || sc.Tunnel == nil || sc.PolicyData == nil {
+		return nil
+	}
+
+	sc.policyDecision = decision
+
+	rules := PolicyFilter{PolicyID: local.PolicyID, NetworkRules: local.NetworkRules}
+	if decision.Filter != nil {
+		rules = *decision.Filter
+	}
+
+	if rules.PolicyID == sc.PolicyData.PolicyID {
+		return nil
+	}
+
+	return s.moveRules(ctx, sc, rules)
+}
+
+// moveRules puts the network rules of rules.PolicyID in force on the
+// session. Caller holds sc.Mutex.
+func (s *SMF) moveRules(ctx context.Context, sc *SMContext, rules PolicyFilter) error {
+	// A dedicated QoS flow's filters are rebuilt from the new policy's rules
+	// by whoever installed them; the session keeps pointing at them.
+	switch {
+	case sc.qosFlow == nil:
+		if err := s.applyDataPlane(ctx, sc, sc.Tunnel.dataPlane, rules.PolicyID); err != nil {
+			return fmt.Errorf("failed to move session %q to the rules of policy %q: %w", sc.Ref, rules.PolicyID, err)
+		}
+	case s.qosFlows != nil:
+		s.qosFlows.QoSFlowPolicyChanged(sc.Ref, rules.PolicyID)
+	}
+
+	next := *sc.PolicyData
+	next.PolicyID, next.NetworkRules = rules.PolicyID, rules.NetworkRules
+	sc.PolicyData = &next
+
+	if sc.pendingPolicy != nil {
+		pending := *sc.pendingPolicy
+		pending.PolicyID, pending.NetworkRules = rules.PolicyID, rules.NetworkRules
+		sc.pendingPolicy = &pending
+	}
+
+	return nil
+}
+
+// followLocalRules moves a session on its local policy's network rules onto
+// those of rulesPolicyID, the policy whose rules apply where the UE is now
+// served. Rules a DN-AAA server or the policy decision point set stay.
+func (s *SMF) followLocalRules(ctx context.Context, sc *SMContext, rulesPolicyID string) error {
+	sc.Mutex.Lock()
+	follow := sc.followsLocalRules(rulesPolicyID)
+	supi, snssai, dnn, access := sc.Supi, sc.Snssai, sc.Dnn, sc.Access
+	sc.Mutex.Unlock()
+
+	if !follow {
+		return nil
+	}
+
+	rules := PolicyFilter{PolicyID: rulesPolicyID}
+
+	// An EPS session carries no resolved rules; the UPF enforces them by
+	// policy.
+	if access != Access4G {
+		local, err := s.GetSessionPolicy(ctx, supi, snssai, dnn)
+		if err != nil {
+			return fmt.Errorf("no local policy for session %q: %w", sc.Ref, err)
+		}
+
+		// The UE has moved on since; its next reconciliation follows it.
+		if local.PolicyID != rulesPolicyID {
+			return nil
+		}
+
+		rules.NetworkRules = local.NetworkRules
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	if !sc.followsLocalRules(rulesPolicyID) {
+		return nil
+	}
+
+	return s.moveRules(ctx, sc, rules)
+}
+
+// followsLocalRules reports whether the session takes its local policy's
+// network rules and is not yet on those of rulesPolicyID. Caller holds
+// Mutex.
+func (sc *SMContext) followsLocalRules(rulesPolicyID string) bool {
+	switch {
+	case rulesPolicyID == "" || sc.releasing || sc.Tunnel == nil || sc.PolicyData == nil:
+		return false
+	case sc.authorizedRules:
+		return false
+	case sc.policyDecision != nil && sc.policyDecision.Filter != nil:
+		return false
+	}
+
+	return sc.PolicyData.PolicyID != rulesPolicyID
+}
+
+// sessionPolicyDelta is the reconciliation target of a local policy.
+func sessionPolicyDelta(policy *Policy) *models.SessionPolicyDelta {
+	delta := &models.SessionPolicyDelta{
+		SessionAmbrUplink:   policy.Ambr.Uplink.String(),
+		SessionAmbrDownlink: policy.Ambr.Downlink.String(),
+		Var5qi:              policy.QosData.Var5qi,
+		MTU:                 policy.MTU,
+		IPv4Pool:            policy.IPv4Pool,
+		IPv6Pool:            policy.IPv6Pool,
+		RulesPolicyID:       policy.PolicyID,
+	}
+
+	if policy.DNS != nil {
+		delta.DNS = policy.DNS.String()
+	}
+
+	if policy.QosData.Arp != nil {
+		delta.Arp = policy.QosData.Arp.PriorityLevel
+		delta.PreemptCap = policy.QosData.Arp.PreemptCap
+		delta.PreemptVuln = policy.QosData.Arp.PreemptVuln
+	}
+
+	return delta
+}
+
+// policyMoved reports a session's move to another access to the policy
+// decision point.
+func (s *SMF) policyMoved(sc *SMContext) {
+	if s.policy == nil {
+		return
+	}
+
+	sc.Mutex.Lock()
+	access, controlled := sc.Access, sc.policyDecision != nil
+	sc.Mutex.Unlock()
+
+	if controlled {
+		s.policy.SessionMoved(sc.Ref, access)
+	}
+}
@@ -0,0 +1,346 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+type fakePolicyControl struct {
+	mu       sync.Mutex
+	decision smf.PolicyDecision
+	ok       bool
+	err      error
+	decided  []smf.PolicySession
+	moved    []smf.AccessType
+	stopped  []string
+}
+
+func (f *fakePolicyControl) Decide(_ context.Context, sess smf.PolicySession) (smf.PolicyDecision, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.decided = append(f.decided, sess)
+
+	return f.decision, f.ok, f.err
+}
+
+func (f *fakePolicyControl) SessionMoved(_ string, access smf.AccessType) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.moved = append(f.moved, access)
+}
+
+func (f *fakePolicyControl) SessionStopped(ref string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = append(f.stopped, ref)
+}
+
+func TestPolicyControl_DecisionAtEstablishment(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	pc := &fakePolicyControl{
+		ok: true,
+		decision: smf.PolicyDecision{
+			Var5qi: 7,
+			Ambr:   &models.Ambr{Uplink: models.MustParseBitRate("10 Mbps"), Downlink: models.MustParseBitRate("20 Mbps")},
+			Filter: &smf.PolicyFilter{PolicyID: "work-order-42"},
+		},
+	}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithPolicyControl(pc))
+
+	ref, _, err := s.CreateSmContext(context.Background(), testSUPI(), 1, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+	if err != nil {
+		t.Fatalf("CreateSmContext: %v", err)
+	}
+
+	if len(pc.decided) != 1 || pc.decided[0].Ref != ref || pc.decided[0].Policy.QosData.Var5qi != 9 {
+		t.Fatalf("decided %+v, want the session with its local policy", pc.decided)
+	}
+
+	if upf.lastEstablish.PolicyID != "work-order-42" {
+		t.Errorf("UPF session policy = %q, want the decided rules", upf.lastEstablish.PolicyID)
+	}
+
+	sc := s.GetSession(ref)
+
+	sc.Mutex.Lock()
+	policy := sc.PolicyData
+	sc.Mutex.Unlock()
+
+	if policy.QosData.Var5qi != 7 || policy.QosData.Arp.PriorityLevel != 1 || policy.Ambr.Downlink != models.MustParseBitRate("20 Mbps") {
+		t.Errorf("session policy %+v, want the decided 5QI and Session-AMBR over the local ARP", policy)
+	}
+
+	if err := s.ReleaseSmContext(context.Background(), ref); err != nil {
+		t.Fatalf("ReleaseSmContext: %v", err)
+	}
+
+	if len(pc.stopped) != 1 || pc.stopped[0] != ref {
+		t.Errorf("stopped = %v, want [%s]", pc.stopped, ref)
+	}
+}
+
+func TestPolicyControl_UnreachableFallsBackToLocalPolicy(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	pcf.policy.PolicyID = "local"
+	pc := &fakePolicyControl{err: errors.New("connection refused")}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithPolicyControl(pc))
+
+	ref, _, err := s.CreateSmContext(context.Background(), testSUPI(), 1, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+	if err != nil {
+		t.Fatalf("CreateSmContext with the decision point down: %v", err)
+	}
+
+	sc := s.GetSession(ref)
+
+	sc.Mutex.Lock()
+	policy := sc.PolicyData
+	sc.Mutex.Unlock()
+
+	if policy.PolicyID != "local" || policy.QosData.Var5qi != 9 {
+		t.Errorf("session policy %+v, want the local policy", policy)
+	}
+}
+
+func TestPolicyControl_ApplyDecisionSurvivesReconciliation(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	local := &smf.Policy{
+		PolicyID: "local",
+		Ambr:     models.Ambr{Uplink: models.MustParseBitRate("100 Mbps"), Downlink: models.MustParseBitRate("200 Mbps")},
+		QosData:  models.QosData{Var5qi: 9, Arp: &models.Arp{PriorityLevel: 1}, QFI: 1},
+	}
+	pcf.policy = local
+	s := smf.New(pcf, store, upf, amfCb, smf.WithPolicyControl(&fakePolicyControl{}))
+
+	smCtx, ref := setupSessionWithTunnel(t, s)
+
+	smCtx.Mutex.Lock()
+	smCtx.PolicyData.PolicyID = "local"
+	smCtx.Mutex.Unlock()
+
+	ctx := context.Background()
+	decision := smf.PolicyDecision{
+		Ambr:   &models.Ambr{Uplink: models.MustParseBitRate("500 Mbps"), Downlink: models.MustParseBitRate("600 Mbps")},
+		Filter: &smf.PolicyFilter{PolicyID: "work-order-42"},
+	}
+
+	if err := s.ApplyPolicyDecision(ctx, ref, decision); err != nil {
+		t.Fatalf("ApplyPolicyDecision: %v", err)
+	}
+
+	upf.mu.Lock()
+	rulesMoved := len(upf.modifyCalls) > 0 && upf.modifyCalls[0].PolicyID == "work-order-42"
+	upf.mu.Unlock()
+
+	if !rulesMoved {
+		t.Error("the UPF session was not moved to the decided rules")
+	}
+
+	if got := modifyCallCount(amfCb); got != 1 {
+		t.Fatalf("modification commands = %d, want 1 for the decided Session-AMBR", got)
+	}
+
+	if _, err := s.UpdateSmContextN1Msg(ctx, ref, buildPDUSessionModificationComplete(smCtx.PDUSessionID, 0)); err != nil {
+		t.Fatalf("modification complete: %v", err)
+	}
+
+	// The reconciler's sweep against the local policy keeps the decision.
+	if err := s.ReconcileSmContext(ctx, &models.SessionReconcileRequest{
+		SmContextRef: ref,
+		Reason:       models.ReconcilePolicyChange,
+		NewPolicy: &models.SessionPolicyDelta{
+			SessionAmbrUplink:   "100 Mbps",
+			SessionAmbrDownlink: "200 Mbps",
+			Var5qi:              9,
+			Arp:                 1,
+		},
+	}); err != nil {
+		t.Fatalf("ReconcileSmContext: %v", err)
+	}
+
+	if got := modifyCallCount(amfCb); got != 1 {
+		t.Errorf("modification commands = %d, want the reconciliation to keep the decision", got)
+	}
+
+	smCtx.Mutex.Lock()
+	policy := smCtx.PolicyData
+	smCtx.Mutex.Unlock()
+
+	if policy.PolicyID != "work-order-42" || policy.Ambr.Downlink != models.MustParseBitRate("600 Mbps") {
+		t.Errorf("session policy %+v, want the decided rules and Session-AMBR", policy)
+	}
+
+	// A decision without rules restores the subscriber's.
+	if err := s.ApplyPolicyDecision(ctx, ref, smf.PolicyDecision{Ambr: decision.Ambr}); err != nil {
+		t.Fatalf("ApplyPolicyDecision: %v", err)
+	}
+
+	smCtx.Mutex.Lock()
+	policyID := smCtx.PolicyData.PolicyID
+	smCtx.Mutex.Unlock()
+
+	if policyID != "local" {
+		t.Errorf("session rules = %q, want the local policy's back", policyID)
+	}
+
+	if err := s.ApplyPolicyDecision(ctx, "unknown", decision); !errors.Is(err, smf.ErrSMContextNotFound) {
+		t.Errorf("ApplyPolicyDecision on an unknown session = %v, want ErrSMContextNotFound", err)
+	}
+}
+
+func TestPolicyControl_EPSSessionTakesDecidedAMBRAndRules(t *testing.T) {
+	pcf, _, _, amfCb := defaultFakes()
+	store, upf := epsTestSMF()
+	pc := &fakePolicyControl{
+		ok: true,
+		decision: smf.PolicyDecision{
+			Var5qi: 7,
+			Ambr:   &models.Ambr{Uplink: models.MustParseBitRate("10 Mbps"), Downlink: models.MustParseBitRate("20 Mbps")},
+			Filter: &smf.PolicyFilter{PolicyID: "work-order-42"},
+		},
+	}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithPolicyControl(pc))
+	ctx := context.Background()
+
+	bearer, err := s.CreateEPSSession(ctx, epsRequest(1))
+	if err != nil {
+		t.Fatalf("CreateEPSSession: %v", err)
+	}
+
+	if len(pc.decided) != 1 || pc.decided[0].Access != smf.Access4G {
+		t.Fatalf("decided %+v, want the EPS session", pc.decided)
+	}
+
+	if upf.lastEstablish.PolicyID != "work-order-42" {
+		t.Errorf("UPF session policy = %q, want the decided rules", upf.lastEstablish.PolicyID)
+	}
+
+	sessionPolicy := func() *smf.Policy {
+		sc := s.GetSession(bearer.Ref)
+
+		sc.Mutex.Lock()
+		defer sc.Mutex.Unlock()
+
+		return sc.PolicyData
+	}
+
+	// The MME signals the bearer's QCI; the decided 5QI waits for 5GS.
+	if policy := sessionPolicy(); policy.Ambr.Downlink != models.MustParseBitRate("20 Mbps") || policy.QosData.Var5qi != 0 {
+		t.Errorf("session policy %+v, want the decided Session-AMBR and no 5QI", policy)
+	}
+
+	// A subscription change on the MME keeps the decided Session-AMBR.
+	if err := s.UpdateEPSSessionAMBR(ctx, bearer.Ref, models.MustParseBitRate("1 Gbps"), models.MustParseBitRate("1 Gbps")); err != nil {
+		t.Fatalf("UpdateEPSSessionAMBR: %v", err)
+	}
+
+	if policy := sessionPolicy(); policy.Ambr.Downlink != models.MustParseBitRate("20 Mbps") {
+		t.Errorf("Session-AMBR %+v, want the decided one kept", policy.Ambr)
+	}
+
+	if err := s.ApplyPolicyDecision(ctx, bearer.Ref, smf.PolicyDecision{
+		Ambr: &models.Ambr{Uplink: models.MustParseBitRate("30 Mbps"), Downlink: models.MustParseBitRate("40 Mbps")},
+	}); err != nil {
+		t.Fatalf("ApplyPolicyDecision: %v", err)
+	}
+
+	if policy := sessionPolicy(); policy.Ambr.Downlink != models.MustParseBitRate("40 Mbps") {
+		t.Errorf("Session-AMBR %+v, want the new decision's", policy.Ambr)
+	}
+
+	if got := modifyCallCount(amfCb); got != 0 {
+		t.Errorf("modification commands = %d, want none for an EPS session", got)
+	}
+}
+
+func TestPolicyControl_SessionFollowsLocationVariantRules(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	local := &smf.Policy{
+		PolicyID: "local",
+		Ambr:     models.Ambr{Uplink: models.MustParseBitRate("100 Mbps"), Downlink: models.MustParseBitRate("200 Mbps")},
+		QosData:  models.QosData{Var5qi: 9, Arp: &models.Arp{PriorityLevel: 1}, QFI: 1},
+	}
+	pcf.policy = local
+	s := smf.New(pcf, store, upf, amfCb, smf.WithPolicyControl(&fakePolicyControl{}))
+
+	smCtx, ref := setupSessionWithTunnel(t, s)
+
+	smCtx.Mutex.Lock()
+	smCtx.PolicyData.PolicyID = "local"
+	smCtx.Mutex.Unlock()
+
+	ctx := context.Background()
+	reconcile := func(rulesPolicyID string) {
+		t.Helper()
+
+		if err := s.ReconcileSmContext(ctx, &models.SessionReconcileRequest{
+			SmContextRef: ref,
+			Reason:       models.ReconcilePolicyChange,
+			NewPolicy: &models.SessionPolicyDelta{
+				SessionAmbrUplink:   "100 Mbps",
+				SessionAmbrDownlink: "200 Mbps",
+				Var5qi:              9,
+				Arp:                 1,
+				RulesPolicyID:       rulesPolicyID,
+			},
+		}); err != nil {
+			t.Fatalf("ReconcileSmContext: %v", err)
+		}
+	}
+
+	rulesOf := func() string {
+		smCtx.Mutex.Lock()
+		defer smCtx.Mutex.Unlock()
+
+		return smCtx.PolicyData.PolicyID
+	}
+
+	// The UE moves into the area of a variant naming another policy's rules.
+	inArea := *local
+	inArea.PolicyID = "site-rules"
+	pcf.policy = &inArea
+
+	reconcile("site-rules")
+
+	upf.mu.Lock()
+	rulesMoved := len(upf.modifyCalls) == 1 && upf.modifyCalls[0].PolicyID == "site-rules"
+	upf.mu.Unlock()
+
+	if !rulesMoved {
+		t.Error("the UPF session was not moved to the variant's rules")
+	}
+
+	if got := rulesOf(); got != "site-rules" {
+		t.Errorf("session rules = %q, want the variant's", got)
+	}
+
+	if got := modifyCallCount(amfCb); got != 0 {
+		t.Errorf("modification commands = %d, want the rules to change without the UE", got)
+	}
+
+	// Rules the policy decision point set stay wherever the UE goes.
+	pcf.policy = local
+
+	if err := s.ApplyPolicyDecision(ctx, ref, smf.PolicyDecision{Filter: &smf.PolicyFilter{PolicyID: "work-order-42"}}); err != nil {
+		t.Fatalf("ApplyPolicyDecision: %v", err)
+	}
+
+	pcf.policy = &inArea
+
+	reconcile("site-rules")
+
+	if got := rulesOf(); got != "work-order-42" {
+		t.Errorf("session rules = %q, want the decided rules to stay", got)
+	}
+}
@@ -0,0 +1,433 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf/nas"
+	"github.com/ellanetworks/core/internal/smf/ngap"
+	"go.uber.org/zap"
+)
+
+// ErrQoSFlowExists indicates that the session already carries a dedicated QoS
+// flow; a session carries at most one.
+var ErrQoSFlowExists = errors.New("session already has a dedicated QoS flow")
+
+// ErrProcedureInFlight indicates that a network-requested procedure is
+// outstanding on the session, so another cannot start.
+var ErrProcedureInFlight = errors.New("a network-requested procedure is in flight")
+
+// QoSFlowEvents is told how the dedicated QoS flows of sessions fare. A
+// session carries at most one, so the session's Ref names it. Its methods
+// are called on signalling paths and must return quickly.
+type QoSFlowEvents interface {
+	QoSFlowEstablished(ref string)
+	QoSFlowFailed(ref string, reason string)
+	// QoSFlowReleased reports a flow the network dropped, as when the
+	// session moves between EPS and 5GS.
+	QoSFlowReleased(ref string)
+	// QoSFlowPolicyChanged reports that the session moved to the network
+	// rules of policy policyID, which the flow's filters must follow.
+	QoSFlowPolicyChanged(ref string, policyID string)
+	SessionStopped(ref string)
+}
+
+// WithQoSFlowEvents reports the outcome of dedicated QoS flows to ev.
+func WithQoSFlowEvents(ev QoSFlowEvents) Option { return func(s *SMF) { s.qosFlows = ev } }
+
+// dedicatedQoSFlow is the dedicated GBR QoS flow of a session. The UPF
+// enforces it with the filters installed under filterID, which carry the
+// flow's rule ahead of the session's network rules. On EPS the flow rides a
+// dedicated bearer the MME activates.
+type dedicatedQoSFlow struct {
+	flow     models.GBRQosFlow
+	filterID string
+	// pending is set while the command adding the flow awaits the UE.
+	pending bool
+}
+
+// QoSFlowSession finds the session a dedicated QoS flow for a UE would be
+// added to: the session of IMSI imsi, or when imsi is empty the one UE
+// address ueIP belongs to. A UE with several sessions yields the one with
+// the lowest PDU session ID. policyID names the policy whose network rules
+// the session has.
+func (s *SMF) QoSFlowSession(imsi string, ueIP netip.Addr) (ref string, policyID string, ok bool) {
+	s.mu.RLock()
+	sessions := make([]*SMContext, 0, len(s.pool))
+
+	for _, sc := range s.pool {
+		sessions = append(sessions, sc)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(sessions, func(a, b *SMContext) int { return int(a.PDUSessionID) - int(b.PDUSessionID) })
+
+	for _, sc := range sessions {
+		sc.Mutex.Lock()
+		match := !sc.releasing && sc.PolicyData != nil && sc.servesUE(imsi, ueIP)
+		ref, policyID = sc.Ref, ""
+
+		if match {
+			policyID = sc.PolicyData.PolicyID
+		}
+		sc.Mutex.Unlock()
+
+		if match {
+			return ref, policyID, true
+		}
+	}
+
+	return "", "", false
+}
+
+// servesUE reports whether the session is of IMSI imsi, or when imsi is
+// empty whether UE address ueIP is its own. Caller holds sc.Mutex.
+func (sc *SMContext) servesUE(imsi string, ueIP netip.Addr) bool {
+	if imsi != "" {
+		return sc.Supi.IsIMSI() && sc.Supi.IMSI() == imsi
+	}
+
+	if ueIP.Is4() {
+		addr, ok := netip.AddrFromSlice(sc.PDUIPV4Address)
+		return ok && addr.Unmap() == ueIP
+	}
+
+	prefix, ok := netip.AddrFromSlice(sc.PDUIPV6Prefix)
+
+	return ok && ueIP.Is6() && netip.PrefixFrom(prefix, 64).Contains(ueIP)
+}
+
+// AddQoSFlow adds a dedicated GBR QoS flow to session ref with a
+// network-requested PDU Session Modification (TS 23.502 §4.3.3.2): the UPF
+// moves the session to the filters installed under filterID at once, and
+// the flow is established when the UE completes the modification. The
+// outcome is reported to QoSFlowEvents. The flow takes QFI
+// models.DedicatedQFI, and the ARP of the session's default flow unless flow
+// sets its own. A session on EPS gets a dedicated bearer instead.
+func (s *SMF) AddQoSFlow(ctx context.Context, ref string, flow models.GBRQosFlow, filterID string) error {
+	sc := s.GetSession(ref)
+	if sc == nil {
+		return ErrSMContextNotFound
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	switch {
+	case sc.releasing || sc.Tunnel == nil || sc.PolicyData == nil:
+		return ErrSMContextNotFound
+	case sc.qosFlow != nil:
+		return ErrQoSFlowExists
+	case sc.procedureTimer.Active():
+		return ErrProcedureInFlight
+	case !sc.upConnectionActive():
+		return ErrUENotReachable
+	}
+
+	flow.QFI = models.DedicatedQFI
+	if flow.Arp == nil {
+		flow.Arp = sc.PolicyData.QosData.Arp
+	}
+
+	if sc.Access == Access4G {
+		return s.addDedicatedBearer(ctx, sc, flow, filterID)
+	}
+
+	n1Msg, err := nas.BuildQoSFlowAddCommand(sc.PDUSessionID, &flow)
+	if err != nil {
+		return fmt.Errorf("build PDU Session Modification Command (N1): %w", err)
+	}
+
+	n2Msg, err := ngap.BuildQosFlowAddRequestTransfer(&flow)
+	if err != nil {
+		return fmt.Errorf("build PDU Session Resource Modify Request Transfer (N2): %w", err)
+	}
+
+	if err := s.applyDataPlane(ctx, sc, sc.Tunnel.dataPlane, filterID); err != nil {
+		return fmt.Errorf("failed to move session %q to the filters of its QoS flow: %w", ref, err)
+	}
+
+	if err := s.amf.ModifyN1N2(ctx, sc.Supi, sc.PDUSessionID, n1Msg, n2Msg); err != nil {
+		s.restoreSessionFilters(ctx, sc)
+
+		return fmt.Errorf("transfer N1N2 message: %w", err)
+	}
+
+	sc.qosFlow = &dedicatedQoSFlow{flow: flow, filterID: filterID, pending: true}
+
+	sc.MarkPTIInUse(0)
+
+	supi, pduSessionID := sc.Supi, sc.PDUSessionID
+	s.armRetransmit(sc, s.t3591,
+		func() error { return s.amf.ModifyN1N2(context.Background(), supi, pduSessionID, n1Msg, n2Msg) },
+		func(sc *SMContext) {
+			sc.ClearPTIInUse(0)
+			s.qosFlowRejected(context.Background(), sc, "the UE did not answer the modification")
+		})
+
+	logger.WithTrace(ctx, logger.SmfLog).Info("dedicated QoS flow requested",
+		logger.SUPI(supi.String()), logger.PDUSessionID(pduSessionID),
+		zap.Uint8("qfi", flow.QFI), zap.Int32("5qi", flow.Var5qi))
+
+	return nil
+}
+
+// ReleaseQoSFlow removes the dedicated QoS flow of session ref: the UPF moves
+// the session back to its own filters at once and the UE and gNB are told
+// with a network-requested PDU Session Modification. A flow still being
+// added is abandoned. An idle UE keeps the deleted QoS rule until its next
+// modification; the network no longer treats the flow's traffic apart.
+func (s *SMF) ReleaseQoSFlow(ctx context.Context, ref string) error {
+	sc := s.GetSession(ref)
+	if sc == nil {
+		return ErrSMContextNotFound
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	released := sc.qosFlow
+	if released == nil || sc.releasing || sc.Tunnel == nil {
+		return nil
+	}
+
+	sc.qosFlow = nil
+
+	next := sc.Tunnel.dataPlane
+	next.Dedicated = AnchorBinding{}
+
+	if err := s.applyDataPlane(ctx, sc, next, sc.policyID()); err != nil {
+		sc.qosFlow = released
+
+		return fmt.Errorf("failed to move session %q back to its own filters: %w", ref, err)
+	}
+
+	if sc.Access == Access4G {
+		return s.releaseDedicatedBearer(ctx, sc)
+	}
+
+	if !sc.upConnectionActive() {
+		return nil
+	}
+
+	// The release supersedes whatever modification is outstanding; a policy
+	// change it carried is re-attempted by the backstop (TS 24.501 §6.3.2.5).
+	if sc.procedureTimer.Active() {
+		sc.stopProcedureTimer()
+		sc.ClearPTIInUse(0)
+		sc.pendingPolicy = nil
+	}
+
+	n1Msg, err := nas.BuildQoSFlowReleaseCommand(sc.PDUSessionID, released.flow.QFI)
+	if err != nil {
+		return fmt.Errorf("build PDU Session Modification Command (N1): %w", err)
+	}
+
+	n2Msg, err := ngap.BuildQosFlowReleaseRequestTransfer(released.flow.QFI)
+	if err != nil {
+		return fmt.Errorf("build PDU Session Resource Modify Request Transfer (N2): %w", err)
+	}
+
+	if err := s.amf.ModifyN1N2(ctx, sc.Supi, sc.PDUSessionID, n1Msg, n2Msg); err != nil {
+		if errors.Is(err, ErrUENotReachable) {
+			return nil
+		}
+
+		return fmt.Errorf("transfer N1N2 message: %w", err)
+	}
+
+	sc.MarkPTIInUse(0)
+
+	supi, pduSessionID := sc.Supi, sc.PDUSessionID
+	s.armRetransmit(sc, s.t3591,
+		func() error { return s.amf.ModifyN1N2(context.Background(), supi, pduSessionID, n1Msg, n2Msg) },
+		func(sc *SMContext) { sc.ClearPTIInUse(0) })
+
+	logger.WithTrace(ctx, logger.SmfLog).Info("dedicated QoS flow released",
+		logger.SUPI(supi.String()), logger.PDUSessionID(pduSessionID), zap.Uint8("qfi", released.flow.QFI))
+
+	return nil
+}
+
+// qosFlowAccepted establishes a flow being added once the UE completes the
+// modification. Caller holds sc.Mutex.
+func (s *SMF) qosFlowAccepted(sc *SMContext) {
+	if sc.qosFlow == nil || !sc.qosFlow.pending {
+		return
+	}
+
+	sc.qosFlow.pending = false
+
+	if s.qosFlows != nil {
+		s.qosFlows.QoSFlowEstablished(sc.Ref)
+	}
+}
+
+// qosFlowRejected abandons a flow being added and moves the session back to
+// its own filters. Caller holds sc.Mutex.
+func (s *SMF) qosFlowRejected(ctx context.Context, sc *SMContext, reason string) {
+	if sc.qosFlow == nil || !sc.qosFlow.pending {
+		return
+	}
+
+	sc.qosFlow = nil
+
+	s.restoreSessionFilters(ctx, sc)
+
+	logger.WithTrace(ctx, logger.SmfLog).Warn("dedicated QoS flow not established",
+		logger.SUPI(sc.Supi.String()), logger.PDUSessionID(sc.PDUSessionID), zap.String("reason", reason))
+
+	if s.qosFlows != nil {
+		s.qosFlows.QoSFlowFailed(sc.Ref, reason)
+	}
+}
+
+// restoreSessionFilters moves the session back to the filters of its policy
+// and off any dedicated bearer. Caller holds sc.Mutex.
+func (s *SMF) restoreSessionFilters(ctx context.Context, sc *SMContext) {
+	if sc.Tunnel == nil || sc.releasing {
+		return
+	}
+
+	next := sc.Tunnel.dataPlane
+	next.Dedicated = AnchorBinding{}
+
+	if err := s.applyDataPlane(ctx, sc, next, sc.policyID()); err != nil {
+		logger.WithTrace(ctx, logger.SmfLog).Warn("failed to move a session back to its own filters",
+			zap.Error(err), logger.SUPI(sc.Supi.String()), logger.PDUSessionID(sc.PDUSessionID))
+	}
+}
+
+// qosFlowDropped reports the flow a session lost moving between EPS and 5GS.
+func (s *SMF) qosFlowDropped(ref string, dropped *droppedSource) {
+	if dropped == nil || !dropped.qosFlow || s.qosFlows == nil {
+		return
+	}
+
+	s.qosFlows.QoSFlowReleased(ref)
+}
+
+// dedicatedQoSFlows lists the dedicated flows a setup or handover request
+// re-establishes beside the default flow. Caller holds sc.Mutex.
+func (sc *SMContext) dedicatedQoSFlows() []models.GBRQosFlow {
+	if sc.qosFlow == nil || sc.Access == Access4G {
+		return nil
+	}
+
+	return []models.GBRQosFlow{sc.qosFlow.flow}
+}
+
+// addDedicatedBearer adds the dedicated QoS flow of an EPS session on a
+// dedicated bearer (TS 23.401 §5.4.1): the UPF moves the session to the
+// filters installed under filterID at once, and the MME activates the
+// bearer. The flow is established when the MME reports the bearer up.
+// Caller holds sc.Mutex.
+func (s *SMF) addDedicatedBearer(ctx context.Context, sc *SMContext, flow models.GBRQosFlow, filterID string) error {
+	if s.mme == nil {
+		return fmt.Errorf("no MME registered to add a dedicated bearer to EPS session %q", sc.Ref)
+	}
+
+	if err := s.applyDataPlane(ctx, sc, sc.Tunnel.dataPlane, filterID); err != nil {
+		return fmt.Errorf("failed to move session %q to the filters of its QoS flow: %w", sc.Ref, err)
+	}
+
+	if err := s.mme.ActivateDedicatedBearer(ctx, sc.Supi.IMSI(), sc.EBI, sc.Ref, flow); err != nil {
+		s.restoreSessionFilters(ctx, sc)
+
+		return fmt.Errorf("activate dedicated EPS bearer: %w", err)
+	}
+
+	sc.qosFlow = &dedicatedQoSFlow{flow: flow, filterID: filterID, pending: true}
+
+	logger.WithTrace(ctx, logger.SmfLog).Info("dedicated EPS bearer requested",
+		logger.SUPI(sc.Supi.String()), zap.Uint8("ebi", sc.EBI), zap.Int32("5qi", flow.Var5qi))
+
+	return nil
+}
+
+// releaseDedicatedBearer asks the MME to deactivate the dedicated bearer of
+// an EPS session whose flow was released. Caller holds sc.Mutex.
+func (s *SMF) releaseDedicatedBearer(ctx context.Context, sc *SMContext) error {
+	if s.mme == nil {
+		return nil
+	}
+
+	if err := s.mme.DeactivateDedicatedBearer(ctx, sc.Supi.IMSI(), sc.EBI, sc.Ref); err != nil {
+		return fmt.Errorf("deactivate dedicated EPS bearer: %w", err)
+	}
+
+	logger.WithTrace(ctx, logger.SmfLog).Info("dedicated EPS bearer released",
+		logger.SUPI(sc.Supi.String()), zap.Uint8("ebi", sc.EBI))
+
+	return nil
+}
+
+// DedicatedBearerUp moves the downlink of the dedicated QoS flow of EPS
+// session ref onto the dedicated bearer whose E-RAB ends at enb, once the
+// bearer is first set up or after it moved to another eNB. The flow being
+// added is established.
+func (s *SMF) DedicatedBearerUp(ctx context.Context, ref string, enb models.FTEID) error {
+	sc := s.GetSession(ref)
+	if sc == nil {
+		return ErrSMContextNotFound
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	if sc.releasing || sc.Tunnel == nil || sc.Access != Access4G || sc.qosFlow == nil {
+		return fmt.Errorf("EPS session %q has no dedicated QoS flow", ref)
+	}
+
+	next := sc.Tunnel.dataPlane
+	next.Dedicated = anchorFromFTEID(enb)
+
+	if err := s.applyDataPlane(ctx, sc, next, sc.policyID()); err != nil {
+		return fmt.Errorf("failed to move session %q onto its dedicated bearer: %w", ref, err)
+	}
+
+	s.qosFlowAccepted(sc)
+
+	return nil
+}
+
+// DedicatedBearerReleased drops the dedicated QoS flow of EPS session ref,
+// whose dedicated bearer is gone: a flow being added failed, an established
+// one was released.
+func (s *SMF) DedicatedBearerReleased(ctx context.Context, ref string, reason string) {
+	sc := s.GetSession(ref)
+	if sc == nil {
+		return
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	if sc.Access != Access4G || sc.qosFlow == nil {
+		return
+	}
+
+	if sc.qosFlow.pending {
+		s.qosFlowRejected(ctx, sc, reason)
+		return
+	}
+
+	sc.qosFlow = nil
+
+	s.restoreSessionFilters(ctx, sc)
+
+	logger.WithTrace(ctx, logger.SmfLog).Info("dedicated EPS bearer dropped",
+		logger.SUPI(sc.Supi.String()), zap.Uint8("ebi", sc.EBI), zap.String("reason", reason))
+
+	if s.qosFlows != nil {
+		s.qosFlows.QoSFlowReleased(sc.Ref)
+	}
+}
@@ -0,0 +1,399 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+	"github.com/ellanetworks/core/nas/fgs"
+	libngap "github.com/ellanetworks/core/ngap"
+)
+
+type fakeQoSFlowEvents struct {
+	mu          sync.Mutex
+	established []string
+	failed      []string
+	released    []string
+	moved       []string
+	stopped     []string
+}
+
+func (f *fakeQoSFlowEvents) QoSFlowEstablished(ref string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.established = append(f.established, ref)
+}
+
+func (f *fakeQoSFlowEvents) QoSFlowFailed(ref string, _ string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failed = append(f.failed, ref)
+}
+
+func (f *fakeQoSFlowEvents) QoSFlowReleased(ref string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.released = append(f.released, ref)
+}
+
+func (f *fakeQoSFlowEvents) QoSFlowPolicyChanged(ref string, policyID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.moved = append(f.moved, ref+" "+policyID)
+}
+
+func (f *fakeQoSFlowEvents) SessionStopped(ref string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stopped = append(f.stopped, ref)
+}
+
+func testGBRQosFlow() models.GBRQosFlow {
+	return models.GBRQosFlow{
+		QosData:      models.QosData{Var5qi: 2},
+		GFBRUplink:   models.MustParseBitRate("4 Mbps"),
+		GFBRDownlink: models.MustParseBitRate("1 Mbps"),
+		MFBRUplink:   models.MustParseBitRate("8 Mbps"),
+		MFBRDownlink: models.MustParseBitRate("2 Mbps"),
+		Filter:       models.QosFlowFilter{RemotePrefix: netip.MustParsePrefix("198.51.100.7/32"), Protocol: 17},
+	}
+}
+
+func lastModifyPolicyID(upf *fakeUPF) string {
+	upf.mu.Lock()
+	defer upf.mu.Unlock()
+
+	if len(upf.modifyCalls) == 0 {
+		return ""
+	}
+
+	return upf.modifyCalls[len(upf.modifyCalls)-1].PolicyID
+}
+
+func lastModificationCommand(t *testing.T, amfCb *fakeAMF) *fgs.PDUSessionModificationCommand {
+	t.Helper()
+
+	amfCb.mu.Lock()
+	call := amfCb.modifyCalls[len(amfCb.modifyCalls)-1]
+	amfCb.mu.Unlock()
+
+	m, err := fgs.ParsePDUSessionModificationCommand(call.n1Msg)
+	if err != nil {
+		t.Fatalf("decode the modification command: %v", err)
+	}
+
+	return m
+}
+
+func TestQoSFlow_AddEstablishAndRelease(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	ev := &fakeQoSFlowEvents{}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithQoSFlowEvents(ev))
+
+	smCtx, ref := setupSessionWithTunnel(t, s)
+
+	smCtx.Mutex.Lock()
+	smCtx.PolicyData.PolicyID = "local"
+	smCtx.Mutex.Unlock()
+
+	ctx := context.Background()
+
+	if err := s.AddQoSFlow(ctx, ref, testGBRQosFlow(), "qos-session:1"); err != nil {
+		t.Fatalf("AddQoSFlow: %v", err)
+	}
+
+	if got := lastModifyPolicyID(upf); got != "qos-session:1" {
+		t.Errorf("UPF session filters = %q, want the QoS flow's", got)
+	}
+
+	cmd := lastModificationCommand(t, amfCb)
+	if len(cmd.QoSFlowDescriptions) != 1 || cmd.QoSFlowDescriptions[0].QFI != 2 || cmd.QoSFlowDescriptions[0].OperationCode != fgs.QoSFlowOpCreate {
+		t.Fatalf("QoS flow descriptions = %+v, want QFI 2 created beside the default flow", cmd.QoSFlowDescriptions)
+	}
+
+	if err := s.AddQoSFlow(ctx, ref, testGBRQosFlow(), "qos-session:2"); !errors.Is(err, smf.ErrQoSFlowExists) {
+		t.Errorf("second AddQoSFlow = %v, want ErrQoSFlowExists", err)
+	}
+
+	if _, err := s.UpdateSmContextN1Msg(ctx, ref, buildPDUSessionModificationComplete(smCtx.PDUSessionID, 0)); err != nil {
+		t.Fatalf("modification complete: %v", err)
+	}
+
+	if len(ev.established) != 1 || ev.established[0] != ref {
+		t.Fatalf("established = %v, want [%s]", ev.established, ref)
+	}
+
+	// A reactivation sets the dedicated flow up again beside the default one.
+	n2, err := s.ActivateSmContext(ctx, ref)
+	if err != nil {
+		t.Fatalf("ActivateSmContext: %v", err)
+	}
+
+	transfer, err := libngap.ParsePDUSessionResourceSetupRequestTransfer(n2)
+	if err != nil {
+		t.Fatalf("decode the setup transfer: %v", err)
+	}
+
+	if len(transfer.QosFlowSetupRequest) != 2 {
+		t.Errorf("setup transfer QoS flows = %d, want the default and the dedicated flow", len(transfer.QosFlowSetupRequest))
+	}
+
+	if err := s.ReleaseQoSFlow(ctx, ref); err != nil {
+		t.Fatalf("ReleaseQoSFlow: %v", err)
+	}
+
+	if got := lastModifyPolicyID(upf); got != "local" {
+		t.Errorf("UPF session filters = %q, want the policy's back", got)
+	}
+
+	cmd = lastModificationCommand(t, amfCb)
+	if len(cmd.QoSFlowDescriptions) != 1 || cmd.QoSFlowDescriptions[0].OperationCode != fgs.QoSFlowOpDelete {
+		t.Errorf("QoS flow descriptions = %+v, want the flow deleted", cmd.QoSFlowDescriptions)
+	}
+
+	if _, err := s.UpdateSmContextN1Msg(ctx, ref, buildPDUSessionModificationComplete(smCtx.PDUSessionID, 0)); err != nil {
+		t.Fatalf("modification complete: %v", err)
+	}
+
+	if err := s.ReleaseSmContext(ctx, ref); err != nil {
+		t.Fatalf("ReleaseSmContext: %v", err)
+	}
+
+	if len(ev.stopped) != 1 || len(ev.failed) != 0 || len(ev.released) != 0 {
+		t.Errorf("events = %+v, want the session stopped and nothing else", ev)
+	}
+}
+
+func TestQoSFlow_PolicyDecisionMovesTheFlowFilters(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	pcf.policy = &smf.Policy{
+		PolicyID: "local",
+		Ambr:     models.Ambr{Uplink: models.MustParseBitRate("100 Mbps"), Downlink: models.MustParseBitRate("200 Mbps")},
+		QosData:  models.QosData{Var5qi: 9, Arp: &models.Arp{PriorityLevel: 1}, QFI: 1},
+	}
+	ev := &fakeQoSFlowEvents{}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithQoSFlowEvents(ev), smf.WithPolicyControl(&fakePolicyControl{}))
+
+	smCtx, ref := setupSessionWithTunnel(t, s)
+
+	smCtx.Mutex.Lock()
+	smCtx.PolicyData.PolicyID = "local"
+	smCtx.Mutex.Unlock()
+
+	ctx := context.Background()
+
+	if err := s.AddQoSFlow(ctx, ref, testGBRQosFlow(), "qos-session:1"); err != nil {
+		t.Fatalf("AddQoSFlow: %v", err)
+	}
+
+	if _, err := s.UpdateSmContextN1Msg(ctx, ref, buildPDUSessionModificationComplete(smCtx.PDUSessionID, 0)); err != nil {
+		t.Fatalf("modification complete: %v", err)
+	}
+
+	if err := s.ApplyPolicyDecision(ctx, ref, smf.PolicyDecision{Filter: &smf.PolicyFilter{PolicyID: "quarantine"}}); err != nil {
+		t.Fatalf("ApplyPolicyDecision: %v", err)
+	}
+
+	// The session keeps the flow's filters, which follow the new rules.
+	if got := lastModifyPolicyID(upf); got != "qos-session:1" {
+		t.Errorf("UPF session filters = %q, want the QoS flow's kept", got)
+	}
+
+	if want := ref + " quarantine"; len(ev.moved) != 1 || ev.moved[0] != want {
+		t.Errorf("policy changes = %v, want [%s]", ev.moved, want)
+	}
+}
+
+func TestQoSFlow_RejectRestoresTheSessionFilters(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	ev := &fakeQoSFlowEvents{}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithQoSFlowEvents(ev))
+
+	smCtx, ref := setupSessionWithTunnel(t, s)
+
+	smCtx.Mutex.Lock()
+	smCtx.PolicyData.PolicyID = "local"
+	smCtx.Mutex.Unlock()
+
+	ctx := context.Background()
+
+	if err := s.AddQoSFlow(ctx, ref, testGBRQosFlow(), "qos-session:1"); err != nil {
+		t.Fatalf("AddQoSFlow: %v", err)
+	}
+
+	if _, err := s.UpdateSmContextN1Msg(ctx, ref, buildPDUSessionModificationCommandReject(smCtx.PDUSessionID, 0)); err != nil {
+		t.Fatalf("modification command reject: %v", err)
+	}
+
+	if len(ev.failed) != 1 || len(ev.established) != 0 {
+		t.Fatalf("failed = %v, established = %v, want the flow failed", ev.failed, ev.established)
+	}
+
+	if got := lastModifyPolicyID(upf); got != "local" {
+		t.Errorf("UPF session filters = %q, want the policy's back", got)
+	}
+
+	if ptiInUse(t, smCtx, 0) {
+		t.Error("PTI 0 still in use after the reject")
+	}
+
+	// The session is free to try again.
+	if err := s.AddQoSFlow(ctx, ref, testGBRQosFlow(), "qos-session:2"); err != nil {
+		t.Errorf("AddQoSFlow after a reject: %v", err)
+	}
+}
+
+func TestQoSFlow_RefusedOnIdleSessions(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	s := smf.New(pcf, store, upf, amfCb)
+
+	smCtx, ref := setupSessionWithTunnel(t, s)
+	ctx := context.Background()
+
+	smCtx.Mutex.Lock()
+	smCtx.Tunnel.Downlink = smf.DownlinkBuffering
+	smCtx.Mutex.Unlock()
+
+	if err := s.AddQoSFlow(ctx, ref, testGBRQosFlow(), "qos-session:1"); !errors.Is(err, smf.ErrUENotReachable) {
+		t.Errorf("AddQoSFlow on an idle UE = %v, want ErrUENotReachable", err)
+	}
+
+	smCtx.Mutex.Lock()
+	smCtx.Access = smf.Access4G
+	smCtx.Mutex.Unlock()
+
+	if err := s.AddQoSFlow(ctx, ref, testGBRQosFlow(), "qos-session:1"); !errors.Is(err, smf.ErrUENotReachable) {
+		t.Errorf("AddQoSFlow on an idle EPS UE = %v, want ErrUENotReachable", err)
+	}
+
+	if err := s.AddQoSFlow(ctx, "unknown", testGBRQosFlow(), "qos-session:1"); !errors.Is(err, smf.ErrSMContextNotFound) {
+		t.Errorf("AddQoSFlow on an unknown session = %v, want ErrSMContextNotFound", err)
+	}
+
+	if got := modifyCallCount(amfCb); got != 0 {
+		t.Errorf("modification commands = %d, want none", got)
+	}
+}
+
+func lastS1UBearers(upf *fakeUPF) []models.S1UBearer {
+	upf.mu.Lock()
+	defer upf.mu.Unlock()
+
+	if len(upf.modifyCalls) == 0 {
+		return nil
+	}
+
+	return upf.modifyCalls[len(upf.modifyCalls)-1].S1UBearers
+}
+
+func TestQoSFlow_DedicatedBearerOnEPS(t *testing.T) {
+	store, upf := epsTestSMF()
+	ev := &fakeQoSFlowEvents{}
+	s := smf.New(&fakePCF{}, store, upf, &fakeAMF{}, smf.WithQoSFlowEvents(ev))
+
+	mmeCb := &fakeMME{}
+	s.SetMME(mmeCb)
+
+	ctx := context.Background()
+
+	bearer, err := s.CreateEPSSession(ctx, epsRequest(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enb := models.FTEID{TEID: 0x55, Addr: netip.MustParseAddr("203.0.113.3")}
+	if err := s.ModifyEPSSession(ctx, bearer.Ref, epsTestEBI, enb); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AddQoSFlow(ctx, bearer.Ref, testGBRQosFlow(), "qos-session:1"); err != nil {
+		t.Fatalf("AddQoSFlow on EPS: %v", err)
+	}
+
+	if len(mmeCb.bearerCalls) != 1 || mmeCb.bearerCalls[0].QFI != models.DedicatedQFI {
+		t.Fatalf("dedicated bearer activations = %+v, want one for the flow", mmeCb.bearerCalls)
+	}
+
+	if got := lastModifyPolicyID(upf); got != "qos-session:1" {
+		t.Errorf("UPF session filters = %q, want the QoS flow's", got)
+	}
+
+	if got := lastS1UBearers(upf); len(got) != 0 {
+		t.Errorf("S1-U bearers before the E-RAB is up = %+v, want none", got)
+	}
+
+	dedicated := models.FTEID{TEID: 0x66, Addr: enb.Addr}
+	if err := s.DedicatedBearerUp(ctx, bearer.Ref, dedicated); err != nil {
+		t.Fatalf("DedicatedBearerUp: %v", err)
+	}
+
+	if len(ev.established) != 1 {
+		t.Fatalf("established = %v, want the flow established", ev.established)
+	}
+
+	if got := lastS1UBearers(upf); len(got) != 1 || got[0].QFI != models.DedicatedQFI || got[0].TEID != 0x66 {
+		t.Errorf("S1-U bearers = %+v, want the flow's downlink on TEID 0x66", got)
+	}
+
+	s.DedicatedBearerReleased(ctx, bearer.Ref, "the UE went idle")
+
+	if len(ev.released) != 1 {
+		t.Fatalf("released = %v, want the flow released", ev.released)
+	}
+
+	if got := lastS1UBearers(upf); len(got) != 0 {
+		t.Errorf("S1-U bearers after the release = %+v, want none", got)
+	}
+
+	// The session is free to add the flow again, and a failed activation
+	// reports the flow failed.
+	if err := s.AddQoSFlow(ctx, bearer.Ref, testGBRQosFlow(), "qos-session:2"); err != nil {
+		t.Fatalf("AddQoSFlow after a release: %v", err)
+	}
+
+	s.DedicatedBearerReleased(ctx, bearer.Ref, "the UE did not answer the bearer activation")
+
+	if len(ev.failed) != 1 {
+		t.Errorf("failed = %v, want the second flow failed", ev.failed)
+	}
+}
+
+func TestQoSFlowSession_FindsTheUEByIMSIOrAddress(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	s := smf.New(pcf, store, upf, amfCb)
+
+	smCtx, ref := setupSessionWithTunnel(t, s)
+
+	smCtx.Mutex.Lock()
+	smCtx.PolicyData.PolicyID = "local"
+	smCtx.Mutex.Unlock()
+
+	got, policyID, ok := s.QoSFlowSession(testSUPI().IMSI(), netip.Addr{})
+	if !ok || got != ref || policyID != "local" {
+		t.Errorf("by IMSI = %q, %q, %v, want %q, local", got, policyID, ok, ref)
+	}
+
+	if got, _, ok := s.QoSFlowSession("", netip.MustParseAddr("10.0.0.1")); !ok || got != ref {
+		t.Errorf("by UE address = %q, %v, want %q", got, ok, ref)
+	}
+
+	if _, _, ok := s.QoSFlowSession("", netip.MustParseAddr("10.0.0.2")); ok {
+		t.Error("found a session for an address no UE holds")
+	}
+
+	if _, _, ok := s.QoSFlowSession("001019999999999", netip.Addr{}); ok {
+		t.Error("found a session for an IMSI with none")
+	}
+}
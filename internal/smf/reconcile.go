@@ -57,6 +57,15 @@ func (s *SMF) ReconcileSmContext(ctx context.Context, req *models.SessionReconci
 		return fmt.Errorf("sm context not found: %s", req.SmContextRef)
 	}
 
+	// The network rules of a location variant need only the UPF, so they
+	// follow the UE even while it is idle or a procedure is in flight.
+	if req.NewPolicy != nil {
+		if err := s.followLocalRules(ctx, smContext, req.NewPolicy.RulesPolicyID); err != nil {
+			logger.SmfLog.Warn("failed to move session to the network rules of its serving area",
+				logger.SUPI(smContext.Supi.String()), zap.String("policy", req.NewPolicy.RulesPolicyID), zap.Error(err))
+		}
+	}
+
 	smContext.Mutex.Lock()
 	defer smContext.Mutex.Unlock()
 
@@ -103,6 +112,10 @@ func (s *SMF) ReconcileSmContext(ctx context.Context, req *models.SessionReconci
 		return nil
 	}
 
+	// A policy decision point's decision overrides the local policy's QoS and
+	// Session-AMBR for as long as it is in force.
+	req.NewPolicy = smContext.policyDecision.applyDelta(req.NewPolicy)
+
 	// Slice (SST/SD) change: stored Snssai matches no configured slice. Release
 	// with cause #39 so the UE re-establishes on the new slice (TS 23.502).
 	if req.Reason == models.ReconcileSliceMismatch {
@@ -316,6 +329,7 @@ func (s *SMF) ReconcileSmContext(ctx context.Context, req *models.SessionReconci
 			// No procedure to await: commit now. ActivateSmContext rebuilds the Setup
 			// Transfer from PolicyData, so the UE gets updated QoS on reconnect.
 			smContext.PolicyData = newPolicy
+			s.policyCommitted(smContext)
 		} else {
 			// UE connected: the modification command is outstanding. Commit only when
 			// the UE answers PDU SESSION MODIFICATION COMPLETE (TS 24.501 §6.3.2.2); a
@@ -421,7 +435,7 @@ func (s *SMF) sendSessionModification(ctx context.Context, smContext *SMContext,
 // updatePFCPRules pushes the policy's QoS (QFI + session-AMBR) to the UPF data
 // plane (TS 29.244).
 func (s *SMF) updatePFCPRules(ctx context.Context, smContext *SMContext, policy *Policy) error {
-	return s.applySessionQERs(ctx, smContext, policy.PolicyID, policy.QosData.QFI, policy.Ambr.Uplink, policy.Ambr.Downlink)
+	return s.applySessionQERs(ctx, smContext, smContext.policyID(), policy.QosData.QFI, policy.Ambr.Uplink, policy.Ambr.Downlink)
 }
 
 func (s *SMF) applySessionQERs(ctx context.Context, smContext *SMContext, policyID string, qfi uint8, ambrUplink, ambrDownlink models.BitRate) error {
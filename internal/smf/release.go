@@ -96,7 +96,14 @@ func (s *SMF) unregisterIPv6Session(ctx context.Context, smContext *SMContext) {
 		return
 	}
 
-	if err := s.upf.UnregisterIPv6Session(ctx, ulTEID); err != nil {
+	// Both callers still hold the PFCP context; without one, SEID zero falls
+	// back to the embedded UPF.
+	var seid uint64
+	if smContext.PFCPContext != nil {
+		seid = smContext.PFCPContext.SEID
+	}
+
+	if err := s.upf.UnregisterIPv6Session(ctx, seid, ulTEID); err != nil {
 		logger.SmfLog.Warn("failed to unregister IPv6 session for RA",
 			zap.Error(err),
 			logger.SUPI(smContext.Supi.String()),
@@ -0,0 +1,366 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/metrics"
+	smfNas "github.com/ellanetworks/core/internal/smf/nas"
+	naslib "github.com/ellanetworks/core/nas"
+	"github.com/ellanetworks/core/nas/fgs"
+	"go.uber.org/zap"
+)
+
+// AAAVerdict is the DN-AAA server's answer to one authentication round.
+type AAAVerdict int
+
+const (
+	// AAAChallenge asks the UE for another EAP round.
+	AAAChallenge AAAVerdict = iota
+	// AAAAccept authenticates and authorizes the UE.
+	AAAAccept
+	// AAAReject denies the UE access to the data network.
+	AAAReject
+)
+
+// AAARequest is one EAP round relayed from the UE to the DN-AAA server.
+type AAARequest struct {
+	IMSI         string
+	DNN          string
+	PDUSessionID uint8
+	// Identity is the user identity of the UE's EAP-Response/Identity.
+	Identity string
+	EAP      []byte
+	// State is the server's opaque round state, echoed back.
+	State []byte
+}
+
+// AAAAnswer is the DN-AAA server's answer to an AAARequest.
+type AAAAnswer struct {
+	Verdict       AAAVerdict
+	EAP           []byte
+	State         []byte
+	Authorization AAAAuthorization
+}
+
+// AAAAuthorization is what a DN-AAA server may set for an accepted session
+// (TS 23.501 §5.6.6): zero values leave the data network's defaults.
+type AAAAuthorization struct {
+	IPv4           netip.Addr
+	Filter         *PolicyFilter
+	SessionTimeout time.Duration
+}
+
+// PolicyFilter is the policy whose network rules replace the subscriber's.
+type PolicyFilter struct {
+	PolicyID     string
+	NetworkRules []*ResolvedNetworkRule
+}
+
+// DNAAA authenticates users against the data networks' DN-AAA servers.
+type DNAAA interface {
+	// Required reports whether the data network asks for secondary
+	// authentication.
+	Required(ctx context.Context, dnn string) (bool, error)
+	Authenticate(ctx context.Context, req *AAARequest) (*AAAAnswer, error)
+}
+
+// EAP codes and the identity type (RFC 3748 §4, §5.1).
+const (
+	eapCodeRequest  = 1
+	eapCodeResponse = 2
+	eapCodeFailure  = 4
+	eapTypeIdentity = 1
+)
+
+// pendingAuth is an establishment waiting on secondary authentication. It
+// holds a Ref but no SM context, so no session state exists until the
+// DN-AAA server accepts.
+type pendingAuth struct {
+	mu       sync.Mutex
+	ref      string
+	est      *establishment
+	identity string
+	state    []byte
+	timer    *time.Timer
+	done     bool // guarded by mu; set once the authentication has concluded
+}
+
+func (s *SMF) secondaryAuthRequired(ctx context.Context, dnn string) (bool, error) {
+	if s.aaa == nil {
+		return false, nil
+	}
+
+	return s.aaa.Required(ctx, dnn)
+}
+
+// startSecondaryAuth reserves a Ref for the establishment and asks the UE for
+// its identity with a PDU SESSION AUTHENTICATION COMMAND (TS 23.502
+// §4.3.2.3). The establishment continues in continueSecondaryAuth.
+func (s *SMF) startSecondaryAuth(ctx context.Context, est *establishment) (string, []byte, error) {
+	supi := est.session.Supi
+	pduSessionID := est.session.Identity.PDUSessionID
+
+	pa := &pendingAuth{est: est}
+	pa.timer = time.AfterFunc(s.authTimeout, func() { s.expireSecondaryAuth(pa) })
+
+	s.mu.Lock()
+
+	// A new request for the same PDU session replaces one still authenticating.
+	for ref, held := range s.pendingAuth {
+		if held.est.session.Supi == supi && held.est.session.Identity.PDUSessionID == pduSessionID {
+			delete(s.pendingAuth, ref)
+			held.timer.Stop()
+		}
+	}
+
+	pa.ref = s.nextRefLocked(supi, est.session.Identity)
+	est.session.Ref = pa.ref
+	s.pendingAuth[pa.ref] = pa
+
+	s.mu.Unlock()
+
+	cmd, err := smfNas.BuildGSMPDUSessionAuthenticationCommand(fgs.PDUSessionID(pduSessionID), []byte{eapCodeRequest, 1, 0, 5, eapTypeIdentity})
+	if err == nil {
+		err = s.amf.TransferN1(ctx, supi, cmd, pduSessionID)
+	}
+
+	if err != nil {
+		pa.mu.Lock()
+		s.concludeSecondaryAuth(pa)
+		pa.mu.Unlock()
+
+		rsp, buildErr := smfNas.BuildGSMPDUSessionEstablishmentReject(fgs.PDUSessionID(pduSessionID), est.pti, fgs.GSMCauseRequestRejectedUnspecified)
+		if buildErr != nil {
+			return "", nil, fmt.Errorf("start secondary authentication: %v (build reject failed: %v)", err, buildErr)
+		}
+
+		return "", rsp, fmt.Errorf("start secondary authentication: %v", err)
+	}
+
+	return pa.ref, nil, nil
+}
+
+func (s *SMF) lookupPendingAuth(ref string) *pendingAuth {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.pendingAuth[ref]
+}
+
+func (s *SMF) dropPendingAuth(pa *pendingAuth) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pendingAuth[pa.ref] == pa {
+		delete(s.pendingAuth, pa.ref)
+	}
+}
+
+// continueSecondaryAuth relays the UE's PDU SESSION AUTHENTICATION COMPLETE
+// to the DN-AAA server and acts on its verdict.
+func (s *SMF) continueSecondaryAuth(ctx context.Context, pa *pendingAuth, n1Msg []byte) (*UpdateResult, error) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	if pa.done {
+		return nil, fmt.Errorf("%w: %s", ErrSMContextNotFound, pa.ref)
+	}
+
+	est := pa.est
+	pduSessionID := fgs.PDUSessionID(est.session.Identity.PDUSessionID)
+
+	msg, err := fgs.ParseMessage(n1Msg)
+	if err != nil && !naslib.SoftOnly(err) {
+		return nil, fmt.Errorf("error decoding N1SmMessage: %v", err)
+	}
+
+	switch m := msg.(type) {
+	case *fgs.PDUSessionAuthenticationComplete:
+		pa.timer.Stop()
+
+		return s.relaySecondaryAuth(ctx, pa, m.EAP)
+	case *fgs.GSMStatus:
+		// The UE gave up on the procedure (TS 24.501 §6.3.1.4).
+		logger.WithTrace(ctx, logger.SmfLog).Info("UE abandoned secondary authentication",
+			logger.SUPI(est.session.Supi.String()), logger.PDUSessionID(uint8(pduSessionID)), zap.Uint8("cause", uint8(m.Cause)))
+		s.concludeSecondaryAuth(pa)
+		recordSessionEstablishmentResult(metrics.RAT5G, metrics.ResultReject)
+
+		return &UpdateResult{SessionRemoved: true}, nil
+	default:
+		rsp, err := smfNas.BuildGSM5GSMStatus(pduSessionID, 0, fgs.GSMCauseMessageTypeNotCompatibleWithTheProtocolState)
+		if err != nil {
+			return nil, fmt.Errorf("build 5GSM STATUS failed: %v", err)
+		}
+
+		return &UpdateResult{N1Msg: rsp}, nil
+	}
+}
+
+// relaySecondaryAuth runs one authentication round. pa.mu must be held.
+func (s *SMF) relaySecondaryAuth(ctx context.Context, pa *pendingAuth, eap []byte) (*UpdateResult, error) {
+	est := pa.est
+	pduSessionID := fgs.PDUSessionID(est.session.Identity.PDUSessionID)
+
+	if pa.identity == "" {
+		identity, ok := eapIdentity(eap)
+		if !ok {
+			return s.rejectSecondaryAuth(pa, fgs.GSMCauseUserAuthenticationOrAuthorizationFailed, nil,
+				fmt.Errorf("UE answered the identity request with no EAP-Response/Identity"))
+		}
+
+		pa.identity = identity
+	}
+
+	ans, err := s.aaa.Authenticate(ctx, &AAARequest{
+		IMSI:         est.session.Supi.IMSI(),
+		DNN:          est.session.Dnn,
+		PDUSessionID: uint8(pduSessionID),
+		Identity:     pa.identity,
+		EAP:          eap,
+		State:        pa.state,
+	})
+	if err != nil {
+		return s.rejectSecondaryAuth(pa, fgs.GSMCauseRequestRejectedUnspecified, nil, fmt.Errorf("DN-AAA server: %w", err))
+	}
+
+	switch ans.Verdict {
+	case AAAChallenge:
+		cmd, err := smfNas.BuildGSMPDUSessionAuthenticationCommand(pduSessionID, ans.EAP)
+		if err != nil {
+			return s.rejectSecondaryAuth(pa, fgs.GSMCauseRequestRejectedUnspecified, nil, fmt.Errorf("build authentication command: %w", err))
+		}
+
+		pa.state = ans.State
+		pa.timer.Reset(s.authTimeout)
+
+		return &UpdateResult{N1Msg: cmd}, nil
+	case AAAAccept:
+		s.concludeSecondaryAuth(pa)
+
+		authz := ans.Authorization
+		est.eap = ans.EAP
+		est.session.AuthorizedIPv4 = authz.IPv4
+
+		if authz.Filter != nil {
+			policy := *est.session.Policy
+			policy.PolicyID = authz.Filter.PolicyID
+			policy.NetworkRules = authz.Filter.NetworkRules
+			est.session.Policy = &policy
+			est.session.AuthorizedRules = true
+		}
+
+		ref, rsp, err := s.establish(ctx, est)
+		if rsp != nil {
+			return &UpdateResult{N1Msg: rsp, SessionRemoved: true}, nil
+		}
+
+		if err != nil {
+			return &UpdateResult{SessionRemoved: true}, err
+		}
+
+		if sc := s.GetSession(ref); sc != nil && authz.SessionTimeout > 0 {
+			sc.sessionTimeout.ArmOnce(authz.SessionTimeout, func() { s.expireAuthorizedSession(sc) })
+		}
+
+		return &UpdateResult{}, nil
+	default:
+		return s.rejectSecondaryAuth(pa, fgs.GSMCauseUserAuthenticationOrAuthorizationFailed, ans.EAP, nil)
+	}
+}
+
+// rejectSecondaryAuth ends the authentication with a PDU SESSION
+// ESTABLISHMENT REJECT carrying an EAP-Failure. pa.mu must be held.
+func (s *SMF) rejectSecondaryAuth(pa *pendingAuth, cause fgs.GSMCause, eap []byte, reason error) (*UpdateResult, error) {
+	s.concludeSecondaryAuth(pa)
+	recordSessionEstablishmentResult(metrics.RAT5G, metrics.ResultReject)
+
+	if eap == nil {
+		eap = []byte{eapCodeFailure, 1, 0, 4}
+	}
+
+	rsp, err := smfNas.BuildGSMPDUSessionEstablishmentRejectEAP(fgs.PDUSessionID(pa.est.session.Identity.PDUSessionID), pa.est.pti, cause, eap)
+	if err != nil {
+		return nil, fmt.Errorf("build reject failed: %v", err)
+	}
+
+	if reason != nil {
+		logger.SmfLog.Warn("secondary authentication failed",
+			logger.SUPI(pa.est.session.Supi.String()), logger.PDUSessionID(pa.est.session.Identity.PDUSessionID), zap.Error(reason))
+	}
+
+	return &UpdateResult{N1Msg: rsp, SessionRemoved: true}, nil
+}
+
+// concludeSecondaryAuth retires pa. pa.mu must be held.
+func (s *SMF) concludeSecondaryAuth(pa *pendingAuth) {
+	pa.done = true
+	pa.timer.Stop()
+	s.dropPendingAuth(pa)
+}
+
+// expireSecondaryAuth rejects an establishment whose UE stopped answering.
+func (s *SMF) expireSecondaryAuth(pa *pendingAuth) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+
+	if pa.done {
+		return
+	}
+
+	rsp, err := s.rejectSecondaryAuth(pa, fgs.GSMCauseUserAuthenticationOrAuthorizationFailed, nil,
+		fmt.Errorf("UE did not answer within %s", s.authTimeout))
+	if err != nil {
+		logger.SmfLog.Warn("couldn't build establishment reject", zap.Error(err))
+		return
+	}
+
+	ctx := context.Background()
+	if err := s.amf.TransferN1(ctx, pa.est.session.Supi, rsp.N1Msg, pa.est.session.Identity.PDUSessionID); err != nil {
+		logger.SmfLog.Warn("couldn't send establishment reject", zap.Error(err))
+	}
+}
+
+// expireAuthorizedSession releases sc when the authorization the DN-AAA
+// server granted runs out. A session that left the pool is left alone.
+func (s *SMF) expireAuthorizedSession(sc *SMContext) {
+	if s.GetSession(sc.Ref) != sc {
+		return
+	}
+
+	sc.Mutex.Lock()
+	defer sc.Mutex.Unlock()
+
+	if sc.releasing || sc.Tunnel == nil {
+		return
+	}
+
+	logger.SmfLog.Info("DN-AAA session timeout reached, releasing session",
+		logger.SUPI(sc.Supi.String()), logger.PDUSessionID(sc.PDUSessionID))
+
+	if err := s.startRelease(context.Background(), sc, 0, fgs.GSMCauseRegularDeactivation); err != nil {
+		logger.SmfLog.Warn("couldn't release session after DN-AAA session timeout", zap.Error(err))
+	}
+}
+
+// eapIdentity returns the identity of an EAP-Response/Identity.
+func eapIdentity(eap []byte) (string, bool) {
+	if len(eap) < 5 || eap[0] != eapCodeResponse || eap[4] != eapTypeIdentity {
+		return "", false
+	}
+
+	n := int(eap[2])<<8 | int(eap[3])
+	if n < 5 || n > len(eap) {
+		return "", false
+	}
+
+	return string(eap[5:n]), true
+}
@@ -0,0 +1,260 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package smf_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/smf"
+	"github.com/ellanetworks/core/nas/fgs"
+)
+
+// fakeDNAAA answers each authentication round with the next scripted answer.
+type fakeDNAAA struct {
+	mu       sync.Mutex
+	answers  []*smf.AAAAnswer
+	requests []smf.AAARequest
+}
+
+func (f *fakeDNAAA) Required(_ context.Context, dnn string) (bool, error) {
+	return dnn == testDNN, nil
+}
+
+func (f *fakeDNAAA) Authenticate(_ context.Context, req *smf.AAARequest) (*smf.AAAAnswer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, *req)
+
+	if len(f.answers) == 0 {
+		return nil, errors.New("no answer scripted")
+	}
+
+	ans := f.answers[0]
+	f.answers = f.answers[1:]
+
+	return ans, nil
+}
+
+func authenticationComplete(t *testing.T, eap []byte) []byte {
+	t.Helper()
+
+	b, err := (&fgs.PDUSessionAuthenticationComplete{PDUSessionID: 1, EAP: eap}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("build authentication complete: %v", err)
+	}
+
+	return b
+}
+
+var eapIdentityAlice = []byte{0x02, 0x01, 0x00, 0x0a, 0x01, 'a', 'l', 'i', 'c', 'e'}
+
+func TestSecondaryAuth_ChallengeThenAccept(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	authorized := netip.MustParseAddr("10.0.0.77")
+	aaa := &fakeDNAAA{answers: []*smf.AAAAnswer{
+		{Verdict: smf.AAAChallenge, EAP: []byte{0x01, 0x02, 0x00, 0x06, 0x04, 0x10}, State: []byte("round-2")},
+		{Verdict: smf.AAAAccept, EAP: []byte{0x03, 0x02, 0x00, 0x04}, Authorization: smf.AAAAuthorization{
+			IPv4:   authorized,
+			Filter: &smf.PolicyFilter{PolicyID: "lab-only"},
+		}},
+	}}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithDNAAA(aaa))
+	ctx := context.Background()
+
+	ref, rsp, err := s.CreateSmContext(ctx, testSUPI(), 1, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+	if err != nil || rsp != nil || ref == "" {
+		t.Fatalf("CreateSmContext = %q, %x, %v; want a ref and no response", ref, rsp, err)
+	}
+
+	if s.GetSession(ref) != nil {
+		t.Fatal("an SM context exists before the DN-AAA server accepted")
+	}
+
+	calls := amfCb.n1()
+	if len(calls) != 1 {
+		t.Fatalf("N1 transfers = %d, want 1 (identity request)", len(calls))
+	}
+
+	cmd, err := fgs.ParsePDUSessionAuthenticationCommand(calls[0].n1Msg)
+	if err != nil {
+		t.Fatalf("decode authentication command: %v", err)
+	}
+
+	if !bytes.Equal(cmd.EAP, []byte{0x01, 0x01, 0x00, 0x05, 0x01}) {
+		t.Fatalf("EAP = %x, want an EAP-Request/Identity", cmd.EAP)
+	}
+
+	upd, err := s.UpdateSmContextN1Msg(ctx, ref, authenticationComplete(t, eapIdentityAlice))
+	if err != nil {
+		t.Fatalf("first round: %v", err)
+	}
+
+	if _, err := fgs.ParsePDUSessionAuthenticationCommand(upd.N1Msg); err != nil {
+		t.Fatalf("challenge is not an authentication command: %v", err)
+	}
+
+	if _, err := s.UpdateSmContextN1Msg(ctx, ref, authenticationComplete(t, []byte{0x02, 0x02, 0x00, 0x06, 0x04, 0x20})); err != nil {
+		t.Fatalf("second round: %v", err)
+	}
+
+	if got := aaa.requests[1]; got.Identity != "alice" || string(got.State) != "round-2" {
+		t.Errorf("second request identity %q state %q, want alice/round-2", got.Identity, got.State)
+	}
+
+	sc := s.GetSession(ref)
+	if sc == nil {
+		t.Fatal("no SM context under the reserved ref after accept")
+	}
+
+	if sc.PolicyData.PolicyID != "lab-only" {
+		t.Errorf("policy = %q, want the DN-AAA filter", sc.PolicyData.PolicyID)
+	}
+
+	if !slices.Equal(store.reservedIPs, []netip.Addr{authorized}) {
+		t.Errorf("reserved = %v, want [%s]", store.reservedIPs, authorized)
+	}
+
+	if len(amfCb.n1n2Calls) != 1 {
+		t.Fatalf("N1N2 transfers = %d, want 1 (accept)", len(amfCb.n1n2Calls))
+	}
+
+	accept, err := fgs.ParsePDUSessionEstablishmentAccept(amfCb.n1n2Calls[0].n1Msg)
+	if err != nil {
+		t.Fatalf("decode accept: %v", err)
+	}
+
+	if !bytes.Equal(accept.EAP, []byte{0x03, 0x02, 0x00, 0x04}) {
+		t.Errorf("accept EAP = %x, want the EAP-Success", accept.EAP)
+	}
+}
+
+func TestSecondaryAuth_Reject(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	aaa := &fakeDNAAA{answers: []*smf.AAAAnswer{{Verdict: smf.AAAReject, EAP: []byte{0x04, 0x01, 0x00, 0x04}}}}
+	s := smf.New(pcf, store, upf, amfCb, smf.WithDNAAA(aaa))
+	ctx := context.Background()
+
+	ref, _, err := s.CreateSmContext(ctx, testSUPI(), 1, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+	if err != nil {
+		t.Fatalf("CreateSmContext: %v", err)
+	}
+
+	upd, err := s.UpdateSmContextN1Msg(ctx, ref, authenticationComplete(t, eapIdentityAlice))
+	if err != nil {
+		t.Fatalf("UpdateSmContextN1Msg: %v", err)
+	}
+
+	if !upd.SessionRemoved {
+		t.Error("SessionRemoved = false, want the AMF told to drop its context")
+	}
+
+	reject, err := fgs.ParsePDUSessionEstablishmentReject(upd.N1Msg)
+	if err != nil {
+		t.Fatalf("decode reject: %v", err)
+	}
+
+	if reject.Cause != fgs.GSMCauseUserAuthenticationOrAuthorizationFailed || reject.PTI != 10 {
+		t.Errorf("reject cause %d PTI %d, want #29 and the request's PTI", reject.Cause, reject.PTI)
+	}
+
+	if len(store.ops()) != 0 {
+		t.Errorf("address operations %v, want none", store.ops())
+	}
+
+	if _, err := s.UpdateSmContextN1Msg(ctx, ref, authenticationComplete(t, eapIdentityAlice)); !errors.Is(err, smf.ErrSMContextNotFound) {
+		t.Errorf("late answer error = %v, want ErrSMContextNotFound", err)
+	}
+}
+
+func TestSecondaryAuth_SessionTimeout(t *testing.T) {
+	accept := func() *smf.AAAAnswer {
+		return &smf.AAAAnswer{Verdict: smf.AAAAccept, EAP: []byte{0x03, 0x01, 0x00, 0x04}, Authorization: smf.AAAAuthorization{
+			SessionTimeout: 20 * time.Millisecond,
+		}}
+	}
+
+	establish := func(t *testing.T) (*smf.SMF, *fakeAMF, string) {
+		t.Helper()
+
+		pcf, store, upf, amfCb := defaultFakes()
+		s := smf.New(pcf, store, upf, amfCb, smf.WithDNAAA(&fakeDNAAA{answers: []*smf.AAAAnswer{accept()}}))
+		ctx := context.Background()
+
+		ref, _, err := s.CreateSmContext(ctx, testSUPI(), 1, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+		if err != nil {
+			t.Fatalf("CreateSmContext: %v", err)
+		}
+
+		if _, err := s.UpdateSmContextN1Msg(ctx, ref, authenticationComplete(t, eapIdentityAlice)); err != nil {
+			t.Fatalf("identity round: %v", err)
+		}
+
+		if s.GetSession(ref) == nil {
+			t.Fatal("no SM context after accept")
+		}
+
+		return s, amfCb, ref
+	}
+
+	t.Run("the session is released when its authorization runs out", func(t *testing.T) {
+		_, amfCb, _ := establish(t)
+
+		deadline := time.Now().Add(2 * time.Second)
+		for releaseCallCount(amfCb) == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		if releaseCallCount(amfCb) != 1 {
+			t.Fatalf("release commands = %d, want 1", releaseCallCount(amfCb))
+		}
+	})
+
+	t.Run("a session gone before then is left alone", func(t *testing.T) {
+		s, amfCb, ref := establish(t)
+
+		removeSession(s, context.Background(), s.GetSession(ref))
+
+		time.Sleep(60 * time.Millisecond)
+
+		if got := releaseCallCount(amfCb); got != 0 {
+			t.Errorf("release commands = %d, want none for a removed session", got)
+		}
+	})
+}
+
+func TestSecondaryAuth_Timeout(t *testing.T) {
+	pcf, store, upf, amfCb := defaultFakes()
+	s := smf.New(pcf, store, upf, amfCb, smf.WithDNAAA(&fakeDNAAA{}), smf.WithSecondaryAuthTimeout(10*time.Millisecond))
+
+	ref, _, err := s.CreateSmContext(context.Background(), testSUPI(), 1, testDNN, testSnssai, fgs.RequestTypeInitialRequest, buildPDUSessionEstRequest(), 0)
+	if err != nil {
+		t.Fatalf("CreateSmContext: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(amfCb.n1()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	calls := amfCb.n1()
+	if len(calls) != 2 {
+		t.Fatalf("N1 transfers = %d, want the identity request and a reject", len(calls))
+	}
+
+	if _, err := fgs.ParsePDUSessionEstablishmentReject(calls[1].n1Msg); err != nil {
+		t.Fatalf("second transfer is not a reject: %v", err)
+	}
+
+	if _, err := s.UpdateSmContextN1Msg(context.Background(), ref, authenticationComplete(t, eapIdentityAlice)); !errors.Is(err, smf.ErrSMContextNotFound) {
+		t.Errorf("late answer error = %v, want ErrSMContextNotFound", err)
+	}
+}
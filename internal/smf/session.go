@@ -12,6 +12,7 @@ import (
 
 	"github.com/ellanetworks/core/etsi"
 	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/metrics"
 	"github.com/ellanetworks/core/internal/models"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -26,6 +27,8 @@ var (
 	errStaticIPResolve     = errors.New("static IP resolution failed")
 	errUPFSession          = errors.New("UPF session establishment failed")
 	errSessionIdentity     = errors.New("session identity is unusable")
+	errCreditDenied        = errors.New("online charging refused the session")
+	errAdmission           = errors.New("session admission failed")
 )
 
 // SessionRequest is the RAT-agnostic input to establishSession, common to the
@@ -38,6 +41,15 @@ type SessionRequest struct {
 	Access   AccessType
 	PDUType  uint8 // the negotiated PDU/PDN type
 	Policy   *Policy
+
+	// Ref is the Ref reserved for the session while it was authenticated;
+	// empty to allocate one.
+	Ref string
+	// AuthorizedIPv4 is the address the DN-AAA server assigned, if any.
+	AuthorizedIPv4 netip.Addr
+	// AuthorizedRules is set when the DN-AAA server's Filter-Id chose
+	// Policy's network rules.
+	AuthorizedRules bool
 }
 
 // ueAddresses is the address set allocated for a session; the IPv6 prefix is the
@@ -59,7 +71,12 @@ func (s *SMF) establishSession(ctx context.Context, req SessionRequest) (*SMCont
 		return nil, ueAddresses{}, fmt.Errorf("%w: %v", errUEAddressAllocation, err)
 	}
 
-	sc, err := s.NewSession(req.Supi, req.Access, req.Identity, req.Dnn, req.Snssai)
+	quota, err := s.sessionQuota(ctx, req.Snssai, req.Dnn)
+	if err != nil {
+		return nil, ueAddresses{}, fmt.Errorf("%w: %v", errAdmission, err)
+	}
+
+	sc, err := s.newSession(req.Supi, req.Access, req.Identity, req.Dnn, req.Snssai, req.Ref)
 	if err != nil {
 		return nil, ueAddresses{}, fmt.Errorf("%w: %v", errSessionIdentity, err)
 	}
@@ -72,13 +89,20 @@ func (s *SMF) establishSession(ctx context.Context, req SessionRequest) (*SMCont
 		}
 	}()
 
+	if quotaErr := s.admit(ctx, quota, sc); quotaErr != nil {
+		metrics.QuotaRejection(quotaErr.Quota)
+
+		return nil, ueAddresses{}, quotaErr
+	}
+
 	// Build under the session lock so a concurrent reader for the same key never
 	// sees a half-built context.
 	sc.Mutex.Lock()
 	sc.PDUSessionType = req.PDUType
 	sc.PolicyData = req.Policy
+	sc.authorizedRules = req.AuthorizedRules
 
-	addrs, err := s.allocateUEAddresses(ctx, dn, sc)
+	addrs, err := s.allocateUEAddresses(ctx, dn, sc, req.AuthorizedIPv4)
 	if err != nil {
 		sc.Mutex.Unlock()
 		return nil, ueAddresses{}, fmt.Errorf("%w: %v", errUEAddressAllocation, err)
@@ -121,6 +145,11 @@ func (s *SMF) establishSession(ctx context.Context, req SessionRequest) (*SMCont
 		}
 	}
 
+	if s.policy != nil {
+		req.Policy = s.decidePolicy(ctx, sc, req)
+		sc.PolicyData = req.Policy
+	}
+
 	sc.Tunnel = &UPTunnel{dataPlane: dataPlane{
 		UEIPv4: addrs.IPv4,
 		UEIPv6: addrs.IPv6Prefix,
@@ -140,8 +169,35 @@ func (s *SMF) establishSession(ctx context.Context, req SessionRequest) (*SMCont
 		return nil, ueAddresses{}, fmt.Errorf("%w: %v", errUPFSession, err)
 	}
 
+	if s.charging != nil {
+		if err := s.authorizeCharging(ctx, sc, seid, req, addrs); err != nil {
+			return nil, ueAddresses{}, err
+		}
+	}
+
 	committed = true
 
+	if s.accounting != nil {
+		acct := AccountingSession{
+			Ref:    sc.Ref,
+			IMSI:   req.Supi.IMSI(),
+			Dnn:    req.Dnn,
+			Access: req.Access,
+			IPv4:   addrs.IPv4,
+			QoS:    accountingQoS(req.Policy),
+		}
+
+		if req.Snssai != nil {
+			acct.Snssai = *req.Snssai
+		}
+
+		if addrs.IPv6Prefix.IsValid() {
+			acct.IPv6Prefix = netip.PrefixFrom(addrs.IPv6Prefix, 64)
+		}
+
+		s.accounting.SessionStarted(acct)
+	}
+
 	return sc, addrs, nil
 }
 
@@ -203,6 +259,11 @@ func (a AnchorBinding) bound() bool {
 	return a.IPv4 != nil || a.IPv6 != nil
 }
 
+// sameNode reports whether a and b are endpoints on the same address.
+func (a AnchorBinding) sameNode(b AnchorBinding) bool {
+	return a.IPv4.Equal(b.IPv4) && a.IPv6.Equal(b.IPv6)
+}
+
 func (s *SMF) establishPFCPSession(ctx context.Context, smContext *SMContext) error {
 	ctx, span := tracer.Start(ctx, "smf/send_pfcp_rules",
 		trace.WithSpanKind(trace.SpanKindInternal),
@@ -234,6 +295,7 @@ func (s *SMF) establishPFCPSession(ctx context.Context, smContext *SMContext) er
 	}
 
 	req := tunnel.establishRequest(smContext.PFCPContext.SEID, smContext.Supi.IMSI(), policyID, smContext.FramedRoutes)
+	req.DNN = smContext.Dnn
 
 	resp, err := s.upf.EstablishSession(ctx, req)
 	if err != nil {
@@ -291,6 +353,7 @@ func (s *SMF) bindDownlink(ctx context.Context, sc *SMContext, access AccessType
 	if commit != nil {
 		policyID = commit.policy.PolicyID
 		next.QFI, next.AMBR = commit.policy.QosData.QFI, commit.policy.Ambr
+		next.Dedicated = AnchorBinding{}
 	}
 
 	if err := s.applyDataPlane(ctx, sc, next, policyID); err != nil {
@@ -328,10 +391,19 @@ func (s *SMF) finishBinding(ctx context.Context, sc *SMContext, dropped *dropped
 
 	s.dropSourceRouting(ctx, sc.Ref, dropped)
 
+	if dropped != nil {
+		s.policyMoved(sc)
+		s.qosFlowDropped(sc.Ref, dropped)
+	}
+
 	return nil
 }
 
 func (sc *SMContext) policyID() string {
+	if sc.qosFlow != nil {
+		return sc.qosFlow.filterID
+	}
+
 	if sc.PolicyData == nil {
 		return ""
 	}
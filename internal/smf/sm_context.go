@@ -77,6 +77,21 @@ type SMContext struct {
 	// previous configuration (§6.3.2.5). Guarded by Mutex.
 	pendingPolicy *Policy
 
+	// policyDecision is the decision of the external policy decision point in
+	// force, nil for a session on its local policy. Reconciliations and moves
+	// keep it applied. Guarded by Mutex.
+	policyDecision *PolicyDecision
+
+	// authorizedRules records that the DN-AAA server's Filter-Id set the
+	// session's network rules, which then stay as the UE moves between the
+	// areas of its policy's location variants. Guarded by Mutex.
+	authorizedRules bool
+
+	// qosFlow is the session's dedicated QoS flow, nil when it has none. While
+	// it is in force the UPF enforces the session with the flow's filters.
+	// Guarded by Mutex.
+	qosFlow *dedicatedQoSFlow
+
 	releasing                bool  // guarded by Mutex
 	establishmentPTI         uint8 // PTI of the Establishment Accept, 0 until sent; guarded by Mutex
 	establishmentOutstanding bool
@@ -86,6 +101,10 @@ type SMContext struct {
 	pending *pendingTransfer
 
 	transferGuard guard.Guard
+
+	// sessionTimeout releases the session when the Session-Timeout the DN-AAA
+	// server granted runs out; stopped when the session leaves the pool.
+	sessionTimeout guard.Guard
 }
 
 // stopProcedureTimer stops the retransmission guard; safe to call when none is
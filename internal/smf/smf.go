@@ -36,6 +36,10 @@ var ErrDNNNotInSlice = errors.New("data network not found in slice")
 // and DNN.
 var ErrNoPolicyMatch = errors.New("no matching policy for slice and DNN")
 
+// ErrDataBarred indicates that the subscriber's lifecycle state bars it from
+// holding PDU sessions.
+var ErrDataBarred = errors.New("subscriber barred from data")
+
 // For a caller holding a routing context of its own — the AMF's SmContextList
 // entry — this is the signal the session is gone for good, as opposed to a
 // transient failure worth retrying.
@@ -53,6 +57,7 @@ type SessionQuerier interface {
 	GetSession(ref string) *SMContext
 	SessionsByDNN(dnn string) []*SMContext
 	SessionCount() int
+	SessionCountsBySlice() map[models.Snssai]int
 }
 
 // PCF abstracts the Policy Control Function (3GPP TS 23.503), backed by the local
@@ -70,6 +75,9 @@ type DNNStore interface {
 	ReleaseIPv6(ctx context.Context, imsi string, sessionKeyID uint8) (netip.Addr, error)
 	ListFramedRoutes(ctx context.Context, imsi string) ([]netip.Prefix, error)
 	GetStaticIP(ctx context.Context, imsi string, ipv6 bool) (netip.Addr, bool, error)
+	// ReserveIP leases a specific IPv4 address, the one a DN-AAA server
+	// authorized for the session.
+	ReserveIP(ctx context.Context, imsi string, sessionKeyID uint8, addr netip.Addr) (netip.Addr, error)
 }
 
 // SessionStore is the minimal DB surface the SMF needs for session-level
@@ -77,6 +85,7 @@ type DNNStore interface {
 type SessionStore interface {
 	ResolveDNN(ctx context.Context, dnn string) (DNNStore, error)
 	IncrementDailyUsage(ctx context.Context, imsi string, uplinkBytes, downlinkBytes uint64) error
+	IncrementRatingGroupUsage(ctx context.Context, imsi string, usage models.RatingGroupUsage) error
 	InsertFlowReports(ctx context.Context, reports []*models.FlowReportRequest) error
 }
 
@@ -85,12 +94,15 @@ type UPFClient interface {
 	EstablishSession(ctx context.Context, req *models.EstablishRequest) (*models.EstablishResponse, error)
 	ModifySession(ctx context.Context, req *models.ModifyRequest) error
 	FlushUsage(ctx context.Context, seid uint64)
+	// SetUsageThreshold makes the session report its usage once it has
+	// carried bytes octets since its last report; zero clears it.
+	SetUsageThreshold(ctx context.Context, seid uint64, bytes uint64)
 	DeleteSession(ctx context.Context, seid uint64) error
 	SuppressDownlinkDataNotification(ctx context.Context, seid uint64)
 	ClearDownlinkDataNotification(ctx context.Context, seid uint64)
 	UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error
 	RegisterIPv6Session(ctx context.Context, reg *models.IPv6SessionRegistration) error
-	UnregisterIPv6Session(ctx context.Context, ulTEID uint32) error
+	UnregisterIPv6Session(ctx context.Context, seid uint64, ulTEID uint32) error
 }
 
 // AMFCallback abstracts the SMF → AMF communication.
@@ -107,6 +119,59 @@ type AMFCallback interface {
 type MMECallback interface {
 	Page(ctx context.Context, imsi string) error
 	SessionDropped(ctx context.Context, imsi string, ebi uint8, ref string)
+	// DeactivateSession tears down the PDN connection of EPS session ref on
+	// the network's initiative.
+	DeactivateSession(ctx context.Context, imsi string, ebi uint8, ref string) error
+	// ActivateDedicatedBearer adds a dedicated bearer for flow to the PDN
+	// connection of EPS session ref; the outcome is reported through
+	// DedicatedBearerUp and DedicatedBearerReleased. DeactivateDedicatedBearer
+	// removes it. Both are called with the session locked and must not call
+	// back into the SMF.
+	ActivateDedicatedBearer(ctx context.Context, imsi string, ebi uint8, ref string, flow models.GBRQosFlow) error
+	DeactivateDedicatedBearer(ctx context.Context, imsi string, ebi uint8, ref string) error
+}
+
+// Accounting is told when sessions start, change QoS and stop, and what
+// traffic they carry, for RADIUS accounting and charging records. Its
+// methods are called on signalling paths and must return quickly.
+type Accounting interface {
+	SessionStarted(sess AccountingSession)
+	SessionUsage(ref string, uplinkBytes, downlinkBytes uint64)
+	SessionModified(ref string, qos AccountingQoS)
+	SessionStopped(ref string)
+}
+
+// AccountingSession describes an established session to Accounting.
+type AccountingSession struct {
+	Ref        string
+	IMSI       string
+	Dnn        string
+	Snssai     models.Snssai
+	Access     AccessType
+	IPv4       netip.Addr
+	IPv6Prefix netip.Prefix
+	QoS        AccountingQoS
+}
+
+// AccountingQoS is the QoS a session is charged under.
+type AccountingQoS struct {
+	FiveQI int32
+	Ambr   models.Ambr
+}
+
+func accountingQoS(p *Policy) AccountingQoS {
+	if p == nil {
+		return AccountingQoS{}
+	}
+
+	return AccountingQoS{FiveQI: p.QosData.Var5qi, Ambr: p.Ambr}
+}
+
+// policyCommitted tells Accounting a session now runs under a new policy.
+func (s *SMF) policyCommitted(sc *SMContext) {
+	if s.accounting != nil {
+		s.accounting.SessionModified(sc.Ref, accountingQoS(sc.PolicyData))
+	}
 }
 
 // ResolvedNetworkRule represents a network rule attached to a policy for PDI/SDF filtering.
@@ -154,6 +219,16 @@ type SMF struct {
 
 	t3591 time.Duration // network-requested modification command retransmission
 	t3592 time.Duration // network-requested release command retransmission
+
+	aaa         DNAAA
+	authTimeout time.Duration           // how long the UE has to answer an authentication command
+	pendingAuth map[string]*pendingAuth // guarded by mu; key: the reserved Ref
+
+	accounting Accounting
+	charging   OnlineCharging
+	policy     PolicyControl
+	qosFlows   QoSFlowEvents
+	quotas     SessionQuotas
 }
 
 // maxSMProcedureRetransmissions is the number of command retransmissions before
@@ -174,6 +249,19 @@ func WithT3591(d time.Duration) Option { return func(s *SMF) { s.t3591 = d } }
 // WithT3592 overrides the network-requested release retransmission interval.
 func WithT3592(d time.Duration) Option { return func(s *SMF) { s.t3592 = d } }
 
+// WithDNAAA enables secondary authentication against the data networks'
+// DN-AAA servers.
+func WithDNAAA(aaa DNAAA) Option { return func(s *SMF) { s.aaa = aaa } }
+
+// WithAccounting reports session lifetimes and usage to acct.
+func WithAccounting(acct Accounting) Option { return func(s *SMF) { s.accounting = acct } }
+
+// WithSecondaryAuthTimeout overrides how long the UE has to answer a PDU
+// session authentication command.
+func WithSecondaryAuthTimeout(d time.Duration) Option {
+	return func(s *SMF) { s.authTimeout = d }
+}
+
 // New creates a new SMF.
 func New(pcf PCF, store SessionStore, upf UPFClient, amf AMFCallback, opts ...Option) *SMF {
 	s := &SMF{
@@ -187,6 +275,9 @@ func New(pcf PCF, store SessionStore, upf UPFClient, amf AMFCallback, opts ...Op
 		clock:  time.Now,
 		t3591:  16 * time.Second, // TS 24.501 table 10.3.2
 		t3592:  16 * time.Second, // TS 24.501 table 10.3.2
+
+		authTimeout: 30 * time.Second,
+		pendingAuth: make(map[string]*pendingAuth),
 	}
 	for _, o := range opts {
 		o(s)
@@ -216,6 +307,12 @@ func (s *SMF) AllocateSEID() uint64 {
 }
 
 func (s *SMF) NewSession(supi etsi.SUPI, access AccessType, id SessionIdentity, dnn string, snssai *models.Snssai) (*SMContext, error) {
+	return s.newSession(supi, access, id, dnn, snssai, "")
+}
+
+// newSession creates the session under ref, one reserved by reserveRef, or
+// under a fresh Ref when ref is empty.
+func (s *SMF) newSession(supi etsi.SUPI, access AccessType, id SessionIdentity, dnn string, snssai *models.Snssai, ref string) (*SMContext, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -229,7 +326,9 @@ func (s *SMF) NewSession(supi etsi.SUPI, access AccessType, id SessionIdentity,
 		}
 	}
 
-	s.refSeq++
+	if ref == "" {
+		ref = s.nextRefLocked(supi, id)
+	}
 
 	ctx := &SMContext{
 		SessionIdentity: id,
@@ -237,7 +336,7 @@ func (s *SMF) NewSession(supi etsi.SUPI, access AccessType, id SessionIdentity,
 		Access:          access,
 		Dnn:             dnn,
 		Snssai:          snssai,
-		Ref:             fmt.Sprintf("%s#%d", canonicalName(supi, id.sessionKey()), s.refSeq),
+		Ref:             ref,
 	}
 
 	s.pool[ctx.Ref] = ctx
@@ -249,6 +348,13 @@ func (s *SMF) NewSession(supi etsi.SUPI, access AccessType, id SessionIdentity,
 	return ctx, nil
 }
 
+// nextRefLocked returns a Ref no session instance has used. s.mu must be held.
+func (s *SMF) nextRefLocked(supi etsi.SUPI, id SessionIdentity) string {
+	s.refSeq++
+
+	return fmt.Sprintf("%s#%d", canonicalName(supi, id.sessionKey()), s.refSeq)
+}
+
 // GetSession retrieves a session by its unique Ref.
 func (s *SMF) GetSession(ref string) *SMContext {
 	s.mu.RLock()
@@ -295,8 +401,37 @@ func (s *SMF) supersedeIdentityHolders(ctx context.Context, supi etsi.SUPI, id S
 
 func (s *SMF) dropFromPool(sc *SMContext) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	held := s.pool[sc.Ref] == sc
+	s.unindex(sc)
+	s.mu.Unlock()
+
+	sc.sessionTimeout.Stop()
+
+	if !held {
+		return
+	}
+
+	if s.accounting != nil {
+		s.accounting.SessionStopped(sc.Ref)
+	}
+
+	if s.charging != nil {
+		s.charging.SessionStopped(sc.Ref)
+	}
+
+	if s.policy != nil {
+		s.policy.SessionStopped(sc.Ref)
+	}
+
+	if s.qosFlows != nil {
+		s.qosFlows.SessionStopped(sc.Ref)
+	}
 
+	s.releaseAdmission(sc)
+}
+
+// unindex removes sc from the pool and its indexes. s.mu must be held.
+func (s *SMF) unindex(sc *SMContext) {
 	delete(s.pool, sc.Ref)
 
 	for seid, held := range s.bySEID {
@@ -366,6 +501,32 @@ func (s *SMF) SessionsByDNN(dnn string) []*SMContext {
 	return out
 }
 
+// SessionOfAddress finds the session of IMSI imsi that holds UE IPv4
+// address ueIP.
+func (s *SMF) SessionOfAddress(imsi string, ueIP netip.Addr) (string, bool) {
+	s.mu.RLock()
+	sessions := make([]*SMContext, 0, len(s.pool))
+
+	for _, sc := range s.pool {
+		sessions = append(sessions, sc)
+	}
+	s.mu.RUnlock()
+
+	for _, sc := range sessions {
+		sc.Mutex.Lock()
+		addr, _ := netip.AddrFromSlice(sc.PDUIPV4Address)
+		match := !sc.releasing && sc.Supi.IsIMSI() && sc.Supi.IMSI() == imsi && addr.Unmap() == ueIP
+		ref := sc.Ref
+		sc.Mutex.Unlock()
+
+		if match {
+			return ref, true
+		}
+	}
+
+	return "", false
+}
+
 func (s *SMF) SessionCount() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
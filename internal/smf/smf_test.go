@@ -27,6 +27,7 @@ type fakeStore struct {
 	releasedIP      netip.Addr
 	releasedIPv6    netip.Addr
 	usageLog        []usageEntry
+	ratingLog       []models.RatingGroupUsage
 	flowLog         []models.FlowReportRequest
 	releasedIPs     []string
 	releasedIPv6s   []string
@@ -40,6 +41,7 @@ type fakeStore struct {
 	staticIPErr     error
 	opLog           []string
 	allocSessionLog []uint8
+	reservedIPs     []netip.Addr
 }
 
 func (f *fakeStore) ResolveDNN(_ context.Context, _ string) (smf.DNNStore, error) {
@@ -88,6 +90,17 @@ func (f *fakeStore) AllocateIP(_ context.Context, _ string, sessionKeyID uint8)
 	return f.allocatedIP, f.err
 }
 
+func (f *fakeStore) ReserveIP(_ context.Context, _ string, sessionKeyID uint8, addr netip.Addr) (netip.Addr, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.opLog = append(f.opLog, "reserve")
+	f.allocSessionLog = append(f.allocSessionLog, sessionKeyID)
+	f.reservedIPs = append(f.reservedIPs, addr)
+
+	return addr, f.err
+}
+
 func (f *fakeStore) ReleaseIP(_ context.Context, imsi string, _ uint8) (netip.Addr, error) {
 	f.teardownSeq.record("release-ip")
 
@@ -167,6 +180,15 @@ func (f *fakeStore) IncrementDailyUsage(_ context.Context, imsi string, uplinkBy
 	return f.err
 }
 
+func (f *fakeStore) IncrementRatingGroupUsage(_ context.Context, _ string, usage models.RatingGroupUsage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.ratingLog = append(f.ratingLog, usage)
+
+	return f.err
+}
+
 func (f *fakeStore) InsertFlowReports(_ context.Context, reports []*models.FlowReportRequest) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -187,6 +209,7 @@ type fakeUPF struct {
 	deleteCalls      []deletionCall
 	suppressDDNCalls []uint64
 	clearDDNCalls    []uint64
+	thresholds       map[uint64]uint64
 	lastIPv6Reg      *models.IPv6SessionRegistration
 	err              error
 }
@@ -240,6 +263,17 @@ func (f *fakeUPF) ClearDownlinkDataNotification(_ context.Context, seid uint64)
 
 func (f *fakeUPF) FlushUsage(_ context.Context, _ uint64) {}
 
+func (f *fakeUPF) SetUsageThreshold(_ context.Context, seid uint64, bytes uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.thresholds == nil {
+		f.thresholds = make(map[uint64]uint64)
+	}
+
+	f.thresholds[seid] = bytes
+}
+
 func (f *fakeUPF) UpdateFilters(_ context.Context, _ string, _ models.Direction, _ []models.FilterRule) error {
 	return nil
 }
@@ -253,7 +287,7 @@ func (f *fakeUPF) RegisterIPv6Session(_ context.Context, reg *models.IPv6Session
 	return nil
 }
 
-func (f *fakeUPF) UnregisterIPv6Session(_ context.Context, _ uint32) error {
+func (f *fakeUPF) UnregisterIPv6Session(_ context.Context, _ uint64, _ uint32) error {
 	return nil
 }
 
@@ -367,7 +401,13 @@ type fakeMME struct {
 	mu           sync.Mutex
 	pagedIMSI    []string
 	droppedCalls []mmeTransferredCall
-	err          error
+	// deactivatedCalls records DeactivateSession calls.
+	deactivatedCalls []mmeTransferredCall
+	// bearerCalls and bearerReleases record ActivateDedicatedBearer and
+	// DeactivateDedicatedBearer calls.
+	bearerCalls    []models.GBRQosFlow
+	bearerReleases []mmeTransferredCall
+	err            error
 }
 
 type mmeTransferredCall struct {
@@ -383,6 +423,33 @@ func (f *fakeMME) SessionDropped(_ context.Context, imsi string, ebi uint8, ref
 	f.droppedCalls = append(f.droppedCalls, mmeTransferredCall{imsi, ebi, ref})
 }
 
+func (f *fakeMME) DeactivateSession(_ context.Context, imsi string, ebi uint8, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deactivatedCalls = append(f.deactivatedCalls, mmeTransferredCall{imsi, ebi, ref})
+
+	return f.err
+}
+
+func (f *fakeMME) ActivateDedicatedBearer(_ context.Context, _ string, _ uint8, _ string, flow models.GBRQosFlow) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.bearerCalls = append(f.bearerCalls, flow)
+
+	return f.err
+}
+
+func (f *fakeMME) DeactivateDedicatedBearer(_ context.Context, imsi string, ebi uint8, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.bearerReleases = append(f.bearerReleases, mmeTransferredCall{imsi, ebi, ref})
+
+	return f.err
+}
+
 func (f *fakeMME) dropped() []mmeTransferredCall {
 	f.mu.Lock()
 	defer f.mu.Unlock()
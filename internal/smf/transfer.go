@@ -40,6 +40,7 @@ type droppedSource struct {
 	access   AccessType
 	id       SessionIdentity
 	upActive bool
+	qosFlow  bool // the session's dedicated QoS flow was dropped
 }
 
 func (s *SMF) findTransferable(supi etsi.SUPI, pduSessionID uint8, req transferRequest) (*SMContext, error) {
@@ -210,7 +211,7 @@ func (s *SMF) beginTransferCommit(ctx context.Context, sc *SMContext, access Acc
 		source:   source,
 		sourceID: sourceID,
 		sourceUP: sourceUP,
-		policy:   transferPolicy(sc.PolicyData, move.policy),
+		policy:   sc.policyDecision.apply(transferPolicy(sc.PolicyData, move.policy)),
 		restore: func() {
 			sc.Access = source
 			s.assignEPSBearerIdentity(ctx, sc, sourceID.EBI)
@@ -221,11 +222,17 @@ func (s *SMF) beginTransferCommit(ctx context.Context, sc *SMContext, access Acc
 func (sc *SMContext) finishTransferCommit(c *transferCommit) *droppedSource {
 	sc.PolicyData = c.policy
 
+	// A dedicated QoS flow is not mapped across the move, whether it rode a
+	// QoS flow in 5GS or a dedicated bearer in EPS: the session leaves on its
+	// default flow or bearer and the filters of its policy.
+	qosFlow := sc.qosFlow != nil
+	sc.qosFlow = nil
+
 	if sc.Access == Access4G {
 		sc.discardOutstandingProcedures()
 	}
 
-	return &droppedSource{supi: sc.Supi, access: c.source, id: c.sourceID, upActive: c.sourceUP}
+	return &droppedSource{supi: sc.Supi, access: c.source, id: c.sourceID, upActive: c.sourceUP, qosFlow: qosFlow}
 }
 
 func transferPolicy(current, target *Policy) *Policy {
@@ -79,7 +79,7 @@ func (s *SMF) transferTo5GS(
 	logger.WithTrace(ctx, logger.SmfLog).Info("moving a PDN connection onto 5GS",
 		logger.SUPI(supi.String()), logger.PDUSessionID(pduSessionID), zap.String("dnn", dnn))
 
-	if err := s.sendPduSessionEstablishmentAccept(ctx, sc, policy, pco, addrs, pti, nil, alwaysOnIndication(req.AlwaysOnRequested), epsBearerIdentity); err != nil {
+	if err := s.sendPduSessionEstablishmentAccept(ctx, sc, policy, pco, addrs, pti, nil, alwaysOnIndication(req.AlwaysOnRequested), epsBearerIdentity, nil); err != nil {
 		sc.abandonTransferTo(Access5G)
 
 		return "", nil, fmt.Errorf("failed to send the establishment accept for a moved session: %w", err)
@@ -143,7 +143,7 @@ func handoverRequestTransferForArrival(sc *SMContext, epsBearerIdentity uint8, t
 		return nil, fmt.Errorf("%w: PDU session %d is EPS bearer %d, not %d", ErrSessionNotMovable, sc.PDUSessionID, sc.EBI, epsBearerIdentity)
 	}
 
-	policy := transferPolicy(sc.PolicyData, target)
+	policy := sc.policyDecision.apply(transferPolicy(sc.PolicyData, target))
 
 	n2, err := smfNgap.BuildHandoverRequestTransfer(&policy.Ambr, &policy.QosData,
 		sc.Tunnel.N3TEID, sc.Tunnel.N3IPv4, sc.Tunnel.N3IPv6,
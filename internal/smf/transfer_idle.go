@@ -73,6 +73,8 @@ func (s *SMF) TransferIdle(ctx context.Context, supi etsi.SUPI, pduSessionID, eb
 		zap.Uint8("ebi", ebi), zap.String("dnn", dnn), zap.Stringer("to", access))
 
 	s.dropSourceRouting(ctx, sc.Ref, dropped)
+	s.policyMoved(sc)
+	s.qosFlowDropped(sc.Ref, dropped)
 
 	return sc.Ref, nil
 }
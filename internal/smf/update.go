@@ -47,6 +47,10 @@ func (s *SMF) UpdateSmContextN1Msg(ctx context.Context, smContextRef string, n1M
 
 	smContext := s.GetSession(smContextRef)
 	if smContext == nil {
+		if pa := s.lookupPendingAuth(smContextRef); pa != nil {
+			return s.continueSecondaryAuth(ctx, pa, n1Msg)
+		}
+
 		return nil, fmt.Errorf("%w: %s", ErrSMContextNotFound, smContextRef)
 	}
 
@@ -147,8 +151,11 @@ func (s *SMF) handleUpdateN1Msg(ctx context.Context, n1Msg []byte, smContext *SM
 		if smContext.pendingPolicy != nil {
 			smContext.PolicyData = smContext.pendingPolicy
 			smContext.pendingPolicy = nil
+			s.policyCommitted(smContext)
 		}
 
+		s.qosFlowAccepted(smContext)
+
 		return nil, nil
 
 	case *fgs.PDUSessionModificationCommandReject:
@@ -158,6 +165,7 @@ func (s *SMF) handleUpdateN1Msg(ctx context.Context, n1Msg []byte, smContext *SM
 		smContext.stopProcedureTimer()
 		smContext.ClearPTIInUse(pti)
 		smContext.pendingPolicy = nil
+		s.qosFlowRejected(ctx, smContext, "the UE rejected the modification")
 
 		return nil, nil
 
@@ -453,7 +461,7 @@ func (s *SMF) UpdateSmContextCauseDuplicatePDUSessionID(ctx context.Context, smC
 // responder if the session has a delegated IPv6 prefix and the gNB's tunnel
 // endpoint is known. Must be called with smContext.Mutex held.
 func (s *SMF) registerIPv6SessionIfNeeded(ctx context.Context, smContext *SMContext, access AccessType) {
-	if smContext.PDUIPV6Prefix == nil || smContext.Tunnel == nil {
+	if smContext.PDUIPV6Prefix == nil || smContext.Tunnel == nil || smContext.PFCPContext == nil {
 		return
 	}
 
@@ -496,6 +504,7 @@ func (s *SMF) registerIPv6SessionIfNeeded(ctx context.Context, smContext *SMCont
 	}
 
 	reg := &models.IPv6SessionRegistration{
+		SEID:         smContext.PFCPContext.SEID,
 		UplinkTEID:   smContext.Tunnel.N3TEID,
 		DownlinkTEID: anInfo.TEID,
 		GnbN3Addr:    gnbIP,
@@ -13,6 +13,8 @@ func getGSMMessageName(msgType uint8) string {
 		return "PDU Session Establishment Accept"
 	case fgs.MsgPDUSessionEstablishmentReject:
 		return "PDU Session Establishment Reject"
+	case fgs.MsgPDUSessionAuthenticationCommand:
+		return "PDU Session Authentication Command"
 	case fgs.MsgPDUSessionAuthenticationComplete:
 		return "PDU Session Authentication Complete"
 	case fgs.MsgPDUSessionModificationRequest:
@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+	"go.uber.org/zap"
+)
+
+// breakoutDatapath is the part of the datapath the tracker programs.
+// *ebpf.BpfObjects satisfies it; a fake satisfies it in tests.
+type breakoutDatapath interface {
+	HasBreakoutEgress() bool
+	PutBreakoutEgress(index uint8, egress ebpf.DataNetworkEgress) error
+	DeleteBreakoutEgress(index uint8) error
+}
+
+// breakoutTracker gives each distinct breakout egress a datapath index and
+// keeps its entry programmed while a filter refers to it. Rules breaking out
+// the same way share an index, across policies.
+type breakoutTracker struct {
+	dp         breakoutDatapath
+	resolveFor func(models.BreakoutEgress) (resolvedEgress, error)
+
+	mu       sync.Mutex
+	indices  map[models.BreakoutEgress]uint8
+	resolved map[uint8]resolvedEgress
+	refs     map[string][]models.BreakoutEgress // by filter key
+	free     []uint8
+	next     int
+	warned   bool
+}
+
+func newBreakoutTracker(dp breakoutDatapath, resolveFor func(models.BreakoutEgress) (resolvedEgress, error)) *breakoutTracker {
+	return &breakoutTracker{
+		dp:         dp,
+		resolveFor: resolveFor,
+		indices:    make(map[models.BreakoutEgress]uint8),
+		resolved:   make(map[uint8]resolvedEgress),
+		refs:       make(map[string][]models.BreakoutEgress),
+		next:       1,
+	}
+}
+
+// resolve returns rules with every breakout rule bound to its egress index.
+// A rule left unbound allows its traffic along the data network's path: one
+// whose egress cannot be resolved or programmed is reported in the returned
+// error, and every one on a datapath without the breakout map is logged once.
+func (t *breakoutTracker) resolve(key string, rules []models.FilterRule) ([]models.FilterRule, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]models.FilterRule, 0, len(rules))
+	keys := make([]models.BreakoutEgress, 0)
+
+	var errs []error
+
+	for _, rule := range rules {
+		rule.BreakoutIndex = 0
+
+		if rule.Action != models.Breakout {
+			out = append(out, rule)
+			continue
+		}
+
+		// The API refuses breakout rules on this datapath; one written
+		// through another node still passes its traffic.
+		if !t.dp.HasBreakoutEgress() {
+			if !t.warned {
+				logger.UpfLog.Error("breakout network rules follow the data network's egress", zap.Error(ebpf.ErrBreakoutUnsupported))
+				t.warned = true
+			}
+
+			out = append(out, rule)
+
+			continue
+		}
+
+		index, err := t.acquireLocked(rule.Breakout)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("breakout via %s: %w", rule.Breakout.Interface, err))
+			out = append(out, rule)
+
+			continue
+		}
+
+		rule.BreakoutIndex = index
+		keys = append(keys, rule.Breakout)
+		out = append(out, rule)
+	}
+
+	previous := t.refs[key]
+
+	if len(keys) == 0 {
+		delete(t.refs, key)
+	} else {
+		t.refs[key] = keys
+	}
+
+	t.releaseUnusedLocked(previous)
+
+	return out, errors.Join(errs...)
+}
+
+// acquireLocked returns the index of target, resolving it again so a
+// recreated interface is picked up.
+func (t *breakoutTracker) acquireLocked(target models.BreakoutEgress) (uint8, error) {
+	r, err := t.resolveFor(target)
+	if err != nil {
+		return 0, err
+	}
+
+	index, ok := t.indices[target]
+	if ok && t.resolved[index] == r {
+		return index, nil
+	}
+
+	if !ok {
+		switch {
+		case len(t.free) > 0:
+			index = t.free[len(t.free)-1]
+			t.free = t.free[:len(t.free)-1]
+		case t.next < ebpf.MaxBreakoutEgress:
+			index = uint8(t.next)
+			t.next++
+		default:
+			return 0, errors.New("no breakout egress entries left")
+		}
+
+		t.indices[target] = index
+	}
+
+	if err := t.dp.PutBreakoutEgress(index, r.entry); err != nil {
+		if !ok {
+			delete(t.indices, target)
+			t.free = append(t.free, index)
+		}
+
+		return 0, err
+	}
+
+	t.resolved[index] = r
+
+	return index, nil
+}
+
+// releaseUnusedLocked clears the entries among candidates no filter refers
+// to.
+func (t *breakoutTracker) releaseUnusedLocked(candidates []models.BreakoutEgress) {
+	used := make(map[models.BreakoutEgress]bool)
+
+	for _, keys := range t.refs {
+		for _, k := range keys {
+			used[k] = true
+		}
+	}
+
+	for _, k := range candidates {
+		if used[k] {
+			continue
+		}
+
+		index, ok := t.indices[k]
+		if !ok {
+			continue
+		}
+
+		if err := t.dp.DeleteBreakoutEgress(index); err != nil {
+			logger.UpfLog.Warn("could not clear breakout egress", zap.String("iface", k.Interface), zap.Error(err))
+		}
+
+		delete(t.indices, k)
+		delete(t.resolved, index)
+		t.free = append(t.free, index)
+	}
+}
+
+// interfaces returns the interfaces the bound breakouts leave through, other
+// than N3 and N6, keyed by ifindex.
+func (t *breakoutTracker) interfaces(n3Index, n6Index int) map[int]datapathIface {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make(map[int]datapathIface)
+
+	for _, r := range t.resolved {
+		if r.attach.index != n3Index && r.attach.index != n6Index {
+			out[r.attach.index] = r.attach
+		}
+	}
+
+	return out
+}
+
+// resolveBreakout looks up a breakout's netdevs as a data network egress on
+// the same interface would be.
+func (u *UPF) resolveBreakout(b models.BreakoutEgress) (resolvedEgress, error) {
+	return u.resolveEgress(DataNetworkEgress{Interface: b.Interface, VlanID: b.VlanID, Table: b.Table})
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+type fakeBreakoutDatapath struct {
+	unsupported bool
+	entries     map[uint8]ebpf.DataNetworkEgress
+}
+
+func (f *fakeBreakoutDatapath) HasBreakoutEgress() bool { return !f.unsupported }
+
+func (f *fakeBreakoutDatapath) PutBreakoutEgress(index uint8, egress ebpf.DataNetworkEgress) error {
+	f.entries[index] = egress
+	return nil
+}
+
+func (f *fakeBreakoutDatapath) DeleteBreakoutEgress(index uint8) error {
+	delete(f.entries, index)
+	return nil
+}
+
+// fakeResolve maps interface names to ifindexes; "missing" does not exist.
+func fakeResolve(b models.BreakoutEgress) (resolvedEgress, error) {
+	indexes := map[string]int{"n6": 3, "eth2": 7, "eth3": 8}
+
+	index, ok := indexes[b.Interface]
+	if !ok {
+		return resolvedEgress{}, errors.New("no such interface")
+	}
+
+	return resolvedEgress{
+		attach: datapathIface{index: index, name: b.Interface},
+		entry:  ebpf.DataNetworkEgress{Table: uint32(b.Table), FibIfindex: uint32(index), Ifindex: uint32(index)},
+	}, nil
+}
+
+func breakoutRule(iface string) models.FilterRule {
+	return models.FilterRule{Action: models.Breakout, Breakout: models.BreakoutEgress{Interface: iface}}
+}
+
+func TestBreakoutTracker_SharesIndexAndReleases(t *testing.T) {
+	dp := &fakeBreakoutDatapath{entries: make(map[uint8]ebpf.DataNetworkEgress)}
+	tracker := newBreakoutTracker(dp, fakeResolve)
+
+	a, err := tracker.resolve("p1:uplink", []models.FilterRule{breakoutRule("eth2"), {Action: models.Allow}})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	b, err := tracker.resolve("p2:uplink", []models.FilterRule{breakoutRule("eth2"), breakoutRule("n6")})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if a[0].BreakoutIndex == 0 || a[0].BreakoutIndex != b[0].BreakoutIndex || a[1].BreakoutIndex != 0 {
+		t.Fatalf("indices %d %d %d, want eth2 bound once and shared", a[0].BreakoutIndex, b[0].BreakoutIndex, a[1].BreakoutIndex)
+	}
+
+	if got := dp.entries[a[0].BreakoutIndex].Ifindex; got != 7 {
+		t.Fatalf("entry ifindex = %d, want eth2's", got)
+	}
+
+	ifaces := tracker.interfaces(2, 3)
+	if _, ok := ifaces[7]; !ok || len(ifaces) != 1 {
+		t.Fatalf("interfaces = %v, want only eth2 beside N3 and N6", ifaces)
+	}
+
+	if _, err := tracker.resolve("p1:uplink", nil); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if len(dp.entries) != 2 {
+		t.Fatalf("entries = %v, want eth2 kept for p2", dp.entries)
+	}
+
+	if _, err := tracker.resolve("p2:uplink", nil); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if len(dp.entries) != 0 || len(tracker.interfaces(2, 3)) != 0 {
+		t.Fatalf("entries = %v, want every breakout cleared", dp.entries)
+	}
+}
+
+func TestBreakoutTracker_UnresolvedStaysOnDataNetworkPath(t *testing.T) {
+	dp := &fakeBreakoutDatapath{entries: make(map[uint8]ebpf.DataNetworkEgress)}
+	tracker := newBreakoutTracker(dp, fakeResolve)
+
+	rules, err := tracker.resolve("p1:uplink", []models.FilterRule{breakoutRule("missing"), breakoutRule("eth3")})
+	if err == nil {
+		t.Fatal("expected the missing interface to be reported")
+	}
+
+	if rules[0].BreakoutIndex != 0 || rules[1].BreakoutIndex == 0 {
+		t.Fatalf("indices %d %d, want only eth3 bound", rules[0].BreakoutIndex, rules[1].BreakoutIndex)
+	}
+
+	dp.unsupported = true
+
+	rules, err = tracker.resolve("p2:uplink", []models.FilterRule{breakoutRule("eth2")})
+	if err != nil || rules[0].BreakoutIndex != 0 {
+		t.Fatalf("got index %d (%v), want the rule left unbound without an error", rules[0].BreakoutIndex, err)
+	}
+}
@@ -31,6 +31,7 @@
 #include "bpf/utils/common.h"
 #include "bpf/utils/frag_needed.h"
 #include "bpf/utils/gtp.h"
+#include "bpf/utils/mss.h"
 #include "bpf/utils/tailcall.h"
 #include "bpf/utils/pdr.h"
 #include "bpf/utils/pdr_maps.h"
@@ -74,7 +75,7 @@ struct {
  * references them and n3_bpf.h may be processed first by clang-tidy. */
 enum ctx_action send_to_gtp_tunnel(struct packet_context *ctx,
 				   const struct far_info *far,
-				   __u8 tos, __u8 qfi);
+				   __u8 tos, __u8 qfi, __u64 seid);
 
 /*
  * fe80::/10 is rejected, so a future link-local-sourced feature (NS/NA proxy,
@@ -209,8 +210,12 @@ local_switch_to_ue(struct packet_context *ctx, const struct pdr_info *dl_pdr,
 	}
 
 	{
+		/* The sender's uplink rules do not pick the receiver's flow. */
+		ctx->qfi = 0;
+
 		enum ctx_action sdf_verdict =
-			match_sdf_filters(ctx, dl_pdr->filter_map_index);
+			match_sdf_filters(ctx, dl_pdr->filter_map_index,
+					  dl_pdr->local_seid);
 		if (sdf_verdict == CTX_ACT_DROP) {
 			account_flow(ctx, n3_ifindex, dl_pdr->imsi, ctx->ip4 ? IPV4 : IPV6, FLOW_DOWNLINK, DROP);
 			return drop_reported(ctx, UPF_DROP_SDF_FILTER);
@@ -240,7 +245,9 @@ local_switch_to_ue(struct packet_context *ctx, const struct pdr_info *dl_pdr,
 	account_flow(ctx, n3_ifindex, dl_pdr->imsi, ctx->ip4 ? IPV4 : IPV6, FLOW_DOWNLINK, ALLOW);
 
 	enum ctx_action tunnel_ret =
-		send_to_gtp_tunnel(ctx, dl_far, tos, dl_qer->qfi);
+		send_to_gtp_tunnel(ctx, dl_far, tos,
+				   ctx->qfi ? ctx->qfi : dl_qer->qfi,
+				   dl_pdr->local_seid);
 
 	if (ctx_action_forwards(tunnel_ret)) {
 		ctx->statistics->byte_counter.bytes += billed_bytes;
@@ -464,15 +471,30 @@ handle_gtp_packet(struct packet_context *ctx)
 			return drop_with(ctx, UPF_DROP_EXTHDR_INVALID);
 		}
 
+		fqdn_snoop(ctx);
+
 		PROFILE_START(PROF_N3_SDF_FILTER);
 		enum ctx_action sdf_verdict =
-			match_sdf_filters(ctx, pdr->filter_map_index);
+			match_sdf_filters(ctx, pdr->filter_map_index,
+					  pdr->local_seid);
 		PROFILE_END(PROF_N3_SDF_FILTER);
 		if (sdf_verdict == CTX_ACT_DROP) {
 			upf_printk("upf: uplink SDF drop teid:%d", teid);
 			account_flow(ctx, n6_ifindex, pdr->imsi, ctx->ip4 ? IPV4 : IPV6, FLOW_UPLINK, DROP);
 			return drop_reported(ctx, UPF_DROP_SDF_FILTER);
 		}
+
+		if (ctx->portal && !portal_redirect(ctx)) {
+			upf_printk("upf: uplink portal drop teid:%d", teid);
+			account_flow(ctx, n6_ifindex, pdr->imsi, ctx->ip6 ? IPV6 : IPV4, FLOW_UPLINK, DROP);
+			return drop_reported(ctx, UPF_DROP_SDF_FILTER);
+		}
+
+		/* The downlink the UE's MSS sizes comes back over the tunnel
+		 * this packet arrived on. */
+		clamp_tcp_mss(ctx, outer_header_removal == OHR_GTP_U_UDP_IPv6 ?
+					   GTP_ENCAP_SIZE_IPV6 :
+					   GTP_ENCAP_SIZE_IPV4);
 	}
 
 	if (local_switch && (ctx->ip4 || ctx->ip6) && !ctx->gtp) {
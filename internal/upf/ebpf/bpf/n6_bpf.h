@@ -31,11 +31,14 @@
 #include "bpf/utils/common.h"
 #include "bpf/utils/frag_needed.h"
 #include "bpf/utils/gtp.h"
+#include "bpf/utils/mss.h"
 #include "bpf/utils/pdr.h"
 #include "bpf/utils/qer.h"
 #include "bpf/utils/sdf.h"
+#include "bpf/utils/fqdn.h"
 #include "bpf/utils/urr.h"
 #include "bpf/utils/routing.h"
+#include "bpf/utils/s1u_bearer.h"
 #include "bpf/utils/statistics.h"
 #include "bpf/utils/nocp.h"
 
@@ -63,15 +66,17 @@ struct {
  */
 static __always_inline enum ctx_action
 send_to_gtp_tunnel(struct packet_context *ctx, const struct far_info *far,
-		   __u8 tos, __u8 qfi)
+		   __u8 tos, __u8 qfi, __u64 seid)
 {
+	const __u32 teid = s1u_bearer_teid(far, seid, qfi);
+
 	if (far->outer_header_creation & OHC_GTP_U_UDP_IPv6) {
 		PROFILE_START(PROF_N6_GTP_MANIP);
 		__u32 encap_result =
 			(far->outer_header_creation & OHC_NO_PSC) ?
 				add_gtp_over_ip6_headers_s1u(ctx, &far->localip,
 							     &far->remoteip,
-							     tos, far->teid) :
+							     tos, teid) :
 				add_gtp_over_ip6_headers(ctx, &far->localip,
 							 &far->remoteip, tos,
 							 qfi, far->teid);
@@ -102,7 +107,7 @@ send_to_gtp_tunnel(struct packet_context *ctx, const struct far_info *far,
 				add_gtp_over_ip4_headers_s1u(
 					ctx, ipv4_from_mapped(&far->localip),
 					ipv4_from_mapped(&far->remoteip), tos,
-					far->teid) :
+					teid) :
 				add_gtp_over_ip4_headers(
 					ctx, ipv4_from_mapped(&far->localip),
 					ipv4_from_mapped(&far->remoteip), tos,
@@ -182,6 +187,11 @@ static __always_inline __u16 handle_n6_packet_ipv4(struct packet_context *ctx)
 			if (translated)
 				return drop_with(ctx, UPF_DROP_NAT_UNSOLICITED);
 
+			/* No host socket owns a pool address: passed up, the
+			 * packet would be routed back out of N6. */
+			if (masquerade && nat_is_pool_addr(ue_addr))
+				return drop_with(ctx, UPF_DROP_NAT_UNSOLICITED);
+
 			return DEFAULT_CTX_ACTION;
 		}
 	}
@@ -239,6 +249,12 @@ static __always_inline __u16 handle_n6_packet_ipv4(struct packet_context *ctx)
 			return abort_with(ctx, UPF_DROP_MALFORMED_HEADER);
 	}
 
+	/* A captive portal's reply, now addressed to the UE, answers for the
+	 * server the UE asked. */
+	if (!portal_restore_source(ctx))
+		return abort_with(ctx, UPF_DROP_MALFORMED_HEADER);
+	ip4 = ctx->ip4;
+
 
 	ctx->interface = INTERFACE_N6;
 
@@ -313,11 +329,14 @@ static __always_inline __u16 handle_n6_packet_ipv4(struct packet_context *ctx)
 			     translated ? wire_sport : ctx->l4_sport,
 			     translated ? wire_dport : ctx->l4_dport);
 
+	fqdn_snoop(ctx);
+
 	/* SDF filter enforcement (downlink) */
 	{
 		PROFILE_START(PROF_N6_SDF_FILTER);
 		enum ctx_action sdf_verdict =
-			match_sdf_filters(ctx, pdr->filter_map_index);
+			match_sdf_filters(ctx, pdr->filter_map_index,
+					  pdr->local_seid);
 		PROFILE_END(PROF_N6_SDF_FILTER);
 		if (sdf_verdict == CTX_ACT_DROP) {
 			upf_printk("upf: downlink SDF drop ip:%pI4",
@@ -327,6 +346,14 @@ static __always_inline __u16 handle_n6_packet_ipv4(struct packet_context *ctx)
 		}
 	}
 
+	clamp_tcp_mss(ctx, encap_size);
+	if (CTX_L4_CSUM_VIA_HELPERS) {
+		/* As after destination_nat_apply. */
+		ip4 = ctx->ip4;
+		if (!ip4)
+			return abort_with(ctx, UPF_DROP_MALFORMED_HEADER);
+	}
+
 	__u8 tos = far->transport_level_marking >> 8;
 	upf_printk("upf: use mapping %pI4 -> TEID:%d", &ip4->daddr, far->teid);
 
@@ -336,7 +363,8 @@ static __always_inline __u16 handle_n6_packet_ipv4(struct packet_context *ctx)
 	account_flow(ctx, n3_ifindex, pdr->imsi, IPV4, FLOW_DOWNLINK, ALLOW);
 
 	/* Only if the frame leaves: encapsulation and routing can still fail. */
-	enum ctx_action tunnel_ret = send_to_gtp_tunnel(ctx, far, tos, qer->qfi);
+	enum ctx_action tunnel_ret = send_to_gtp_tunnel(
+		ctx, far, tos, ctx->qfi ? ctx->qfi : qer->qfi, pdr->local_seid);
 
 	if (ctx_action_forwards(tunnel_ret)) {
 		/* Exported throughput follows the verdict, as billing does. */
@@ -411,6 +439,8 @@ handle_n6_packet_ipv6(struct packet_context *ctx)
 
 	frag_record6(ctx);
 
+	fqdn_snoop(ctx);
+
 	// IPv6 is not NATed (each UE owns its /64), so the inner L4 checksum is
 	// unchanged; the outer GTP-over-IPv6 UDP checksum is built during
 	// encapsulation in gtp.h.
@@ -428,7 +458,8 @@ handle_n6_packet_ipv6(struct packet_context *ctx)
 	{
 		PROFILE_START(PROF_N6_SDF_FILTER);
 		enum ctx_action sdf_verdict =
-			match_sdf_filters(ctx, pdr->filter_map_index);
+			match_sdf_filters(ctx, pdr->filter_map_index,
+					  pdr->local_seid);
 		PROFILE_END(PROF_N6_SDF_FILTER);
 		if (sdf_verdict == CTX_ACT_DROP) {
 			upf_printk("upf: downlink SDF drop ip:%pI6c",
@@ -438,6 +469,13 @@ handle_n6_packet_ipv6(struct packet_context *ctx)
 		}
 	}
 
+	clamp_tcp_mss(ctx, encap_size);
+	if (CTX_L4_CSUM_VIA_HELPERS) {
+		ip6 = ctx->ip6;
+		if (!ip6)
+			return abort_with(ctx, UPF_DROP_MALFORMED_HEADER);
+	}
+
 	upf_printk("upf: downlink session for ip:%pI6c action:%d", &ip6->daddr,
 		   far->action);
 
@@ -496,7 +534,8 @@ handle_n6_packet_ipv6(struct packet_context *ctx)
 	account_flow(ctx, n3_ifindex, pdr->imsi, IPV6, FLOW_DOWNLINK, ALLOW);
 
 	/* As in the IPv4 path: billing follows the verdict. */
-	enum ctx_action tunnel_ret = send_to_gtp_tunnel(ctx, far, tos, qer->qfi);
+	enum ctx_action tunnel_ret = send_to_gtp_tunnel(
+		ctx, far, tos, ctx->qfi ? ctx->qfi : qer->qfi, pdr->local_seid);
 
 	if (ctx_action_forwards(tunnel_ret)) {
 		/* Exported throughput follows the verdict, as billing does. */
@@ -0,0 +1,164 @@
+/**
+ * SPDX-FileCopyrightText: Ella Networks Inc.
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include "bpf/ctx/ctx.h"
+#include "bpf/utils/ip_addr.h"
+#include "bpf/utils/packet_context.h"
+#include <linux/bpf.h>
+#include <linux/in6.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_helpers.h>
+
+/*
+ * FQDN network rules. A rule with a non-zero fqdn_set matches remote addresses
+ * in sdf_fqdn_addrs instead of its prefix. The datapath only copies candidate
+ * payloads (DNS answers from N6, TLS ClientHellos toward N6) to userspace,
+ * which parses them, programs the learned addresses and expires them on the
+ * record TTL. Snooping is best effort: the packet is never held back, so a
+ * flow opened before its address is programmed is filtered as unknown.
+ */
+
+#define FQDN_MAX_ADDRS 65536
+#define FQDN_SNOOP_MAX_PAYLOAD 1280
+#define FQDN_DNS_PORT 53
+#define FQDN_TLS_PORT 443
+#define FQDN_TLS_HANDSHAKE 0x16
+
+enum fqdn_snoop_kind {
+	FQDN_SNOOP_DNS = 1,
+	FQDN_SNOOP_TLS = 2,
+};
+
+struct sdf_fqdn_key {
+	__u16 set;
+	__u16 pad;
+	struct in6_addr addr; /* ::ffff:x.x.x.x for IPv4, native for IPv6 */
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, struct sdf_fqdn_key);
+	__type(value, __u8);
+	__uint(max_entries, FQDN_MAX_ADDRS);
+} sdf_fqdn_addrs SEC(".maps");
+
+/* Index 0 is non-zero while any FQDN rule is installed, so sessions without
+ * one pay a single array lookup. */
+struct {
+	__uint(type, BPF_MAP_TYPE_ARRAY);
+	__type(key, __u32);
+	__type(value, __u32);
+	__uint(max_entries, 1);
+} fqdn_snoop_config SEC(".maps");
+
+struct fqdn_snoop_event {
+	__u8 kind;
+	__u8 pad;
+	__u16 len;
+	struct in6_addr remote;
+	__u8 payload[FQDN_SNOOP_MAX_PAYLOAD];
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_RINGBUF);
+	__uint(key, 0);
+	__uint(value, 0);
+	__uint(max_entries, 1 << 20);
+} fqdn_snoop_events SEC(".maps");
+
+static __always_inline bool fqdn_snoop_enabled(void)
+{
+	__u32 zero = 0;
+	__u32 *enabled = bpf_map_lookup_elem(&fqdn_snoop_config, &zero);
+
+	return enabled && *enabled;
+}
+
+/* A full ring drops the sample. */
+static __always_inline void fqdn_snoop_copy(struct packet_context *ctx,
+					    __u8 kind, __u32 off,
+					    const struct in6_addr *remote)
+{
+	__u64 total = ctx_full_len(ctx->ctx_buff);
+
+	if (off >= total)
+		return;
+
+	/* 64-bit, so the clamp bounds the very register handed to the
+	 * helper rather than a zero-extended copy. */
+	__u64 len = total - off;
+
+	if (len > FQDN_SNOOP_MAX_PAYLOAD)
+		len = FQDN_SNOOP_MAX_PAYLOAD;
+
+	if (len == 0)
+		return;
+
+	struct fqdn_snoop_event *ev = bpf_ringbuf_reserve(
+		&fqdn_snoop_events, sizeof(struct fqdn_snoop_event), 0);
+	if (!ev)
+		return;
+
+	ev->kind = kind;
+	ev->pad = 0;
+	ev->len = len;
+	ev->remote = *remote;
+
+	if (ctx_load_bytes(ctx->ctx_buff, off, ev->payload, len) < 0) {
+		bpf_ringbuf_discard(ev, 0);
+		return;
+	}
+
+	bpf_ringbuf_submit(ev, 0);
+}
+
+/*
+ * fqdn_snoop – hand DNS answers arriving on N6 and TLS handshakes leaving
+ * toward N6 to userspace. Call after parse_l4 on the inner packet. Fragments
+ * and TCP-carried DNS are not followed.
+ */
+static __always_inline void fqdn_snoop(struct packet_context *ctx)
+{
+	if (ctx->is_fragment || ctx->l4_unavailable)
+		return;
+
+	if (!fqdn_snoop_enabled())
+		return;
+
+	const bool from_n6 = ctx->interface == INTERFACE_N6;
+	struct in6_addr remote = {};
+
+	if (ctx->ip4)
+		ipv4_to_mapped(&remote,
+			       from_n6 ? ctx->ip4->saddr : ctx->ip4->daddr);
+	else if (ctx->ip6)
+		remote = from_n6 ? ctx->ip6->saddr : ctx->ip6->daddr;
+	else
+		return;
+
+	const void *start = ctx_data(ctx->ctx_buff);
+
+	if (from_n6 && ctx->udp && ctx->l4_proto == IPPROTO_UDP &&
+	    ctx->l4_sport == FQDN_DNS_PORT) {
+		fqdn_snoop_copy(ctx, FQDN_SNOOP_DNS,
+				(const void *)(ctx->udp + 1) - start, &remote);
+		return;
+	}
+
+	if (!from_n6 && ctx->tcp && ctx->l4_proto == IPPROTO_TCP &&
+	    ctx->l4_dport == FQDN_TLS_PORT) {
+		__u32 off = (const void *)ctx->tcp - start + ctx->tcp->doff * 4;
+		__u8 record_type = 0;
+
+		if (ctx_load_bytes(ctx->ctx_buff, off, &record_type,
+				   sizeof(record_type)) < 0 ||
+		    record_type != FQDN_TLS_HANDSHAKE)
+			return;
+
+		fqdn_snoop_copy(ctx, FQDN_SNOOP_TLS, off, &remote);
+	}
+}
@@ -0,0 +1,242 @@
+/**
+ * SPDX-FileCopyrightText: Ella Networks Inc.
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include "bpf/ctx/ctx.h"
+#include "bpf/utils/common.h"
+#include "bpf/utils/csum.h"
+#include "bpf/utils/parsers.h"
+#include "bpf/utils/pdr_maps.h"
+#include "bpf/utils/packet_context.h"
+#include <linux/bpf.h>
+#include <linux/ip.h>
+#include <linux/ipv6.h>
+#include <linux/tcp.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_helpers.h>
+
+/*
+ * TCP MSS clamping. A UE that ignores the MTU the network advertised opens
+ * connections with an MSS its tunnel cannot carry, and every full segment is
+ * then fragmented or dropped on the GTP path. The datapath lowers the MSS
+ * option of SYN and SYN-ACK segments in both directions to what fits, keyed
+ * by the data network's UE pool. Written by PutTCPMSSClamp
+ * (internal/upf/ebpf/mss.go).
+ */
+
+#define TCP_MSS_CLAMP_MAP_SIZE 64
+#define TCP_OPT_EOL 0
+#define TCP_OPT_NOP 1
+#define TCP_OPT_MSS 2
+#define TCP_OPT_MSS_LEN 4
+/* doff is 4 bits, so at most 40 bytes of options follow the fixed header. */
+#define TCP_MAX_OPTION_BYTES 40
+
+/* A non-zero mss is used as is. Otherwise the limit is derived per packet
+ * from mtu, the data network's MTU, less the GTP-U encapsulation of the
+ * session's tunnel and the inner IP and TCP headers. */
+struct tcp_mss_clamp {
+	__u16 mtu;
+	__u16 mss;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LPM_TRIE);
+	__type(key, struct framed_ip4_key);
+	__type(value, struct tcp_mss_clamp);
+	__uint(max_entries, TCP_MSS_CLAMP_MAP_SIZE);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} tcp_mss_clamp_ip4 SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LPM_TRIE);
+	__type(key, struct framed_ip6_key);
+	__type(value, struct tcp_mss_clamp);
+	__uint(max_entries, TCP_MSS_CLAMP_MAP_SIZE);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} tcp_mss_clamp_ip6 SEC(".maps");
+
+/* The UE's address is the source on N3 and the destination on N6; downlink
+ * is looked up after destination NAT. */
+static __always_inline const struct tcp_mss_clamp *
+lookup_tcp_mss_clamp(const struct packet_context *ctx)
+{
+	const bool uplink = ctx->interface == INTERFACE_N3;
+
+	if (ctx->ip4) {
+		struct framed_ip4_key key = {
+			.prefixlen = 32,
+			.addr = uplink ? ctx->ip4->saddr : ctx->ip4->daddr,
+		};
+
+		return bpf_map_lookup_elem(&tcp_mss_clamp_ip4, &key);
+	}
+
+	if (ctx->ip6) {
+		struct framed_ip6_key key = {
+			.prefixlen = 128,
+		};
+
+		__builtin_memcpy(&key.addr,
+				 uplink ? &ctx->ip6->saddr : &ctx->ip6->daddr,
+				 sizeof(key.addr));
+
+		return bpf_map_lookup_elem(&tcp_mss_clamp_ip6, &key);
+	}
+
+	return NULL;
+}
+
+static __always_inline __u16 tcp_mss_limit(const struct tcp_mss_clamp *clamp,
+					   const struct packet_context *ctx,
+					   __u32 encap_size)
+{
+	if (clamp->mss)
+		return clamp->mss;
+
+	const __u32 overhead =
+		encap_size +
+		(ctx->ip6 ? sizeof(struct ipv6hdr) : sizeof(struct iphdr)) +
+		sizeof(struct tcphdr);
+
+	if (clamp->mtu <= overhead)
+		return 0;
+
+	return clamp->mtu - overhead;
+}
+
+struct tcp_opt_walk {
+	struct __ctx_buff *ctx_buff;
+	__u32 base; /* frame offset of the first option byte */
+	__u32 len;
+	__u32 off;
+	__s32 mss_off; /* offset of the MSS option in the options, or -1 */
+};
+
+/* bpf_loop() callback: step over one option. Returns 1 once the MSS option is
+ * found or the options end, 0 to continue. Options are read with
+ * ctx_load_bytes, at scalar offsets, so the walk needs no packet-pointer
+ * range. */
+static long tcp_opt_step(__u32 index, void *vctx)
+{
+	struct tcp_opt_walk *w = vctx;
+	__u8 opt[2];
+
+	if (w->off >= w->len)
+		return 1;
+
+	if (ctx_load_bytes(w->ctx_buff, w->base + w->off, opt, 1) < 0)
+		return 1;
+
+	if (opt[0] == TCP_OPT_EOL)
+		return 1;
+
+	if (opt[0] == TCP_OPT_NOP) {
+		w->off++;
+		return 0;
+	}
+
+	if (w->off + 2 > w->len ||
+	    ctx_load_bytes(w->ctx_buff, w->base + w->off, opt, 2) < 0)
+		return 1;
+
+	if (opt[1] < 2)
+		return 1;
+
+	if (opt[0] == TCP_OPT_MSS) {
+		if (opt[1] == TCP_OPT_MSS_LEN &&
+		    w->off + TCP_OPT_MSS_LEN <= w->len)
+			w->mss_off = (__s32)w->off;
+		return 1;
+	}
+
+	w->off += opt[1];
+
+	return 0;
+}
+
+/* Lowers the MSS option of a SYN to the data network's limit. encap_size is
+ * the GTP-U encapsulation of the session's tunnel. Runs once the SDF filters
+ * have passed the packet. Under the skb build the checksum helper invalidates
+ * every packet pointer, so the context is re-derived, L4 included, and the
+ * caller re-reads its own header pointers. A segment that cannot be walked
+ * is forwarded unchanged. */
+static __always_inline void clamp_tcp_mss(struct packet_context *ctx,
+					  __u32 encap_size)
+{
+	struct tcphdr *tcp = ctx->tcp;
+
+	if (!tcp || ctx->is_fragment || ctx->l4_unavailable)
+		return;
+
+	if ((const void *)(tcp + 1) > ctx->data_end || !tcp->syn)
+		return;
+
+	const struct tcp_mss_clamp *clamp = lookup_tcp_mss_clamp(ctx);
+	if (!clamp)
+		return;
+
+	const __u16 limit = tcp_mss_limit(clamp, ctx, encap_size);
+	if (!limit)
+		return;
+
+	const __u32 hdr_len = (__u32)tcp->doff * 4;
+	if (hdr_len <= sizeof(*tcp))
+		return;
+
+	struct tcp_opt_walk w = {
+		.ctx_buff = ctx->ctx_buff,
+		.base = ctx_frame_offset(ctx->ctx_buff, tcp) + sizeof(*tcp),
+		.len = hdr_len - sizeof(*tcp),
+		.off = 0,
+		.mss_off = -1,
+	};
+
+	bpf_loop(TCP_MAX_OPTION_BYTES, tcp_opt_step, &w, 0);
+
+	if (w.mss_off < 0)
+		return;
+
+	/* Masked for the verifier; the walk already bounds it below 40. */
+	__u32 mss_off = (__u32)w.mss_off;
+	barrier_var(mss_off);
+	mss_off &= 0x3f;
+
+	__u8 *opt = (__u8 *)(tcp + 1) + mss_off;
+	if ((const void *)(opt + TCP_OPT_MSS_LEN) > ctx->data_end)
+		return;
+
+	__be16 old_mss;
+	__builtin_memcpy(&old_mss, opt + 2, sizeof(old_mss));
+
+	if (bpf_ntohs(old_mss) <= limit)
+		return;
+
+	const __be16 new_mss = bpf_htons(limit);
+	__builtin_memcpy(opt + 2, &new_mss, sizeof(new_mss));
+
+	/* The checksum sums 16-bit words from the start of the header. After
+	 * an odd number of single-byte options the value straddles two of
+	 * them, and the delta is the byte-swapped one. */
+	const bool odd = mss_off & 1;
+	const __u16 from = odd ? __builtin_bswap16(old_mss) : old_mss;
+	const __u16 to = odd ? __builtin_bswap16(new_mss) : new_mss;
+
+	if (!CTX_L4_CSUM_VIA_HELPERS) {
+		tcp->check = ipv4_csum_update_u16(tcp->check, from, to);
+		return;
+	}
+
+	const __u32 csum_off =
+		ctx_frame_offset(ctx->ctx_buff, &tcp->check);
+
+	if (ctx_l4_csum_replace(ctx->ctx_buff, csum_off, from, to, 2) < 0)
+		upf_printk("upf: tcp mss clamp checksum update failed");
+
+	if (context_reinit(ctx, ctx_data(ctx->ctx_buff),
+			   ctx_data_end(ctx->ctx_buff)) == 0)
+		parse_l4(ctx->l4_proto, ctx);
+}
@@ -72,7 +72,8 @@ nat_translate_icmp_quote(struct five_tuple *key, struct packet_context *ctx,
 		if (!udp || (const void *)(udp + 1) > msg_end) {
 			return NULL;
 		}
-		if (!nat_port_in_range(udp->source)) {
+		if (!nat_port_in_range(ip4->saddr, udp->source,
+				       IPPROTO_UDP)) {
 			return NULL;
 		}
 		key->proto = ip4->protocol;
@@ -113,7 +114,8 @@ nat_translate_icmp_quote(struct five_tuple *key, struct packet_context *ctx,
 		if (!tcp || (const void *)((__u8 *)tcp + 8) > msg_end) {
 			return NULL;
 		}
-		if (!nat_port_in_range(tcp->source)) {
+		if (!nat_port_in_range(ip4->saddr, tcp->source,
+				       IPPROTO_TCP)) {
 			return NULL;
 		}
 		key->proto = ip4->protocol;
@@ -529,10 +531,10 @@ source_nat_apply_csum_helpers(struct packet_context *ctx, __u32 old_saddr,
 
 	return err;
 }
-static __always_inline __u16 nat_random_port(void)
+static __always_inline __u16 nat_random_port(struct nat_port_range range)
 {
-	__u16 min = nat_port_min;
-	__u16 max = nat_port_max;
+	__u16 min = range.min;
+	__u16 max = range.max;
 
 	if (max < min)
 		return bpf_htons(min);
@@ -542,12 +544,13 @@ static __always_inline __u16 nat_random_port(void)
 	return bpf_htons(min + (__u16)(bpf_get_prandom_u32() % span));
 }
 
-static __always_inline __u16 nat_next_port(__u16 port_be)
+static __always_inline __u16 nat_next_port(__u16 port_be,
+					   struct nat_port_range range)
 {
 	__u16 port = bpf_ntohs(port_be) + 1;
 
-	if (port < nat_port_min || port > nat_port_max)
-		port = nat_port_min;
+	if (port < range.min || port > range.max)
+		port = range.min;
 
 	return bpf_htons(port);
 }
@@ -576,9 +579,13 @@ static __always_inline bool source_nat(struct packet_context *ctx,
 	orig.daddr = ctx->ip4->daddr;
 	orig.proto = proto;
 
+	struct nat_port_range range;
+	const __u32 nat_saddr =
+		nat_select_source(orig.saddr, fib_params->ipv4_src, &range);
+
 	/* Incremental update: a recompute would have to cover the options a
 	 * header with ihl > 5 carries. */
-	ctx->ip4->saddr = fib_params->ipv4_src;
+	ctx->ip4->saddr = nat_saddr;
 	ctx->ip4->check = ipv4_csum_update_u32(ctx->ip4->check, orig.saddr,
 					       ctx->ip4->saddr);
 
@@ -696,9 +703,10 @@ static __always_inline bool source_nat(struct packet_context *ctx,
 	}
 
 	struct five_tuple natted = {};
-	natted.saddr = fib_params->ipv4_src;
-	natted.sport = nat_id_reusable(proto, orig.sport) ? orig.sport :
-							    nat_random_port();
+	natted.saddr = nat_saddr;
+	natted.sport = nat_id_reusable(proto, orig.sport, range) ?
+			       orig.sport :
+			       nat_random_port(range);
 	natted.daddr = ctx->ip4->daddr;
 	natted.dport = orig.dport;
 	natted.proto = proto;
@@ -721,7 +729,7 @@ static __always_inline bool source_nat(struct packet_context *ctx,
 		__u8 closed = NAT_READ_ONCE(tracked->closed);
 		__u8 replied = NAT_READ_ONCE(tracked->replied);
 
-		if (mapped.saddr != fib_params->ipv4_src) {
+		if (mapped.saddr != nat_saddr) {
 			/* The reservation on the old address is only ours to
 			 * release while the entry still names this flow. */
 			struct nat_entry *stale =
@@ -820,7 +828,7 @@ allocate:;
 			NAT_WRITE_ONCE(existing->refresh_ts, now);
 			reserved = true;
 		} else {
-			__u16 port = nat_random_port();
+			__u16 port = nat_random_port(range);
 			for (int i = 0; i < NAT_PORT_RETRIES - 1; i++) {
 				natted.sport = port;
 				if (0 == bpf_map_update_elem(&nat_ct, &natted,
@@ -829,7 +837,7 @@ allocate:;
 					reserved = true;
 					break;
 				}
-				port = nat_next_port(port);
+				port = nat_next_port(port, range);
 			}
 		}
 	}
@@ -1077,6 +1085,49 @@ static __always_inline void destination_nat_apply(struct packet_context *ctx,
 	}
 }
 
+/* Opens the mapping of an inbound flow to a port forward, as the UE's first
+ * uplink packet would: the pair is reserved in nat_ct and later packets in
+ * both directions resolve through it. key is the NAT-side tuple. TCP opens
+ * only on a SYN, so a stray segment cannot claim the forward. */
+static __always_inline struct nat_entry *
+nat_forward_open(const struct five_tuple *key, const struct nat_forward *fwd,
+		 bool tcp_syn)
+{
+	if (key->proto == IPPROTO_TCP && !tcp_syn)
+		return NULL;
+
+	__u64 now = bpf_ktime_get_ns();
+
+	struct five_tuple ue = {};
+	ue.saddr = fwd->ue_addr;
+	ue.daddr = key->daddr;
+	ue.sport = fwd->ue_port;
+	ue.dport = key->dport;
+	ue.proto = key->proto;
+
+	struct nat_entry nat_side = {};
+	nat_side.peer = ue;
+	nat_side.refresh_ts = now;
+
+	if (0 != bpf_map_update_elem(&nat_ct, key, &nat_side, BPF_NOEXIST))
+		return NULL;
+
+	struct nat_entry ue_val = {};
+	ue_val.peer = *key;
+	ue_val.refresh_ts = now;
+	ue_val.state = NAT_CT_NEW;
+	ue_val.ue_side = 1;
+
+	if (0 != bpf_map_update_elem(&nat_ct, &ue, &ue_val, BPF_NOEXIST)) {
+		/* The UE already reaches this remote from the same port under
+		 * another mapping. */
+		bpf_map_delete_elem(&nat_ct, key);
+		return NULL;
+	}
+
+	return bpf_map_lookup_elem(&nat_ct, key);
+}
+
 /* Resolves the mapping without touching the packet, except an ICMP error's
  * quote, which is both the lookup key and part of what must be translated. */
 static __always_inline bool destination_nat_lookup(struct packet_context *ctx,
@@ -1105,7 +1156,7 @@ static __always_inline bool destination_nat_lookup(struct packet_context *ctx,
 
 		const __be16 dport = bpf_htons(ctx->l4_dport);
 
-		if (!nat_port_in_range(dport))
+		if (!nat_port_in_range(key.saddr, dport, proto))
 			return false;
 
 		key.sport = dport;
@@ -1173,13 +1224,24 @@ static __always_inline bool destination_nat_lookup(struct packet_context *ctx,
 				return false;
 			}
 		}
-		if (!nat_port_in_range(ctx->tcp->dest)) {
+		key.sport = ctx->tcp->dest;
+		key.dport = ctx->tcp->source;
+
+		struct nat_forward tcp_fwd;
+		const bool tcp_forwarded = nat_forward_lookup(
+			key.saddr, key.sport, proto, &tcp_fwd);
+
+		if (!tcp_forwarded &&
+		    !nat_port_in(nat_range_of(key.saddr), key.sport)) {
 			return false;
 		}
 
-		key.sport = ctx->tcp->dest;
-		key.dport = ctx->tcp->source;
 		origin = bpf_map_lookup_elem(&nat_ct, &key);
+		if (!origin && tcp_forwarded) {
+			origin = nat_forward_open(
+				&key, &tcp_fwd,
+				ctx->tcp->syn && !ctx->tcp->ack);
+		}
 		if (!origin || origin->ue_side) {
 			return false;
 		}
@@ -1206,13 +1268,22 @@ static __always_inline bool destination_nat_lookup(struct packet_context *ctx,
 				return false;
 			}
 		}
-		if (!nat_port_in_range(ctx->udp->dest)) {
+		key.sport = ctx->udp->dest;
+		key.dport = ctx->udp->source;
+
+		struct nat_forward udp_fwd;
+		const bool udp_forwarded = nat_forward_lookup(
+			key.saddr, key.sport, proto, &udp_fwd);
+
+		if (!udp_forwarded &&
+		    !nat_port_in(nat_range_of(key.saddr), key.sport)) {
 			return false;
 		}
 
-		key.sport = ctx->udp->dest;
-		key.dport = ctx->udp->source;
 		origin = bpf_map_lookup_elem(&nat_ct, &key);
+		if (!origin && udp_forwarded) {
+			origin = nat_forward_open(&key, &udp_fwd, false);
+		}
 		if (!origin || origin->ue_side) {
 			return false;
 		}
@@ -16,6 +16,7 @@
 #include "bpf/utils/packet_context.h"
 #include "bpf/utils/parsers.h"
 #include "bpf/utils/pdr.h"
+#include "bpf/utils/pdr_maps.h"
 
 #ifndef NAT_CT_H
 #define NAT_CT_H
@@ -49,21 +50,243 @@ volatile const __u16 nat_port_min = 1024;
 volatile const __u16 nat_port_max;
 volatile const __u16 nat_port_max = 32767;
 
-static __always_inline bool nat_port_in_range(__u16 port_be)
+/* Per-data-network source pools. A UE inside a pool's UE prefix is
+ * translated to one of the pool's addresses and ports; any other UE
+ * masquerades to the egress address in the range above. */
+#define NAT_POOL_MAX_ADDRS 16
+#define NAT_POOL_MAP_SIZE 64
+#define NAT_POOL_ADDR_MAP_SIZE (NAT_POOL_MAX_ADDRS * NAT_POOL_MAP_SIZE)
+#define NAT_FORWARD_MAP_SIZE 1024
+
+enum nat_pool_mapping {
+	/* The UE address picks the pool address, so a UE keeps it across
+	 * restarts and nodes. */
+	NAT_POOL_DETERMINISTIC = 0,
+	/* Each UE takes the next address on first use and keeps it while it
+	 * has traffic (paired pooling, RFC 4787 REQ-2). */
+	NAT_POOL_ROUND_ROBIN = 1,
+};
+
+struct nat_port_range {
+	__u16 min;
+	__u16 max;
+};
+
+struct nat_pool {
+	__u32 addrs[NAT_POOL_MAX_ADDRS];
+	__u32 count;
+	/* Round-robin position, advanced atomically. */
+	__u32 cursor;
+	struct nat_port_range ports;
+	__u8 mapping;
+	__u8 pad[3];
+};
+
+/* Keyed by the data network's UE pool. */
+struct {
+	__uint(type, BPF_MAP_TYPE_LPM_TRIE);
+	__type(key, struct framed_ip4_key);
+	__type(value, struct nat_pool);
+	__uint(max_entries, NAT_POOL_MAP_SIZE);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} nat_pools SEC(".maps");
+
+/* The port range of every pool address: the downlink sees only the public
+ * address, so it cannot reach the pool through the UE prefix. */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, __u32);
+	__type(value, struct nat_port_range);
+	__uint(max_entries, NAT_POOL_ADDR_MAP_SIZE);
+} nat_pool_addrs SEC(".maps");
+
+/* Round-robin bindings, UE address to pool address. */
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, __u32);
+	__type(value, __u32);
+	__uint(max_entries, MAX_PDU_SESSIONS);
+} nat_pool_bindings SEC(".maps");
+
+/* A UE with port forwards sources all of its traffic from the forwards'
+ * public address, so a reply to a forwarded flow resolves to the mapping the
+ * inbound packet opened. */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, __u32);
+	__type(value, __u32);
+	__uint(max_entries, NAT_FORWARD_MAP_SIZE);
+} nat_source_overrides SEC(".maps");
+
+struct nat_forward_key {
+	__u32 addr;
+	__u16 port;
+	__u8 proto;
+	__u8 pad;
+};
+
+struct nat_forward {
+	__u32 ue_addr;
+	__u16 ue_port;
+	__u16 pad;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, struct nat_forward_key);
+	__type(value, struct nat_forward);
+	__uint(max_entries, NAT_FORWARD_MAP_SIZE);
+} nat_forwards SEC(".maps");
+
+static __always_inline struct nat_port_range nat_default_range(void)
+{
+	struct nat_port_range range = { .min = nat_port_min,
+					.max = nat_port_max };
+
+	return range;
+}
+
+/* The range ports on addr are allocated from: its pool's, or the masquerade
+ * range for the egress address. */
+static __always_inline struct nat_port_range nat_range_of(__u32 addr)
+{
+	struct nat_port_range *range = bpf_map_lookup_elem(&nat_pool_addrs,
+							   &addr);
+	if (range)
+		return *range;
+
+	return nat_default_range();
+}
+
+static __always_inline bool nat_port_in(struct nat_port_range range,
+					__u16 port_be)
 {
 	__u16 port = bpf_ntohs(port_be);
 
-	return port >= nat_port_min && port <= nat_port_max;
+	return port >= range.min && port <= range.max;
+}
+
+static __always_inline bool nat_forward_lookup(__u32 addr, __u16 port_be,
+					       __u16 proto,
+					       struct nat_forward *out)
+{
+	struct nat_forward_key key = {};
+	key.addr = addr;
+	key.port = port_be;
+	key.proto = (__u8)proto;
+
+	struct nat_forward *fwd = bpf_map_lookup_elem(&nat_forwards, &key);
+	if (!fwd)
+		return false;
+
+	*out = *fwd;
+	return true;
+}
+
+/* Whether a port on a public address can name a mapping: one allocated from
+ * the address's range, or a port forward. */
+static __always_inline bool nat_port_in_range(__u32 addr, __u16 port_be,
+					      __u16 proto)
+{
+	struct nat_forward fwd;
+
+	if (nat_port_in(nat_range_of(addr), port_be))
+		return true;
+
+	return nat_forward_lookup(addr, port_be, proto, &fwd);
 }
 
 /* An ICMP identifier is not drawn from the host's ephemeral range, so the
  * masquerade range neither constrains nor protects it. */
-static __always_inline bool nat_id_reusable(__u16 proto, __u16 id_be)
+static __always_inline bool nat_id_reusable(__u16 proto, __u16 id_be,
+					    struct nat_port_range range)
 {
 	if (proto == IPPROTO_ICMP)
 		return bpf_ntohs(id_be) >= NAT_ID_MIN;
 
-	return nat_port_in_range(id_be);
+	return nat_port_in(range, id_be);
+}
+
+static __always_inline bool nat_pool_contains(const struct nat_pool *pool,
+					      __u32 count, __u32 addr)
+{
+	for (__u32 i = 0; i < NAT_POOL_MAX_ADDRS; i++) {
+		if (i >= count)
+			break;
+		if (pool->addrs[i] == addr)
+			return true;
+	}
+
+	return false;
+}
+
+/* idx is already below count, so the compiler drops a plain mask; the
+ * verifier tracks no bound through the modulo and needs it. */
+static __always_inline __u32 nat_pool_addr_at(const struct nat_pool *pool,
+					      __u32 idx)
+{
+	barrier_var(idx);
+
+	return pool->addrs[idx & (NAT_POOL_MAX_ADDRS - 1)];
+}
+
+/* The public source for a UE and the range its ports come from. fib_src,
+ * the egress address, is the answer for a UE outside every pool. */
+static __always_inline __u32 nat_select_source(__u32 ue_addr, __u32 fib_src,
+					       struct nat_port_range *range)
+{
+	__u32 *override = bpf_map_lookup_elem(&nat_source_overrides, &ue_addr);
+	if (override) {
+		__u32 addr = *override;
+
+		*range = nat_range_of(addr);
+		return addr;
+	}
+
+	*range = nat_default_range();
+
+	struct framed_ip4_key key = { .prefixlen = 32, .addr = ue_addr };
+	struct nat_pool *pool = bpf_map_lookup_elem(&nat_pools, &key);
+	if (!pool)
+		return fib_src;
+
+	__u32 count = pool->count;
+	if (count == 0 || count > NAT_POOL_MAX_ADDRS)
+		return fib_src;
+
+	*range = pool->ports;
+
+	if (pool->mapping != NAT_POOL_ROUND_ROBIN) {
+		__u32 idx = bpf_ntohl(ue_addr) % count;
+
+		return nat_pool_addr_at(pool, idx);
+	}
+
+	__u32 *bound = bpf_map_lookup_elem(&nat_pool_bindings, &ue_addr);
+	if (bound) {
+		__u32 addr = *bound;
+
+		/* An address removed from the pool is not kept. */
+		if (nat_pool_contains(pool, count, addr))
+			return addr;
+	}
+
+	/* Read, then bumped on its own: using the value an atomic add fetches
+	 * needs the v3 instruction set. CPUs racing here may pick the same
+	 * address, which only skews the spread. */
+	__u32 idx = pool->cursor % count;
+	__sync_fetch_and_add(&pool->cursor, 1);
+	__u32 addr = nat_pool_addr_at(pool, idx);
+
+	bpf_map_update_elem(&nat_pool_bindings, &ue_addr, &addr, BPF_ANY);
+
+	return addr;
+}
+
+/* Whether addr is one of the pool addresses, which no host socket owns. */
+static __always_inline bool nat_is_pool_addr(__u32 addr)
+{
+	return bpf_map_lookup_elem(&nat_pool_addrs, &addr) != NULL;
 }
 
 /* Explicit padding: the kernel compares the whole key, so no byte may be
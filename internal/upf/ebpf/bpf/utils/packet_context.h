@@ -82,6 +82,18 @@ struct packet_context {
 	__be32 frag_id6;
 	/* Set by drop_with()/abort_with(), read once by record_action(). */
 	enum upf_drop_reason drop_reason;
+	/* Uplink: the breakout_egress entry an SDF breakout rule matched; 0 is
+	 * the data network's egress. */
+	__u8 breakout;
+	/* Uplink: the captive portal an SDF portal rule redirects to; 0 is
+	 * none. */
+	__u32 portal;
+	/* Downlink: the QoS flow an SDF QoS flow rule marks the packet with; 0
+	 * is the session's. */
+	__u8 qfi;
+	/* The sdf_ratings slot of the rule that passed the packet, plus one; 0
+	 * is none. */
+	__u16 rating_slot;
 	__u8 interface : 1;
 	__u8 l4_unavailable : 1;
 	__u8 exthdr_invalid : 1;
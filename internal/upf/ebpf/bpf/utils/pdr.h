@@ -44,6 +44,17 @@
 	(2 * MAX_POLICIES) /* one slot per policy-direction pair */
 #define SDF_PROTO_ANY 255 /* wildcard protocol */
 #define SDF_PORT_ANY 0 /* wildcard port (low == high == 0 means any) */
+#define SDF_ACTION_ALLOW 0
+#define SDF_ACTION_DENY 1
+#define SDF_ACTION_RATE_LIMIT 2 /* allow, policed to rate_kbps */
+#define SDF_ACTION_BREAKOUT 3 /* allow, out of breakout_egress[breakout] */
+/* Unless the UE is in portal_lifted: redirect to the IPv4 portal, or
+ * drop. */
+#define SDF_ACTION_PORTAL 4
+#define SDF_ACTION_PORTAL_DROP 5
+/* Not a verdict: what the rules after it pass is downlink marked with the
+ * rule's qfi and policed to rate_kbps unless it is zero. */
+#define SDF_ACTION_QOS_FLOW 6
 
 enum outer_header_removal_values {
 	OHR_GTP_U_UDP_IPv4 = 0,
@@ -82,14 +93,28 @@ struct sdf_rule {
 	struct in6_addr
 		remote_ip; /* ::ffff:x.x.x.x for IPv4, native for IPv6; all zeros = wildcard */
 	__u8 prefix_len; /* 0 = wildcard (matches all); 0-32 for IPv4, 0-128 for IPv6 */
+	__u8 rated; /* non-zero: sdf_ratings (rating.h) holds the rule's rating */
 	/* Inclusive. Only low == high == 0 is the wildcard (SDF_PORT_ANY). */
 	__u16 port_low;
 	__u16 port_high;
 	__u8 protocol; /* IP protocol; SDF_PROTO_ANY (255) = wildcard */
-	__u8 action; /* 0 = allow, 1 = deny */
-	__u8 pad[7]; /* padding to 32 bytes for verifier-friendly array indexing */
+	__u8 action; /* SDF_ACTION_* */
+	__u16 fqdn_set; /* non-zero: match sdf_fqdn_addrs (fqdn.h) instead of remote_ip */
+	__u8 rate_shared; /* rate limit: one bucket for every session of the policy */
+	union {
+		__u8 breakout; /* SDF_ACTION_BREAKOUT: index into breakout_egress (routing.h) */
+		__u8 qfi; /* SDF_ACTION_QOS_FLOW */
+	};
+	/* Sizes the struct to 32 bytes. */
+	union {
+		__u32 rate_kbps; /* SDF_ACTION_RATE_LIMIT, SDF_ACTION_QOS_FLOW */
+		__u32 portal; /* SDF_ACTION_PORTAL: IPv4, network order */
+	};
 };
 
+_Static_assert(sizeof(struct sdf_rule) == 32,
+	       "sdf_rule layout must match SdfRule (internal/upf/ebpf/pdr.go)");
+
 struct sdf_filter_list {
 	__u8 num_rules; /* number of valid entries in rules[] */
 	__u8 pad[3];
@@ -0,0 +1,196 @@
+/**
+ * SPDX-FileCopyrightText: Ella Networks Inc.
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include "bpf/ctx/ctx.h"
+#include "bpf/utils/csum.h"
+#include "bpf/utils/nat.h"
+#include "bpf/utils/parsers.h"
+#include "bpf/utils/pdr.h"
+#include "bpf/utils/packet_context.h"
+#include "bpf/utils/trace.h"
+#include <linux/bpf.h>
+#include <linux/in.h>
+#include <linux/in6.h>
+#include <linux/ip.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_helpers.h>
+
+/*
+ * Captive portal. A policy in redirect mode leads its uplink filter list with
+ * SDF_ACTION_PORTAL rules, which send the UE's DNS and web traffic to the
+ * IPv4 portal the rule carries, and closes the redirect with an
+ * SDF_ACTION_PORTAL_DROP rule. A UE whose address is in portal_lifted skips
+ * both and meets the policy's own rules. Only the destination address is
+ * rewritten, so the portal answers DNS for every name and serves the port
+ * the UE asked for; portal_ct turns its replies back into the address the UE
+ * sent to. Written by PutPortalLifted (internal/upf/ebpf/portal.go).
+ */
+
+#define PORTAL_LIFTED_MAP_SIZE MAX_PDU_SESSIONS
+/* A redirected UE has little more open than DNS lookups and a browser. */
+#define PORTAL_CT_MAP_SIZE (16 * MAX_PDU_SESSIONS)
+/* An IPv6 UE is known by its /64. */
+#define PORTAL_IPV6_PREFIX_WORDS 2
+
+/* UE address -> present. IPv4 addresses are IPv4-mapped. */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, struct in6_addr);
+	__type(value, __u8);
+	__uint(max_entries, PORTAL_LIFTED_MAP_SIZE);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} portal_lifted SEC(".maps");
+
+struct portal_ct_key {
+	__u32 ue_addr;
+	__u16 ue_port;
+	__u8 proto;
+	__u8 pad;
+};
+
+struct portal_ct_entry {
+	__u32 portal;
+	__u32 orig_daddr;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__type(key, struct portal_ct_key);
+	__type(value, struct portal_ct_entry);
+	__uint(max_entries, PORTAL_CT_MAP_SIZE);
+} portal_ct SEC(".maps");
+
+/* The portal_lifted key of the UE that sent (N3) or receives (N6) the
+ * packet. */
+static __always_inline void portal_ue_key(const struct packet_context *ctx,
+					  struct in6_addr *key)
+{
+	if (ctx->ip4) {
+		ipv4_to_mapped(key, (ctx->interface == INTERFACE_N3) ?
+					    ctx->ip4->saddr :
+					    ctx->ip4->daddr);
+	} else if (ctx->ip6) {
+		*key = (ctx->interface == INTERFACE_N3) ? ctx->ip6->saddr :
+							  ctx->ip6->daddr;
+		key->in6_u.u6_addr32[PORTAL_IPV6_PREFIX_WORDS] = 0;
+		key->in6_u.u6_addr32[PORTAL_IPV6_PREFIX_WORDS + 1] = 0;
+	}
+}
+
+static __always_inline bool portal_ct_key_of(const struct packet_context *ctx,
+					     __u32 ue_addr, __u16 ue_port,
+					     struct portal_ct_key *key)
+{
+	key->ue_addr = ue_addr;
+	key->ue_port = ue_port;
+	key->proto = ctx->ip4->protocol;
+
+	return key->proto == IPPROTO_TCP || key->proto == IPPROTO_UDP;
+}
+
+/* Sends an uplink packet an SDF_ACTION_PORTAL rule matched to ctx->portal.
+ * Returns false when the packet cannot be redirected and must be dropped:
+ * the portal is IPv4 and needs the ports to return its replies. */
+static __always_inline bool portal_redirect(struct packet_context *ctx)
+{
+	if (!ctx->ip4 || ctx->l4_unavailable || ctx->is_fragment)
+		return false;
+
+	const __u32 portal = ctx->portal;
+
+	/* Traffic the UE already sends to the portal needs nothing done. */
+	if (ctx->ip4->daddr == portal)
+		return true;
+
+	struct portal_ct_key key = {};
+	if (!portal_ct_key_of(ctx, ctx->ip4->saddr, ctx->l4_sport, &key))
+		return false;
+
+	struct portal_ct_entry entry = {
+		.portal = portal,
+		.orig_daddr = ctx->ip4->daddr,
+	};
+
+	if (bpf_map_update_elem(&portal_ct, &key, &entry, BPF_ANY) < 0)
+		return false;
+
+	upf_printk("upf: portal redirect %pI4 -> %pI4", &ctx->ip4->daddr,
+		   &portal);
+
+	struct nat_xlate x = {
+		.daddr = portal,
+		.proto = key.proto,
+		.valid = true,
+	};
+
+	destination_nat_apply(ctx, &x);
+
+	/* The skb build re-parses the frame; a failed re-parse or checksum
+	 * update leaves no header to send. */
+	return ctx->ip4 != NULL;
+}
+
+/* Gives a downlink reply from the portal the source address the UE's
+ * request went to. Call once the destination is the UE's own. Returns false
+ * when the rewrite failed and the packet must be dropped. */
+static __always_inline bool portal_restore_source(struct packet_context *ctx)
+{
+	if (!ctx->ip4 || ctx->is_fragment)
+		return true;
+
+	/* Only destination NAT has parsed L4 this early on N6. */
+	parse_l4(ctx->ip4->protocol, ctx);
+
+	/* Host order, as portal_redirect keyed it from l4_sport. Read from
+	 * the header: l4_dport predates destination NAT. */
+	__u16 ue_port;
+
+	if (ctx->ip4->protocol == IPPROTO_TCP && ctx->tcp)
+		ue_port = bpf_ntohs(ctx->tcp->dest);
+	else if (ctx->ip4->protocol == IPPROTO_UDP && ctx->udp)
+		ue_port = bpf_ntohs(ctx->udp->dest);
+	else
+		return true;
+
+	struct portal_ct_key key = {};
+	portal_ct_key_of(ctx, ctx->ip4->daddr, ue_port, &key);
+
+	const struct portal_ct_entry *entry =
+		bpf_map_lookup_elem(&portal_ct, &key);
+	if (!entry || entry->portal != ctx->ip4->saddr)
+		return true;
+
+	const __u32 old_saddr = ctx->ip4->saddr;
+	const __u32 new_saddr = entry->orig_daddr;
+
+	ctx->ip4->saddr = new_saddr;
+	ctx->ip4->check =
+		ipv4_csum_update_u32(ctx->ip4->check, old_saddr, new_saddr);
+
+	if (CTX_L4_CSUM_VIA_HELPERS) {
+		if (source_nat_apply_csum_helpers(ctx, old_saddr, new_saddr,
+						  ue_port, ue_port) != 0) {
+			ctx->ip4 = NULL;
+			ctx->ip6 = NULL;
+		}
+
+		return ctx->ip4 != NULL;
+	}
+
+	if (ctx->tcp) {
+		ctx->tcp->check = ipv4_csum_update_u32(ctx->tcp->check,
+						       old_saddr, new_saddr);
+	} else if (ctx->udp && ctx->udp->check != 0) {
+		ctx->udp->check = ipv4_csum_update_u32(ctx->udp->check,
+						       old_saddr, new_saddr);
+		/* Zero means "no checksum" in IPv4 UDP (RFC 768). */
+		if (ctx->udp->check == 0)
+			ctx->udp->check = 0xFFFF;
+	}
+
+	return true;
+}
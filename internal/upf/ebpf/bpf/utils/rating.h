@@ -0,0 +1,98 @@
+/**
+ * SPDX-FileCopyrightText: Ella Networks Inc.
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include "bpf/utils/pdr.h"
+#include "bpf/utils/trace.h"
+#include "bpf/utils/packet_context.h"
+#include <linux/bpf.h>
+#include <bpf/bpf_helpers.h>
+
+/*
+ * Rating groups. A network rule may carry a rating group, and traffic it
+ * passes is counted per (session, URR, rating group) beside the session's
+ * URR. Traffic of a zero-rated rule is counted there only, so it stays out
+ * of the subscriber's usage. Written by PutSdfRating
+ * (internal/upf/ebpf/rating.go).
+ */
+
+#define SDF_RATING_MAP_SIZE (MAX_SDF_FILTERS * MAX_RULES_PER_FILTER)
+/* Sessions seldom use more than a couple of rating groups per direction;
+ * entries a poll has not drained are the ones LRU reclaims. */
+#define RATING_USAGE_MAP_SIZE (8 * MAX_PDU_SESSIONS)
+
+struct sdf_rating {
+	__u32 rating_group;
+	__u8 zero_rated;
+	__u8 pad[3];
+};
+
+/* Indexed by sdf_rating_slot(): the rule's place in sdf_filters. */
+struct {
+	__uint(type, BPF_MAP_TYPE_ARRAY);
+	__type(key, __u32);
+	__type(value, struct sdf_rating);
+	__uint(max_entries, SDF_RATING_MAP_SIZE);
+} sdf_ratings SEC(".maps");
+
+struct rating_usage_key {
+	__u64 seid;
+	__u32 urr_id;
+	__u32 rating_group;
+};
+
+/* (SEID, URR ID, rating group) -> byte count, drained by the usage poll. */
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_PERCPU_HASH);
+	__type(key, struct rating_usage_key);
+	__type(value, __u64);
+	__uint(max_entries, RATING_USAGE_MAP_SIZE);
+} rating_usage SEC(".maps");
+
+static __always_inline __u32 sdf_rating_slot(__u32 filter_index,
+					     __u8 rule_index)
+{
+	return filter_index * MAX_RULES_PER_FILTER + rule_index;
+}
+
+/* Counts bytes against the rating group of the rule that passed the packet.
+ * Returns true when the rule is zero-rated and the session's URR must not
+ * see them. */
+static __always_inline bool update_rating_bytes(struct packet_context *ctx,
+						__u64 seid, __u32 urr_id,
+						__u64 bytes)
+{
+	if (!ctx->rating_slot)
+		return false;
+
+	const __u32 slot = ctx->rating_slot - 1;
+	const struct sdf_rating *rating =
+		bpf_map_lookup_elem(&sdf_ratings, &slot);
+	if (!rating)
+		return false;
+
+	if (rating->rating_group) {
+		struct rating_usage_key key = {
+			.seid = seid,
+			.urr_id = urr_id,
+			.rating_group = rating->rating_group,
+		};
+
+		__u64 *count = bpf_map_lookup_elem(&rating_usage, &key);
+		if (count) {
+			*count += bytes;
+		} else if (bpf_map_update_elem(&rating_usage, &key, &bytes,
+					       BPF_NOEXIST) < 0) {
+			/* Another CPU created it first: the slot this CPU
+			 * sees is its own, so a second lookup finds it. */
+			count = bpf_map_lookup_elem(&rating_usage, &key);
+			if (count)
+				*count += bytes;
+		}
+	}
+
+	return rating->zero_rated;
+}
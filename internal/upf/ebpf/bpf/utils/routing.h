@@ -35,9 +35,100 @@
 #include <sys/socket.h>
 
 #include "bpf/utils/nat.h"
+#include "bpf/utils/pdr_maps.h"
 #include "bpf/utils/profiling.h"
 #include "bpf/utils/trace.h"
 
+#define DN_EGRESS_MAP_SIZE 64
+#define BREAKOUT_EGRESS_MAP_SIZE 256 /* indexed by sdf_rule.breakout */
+
+/* Per-data-network egress, keyed by the data network's UE pool. An uplink
+ * packet sourced from the pool is looked up in the data network's kernel
+ * table and leaves through its own interface instead of n6_ifindex. The
+ * FIB reports fib_ifindex (the VLAN sub-interface when vlan is set); the
+ * frame is redirected to ifindex, the device the program is attached to,
+ * with vlan pushed. Written by UpdateDataNetworkEgress
+ * (internal/upf/ebpf/egress.go). */
+struct dn_egress {
+	__u32 table;
+	__u32 fib_ifindex;
+	__u32 ifindex;
+	__u32 vlan;
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LPM_TRIE);
+	__type(key, struct framed_ip4_key);
+	__type(value, struct dn_egress);
+	__uint(max_entries, DN_EGRESS_MAP_SIZE);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} dn_egress_ip4 SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_LPM_TRIE);
+	__type(key, struct framed_ip6_key);
+	__type(value, struct dn_egress);
+	__uint(max_entries, DN_EGRESS_MAP_SIZE);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} dn_egress_ip6 SEC(".maps");
+
+static __always_inline const struct dn_egress *
+lookup_dn_egress_ip4(const struct packet_context *ctx)
+{
+	if (ctx->interface != INTERFACE_N3)
+		return NULL;
+
+	struct framed_ip4_key key = {
+		.prefixlen = 32,
+		.addr = ctx->ip4->saddr,
+	};
+
+	return bpf_map_lookup_elem(&dn_egress_ip4, &key);
+}
+
+static __always_inline const struct dn_egress *
+lookup_dn_egress_ip6(const struct packet_context *ctx)
+{
+	if (ctx->interface != INTERFACE_N3)
+		return NULL;
+
+	struct framed_ip6_key key = {
+		.prefixlen = 128,
+	};
+
+	__builtin_memcpy(&key.addr, &ctx->ip6->saddr, sizeof(key.addr));
+
+	return bpf_map_lookup_elem(&dn_egress_ip6, &key);
+}
+
+/* Local breakout: an uplink packet an SDF breakout rule matched leaves
+ * through the rule's egress instead of its data network's, e.g. toward an
+ * edge compute subnet beside the radios. Entries have the dn_egress layout;
+ * index 0 and unprogrammed entries (ifindex 0) fall back to the data
+ * network's egress. Written by PutBreakoutEgress
+ * (internal/upf/ebpf/breakout.go). */
+struct {
+	__uint(type, BPF_MAP_TYPE_ARRAY);
+	__type(key, __u32);
+	__type(value, struct dn_egress);
+	__uint(max_entries, BREAKOUT_EGRESS_MAP_SIZE);
+} breakout_egress SEC(".maps");
+
+static __always_inline const struct dn_egress *
+lookup_breakout_egress(const struct packet_context *ctx)
+{
+	if (ctx->interface != INTERFACE_N3 || !ctx->breakout)
+		return NULL;
+
+	__u32 key = ctx->breakout;
+	const struct dn_egress *egress =
+		bpf_map_lookup_elem(&breakout_egress, &key);
+	if (!egress || !egress->ifindex)
+		return NULL;
+
+	return egress;
+}
+
 struct {
 	__uint(type, BPF_MAP_TYPE_RINGBUF);
 	__uint(key, 0);
@@ -88,7 +179,8 @@ struct route_stat {
 
 static __always_inline enum ctx_action
 do_route_ipv4(struct packet_context *ctx, struct bpf_fib_lookup *fib_params,
-	      struct route_stat *statistic, bool trust_fib)
+	      struct route_stat *statistic, bool trust_fib,
+	      const struct dn_egress *egress)
 {
 	/*
 	 * trust_fib: forward to the egress interface the kernel routing table
@@ -105,6 +197,7 @@ do_route_ipv4(struct packet_context *ctx, struct bpf_fib_lookup *fib_params,
 	}
 
 	__u32 expected_ifindex;
+	int egress_vid = egress_vlan_forwarded(ctx);
 
 	if (ctx->interface == INTERFACE_N3) {
 		expected_ifindex = n6_ifindex;
@@ -112,8 +205,12 @@ do_route_ipv4(struct packet_context *ctx, struct bpf_fib_lookup *fib_params,
 		expected_ifindex = n3_ifindex;
 	}
 
-	if (n3_ifindex != 0 && n6_ifindex != 0 &&
-	    fib_params->ifindex != expected_ifindex) {
+	/* The data network's or breakout's table resolved to its own device. */
+	if (egress && fib_params->ifindex == egress->fib_ifindex) {
+		expected_ifindex = egress->ifindex;
+		egress_vid = egress->vlan;
+	} else if (n3_ifindex != 0 && n6_ifindex != 0 &&
+		   fib_params->ifindex != expected_ifindex) {
 		upf_printk("upf: ifindex mismatch: fib=%d expected=%d",
 			   fib_params->ifindex, expected_ifindex);
 		statistic->ip4_ifindex_mismatch += 1;
@@ -137,14 +234,14 @@ do_route_ipv4(struct packet_context *ctx, struct bpf_fib_lookup *fib_params,
 	}
 
 	if (expected_ifindex == ctx_ingress_ifindex(ctx->ctx_buff))
-		return tx_back(ctx, egress_vlan_forwarded(ctx));
-	return redirect_out(ctx, expected_ifindex,
-			    egress_vlan_forwarded(ctx));
+		return tx_back(ctx, egress_vid);
+	return redirect_out(ctx, expected_ifindex, egress_vid);
 }
 
 static __always_inline enum ctx_action
 do_route_ipv6(struct packet_context *ctx, struct bpf_fib_lookup *fib_params,
-	      struct route_stat *statistic, bool trust_fib)
+	      struct route_stat *statistic, bool trust_fib,
+	      const struct dn_egress *egress)
 {
 	if (trust_fib) {
 		__builtin_memcpy(ctx->eth->h_source, fib_params->smac,
@@ -155,6 +252,7 @@ do_route_ipv6(struct packet_context *ctx, struct bpf_fib_lookup *fib_params,
 	}
 
 	__u32 expected_ifindex;
+	int egress_vid = egress_vlan_forwarded(ctx);
 
 	if (ctx->interface == INTERFACE_N3) {
 		expected_ifindex = n6_ifindex;
@@ -162,8 +260,12 @@ do_route_ipv6(struct packet_context *ctx, struct bpf_fib_lookup *fib_params,
 		expected_ifindex = n3_ifindex;
 	}
 
-	if (n3_ifindex != 0 && n6_ifindex != 0 &&
-	    fib_params->ifindex != expected_ifindex) {
+	/* The data network's or breakout's table resolved to its own device. */
+	if (egress && fib_params->ifindex == egress->fib_ifindex) {
+		expected_ifindex = egress->ifindex;
+		egress_vid = egress->vlan;
+	} else if (n3_ifindex != 0 && n6_ifindex != 0 &&
+		   fib_params->ifindex != expected_ifindex) {
 		upf_printk("upf: ifindex mismatch: fib=%d expected=%d",
 			   fib_params->ifindex, expected_ifindex);
 		statistic->ip6_ifindex_mismatch += 1;
@@ -178,11 +280,12 @@ do_route_ipv6(struct packet_context *ctx, struct bpf_fib_lookup *fib_params,
 
 	if (expected_ifindex == ctx_ingress_ifindex(ctx->ctx_buff) &&
 	    expected_ifindex != 0)
-		return tx_back(ctx, egress_vlan_forwarded(ctx));
+		return tx_back(ctx, egress_vid);
 	upf_printk("upf: bpf_redirect: if=%d %lu -> %lu", fib_params->ifindex,
 		   fib_params->smac, fib_params->dmac);
-	return redirect_out(ctx, fib_params->ifindex,
-			    egress_vlan_forwarded(ctx));
+	if (egress && fib_params->ifindex == egress->fib_ifindex)
+		return redirect_out(ctx, expected_ifindex, egress_vid);
+	return redirect_out(ctx, fib_params->ifindex, egress_vid);
 }
 
 static __always_inline enum ctx_action route_ipv4(struct packet_context *ctx,
@@ -206,6 +309,18 @@ static __always_inline enum ctx_action route_ipv4(struct packet_context *ctx,
 	if (!trust_fib && masquerade) {
 		flags |= BPF_FIB_LOOKUP_SRC;
 	}
+
+	const struct dn_egress *egress = NULL;
+	if (!trust_fib) {
+		egress = lookup_breakout_egress(ctx);
+		if (!egress)
+			egress = lookup_dn_egress_ip4(ctx);
+		if (egress && egress->table) {
+			fib_params.tbid = egress->table;
+			flags |= BPF_FIB_LOOKUP_TBID;
+		}
+	}
+
 	int rc = bpf_fib_lookup(ctx->ctx_buff, &fib_params, sizeof(fib_params),
 				flags);
 	switch (rc) {
@@ -231,7 +346,8 @@ static __always_inline enum ctx_action route_ipv4(struct packet_context *ctx,
 			   &fib_params.ipv4_dst);
 		statistic->fib_lookup_ip4_success += 1;
 
-		return do_route_ipv4(ctx, &fib_params, statistic, trust_fib);
+		return do_route_ipv4(ctx, &fib_params, statistic, trust_fib,
+				     egress);
 
 	case BPF_FIB_LKUP_RET_BLACKHOLE:
 		upf_printk("upf: bpf_fib_lookup %pI4 -> %pI4: %d",
@@ -308,8 +424,21 @@ static __always_inline enum ctx_action route_ipv6(struct packet_context *ctx,
 			 sizeof(ctx->ip6->daddr));
 	fib_params.ifindex = ctx_ingress_ifindex(ctx->ctx_buff);
 
+	__u64 flags = 0 /*BPF_FIB_LOOKUP_OUTPUT*/;
+
+	const struct dn_egress *egress = NULL;
+	if (!trust_fib) {
+		egress = lookup_breakout_egress(ctx);
+		if (!egress)
+			egress = lookup_dn_egress_ip6(ctx);
+		if (egress && egress->table) {
+			fib_params.tbid = egress->table;
+			flags |= BPF_FIB_LOOKUP_DIRECT | BPF_FIB_LOOKUP_TBID;
+		}
+	}
+
 	int rc = bpf_fib_lookup(ctx->ctx_buff, &fib_params, sizeof(fib_params),
-				0 /*BPF_FIB_LOOKUP_OUTPUT*/);
+				flags);
 	switch (rc) {
 	case BPF_FIB_LKUP_RET_NO_NEIGH: {
 		// smac is unset on this branch, so the frame cannot be completed
@@ -334,7 +463,8 @@ static __always_inline enum ctx_action route_ipv6(struct packet_context *ctx,
 		statistic->fib_lookup_ip6_success += 1;
 		//_decr_ttl(ether_proto, l3hdr);
 
-		return do_route_ipv6(ctx, &fib_params, statistic, trust_fib);
+		return do_route_ipv6(ctx, &fib_params, statistic, trust_fib,
+				     egress);
 	case BPF_FIB_LKUP_RET_BLACKHOLE:
 		upf_printk("upf: bpf_fib_lookup %pI6c -> %pI6c: %d",
 			   &ctx->ip6->saddr, &ctx->ip6->daddr, rc);
@@ -0,0 +1,49 @@
+/**
+ * SPDX-FileCopyrightText: Ella Networks Inc.
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+#pragma once
+
+#include "bpf/utils/pdr.h"
+#include <linux/bpf.h>
+#include <bpf/bpf_helpers.h>
+
+/*
+ * Dedicated EPS bearers. S1-U has no PDU session container, so the eNB tells
+ * a session's bearers apart by TEID alone. A dedicated bearer's downlink
+ * leaves through the default bearer's FAR with the TEID the eNB assigned to
+ * its E-RAB, keyed by the session and the QFI the SDF rule of its QoS flow
+ * marks. Written by PutS1UBearer (internal/upf/ebpf/s1u_bearer.go).
+ */
+
+#define S1U_BEARER_MAP_SIZE MAX_PDU_SESSIONS
+
+struct s1u_bearer_key {
+	__u64 seid;
+	__u8 qfi;
+	__u8 pad[7];
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__type(key, struct s1u_bearer_key);
+	__type(value, __u32); /* eNB TEID of the dedicated E-RAB */
+	__uint(max_entries, S1U_BEARER_MAP_SIZE);
+	__uint(map_flags, BPF_F_NO_PREALLOC);
+} s1u_bearers SEC(".maps");
+
+/* s1u_bearer_teid is the TEID an S1-U downlink packet marked with qfi
+ * leaves with: the dedicated bearer's when the session has one for qfi,
+ * otherwise the default bearer's in the FAR. */
+static __always_inline __u32 s1u_bearer_teid(const struct far_info *far,
+					     __u64 seid, __u8 qfi)
+{
+	if (!(far->outer_header_creation & OHC_NO_PSC) || qfi == 0)
+		return far->teid;
+
+	struct s1u_bearer_key key = { .seid = seid, .qfi = qfi };
+	__u32 *teid = bpf_map_lookup_elem(&s1u_bearers, &key);
+
+	return teid ? *teid : far->teid;
+}
@@ -4,7 +4,11 @@
 #pragma once
 
 #include "bpf/utils/pdr.h"
+#include "bpf/utils/fqdn.h"
+#include "bpf/utils/qer.h"
+#include "bpf/utils/rating.h"
 #include "bpf/utils/packet_context.h"
+#include "bpf/utils/portal.h"
 #include "bpf/utils/trace.h"
 #include "bpf/utils/ip_addr.h"
 #include <linux/ip.h>
@@ -30,7 +34,9 @@ struct {
  * Matching semantics (first-match wins):
  *   1. If filter_map_index == 0, no filtering → allow.
  *   2. Look up the filter list; if not found → allow (fail-open).
- *   3. Iterate rules in order; first match wins.
+ *   3. Iterate rules in order; first match wins. A QoS flow rule is the
+ *      exception: it records its flow and evaluation goes on, so the rules
+ *      after it still decide whether the packet passes.
  *   4. No rule matched → default allow.
  *
  * Direction is implicit: uplink PDRs carry the uplink filter index, downlink
@@ -50,15 +56,39 @@ static __always_inline bool rule_constrains_ports(const struct sdf_rule *r)
 #define SDF_VERDICT_PASS 0
 #define SDF_VERDICT_DENY 1
 #define SDF_VERDICT_UNFILTERABLE 2
+#define SDF_VERDICT_RATE_LIMIT 3
+#define SDF_VERDICT_BREAKOUT 4
+#define SDF_VERDICT_PORTAL 5
+
+/* A rate-limit rule's bucket lives in qer_windows under a QER ID no SMF
+ * allocates: the rule's slot, so it needs no state of its own. A rule that
+ * moves slot restarts from a fresh window, which costs one window of burst.
+ * Shared buckets are keyed by SEID 0, which no session holds. */
+#define SDF_RATE_QER_ID_BASE 0x80000000u
+#define SDF_RATE_SHARED_SEID 0
 
 struct sdf_query {
 	struct in6_addr remote;
+	/* The portal_lifted key of the packet's UE. */
+	struct in6_addr ue;
 	__u32 filter_index;
 	__u16 dport;
 	__u8 proto;
 	__u8 is_ipv4;
 	__u8 ports_unreadable;
-	__u8 pad[3];
+	/* Out, for SDF_VERDICT_RATE_LIMIT, SDF_VERDICT_BREAKOUT and
+	 * SDF_VERDICT_PORTAL, and for any verdict that passes a rated rule. */
+	__u8 rule_index;
+	__u8 rate_shared;
+	__u8 breakout;
+	__u8 rated;
+	/* Out, the first QoS flow rule matched: its QFI, zero when none was,
+	 * its slot and its rate. */
+	__u8 qfi;
+	__u8 qos_rule_index;
+	__u32 rate_kbps; /* SDF_VERDICT_RATE_LIMIT */
+	__u32 qos_rate_kbps;
+	__u32 portal; /* SDF_VERDICT_PORTAL */
 };
 
 __noinline __weak int sdf_match(struct sdf_query *q);
@@ -108,8 +138,36 @@ static __always_inline int match_ipv6_prefix(const struct in6_addr *rule_ip,
 	return 1;
 }
 
+/* Polices the match of the rule at rule_index with the QER sliding window,
+ * uplink and downlink in their own halves of it. */
+static __always_inline enum ctx_action
+sdf_rate_limit(struct packet_context *ctx, __u64 seid, __u32 filter_index,
+	       __u8 rule_index, __u8 shared, __u32 rate_kbps)
+{
+	__u32 qer_id = SDF_RATE_QER_ID_BASE | (filter_index << 4) |
+		       (rule_index & 0xf);
+
+	if (shared)
+		seid = SDF_RATE_SHARED_SEID;
+
+	struct qer_window *window = qer_window_for(seid, qer_id);
+	if (!window)
+		return CTX_ACT_OK;
+
+	volatile __u64 *start = (ctx->interface == INTERFACE_N3) ?
+					&window->ul_start :
+					&window->dl_start;
+
+	const __u64 packet_size =
+		ctx_len_from(ctx->ctx_buff, ctx->data_end, ctx->data);
+
+	return limit_rate_sliding_window(packet_size, start,
+					 (__u64)rate_kbps * 1000);
+}
+
 static __always_inline enum ctx_action
-match_sdf_filters(struct packet_context *ctx, __u32 filter_map_index)
+match_sdf_filters(struct packet_context *ctx, __u32 filter_map_index,
+		  __u64 seid)
 {
 	__u8 pkt_proto;
 	__u16 pkt_dport = 0;
@@ -152,16 +210,54 @@ match_sdf_filters(struct packet_context *ctx, __u32 filter_map_index)
 		.ports_unreadable = ctx->l4_unavailable,
 	};
 
+	portal_ue_key(ctx, &q.ue);
+
 	int verdict = sdf_match(&q);
 
-	if (verdict == SDF_VERDICT_PASS)
-		return CTX_ACT_OK;
+	if (verdict == SDF_VERDICT_DENY || verdict == SDF_VERDICT_UNFILTERABLE) {
+		set_drop_reason(ctx, verdict == SDF_VERDICT_UNFILTERABLE ?
+					     UPF_DROP_FRAGMENT_UNFILTERABLE :
+					     UPF_DROP_SDF_FILTER);
+
+		return CTX_ACT_DROP;
+	}
+
+	/* Whatever passes is billed to the rule's rating group. */
+	if (q.rated)
+		ctx->rating_slot =
+			sdf_rating_slot(filter_map_index, q.rule_index) + 1;
+
+	if (verdict == SDF_VERDICT_RATE_LIMIT &&
+	    sdf_rate_limit(ctx, seid, filter_map_index, q.rule_index,
+			   q.rate_shared, q.rate_kbps) != CTX_ACT_OK) {
+		set_drop_reason(ctx, UPF_DROP_QER_RATE_LIMIT);
+
+		return CTX_ACT_DROP;
+	}
+
+	/* Routing reads it to leave through the breakout egress. */
+	if (verdict == SDF_VERDICT_BREAKOUT)
+		ctx->breakout = q.breakout;
+
+	/* The N3 path redirects it once the rest of the uplink checks pass. */
+	if (verdict == SDF_VERDICT_PORTAL)
+		ctx->portal = q.portal;
+
+	/* Encapsulation reads it to mark the packet with the flow's QFI. */
+	if (q.qfi) {
+		ctx->qfi = q.qfi;
+
+		if (q.qos_rate_kbps != 0 &&
+		    sdf_rate_limit(ctx, seid, filter_map_index,
+				   q.qos_rule_index, 0,
+				   q.qos_rate_kbps) != CTX_ACT_OK) {
+			set_drop_reason(ctx, UPF_DROP_QER_RATE_LIMIT);
 
-	set_drop_reason(ctx, verdict == SDF_VERDICT_UNFILTERABLE ?
-				     UPF_DROP_FRAGMENT_UNFILTERABLE :
-				     UPF_DROP_SDF_FILTER);
+			return CTX_ACT_DROP;
+		}
+	}
 
-	return CTX_ACT_DROP;
+	return CTX_ACT_OK;
 }
 
 __noinline __weak int sdf_match(struct sdf_query *q)
@@ -184,6 +280,8 @@ __noinline __weak int sdf_match(struct sdf_query *q)
 	const __u8 pkt_is_ipv4 = q->is_ipv4;
 	const __u8 ports_unreadable = q->ports_unreadable;
 	const struct in6_addr pkt_remote = q->remote;
+	/* Looked up on the first portal rule only: -1 until then. */
+	int lifted = -1;
 
 #pragma clang loop unroll(disable)
 	for (__u8 i = 0; i < num; i++) {
@@ -192,7 +290,15 @@ __noinline __weak int sdf_match(struct sdf_query *q)
 		if (r->protocol != SDF_PROTO_ANY && r->protocol != pkt_proto)
 			continue;
 
-		if (r->prefix_len != 0) {
+		if (r->fqdn_set != 0) {
+			struct sdf_fqdn_key fk = {
+				.set = r->fqdn_set,
+				.addr = pkt_remote,
+			};
+
+			if (!bpf_map_lookup_elem(&sdf_fqdn_addrs, &fk))
+				continue;
+		} else if (r->prefix_len != 0) {
 			__u8 rule_is_ipv4 = is_ipv4_mapped_ipv6(&r->remote_ip);
 
 			if (pkt_is_ipv4 != rule_is_ipv4)
@@ -236,9 +342,53 @@ __noinline __weak int sdf_match(struct sdf_query *q)
 				continue;
 		}
 
-		if (r->action == 1)
+		if (r->action == SDF_ACTION_PORTAL ||
+		    r->action == SDF_ACTION_PORTAL_DROP) {
+			if (lifted < 0)
+				lifted = bpf_map_lookup_elem(&portal_lifted,
+							     &q->ue) != NULL;
+
+			if (lifted)
+				continue;
+		}
+
+		/* The flow's traffic still meets the rules after it. */
+		if (r->action == SDF_ACTION_QOS_FLOW) {
+			if (!q->qfi) {
+				q->qfi = r->qfi;
+				q->qos_rule_index = i;
+				q->qos_rate_kbps = r->rate_kbps;
+			}
+
+			continue;
+		}
+
+		if (r->action == SDF_ACTION_DENY ||
+		    r->action == SDF_ACTION_PORTAL_DROP)
 			return SDF_VERDICT_DENY;
 
+		q->rule_index = i;
+		q->rated = r->rated;
+
+		if (r->action == SDF_ACTION_RATE_LIMIT) {
+			q->rate_shared = r->rate_shared;
+			q->rate_kbps = r->rate_kbps;
+
+			return SDF_VERDICT_RATE_LIMIT;
+		}
+
+		if (r->action == SDF_ACTION_BREAKOUT) {
+			q->breakout = r->breakout;
+
+			return SDF_VERDICT_BREAKOUT;
+		}
+
+		if (r->action == SDF_ACTION_PORTAL) {
+			q->portal = r->portal;
+
+			return SDF_VERDICT_PORTAL;
+		}
+
 		return SDF_VERDICT_PASS;
 	}
 
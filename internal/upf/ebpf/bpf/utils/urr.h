@@ -8,6 +8,7 @@
 #include "bpf/utils/pdr.h"
 #include "bpf/utils/trace.h"
 #include "bpf/utils/packet_context.h"
+#include "bpf/utils/rating.h"
 
 /* Up to two URRs (uplink, downlink) per session. */
 #define URR_MAP_SIZE (2 * MAX_PDU_SESSIONS)
@@ -38,6 +39,10 @@ static __always_inline void update_urr_bytes(struct packet_context *ctx,
 		upf_printk("upf: urr_id is 0 - no URR associated with packet");
 		return;
 	}
+	if (update_rating_bytes(ctx, seid, urr_id, bytes)) {
+		upf_printk("upf: zero-rated, urr_id:%d not charged", urr_id);
+		return;
+	}
 	upf_printk("upf: update URR found for urr_id:%d", urr_id);
 	struct urr_key key = { .seid = seid, .urr_id = urr_id };
 	__u64 *byte_count = bpf_map_lookup_elem(&urr_map, &key);
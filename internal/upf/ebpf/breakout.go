@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// MaxBreakoutEgress is the size of the breakout egress map. Index 0 stands
+// for the data network's egress, so at most MaxBreakoutEgress-1 breakouts are
+// in use at once. Must match BREAKOUT_EGRESS_MAP_SIZE in C.
+const MaxBreakoutEgress = 256
+
+// ErrBreakoutUnsupported is returned when the loaded datapath predates the
+// breakout_egress map.
+var ErrBreakoutUnsupported = errors.New("datapath has no breakout egress map; regenerate the eBPF bindings")
+
+// HasBreakoutEgress reports whether the loaded datapath carries the
+// breakout egress map.
+func (bpfObjects *BpfObjects) HasBreakoutEgress() bool {
+	return bpfObjects.BreakoutEgress != nil
+}
+
+// PutBreakoutEgress sends uplink traffic matched by breakout rules bound to
+// index through egress.
+func (bpfObjects *BpfObjects) PutBreakoutEgress(index uint8, egress DataNetworkEgress) error {
+	if !bpfObjects.HasBreakoutEgress() {
+		return ErrBreakoutUnsupported
+	}
+
+	if index == 0 {
+		return errors.New("breakout egress index 0 is reserved")
+	}
+
+	logger.UpfLog.Debug("Put breakout egress", zap.Uint8("index", index), zap.Uint32("table", egress.Table), zap.Uint32("ifindex", egress.Ifindex))
+
+	if err := bpfObjects.BreakoutEgress.Put(uint32(index), unsafe.Pointer(&egress)); err != nil {
+		return fmt.Errorf("put breakout egress %d: %w", index, err)
+	}
+
+	return nil
+}
+
+// DeleteBreakoutEgress returns traffic bound to index to its data network's
+// egress. The map is an array, so the entry is zeroed rather than removed.
+func (bpfObjects *BpfObjects) DeleteBreakoutEgress(index uint8) error {
+	return bpfObjects.PutBreakoutEgress(index, DataNetworkEgress{})
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package ebpf
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+const (
+	t2EdgeDev  = "ellt2edge"
+	t2EdgePeer = "ellt2edgep"
+)
+
+var (
+	edgeLocalIP  = [4]byte{192, 0, 2, 129}  // the UPF's address on the edge subnet
+	edgeServerIP = [4]byte{100, 64, 50, 10} // MEC server beside the radios
+)
+
+// requireBreakoutEgress skips on a datapath built before the breakout
+// egress map.
+func requireBreakoutEgress(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasBreakoutEgress() {
+		t.Skip("datapath built without local breakout")
+	}
+}
+
+// TestBreakoutRoutesToEdgeInterface checks that uplink traffic a breakout
+// rule matches leaves through the rule's egress, while the rest of the
+// session's traffic still leaves through N6.
+func TestBreakoutRoutesToEdgeInterface(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid        = 0x42524B31
+		filterIndex = 1
+		index       = 1
+	)
+
+	f := setupT2(t, false)
+	requireBreakoutEgress(t, f.obj)
+
+	_, _ = ipCmd("link", "del", t2EdgeDev)
+	addVethPair(t, t2EdgeDev, t2EdgePeer)
+	addAddr(t, t2EdgeDev, ip4String(edgeLocalIP)+"/25")
+	addRoute(t, "100.64.50.0/24", t2EdgeDev, edgeLocalIP)
+	addNeigh(t, t2EdgeDev, edgeServerIP, "02:00:00:00:00:cc")
+
+	edge := ifByName(t, t2EdgeDev)
+
+	putForwardingUplinkPDRUE(t, f.obj, teid, filterIndex, netip.AddrFrom4(ueIP), netip.Addr{})
+
+	rule := sdfRuleIPv4([4]byte{100, 64, 50, 0}, 24, 0, 0, SdfProtoAny, SdfActionBreakout)
+	rule.Breakout = index
+	putSDFFilter(t, f.obj, filterIndex, []SdfRule{rule})
+
+	if err := f.obj.PutBreakoutEgress(index, DataNetworkEgress{
+		FibIfindex: uint32(edge.Index),
+		Ifindex:    uint32(edge.Index),
+	}); err != nil {
+		t.Fatalf("put breakout egress: %v", err)
+	}
+
+	edgeFD := openCapture(t, ifByName(t, t2EdgePeer).Index)
+
+	f.injectUplink(t, uplinkGPDU(teid, ipv4Packet(ueIP, edgeServerIP, 17,
+		udpDatagramChecksummed(ueIP, edgeServerIP, 1234, 8080, bytesOf(100)))))
+
+	got := captureMatching(edgeFD, time.Second, func(fr []byte) bool {
+		return isInnerIPv4(fr, 17, edgeServerIP)
+	})
+	if got == nil {
+		t.Fatal("broken out packet did not leave through the edge interface")
+	}
+
+	if mac := got[0:6]; mac[5] != 0xcc {
+		t.Errorf("destination MAC = %x, want the edge server's neighbor", mac)
+	}
+
+	n6FD := f.captureN6(t)
+
+	f.injectUplink(t, uplinkGPDU(teid, ipv4Packet(ueIP, serverIP, 17,
+		udpDatagramChecksummed(ueIP, serverIP, 1234, 53, bytesOf(100)))))
+
+	if captureMatching(n6FD, time.Second, func(fr []byte) bool {
+		return isInnerIPv4(fr, 17, serverIP)
+	}) == nil {
+		t.Fatal("traffic the rule does not match did not leave through N6")
+	}
+
+	// Unbound, the rule's traffic follows the data network's egress, which
+	// has no route to the edge subnet on N6.
+	if err := f.obj.DeleteBreakoutEgress(index); err != nil {
+		t.Fatalf("delete breakout egress: %v", err)
+	}
+
+	edgeFD = openCapture(t, ifByName(t, t2EdgePeer).Index)
+
+	f.injectUplink(t, uplinkGPDU(teid, ipv4Packet(ueIP, edgeServerIP, 17,
+		udpDatagramChecksummed(ueIP, edgeServerIP, 1234, 8080, bytesOf(100)))))
+
+	if captureMatching(edgeFD, 300*time.Millisecond, func(fr []byte) bool {
+		return isInnerIPv4(fr, 17, edgeServerIP)
+	}) != nil {
+		t.Fatal("packet left through the edge interface after its breakout was removed")
+	}
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ErrDataNetworkEgressUnsupported is returned when the loaded datapath
+// predates the dn_egress maps.
+var ErrDataNetworkEgressUnsupported = errors.New("datapath has no data network egress maps; regenerate the eBPF bindings")
+
+// DataNetworkEgress mirrors struct dn_egress in routing.h. FibIfindex is
+// the device the data network's table resolves to; Ifindex is the device
+// the datapath redirects to, tagging with Vlan when it is non-zero.
+type DataNetworkEgress struct {
+	Table      uint32
+	FibIfindex uint32
+	Ifindex    uint32
+	Vlan       uint32
+}
+
+// HasDataNetworkEgress reports whether the loaded datapath carries the
+// per-data-network egress maps.
+func (bpfObjects *BpfObjects) HasDataNetworkEgress() bool {
+	return bpfObjects.DnEgressIp4 != nil && bpfObjects.DnEgressIp6 != nil
+}
+
+// PutDataNetworkEgress steers uplink traffic sourced from pool through the
+// given egress.
+func (bpfObjects *BpfObjects) PutDataNetworkEgress(pool netip.Prefix, egress DataNetworkEgress) error {
+	if !bpfObjects.HasDataNetworkEgress() {
+		return ErrDataNetworkEgressUnsupported
+	}
+
+	pool = pool.Masked()
+
+	logger.UpfLog.Debug("Put data network egress", logger.IPAddress(pool.String()), zap.Uint32("table", egress.Table), zap.Uint32("ifindex", egress.Ifindex))
+
+	if pool.Addr().Is4() {
+		key := framedIP4Key{PrefixLen: uint32(pool.Bits()), Addr: pool.Addr().As4()}
+		return bpfObjects.DnEgressIp4.Put(key, unsafe.Pointer(&egress))
+	}
+
+	key := framedIP6Key{PrefixLen: uint32(pool.Bits()), Addr: pool.Addr().As16()}
+
+	return bpfObjects.DnEgressIp6.Put(key, unsafe.Pointer(&egress))
+}
+
+// DeleteDataNetworkEgress returns traffic sourced from pool to n6_ifindex.
+// A missing entry is not an error.
+func (bpfObjects *BpfObjects) DeleteDataNetworkEgress(pool netip.Prefix) error {
+	if !bpfObjects.HasDataNetworkEgress() {
+		return ErrDataNetworkEgressUnsupported
+	}
+
+	pool = pool.Masked()
+
+	var err error
+
+	if pool.Addr().Is4() {
+		err = bpfObjects.DnEgressIp4.Delete(framedIP4Key{PrefixLen: uint32(pool.Bits()), Addr: pool.Addr().As4()})
+	} else {
+		err = bpfObjects.DnEgressIp6.Delete(framedIP6Key{PrefixLen: uint32(pool.Bits()), Addr: pool.Addr().As16()})
+	}
+
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete data network egress %s: %w", pool, err)
+	}
+
+	return nil
+}
+
+// ListDataNetworkEgressPools returns every pool with an egress entry.
+func (bpfObjects *BpfObjects) ListDataNetworkEgressPools() ([]netip.Prefix, error) {
+	if !bpfObjects.HasDataNetworkEgress() {
+		return nil, ErrDataNetworkEgressUnsupported
+	}
+
+	var pools []netip.Prefix
+
+	var (
+		key4 framedIP4Key
+		val  DataNetworkEgress
+	)
+
+	iter4 := bpfObjects.DnEgressIp4.Iterate()
+	for iter4.Next(&key4, &val) {
+		pools = append(pools, netip.PrefixFrom(netip.AddrFrom4(key4.Addr), int(key4.PrefixLen)))
+	}
+
+	if err := iter4.Err(); err != nil {
+		return nil, fmt.Errorf("iterate dn_egress_ip4: %w", err)
+	}
+
+	var key6 framedIP6Key
+
+	iter6 := bpfObjects.DnEgressIp6.Iterate()
+	for iter6.Next(&key6, &val) {
+		pools = append(pools, netip.PrefixFrom(netip.AddrFrom16(key6.Addr), int(key6.PrefixLen)))
+	}
+
+	if err := iter6.Err(); err != nil {
+		return nil, fmt.Errorf("iterate dn_egress_ip6: %w", err)
+	}
+
+	return pools, nil
+}
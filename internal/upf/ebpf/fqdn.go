@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/cilium/ebpf"
+)
+
+// ErrFQDNUnsupported is returned when the loaded datapath predates the FQDN
+// rule maps.
+var ErrFQDNUnsupported = errors.New("datapath has no FQDN rule maps; regenerate the eBPF bindings")
+
+// FQDNSnoopMaxPayload matches FQDN_SNOOP_MAX_PAYLOAD in fqdn.h.
+const FQDNSnoopMaxPayload = 1280
+
+// Snooped payload kinds, matching enum fqdn_snoop_kind.
+const (
+	FQDNSnoopDNS uint8 = 1
+	FQDNSnoopTLS uint8 = 2
+)
+
+// sdfFqdnKey mirrors struct sdf_fqdn_key.
+type sdfFqdnKey struct {
+	Set  uint16
+	Pad  uint16
+	Addr [16]byte
+}
+
+// FQDNSnoopEvent is one payload the datapath copied for userspace. Remote is
+// the peer on the N6 side: the DNS server, or the TLS server being dialled.
+type FQDNSnoopEvent struct {
+	Kind    uint8
+	Remote  netip.Addr
+	Payload []byte
+}
+
+// fqdnSnoopHeaderLen is the size of struct fqdn_snoop_event before payload.
+const fqdnSnoopHeaderLen = 4 + 16
+
+// ParseFQDNSnoopEvent decodes a fqdn_snoop_events ring buffer sample.
+func ParseFQDNSnoopEvent(raw []byte) (FQDNSnoopEvent, error) {
+	if len(raw) < fqdnSnoopHeaderLen {
+		return FQDNSnoopEvent{}, fmt.Errorf("FQDN snoop event too short: %d bytes", len(raw))
+	}
+
+	n := int(binary.NativeEndian.Uint16(raw[2:4]))
+	if n > FQDNSnoopMaxPayload || fqdnSnoopHeaderLen+n > len(raw) {
+		return FQDNSnoopEvent{}, fmt.Errorf("FQDN snoop event length %d out of range", n)
+	}
+
+	return FQDNSnoopEvent{
+		Kind:    raw[0],
+		Remote:  netip.AddrFrom16([16]byte(raw[4:20])).Unmap(),
+		Payload: raw[fqdnSnoopHeaderLen : fqdnSnoopHeaderLen+n],
+	}, nil
+}
+
+// HasFQDNSets reports whether the loaded datapath carries the FQDN rule maps.
+func (bpfObjects *BpfObjects) HasFQDNSets() bool {
+	return bpfObjects.SdfFqdnAddrs != nil && bpfObjects.FqdnSnoopConfig != nil &&
+		bpfObjects.FqdnSnoopEvents != nil
+}
+
+func fqdnKey(set uint16, addr netip.Addr) sdfFqdnKey {
+	return sdfFqdnKey{Set: set, Addr: addr.As16()}
+}
+
+// SetFQDNSnoop turns payload snooping on or off. It is off while no FQDN
+// rule is installed.
+func (bpfObjects *BpfObjects) SetFQDNSnoop(enabled bool) error {
+	if !bpfObjects.HasFQDNSets() {
+		return ErrFQDNUnsupported
+	}
+
+	var val uint32
+	if enabled {
+		val = 1
+	}
+
+	return bpfObjects.FqdnSnoopConfig.Put(uint32(0), val)
+}
+
+// PutFQDNAddr makes addr match rules carrying the given FQDN set. IPv4
+// addresses are stored IPv4-mapped, as sdf_match compares them.
+func (bpfObjects *BpfObjects) PutFQDNAddr(set uint16, addr netip.Addr) error {
+	if !bpfObjects.HasFQDNSets() {
+		return ErrFQDNUnsupported
+	}
+
+	var one uint8 = 1
+
+	if err := bpfObjects.SdfFqdnAddrs.Put(fqdnKey(set, addr), one); err != nil {
+		return fmt.Errorf("put FQDN address %s in set %d: %w", addr, set, err)
+	}
+
+	return nil
+}
+
+// DeleteFQDNAddr removes a learned address. A missing entry is not an error.
+func (bpfObjects *BpfObjects) DeleteFQDNAddr(set uint16, addr netip.Addr) error {
+	if !bpfObjects.HasFQDNSets() {
+		return ErrFQDNUnsupported
+	}
+
+	err := bpfObjects.SdfFqdnAddrs.Delete(fqdnKey(set, addr))
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete FQDN address %s from set %d: %w", addr, set, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package ebpf
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+// requireFQDNSets skips on a datapath built before the FQDN rule maps.
+func requireFQDNSets(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasFQDNSets() {
+		t.Skip("datapath built without FQDN rules")
+	}
+}
+
+// TestSDFFQDNLearnedAddressMatching checks that a rule carrying an FQDN set
+// matches exactly the addresses learned into that set, and stops matching
+// one once it is removed.
+func TestSDFFQDNLearnedAddressMatching(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid        = 0x46514401
+		filterIndex = 1
+		set         = 3
+	)
+
+	learned := [4]byte{8, 8, 8, 8}
+	other := [4]byte{9, 9, 9, 9}
+
+	obj := loadN3N6Program(t)
+	requireFQDNSets(t, obj)
+	putForwardingUplinkPDR(t, obj, teid, filterIndex)
+
+	// The rule's prefix is ignored for an FQDN set: a wildcard here must
+	// not make it match everything.
+	deny := sdfRuleIPv4([4]byte{}, 0, 0, 0, SdfProtoAny, SdfActionDeny)
+	deny.FQDNSet = set
+	putSDFFilter(t, obj, filterIndex, []SdfRule{deny})
+
+	if err := obj.PutFQDNAddr(set, netip.AddrFrom4(learned)); err != nil {
+		t.Fatalf("put FQDN address: %v", err)
+	}
+
+	if err := obj.PutFQDNAddr(set+1, netip.AddrFrom4(other)); err != nil {
+		t.Fatalf("put FQDN address: %v", err)
+	}
+
+	run := func(dst [4]byte) uint32 {
+		action, _ := runXDPOut(t, obj.UpfEntryFunc, uplinkGPDU(teid, innerIPv4UDP(dst, 53)))
+
+		return action
+	}
+
+	if action := run(learned); action != ActionDrop {
+		t.Fatalf("learned address: got XDP action %d, want ActionDrop", action)
+	}
+
+	if action := run(other); action == ActionDrop {
+		t.Fatal("address learned into another set was dropped")
+	}
+
+	if err := obj.DeleteFQDNAddr(set, netip.AddrFrom4(learned)); err != nil {
+		t.Fatalf("delete FQDN address: %v", err)
+	}
+
+	if action := run(learned); action == ActionDrop {
+		t.Fatal("expired address still matched the rule")
+	}
+}
+
+// TestFQDNSnoopTLSClientHello checks that, while snooping is on, an uplink
+// TLS handshake record toward port 443 is copied to userspace with the
+// server address, and that other TCP payloads are not.
+func TestFQDNSnoopTLSClientHello(t *testing.T) {
+	requireProgTestRun(t)
+
+	const teid = 0x46514402
+
+	server := [4]byte{198, 51, 100, 7}
+
+	obj := loadN3N6Program(t)
+	requireFQDNSets(t, obj)
+	putForwardingUplinkPDR(t, obj, teid, 0)
+
+	if err := obj.SetFQDNSnoop(true); err != nil {
+		t.Fatalf("enable FQDN snoop: %v", err)
+	}
+
+	rd, err := ringbuf.NewReader(obj.FqdnSnoopEvents)
+	if err != nil {
+		t.Fatalf("open fqdn_snoop_events ring buffer: %v", err)
+	}
+
+	defer func() { _ = rd.Close() }()
+
+	segment := func(payload []byte) []byte {
+		return ipv4Packet([4]byte{10, 0, 0, 9}, server, 6, append(tcpSegment(40000, 443), payload...))
+	}
+
+	// A handshake record (0x16) opening a ClientHello; the application-data
+	// record (0x17) sent first must be skipped.
+	hello := []byte{0x16, 0x03, 0x01, 0x00, 0x04, 0x01, 0x00, 0x00, 0x00}
+
+	runXDPOut(t, obj.UpfEntryFunc, uplinkGPDU(teid, segment([]byte{0x17, 0x03, 0x03, 0x00, 0x00})))
+	runXDPOut(t, obj.UpfEntryFunc, uplinkGPDU(teid, segment(hello)))
+
+	rd.SetDeadline(time.Now().Add(time.Second))
+
+	rec, err := rd.Read()
+	if err != nil {
+		t.Fatalf("no snoop event for the ClientHello: %v", err)
+	}
+
+	ev, err := ParseFQDNSnoopEvent(rec.RawSample)
+	if err != nil {
+		t.Fatalf("decode snoop event: %v", err)
+	}
+
+	if ev.Kind != FQDNSnoopTLS {
+		t.Fatalf("event kind = %d, want %d", ev.Kind, FQDNSnoopTLS)
+	}
+
+	if ev.Remote != netip.AddrFrom4(server) {
+		t.Errorf("event remote = %s, want %s", ev.Remote, netip.AddrFrom4(server))
+	}
+
+	if !bytes.Equal(ev.Payload, hello) {
+		t.Errorf("event payload = %x, want the ClientHello %x", ev.Payload, hello)
+	}
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ErrTCPMSSClampUnsupported is returned when the loaded datapath predates
+// the tcp_mss_clamp maps.
+var ErrTCPMSSClampUnsupported = errors.New("datapath has no TCP MSS clamp maps; regenerate the eBPF bindings")
+
+// TCPMSSClamp mirrors struct tcp_mss_clamp in mss.h. A non-zero MSS is the
+// limit; otherwise the datapath derives it from MTU less the session's
+// GTP-U encapsulation and the inner IP and TCP headers.
+type TCPMSSClamp struct {
+	MTU uint16
+	MSS uint16
+}
+
+// HasTCPMSSClamp reports whether the loaded datapath carries the TCP MSS
+// clamp maps.
+func (bpfObjects *BpfObjects) HasTCPMSSClamp() bool {
+	return bpfObjects.TcpMssClampIp4 != nil && bpfObjects.TcpMssClampIp6 != nil
+}
+
+// PutTCPMSSClamp clamps the MSS of TCP handshakes to and from pool.
+func (bpfObjects *BpfObjects) PutTCPMSSClamp(pool netip.Prefix, clamp TCPMSSClamp) error {
+	if !bpfObjects.HasTCPMSSClamp() {
+		return ErrTCPMSSClampUnsupported
+	}
+
+	pool = pool.Masked()
+
+	logger.UpfLog.Debug("Put TCP MSS clamp", logger.IPAddress(pool.String()), zap.Uint16("mtu", clamp.MTU), zap.Uint16("mss", clamp.MSS))
+
+	if pool.Addr().Is4() {
+		key := framedIP4Key{PrefixLen: uint32(pool.Bits()), Addr: pool.Addr().As4()}
+		return bpfObjects.TcpMssClampIp4.Put(key, unsafe.Pointer(&clamp))
+	}
+
+	key := framedIP6Key{PrefixLen: uint32(pool.Bits()), Addr: pool.Addr().As16()}
+
+	return bpfObjects.TcpMssClampIp6.Put(key, unsafe.Pointer(&clamp))
+}
+
+// DeleteTCPMSSClamp leaves TCP handshakes to and from pool unchanged. A
+// missing entry is not an error.
+func (bpfObjects *BpfObjects) DeleteTCPMSSClamp(pool netip.Prefix) error {
+	if !bpfObjects.HasTCPMSSClamp() {
+		return ErrTCPMSSClampUnsupported
+	}
+
+	pool = pool.Masked()
+
+	var err error
+
+	if pool.Addr().Is4() {
+		err = bpfObjects.TcpMssClampIp4.Delete(framedIP4Key{PrefixLen: uint32(pool.Bits()), Addr: pool.Addr().As4()})
+	} else {
+		err = bpfObjects.TcpMssClampIp6.Delete(framedIP6Key{PrefixLen: uint32(pool.Bits()), Addr: pool.Addr().As16()})
+	}
+
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete TCP MSS clamp %s: %w", pool, err)
+	}
+
+	return nil
+}
+
+// ListTCPMSSClampPools returns every pool with a clamp entry.
+func (bpfObjects *BpfObjects) ListTCPMSSClampPools() ([]netip.Prefix, error) {
+	if !bpfObjects.HasTCPMSSClamp() {
+		return nil, ErrTCPMSSClampUnsupported
+	}
+
+	var (
+		pools []netip.Prefix
+		key4  framedIP4Key
+		key6  framedIP6Key
+		val   TCPMSSClamp
+	)
+
+	iter4 := bpfObjects.TcpMssClampIp4.Iterate()
+	for iter4.Next(&key4, &val) {
+		pools = append(pools, netip.PrefixFrom(netip.AddrFrom4(key4.Addr), int(key4.PrefixLen)))
+	}
+
+	if err := iter4.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tcp_mss_clamp_ip4: %w", err)
+	}
+
+	iter6 := bpfObjects.TcpMssClampIp6.Iterate()
+	for iter6.Next(&key6, &val) {
+		pools = append(pools, netip.PrefixFrom(netip.AddrFrom16(key6.Addr), int(key6.PrefixLen)))
+	}
+
+	if err := iter6.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tcp_mss_clamp_ip6: %w", err)
+	}
+
+	return pools, nil
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+// requireTCPMSSClamp skips on a datapath built before the TCP MSS clamp
+// maps.
+func requireTCPMSSClamp(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasTCPMSSClamp() {
+		t.Skip("datapath built without TCP MSS clamping")
+	}
+}
+
+// tcpSYNWithOptions builds a checksummed TCP segment with the given flags
+// whose header carries options, padded to a multiple of four bytes.
+func tcpSYNWithOptions(src, dst [4]byte, sport, dport uint16, flags byte, options []byte) []byte {
+	optLen := (len(options) + 3) &^ 3
+	seg := make([]byte, 20+optLen)
+
+	binary.BigEndian.PutUint16(seg[0:2], sport)
+	binary.BigEndian.PutUint16(seg[2:4], dport)
+	seg[12] = byte((20+optLen)/4) << 4
+	seg[13] = flags
+	binary.BigEndian.PutUint16(seg[14:16], 64240)
+	copy(seg[20:], options)
+	binary.BigEndian.PutUint16(seg[16:18], ipv4L4Checksum(src, dst, 6, seg))
+
+	return seg
+}
+
+// mssOption is an MSS option preceded by pad NOPs, so it starts at an odd
+// offset when pad is odd.
+func mssOption(pad int, mss uint16) []byte {
+	opts := make([]byte, pad, pad+4)
+	for i := range opts {
+		opts[i] = 1
+	}
+
+	return append(opts, 2, 4, byte(mss>>8), byte(mss))
+}
+
+// checkClampedSegment checks that the TCP segment after ip carries an MSS of
+// want in its option at offset pad, and that both checksums still verify.
+func checkClampedSegment(t *testing.T, ip []byte, pad int, want uint16) {
+	t.Helper()
+
+	if !validIPv4Checksum(ip[:20]) {
+		t.Fatal("IPv4 header checksum invalid")
+	}
+
+	var src, dst [4]byte
+
+	copy(src[:], ip[12:16])
+	copy(dst[:], ip[16:20])
+
+	seg := ip[20:binary.BigEndian.Uint16(ip[2:4])]
+
+	if got := binary.BigEndian.Uint16(seg[20+pad+2:]); got != want {
+		t.Errorf("MSS = %d, want %d", got, want)
+	}
+
+	if !validIPv4L4Checksum(src, dst, 6, seg) {
+		t.Error("TCP checksum invalid after clamping")
+	}
+}
+
+// TestTCPMSSClampUplinkSYN checks that an uplink SYN from a clamped pool has
+// its MSS lowered to the fixed limit with the checksum fixed up, whether the
+// option starts at an even or an odd offset, and that an MSS already below
+// the limit and a SYN from another pool are left alone.
+func TestTCPMSSClampUplinkSYN(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid  = 0x4D535331
+		limit = 1200
+	)
+
+	server := [4]byte{198, 51, 100, 80}
+
+	obj := loadN3N6Program(t)
+	requireTCPMSSClamp(t, obj)
+	putForwardingUplinkPDRUE(t, obj, teid, 0, netip.AddrFrom4(ueIP), netip.Addr{})
+
+	if err := obj.PutTCPMSSClamp(netip.MustParsePrefix("10.45.0.0/24"), TCPMSSClamp{MSS: limit}); err != nil {
+		t.Fatalf("put TCP MSS clamp: %v", err)
+	}
+
+	run := func(t *testing.T, src [4]byte, pad int, mss uint16) []byte {
+		t.Helper()
+
+		seg := tcpSYNWithOptions(src, server, 40000, 443, 0x02, mssOption(pad, mss))
+
+		action, out := runXDPOut(t, obj.UpfEntryFunc, uplinkGPDU(teid, ipv4Packet(src, server, 6, seg)))
+		if action == ActionDrop {
+			t.Fatal("SYN was dropped")
+		}
+
+		return out[ethHdrLen:]
+	}
+
+	t.Run("even offset", func(t *testing.T) {
+		checkClampedSegment(t, run(t, ueIP, 0, 1460), 0, limit)
+	})
+
+	t.Run("odd offset", func(t *testing.T) {
+		checkClampedSegment(t, run(t, ueIP, 1, 1460), 1, limit)
+	})
+
+	t.Run("below the limit", func(t *testing.T) {
+		checkClampedSegment(t, run(t, ueIP, 0, 1000), 0, 1000)
+	})
+
+	t.Run("other pool", func(t *testing.T) {
+		other := [4]byte{10, 46, 0, 1}
+		putForwardingUplinkPDRUE(t, obj, teid, 0, netip.AddrFrom4(other), netip.Addr{})
+
+		checkClampedSegment(t, run(t, other, 0, 1460), 0, 1460)
+	})
+}
+
+// TestTCPMSSClampDownlinkSYNACK checks that a downlink SYN-ACK toward a
+// clamped pool has its MSS lowered to the data network's MTU less the
+// GTP-U, IP and TCP headers, with the inner checksum fixed up.
+func TestTCPMSSClampDownlinkSYNACK(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid = 0x4D535332
+		qfi  = 5
+		mtu  = 1400
+	)
+
+	server := [4]byte{198, 51, 100, 80}
+	local := [4]byte{192, 168, 100, 1}
+	remote := [4]byte{192, 168, 100, 9}
+
+	obj := loadProgram(t, 1, 0)
+	requireTCPMSSClamp(t, obj)
+	putDownlinkPDR(t, obj, ueIP, teid, local, remote, qfi)
+
+	if err := obj.PutTCPMSSClamp(netip.MustParsePrefix("10.45.0.0/24"), TCPMSSClamp{MTU: mtu}); err != nil {
+		t.Fatalf("put TCP MSS clamp: %v", err)
+	}
+
+	seg := tcpSYNWithOptions(server, ueIP, 443, 40000, 0x12, mssOption(1, 1460))
+
+	action, out := runXDPOut(t, obj.UpfEntryFunc, ethFrame(0x0800, ipv4Packet(server, ueIP, 6, seg)))
+	if action == ActionDrop {
+		t.Fatal("SYN-ACK was dropped")
+	}
+
+	checkClampedSegment(t, parseGTPv4Frame(t, out).inner, 1, mtu-gtpV4EncapLen-20-20)
+}
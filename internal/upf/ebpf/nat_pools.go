@@ -0,0 +1,329 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ErrNATPoolsUnsupported is returned when the loaded datapath predates the
+// NAT pool and port-forward maps.
+var ErrNATPoolsUnsupported = errors.New("datapath has no NAT pool maps; regenerate the eBPF bindings")
+
+// NATPoolMaxAddrs matches NAT_POOL_MAX_ADDRS in nat_ct.h.
+const NATPoolMaxAddrs = 16
+
+// Mapping modes, matching enum nat_pool_mapping.
+const (
+	NATMappingDeterministic uint8 = 0
+	NATMappingRoundRobin    uint8 = 1
+)
+
+// NATPool is one data network's public source pool. A zero port range
+// means NatPortMin to NatPortMax.
+type NATPool struct {
+	Addresses []netip.Addr
+	Mapping   uint8
+	PortMin   uint16
+	PortMax   uint16
+}
+
+// natPool mirrors struct nat_pool. Addresses are kept in network byte
+// order, as the datapath compares them against iphdr fields.
+type natPool struct {
+	Addrs   [NATPoolMaxAddrs][4]byte
+	Count   uint32
+	Cursor  uint32
+	PortMin uint16
+	PortMax uint16
+	Mapping uint8
+	Pad     [3]uint8
+}
+
+// natPortRange mirrors struct nat_port_range.
+type natPortRange struct {
+	Min uint16
+	Max uint16
+}
+
+// NATForwardKey is the public side of a port forward. Proto is an IP
+// protocol number.
+type NATForwardKey struct {
+	Address netip.Addr
+	Port    uint16
+	Proto   uint8
+}
+
+// NATForward is the UE side of a port forward.
+type NATForward struct {
+	UEAddress netip.Addr
+	UEPort    uint16
+}
+
+// natForwardKey and natForward mirror the C structs; ports are big-endian.
+type natForwardKey struct {
+	Addr  [4]byte
+	Port  [2]byte
+	Proto uint8
+	Pad   uint8
+}
+
+type natForward struct {
+	UEAddr [4]byte
+	UEPort [2]byte
+	Pad    uint16
+}
+
+func portBE(port uint16) [2]byte {
+	var b [2]byte
+
+	binary.BigEndian.PutUint16(b[:], port)
+
+	return b
+}
+
+func (k NATForwardKey) raw() natForwardKey {
+	return natForwardKey{Addr: k.Address.As4(), Port: portBE(k.Port), Proto: k.Proto}
+}
+
+// HasNATPools reports whether the loaded datapath carries the NAT pool and
+// port-forward maps.
+func (bpfObjects *BpfObjects) HasNATPools() bool {
+	return bpfObjects.NatPools != nil && bpfObjects.NatPoolAddrs != nil &&
+		bpfObjects.NatSourceOverrides != nil && bpfObjects.NatForwards != nil
+}
+
+// PutNATPool translates UEs inside uePool to the given public pool. The
+// round-robin cursor restarts on every write.
+func (bpfObjects *BpfObjects) PutNATPool(uePool netip.Prefix, pool NATPool) error {
+	if !bpfObjects.HasNATPools() {
+		return ErrNATPoolsUnsupported
+	}
+
+	if len(pool.Addresses) == 0 || len(pool.Addresses) > NATPoolMaxAddrs {
+		return fmt.Errorf("NAT pool needs 1 to %d addresses, got %d", NATPoolMaxAddrs, len(pool.Addresses))
+	}
+
+	uePool = uePool.Masked()
+
+	if pool.PortMin == 0 && pool.PortMax == 0 {
+		pool.PortMin, pool.PortMax = NatPortMin, NatPortMax
+	}
+
+	val := natPool{
+		Count:   uint32(len(pool.Addresses)),
+		PortMin: pool.PortMin,
+		PortMax: pool.PortMax,
+		Mapping: pool.Mapping,
+	}
+
+	for i, addr := range pool.Addresses {
+		val.Addrs[i] = addr.As4()
+
+		rng := natPortRange{Min: pool.PortMin, Max: pool.PortMax}
+		if err := bpfObjects.NatPoolAddrs.Put(addr.As4(), unsafe.Pointer(&rng)); err != nil {
+			return fmt.Errorf("put NAT pool address %s: %w", addr, err)
+		}
+	}
+
+	logger.UpfLog.Debug("Put NAT pool", logger.IPAddress(uePool.String()), zap.Int("addresses", len(pool.Addresses)), zap.Uint8("mapping", pool.Mapping))
+
+	key := framedIP4Key{PrefixLen: uint32(uePool.Bits()), Addr: uePool.Addr().As4()}
+
+	return bpfObjects.NatPools.Put(key, unsafe.Pointer(&val))
+}
+
+// DeleteNATPool returns UEs inside uePool to masquerading on the egress
+// address. A missing entry is not an error.
+func (bpfObjects *BpfObjects) DeleteNATPool(uePool netip.Prefix) error {
+	if !bpfObjects.HasNATPools() {
+		return ErrNATPoolsUnsupported
+	}
+
+	uePool = uePool.Masked()
+
+	err := bpfObjects.NatPools.Delete(framedIP4Key{PrefixLen: uint32(uePool.Bits()), Addr: uePool.Addr().As4()})
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete NAT pool %s: %w", uePool, err)
+	}
+
+	return nil
+}
+
+// ListNATPoolPrefixes returns every UE pool with a NAT pool entry.
+func (bpfObjects *BpfObjects) ListNATPoolPrefixes() ([]netip.Prefix, error) {
+	if !bpfObjects.HasNATPools() {
+		return nil, ErrNATPoolsUnsupported
+	}
+
+	var (
+		key      framedIP4Key
+		val      natPool
+		prefixes []netip.Prefix
+	)
+
+	iter := bpfObjects.NatPools.Iterate()
+	for iter.Next(&key, &val) {
+		prefixes = append(prefixes, netip.PrefixFrom(netip.AddrFrom4(key.Addr), int(key.PrefixLen)))
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate nat_pools: %w", err)
+	}
+
+	return prefixes, nil
+}
+
+// DeleteNATPoolAddress drops the port range of a public address no pool
+// uses any more. A missing entry is not an error.
+func (bpfObjects *BpfObjects) DeleteNATPoolAddress(addr netip.Addr) error {
+	if !bpfObjects.HasNATPools() {
+		return ErrNATPoolsUnsupported
+	}
+
+	err := bpfObjects.NatPoolAddrs.Delete(addr.As4())
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete NAT pool address %s: %w", addr, err)
+	}
+
+	return nil
+}
+
+// ListNATPoolAddresses returns every public address with a port range.
+func (bpfObjects *BpfObjects) ListNATPoolAddresses() ([]netip.Addr, error) {
+	if !bpfObjects.HasNATPools() {
+		return nil, ErrNATPoolsUnsupported
+	}
+
+	var (
+		key   [4]byte
+		val   natPortRange
+		addrs []netip.Addr
+	)
+
+	iter := bpfObjects.NatPoolAddrs.Iterate()
+	for iter.Next(&key, &val) {
+		addrs = append(addrs, netip.AddrFrom4(key))
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate nat_pool_addrs: %w", err)
+	}
+
+	return addrs, nil
+}
+
+// PutNATForward exposes a UE port on a public address and port, and makes
+// the UE source all of its traffic from that address.
+func (bpfObjects *BpfObjects) PutNATForward(key NATForwardKey, fwd NATForward) error {
+	if !bpfObjects.HasNATPools() {
+		return ErrNATPoolsUnsupported
+	}
+
+	logger.UpfLog.Debug("Put NAT port forward", logger.IPAddress(key.Address.String()), zap.Uint16("port", key.Port), zap.Uint8("proto", key.Proto), zap.String("ue", fwd.UEAddress.String()), zap.Uint16("ue_port", fwd.UEPort))
+
+	val := natForward{UEAddr: fwd.UEAddress.As4(), UEPort: portBE(fwd.UEPort)}
+
+	raw := key.raw()
+	if err := bpfObjects.NatForwards.Put(raw, unsafe.Pointer(&val)); err != nil {
+		return fmt.Errorf("put NAT port forward %s:%d: %w", key.Address, key.Port, err)
+	}
+
+	public := key.Address.As4()
+
+	return bpfObjects.NatSourceOverrides.Put(fwd.UEAddress.As4(), public)
+}
+
+// DeleteNATForward removes a port forward. A missing entry is not an error.
+func (bpfObjects *BpfObjects) DeleteNATForward(key NATForwardKey) error {
+	if !bpfObjects.HasNATPools() {
+		return ErrNATPoolsUnsupported
+	}
+
+	err := bpfObjects.NatForwards.Delete(key.raw())
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete NAT port forward %s:%d: %w", key.Address, key.Port, err)
+	}
+
+	return nil
+}
+
+// ListNATForwards returns every installed port forward.
+func (bpfObjects *BpfObjects) ListNATForwards() (map[NATForwardKey]NATForward, error) {
+	if !bpfObjects.HasNATPools() {
+		return nil, ErrNATPoolsUnsupported
+	}
+
+	var (
+		key natForwardKey
+		val natForward
+	)
+
+	out := make(map[NATForwardKey]NATForward)
+
+	iter := bpfObjects.NatForwards.Iterate()
+	for iter.Next(&key, &val) {
+		out[NATForwardKey{
+			Address: netip.AddrFrom4(key.Addr),
+			Port:    binary.BigEndian.Uint16(key.Port[:]),
+			Proto:   key.Proto,
+		}] = NATForward{
+			UEAddress: netip.AddrFrom4(val.UEAddr),
+			UEPort:    binary.BigEndian.Uint16(val.UEPort[:]),
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate nat_forwards: %w", err)
+	}
+
+	return out, nil
+}
+
+// DeleteNATSourceOverride returns a UE without port forwards to its pool's
+// mapping. A missing entry is not an error.
+func (bpfObjects *BpfObjects) DeleteNATSourceOverride(ue netip.Addr) error {
+	if !bpfObjects.HasNATPools() {
+		return ErrNATPoolsUnsupported
+	}
+
+	err := bpfObjects.NatSourceOverrides.Delete(ue.As4())
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete NAT source override %s: %w", ue, err)
+	}
+
+	return nil
+}
+
+// ListNATSourceOverrides returns every UE with a source override.
+func (bpfObjects *BpfObjects) ListNATSourceOverrides() ([]netip.Addr, error) {
+	if !bpfObjects.HasNATPools() {
+		return nil, ErrNATPoolsUnsupported
+	}
+
+	var (
+		key [4]byte
+		val [4]byte
+		out []netip.Addr
+	)
+
+	iter := bpfObjects.NatSourceOverrides.Iterate()
+	for iter.Next(&key, &val) {
+		out = append(out, netip.AddrFrom4(key))
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate nat_source_overrides: %w", err)
+	}
+
+	return out, nil
+}
@@ -1600,3 +1600,178 @@ func TestNATReusedFragmentIDKeepsOneIdentification(t *testing.T) {
 			first, second, later)
 	}
 }
+
+// requireNATPools skips on a datapath built before the NAT pool maps.
+func requireNATPools(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasNATPools() {
+		t.Skip("datapath built without NAT pools")
+	}
+}
+
+// TestNATPoolSourceAddress verifies that a UE inside a pool's UE prefix is
+// translated to the pool address its own address picks, with a port from
+// the pool's range, and that the reply to that address and port is
+// translated back to the UE.
+func TestNATPoolSourceAddress(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		ulTEID  = 0x504F4F31
+		dlTEID  = 0x504F4F32
+		qfi     = 7
+		portMin = 40000
+		portMax = 40100
+	)
+
+	poolAddrs := [][4]byte{{203, 0, 113, 10}, {203, 0, 113, 11}}
+
+	f := setupT2(t, true)
+	requireNATPools(t, f.obj)
+	putForwardingUplinkPDRUE(t, f.obj, ulTEID, 0, netip.AddrFrom4(ueIP), netip.Addr{})
+	putDownlinkPDR(t, f.obj, ueIP, dlTEID, testUPFN3IP, testGNBIP, qfi)
+
+	if err := f.obj.PutNATPool(netip.PrefixFrom(netip.AddrFrom4(ueIP), 24), NATPool{
+		Addresses: []netip.Addr{netip.AddrFrom4(poolAddrs[0]), netip.AddrFrom4(poolAddrs[1])},
+		Mapping:   NATMappingDeterministic,
+		PortMin:   portMin,
+		PortMax:   portMax,
+	}); err != nil {
+		t.Fatalf("put NAT pool: %v", err)
+	}
+
+	// Deterministic mapping indexes the pool by the UE address.
+	want := poolAddrs[binary.BigEndian.Uint32(ueIP[:])%uint32(len(poolAddrs))]
+
+	capFD := f.captureN6(t)
+
+	origL4 := udpDatagramChecksummed(ueIP, serverIP, 1234, 53, bytesOf(100))
+	f.injectUplink(t, uplinkGPDU(ulTEID, ipv4Packet(ueIP, serverIP, 17, origL4)))
+
+	got := captureMatching(capFD, time.Second, func(fr []byte) bool {
+		return isInnerIPv4(fr, 17, serverIP)
+	})
+	if got == nil {
+		t.Fatal("did not capture a NAT'd packet on the N6 side")
+	}
+
+	ip := got[ethHdrLen : ethHdrLen+20]
+	l4 := got[ethHdrLen+20:]
+
+	if !bytes.Equal(ip[12:16], want[:]) {
+		t.Fatalf("inner src = %v, want pool address %v", ip[12:16], want)
+	}
+
+	sport := binary.BigEndian.Uint16(l4[0:2])
+	if sport < portMin || sport > portMax {
+		t.Fatalf("source port = %d, want one in the pool range %d-%d", sport, portMin, portMax)
+	}
+
+	if !validIPv4Checksum(ip) || !validIPv4L4Checksum(want, serverIP, 17, l4) {
+		t.Fatal("checksums invalid after pool NAT")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	capFD = f.captureN3(t)
+
+	reply := udpDatagramChecksummed(serverIP, want, 53, sport, bytesOf(100))
+	f.injectDownlink(t, ethFrame(0x0800, ipv4Packet(serverIP, want, 17, reply)))
+
+	got = captureMatching(capFD, time.Second, func(fr []byte) bool {
+		inner := gtpInner(fr)
+
+		return inner != nil && inner[9] == 17
+	})
+	if got == nil {
+		t.Fatal("did not capture the reply re-encapsulated on the N3 side")
+	}
+
+	assertDestinationNATd(t, got, natProtos[1])
+}
+
+// TestNATPortForwardDNAT verifies that a SYN to a forwarded public address
+// and port reaches the UE's address and port, and that the UE's answer
+// leaves from the forwarded address and port.
+func TestNATPortForwardDNAT(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		ulTEID     = 0x46574431
+		dlTEID     = 0x46574432
+		qfi        = 7
+		publicPort = 8080
+		uePort     = 80
+		clientPort = 51000
+	)
+
+	publicAddr := [4]byte{203, 0, 113, 20}
+
+	f := setupT2(t, true)
+	requireNATPools(t, f.obj)
+	putForwardingUplinkPDRUE(t, f.obj, ulTEID, 0, netip.AddrFrom4(ueIP), netip.Addr{})
+	putDownlinkPDR(t, f.obj, ueIP, dlTEID, testUPFN3IP, testGNBIP, qfi)
+
+	if err := f.obj.PutNATForward(
+		NATForwardKey{Address: netip.AddrFrom4(publicAddr), Port: publicPort, Proto: 6},
+		NATForward{UEAddress: netip.AddrFrom4(ueIP), UEPort: uePort},
+	); err != nil {
+		t.Fatalf("put NAT forward: %v", err)
+	}
+
+	capFD := f.captureN3(t)
+
+	syn := tcpSegmentWithFlags(serverIP, publicAddr, clientPort, publicPort, 0x02)
+	f.injectDownlink(t, ethFrame(0x0800, ipv4Packet(serverIP, publicAddr, 6, syn)))
+
+	got := captureMatching(capFD, time.Second, func(fr []byte) bool {
+		inner := gtpInner(fr)
+
+		return inner != nil && inner[9] == 6
+	})
+	if got == nil {
+		t.Fatal("did not capture the forwarded SYN on the N3 side")
+	}
+
+	inner := gtpInner(got)
+	ip := inner[:20]
+	l4 := inner[20:]
+
+	if !bytes.Equal(ip[16:20], ueIP[:]) {
+		t.Fatalf("inner dst = %v, want UE %v", ip[16:20], ueIP)
+	}
+
+	if dp := binary.BigEndian.Uint16(l4[2:4]); dp != uePort {
+		t.Fatalf("inner dest port = %d, want %d", dp, uePort)
+	}
+
+	if !validIPv4Checksum(ip) || !validIPv4L4Checksum(serverIP, ueIP, 6, l4) {
+		t.Fatal("checksums invalid after forward DNAT")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	capFD = f.captureN6(t)
+
+	synAck := tcpSegmentWithFlags(ueIP, serverIP, uePort, clientPort, 0x12)
+	f.injectUplink(t, uplinkGPDU(ulTEID, ipv4Packet(ueIP, serverIP, 6, synAck)))
+
+	got = captureMatching(capFD, time.Second, func(fr []byte) bool {
+		return isInnerIPv4(fr, 6, serverIP)
+	})
+	if got == nil {
+		t.Fatal("did not capture the UE's answer on the N6 side")
+	}
+
+	ip = got[ethHdrLen : ethHdrLen+20]
+	l4 = got[ethHdrLen+20:]
+
+	if !bytes.Equal(ip[12:16], publicAddr[:]) {
+		t.Fatalf("answer src = %v, want forwarded address %v", ip[12:16], publicAddr)
+	}
+
+	if sp := binary.BigEndian.Uint16(l4[0:2]); sp != publicPort {
+		t.Fatalf("answer source port = %d, want %d", sp, publicPort)
+	}
+}
@@ -70,6 +70,55 @@ type BpfObjects struct {
 	// without a compile error when profiling is absent.
 	ProfilingMap *ebpf.Map
 
+	// DnEgressIp4 and DnEgressIp6 are the per-data-network egress tries
+	// (dn_egress_ip4/ip6 in routing.h). They follow the ProfilingMap
+	// pattern: nil until the bindings are regenerated from sources that
+	// declare them, in which case UpdateDataNetworkEgress reports
+	// ErrDataNetworkEgressUnsupported.
+	DnEgressIp4 *ebpf.Map
+	DnEgressIp6 *ebpf.Map
+
+	// NatPools, NatPoolAddrs, NatSourceOverrides and NatForwards carry the
+	// per-data-network NAT pools and port forwards (nat_ct.h), on the same
+	// terms: nil until the bindings are regenerated.
+	NatPools           *ebpf.Map
+	NatPoolAddrs       *ebpf.Map
+	NatSourceOverrides *ebpf.Map
+	NatForwards        *ebpf.Map
+
+	// SdfFqdnAddrs, FqdnSnoopConfig and FqdnSnoopEvents carry the addresses
+	// learned for FQDN network rules (fqdn.h), on the same terms.
+	SdfFqdnAddrs    *ebpf.Map
+	FqdnSnoopConfig *ebpf.Map
+	FqdnSnoopEvents *ebpf.Map
+
+	// BreakoutEgress holds the local egress of breakout network rules
+	// (breakout_egress in routing.h), on the same terms.
+	BreakoutEgress *ebpf.Map
+
+	// TcpMssClampIp4 and TcpMssClampIp6 hold the per-data-network TCP MSS
+	// limits (mss.h), on the same terms.
+	TcpMssClampIp4 *ebpf.Map
+	TcpMssClampIp6 *ebpf.Map
+
+	// SdfRatings and RatingUsage carry the rating groups of network rules
+	// and the bytes counted against them (rating.h), on the same terms.
+	SdfRatings  *ebpf.Map
+	RatingUsage *ebpf.Map
+
+	// PortalLifted and PortalCt hold the UEs let past their captive portal
+	// and the redirected connections (portal.h), on the same terms.
+	PortalLifted *ebpf.Map
+	PortalCt     *ebpf.Map
+
+	// S1uBearers holds the eNB TEIDs of dedicated EPS bearers
+	// (s1u_bearer.h), on the same terms.
+	S1uBearers *ebpf.Map
+
+	// sdfRateLimit is set at load when the datapath polices rate-limit
+	// rules; see HasSDFRateLimit.
+	sdfRateLimit bool
+
 	FlowAccounting bool
 	Masquerade     bool
 	LocalSwitch    bool
@@ -154,6 +203,8 @@ func (bpfObjects *BpfObjects) Load() error {
 		m.MaxEntries = uint32(runtime.NumCPU())
 	}
 
+	bpfObjects.sdfRateLimit = specHasSDFRate(n3n6Spec)
+
 	if err := bpfObjects.loadAndAssignFromSpec(n3n6Spec, &bpfObjects.N3N6EntrypointObjects, nil); err != nil {
 		logger.UpfLog.Error("failed to load N3/N6 program", zap.Error(err))
 		return err
@@ -167,7 +218,24 @@ func (bpfObjects *BpfObjects) Load() error {
 	// Populate the optional profiling map from the embedded maps struct. The
 	// field only exists in N3N6EntrypointMaps when compiled with
 	// -DENABLE_PROFILING; reflection lets us check without a hard reference.
-	bpfObjects.ProfilingMap = profilingMapFromMaps(bpfObjects.N3N6EntrypointMaps)
+	bpfObjects.ProfilingMap = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "ProfilingMap")
+	bpfObjects.DnEgressIp4 = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "DnEgressIp4")
+	bpfObjects.DnEgressIp6 = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "DnEgressIp6")
+	bpfObjects.NatPools = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "NatPools")
+	bpfObjects.NatPoolAddrs = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "NatPoolAddrs")
+	bpfObjects.NatSourceOverrides = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "NatSourceOverrides")
+	bpfObjects.NatForwards = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "NatForwards")
+	bpfObjects.SdfFqdnAddrs = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "SdfFqdnAddrs")
+	bpfObjects.FqdnSnoopConfig = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "FqdnSnoopConfig")
+	bpfObjects.FqdnSnoopEvents = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "FqdnSnoopEvents")
+	bpfObjects.BreakoutEgress = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "BreakoutEgress")
+	bpfObjects.TcpMssClampIp4 = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "TcpMssClampIp4")
+	bpfObjects.TcpMssClampIp6 = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "TcpMssClampIp6")
+	bpfObjects.SdfRatings = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "SdfRatings")
+	bpfObjects.RatingUsage = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "RatingUsage")
+	bpfObjects.PortalLifted = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "PortalLifted")
+	bpfObjects.PortalCt = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "PortalCt")
+	bpfObjects.S1uBearers = optionalMapFromMaps(bpfObjects.N3N6EntrypointMaps, "S1uBearers")
 
 	return nil
 }
@@ -395,15 +463,16 @@ func CloseAllObjects(closers ...io.Closer) error {
 	return nil
 }
 
-// profilingMapFromMaps extracts the ProfilingMap field from an
-// N3N6EntrypointMaps value using reflection. The field only exists when the
-// BPF code was compiled with -DENABLE_PROFILING; reflection lets the rest of
-// the package refer to BpfObjects.ProfilingMap unconditionally without a
-// compile error when the field is absent in the generated struct.
-func profilingMapFromMaps(maps N3N6EntrypointMaps) *ebpf.Map {
+// optionalMapFromMaps extracts a named map field from an N3N6EntrypointMaps
+// value using reflection. ProfilingMap only exists when the BPF code was
+// compiled with -DENABLE_PROFILING, and newer maps only once the bindings
+// are regenerated; reflection lets the rest of the package refer to the
+// BpfObjects fields unconditionally without a compile error when the field
+// is absent in the generated struct.
+func optionalMapFromMaps(maps N3N6EntrypointMaps, name string) *ebpf.Map {
 	v := reflect.ValueOf(maps)
 
-	f := v.FieldByName("ProfilingMap")
+	f := v.FieldByName(name)
 	if !f.IsValid() || f.IsNil() {
 		return nil
 	}
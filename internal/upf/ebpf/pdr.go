@@ -20,6 +20,7 @@
 package ebpf
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"net/netip"
@@ -31,13 +32,22 @@ import (
 )
 
 const (
-	MaxSdfFilters     = 288 // 2 * MaxPolicies; must match MAX_SDF_FILTERS in C
-	MaxPolicies       = 144 // must match MAX_POLICIES in C
-	MaxRulesPerFilter = 12  // must match MAX_RULES_PER_FILTER in C
-	SdfProtoAny       = 255
-	SdfActionAllow    = 0
-	SdfActionDeny     = 1
-	NoFilterIndex     = 0 // reserved; means "no filtering"
+	MaxSdfFilters      = 288 // 2 * MaxPolicies; must match MAX_SDF_FILTERS in C
+	MaxPolicies        = 144 // must match MAX_POLICIES in C
+	MaxRulesPerFilter  = 12  // must match MAX_RULES_PER_FILTER in C
+	SdfProtoAny        = 255
+	SdfActionAllow     = 0
+	SdfActionDeny      = 1
+	SdfActionRateLimit = 2 // allow, policed to RateKbps
+	SdfActionBreakout  = 3 // allow, out of the BreakoutEgress at Breakout
+	// Unless the UE is in PortalLifted: redirect to the rule's Portal, or
+	// drop.
+	SdfActionPortal     = 4
+	SdfActionPortalDrop = 5
+	// Not a verdict: what the rules after it pass is downlink marked with
+	// the rule's QFI and policed to RateKbps unless it is zero.
+	SdfActionQoSFlow = 6
+	NoFilterIndex    = 0 // reserved; means "no filtering"
 
 	// Flow direction, as the datapath records it in struct flow.
 	FlowDirectionUplink   = 0 // must match FLOW_UPLINK in C
@@ -242,15 +252,46 @@ type QerInfo struct {
 // Every pad byte is explicit: implicit padding is invisible to encoding/binary,
 // which reads the map value back, so unsafe.Sizeof and binary.Size disagree and
 // Lookup fails with "doesn't consume all data".
+//
+// Go has no unions, so the two unions of sdf_rule are stored under their
+// first member: the qfi member shares Breakout and the portal member shares
+// RateKbps. SetQFI and SetPortal write them.
 type SdfRule struct {
 	RemoteIP  [16]byte // in6_addr: ::ffff:x.x.x.x for IPv4, native for IPv6
 	PrefixLen uint8
-	_         [1]byte // alignment before PortLow
+	Rated     uint8 // non-zero: SdfRatings holds the rule's rating group
 	PortLow   uint16
 	PortHigh  uint16
 	Protocol  uint8
 	Action    uint8
-	_         [8]byte // padding to 32 bytes for verifier-friendly array indexing
+	FQDNSet   uint16 // non-zero: match learned addresses instead of RemoteIP
+	// RateShared polices every session of the policy as one bucket instead
+	// of one per session. Only for SdfActionRateLimit, as is RateKbps.
+	RateShared uint8
+	Breakout   uint8  // SdfActionBreakout: index into breakout_egress
+	RateKbps   uint32 // SdfActionRateLimit, SdfActionQoSFlow; sizes the struct to 32 bytes for verifier-friendly array indexing
+}
+
+// SetQFI sets the QFI an SdfActionQoSFlow rule marks its flow with.
+func (r *SdfRule) SetQFI(qfi uint8) { r.Breakout = qfi }
+
+// QFI returns the QFI of an SdfActionQoSFlow rule.
+func (r *SdfRule) QFI() uint8 { return r.Breakout }
+
+// SetPortal sets the IPv4 portal an SdfActionPortal rule redirects to. The
+// datapath compares it against the header as stored.
+func (r *SdfRule) SetPortal(portal netip.Addr) {
+	a4 := portal.As4()
+	r.RateKbps = binary.NativeEndian.Uint32(a4[:])
+}
+
+// Portal returns the portal of an SdfActionPortal rule.
+func (r *SdfRule) Portal() netip.Addr {
+	var a4 [4]byte
+
+	binary.NativeEndian.PutUint32(a4[:], r.RateKbps)
+
+	return netip.AddrFrom4(a4)
 }
 
 // SdfFilterList mirrors struct sdf_filter_list in pdr.h.
@@ -305,6 +346,22 @@ func (bpfObjects *BpfObjects) GetAndResetUrr(seid uint64, id uint32) (uint64, er
 	return total, nil
 }
 
+// PeekUrr returns the (SEID, id) byte counter summed across CPUs, leaving
+// it as it is.
+func (bpfObjects *BpfObjects) PeekUrr(seid uint64, id uint32) (uint64, error) {
+	var perCPU []uint64
+	if err := bpfObjects.UrrMap.Lookup(N3N6EntrypointUrrKey{Seid: seid, UrrId: id}, &perCPU); err != nil {
+		return 0, fmt.Errorf("failed to lookup URR: %w", err)
+	}
+
+	var total uint64
+	for _, v := range perCPU {
+		total += v
+	}
+
+	return total, nil
+}
+
 // AddUrr adds bytes to the (SEID, id) counter. The read-modify-write can drop
 // datapath increments landing between the lookup and the update, the same bound
 // GetAndResetUrr's reset carries; it runs only on the rare report-failure path.
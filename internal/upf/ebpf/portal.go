@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/cilium/ebpf"
+	"github.com/ellanetworks/core/internal/logger"
+)
+
+// ErrCaptivePortalUnsupported is returned when the loaded datapath predates
+// the portal_lifted and portal_ct maps.
+var ErrCaptivePortalUnsupported = errors.New("datapath has no captive portal maps; regenerate the eBPF bindings")
+
+// portalIPv6Bits is how much of an IPv6 UE address portal_lifted keys on:
+// the /64 the UE was given. Must match PORTAL_IPV6_PREFIX_WORDS in C.
+const portalIPv6Bits = 64
+
+// HasCaptivePortal reports whether the loaded datapath carries the captive
+// portal maps.
+func (bpfObjects *BpfObjects) HasCaptivePortal() bool {
+	return bpfObjects.PortalLifted != nil && bpfObjects.PortalCt != nil
+}
+
+// portalLiftedKey is the portal_lifted key of ue: IPv4-mapped for IPv4, the
+// /64 for IPv6.
+func portalLiftedKey(ue netip.Addr) [16]byte {
+	if ue.Is4() {
+		return netip.AddrFrom16(ue.As16()).As16()
+	}
+
+	return netip.PrefixFrom(ue, portalIPv6Bits).Masked().Addr().As16()
+}
+
+// PutPortalLifted lets the UE at ue past the captive portal rules of its
+// policy.
+func (bpfObjects *BpfObjects) PutPortalLifted(ue netip.Addr) error {
+	if !bpfObjects.HasCaptivePortal() {
+		return ErrCaptivePortalUnsupported
+	}
+
+	logger.UpfLog.Debug("Put portal lifted", logger.IPAddress(ue.String()))
+
+	if err := bpfObjects.PortalLifted.Put(portalLiftedKey(ue), uint8(1)); err != nil {
+		return fmt.Errorf("put portal lifted %s: %w", ue, err)
+	}
+
+	return nil
+}
+
+// DeletePortalLifted puts the UE at ue back behind the captive portal rules
+// of its policy. A missing entry is not an error.
+func (bpfObjects *BpfObjects) DeletePortalLifted(ue netip.Addr) error {
+	if !bpfObjects.HasCaptivePortal() {
+		return ErrCaptivePortalUnsupported
+	}
+
+	if err := bpfObjects.PortalLifted.Delete(portalLiftedKey(ue)); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete portal lifted %s: %w", ue, err)
+	}
+
+	return nil
+}
+
+// ListPortalLifted returns every UE address past the captive portal, as
+// portal_lifted keys it.
+func (bpfObjects *BpfObjects) ListPortalLifted() ([]netip.Addr, error) {
+	if !bpfObjects.HasCaptivePortal() {
+		return nil, ErrCaptivePortalUnsupported
+	}
+
+	var (
+		addrs []netip.Addr
+		key   [16]byte
+		val   uint8
+	)
+
+	iter := bpfObjects.PortalLifted.Iterate()
+	for iter.Next(&key, &val) {
+		addrs = append(addrs, netip.AddrFrom16(key).Unmap())
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate portal_lifted: %w", err)
+	}
+
+	return addrs, nil
+}
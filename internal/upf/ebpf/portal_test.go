@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package ebpf
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+)
+
+// requireCaptivePortal skips on a datapath built before the captive portal
+// maps.
+func requireCaptivePortal(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasCaptivePortal() {
+		t.Skip("datapath built without captive portals")
+	}
+}
+
+// portalRules is the uplink list of a policy in redirect mode: DNS to the
+// portal, then everything else dropped.
+func portalRules(portal [4]byte) []SdfRule {
+	redirect := sdfRuleIPv4([4]byte{}, 0, 53, 53, 17, SdfActionPortal)
+	redirect.SetPortal(netip.AddrFrom4(portal))
+
+	return []SdfRule{redirect, sdfRuleIPv4([4]byte{}, 0, 0, 0, SdfProtoAny, SdfActionPortalDrop)}
+}
+
+// TestCaptivePortalRedirect checks the uplink half of a captive portal: a DNS
+// query to any resolver is sent to the portal with valid checksums, other
+// traffic is dropped, and a UE let past the portal reaches the resolver it
+// asked for.
+func TestCaptivePortalRedirect(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid        = 0x504F5231
+		filterIndex = 1
+		sport       = 40053
+	)
+
+	portal := [4]byte{203, 0, 113, 80}
+	resolver := [4]byte{8, 8, 8, 8}
+
+	obj := loadN3N6Program(t)
+	requireCaptivePortal(t, obj)
+	putForwardingUplinkPDRUE(t, obj, teid, filterIndex, netip.AddrFrom4(ueIP), netip.Addr{})
+	putSDFFilter(t, obj, filterIndex, portalRules(portal))
+
+	query := func(dst [4]byte, dport uint16) []byte {
+		return uplinkGPDU(teid, ipv4Packet(ueIP, dst, 17, udpDatagramChecksummed(ueIP, dst, sport, dport, bytesOf(40))))
+	}
+
+	t.Run("DNS goes to the portal", func(t *testing.T) {
+		action, out := runXDPOut(t, obj.UpfEntryFunc, query(resolver, 53))
+		if action == ActionDrop {
+			t.Fatal("DNS query was dropped")
+		}
+
+		ip := out[ethHdrLen : ethHdrLen+20]
+		if !bytes.Equal(ip[16:20], portal[:]) {
+			t.Fatalf("destination = %v, want the portal %v", ip[16:20], portal)
+		}
+
+		if !validIPv4Checksum(ip) || !validIPv4L4Checksum(ueIP, portal, 17, out[ethHdrLen+20:]) {
+			t.Fatal("checksums invalid after the redirect")
+		}
+	})
+
+	t.Run("other traffic is dropped", func(t *testing.T) {
+		if action, _ := runXDPOut(t, obj.UpfEntryFunc, query(resolver, 443)); action != ActionDrop {
+			t.Fatalf("got XDP action %d, want ActionDrop", action)
+		}
+	})
+
+	t.Run("lifted UE passes", func(t *testing.T) {
+		if err := obj.PutPortalLifted(netip.AddrFrom4(ueIP)); err != nil {
+			t.Fatalf("lift portal: %v", err)
+		}
+
+		action, out := runXDPOut(t, obj.UpfEntryFunc, query(resolver, 53))
+		if action == ActionDrop {
+			t.Fatal("lifted UE's DNS query was dropped")
+		}
+
+		if dst := out[ethHdrLen+16 : ethHdrLen+20]; !bytes.Equal(dst, resolver[:]) {
+			t.Fatalf("destination = %v, want the resolver %v", dst, resolver)
+		}
+
+		if action, _ := runXDPOut(t, obj.UpfEntryFunc, query(resolver, 443)); action == ActionDrop {
+			t.Fatal("lifted UE's traffic was dropped")
+		}
+	})
+}
+
+// TestCaptivePortalDNSAnswer checks the downlink half: the portal's answer
+// to a redirected query reaches the UE from the resolver it asked, with
+// valid checksums, while a reply to a port nothing was redirected from keeps
+// its source.
+func TestCaptivePortalDNSAnswer(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		ulTEID      = 0x504F5232
+		dlTEID      = 0x504F5233
+		filterIndex = 1
+		qfi         = 5
+		sport       = 40054
+	)
+
+	portal := [4]byte{203, 0, 113, 80}
+	resolver := [4]byte{8, 8, 8, 8}
+	local := [4]byte{192, 168, 100, 1}
+	remote := [4]byte{192, 168, 100, 9}
+
+	obj := loadN3N6Program(t)
+	requireCaptivePortal(t, obj)
+	putForwardingUplinkPDRUE(t, obj, ulTEID, filterIndex, netip.AddrFrom4(ueIP), netip.Addr{})
+	putDownlinkPDR(t, obj, ueIP, dlTEID, local, remote, qfi)
+	putSDFFilter(t, obj, filterIndex, portalRules(portal))
+
+	query := uplinkGPDU(ulTEID, ipv4Packet(ueIP, resolver, 17, udpDatagramChecksummed(ueIP, resolver, sport, 53, bytesOf(40))))
+	if action, _ := runXDPOut(t, obj.UpfEntryFunc, query); action == ActionDrop {
+		t.Fatal("DNS query was dropped")
+	}
+
+	answer := func(dport uint16) []byte {
+		reply := ipv4Packet(portal, ueIP, 17, udpDatagramChecksummed(portal, ueIP, 53, dport, bytesOf(60)))
+
+		action, out := runXDPOut(t, obj.UpfEntryFunc, ethFrame(0x0800, reply))
+		if action == ActionDrop {
+			t.Fatal("portal answer was dropped")
+		}
+
+		return parseGTPv4Frame(t, out).inner
+	}
+
+	inner := answer(sport)
+
+	if !bytes.Equal(inner[12:16], resolver[:]) {
+		t.Fatalf("answer source = %v, want the resolver %v", inner[12:16], resolver)
+	}
+
+	if !validIPv4Checksum(inner[:20]) || !validIPv4L4Checksum(resolver, ueIP, 17, inner[20:]) {
+		t.Fatal("checksums invalid after restoring the source")
+	}
+
+	if inner := answer(sport + 1); !bytes.Equal(inner[12:16], portal[:]) {
+		t.Fatalf("unrelated reply source = %v, want the portal %v", inner[12:16], portal)
+	}
+}
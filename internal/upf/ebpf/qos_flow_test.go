@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package ebpf
+
+import "testing"
+
+// requireQoSFlows skips on a datapath built before QoS flow rules.
+func requireQoSFlows(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasQoSFlows() {
+		t.Skip("datapath built without QoS flow rules")
+	}
+}
+
+// TestQoSFlowMarksWhatPolicyPasses checks that downlink traffic a QoS flow
+// rule matches is marked with the flow's QFI, that the rest keeps the
+// session's, and that a deny rule after the flow's still drops its traffic.
+func TestQoSFlowMarksWhatPolicyPasses(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid        = 0x514F5331
+		filterIndex = 1
+		defaultQFI  = 5
+		flowQFI     = 2
+	)
+
+	server := [4]byte{198, 51, 100, 80}
+	other := [4]byte{198, 51, 100, 81}
+	local := [4]byte{192, 168, 100, 1}
+	remote := [4]byte{192, 168, 100, 9}
+
+	obj := loadProgram(t, 1, 0)
+	requireQoSFlows(t, obj)
+	putDownlinkPDRFiltered(t, obj, ueIP, teid, local, remote, defaultQFI, filterIndex)
+
+	flow := sdfRuleIPv4(server, 32, 0, 0, SdfProtoAny, SdfActionQoSFlow)
+	flow.SetQFI(flowQFI)
+	putSDFFilter(t, obj, filterIndex, []SdfRule{flow, sdfRuleIPv4(server, 32, 0, 0, 6, SdfActionDeny)})
+
+	udp := func(src [4]byte) []byte {
+		return ethFrame(0x0800, ipv4Packet(src, ueIP, 17, udpDatagramChecksummed(src, ueIP, 5000, 40000, bytesOf(40))))
+	}
+
+	t.Run("flow traffic", func(t *testing.T) {
+		action, out := runXDPOut(t, obj.UpfEntryFunc, udp(server))
+		if action == ActionDrop {
+			t.Fatal("flow traffic was dropped")
+		}
+
+		if qfi := parseGTPv4Frame(t, out).qfi; qfi != flowQFI {
+			t.Fatalf("QFI = %d, want the flow's %d", qfi, flowQFI)
+		}
+	})
+
+	t.Run("other traffic", func(t *testing.T) {
+		action, out := runXDPOut(t, obj.UpfEntryFunc, udp(other))
+		if action == ActionDrop {
+			t.Fatal("other traffic was dropped")
+		}
+
+		if qfi := parseGTPv4Frame(t, out).qfi; qfi != defaultQFI {
+			t.Fatalf("QFI = %d, want the session's %d", qfi, defaultQFI)
+		}
+	})
+
+	t.Run("denied by the policy", func(t *testing.T) {
+		syn := tcpSYNWithOptions(server, ueIP, 443, 40000, 0x12, nil)
+
+		if action, _ := runXDPOut(t, obj.UpfEntryFunc, ethFrame(0x0800, ipv4Packet(server, ueIP, 6, syn))); action != ActionDrop {
+			t.Fatalf("got XDP action %d, want ActionDrop", action)
+		}
+	})
+}
+
+// TestQoSFlowPolicesAboveRate checks that uplink traffic a QoS flow rule
+// matches is held to the flow's rate as qer_rate_limit, and that traffic
+// outside the flow is not.
+func TestQoSFlowPolicesAboveRate(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid        = 0x514F5332
+		filterIndex = 1
+		// tx_time = 1250*8 bits at 100 kbit/s = 100 ms: the burst below
+		// is sent well within it.
+		innerLen = 1250
+		rateKbps = 100
+		burst    = 8
+	)
+
+	server := [4]byte{8, 8, 8, 8}
+	other := [4]byte{9, 9, 9, 9}
+
+	obj := loadN3N6Program(t)
+	requireQoSFlows(t, obj)
+	putForwardingUplinkPDR(t, obj, teid, filterIndex)
+
+	flow := sdfRuleIPv4(server, 32, 0, 0, SdfProtoAny, SdfActionQoSFlow)
+	flow.SetQFI(2)
+	flow.RateKbps = rateKbps
+	putSDFFilter(t, obj, filterIndex, []SdfRule{flow})
+
+	run := func(dst [4]byte) uint32 {
+		action, _ := runXDPOut(t, obj.UpfEntryFunc, uplinkGPDU(teid, innerIPv4UDPSized(dst, innerLen)))
+
+		return action
+	}
+
+	if action := run(server); action == ActionDrop {
+		t.Fatal("first packet under the rate was dropped")
+	}
+
+	for i := range burst {
+		if action := run(server); action != ActionDrop {
+			t.Fatalf("packet %d above the rate: got XDP action %d, want ActionDrop", i+1, action)
+		}
+	}
+
+	if got := DropCount(obj, Uplink, "qer_rate_limit"); got != burst {
+		t.Errorf("qer_rate_limit drops = %d, want %d", got, burst)
+	}
+
+	for i := range burst {
+		if action := run(other); action == ActionDrop {
+			t.Fatalf("packet %d outside the flow was dropped", i+1)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"errors"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// ErrSDFRateLimitUnsupported is returned when the loaded datapath predates
+// rate-limit network rules.
+var ErrSDFRateLimitUnsupported = errors.New("datapath does not police rate-limit rules; regenerate the eBPF bindings")
+
+// HasSDFRateLimit reports whether the loaded datapath polices rate-limit
+// rules. The action keeps its buckets in qer_windows and has no map to
+// probe, so this reads the rate_kbps field of struct sdf_rule: older objects
+// have padding there and pass the rule's traffic as allowed.
+func (bpfObjects *BpfObjects) HasSDFRateLimit() bool {
+	return bpfObjects.sdfRateLimit
+}
+
+// specHasSDFRate reports whether spec's struct sdf_rule carries rate_kbps.
+func specHasSDFRate(spec *ebpf.CollectionSpec) bool {
+	if spec.Types == nil {
+		return false
+	}
+
+	var rule *btf.Struct
+	if err := spec.Types.TypeByName("sdf_rule", &rule); err != nil {
+		return false
+	}
+
+	for _, m := range rule.Members {
+		if m.Name == "rate_kbps" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasQoSFlows reports whether the loaded datapath marks and polices the
+// traffic of QoS flow rules. The action reads its rate from the rate_kbps
+// field that came with rate-limit rules and leaves no mark of its own in
+// BTF, so it shares their probe.
+func (bpfObjects *BpfObjects) HasQoSFlows() bool {
+	return bpfObjects.sdfRateLimit
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package ebpf
+
+import "testing"
+
+// requireSDFRateLimit skips on a datapath built before rate-limit rules.
+func requireSDFRateLimit(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasSDFRateLimit() {
+		t.Skip("datapath built without rate-limit rules")
+	}
+}
+
+// TestSDFRateLimitDropsAboveRate checks that a rate-limit rule admits a
+// packet, drops the ones that follow it within its transmit time as
+// qer_rate_limit, and leaves traffic it does not match alone.
+func TestSDFRateLimitDropsAboveRate(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid        = 0x52415401
+		filterIndex = 1
+		// tx_time = 1250*8 bits at 100 kbit/s = 100 ms: the burst below
+		// is sent well within it.
+		innerLen = 1250
+		rateKbps = 100
+		burst    = 8
+	)
+
+	limited := [4]byte{8, 8, 8, 8}
+	other := [4]byte{9, 9, 9, 9}
+
+	obj := loadN3N6Program(t)
+	requireSDFRateLimit(t, obj)
+	putForwardingUplinkPDR(t, obj, teid, filterIndex)
+
+	rule := sdfRuleIPv4(limited, 32, 0, 0, SdfProtoAny, SdfActionRateLimit)
+	rule.RateKbps = rateKbps
+	putSDFFilter(t, obj, filterIndex, []SdfRule{rule})
+
+	run := func(dst [4]byte) uint32 {
+		action, _ := runXDPOut(t, obj.UpfEntryFunc, uplinkGPDU(teid, innerIPv4UDPSized(dst, innerLen)))
+
+		return action
+	}
+
+	if action := run(limited); action == ActionDrop {
+		t.Fatal("first packet under the rate was dropped")
+	}
+
+	for i := range burst {
+		if action := run(limited); action != ActionDrop {
+			t.Fatalf("packet %d above the rate: got XDP action %d, want ActionDrop", i+1, action)
+		}
+	}
+
+	if got := DropCount(obj, Uplink, "qer_rate_limit"); got != burst {
+		t.Errorf("qer_rate_limit drops = %d, want %d", got, burst)
+	}
+
+	for i := range burst {
+		if action := run(other); action == ActionDrop {
+			t.Fatalf("unmatched packet %d was dropped", i+1)
+		}
+	}
+}
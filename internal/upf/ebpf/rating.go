@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+)
+
+// ErrRatingGroupsUnsupported is returned when the loaded datapath predates
+// the sdf_ratings and rating_usage maps.
+var ErrRatingGroupsUnsupported = errors.New("datapath has no rating group maps; regenerate the eBPF bindings")
+
+// SdfRating mirrors struct sdf_rating in rating.h.
+type SdfRating struct {
+	RatingGroup uint32
+	ZeroRated   uint8
+	_           [3]byte
+}
+
+// RatingUsageKey mirrors struct rating_usage_key in rating.h.
+type RatingUsageKey struct {
+	Seid        uint64
+	UrrID       uint32
+	RatingGroup uint32
+}
+
+// RatingUsage is the bytes one session's URR counted against a rating
+// group since the last drain.
+type RatingUsage struct {
+	RatingUsageKey
+	Bytes uint64
+}
+
+// HasRatingGroups reports whether the loaded datapath carries the rating
+// group maps.
+func (bpfObjects *BpfObjects) HasRatingGroups() bool {
+	return bpfObjects.SdfRatings != nil && bpfObjects.RatingUsage != nil
+}
+
+func sdfRatingSlot(filterIndex uint32, ruleIndex int) uint32 {
+	return filterIndex*MaxRulesPerFilter + uint32(ruleIndex)
+}
+
+// PutSdfRating sets the rating of the rule at ruleIndex in the filter list
+// at filterIndex. A zero rating leaves the rule unrated.
+func (bpfObjects *BpfObjects) PutSdfRating(filterIndex uint32, ruleIndex int, rating SdfRating) error {
+	if !bpfObjects.HasRatingGroups() {
+		return ErrRatingGroupsUnsupported
+	}
+
+	if ruleIndex < 0 || ruleIndex >= MaxRulesPerFilter {
+		return fmt.Errorf("rule index %d out of range", ruleIndex)
+	}
+
+	if err := bpfObjects.SdfRatings.Put(sdfRatingSlot(filterIndex, ruleIndex), unsafe.Pointer(&rating)); err != nil {
+		return fmt.Errorf("put sdf rating %d/%d: %w", filterIndex, ruleIndex, err)
+	}
+
+	return nil
+}
+
+// DrainRatingUsage returns and removes the rating group counters of every
+// session keep accepts. A nil keep drains them all.
+func (bpfObjects *BpfObjects) DrainRatingUsage(keep func(seid uint64) bool) ([]RatingUsage, error) {
+	if !bpfObjects.HasRatingGroups() {
+		return nil, ErrRatingGroupsUnsupported
+	}
+
+	var (
+		key    RatingUsageKey
+		perCPU []uint64
+		keys   []RatingUsageKey
+	)
+
+	iter := bpfObjects.RatingUsage.Iterate()
+	for iter.Next(&key, &perCPU) {
+		if keep == nil || keep(key.Seid) {
+			keys = append(keys, key)
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rating_usage: %w", err)
+	}
+
+	// Read again right before the delete: bytes the datapath counts in
+	// between are lost, the same bound GetAndResetUrr carries.
+	out := make([]RatingUsage, 0, len(keys))
+
+	for _, k := range keys {
+		if err := bpfObjects.RatingUsage.Lookup(k, &perCPU); err != nil {
+			continue
+		}
+
+		if err := bpfObjects.RatingUsage.Delete(k); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return out, fmt.Errorf("delete rating usage: %w", err)
+		}
+
+		var total uint64
+		for _, v := range perCPU {
+			total += v
+		}
+
+		if total > 0 {
+			out = append(out, RatingUsage{RatingUsageKey: k, Bytes: total})
+		}
+	}
+
+	return out, nil
+}
+
+// AddRatingUsage puts bytes back on a rating group counter, for a report
+// that could not be delivered.
+func (bpfObjects *BpfObjects) AddRatingUsage(usage RatingUsage) error {
+	if !bpfObjects.HasRatingGroups() {
+		return ErrRatingGroupsUnsupported
+	}
+
+	perCPU := make([]uint64, runtime.NumCPU())
+
+	var current []uint64
+	if err := bpfObjects.RatingUsage.Lookup(usage.RatingUsageKey, &current); err == nil {
+		copy(perCPU, current)
+	}
+
+	perCPU[0] += usage.Bytes
+
+	if err := bpfObjects.RatingUsage.Update(usage.RatingUsageKey, perCPU, ebpf.UpdateAny); err != nil {
+		return fmt.Errorf("restore rating usage: %w", err)
+	}
+
+	return nil
+}
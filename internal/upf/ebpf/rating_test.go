@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package ebpf
+
+import "testing"
+
+// requireRatingGroups skips on a datapath built before the rating group
+// maps.
+func requireRatingGroups(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasRatingGroups() {
+		t.Skip("datapath built without rating groups")
+	}
+}
+
+// TestRatingBytesZeroRatedSkipsURR checks update_rating_bytes through the
+// uplink: traffic passed by a rated rule is counted against its rating group
+// and the session's URR, while a zero-rated rule's traffic is counted against
+// its rating group only.
+func TestRatingBytesZeroRatedSkipsURR(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		teid        = 0x52415431
+		seid        = 0x5241
+		urrID       = 5
+		filterIndex = 1
+		ratedGroup  = 100
+		zeroGroup   = 200
+	)
+
+	rated := [4]byte{8, 8, 8, 8}
+	zero := [4]byte{9, 9, 9, 9}
+
+	obj := loadN3N6Program(t)
+	requireRatingGroups(t, obj)
+
+	if err := obj.NewUrr(seid, urrID); err != nil {
+		t.Fatalf("create URR: %v", err)
+	}
+
+	pdr := PdrInfo{
+		SEID:           seid,
+		UrrID:          urrID,
+		IMSI:           "001010000000001",
+		Far:            FarInfo{Action: 0x02 /* FAR_FORW */},
+		Qer:            QerInfo{GateStatusUL: 0 /* GATE_STATUS_OPEN */},
+		FilterMapIndex: filterIndex,
+		UEIPv4:         canonicalUEv4,
+		UEIPv6Prefix:   canonicalUEv6Prefix,
+	}
+	if err := obj.PutPdrUplink(teid, pdr); err != nil {
+		t.Fatalf("install uplink PDR: %v", err)
+	}
+
+	ratedRule := sdfRuleIPv4(rated, 32, 0, 0, SdfProtoAny, SdfActionAllow)
+	ratedRule.Rated = 1
+	zeroRule := sdfRuleIPv4(zero, 32, 0, 0, SdfProtoAny, SdfActionAllow)
+	zeroRule.Rated = 1
+
+	// Ratings first, as the engine writes them, so no rule is marked rated
+	// ahead of its rating.
+	if err := obj.PutSdfRating(filterIndex, 0, SdfRating{RatingGroup: ratedGroup}); err != nil {
+		t.Fatalf("put rating: %v", err)
+	}
+
+	if err := obj.PutSdfRating(filterIndex, 1, SdfRating{RatingGroup: zeroGroup, ZeroRated: 1}); err != nil {
+		t.Fatalf("put rating: %v", err)
+	}
+
+	putSDFFilter(t, obj, filterIndex, []SdfRule{ratedRule, zeroRule})
+
+	send := func(t *testing.T, dst [4]byte) uint64 {
+		t.Helper()
+
+		inner := innerIPv4UDP(dst, 53)
+
+		if action := runXDP(t, obj.UpfEntryFunc, uplinkGPDU(teid, inner)); action == ActionDrop || action == ActionAborted {
+			t.Skipf("this environment did not forward the frame (XDP action %d)", action)
+		}
+
+		return uint64(ethHdrLen + len(inner))
+	}
+
+	drain := func(t *testing.T) (uint64, map[uint32]uint64) {
+		t.Helper()
+
+		volume, err := obj.GetAndResetUrr(seid, urrID)
+		if err != nil {
+			t.Fatalf("read URR: %v", err)
+		}
+
+		usage, err := obj.DrainRatingUsage(nil)
+		if err != nil {
+			t.Fatalf("drain rating usage: %v", err)
+		}
+
+		groups := make(map[uint32]uint64)
+
+		for _, u := range usage {
+			if u.Seid != seid || u.UrrID != urrID {
+				t.Errorf("rating usage under SEID %#x URR %d, want %#x/%d", u.Seid, u.UrrID, seid, urrID)
+			}
+
+			groups[u.RatingGroup] += u.Bytes
+		}
+
+		return volume, groups
+	}
+
+	t.Run("rated", func(t *testing.T) {
+		want := send(t, rated)
+		volume, groups := drain(t)
+
+		if volume != want {
+			t.Errorf("URR charged %d bytes, want %d", volume, want)
+		}
+
+		if groups[ratedGroup] != want || len(groups) != 1 {
+			t.Errorf("rating usage = %v, want %d bytes under %d", groups, want, ratedGroup)
+		}
+	})
+
+	t.Run("zero-rated", func(t *testing.T) {
+		want := send(t, zero)
+		volume, groups := drain(t)
+
+		if volume != 0 {
+			t.Errorf("URR charged %d bytes for zero-rated traffic", volume)
+		}
+
+		if groups[zeroGroup] != want || len(groups) != 1 {
+			t.Errorf("rating usage = %v, want %d bytes under %d", groups, want, zeroGroup)
+		}
+	})
+}
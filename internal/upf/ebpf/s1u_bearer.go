@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ErrDedicatedBearersUnsupported is returned when the loaded datapath
+// predates the s1u_bearers map.
+var ErrDedicatedBearersUnsupported = errors.New("datapath has no dedicated EPS bearer map; regenerate the eBPF bindings")
+
+// s1uBearerKey mirrors struct s1u_bearer_key in s1u_bearer.h.
+type s1uBearerKey struct {
+	SEID uint64
+	QFI  uint8
+	_    [7]uint8
+}
+
+// HasDedicatedBearers reports whether the loaded datapath carries the
+// s1u_bearers map.
+func (bpfObjects *BpfObjects) HasDedicatedBearers() bool {
+	return bpfObjects.S1uBearers != nil
+}
+
+// PutS1UBearer sends the S1-U downlink of session seid marked with qfi to
+// the eNB under teid rather than the default bearer's TEID.
+func (bpfObjects *BpfObjects) PutS1UBearer(seid uint64, qfi uint8, teid uint32) error {
+	if !bpfObjects.HasDedicatedBearers() {
+		return ErrDedicatedBearersUnsupported
+	}
+
+	logger.UpfLog.Debug("Put S1-U bearer", zap.Uint64("seid", seid), zap.Uint8("qfi", qfi), zap.Uint32("teid", teid))
+
+	if err := bpfObjects.S1uBearers.Put(s1uBearerKey{SEID: seid, QFI: qfi}, teid); err != nil {
+		return fmt.Errorf("put S1-U bearer %d/%d: %w", seid, qfi, err)
+	}
+
+	return nil
+}
+
+// DeleteS1UBearer returns the downlink of session seid marked with qfi to
+// the default bearer. A missing entry is not an error.
+func (bpfObjects *BpfObjects) DeleteS1UBearer(seid uint64, qfi uint8) error {
+	if !bpfObjects.HasDedicatedBearers() {
+		return ErrDedicatedBearersUnsupported
+	}
+
+	if err := bpfObjects.S1uBearers.Delete(s1uBearerKey{SEID: seid, QFI: qfi}); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return fmt.Errorf("delete S1-U bearer %d/%d: %w", seid, qfi, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package ebpf
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+)
+
+// requireDedicatedBearers skips on a datapath built before dedicated EPS
+// bearers.
+func requireDedicatedBearers(t *testing.T, obj *BpfObjects) {
+	t.Helper()
+
+	if !obj.HasDedicatedBearers() {
+		t.Skip("datapath built without dedicated EPS bearers")
+	}
+}
+
+// TestS1UBearerSelectsTheDedicatedTEID checks that S1-U downlink traffic a
+// QoS flow rule marks leaves with the TEID of the session's dedicated bearer
+// for the flow's QFI, and that the rest keeps the default bearer's.
+func TestS1UBearerSelectsTheDedicatedTEID(t *testing.T) {
+	requireProgTestRun(t)
+
+	const (
+		seid          = 0x5331
+		defaultTEID   = 0x53315501
+		dedicatedTEID = 0x53315502
+		filterIndex   = 1
+		flowQFI       = 2
+	)
+
+	server := [4]byte{198, 51, 100, 80}
+	other := [4]byte{198, 51, 100, 81}
+	local := [4]byte{192, 168, 100, 1}
+	remote := [4]byte{192, 168, 100, 9}
+
+	obj := loadProgram(t, 1, 0)
+	requireQoSFlows(t, obj)
+	requireDedicatedBearers(t, obj)
+
+	pdr := ipv4OuterDownlinkPDR(defaultTEID, local, remote, 0)
+	pdr.SEID = seid
+	pdr.FilterMapIndex = filterIndex
+	pdr.Far.OuterHeaderCreation |= 0x10 // OHC_NO_PSC
+
+	if err := obj.PutPdrDownlink(netip.AddrFrom4(ueIP), pdr); err != nil {
+		t.Fatalf("install S1-U downlink PDR: %v", err)
+	}
+
+	flow := sdfRuleIPv4(server, 32, 0, 0, SdfProtoAny, SdfActionQoSFlow)
+	flow.SetQFI(flowQFI)
+	putSDFFilter(t, obj, filterIndex, []SdfRule{flow})
+
+	teidOf := func(src [4]byte) uint32 {
+		t.Helper()
+
+		frame := ethFrame(0x0800, ipv4Packet(src, ueIP, 17, udpDatagramChecksummed(src, ueIP, 5000, 40000, bytesOf(40))))
+
+		action, out := runXDPOut(t, obj.UpfEntryFunc, frame)
+		if action == ActionDrop || action == ActionAborted {
+			t.Fatalf("downlink traffic got XDP action %d", action)
+		}
+
+		// The GTP-U TEID follows eth + IPv4 (20) + UDP (8) + flags, type, length.
+		return binary.BigEndian.Uint32(out[ethHdrLen+32 : ethHdrLen+36])
+	}
+
+	if got := teidOf(server); got != defaultTEID {
+		t.Fatalf("flow traffic before the bearer TEID = %#x, want the default bearer's %#x", got, uint32(defaultTEID))
+	}
+
+	if err := obj.PutS1UBearer(seid, flowQFI, dedicatedTEID); err != nil {
+		t.Fatalf("PutS1UBearer: %v", err)
+	}
+
+	if got := teidOf(server); got != dedicatedTEID {
+		t.Errorf("flow traffic TEID = %#x, want the dedicated bearer's %#x", got, uint32(dedicatedTEID))
+	}
+
+	if got := teidOf(other); got != defaultTEID {
+		t.Errorf("other traffic TEID = %#x, want the default bearer's %#x", got, uint32(defaultTEID))
+	}
+
+	if err := obj.DeleteS1UBearer(seid, flowQFI); err != nil {
+		t.Fatalf("DeleteS1UBearer: %v", err)
+	}
+
+	if got := teidOf(server); got != defaultTEID {
+		t.Errorf("flow traffic after the release TEID = %#x, want the default bearer's %#x", got, uint32(defaultTEID))
+	}
+}
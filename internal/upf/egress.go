@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+
+	"github.com/cilium/ebpf/link"
+	"github.com/ellanetworks/core/internal/config"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+	"github.com/vishvananda/netlink"
+	"go.uber.org/zap"
+)
+
+// DataNetworkEgress is one data network's egress override as the datapath
+// needs it. An empty Interface means the N6 attachment interface.
+type DataNetworkEgress struct {
+	Name      string
+	Pools     []netip.Prefix
+	Interface string
+	VlanID    int
+	Table     int
+}
+
+// resolvedEgress is a DataNetworkEgress with its netdevs looked up.
+type resolvedEgress struct {
+	attach datapathIface
+	entry  ebpf.DataNetworkEgress
+}
+
+func (u *UPF) resolveEgress(e DataNetworkEgress) (resolvedEgress, error) {
+	name := e.Interface
+	if name == "" {
+		name = u.n6Iface.name
+	}
+
+	master, err := netlink.LinkByName(name)
+	if err != nil {
+		return resolvedEgress{}, fmt.Errorf("lookup %q: %w", name, err)
+	}
+
+	r := resolvedEgress{
+		attach: datapathIface{index: master.Attrs().Index, name: name},
+		entry: ebpf.DataNetworkEgress{
+			Table:      uint32(e.Table),
+			FibIfindex: uint32(master.Attrs().Index),
+			Ifindex:    uint32(master.Attrs().Index),
+		},
+	}
+
+	if e.VlanID == 0 {
+		return r, nil
+	}
+
+	// In XDP the datapath writes the N6 tag in-band during decap, so a
+	// second vid cannot be pushed on redirect.
+	if u.attachedMode != config.DatapathTCX {
+		return resolvedEgress{}, fmt.Errorf("VLAN egress needs the tcx attach mode, datapath is %s", u.attachedMode)
+	}
+
+	links, err := netlink.LinkList()
+	if err != nil {
+		return resolvedEgress{}, fmt.Errorf("list links: %w", err)
+	}
+
+	for _, l := range links {
+		vlan, ok := l.(*netlink.Vlan)
+		if !ok || vlan.Attrs().ParentIndex != master.Attrs().Index || vlan.VlanId != e.VlanID {
+			continue
+		}
+
+		r.entry.FibIfindex = uint32(vlan.Attrs().Index)
+		r.entry.Vlan = uint32(e.VlanID)
+
+		return r, nil
+	}
+
+	return resolvedEgress{}, fmt.Errorf("no VLAN %d sub-interface on %q", e.VlanID, name)
+}
+
+// UpdateDataNetworkEgress makes the datapath's per-data-network egress
+// match egress: the entry program is attached to every extra egress
+// interface, so return traffic is processed, and each data network's
+// pools are steered to its interface and table. A data network whose
+// interface cannot be resolved keeps using N6 and is reported in the
+// returned error after the rest are applied.
+func (u *UPF) UpdateDataNetworkEgress(egress []DataNetworkEgress) error {
+	objs := u.se.BpfObjects
+	if !objs.HasDataNetworkEgress() {
+		return ebpf.ErrDataNetworkEgressUnsupported
+	}
+
+	u.egressMu.Lock()
+	defer u.egressMu.Unlock()
+
+	var errs []error
+
+	desiredPools := make(map[netip.Prefix]ebpf.DataNetworkEgress)
+	desiredLinks := make(map[int]datapathIface)
+
+	for _, e := range egress {
+		r, err := u.resolveEgress(e)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("data network %s: %w", e.Name, err))
+			continue
+		}
+
+		if r.attach.index != u.n3Iface.index && r.attach.index != u.n6Iface.index {
+			desiredLinks[r.attach.index] = r.attach
+		}
+
+		for _, pool := range e.Pools {
+			desiredPools[pool.Masked()] = r.entry
+		}
+	}
+
+	u.dnEgressIfaces = desiredLinks
+	errs = append(errs, u.syncEgressLinksLocked()...)
+
+	for pool, entry := range desiredPools {
+		if err := objs.PutDataNetworkEgress(pool, entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	current, err := objs.ListDataNetworkEgressPools()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, pool := range current {
+		if _, ok := desiredPools[pool.Masked()]; ok {
+			continue
+		}
+
+		if err := objs.DeleteDataNetworkEgress(pool); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// syncEgressLinksLocked attaches the entry program to every interface a data
+// network egress or a breakout rule leaves through, so return traffic is
+// processed, and detaches it from the ones neither uses any more. The caller
+// holds egressMu.
+func (u *UPF) syncEgressLinksLocked() []error {
+	desired := make(map[int]datapathIface, len(u.dnEgressIfaces)+len(u.breakoutIfaces))
+	maps.Copy(desired, u.dnEgressIfaces)
+	maps.Copy(desired, u.breakoutIfaces)
+
+	var errs []error
+
+	for index, iface := range desired {
+		if _, ok := u.egressLinks[index]; ok {
+			continue
+		}
+
+		l, err := u.attachEgress(iface)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("attach %s: %w", iface.name, err))
+			continue
+		}
+
+		u.egressLinks[index] = l
+
+		logger.UpfLog.Info("datapath attached to egress interface", zap.String("iface", iface.name))
+	}
+
+	for index, l := range u.egressLinks {
+		if _, ok := desired[index]; ok {
+			continue
+		}
+
+		if err := l.Close(); err != nil {
+			logger.UpfLog.Warn("failed to detach eBPF from egress interface", zap.Int("ifindex", index), zap.Error(err))
+		}
+
+		delete(u.egressLinks, index)
+	}
+
+	return errs
+}
+
+func (u *UPF) attachEgress(iface datapathIface) (link.Link, error) {
+	switch u.attachedMode {
+	case config.DatapathTCX:
+		return attachTCX(u.se.BpfObjects.UpfEntryFunc, iface.index, iface.name)
+	case config.DatapathXDPGeneric:
+		return attachXDP(u.se.BpfObjects.UpfEntryFunc, iface.index, link.XDPGenericMode)
+	default:
+		return attachXDP(u.se.BpfObjects.UpfEntryFunc, iface.index, link.XDPDriverMode)
+	}
+}
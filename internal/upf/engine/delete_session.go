@@ -68,6 +68,10 @@ func (conn *SessionEngine) DeleteSession(ctx context.Context, req *models.Delete
 		}
 	}
 
+	if err := deleteS1UBearers(session, bpfObjects); err != nil {
+		pdrErr = errors.Join(pdrErr, err)
+	}
+
 	if bpfObjects != nil {
 		bpfObjects.ClearNotifiedForSEID(req.SEID)
 	}
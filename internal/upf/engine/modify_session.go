@@ -70,6 +70,14 @@ func (conn *SessionEngine) ModifySession(ctx context.Context, req *models.Modify
 		return err
 	}
 
+	snapBearers := session.S1UBearers()
+
+	if err := applyS1UBearers(ctx, session, bpfObjects, req.S1UBearers); err != nil {
+		return fail(fmt.Errorf("couldn't apply dedicated EPS bearers: %w", err))
+	}
+
+	txn.onRollback(func() error { return applyS1UBearers(ctx, session, bpfObjects, snapBearers) })
+
 	touched := make(map[uint32]struct{}, len(req.UpdatePDRs))
 
 	for _, far := range req.UpdateFARs {
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+//go:build linux
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/ellanetworks/core/internal/models"
+	upfebpf "github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+func TestModifySessionS1UBearers(t *testing.T) {
+	if os.Geteuid() != 0 {
+		const msg = "loading eBPF maps requires root/CAP_BPF"
+		if os.Getenv("EBPF_REQUIRE_PRIVILEGED") != "" {
+			t.Fatal(msg)
+		}
+
+		t.Skip(msg + "; skipping")
+	}
+
+	if err := rlimit.RemoveMemlock(); err != nil {
+		t.Fatalf("cannot remove memlock rlimit: %v", err)
+	}
+
+	obj := upfebpf.NewBpfObjects(false, false, false, 1, 0, 0, 0)
+	if err := obj.Load(); err != nil {
+		t.Fatalf("load eBPF objects: %v", err)
+	}
+
+	t.Cleanup(func() { _ = obj.Close() })
+
+	rm, err := NewFteIDResourceManager(1024)
+	if err != nil {
+		t.Fatalf("new fteid resource manager: %v", err)
+	}
+
+	conn, err := NewSessionEngine("1.2.3.4", "nodeId", "2.3.4.5", "", "2.3.4.5", "", obj, rm)
+	if err != nil {
+		t.Fatalf("new session engine: %v", err)
+	}
+
+	ctx := context.Background()
+
+	const seid = uint64(41)
+
+	pdrs := []models.PDR{
+		{PDRID: 1, FARID: 1, URRID: 1, PDI: models.PDI{LocalFTEID: &models.FTEID{}}},
+		{PDRID: 2, FARID: 1, URRID: 1, PDI: models.PDI{UEIPAddress: netip.MustParseAddr("10.0.0.12")}},
+	}
+
+	if _, err := conn.EstablishSession(ctx, &models.EstablishRequest{
+		SEID: seid,
+		IMSI: "001010000000001",
+		URRs: []models.URR{{URRID: 1}},
+		FARs: []models.FAR{{FARID: 1, ApplyAction: models.ApplyAction{Forw: true}}},
+		PDRs: pdrs,
+	}); err != nil {
+		t.Fatalf("establish: %v", err)
+	}
+
+	bearers := []models.S1UBearer{{QFI: models.DedicatedQFI, TEID: 0x66}}
+
+	err = conn.ModifySession(ctx, &models.ModifyRequest{SEID: seid, UpdatePDRs: pdrs, S1UBearers: bearers})
+
+	if !obj.HasDedicatedBearers() {
+		if !errors.Is(err, upfebpf.ErrDedicatedBearersUnsupported) {
+			t.Fatalf("modify onto a dedicated bearer without datapath support = %v, want ErrDedicatedBearersUnsupported", err)
+		}
+
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("modify onto a dedicated bearer: %v", err)
+	}
+
+	if got := conn.GetSession(seid).S1UBearers(); !slices.Equal(got, bearers) {
+		t.Fatalf("session bearers = %+v, want %+v", got, bearers)
+	}
+
+	if err := conn.ModifySession(ctx, &models.ModifyRequest{SEID: seid, UpdatePDRs: pdrs}); err != nil {
+		t.Fatalf("modify off the dedicated bearer: %v", err)
+	}
+
+	if got := conn.GetSession(seid).S1UBearers(); len(got) != 0 {
+		t.Errorf("session bearers after the release = %+v, want none", got)
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+	"go.uber.org/zap"
+)
+
+// applyS1UBearers brings the dedicated EPS bearers of session to want,
+// adding and removing the datapath entries that differ from what it holds.
+// A datapath without the s1u_bearers map refuses any bearer rather than
+// send its traffic down the default bearer. Caller holds session.opMu.
+func applyS1UBearers(ctx context.Context, session *Session, bpfObjects *ebpf.BpfObjects, want []models.S1UBearer) error {
+	have := session.S1UBearers()
+	if len(have) == 0 && len(want) == 0 {
+		return nil
+	}
+
+	if !bpfObjects.HasDedicatedBearers() {
+		if len(want) == 0 {
+			session.SetS1UBearers(nil)
+			return nil
+		}
+
+		return ebpf.ErrDedicatedBearersUnsupported
+	}
+
+	for _, b := range want {
+		if slices.Contains(have, b) {
+			continue
+		}
+
+		if err := bpfObjects.PutS1UBearer(session.SEID, b.QFI, b.TEID); err != nil {
+			return err
+		}
+
+		logger.WithTrace(ctx, logger.UpfLog).Info("Added dedicated EPS bearer",
+			logger.SEID(session.SEID), zap.Uint8("qfi", b.QFI), logger.TEID(b.TEID))
+	}
+
+	for _, b := range have {
+		if slices.ContainsFunc(want, func(w models.S1UBearer) bool { return w.QFI == b.QFI }) {
+			continue
+		}
+
+		if err := bpfObjects.DeleteS1UBearer(session.SEID, b.QFI); err != nil {
+			return err
+		}
+
+		logger.WithTrace(ctx, logger.UpfLog).Info("Removed dedicated EPS bearer",
+			logger.SEID(session.SEID), zap.Uint8("qfi", b.QFI))
+	}
+
+	session.SetS1UBearers(slices.Clone(want))
+
+	return nil
+}
+
+// deleteS1UBearers removes every dedicated EPS bearer of session from the
+// datapath.
+func deleteS1UBearers(session *Session, bpfObjects *ebpf.BpfObjects) error {
+	bearers := session.S1UBearers()
+	if len(bearers) == 0 || !bpfObjects.HasDedicatedBearers() {
+		return nil
+	}
+
+	var errs error
+
+	for _, b := range bearers {
+		if err := bpfObjects.DeleteS1UBearer(session.SEID, b.QFI); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("delete dedicated EPS bearer: %w", err))
+		}
+	}
+
+	session.SetS1UBearers(nil)
+
+	return errs
+}
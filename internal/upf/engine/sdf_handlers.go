@@ -7,10 +7,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/netip"
 
+	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/internal/upf/ebpf"
+	"go.uber.org/zap"
 )
 
 // updateFiltersRule converts a FilterRule to an internal Action for BPF operations
@@ -23,8 +26,35 @@ func updateFiltersRule(rule models.FilterRule) ebpf.SdfRule {
 		sdfRule.Protocol = uint8(rule.Protocol)
 	}
 
-	if rule.Action == models.Deny {
+	switch rule.Action {
+	case models.Deny:
 		sdfRule.Action = ebpf.SdfActionDeny
+	case models.RateLimit:
+		sdfRule.Action = ebpf.SdfActionRateLimit
+		sdfRule.RateKbps = uint32(min(rule.RateLimit.Kbps(), math.MaxUint32))
+
+		if rule.RateLimitShared {
+			sdfRule.RateShared = 1
+		}
+	case models.Breakout:
+		// Unbound, the rule allows traffic along the data network's path.
+		if rule.BreakoutIndex != 0 {
+			sdfRule.Action = ebpf.SdfActionBreakout
+			sdfRule.Breakout = rule.BreakoutIndex
+		}
+	case models.Portal:
+		if addr, err := netip.ParseAddr(rule.Portal); err == nil && addr.Is4() {
+			sdfRule.Action = ebpf.SdfActionPortal
+			sdfRule.SetPortal(addr)
+		} else {
+			sdfRule.Action = ebpf.SdfActionPortalDrop
+		}
+	case models.PortalDrop:
+		sdfRule.Action = ebpf.SdfActionPortalDrop
+	case models.QoSFlow:
+		sdfRule.Action = ebpf.SdfActionQoSFlow
+		sdfRule.SetQFI(rule.QFI)
+		sdfRule.RateKbps = uint32(min(rule.RateLimit.Kbps(), math.MaxUint32))
 	}
 
 	if rule.RemotePrefix != "" {
@@ -35,12 +65,104 @@ func updateFiltersRule(rule models.FilterRule) ebpf.SdfRule {
 		}
 	}
 
+	// Carries no prefix: a zero RemoteIP would otherwise match every address.
+	if rule.FQDNSet != 0 {
+		sdfRule.FQDNSet = rule.FQDNSet
+		sdfRule.RemoteIP = [16]byte{}
+		sdfRule.PrefixLen = 0
+	}
+
 	sdfRule.PortLow = uint16(rule.PortLow)
 	sdfRule.PortHigh = uint16(rule.PortHigh)
 
+	if rule.Action != models.Deny && (rule.RatingGroup != 0 || rule.ZeroRated) {
+		sdfRule.Rated = 1
+	}
+
 	return sdfRule
 }
 
+// ruleRating is the datapath's rating for rule; zero when it is unrated.
+func ruleRating(rule models.FilterRule) ebpf.SdfRating {
+	rating := ebpf.SdfRating{RatingGroup: rule.RatingGroup}
+
+	if rule.ZeroRated {
+		rating.ZeroRated = 1
+	}
+
+	return rating
+}
+
+// putRatingsLocked writes the rating of every rule slot at idx, so a slot a
+// previous list rated does not keep its rating. Caller holds filterMu for
+// writing.
+func (conn *SessionEngine) putRatingsLocked(idx uint32, rules []models.FilterRule) error {
+	// The API refuses rated rules on this datapath; one written through
+	// another node is counted in full.
+	if !conn.BpfObjects.HasRatingGroups() {
+		for _, r := range rules {
+			if (r.RatingGroup != 0 || r.ZeroRated) && !conn.ratingsWarned {
+				logger.UpfLog.Error("rated network rules are counted as ordinary traffic", zap.Error(ebpf.ErrRatingGroupsUnsupported))
+				conn.ratingsWarned = true
+			}
+		}
+
+		return nil
+	}
+
+	for i := range ebpf.MaxRulesPerFilter {
+		var rating ebpf.SdfRating
+		if i < len(rules) && rules[i].Action != models.Deny {
+			rating = ruleRating(rules[i])
+		}
+
+		if err := conn.BpfObjects.PutSdfRating(idx, i, rating); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// warnPortalUnsupportedLocked logs once that captive portal rules pass
+// their traffic on a datapath without the portal maps, which knows nothing
+// of their actions. The API refuses redirect mode there, so this is a policy
+// written through a node whose datapath has them. Caller holds filterMu for
+// writing.
+func (conn *SessionEngine) warnPortalUnsupportedLocked(rules []models.FilterRule) {
+	if conn.portalWarned || conn.BpfObjects.HasCaptivePortal() {
+		return
+	}
+
+	for _, r := range rules {
+		if r.Action == models.Portal || r.Action == models.PortalDrop {
+			logger.UpfLog.Error("captive portal rules allow their traffic", zap.Error(ebpf.ErrCaptivePortalUnsupported))
+			conn.portalWarned = true
+
+			return
+		}
+	}
+}
+
+// warnRateLimitUnsupportedLocked logs once that rate-limit and QoS flow rules
+// pass their traffic unpoliced and unmarked on a datapath that predates them.
+// The API refuses both, so this is a policy or QoS session written through a
+// node whose datapath has them. Caller holds filterMu for writing.
+func (conn *SessionEngine) warnRateLimitUnsupportedLocked(rules []models.FilterRule) {
+	if conn.rateLimitWarned || conn.BpfObjects.HasSDFRateLimit() {
+		return
+	}
+
+	for _, r := range rules {
+		if r.Action == models.RateLimit || r.Action == models.QoSFlow {
+			logger.UpfLog.Error("rate-limit and QoS flow rules allow their traffic unpoliced", zap.Error(ebpf.ErrSDFRateLimitUnsupported))
+			conn.rateLimitWarned = true
+
+			return
+		}
+	}
+}
+
 // The caller holds filterMu until it has applied the index: a slot released in
 // between is reissued to the next policy.
 func (conn *SessionEngine) resolveFilterIndexLocked(policyID string, direction models.Direction) uint32 {
@@ -106,6 +228,18 @@ func (conn *SessionEngine) installFilter(policyID string, direction models.Direc
 	}
 
 	if conn.BpfObjects != nil {
+		conn.warnPortalUnsupportedLocked(rules)
+		conn.warnRateLimitUnsupportedLocked(rules)
+
+		// Before the list, so no rule is marked rated ahead of its rating.
+		if err := conn.putRatingsLocked(idx, rules); err != nil {
+			if !existing {
+				conn.SdfIndexAllocator.Release(idx)
+			}
+
+			return fmt.Errorf("write sdf ratings: %w", err)
+		}
+
 		if err := conn.BpfObjects.PutSdfFilterList(idx, list); err != nil {
 			if !existing {
 				conn.SdfIndexAllocator.Release(idx)
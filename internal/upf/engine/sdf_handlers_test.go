@@ -391,6 +391,114 @@ func TestUpdateFiltersRule_NoPrefix(t *testing.T) {
 	}
 }
 
+func TestUpdateFiltersRule_RateLimit(t *testing.T) {
+	rule := models.FilterRule{
+		Protocol:        6,
+		PortLow:         443,
+		PortHigh:        443,
+		Action:          models.RateLimit,
+		RateLimit:       models.MustParseBitRate("5 Mbps"),
+		RateLimitShared: true,
+	}
+
+	sdfRule := updateFiltersRule(rule)
+
+	if sdfRule.Action != ebpf.SdfActionRateLimit {
+		t.Errorf("Action = %d, want rate limit", sdfRule.Action)
+	}
+
+	if sdfRule.RateKbps != 5000 {
+		t.Errorf("RateKbps = %d, want 5000", sdfRule.RateKbps)
+	}
+
+	if sdfRule.RateShared != 1 {
+		t.Errorf("RateShared = %d, want 1", sdfRule.RateShared)
+	}
+
+	allow := updateFiltersRule(models.FilterRule{Action: models.Allow, RateLimit: models.MustParseBitRate("5 Mbps")})
+	if allow.RateKbps != 0 {
+		t.Errorf("RateKbps = %d on an allow rule, want 0", allow.RateKbps)
+	}
+}
+
+func TestUpdateFiltersRule_Breakout(t *testing.T) {
+	rule := models.FilterRule{
+		RemotePrefix:  "192.168.50.0/24",
+		Action:        models.Breakout,
+		Breakout:      models.BreakoutEgress{Interface: "eth2"},
+		BreakoutIndex: 3,
+	}
+
+	sdfRule := updateFiltersRule(rule)
+
+	if sdfRule.Action != ebpf.SdfActionBreakout || sdfRule.Breakout != 3 {
+		t.Errorf("Action = %d Breakout = %d, want breakout to index 3", sdfRule.Action, sdfRule.Breakout)
+	}
+
+	rule.BreakoutIndex = 0
+
+	unbound := updateFiltersRule(rule)
+	if unbound.Action != ebpf.SdfActionAllow || unbound.Breakout != 0 {
+		t.Errorf("unbound breakout: Action = %d Breakout = %d, want allow", unbound.Action, unbound.Breakout)
+	}
+}
+
+func TestUpdateFiltersRule_Portal(t *testing.T) {
+	sdfRule := updateFiltersRule(models.FilterRule{Protocol: 6, PortLow: 80, PortHigh: 80, Action: models.Portal, Portal: "10.45.0.1"})
+
+	if sdfRule.Action != ebpf.SdfActionPortal || sdfRule.Portal() != netip.MustParseAddr("10.45.0.1") {
+		t.Errorf("Action = %d Portal = %s, want portal redirect to 10.45.0.1", sdfRule.Action, sdfRule.Portal())
+	}
+
+	// Nothing to redirect to: the packet must not pass.
+	if bad := updateFiltersRule(models.FilterRule{Action: models.Portal, Portal: "2001:db8::1"}); bad.Action != ebpf.SdfActionPortalDrop {
+		t.Errorf("Action = %d for an IPv6 portal, want portal drop", bad.Action)
+	}
+
+	if drop := updateFiltersRule(models.FilterRule{Action: models.PortalDrop}); drop.Action != ebpf.SdfActionPortalDrop {
+		t.Errorf("Action = %d, want portal drop", drop.Action)
+	}
+}
+
+func TestUpdateFiltersRule_QoSFlow(t *testing.T) {
+	rate, err := models.ParseBitRate("5 Mbps")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sdfRule := updateFiltersRule(models.FilterRule{RemotePrefix: "198.51.100.0/24", Protocol: 17, Action: models.QoSFlow, QFI: 2, RateLimit: rate})
+
+	if sdfRule.Action != ebpf.SdfActionQoSFlow || sdfRule.QFI() != 2 || sdfRule.RateKbps != 5000 {
+		t.Errorf("Action = %d QFI = %d RateKbps = %d, want QoS flow 2 policed to 5000 kbps", sdfRule.Action, sdfRule.QFI(), sdfRule.RateKbps)
+	}
+}
+
+func TestUpdateFiltersRule_Rated(t *testing.T) {
+	rated := updateFiltersRule(models.FilterRule{Action: models.Allow, RatingGroup: 10})
+	if rated.Rated != 1 {
+		t.Errorf("Rated = %d on a rule with a rating group, want 1", rated.Rated)
+	}
+
+	zeroRated := updateFiltersRule(models.FilterRule{Action: models.Allow, ZeroRated: true})
+	if zeroRated.Rated != 1 {
+		t.Errorf("Rated = %d on a zero-rated rule, want 1", zeroRated.Rated)
+	}
+
+	if rating := ruleRating(models.FilterRule{RatingGroup: 10, ZeroRated: true}); rating.RatingGroup != 10 || rating.ZeroRated != 1 {
+		t.Errorf("ruleRating = %+v, want rating group 10, zero-rated", rating)
+	}
+
+	deny := updateFiltersRule(models.FilterRule{Action: models.Deny, RatingGroup: 10})
+	if deny.Rated != 0 {
+		t.Errorf("Rated = %d on a deny rule, want 0", deny.Rated)
+	}
+
+	plain := updateFiltersRule(models.FilterRule{Action: models.Allow})
+	if plain.Rated != 0 {
+		t.Errorf("Rated = %d on an unrated rule, want 0", plain.Rated)
+	}
+}
+
 func TestDeleteSession_DeregistersFromPolicyIndex(t *testing.T) {
 	eng := newTestEngine()
 
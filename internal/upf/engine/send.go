@@ -11,11 +11,13 @@ import (
 )
 
 // SMFReportHandler is the callback interface the UPF uses to deliver
-// reports (downlink data notifications, usage measurements, flow stats)
-// back to the SMF.
+// reports (downlink data notifications, usage measurements, flow stats,
+// GTP-U path events) back to the SMF.
 type SMFReportHandler interface {
 	HandleDownlinkDataReport(context.Context, *models.DownlinkDataReport) error
 	HandleUsageReport(context.Context, *models.UsageReport) error
+	HandleErrorIndicationReport(context.Context, *models.ErrorIndicationReport) error
+	HandlePathFailureReport(context.Context, *models.PathFailureReport) error
 	SendFlowReports(context.Context, []*models.FlowReportRequest) error
 }
 
@@ -32,7 +34,7 @@ func (conn *SessionEngine) SendDownlinkDataReport(ctx context.Context, smf SMFRe
 	})
 }
 
-func (conn *SessionEngine) SendUsageReport(ctx context.Context, smf SMFReportHandler, localSeid uint64, uvol uint64, dvol uint64) error {
+func (conn *SessionEngine) SendUsageReport(ctx context.Context, smf SMFReportHandler, localSeid uint64, uvol uint64, dvol uint64, ratingGroups []models.RatingGroupUsage) error {
 	session := conn.GetSession(localSeid)
 	if session == nil {
 		return fmt.Errorf("failed to find session with localSeid: %d", localSeid)
@@ -42,5 +44,6 @@ func (conn *SessionEngine) SendUsageReport(ctx context.Context, smf SMFReportHan
 		SEID:           session.SEID,
 		UplinkVolume:   uvol,
 		DownlinkVolume: dvol,
+		RatingGroups:   ratingGroups,
 	})
 }
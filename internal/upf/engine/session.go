@@ -8,6 +8,7 @@ import (
 	"net/netip"
 	"sync"
 
+	"github.com/ellanetworks/core/internal/models"
 	"github.com/ellanetworks/core/internal/upf/ebpf"
 )
 
@@ -23,6 +24,7 @@ type Session struct {
 	fars         map[uint32]ebpf.FarInfo
 	qers         map[uint32]ebpf.QerInfo
 	framedRoutes []netip.Prefix
+	s1uBearers   []models.S1UBearer
 	ueIPv4       netip.Addr
 	ueIPv6       netip.Addr
 }
@@ -128,6 +130,31 @@ func (s *Session) ListFARs() map[uint32]ebpf.FarInfo {
 	return c
 }
 
+// DownlinkTunnel is a GTP-U tunnel toward an access node.
+type DownlinkTunnel struct {
+	TEID uint32
+	Peer netip.Addr
+}
+
+// DownlinkTunnels lists the tunnels the session's FARs currently forward
+// into. A buffering FAR has no live tunnel and is left out.
+func (s *Session) DownlinkTunnels() []DownlinkTunnel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tunnels []DownlinkTunnel
+
+	for _, far := range s.fars {
+		if far.Action&farForward == 0 || far.OuterHeaderCreation == 0 {
+			continue
+		}
+
+		tunnels = append(tunnels, DownlinkTunnel{TEID: far.TeID, Peer: ebpf.In6AddrToIP(far.RemoteIP)})
+	}
+
+	return tunnels
+}
+
 func (s *Session) GetQer(id uint32) ebpf.QerInfo {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -176,6 +203,23 @@ func (s *Session) FramedRoutes() []netip.Prefix {
 	return append([]netip.Prefix(nil), s.framedRoutes...)
 }
 
+// SetS1UBearers records the session's dedicated EPS bearers so they can be
+// removed from the datapath when they go or the session is deleted.
+func (s *Session) SetS1UBearers(bearers []models.S1UBearer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.s1uBearers = bearers
+}
+
+// S1UBearers returns a snapshot copy of the session's dedicated EPS bearers.
+func (s *Session) S1UBearers() []models.S1UBearer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.S1UBearer(nil), s.s1uBearers...)
+}
+
 // ListQERs returns a snapshot copy of the QER map.
 func (s *Session) ListQERs() map[uint32]ebpf.QerInfo {
 	s.mu.RLock()
@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/netip"
 	"sync"
+	"time"
 
 	"github.com/ellanetworks/core/internal/db"
 	"github.com/ellanetworks/core/internal/logger"
@@ -39,6 +40,13 @@ type SessionEngine struct {
 	// holding either.
 	filterMu     sync.RWMutex
 	filtersByKey map[string]uint32
+	// ratingsWarned is set once the missing rating group maps have been
+	// reported. Guarded by filterMu.
+	ratingsWarned bool
+	// portalWarned is the same for the missing captive portal maps.
+	portalWarned bool
+	// rateLimitWarned is the same for a datapath without rate-limit rules.
+	rateLimitWarned bool
 }
 
 func (pc *SessionEngine) ListSessions() map[uint64]*Session {
@@ -51,6 +59,20 @@ func (pc *SessionEngine) ListSessions() map[uint64]*Session {
 	return sessCopy
 }
 
+// DownlinkTunnels maps the SMF SEID of every session forwarding downlink
+// traffic to the GTP-U tunnels it uses.
+func (pc *SessionEngine) DownlinkTunnels() map[uint64][]DownlinkTunnel {
+	tunnels := make(map[uint64][]DownlinkTunnel)
+
+	for _, session := range pc.ListSessions() {
+		if t := session.DownlinkTunnels(); len(t) > 0 {
+			tunnels[session.SEID] = t
+		}
+	}
+
+	return tunnels
+}
+
 func (pc *SessionEngine) GetSession(seid uint64) *Session {
 	pc.mu.RLock()
 	defer pc.mu.RUnlock()
@@ -128,6 +150,12 @@ func (pc *SessionEngine) InitializeFiltersFromDB(ctx context.Context, dbInstance
 		return nil
 	}
 
+	// The settings reconciler flips scheduled rules from here on.
+	activeSchedules, err := dbInstance.ActiveSchedules(ctx, time.Now())
+	if err != nil {
+		logger.WithTrace(ctx, logger.DBLog).Warn("failed to evaluate schedules", zap.Error(err))
+	}
+
 	for _, policy := range policies {
 		rules, err := dbInstance.ListRulesForPolicy(ctx, policy.ID)
 		if err != nil {
@@ -140,10 +168,53 @@ func (pc *SessionEngine) InitializeFiltersFromDB(ctx context.Context, dbInstance
 			continue
 		}
 
+		fqdns, err := dbInstance.ListNetworkRuleFQDNsByPolicy(ctx, policy.ID)
+		if err != nil {
+			logger.WithTrace(ctx, logger.DBLog).Error(
+				"failed to list FQDN rules for policy",
+				zap.String("policyID", policy.ID),
+				zap.Error(err),
+			)
+
+			continue
+		}
+
+		limits, err := dbInstance.ListNetworkRuleRateLimitsByPolicy(ctx, policy.ID)
+		if err != nil {
+			logger.WithTrace(ctx, logger.DBLog).Error(
+				"failed to list rate-limited rules for policy",
+				zap.String("policyID", policy.ID),
+				zap.Error(err),
+			)
+
+			continue
+		}
+
+		schedules, err := dbInstance.ListNetworkRuleSchedulesByPolicy(ctx, policy.ID)
+		if err != nil {
+			logger.WithTrace(ctx, logger.DBLog).Error(
+				"failed to list scheduled rules for policy",
+				zap.String("policyID", policy.ID),
+				zap.Error(err),
+			)
+
+			continue
+		}
+
 		uplinkRules := make([]models.FilterRule, 0)
 		downlinkRules := make([]models.FilterRule, 0)
 
 		for _, rule := range rules {
+			// Without a set from the UPF it would match every address; the
+			// settings reconciler installs it.
+			if _, ok := fqdns[rule.ID]; ok {
+				continue
+			}
+
+			if sched, ok := schedules[rule.ID]; ok && !activeSchedules[sched.ScheduleID] {
+				continue
+			}
+
 			filterRule := models.FilterRule{
 				RemotePrefix: "",
 				Protocol:     rule.Protocol,
@@ -156,6 +227,11 @@ func (pc *SessionEngine) InitializeFiltersFromDB(ctx context.Context, dbInstance
 				filterRule.RemotePrefix = *rule.RemotePrefix
 			}
 
+			if l, ok := limits[rule.ID]; ok && filterRule.Action == models.RateLimit {
+				filterRule.RateLimit, _ = models.ParseBitRate(l.Bitrate)
+				filterRule.RateLimitShared = l.Shared
+			}
+
 			switch rule.Direction {
 			case "uplink":
 				uplinkRules = append(uplinkRules, filterRule)
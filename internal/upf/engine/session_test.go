@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package engine
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+func TestDownlinkTunnels_OnlyForwardingGTPFARs(t *testing.T) {
+	conn, err := NewSessionEngine("1.2.3.4", "nodeId", "2.3.4.5", "", "2.3.4.5", "", nil, nil)
+	if err != nil {
+		t.Fatalf("new session engine: %v", err)
+	}
+
+	gnb := netip.MustParseAddr("10.0.0.1")
+
+	forwarding := NewSession(100)
+	forwarding.PutFar(1, ebpf.FarInfo{Action: farForward})
+	forwarding.PutFar(2, ebpf.FarInfo{Action: farForward, OuterHeaderCreation: 1, TeID: 7, RemoteIP: ebpf.IPToIn6Addr(gnb)})
+	conn.AddSession(1, forwarding)
+
+	buffering := NewSession(200)
+	buffering.PutFar(2, ebpf.FarInfo{Action: farBuffer | farNotifyCP, OuterHeaderCreation: 1, TeID: 8, RemoteIP: ebpf.IPToIn6Addr(gnb)})
+	conn.AddSession(2, buffering)
+
+	got := conn.DownlinkTunnels()
+
+	if len(got) != 1 {
+		t.Fatalf("expected only the forwarding session, got %v", got)
+	}
+
+	tunnels := got[100]
+	if len(tunnels) != 1 || tunnels[0].TEID != 7 || tunnels[0].Peer != gnb {
+		t.Fatalf("tunnels keyed by SMF SEID = %v, want TEID 7 toward %s", tunnels, gnb)
+	}
+}
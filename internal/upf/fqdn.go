@@ -0,0 +1,551 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// Learned addresses live for the record TTL, clamped: clients commonly
+	// hold an answer past a very short TTL, and a long one would keep an
+	// address the name has moved away from.
+	fqdnMinTTL = time.Minute
+	fqdnMaxTTL = time.Hour
+	// A TLS server name carries no TTL.
+	fqdnSNITTL = 10 * time.Minute
+
+	fqdnSweepInterval = 30 * time.Second
+)
+
+// fqdnDatapath is the part of the datapath the tracker programs.
+// *ebpf.BpfObjects satisfies it; a fake satisfies it in tests.
+type fqdnDatapath interface {
+	HasFQDNSets() bool
+	SetFQDNSnoop(enabled bool) error
+	PutFQDNAddr(set uint16, addr netip.Addr) error
+	DeleteFQDNAddr(set uint16, addr netip.Addr) error
+}
+
+type fqdnSetKey struct {
+	pattern  string
+	matchSNI bool
+}
+
+// fqdnTracker gives each distinct FQDN rule a datapath set and fills it with
+// the addresses learned from snooped DNS answers and TLS server names.
+// Rules naming the same domain share a set, across policies.
+type fqdnTracker struct {
+	dp fqdnDatapath
+
+	mu       sync.Mutex
+	sets     map[fqdnSetKey]uint16
+	refs     map[string][]fqdnSetKey // by filter key
+	learned  map[uint16]map[netip.Addr]time.Time
+	free     []uint16
+	nextSet  uint16
+	snooping bool
+	warned   bool
+}
+
+func newFQDNTracker(dp fqdnDatapath) *fqdnTracker {
+	return &fqdnTracker{
+		dp:      dp,
+		sets:    make(map[fqdnSetKey]uint16),
+		refs:    make(map[string][]fqdnSetKey),
+		learned: make(map[uint16]map[netip.Addr]time.Time),
+		nextSet: 1,
+	}
+}
+
+// resolve returns rules with every FQDN rule bound to its set. On a datapath
+// without the FQDN maps the FQDN rules are left out, since without a set they
+// would match every address, and the rest still apply.
+func (t *fqdnTracker) resolve(key string, rules []models.FilterRule) ([]models.FilterRule, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]models.FilterRule, 0, len(rules))
+	keys := make([]fqdnSetKey, 0)
+
+	for _, rule := range rules {
+		if rule.FQDN == "" {
+			out = append(out, rule)
+			continue
+		}
+
+		// The API refuses FQDN rules on such a datapath; these were written
+		// through a node with a newer one.
+		if !t.dp.HasFQDNSets() {
+			if !t.warned {
+				logger.UpfLog.Error("FQDN network rules are not enforced", zap.Error(ebpf.ErrFQDNUnsupported))
+				t.warned = true
+			}
+
+			continue
+		}
+
+		setKey := fqdnSetKey{pattern: normalizeFQDN(rule.FQDN), matchSNI: rule.MatchSNI}
+
+		set, err := t.acquireLocked(setKey)
+		if err != nil {
+			return nil, err
+		}
+
+		rule.FQDNSet = set
+		keys = append(keys, setKey)
+		out = append(out, rule)
+	}
+
+	previous := t.refs[key]
+
+	if len(keys) == 0 {
+		delete(t.refs, key)
+	} else {
+		t.refs[key] = keys
+	}
+
+	t.releaseUnusedLocked(previous)
+
+	return out, t.updateSnoopLocked()
+}
+
+func (t *fqdnTracker) acquireLocked(key fqdnSetKey) (uint16, error) {
+	if set, ok := t.sets[key]; ok {
+		return set, nil
+	}
+
+	var set uint16
+
+	switch {
+	case len(t.free) > 0:
+		set = t.free[len(t.free)-1]
+		t.free = t.free[:len(t.free)-1]
+	case t.nextSet < math.MaxUint16:
+		set = t.nextSet
+		t.nextSet++
+	default:
+		return 0, errors.New("no FQDN sets left")
+	}
+
+	t.sets[key] = set
+	t.learned[set] = make(map[netip.Addr]time.Time)
+
+	return set, nil
+}
+
+// releaseUnusedLocked frees the sets among candidates no filter refers to.
+func (t *fqdnTracker) releaseUnusedLocked(candidates []fqdnSetKey) {
+	used := make(map[fqdnSetKey]bool)
+
+	for _, keys := range t.refs {
+		for _, k := range keys {
+			used[k] = true
+		}
+	}
+
+	for _, k := range candidates {
+		if used[k] {
+			continue
+		}
+
+		set, ok := t.sets[k]
+		if !ok {
+			continue
+		}
+
+		for addr := range t.learned[set] {
+			if err := t.dp.DeleteFQDNAddr(set, addr); err != nil {
+				logger.UpfLog.Warn("could not remove learned FQDN address", zap.String("fqdn", k.pattern), zap.Error(err))
+			}
+		}
+
+		delete(t.learned, set)
+		delete(t.sets, k)
+		t.free = append(t.free, set)
+	}
+}
+
+func (t *fqdnTracker) updateSnoopLocked() error {
+	want := len(t.sets) > 0
+	if want == t.snooping || !t.dp.HasFQDNSets() {
+		return nil
+	}
+
+	if err := t.dp.SetFQDNSnoop(want); err != nil {
+		return fmt.Errorf("set FQDN snooping: %w", err)
+	}
+
+	t.snooping = want
+
+	return nil
+}
+
+// handle learns addresses from one snooped payload.
+func (t *fqdnTracker) handle(ev ebpf.FQDNSnoopEvent, now time.Time) {
+	switch ev.Kind {
+	case ebpf.FQDNSnoopDNS:
+		names, addrs := parseDNSAnswer(ev.Payload)
+		if len(addrs) == 0 {
+			return
+		}
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		for key, set := range t.sets {
+			if !matchesAnyFQDN(key.pattern, names) {
+				continue
+			}
+
+			for _, a := range addrs {
+				t.learnLocked(set, key.pattern, a.addr, now.Add(clampTTL(a.ttl)))
+			}
+		}
+	case ebpf.FQDNSnoopTLS:
+		name, ok := parseClientHelloSNI(ev.Payload)
+		if !ok || !ev.Remote.IsValid() {
+			return
+		}
+
+		name = normalizeFQDN(name)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		for key, set := range t.sets {
+			if key.matchSNI && matchesFQDN(key.pattern, name) {
+				t.learnLocked(set, key.pattern, ev.Remote, now.Add(fqdnSNITTL))
+			}
+		}
+	}
+}
+
+func (t *fqdnTracker) learnLocked(set uint16, pattern string, addr netip.Addr, expiry time.Time) {
+	learned := t.learned[set]
+
+	if current, ok := learned[addr]; ok {
+		if expiry.After(current) {
+			learned[addr] = expiry
+		}
+
+		return
+	}
+
+	if err := t.dp.PutFQDNAddr(set, addr); err != nil {
+		logger.UpfLog.Debug("could not program learned FQDN address", zap.String("fqdn", pattern), zap.String("address", addr.String()), zap.Error(err))
+		return
+	}
+
+	learned[addr] = expiry
+}
+
+// sweep drops learned addresses whose TTL has run out.
+func (t *fqdnTracker) sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for set, learned := range t.learned {
+		for addr, expiry := range learned {
+			if now.Before(expiry) {
+				continue
+			}
+
+			if err := t.dp.DeleteFQDNAddr(set, addr); err != nil {
+				logger.UpfLog.Warn("could not expire learned FQDN address", zap.String("address", addr.String()), zap.Error(err))
+				continue
+			}
+
+			delete(learned, addr)
+		}
+	}
+}
+
+func (t *fqdnTracker) listen(reader *ringbuf.Reader) {
+	var record ringbuf.Record
+
+	for {
+		err := reader.ReadInto(&record)
+		if errors.Is(err, os.ErrClosed) {
+			return
+		}
+
+		// record still holds the previous sample; see listenForTrafficNotifications.
+		if err != nil {
+			logger.UpfLog.Warn("FQDN snoop ring buffer read error", zap.Error(err))
+			continue
+		}
+
+		ev, err := ebpf.ParseFQDNSnoopEvent(record.RawSample)
+		if err != nil {
+			logger.UpfLog.Debug("could not parse FQDN snoop event", zap.Error(err))
+			continue
+		}
+
+		t.handle(ev, time.Now())
+	}
+}
+
+func (t *fqdnTracker) sweepEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			t.sweep(now)
+		}
+	}
+}
+
+func clampTTL(ttl uint32) time.Duration {
+	d := time.Duration(ttl) * time.Second
+
+	return min(max(d, fqdnMinTTL), fqdnMaxTTL)
+}
+
+func normalizeFQDN(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// matchesFQDN reports whether name matches pattern. "*.example.com" matches
+// every name below example.com but not example.com itself.
+func matchesFQDN(pattern, name string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return len(name) > len(suffix) && strings.HasSuffix(name, suffix)
+	}
+
+	return name == pattern
+}
+
+func matchesAnyFQDN(pattern string, names []string) bool {
+	for _, name := range names {
+		if matchesFQDN(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type dnsAddr struct {
+	addr netip.Addr
+	ttl  uint32
+}
+
+// parseDNSAnswer returns the queried name with the names it aliases through
+// CNAME records, and the addresses they resolve to. A truncated message
+// yields what was read before the cut.
+func parseDNSAnswer(msg []byte) ([]string, []dnsAddr) {
+	var p dnsmessage.Parser
+
+	hdr, err := p.Start(msg)
+	if err != nil || !hdr.Response || hdr.RCode != dnsmessage.RCodeSuccess {
+		return nil, nil
+	}
+
+	q, err := p.Question()
+	if err != nil {
+		return nil, nil
+	}
+
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, nil
+	}
+
+	type record struct {
+		owner string
+		addr  dnsAddr
+	}
+
+	aliases := make(map[string][]string)
+
+	var records []record
+
+answers:
+	for {
+		h, err := p.AnswerHeader()
+		if err != nil {
+			break
+		}
+
+		owner := normalizeFQDN(h.Name.String())
+
+		switch h.Type {
+		case dnsmessage.TypeCNAME:
+			r, err := p.CNAMEResource()
+			if err != nil {
+				break answers
+			}
+
+			aliases[owner] = append(aliases[owner], normalizeFQDN(r.CNAME.String()))
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				break answers
+			}
+
+			records = append(records, record{owner, dnsAddr{netip.AddrFrom4(r.A), h.TTL}})
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				break answers
+			}
+
+			records = append(records, record{owner, dnsAddr{netip.AddrFrom16(r.AAAA), h.TTL}})
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				break answers
+			}
+		}
+	}
+
+	names := []string{normalizeFQDN(q.Name.String())}
+	seen := map[string]bool{names[0]: true}
+
+	for i := 0; i < len(names); i++ {
+		for _, target := range aliases[names[i]] {
+			if !seen[target] {
+				seen[target] = true
+				names = append(names, target)
+			}
+		}
+	}
+
+	var addrs []dnsAddr
+
+	for _, r := range records {
+		if seen[r.owner] {
+			addrs = append(addrs, r.addr)
+		}
+	}
+
+	return names, addrs
+}
+
+// parseClientHelloSNI returns the server name of a TLS ClientHello
+// (RFC 8446 §4.1.2, RFC 6066 §3). A hello cut short before the extension
+// yields nothing.
+func parseClientHelloSNI(b []byte) (string, bool) {
+	const (
+		recordHeaderLen    = 5
+		handshakeHeaderLen = 4
+		clientHello        = 1
+		extServerName      = 0
+		hostName           = 0
+	)
+
+	if len(b) < recordHeaderLen+handshakeHeaderLen || b[0] != 0x16 || b[recordHeaderLen] != clientHello {
+		return "", false
+	}
+
+	// Handshake body: version, random, then variable-length fields.
+	b = b[recordHeaderLen+handshakeHeaderLen:]
+
+	skip := func(n int) bool {
+		if len(b) < n {
+			return false
+		}
+
+		b = b[n:]
+
+		return true
+	}
+
+	vec := func(lenBytes int) ([]byte, bool) {
+		if len(b) < lenBytes {
+			return nil, false
+		}
+
+		var n int
+		for _, c := range b[:lenBytes] {
+			n = n<<8 | int(c)
+		}
+
+		b = b[lenBytes:]
+		if len(b) < n {
+			return nil, false
+		}
+
+		v := b[:n]
+		b = b[n:]
+
+		return v, true
+	}
+
+	if !skip(2 + 32) {
+		return "", false
+	}
+
+	for _, lenBytes := range []int{1, 2, 1} { // session id, cipher suites, compression
+		if _, ok := vec(lenBytes); !ok {
+			return "", false
+		}
+	}
+
+	if len(b) < 2 {
+		return "", false
+	}
+
+	// Extensions may run past the snooped bytes; walk what arrived.
+	b = b[2:]
+
+	for len(b) >= 4 {
+		typ := binary.BigEndian.Uint16(b)
+		n := int(binary.BigEndian.Uint16(b[2:]))
+		b = b[4:]
+
+		if len(b) < n {
+			return "", false
+		}
+
+		if typ != extServerName {
+			b = b[n:]
+			continue
+		}
+
+		ext := b[:n]
+		if len(ext) < 2 {
+			return "", false
+		}
+
+		list := ext[2:]
+		for len(list) >= 3 {
+			nameType := list[0]
+			nameLen := int(binary.BigEndian.Uint16(list[1:]))
+			list = list[3:]
+
+			if len(list) < nameLen {
+				return "", false
+			}
+
+			if nameType == hostName && nameLen > 0 {
+				return string(list[:nameLen]), true
+			}
+
+			list = list[nameLen:]
+		}
+
+		return "", false
+	}
+
+	return "", false
+}
@@ -0,0 +1,296 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type fqdnAddrKey struct {
+	set  uint16
+	addr netip.Addr
+}
+
+type fakeFQDNDatapath struct {
+	mu          sync.Mutex
+	unsupported bool
+	snoop       bool
+	addrs       map[fqdnAddrKey]bool
+}
+
+func newFakeFQDNDatapath() *fakeFQDNDatapath {
+	return &fakeFQDNDatapath{addrs: make(map[fqdnAddrKey]bool)}
+}
+
+func (f *fakeFQDNDatapath) HasFQDNSets() bool { return !f.unsupported }
+
+func (f *fakeFQDNDatapath) SetFQDNSnoop(enabled bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.snoop = enabled
+
+	return nil
+}
+
+func (f *fakeFQDNDatapath) PutFQDNAddr(set uint16, addr netip.Addr) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.addrs[fqdnAddrKey{set, addr}] = true
+
+	return nil
+}
+
+func (f *fakeFQDNDatapath) DeleteFQDNAddr(set uint16, addr netip.Addr) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.addrs, fqdnAddrKey{set, addr})
+
+	return nil
+}
+
+func (f *fakeFQDNDatapath) has(set uint16, addr string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.addrs[fqdnAddrKey{set, netip.MustParseAddr(addr)}]
+}
+
+func buildDNSAnswer(t *testing.T, question string, cname string, addr [4]byte, ttl uint32) []byte {
+	t.Helper()
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, RCode: dnsmessage.RCodeSuccess})
+	b.EnableCompression()
+
+	if err := b.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(question), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.StartAnswers(); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := question
+
+	if cname != "" {
+		h := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(question), Class: dnsmessage.ClassINET, TTL: ttl}
+		if err := b.CNAMEResource(h, dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(cname)}); err != nil {
+			t.Fatal(err)
+		}
+
+		owner = cname
+	}
+
+	h := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(owner), Class: dnsmessage.ClassINET, TTL: ttl}
+	if err := b.AResource(h, dnsmessage.AResource{A: addr}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return msg
+}
+
+// clientHello captures the first TLS record crypto/tls sends for serverName.
+func clientHello(t *testing.T, serverName string) []byte {
+	t.Helper()
+
+	client, server := net.Pipe()
+
+	defer func() {
+		_ = client.Close()
+		_ = server.Close()
+	}()
+
+	go func() {
+		_ = tls.Client(client, &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}).Handshake()
+	}()
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(server, header); err != nil {
+		t.Fatalf("read record header: %v", err)
+	}
+
+	body := make([]byte, int(header[3])<<8|int(header[4]))
+	if _, err := io.ReadFull(server, body); err != nil {
+		t.Fatalf("read record body: %v", err)
+	}
+
+	return append(header, body...)
+}
+
+func TestMatchesFQDN(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.vendor-cloud.example", "api.vendor-cloud.example", true},
+		{"*.vendor-cloud.example", "a.b.vendor-cloud.example", true},
+		{"*.vendor-cloud.example", "vendor-cloud.example", false},
+		{"*.vendor-cloud.example", "evilvendor-cloud.example", false},
+		{"api.vendor-cloud.example", "api.vendor-cloud.example", true},
+		{"api.vendor-cloud.example", "x.api.vendor-cloud.example", false},
+	}
+
+	for _, tc := range cases {
+		if got := matchesFQDN(tc.pattern, tc.name); got != tc.want {
+			t.Errorf("matchesFQDN(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFQDNTracker_LearnsFromDNSThroughCNAMEAndExpires(t *testing.T) {
+	dp := newFakeFQDNDatapath()
+	tracker := newFQDNTracker(dp)
+
+	rules, err := tracker.resolve("policy-1:uplink", []models.FilterRule{
+		{FQDN: "*.Vendor-Cloud.example.", Action: models.Allow},
+		{Action: models.Deny},
+	})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	set := rules[0].FQDNSet
+	if set == 0 || rules[1].FQDNSet != 0 {
+		t.Fatalf("expected only the FQDN rule to get a set, got %+v", rules)
+	}
+
+	if !dp.snoop {
+		t.Fatalf("expected snooping on once an FQDN rule is installed")
+	}
+
+	now := time.Unix(1_800_000_000, 0)
+	msg := buildDNSAnswer(t, "api.vendor-cloud.example.", "edge.cdn.example.", [4]byte{198, 51, 100, 7}, 5)
+
+	tracker.handle(ebpf.FQDNSnoopEvent{Kind: ebpf.FQDNSnoopDNS, Payload: msg}, now)
+
+	if !dp.has(set, "198.51.100.7") {
+		t.Fatalf("expected the CNAME target's address to be learned")
+	}
+
+	// A 5 s TTL is held for fqdnMinTTL.
+	tracker.sweep(now.Add(30 * time.Second))
+
+	if !dp.has(set, "198.51.100.7") {
+		t.Fatalf("address expired before the minimum TTL")
+	}
+
+	tracker.sweep(now.Add(fqdnMinTTL + time.Second))
+
+	if dp.has(set, "198.51.100.7") {
+		t.Fatalf("expected the address to expire")
+	}
+
+	other := buildDNSAnswer(t, "www.unrelated.example.", "", [4]byte{203, 0, 113, 9}, 300)
+	tracker.handle(ebpf.FQDNSnoopEvent{Kind: ebpf.FQDNSnoopDNS, Payload: other}, now)
+
+	if dp.has(set, "203.0.113.9") {
+		t.Fatalf("learned an address for a name outside the pattern")
+	}
+}
+
+func TestFQDNTracker_LearnsFromSNIOnlyWhenEnabled(t *testing.T) {
+	dp := newFakeFQDNDatapath()
+	tracker := newFQDNTracker(dp)
+
+	rules, err := tracker.resolve("policy-1:uplink", []models.FilterRule{
+		{FQDN: "*.vendor-cloud.example", MatchSNI: true},
+		{FQDN: "*.vendor-cloud.example"},
+	})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	sniSet, dnsSet := rules[0].FQDNSet, rules[1].FQDNSet
+	if sniSet == dnsSet {
+		t.Fatalf("expected separate sets for SNI and DNS-only rules")
+	}
+
+	hello := clientHello(t, "api.vendor-cloud.example")
+	tracker.handle(ebpf.FQDNSnoopEvent{Kind: ebpf.FQDNSnoopTLS, Remote: netip.MustParseAddr("192.0.2.44"), Payload: hello}, time.Now())
+
+	if !dp.has(sniSet, "192.0.2.44") {
+		t.Fatalf("expected the server address to be learned from SNI")
+	}
+
+	if dp.has(dnsSet, "192.0.2.44") {
+		t.Fatalf("learned from SNI for a rule without match_sni")
+	}
+
+	if _, ok := parseClientHelloSNI(hello[:20]); ok {
+		t.Fatalf("expected a truncated hello to yield no name")
+	}
+}
+
+func TestFQDNTracker_ReleasesUnusedSets(t *testing.T) {
+	dp := newFakeFQDNDatapath()
+	tracker := newFQDNTracker(dp)
+
+	rules, err := tracker.resolve("policy-1:uplink", []models.FilterRule{{FQDN: "api.vendor-cloud.example"}})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if _, err := tracker.resolve("policy-2:downlink", []models.FilterRule{{FQDN: "api.vendor-cloud.example"}}); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	set := rules[0].FQDNSet
+	msg := buildDNSAnswer(t, "api.vendor-cloud.example.", "", [4]byte{198, 51, 100, 8}, 300)
+	tracker.handle(ebpf.FQDNSnoopEvent{Kind: ebpf.FQDNSnoopDNS, Payload: msg}, time.Now())
+
+	if _, err := tracker.resolve("policy-1:uplink", nil); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if !dp.has(set, "198.51.100.8") {
+		t.Fatalf("set released while another policy still uses it")
+	}
+
+	if _, err := tracker.resolve("policy-2:downlink", nil); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if dp.has(set, "198.51.100.8") || dp.snoop {
+		t.Fatalf("expected the set emptied and snooping off once no rule uses it")
+	}
+}
+
+func TestFQDNTracker_UnsupportedDatapathDropsFQDNRules(t *testing.T) {
+	dp := newFakeFQDNDatapath()
+	dp.unsupported = true
+	tracker := newFQDNTracker(dp)
+
+	rules, err := tracker.resolve("policy-1:uplink", []models.FilterRule{
+		{FQDN: "api.vendor-cloud.example", Action: models.Allow},
+		{Action: models.Deny},
+	})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	if len(rules) != 1 || rules[0].Action != models.Deny {
+		t.Fatalf("expected only the prefix rule to remain, got %+v", rules)
+	}
+}
@@ -0,0 +1,575 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/config"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/engine"
+	"go.uber.org/zap"
+)
+
+const (
+	gtpuPort = 2152
+
+	gtpuMsgEchoRequest     = 1
+	gtpuMsgEchoResponse    = 2
+	gtpuMsgErrorIndication = 26
+
+	gtpuIERecovery    = 14
+	gtpuIETEIDDataI   = 16
+	gtpuIEPeerAddress = 133
+
+	// T3-RESPONSE and N3-REQUESTS (TS 29.281 §7.2.1): a path fails once this
+	// many consecutive Echo Requests go unanswered.
+	echoResponseTimeout = 3 * time.Second
+	echoRequestAttempts = 3
+)
+
+var errNotGTPU = errors.New("not a GTPv1-U message")
+
+// gtpuMessage is a parsed GTPv1-U header; ies holds the information
+// elements after the header and any extension headers.
+type gtpuMessage struct {
+	msgType uint8
+	seq     uint16
+	ies     []byte
+}
+
+// buildEchoRequest returns an Echo Request carrying seq (TS 29.281 §7.2.1).
+func buildEchoRequest(seq uint16) []byte {
+	b := make([]byte, 12)
+	b[0] = 0x32 // version 1, protocol type GTP, sequence number present
+	b[1] = gtpuMsgEchoRequest
+	binary.BigEndian.PutUint16(b[2:4], 4)
+	binary.BigEndian.PutUint16(b[8:10], seq)
+
+	return b
+}
+
+func parseGTPU(b []byte) (gtpuMessage, error) {
+	if len(b) < 8 || b[0]>>5 != 1 || b[0]&0x10 == 0 {
+		return gtpuMessage{}, errNotGTPU
+	}
+
+	flags := b[0]
+	msg := gtpuMessage{msgType: b[1]}
+
+	end := 8 + int(binary.BigEndian.Uint16(b[2:4]))
+	if end > len(b) {
+		return gtpuMessage{}, fmt.Errorf("GTP-U length %d exceeds the %d byte packet", end, len(b))
+	}
+
+	off := 8
+
+	// Any of E, S or PN brings the 4 optional octets.
+	if flags&0x07 != 0 {
+		if end < 12 {
+			return gtpuMessage{}, errors.New("GTP-U optional header truncated")
+		}
+
+		msg.seq = binary.BigEndian.Uint16(b[8:10])
+		next := b[11]
+		off = 12
+
+		for flags&0x04 != 0 && next != 0 {
+			if off >= end {
+				return gtpuMessage{}, errors.New("GTP-U extension header truncated")
+			}
+
+			n := int(b[off]) * 4
+			if n == 0 || off+n > end {
+				return gtpuMessage{}, errors.New("GTP-U extension header has an invalid length")
+			}
+
+			next = b[off+n-1]
+			off += n
+		}
+	}
+
+	msg.ies = b[off:end]
+
+	return msg, nil
+}
+
+// parseErrorIndication reads the TEID Data I and GTP-U Peer Address IEs of
+// an Error Indication (TS 29.281 §7.3.1). The peer address is invalid when
+// the sender left it out.
+func parseErrorIndication(ies []byte) (uint32, netip.Addr, error) {
+	var (
+		teid     uint32
+		haveTEID bool
+		peer     netip.Addr
+	)
+
+	for len(ies) > 0 {
+		switch t := ies[0]; {
+		case t == gtpuIERecovery:
+			if len(ies) < 2 {
+				return 0, netip.Addr{}, errors.New("recovery IE truncated")
+			}
+
+			ies = ies[2:]
+		case t == gtpuIETEIDDataI:
+			if len(ies) < 5 {
+				return 0, netip.Addr{}, errors.New("TEID Data I IE truncated")
+			}
+
+			teid, haveTEID = binary.BigEndian.Uint32(ies[1:5]), true
+			ies = ies[5:]
+		case t >= 128:
+			if len(ies) < 3 {
+				return 0, netip.Addr{}, fmt.Errorf("IE %d truncated", t)
+			}
+
+			n := int(binary.BigEndian.Uint16(ies[1:3]))
+			if len(ies) < 3+n {
+				return 0, netip.Addr{}, fmt.Errorf("IE %d truncated", t)
+			}
+
+			if t == gtpuIEPeerAddress {
+				addr, ok := netip.AddrFromSlice(ies[3 : 3+n])
+				if !ok {
+					return 0, netip.Addr{}, fmt.Errorf("GTP-U peer address has invalid length %d", n)
+				}
+
+				peer = addr
+			}
+
+			ies = ies[3+n:]
+		default:
+			return 0, netip.Addr{}, fmt.Errorf("unknown TV IE %d", t)
+		}
+	}
+
+	if !haveTEID {
+		return 0, netip.Addr{}, errors.New("error indication has no TEID Data I")
+	}
+
+	return teid, peer, nil
+}
+
+// n3Peers groups the sessions by the access node they tunnel downlink
+// traffic to.
+func n3Peers(tunnels map[uint64][]engine.DownlinkTunnel) map[netip.Addr][]uint64 {
+	peers := make(map[netip.Addr][]uint64)
+
+	for seid, ts := range tunnels {
+		seen := make(map[netip.Addr]bool, len(ts))
+
+		for _, t := range ts {
+			if seen[t.Peer] {
+				continue
+			}
+
+			seen[t.Peer] = true
+			peers[t.Peer] = append(peers[t.Peer], seid)
+		}
+	}
+
+	return peers
+}
+
+// findTunnel returns the session forwarding into teid at peer.
+func findTunnel(tunnels map[uint64][]engine.DownlinkTunnel, teid uint32, peer netip.Addr) (uint64, bool) {
+	for seid, ts := range tunnels {
+		for _, t := range ts {
+			if t.TEID == teid && t.Peer == peer {
+				return seid, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+type pendingEcho struct {
+	peer  netip.Addr
+	reply chan struct{}
+}
+
+// gtpuPathMonitor supervises the GTP-U paths to the radios' N3 endpoints.
+// It echoes every peer a session forwards to, declares a path failed after
+// echoRequestAttempts unanswered requests, and hands Error Indications for
+// stale tunnels to the SMF. The datapath answers echoes from the radios
+// itself and passes Echo Responses and Error Indications up to this socket.
+type gtpuPathMonitor struct {
+	smf             engine.SMFReportHandler
+	tunnels         func() map[uint64][]engine.DownlinkTunnel
+	send            func(netip.Addr, []byte) error
+	localIPv4       netip.Addr
+	localIPv6       netip.Addr
+	interval        time.Duration
+	responseTimeout time.Duration
+	releaseSessions bool
+
+	mu      sync.Mutex
+	nextSeq uint16
+	pending map[uint16]pendingEcho
+	failed  map[netip.Addr]bool // every supervised peer, true while its path is down
+}
+
+func newGTPUPathMonitor(smf engine.SMFReportHandler, tunnels func() map[uint64][]engine.DownlinkTunnel, pm config.PathManagement) *gtpuPathMonitor {
+	return &gtpuPathMonitor{
+		smf:             smf,
+		tunnels:         tunnels,
+		interval:        pm.EchoInterval,
+		responseTimeout: echoResponseTimeout,
+		releaseSessions: pm.ReleaseSessions,
+		pending:         make(map[uint16]pendingEcho),
+		failed:          make(map[netip.Addr]bool),
+	}
+}
+
+// listenGTPU binds the N3 addresses' GTP-U port and routes each peer's
+// traffic through the socket of its family.
+func (m *gtpuPathMonitor) listenGTPU(n3IPv4, n3IPv6 netip.Addr) ([]*net.UDPConn, error) {
+	var conn4, conn6 *net.UDPConn
+
+	if n3IPv4.IsValid() {
+		c, err := net.ListenUDP("udp4", net.UDPAddrFromAddrPort(netip.AddrPortFrom(n3IPv4, gtpuPort)))
+		if err != nil {
+			return nil, fmt.Errorf("listen on %s: %w", n3IPv4, err)
+		}
+
+		conn4 = c
+	}
+
+	if n3IPv6.IsValid() {
+		c, err := net.ListenUDP("udp6", net.UDPAddrFromAddrPort(netip.AddrPortFrom(n3IPv6, gtpuPort)))
+		if err != nil {
+			if conn4 != nil {
+				_ = conn4.Close()
+			}
+
+			return nil, fmt.Errorf("listen on %s: %w", n3IPv6, err)
+		}
+
+		conn6 = c
+	}
+
+	m.localIPv4, m.localIPv6 = n3IPv4, n3IPv6
+	m.send = func(peer netip.Addr, b []byte) error {
+		conn := conn4
+		if peer.Is6() {
+			conn = conn6
+		}
+
+		if conn == nil {
+			return fmt.Errorf("no N3 address of the family of %s", peer)
+		}
+
+		_, err := conn.WriteToUDPAddrPort(b, netip.AddrPortFrom(peer, gtpuPort))
+
+		return err
+	}
+
+	var conns []*net.UDPConn
+
+	for _, c := range []*net.UDPConn{conn4, conn6} {
+		if c != nil {
+			conns = append(conns, c)
+		}
+	}
+
+	return conns, nil
+}
+
+// serve reads GTP-U control messages from conn until it is closed.
+func (m *gtpuPathMonitor) serve(ctx context.Context, conn *net.UDPConn) {
+	buf := make([]byte, 2048)
+
+	for {
+		n, from, err := conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+
+			logger.UpfLog.Debug("GTP-U control read failed", zap.Error(err))
+
+			continue
+		}
+
+		m.handlePacket(ctx, buf[:n], from.Addr().Unmap())
+	}
+}
+
+func (m *gtpuPathMonitor) handlePacket(ctx context.Context, b []byte, from netip.Addr) {
+	msg, err := parseGTPU(b)
+	if err != nil {
+		logger.UpfLog.Debug("dropping malformed GTP-U control message", zap.String("peer", from.String()), zap.Error(err))
+		return
+	}
+
+	switch msg.msgType {
+	case gtpuMsgEchoResponse:
+		m.mu.Lock()
+		p, ok := m.pending[msg.seq]
+
+		if ok && p.peer == from {
+			delete(m.pending, msg.seq)
+			close(p.reply)
+		}
+		m.mu.Unlock()
+	case gtpuMsgErrorIndication:
+		m.handleErrorIndication(ctx, msg, append([]byte(nil), b...), from)
+	}
+}
+
+func (m *gtpuPathMonitor) handleErrorIndication(ctx context.Context, msg gtpuMessage, raw []byte, from netip.Addr) {
+	gtpuErrorIndications.WithLabelValues(from.String()).Inc()
+	logger.LogNetworkEvent(ctx, logger.GTPUNetworkProtocol, "ErrorIndication", logger.DirectionInbound,
+		m.localAddr(from), from.String(), "", raw)
+
+	teid, peer, err := parseErrorIndication(msg.ies)
+	if err != nil {
+		logger.UpfLog.Debug("dropping malformed GTP-U Error Indication", zap.String("peer", from.String()), zap.Error(err))
+		return
+	}
+
+	if !peer.IsValid() {
+		peer = from
+	}
+
+	seid, ok := findTunnel(m.tunnels(), teid, peer)
+	if !ok {
+		logger.UpfLog.Debug("GTP-U Error Indication matches no forwarding tunnel",
+			zap.Uint32("teid", teid), zap.String("peer", peer.String()))
+
+		return
+	}
+
+	logger.UpfLog.Info("access node rejected a downlink tunnel",
+		zap.Uint32("teid", teid), zap.String("peer", peer.String()), logger.SEID(seid))
+
+	if err := m.smf.HandleErrorIndicationReport(ctx, &models.ErrorIndicationReport{
+		SEID:          seid,
+		RemoteTEID:    teid,
+		RemoteAddress: peer,
+	}); err != nil {
+		logger.UpfLog.Warn("failed to report GTP-U Error Indication", zap.Error(err), logger.SEID(seid))
+	}
+}
+
+func (m *gtpuPathMonitor) localAddr(peer netip.Addr) string {
+	if peer.Is6() {
+		return m.localIPv6.String()
+	}
+
+	return m.localIPv4.String()
+}
+
+// run probes every peer each interval until ctx is done.
+func (m *gtpuPathMonitor) run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.probeAll(ctx)
+		}
+	}
+}
+
+// probeAll echoes every current peer concurrently and drops supervision of
+// peers no session forwards to anymore.
+func (m *gtpuPathMonitor) probeAll(ctx context.Context) {
+	peers := n3Peers(m.tunnels())
+
+	m.mu.Lock()
+	for peer := range m.failed {
+		if _, ok := peers[peer]; !ok {
+			delete(m.failed, peer)
+			forgetPathMetrics(peer)
+		}
+	}
+
+	for peer := range peers {
+		if _, ok := m.failed[peer]; !ok {
+			m.failed[peer] = false
+		}
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+
+	for peer := range peers {
+		wg.Go(func() {
+			rtt, ok := m.probe(ctx, peer)
+			if ctx.Err() != nil {
+				return
+			}
+
+			m.record(ctx, peer, rtt, ok)
+		})
+	}
+
+	wg.Wait()
+}
+
+// probe sends up to echoRequestAttempts Echo Requests to peer and returns the
+// round-trip time of the first one answered.
+func (m *gtpuPathMonitor) probe(ctx context.Context, peer netip.Addr) (time.Duration, bool) {
+	label := peer.String()
+
+	for range echoRequestAttempts {
+		seq, reply := m.expectEcho(peer)
+		sent := time.Now()
+
+		gtpuEchoRequests.WithLabelValues(label).Inc()
+
+		if err := m.send(peer, buildEchoRequest(seq)); err != nil {
+			logger.UpfLog.Debug("failed to send GTP-U Echo Request", zap.String("peer", label), zap.Error(err))
+		} else {
+			timer := time.NewTimer(m.responseTimeout)
+
+			select {
+			case <-reply:
+				timer.Stop()
+				return time.Since(sent), true
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				m.forgetEcho(seq)
+
+				return 0, false
+			}
+		}
+
+		m.forgetEcho(seq)
+		gtpuEchoTimeouts.WithLabelValues(label).Inc()
+	}
+
+	return 0, false
+}
+
+func (m *gtpuPathMonitor) expectEcho(peer netip.Addr) (uint16, chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextSeq++
+	reply := make(chan struct{})
+	m.pending[m.nextSeq] = pendingEcho{peer: peer, reply: reply}
+
+	return m.nextSeq, reply
+}
+
+func (m *gtpuPathMonitor) forgetEcho(seq uint16) {
+	m.mu.Lock()
+	delete(m.pending, seq)
+	m.mu.Unlock()
+}
+
+// record applies a probe outcome. Only a change of path state raises a radio
+// event, and only the transition to failed releases sessions.
+func (m *gtpuPathMonitor) record(ctx context.Context, peer netip.Addr, rtt time.Duration, ok bool) {
+	m.mu.Lock()
+	wasFailed, supervised := m.failed[peer]
+
+	if supervised {
+		m.failed[peer] = !ok
+	}
+	m.mu.Unlock()
+
+	if !supervised {
+		return
+	}
+
+	label := peer.String()
+
+	if ok {
+		gtpuPathRTT.WithLabelValues(label).Set(rtt.Seconds())
+		gtpuPathUp.WithLabelValues(label).Set(1)
+
+		if wasFailed {
+			logger.UpfLog.Info("GTP-U path to access node restored", zap.String("peer", label))
+			logger.LogNetworkEvent(ctx, logger.GTPUNetworkProtocol, "PathRestored", logger.DirectionInbound,
+				m.localAddr(peer), label, "", nil)
+		}
+
+		return
+	}
+
+	gtpuPathUp.WithLabelValues(label).Set(0)
+
+	if wasFailed {
+		return
+	}
+
+	logger.UpfLog.Warn("GTP-U path to access node failed", zap.String("peer", label),
+		zap.Int("unanswered_echo_requests", echoRequestAttempts))
+	logger.LogNetworkEvent(ctx, logger.GTPUNetworkProtocol, "PathFailure", logger.DirectionOutbound,
+		m.localAddr(peer), label, "", nil)
+
+	if !m.releaseSessions {
+		return
+	}
+
+	seids := n3Peers(m.tunnels())[peer]
+	if len(seids) == 0 {
+		return
+	}
+
+	if err := m.smf.HandlePathFailureReport(ctx, &models.PathFailureReport{
+		RemoteAddress: peer,
+		SEIDs:         seids,
+	}); err != nil {
+		logger.UpfLog.Warn("failed to release sessions on a failed GTP-U path", zap.String("peer", label), zap.Error(err))
+	}
+}
+
+// startPathMonitor opens the GTP-U control sockets and starts echo
+// supervision. A socket that cannot be bound leaves the UPF without path
+// management rather than failing startup.
+func (u *UPF) startPathMonitor(ctx context.Context, n3IPv4, n3IPv6 netip.Addr, pm config.PathManagement) {
+	m := newGTPUPathMonitor(u.smf, u.se.DownlinkTunnels, pm)
+
+	conns, err := m.listenGTPU(n3IPv4, n3IPv6)
+	if err != nil {
+		logger.UpfLog.Warn("failed to open the GTP-U control socket, path management will be unavailable", zap.Error(err))
+		return
+	}
+
+	pctx, cancel := context.WithCancel(ctx)
+
+	u.pathCancel = cancel
+	u.pathConns = conns
+
+	for _, conn := range conns {
+		u.pathWG.Go(func() { m.serve(pctx, conn) })
+	}
+
+	if pm.EchoInterval > 0 {
+		u.pathWG.Go(func() { m.run(pctx) })
+	}
+}
+
+// stopPathMonitor stops echo supervision and closes the control sockets.
+func (u *UPF) stopPathMonitor() {
+	if u.pathCancel == nil {
+		return
+	}
+
+	u.pathCancel()
+
+	for _, conn := range u.pathConns {
+		_ = conn.Close()
+	}
+
+	u.pathWG.Wait()
+}
@@ -0,0 +1,246 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/config"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/engine"
+)
+
+type fakePathReports struct {
+	mu               sync.Mutex
+	errorIndications []*models.ErrorIndicationReport
+	pathFailures     []*models.PathFailureReport
+}
+
+func (f *fakePathReports) HandleDownlinkDataReport(context.Context, *models.DownlinkDataReport) error {
+	return nil
+}
+
+func (f *fakePathReports) HandleUsageReport(context.Context, *models.UsageReport) error {
+	return nil
+}
+
+func (f *fakePathReports) SendFlowReports(context.Context, []*models.FlowReportRequest) error {
+	return nil
+}
+
+func (f *fakePathReports) HandleErrorIndicationReport(_ context.Context, r *models.ErrorIndicationReport) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errorIndications = append(f.errorIndications, r)
+
+	return nil
+}
+
+func (f *fakePathReports) HandlePathFailureReport(_ context.Context, r *models.PathFailureReport) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pathFailures = append(f.pathFailures, r)
+
+	return nil
+}
+
+// buildErrorIndication carries a UDP Port extension header ahead of the IEs,
+// as radios commonly send it.
+func buildErrorIndication(teid uint32, peer netip.Addr) []byte {
+	ies := []byte{gtpuIETEIDDataI, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(ies[1:5], teid)
+
+	addr := peer.AsSlice()
+	ies = append(ies, gtpuIEPeerAddress, 0, byte(len(addr)))
+	ies = append(ies, addr...)
+
+	ext := []byte{1, 0x08, 0x68, 0} // UDP Port extension header: 4 octets, port 2152, no next header
+
+	b := []byte{0x36, gtpuMsgErrorIndication, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x40}
+	b = append(b, ext...)
+	b = append(b, ies...)
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)-8))
+
+	return b
+}
+
+func TestParseGTPU_EchoRequestRoundTrip(t *testing.T) {
+	msg, err := parseGTPU(buildEchoRequest(0xbeef))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if msg.msgType != gtpuMsgEchoRequest || msg.seq != 0xbeef || len(msg.ies) != 0 {
+		t.Fatalf("got %+v, want an Echo Request with sequence 0xbeef and no IEs", msg)
+	}
+}
+
+func TestParseErrorIndication_SkipsExtensionHeaders(t *testing.T) {
+	gnb := netip.MustParseAddr("10.0.0.1")
+
+	msg, err := parseGTPU(buildErrorIndication(0x01020304, gnb))
+	if err != nil {
+		t.Fatalf("parse header: %v", err)
+	}
+
+	teid, peer, err := parseErrorIndication(msg.ies)
+	if err != nil {
+		t.Fatalf("parse IEs: %v", err)
+	}
+
+	if teid != 0x01020304 || peer != gnb {
+		t.Fatalf("got TEID %#x peer %s, want 0x1020304 and %s", teid, peer, gnb)
+	}
+}
+
+func TestParseErrorIndication_RequiresTEID(t *testing.T) {
+	if _, _, err := parseErrorIndication([]byte{gtpuIERecovery, 0}); err == nil {
+		t.Fatal("expected an error for an Error Indication without TEID Data I")
+	}
+}
+
+func TestParseGTPU_RejectsTruncatedLength(t *testing.T) {
+	b := buildEchoRequest(1)
+	binary.BigEndian.PutUint16(b[2:4], 40)
+
+	if _, err := parseGTPU(b); err == nil {
+		t.Fatal("expected an error for a length past the end of the packet")
+	}
+}
+
+func newTestPathMonitor(smf *fakePathReports, tunnels map[uint64][]engine.DownlinkTunnel, release bool) *gtpuPathMonitor {
+	m := newGTPUPathMonitor(smf, func() map[uint64][]engine.DownlinkTunnel { return tunnels },
+		config.PathManagement{EchoInterval: time.Minute, ReleaseSessions: release})
+	m.responseTimeout = 10 * time.Millisecond
+
+	return m
+}
+
+func TestPathMonitor_FailureReleasesOnceAndRecovers(t *testing.T) {
+	gnb := netip.MustParseAddr("10.0.0.1")
+	tunnels := map[uint64][]engine.DownlinkTunnel{
+		1: {{TEID: 10, Peer: gnb}},
+		2: {{TEID: 11, Peer: gnb}},
+	}
+
+	smf := &fakePathReports{}
+	m := newTestPathMonitor(smf, tunnels, true)
+
+	var (
+		sendMu sync.Mutex
+		sent   int
+		answer bool
+	)
+
+	m.send = func(peer netip.Addr, b []byte) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+
+		sent++
+
+		if answer {
+			msg, _ := parseGTPU(b)
+			resp := buildEchoRequest(msg.seq)
+			resp[1] = gtpuMsgEchoResponse
+
+			go m.handlePacket(context.Background(), resp, peer)
+		}
+
+		return nil
+	}
+
+	ctx := context.Background()
+
+	m.probeAll(ctx)
+	m.probeAll(ctx)
+
+	if sent != 2*echoRequestAttempts {
+		t.Fatalf("sent %d Echo Requests, want %d per round", sent, echoRequestAttempts)
+	}
+
+	if len(smf.pathFailures) != 1 {
+		t.Fatalf("got %d path failure reports, want one for the transition", len(smf.pathFailures))
+	}
+
+	report := smf.pathFailures[0]
+	slices.Sort(report.SEIDs)
+
+	if report.RemoteAddress != gnb || !slices.Equal(report.SEIDs, []uint64{1, 2}) {
+		t.Fatalf("report = %+v, want both sessions toward %s", report, gnb)
+	}
+
+	sendMu.Lock()
+	answer = true
+	sendMu.Unlock()
+
+	m.probeAll(ctx)
+
+	if m.failed[gnb] {
+		t.Fatal("path still marked failed after an answered Echo Request")
+	}
+}
+
+func TestPathMonitor_FailureWithoutReleaseOnlyRecordsState(t *testing.T) {
+	gnb := netip.MustParseAddr("10.0.0.1")
+	smf := &fakePathReports{}
+	m := newTestPathMonitor(smf, map[uint64][]engine.DownlinkTunnel{1: {{TEID: 10, Peer: gnb}}}, false)
+	m.send = func(netip.Addr, []byte) error { return nil }
+
+	m.probeAll(context.Background())
+
+	if !m.failed[gnb] {
+		t.Fatal("path not marked failed after unanswered Echo Requests")
+	}
+
+	if len(smf.pathFailures) != 0 {
+		t.Fatalf("sessions released with release-sessions off: %+v", smf.pathFailures)
+	}
+}
+
+func TestPathMonitor_DropsPeersWithoutSessions(t *testing.T) {
+	gnb := netip.MustParseAddr("10.0.0.1")
+	tunnels := map[uint64][]engine.DownlinkTunnel{1: {{TEID: 10, Peer: gnb}}}
+
+	m := newTestPathMonitor(&fakePathReports{}, nil, false)
+	m.tunnels = func() map[uint64][]engine.DownlinkTunnel { return tunnels }
+	m.send = func(netip.Addr, []byte) error { return nil }
+
+	m.probeAll(context.Background())
+
+	tunnels = nil
+
+	m.probeAll(context.Background())
+
+	if _, ok := m.failed[gnb]; ok {
+		t.Fatal("peer still supervised after its last session left")
+	}
+}
+
+func TestPathMonitor_ErrorIndicationReportsMatchingSession(t *testing.T) {
+	gnb := netip.MustParseAddr("10.0.0.1")
+	smf := &fakePathReports{}
+	m := newTestPathMonitor(smf, map[uint64][]engine.DownlinkTunnel{
+		1: {{TEID: 10, Peer: gnb}},
+		2: {{TEID: 11, Peer: gnb}},
+	}, false)
+
+	m.handlePacket(context.Background(), buildErrorIndication(11, gnb), gnb)
+	m.handlePacket(context.Background(), buildErrorIndication(99, gnb), gnb)
+
+	if len(smf.errorIndications) != 1 {
+		t.Fatalf("got %d reports, want one for the known tunnel", len(smf.errorIndications))
+	}
+
+	if r := smf.errorIndications[0]; r.SEID != 2 || r.RemoteTEID != 11 || r.RemoteAddress != gnb {
+		t.Fatalf("report = %+v, want session 2 tunnel 11 toward %s", r, gnb)
+	}
+}
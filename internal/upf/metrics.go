@@ -4,12 +4,56 @@
 package upf
 
 import (
+	"net/netip"
+
 	"github.com/ellanetworks/core/internal/upf/ebpf"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 var flowReportsDropped prometheus.Counter
 
+// GTP-U path supervision, labelled by the access node's N3 address. They are
+// built up front so the path monitor can record before RegisterMetrics runs.
+var (
+	gtpuEchoRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "app_upf_gtpu_echo_requests_total",
+		Help: "GTP-U Echo Requests sent to each access node.",
+	}, []string{"peer"})
+
+	gtpuEchoTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "app_upf_gtpu_echo_timeouts_total",
+		Help: "GTP-U Echo Requests to each access node that went unanswered.",
+	}, []string{"peer"})
+
+	gtpuPathRTT = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "app_upf_gtpu_path_rtt_seconds",
+		Help: "Round-trip time of the last answered GTP-U Echo Request to each access node.",
+	}, []string{"peer"})
+
+	gtpuPathUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "app_upf_gtpu_path_up",
+		Help: "Whether the GTP-U path to each access node answers echo (1) or has failed (0).",
+	}, []string{"peer"})
+
+	gtpuErrorIndications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "app_upf_gtpu_error_indications_total",
+		Help: "GTP-U Error Indications received from each access node.",
+	}, []string{"peer"})
+)
+
+// forgetPathMetrics drops the series of a peer no session uses anymore.
+func forgetPathMetrics(peer netip.Addr) {
+	for _, vec := range []*prometheus.MetricVec{
+		gtpuEchoRequests.MetricVec,
+		gtpuEchoTimeouts.MetricVec,
+		gtpuPathRTT.MetricVec,
+		gtpuPathUp.MetricVec,
+		gtpuErrorIndications.MetricVec,
+	} {
+		vec.DeleteLabelValues(peer.String())
+	}
+}
+
 func RegisterMetrics() {
 	flowReportsDropped = prometheus.NewCounter(prometheus.CounterOpts{
 		Name: "app_flow_reports_dropped_total",
@@ -18,6 +62,8 @@ func RegisterMetrics() {
 
 	prometheus.MustRegister(flowReportsDropped)
 
+	prometheus.MustRegister(gtpuEchoRequests, gtpuEchoTimeouts, gtpuPathRTT, gtpuPathUp, gtpuErrorIndications)
+
 	upfUplinkBytes := prometheus.NewCounterFunc(prometheus.CounterOpts{
 		Name: "app_uplink_bytes",
 		Help: "The total number of uplink bytes going through the data plane (N3 -> N6). This value includes the Ethernet header.",
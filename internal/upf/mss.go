@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"errors"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+// DataNetworkTCPMSS is one data network's TCP MSS clamp as the datapath
+// needs it. A zero MSS is derived per packet from MTU.
+type DataNetworkTCPMSS struct {
+	Name  string
+	Pools []netip.Prefix
+	MTU   uint16
+	MSS   uint16
+}
+
+// UpdateTCPMSSClamp makes the datapath's TCP MSS clamps match clamps: the
+// pools of each listed data network are clamped, every other pool is left
+// alone.
+func (u *UPF) UpdateTCPMSSClamp(clamps []DataNetworkTCPMSS) error {
+	objs := u.se.BpfObjects
+	if !objs.HasTCPMSSClamp() {
+		return ebpf.ErrTCPMSSClampUnsupported
+	}
+
+	var errs []error
+
+	desired := make(map[netip.Prefix]ebpf.TCPMSSClamp)
+
+	for _, c := range clamps {
+		for _, pool := range c.Pools {
+			desired[pool.Masked()] = ebpf.TCPMSSClamp{MTU: c.MTU, MSS: c.MSS}
+		}
+	}
+
+	for pool, clamp := range desired {
+		if err := objs.PutTCPMSSClamp(pool, clamp); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	current, err := objs.ListTCPMSSClampPools()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, pool := range current {
+		if _, ok := desired[pool.Masked()]; ok {
+			continue
+		}
+
+		if err := objs.DeleteTCPMSSClamp(pool); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+// DataNetworkNAT is one data network's NAT pool as the datapath needs it.
+// Pools holds the data network's IPv4 UE pool; NAT is IPv4 only.
+type DataNetworkNAT struct {
+	Name       string
+	Pools      []netip.Prefix
+	Addresses  []netip.Addr
+	RoundRobin bool
+	PortMin    uint16
+	PortMax    uint16
+}
+
+// NATPortForward exposes UEPort on UEAddress as PublicPort on
+// PublicAddress. Protocol is an IP protocol number (TCP or UDP).
+type NATPortForward struct {
+	Protocol      uint8
+	PublicAddress netip.Addr
+	PublicPort    uint16
+	UEAddress     netip.Addr
+	UEPort        uint16
+}
+
+// UpdateNAT makes the datapath's NAT pools and port forwards match the
+// arguments. Entries that fail to apply are reported in the returned error
+// after the rest are applied. Flows already tracked keep their mapping
+// until they expire.
+func (u *UPF) UpdateNAT(pools []DataNetworkNAT, forwards []NATPortForward) error {
+	objs := u.se.BpfObjects
+	if !objs.HasNATPools() {
+		return ebpf.ErrNATPoolsUnsupported
+	}
+
+	var errs []error
+
+	desiredPools := make(map[netip.Prefix]struct{})
+	desiredAddrs := make(map[netip.Addr]struct{})
+
+	for _, p := range pools {
+		mapping := ebpf.NATMappingDeterministic
+		if p.RoundRobin {
+			mapping = ebpf.NATMappingRoundRobin
+		}
+
+		entry := ebpf.NATPool{
+			Addresses: p.Addresses,
+			Mapping:   mapping,
+			PortMin:   p.PortMin,
+			PortMax:   p.PortMax,
+		}
+
+		for _, pool := range p.Pools {
+			if !pool.Addr().Is4() {
+				continue
+			}
+
+			if err := objs.PutNATPool(pool, entry); err != nil {
+				errs = append(errs, fmt.Errorf("data network %s: %w", p.Name, err))
+				continue
+			}
+
+			desiredPools[pool.Masked()] = struct{}{}
+
+			for _, addr := range p.Addresses {
+				desiredAddrs[addr] = struct{}{}
+			}
+		}
+	}
+
+	desiredForwards := make(map[ebpf.NATForwardKey]ebpf.NATForward, len(forwards))
+	desiredOverrides := make(map[netip.Addr]struct{})
+
+	for _, f := range forwards {
+		key := ebpf.NATForwardKey{Address: f.PublicAddress, Port: f.PublicPort, Proto: f.Protocol}
+		fwd := ebpf.NATForward{UEAddress: f.UEAddress, UEPort: f.UEPort}
+
+		if err := objs.PutNATForward(key, fwd); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		desiredForwards[key] = fwd
+		desiredOverrides[f.UEAddress] = struct{}{}
+	}
+
+	currentForwards, err := objs.ListNATForwards()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for key := range currentForwards {
+		if _, ok := desiredForwards[key]; ok {
+			continue
+		}
+
+		if err := objs.DeleteNATForward(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	currentOverrides, err := objs.ListNATSourceOverrides()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, ue := range currentOverrides {
+		if _, ok := desiredOverrides[ue]; ok {
+			continue
+		}
+
+		if err := objs.DeleteNATSourceOverride(ue); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	currentPools, err := objs.ListNATPoolPrefixes()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, pool := range currentPools {
+		if _, ok := desiredPools[pool.Masked()]; ok {
+			continue
+		}
+
+		if err := objs.DeleteNATPool(pool); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	currentAddrs, err := objs.ListNATPoolAddresses()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, addr := range currentAddrs {
+		if _, ok := desiredAddrs[addr]; ok {
+			continue
+		}
+
+		// Forward-only public addresses keep the default port range.
+		if err := objs.DeleteNATPoolAddress(addr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
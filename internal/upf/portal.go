@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+const (
+	portDNS   = 53
+	portHTTP  = 80
+	portHTTPS = 443
+)
+
+// CaptivePortalRules is how many rules a policy in redirect mode places
+// ahead of its own uplink rules.
+const CaptivePortalRules = 4
+
+// captivePortalRules returns the rules that put a policy's subscribers behind
+// the portal at portal: DNS and web traffic is redirected to it, anything
+// else dropped. A subscriber whose redirect was lifted skips them all. The
+// portal answers the DNS queries itself; the built-in resolver is not in the
+// path.
+func captivePortalRules(portal string) []models.FilterRule {
+	return []models.FilterRule{
+		{PortLow: portDNS, PortHigh: portDNS, Action: models.Portal, Portal: portal},
+		{Protocol: protoTCP, PortLow: portHTTP, PortHigh: portHTTP, Action: models.Portal, Portal: portal},
+		{Protocol: protoTCP, PortLow: portHTTPS, PortHigh: portHTTPS, Action: models.Portal, Portal: portal},
+		{Action: models.PortalDrop},
+	}
+}
+
+// withCaptivePortal places the captive portal rules ahead of rules. The
+// datapath holds ebpf.MaxRulesPerFilter rules per list, which the API keeps
+// a policy in redirect mode within; rules that would not fit are an error,
+// not cut short.
+func withCaptivePortal(rules []models.FilterRule, portal string) ([]models.FilterRule, error) {
+	if len(rules)+CaptivePortalRules > ebpf.MaxRulesPerFilter {
+		return nil, fmt.Errorf("%d uplink rules behind the captive portal exceed the %d a filter list holds",
+			len(rules), ebpf.MaxRulesPerFilter-CaptivePortalRules)
+	}
+
+	return append(captivePortalRules(portal), rules...), nil
+}
+
+// UpdatePortalLifted makes the set of UEs let past their captive portal
+// match ues.
+func (u *UPF) UpdatePortalLifted(ues []netip.Addr) error {
+	objs := u.se.BpfObjects
+	if !objs.HasCaptivePortal() {
+		return ebpf.ErrCaptivePortalUnsupported
+	}
+
+	var errs []error
+
+	desired := make(map[netip.Addr]bool, len(ues))
+
+	for _, ue := range ues {
+		if err := objs.PutPortalLifted(ue); err != nil {
+			errs = append(errs, err)
+		}
+
+		desired[portalKeyAddr(ue)] = true
+	}
+
+	current, err := objs.ListPortalLifted()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, ue := range current {
+		if desired[portalKeyAddr(ue)] {
+			continue
+		}
+
+		if err := objs.DeletePortalLifted(ue); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// portalKeyAddr is ue as portal_lifted keys it: an IPv6 UE by its /64.
+func portalKeyAddr(ue netip.Addr) netip.Addr {
+	ue = ue.Unmap()
+	if ue.Is4() {
+		return ue
+	}
+
+	return netip.PrefixFrom(ue, 64).Masked().Addr()
+}
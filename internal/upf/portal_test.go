@@ -0,0 +1,32 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+func TestWithCaptivePortalStaysWithinFilterList(t *testing.T) {
+	rules := make([]models.FilterRule, ebpf.MaxRulesPerFilter-CaptivePortalRules)
+
+	got, err := withCaptivePortal(rules, "192.0.2.10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != ebpf.MaxRulesPerFilter {
+		t.Fatalf("len = %d, want %d", len(got), ebpf.MaxRulesPerFilter)
+	}
+
+	if got[0].Action != models.Portal || got[CaptivePortalRules-1].Action != models.PortalDrop {
+		t.Fatalf("expected the portal rules first, got %+v", got[:CaptivePortalRules])
+	}
+
+	if _, err := withCaptivePortal(append(rules, models.FilterRule{}), "192.0.2.10"); err == nil {
+		t.Fatal("expected an error for a rule past the filter list, got nil")
+	}
+}
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"fmt"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+// qosSessionFilters returns the filters a session moves to while it carries
+// the dedicated QoS flow of qs: the flow's rule, capped at the flow's maximum
+// bit rate, ahead of base, the filters of the session's policy, which still
+// decide what passes. A policy already holding ebpf.MaxRulesPerFilter rules
+// leaves no room for it.
+func qosSessionFilters(qs db.QosSession, base filterSnapshot) (filterSnapshot, error) {
+	if len(base.uplink) >= ebpf.MaxRulesPerFilter || len(base.downlink) >= ebpf.MaxRulesPerFilter {
+		return filterSnapshot{}, fmt.Errorf("policy already holds %d rules in a direction", ebpf.MaxRulesPerFilter)
+	}
+
+	mbrUplink, err := models.ParseBitRate(qs.MBRUplink)
+	if err != nil {
+		return filterSnapshot{}, fmt.Errorf("uplink maximum bit rate: %w", err)
+	}
+
+	mbrDownlink, err := models.ParseBitRate(qs.MBRDownlink)
+	if err != nil {
+		return filterSnapshot{}, fmt.Errorf("downlink maximum bit rate: %w", err)
+	}
+
+	rule := models.FilterRule{
+		RemotePrefix: qs.RemotePrefix,
+		Protocol:     int32(qs.Protocol),
+		PortLow:      int32(qs.PortLow),
+		PortHigh:     int32(qs.PortHigh),
+		Action:       models.QoSFlow,
+		QFI:          models.DedicatedQFI,
+	}
+
+	uplink, downlink := rule, rule
+	uplink.RateLimit = mbrUplink
+	downlink.RateLimit = mbrDownlink
+
+	return filterSnapshot{
+		uplink:   append([]models.FilterRule{uplink}, base.uplink...),
+		downlink: append([]models.FilterRule{downlink}, base.downlink...),
+	}, nil
+}
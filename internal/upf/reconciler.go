@@ -9,12 +9,14 @@ import (
 	"fmt"
 	"net/netip"
 	"reflect"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/ellanetworks/core/internal/db"
 	"github.com/ellanetworks/core/internal/logger"
 	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
 	"go.uber.org/zap"
 )
 
@@ -36,6 +38,21 @@ type SettingsStore interface {
 	GetN3Settings(ctx context.Context) (*db.N3Settings, error)
 	ListPoliciesPage(ctx context.Context, page int, perPage int) ([]db.Policy, int, error)
 	ListRulesForPolicy(ctx context.Context, policyID string) ([]*db.NetworkRule, error)
+	ListNetworkRuleFQDNsByPolicy(ctx context.Context, policyID string) (map[string]db.NetworkRuleFQDN, error)
+	ListNetworkRuleRateLimitsByPolicy(ctx context.Context, policyID string) (map[string]db.NetworkRuleRateLimit, error)
+	ListNetworkRuleBreakoutsByPolicy(ctx context.Context, policyID string) (map[string]db.NetworkRuleBreakout, error)
+	ListNetworkRuleRatingsByPolicy(ctx context.Context, policyID string) (map[string]db.NetworkRuleRating, error)
+	ListNetworkRuleSchedulesByPolicy(ctx context.Context, policyID string) (map[string]db.NetworkRuleSchedule, error)
+	ActiveSchedules(ctx context.Context, now time.Time) (map[string]bool, error)
+	ListAllDataNetworks(ctx context.Context) ([]db.DataNetwork, error)
+	ListAllDataNetworkEgress(ctx context.Context) ([]db.DataNetworkEgress, error)
+	ListAllDataNetworkNAT(ctx context.Context) ([]db.DataNetworkNAT, error)
+	ListAllDataNetworkTCPMSS(ctx context.Context) ([]db.DataNetworkTCPMSS, error)
+	ListAllNATPortForwards(ctx context.Context) ([]db.NATPortForward, error)
+	ListActiveLeases(ctx context.Context) ([]db.IPLease, error)
+	ListAllPolicyCaptivePortals(ctx context.Context) ([]db.PolicyCaptivePortal, error)
+	ListAllCaptivePortalLifts(ctx context.Context) ([]db.CaptivePortalLift, error)
+	ListQosSessions(ctx context.Context) ([]db.QosSession, error)
 }
 
 // Updater is the narrow view the reconciler needs over the UPF runtime.
@@ -46,11 +63,15 @@ type Updater interface {
 	ReloadLocalSwitch(enabled bool) error
 	UpdateAdvertisedN3Address(addr netip.Addr)
 	UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error
+	UpdateDataNetworkEgress(egress []DataNetworkEgress) error
+	UpdateNAT(pools []DataNetworkNAT, forwards []NATPortForward) error
+	UpdateTCPMSSClamp(clamps []DataNetworkTCPMSS) error
+	UpdatePortalLifted(ues []netip.Addr) error
 }
 
 // SettingsReconciler drives this node's UPF runtime from replicated DB
 // settings: NAT toggle, flow accounting toggle, advertised N3 address,
-// and per-policy SDF filters. Each tick reads the desired state from
+// per-policy SDF filters and captive portals, per-data-network egress, NAT pools and TCP MSS clamps. Each tick reads the desired state from
 // the DB and applies it to the local UPF only when it differs from the
 // last-applied snapshot — the underlying Reload* and UpdateFilters
 // calls re-attach XDP / re-write eBPF maps, so calling them
@@ -61,17 +82,32 @@ type SettingsReconciler struct {
 	changefeed   *db.Changefeed
 	fallbackN3IP netip.Addr
 	backstop     time.Duration
+	// now is the clock scheduled rules are evaluated against.
+	now func() time.Time
 
 	mu     sync.Mutex
 	cancel context.CancelFunc
 	done   chan struct{}
 
+	// passMu serializes passes, which the loop and callers forcing
+	// convergence may start at once.
+	passMu sync.Mutex
+
 	stateMu               sync.Mutex
 	appliedNAT            *bool
 	appliedFlowAccounting *bool
 	appliedLocalSwitch    *bool
 	appliedN3Address      netip.Addr
 	appliedFilters        map[string]filterSnapshot
+	appliedEgress         []DataNetworkEgress
+	appliedNATPools       *natSnapshot
+	appliedTCPMSS         []DataNetworkTCPMSS
+	appliedPortalLifted   []netip.Addr
+}
+
+type natSnapshot struct {
+	pools    []DataNetworkNAT
+	forwards []NATPortForward
 }
 
 type filterSnapshot struct {
@@ -90,6 +126,7 @@ func NewSettingsReconciler(updater Updater, store SettingsStore, changefeed *db.
 		changefeed:     changefeed,
 		fallbackN3IP:   fallbackN3IP,
 		backstop:       upfReconcileBackstop,
+		now:            time.Now,
 		appliedFilters: make(map[string]filterSnapshot),
 	}
 }
@@ -145,6 +182,14 @@ func (r *SettingsReconciler) loop(ctx context.Context, done chan struct{}) {
 			db.TopicN3Settings,
 			db.TopicPolicies,
 			db.TopicNetworkRules,
+			db.TopicSchedules,
+			db.TopicDataNetworks,
+			db.TopicDataNetworkEgress,
+			db.TopicDataNetworkNAT,
+			db.TopicDataNetworkTCPMSS,
+			db.TopicIPLeases,
+			db.TopicCaptivePortals,
+			db.TopicQosSessions,
 		)
 		defer sub.Close()
 
@@ -177,6 +222,9 @@ func (r *SettingsReconciler) loop(ctx context.Context, done chan struct{}) {
 // Reconcile performs one reconcile pass. Exposed for tests and for
 // callers that want to force convergence after a known change.
 func (r *SettingsReconciler) Reconcile(ctx context.Context) error {
+	r.passMu.Lock()
+	defer r.passMu.Unlock()
+
 	if err := r.reconcileNAT(ctx); err != nil {
 		return fmt.Errorf("nat: %w", err)
 	}
@@ -197,6 +245,22 @@ func (r *SettingsReconciler) Reconcile(ctx context.Context) error {
 		return fmt.Errorf("policy filters: %w", err)
 	}
 
+	if err := r.reconcileDataNetworkEgress(ctx); err != nil {
+		return fmt.Errorf("data network egress: %w", err)
+	}
+
+	if err := r.reconcileDataNetworkNAT(ctx); err != nil {
+		return fmt.Errorf("data network nat: %w", err)
+	}
+
+	if err := r.reconcileDataNetworkTCPMSS(ctx); err != nil {
+		return fmt.Errorf("data network tcp mss: %w", err)
+	}
+
+	if err := r.reconcilePortalLifted(ctx); err != nil {
+		return fmt.Errorf("captive portal lifts: %w", err)
+	}
+
 	return nil
 }
 
@@ -335,6 +399,27 @@ func (r *SettingsReconciler) reconcileFilters(ctx context.Context) error {
 		return fmt.Errorf("list policies: %w", err)
 	}
 
+	// A schedule that no longer parses is left out of active, so its rules
+	// stay off rather than failing every other policy's filters.
+	active, err := r.store.ActiveSchedules(ctx, r.now())
+	if err != nil {
+		if active == nil {
+			return fmt.Errorf("evaluate schedules: %w", err)
+		}
+
+		logger.UpfLog.Warn("some schedules could not be evaluated", zap.Error(err))
+	}
+
+	portals, err := r.store.ListAllPolicyCaptivePortals(ctx)
+	if err != nil {
+		return fmt.Errorf("list captive portals: %w", err)
+	}
+
+	portalByPolicy := make(map[string]string, len(portals))
+	for _, p := range portals {
+		portalByPolicy[p.PolicyID] = p.PortalAddress
+	}
+
 	desired := make(map[string]filterSnapshot, len(policies))
 
 	for _, p := range policies {
@@ -343,10 +428,74 @@ func (r *SettingsReconciler) reconcileFilters(ctx context.Context) error {
 			return fmt.Errorf("list rules for policy %s: %w", p.ID, err)
 		}
 
+		fqdns, err := r.store.ListNetworkRuleFQDNsByPolicy(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("list FQDN rules for policy %s: %w", p.ID, err)
+		}
+
+		limits, err := r.store.ListNetworkRuleRateLimitsByPolicy(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("list rate-limited rules for policy %s: %w", p.ID, err)
+		}
+
+		breakouts, err := r.store.ListNetworkRuleBreakoutsByPolicy(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("list breakout rules for policy %s: %w", p.ID, err)
+		}
+
+		ratings, err := r.store.ListNetworkRuleRatingsByPolicy(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("list rated rules for policy %s: %w", p.ID, err)
+		}
+
+		schedules, err := r.store.ListNetworkRuleSchedulesByPolicy(ctx, p.ID)
+		if err != nil {
+			return fmt.Errorf("list scheduled rules for policy %s: %w", p.ID, err)
+		}
+
+		rules = scheduledRulesActive(rules, schedules, active)
+
+		uplink := networkRulesToFilterRules(rules, fqdns, limits, breakouts, ratings, directionUplinkString)
+		if portal, ok := portalByPolicy[p.ID]; ok {
+			withPortal, err := withCaptivePortal(uplink, portal)
+			if err != nil {
+				// Leave its own rules out rather than the portal.
+				logger.UpfLog.Warn("captive portal policy's uplink rules left out", zap.String("policy", p.ID), zap.Error(err))
+
+				withPortal = captivePortalRules(portal)
+			}
+
+			uplink = withPortal
+		}
+
 		desired[p.ID] = filterSnapshot{
-			uplink:   networkRulesToFilterRules(rules, directionUplinkString),
-			downlink: networkRulesToFilterRules(rules, directionDownlinkString),
+			uplink:   uplink,
+			downlink: networkRulesToFilterRules(rules, fqdns, limits, breakouts, ratings, directionDownlinkString),
+		}
+	}
+
+	qosSessions, err := r.store.ListQosSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("list QoS sessions: %w", err)
+	}
+
+	for _, qs := range qosSessions {
+		if qs.Status != db.QosSessionInstalling && qs.Status != db.QosSessionActive || qs.PolicyID == nil {
+			continue
+		}
+
+		base, ok := desired[*qs.PolicyID]
+		if !ok {
+			continue
+		}
+
+		snap, err := qosSessionFilters(qs, base)
+		if err != nil {
+			logger.UpfLog.Warn("QoS session filters left out", zap.String("qosSession", qs.ID), zap.Error(err))
+			continue
 		}
+
+		desired[models.QosSessionFilterID(qs.ID)] = snap
 	}
 
 	r.stateMu.Lock()
@@ -412,7 +561,297 @@ func (r *SettingsReconciler) reconcileFilters(ctx context.Context) error {
 	return errors.Join(errs...)
 }
 
-func networkRulesToFilterRules(rules []*db.NetworkRule, direction string) []models.FilterRule {
+// FiltersApplied reports whether the filters installed under id reached the
+// data plane in both directions on the last pass. Only meaningful for IDs
+// whose filters are never empty, such as those of a QoS session.
+func (r *SettingsReconciler) FiltersApplied(id string) bool {
+	r.stateMu.Lock()
+	defer r.stateMu.Unlock()
+
+	snap, ok := r.appliedFilters[id]
+
+	return ok && len(snap.uplink) > 0 && len(snap.downlink) > 0
+}
+
+func (r *SettingsReconciler) reconcileDataNetworkEgress(ctx context.Context) error {
+	rows, err := r.store.ListAllDataNetworkEgress(ctx)
+	if err != nil {
+		return fmt.Errorf("list data network egress: %w", err)
+	}
+
+	dataNetworks, err := r.store.ListAllDataNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("list data networks: %w", err)
+	}
+
+	byID := make(map[string]db.DataNetwork, len(dataNetworks))
+	for _, dn := range dataNetworks {
+		byID[dn.ID] = dn
+	}
+
+	desired := make([]DataNetworkEgress, 0, len(rows))
+
+	for _, row := range rows {
+		dn, ok := byID[row.DataNetworkID]
+		if !ok {
+			continue
+		}
+
+		e := DataNetworkEgress{
+			Name:      dn.Name,
+			Interface: row.InterfaceName,
+			VlanID:    row.VlanID,
+			Table:     row.RoutingTable,
+		}
+
+		for _, pool := range []string{dn.IPv4Pool, dn.IPv6Pool} {
+			if pool == "" {
+				continue
+			}
+
+			if prefix, err := netip.ParsePrefix(pool); err == nil {
+				e.Pools = append(e.Pools, prefix.Masked())
+			}
+		}
+
+		desired = append(desired, e)
+	}
+
+	r.stateMu.Lock()
+	applied := r.appliedEgress
+	r.stateMu.Unlock()
+
+	if applied != nil && reflect.DeepEqual(applied, desired) {
+		return nil
+	}
+
+	err = r.updater.UpdateDataNetworkEgress(desired)
+	if errors.Is(err, ebpf.ErrDataNetworkEgressUnsupported) {
+		// The API refuses egress on such a datapath, so only rows
+		// written by a node with a newer one get here. Nothing to retry
+		// until the datapath is rebuilt; record the snapshot so the
+		// error is logged once per change.
+		if len(desired) > 0 {
+			logger.UpfLog.Error("data network egress is configured but the datapath cannot apply it, traffic keeps using n6", zap.Error(err))
+		}
+
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.stateMu.Lock()
+	r.appliedEgress = desired
+	r.stateMu.Unlock()
+
+	logger.UpfLog.Info("applied data network egress", zap.Int("data_networks", len(desired)))
+
+	return nil
+}
+
+// natProtocols maps the stored port forward protocol to its IP protocol
+// number.
+var natProtocols = map[string]uint8{"tcp": 6, "udp": 17}
+
+func (r *SettingsReconciler) reconcileDataNetworkNAT(ctx context.Context) error {
+	rows, err := r.store.ListAllDataNetworkNAT(ctx)
+	if err != nil {
+		return fmt.Errorf("list data network nat: %w", err)
+	}
+
+	forwardRows, err := r.store.ListAllNATPortForwards(ctx)
+	if err != nil {
+		return fmt.Errorf("list port forwards: %w", err)
+	}
+
+	dataNetworks, err := r.store.ListAllDataNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("list data networks: %w", err)
+	}
+
+	byID := make(map[string]db.DataNetwork, len(dataNetworks))
+	for _, dn := range dataNetworks {
+		byID[dn.ID] = dn
+	}
+
+	desired := natSnapshot{
+		pools:    make([]DataNetworkNAT, 0, len(rows)),
+		forwards: make([]NATPortForward, 0, len(forwardRows)),
+	}
+
+	for _, row := range rows {
+		dn, ok := byID[row.DataNetworkID]
+		if !ok || dn.IPv4Pool == "" {
+			continue
+		}
+
+		pool, err := netip.ParsePrefix(dn.IPv4Pool)
+		if err != nil {
+			continue
+		}
+
+		n := DataNetworkNAT{
+			Name:       dn.Name,
+			Pools:      []netip.Prefix{pool.Masked()},
+			RoundRobin: row.Mapping == db.NATMappingRoundRobin,
+			PortMin:    uint16(row.PortMin),
+			PortMax:    uint16(row.PortMax),
+		}
+
+		for _, s := range row.AddressList() {
+			if addr, err := netip.ParseAddr(s); err == nil && addr.Is4() {
+				n.Addresses = append(n.Addresses, addr)
+			}
+		}
+
+		if len(n.Addresses) == 0 {
+			continue
+		}
+
+		desired.pools = append(desired.pools, n)
+	}
+
+	if len(forwardRows) > 0 {
+		desired.forwards, err = r.resolvePortForwards(ctx, forwardRows)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.stateMu.Lock()
+	applied := r.appliedNATPools
+	r.stateMu.Unlock()
+
+	if applied != nil && reflect.DeepEqual(*applied, desired) {
+		return nil
+	}
+
+	err = r.updater.UpdateNAT(desired.pools, desired.forwards)
+	if errors.Is(err, ebpf.ErrNATPoolsUnsupported) {
+		// Same as egress: the API refuses them on such a datapath, and
+		// the snapshot is recorded so the error is logged once per change.
+		if len(desired.pools) > 0 || len(desired.forwards) > 0 {
+			logger.UpfLog.Error("NAT pools or port forwards are configured but the datapath cannot apply them, UEs keep masquerading on the egress address", zap.Error(err))
+		}
+
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.stateMu.Lock()
+	r.appliedNATPools = &desired
+	r.stateMu.Unlock()
+
+	logger.UpfLog.Info("applied NAT pools", zap.Int("pools", len(desired.pools)), zap.Int("port_forwards", len(desired.forwards)))
+
+	return nil
+}
+
+// resolvePortForwards turns stored forwards into datapath entries. A forward
+// by IMSI follows the subscriber's active IPv4 lease in the forward's data
+// network and is skipped while the subscriber has none. The datapath sources
+// all of a UE's traffic from its forwards' public address, so a forward that
+// would give a UE a second one is skipped.
+func (r *SettingsReconciler) resolvePortForwards(ctx context.Context, rows []db.NATPortForward) ([]NATPortForward, error) {
+	leases, err := r.store.ListActiveLeases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list active leases: %w", err)
+	}
+
+	type leaseKey struct {
+		poolID string
+		imsi   string
+	}
+
+	ueByIMSI := make(map[leaseKey]netip.Addr, len(leases))
+
+	for i := range leases {
+		addr := leases[i].Address()
+		if !addr.Is4() {
+			continue
+		}
+
+		ueByIMSI[leaseKey{poolID: leases[i].PoolID, imsi: leases[i].IMSI}] = addr
+	}
+
+	publicByUE := make(map[netip.Addr]netip.Addr)
+	forwards := make([]NATPortForward, 0, len(rows))
+
+	for _, row := range rows {
+		proto, ok := natProtocols[row.Protocol]
+		if !ok {
+			continue
+		}
+
+		public, err := netip.ParseAddr(row.PublicAddress)
+		if err != nil || !public.Is4() {
+			continue
+		}
+
+		var ue netip.Addr
+
+		if row.UEAddress != "" {
+			ue, err = netip.ParseAddr(row.UEAddress)
+			if err != nil || !ue.Is4() {
+				continue
+			}
+		} else {
+			ue, ok = ueByIMSI[leaseKey{poolID: row.DataNetworkID, imsi: row.IMSI}]
+			if !ok {
+				continue
+			}
+		}
+
+		if prev, ok := publicByUE[ue]; ok && prev != public {
+			logger.UpfLog.Warn("skipping port forward: UE already forwarded from another public address",
+				zap.String("id", row.ID),
+				zap.String("ue", ue.String()),
+				zap.String("public_address", public.String()),
+				zap.String("in_use", prev.String()))
+
+			continue
+		}
+
+		publicByUE[ue] = public
+
+		forwards = append(forwards, NATPortForward{
+			Protocol:      proto,
+			PublicAddress: public,
+			PublicPort:    uint16(row.PublicPort),
+			UEAddress:     ue,
+			UEPort:        uint16(row.UEPort),
+		})
+	}
+
+	return forwards, nil
+}
+
+// scheduledRulesActive drops the rules whose schedule is outside a window.
+// Later rules keep their order, so matching traffic falls through to them.
+func scheduledRulesActive(rules []*db.NetworkRule, schedules map[string]db.NetworkRuleSchedule, active map[string]bool) []*db.NetworkRule {
+	if len(schedules) == 0 {
+		return rules
+	}
+
+	out := make([]*db.NetworkRule, 0, len(rules))
+
+	for _, rule := range rules {
+		if s, ok := schedules[rule.ID]; ok && !active[s.ScheduleID] {
+			continue
+		}
+
+		out = append(out, rule)
+	}
+
+	return out
+}
+
+func networkRulesToFilterRules(rules []*db.NetworkRule, fqdns map[string]db.NetworkRuleFQDN, limits map[string]db.NetworkRuleRateLimit, breakouts map[string]db.NetworkRuleBreakout, ratings map[string]db.NetworkRuleRating, direction string) []models.FilterRule {
 	out := make([]models.FilterRule, 0, len(rules))
 
 	for _, rule := range rules {
@@ -431,8 +870,169 @@ func networkRulesToFilterRules(rules []*db.NetworkRule, direction string) []mode
 			fr.RemotePrefix = *rule.RemotePrefix
 		}
 
+		if f, ok := fqdns[rule.ID]; ok {
+			fr.RemotePrefix = ""
+			fr.FQDN = f.FQDN
+			fr.MatchSNI = f.MatchSNI
+		}
+
+		// A rate the API accepted always parses; a zero one leaves the
+		// datapath unlimited, which is what an allow would do.
+		if l, ok := limits[rule.ID]; ok && fr.Action == models.RateLimit {
+			fr.RateLimit, _ = models.ParseBitRate(l.Bitrate)
+			fr.RateLimitShared = l.Shared
+		}
+
+		if b, ok := breakouts[rule.ID]; ok && fr.Action == models.Breakout {
+			fr.Breakout = models.BreakoutEgress{
+				Interface: b.InterfaceName,
+				VlanID:    b.VlanID,
+				Table:     b.RoutingTable,
+			}
+		}
+
+		if rt, ok := ratings[rule.ID]; ok && fr.Action != models.Deny {
+			fr.RatingGroup = uint32(rt.RatingGroup)
+			fr.ZeroRated = rt.ZeroRated
+		}
+
 		out = append(out, fr)
 	}
 
 	return out
 }
+
+func (r *SettingsReconciler) reconcileDataNetworkTCPMSS(ctx context.Context) error {
+	rows, err := r.store.ListAllDataNetworkTCPMSS(ctx)
+	if err != nil {
+		return fmt.Errorf("list data network tcp mss: %w", err)
+	}
+
+	dataNetworks, err := r.store.ListAllDataNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("list data networks: %w", err)
+	}
+
+	byID := make(map[string]db.DataNetwork, len(dataNetworks))
+	for _, dn := range dataNetworks {
+		byID[dn.ID] = dn
+	}
+
+	desired := make([]DataNetworkTCPMSS, 0, len(rows))
+
+	for _, row := range rows {
+		dn, ok := byID[row.DataNetworkID]
+		if !ok {
+			continue
+		}
+
+		c := DataNetworkTCPMSS{
+			Name: dn.Name,
+			MTU:  uint16(dn.MTU),
+			MSS:  uint16(row.MSS),
+		}
+
+		for _, pool := range []string{dn.IPv4Pool, dn.IPv6Pool} {
+			if pool == "" {
+				continue
+			}
+
+			if prefix, err := netip.ParsePrefix(pool); err == nil {
+				c.Pools = append(c.Pools, prefix.Masked())
+			}
+		}
+
+		desired = append(desired, c)
+	}
+
+	r.stateMu.Lock()
+	applied := r.appliedTCPMSS
+	r.stateMu.Unlock()
+
+	if applied != nil && reflect.DeepEqual(applied, desired) {
+		return nil
+	}
+
+	err = r.updater.UpdateTCPMSSClamp(desired)
+	if errors.Is(err, ebpf.ErrTCPMSSClampUnsupported) {
+		// Same as egress: the API refuses it on such a datapath, and the
+		// snapshot is recorded so the error is logged once per change.
+		if len(desired) > 0 {
+			logger.UpfLog.Error("TCP MSS clamping is configured but the datapath cannot apply it, handshakes pass unchanged", zap.Error(err))
+		}
+
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.stateMu.Lock()
+	r.appliedTCPMSS = desired
+	r.stateMu.Unlock()
+
+	logger.UpfLog.Info("applied TCP MSS clamps", zap.Int("data_networks", len(desired)))
+
+	return nil
+}
+
+// reconcilePortalLifted lets the addresses of subscribers whose captive
+// portal was lifted past it.
+func (r *SettingsReconciler) reconcilePortalLifted(ctx context.Context) error {
+	lifts, err := r.store.ListAllCaptivePortalLifts(ctx)
+	if err != nil {
+		return fmt.Errorf("list captive portal lifts: %w", err)
+	}
+
+	desired := []netip.Addr{}
+
+	if len(lifts) > 0 {
+		lifted := make(map[string]bool, len(lifts))
+		for _, l := range lifts {
+			lifted[l.IMSI] = true
+		}
+
+		leases, err := r.store.ListActiveLeases(ctx)
+		if err != nil {
+			return fmt.Errorf("list active leases: %w", err)
+		}
+
+		for _, l := range leases {
+			if !lifted[l.IMSI] {
+				continue
+			}
+
+			if addr := l.Address(); addr.IsValid() {
+				desired = append(desired, addr)
+			}
+		}
+
+		slices.SortFunc(desired, netip.Addr.Compare)
+	}
+
+	r.stateMu.Lock()
+	applied := r.appliedPortalLifted
+	r.stateMu.Unlock()
+
+	if applied != nil && slices.Equal(applied, desired) {
+		return nil
+	}
+
+	err = r.updater.UpdatePortalLifted(desired)
+	if errors.Is(err, ebpf.ErrCaptivePortalUnsupported) {
+		// Such a datapath passes portal rules as allows, so there is
+		// nothing to lift.
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.stateMu.Lock()
+	r.appliedPortalLifted = desired
+	r.stateMu.Unlock()
+
+	return nil
+}
@@ -14,17 +14,33 @@ import (
 
 	"github.com/ellanetworks/core/internal/db"
 	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
 )
 
 type fakeStore struct {
-	mu              sync.Mutex
-	natEnabled      bool
-	flowAccounting  bool
-	localSwitch     bool
-	n3External      string
-	n3GetErr        error
-	policies        []db.Policy
-	rulesByPolicyID map[string][]*db.NetworkRule
+	mu               sync.Mutex
+	natEnabled       bool
+	flowAccounting   bool
+	localSwitch      bool
+	n3External       string
+	n3GetErr         error
+	policies         []db.Policy
+	rulesByPolicyID  map[string][]*db.NetworkRule
+	fqdnsByPolicyID  map[string]map[string]db.NetworkRuleFQDN
+	limitsByPolicyID map[string]map[string]db.NetworkRuleRateLimit
+	breakByPolicyID  map[string]map[string]db.NetworkRuleBreakout
+	ratesByPolicyID  map[string]map[string]db.NetworkRuleRating
+	schedsByPolicyID map[string]map[string]db.NetworkRuleSchedule
+	activeSchedules  map[string]bool
+	dataNetworks     []db.DataNetwork
+	egress           []db.DataNetworkEgress
+	nat              []db.DataNetworkNAT
+	portForwards     []db.NATPortForward
+	tcpMSS           []db.DataNetworkTCPMSS
+	leases           []db.IPLease
+	portals          []db.PolicyCaptivePortal
+	portalLifts      []db.CaptivePortalLift
+	qosSessions      []db.QosSession
 }
 
 func (f *fakeStore) IsNATEnabled(_ context.Context) (bool, error) {
@@ -80,6 +96,168 @@ func (f *fakeStore) ListRulesForPolicy(_ context.Context, policyID string) ([]*d
 	return out, nil
 }
 
+func (f *fakeStore) ListNetworkRuleFQDNsByPolicy(_ context.Context, policyID string) (map[string]db.NetworkRuleFQDN, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make(map[string]db.NetworkRuleFQDN, len(f.fqdnsByPolicyID[policyID]))
+	for id, row := range f.fqdnsByPolicyID[policyID] {
+		out[id] = row
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) ListNetworkRuleRateLimitsByPolicy(_ context.Context, policyID string) (map[string]db.NetworkRuleRateLimit, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make(map[string]db.NetworkRuleRateLimit, len(f.limitsByPolicyID[policyID]))
+	for id, row := range f.limitsByPolicyID[policyID] {
+		out[id] = row
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) ListNetworkRuleBreakoutsByPolicy(_ context.Context, policyID string) (map[string]db.NetworkRuleBreakout, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make(map[string]db.NetworkRuleBreakout, len(f.breakByPolicyID[policyID]))
+	for id, row := range f.breakByPolicyID[policyID] {
+		out[id] = row
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) ListNetworkRuleRatingsByPolicy(_ context.Context, policyID string) (map[string]db.NetworkRuleRating, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make(map[string]db.NetworkRuleRating, len(f.ratesByPolicyID[policyID]))
+	for id, row := range f.ratesByPolicyID[policyID] {
+		out[id] = row
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) ListNetworkRuleSchedulesByPolicy(_ context.Context, policyID string) (map[string]db.NetworkRuleSchedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make(map[string]db.NetworkRuleSchedule, len(f.schedsByPolicyID[policyID]))
+	for id, row := range f.schedsByPolicyID[policyID] {
+		out[id] = row
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) ActiveSchedules(_ context.Context, _ time.Time) (map[string]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make(map[string]bool, len(f.activeSchedules))
+	for id, on := range f.activeSchedules {
+		out[id] = on
+	}
+
+	return out, nil
+}
+
+func (f *fakeStore) ListAllDataNetworks(_ context.Context) ([]db.DataNetwork, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.DataNetwork, len(f.dataNetworks))
+	copy(out, f.dataNetworks)
+
+	return out, nil
+}
+
+func (f *fakeStore) ListAllDataNetworkEgress(_ context.Context) ([]db.DataNetworkEgress, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.DataNetworkEgress, len(f.egress))
+	copy(out, f.egress)
+
+	return out, nil
+}
+
+func (f *fakeStore) ListAllDataNetworkNAT(_ context.Context) ([]db.DataNetworkNAT, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.DataNetworkNAT, len(f.nat))
+	copy(out, f.nat)
+
+	return out, nil
+}
+
+func (f *fakeStore) ListAllNATPortForwards(_ context.Context) ([]db.NATPortForward, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.NATPortForward, len(f.portForwards))
+	copy(out, f.portForwards)
+
+	return out, nil
+}
+
+func (f *fakeStore) ListAllDataNetworkTCPMSS(_ context.Context) ([]db.DataNetworkTCPMSS, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.DataNetworkTCPMSS, len(f.tcpMSS))
+	copy(out, f.tcpMSS)
+
+	return out, nil
+}
+
+func (f *fakeStore) ListActiveLeases(_ context.Context) ([]db.IPLease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.IPLease, len(f.leases))
+	copy(out, f.leases)
+
+	return out, nil
+}
+
+func (f *fakeStore) ListAllPolicyCaptivePortals(_ context.Context) ([]db.PolicyCaptivePortal, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.PolicyCaptivePortal, len(f.portals))
+	copy(out, f.portals)
+
+	return out, nil
+}
+
+func (f *fakeStore) ListAllCaptivePortalLifts(_ context.Context) ([]db.CaptivePortalLift, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.CaptivePortalLift, len(f.portalLifts))
+	copy(out, f.portalLifts)
+
+	return out, nil
+}
+
+func (f *fakeStore) ListQosSessions(_ context.Context) ([]db.QosSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := make([]db.QosSession, len(f.qosSessions))
+	copy(out, f.qosSessions)
+
+	return out, nil
+}
+
 type filterCall struct {
 	policyID  string
 	direction models.Direction
@@ -100,6 +278,13 @@ type fakeUpdater struct {
 	// set, takes precedence and decides per (policyID, direction, rules).
 	updateFiltersErr  error
 	updateFiltersFunc func(policyID string, direction models.Direction, rules []models.FilterRule) error
+	egressCalls       [][]DataNetworkEgress
+	egressErr         error
+	natPoolCalls      []natSnapshot
+	natPoolErr        error
+	tcpMSSCalls       [][]DataNetworkTCPMSS
+	tcpMSSErr         error
+	portalLiftCalls   [][]netip.Addr
 }
 
 func (f *fakeUpdater) ReloadNAT(enabled bool) error {
@@ -168,6 +353,54 @@ func (f *fakeUpdater) UpdateFilters(_ context.Context, policyID string, directio
 	return nil
 }
 
+func (f *fakeUpdater) UpdateDataNetworkEgress(egress []DataNetworkEgress) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.egressErr != nil {
+		return f.egressErr
+	}
+
+	f.egressCalls = append(f.egressCalls, egress)
+
+	return nil
+}
+
+func (f *fakeUpdater) UpdateNAT(pools []DataNetworkNAT, forwards []NATPortForward) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.natPoolErr != nil {
+		return f.natPoolErr
+	}
+
+	f.natPoolCalls = append(f.natPoolCalls, natSnapshot{pools: pools, forwards: forwards})
+
+	return nil
+}
+
+func (f *fakeUpdater) UpdateTCPMSSClamp(clamps []DataNetworkTCPMSS) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.tcpMSSErr != nil {
+		return f.tcpMSSErr
+	}
+
+	f.tcpMSSCalls = append(f.tcpMSSCalls, clamps)
+
+	return nil
+}
+
+func (f *fakeUpdater) UpdatePortalLifted(ues []netip.Addr) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.portalLiftCalls = append(f.portalLiftCalls, ues)
+
+	return nil
+}
+
 func newReconciler(updater Updater, store SettingsStore, fallback netip.Addr) *SettingsReconciler {
 	return NewSettingsReconciler(updater, store, nil, fallback)
 }
@@ -378,6 +611,340 @@ func TestReconcile_FiltersAddRemoveModify(t *testing.T) {
 	}
 }
 
+func TestReconcile_FQDNRuleReplacesRemotePrefix(t *testing.T) {
+	prefix := "0.0.0.0/0"
+	store := &fakeStore{
+		policies: []db.Policy{{ID: "policy-1"}},
+		rulesByPolicyID: map[string][]*db.NetworkRule{
+			"policy-1": {
+				{ID: "rule-1", Direction: directionUplinkString, RemotePrefix: &prefix, Protocol: 6, PortLow: 443, PortHigh: 443, Action: "allow"},
+				{ID: "rule-2", Direction: directionUplinkString, Action: "deny"},
+			},
+		},
+		fqdnsByPolicyID: map[string]map[string]db.NetworkRuleFQDN{
+			"policy-1": {"rule-1": {NetworkRuleID: "rule-1", FQDN: "*.vendor-cloud.example", MatchSNI: true}},
+		},
+	}
+	updater := &fakeUpdater{}
+
+	r := newReconciler(updater, store, netip.MustParseAddr("10.0.0.5"))
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	uplinkCalls, _ := splitFilterCalls(updater.filterCalls)
+	if len(uplinkCalls) != 1 || len(uplinkCalls[0].rules) != 2 {
+		t.Fatalf("expected one uplink call with two rules, got %v", uplinkCalls)
+	}
+
+	got := uplinkCalls[0].rules[0]
+	if got.FQDN != "*.vendor-cloud.example" || !got.MatchSNI || got.RemotePrefix != "" {
+		t.Fatalf("expected the FQDN to replace the prefix, got %+v", got)
+	}
+
+	if uplinkCalls[0].rules[1].FQDN != "" {
+		t.Fatalf("expected the prefix rule to carry no FQDN, got %+v", uplinkCalls[0].rules[1])
+	}
+}
+
+func TestReconcile_RateLimitRuleCarriesBitrate(t *testing.T) {
+	store := &fakeStore{
+		policies: []db.Policy{{ID: "policy-1"}},
+		rulesByPolicyID: map[string][]*db.NetworkRule{
+			"policy-1": {
+				{ID: "rule-1", Direction: directionDownlinkString, Protocol: 6, PortLow: 443, PortHigh: 443, Action: db.RuleActionRateLimit},
+				{ID: "rule-2", Direction: directionDownlinkString, Action: "allow"},
+			},
+		},
+		limitsByPolicyID: map[string]map[string]db.NetworkRuleRateLimit{
+			"policy-1": {"rule-1": {NetworkRuleID: "rule-1", Bitrate: "2 Mbps", Shared: true}},
+		},
+	}
+	updater := &fakeUpdater{}
+
+	r := newReconciler(updater, store, netip.MustParseAddr("10.0.0.5"))
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	_, downlinkCalls := splitFilterCalls(updater.filterCalls)
+	if len(downlinkCalls) != 1 || len(downlinkCalls[0].rules) != 2 {
+		t.Fatalf("expected one downlink call with two rules, got %v", downlinkCalls)
+	}
+
+	got := downlinkCalls[0].rules[0]
+	if got.Action != models.RateLimit || got.RateLimit.Bps() != 2_000_000 || !got.RateLimitShared {
+		t.Fatalf("expected a shared 2 Mbps rate limit, got %+v", got)
+	}
+
+	if !downlinkCalls[0].rules[1].RateLimit.IsZero() {
+		t.Fatalf("expected the allow rule to carry no rate, got %+v", downlinkCalls[0].rules[1])
+	}
+}
+
+func TestReconcile_BreakoutRuleCarriesEgress(t *testing.T) {
+	edge := "192.168.50.0/24"
+	store := &fakeStore{
+		policies: []db.Policy{{ID: "policy-1"}},
+		rulesByPolicyID: map[string][]*db.NetworkRule{
+			"policy-1": {
+				{ID: "rule-1", Direction: directionUplinkString, RemotePrefix: &edge, Action: db.RuleActionBreakout},
+				{ID: "rule-2", Direction: directionUplinkString, Action: "allow"},
+			},
+		},
+		breakByPolicyID: map[string]map[string]db.NetworkRuleBreakout{
+			"policy-1": {"rule-1": {NetworkRuleID: "rule-1", InterfaceName: "eth2", VlanID: 30, RoutingTable: 100}},
+		},
+	}
+	updater := &fakeUpdater{}
+
+	r := newReconciler(updater, store, netip.MustParseAddr("10.0.0.5"))
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	uplinkCalls, _ := splitFilterCalls(updater.filterCalls)
+	if len(uplinkCalls) != 1 || len(uplinkCalls[0].rules) != 2 {
+		t.Fatalf("expected one uplink call with two rules, got %v", uplinkCalls)
+	}
+
+	want := models.BreakoutEgress{Interface: "eth2", VlanID: 30, Table: 100}
+	if got := uplinkCalls[0].rules[0]; got.Action != models.Breakout || got.Breakout != want || got.RemotePrefix != edge {
+		t.Fatalf("expected a breakout to %+v, got %+v", want, got)
+	}
+
+	if got := uplinkCalls[0].rules[1].Breakout; got != (models.BreakoutEgress{}) {
+		t.Fatalf("expected the allow rule to carry no breakout, got %+v", got)
+	}
+}
+
+func TestReconcile_RatedRulesCarryTheirRating(t *testing.T) {
+	portal := "198.51.100.0/24"
+	store := &fakeStore{
+		policies: []db.Policy{{ID: "policy-1"}},
+		rulesByPolicyID: map[string][]*db.NetworkRule{
+			"policy-1": {
+				{ID: "rule-1", Direction: directionDownlinkString, RemotePrefix: &portal, Action: "allow"},
+				{ID: "rule-2", Direction: directionDownlinkString, Action: "allow"},
+			},
+		},
+		ratesByPolicyID: map[string]map[string]db.NetworkRuleRating{
+			"policy-1": {"rule-1": {NetworkRuleID: "rule-1", RatingGroup: 10, ZeroRated: true}},
+		},
+	}
+	updater := &fakeUpdater{}
+
+	r := newReconciler(updater, store, netip.MustParseAddr("10.0.0.5"))
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	_, downlinkCalls := splitFilterCalls(updater.filterCalls)
+	if len(downlinkCalls) != 1 || len(downlinkCalls[0].rules) != 2 {
+		t.Fatalf("expected one downlink call with two rules, got %v", downlinkCalls)
+	}
+
+	if got := downlinkCalls[0].rules[0]; got.RatingGroup != 10 || !got.ZeroRated {
+		t.Fatalf("expected a zero-rated rule in rating group 10, got %+v", got)
+	}
+
+	if got := downlinkCalls[0].rules[1]; got.RatingGroup != 0 || got.ZeroRated {
+		t.Fatalf("expected the second rule to be unrated, got %+v", got)
+	}
+}
+
+func TestReconcile_CaptivePortalLeadsUplinkRules(t *testing.T) {
+	store := &fakeStore{
+		policies: []db.Policy{{ID: "policy-1"}},
+		rulesByPolicyID: map[string][]*db.NetworkRule{
+			"policy-1": {
+				{ID: "rule-1", Direction: directionUplinkString, Action: "allow"},
+				{ID: "rule-2", Direction: directionDownlinkString, Action: "allow"},
+			},
+		},
+		portals: []db.PolicyCaptivePortal{{PolicyID: "policy-1", PortalAddress: "192.0.2.10"}},
+	}
+	updater := &fakeUpdater{}
+
+	r := newReconciler(updater, store, netip.MustParseAddr("10.0.0.5"))
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	uplinkCalls, downlinkCalls := splitFilterCalls(updater.filterCalls)
+	if len(uplinkCalls) != 1 || len(uplinkCalls[0].rules) != CaptivePortalRules+1 {
+		t.Fatalf("expected one uplink call with the portal rules and the policy's, got %v", uplinkCalls)
+	}
+
+	rules := uplinkCalls[0].rules
+	for i, rule := range rules[:CaptivePortalRules-1] {
+		if rule.Action != models.Portal || rule.Portal != "192.0.2.10" {
+			t.Fatalf("rule %d = %+v, want a redirect to 192.0.2.10", i, rule)
+		}
+	}
+
+	if rules[CaptivePortalRules-1].Action != models.PortalDrop || rules[CaptivePortalRules].Action != models.Allow {
+		t.Fatalf("expected the portal drop ahead of the policy's allow, got %+v", rules[CaptivePortalRules-1:])
+	}
+
+	if len(downlinkCalls) != 1 || len(downlinkCalls[0].rules) != 1 {
+		t.Fatalf("expected the downlink rules untouched, got %v", downlinkCalls)
+	}
+}
+
+func TestReconcile_QosSessionFlowLeadsPolicyRules(t *testing.T) {
+	policyID := "policy-1"
+	store := &fakeStore{
+		policies: []db.Policy{{ID: policyID}},
+		rulesByPolicyID: map[string][]*db.NetworkRule{
+			policyID: {
+				{ID: "rule-1", Direction: directionUplinkString, Action: "deny", Protocol: 6},
+				{ID: "rule-2", Direction: directionDownlinkString, Action: "allow"},
+			},
+		},
+		qosSessions: []db.QosSession{
+			{ID: "qs-1", Status: db.QosSessionInstalling, PolicyID: &policyID, RemotePrefix: "198.51.100.7/32", Protocol: 17, PortLow: 5000, PortHigh: 5010, MBRUplink: "8 Mbps", MBRDownlink: "2 Mbps"},
+			{ID: "qs-2", Status: db.QosSessionRequested, PolicyID: &policyID, MBRUplink: "8 Mbps", MBRDownlink: "2 Mbps"},
+		},
+	}
+	updater := &fakeUpdater{}
+
+	r := newReconciler(updater, store, netip.MustParseAddr("10.0.0.5"))
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	filterID := models.QosSessionFilterID("qs-1")
+
+	var uplink, downlink []models.FilterRule
+
+	for _, c := range updater.filterCalls {
+		switch {
+		case c.policyID == models.QosSessionFilterID("qs-2"):
+			t.Fatalf("filters installed for a QoS session not yet claimed: %v", c)
+		case c.policyID == filterID && c.direction == models.DirectionUplink:
+			uplink = c.rules
+		case c.policyID == filterID && c.direction == models.DirectionDownlink:
+			downlink = c.rules
+		}
+	}
+
+	want := models.FilterRule{
+		RemotePrefix: "198.51.100.7/32",
+		Protocol:     17,
+		PortLow:      5000,
+		PortHigh:     5010,
+		Action:       models.QoSFlow,
+		QFI:          models.DedicatedQFI,
+		RateLimit:    models.MustParseBitRate("8 Mbps"),
+	}
+
+	if len(uplink) != 2 || uplink[0] != want || uplink[1].Action != models.Deny {
+		t.Fatalf("uplink = %+v, want the flow rule ahead of the policy's deny", uplink)
+	}
+
+	want.RateLimit = models.MustParseBitRate("2 Mbps")
+	if len(downlink) != 2 || downlink[0] != want || downlink[1].Action != models.Allow {
+		t.Fatalf("downlink = %+v, want the flow rule ahead of the policy's allow", downlink)
+	}
+
+	if !r.FiltersApplied(filterID) {
+		t.Error("FiltersApplied = false after the filters were installed")
+	}
+
+	// Once the session ends its filters are cleared.
+	store.qosSessions = nil
+	updater.filterCalls = nil
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.filterCalls) != 2 || updater.filterCalls[0].policyID != filterID || len(updater.filterCalls[0].rules) != 0 {
+		t.Fatalf("expected the QoS session's filters cleared, got %v", updater.filterCalls)
+	}
+
+	if r.FiltersApplied(filterID) {
+		t.Error("FiltersApplied = true after the filters were cleared")
+	}
+}
+
+func TestReconcile_CaptivePortalLiftsFollowLeases(t *testing.T) {
+	store := &fakeStore{
+		leases: []db.IPLease{
+			{IMSI: "001010000000001", AddressBin: netip.MustParseAddr("::ffff:10.45.0.2").AsSlice()},
+			{IMSI: "001010000000002", AddressBin: netip.MustParseAddr("::ffff:10.45.0.3").AsSlice()},
+		},
+		portalLifts: []db.CaptivePortalLift{{IMSI: "001010000000002"}},
+	}
+	updater := &fakeUpdater{}
+
+	r := newReconciler(updater, store, netip.MustParseAddr("10.0.0.5"))
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	want := []netip.Addr{netip.MustParseAddr("10.45.0.3")}
+	if len(updater.portalLiftCalls) != 1 || !reflect.DeepEqual(updater.portalLiftCalls[0], want) {
+		t.Fatalf("portal lift calls = %v, want one with %v", updater.portalLiftCalls, want)
+	}
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("second reconcile: %v", err)
+	}
+
+	if len(updater.portalLiftCalls) != 1 {
+		t.Fatalf("expected no reapply without a change, got %d calls", len(updater.portalLiftCalls))
+	}
+}
+
+func TestReconcile_ScheduledRuleFollowsItsWindow(t *testing.T) {
+	store := &fakeStore{
+		policies: []db.Policy{{ID: "policy-1"}},
+		rulesByPolicyID: map[string][]*db.NetworkRule{
+			"policy-1": {
+				{ID: "rule-1", Direction: directionUplinkString, Protocol: 6, PortLow: 443, PortHigh: 443, Action: "deny"},
+				{ID: "rule-2", Direction: directionUplinkString, Action: "allow"},
+			},
+		},
+		schedsByPolicyID: map[string]map[string]db.NetworkRuleSchedule{
+			"policy-1": {"rule-1": {NetworkRuleID: "rule-1", ScheduleID: "class-hours"}},
+		},
+	}
+	updater := &fakeUpdater{}
+
+	r := newReconciler(updater, store, netip.MustParseAddr("10.0.0.5"))
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	uplinkCalls, _ := splitFilterCalls(updater.filterCalls)
+	if len(uplinkCalls) != 1 || len(uplinkCalls[0].rules) != 1 || uplinkCalls[0].rules[0].Action != models.Allow {
+		t.Fatalf("expected only the unscheduled rule outside the window, got %v", uplinkCalls)
+	}
+
+	store.mu.Lock()
+	store.activeSchedules = map[string]bool{"class-hours": true}
+	store.mu.Unlock()
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	uplinkCalls, _ = splitFilterCalls(updater.filterCalls)
+	if len(uplinkCalls) != 2 || len(uplinkCalls[1].rules) != 2 || uplinkCalls[1].rules[0].Action != models.Deny {
+		t.Fatalf("expected the scheduled deny installed first once its window opens, got %v", uplinkCalls)
+	}
+}
+
 func TestReconcile_FilterUpdateFailureRetried(t *testing.T) {
 	store := &fakeStore{
 		policies: []db.Policy{{ID: "policy-1"}},
@@ -612,3 +1179,242 @@ func splitFilterCalls(calls []filterCall) (uplink, downlink []filterCall) {
 
 	return uplink, downlink
 }
+
+func TestReconcile_DataNetworkEgressAppliesOnChangeOnly(t *testing.T) {
+	store := &fakeStore{
+		dataNetworks: []db.DataNetwork{
+			{ID: "dn-1", Name: "internet", IPv4Pool: "10.45.0.0/16"},
+			{ID: "dn-2", Name: "enterprise", IPv4Pool: "10.46.0.0/16", IPv6Pool: "2001:db8::/48"},
+		},
+		egress: []db.DataNetworkEgress{
+			{DataNetworkID: "dn-2", InterfaceName: "eth2", VlanID: 100, RoutingTable: 100},
+		},
+	}
+	updater := &fakeUpdater{}
+	r := newReconciler(updater, store, netip.Addr{})
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.egressCalls) != 1 {
+		t.Fatalf("expected one egress update, got %d", len(updater.egressCalls))
+	}
+
+	want := []DataNetworkEgress{{
+		Name:      "enterprise",
+		Pools:     []netip.Prefix{netip.MustParsePrefix("10.46.0.0/16"), netip.MustParsePrefix("2001:db8::/48")},
+		Interface: "eth2",
+		VlanID:    100,
+		Table:     100,
+	}}
+
+	if !reflect.DeepEqual(updater.egressCalls[0], want) {
+		t.Fatalf("unexpected egress update:\n got %+v\nwant %+v", updater.egressCalls[0], want)
+	}
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.egressCalls) != 1 {
+		t.Fatalf("expected no update when unchanged, got %d calls", len(updater.egressCalls))
+	}
+
+	store.mu.Lock()
+	store.egress = nil
+	store.mu.Unlock()
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.egressCalls) != 2 || len(updater.egressCalls[1]) != 0 {
+		t.Fatalf("expected an empty egress update after removal, got %+v", updater.egressCalls)
+	}
+}
+
+func TestReconcile_DataNetworkEgressUnsupportedDatapathIsNotRetried(t *testing.T) {
+	store := &fakeStore{
+		dataNetworks: []db.DataNetwork{{ID: "dn-1", Name: "enterprise", IPv4Pool: "10.46.0.0/16"}},
+		egress:       []db.DataNetworkEgress{{DataNetworkID: "dn-1", RoutingTable: 100}},
+	}
+	updater := &fakeUpdater{egressErr: ebpf.ErrDataNetworkEgressUnsupported}
+	r := newReconciler(updater, store, netip.Addr{})
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("an unsupported datapath must not fail the reconcile: %v", err)
+	}
+
+	r.stateMu.Lock()
+	applied := r.appliedEgress
+	r.stateMu.Unlock()
+
+	if len(applied) != 1 {
+		t.Fatalf("expected the snapshot to be recorded, got %+v", applied)
+	}
+}
+
+func TestReconcile_DataNetworkNATResolvesForwardsThroughLeases(t *testing.T) {
+	ueAddr := netip.MustParseAddr("10.46.0.7")
+	mapped := ueAddr.As16()
+	store := &fakeStore{
+		dataNetworks: []db.DataNetwork{{ID: "dn-1", Name: "enterprise", IPv4Pool: "10.46.0.0/16"}},
+		nat: []db.DataNetworkNAT{{
+			DataNetworkID: "dn-1",
+			Addresses:     "203.0.113.10,203.0.113.11",
+			Mapping:       db.NATMappingRoundRobin,
+			PortMin:       20000,
+			PortMax:       29999,
+		}},
+		portForwards: []db.NATPortForward{
+			{ID: "f1", DataNetworkID: "dn-1", Protocol: "tcp", PublicAddress: "203.0.113.10", PublicPort: 8080, IMSI: "001010000000001", UEPort: 80},
+			// Conflicts with f1's public address for the same UE.
+			{ID: "f2", DataNetworkID: "dn-1", Protocol: "udp", PublicAddress: "203.0.113.11", PublicPort: 5000, IMSI: "001010000000001", UEPort: 5000},
+			// No active lease yet.
+			{ID: "f3", DataNetworkID: "dn-1", Protocol: "tcp", PublicAddress: "203.0.113.11", PublicPort: 2222, IMSI: "001010000000002", UEPort: 22},
+		},
+		leases: []db.IPLease{{PoolID: "dn-1", IMSI: "001010000000001", AddressBin: mapped[:]}},
+	}
+	updater := &fakeUpdater{}
+	r := newReconciler(updater, store, netip.Addr{})
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.natPoolCalls) != 1 {
+		t.Fatalf("expected one NAT update, got %d", len(updater.natPoolCalls))
+	}
+
+	want := natSnapshot{
+		pools: []DataNetworkNAT{{
+			Name:       "enterprise",
+			Pools:      []netip.Prefix{netip.MustParsePrefix("10.46.0.0/16")},
+			Addresses:  []netip.Addr{netip.MustParseAddr("203.0.113.10"), netip.MustParseAddr("203.0.113.11")},
+			RoundRobin: true,
+			PortMin:    20000,
+			PortMax:    29999,
+		}},
+		forwards: []NATPortForward{{
+			Protocol:      6,
+			PublicAddress: netip.MustParseAddr("203.0.113.10"),
+			PublicPort:    8080,
+			UEAddress:     ueAddr,
+			UEPort:        80,
+		}},
+	}
+
+	if !reflect.DeepEqual(updater.natPoolCalls[0], want) {
+		t.Fatalf("unexpected NAT update:\n got %+v\nwant %+v", updater.natPoolCalls[0], want)
+	}
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.natPoolCalls) != 1 {
+		t.Fatalf("expected no update when unchanged, got %d calls", len(updater.natPoolCalls))
+	}
+
+	// The forward follows the subscriber's lease.
+	store.mu.Lock()
+	store.leases = nil
+	store.mu.Unlock()
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.natPoolCalls) != 2 || len(updater.natPoolCalls[1].forwards) != 0 {
+		t.Fatalf("expected forwards to be withdrawn with the lease, got %+v", updater.natPoolCalls)
+	}
+}
+
+func TestReconcile_DataNetworkNATUnsupportedDatapathIsNotRetried(t *testing.T) {
+	store := &fakeStore{
+		dataNetworks: []db.DataNetwork{{ID: "dn-1", Name: "enterprise", IPv4Pool: "10.46.0.0/16"}},
+		nat:          []db.DataNetworkNAT{{DataNetworkID: "dn-1", Addresses: "203.0.113.10", Mapping: db.NATMappingDeterministic}},
+	}
+	updater := &fakeUpdater{natPoolErr: ebpf.ErrNATPoolsUnsupported}
+	r := newReconciler(updater, store, netip.Addr{})
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("an unsupported datapath must not fail the reconcile: %v", err)
+	}
+
+	r.stateMu.Lock()
+	applied := r.appliedNATPools
+	r.stateMu.Unlock()
+
+	if applied == nil || len(applied.pools) != 1 {
+		t.Fatalf("expected the snapshot to be recorded, got %+v", applied)
+	}
+}
+
+func TestReconcile_DataNetworkTCPMSSAppliesOnChangeOnly(t *testing.T) {
+	store := &fakeStore{
+		dataNetworks: []db.DataNetwork{
+			{ID: "dn-1", Name: "internet", IPv4Pool: "10.45.0.0/16", MTU: 1500},
+			{ID: "dn-2", Name: "enterprise", IPv4Pool: "10.46.0.0/16", IPv6Pool: "2001:db8::/48", MTU: 1400},
+		},
+		tcpMSS: []db.DataNetworkTCPMSS{{DataNetworkID: "dn-2"}},
+	}
+	updater := &fakeUpdater{}
+	r := newReconciler(updater, store, netip.Addr{})
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	want := []DataNetworkTCPMSS{{
+		Name:  "enterprise",
+		Pools: []netip.Prefix{netip.MustParsePrefix("10.46.0.0/16"), netip.MustParsePrefix("2001:db8::/48")},
+		MTU:   1400,
+	}}
+
+	if len(updater.tcpMSSCalls) != 1 || !reflect.DeepEqual(updater.tcpMSSCalls[0], want) {
+		t.Fatalf("unexpected TCP MSS updates:\n got %+v\nwant %+v", updater.tcpMSSCalls, want)
+	}
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.tcpMSSCalls) != 1 {
+		t.Fatalf("expected no update when unchanged, got %d calls", len(updater.tcpMSSCalls))
+	}
+
+	store.mu.Lock()
+	store.tcpMSS = []db.DataNetworkTCPMSS{{DataNetworkID: "dn-2", MSS: 1200}}
+	store.mu.Unlock()
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(updater.tcpMSSCalls) != 2 || updater.tcpMSSCalls[1][0].MSS != 1200 {
+		t.Fatalf("expected an update with the explicit MSS, got %+v", updater.tcpMSSCalls)
+	}
+}
+
+func TestReconcile_DataNetworkTCPMSSUnsupportedDatapathIsNotRetried(t *testing.T) {
+	store := &fakeStore{
+		dataNetworks: []db.DataNetwork{{ID: "dn-1", Name: "enterprise", IPv4Pool: "10.46.0.0/16", MTU: 1400}},
+		tcpMSS:       []db.DataNetworkTCPMSS{{DataNetworkID: "dn-1"}},
+	}
+	updater := &fakeUpdater{tcpMSSErr: ebpf.ErrTCPMSSClampUnsupported}
+	r := newReconciler(updater, store, netip.Addr{})
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatalf("an unsupported datapath must not fail the reconcile: %v", err)
+	}
+
+	r.stateMu.Lock()
+	applied := r.appliedTCPMSS
+	r.stateMu.Unlock()
+
+	if len(applied) != 1 {
+		t.Fatalf("expected the snapshot to be recorded, got %+v", applied)
+	}
+}
@@ -58,6 +58,11 @@ type UPF struct {
 	noNeighReader      *ringbuf.Reader
 	raResponder        *RAResponder
 
+	// fqdn binds FQDN network rules to datapath sets. fqdnReader is nil on a
+	// datapath without the FQDN maps.
+	fqdn       *fqdnTracker
+	fqdnReader *ringbuf.Reader
+
 	ctx context.Context
 
 	// gcMu serialises startGC / stopGC (e.g. from ReloadNAT and Close).
@@ -72,13 +77,34 @@ type UPF struct {
 	fcScanDone chan struct{} // closed when collectExpiredFlows exits
 	fcDone     chan struct{} // closed when reportFlows exits (all flows reported)
 
+	// GTP-U path supervision; pathCancel is nil when its sockets could not
+	// be opened.
+	pathCancel context.CancelFunc
+	pathConns  []*net.UDPConn
+	pathWG     sync.WaitGroup
+
 	// Own cancel, so Close can stop the poller and wait for its final flush.
 	usageCancel context.CancelFunc
 	usageDone   chan struct{} // closed when monitorUsage exits
+	thresholds  usageThresholds
 
 	// Differs from the configured mode when config.DatapathChain falls back.
 	// Written once in Start, before this struct is returned.
 	attachedMode string
+
+	n3Iface datapathIface
+	n6Iface datapathIface
+
+	// egressMu guards egressLinks, the attachments on data network egress
+	// and breakout interfaces other than N3 and N6, keyed by ifindex, and
+	// the interfaces each of the two wants attached.
+	egressMu       sync.Mutex
+	egressLinks    map[int]link.Link
+	dnEgressIfaces map[int]datapathIface
+	breakoutIfaces map[int]datapathIface
+
+	// breakout binds breakout network rules to datapath egress entries.
+	breakout *breakoutTracker
 }
 
 // DatapathAttachMode is empty before the UPF is up.
@@ -90,6 +116,27 @@ func (u *UPF) DatapathAttachMode() string {
 	return u.attachedMode
 }
 
+// DatapathFeatures is the zero value before the UPF is up.
+func (u *UPF) DatapathFeatures() models.DatapathFeatures {
+	if u == nil || u.se == nil {
+		return models.DatapathFeatures{}
+	}
+
+	objs := u.se.BpfObjects
+
+	return models.DatapathFeatures{
+		DataNetworkEgress: objs.HasDataNetworkEgress(),
+		NATPools:          objs.HasNATPools(),
+		FQDNRules:         objs.HasFQDNSets(),
+		RateLimitRules:    objs.HasSDFRateLimit(),
+		BreakoutRules:     objs.HasBreakoutEgress(),
+		TCPMSSClamp:       objs.HasTCPMSSClamp(),
+		RatedRules:        objs.HasRatingGroups(),
+		CaptivePortal:     objs.HasCaptivePortal(),
+		QoSFlows:          objs.HasQoSFlows(),
+	}
+}
+
 func Start(ctx context.Context, smfHandler engine.SMFReportHandler, n3Interface config.N3Interface, n3IPv4 string, n3IPv6 string, advertisedN3IPv4 string, advertisedN3IPv6 string, n6Interface config.N6Interface, attachMode string, masquerade bool, flowact bool, localSwitch bool) (*UPF, error) {
 	var (
 		n3Vlan uint32
@@ -131,9 +178,10 @@ func Start(ctx context.Context, smfHandler engine.SMFReportHandler, n3Interface
 		return nil, err
 	}
 
-	attachedMode, n3Link, n6Link, err := attachDatapath(bpfObjects, attachMode,
-		datapathIface{index: n3Iface.Index, name: n3AttachmentInterface},
-		datapathIface{index: n6Iface.Index, name: n6AttachmentInterface})
+	n3Attach := datapathIface{index: n3Iface.Index, name: n3AttachmentInterface}
+	n6Attach := datapathIface{index: n6Iface.Index, name: n6AttachmentInterface}
+
+	attachedMode, n3Link, n6Link, err := attachDatapath(bpfObjects, attachMode, n3Attach, n6Attach)
 	if err != nil {
 		return nil, err
 	}
@@ -169,13 +217,31 @@ func Start(ctx context.Context, smfHandler engine.SMFReportHandler, n3Interface
 		attachedMode:       attachedMode,
 		n3Link:             n3Link,
 		n6Link:             n6Link,
+		n3Iface:            n3Attach,
+		n6Iface:            n6Attach,
+		egressLinks:        make(map[int]link.Link),
 		se:                 se,
 		smf:                smfHandler,
 		notificationReader: notificationReader,
 		noNeighReader:      noNeighReader,
+		fqdn:               newFQDNTracker(bpfObjects),
 		ctx:                ctx,
 	}
 
+	upf.breakout = newBreakoutTracker(bpfObjects, upf.resolveBreakout)
+
+	if bpfObjects.HasFQDNSets() {
+		fqdnReader, err := ringbuf.NewReader(bpfObjects.FqdnSnoopEvents)
+		if err != nil {
+			return nil, fmt.Errorf("could not start FQDN snoop reader: %w", err)
+		}
+
+		upf.fqdnReader = fqdnReader
+
+		go upf.fqdn.listen(fqdnReader)                        // #nosec: G118 -- lifecycle goroutine, not request-scoped
+		go upf.fqdn.sweepEvery(fqdnSweepInterval, ctx.Done()) // #nosec: G118 -- lifecycle goroutine, not request-scoped
+	}
+
 	// Start the RA responder for IPv6 prefix delegation (RS → RA via veth).
 	var n3IPv4Addr netip.Addr
 	if parsed, err := netip.ParseAddr(n3IPv4); err == nil && parsed.Is4() {
@@ -208,6 +274,8 @@ func Start(ctx context.Context, smfHandler engine.SMFReportHandler, n3Interface
 		)
 	}
 
+	upf.startPathMonitor(ctx, n3IPv4Addr, n3IPv6Addr, n3Interface.PathManagement)
+
 	go upf.listenForTrafficNotifications() // #nosec: G118 -- lifecycle goroutine, not request-scoped
 
 	upf.startUsageMonitor(ctx, 30*time.Second)
@@ -229,6 +297,7 @@ func (u *UPF) Close(ctx context.Context) {
 	u.stopGC()
 	u.stopFlowCollection()
 	u.stopUsageMonitor()
+	u.stopPathMonitor()
 
 	// Resource cleanup: BPF detach, object close, perf reader close.
 	// These are kernel-level operations that are normally fast, but run
@@ -250,6 +319,14 @@ func (u *UPF) Close(ctx context.Context) {
 			}
 		}
 
+		u.egressMu.Lock()
+		for index, l := range u.egressLinks {
+			if err := l.Close(); err != nil {
+				logger.UpfLog.Warn("Failed to detach eBPF from data network egress interface", zap.Int("ifindex", index), zap.Error(err))
+			}
+		}
+		u.egressMu.Unlock()
+
 		if err := u.n3Link.Close(); err != nil {
 			logger.UpfLog.Warn("Failed to detach eBPF from n3", zap.Error(err))
 		}
@@ -265,6 +342,12 @@ func (u *UPF) Close(ctx context.Context) {
 		if err := u.noNeighReader.Close(); err != nil {
 			logger.UpfLog.Warn("Failed to close missing neighbour reader", zap.Error(err))
 		}
+
+		if u.fqdnReader != nil {
+			if err := u.fqdnReader.Close(); err != nil {
+				logger.UpfLog.Warn("Failed to close FQDN snoop reader", zap.Error(err))
+			}
+		}
 	}()
 
 	select {
@@ -308,7 +391,28 @@ func (u *UPF) UpdateAdvertisedN3Address(newN3Addr netip.Addr) {
 }
 
 func (u *UPF) UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error {
-	return u.se.UpdateFilters(ctx, policyID, direction, rules)
+	key := policyID + ":" + direction.String()
+
+	rules, err := u.fqdn.resolve(key, rules)
+	if err != nil {
+		return err
+	}
+
+	// A breakout that cannot be resolved leaves its rule on the data
+	// network's path; the error is returned once the rest are applied, so
+	// the filters are retried.
+	rules, breakoutErr := u.breakout.resolve(key, rules)
+
+	u.egressMu.Lock()
+	u.breakoutIfaces = u.breakout.interfaces(u.n3Iface.index, u.n6Iface.index)
+	linkErrs := u.syncEgressLinksLocked()
+	u.egressMu.Unlock()
+
+	if err := u.se.UpdateFilters(ctx, policyID, direction, rules); err != nil {
+		return err
+	}
+
+	return errors.Join(append(linkErrs, breakoutErr)...)
 }
 
 // updateAttachedPrograms points every attached link at the newly loaded
@@ -324,6 +428,15 @@ func (u *UPF) updateAttachedPrograms() error {
 		}
 	}
 
+	u.egressMu.Lock()
+	defer u.egressMu.Unlock()
+
+	for _, l := range u.egressLinks {
+		if err := l.Update(u.se.BpfObjects.UpfEntryFunc); err != nil {
+			return err
+		}
+	}
+
 	if u.raResponder != nil {
 		if err := u.raResponder.UpdateProgram(u.se.BpfObjects.VethXdpFunc); err != nil {
 			return err
@@ -655,6 +768,9 @@ func (u *UPF) monitorUsage(interval time.Duration, stop <-chan struct{}) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	thresholdTicker := time.NewTicker(thresholdInterval)
+	defer thresholdTicker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
@@ -662,6 +778,8 @@ func (u *UPF) monitorUsage(interval time.Duration, stop <-chan struct{}) {
 			if err != nil {
 				logger.UpfLog.Warn("Failed to poll usage and reset counters", zap.Error(err))
 			}
+		case <-thresholdTicker.C:
+			u.checkUsageThresholds(u.ctx)
 		case <-stop:
 			// Drains what was accounted since the last tick; counters are read
 			// and reset together, so this cannot double-count. Own context,
@@ -690,13 +808,42 @@ func (u *UPF) pollUsageAndResetCounters(ctx context.Context) error {
 		return fmt.Errorf("PFCP connection is nil")
 	}
 
-	for localSeid, session := range u.se.ListSessions() {
-		u.flushUsageForSession(ctx, localSeid, session)
+	sessions := u.se.ListSessions()
+
+	rated := u.drainRatingUsage(nil)
+
+	for localSeid, session := range sessions {
+		u.flushUsageForSession(ctx, localSeid, session, rated[localSeid])
 	}
 
 	return nil
 }
 
+// drainRatingUsage collects the rating group counters of the sessions keep
+// accepts, by session and URR.
+func (u *UPF) drainRatingUsage(keep func(seid uint64) bool) map[uint64]map[uint32][]ebpf.RatingUsage {
+	if !u.se.BpfObjects.HasRatingGroups() {
+		return nil
+	}
+
+	counts, err := u.se.BpfObjects.DrainRatingUsage(keep)
+	if err != nil {
+		logger.UpfLog.Warn("could not drain rating group usage", zap.Error(err))
+	}
+
+	out := make(map[uint64]map[uint32][]ebpf.RatingUsage)
+
+	for _, c := range counts {
+		if out[c.Seid] == nil {
+			out[c.Seid] = make(map[uint32][]ebpf.RatingUsage)
+		}
+
+		out[c.Seid][c.UrrID] = append(out[c.Seid][c.UrrID], c)
+	}
+
+	return out
+}
+
 // FlushUsage drains and reports any pending URR counters for the given SEID.
 // Called by the SMF immediately before session deletion so that traffic
 // accounted since the last periodic poll is reported to the subscriber-usage
@@ -712,10 +859,14 @@ func (u *UPF) FlushUsage(ctx context.Context, seid uint64) {
 		return
 	}
 
-	u.flushUsageForSession(ctx, seid, session)
+	rated := u.drainRatingUsage(func(s uint64) bool { return s == seid })
+
+	u.flushUsageForSession(ctx, seid, session, rated[seid])
 }
 
-func (u *UPF) flushUsageForSession(ctx context.Context, localSeid uint64, session *engine.Session) {
+// flushUsageForSession reports each URR of the session, with the rating
+// groups rated counted against it.
+func (u *UPF) flushUsageForSession(ctx context.Context, localSeid uint64, session *engine.Session, rated map[uint32][]ebpf.RatingUsage) {
 	for _, pdr := range session.ListPDRs() {
 		urrID := pdr.PdrInfo.UrrID
 		if urrID == 0 {
@@ -743,7 +894,24 @@ func (u *UPF) flushUsageForSession(ctx context.Context, localSeid uint64, sessio
 			}
 		}
 
-		err = u.se.SendUsageReport(ctx, u.smf, localSeid, uvol, dvol)
+		groups := make([]models.RatingGroupUsage, 0, len(rated[urrID]))
+
+		for _, c := range rated[urrID] {
+			g := models.RatingGroupUsage{RatingGroup: c.RatingGroup}
+			if pdr.UEIP.IsValid() {
+				g.DownlinkVolume = c.Bytes
+			} else {
+				g.UplinkVolume = c.Bytes
+			}
+
+			groups = append(groups, g)
+		}
+
+		// A URR shared by several PDRs is drained by the first; the rest
+		// must not report its rating groups again.
+		delete(rated, urrID)
+
+		err = u.se.SendUsageReport(ctx, u.smf, localSeid, uvol, dvol, groups)
 		if err != nil {
 			logger.UpfLog.Warn("could not send PFCP session report request for usage", zap.Error(err), logger.SEID(localSeid), logger.URRID(urrID))
 
@@ -753,6 +921,18 @@ func (u *UPF) flushUsageForSession(ctx context.Context, localSeid uint64, sessio
 					zap.Uint64("bytes", uvol+dvol), zap.Error(restoreErr), logger.SEID(localSeid), logger.URRID(urrID))
 			}
 
+			for _, g := range groups {
+				c := ebpf.RatingUsage{
+					RatingUsageKey: ebpf.RatingUsageKey{Seid: localSeid, UrrID: urrID, RatingGroup: g.RatingGroup},
+					Bytes:          g.UplinkVolume + g.DownlinkVolume,
+				}
+
+				if restoreErr := u.se.BpfObjects.AddRatingUsage(c); restoreErr != nil {
+					logger.UpfLog.Error("rating group usage lost: report failed and counter could not be restored",
+						zap.Uint32("rating_group", g.RatingGroup), zap.Uint64("bytes", c.Bytes), zap.Error(restoreErr), logger.SEID(localSeid))
+				}
+			}
+
 			continue
 		}
 
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/logger"
+	"go.uber.org/zap"
+)
+
+// thresholdInterval is how often the sessions with a usage threshold are
+// checked. It bounds how far a session can run past its threshold before
+// its usage is reported.
+const thresholdInterval = time.Second
+
+// usageThresholds holds the sessions whose usage is reported as soon as
+// they have carried a number of octets since their last report, rather
+// than at the next periodic poll: the volume threshold of a URR (TS 29.244
+// §5.2.2.2), which online charging sets to learn in time that a grant is
+// running out.
+type usageThresholds struct {
+	mu    sync.Mutex
+	bytes map[uint64]uint64 // by local SEID
+}
+
+func (t *usageThresholds) set(seid, bytes uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if bytes == 0 {
+		delete(t.bytes, seid)
+		return
+	}
+
+	if t.bytes == nil {
+		t.bytes = make(map[uint64]uint64)
+	}
+
+	t.bytes[seid] = bytes
+}
+
+func (t *usageThresholds) snapshot() map[uint64]uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make(map[uint64]uint64, len(t.bytes))
+	for seid, bytes := range t.bytes {
+		out[seid] = bytes
+	}
+
+	return out
+}
+
+// SetUsageThreshold makes the session with the given local SEID report its
+// usage once it has carried bytes octets since its last report. Zero
+// clears the threshold. It stays set across reports until changed.
+func (u *UPF) SetUsageThreshold(seid, bytes uint64) {
+	u.thresholds.set(seid, bytes)
+}
+
+// checkUsageThresholds reports the usage of the sessions that reached
+// their threshold, and forgets the thresholds of sessions that are gone.
+func (u *UPF) checkUsageThresholds(ctx context.Context) {
+	if u.se == nil {
+		return
+	}
+
+	for seid, threshold := range u.thresholds.snapshot() {
+		session := u.se.GetSession(seid)
+		if session == nil {
+			u.thresholds.set(seid, 0)
+			continue
+		}
+
+		var (
+			total uint64
+			seen  = make(map[uint32]bool)
+		)
+
+		for _, pdr := range session.ListPDRs() {
+			urrID := pdr.PdrInfo.UrrID
+			if urrID == 0 || seen[urrID] {
+				continue
+			}
+
+			seen[urrID] = true
+
+			bytes, err := u.se.BpfObjects.PeekUrr(seid, urrID)
+			if err != nil {
+				logger.UpfLog.Debug("could not read usage for threshold", logger.SEID(seid), logger.URRID(urrID), zap.Error(err))
+				continue
+			}
+
+			total += bytes
+		}
+
+		if total >= threshold {
+			u.FlushUsage(ctx, seid)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package upf
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUsageThresholds(t *testing.T) {
+	u := &UPF{}
+
+	u.SetUsageThreshold(1, 800)
+	u.SetUsageThreshold(2, 100)
+	u.SetUsageThreshold(2, 200)
+	u.SetUsageThreshold(3, 50)
+	u.SetUsageThreshold(3, 0)
+
+	got := u.thresholds.snapshot()
+	if len(got) != 2 || got[1] != 800 || got[2] != 200 {
+		t.Fatalf("thresholds = %v, want 1:800 and 2:200", got)
+	}
+
+	// The snapshot is a copy: changing it leaves the thresholds alone.
+	got[1] = 1
+
+	if u.thresholds.snapshot()[1] != 800 {
+		t.Fatal("snapshot shares the thresholds map")
+	}
+}
+
+// TestCheckUsageThresholdsWithoutEngine checks the nil case: the monitor
+// runs before the PFCP engine is set up.
+func TestCheckUsageThresholdsWithoutEngine(t *testing.T) {
+	u := &UPF{}
+	u.SetUsageThreshold(1, 800)
+
+	u.checkUsageThresholds(context.Background())
+
+	if len(u.thresholds.snapshot()) != 1 {
+		t.Fatal("threshold dropped without an engine to check it against")
+	}
+}
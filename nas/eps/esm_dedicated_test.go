@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package eps
+
+import (
+	"bytes"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestActivateDedicatedEPSBearerContextRequestRoundTrip(t *testing.T) {
+	pf, err := RemoteTFTPacketFilter(1, TFTBidirectional, 10, netip.MustParsePrefix("198.51.100.0/24"), 17, 5060, 5060)
+	if err != nil {
+		t.Fatalf("packet filter: %v", err)
+	}
+
+	tft, err := NewTFT(pf)
+	if err != nil {
+		t.Fatalf("tft: %v", err)
+	}
+
+	rates, err := GBRBitRates(128_000, 128_000, 64_000, 64_000)
+	if err != nil {
+		t.Fatalf("rates: %v", err)
+	}
+
+	req := &ActivateDedicatedEPSBearerContextRequest{
+		EPSBearerIdentity:       6,
+		LinkedEPSBearerIdentity: 5,
+		EPSQoS:                  EPSQoS{QCI: 1, BitRates: rates},
+		TFT:                     tft,
+	}
+
+	wire, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	if wire[0] != (6<<4|0x02) || wire[2] != byte(MsgActivateDedicatedEPSBearerContextRequest) || wire[3] != 0x05 {
+		t.Fatalf("header = % x, want EBI 6, message type %#x and linked EBI 5", wire[:4], byte(MsgActivateDedicatedEPSBearerContextRequest))
+	}
+
+	got, err := ParseActivateDedicatedEPSBearerContextRequest(wire)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, req) {
+		t.Fatalf("round trip:\n got %+v\nwant %+v", got, req)
+	}
+}
+
+func TestActivateDedicatedEPSBearerContextAcceptAndRejectRoundTrip(t *testing.T) {
+	acc := &ActivateDedicatedEPSBearerContextAccept{EPSBearerIdentity: 6}
+
+	wire, err := acc.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal accept: %v", err)
+	}
+
+	gotAcc, err := ParseActivateDedicatedEPSBearerContextAccept(wire)
+	if err != nil || !reflect.DeepEqual(gotAcc, acc) {
+		t.Fatalf("accept round trip = %+v, %v; want %+v", gotAcc, err, acc)
+	}
+
+	rej := &ActivateDedicatedEPSBearerContextReject{EPSBearerIdentity: 6, Cause: 31}
+
+	wire, err = rej.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal reject: %v", err)
+	}
+
+	gotRej, err := ParseActivateDedicatedEPSBearerContextReject(wire)
+	if err != nil || !reflect.DeepEqual(gotRej, rej) {
+		t.Fatalf("reject round trip = %+v, %v; want %+v", gotRej, err, rej)
+	}
+}
+
+func TestNewTFTEncoding(t *testing.T) {
+	v4, err := RemoteTFTPacketFilter(1, TFTBidirectional, 10, netip.MustParsePrefix("198.51.100.7/24"), 6, 8000, 8100)
+	if err != nil {
+		t.Fatalf("packet filter: %v", err)
+	}
+
+	tft, err := NewTFT(v4)
+	if err != nil {
+		t.Fatalf("tft: %v", err)
+	}
+
+	// Create new TFT with one filter; bidirectional, id 1, precedence 10, then
+	// the address and mask, the protocol and the remote port range.
+	want := []byte{
+		0x21, 0x31, 0x0A, 0x10,
+		0x10, 198, 51, 100, 0, 255, 255, 255, 0,
+		0x30, 6,
+		0x51, 0x1F, 0x40, 0x1F, 0xA4,
+	}
+	if !bytes.Equal(tft, want) {
+		t.Errorf("tft = % x, want % x", tft, want)
+	}
+
+	if _, err := RemoteTFTPacketFilter(1, TFTBidirectional, 10, netip.Prefix{}, 0, 0, 0); err == nil {
+		t.Error("match-all packet filter accepted")
+	}
+
+	if _, err := NewTFT(); err == nil {
+		t.Error("empty TFT accepted")
+	}
+}
+
+func TestGBRBitRates(t *testing.T) {
+	rates, err := GBRBitRates(64_000, 64_000, 64_000, 64_000)
+	if err != nil {
+		t.Fatalf("rates: %v", err)
+	}
+
+	if len(rates) != 4 {
+		t.Errorf("64 kbit/s rates = % x, want the four base octets only", rates)
+	}
+
+	rates, err = GBRBitRates(100_000_000, 100_000_000, 0, 0)
+	if err != nil {
+		t.Fatalf("rates: %v", err)
+	}
+
+	if len(rates) != 8 {
+		t.Errorf("100 Mbit/s rates = % x, want the extended octets too", rates)
+	}
+
+	if _, err := GBRBitRates(300_000_000, 0, 0, 0); err == nil {
+		t.Error("rate above 256 Mbit/s accepted")
+	}
+}
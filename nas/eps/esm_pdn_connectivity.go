@@ -156,11 +156,23 @@ type PDNConnectivityReject struct {
 	PTI               nas.ProcedureTransactionIdentity
 	Cause             ESMCause
 
+	// T3396 is the back-off timer the UE runs before requesting the APN
+	// again, sent with ESM cause #26 (TS 24.301 §6.5.1.4.3).
+	T3396 *nas.GPRSTimer3 // optional (IEI 0x37)
+
 	// Unrecognized carries the optional information elements this message does
 	// not model, so they survive decoding and re-encode unchanged.
 	Unrecognized []nas.RawIE
 }
 
+// pdnConnectivityRejectIEs is the optional-IE table of the PDN CONNECTIVITY
+// REJECT (TS 24.301 §8.3.19, table 8.3.19.1).
+var pdnConnectivityRejectIEs = []nas.OptionalIE{
+	{IEI: ieiProtocolConfigurationOptions, Format: nas.IETLV, Name: "Protocol configuration options"},
+	{IEI: ieiT3396Value, Format: nas.IETLV, Name: "T3396 value"},
+	{IEI: ieiExtendedProtocolConfigurationOptions, Format: nas.IETLVE, Name: "Extended protocol configuration options"},
+}
+
 // AppendBinary encodes the PDN CONNECTIVITY REJECT message.
 // The encoding is appended to b.
 func (m *PDNConnectivityReject) AppendBinary(b []byte) ([]byte, error) {
@@ -171,6 +183,15 @@ func (m *PDNConnectivityReject) AppendBinary(b []byte) ([]byte, error) {
 	writeESMHeader(w, m.EPSBearerIdentity, m.PTI, MsgPDNConnectivityReject)
 	w.U8(uint8(m.Cause))
 
+	if m.T3396 != nil {
+		raw, err := m.T3396.MarshalBinary()
+		if err != nil {
+			return b, err
+		}
+
+		o.TLV(ieiT3396Value, raw)
+	}
+
 	o.Raw(m.Unrecognized...)
 	o.WriteTo(w)
 
@@ -198,7 +219,20 @@ func ParsePDNConnectivityReject(b []byte) (*PDNConnectivityReject, error) {
 		EPSBearerIdentity: ebi, PTI: pti, Cause: ESMCause(cause),
 	}
 
-	_unrec, err := walkOptionalIEs(r, nil, declineAll)
+	_unrec, err := walkOptionalIEs(r, pdnConnectivityRejectIEs, func(iei uint8, value []byte) (bool, error) {
+		if iei != ieiT3396Value {
+			return false, nil
+		}
+
+		timer, err := nas.ParseGPRSTimer3(value)
+		if err != nil {
+			return false, err
+		}
+
+		out.T3396 = &timer
+
+		return true, nil
+	})
 	if err != nil && !nas.SoftOnly(err) {
 		return nil, err
 	}
@@ -7,6 +7,9 @@ import (
 	"bytes"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/nas"
 )
 
 // TestPDNConnectivityRequestGolden walks the full real capture end to end:
@@ -104,6 +107,25 @@ func TestESMRoundTrips(t *testing.T) {
 		}
 	})
 
+	t.Run("PDNConnectivityRejectT3396", func(t *testing.T) {
+		t3396, err := nas.GPRSTimer3FromDuration(10 * time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		in := &PDNConnectivityReject{PTI: 0x15, Cause: ESMCauseInsufficientResources, T3396: &t3396}
+
+		b, err := in.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := ParsePDNConnectivityReject(b)
+		if err != nil || out.T3396 == nil || *out.T3396 != t3396 || len(out.Unrecognized) != 0 {
+			t.Fatalf("got %+v err %v", out, err)
+		}
+	})
+
 	t.Run("InfoRequest", func(t *testing.T) {
 		b, _ := (&ESMInformationRequest{PTI: 1}).MarshalBinary()
 
@@ -24,6 +24,7 @@ const (
 	ieiNewEPSQoS              uint8 = 0x5B
 	ieiRequiredTrafficFlowQoS uint8 = 0x5B
 	ieiAPNAMBR                uint8 = 0x5E
+	ieiT3396Value             uint8 = 0x37 // GPRS timer 3
 )
 
 // These IE codecs produce/consume the *value part* of an information element
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package eps
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/ellanetworks/core/nas"
+)
+
+// TFTDirection is the direction a TFT packet filter applies to (TS 24.008
+// §10.5.6.12).
+type TFTDirection uint8
+
+const (
+	TFTDownlink      TFTDirection = 1
+	TFTUplink        TFTDirection = 2
+	TFTBidirectional TFTDirection = 3
+)
+
+// TFT packet filter component types (TS 24.008 table 10.5.162).
+const (
+	tftComponentIPv4RemoteAddress = 0x10
+	tftComponentIPv6RemotePrefix  = 0x21
+	tftComponentProtocol          = 0x30
+	tftComponentSingleRemotePort  = 0x50
+	tftComponentRemotePortRange   = 0x51
+)
+
+// tftOpCreateNew is the "create new TFT" operation code, in bits 8-6 of the
+// first octet.
+const tftOpCreateNew = 1 << 5
+
+// maxTFTPacketFilters is how many packet filters a TFT carries at most.
+const maxTFTPacketFilters = 15
+
+// TFTPacketFilter is one packet filter of a traffic flow template.
+type TFTPacketFilter struct {
+	Identifier uint8
+	Direction  TFTDirection
+	Precedence uint8
+	Contents   []byte
+}
+
+// RemoteTFTPacketFilter builds a packet filter matching the traffic exchanged
+// with a remote party: an address prefix, an IP protocol and a remote port
+// range. An invalid prefix, a zero protocol and a zero port range match any.
+// TS 24.008 has no match-all component, so a filter matching any traffic is
+// an error.
+func RemoteTFTPacketFilter(id uint8, dir TFTDirection, precedence uint8, remote netip.Prefix, protocol uint8, portLow, portHigh uint16) (TFTPacketFilter, error) {
+	pf := TFTPacketFilter{Identifier: id, Direction: dir, Precedence: precedence}
+
+	if remote.IsValid() {
+		remote = remote.Masked()
+		addr := remote.Addr()
+
+		if addr.Is4() {
+			a4 := addr.As4()
+
+			var m uint32
+			if remote.Bits() > 0 {
+				m = ^uint32(0) << (32 - remote.Bits())
+			}
+
+			pf.Contents = append(pf.Contents, tftComponentIPv4RemoteAddress)
+			pf.Contents = append(pf.Contents, a4[:]...)
+			pf.Contents = append(pf.Contents, byte(m>>24), byte(m>>16), byte(m>>8), byte(m))
+		} else {
+			a16 := addr.As16()
+
+			pf.Contents = append(pf.Contents, tftComponentIPv6RemotePrefix)
+			pf.Contents = append(pf.Contents, a16[:]...)
+			pf.Contents = append(pf.Contents, byte(remote.Bits()))
+		}
+	}
+
+	if protocol != 0 {
+		pf.Contents = append(pf.Contents, tftComponentProtocol, protocol)
+	}
+
+	switch {
+	case portLow == 0 && portHigh == 0:
+	case portLow == portHigh:
+		pf.Contents = append(pf.Contents, tftComponentSingleRemotePort, byte(portLow>>8), byte(portLow))
+	default:
+		pf.Contents = append(pf.Contents, tftComponentRemotePortRange,
+			byte(portLow>>8), byte(portLow), byte(portHigh>>8), byte(portHigh))
+	}
+
+	if len(pf.Contents) == 0 {
+		return TFTPacketFilter{}, errors.New("nas/eps: a TFT packet filter cannot match all traffic")
+	}
+
+	return pf, nil
+}
+
+// NewTFT encodes a traffic flow template value that creates a TFT of filters
+// (TS 24.008 §10.5.6.12).
+func NewTFT(filters ...TFTPacketFilter) ([]byte, error) {
+	if len(filters) == 0 || len(filters) > maxTFTPacketFilters {
+		return nil, fmt.Errorf("nas/eps: a new TFT carries 1 to %d packet filters, not %d", maxTFTPacketFilters, len(filters))
+	}
+
+	w := nas.NewWriter(nil)
+
+	w.U8(tftOpCreateNew | uint8(len(filters)))
+
+	for _, pf := range filters {
+		w.U8(uint8(pf.Direction)&0x03<<4 | pf.Identifier&0x0F)
+		w.U8(pf.Precedence)
+		w.LV(pf.Contents)
+	}
+
+	return w.Result(nil)
+}
+
+// gbrRateMax is the highest rate the EPS QoS bit-rate octets carry without
+// their extended-2 octets, which Ella Core does not emit.
+const gbrRateMax = 256_000_000
+
+// GBRBitRates encodes the maximum and guaranteed bit rates of a GBR bearer,
+// in bit/s, into the optional octets of the EPS QoS (TS 24.301 §9.9.4.3).
+// The extended octets follow only when a rate needs them. A rate above
+// 256 Mbit/s is an error.
+func GBRBitRates(mbrUplink, mbrDownlink, gbrUplink, gbrDownlink uint64) ([]byte, error) {
+	rates := [4]uint64{mbrUplink, mbrDownlink, gbrUplink, gbrDownlink}
+
+	var base, ext [4]uint8
+
+	extended := false
+
+	for i, bps := range rates {
+		if bps > gbrRateMax {
+			return nil, fmt.Errorf("nas/eps: %d bit/s exceeds the %d bit/s an EPS QoS carries", bps, gbrRateMax)
+		}
+
+		base[i], ext[i] = encodeAPNAMBRBase(bps)
+		extended = extended || ext[i] != 0
+	}
+
+	if !extended {
+		return base[:], nil
+	}
+
+	return append(base[:], ext[:]...), nil
+}
@@ -68,3 +68,12 @@ const (
 	ieiOldGUTIType             uint8 = 0xE0
 	ieiAdditionalUpdateType    uint8 = 0xF0
 )
+
+// ESM bearer context IEs of the dedicated bearer messages (TS 24.301 §8.3.1 to
+// §8.3.3) that Ella Core steps over without modelling.
+const (
+	ieiTransactionIdentifier uint8 = 0x5D
+	ieiNegotiatedQoS         uint8 = 0x30
+	ieiPacketFlowIdentifier  uint8 = 0x34
+	ieiNBIFOMContainer       uint8 = 0x33
+)
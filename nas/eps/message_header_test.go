@@ -22,6 +22,9 @@ func TestESMHeaderAccessors(t *testing.T) {
 		&ActivateDefaultEPSBearerContextRequest{EPSBearerIdentity: bearer, PTI: pti},
 		&ActivateDefaultEPSBearerContextAccept{EPSBearerIdentity: bearer, PTI: pti},
 		&ActivateDefaultEPSBearerContextReject{EPSBearerIdentity: bearer, PTI: pti},
+		&ActivateDedicatedEPSBearerContextRequest{EPSBearerIdentity: bearer, PTI: pti},
+		&ActivateDedicatedEPSBearerContextAccept{EPSBearerIdentity: bearer, PTI: pti},
+		&ActivateDedicatedEPSBearerContextReject{EPSBearerIdentity: bearer, PTI: pti},
 		&BearerResourceAllocationRequest{EPSBearerIdentity: bearer, PTI: pti},
 		&BearerResourceAllocationReject{EPSBearerIdentity: bearer, PTI: pti},
 		&BearerResourceModificationRequest{EPSBearerIdentity: bearer, PTI: pti},
@@ -51,6 +51,18 @@ func (m *ActivateDefaultEPSBearerContextReject) MessageType() ESMMessageType {
 	return MsgActivateDefaultEPSBearerContextReject
 }
 
+func (m *ActivateDedicatedEPSBearerContextRequest) MessageType() ESMMessageType {
+	return MsgActivateDedicatedEPSBearerContextRequest
+}
+
+func (m *ActivateDedicatedEPSBearerContextAccept) MessageType() ESMMessageType {
+	return MsgActivateDedicatedEPSBearerContextAccept
+}
+
+func (m *ActivateDedicatedEPSBearerContextReject) MessageType() ESMMessageType {
+	return MsgActivateDedicatedEPSBearerContextReject
+}
+
 func (m *BearerResourceAllocationRequest) MessageType() ESMMessageType {
 	return MsgBearerResourceAllocationRequest
 }
@@ -94,52 +106,55 @@ func (m *PDNDisconnectReject) MessageType() ESMMessageType    { return MsgPDNDis
 func (m *ESMStatus) MessageType() ESMMessageType              { return MsgESMStatus }
 
 // The messages of this package, and only they, are Messages.
-func (m *AttachRequest) isMessage()                          {}
-func (m *AttachAccept) isMessage()                           {}
-func (m *AttachComplete) isMessage()                         {}
-func (m *AttachReject) isMessage()                           {}
-func (m *AuthenticationRequest) isMessage()                  {}
-func (m *AuthenticationResponse) isMessage()                 {}
-func (m *AuthenticationReject) isMessage()                   {}
-func (m *AuthenticationFailure) isMessage()                  {}
-func (m *DetachRequestUE) isMessage()                        {}
-func (m *DetachRequestNetwork) isMessage()                   {}
-func (m *DetachAccept) isMessage()                           {}
-func (m *GUTIReallocationCommand) isMessage()                {}
-func (m *GUTIReallocationComplete) isMessage()               {}
-func (m *IdentityRequest) isMessage()                        {}
-func (m *IdentityResponse) isMessage()                       {}
-func (m *EMMInformation) isMessage()                         {}
-func (m *SecurityModeCommand) isMessage()                    {}
-func (m *SecurityModeComplete) isMessage()                   {}
-func (m *SecurityModeReject) isMessage()                     {}
-func (m *ServiceReject) isMessage()                          {}
-func (m *ServiceAccept) isMessage()                          {}
-func (m *EMMStatus) isMessage()                              {}
-func (m *TrackingAreaUpdateRequest) isMessage()              {}
-func (m *TrackingAreaUpdateAccept) isMessage()               {}
-func (m *TrackingAreaUpdateComplete) isMessage()             {}
-func (m *TrackingAreaUpdateReject) isMessage()               {}
-func (m *ActivateDefaultEPSBearerContextRequest) isMessage() {}
-func (m *ActivateDefaultEPSBearerContextAccept) isMessage()  {}
-func (m *ActivateDefaultEPSBearerContextReject) isMessage()  {}
-func (m *BearerResourceAllocationRequest) isMessage()        {}
-func (m *BearerResourceAllocationReject) isMessage()         {}
-func (m *BearerResourceModificationRequest) isMessage()      {}
-func (m *BearerResourceModificationReject) isMessage()       {}
-func (m *DeactivateEPSBearerContextRequest) isMessage()      {}
-func (m *DeactivateEPSBearerContextAccept) isMessage()       {}
-func (m *ESMInformationRequest) isMessage()                  {}
-func (m *ESMInformationResponse) isMessage()                 {}
-func (m *ModifyEPSBearerContextRequest) isMessage()          {}
-func (m *ModifyEPSBearerContextAccept) isMessage()           {}
-func (m *ModifyEPSBearerContextReject) isMessage()           {}
-func (m *PDNConnectivityRequest) isMessage()                 {}
-func (m *PDNConnectivityReject) isMessage()                  {}
-func (m *PDNDisconnectRequest) isMessage()                   {}
-func (m *PDNDisconnectReject) isMessage()                    {}
-func (m *ESMStatus) isMessage()                              {}
-func (m *ServiceRequest) isMessage()                         {}
+func (m *AttachRequest) isMessage()                            {}
+func (m *AttachAccept) isMessage()                             {}
+func (m *AttachComplete) isMessage()                           {}
+func (m *AttachReject) isMessage()                             {}
+func (m *AuthenticationRequest) isMessage()                    {}
+func (m *AuthenticationResponse) isMessage()                   {}
+func (m *AuthenticationReject) isMessage()                     {}
+func (m *AuthenticationFailure) isMessage()                    {}
+func (m *DetachRequestUE) isMessage()                          {}
+func (m *DetachRequestNetwork) isMessage()                     {}
+func (m *DetachAccept) isMessage()                             {}
+func (m *GUTIReallocationCommand) isMessage()                  {}
+func (m *GUTIReallocationComplete) isMessage()                 {}
+func (m *IdentityRequest) isMessage()                          {}
+func (m *IdentityResponse) isMessage()                         {}
+func (m *EMMInformation) isMessage()                           {}
+func (m *SecurityModeCommand) isMessage()                      {}
+func (m *SecurityModeComplete) isMessage()                     {}
+func (m *SecurityModeReject) isMessage()                       {}
+func (m *ServiceReject) isMessage()                            {}
+func (m *ServiceAccept) isMessage()                            {}
+func (m *EMMStatus) isMessage()                                {}
+func (m *TrackingAreaUpdateRequest) isMessage()                {}
+func (m *TrackingAreaUpdateAccept) isMessage()                 {}
+func (m *TrackingAreaUpdateComplete) isMessage()               {}
+func (m *TrackingAreaUpdateReject) isMessage()                 {}
+func (m *ActivateDefaultEPSBearerContextRequest) isMessage()   {}
+func (m *ActivateDefaultEPSBearerContextAccept) isMessage()    {}
+func (m *ActivateDefaultEPSBearerContextReject) isMessage()    {}
+func (m *ActivateDedicatedEPSBearerContextRequest) isMessage() {}
+func (m *ActivateDedicatedEPSBearerContextAccept) isMessage()  {}
+func (m *ActivateDedicatedEPSBearerContextReject) isMessage()  {}
+func (m *BearerResourceAllocationRequest) isMessage()          {}
+func (m *BearerResourceAllocationReject) isMessage()           {}
+func (m *BearerResourceModificationRequest) isMessage()        {}
+func (m *BearerResourceModificationReject) isMessage()         {}
+func (m *DeactivateEPSBearerContextRequest) isMessage()        {}
+func (m *DeactivateEPSBearerContextAccept) isMessage()         {}
+func (m *ESMInformationRequest) isMessage()                    {}
+func (m *ESMInformationResponse) isMessage()                   {}
+func (m *ModifyEPSBearerContextRequest) isMessage()            {}
+func (m *ModifyEPSBearerContextAccept) isMessage()             {}
+func (m *ModifyEPSBearerContextReject) isMessage()             {}
+func (m *PDNConnectivityRequest) isMessage()                   {}
+func (m *PDNConnectivityReject) isMessage()                    {}
+func (m *PDNDisconnectRequest) isMessage()                     {}
+func (m *PDNDisconnectReject) isMessage()                      {}
+func (m *ESMStatus) isMessage()                                {}
+func (m *ServiceRequest) isMessage()                           {}
 
 // Every message of this package implements its generation's interface, whether
 // or not the dispatch table reaches it.
@@ -173,6 +188,9 @@ var (
 	_ ESMMessage = (*ActivateDefaultEPSBearerContextRequest)(nil)
 	_ ESMMessage = (*ActivateDefaultEPSBearerContextAccept)(nil)
 	_ ESMMessage = (*ActivateDefaultEPSBearerContextReject)(nil)
+	_ ESMMessage = (*ActivateDedicatedEPSBearerContextRequest)(nil)
+	_ ESMMessage = (*ActivateDedicatedEPSBearerContextAccept)(nil)
+	_ ESMMessage = (*ActivateDedicatedEPSBearerContextReject)(nil)
 	_ ESMMessage = (*BearerResourceAllocationRequest)(nil)
 	_ ESMMessage = (*BearerResourceAllocationReject)(nil)
 	_ ESMMessage = (*BearerResourceModificationRequest)(nil)
@@ -207,6 +225,18 @@ func (m *ActivateDefaultEPSBearerContextReject) BearerIdentity() EPSBearerIdenti
 	return m.EPSBearerIdentity
 }
 
+func (m *ActivateDedicatedEPSBearerContextRequest) BearerIdentity() EPSBearerIdentity {
+	return m.EPSBearerIdentity
+}
+
+func (m *ActivateDedicatedEPSBearerContextAccept) BearerIdentity() EPSBearerIdentity {
+	return m.EPSBearerIdentity
+}
+
+func (m *ActivateDedicatedEPSBearerContextReject) BearerIdentity() EPSBearerIdentity {
+	return m.EPSBearerIdentity
+}
+
 func (m *BearerResourceAllocationRequest) BearerIdentity() EPSBearerIdentity {
 	return m.EPSBearerIdentity
 }
@@ -256,6 +286,18 @@ func (m *ActivateDefaultEPSBearerContextReject) TransactionIdentity() nas.Proced
 	return m.PTI
 }
 
+func (m *ActivateDedicatedEPSBearerContextRequest) TransactionIdentity() nas.ProcedureTransactionIdentity {
+	return m.PTI
+}
+
+func (m *ActivateDedicatedEPSBearerContextAccept) TransactionIdentity() nas.ProcedureTransactionIdentity {
+	return m.PTI
+}
+
+func (m *ActivateDedicatedEPSBearerContextReject) TransactionIdentity() nas.ProcedureTransactionIdentity {
+	return m.PTI
+}
+
 func (m *BearerResourceAllocationRequest) TransactionIdentity() nas.ProcedureTransactionIdentity {
 	return m.PTI
 }
@@ -320,23 +320,26 @@ var emmParsers = map[MessageType]func([]byte) (Message, error){
 
 // esmParsers dispatches an ESM message type to its parser.
 var esmParsers = map[ESMMessageType]func([]byte) (Message, error){
-	MsgActivateDefaultEPSBearerContextAccept:  esmParser(ParseActivateDefaultEPSBearerContextAccept),
-	MsgActivateDefaultEPSBearerContextReject:  esmParser(ParseActivateDefaultEPSBearerContextReject),
-	MsgActivateDefaultEPSBearerContextRequest: esmParser(ParseActivateDefaultEPSBearerContextRequest),
-	MsgBearerResourceAllocationReject:         esmParser(ParseBearerResourceAllocationReject),
-	MsgBearerResourceAllocationRequest:        esmParser(ParseBearerResourceAllocationRequest),
-	MsgBearerResourceModificationReject:       esmParser(ParseBearerResourceModificationReject),
-	MsgBearerResourceModificationRequest:      esmParser(ParseBearerResourceModificationRequest),
-	MsgDeactivateEPSBearerContextAccept:       esmParser(ParseDeactivateEPSBearerContextAccept),
-	MsgDeactivateEPSBearerContextRequest:      esmParser(ParseDeactivateEPSBearerContextRequest),
-	MsgESMInformationRequest:                  esmParser(ParseESMInformationRequest),
-	MsgESMInformationResponse:                 esmParser(ParseESMInformationResponse),
-	MsgESMStatus:                              esmParser(ParseESMStatus),
-	MsgModifyEPSBearerContextAccept:           esmParser(ParseModifyEPSBearerContextAccept),
-	MsgModifyEPSBearerContextReject:           esmParser(ParseModifyEPSBearerContextReject),
-	MsgModifyEPSBearerContextRequest:          esmParser(ParseModifyEPSBearerContextRequest),
-	MsgPDNConnectivityReject:                  esmParser(ParsePDNConnectivityReject),
-	MsgPDNConnectivityRequest:                 esmParser(ParsePDNConnectivityRequest),
-	MsgPDNDisconnectReject:                    esmParser(ParsePDNDisconnectReject),
-	MsgPDNDisconnectRequest:                   esmParser(ParsePDNDisconnectRequest),
+	MsgActivateDefaultEPSBearerContextAccept:    esmParser(ParseActivateDefaultEPSBearerContextAccept),
+	MsgActivateDefaultEPSBearerContextReject:    esmParser(ParseActivateDefaultEPSBearerContextReject),
+	MsgActivateDefaultEPSBearerContextRequest:   esmParser(ParseActivateDefaultEPSBearerContextRequest),
+	MsgActivateDedicatedEPSBearerContextAccept:  esmParser(ParseActivateDedicatedEPSBearerContextAccept),
+	MsgActivateDedicatedEPSBearerContextReject:  esmParser(ParseActivateDedicatedEPSBearerContextReject),
+	MsgActivateDedicatedEPSBearerContextRequest: esmParser(ParseActivateDedicatedEPSBearerContextRequest),
+	MsgBearerResourceAllocationReject:           esmParser(ParseBearerResourceAllocationReject),
+	MsgBearerResourceAllocationRequest:          esmParser(ParseBearerResourceAllocationRequest),
+	MsgBearerResourceModificationReject:         esmParser(ParseBearerResourceModificationReject),
+	MsgBearerResourceModificationRequest:        esmParser(ParseBearerResourceModificationRequest),
+	MsgDeactivateEPSBearerContextAccept:         esmParser(ParseDeactivateEPSBearerContextAccept),
+	MsgDeactivateEPSBearerContextRequest:        esmParser(ParseDeactivateEPSBearerContextRequest),
+	MsgESMInformationRequest:                    esmParser(ParseESMInformationRequest),
+	MsgESMInformationResponse:                   esmParser(ParseESMInformationResponse),
+	MsgESMStatus:                                esmParser(ParseESMStatus),
+	MsgModifyEPSBearerContextAccept:             esmParser(ParseModifyEPSBearerContextAccept),
+	MsgModifyEPSBearerContextReject:             esmParser(ParseModifyEPSBearerContextReject),
+	MsgModifyEPSBearerContextRequest:            esmParser(ParseModifyEPSBearerContextRequest),
+	MsgPDNConnectivityReject:                    esmParser(ParsePDNConnectivityReject),
+	MsgPDNConnectivityRequest:                   esmParser(ParsePDNConnectivityRequest),
+	MsgPDNDisconnectReject:                      esmParser(ParsePDNDisconnectReject),
+	MsgPDNDisconnectRequest:                     esmParser(ParsePDNDisconnectRequest),
 }
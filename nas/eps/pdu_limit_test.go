@@ -27,6 +27,9 @@ func TestAppendBinaryEnforcesPDULimit(t *testing.T) {
 		&ActivateDefaultEPSBearerContextAccept{},
 		&ActivateDefaultEPSBearerContextReject{},
 		&ActivateDefaultEPSBearerContextRequest{AccessPointName: APN("internet")},
+		&ActivateDedicatedEPSBearerContextAccept{},
+		&ActivateDedicatedEPSBearerContextReject{},
+		&ActivateDedicatedEPSBearerContextRequest{},
 		&AttachAccept{TAIList: TAIList{{Type: PartialTAIListConsecutive, TAIs: []TAI{{PLMN: nas.PLMN{MCC: "001", MNC: "01"}, TAC: 1}}}}},
 		&AttachComplete{},
 		&AttachReject{},
@@ -209,7 +209,7 @@ func canonicalCases(t *testing.T) []canonicalCase {
 		{
 			name:   "PDUSessionModificationCommand (TS 24.501 §8.3.9)",
 			bare:   &PDUSessionModificationCommand{},
-			order:  []canonicalIE{{ieiSessionAMBR, nas.IETLV}, {ieiQoSFlowDescription, nas.IETLVE}, {ieiExtendedPCO, nas.IETLVE}},
+			order:  []canonicalIE{{ieiSessionAMBR, nas.IETLV}, {ieiAuthorizedQoSRules, nas.IETLVE}, {ieiQoSFlowDescription, nas.IETLVE}, {ieiExtendedPCO, nas.IETLVE}},
 			values: qos,
 		},
 		{
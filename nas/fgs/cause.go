@@ -160,6 +160,7 @@ const (
 	GSMCausePDUSessionTypeUnstructuredOnlyAllowed           GSMCause = 58
 	GSMCauseUnsupported5QIValue                             GSMCause = 59
 	GSMCausePDUSessionTypeEthernetOnlyAllowed               GSMCause = 61
+	GSMCauseMaximumNumberOfPDUSessionsReached               GSMCause = 65
 	GSMCauseInsufficientResourcesForSpecificSliceAndDNN     GSMCause = 67
 	GSMCauseNotSupportedSSCMode                             GSMCause = 68
 	GSMCauseInsufficientResourcesForSpecificSlice           GSMCause = 69
@@ -208,6 +209,7 @@ var cause5GSMNames = map[GSMCause]string{
 	GSMCausePDUSessionTypeUnstructuredOnlyAllowed:           "PDU session type Unstructured only allowed",
 	GSMCauseUnsupported5QIValue:                             "Unsupported 5QI value",
 	GSMCausePDUSessionTypeEthernetOnlyAllowed:               "PDU session type Ethernet only allowed",
+	GSMCauseMaximumNumberOfPDUSessionsReached:               "Maximum number of PDU sessions reached",
 	GSMCauseInsufficientResourcesForSpecificSliceAndDNN:     "Insufficient resources for specific slice and DNN",
 	GSMCauseNotSupportedSSCMode:                             "Not supported SSC mode",
 	GSMCauseInsufficientResourcesForSpecificSlice:           "Insufficient resources for specific slice",
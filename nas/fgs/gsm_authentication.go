@@ -5,6 +5,96 @@ package fgs
 
 import "github.com/ellanetworks/core/nas"
 
+// PDUSessionAuthenticationCommand is the PDU SESSION AUTHENTICATION COMMAND
+// (TS 24.501 §8.3.4): the 5GSM header, a mandatory EAP message, and optionally
+// the extended protocol configuration options. The network sends it with the
+// PTI unassigned, to relay one EAP request of the DN-AAA server to the UE
+// (§6.3.1).
+type PDUSessionAuthenticationCommand struct {
+	PDUSessionID PDUSessionID
+	PTI          nas.ProcedureTransactionIdentity
+	// EAP is the EAP request relayed to the UE (TS 24.501 §9.11.2.2).
+	EAP         []byte
+	ExtendedPCO *nas.ProtocolConfigurationOptions // optional (IEI 0x7B)
+
+	// Unrecognized carries the optional information elements this message does
+	// not model, so they survive decoding and re-encode unchanged.
+	Unrecognized []nas.RawIE
+}
+
+// AppendBinary encodes the plain PDU SESSION AUTHENTICATION COMMAND message.
+// The encoding is appended to b.
+func (m *PDUSessionAuthenticationCommand) AppendBinary(b []byte) ([]byte, error) {
+	w := nas.NewWriter(b)
+
+	var o nas.OptionalWriter
+
+	writeGSMHeader(w, m.PDUSessionID, m.PTI, MsgPDUSessionAuthenticationCommand)
+	w.LVE(m.EAP)
+
+	if m.ExtendedPCO != nil {
+		raw, err := m.ExtendedPCO.MarshalBinary()
+		if err != nil {
+			return b, err
+		}
+
+		o.TLVE(ieiExtendedPCO, raw)
+	}
+
+	o.Raw(m.Unrecognized...)
+	o.WriteTo(w)
+
+	return messageResult(w, b)
+}
+
+// MarshalBinary encodes the message.
+func (m *PDUSessionAuthenticationCommand) MarshalBinary() ([]byte, error) { return marshalMessage(m) }
+
+// authenticationCommandIEs is the full-octet optional-IE table of the PDU
+// SESSION AUTHENTICATION COMMAND (TS 24.501 §8.3.4, table 8.3.4.1.1).
+var authenticationCommandIEs = []nas.OptionalIE{
+	{IEI: ieiExtendedPCO, Format: nas.IETLVE, Name: "Extended PCO"},
+}
+
+// ParsePDUSessionAuthenticationCommand decodes the message.
+func ParsePDUSessionAuthenticationCommand(b []byte) (*PDUSessionAuthenticationCommand, error) {
+	r := nas.NewReader(b)
+
+	psi, pti, err := readGSMHeader(r, MsgPDUSessionAuthenticationCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	eap, err := r.LVE()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &PDUSessionAuthenticationCommand{PDUSessionID: psi, PTI: pti, EAP: eap}
+
+	_unrec, err := walkOptionalIEs(r, authenticationCommandIEs, func(iei uint8, value []byte) (bool, error) {
+		if iei != ieiExtendedPCO {
+			return false, nil
+		}
+
+		parsed, err := nas.ParseExtendedProtocolConfigurationOptions(value, nas.PCONetworkToMS)
+		if err != nil {
+			return false, err
+		}
+
+		out.ExtendedPCO = &parsed
+
+		return true, nil
+	})
+	if err != nil && !nas.SoftOnly(err) {
+		return nil, err
+	}
+
+	out.Unrecognized = _unrec
+
+	return out, err
+}
+
 // PDUSessionAuthenticationComplete is the PDU SESSION AUTHENTICATION COMPLETE
 // (TS 24.501 §8.3.5): the 5GSM header, a mandatory EAP message, and optionally
 // the extended protocol configuration options.
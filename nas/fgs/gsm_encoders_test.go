@@ -6,6 +6,7 @@ package fgs
 import (
 	"encoding/hex"
 	"net"
+	"net/netip"
 	"reflect"
 	"testing"
 
@@ -114,6 +115,7 @@ func TestModificationCommandRoundTrip(t *testing.T) {
 		PDUSessionID:        4,
 		PTI:                 0,
 		SessionAMBR:         &SessionAMBR{DownlinkUnit: SessionAMBRUnit1Mbps, Downlink: 100, UplinkUnit: SessionAMBRUnit1Mbps, Uplink: 50},
+		QoSRules:            QoSRules{DedicatedQoSRule(2, 3, 10, RemotePacketFilter(1, PacketFilterBidirectional, netip.MustParsePrefix("198.51.100.7/32"), 6, 0, 0))},
 		QoSFlowDescriptions: QoSFlowDescriptions{FiveQIQoSFlow(3, 7, QoSFlowOpModify)},
 	}
 
@@ -127,7 +129,7 @@ func TestModificationCommandRoundTrip(t *testing.T) {
 		t.Fatalf("Parse: %v", err)
 	}
 
-	if !reflect.DeepEqual(got.SessionAMBR, orig.SessionAMBR) ||
+	if !reflect.DeepEqual(got.SessionAMBR, orig.SessionAMBR) || !reflect.DeepEqual(got.QoSRules, orig.QoSRules) ||
 		!reflect.DeepEqual(got.QoSFlowDescriptions, orig.QoSFlowDescriptions) || got.ExtendedPCO != nil {
 		t.Fatalf("round-trip mismatch:\n got %+v\nwant %+v", got, orig)
 	}
@@ -145,3 +147,48 @@ func assertMarshal(t *testing.T, fn func() ([]byte, error), want string) {
 		t.Fatalf("MarshalBinary =\n %s\nwant\n %s", got, want)
 	}
 }
+
+func TestDedicatedQoSFlowEncoders(t *testing.T) {
+	rule := DedicatedQoSRule(2, 2, 10, RemotePacketFilter(1, PacketFilterBidirectional, netip.MustParsePrefix("192.0.2.0/24"), 17, 5000, 5010))
+	if got := hex.EncodeToString(mustBytes(QoSRules{rule}.MarshalBinary())); got != "02001521311010c0000200ffffff00301151138813920a02" {
+		t.Errorf("dedicated QoS rule = %s", got)
+	}
+
+	if got := hex.EncodeToString(mustBytes(QoSRules{DeletedQoSRule(2)}.MarshalBinary())); got != "02000140" {
+		t.Errorf("deleted QoS rule = %s", got)
+	}
+
+	flow, err := GBRQoSFlow(2, 2, QoSFlowOpCreate, 2000, 4000, 5000, 10000)
+	if err != nil {
+		t.Fatalf("GBRQoSFlow: %v", err)
+	}
+
+	back, err := ParseQoSFlowDescriptions(mustBytes(QoSFlowDescriptions{flow}.MarshalBinary()))
+	if err != nil || len(back) != 1 {
+		t.Fatalf("ParseQoSFlowDescriptions = %v, %v", back, err)
+	}
+
+	want := map[QoSFlowParameterID]uint64{QoSFlowParamGFBRUplink: 2000, QoSFlowParamGFBRDownlink: 4000, QoSFlowParamMFBRUplink: 5000, QoSFlowParamMFBRDownlink: 10000}
+	for _, p := range back[0].Parameters {
+		if kbps, ok := p.Kbps(); ok && kbps != want[p.ID] {
+			t.Errorf("%s = %d kbps, want %d", p.ID, kbps, want[p.ID])
+		}
+	}
+
+	if got := hex.EncodeToString(mustBytes(QoSFlowDescriptions{DeletedQoSFlow(2)}.MarshalBinary())); got != "024000" {
+		t.Errorf("deleted QoS flow = %s", got)
+	}
+}
+
+func TestRemotePacketFilterMatchAll(t *testing.T) {
+	pf := RemotePacketFilter(1, PacketFilterUplink, netip.Prefix{}, 0, 0, 0)
+	if len(pf.Components) != 1 || pf.Components[0].Type != pfComponentTypeMatchAll {
+		t.Fatalf("components = %+v, want match-all", pf.Components)
+	}
+
+	v6 := RemotePacketFilter(1, PacketFilterDownlink, netip.MustParsePrefix("2001:db8::/32"), 0, 443, 443)
+	if len(v6.Components) != 2 || v6.Components[0].Type != pfComponentTypeIPv6RemoteAddress || v6.Components[0].Value[16] != 32 ||
+		v6.Components[1].Type != pfComponentTypeSingleRemotePort {
+		t.Fatalf("components = %+v", v6.Components)
+	}
+}
@@ -219,6 +219,12 @@ type PDUSessionEstablishmentReject struct {
 	PDUSessionID PDUSessionID
 	PTI          nas.ProcedureTransactionIdentity
 	Cause        GSMCause
+	// BackoffTimer is how long the UE waits before requesting the session
+	// again (TS 24.501 §6.4.1.4.2).
+	BackoffTimer *nas.GPRSTimer3 // optional (IEI 0x37)
+	// EAP carries the EAP-Failure of a failed secondary authentication
+	// (TS 24.501 §6.3.1.3).
+	EAP []byte // optional (IEI 0x78)
 
 	// Unrecognized carries the optional information elements this message does
 	// not model, so they survive decoding and re-encode unchanged.
@@ -235,6 +241,19 @@ func (m *PDUSessionEstablishmentReject) AppendBinary(b []byte) ([]byte, error) {
 	writeGSMHeader(w, m.PDUSessionID, m.PTI, MsgPDUSessionEstablishmentReject)
 	w.U8(uint8(m.Cause))
 
+	if m.BackoffTimer != nil {
+		raw, err := m.BackoffTimer.MarshalBinary()
+		if err != nil {
+			return b, err
+		}
+
+		o.TLV(ieiBackoffTimer, raw)
+	}
+
+	if m.EAP != nil {
+		o.TLVE(ieiEAPMessageSession, m.EAP)
+	}
+
 	o.Raw(m.Unrecognized...)
 	o.WriteTo(w)
 
@@ -264,7 +283,23 @@ func ParsePDUSessionEstablishmentReject(b []byte) (*PDUSessionEstablishmentRejec
 		Cause:        GSMCause(cause),
 	}
 
-	_unrec, err := walkOptionalIEs(r, nil, declineAll)
+	_unrec, err := walkOptionalIEs(r, establishmentRejectIEs, func(iei uint8, value []byte) (bool, error) {
+		switch iei {
+		case ieiBackoffTimer:
+			timer, err := nas.ParseGPRSTimer3(value)
+			if err != nil {
+				return false, err
+			}
+
+			out.BackoffTimer = &timer
+		case ieiEAPMessageSession:
+			out.EAP = value
+		default:
+			return false, nil
+		}
+
+		return true, nil
+	})
 	if err != nil && !nas.SoftOnly(err) {
 		return nil, err
 	}
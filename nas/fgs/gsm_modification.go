@@ -315,6 +315,7 @@ type PDUSessionModificationCommand struct {
 	PDUSessionID PDUSessionID
 	PTI          nas.ProcedureTransactionIdentity
 	SessionAMBR  *SessionAMBR // optional (IEI 0x2A)
+	QoSRules     QoSRules     // optional (IEI 0x7A)
 
 	// MappedEPSBearerContexts carries the EPS bearer contexts the session's QoS
 	// flows map to (IEI 0x75). TS 24.501 §6.1.4.2 has the SMF provide them only
@@ -348,6 +349,15 @@ func (m *PDUSessionModificationCommand) AppendBinary(b []byte) ([]byte, error) {
 		o.TLV(ieiSessionAMBR, raw)
 	}
 
+	if m.QoSRules != nil {
+		raw, err := m.QoSRules.MarshalBinary()
+		if err != nil {
+			return b, err
+		}
+
+		o.TLVE(ieiAuthorizedQoSRules, raw)
+	}
+
 	if m.MappedEPSBearerContexts != nil {
 		raw, err := m.MappedEPSBearerContexts.MarshalBinary()
 		if err != nil {
@@ -404,6 +414,13 @@ func ParsePDUSessionModificationCommand(b []byte) (*PDUSessionModificationComman
 			}
 
 			out.SessionAMBR = &parsed
+		case ieiAuthorizedQoSRules:
+			parsed, err := ParseQoSRules(value)
+			if err != nil {
+				return false, err
+			}
+
+			out.QoSRules = parsed
 		case ieiMappedEPSBearerContext:
 			parsed, err := ParseMappedEPSBearerContexts(value)
 			if err != nil {
@@ -5,6 +5,7 @@ package fgs
 
 import (
 	"fmt"
+	"net/netip"
 
 	"github.com/ellanetworks/core/nas"
 )
@@ -203,6 +204,73 @@ func DefaultQoSRule(id, qfi uint8) QoSRule {
 	}
 }
 
+// DedicatedQoSRule builds a QoS rule sending the traffic filters match to the
+// QoS flow qfi, evaluated ahead of every rule of a higher precedence value.
+func DedicatedQoSRule(id, qfi, precedence uint8, filters ...PacketFilter) QoSRule {
+	return QoSRule{
+		Identifier:    id,
+		OperationCode: QoSRuleOpCreate,
+		Parameters:    &QoSRuleParameters{Precedence: precedence, QFI: qfi},
+		Filters:       filters,
+	}
+}
+
+// DeletedQoSRule builds the deletion of the QoS rule id, which carries no
+// packet filters and no parameters (TS 24.501 §9.11.4.13).
+func DeletedQoSRule(id uint8) QoSRule {
+	return QoSRule{Identifier: id, OperationCode: QoSRuleOpDelete}
+}
+
+// RemotePacketFilter builds a packet filter matching the traffic exchanged
+// with a remote party: an address prefix, an IP protocol and a remote port
+// range. An invalid prefix, a zero protocol and a zero port range match any;
+// a filter left matching everything is the match-all filter.
+func RemotePacketFilter(id uint8, dir PacketFilterDirection, remote netip.Prefix, protocol uint8, portLow, portHigh uint16) PacketFilter {
+	pf := PacketFilter{Identifier: id, Direction: dir}
+
+	if remote.IsValid() {
+		remote = remote.Masked()
+		addr := remote.Addr()
+
+		if addr.Is4() {
+			a4 := addr.As4()
+
+			var m uint32
+			if remote.Bits() > 0 {
+				m = ^uint32(0) << (32 - remote.Bits())
+			}
+
+			value := append(a4[:], byte(m>>24), byte(m>>16), byte(m>>8), byte(m))
+			pf.Components = append(pf.Components, PacketFilterComponent{Type: pfComponentTypeIPv4RemoteAddress, Value: value})
+		} else {
+			a16 := addr.As16()
+			value := append(a16[:], byte(remote.Bits()))
+			pf.Components = append(pf.Components, PacketFilterComponent{Type: pfComponentTypeIPv6RemoteAddress, Value: value})
+		}
+	}
+
+	if protocol != 0 {
+		pf.Components = append(pf.Components, PacketFilterComponent{Type: pfComponentTypeProtocolIdentifier, Value: []byte{protocol}})
+	}
+
+	switch {
+	case portLow == 0 && portHigh == 0:
+	case portLow == portHigh:
+		pf.Components = append(pf.Components, PacketFilterComponent{Type: pfComponentTypeSingleRemotePort, Value: []byte{byte(portLow >> 8), byte(portLow)}})
+	default:
+		pf.Components = append(pf.Components, PacketFilterComponent{
+			Type:  pfComponentTypeRemotePortRange,
+			Value: []byte{byte(portLow >> 8), byte(portLow), byte(portHigh >> 8), byte(portHigh)},
+		})
+	}
+
+	if len(pf.Components) == 0 {
+		pf.Components = []PacketFilterComponent{{Type: pfComponentTypeMatchAll}}
+	}
+
+	return pf
+}
+
 func (f PacketFilter) marshal(w *nas.Writer) {
 	// Direction is 2 bits at bits 6-5 and the identifier 4 bits at bits 4-1
 	// (TS 24.501 figure 9.11.4.13.4); masking keeps an out-of-range field from
@@ -324,6 +392,38 @@ func FiveQIQoSFlow(qfi, fiveQI uint8, opCode QoSFlowOperation) QoSFlowDescriptio
 	}
 }
 
+// GBRQoSFlow builds the description of a guaranteed bit rate QoS flow: its
+// 5QI and its guaranteed and maximum flow bit rates, in kbps, each carried in
+// the finest unit that holds it (TS 24.501 §9.11.4.12).
+func GBRQoSFlow(qfi, fiveQI uint8, opCode QoSFlowOperation, gfbrUplink, gfbrDownlink, mfbrUplink, mfbrDownlink uint64) (QoSFlowDescription, error) {
+	flow := FiveQIQoSFlow(qfi, fiveQI, opCode)
+
+	for _, r := range []struct {
+		id   QoSFlowParameterID
+		kbps uint64
+	}{
+		{QoSFlowParamGFBRUplink, gfbrUplink},
+		{QoSFlowParamGFBRDownlink, gfbrDownlink},
+		{QoSFlowParamMFBRUplink, mfbrUplink},
+		{QoSFlowParamMFBRDownlink, mfbrDownlink},
+	} {
+		unit, v, err := sessionAMBRFields(r.kbps)
+		if err != nil {
+			return QoSFlowDescription{}, fmt.Errorf("nas/fgs: QoS flow %d %s: %w", qfi, r.id.Name(), err)
+		}
+
+		flow.Parameters = append(flow.Parameters, QoSFlowParameter{ID: r.id, Value: []byte{uint8(unit), byte(v >> 8), byte(v)}})
+	}
+
+	return flow, nil
+}
+
+// DeletedQoSFlow builds the deletion of the description of QoS flow qfi,
+// which carries no parameters (TS 24.501 §9.11.4.12).
+func DeletedQoSFlow(qfi uint8) QoSFlowDescription {
+	return QoSFlowDescription{QFI: qfi & qfdQFIBitmask, OperationCode: QoSFlowOpDelete}
+}
+
 // valueLength returns the fixed value-field length in octets of this component
 // type, and whether the type is one TS 24.501 table 9.11.4.13.1 assigns.
 func (t PacketFilterComponentType) valueLength() (int, bool) {
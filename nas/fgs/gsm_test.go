@@ -8,6 +8,7 @@ import (
 	"net/netip"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ellanetworks/core/nas"
 )
@@ -225,6 +226,82 @@ func TestPDUSessionEstablishmentRejectRoundTrip(t *testing.T) {
 	}
 }
 
+func TestPDUSessionEstablishmentRejectEAPRoundTrip(t *testing.T) {
+	eapFailure := []byte{0x04, 0x02, 0x00, 0x04}
+	in := &PDUSessionEstablishmentReject{PDUSessionID: 5, PTI: 1, Cause: GSMCauseUserAuthenticationOrAuthorizationFailed, EAP: eapFailure}
+
+	b, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	out, err := ParsePDUSessionEstablishmentReject(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if out.Cause != GSMCauseUserAuthenticationOrAuthorizationFailed || !bytes.Equal(out.EAP, eapFailure) || len(out.Unrecognized) != 0 {
+		t.Fatalf("round-trip mismatch: got %+v", out)
+	}
+}
+
+func TestPDUSessionEstablishmentRejectBackoffTimerRoundTrip(t *testing.T) {
+	backoff, err := nas.GPRSTimer3FromDuration(5 * time.Minute)
+	if err != nil {
+		t.Fatalf("GPRSTimer3FromDuration: %v", err)
+	}
+
+	in := &PDUSessionEstablishmentReject{PDUSessionID: 5, PTI: 1, Cause: GSMCauseInsufficientResourcesForSpecificSliceAndDNN, BackoffTimer: &backoff}
+
+	b, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	// The back-off timer follows the cause as a TLV (TS 24.501 table 8.3.3.1.1).
+	if !bytes.Equal(b[5:], []byte{ieiBackoffTimer, 0x01, 0x8A}) {
+		t.Fatalf("unexpected optional part % x", b[5:])
+	}
+
+	out, err := ParsePDUSessionEstablishmentReject(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if out.BackoffTimer == nil || *out.BackoffTimer != backoff || len(out.Unrecognized) != 0 {
+		t.Fatalf("round-trip mismatch: got %+v", out)
+	}
+}
+
+func TestPDUSessionAuthenticationCommandRoundTrip(t *testing.T) {
+	eapIdentity := []byte{0x01, 0x01, 0x00, 0x05, 0x01}
+	in := &PDUSessionAuthenticationCommand{PDUSessionID: 5, PTI: 0, EAP: eapIdentity}
+
+	b, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	want := []byte{uint8(EPD5GSM), 5, 0, uint8(MsgPDUSessionAuthenticationCommand), 0x00, 0x05, 0x01, 0x01, 0x00, 0x05, 0x01}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("wire = % x, want % x", b, want)
+	}
+
+	msg, err := ParseMessage(b)
+	if err != nil {
+		t.Fatalf("ParseMessage: %v", err)
+	}
+
+	out, ok := msg.(*PDUSessionAuthenticationCommand)
+	if !ok {
+		t.Fatalf("ParseMessage returned %T", msg)
+	}
+
+	if out.PDUSessionID != 5 || out.PTI != 0 || !bytes.Equal(out.EAP, eapIdentity) {
+		t.Fatalf("round-trip mismatch: got %+v", out)
+	}
+}
+
 func TestPDUSessionReleaseCommandRoundTrip(t *testing.T) {
 	in := &PDUSessionReleaseCommand{PDUSessionID: 5, PTI: 0, Cause: 0x27}
 
@@ -86,6 +86,13 @@ var causeAndPCOIEs = []nas.OptionalIE{
 	{IEI: ieiExtendedPCO, Format: nas.IETLVE, Name: "Extended PCO"},
 }
 
+// establishmentRejectIEs is the full-octet optional-IE table of the PDU SESSION
+// ESTABLISHMENT REJECT (TS 24.501 §8.3.3, table 8.3.3.1.1) this codec models.
+var establishmentRejectIEs = []nas.OptionalIE{
+	{IEI: ieiBackoffTimer, Format: nas.IETLV, Name: "Back-off timer value"},
+	{IEI: ieiEAPMessageSession, Format: nas.IETLVE, Name: "EAP message"},
+}
+
 // establishmentAcceptIEs is the full-octet optional-IE table of the PDU SESSION
 // ESTABLISHMENT ACCEPT (TS 24.501 §8.3.2, table 8.3.2.1.1); the type-1 always-on
 // indication is delimited generically by the walker.
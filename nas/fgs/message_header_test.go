@@ -19,6 +19,7 @@ func TestGSMHeaderAccessors(t *testing.T) {
 	)
 
 	msgs := []GSMMessage{
+		&PDUSessionAuthenticationCommand{PDUSessionID: session, PTI: pti},
 		&PDUSessionAuthenticationComplete{PDUSessionID: session, PTI: pti},
 		&PDUSessionEstablishmentRequest{PDUSessionID: session, PTI: pti},
 		&PDUSessionEstablishmentAccept{PDUSessionID: session, PTI: pti},
@@ -36,6 +36,16 @@ func (m *DeregistrationAcceptUETerminated) MessageType() MessageType {
 	return MsgDeregistrationAcceptUETerm
 }
 
+func (m *PDUSessionAuthenticationCommand) MessageType() GSMMessageType {
+	return MsgPDUSessionAuthenticationCommand
+}
+
+func (m *PDUSessionAuthenticationCommand) SessionIdentity() PDUSessionID { return m.PDUSessionID }
+
+func (m *PDUSessionAuthenticationCommand) TransactionIdentity() nas.ProcedureTransactionIdentity {
+	return m.PTI
+}
+
 func (m *PDUSessionAuthenticationComplete) MessageType() GSMMessageType {
 	return MsgPDUSessionAuthenticationComplete
 }
@@ -110,6 +120,7 @@ func (m *DeregistrationRequestUETerminated) isMessage()   {}
 func (m *DeregistrationRequestUEOriginating) isMessage()  {}
 func (m *DeregistrationAcceptUEOriginating) isMessage()   {}
 func (m *DeregistrationAcceptUETerminated) isMessage()    {}
+func (m *PDUSessionAuthenticationCommand) isMessage()     {}
 func (m *PDUSessionAuthenticationComplete) isMessage()    {}
 func (m *IdentityRequest) isMessage()                     {}
 func (m *IdentityResponse) isMessage()                    {}
@@ -153,6 +164,7 @@ var (
 	_ GMMMessage = (*DeregistrationRequestUEOriginating)(nil)
 	_ GMMMessage = (*DeregistrationAcceptUEOriginating)(nil)
 	_ GMMMessage = (*DeregistrationAcceptUETerminated)(nil)
+	_ GSMMessage = (*PDUSessionAuthenticationCommand)(nil)
 	_ GSMMessage = (*PDUSessionAuthenticationComplete)(nil)
 	_ GMMMessage = (*IdentityRequest)(nil)
 	_ GMMMessage = (*IdentityResponse)(nil)
@@ -306,6 +306,7 @@ var gsmParsers = map[GSMMessageType]func([]byte) (Message, error){
 	MsgPDUSessionModificationCommand:    gsmParser(ParsePDUSessionModificationCommand),
 	MsgPDUSessionModificationComplete:   gsmParser(ParsePDUSessionModificationComplete),
 	MsgPDUSessionModificationReject:     gsmParser(ParsePDUSessionModificationReject),
+	MsgPDUSessionAuthenticationCommand:  gsmParser(ParsePDUSessionAuthenticationCommand),
 	MsgPDUSessionAuthenticationComplete: gsmParser(ParsePDUSessionAuthenticationComplete),
 	MsgPDUSessionModificationRequest:    gsmParser(ParsePDUSessionModificationRequest),
 	MsgPDUSessionReleaseCommand:         gsmParser(ParsePDUSessionReleaseCommand),
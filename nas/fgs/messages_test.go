@@ -101,7 +101,7 @@ func TestParseMessageUnknownType(t *testing.T) {
 		gsm bool
 	}{
 		{"5GMM", []byte{uint8(EPD5GMM), 0x00, 0x50, 0xAA, 0xBB}, 0x50, false},
-		{"5GSM", []byte{uint8(EPD5GSM), 0x05, 0x01, 0xC5, 0xAA}, 0xC5, true},
+		{"5GSM", []byte{uint8(EPD5GSM), 0x05, 0x01, 0xC7, 0xAA}, 0xC7, true},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			msg, err := ParseMessage(tc.raw)
@@ -38,6 +38,7 @@ func TestAppendBinaryEnforcesPDULimit(t *testing.T) {
 		&IdentityRequest{},
 		&IdentityResponse{},
 		&NotificationResponse{},
+		&PDUSessionAuthenticationCommand{},
 		&PDUSessionAuthenticationComplete{},
 		&PDUSessionEstablishmentAccept{},
 		&PDUSessionEstablishmentReject{},
@@ -126,6 +126,9 @@ var messageParsers = []messageParser{
 	{"ParseUEContextReleaseCommand", func(v []byte) error { _, err := ParseUEContextReleaseCommand(v); return err }},
 	{"ParseUEContextReleaseComplete", func(v []byte) error { _, err := ParseUEContextReleaseComplete(v); return err }},
 	{"ParseUEContextReleaseRequest", func(v []byte) error { _, err := ParseUEContextReleaseRequest(v); return err }},
+	{"ParseUEContextModificationRequest", func(v []byte) error { _, err := ParseUEContextModificationRequest(v); return err }},
+	{"ParseUEContextModificationResponse", func(v []byte) error { _, err := ParseUEContextModificationResponse(v); return err }},
+	{"ParseUEContextModificationFailure", func(v []byte) error { _, err := ParseUEContextModificationFailure(v); return err }},
 	{"ParseRANConfigurationUpdate", func(v []byte) error { _, err := ParseRANConfigurationUpdate(v); return err }},
 	{"ParseRANConfigurationUpdateAcknowledge", func(v []byte) error {
 		_, err := ParseRANConfigurationUpdateAcknowledge(v)
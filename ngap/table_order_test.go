@@ -63,6 +63,9 @@ func TestTableOrderMatchesASN1(t *testing.T) {
 		{"UEContextReleaseRequest", tableIDs(uEContextReleaseRequestIEs), []ProtocolIEID{IDAMFUENGAPID, IDRANUENGAPID, IDPDUSessionResourceListCxtRelReq, IDCause}},
 		{"UEContextReleaseCommand", tableIDs(uEContextReleaseCommandIEs), []ProtocolIEID{IDUENGAPIDs, IDCause}},
 		{"UEContextReleaseComplete", tableIDs(uEContextReleaseCompleteIEs), []ProtocolIEID{IDAMFUENGAPID, IDRANUENGAPID, IDUserLocationInformation, IDPDUSessionResourceListCxtRelCpl, IDCriticalityDiagnostics}},
+		{"UEContextModificationRequest", tableIDs(uEContextModificationRequestIEs), []ProtocolIEID{IDAMFUENGAPID, IDRANUENGAPID, IDUEAggregateMaximumBitRate}},
+		{"UEContextModificationResponse", tableIDs(uEContextModificationResponseIEs), []ProtocolIEID{IDAMFUENGAPID, IDRANUENGAPID, IDUserLocationInformation, IDCriticalityDiagnostics}},
+		{"UEContextModificationFailure", tableIDs(uEContextModificationFailureIEs), []ProtocolIEID{IDAMFUENGAPID, IDRANUENGAPID, IDCause, IDCriticalityDiagnostics}},
 		{"Paging", tableIDs(pagingIEs), []ProtocolIEID{IDUEPagingIdentity, IDPagingDRX, IDTAIListForPaging, IDPagingPriority, IDUERadioCapabilityForPaging, IDPagingOrigin}},
 		{"RANConfigurationUpdate", tableIDs(rANConfigurationUpdateIEs), []ProtocolIEID{IDRANNodeName, IDSupportedTAList, IDDefaultPagingDRX, IDGlobalRANNodeID, IDNGRANTNLAssociationToRemoveList}},
 		{"RANConfigurationUpdateAcknowledge", tableIDs(rANConfigurationUpdateAcknowledgeIEs), []ProtocolIEID{IDCriticalityDiagnostics}},
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package ngap
+
+import (
+	"testing"
+)
+
+func TestUEContextModificationRoundTrips(t *testing.T) {
+	t.Run("Request", func(t *testing.T) {
+		in := &UEContextModificationRequest{
+			AMFUENGAPID:               1,
+			RANUENGAPID:               7,
+			UEAggregateMaximumBitRate: &UEAggregateMaximumBitRate{DL: 200_000_000, UL: 50_000_000},
+		}
+
+		b, err := in.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pdu, err := Unmarshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		im, ok := pdu.(*InitiatingMessage)
+		if !ok || im.ProcedureCode != ProcUEContextModification || im.Criticality != CriticalityReject {
+			t.Fatalf("got %T procedureCode %d", pdu, pdu.procedureCode())
+		}
+
+		out, err := ParseUEContextModificationRequest(im.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if out.AMFUENGAPID != 1 || out.RANUENGAPID != 7 || out.UEAggregateMaximumBitRate == nil ||
+			out.UEAggregateMaximumBitRate.DL != 200_000_000 || out.UEAggregateMaximumBitRate.UL != 50_000_000 {
+			t.Fatalf("mismatch:\n in  %+v\n out %+v", in, out)
+		}
+	})
+
+	t.Run("Response", func(t *testing.T) {
+		in := &UEContextModificationResponse{AMFUENGAPID: Ptr(AMFUENGAPID(1)), RANUENGAPID: Ptr(RANUENGAPID(7))}
+
+		b, err := in.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pdu, err := Unmarshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		so, ok := pdu.(*SuccessfulOutcome)
+		if !ok || so.ProcedureCode != ProcUEContextModification {
+			t.Fatalf("got %T procedureCode %d", pdu, pdu.procedureCode())
+		}
+
+		out, err := ParseUEContextModificationResponse(so.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if deref(out.AMFUENGAPID) != 1 || deref(out.RANUENGAPID) != 7 {
+			t.Fatalf("mismatch:\n in  %+v\n out %+v", in, out)
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		cause := Cause{Group: CauseGroupRadioNetwork, Value: CauseRadioNetworkUnspecified}
+		in := &UEContextModificationFailure{AMFUENGAPID: Ptr(AMFUENGAPID(1)), RANUENGAPID: Ptr(RANUENGAPID(7)), Cause: &cause}
+
+		b, err := in.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pdu, err := Unmarshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		uo, ok := pdu.(*UnsuccessfulOutcome)
+		if !ok || uo.ProcedureCode != ProcUEContextModification {
+			t.Fatalf("got %T procedureCode %d", pdu, pdu.procedureCode())
+		}
+
+		out, err := ParseUEContextModificationFailure(uo.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if deref(out.AMFUENGAPID) != 1 || deref(out.RANUENGAPID) != 7 || deref(out.Cause) != cause {
+			t.Fatalf("mismatch:\n in  %+v\n out %+v", in, out)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/accounting"
+	"github.com/ellanetworks/core/internal/amf"
+	"github.com/ellanetworks/core/internal/cdr"
+	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/internal/monitoringevent"
+	"github.com/ellanetworks/core/internal/smf"
+)
+
+// smfAccounting adapts the accounting service, the CDR writer and the
+// monitoring event reports to smf.Accounting.
+type smfAccounting struct {
+	svc    *accounting.Service
+	cdr    *cdr.Service
+	events *monitoringevent.Service
+}
+
+func (a *smfAccounting) SessionStarted(sess smf.AccountingSession) {
+	rat := accounting.RATNR
+	if sess.Access == smf.Access4G {
+		rat = accounting.RATEUTRAN
+	}
+
+	a.svc.SessionStarted(accounting.Session{
+		Ref:        sess.Ref,
+		IMSI:       sess.IMSI,
+		DNN:        sess.Dnn,
+		RAT:        rat,
+		IPv4:       sess.IPv4,
+		IPv6Prefix: sess.IPv6Prefix,
+	})
+
+	a.cdr.SessionStarted(cdr.Session{
+		Ref:        sess.Ref,
+		IMSI:       sess.IMSI,
+		DNN:        sess.Dnn,
+		Snssai:     sess.Snssai,
+		RAT:        rat,
+		IPv4:       sess.IPv4,
+		IPv6Prefix: sess.IPv6Prefix,
+		QoS:        cdr.QoS(sess.QoS),
+	})
+
+	eventRAT := monitoringevent.RATNR
+	if sess.Access == smf.Access4G {
+		eventRAT = monitoringevent.RATEUTRA
+	}
+
+	a.events.SessionStarted(monitoringevent.Session{
+		Ref:        sess.Ref,
+		IMSI:       sess.IMSI,
+		DNN:        sess.Dnn,
+		RAT:        eventRAT,
+		IPv4:       sess.IPv4,
+		IPv6Prefix: sess.IPv6Prefix,
+	})
+}
+
+func (a *smfAccounting) SessionUsage(ref string, uplinkBytes, downlinkBytes uint64) {
+	a.svc.SessionUsage(ref, uplinkBytes, downlinkBytes)
+	a.cdr.SessionUsage(ref, uplinkBytes, downlinkBytes)
+}
+
+// SessionModified only concerns charging records: RADIUS accounting has no
+// record for a QoS change.
+func (a *smfAccounting) SessionModified(ref string, qos smf.AccountingQoS) {
+	a.cdr.SessionModified(ref, cdr.QoS(qos))
+}
+
+func (a *smfAccounting) SessionStopped(ref string) {
+	a.svc.SessionStopped(ref)
+	a.cdr.SessionStopped(ref)
+	a.events.SessionStopped(ref)
+}
+
+// accountingUEs answers accounting.UEDirectory from the AMF and the MME.
+// Both are created after the SMF and set once they exist.
+type accountingUEs struct {
+	amf *amf.AMF
+	mme *mme.MME
+}
+
+func (d *accountingUEs) LookupUE(imsi string, rat accounting.RAT) (accounting.UE, bool) {
+	supi, err := etsi.NewSUPIFromIMSI(imsi)
+	if err != nil {
+		return accounting.UE{}, false
+	}
+
+	switch {
+	case rat == accounting.RATEUTRAN && d.mme != nil:
+		ue, ok := d.mme.LookupUeBySupi(supi)
+		if !ok {
+			return accounting.UE{}, false
+		}
+
+		return accounting.UE{IMEI: ue.Snapshot().Imei, Location: ue.GetUserLocation()}, true
+	case rat == accounting.RATNR && d.amf != nil:
+		ue, ok := d.amf.LookupUeBySupi(supi)
+		if !ok {
+			return accounting.UE{}, false
+		}
+
+		return accounting.UE{IMEI: ue.Snapshot().Imei, Location: ue.GetUserLocation()}, true
+	default:
+		return accounting.UE{}, false
+	}
+}
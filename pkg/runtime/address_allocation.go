@@ -0,0 +1,478 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/dhcp"
+	"github.com/ellanetworks/core/internal/ipam"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/radius"
+	"go.uber.org/zap"
+)
+
+const (
+	// renewalInterval is how often DHCP leases due for renewal are looked
+	// for.
+	renewalInterval = 30 * time.Second
+	// renewalRetry is how long a failed renewal waits before the next try.
+	renewalRetry = time.Minute
+	// externalRequestTimeout bounds a whole exchange with the server, so
+	// a server that is down delays session setup by at most this much.
+	externalRequestTimeout = 5 * time.Second
+)
+
+var errAccessRejected = errors.New("RADIUS server rejected the subscriber")
+
+// externalAllocator leases UE IPv4 addresses from the DHCPv4 or RADIUS
+// server a data network is set up with. Leases land in ip_leases like
+// pool leases, so release, BGP and the lease views handle them alike.
+type externalAllocator struct {
+	db *db.Database
+
+	// sessions releases the session of a lease the server will not
+	// renew; nil until the SMF is up.
+	sessions leaseSessions
+
+	// dhcpPort is where relays read replies; tests set it to 0.
+	dhcpPort uint16
+
+	mu    sync.Mutex
+	relay map[string]*dhcpRelay // by data network ID
+}
+
+// leaseSessions is the narrow SMF view the allocator needs to end the
+// session of a lost lease. *smf.SMF satisfies it.
+type leaseSessions interface {
+	SessionOfAddress(imsi string, ueIP netip.Addr) (string, bool)
+	TerminateSession(ctx context.Context, ref string) error
+}
+
+type dhcpRelay struct {
+	server string
+	relay  string
+	client *dhcp.Client
+}
+
+func newExternalAllocator(database *db.Database) *externalAllocator {
+	return &externalAllocator{
+		db:       database,
+		dhcpPort: dhcp.ServerPort,
+		relay:    map[string]*dhcpRelay{},
+	}
+}
+
+// allocate returns an address from the data network's external server. It
+// reports false when the pool should be used instead: the data network
+// has no external server, the session already holds a lease, or the
+// server failed and the data network falls back to its pool.
+func (x *externalAllocator) allocate(ctx context.Context, pool ipam.Pool, dnn string, imsi string, sessionID int) (netip.Addr, bool, error) {
+	cfg, err := x.db.GetDataNetworkAddressAllocation(ctx, pool.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return netip.Addr{}, false, nil
+		}
+
+		return netip.Addr{}, true, fmt.Errorf("get address allocation: %w", err)
+	}
+
+	// A static reservation, or a lease this session already holds after a
+	// retry or failover, is the pool path's to hand back.
+	if _, err := x.db.GetStaticLease(ctx, pool.ID, pool.IPVersion, imsi); err == nil {
+		return netip.Addr{}, false, nil
+	}
+
+	if _, err := x.db.GetLeaseBySession(ctx, pool.ID, pool.IPVersion, sessionID, imsi); err == nil {
+		return netip.Addr{}, false, nil
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, externalRequestTimeout)
+	defer cancel()
+
+	clientID := externalClientID(imsi, sessionID)
+
+	var (
+		addr     netip.Addr
+		external = db.ExternalIPLease{Source: cfg.Mode, ClientID: clientID}
+	)
+
+	switch cfg.Mode {
+	case db.AddressAllocationDHCP:
+		var lease *dhcp.Lease
+
+		lease, err = x.acquireDHCP(reqCtx, cfg, dnn, imsi, clientID)
+		if err == nil {
+			addr = lease.Address
+			external.ServerID = lease.ServerID.String()
+			external.RenewAt = unixOrZero(lease.RenewAt)
+			external.ExpiresAt = unixOrZero(lease.ExpiresAt)
+		}
+	case db.AddressAllocationRADIUS:
+		addr, err = x.requestRADIUS(reqCtx, cfg, dnn, imsi)
+		external.ServerID = cfg.Server
+	default:
+		err = fmt.Errorf("unknown address allocation mode %q", cfg.Mode)
+	}
+
+	if err == nil && !addr.IsValid() {
+		// The server leaves the choice to us (RFC 2865 §5.8).
+		return netip.Addr{}, false, nil
+	}
+
+	if err == nil {
+		lease := &db.IPLease{
+			PoolID:    pool.ID,
+			PoolType:  pool.IPVersion,
+			IMSI:      imsi,
+			SessionID: &sessionID,
+			CreatedAt: time.Now().Unix(),
+			NodeID:    x.db.NodeID(),
+		}
+
+		err = x.db.CreateExternalLease(ctx, lease, addr, &external)
+		if err == nil {
+			return addr, true, nil
+		}
+
+		if errors.Is(err, db.ErrAlreadyExists) {
+			err = fmt.Errorf("%s server handed out %s, which is in use", cfg.Mode, addr)
+		}
+	}
+
+	if errors.Is(err, errAccessRejected) || !cfg.FallbackToPool {
+		return netip.Addr{}, true, err
+	}
+
+	logger.SmfLog.Warn("external address allocation failed, falling back to the pool",
+		zap.String("dnn", dnn), zap.String("imsi", imsi), zap.String("mode", cfg.Mode), zap.Error(err))
+
+	return netip.Addr{}, false, nil
+}
+
+// externalLease is what release needs to give an external address back.
+type externalLease struct {
+	dnID     string
+	address  netip.Addr
+	external *db.ExternalIPLease
+}
+
+// lookup returns the external details of the session's lease, or nil.
+func (x *externalAllocator) lookup(ctx context.Context, pool ipam.Pool, imsi string, sessionID int) *externalLease {
+	lease, err := x.db.GetLeaseBySession(ctx, pool.ID, pool.IPVersion, sessionID, imsi)
+	if err != nil || lease.Type != "dynamic" {
+		return nil
+	}
+
+	external, err := x.db.GetExternalLease(ctx, lease.ID)
+	if err != nil {
+		return nil
+	}
+
+	return &externalLease{dnID: pool.ID, address: lease.Address(), external: external}
+}
+
+// giveBack tells the server an address is no longer used. RADIUS servers
+// learn it from accounting, so only DHCP leases are released here.
+func (x *externalAllocator) giveBack(ctx context.Context, l *externalLease) {
+	if l.external.Source != db.AddressAllocationDHCP {
+		return
+	}
+
+	cfg, err := x.db.GetDataNetworkAddressAllocation(ctx, l.dnID)
+	if err != nil || cfg.Mode != db.AddressAllocationDHCP {
+		return
+	}
+
+	client, err := x.dhcpClient(cfg)
+	if err != nil {
+		logger.SmfLog.Warn("couldn't release DHCP lease", zap.String("address", l.address.String()), zap.Error(err))
+		return
+	}
+
+	serverID, _ := netip.ParseAddr(l.external.ServerID)
+
+	if err := client.Release(dhcp.Request{ClientID: l.external.ClientID}, l.address, serverID); err != nil {
+		logger.SmfLog.Warn("couldn't release DHCP lease", zap.String("address", l.address.String()), zap.Error(err))
+	}
+}
+
+func (x *externalAllocator) acquireDHCP(ctx context.Context, cfg *db.DataNetworkAddressAllocation, dnn string, imsi string, clientID string) (*dhcp.Lease, error) {
+	client, err := x.dhcpClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Acquire(ctx, dhcp.Request{ClientID: clientID, CircuitID: dnn, RemoteID: imsi})
+}
+
+// dhcpClient returns the relay for cfg, replacing one set up for other
+// addresses.
+func (x *externalAllocator) dhcpClient(cfg *db.DataNetworkAddressAllocation) (*dhcp.Client, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if r, ok := x.relay[cfg.DataNetworkID]; ok {
+		if r.server == cfg.Server && r.relay == cfg.RelayAddress {
+			return r.client, nil
+		}
+
+		_ = r.client.Close()
+		delete(x.relay, cfg.DataNetworkID)
+	}
+
+	server, err := parseServer(cfg.Server, dhcp.ServerPort)
+	if err != nil {
+		return nil, err
+	}
+
+	relay, err := netip.ParseAddr(cfg.RelayAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relay address %q: %w", cfg.RelayAddress, err)
+	}
+
+	client, err := dhcp.NewClient(dhcp.Config{
+		Server: server,
+		Relay:  relay,
+		Listen: netip.AddrPortFrom(relay, x.dhcpPort),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	x.relay[cfg.DataNetworkID] = &dhcpRelay{server: cfg.Server, relay: cfg.RelayAddress, client: client}
+
+	return client, nil
+}
+
+func (x *externalAllocator) requestRADIUS(ctx context.Context, cfg *db.DataNetworkAddressAllocation, dnn string, imsi string) (netip.Addr, error) {
+	server, err := parseServer(cfg.Server, radius.AuthPort)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	client, err := radius.NewClient(radius.Config{Server: server, Secret: []byte(cfg.Secret)})
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	req, err := radius.NewRequest(radius.CodeAccessRequest)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	req.AddString(radius.TypeUserName, imsi)
+	req.Add(radius.TypeUserPassword, radius.HidePassword([]byte(imsi), client.Secret(), req.Authenticator))
+	req.AddString(radius.TypeCallingStationID, imsi)
+	req.AddString(radius.TypeCalledStationID, dnn)
+	req.AddUint32(radius.TypeServiceType, radius.ServiceTypeFramed)
+	req.AddUint32(radius.TypeFramedProtocol, radius.FramedProtocolGPRSPDPCtxt)
+	req.AddString(radius.TypeNASIdentifier, nasIdentifier(x.db.NodeID()))
+	req.Add(radius.TypeMessageAuthenticator, nil)
+
+	resp, err := client.Exchange(ctx, req)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	switch resp.Code {
+	case radius.CodeAccessAccept:
+	case radius.CodeAccessReject:
+		return netip.Addr{}, errAccessRejected
+	default:
+		return netip.Addr{}, fmt.Errorf("unexpected %s", resp.Code)
+	}
+
+	addr, ok := resp.Addr(radius.TypeFramedIPAddress)
+	if !ok || addr == netip.AddrFrom4([4]byte{255, 255, 255, 254}) || addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return netip.Addr{}, nil
+	}
+
+	return addr, nil
+}
+
+// run renews this node's DHCP leases until ctx ends.
+func (x *externalAllocator) run(ctx context.Context) {
+	ticker := time.NewTicker(renewalInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			x.close()
+			return
+		case <-ticker.C:
+			x.renew(ctx, time.Now())
+		}
+	}
+}
+
+// renew renews the leases due at now. The session of a lease the server
+// refuses, or of one that expired without renewal, is released, so the
+// address is not used after the server may hand it out again.
+func (x *externalAllocator) renew(ctx context.Context, now time.Time) {
+	externals, err := x.db.ListExternalLeasesByNode(ctx, x.db.NodeID())
+	if err != nil {
+		logger.SmfLog.Warn("couldn't list external leases", zap.Error(err))
+		return
+	}
+
+	var leases map[string]db.IPLease
+
+	for i := range externals {
+		external := &externals[i]
+		if external.Source != db.AddressAllocationDHCP || external.RenewAt == 0 || external.RenewAt > now.Unix() {
+			continue
+		}
+
+		if leases == nil {
+			active, err := x.db.ListActiveLeasesByNode(ctx, x.db.NodeID())
+			if err != nil {
+				logger.SmfLog.Warn("couldn't list leases", zap.Error(err))
+				return
+			}
+
+			leases = make(map[string]db.IPLease, len(active))
+			for _, l := range active {
+				leases[l.ID] = l
+			}
+		}
+
+		lease, ok := leases[external.LeaseID]
+		if !ok {
+			continue
+		}
+
+		x.renewOne(ctx, now, &lease, external)
+	}
+}
+
+func (x *externalAllocator) renewOne(ctx context.Context, now time.Time, lease *db.IPLease, external *db.ExternalIPLease) {
+	address := lease.Address()
+
+	err := func() error {
+		cfg, err := x.db.GetDataNetworkAddressAllocation(ctx, lease.PoolID)
+		if err != nil {
+			return fmt.Errorf("get address allocation: %w", err)
+		}
+
+		if cfg.Mode != db.AddressAllocationDHCP {
+			return errors.New("data network no longer uses DHCP")
+		}
+
+		client, err := x.dhcpClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		var dnn string
+		if dn, err := x.db.GetDataNetworkByID(ctx, lease.PoolID); err == nil {
+			dnn = dn.Name
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx, externalRequestTimeout)
+		defer cancel()
+
+		renewed, err := client.Renew(reqCtx, dhcp.Request{ClientID: external.ClientID, CircuitID: dnn, RemoteID: lease.IMSI}, address)
+		if err != nil {
+			return err
+		}
+
+		if renewed.ServerID.IsValid() {
+			external.ServerID = renewed.ServerID.String()
+		}
+
+		external.RenewAt = unixOrZero(renewed.RenewAt)
+		external.ExpiresAt = unixOrZero(renewed.ExpiresAt)
+
+		return nil
+	}()
+	if err != nil {
+		lost := errors.Is(err, dhcp.ErrNak) || (external.ExpiresAt != 0 && now.Unix() >= external.ExpiresAt)
+
+		if lost && x.releaseSession(ctx, lease, address) {
+			logger.SmfLog.Warn("DHCP lease lost, releasing the session",
+				zap.String("imsi", lease.IMSI), zap.String("address", address.String()), zap.Error(err))
+		} else {
+			logger.SmfLog.Info("couldn't renew DHCP lease, will retry",
+				zap.String("imsi", lease.IMSI), zap.String("address", address.String()), zap.Error(err))
+		}
+
+		external.RenewAt = now.Add(renewalRetry).Unix()
+	}
+
+	if err := x.db.UpdateExternalLeaseTimers(ctx, external); err != nil {
+		logger.SmfLog.Warn("couldn't store DHCP lease timers", zap.String("address", address.String()), zap.Error(err))
+	}
+}
+
+// releaseSession releases the session holding lease, reporting whether
+// one was found and released.
+func (x *externalAllocator) releaseSession(ctx context.Context, lease *db.IPLease, address netip.Addr) bool {
+	if x.sessions == nil {
+		return false
+	}
+
+	ref, ok := x.sessions.SessionOfAddress(lease.IMSI, address)
+	if !ok {
+		return false
+	}
+
+	if err := x.sessions.TerminateSession(ctx, ref); err != nil {
+		logger.SmfLog.Warn("couldn't release the session of a lost DHCP lease",
+			zap.String("imsi", lease.IMSI), zap.String("address", address.String()), zap.Error(err))
+
+		return false
+	}
+
+	return true
+}
+
+func (x *externalAllocator) close() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	for id, r := range x.relay {
+		_ = r.client.Close()
+		delete(x.relay, id)
+	}
+}
+
+// externalClientID names a session's lease on the external server.
+func externalClientID(imsi string, sessionID int) string {
+	return "imsi-" + imsi + "-" + strconv.Itoa(sessionID)
+}
+
+func nasIdentifier(nodeID int) string {
+	return "ella-core-" + strconv.Itoa(nodeID)
+}
+
+// parseServer accepts an address, or an address and port.
+func parseServer(s string, defaultPort uint16) (netip.AddrPort, error) {
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap, nil
+	}
+
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid server address %q", s)
+	}
+
+	return netip.AddrPortFrom(a, defaultPort), nil
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/dhcp/dhcptest"
+	"github.com/ellanetworks/core/internal/radius"
+	"github.com/ellanetworks/core/internal/radius/radiustest"
+)
+
+var externalPool = netip.MustParsePrefix("172.20.0.0/29")
+
+func withExternalAllocation(t *testing.T, adapter *smfDBAdapter, poolID string, cfg db.DataNetworkAddressAllocation) {
+	t.Helper()
+
+	cfg.DataNetworkID = poolID
+
+	if err := adapter.db.SetDataNetworkAddressAllocation(context.Background(), &cfg); err != nil {
+		t.Fatalf("SetDataNetworkAddressAllocation: %s", err)
+	}
+
+	adapter.external = newExternalAllocator(adapter.db)
+	adapter.external.dhcpPort = 0
+
+	t.Cleanup(adapter.external.close)
+}
+
+func TestAllocateIP_DHCP(t *testing.T) {
+	adapter, dnn, poolID, imsi := setupAdapterTestDB(t)
+	ctx := context.Background()
+
+	srv, err := dhcptest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), externalPool, time.Hour)
+	if err != nil {
+		t.Fatalf("dhcptest.NewServer: %s", err)
+	}
+
+	defer func() { _ = srv.Close() }()
+
+	withExternalAllocation(t, adapter, poolID, db.DataNetworkAddressAllocation{
+		Mode:         db.AddressAllocationDHCP,
+		Server:       srv.Addr().String(),
+		RelayAddress: "127.0.0.1",
+	})
+
+	dn, err := adapter.ResolveDNN(ctx, dnn)
+	if err != nil {
+		t.Fatalf("ResolveDNN: %s", err)
+	}
+
+	got, err := dn.AllocateIP(ctx, imsi, 5)
+	if err != nil {
+		t.Fatalf("AllocateIP: %s", err)
+	}
+
+	if !externalPool.Contains(got) {
+		t.Fatalf("expected an address from the DHCP server, got %s", got)
+	}
+
+	clientID := externalClientID(imsi, 5)
+	if srv.Leases()[clientID] != got {
+		t.Fatalf("server leases %v, want %s for %s", srv.Leases(), got, clientID)
+	}
+
+	if srv.RemoteID(clientID) != imsi {
+		t.Fatalf("server saw remote id %q, want the IMSI", srv.RemoteID(clientID))
+	}
+
+	again, err := dn.AllocateIP(ctx, imsi, 5)
+	if err != nil || again != got {
+		t.Fatalf("retry AllocateIP = %s, %v, want %s", again, err, got)
+	}
+
+	lease, err := adapter.db.GetLeaseBySession(ctx, poolID, "ipv4", 5, imsi)
+	if err != nil {
+		t.Fatalf("GetLeaseBySession: %s", err)
+	}
+
+	before, err := adapter.db.GetExternalLease(ctx, lease.ID)
+	if err != nil {
+		t.Fatalf("GetExternalLease: %s", err)
+	}
+
+	// Half the lease time later the lease is due and gets renewed.
+	adapter.external.renew(ctx, time.Now().Add(31*time.Minute))
+
+	after, err := adapter.db.GetExternalLease(ctx, lease.ID)
+	if err != nil {
+		t.Fatalf("GetExternalLease: %s", err)
+	}
+
+	if after.RenewAt < before.RenewAt || after.ExpiresAt < before.ExpiresAt || after.ClientID != clientID {
+		t.Fatalf("renewal did not refresh the timers: before %+v after %+v", before, after)
+	}
+
+	released, err := dn.ReleaseIP(ctx, imsi, 5)
+	if err != nil || released != got {
+		t.Fatalf("ReleaseIP = %s, %v, want %s", released, err, got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, held := srv.Leases()[clientID]; !held {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("the DHCP server kept the released lease")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// fakeLeaseSessions stands in for the SMF, holding one session.
+type fakeLeaseSessions struct {
+	imsi       string
+	address    netip.Addr
+	terminated []string
+}
+
+func (f *fakeLeaseSessions) SessionOfAddress(imsi string, ueIP netip.Addr) (string, bool) {
+	return "session-1", imsi == f.imsi && ueIP == f.address
+}
+
+func (f *fakeLeaseSessions) TerminateSession(_ context.Context, ref string) error {
+	f.terminated = append(f.terminated, ref)
+	return nil
+}
+
+func TestRenew_DHCPNakReleasesTheSession(t *testing.T) {
+	adapter, dnn, poolID, imsi := setupAdapterTestDB(t)
+	ctx := context.Background()
+
+	srv, err := dhcptest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), externalPool, time.Hour)
+	if err != nil {
+		t.Fatalf("dhcptest.NewServer: %s", err)
+	}
+
+	defer func() { _ = srv.Close() }()
+
+	withExternalAllocation(t, adapter, poolID, db.DataNetworkAddressAllocation{
+		Mode:         db.AddressAllocationDHCP,
+		Server:       srv.Addr().String(),
+		RelayAddress: "127.0.0.1",
+	})
+
+	dn, err := adapter.ResolveDNN(ctx, dnn)
+	if err != nil {
+		t.Fatalf("ResolveDNN: %s", err)
+	}
+
+	got, err := dn.AllocateIP(ctx, imsi, 5)
+	if err != nil {
+		t.Fatalf("AllocateIP: %s", err)
+	}
+
+	sessions := &fakeLeaseSessions{imsi: imsi, address: got}
+	adapter.external.sessions = sessions
+
+	// A server that lost the lease and serves another range refuses it.
+	other, err := dhcptest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), netip.MustParsePrefix("172.21.0.0/29"), time.Hour)
+	if err != nil {
+		t.Fatalf("dhcptest.NewServer: %s", err)
+	}
+
+	defer func() { _ = other.Close() }()
+
+	if err := adapter.db.SetDataNetworkAddressAllocation(ctx, &db.DataNetworkAddressAllocation{
+		DataNetworkID: poolID,
+		Mode:          db.AddressAllocationDHCP,
+		Server:        other.Addr().String(),
+		RelayAddress:  "127.0.0.1",
+	}); err != nil {
+		t.Fatalf("SetDataNetworkAddressAllocation: %s", err)
+	}
+
+	adapter.external.renew(ctx, time.Now().Add(31*time.Minute))
+
+	if len(sessions.terminated) != 1 {
+		t.Fatalf("terminated sessions = %v, want the one holding %s", sessions.terminated, got)
+	}
+}
+
+func TestAllocateIP_DHCPServerDown(t *testing.T) {
+	for _, fallback := range []bool{true, false} {
+		adapter, dnn, poolID, imsi := setupAdapterTestDB(t)
+		ctx := context.Background()
+
+		withExternalAllocation(t, adapter, poolID, db.DataNetworkAddressAllocation{
+			Mode:           db.AddressAllocationDHCP,
+			Server:         "127.0.0.1:9",
+			RelayAddress:   "127.0.0.1",
+			FallbackToPool: fallback,
+		})
+
+		dn, err := adapter.ResolveDNN(ctx, dnn)
+		if err != nil {
+			t.Fatalf("ResolveDNN: %s", err)
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		got, err := dn.AllocateIP(reqCtx, imsi, 1)
+
+		cancel()
+
+		if fallback {
+			if err != nil || !netip.MustParsePrefix("192.168.1.0/24").Contains(got) {
+				t.Fatalf("expected a pool address, got %s, %v", got, err)
+			}
+
+			continue
+		}
+
+		if err == nil {
+			t.Fatalf("expected allocation to fail without fallback, got %s", got)
+		}
+	}
+}
+
+func TestAllocateIP_RADIUS(t *testing.T) {
+	adapter, dnn, poolID, imsi := setupAdapterTestDB(t)
+	ctx := context.Background()
+
+	secret := []byte("testing123")
+	framed := netip.MustParseAddr("172.20.0.6")
+
+	srv, err := radiustest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), secret, func(req *radius.Packet) *radius.Packet {
+		if req.String(radius.TypeCalledStationID) != dnn {
+			return &radius.Packet{Code: radius.CodeAccessReject}
+		}
+
+		if req.String(radius.TypeUserName) != imsi {
+			return &radius.Packet{Code: radius.CodeAccessReject}
+		}
+
+		resp := &radius.Packet{Code: radius.CodeAccessAccept}
+		resp.AddAddr(radius.TypeFramedIPAddress, framed)
+
+		return resp
+	})
+	if err != nil {
+		t.Fatalf("radiustest.NewServer: %s", err)
+	}
+
+	defer func() { _ = srv.Close() }()
+
+	withExternalAllocation(t, adapter, poolID, db.DataNetworkAddressAllocation{
+		Mode:           db.AddressAllocationRADIUS,
+		Server:         srv.Addr().String(),
+		Secret:         string(secret),
+		FallbackToPool: true,
+	})
+
+	dn, err := adapter.ResolveDNN(ctx, dnn)
+	if err != nil {
+		t.Fatalf("ResolveDNN: %s", err)
+	}
+
+	got, err := dn.AllocateIP(ctx, imsi, 3)
+	if err != nil || got != framed {
+		t.Fatalf("AllocateIP = %s, %v, want %s", got, err, framed)
+	}
+
+	if _, err := dn.ReleaseIP(ctx, imsi, 3); err != nil {
+		t.Fatalf("ReleaseIP: %s", err)
+	}
+
+	// A rejected subscriber does not fall back to the pool.
+	if _, err := dn.AllocateIP(ctx, "001019999999999", 1); !errors.Is(err, errAccessRejected) {
+		t.Fatalf("expected errAccessRejected, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+	"go.uber.org/zap"
+)
+
+// sessionQuotas resolves the admission quotas of the slice and data network
+// a session is established on.
+type sessionQuotas struct {
+	db *db.Database
+}
+
+func (q *sessionQuotas) SessionQuota(ctx context.Context, snssai *models.Snssai, dnn string) (smf.SessionQuota, error) {
+	var quota smf.SessionQuota
+
+	if snssai != nil {
+		sliceQuota, err := sliceQuota(ctx, q.db, snssai)
+		if err != nil {
+			return smf.SessionQuota{}, err
+		}
+
+		if sliceQuota != nil {
+			quota.SliceMaxSessions = sliceQuota.MaxSessions
+			quota.SliceMaxSessionsPerSubscriber = sliceQuota.MaxSessionsPerSubscriber
+			quota.SliceBackOff = sliceQuota.BackOff()
+		}
+	}
+
+	dn, err := q.db.GetDataNetwork(ctx, dnn)
+	if err != nil {
+		return smf.SessionQuota{}, fmt.Errorf("get data network: %w", err)
+	}
+
+	dnQuota, err := q.db.GetDataNetworkQuota(ctx, dn.ID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return smf.SessionQuota{}, fmt.Errorf("get data network quota: %w", err)
+	}
+
+	if dnQuota != nil {
+		quota.DataNetworkMaxSessions = dnQuota.MaxSessions
+		quota.DataNetworkBackOff = dnQuota.BackOff()
+	}
+
+	return quota, nil
+}
+
+// AdmitSession counts session ref against q across the cluster.
+func (q *sessionQuotas) AdmitSession(ctx context.Context, ref string, supi etsi.SUPI, snssai *models.Snssai, dnn string, quota smf.SessionQuota) (models.Quota, error) {
+	session := &db.AdmittedSession{
+		Ref:         ref,
+		IMSI:        supi.IMSI(),
+		DataNetwork: dnn,
+		CreatedAt:   time.Now().Unix(),
+	}
+
+	if snssai != nil {
+		session.Sst = &snssai.Sst
+		session.Sd = snssai.Sd
+	}
+
+	return q.db.AdmitSession(ctx, session, db.SessionLimits{
+		MaxSessionsPerSubscriber: quota.SliceMaxSessionsPerSubscriber,
+		MaxSliceSessions:         quota.SliceMaxSessions,
+		MaxDataNetworkSessions:   quota.DataNetworkMaxSessions,
+	})
+}
+
+func (q *sessionQuotas) ReleaseSession(ctx context.Context, ref string) error {
+	return q.db.ReleaseAdmittedSession(ctx, ref)
+}
+
+// registrationQuotas counts the UEs registered to each network slice against
+// the slice's quota.
+type registrationQuotas struct {
+	db *db.Database
+}
+
+// AdmitRegistration counts supi against the slices of nssai across the
+// cluster.
+func (q *registrationQuotas) AdmitRegistration(ctx context.Context, supi etsi.SUPI, nssai []models.Snssai) ([]models.Snssai, time.Duration, error) {
+	admissions := make([]db.SliceAdmission, len(nssai))
+	backOffs := make([]time.Duration, len(nssai))
+	limited := false
+
+	for i := range nssai {
+		admissions[i].Snssai = nssai[i]
+
+		quota, err := sliceQuota(ctx, q.db, &nssai[i])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if quota != nil {
+			admissions[i].MaxRegisteredUEs = quota.MaxRegisteredUEs
+			backOffs[i] = quota.BackOff()
+			limited = limited || quota.MaxRegisteredUEs > 0
+		}
+	}
+
+	// Without a limit there is nothing to refuse, so the registration goes
+	// unrecorded rather than through the leader. The reconciler records it
+	// should a quota be set later.
+	if !limited {
+		return nil, 0, nil
+	}
+
+	refused, err := q.db.AdmitSliceRegistrations(ctx, supi.IMSI(), admissions, time.Now().Unix())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		full    []models.Snssai
+		backOff time.Duration
+	)
+
+	for _, i := range refused {
+		full = append(full, nssai[i])
+		backOff = max(backOff, backOffs[i])
+	}
+
+	return full, backOff, nil
+}
+
+func (q *registrationQuotas) ReleaseRegistration(ctx context.Context, supi etsi.SUPI) error {
+	return q.db.ReleaseSliceRegistrations(ctx, supi.IMSI())
+}
+
+// sliceQuota returns the quota of the slice snssai names, nil when it has
+// none.
+func sliceQuota(ctx context.Context, database *db.Database, snssai *models.Snssai) (*db.NetworkSliceQuota, error) {
+	slices, err := database.ListAllNetworkSlices(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list network slices: %w", err)
+	}
+
+	for _, slice := range slices {
+		sd := ""
+		if slice.Sd != nil {
+			sd = *slice.Sd
+		}
+
+		if slice.Sst != snssai.Sst || models.NormalizeSD(sd) != models.NormalizeSD(snssai.Sd) {
+			continue
+		}
+
+		quota, err := database.GetNetworkSliceQuota(ctx, slice.ID)
+		if errors.Is(err, db.ErrNotFound) {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("get network slice quota: %w", err)
+		}
+
+		return quota, nil
+	}
+
+	return nil, nil
+}
+
+const (
+	// quotaReconcileInterval is how often a node squares its admission
+	// records with the registrations and sessions it holds.
+	quotaReconcileInterval = 30 * time.Second
+
+	// quotaReconcileGrace spares records younger than this: an admission in
+	// progress records before its UE or session is visible to a snapshot.
+	quotaReconcileGrace = 30 * time.Second
+)
+
+// quotaReconciler repairs the admission records of this node, which a
+// release that failed, or a restart, leaves behind, and adds the ones an
+// admission that failed to record missed.
+type quotaReconciler struct {
+	db       *db.Database
+	amf      registeredSlices
+	sessions quotaSessions
+}
+
+type registeredSlices interface {
+	RegisteredSlices() map[etsi.SUPI][]models.Snssai
+}
+
+type quotaSessions interface {
+	QuotaSessions() []smf.QuotaSession
+}
+
+// run reconciles at start and then every quotaReconcileInterval until ctx
+// ends.
+func (r *quotaReconciler) run(ctx context.Context) {
+	r.reconcile(ctx, time.Now())
+
+	ticker := time.NewTicker(quotaReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reconcile(ctx, time.Now())
+		}
+	}
+}
+
+func (r *quotaReconciler) reconcile(ctx context.Context, now time.Time) {
+	registrations, err := r.db.ListSliceRegistrationsByNode(ctx, r.db.NodeID())
+	if err != nil {
+		logger.EllaLog.Warn("couldn't list slice registrations", zap.Error(err))
+		return
+	}
+
+	admitted, err := r.db.ListAdmittedSessionsByNode(ctx, r.db.NodeID())
+	if err != nil {
+		logger.EllaLog.Warn("couldn't list admitted sessions", zap.Error(err))
+		return
+	}
+
+	changes := quotaAdmissionChanges(registrations, admitted, r.amf.RegisteredSlices(), r.sessions.QuotaSessions(), now)
+
+	if err := r.db.ReconcileQuotaAdmissions(ctx, &changes); err != nil {
+		logger.EllaLog.Warn("couldn't reconcile admission records", zap.Error(err))
+	}
+}
+
+type sliceRegistrationKey struct {
+	imsi string
+	sst  int32
+	sd   string
+}
+
+// quotaAdmissionChanges compares the records of this node with what it
+// holds at now. Records younger than quotaReconcileGrace are kept.
+func quotaAdmissionChanges(registrations []db.SliceRegistration, admitted []db.AdmittedSession, registered map[etsi.SUPI][]models.Snssai, sessions []smf.QuotaSession, now time.Time) db.QuotaAdmissionChanges {
+	var changes db.QuotaAdmissionChanges
+
+	cutoff := now.Add(-quotaReconcileGrace).Unix()
+
+	recorded := make(map[sliceRegistrationKey]db.SliceRegistration, len(registrations))
+	for _, row := range registrations {
+		recorded[sliceRegistrationKey{row.IMSI, row.Sst, row.Sd}] = row
+	}
+
+	held := make(map[sliceRegistrationKey]struct{})
+
+	for supi, nssai := range registered {
+		for _, snssai := range nssai {
+			key := sliceRegistrationKey{supi.IMSI(), snssai.Sst, models.NormalizeSD(snssai.Sd)}
+			held[key] = struct{}{}
+
+			if _, ok := recorded[key]; !ok {
+				changes.AddRegistrations = append(changes.AddRegistrations, db.SliceRegistration{
+					IMSI: key.imsi, Sst: key.sst, Sd: key.sd, CreatedAt: now.Unix(),
+				})
+			}
+		}
+	}
+
+	for key, row := range recorded {
+		if _, ok := held[key]; !ok && row.CreatedAt <= cutoff {
+			changes.RemoveRegistrations = append(changes.RemoveRegistrations, row)
+		}
+	}
+
+	recordedSessions := make(map[string]db.AdmittedSession, len(admitted))
+	for _, row := range admitted {
+		recordedSessions[row.Ref] = row
+	}
+
+	heldSessions := make(map[string]struct{}, len(sessions))
+
+	for _, s := range sessions {
+		heldSessions[s.Ref] = struct{}{}
+
+		if _, ok := recordedSessions[s.Ref]; ok {
+			continue
+		}
+
+		row := db.AdmittedSession{Ref: s.Ref, IMSI: s.Supi.IMSI(), DataNetwork: s.Dnn, CreatedAt: now.Unix()}
+
+		if s.Snssai != nil {
+			sst := s.Snssai.Sst
+			row.Sst = &sst
+			row.Sd = models.NormalizeSD(s.Snssai.Sd)
+		}
+
+		changes.AddSessions = append(changes.AddSessions, row)
+	}
+
+	for ref, row := range recordedSessions {
+		if _, ok := heldSessions[ref]; !ok && row.CreatedAt <= cutoff {
+			changes.RemoveSessions = append(changes.RemoveSessions, ref)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+)
+
+func TestSessionQuotas(t *testing.T) {
+	adapter, dnn, dnID, _ := setupAdapterTestDB(t)
+	ctx := context.Background()
+	quotas := &sessionQuotas{db: adapter.db}
+	sd := "102030"
+	snssai := &models.Snssai{Sst: 1, Sd: sd}
+
+	if err := adapter.db.CreateNetworkSlice(ctx, &db.NetworkSlice{Name: "tenant-a", Sst: 1, Sd: &sd}); err != nil {
+		t.Fatalf("CreateNetworkSlice: %v", err)
+	}
+
+	quota, err := quotas.SessionQuota(ctx, snssai, dnn)
+	if err != nil {
+		t.Fatalf("SessionQuota: %v", err)
+	}
+
+	if quota != (smf.SessionQuota{}) {
+		t.Fatalf("quota without configuration = %+v, want none", quota)
+	}
+
+	slice, err := adapter.db.GetNetworkSlice(ctx, "tenant-a")
+	if err != nil {
+		t.Fatalf("GetNetworkSlice: %v", err)
+	}
+
+	if err := adapter.db.SetNetworkSliceQuota(ctx, &db.NetworkSliceQuota{NetworkSliceID: slice.ID, MaxSessions: 100, MaxSessionsPerSubscriber: 2}); err != nil {
+		t.Fatalf("SetNetworkSliceQuota: %v", err)
+	}
+
+	if err := adapter.db.SetDataNetworkQuota(ctx, &db.DataNetworkQuota{DataNetworkID: dnID, MaxSessions: 50, BackOffTimer: 60}); err != nil {
+		t.Fatalf("SetDataNetworkQuota: %v", err)
+	}
+
+	quota, err = quotas.SessionQuota(ctx, snssai, dnn)
+	if err != nil {
+		t.Fatalf("SessionQuota: %v", err)
+	}
+
+	want := smf.SessionQuota{
+		SliceMaxSessions:              100,
+		SliceMaxSessionsPerSubscriber: 2,
+		SliceBackOff:                  db.DefaultQuotaBackOff,
+		DataNetworkMaxSessions:        50,
+		DataNetworkBackOff:            time.Minute,
+	}
+
+	if quota != want {
+		t.Fatalf("quota = %+v, want %+v", quota, want)
+	}
+
+	// Another slice has no quota.
+	quota, err = quotas.SessionQuota(ctx, &models.Snssai{Sst: 1}, dnn)
+	if err != nil {
+		t.Fatalf("SessionQuota: %v", err)
+	}
+
+	if quota.SliceMaxSessions != 0 || quota.DataNetworkMaxSessions != 50 {
+		t.Fatalf("quota on another slice = %+v, want only the data network's", quota)
+	}
+}
+
+func TestRegistrationQuotas(t *testing.T) {
+	adapter, _, _, _ := setupAdapterTestDB(t)
+	ctx := context.Background()
+	quotas := &registrationQuotas{db: adapter.db}
+	sd := "102030"
+	full := models.Snssai{Sst: 1, Sd: sd}
+	open := models.Snssai{Sst: 2}
+
+	if err := adapter.db.CreateNetworkSlice(ctx, &db.NetworkSlice{Name: "tenant-a", Sst: 1, Sd: &sd}); err != nil {
+		t.Fatalf("CreateNetworkSlice: %v", err)
+	}
+
+	slice, err := adapter.db.GetNetworkSlice(ctx, "tenant-a")
+	if err != nil {
+		t.Fatalf("GetNetworkSlice: %v", err)
+	}
+
+	if err := adapter.db.SetNetworkSliceQuota(ctx, &db.NetworkSliceQuota{NetworkSliceID: slice.ID, MaxRegisteredUEs: 1, BackOffTimer: 120}); err != nil {
+		t.Fatalf("SetNetworkSliceQuota: %v", err)
+	}
+
+	first, _ := etsi.NewSUPIFromIMSI("001010000000001")
+	second, _ := etsi.NewSUPIFromIMSI("001010000000002")
+
+	refused, _, err := quotas.AdmitRegistration(ctx, first, []models.Snssai{full, open})
+	if err != nil || len(refused) != 0 {
+		t.Fatalf("first UE refused %v, err %v", refused, err)
+	}
+
+	refused, backOff, err := quotas.AdmitRegistration(ctx, second, []models.Snssai{full, open})
+	if err != nil {
+		t.Fatalf("AdmitRegistration: %v", err)
+	}
+
+	if len(refused) != 1 || refused[0] != full || backOff != 2*time.Minute {
+		t.Fatalf("second UE refused %v with back-off %v, want the full slice and 2m", refused, backOff)
+	}
+
+	if err := quotas.ReleaseRegistration(ctx, first); err != nil {
+		t.Fatalf("ReleaseRegistration: %v", err)
+	}
+
+	refused, _, err = quotas.AdmitRegistration(ctx, second, []models.Snssai{full, open})
+	if err != nil || len(refused) != 0 {
+		t.Fatalf("second UE refused %v after the first left, err %v", refused, err)
+	}
+
+	// A UE on slices without a limit is admitted unrecorded.
+	third, _ := etsi.NewSUPIFromIMSI("001010000000003")
+
+	refused, _, err = quotas.AdmitRegistration(ctx, third, []models.Snssai{open})
+	if err != nil || len(refused) != 0 {
+		t.Fatalf("UE on an open slice refused %v, err %v", refused, err)
+	}
+
+	registrations, err := adapter.db.ListSliceRegistrationsByNode(ctx, adapter.db.NodeID())
+	if err != nil {
+		t.Fatalf("ListSliceRegistrationsByNode: %v", err)
+	}
+
+	for _, row := range registrations {
+		if row.IMSI == third.IMSI() {
+			t.Fatalf("registration %+v recorded without a limit", row)
+		}
+	}
+}
+
+func TestQuotaAdmissionChanges(t *testing.T) {
+	now := time.Unix(1000, 0)
+	young, old := now.Unix()-1, now.Add(-quotaReconcileGrace).Unix()
+	live, _ := etsi.NewSUPIFromIMSI("001010000000001")
+	snssai := &models.Snssai{Sst: 1, Sd: "0A0B0C"}
+	sst := int32(1)
+
+	registrations := []db.SliceRegistration{
+		{IMSI: "001010000000001", Sst: 1, Sd: "0a0b0c", CreatedAt: old},
+		{IMSI: "001010000000002", Sst: 1, CreatedAt: old},
+		{IMSI: "001010000000003", Sst: 1, CreatedAt: young},
+	}
+	admitted := []db.AdmittedSession{
+		{Ref: "gone", IMSI: "001010000000002", DataNetwork: "internet", CreatedAt: old},
+		{Ref: "admitting", IMSI: "001010000000003", DataNetwork: "internet", CreatedAt: young},
+	}
+	registered := map[etsi.SUPI][]models.Snssai{live: {*snssai, {Sst: 2}}}
+	sessions := []smf.QuotaSession{{Ref: "live", Supi: live, Snssai: snssai, Dnn: "internet"}}
+
+	changes := quotaAdmissionChanges(registrations, admitted, registered, sessions, now)
+
+	if len(changes.AddRegistrations) != 1 || changes.AddRegistrations[0].Sst != 2 {
+		t.Errorf("added registrations %+v, want only the unrecorded slice", changes.AddRegistrations)
+	}
+
+	if len(changes.RemoveRegistrations) != 1 || changes.RemoveRegistrations[0].IMSI != "001010000000002" {
+		t.Errorf("removed registrations %+v, want only the old one no UE holds", changes.RemoveRegistrations)
+	}
+
+	if len(changes.AddSessions) != 1 || changes.AddSessions[0].Ref != "live" || *changes.AddSessions[0].Sst != sst || changes.AddSessions[0].Sd != "0a0b0c" {
+		t.Errorf("added sessions %+v, want the live session", changes.AddSessions)
+	}
+
+	if len(changes.RemoveSessions) != 1 || changes.RemoveSessions[0] != "gone" {
+		t.Errorf("removed sessions %v, want only the old one no session holds", changes.RemoveSessions)
+	}
+}
@@ -0,0 +1,60 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/amf"
+	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/monitoringevent"
+)
+
+// monitoredUEs announces UEs to monitoringevent from the AMF and the MME.
+type monitoredUEs struct {
+	amf *amf.AMF
+	mme *mme.MME
+}
+
+func (m *monitoredUEs) Announce(imsi string) {
+	supi, err := etsi.NewSUPIFromIMSI(imsi)
+	if err != nil {
+		return
+	}
+
+	m.amf.AnnounceUE(supi)
+	m.mme.AnnounceUE(supi)
+}
+
+// amfObserver hands the transitions of the AMF's UEs to monitoringevent.
+type amfObserver struct {
+	svc *monitoringevent.Service
+}
+
+// mmeObserver hands the transitions of the MME's UEs to monitoringevent.
+type mmeObserver struct {
+	svc *monitoringevent.Service
+}
+
+func (o amfObserver) UEChanged(supi etsi.SUPI, status amf.UEStatus) {
+	observeUE(o.svc, supi, monitoringevent.RATNR, status.Registered, status.Connected, status.Location)
+}
+
+func (o mmeObserver) UEChanged(supi etsi.SUPI, status mme.UEStatus) {
+	observeUE(o.svc, supi, monitoringevent.RATEUTRA, status.Registered, status.Connected, status.Location)
+}
+
+func observeUE(svc *monitoringevent.Service, supi etsi.SUPI, rat string, registered, connected bool, loc models.UserLocation) {
+	if !supi.IsIMSI() {
+		return
+	}
+
+	if !registered {
+		svc.Observe(supi.IMSI(), rat, nil)
+		return
+	}
+
+	at, _ := monitoringevent.LocationOf(loc)
+	svc.Observe(supi.IMSI(), rat, &monitoringevent.UE{RAT: rat, Connected: connected, Location: at})
+}
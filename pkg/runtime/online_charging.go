@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+
+	"github.com/ellanetworks/core/internal/accounting"
+	"github.com/ellanetworks/core/internal/charging"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/smf"
+)
+
+// smfOnlineCharging adapts the online charging service to
+// smf.OnlineCharging.
+type smfOnlineCharging struct {
+	svc *charging.Service
+}
+
+func (c *smfOnlineCharging) Authorize(ctx context.Context, sess smf.ChargingSession) (smf.ChargingGrant, error) {
+	rat := accounting.RATNR
+	if sess.Access == smf.Access4G {
+		rat = accounting.RATEUTRAN
+	}
+
+	threshold, err := c.svc.Authorize(ctx, charging.Session{
+		Ref:        sess.Ref,
+		IMSI:       sess.IMSI,
+		DNN:        sess.Dnn,
+		Snssai:     sess.Snssai,
+		RAT:        rat,
+		IPv4:       sess.IPv4,
+		IPv6Prefix: sess.IPv6Prefix,
+		PolicyID:   sess.PolicyID,
+	})
+	if err != nil {
+		return smf.ChargingGrant{}, err
+	}
+
+	return smf.ChargingGrant{UsageThreshold: threshold}, nil
+}
+
+func (c *smfOnlineCharging) SessionUsage(ref string, uplinkBytes, downlinkBytes uint64, groups []models.RatingGroupUsage) {
+	c.svc.SessionUsage(ref, uplinkBytes, downlinkBytes, groups)
+}
+
+func (c *smfOnlineCharging) SessionStopped(ref string) {
+	c.svc.SessionStopped(ref)
+}
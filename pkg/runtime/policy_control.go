@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/policycontrol"
+	"github.com/ellanetworks/core/internal/smf"
+)
+
+// smfPolicyControl adapts the policy control service to smf.PolicyControl.
+type smfPolicyControl struct {
+	svc *policycontrol.Service
+	db  *db.Database
+}
+
+func (c *smfPolicyControl) Decide(ctx context.Context, sess smf.PolicySession) (smf.PolicyDecision, bool, error) {
+	ps := policycontrol.Session{
+		Ref:          sess.Ref,
+		IMSI:         sess.IMSI,
+		PDUSessionID: sess.PDUSessionID,
+		DNN:          sess.Dnn,
+		Snssai:       sess.Snssai,
+		RAT:          ratOf(sess.Access),
+	}
+
+	if p := sess.Policy; p != nil {
+		ps.Var5qi = p.QosData.Var5qi
+		ps.SessionAmbrUplink = p.Ambr.Uplink.String()
+		ps.SessionAmbrDownlink = p.Ambr.Downlink.String()
+
+		if p.QosData.Arp != nil {
+			ps.Arp = p.QosData.Arp.PriorityLevel
+		}
+	}
+
+	decision, ok, err := c.svc.Create(ctx, ps)
+	if err != nil || !ok {
+		return smf.PolicyDecision{}, ok, err
+	}
+
+	d, err := smfDecision(ctx, c.db, decision)
+	if err != nil {
+		c.svc.SessionStopped(sess.Ref)
+		return smf.PolicyDecision{}, false, err
+	}
+
+	return d, true, nil
+}
+
+func (c *smfPolicyControl) SessionMoved(ref string, access smf.AccessType) {
+	c.svc.SessionMoved(ref, ratOf(access))
+}
+
+func (c *smfPolicyControl) SessionStopped(ref string) {
+	c.svc.SessionStopped(ref)
+}
+
+// policyApplier puts the decisions of the policy control service in force
+// on the SMF's sessions.
+type policyApplier struct {
+	smf *smf.SMF
+	db  *db.Database
+}
+
+func (a *policyApplier) ApplyDecision(ctx context.Context, ref string, decision policycontrol.Decision) error {
+	d, err := smfDecision(ctx, a.db, decision)
+	if err != nil {
+		return err
+	}
+
+	return a.smf.ApplyPolicyDecision(ctx, ref, d)
+}
+
+func ratOf(access smf.AccessType) policycontrol.RAT {
+	if access == smf.Access4G {
+		return policycontrol.RATEUTRA
+	}
+
+	return policycontrol.RATNR
+}
+
+// smfDecision resolves the Session-AMBR and network rules of a decision.
+func smfDecision(ctx context.Context, database *db.Database, decision policycontrol.Decision) (smf.PolicyDecision, error) {
+	d := smf.PolicyDecision{Var5qi: decision.Var5qi, Arp: decision.Arp}
+
+	if decision.SessionAmbrUplink != "" {
+		uplink, err := models.ParseBitRate(decision.SessionAmbrUplink)
+		if err != nil {
+			return smf.PolicyDecision{}, err
+		}
+
+		downlink, err := models.ParseBitRate(decision.SessionAmbrDownlink)
+		if err != nil {
+			return smf.PolicyDecision{}, err
+		}
+
+		d.Ambr = &models.Ambr{Uplink: uplink, Downlink: downlink}
+	}
+
+	if decision.RulesPolicyID == "" {
+		return d, nil
+	}
+
+	dbRules, err := database.ListRulesForPolicy(ctx, decision.RulesPolicyID)
+	if err != nil {
+		return smf.PolicyDecision{}, fmt.Errorf("couldn't list network rules: %w", err)
+	}
+
+	rules, err := resolveNetworkRules(dbRules)
+	if err != nil {
+		return smf.PolicyDecision{}, err
+	}
+
+	d.Filter = &smf.PolicyFilter{PolicyID: decision.RulesPolicyID, NetworkRules: rules}
+
+	return d, nil
+}
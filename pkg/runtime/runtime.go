@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/accounting"
 	"github.com/ellanetworks/core/internal/amf"
 	"github.com/ellanetworks/core/internal/amf/nas"
 	"github.com/ellanetworks/core/internal/amf/ngap"
@@ -24,6 +25,8 @@ import (
 	"github.com/ellanetworks/core/internal/api/server"
 	"github.com/ellanetworks/core/internal/ausf"
 	"github.com/ellanetworks/core/internal/bgp"
+	"github.com/ellanetworks/core/internal/cdr"
+	"github.com/ellanetworks/core/internal/charging"
 	"github.com/ellanetworks/core/internal/cluster/listener"
 	"github.com/ellanetworks/core/internal/cluster/pkiissuer"
 	"github.com/ellanetworks/core/internal/config"
@@ -37,7 +40,11 @@ import (
 	"github.com/ellanetworks/core/internal/mme"
 	mmenas "github.com/ellanetworks/core/internal/mme/nas"
 	mmes1ap "github.com/ellanetworks/core/internal/mme/s1ap"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/monitoringevent"
 	"github.com/ellanetworks/core/internal/netutil"
+	"github.com/ellanetworks/core/internal/policycontrol"
+	"github.com/ellanetworks/core/internal/qossession"
 	ellaraft "github.com/ellanetworks/core/internal/raft"
 	amfsctp "github.com/ellanetworks/core/internal/sctp"
 	"github.com/ellanetworks/core/internal/sessions"
@@ -296,6 +303,18 @@ func Start(ctx context.Context, rc RuntimeConfig) error {
 		jobs.RunJoinTokenTidyWorker(ctx, dbInstance, jobsGuard)
 	})
 
+	wg.Go(func() {
+		jobs.RunScheduleWorker(ctx, dbInstance)
+	})
+
+	wg.Go(func() {
+		jobs.RunAmbrOverrideWorker(ctx, dbInstance, jobsGuard)
+	})
+
+	wg.Go(func() {
+		jobs.RunSubscriberLifecycleWorker(ctx, dbInstance, jobsGuard)
+	})
+
 	wg.Go(func() {
 		sessions.CleanUp(ctx, dbInstance, sessionsGuard)
 	})
@@ -429,26 +448,89 @@ func Start(ctx context.Context, rc RuntimeConfig) error {
 	}
 
 	// Create SMF with dependency-injected adapters.
-	smfPCF := &pcfDBAdapter{db: dbInstance}
-	smfStore := &smfDBAdapter{db: dbInstance}
+	areas := &servingAreas{}
+	smfPCF := &pcfDBAdapter{db: dbInstance, areas: areas}
+	externalAllocator := newExternalAllocator(dbInstance)
+	smfStore := &smfDBAdapter{db: dbInstance, external: externalAllocator}
 	smfAMF := &smfAMFAdapter{}
 
-	smfInstance := smf.New(smfPCF, smfStore, nil, smfAMF)
+	acctUEs := &accountingUEs{}
+	acctWakeup, stopAcctWakeup := dbInstance.Changefeed().Wakeup(db.TopicAccountingServers)
+	acctService := accounting.NewService(dbInstance, acctUEs, nasIdentifier(dbInstance.NodeID()), acctWakeup)
+	cdrService := cdr.NewService(cdr.Dir(cfg.DB.Path), nasIdentifier(dbInstance.NodeID()), n3Addr, acctUEs)
+	chargingService := charging.NewService(dbInstance, nasIdentifier(dbInstance.NodeID()))
+	policyWakeup, stopPolicyWakeup := dbInstance.Changefeed().Wakeup(db.TopicPolicyControl)
+	policyService := policycontrol.NewService(dbInstance, dbInstance.NodeID(), policyWakeup)
+	qosWakeup, stopQosWakeup := dbInstance.Changefeed().Wakeup(db.TopicQosSessions)
+	qosService := qossession.NewService(dbInstance, dbInstance.NodeID(), qosWakeup)
+	monitoringWakeup, stopMonitoringWakeup := dbInstance.Changefeed().Wakeup(db.TopicMonitoringSubscriptions)
+	monitoringService := monitoringevent.NewService(dbInstance, monitoringWakeup)
+
+	smfInstance := smf.New(smfPCF, smfStore, nil, smfAMF,
+		smf.WithDNAAA(&dnAAA{db: dbInstance}),
+		smf.WithAccounting(&smfAccounting{svc: acctService, cdr: cdrService, events: monitoringService}),
+		smf.WithOnlineCharging(&smfOnlineCharging{svc: chargingService}),
+		smf.WithPolicyControl(&smfPolicyControl{svc: policyService, db: dbInstance}),
+		smf.WithQoSFlowEvents(qosService),
+		smf.WithSessionQuotas(&sessionQuotas{db: dbInstance}),
+	)
+
+	externalAllocator.sessions = smfInstance
+
+	acctService.Start()
+	cdrService.Start()
 
-	upfInstance, err := upf.Start(ctx, smfInstance, cfg.Interfaces.N3, n3IPv4, n3IPv6, advertisedN3IPv4, advertisedN3IPv6, cfg.Interfaces.N6, cfg.Datapath.AttachMode, isNATEnabled, isFlowAccountingEnabled, isLocalSwitchEnabled)
+	defer func() {
+		cdrService.Stop()
+		acctService.Stop()
+		stopAcctWakeup()
+		stopPolicyWakeup()
+		stopQosWakeup()
+		stopMonitoringWakeup()
+	}()
+
+	wg.Go(func() {
+		externalAllocator.run(ctx)
+	})
+
+	upfReports := &reportRouter{smf: smfInstance}
+
+	upfInstance, err := upf.Start(ctx, upfReports, cfg.Interfaces.N3, n3IPv4, n3IPv6, advertisedN3IPv4, advertisedN3IPv6, cfg.Interfaces.N6, cfg.Datapath.AttachMode, isNATEnabled, isFlowAccountingEnabled, isLocalSwitchEnabled)
 	if err != nil {
 		return fmt.Errorf("couldn't start UPF: %w", err)
 	}
 
+	eng := upfInstance.Engine()
+	localUPF := &smfUPFAdapter{engine: eng, upf: upfInstance}
+
+	smfUPF, n4, err := startUserPlanes(ctx, cfg, localUPF, smfInstance)
+	if err != nil {
+		return err
+	}
+
+	var upfUpdater upf.Updater = upfInstance
+
+	if n4 != nil {
+		defer n4.Close()
+
+		upfUpdater = &userPlaneUpdater{Updater: upfInstance, remote: n4}
+	}
+
+	n4Server, err := startControlPlanes(ctx, cfg, localUPF, upfReports)
+	if err != nil {
+		return err
+	}
+
+	if n4Server != nil {
+		defer n4Server.Close()
+	}
+
 	fallbackN3, _ := netip.ParseAddr(n3IPv4)
-	upfReconciler := upf.NewSettingsReconciler(upfInstance, dbInstance, dbInstance.Changefeed(), fallbackN3)
+	upfReconciler := upf.NewSettingsReconciler(upfUpdater, dbInstance, dbInstance.Changefeed(), fallbackN3)
 	upfReconciler.Start()
 
 	defer upfReconciler.Stop()
 
-	eng := upfInstance.Engine()
-
-	smfUPF := &smfUPFAdapter{engine: eng, upf: upfInstance}
 	smfInstance.SetUPF(smfUPF)
 
 	// Initialize SDF filters from database
@@ -502,15 +584,39 @@ func Start(ctx context.Context, rc RuntimeConfig) error {
 
 	amfInstance := amf.New(dbInstance, ausfInstance, smfInstance)
 	amfInstance.NAS = &nasAdapter{amf: amfInstance}
+	amfInstance.Quotas = &registrationQuotas{db: dbInstance}
+	amfInstance.Observer = amfObserver{svc: monitoringService}
 	smfAMF.amf = amfInstance
 	mmeInstance := mme.New(udm.New(ausfStore, keyResolver), dbInstance, smfInstance)
 	mmeInstance.NAS = &mmeNASAdapter{mme: mmeInstance}
+	mmeInstance.Observer = mmeObserver{svc: monitoringService}
 	smfInstance.SetMME(mmeInstance)
+	chargingService.Start(smfInstance, upfInstance.DatapathFeatures)
+
+	defer chargingService.Stop()
+
+	policyService.Start(&policyApplier{smf: smfInstance, db: dbInstance})
+
+	defer policyService.Stop()
+
+	qosService.Start(smfInstance, upfReconciler, upfInstance.DatapathFeatures)
+
+	defer qosService.Stop()
+
+	acctUEs.amf = amfInstance
+	acctUEs.mme = mmeInstance
+	areas.amf = amfInstance
+	areas.mme = mmeInstance
+
+	monitoringService.Start(&monitoredUEs{amf: amfInstance, mme: mmeInstance})
+
+	defer monitoringService.Stop()
 	amfInstance.EPS = mmeInstance
 	mmeInstance.FiveGS = amfInstance
 
 	metrics.RegisterMetrics()
 	metrics.RegisterRadioGauges(amfInstance.CountRadios, amfInstance.CountRegisteredSubscribers, mmeInstance.CountRadios, mmeInstance.CountRegisteredSubscribers)
+	metrics.RegisterSliceGauges(func() map[models.Snssai]int { return amfInstance.CountRegisteredSubscribersBySlice(etsi.SUPI{}) })
 
 	lmfInstance := lmf.New(amfInstance, mmeInstance, dbInstance)
 
@@ -518,6 +624,12 @@ func Start(ctx context.Context, rc RuntimeConfig) error {
 	lmfInstance.SetLPPHandler(lmfAMF)
 	amfInstance.LPPHandler = lmfAMF
 
+	quotas := &quotaReconciler{db: dbInstance, amf: amfInstance, sessions: smfInstance}
+
+	wg.Go(func() {
+		quotas.run(ctx)
+	})
+
 	// Session reconciler: watches the session_reconcile changefeed topic
 	// and reconciles every local PDU session against the current DB policy.
 	// Triggered by profile, subscriber, and policy writes.
@@ -565,6 +677,7 @@ func Start(ctx context.Context, rc RuntimeConfig) error {
 		RegisterExtraRoutes: rc.RegisterExtraRoutes,
 		ClusterListener:     clusterLn,
 		DatapathAttachMode:  upfInstance.DatapathAttachMode,
+		DatapathFeatures:    upfInstance.DatapathFeatures,
 	}); err != nil {
 		return fmt.Errorf("couldn't upgrade API: %w", err)
 	}
@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/radius"
+	"github.com/ellanetworks/core/internal/smf"
+)
+
+// dnAAA relays secondary authentication to the RADIUS server of the data
+// network (TS 29.561 §16), carrying EAP in EAP-Message attributes (RFC
+// 3579).
+type dnAAA struct {
+	db *db.Database
+}
+
+func (a *dnAAA) Required(ctx context.Context, dnn string) (bool, error) {
+	dn, err := a.db.GetDataNetwork(ctx, dnn)
+	if err != nil {
+		return false, fmt.Errorf("get data network: %w", err)
+	}
+
+	if _, err := a.db.GetDataNetworkSecondaryAuth(ctx, dn.ID); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("get secondary authentication: %w", err)
+	}
+
+	return true, nil
+}
+
+func (a *dnAAA) Authenticate(ctx context.Context, req *smf.AAARequest) (*smf.AAAAnswer, error) {
+	dn, err := a.db.GetDataNetwork(ctx, req.DNN)
+	if err != nil {
+		return nil, fmt.Errorf("get data network: %w", err)
+	}
+
+	cfg, err := a.db.GetDataNetworkSecondaryAuth(ctx, dn.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get secondary authentication: %w", err)
+	}
+
+	server, err := parseServer(cfg.Server, radius.AuthPort)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := radius.NewClient(radius.Config{Server: server, Secret: []byte(cfg.Secret)})
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := radius.NewRequest(radius.CodeAccessRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	p.AddString(radius.TypeUserName, req.Identity)
+	p.AddString(radius.TypeCallingStationID, req.IMSI)
+	p.AddString(radius.TypeCalledStationID, req.DNN)
+	p.AddUint32(radius.TypeServiceType, radius.ServiceTypeFramed)
+	p.AddUint32(radius.TypeFramedProtocol, radius.FramedProtocolGPRSPDPCtxt)
+	p.AddString(radius.TypeNASIdentifier, nasIdentifier(a.db.NodeID()))
+	p.Add(radius.TypeEAPMessage, req.EAP)
+
+	if req.State != nil {
+		p.Add(radius.TypeState, req.State)
+	}
+
+	p.Add(radius.TypeMessageAuthenticator, nil)
+
+	reqCtx, cancel := context.WithTimeout(ctx, externalRequestTimeout)
+	defer cancel()
+
+	resp, err := client.Exchange(reqCtx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &smf.AAAAnswer{EAP: resp.Concat(radius.TypeEAPMessage)}
+
+	switch resp.Code {
+	case radius.CodeAccessChallenge:
+		ans.Verdict = smf.AAAChallenge
+		ans.State, _ = resp.Get(radius.TypeState)
+	case radius.CodeAccessReject:
+		ans.Verdict = smf.AAAReject
+	case radius.CodeAccessAccept:
+		ans.Verdict = smf.AAAAccept
+
+		ans.Authorization, err = a.authorization(ctx, dn, resp)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected %s", resp.Code)
+	}
+
+	return ans, nil
+}
+
+// authorization reads what an Access-Accept sets for the session. Filter-Id
+// names a policy of the same data network, whose network rules apply.
+func (a *dnAAA) authorization(ctx context.Context, dn *db.DataNetwork, resp *radius.Packet) (smf.AAAAuthorization, error) {
+	var authz smf.AAAAuthorization
+
+	// 255.255.255.254 and 255.255.255.255 leave the choice to us (RFC 2865
+	// §5.8).
+	if addr, ok := resp.Addr(radius.TypeFramedIPAddress); ok && addr != netip.AddrFrom4([4]byte{255, 255, 255, 254}) && addr != netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		authz.IPv4 = addr
+	}
+
+	if secs, ok := resp.Uint32(radius.TypeSessionTimeout); ok && secs > 0 {
+		authz.SessionTimeout = time.Duration(secs) * time.Second
+	}
+
+	if name := resp.String(radius.TypeFilterID); name != "" {
+		pol, err := a.db.GetPolicy(ctx, name)
+		if err != nil {
+			return authz, fmt.Errorf("policy %q from Filter-Id: %w", name, err)
+		}
+
+		if pol.DataNetworkID != dn.ID {
+			return authz, fmt.Errorf("policy %q from Filter-Id belongs to another data network", name)
+		}
+
+		dbRules, err := a.db.ListRulesForPolicy(ctx, pol.ID)
+		if err != nil {
+			return authz, fmt.Errorf("list rules of policy %q: %w", name, err)
+		}
+
+		rules, err := resolveNetworkRules(dbRules)
+		if err != nil {
+			return authz, err
+		}
+
+		authz.Filter = &smf.PolicyFilter{PolicyID: pol.ID, NetworkRules: rules}
+	}
+
+	return authz, nil
+}
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ellanetworks/core/internal/db"
+	"github.com/ellanetworks/core/internal/radius"
+	"github.com/ellanetworks/core/internal/radius/radiustest"
+	"github.com/ellanetworks/core/internal/smf"
+)
+
+func TestDNAAA_ChallengeThenAccept(t *testing.T) {
+	adapter, dnn, dnID, imsi := setupAdapterTestDB(t)
+	ctx := context.Background()
+
+	secret := []byte("testing123")
+	framed := netip.MustParseAddr("192.168.1.77")
+	challenge := bytes.Repeat([]byte{0x01}, 300) // spans two EAP-Message attributes
+
+	srv, err := radiustest.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), secret, func(req *radius.Packet) *radius.Packet {
+		if req.String(radius.TypeUserName) != "alice" || req.String(radius.TypeCallingStationID) != imsi || req.String(radius.TypeCalledStationID) != dnn {
+			return &radius.Packet{Code: radius.CodeAccessReject}
+		}
+
+		if _, ok := req.Get(radius.TypeState); !ok {
+			resp := &radius.Packet{Code: radius.CodeAccessChallenge}
+			resp.Add(radius.TypeEAPMessage, challenge)
+			resp.Add(radius.TypeState, []byte("round-2"))
+			resp.Add(radius.TypeMessageAuthenticator, nil)
+
+			return resp
+		}
+
+		resp := &radius.Packet{Code: radius.CodeAccessAccept}
+		resp.Add(radius.TypeEAPMessage, []byte{0x03, 0x02, 0x00, 0x04})
+		resp.AddAddr(radius.TypeFramedIPAddress, framed)
+		resp.AddString(radius.TypeFilterID, "test-policy")
+		resp.AddUint32(radius.TypeSessionTimeout, 3600)
+		resp.Add(radius.TypeMessageAuthenticator, nil)
+
+		return resp
+	})
+	if err != nil {
+		t.Fatalf("radiustest.NewServer: %s", err)
+	}
+
+	defer func() { _ = srv.Close() }()
+
+	aaa := &dnAAA{db: adapter.db}
+
+	if required, err := aaa.Required(ctx, dnn); err != nil || required {
+		t.Fatalf("Required before setup = %v, %v, want false", required, err)
+	}
+
+	if err := adapter.db.SetDataNetworkSecondaryAuth(ctx, &db.DataNetworkSecondaryAuth{DataNetworkID: dnID, Server: srv.Addr().String(), Secret: string(secret)}); err != nil {
+		t.Fatalf("SetDataNetworkSecondaryAuth: %s", err)
+	}
+
+	if required, err := aaa.Required(ctx, dnn); err != nil || !required {
+		t.Fatalf("Required = %v, %v, want true", required, err)
+	}
+
+	req := &smf.AAARequest{IMSI: imsi, DNN: dnn, PDUSessionID: 1, Identity: "alice", EAP: []byte{0x02, 0x01, 0x00, 0x0a, 0x01, 'a', 'l', 'i', 'c', 'e'}}
+
+	ans, err := aaa.Authenticate(ctx, req)
+	if err != nil {
+		t.Fatalf("first round: %s", err)
+	}
+
+	if ans.Verdict != smf.AAAChallenge || !bytes.Equal(ans.EAP, challenge) || string(ans.State) != "round-2" {
+		t.Fatalf("first round = verdict %d, %d EAP bytes, state %q", ans.Verdict, len(ans.EAP), ans.State)
+	}
+
+	req.EAP = []byte{0x02, 0x02, 0x00, 0x04}
+	req.State = ans.State
+
+	ans, err = aaa.Authenticate(ctx, req)
+	if err != nil {
+		t.Fatalf("second round: %s", err)
+	}
+
+	if ans.Verdict != smf.AAAAccept {
+		t.Fatalf("verdict = %d, want accept", ans.Verdict)
+	}
+
+	authz := ans.Authorization
+	if authz.IPv4 != framed || authz.SessionTimeout != time.Hour {
+		t.Fatalf("authorization = %+v, want %s for an hour", authz, framed)
+	}
+
+	pol, err := adapter.db.GetPolicy(ctx, "test-policy")
+	if err != nil {
+		t.Fatalf("GetPolicy: %s", err)
+	}
+
+	if authz.Filter == nil || authz.Filter.PolicyID != pol.ID {
+		t.Fatalf("filter = %+v, want policy %s", authz.Filter, pol.ID)
+	}
+}
+
+func TestReserveIP_RecordsExternalLease(t *testing.T) {
+	adapter, dnn, dnID, imsi := setupAdapterTestDB(t)
+	ctx := context.Background()
+
+	dn, err := adapter.ResolveDNN(ctx, dnn)
+	if err != nil {
+		t.Fatalf("ResolveDNN: %s", err)
+	}
+
+	addr := netip.MustParseAddr("192.168.1.77")
+
+	got, err := dn.ReserveIP(ctx, imsi, 2, addr)
+	if err != nil || got != addr {
+		t.Fatalf("ReserveIP = %s, %v, want %s", got, err, addr)
+	}
+
+	lease, err := adapter.db.GetLeaseBySession(ctx, dnID, "ipv4", 2, imsi)
+	if err != nil || lease.Address() != addr {
+		t.Fatalf("GetLeaseBySession = %+v, %v", lease, err)
+	}
+
+	if _, err := dn.ReserveIP(ctx, "001019999999999", 1, addr); err == nil {
+		t.Fatal("reserving an address in use should fail")
+	}
+
+	if released, err := dn.ReleaseIP(ctx, imsi, 2); err != nil || released != addr {
+		t.Fatalf("ReleaseIP = %s, %v, want %s", released, err, addr)
+	}
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"github.com/ellanetworks/core/etsi"
+	"github.com/ellanetworks/core/internal/amf"
+	"github.com/ellanetworks/core/internal/mme"
+	"github.com/ellanetworks/core/internal/models"
+)
+
+// servingAreas answers where a UE is served from the AMF and the MME, so
+// session policy can follow it between sites. Both are created after the
+// SMF and set once they exist.
+type servingAreas struct {
+	amf *amf.AMF
+	mme *mme.MME
+}
+
+// ServingArea returns the area of the UE's last reported location: the 5G
+// one while the UE is registered with the AMF, the 4G one otherwise. An
+// unknown UE is in the zero area.
+func (s *servingAreas) ServingArea(imsi string) models.ServingArea {
+	if s == nil {
+		return models.ServingArea{}
+	}
+
+	supi, err := etsi.NewSUPIFromIMSI(imsi)
+	if err != nil {
+		return models.ServingArea{}
+	}
+
+	if s.amf != nil {
+		if ue, ok := s.amf.LookupUeBySupi(supi); ok && ue.State() == amf.Registered {
+			return ue.GetUserLocation().ServingArea()
+		}
+	}
+
+	if s.mme != nil {
+		if ue, ok := s.mme.LookupUeBySupi(supi); ok {
+			return ue.GetUserLocation().ServingArea()
+		}
+	}
+
+	return models.ServingArea{}
+}
@@ -36,6 +36,9 @@ var tracer = otel.Tracer("ella-core/runtime")
 
 type pcfDBAdapter struct {
 	db *db.Database
+	// areas locates UEs for policy location variants. Nil resolves every
+	// UE's policy as if its location were unknown.
+	areas *servingAreas
 }
 
 // NewPCFDBAdapter creates a new PCF database adapter.
@@ -49,6 +52,9 @@ func NewPCFDBAdapter(database *db.Database) smf.PCF {
 
 type smfDBAdapter struct {
 	db *db.Database
+	// external leases IPv4 addresses for data networks set up with a
+	// DHCP or RADIUS server. Nil allocates from pools only.
+	external *externalAllocator
 }
 
 // smfDNNStore is smf.DNNStore bound to one data-network row. A pool derivation
@@ -116,6 +122,22 @@ func (s *smfDNNStore) AllocateIP(ctx context.Context, imsi string, pduSessionID
 		return netip.Addr{}, fmt.Errorf("resolve pool: %w", err)
 	}
 
+	if s.a.external != nil {
+		addr, handled, err := s.a.external.allocate(ctx, pool, s.dnn, imsi, int(pduSessionID))
+		if handled {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "external allocate failed")
+
+				return netip.Addr{}, err
+			}
+
+			span.SetAttributes(attribute.String("ip", addr.String()))
+
+			return addr, nil
+		}
+	}
+
 	// AllocateIPLease runs the SELECT-then-INSERT atomically on the
 	// leader inside leaderCaptureAndPropose's proposeMu, so concurrent
 	// allocations from any node serialise correctly. The legacy
@@ -133,6 +155,57 @@ func (s *smfDNNStore) AllocateIP(ctx context.Context, imsi string, pduSessionID
 	return addr, nil
 }
 
+// ReserveIP leases the address a DN-AAA server authorized. The lease is
+// recorded as an external one so the address is returned to the pool's
+// bookkeeping, not the server, on release.
+func (s *smfDNNStore) ReserveIP(ctx context.Context, imsi string, pduSessionID uint8, addr netip.Addr) (netip.Addr, error) {
+	ctx, span := tracer.Start(ctx, "smf/reserve_ip",
+		trace.WithAttributes(
+			attribute.String("imsi", imsi),
+			attribute.String("dnn", s.dnn),
+			attribute.Int("pdu_session_id", int(pduSessionID)),
+			attribute.String("ip", addr.String()),
+		),
+	)
+	defer span.End()
+
+	pool, err := s.pool(false)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "resolve pool failed")
+
+		return netip.Addr{}, fmt.Errorf("resolve pool: %w", err)
+	}
+
+	sessionID := int(pduSessionID)
+	lease := &db.IPLease{
+		PoolID:    pool.ID,
+		PoolType:  pool.IPVersion,
+		IMSI:      imsi,
+		SessionID: &sessionID,
+		CreatedAt: time.Now().Unix(),
+		NodeID:    s.a.db.NodeID(),
+	}
+
+	external := &db.ExternalIPLease{
+		Source:   db.AddressAllocationRADIUS,
+		ClientID: externalClientID(imsi, sessionID),
+	}
+
+	if err := s.a.db.CreateExternalLease(ctx, lease, addr, external); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "reserve failed")
+
+		if errors.Is(err, db.ErrAlreadyExists) {
+			return netip.Addr{}, fmt.Errorf("authorized address %s is in use", addr)
+		}
+
+		return netip.Addr{}, err
+	}
+
+	return addr, nil
+}
+
 func (s *smfDNNStore) ReleaseIP(ctx context.Context, imsi string, pduSessionID uint8) (netip.Addr, error) {
 	ctx, span := tracer.Start(ctx, "smf/release_ip",
 		trace.WithAttributes(
@@ -151,6 +224,11 @@ func (s *smfDNNStore) ReleaseIP(ctx context.Context, imsi string, pduSessionID u
 		return netip.Addr{}, fmt.Errorf("resolve pool: %w", err)
 	}
 
+	var external *externalLease
+	if s.a.external != nil {
+		external = s.a.external.lookup(ctx, pool, imsi, int(pduSessionID))
+	}
+
 	addr, err := s.a.db.ReleaseIPLease(ctx, pool.ID, pool.IPVersion, imsi, int(pduSessionID), s.a.db.NodeID())
 	if err != nil {
 		span.RecordError(err)
@@ -159,6 +237,10 @@ func (s *smfDNNStore) ReleaseIP(ctx context.Context, imsi string, pduSessionID u
 		return netip.Addr{}, err
 	}
 
+	if external != nil {
+		s.a.external.giveBack(ctx, external)
+	}
+
 	return addr, nil
 }
 
@@ -223,6 +305,15 @@ func (s *smfDNNStore) ReleaseIPv6(ctx context.Context, imsi string, pduSessionID
 }
 
 func (a *pcfDBAdapter) GetSessionPolicy(ctx context.Context, imsi string, snssai *models.Snssai, dnn string) (*smf.Policy, error) {
+	state, err := a.db.SubscriberStateAt(ctx, imsi, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("subscriber %s state: %w", imsi, err)
+	}
+
+	if !state.AllowsData() {
+		return nil, fmt.Errorf("%w: subscriber %s is %s", smf.ErrDataBarred, imsi, state)
+	}
+
 	pol, dbRules, dn, err := a.db.GetSessionPolicy(ctx, imsi, snssai.Sst, snssai.Sd, dnn)
 	if err != nil {
 		if errors.Is(err, db.ErrDataNetworkNotFound) {
@@ -240,21 +331,60 @@ func (a *pcfDBAdapter) GetSessionPolicy(ctx context.Context, imsi string, snssai
 		return nil, fmt.Errorf("get session policy: %w", err)
 	}
 
-	dns := net.ParseIP(dn.DNS)
+	// A location variant replaces the policy's Session-AMBR, and maybe its
+	// network rules, where the UE is served; the AMF and MME reconcile its
+	// sessions when it moves.
+	located, rulesPolicyID, err := a.db.PolicyInArea(ctx, pol, a.areas.ServingArea(imsi))
+	if err != nil {
+		return nil, fmt.Errorf("policy %s location variants: %w", pol.ID, err)
+	}
+
+	pol = located
+
+	if rulesPolicyID != pol.ID {
+		dbRules, err = a.db.ListRulesForPolicy(ctx, rulesPolicyID)
+		if err != nil {
+			return nil, fmt.Errorf("list rules of policy %s: %w", rulesPolicyID, err)
+		}
+	}
+
+	// The embedded DNS forwarder replaces the data network's own server
+	// while it is on.
+	dnsServer, err := a.db.EffectiveDNS(ctx, dn)
+	if err != nil {
+		return nil, fmt.Errorf("data network %s DNS server: %w", dn.Name, err)
+	}
+
+	dns := net.ParseIP(dnsServer)
+
+	// A scheduled alternate Session-AMBR replaces the policy's own while its
+	// window is open; the session reconciler re-resolves when it flips.
+	sessAmbrUL, sessAmbrDL, err := a.db.EffectiveSessionAmbr(ctx, pol, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("policy %s scheduled Session-AMBR: %w", pol.ID, err)
+	}
+
+	// A subscriber's own override, while it lasts, wins over both.
+	override, err := a.db.ActiveSubscriberAmbrOverride(ctx, imsi, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("subscriber %s AMBR override: %w", imsi, err)
+	}
+
+	sessAmbrUL, sessAmbrDL = override.SessionAmbr(sessAmbrUL, sessAmbrDL)
 
 	// The stored policy text becomes a rate here, at the edge of the DB layer.
-	ambrUL, err := models.ParseBitRate(pol.SessionAmbrUplink)
+	ambrUL, err := models.ParseBitRate(sessAmbrUL)
 	if err != nil {
 		return nil, fmt.Errorf("policy %s Session-AMBR uplink: %w", pol.ID, err)
 	}
 
-	ambrDL, err := models.ParseBitRate(pol.SessionAmbrDownlink)
+	ambrDL, err := models.ParseBitRate(sessAmbrDL)
 	if err != nil {
 		return nil, fmt.Errorf("policy %s Session-AMBR downlink: %w", pol.ID, err)
 	}
 
 	policy := &smf.Policy{
-		PolicyID: pol.ID,
+		PolicyID: rulesPolicyID,
 		Ambr:     models.Ambr{Uplink: ambrUL, Downlink: ambrDL},
 		QosData: models.QosData{
 			QFI:    models.DefaultQFI,
@@ -269,6 +399,15 @@ func (a *pcfDBAdapter) GetSessionPolicy(ctx context.Context, imsi string, snssai
 		IPv6Pool: dn.IPv6Pool,
 	}
 
+	policy.NetworkRules, err = resolveNetworkRules(dbRules)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func resolveNetworkRules(dbRules []*db.NetworkRule) ([]*smf.ResolvedNetworkRule, error) {
 	resolvedRules := make([]*smf.ResolvedNetworkRule, len(dbRules))
 	for i, dbRule := range dbRules {
 		dir, err := models.ParseDirection(dbRule.Direction)
@@ -289,9 +428,7 @@ func (a *pcfDBAdapter) GetSessionPolicy(ctx context.Context, imsi string, snssai
 		}
 	}
 
-	policy.NetworkRules = resolvedRules
-
-	return policy, nil
+	return resolvedRules, nil
 }
 
 func (a *smfDBAdapter) IncrementDailyUsage(ctx context.Context, imsi string, uplinkBytes, downlinkBytes uint64) error {
@@ -305,6 +442,18 @@ func (a *smfDBAdapter) IncrementDailyUsage(ctx context.Context, imsi string, upl
 	})
 }
 
+func (a *smfDBAdapter) IncrementRatingGroupUsage(ctx context.Context, imsi string, usage models.RatingGroupUsage) error {
+	epochDay := time.Now().UTC().Unix() / 86400
+
+	return a.db.IncrementRatingGroupUsage(ctx, db.DailyRatingGroupUsage{
+		EpochDay:      epochDay,
+		IMSI:          imsi,
+		RatingGroup:   int64(usage.RatingGroup),
+		BytesUplink:   int64(usage.UplinkVolume),
+		BytesDownlink: int64(usage.DownlinkVolume),
+	})
+}
+
 func (a *smfDBAdapter) InsertFlowReports(ctx context.Context, reports []*models.FlowReportRequest) error {
 	batch := make([]*dbwriter.FlowReport, len(reports))
 
@@ -394,6 +543,12 @@ func (a *smfUPFAdapter) FlushUsage(ctx context.Context, seid uint64) {
 	}
 }
 
+func (a *smfUPFAdapter) SetUsageThreshold(ctx context.Context, seid uint64, bytes uint64) {
+	if a.upf != nil {
+		a.upf.SetUsageThreshold(seid, bytes)
+	}
+}
+
 func (a *smfUPFAdapter) DeleteSession(ctx context.Context, seid uint64) error {
 	return a.engine.DeleteSession(ctx, &models.DeleteRequest{SEID: seid})
 }
@@ -427,7 +582,7 @@ func (a *smfUPFAdapter) RegisterIPv6Session(_ context.Context, reg *models.IPv6S
 	return nil
 }
 
-func (a *smfUPFAdapter) UnregisterIPv6Session(_ context.Context, ulTEID uint32) error {
+func (a *smfUPFAdapter) UnregisterIPv6Session(_ context.Context, _ uint64, ulTEID uint32) error {
 	if a.upf == nil {
 		return nil
 	}
@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/ellanetworks/core/internal/config"
+	"github.com/ellanetworks/core/internal/logger"
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/pfcp"
+	"github.com/ellanetworks/core/internal/smf"
+	"github.com/ellanetworks/core/internal/upf"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+	upfengine "github.com/ellanetworks/core/internal/upf/engine"
+	"go.uber.org/zap"
+)
+
+// remoteUserPlane is a UPF the SMF drives over N4.
+type remoteUserPlane interface {
+	EstablishSession(ctx context.Context, req *models.EstablishRequest) (*models.EstablishResponse, error)
+	ModifySession(ctx context.Context, req *models.ModifyRequest) error
+	DeleteSession(ctx context.Context, seid uint64) error
+	HasSession(seid uint64) bool
+}
+
+// userPlaneRouter implements smf.UPFClient over the embedded UPF and the
+// remote ones: a session is established on the user plane serving its data
+// network, and later calls follow its SEID there.
+//
+// Remote user planes answer Router Solicitations themselves and report
+// usage on their URRs' period, so the RA, usage flush, usage threshold and
+// DDN suppression calls only reach the embedded UPF. The network rules
+// reach them from the settings reconciler instead, through
+// userPlaneUpdater, so UpdateFilters does too.
+type userPlaneRouter struct {
+	local  smf.UPFClient
+	byDNN  map[string]remoteUserPlane
+	remote []remoteUserPlane
+}
+
+func (r *userPlaneRouter) remoteFor(seid uint64) remoteUserPlane {
+	for _, up := range r.remote {
+		if up.HasSession(seid) {
+			return up
+		}
+	}
+
+	return nil
+}
+
+func (r *userPlaneRouter) EstablishSession(ctx context.Context, req *models.EstablishRequest) (*models.EstablishResponse, error) {
+	if up, ok := r.byDNN[req.DNN]; ok {
+		return up.EstablishSession(ctx, req)
+	}
+
+	return r.local.EstablishSession(ctx, req)
+}
+
+func (r *userPlaneRouter) ModifySession(ctx context.Context, req *models.ModifyRequest) error {
+	if up := r.remoteFor(req.SEID); up != nil {
+		return up.ModifySession(ctx, req)
+	}
+
+	return r.local.ModifySession(ctx, req)
+}
+
+func (r *userPlaneRouter) FlushUsage(ctx context.Context, seid uint64) {
+	if r.remoteFor(seid) == nil {
+		r.local.FlushUsage(ctx, seid)
+	}
+}
+
+func (r *userPlaneRouter) DeleteSession(ctx context.Context, seid uint64) error {
+	if up := r.remoteFor(seid); up != nil {
+		return up.DeleteSession(ctx, seid)
+	}
+
+	return r.local.DeleteSession(ctx, seid)
+}
+
+func (r *userPlaneRouter) SetUsageThreshold(ctx context.Context, seid uint64, bytes uint64) {
+	if r.remoteFor(seid) == nil {
+		r.local.SetUsageThreshold(ctx, seid, bytes)
+	}
+}
+
+func (r *userPlaneRouter) SuppressDownlinkDataNotification(ctx context.Context, seid uint64) {
+	if r.remoteFor(seid) == nil {
+		r.local.SuppressDownlinkDataNotification(ctx, seid)
+	}
+}
+
+func (r *userPlaneRouter) ClearDownlinkDataNotification(ctx context.Context, seid uint64) {
+	if r.remoteFor(seid) == nil {
+		r.local.ClearDownlinkDataNotification(ctx, seid)
+	}
+}
+
+func (r *userPlaneRouter) UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error {
+	return r.local.UpdateFilters(ctx, policyID, direction, rules)
+}
+
+func (r *userPlaneRouter) RegisterIPv6Session(ctx context.Context, reg *models.IPv6SessionRegistration) error {
+	if r.remoteFor(reg.SEID) != nil {
+		return nil
+	}
+
+	return r.local.RegisterIPv6Session(ctx, reg)
+}
+
+func (r *userPlaneRouter) UnregisterIPv6Session(ctx context.Context, seid uint64, ulTEID uint32) error {
+	if r.remoteFor(seid) != nil {
+		return nil
+	}
+
+	return r.local.UnregisterIPv6Session(ctx, seid, ulTEID)
+}
+
+// remoteFilters is the N4 endpoint's view of the network rules: it sends
+// them to every remote user plane as SDF-filtered PDRs.
+type remoteFilters interface {
+	UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error
+	UpdatePortalLifted(ctx context.Context, ues []netip.Addr) error
+}
+
+// userPlaneUpdater applies the network rules and captive portal lifts the
+// settings reconciler computes to the embedded UPF and to the remote user
+// planes alike. A failure on either is returned, so the reconciler retries
+// the lot.
+type userPlaneUpdater struct {
+	upf.Updater
+	remote remoteFilters
+}
+
+func (u *userPlaneUpdater) UpdateFilters(ctx context.Context, policyID string, direction models.Direction, rules []models.FilterRule) error {
+	return errors.Join(
+		u.Updater.UpdateFilters(ctx, policyID, direction, rules),
+		u.remote.UpdateFilters(ctx, policyID, direction, rules),
+	)
+}
+
+// UpdatePortalLifted ignores a datapath without captive portals, as the
+// reconciler does: the remote user planes still drop portal traffic until
+// the lift.
+func (u *userPlaneUpdater) UpdatePortalLifted(ues []netip.Addr) error {
+	err := u.Updater.UpdatePortalLifted(ues)
+	if errors.Is(err, ebpf.ErrCaptivePortalUnsupported) {
+		err = nil
+	}
+
+	return errors.Join(err, u.remote.UpdatePortalLifted(context.Background(), ues))
+}
+
+// reportRouter sends what the embedded UPF reports about the sessions of
+// the N4 server back to their control planes, and the rest to the SMF.
+type reportRouter struct {
+	smf    upfengine.SMFReportHandler
+	server atomic.Pointer[pfcp.Server]
+}
+
+func (r *reportRouter) handler(seid uint64) pfcp.ReportHandler {
+	if s := r.server.Load(); s != nil && s.Owns(seid) {
+		return s
+	}
+
+	return r.smf
+}
+
+func (r *reportRouter) HandleDownlinkDataReport(ctx context.Context, report *models.DownlinkDataReport) error {
+	return r.handler(report.SEID).HandleDownlinkDataReport(ctx, report)
+}
+
+func (r *reportRouter) HandleUsageReport(ctx context.Context, report *models.UsageReport) error {
+	return r.handler(report.SEID).HandleUsageReport(ctx, report)
+}
+
+func (r *reportRouter) HandleErrorIndicationReport(ctx context.Context, report *models.ErrorIndicationReport) error {
+	return r.handler(report.SEID).HandleErrorIndicationReport(ctx, report)
+}
+
+// HandlePathFailureReport splits the sessions tunnelled to the failed
+// access node between the SMF and the control planes.
+func (r *reportRouter) HandlePathFailureReport(ctx context.Context, report *models.PathFailureReport) error {
+	s := r.server.Load()
+	if s == nil {
+		return r.smf.HandlePathFailureReport(ctx, report)
+	}
+
+	local := &models.PathFailureReport{RemoteAddress: report.RemoteAddress}
+	served := &models.PathFailureReport{RemoteAddress: report.RemoteAddress}
+
+	for _, seid := range report.SEIDs {
+		if s.Owns(seid) {
+			served.SEIDs = append(served.SEIDs, seid)
+		} else {
+			local.SEIDs = append(local.SEIDs, seid)
+		}
+	}
+
+	var errs []error
+
+	if len(local.SEIDs) > 0 || len(served.SEIDs) == 0 {
+		errs = append(errs, r.smf.HandlePathFailureReport(ctx, local))
+	}
+
+	if len(served.SEIDs) > 0 {
+		errs = append(errs, s.HandlePathFailureReport(ctx, served))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SendFlowReports goes to the SMF, which attributes flows to subscribers:
+// the sessions of the N4 server have none here.
+func (r *reportRouter) SendFlowReports(ctx context.Context, reports []*models.FlowReportRequest) error {
+	return r.smf.SendFlowReports(ctx, reports)
+}
+
+// startUserPlanes opens the N4 endpoint toward the configured remote user
+// planes and wraps local so their data networks are routed to them. With no
+// user plane configured it returns local unchanged and a nil endpoint.
+func startUserPlanes(ctx context.Context, cfg config.Config, local smf.UPFClient, reports pfcp.ReportHandler) (smf.UPFClient, *pfcp.Endpoint, error) {
+	if len(cfg.UserPlanes) == 0 {
+		return local, nil, nil
+	}
+
+	endpoint, err := pfcp.Listen(cfg.Interfaces.N4.Address, reports)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't open the N4 endpoint: %w", err)
+	}
+
+	router := &userPlaneRouter{local: local, byDNN: make(map[string]remoteUserPlane)}
+
+	for _, up := range cfg.UserPlanes {
+		peer := endpoint.AddPeer(up.Name, up.Address)
+		router.remote = append(router.remote, peer)
+
+		for _, dnn := range up.DataNetworks {
+			router.byDNN[dnn] = peer
+		}
+
+		logger.EllaLog.Info("Serving data networks from a remote user plane",
+			zap.String("user-plane", up.Name), zap.Stringer("address", up.Address), zap.Strings("data-networks", up.DataNetworks))
+	}
+
+	endpoint.Start(ctx)
+
+	return router, endpoint, nil
+}
+
+// startControlPlanes serves N4 to the configured control planes, installing
+// their sessions on up, the embedded UPF. With no control plane configured
+// it returns a nil server.
+func startControlPlanes(ctx context.Context, cfg config.Config, up pfcp.UserPlane, reports *reportRouter) (*pfcp.Server, error) {
+	if len(cfg.ControlPlanes) == 0 {
+		return nil, nil
+	}
+
+	server, err := pfcp.NewServer(cfg.Interfaces.N4.Address, up, ebpf.MaxRulesPerFilter)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the N4 endpoint: %w", err)
+	}
+
+	for _, cp := range cfg.ControlPlanes {
+		server.AddControlPlane(cp.Name, cp.Address)
+
+		logger.EllaLog.Info("Serving as the user plane of a remote control plane",
+			zap.String("control-plane", cp.Name), zap.Stringer("address", cp.Address))
+	}
+
+	reports.server.Store(server)
+	server.Start(ctx)
+
+	return server, nil
+}
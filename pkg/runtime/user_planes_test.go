@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: Ella Networks Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/ellanetworks/core/internal/models"
+	"github.com/ellanetworks/core/internal/pfcp"
+	"github.com/ellanetworks/core/internal/upf"
+	"github.com/ellanetworks/core/internal/upf/ebpf"
+)
+
+// recordingUserPlane records which sessions reached it; it serves both as
+// the embedded UPF and as a remote one.
+type recordingUserPlane struct {
+	sessions map[uint64]bool
+	calls    []string
+}
+
+func newRecordingUserPlane() *recordingUserPlane {
+	return &recordingUserPlane{sessions: make(map[uint64]bool)}
+}
+
+func (u *recordingUserPlane) EstablishSession(_ context.Context, req *models.EstablishRequest) (*models.EstablishResponse, error) {
+	u.sessions[req.SEID] = true
+	u.calls = append(u.calls, "establish")
+
+	return &models.EstablishResponse{N3TEID: 1}, nil
+}
+
+func (u *recordingUserPlane) ModifySession(context.Context, *models.ModifyRequest) error {
+	u.calls = append(u.calls, "modify")
+	return nil
+}
+
+func (u *recordingUserPlane) DeleteSession(_ context.Context, seid uint64) error {
+	delete(u.sessions, seid)
+	u.calls = append(u.calls, "delete")
+
+	return nil
+}
+
+func (u *recordingUserPlane) HasSession(seid uint64) bool {
+	return u.sessions[seid]
+}
+
+func (u *recordingUserPlane) FlushUsage(context.Context, uint64) {
+	u.calls = append(u.calls, "flush")
+}
+
+func (u *recordingUserPlane) SetUsageThreshold(context.Context, uint64, uint64) {
+	u.calls = append(u.calls, "threshold")
+}
+
+func (u *recordingUserPlane) SuppressDownlinkDataNotification(context.Context, uint64) {}
+
+func (u *recordingUserPlane) ClearDownlinkDataNotification(context.Context, uint64) {}
+
+func (u *recordingUserPlane) UpdateFilters(context.Context, string, models.Direction, []models.FilterRule) error {
+	return nil
+}
+
+func (u *recordingUserPlane) RegisterIPv6Session(context.Context, *models.IPv6SessionRegistration) error {
+	u.calls = append(u.calls, "register-ra")
+	return nil
+}
+
+func (u *recordingUserPlane) UnregisterIPv6Session(context.Context, uint64, uint32) error {
+	u.calls = append(u.calls, "unregister-ra")
+	return nil
+}
+
+func TestUserPlaneRouter_RoutesByDataNetworkThenSEID(t *testing.T) {
+	local, edge := newRecordingUserPlane(), newRecordingUserPlane()
+	r := &userPlaneRouter{local: local, byDNN: map[string]remoteUserPlane{"edge-internet": edge}, remote: []remoteUserPlane{edge}}
+
+	ctx := context.Background()
+
+	for seid, dnn := range map[uint64]string{1: "internet", 2: "edge-internet"} {
+		if _, err := r.EstablishSession(ctx, &models.EstablishRequest{SEID: seid, DNN: dnn}); err != nil {
+			t.Fatalf("establish %d: %v", seid, err)
+		}
+	}
+
+	if !local.sessions[1] || local.sessions[2] || !edge.sessions[2] || edge.sessions[1] {
+		t.Fatalf("local %v edge %v, want session 1 local and 2 on the edge", local.sessions, edge.sessions)
+	}
+
+	for _, seid := range []uint64{1, 2} {
+		_ = r.ModifySession(ctx, &models.ModifyRequest{SEID: seid})
+		r.FlushUsage(ctx, seid)
+		_ = r.RegisterIPv6Session(ctx, &models.IPv6SessionRegistration{SEID: seid})
+		_ = r.UnregisterIPv6Session(ctx, seid, 1)
+		_ = r.DeleteSession(ctx, seid)
+	}
+
+	want := []string{"establish", "modify", "flush", "register-ra", "unregister-ra", "delete"}
+	if len(local.calls) != len(want) {
+		t.Fatalf("local calls = %v, want %v", local.calls, want)
+	}
+
+	// The edge takes no flush or RA calls: it reports usage and answers
+	// Router Solicitations itself.
+	wantEdge := []string{"establish", "modify", "delete"}
+	if len(edge.calls) != len(wantEdge) {
+		t.Fatalf("edge calls = %v, want %v", edge.calls, wantEdge)
+	}
+
+	for i := range wantEdge {
+		if edge.calls[i] != wantEdge[i] {
+			t.Fatalf("edge calls = %v, want %v", edge.calls, wantEdge)
+		}
+	}
+}
+
+// recordingFilters records the network rules and portal lifts that reach
+// it, as the embedded UPF or as the N4 endpoint.
+type recordingFilters struct {
+	upf.Updater
+
+	filters   []string
+	lifted    [][]netip.Addr
+	err       error
+	portalErr error
+}
+
+func (f *recordingFilters) UpdateFilters(_ context.Context, policyID string, direction models.Direction, _ []models.FilterRule) error {
+	f.filters = append(f.filters, policyID+":"+direction.String())
+	return f.err
+}
+
+func (f *recordingFilters) UpdatePortalLifted(ues []netip.Addr) error {
+	f.lifted = append(f.lifted, ues)
+	return f.portalErr
+}
+
+type recordingRemoteFilters struct{ recordingFilters }
+
+func (f *recordingRemoteFilters) UpdatePortalLifted(_ context.Context, ues []netip.Addr) error {
+	return f.recordingFilters.UpdatePortalLifted(ues)
+}
+
+func TestUserPlaneUpdater_SendsNetworkRulesToRemoteUserPlanes(t *testing.T) {
+	local := &recordingFilters{portalErr: ebpf.ErrCaptivePortalUnsupported}
+	remote := &recordingRemoteFilters{recordingFilters{err: errors.New("user plane edge: no response")}}
+	u := &userPlaneUpdater{Updater: local, remote: remote}
+
+	if err := u.UpdateFilters(context.Background(), "policy-1", models.DirectionUplink, nil); err == nil {
+		t.Fatal("a remote user plane's failure must be returned, so the reconciler retries")
+	}
+
+	if !slices.Equal(local.filters, remote.filters) || len(remote.filters) != 1 {
+		t.Fatalf("local %v remote %v, want the rules on both", local.filters, remote.filters)
+	}
+
+	ues := []netip.Addr{netip.MustParseAddr("10.45.0.2")}
+
+	// A datapath without captive portals has nothing to lift, but the
+	// remote user planes drop portal traffic until they are told.
+	if err := u.UpdatePortalLifted(ues); err != nil {
+		t.Fatalf("portal lift: %v", err)
+	}
+
+	if len(remote.lifted) != 1 || !slices.Equal(remote.lifted[0], ues) {
+		t.Fatalf("remote lifts = %v, want %v", remote.lifted, ues)
+	}
+}
+
+type recordingReports struct {
+	usage        []uint64
+	pathFailures [][]uint64
+}
+
+func (r *recordingReports) HandleDownlinkDataReport(context.Context, *models.DownlinkDataReport) error {
+	return nil
+}
+
+func (r *recordingReports) HandleUsageReport(_ context.Context, report *models.UsageReport) error {
+	r.usage = append(r.usage, report.SEID)
+	return nil
+}
+
+func (r *recordingReports) HandleErrorIndicationReport(context.Context, *models.ErrorIndicationReport) error {
+	return nil
+}
+
+func (r *recordingReports) HandlePathFailureReport(_ context.Context, report *models.PathFailureReport) error {
+	r.pathFailures = append(r.pathFailures, report.SEIDs)
+	return nil
+}
+
+func (r *recordingReports) SendFlowReports(context.Context, []*models.FlowReportRequest) error {
+	return nil
+}
+
+func TestReportRouter_SendsServedSessionsToTheirControlPlane(t *testing.T) {
+	server, err := pfcp.NewServer(netip.MustParseAddrPort("127.0.0.1:0"), nil, ebpf.MaxRulesPerFilter)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	defer server.Close()
+
+	smfReports := &recordingReports{}
+	r := &reportRouter{smf: smfReports}
+
+	const served = uint64(1)<<63 | 1
+
+	if err := r.HandleUsageReport(context.Background(), &models.UsageReport{SEID: served}); err != nil {
+		t.Fatalf("usage before the server starts: %v", err)
+	}
+
+	r.server.Store(server)
+
+	// The server holds no such session, so it refuses the report rather
+	// than the SMF taking it.
+	if err := r.HandleUsageReport(context.Background(), &models.UsageReport{SEID: served}); err == nil {
+		t.Fatal("the server took no report for an unknown session")
+	}
+
+	if err := r.HandlePathFailureReport(context.Background(), &models.PathFailureReport{SEIDs: []uint64{3, served, 4}}); err != nil {
+		t.Fatalf("path failure: %v", err)
+	}
+
+	if !slices.Equal(smfReports.usage, []uint64{served}) {
+		t.Fatalf("SMF usage = %v, want only the report from before the server", smfReports.usage)
+	}
+
+	if len(smfReports.pathFailures) != 1 || !slices.Equal(smfReports.pathFailures[0], []uint64{3, 4}) {
+		t.Fatalf("SMF path failures = %v, want its own sessions", smfReports.pathFailures)
+	}
+}
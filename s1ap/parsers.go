@@ -55,6 +55,9 @@ var messageParsers = []messageParser{
 	{"ParseUEContextReleaseCommand", func(v []byte) error { _, err := ParseUEContextReleaseCommand(v); return err }},
 	{"ParseUEContextReleaseComplete", func(v []byte) error { _, err := ParseUEContextReleaseComplete(v); return err }},
 	{"ParseUEContextReleaseRequest", func(v []byte) error { _, err := ParseUEContextReleaseRequest(v); return err }},
+	{"ParseUEContextModificationRequest", func(v []byte) error { _, err := ParseUEContextModificationRequest(v); return err }},
+	{"ParseUEContextModificationResponse", func(v []byte) error { _, err := ParseUEContextModificationResponse(v); return err }},
+	{"ParseUEContextModificationFailure", func(v []byte) error { _, err := ParseUEContextModificationFailure(v); return err }},
 	{"ParseUplinkNASTransport", func(v []byte) error { _, err := ParseUplinkNASTransport(v); return err }},
 	{"ParseDownlinkNonUEAssociatedLPPaTransport", func(v []byte) error {
 		_, err := ParseDownlinkNonUEAssociatedLPPaTransport(v)
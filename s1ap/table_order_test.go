@@ -62,6 +62,9 @@ func TestTableOrderMatchesASN1(t *testing.T) {
 		{"UEContextReleaseCommand", tableIDs(uEContextReleaseCommandIEs), []ProtocolIEID{IDUES1APIDs, IDCause}},
 		{"UEContextReleaseComplete", tableIDs(uEContextReleaseCompleteIEs), []ProtocolIEID{IDMMEUES1APID, IDENBUES1APID, IDCriticalityDiagnostics, IDUserLocationInformation}},
 		{"UEContextReleaseRequest", tableIDs(uEContextReleaseRequestIEs), []ProtocolIEID{IDMMEUES1APID, IDENBUES1APID, IDCause}},
+		{"UEContextModificationRequest", tableIDs(uEContextModificationRequestIEs), []ProtocolIEID{IDMMEUES1APID, IDENBUES1APID, IDUEAggregateMaximumBitrate}},
+		{"UEContextModificationResponse", tableIDs(uEContextModificationResponseIEs), []ProtocolIEID{IDMMEUES1APID, IDENBUES1APID, IDCriticalityDiagnostics}},
+		{"UEContextModificationFailure", tableIDs(uEContextModificationFailureIEs), []ProtocolIEID{IDMMEUES1APID, IDENBUES1APID, IDCause, IDCriticalityDiagnostics}},
 	}
 
 	for _, tt := range tests {
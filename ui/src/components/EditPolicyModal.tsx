@@ -91,6 +91,7 @@ const EditPolicyModal: React.FC<EditPolicyModalProps> = ({
       var5qi: values.fiveQi,
       arp: values.arp,
       rules: currentRules,
+      scheduled_ambr: policy?.scheduled_ambr,
       default: values.isDefault,
     });
   };
@@ -46,8 +46,60 @@ const schema = yup.object({
     .max(256, "Description must be 256 characters or fewer"),
   action: yup
     .string()
-    .oneOf(["allow", "deny"], "Invalid action")
+    .oneOf(["allow", "deny", "rate_limit", "breakout"], "Invalid action")
     .required("Action is required"),
+  rateLimit: yup
+    .string()
+    .default("")
+    .when("action", {
+      is: "rate_limit",
+      then: (s) =>
+        s
+          .required("Rate limit is required")
+          .matches(
+            /^[1-9]\d* (Kbps|Mbps|Gbps)$/,
+            "Must be a bitrate such as 5 Mbps",
+          ),
+    }),
+  rateLimitScope: yup
+    .string()
+    .oneOf(["session", "rule"], "Invalid scope")
+    .default("session"),
+  breakoutInterface: yup
+    .string()
+    .default("")
+    .when("action", {
+      is: "breakout",
+      then: (s) =>
+        s
+          .required("Interface is required")
+          .matches(/^[^/:\s]{1,15}$/, "Must be a valid interface name"),
+    }),
+  breakoutVlanId: yup
+    .string()
+    .default("")
+    .test("valid-vlan", "VLAN ID must be between 1 and 4094", (val) => {
+      if (!val) return true;
+      const num = Number(val);
+      return Number.isInteger(num) && num >= 1 && num <= 4094;
+    }),
+  breakoutRoutingTable: yup
+    .string()
+    .default("")
+    .test(
+      "valid-table",
+      "Must be a table number other than 253, 254 or 255",
+      (val) => {
+        if (!val) return true;
+        const num = Number(val);
+        return (
+          Number.isInteger(num) &&
+          num >= 1 &&
+          num <= 4294967295 &&
+          ![253, 254, 255].includes(num)
+        );
+      },
+    ),
   remotePrefix: yup
     .string()
     .default("")
@@ -79,6 +131,11 @@ export type PolicyRuleFormValues = yup.InferType<typeof schema>;
 export const EMPTY_RULE_FORM: PolicyRuleFormValues = {
   description: "",
   action: "allow",
+  rateLimit: "",
+  rateLimitScope: "session",
+  breakoutInterface: "",
+  breakoutVlanId: "",
+  breakoutRoutingTable: "",
   remotePrefix: "",
   protocol: "",
   portLow: "",
@@ -88,6 +145,13 @@ export const EMPTY_RULE_FORM: PolicyRuleFormValues = {
 const ACTION_OPTIONS = [
   { value: "allow", label: "Allow" },
   { value: "deny", label: "Deny" },
+  { value: "rate_limit", label: "Rate limit" },
+  { value: "breakout", label: "Local breakout" },
+] as const;
+
+const RATE_LIMIT_SCOPE_OPTIONS = [
+  { value: "session", label: "Per session" },
+  { value: "rule", label: "Shared by all sessions" },
 ] as const;
 
 interface PolicyRuleFormDialogProps {
@@ -96,6 +160,8 @@ interface PolicyRuleFormDialogProps {
   onSave: (values: PolicyRuleFormValues) => void;
   initialValues: PolicyRuleFormValues;
   isEditing: boolean;
+  // Breakout is only offered on uplink rules.
+  direction: "uplink" | "downlink";
 }
 
 const PolicyRuleFormDialog: React.FC<PolicyRuleFormDialogProps> = ({
@@ -104,6 +170,7 @@ const PolicyRuleFormDialog: React.FC<PolicyRuleFormDialogProps> = ({
   onSave,
   initialValues,
   isEditing,
+  direction,
 }) => {
   const form = useForm<PolicyRuleFormValues>({
     mode: "onTouched",
@@ -116,6 +183,11 @@ const PolicyRuleFormDialog: React.FC<PolicyRuleFormDialogProps> = ({
     name: "protocol",
   });
   const showProtocolError = !!protocolState.error && protocolState.isTouched;
+  const isRateLimit = form.watch("action") === "rate_limit";
+  const isBreakout = form.watch("action") === "breakout";
+  const actionOptions = ACTION_OPTIONS.filter(
+    (option) => direction === "uplink" || option.value !== "breakout",
+  );
 
   const submit = async (values: PolicyRuleFormValues) => {
     onSave(values);
@@ -141,8 +213,51 @@ const PolicyRuleFormDialog: React.FC<PolicyRuleFormDialogProps> = ({
       <SelectControl<PolicyRuleFormValues, string>
         name="action"
         label="Action"
-        options={ACTION_OPTIONS}
+        options={actionOptions}
       />
+      {isRateLimit && (
+        <Box sx={{ display: "flex", gap: 2 }}>
+          <TextControl<PolicyRuleFormValues>
+            name="rateLimit"
+            label="Rate Limit"
+            placeholder="e.g., 5 Mbps"
+            helperText="Matching traffic above this rate is dropped"
+            sx={{ flex: 1 }}
+          />
+          <Box sx={{ flex: 1 }}>
+            <SelectControl<PolicyRuleFormValues, string>
+              name="rateLimitScope"
+              label="Applies"
+              options={RATE_LIMIT_SCOPE_OPTIONS}
+            />
+          </Box>
+        </Box>
+      )}
+      {isBreakout && (
+        <Box sx={{ display: "flex", gap: 2 }}>
+          <TextControl<PolicyRuleFormValues>
+            name="breakoutInterface"
+            label="Breakout Interface"
+            placeholder="e.g., eth2"
+            helperText="Matching traffic leaves through this interface"
+            sx={{ flex: 2 }}
+          />
+          <TextControl<PolicyRuleFormValues>
+            name="breakoutVlanId"
+            label="VLAN ID"
+            type="number"
+            helperText="Optional"
+            sx={{ flex: 1 }}
+          />
+          <TextControl<PolicyRuleFormValues>
+            name="breakoutRoutingTable"
+            label="Routing Table"
+            type="number"
+            helperText="Optional — main if empty"
+            sx={{ flex: 1 }}
+          />
+        </Box>
+      )}
       <TextControl<PolicyRuleFormValues>
         name="remotePrefix"
         label="Remote Prefix (CIDR)"
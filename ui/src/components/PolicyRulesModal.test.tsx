@@ -224,6 +224,67 @@ describe("PolicyRulesModal", () => {
     });
   });
 
+  it("saves a rate limit with its scope", async () => {
+    const user = userEvent.setup();
+    api.put(POLICY_PATH, () => ({}));
+    render();
+
+    await user.click(screen.getByRole("button", { name: /Add Rule/ }));
+    const form = ruleForm();
+    await user.type(within(form).getByLabelText(/Description/), "updates");
+    await user.click(within(form).getByLabelText(/Action/));
+    await user.click(await screen.findByRole("option", { name: "Rate limit" }));
+    await user.type(within(form).getByLabelText(/Rate Limit/), "2 Mbps");
+    await user.click(within(form).getByLabelText(/Applies/));
+    await user.click(
+      await screen.findByRole("option", { name: "Shared by all sessions" }),
+    );
+    await user.click(within(form).getByRole("button", { name: /^Add$/ }));
+
+    await waitFor(() =>
+      expect(screen.getByText("≤ 2 Mbps")).toBeInTheDocument(),
+    );
+    await user.click(
+      within(await rulesDialog()).getByRole("button", { name: /^Save$/ }),
+    );
+
+    await waitFor(() => expect(savedRules().uplink).toHaveLength(3));
+    expect(savedRules().uplink?.[2]).toMatchObject({
+      description: "updates",
+      action: "rate_limit",
+      rate_limit: "2 Mbps",
+      rate_limit_scope: "rule",
+    });
+  });
+
+  it("adds a local breakout rule with its interface", async () => {
+    const user = userEvent.setup();
+    render();
+
+    await user.click(screen.getByRole("button", { name: /Add Rule/ }));
+    const form = ruleForm();
+    await user.type(within(form).getByLabelText(/Description/), "corporate");
+    await user.click(within(form).getByLabelText(/Action/));
+    await user.click(
+      await screen.findByRole("option", { name: "Local breakout" }),
+    );
+    await user.type(within(form).getByLabelText(/Breakout Interface/), "eth2");
+    await user.type(within(form).getByLabelText(/VLAN ID/), "30");
+    await user.click(within(form).getByRole("button", { name: /^Add$/ }));
+
+    await waitFor(() => expect(screen.getByText("→ eth2")).toBeInTheDocument());
+    await user.click(
+      within(await rulesDialog()).getByRole("button", { name: /^Save$/ }),
+    );
+
+    await waitFor(() => expect(savedRules().uplink).toHaveLength(3));
+    expect(savedRules().uplink?.[2]).toMatchObject({
+      description: "corporate",
+      action: "breakout",
+      breakout: { interface: "eth2", vlan_id: 30 },
+    });
+  });
+
   it("keeps the dialog open and reports a failed save", async () => {
     const user = userEvent.setup();
     api.put(POLICY_PATH, () => httpError(500, "rules rejected"));
@@ -16,7 +16,7 @@ import {
   type PolicyRule,
 } from "@/queries/policies";
 import { useAuth } from "@/contexts/AuthContext";
-import { PROTOCOL_NAMES } from "@/utils/formatters";
+import { PROTOCOL_NAMES, formatRuleAction } from "@/utils/formatters";
 import IPProtocolChip from "@/components/IPProtocolChip";
 import FormDialog from "@/components/form/FormDialog";
 import PolicyRuleFormDialog, {
@@ -33,7 +33,7 @@ interface PolicyRulesModalProps {
   direction: "uplink" | "downlink";
 }
 
-type Action = "allow" | "deny";
+type Action = PolicyRule["action"];
 
 interface InMemoryRule {
   tempId: string;
@@ -43,6 +43,15 @@ interface InMemoryRule {
   protocol: number;
   port_low: number;
   port_high: number;
+  // Not edited here, but carried so saving keeps them.
+  fqdn?: string;
+  match_sni?: boolean;
+  rate_limit?: string;
+  rate_limit_scope?: PolicyRule["rate_limit_scope"];
+  schedule?: string;
+  breakout?: PolicyRule["breakout"];
+  rating_group?: number;
+  zero_rated?: boolean;
 }
 
 interface FormValues {
@@ -58,6 +67,13 @@ const formatPorts = (rule: InMemoryRule): string => {
 const toFormValues = (rule: InMemoryRule): PolicyRuleFormValues => ({
   description: rule.description,
   action: rule.action,
+  rateLimit: rule.rate_limit || "",
+  rateLimitScope: rule.rate_limit_scope || "session",
+  breakoutInterface: rule.breakout?.interface || "",
+  breakoutVlanId: rule.breakout?.vlan_id ? String(rule.breakout.vlan_id) : "",
+  breakoutRoutingTable: rule.breakout?.routing_table
+    ? String(rule.breakout.routing_table)
+    : "",
   remotePrefix: rule.remote_prefix || "",
   protocol:
     rule.protocol !== 0
@@ -70,6 +86,23 @@ const toFormValues = (rule: InMemoryRule): PolicyRuleFormValues => ({
 const fromFormValues = (values: PolicyRuleFormValues) => ({
   description: values.description,
   action: values.action as Action,
+  rate_limit: values.action === "rate_limit" ? values.rateLimit : undefined,
+  rate_limit_scope:
+    values.action === "rate_limit"
+      ? (values.rateLimitScope as PolicyRule["rate_limit_scope"])
+      : undefined,
+  breakout:
+    values.action === "breakout"
+      ? {
+          interface: values.breakoutInterface,
+          vlan_id: values.breakoutVlanId
+            ? Number(values.breakoutVlanId)
+            : undefined,
+          routing_table: values.breakoutRoutingTable
+            ? Number(values.breakoutRoutingTable)
+            : undefined,
+        }
+      : undefined,
   remote_prefix: values.remotePrefix || undefined,
   protocol: (values.protocol ? parseProtocol(values.protocol) : undefined) ?? 0,
   port_low: values.portLow ? Number(values.portLow) : 0,
@@ -84,6 +117,14 @@ const toApiRules = (rules: InMemoryRule[]): PolicyRule[] =>
     port_low: rule.port_low,
     port_high: rule.port_high,
     action: rule.action,
+    fqdn: rule.fqdn,
+    match_sni: rule.match_sni,
+    rate_limit: rule.rate_limit,
+    rate_limit_scope: rule.rate_limit_scope,
+    schedule: rule.schedule,
+    breakout: rule.breakout,
+    rating_group: rule.rating_group,
+    zero_rated: rule.zero_rated,
   }));
 
 const PolicyRulesModal: React.FC<PolicyRulesModalProps> = ({
@@ -109,6 +150,14 @@ const PolicyRulesModal: React.FC<PolicyRulesModalProps> = ({
         protocol: rule.protocol,
         port_low: rule.port_low,
         port_high: rule.port_high,
+        fqdn: rule.fqdn,
+        match_sni: rule.match_sni,
+        rate_limit: rule.rate_limit,
+        rate_limit_scope: rule.rate_limit_scope,
+        schedule: rule.schedule,
+        breakout: rule.breakout,
+        rating_group: rule.rating_group,
+        zero_rated: rule.zero_rated,
       })),
     },
   });
@@ -216,6 +265,7 @@ const PolicyRulesModal: React.FC<PolicyRulesModalProps> = ({
       var5qi: policy.var5qi,
       arp: policy.arp,
       rules: updatedRules,
+      scheduled_ambr: policy.scheduled_ambr,
     });
   };
 
@@ -278,9 +328,9 @@ const PolicyRulesModal: React.FC<PolicyRulesModalProps> = ({
                 </Typography>
                 <Box sx={{ width: 72, flexShrink: 0 }}>
                   <Chip
-                    label={rule.action.toUpperCase()}
+                    label={formatRuleAction(rule).label}
                     size="small"
-                    color={rule.action === "allow" ? "success" : "error"}
+                    color={formatRuleAction(rule).color}
                     variant="outlined"
                   />
                 </Box>
@@ -365,6 +415,7 @@ const PolicyRulesModal: React.FC<PolicyRulesModalProps> = ({
         onSave={saveRule}
         initialValues={editingValues}
         isEditing={!!editingRuleId}
+        direction={direction}
       />
     </>
   );
@@ -43,6 +43,7 @@ import PolicyRulesModal from "@/components/PolicyRulesModal";
 import DeleteConfirmationModal from "@/components/DeleteConfirmationModal";
 import QueryState from "@/components/QueryState";
 import { MAX_WIDTH, PAGE_PADDING_X } from "@/utils/layout";
+import { formatRuleAction } from "@/utils/formatters";
 import IPProtocolChip from "@/components/IPProtocolChip";
 
 const labelCellSx = { fontWeight: 600, width: "35%" } as const;
@@ -134,8 +135,8 @@ const PolicyDetail: React.FC = () => {
           >
             <Chip
               size="small"
-              label={params.row.action.toUpperCase()}
-              color={params.row.action === "allow" ? "success" : "error"}
+              label={formatRuleAction(params.row).label}
+              color={formatRuleAction(params.row).color}
               variant="outlined"
             />
           </Box>
@@ -165,9 +165,13 @@ const S1AP_MESSAGE_TYPES = [
   "UplinkNASTransport",
 ];
 
+// GTP-U path events recorded by the UPF (see internal/upf/gtpu_path.go).
+const GTPU_MESSAGE_TYPES = ["ErrorIndication", "PathFailure", "PathRestored"];
+
 const MESSAGE_TYPES_BY_PROTOCOL: Record<string, string[]> = {
   NGAP: NGAP_MESSAGE_TYPES,
   S1AP: S1AP_MESSAGE_TYPES,
+  "GTP-U": GTPU_MESSAGE_TYPES,
 };
 
 const DirectionCell: React.FC<{ value?: string }> = ({ value }) => {
@@ -258,6 +262,7 @@ export default function EventsTab() {
       MESSAGE_TYPES_BY_PROTOCOL[protocolFilter] ?? [
         ...NGAP_MESSAGE_TYPES,
         ...S1AP_MESSAGE_TYPES,
+        ...GTPU_MESSAGE_TYPES,
       ],
     [protocolFilter],
   );
@@ -506,7 +511,7 @@ export default function EventsTab() {
   );
 
   const subDescription =
-    "Review NGAP (5G) and S1AP (4G) control-plane messages exchanged between Ella Core and connected radios. These logs are useful for auditing and troubleshooting purposes.";
+    "Review NGAP (5G) and S1AP (4G) control-plane messages exchanged between Ella Core and connected radios, and GTP-U path events on N3. These logs are useful for auditing and troubleshooting purposes.";
 
   const closePanel = useCallback(() => {
     setViewEventDrawerOpen(false);
@@ -600,6 +605,7 @@ export default function EventsTab() {
             <MenuItem value="">All</MenuItem>
             <MenuItem value="NGAP">NGAP (5G)</MenuItem>
             <MenuItem value="S1AP">S1AP (4G)</MenuItem>
+            <MenuItem value="GTP-U">GTP-U (N3)</MenuItem>
           </TextField>
           <TextField
             select
@@ -657,6 +663,12 @@ export default function EventsTab() {
                       {mt}
                     </MenuItem>
                   )),
+                  <ListSubheader key="gtpu-header">GTP-U (N3)</ListSubheader>,
+                  ...GTPU_MESSAGE_TYPES.map((mt) => (
+                    <MenuItem key={`gtpu-${mt}`} value={mt}>
+                      {mt}
+                    </MenuItem>
+                  )),
                 ]}
           </TextField>
           <TextField
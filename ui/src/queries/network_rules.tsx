@@ -12,7 +12,7 @@ export type NetworkRule = {
   protocol: number;
   port_low: number;
   port_high: number;
-  action: "allow" | "deny";
+  action: "allow" | "deny" | "rate_limit" | "breakout";
   precedence: number;
   created_at: string;
   updated_at: string;
@@ -9,7 +9,27 @@ export type PolicyRule = {
   protocol: number;
   port_low: number;
   port_high: number;
-  action: "allow" | "deny";
+  action: "allow" | "deny" | "rate_limit" | "breakout";
+  fqdn?: string;
+  match_sni?: boolean;
+  rate_limit?: string;
+  rate_limit_scope?: "session" | "rule";
+  schedule?: string;
+  breakout?: RuleBreakout;
+  rating_group?: number;
+  zero_rated?: boolean;
+};
+
+export type RuleBreakout = {
+  interface: string;
+  vlan_id?: number;
+  routing_table?: number;
+};
+
+export type ScheduledAmbr = {
+  schedule: string;
+  session_ambr_uplink: string;
+  session_ambr_downlink: string;
 };
 
 export type PolicyRules = {
@@ -27,6 +47,7 @@ export type APIPolicy = {
   var5qi: number;
   arp: number;
   rules?: PolicyRules;
+  scheduled_ambr?: ScheduledAmbr;
   default: boolean;
 };
 
@@ -92,6 +113,7 @@ export async function updatePolicy(
     var5qi: number;
     arp: number;
     rules?: PolicyRules;
+    scheduled_ambr?: ScheduledAmbr;
     default?: boolean;
   },
 ): Promise<void> {
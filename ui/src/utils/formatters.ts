@@ -215,6 +215,23 @@ export const PROTOCOL_NAMES: Record<number, string> = {
 export const formatProtocol = (value: number): string =>
   PROTOCOL_NAMES[value] ?? String(value);
 
+/**
+ * A rate-limit rule reads as its rate and a breakout rule as its interface,
+ * which is what sets them apart.
+ */
+export const formatRuleAction = (rule: {
+  action: string;
+  rate_limit?: string;
+  breakout?: { interface: string };
+}): { label: string; color: "success" | "error" | "warning" | "info" } => {
+  if (rule.action === "rate_limit")
+    return { label: `≤ ${rule.rate_limit ?? "?"}`, color: "warning" };
+  if (rule.action === "breakout")
+    return { label: `→ ${rule.breakout?.interface ?? "?"}`, color: "info" };
+  if (rule.action === "allow") return { label: "ALLOW", color: "success" };
+  return { label: rule.action.toUpperCase(), color: "error" };
+};
+
 /**
  * Names the unit a share is measured in, so a share of flows cannot be read as
  * a share of bytes.